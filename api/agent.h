@@ -43,6 +43,23 @@ struct cp_device;
 struct yanet_shm *
 yanet_shm_attach(const char *path);
 
+// Attaches to YANET shared memory segment in read-only mode.
+//
+// The segment is mapped without write permission, so the handle is suitable
+// for inspection tools only: agent_attach() and agent_reattach() fail on it,
+// and routines acquiring the controlplane configuration lock must not be
+// called.
+//
+// @param path Path to the shared memory file (e.g. "/dev/hugepages/yanet").
+//
+// @return Handle to the shared memory segment on success.
+//         On failure, the function return NULL and set errno to indicate the
+//         error.
+//         The caller is responsible for detaching the handle using
+//         yanet_shm_detach().
+struct yanet_shm *
+yanet_shm_attach_ro(const char *path);
+
 // Detaches from YANET shared memory segment.
 //
 // Releases all resources associated with the shared memory handle.
@@ -79,6 +96,7 @@ agent_dp_config_ready(struct yanet_shm *shm, uint32_t instance_idx);
 //
 // Creates a new agent for a specific module in the given dataplane instance.
 // The agent provides module-specific operations and memory management.
+// Fails for a segment attached with yanet_shm_attach_ro().
 //
 // @param shm Handle to shared memory segment
 // @param instance_idx Index of the dataplane instance where the agent should
@@ -157,6 +175,16 @@ agent_detach(struct agent *agent);
 struct dp_config *
 agent_dp_config(struct agent *agent);
 
+// Returns 1 if the dataplane instance the agent is attached to is ready,
+// 0 otherwise.
+//
+// Like agent_dp_config_ready, it neither allocates nor acquires any lock, so
+// it may be polled to report the health of the agent.
+//
+// @param agent Handle to the module agent
+int
+agent_dp_ready(struct agent *agent);
+
 int
 agent_update_modules(
 	struct agent *agent,
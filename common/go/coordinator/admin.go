@@ -0,0 +1,327 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/coordinatorpb/v1"
+)
+
+// AdminOption configures NewAdminService.
+type AdminOption func(*adminOptions)
+
+type adminOptions struct {
+	Registry *Registry
+	Recovery *Recovery
+	Log      *zap.Logger
+}
+
+func newAdminOptions() *adminOptions {
+	return &adminOptions{
+		Log: zap.NewNop(),
+	}
+}
+
+// WithAdminRegistry sets the registry whose instances the admin service
+// lists.
+func WithAdminRegistry(registry *Registry) AdminOption {
+	return func(o *adminOptions) {
+		o.Registry = registry
+	}
+}
+
+// WithAdminRecovery sets the recovery the admin service reports and
+// controls.
+func WithAdminRecovery(recovery *Recovery) AdminOption {
+	return func(o *adminOptions) {
+		o.Recovery = recovery
+	}
+}
+
+// WithAdminLog sets the logger of the admin service.
+func WithAdminLog(log *zap.Logger) AdminOption {
+	return func(o *adminOptions) {
+		o.Log = log
+	}
+}
+
+// AdminService implements coordinatorpb.AdminService on top of the
+// registry and the recovery of a coordinator.
+//
+// Either may be left out: the instances of a missing registry list empty,
+// while controlling a missing recovery fails with FAILED_PRECONDITION.
+type AdminService struct {
+	coordinatorpb.UnimplementedAdminServiceServer
+
+	registry *Registry
+	recovery *Recovery
+	log      *zap.Logger
+}
+
+// NewAdminService creates the admin service of a coordinator.
+func NewAdminService(options ...AdminOption) *AdminService {
+	opts := newAdminOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &AdminService{
+		registry: opts.Registry,
+		recovery: opts.Recovery,
+		log:      opts.Log,
+	}
+}
+
+// ListInstances lists the instances of the registry.
+func (m *AdminService) ListInstances(
+	ctx context.Context,
+	req *coordinatorpb.ListInstancesRequest,
+) (*coordinatorpb.ListInstancesResponse, error) {
+	response := &coordinatorpb.ListInstancesResponse{}
+	if m.registry == nil {
+		return response, nil
+	}
+
+	for _, instance := range m.registry.List() {
+		response.Instances = append(response.Instances, &coordinatorpb.Instance{
+			Name:        instance.Name,
+			Generation:  instance.Generation,
+			State:       instanceStateToProto(instance.State),
+			CreatedAt:   unixNano(instance.CreatedAt),
+			Restarts:    uint64(instance.Restarts),
+			LastError:   errorString(instance.LastError),
+			LastErrorAt: unixNano(instance.LastErrorAt),
+		})
+	}
+	return response, nil
+}
+
+// ListConfigs lists the configurations known to the recovery.
+func (m *AdminService) ListConfigs(
+	ctx context.Context,
+	req *coordinatorpb.ListConfigsRequest,
+) (*coordinatorpb.ListConfigsResponse, error) {
+	response := &coordinatorpb.ListConfigsResponse{}
+	if m.recovery == nil {
+		return response, nil
+	}
+
+	for _, config := range m.recovery.Configs() {
+		response.Configs = append(response.Configs, &coordinatorpb.Config{
+			Instance:      config.Instance,
+			Module:        config.Module,
+			Name:          config.Name,
+			Version:       config.Version,
+			Digest:        config.Digest,
+			Pending:       config.Pending,
+			LastAppliedAt: unixNano(config.AppliedAt),
+			LastError:     errorString(config.LastError),
+			LastErrorAt:   unixNano(config.LastErrorAt),
+		})
+	}
+	return response, nil
+}
+
+// Repush applies the configurations of a dataplane instance again.
+func (m *AdminService) Repush(
+	ctx context.Context,
+	req *coordinatorpb.RepushRequest,
+) (*coordinatorpb.RepushResponse, error) {
+	if m.recovery == nil {
+		return nil, status.Error(codes.FailedPrecondition, "recovery is not enabled")
+	}
+
+	selected, failed := m.recovery.Repush(ctx, req.GetInstance(), req.GetModule())
+	if selected == 0 {
+		return nil, status.Errorf(codes.NotFound, "no configs of module %q known for instance %d", req.GetModule(), req.GetInstance())
+	}
+
+	m.log.Info("applied configs again on request",
+		zap.Uint32("instance", req.GetInstance()),
+		zap.String("module", req.GetModule()),
+		zap.Int("configs", selected),
+		zap.Int("failed", failed),
+	)
+
+	return &coordinatorpb.RepushResponse{
+		Configs: uint64(selected),
+		Failed:  uint64(failed),
+	}, nil
+}
+
+// RollbackConfig applies a previous version of a configuration again.
+func (m *AdminService) RollbackConfig(
+	ctx context.Context,
+	req *coordinatorpb.RollbackConfigRequest,
+) (*coordinatorpb.RollbackConfigResponse, error) {
+	if m.recovery == nil {
+		return nil, status.Error(codes.FailedPrecondition, "recovery is not enabled")
+	}
+	if req.GetModule() == "" || req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "module and name are required")
+	}
+
+	version, err := m.recovery.Rollback(ctx, req.GetInstance(), req.GetModule(), req.GetName(), req.GetVersion())
+	if errors.Is(err, ErrVersionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		// Keep the code of a module service refusing the rollback.
+		code := codes.Internal
+		if st, ok := status.FromError(err); ok {
+			code = st.Code()
+		}
+		return nil, status.Errorf(code, "%s:%s: %v", req.GetModule(), req.GetName(), err)
+	}
+
+	m.log.Info("rolled config back on request",
+		zap.Uint32("instance", req.GetInstance()),
+		zap.String("module", req.GetModule()),
+		zap.String("name", req.GetName()),
+		zap.Uint64("target_version", req.GetVersion()),
+		zap.Uint64("version", version),
+	)
+
+	return &coordinatorpb.RollbackConfigResponse{Version: version}, nil
+}
+
+// PauseReconciliation pauses the probes of the recovery.
+func (m *AdminService) PauseReconciliation(
+	ctx context.Context,
+	req *coordinatorpb.PauseReconciliationRequest,
+) (*coordinatorpb.PauseReconciliationResponse, error) {
+	if m.recovery == nil {
+		return nil, status.Error(codes.FailedPrecondition, "recovery is not enabled")
+	}
+
+	m.recovery.Pause()
+	m.log.Info("paused reconciliation")
+
+	return &coordinatorpb.PauseReconciliationResponse{}, nil
+}
+
+// ResumeReconciliation resumes the probes of the recovery.
+func (m *AdminService) ResumeReconciliation(
+	ctx context.Context,
+	req *coordinatorpb.ResumeReconciliationRequest,
+) (*coordinatorpb.ResumeReconciliationResponse, error) {
+	if m.recovery == nil {
+		return nil, status.Error(codes.FailedPrecondition, "recovery is not enabled")
+	}
+
+	m.recovery.Resume()
+	m.log.Info("resumed reconciliation")
+
+	return &coordinatorpb.ResumeReconciliationResponse{}, nil
+}
+
+// GetStatus returns the reconciliation state and the recovery counters.
+func (m *AdminService) GetStatus(
+	ctx context.Context,
+	req *coordinatorpb.GetStatusRequest,
+) (*coordinatorpb.GetStatusResponse, error) {
+	response := &coordinatorpb.GetStatusResponse{}
+	if m.recovery == nil {
+		return response, nil
+	}
+
+	response.Paused = m.recovery.Paused()
+	for _, instance := range m.recovery.Instances() {
+		response.Instances = append(response.Instances, &coordinatorpb.InstanceRecovery{
+			Instance:   instance.Instance,
+			Generation: instance.Generation,
+			Recoveries: instance.Stats.Recoveries,
+			Repushed:   instance.Stats.Repushed,
+			Failed:     instance.Stats.Failed,
+		})
+	}
+	return response, nil
+}
+
+// GetApplyHistory returns the recent applies to a dataplane instance.
+func (m *AdminService) GetApplyHistory(
+	ctx context.Context,
+	req *coordinatorpb.GetApplyHistoryRequest,
+) (*coordinatorpb.GetApplyHistoryResponse, error) {
+	if req.GetSince() != 0 && req.GetUntil() != 0 && req.GetSince() > req.GetUntil() {
+		return nil, status.Error(codes.InvalidArgument, "since must not be after until")
+	}
+
+	response := &coordinatorpb.GetApplyHistoryResponse{}
+	if m.recovery == nil {
+		return response, nil
+	}
+
+	events := m.recovery.History(req.GetInstance(), fromUnixNano(req.GetSince()), fromUnixNano(req.GetUntil()))
+	for _, event := range events {
+		if req.GetModule() != "" && event.Module != req.GetModule() {
+			continue
+		}
+		response.Events = append(response.Events, &coordinatorpb.ApplyEvent{
+			Module:    event.Module,
+			Name:      event.Name,
+			Version:   event.Version,
+			Digest:    event.Digest,
+			Kind:      applyKindToProto(event.Kind),
+			StartedAt: unixNano(event.At),
+			Duration:  event.Duration.Nanoseconds(),
+			Error:     errorString(event.Err),
+		})
+	}
+	return response, nil
+}
+
+func instanceStateToProto(state State) coordinatorpb.InstanceState {
+	switch state {
+	case StateRunning:
+		return coordinatorpb.InstanceState_INSTANCE_STATE_RUNNING
+	case StateBackoff:
+		return coordinatorpb.InstanceState_INSTANCE_STATE_BACKOFF
+	case StateStopped:
+		return coordinatorpb.InstanceState_INSTANCE_STATE_STOPPED
+	default:
+		return coordinatorpb.InstanceState_INSTANCE_STATE_UNSPECIFIED
+	}
+}
+
+func applyKindToProto(kind ApplyKind) coordinatorpb.ApplyKind {
+	switch kind {
+	case ApplyPushed:
+		return coordinatorpb.ApplyKind_APPLY_KIND_PUSHED
+	case ApplyRepushed:
+		return coordinatorpb.ApplyKind_APPLY_KIND_REPUSHED
+	case ApplyRolledBack:
+		return coordinatorpb.ApplyKind_APPLY_KIND_ROLLED_BACK
+	default:
+		return coordinatorpb.ApplyKind_APPLY_KIND_UNSPECIFIED
+	}
+}
+
+// fromUnixNano returns the time of Unix nanoseconds, the zero time for
+// zero.
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// unixNano returns the time in Unix nanoseconds, zero for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,126 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/coordinatorpb/v1"
+)
+
+func TestAdminService(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+	recovery.Record(0, "route", "route0", func(ctx context.Context) error {
+		return nil
+	})
+	svc := NewAdminService(WithAdminRecovery(recovery))
+
+	_, err := svc.PauseReconciliation(t.Context(), &coordinatorpb.PauseReconciliationRequest{})
+	require.NoError(t, err)
+	resp, err := svc.GetStatus(t.Context(), &coordinatorpb.GetStatusRequest{})
+	require.NoError(t, err)
+	require.True(t, resp.GetPaused())
+	require.Len(t, resp.GetInstances(), 1)
+
+	repushed, err := svc.Repush(t.Context(), &coordinatorpb.RepushRequest{Instance: 0})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), repushed.GetConfigs())
+
+	_, err = svc.Repush(t.Context(), &coordinatorpb.RepushRequest{Instance: 0, Module: "dscp"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	configs, err := svc.ListConfigs(t.Context(), &coordinatorpb.ListConfigsRequest{})
+	require.NoError(t, err)
+	require.Len(t, configs.GetConfigs(), 1)
+	require.NotZero(t, configs.GetConfigs()[0].GetLastAppliedAt())
+
+	// Without a registry there are no instances, while the recovery
+	// controls need one.
+	instancesResp, err := svc.ListInstances(t.Context(), &coordinatorpb.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, instancesResp.GetInstances())
+
+	_, err = NewAdminService().ResumeReconciliation(t.Context(), &coordinatorpb.ResumeReconciliationRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAdminService_GetApplyHistory(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+	ok := func(ctx context.Context) error { return nil }
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "aaa", ok))
+	require.Error(t, recovery.Apply(t.Context(), 0, "dscp", "dscp0", "bbb", func(ctx context.Context) error {
+		return errors.New("invalid rule")
+	}))
+	svc := NewAdminService(WithAdminRecovery(recovery))
+
+	resp, err := svc.GetApplyHistory(t.Context(), &coordinatorpb.GetApplyHistoryRequest{Instance: 0})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 2)
+	require.Equal(t, "route", resp.GetEvents()[0].GetModule())
+	require.Equal(t, coordinatorpb.ApplyKind_APPLY_KIND_PUSHED, resp.GetEvents()[0].GetKind())
+	require.NotZero(t, resp.GetEvents()[0].GetStartedAt())
+	require.Equal(t, "invalid rule", resp.GetEvents()[1].GetError())
+
+	resp, err = svc.GetApplyHistory(t.Context(), &coordinatorpb.GetApplyHistoryRequest{Instance: 0, Module: "dscp"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 1)
+	require.Equal(t, "bbb", resp.GetEvents()[0].GetDigest())
+
+	_, err = svc.GetApplyHistory(t.Context(), &coordinatorpb.GetApplyHistoryRequest{Since: 2, Until: 1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Without a recovery there is no history.
+	resp, err = NewAdminService().GetApplyHistory(t.Context(), &coordinatorpb.GetApplyHistoryRequest{})
+	require.NoError(t, err)
+	require.Empty(t, resp.GetEvents())
+}
+
+func TestAdminService_RollbackConfig(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+	applied := ""
+	push := func(digest string) ApplyFunc {
+		return func(ctx context.Context) error {
+			applied = digest
+			return nil
+		}
+	}
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "aaa", push("aaa")))
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "bbb", push("bbb")))
+	svc := NewAdminService(WithAdminRecovery(recovery))
+
+	resp, err := svc.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{Instance: 0, Module: "route", Name: "route0", Version: 1})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), resp.GetVersion())
+	require.Equal(t, "aaa", applied)
+
+	configs, err := svc.ListConfigs(t.Context(), &coordinatorpb.ListConfigsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), configs.GetConfigs()[0].GetVersion())
+	require.Equal(t, "aaa", configs.GetConfigs()[0].GetDigest())
+
+	_, err = svc.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{Instance: 0, Module: "route", Name: "route0", Version: 7})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{Instance: 0, Module: "route"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = NewAdminService().RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The code of a push refusing the rollback is kept.
+	refusing := func(ctx context.Context) error {
+		if ApplyKindFromContext(ctx) == ApplyRolledBack {
+			return status.Error(codes.FailedPrecondition, "signature required")
+		}
+		return nil
+	}
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route1", "ccc", refusing))
+	_, err = svc.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{Instance: 0, Module: "route", Name: "route1", Version: 1})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "signature required")
+}
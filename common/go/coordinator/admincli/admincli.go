@@ -0,0 +1,424 @@
+// Package admincli provides the CLI verbs of the coordinator admin API, to
+// be added to the command tree of a service embedding the coordinator.
+package admincli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+
+	"github.com/yanet-platform/yanet2/common/coordinatorpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
+)
+
+// DefaultTimeout is the default timeout of an admin call.
+const DefaultTimeout = 10 * time.Second
+
+type adminArgs struct {
+	Endpoint string
+	Timeout  time.Duration
+	TLS      bool
+	// TLSConfig is used when TLS is set.
+	TLSConfig grpctls.ClientConfig
+}
+
+// NewCommand returns the "admin" command whose subcommands call the admin
+// API of a coordinator.
+func NewCommand() *cobra.Command {
+	args := &adminArgs{}
+
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Manage the coordinator remotely",
+	}
+	cmd.PersistentFlags().StringVar(&args.Endpoint, "endpoint", "", "Endpoint of the coordinator admin API (required)")
+	cmd.PersistentFlags().DurationVar(&args.Timeout, "timeout", DefaultTimeout, "Timeout of the call")
+	cmd.PersistentFlags().BoolVar(&args.TLS, "tls", false, "Connect with TLS")
+	cmd.PersistentFlags().StringVar(&args.TLSConfig.CAFile, "tls-ca", "", "Path to the CA certificates of the coordinator (default: the system roots)")
+	cmd.PersistentFlags().StringVar(&args.TLSConfig.CertFile, "tls-cert", "", "Path to the client certificate, for mutual authentication")
+	cmd.PersistentFlags().StringVar(&args.TLSConfig.KeyFile, "tls-key", "", "Path to the client private key")
+	cmd.PersistentFlags().StringVar(&args.TLSConfig.ServerName, "tls-server-name", "", "Name the coordinator certificate is verified against (default: the endpoint host)")
+	cmd.MarkPersistentFlagRequired("endpoint")
+
+	cmd.AddCommand(
+		newListInstancesCommand(args),
+		newListConfigsCommand(args),
+		newRepushCommand(args),
+		newRollbackCommand(args),
+		newPauseCommand(args),
+		newResumeCommand(args),
+		newStatusCommand(args),
+		newHistoryCommand(args),
+	)
+
+	return cmd
+}
+
+// call connects to the admin API and runs fn with a client.
+func (m *adminArgs) call(fn func(ctx context.Context, client coordinatorpb.AdminServiceClient) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.Timeout)
+	defer cancel()
+
+	var tlsConfig *grpctls.ClientConfig
+	if m.TLS {
+		tlsConfig = &m.TLSConfig
+	}
+	creds, err := tlsConfig.Credentials()
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(
+		m.Endpoint,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to coordinator: %w", err)
+	}
+	defer conn.Close()
+
+	return fn(ctx, coordinatorpb.NewAdminServiceClient(conn))
+}
+
+func newListInstancesCommand(args *adminArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-instances",
+		Short: "List the instances run per module configuration",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.ListInstances(ctx, &coordinatorpb.ListInstancesRequest{})
+				if err != nil {
+					return fmt.Errorf("failed to list instances: %w", err)
+				}
+
+				if len(resp.GetInstances()) == 0 {
+					fmt.Println("No instances")
+					return nil
+				}
+
+				fmt.Printf("Instances (%d):\n", len(resp.GetInstances()))
+				fmt.Println(strings.Repeat("-", 80))
+				for _, instance := range resp.GetInstances() {
+					fmt.Printf("Name:       %s\n", instance.GetName())
+					fmt.Printf("Generation: %d\n", instance.GetGeneration())
+					fmt.Printf("State:      %s\n", instanceStateToString(instance.GetState()))
+					fmt.Printf("Created:    %s\n", timeToString(instance.GetCreatedAt()))
+					fmt.Printf("Restarts:   %d\n", instance.GetRestarts())
+					if instance.GetLastError() != "" {
+						fmt.Printf("Last error: %s (%s)\n", instance.GetLastError(), timeToString(instance.GetLastErrorAt()))
+					}
+					fmt.Println(strings.Repeat("-", 80))
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func newListConfigsCommand(args *adminArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-configs",
+		Short: "List the module configurations and their last apply results",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.ListConfigs(ctx, &coordinatorpb.ListConfigsRequest{})
+				if err != nil {
+					return fmt.Errorf("failed to list configs: %w", err)
+				}
+
+				if len(resp.GetConfigs()) == 0 {
+					fmt.Println("No configs")
+					return nil
+				}
+
+				fmt.Printf("Configs (%d):\n", len(resp.GetConfigs()))
+				fmt.Println(strings.Repeat("-", 80))
+				for _, config := range resp.GetConfigs() {
+					fmt.Printf("Config:     %s:%s on instance %d\n", config.GetModule(), config.GetName(), config.GetInstance())
+					fmt.Printf("Version:    %d\n", config.GetVersion())
+					fmt.Printf("Applied:    %s\n", timeToString(config.GetLastAppliedAt()))
+					fmt.Printf("Pending:    %t\n", config.GetPending())
+					if config.GetLastError() != "" {
+						fmt.Printf("Last error: %s (%s)\n", config.GetLastError(), timeToString(config.GetLastErrorAt()))
+					}
+					fmt.Println(strings.Repeat("-", 80))
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func newRepushCommand(args *adminArgs) *cobra.Command {
+	req := &coordinatorpb.RepushRequest{}
+
+	cmd := &cobra.Command{
+		Use:   "repush",
+		Short: "Apply the configurations of a dataplane instance again",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.Repush(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to repush configs: %w", err)
+				}
+
+				fmt.Printf("Applied %d configs again on instance %d (%d failed)\n", resp.GetConfigs(), req.GetInstance(), resp.GetFailed())
+				return nil
+			})
+		},
+	}
+	cmd.Flags().Uint32Var(&req.Instance, "instance", 0, "Dataplane instance")
+	cmd.Flags().StringVar(&req.Module, "module", "", "Module whose configs to apply again, all if empty")
+
+	return cmd
+}
+
+func newRollbackCommand(args *adminArgs) *cobra.Command {
+	req := &coordinatorpb.RollbackConfigRequest{}
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Apply a previous version of a configuration again",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.RollbackConfig(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to roll config back: %w", err)
+				}
+
+				fmt.Printf("Rolled %s:%s back to v%d on instance %d as v%d\n",
+					req.GetModule(),
+					req.GetName(),
+					req.GetVersion(),
+					req.GetInstance(),
+					resp.GetVersion(),
+				)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().Uint32Var(&req.Instance, "instance", 0, "Dataplane instance")
+	cmd.Flags().StringVar(&req.Module, "module", "", "Module the config is for (required)")
+	cmd.Flags().StringVar(&req.Name, "name", "", "Config name (required)")
+	cmd.Flags().Uint64Var(&req.Version, "version", 0, "Version to roll back to, see history (required)")
+	cmd.MarkFlagRequired("module")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("version")
+
+	return cmd
+}
+
+func newPauseCommand(args *adminArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause",
+		Short: "Pause the reconciliation of restarted dataplane instances",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				if _, err := client.PauseReconciliation(ctx, &coordinatorpb.PauseReconciliationRequest{}); err != nil {
+					return fmt.Errorf("failed to pause reconciliation: %w", err)
+				}
+
+				fmt.Println("Reconciliation paused")
+				return nil
+			})
+		},
+	}
+}
+
+func newResumeCommand(args *adminArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume the reconciliation of restarted dataplane instances",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				if _, err := client.ResumeReconciliation(ctx, &coordinatorpb.ResumeReconciliationRequest{}); err != nil {
+					return fmt.Errorf("failed to resume reconciliation: %w", err)
+				}
+
+				fmt.Println("Reconciliation resumed")
+				return nil
+			})
+		},
+	}
+}
+
+func newStatusCommand(args *adminArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the reconciliation state and recovery counters",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.GetStatus(ctx, &coordinatorpb.GetStatusRequest{})
+				if err != nil {
+					return fmt.Errorf("failed to get status: %w", err)
+				}
+
+				if resp.GetPaused() {
+					fmt.Println("Reconciliation: paused")
+				} else {
+					fmt.Println("Reconciliation: active")
+				}
+				for _, instance := range resp.GetInstances() {
+					fmt.Printf("Instance %d: generation %d, %d recoveries, %d repushed, %d failed\n",
+						instance.GetInstance(),
+						instance.GetGeneration(),
+						instance.GetRecoveries(),
+						instance.GetRepushed(),
+						instance.GetFailed(),
+					)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+type historyArgs struct {
+	Instance uint32
+	Module   string
+	Since    string
+	Until    string
+	Around   string
+	Window   time.Duration
+}
+
+func newHistoryCommand(args *adminArgs) *cobra.Command {
+	historyArgs := &historyArgs{}
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the recent applies of the configurations of a dataplane instance",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			req, err := historyArgs.request()
+			if err != nil {
+				return err
+			}
+
+			return args.call(func(ctx context.Context, client coordinatorpb.AdminServiceClient) error {
+				resp, err := client.GetApplyHistory(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to get apply history: %w", err)
+				}
+
+				if len(resp.GetEvents()) == 0 {
+					fmt.Println("No applies")
+					return nil
+				}
+
+				for _, event := range resp.GetEvents() {
+					result := "ok"
+					if event.GetError() != "" {
+						result = "failed: " + event.GetError()
+					}
+					digest := event.GetDigest()
+					if digest == "" {
+						digest = "-"
+					}
+					fmt.Printf("%s  %-8s  %s:%s v%d  digest %s  %s  %s\n",
+						time.Unix(0, event.GetStartedAt()).Format(time.RFC3339Nano),
+						applyKindToString(event.GetKind()),
+						event.GetModule(),
+						event.GetName(),
+						event.GetVersion(),
+						digest,
+						time.Duration(event.GetDuration()),
+						result,
+					)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().Uint32Var(&historyArgs.Instance, "instance", 0, "Dataplane instance")
+	cmd.Flags().StringVar(&historyArgs.Module, "module", "", "Module whose applies to show, all if empty")
+	cmd.Flags().StringVar(&historyArgs.Since, "since", "", "Show the applies started at or after this RFC 3339 time")
+	cmd.Flags().StringVar(&historyArgs.Until, "until", "", "Show the applies started at or before this RFC 3339 time")
+	cmd.Flags().StringVar(&historyArgs.Around, "around", "", "Show the applies started within --window of this RFC 3339 time")
+	cmd.Flags().DurationVar(&historyArgs.Window, "window", 5*time.Minute, "Half-width of the --around interval")
+	cmd.MarkFlagsMutuallyExclusive("around", "since")
+	cmd.MarkFlagsMutuallyExclusive("around", "until")
+
+	return cmd
+}
+
+// request builds the history request, turning --around into an interval.
+func (m *historyArgs) request() (*coordinatorpb.GetApplyHistoryRequest, error) {
+	req := &coordinatorpb.GetApplyHistoryRequest{
+		Instance: m.Instance,
+		Module:   m.Module,
+	}
+
+	parse := func(flag string, value string) (time.Time, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+		return t, nil
+	}
+
+	if m.Around != "" {
+		around, err := parse("around", m.Around)
+		if err != nil {
+			return nil, err
+		}
+		req.Since = around.Add(-m.Window).UnixNano()
+		req.Until = around.Add(m.Window).UnixNano()
+		return req, nil
+	}
+	if m.Since != "" {
+		since, err := parse("since", m.Since)
+		if err != nil {
+			return nil, err
+		}
+		req.Since = since.UnixNano()
+	}
+	if m.Until != "" {
+		until, err := parse("until", m.Until)
+		if err != nil {
+			return nil, err
+		}
+		req.Until = until.UnixNano()
+	}
+	return req, nil
+}
+
+func applyKindToString(kind coordinatorpb.ApplyKind) string {
+	switch kind {
+	case coordinatorpb.ApplyKind_APPLY_KIND_PUSHED:
+		return "pushed"
+	case coordinatorpb.ApplyKind_APPLY_KIND_REPUSHED:
+		return "repushed"
+	case coordinatorpb.ApplyKind_APPLY_KIND_ROLLED_BACK:
+		return "rollback"
+	default:
+		return "unknown"
+	}
+}
+
+func instanceStateToString(state coordinatorpb.InstanceState) string {
+	switch state {
+	case coordinatorpb.InstanceState_INSTANCE_STATE_RUNNING:
+		return "running"
+	case coordinatorpb.InstanceState_INSTANCE_STATE_BACKOFF:
+		return "backoff"
+	case coordinatorpb.InstanceState_INSTANCE_STATE_STOPPED:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// timeToString formats Unix nanoseconds, "never" for zero.
+func timeToString(nanos int64) string {
+	if nanos == 0 {
+		return "never"
+	}
+	t := time.Unix(0, nanos)
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), time.Since(t).Round(time.Second))
+}
@@ -0,0 +1,199 @@
+package coordinator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultCapabilitiesTTL is the default time the capabilities reported by
+// a dataplane instance are trusted for before it is probed again.
+const DefaultCapabilitiesTTL = 30 * time.Second
+
+// Capabilities is the set of features a dataplane instance reports, such
+// as the modules it has loaded.
+type Capabilities map[string]struct{}
+
+// NewCapabilities returns the set of the given capabilities.
+func NewCapabilities(names ...string) Capabilities {
+	capabilities := Capabilities{}
+	for _, name := range names {
+		capabilities[name] = struct{}{}
+	}
+	return capabilities
+}
+
+// Has reports whether the capability is in the set.
+func (m Capabilities) Has(name string) bool {
+	_, ok := m[name]
+	return ok
+}
+
+// Missing returns the required capabilities not in the set, sorted and
+// without duplicates.
+func (m Capabilities) Missing(required ...string) []string {
+	missing := []string{}
+	for _, name := range required {
+		if !m.Has(name) && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// CapabilityProbe returns the capabilities of a dataplane instance.
+type CapabilityProbe func(ctx context.Context, instance uint32) (Capabilities, error)
+
+// NegotiatorOption configures NewNegotiator.
+type NegotiatorOption func(*negotiatorOptions)
+
+type negotiatorOptions struct {
+	TTL      time.Duration
+	FailOpen bool
+	Log      *zap.Logger
+}
+
+func newNegotiatorOptions() *negotiatorOptions {
+	return &negotiatorOptions{
+		TTL: DefaultCapabilitiesTTL,
+		Log: zap.NewNop(),
+	}
+}
+
+// WithCapabilitiesTTL sets how long the probed capabilities are trusted
+// for. Zero probes the instance on every check.
+func WithCapabilitiesTTL(d time.Duration) NegotiatorOption {
+	return func(o *negotiatorOptions) {
+		o.TTL = d
+	}
+}
+
+// WithFailOpen makes Require skip the check of an instance that cannot be
+// probed, e.g. while it restarts, instead of failing it with UNAVAILABLE,
+// so its configurations are not refused for being pushed early.
+//
+// Require reports the skipped checks, for the caller to tell the pushes
+// applied unchecked apart.
+func WithFailOpen(enabled bool) NegotiatorOption {
+	return func(o *negotiatorOptions) {
+		o.FailOpen = enabled
+	}
+}
+
+// WithNegotiatorLog sets the logger of the negotiator.
+func WithNegotiatorLog(log *zap.Logger) NegotiatorOption {
+	return func(o *negotiatorOptions) {
+		o.Log = log
+	}
+}
+
+type probedCapabilities struct {
+	capabilities Capabilities
+	probedAt     time.Time
+}
+
+// Negotiator checks the capabilities a module configuration requires
+// against the ones the dataplane instance it is pushed to reports, so a
+// push the instance cannot apply fails before anything is set up.
+//
+// The capabilities are probed on demand and cached per instance. The
+// negotiator only refuses configurations, it does not adapt them to the
+// instance: a configuration that can do without a capability must not
+// require it, and is pushed as is.
+type Negotiator struct {
+	probe CapabilityProbe
+	opts  *negotiatorOptions
+	log   *zap.Logger
+
+	mu     sync.Mutex
+	probed map[uint32]probedCapabilities
+}
+
+// NewNegotiator creates a negotiator probing the capabilities with the
+// given probe.
+func NewNegotiator(probe CapabilityProbe, options ...NegotiatorOption) *Negotiator {
+	opts := newNegotiatorOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &Negotiator{
+		probe:  probe,
+		opts:   opts,
+		log:    opts.Log,
+		probed: map[uint32]probedCapabilities{},
+	}
+}
+
+// Capabilities returns the capabilities of an instance, probing it unless
+// the cached ones are recent enough.
+func (m *Negotiator) Capabilities(ctx context.Context, instance uint32) (Capabilities, error) {
+	m.mu.Lock()
+	probed, ok := m.probed[instance]
+	m.mu.Unlock()
+	if ok && time.Since(probed.probedAt) < m.opts.TTL {
+		return probed.capabilities, nil
+	}
+
+	capabilities, err := m.probe(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.probed[instance] = probedCapabilities{capabilities: capabilities, probedAt: time.Now()}
+	m.mu.Unlock()
+
+	return capabilities, nil
+}
+
+// Require checks that an instance reports every required capability,
+// failing with FAILED_PRECONDITION listing the missing ones otherwise,
+// ready to be returned by the RPC handler.
+//
+// The missing capabilities are probed again before being reported, as an
+// instance may have loaded them since it was last probed. An instance that
+// cannot be probed fails the check with UNAVAILABLE, unless the negotiator
+// fails open, see WithFailOpen, in which case Require reports the check
+// skipped by returning true.
+func (m *Negotiator) Require(ctx context.Context, instance uint32, required ...string) (bool, error) {
+	if len(required) == 0 {
+		return false, nil
+	}
+
+	capabilities, err := m.Capabilities(ctx, instance)
+	if err == nil && len(capabilities.Missing(required...)) != 0 {
+		m.mu.Lock()
+		delete(m.probed, instance)
+		m.mu.Unlock()
+		capabilities, err = m.Capabilities(ctx, instance)
+	}
+	if err != nil && m.opts.FailOpen {
+		m.log.Warn("failed to probe the dataplane instance capabilities, skipping the check",
+			zap.Uint32("instance", instance),
+			zap.Error(err),
+		)
+		return true, nil
+	}
+	if err != nil {
+		return false, status.Errorf(codes.Unavailable,
+			"failed to probe the capabilities of dataplane instance %d: %v", instance, err,
+		)
+	}
+
+	if missing := capabilities.Missing(required...); len(missing) != 0 {
+		return false, status.Errorf(codes.FailedPrecondition,
+			"dataplane instance %d does not support %s",
+			instance,
+			strings.Join(missing, ", "),
+		)
+	}
+	return false, nil
+}
@@ -0,0 +1,70 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCapabilities_Missing(t *testing.T) {
+	capabilities := NewCapabilities("route", "route-mpls")
+	require.True(t, capabilities.Has("route"))
+	require.False(t, capabilities.Has("dscp"))
+	require.Empty(t, capabilities.Missing("route", "route-mpls"))
+	require.Equal(t, []string{"dscp", "nat64"}, capabilities.Missing("nat64", "route", "dscp", "nat64"))
+}
+
+func TestNegotiator_Require(t *testing.T) {
+	probes := 0
+	reported := NewCapabilities("route")
+	var probeErr error
+	negotiator := NewNegotiator(func(ctx context.Context, instance uint32) (Capabilities, error) {
+		probes++
+		return reported, probeErr
+	})
+
+	skipped, err := negotiator.Require(t.Context(), 0, "route")
+	require.NoError(t, err)
+	require.False(t, skipped)
+	_, err = negotiator.Require(t.Context(), 0, "route")
+	require.NoError(t, err)
+	require.Equal(t, 1, probes)
+
+	// A missing capability is probed again before the push is refused.
+	_, err = negotiator.Require(t.Context(), 0, "route", "route-mpls")
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "route-mpls")
+	require.Equal(t, 2, probes)
+
+	// So a module loaded since the last probe is found at once.
+	reported = NewCapabilities("route", "route-mpls")
+	_, err = negotiator.Require(t.Context(), 0, "route-mpls")
+	require.NoError(t, err)
+	require.Equal(t, 3, probes)
+
+	// Nothing is probed for configurations requiring nothing.
+	probeErr = errors.New("unexpected probe")
+	_, err = negotiator.Require(t.Context(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, probes)
+}
+
+func TestNegotiator_FailOpen(t *testing.T) {
+	down := func(ctx context.Context, instance uint32) (Capabilities, error) {
+		return nil, errors.New("instance is down")
+	}
+
+	// An instance that cannot be probed fails the check.
+	skipped, err := NewNegotiator(down).Require(t.Context(), 0, "dscp")
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.False(t, skipped)
+
+	// Unless the negotiator fails open, which reports the check skipped.
+	skipped, err = NewNegotiator(down, WithFailOpen(true)).Require(t.Context(), 0, "dscp")
+	require.NoError(t, err)
+	require.True(t, skipped)
+}
@@ -0,0 +1,39 @@
+package coordinator
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/xcfg"
+)
+
+type defaultable interface {
+	Default()
+}
+
+// DecodeConfig decodes the YAML configuration of a SetupConfig request.
+//
+// Like xcfg.LoadConfig, defaults are applied before decoding and every
+// field implementing Validate is validated. Errors are returned as
+// INVALID_ARGUMENT, ready to be returned by the RPC handler.
+func DecodeConfig[T any](buf []byte) (*T, error) {
+	cfg := new(T)
+	if def, ok := any(cfg).(defaultable); ok {
+		def.Default()
+	}
+	if err := xcfg.Decode(buf, cfg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid config: %v", err))
+	}
+
+	return cfg, nil
+}
+
+// ValidateName checks the configuration name of a SetupConfig request.
+func ValidateName(name string) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "config name is required")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package coordinator
+
+import (
+	"time"
+)
+
+// DefaultHistorySize is the default number of applies kept per dataplane
+// instance.
+const DefaultHistorySize = 256
+
+// ApplyKind tells how a configuration came to be applied.
+type ApplyKind int
+
+const (
+	// ApplyPushed is a push of a module configuration, see Record and
+	// Apply.
+	ApplyPushed ApplyKind = iota
+	// ApplyRepushed is an apply again by the recovery, after a reset or
+	// on request.
+	ApplyRepushed
+	// ApplyRolledBack is a push of a previous version of the
+	// configuration, see Rollback.
+	ApplyRolledBack
+)
+
+// String returns the name of the kind.
+func (m ApplyKind) String() string {
+	switch m {
+	case ApplyPushed:
+		return "pushed"
+	case ApplyRepushed:
+		return "repushed"
+	case ApplyRolledBack:
+		return "rollback"
+	default:
+		return "unknown"
+	}
+}
+
+// ApplyEvent is an apply of a module configuration to a dataplane
+// instance.
+type ApplyEvent struct {
+	Instance uint32
+	Module   string
+	Name     string
+	// Version counts the pushes of the configuration, failed ones and
+	// rollbacks included. Applying it again keeps the version of its last
+	// push.
+	Version uint64
+	// Digest identifies the content of the configuration, empty if the
+	// module service did not provide one.
+	Digest string
+	Kind   ApplyKind
+	// At is the time the apply started.
+	At time.Time
+	// Duration is the time the apply took, zero if unknown.
+	Duration time.Duration
+	// Err is the error the apply failed with, nil if it succeeded.
+	Err error
+}
+
+// applyHistory keeps the most recent applies of every dataplane instance,
+// oldest first.
+type applyHistory struct {
+	size   int
+	events map[uint32][]ApplyEvent
+}
+
+func newApplyHistory(size int) *applyHistory {
+	return &applyHistory{
+		size:   size,
+		events: map[uint32][]ApplyEvent{},
+	}
+}
+
+// add appends an apply, dropping the oldest ones of the instance beyond the
+// size of the history.
+//
+// Applies are added once they complete, so an apply of the recovery
+// started before a push that completed first may be added after it. The
+// events are kept ordered by their start time regardless.
+func (m *applyHistory) add(event ApplyEvent) {
+	if m.size <= 0 {
+		return
+	}
+
+	events := m.events[event.Instance]
+	idx := len(events)
+	for idx > 0 && events[idx-1].At.After(event.At) {
+		idx--
+	}
+	events = append(events, ApplyEvent{})
+	copy(events[idx+1:], events[idx:])
+	events[idx] = event
+
+	if len(events) > m.size {
+		events = append(events[:0:0], events[len(events)-m.size:]...)
+	}
+	m.events[event.Instance] = events
+}
+
+// list returns the applies of an instance started within [since, until],
+// oldest first. A zero bound leaves that side of the interval open.
+func (m *applyHistory) list(instance uint32, since time.Time, until time.Time) []ApplyEvent {
+	events := []ApplyEvent{}
+	for _, event := range m.events[instance] {
+		if !since.IsZero() && event.At.Before(since) {
+			continue
+		}
+		if !until.IsZero() && event.At.After(until) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,706 @@
+package coordinator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultProbeInterval is the default period of the dataplane instance
+// generation probes.
+const DefaultProbeInterval = 5 * time.Second
+
+// DefaultRevisions is the default number of successful pushes kept per
+// configuration to roll back to.
+const DefaultRevisions = 8
+
+// ErrVersionNotFound is returned by Rollback for a version of a
+// configuration that failed to apply or is no longer kept.
+var ErrVersionNotFound = errors.New("config version not found")
+
+// GenerationProbe returns the memory generation of a dataplane instance.
+//
+// The generation must grow for as long as the shared memory of the
+// instance lives, such as the highest generation of the module configs
+// the instance reports, so that a lower one tells the instance restarted
+// and lost the configs pushed to it.
+type GenerationProbe func(ctx context.Context, instance uint32) (uint64, error)
+
+// ApplyFunc pushes a module configuration to a dataplane instance.
+//
+// The recovery calls it with the kind of the apply in the context, see
+// ApplyKindFromContext, so that a module service can refuse to roll back
+// to a configuration it no longer accepts.
+type ApplyFunc func(ctx context.Context) error
+
+type applyKindKey struct{}
+
+// withApplyKind returns the context an ApplyFunc is called with for an
+// apply of the given kind.
+func withApplyKind(ctx context.Context, kind ApplyKind) context.Context {
+	return context.WithValue(ctx, applyKindKey{}, kind)
+}
+
+// ApplyKindFromContext returns the kind of the apply an ApplyFunc is
+// called for, ApplyPushed unless called by Rollback or by the recovery.
+func ApplyKindFromContext(ctx context.Context) ApplyKind {
+	kind, _ := ctx.Value(applyKindKey{}).(ApplyKind)
+	return kind
+}
+
+// RecoveryOption configures NewRecovery.
+type RecoveryOption func(*recoveryOptions)
+
+type recoveryOptions struct {
+	ProbeInterval time.Duration
+	HistorySize   int
+	Revisions     int
+	Log           *zap.Logger
+}
+
+func newRecoveryOptions() *recoveryOptions {
+	return &recoveryOptions{
+		ProbeInterval: DefaultProbeInterval,
+		HistorySize:   DefaultHistorySize,
+		Revisions:     DefaultRevisions,
+		Log:           zap.NewNop(),
+	}
+}
+
+// WithProbeInterval sets how often the instances are probed for resets.
+func WithProbeInterval(d time.Duration) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.ProbeInterval = d
+	}
+}
+
+// WithHistorySize sets how many applies are kept per dataplane instance,
+// see History. Zero keeps none.
+func WithHistorySize(size int) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.HistorySize = size
+	}
+}
+
+// WithRevisions sets how many successful pushes of every configuration are
+// kept to roll back to, the current one included, see Rollback.
+func WithRevisions(count int) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.Revisions = count
+	}
+}
+
+// WithRecoveryLog sets the logger of the recovery.
+func WithRecoveryLog(log *zap.Logger) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.Log = log
+	}
+}
+
+// RecoveryStats counts the recoveries of a dataplane instance.
+type RecoveryStats struct {
+	// Recoveries is the number of detected memory generation resets.
+	Recoveries uint64
+	// Repushed is the number of configurations applied again.
+	Repushed uint64
+	// Failed is the number of failed attempts to apply a configuration
+	// again.
+	Failed uint64
+}
+
+type configKey struct {
+	instance uint32
+	module   string
+	name     string
+}
+
+type appliedConfig struct {
+	apply ApplyFunc
+	// seq orders the configurations by their last push, so they are
+	// applied again in the order they were set up in.
+	seq uint64
+	// version and digest are those of the push that set the
+	// configuration up, see ApplyEvent.
+	version uint64
+	digest  string
+
+	appliedAt   time.Time
+	lastError   error
+	lastErrorAt time.Time
+}
+
+// revision is a successful push of a configuration, kept to roll back to.
+type revision struct {
+	version uint64
+	digest  string
+	apply   ApplyFunc
+}
+
+// ConfigStatus is a snapshot of a configuration known to the recovery.
+type ConfigStatus struct {
+	Instance uint32
+	Module   string
+	Name     string
+	// Version and Digest are those of the push that set the configuration
+	// up, see ApplyEvent.
+	Version uint64
+	Digest  string
+	// Pending tells the configuration waits to be applied again.
+	Pending bool
+	// AppliedAt is the time of the last successful apply, either recorded
+	// or done by the recovery.
+	AppliedAt time.Time
+	// LastError is the error of the last failed attempt to apply the
+	// configuration again, nil if the last attempt succeeded.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// InstanceStatus is a snapshot of the recovery of a dataplane instance.
+type InstanceStatus struct {
+	Instance uint32
+	// Generation is the last probed memory generation, zero if the
+	// instance was never probed.
+	Generation uint64
+	Stats      RecoveryStats
+}
+
+// Recovery applies the last known module configurations again to the
+// dataplane instances that restarted.
+//
+// A restarted dataplane instance starts from empty shared memory, while
+// the module services keep believing their configs are applied. The
+// recovery probes the memory generation of every instance it knows
+// configs of, and once the generation goes backwards, it pushes all of
+// them again. Configurations failing to apply are retried on every probe
+// until they succeed or are forgotten.
+//
+// A restart followed by enough pushes to overtake the last seen generation
+// before the next probe goes unnoticed, so the probe interval must be well
+// below the time the instance takes to restart.
+//
+// The recent applies of every instance, pushes and applies again alike,
+// are kept in a bounded history, see History.
+type Recovery struct {
+	probe GenerationProbe
+	opts  *recoveryOptions
+	log   *zap.Logger
+
+	mu          sync.Mutex
+	seq         uint64
+	paused      bool
+	configs     map[configKey]*appliedConfig
+	pending     map[configKey]struct{}
+	generations map[uint32]uint64
+	stats       map[uint32]*RecoveryStats
+	versions    map[configKey]uint64
+	revisions   map[configKey][]revision
+	history     *applyHistory
+}
+
+// NewRecovery creates a recovery detecting the instance resets with the
+// given probe.
+func NewRecovery(probe GenerationProbe, options ...RecoveryOption) *Recovery {
+	opts := newRecoveryOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &Recovery{
+		probe:       probe,
+		opts:        opts,
+		log:         opts.Log,
+		configs:     map[configKey]*appliedConfig{},
+		pending:     map[configKey]struct{}{},
+		generations: map[uint32]uint64{},
+		stats:       map[uint32]*RecoveryStats{},
+		versions:    map[configKey]uint64{},
+		revisions:   map[configKey][]revision{},
+		history:     newApplyHistory(opts.HistorySize),
+	}
+}
+
+// Record remembers how to apply a module configuration again, replacing
+// the previous one of the same name.
+//
+// It must be called once the configuration is successfully applied. The
+// push is added to the apply history without a digest or a duration, see
+// Apply.
+func (m *Recovery) Record(instance uint32, module string, name string, apply ApplyFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := configKey{instance: instance, module: module, name: name}
+	m.record(key, ApplyPushed, apply, "", time.Now(), 0, nil)
+}
+
+// Apply pushes a module configuration to a dataplane instance and
+// records it on success, as Record does.
+//
+// Unlike Record, the push is added to the apply history along with the
+// digest of the configuration, the time it took and its error. A failed
+// push keeps the previously recorded configuration to apply again.
+func (m *Recovery) Apply(
+	ctx context.Context,
+	instance uint32,
+	module string,
+	name string,
+	digest string,
+	apply ApplyFunc,
+) error {
+	_, _, err := ApplyResult(ctx, m, instance, module, name, digest, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, apply(ctx)
+	})
+	return err
+}
+
+// ApplyResult is Recovery.Apply for a push returning a result, such as the
+// generation the module service set the configuration up as. It also
+// returns the version of the push, which Rollback rolls back to.
+//
+// The push is what is applied again, its result is dropped then, so it
+// must not depend on being the first push of the configuration.
+func ApplyResult[T any](
+	ctx context.Context,
+	m *Recovery,
+	instance uint32,
+	module string,
+	name string,
+	digest string,
+	push func(ctx context.Context) (T, error),
+) (T, uint64, error) {
+	startedAt := time.Now()
+	result, err := push(ctx)
+	duration := time.Since(startedAt)
+
+	apply := func(ctx context.Context) error {
+		_, err := push(ctx)
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := configKey{instance: instance, module: module, name: name}
+	version := m.record(key, ApplyPushed, apply, digest, startedAt, duration, err)
+	return result, version, err
+}
+
+// Version returns the version of the push that set a module configuration
+// up, zero if the configuration is not known.
+func (m *Recovery) Version(instance uint32, module string, name string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := configKey{instance: instance, module: module, name: name}
+	if config, ok := m.configs[key]; ok {
+		return config.version
+	}
+	return 0
+}
+
+// Rollback applies a previous version of a module configuration again as
+// a new push, and returns the version of that push.
+//
+// Only the versions that applied successfully can be rolled back to, and
+// only the most recent ones are kept, see WithRevisions; others fail with
+// ErrVersionNotFound. The rollback replays the ApplyFunc of the version,
+// so it restores as much of the configuration as that function pushes, and
+// fails with the error of that function, wrapped.
+func (m *Recovery) Rollback(
+	ctx context.Context,
+	instance uint32,
+	module string,
+	name string,
+	version uint64,
+) (uint64, error) {
+	key := configKey{instance: instance, module: module, name: name}
+
+	m.mu.Lock()
+	idx := slices.IndexFunc(m.revisions[key], func(rev revision) bool {
+		return rev.version == version
+	})
+	if idx < 0 {
+		m.mu.Unlock()
+		return 0, fmt.Errorf("%w: %s:%s v%d on instance %d", ErrVersionNotFound, module, name, version, instance)
+	}
+	target := m.revisions[key][idx]
+	m.mu.Unlock()
+
+	startedAt := time.Now()
+	err := target.apply(withApplyKind(ctx, ApplyRolledBack))
+	duration := time.Since(startedAt)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pushed := m.record(key, ApplyRolledBack, target.apply, target.digest, startedAt, duration, err)
+	if err != nil {
+		return pushed, fmt.Errorf("failed to roll back to v%d: %w", version, err)
+	}
+	return pushed, nil
+}
+
+// record adds a push to the apply history, and remembers the
+// configuration if it succeeded. It returns the version of the push.
+//
+// The caller must hold mu.
+func (m *Recovery) record(
+	key configKey,
+	kind ApplyKind,
+	apply ApplyFunc,
+	digest string,
+	startedAt time.Time,
+	duration time.Duration,
+	err error,
+) uint64 {
+	m.versions[key]++
+	version := m.versions[key]
+	m.history.add(ApplyEvent{
+		Instance: key.instance,
+		Module:   key.module,
+		Name:     key.name,
+		Version:  version,
+		Digest:   digest,
+		Kind:     kind,
+		At:       startedAt,
+		Duration: duration,
+		Err:      err,
+	})
+	if err != nil {
+		return version
+	}
+
+	m.seq++
+	m.configs[key] = &appliedConfig{
+		apply:     apply,
+		seq:       m.seq,
+		version:   version,
+		digest:    digest,
+		appliedAt: startedAt.Add(duration),
+	}
+	delete(m.pending, key)
+
+	if m.opts.Revisions > 0 {
+		revisions := append(m.revisions[key], revision{version: version, digest: digest, apply: apply})
+		if len(revisions) > m.opts.Revisions {
+			revisions = append(revisions[:0:0], revisions[len(revisions)-m.opts.Revisions:]...)
+		}
+		m.revisions[key] = revisions
+	}
+	return version
+}
+
+// History returns the applies to a dataplane instance started within
+// [since, until], oldest first, a zero bound leaving that side open.
+//
+// Only the most recent applies are kept, see WithHistorySize.
+func (m *Recovery) History(instance uint32, since time.Time, until time.Time) []ApplyEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.history.list(instance, since, until)
+}
+
+// Forget stops applying a module configuration again, e.g. once it is
+// deleted, and drops the versions kept to roll it back.
+func (m *Recovery) Forget(instance uint32, module string, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := configKey{instance: instance, module: module, name: name}
+	delete(m.configs, key)
+	delete(m.pending, key)
+	delete(m.revisions, key)
+}
+
+// Stats returns the recovery counters of a dataplane instance.
+func (m *Recovery) Stats(instance uint32) RecoveryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stats, ok := m.stats[instance]; ok {
+		return *stats
+	}
+	return RecoveryStats{}
+}
+
+// Configs returns the status of every known configuration, ordered by
+// instance, module and name.
+func (m *Recovery) Configs() []ConfigStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	configs := make([]ConfigStatus, 0, len(m.configs))
+	for key, config := range m.configs {
+		_, pending := m.pending[key]
+		configs = append(configs, ConfigStatus{
+			Instance:    key.instance,
+			Module:      key.module,
+			Name:        key.name,
+			Version:     config.version,
+			Digest:      config.digest,
+			Pending:     pending,
+			AppliedAt:   config.appliedAt,
+			LastError:   config.lastError,
+			LastErrorAt: config.lastErrorAt,
+		})
+	}
+	slices.SortFunc(configs, func(a ConfigStatus, b ConfigStatus) int {
+		return cmp.Or(
+			cmp.Compare(a.Instance, b.Instance),
+			cmp.Compare(a.Module, b.Module),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	return configs
+}
+
+// Instances returns the status of every instance with known
+// configurations, ordered by instance.
+func (m *Recovery) Instances() []InstanceStatus {
+	instances := m.instances()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]InstanceStatus, 0, len(instances))
+	for _, instance := range instances {
+		status := InstanceStatus{
+			Instance:   instance,
+			Generation: m.generations[instance],
+		}
+		if stats, ok := m.stats[instance]; ok {
+			status.Stats = *stats
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// Pause stops the probes of Check until Resume, e.g. while the dataplane
+// is under maintenance and its restarts are expected.
+//
+// Repush keeps working while paused.
+func (m *Recovery) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.paused = true
+}
+
+// Resume restarts the probes paused by Pause.
+//
+// The last generations seen before the pause are kept, so the first probe
+// detects the resets that happened in the meantime.
+func (m *Recovery) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.paused = false
+}
+
+// Paused reports whether the probes are paused.
+func (m *Recovery) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.paused
+}
+
+// Repush applies again the configurations of an instance, only those of
+// the given module unless it is empty, and returns the number of
+// configurations selected and of those that failed to apply.
+//
+// The other configurations of the instance already pending are applied
+// along.
+func (m *Recovery) Repush(ctx context.Context, instance uint32, module string) (int, int) {
+	m.mu.Lock()
+	selected := 0
+	for key := range m.configs {
+		if key.instance == instance && (module == "" || key.module == module) {
+			m.pending[key] = struct{}{}
+			selected++
+		}
+	}
+	m.mu.Unlock()
+
+	return selected, m.repush(ctx, instance)
+}
+
+// Run probes the instances until ctx is cancelled.
+func (m *Recovery) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check probes every instance with known configurations once, applying
+// them again to the instances that were reset.
+//
+// It does nothing while paused.
+func (m *Recovery) Check(ctx context.Context) {
+	if m.Paused() {
+		return
+	}
+
+	for _, instance := range m.instances() {
+		generation, err := m.probe(ctx, instance)
+		if err != nil {
+			// A restarting instance is expected to be unreachable for a
+			// while, the reset is detected once it is back.
+			m.log.Debug("failed to probe dataplane instance",
+				zap.Uint32("instance", instance),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if m.observe(instance, generation) {
+			m.log.Warn("dataplane instance memory generation reset, applying configs again",
+				zap.Uint32("instance", instance),
+				zap.Uint64("generation", generation),
+			)
+		}
+		m.repush(ctx, instance)
+	}
+}
+
+// instances returns the instances with known configurations.
+func (m *Recovery) instances() []uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instances := []uint32{}
+	for key := range m.configs {
+		if !slices.Contains(instances, key.instance) {
+			instances = append(instances, key.instance)
+		}
+	}
+	slices.Sort(instances)
+
+	return instances
+}
+
+// observe records the generation of an instance, reporting whether it
+// went backwards.
+//
+// A reset marks every configuration of the instance to be applied again.
+func (m *Recovery) observe(instance uint32, generation uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev, seen := m.generations[instance]
+	m.generations[instance] = generation
+	if !seen || generation >= prev {
+		return false
+	}
+
+	m.instanceStats(instance).Recoveries++
+	for key := range m.configs {
+		if key.instance == instance {
+			m.pending[key] = struct{}{}
+		}
+	}
+
+	return true
+}
+
+// repush applies again the pending configurations of an instance in the
+// order they were set up in, returning the number of those that failed.
+func (m *Recovery) repush(ctx context.Context, instance uint32) int {
+	type repush struct {
+		key    configKey
+		config *appliedConfig
+	}
+
+	m.mu.Lock()
+	configs := []repush{}
+	for key := range m.pending {
+		if key.instance == instance {
+			configs = append(configs, repush{key: key, config: m.configs[key]})
+		}
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(configs, func(a repush, b repush) int {
+		return cmp.Compare(a.config.seq, b.config.seq)
+	})
+
+	failed := 0
+	for _, p := range configs {
+		log := m.log.With(
+			zap.Uint32("instance", instance),
+			zap.String("module", p.key.module),
+			zap.String("name", p.key.name),
+		)
+		startedAt := time.Now()
+		err := p.config.apply(withApplyKind(ctx, ApplyRepushed))
+		duration := time.Since(startedAt)
+
+		m.mu.Lock()
+		m.history.add(ApplyEvent{
+			Instance: instance,
+			Module:   p.key.module,
+			Name:     p.key.name,
+			Version:  p.config.version,
+			Digest:   p.config.digest,
+			Kind:     ApplyRepushed,
+			At:       startedAt,
+			Duration: duration,
+			Err:      err,
+		})
+		// The configuration may have been pushed again or forgotten in the
+		// meantime, which supersedes this attempt.
+		current := m.configs[p.key] == p.config
+		if current && err == nil {
+			delete(m.pending, p.key)
+			p.config.appliedAt = time.Now()
+			p.config.lastError = nil
+			m.instanceStats(instance).Repushed++
+		}
+		if current && err != nil {
+			p.config.lastError = err
+			p.config.lastErrorAt = time.Now()
+			m.instanceStats(instance).Failed++
+		}
+		m.mu.Unlock()
+
+		if err != nil {
+			log.Error("failed to apply config again", zap.Error(err))
+			failed++
+			continue
+		}
+		log.Info("applied config again")
+	}
+
+	return failed
+}
+
+// instanceStats returns the counters of an instance, creating them.
+//
+// The caller must hold mu.
+func (m *Recovery) instanceStats(instance uint32) *RecoveryStats {
+	stats, ok := m.stats[instance]
+	if !ok {
+		stats = &RecoveryStats{}
+		m.stats[instance] = stats
+	}
+	return stats
+}
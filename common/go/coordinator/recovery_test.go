@@ -0,0 +1,295 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInstances reports a settable memory generation per instance.
+type fakeInstances struct {
+	mu          sync.Mutex
+	generations map[uint32]uint64
+}
+
+func (m *fakeInstances) set(instance uint32, generation uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generations[instance] = generation
+}
+
+func (m *fakeInstances) probe(ctx context.Context, instance uint32) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	generation, ok := m.generations[instance]
+	if !ok {
+		return 0, errors.New("instance is down")
+	}
+	return generation, nil
+}
+
+func TestRecovery_RepushesAfterReset(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10, 1: 10}}
+	recovery := NewRecovery(instances.probe)
+
+	applied := []string{}
+	record := func(instance uint32, module string, name string) {
+		recovery.Record(instance, module, name, func(ctx context.Context) error {
+			applied = append(applied, module+":"+name)
+			return nil
+		})
+	}
+	record(0, "route", "route0")
+	record(0, "dscp", "dscp0")
+	record(1, "route", "route1")
+
+	recovery.Check(t.Context())
+	require.Empty(t, applied)
+
+	// Pushes grow the generation, which is no reset.
+	instances.set(0, 12)
+	recovery.Check(t.Context())
+	require.Empty(t, applied)
+
+	// A restarted instance is unreachable for a while.
+	instances.set(0, 0)
+	delete(instances.generations, 0)
+	recovery.Check(t.Context())
+	require.Empty(t, applied)
+
+	instances.set(0, 1)
+	recovery.Check(t.Context())
+	require.Equal(t, []string{"route:route0", "dscp:dscp0"}, applied)
+	require.Equal(t, RecoveryStats{Recoveries: 1, Repushed: 2}, recovery.Stats(0))
+	require.Equal(t, RecoveryStats{}, recovery.Stats(1))
+
+	// Nothing is left to apply again.
+	recovery.Check(t.Context())
+	require.Len(t, applied, 2)
+}
+
+func TestRecovery_RetriesFailedConfigs(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+
+	attempts := 0
+	recovery.Record(0, "route", "route0", func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("module is not ready")
+		}
+		return nil
+	})
+	recovery.Record(0, "dscp", "dscp0", func(ctx context.Context) error {
+		return nil
+	})
+	recovery.Check(t.Context())
+
+	instances.set(0, 1)
+	recovery.Check(t.Context())
+	require.Equal(t, 1, attempts)
+	require.Equal(t, RecoveryStats{Recoveries: 1, Repushed: 1, Failed: 1}, recovery.Stats(0))
+
+	recovery.Check(t.Context())
+	require.Equal(t, 2, attempts)
+	require.Equal(t, RecoveryStats{Recoveries: 1, Repushed: 2, Failed: 1}, recovery.Stats(0))
+
+	// Forgotten configs are not applied again.
+	recovery.Forget(0, "route", "route0")
+	instances.set(0, 0)
+	recovery.Check(t.Context())
+	require.Equal(t, 2, attempts)
+	require.Equal(t, RecoveryStats{Recoveries: 2, Repushed: 3, Failed: 1}, recovery.Stats(0))
+}
+
+func TestRecovery_PauseAndRepush(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+
+	applied := []string{}
+	for _, module := range []string{"route", "dscp"} {
+		recovery.Record(0, module, module+"0", func(ctx context.Context) error {
+			applied = append(applied, module)
+			if module == "dscp" {
+				return errors.New("module is not ready")
+			}
+			return nil
+		})
+	}
+	recovery.Check(t.Context())
+
+	// Resets are not detected while paused, but right after resuming.
+	recovery.Pause()
+	require.True(t, recovery.Paused())
+	instances.set(0, 1)
+	recovery.Check(t.Context())
+	require.Empty(t, applied)
+
+	// Repushing by hand works while paused.
+	selected, failed := recovery.Repush(t.Context(), 0, "route")
+	require.Equal(t, 1, selected)
+	require.Zero(t, failed)
+	require.Equal(t, []string{"route"}, applied)
+
+	recovery.Resume()
+	recovery.Check(t.Context())
+	require.Equal(t, []string{"route", "route", "dscp"}, applied)
+
+	configs := recovery.Configs()
+	require.Len(t, configs, 2)
+	require.Equal(t, "dscp", configs[0].Module)
+	require.True(t, configs[0].Pending)
+	require.EqualError(t, configs[0].LastError, "module is not ready")
+	require.Equal(t, "route", configs[1].Module)
+	require.False(t, configs[1].Pending)
+	require.NoError(t, configs[1].LastError)
+
+	require.Equal(t, []InstanceStatus{{
+		Instance:   0,
+		Generation: 1,
+		Stats:      RecoveryStats{Recoveries: 1, Repushed: 2, Failed: 1},
+	}}, recovery.Instances())
+
+	selected, _ = recovery.Repush(t.Context(), 1, "")
+	require.Zero(t, selected)
+}
+
+func TestRecovery_History(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe, WithHistorySize(4))
+
+	fail := errors.New("dataplane is busy")
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return fail }
+
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "aaa", ok))
+	require.ErrorIs(t, recovery.Apply(t.Context(), 0, "route", "route0", "bbb", failing), fail)
+	recovery.Record(0, "dscp", "dscp0", ok)
+
+	// The failed push is in the history, while the one applied again is
+	// the last successful push.
+	_, failed := recovery.Repush(t.Context(), 0, "route")
+	require.Zero(t, failed)
+
+	events := recovery.History(0, time.Time{}, time.Time{})
+	require.Len(t, events, 4)
+	require.Equal(t, ApplyEvent{Module: "route", Name: "route0", Version: 1, Digest: "aaa", Kind: ApplyPushed}, withoutTimes(events[0]))
+	require.Equal(t, "bbb", events[1].Digest)
+	require.Equal(t, uint64(2), events[1].Version)
+	require.ErrorIs(t, events[1].Err, fail)
+	require.Equal(t, ApplyEvent{Module: "dscp", Name: "dscp0", Version: 1, Kind: ApplyPushed}, withoutTimes(events[2]))
+	require.Equal(t, ApplyEvent{Module: "route", Name: "route0", Version: 1, Digest: "aaa", Kind: ApplyRepushed}, withoutTimes(events[3]))
+	for idx := 1; idx < len(events); idx++ {
+		require.False(t, events[idx].At.Before(events[idx-1].At))
+	}
+
+	// Only the most recent applies are kept.
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "ccc", ok))
+	events = recovery.History(0, time.Time{}, time.Time{})
+	require.Len(t, events, 4)
+	require.Equal(t, "bbb", events[0].Digest)
+	require.Equal(t, "ccc", events[3].Digest)
+	require.Equal(t, uint64(3), events[3].Version)
+
+	// The interval selects by start time.
+	require.Len(t, recovery.History(0, events[2].At, events[2].At), 1)
+	require.Empty(t, recovery.History(0, events[3].At.Add(time.Second), time.Time{}))
+	require.Empty(t, recovery.History(1, time.Time{}, time.Time{}))
+}
+
+func TestApplyResult(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe)
+
+	pushes := uint64(0)
+	push := func(ctx context.Context) (uint64, error) {
+		pushes++
+		return pushes, nil
+	}
+	generation, version, err := ApplyResult(t.Context(), recovery, 0, "route", "route0", "aaa", push)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), generation)
+	require.Equal(t, uint64(1), version)
+	require.Equal(t, uint64(1), recovery.Version(0, "route", "route0"))
+	require.Zero(t, recovery.Version(0, "route", "route1"))
+
+	// Applying again runs the push once more, dropping its result.
+	selected, failed := recovery.Repush(t.Context(), 0, "")
+	require.Equal(t, 1, selected)
+	require.Zero(t, failed)
+	require.Equal(t, uint64(2), pushes)
+
+	events := recovery.History(0, time.Time{}, time.Time{})
+	require.Len(t, events, 2)
+	require.Equal(t, ApplyEvent{Module: "route", Name: "route0", Version: 1, Digest: "aaa", Kind: ApplyPushed}, withoutTimes(events[0]))
+	require.Equal(t, ApplyRepushed, events[1].Kind)
+}
+
+// withoutTimes returns the event without the fields varying from run to
+// run.
+func withoutTimes(event ApplyEvent) ApplyEvent {
+	event.At = time.Time{}
+	event.Duration = 0
+	return event
+}
+
+func TestRecovery_Rollback(t *testing.T) {
+	instances := &fakeInstances{generations: map[uint32]uint64{0: 10}}
+	recovery := NewRecovery(instances.probe, WithRevisions(2))
+
+	applied := []string{}
+	kinds := []ApplyKind{}
+	push := func(digest string) ApplyFunc {
+		return func(ctx context.Context) error {
+			applied = append(applied, digest)
+			kinds = append(kinds, ApplyKindFromContext(ctx))
+			return nil
+		}
+	}
+	fail := errors.New("invalid rule")
+	failing := func(ctx context.Context) error { return fail }
+
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "aaa", push("aaa")))
+	require.NoError(t, recovery.Apply(t.Context(), 0, "route", "route0", "bbb", push("bbb")))
+	require.ErrorIs(t, recovery.Apply(t.Context(), 0, "route", "route0", "ccc", failing), fail)
+
+	// A failed version cannot be rolled back to.
+	_, err := recovery.Rollback(t.Context(), 0, "route", "route0", 3)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+
+	version, err := recovery.Rollback(t.Context(), 0, "route", "route0", 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), version)
+	require.Equal(t, []string{"aaa", "bbb", "aaa"}, applied)
+
+	configs := recovery.Configs()
+	require.Len(t, configs, 1)
+	require.Equal(t, uint64(4), configs[0].Version)
+	require.Equal(t, "aaa", configs[0].Digest)
+
+	events := recovery.History(0, time.Time{}, time.Time{})
+	require.Equal(t, ApplyEvent{Module: "route", Name: "route0", Version: 4, Digest: "aaa", Kind: ApplyRolledBack}, withoutTimes(events[len(events)-1]))
+
+	// The rollback is applied again after a reset.
+	recovery.Check(t.Context())
+	instances.set(0, 1)
+	recovery.Check(t.Context())
+	require.Equal(t, []string{"aaa", "bbb", "aaa", "aaa"}, applied)
+	// The push is told how it is applied.
+	require.Equal(t, []ApplyKind{ApplyPushed, ApplyPushed, ApplyRolledBack, ApplyRepushed}, kinds)
+
+	// Only the most recent versions are kept, the rollback included.
+	_, err = recovery.Rollback(t.Context(), 0, "route", "route0", 1)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = recovery.Rollback(t.Context(), 0, "route", "route0", 2)
+	require.NoError(t, err)
+
+	recovery.Forget(0, "route", "route0")
+	_, err = recovery.Rollback(t.Context(), 0, "route", "route0", 5)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+}
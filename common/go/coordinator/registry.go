@@ -0,0 +1,310 @@
+// Package coordinator provides the building blocks of services that set up
+// named module configurations and keep a long-running instance per
+// configuration, such as the BIRD adapter imports: config decoding, an
+// instance registry restarting failed instances with backoff, the
+// instance status to report, the capabilities the configs require from
+// the dataplane instances, the recovery of the configs lost by restarted
+// dataplane instances and the admin API managing them remotely.
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/common/go/xbackoff"
+)
+
+const (
+	// DefaultInitialBackoff is the default delay before the first restart
+	// of a failed instance.
+	DefaultInitialBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff is the default cap of the restart delay.
+	DefaultMaxBackoff = time.Minute
+	// DefaultBackoffResetAfter is the default duration an instance must run
+	// for its restart delay to return to the initial one.
+	DefaultBackoffResetAfter = 10 * time.Minute
+)
+
+// Instance is a long-running piece of work set up for one named module
+// configuration, such as a route import stream.
+type Instance interface {
+	// Run blocks until the instance stops.
+	//
+	// A nil error stops the instance for good, while any other error
+	// restarts it after a backoff delay, unless ctx is cancelled.
+	Run(ctx context.Context) error
+}
+
+// InstanceFunc adapts a function to the Instance interface.
+type InstanceFunc func(ctx context.Context) error
+
+// Run calls the function.
+func (m InstanceFunc) Run(ctx context.Context) error {
+	return m(ctx)
+}
+
+// RegistryOption configures NewRegistry.
+type RegistryOption func(*registryOptions)
+
+type registryOptions struct {
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffResetAfter time.Duration
+	Log               *zap.Logger
+}
+
+func newRegistryOptions() *registryOptions {
+	return &registryOptions{
+		InitialBackoff:    DefaultInitialBackoff,
+		MaxBackoff:        DefaultMaxBackoff,
+		BackoffResetAfter: DefaultBackoffResetAfter,
+		Log:               zap.NewNop(),
+	}
+}
+
+// WithBackoff sets the restart delay bounds of failed instances.
+func WithBackoff(initial time.Duration, max time.Duration) RegistryOption {
+	return func(o *registryOptions) {
+		o.InitialBackoff = initial
+		o.MaxBackoff = max
+	}
+}
+
+// WithBackoffResetAfter sets how long an instance must run for its restart
+// delay to return to the initial one.
+func WithBackoffResetAfter(d time.Duration) RegistryOption {
+	return func(o *registryOptions) {
+		o.BackoffResetAfter = d
+	}
+}
+
+// WithLog sets the logger of the registry.
+func WithLog(log *zap.Logger) RegistryOption {
+	return func(o *registryOptions) {
+		o.Log = log
+	}
+}
+
+// Registry runs at most one instance per configuration name.
+//
+// Setting up a configuration that already has an instance stops the old
+// one before the new one starts, so two instances of the same
+// configuration never run concurrently.
+type Registry struct {
+	mu        sync.Mutex
+	instances map[string]*instanceHolder
+	stopped   bool
+	opts      *registryOptions
+	log       *zap.Logger
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(options ...RegistryOption) *Registry {
+	opts := newRegistryOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &Registry{
+		instances: map[string]*instanceHolder{},
+		opts:      opts,
+		log:       opts.Log,
+	}
+}
+
+// ErrRegistryStopped is returned by Setup after the registry was stopped.
+var ErrRegistryStopped = errors.New("instance registry is stopped")
+
+// Setup starts the instance of a configuration, replacing the current one,
+// and returns the generation it runs as.
+//
+// Generations start from 1 and are incremented by every Setup of the same
+// configuration name until it is removed.
+func (m *Registry) Setup(name string, instance Instance) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return 0, ErrRegistryStopped
+	}
+
+	generation := uint64(1)
+	if prev, ok := m.instances[name]; ok {
+		m.log.Info("replacing instance", zap.String("name", name))
+		prev.stop()
+		generation = prev.generation + 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	holder := &instanceHolder{
+		name:       name,
+		instance:   instance,
+		generation: generation,
+		createdAt:  time.Now(),
+		state:      StateRunning,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	m.instances[name] = holder
+
+	go m.run(ctx, holder)
+
+	return generation, nil
+}
+
+// Remove stops and forgets the instance of a configuration.
+//
+// Returns false if the configuration has no instance.
+func (m *Registry) Remove(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	holder, ok := m.instances[name]
+	if !ok {
+		return false
+	}
+
+	holder.stop()
+	delete(m.instances, name)
+	return true
+}
+
+// Status returns the status of the instance of a configuration.
+func (m *Registry) Status(name string) (Status, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	holder, ok := m.instances[name]
+	if !ok {
+		return Status{}, false
+	}
+	return holder.status(), true
+}
+
+// List returns the status of every instance, ordered by name.
+func (m *Registry) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.instances))
+	for _, holder := range m.instances {
+		statuses = append(statuses, holder.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// Stop stops every instance and waits for them to return.
+//
+// Further Setup calls fail with ErrRegistryStopped.
+func (m *Registry) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopped = true
+	for name, holder := range m.instances {
+		holder.stop()
+		delete(m.instances, name)
+	}
+}
+
+// run runs the instance until it stops cleanly or its context is
+// cancelled, restarting it with a backoff delay after failures.
+func (m *Registry) run(ctx context.Context, holder *instanceHolder) {
+	defer close(holder.done)
+
+	log := m.log.With(zap.String("name", holder.name), zap.Uint64("generation", holder.generation))
+	backoff := xbackoff.New(m.opts.InitialBackoff, xbackoff.WithMax(m.opts.MaxBackoff))
+	sleeper := xbackoff.TimerSleeper{}
+
+	for {
+		startedAt := time.Now()
+		err := holder.instance.Run(ctx)
+		if ctx.Err() != nil {
+			holder.setState(StateStopped, nil)
+			return
+		}
+		if err == nil {
+			log.Info("instance stopped")
+			holder.setState(StateStopped, nil)
+			return
+		}
+
+		if time.Since(startedAt) > m.opts.BackoffResetAfter {
+			backoff.Reset()
+		}
+		delay := backoff.Next()
+
+		log.Warn("instance failed, restarting", zap.Duration("delay", delay), zap.Error(err))
+		holder.setState(StateBackoff, err)
+
+		if err := sleeper.Sleep(ctx, delay); err != nil {
+			holder.setState(StateStopped, nil)
+			return
+		}
+		holder.setState(StateRunning, nil)
+	}
+}
+
+// instanceHolder bundles an instance with its lifecycle state.
+//
+// The state is guarded by its own mutex rather than the registry one, so
+// the run loop can update it while the registry waits for the loop to
+// return.
+type instanceHolder struct {
+	name       string
+	instance   Instance
+	generation uint64
+	createdAt  time.Time
+	cancel     context.CancelFunc // Stops the run loop of the instance
+	done       chan struct{}      // Closed once the run loop returns
+
+	mu          sync.Mutex
+	state       State
+	restarts    int
+	lastError   error
+	lastErrorAt time.Time
+}
+
+// stop cancels the instance and waits for its run loop to return.
+func (m *instanceHolder) stop() {
+	m.cancel()
+	<-m.done
+}
+
+// setState records a state transition, counting a restart for a non-nil
+// error.
+func (m *instanceHolder) setState(state State, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = state
+	if err != nil {
+		m.restarts++
+		m.lastError = err
+		m.lastErrorAt = time.Now()
+	}
+}
+
+func (m *instanceHolder) status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return Status{
+		Name:        m.name,
+		Generation:  m.generation,
+		State:       m.state,
+		CreatedAt:   m.createdAt,
+		Restarts:    m.restarts,
+		LastError:   m.lastError,
+		LastErrorAt: m.lastErrorAt,
+	}
+}
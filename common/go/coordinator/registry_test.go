@@ -0,0 +1,112 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/xcfg"
+)
+
+// blockingInstance runs until cancelled, counting its runs.
+func blockingInstance(runs *atomic.Int32) Instance {
+	return InstanceFunc(func(ctx context.Context) error {
+		runs.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+}
+
+func TestRegistry_SetupReplaces(t *testing.T) {
+	registry := NewRegistry()
+	defer registry.Stop()
+
+	var first, second atomic.Int32
+	generation, err := registry.Setup("route0", blockingInstance(&first))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), generation)
+	require.Eventually(t, func() bool { return first.Load() == 1 }, time.Second, time.Millisecond)
+
+	generation, err = registry.Setup("route0", blockingInstance(&second))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), generation)
+	require.Eventually(t, func() bool { return second.Load() == 1 }, time.Second, time.Millisecond)
+
+	statuses := registry.List()
+	require.Len(t, statuses, 1)
+	require.Equal(t, "route0", statuses[0].Name)
+	require.Equal(t, uint64(2), statuses[0].Generation)
+	require.Equal(t, StateRunning, statuses[0].State)
+
+	require.True(t, registry.Remove("route0"))
+	require.False(t, registry.Remove("route0"))
+	require.Empty(t, registry.List())
+}
+
+func TestRegistry_RestartsFailedInstance(t *testing.T) {
+	registry := NewRegistry(WithBackoff(time.Millisecond, time.Millisecond))
+	defer registry.Stop()
+
+	errFailed := errors.New("stream broken")
+	var runs atomic.Int32
+	_, err := registry.Setup("route0", InstanceFunc(func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			return errFailed
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		status, ok := registry.Status("route0")
+		return ok && status.State == StateStopped
+	}, time.Second, time.Millisecond)
+
+	status, ok := registry.Status("route0")
+	require.True(t, ok)
+	require.Equal(t, 2, status.Restarts)
+	require.ErrorIs(t, status.LastError, errFailed)
+	require.Equal(t, int32(3), runs.Load())
+}
+
+func TestRegistry_Stop(t *testing.T) {
+	registry := NewRegistry()
+
+	var runs atomic.Int32
+	_, err := registry.Setup("route0", blockingInstance(&runs))
+	require.NoError(t, err)
+
+	registry.Stop()
+	require.Empty(t, registry.List())
+
+	_, err = registry.Setup("route1", blockingInstance(&runs))
+	require.ErrorIs(t, err, ErrRegistryStopped)
+}
+
+type testConfig struct {
+	Name    xcfg.NonEmptyString `yaml:"name"`
+	Workers int                 `yaml:"workers"`
+}
+
+func (m *testConfig) Default() {
+	m.Workers = 4
+}
+
+func TestDecodeConfig(t *testing.T) {
+	cfg, err := DecodeConfig[testConfig]([]byte("name: route0"))
+	require.NoError(t, err)
+	require.Equal(t, "route0", cfg.Name.Unwrap())
+	require.Equal(t, 4, cfg.Workers)
+
+	_, err = DecodeConfig[testConfig]([]byte("workers: 1"))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.Equal(t, codes.InvalidArgument, status.Code(ValidateName("")))
+	require.NoError(t, ValidateName("route0"))
+}
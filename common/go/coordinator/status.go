@@ -0,0 +1,49 @@
+package coordinator
+
+import (
+	"time"
+)
+
+// State is the lifecycle state of an instance.
+type State int
+
+const (
+	// StateRunning is an instance whose Run is in progress.
+	StateRunning State = iota
+	// StateBackoff is a failed instance waiting to be restarted.
+	StateBackoff
+	// StateStopped is an instance that returned cleanly or was stopped.
+	StateStopped
+)
+
+// String returns the lowercase name of the state.
+func (m State) String() string {
+	switch m {
+	case StateRunning:
+		return "running"
+	case StateBackoff:
+		return "backoff"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is a snapshot of the lifecycle of an instance, suitable for
+// reporting through a listing RPC.
+type Status struct {
+	// Name of the configuration the instance is set up for.
+	Name string
+	// Generation is the number of the Setup that started the instance.
+	Generation uint64
+	State      State
+	CreatedAt  time.Time
+	// Restarts is the number of times the instance failed and was
+	// restarted.
+	Restarts int
+	// LastError is the error the instance last failed with, nil if it has
+	// never failed.
+	LastError   error
+	LastErrorAt time.Time
+}
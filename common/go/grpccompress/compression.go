@@ -0,0 +1,63 @@
+// Package grpccompress selects the gRPC message compression from the
+// configuration and registers the zstd compressor next to the gzip one.
+package grpccompress
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compression is the name of a gRPC message compressor.
+type Compression string
+
+const (
+	// None disables the compression.
+	None Compression = "none"
+	// Gzip compresses the messages with gzip.
+	Gzip Compression = gzip.Name
+	// Zstd compresses the messages with zstd, usually faster and denser
+	// than gzip on route tables.
+	Zstd Compression = ZstdName
+)
+
+// Validate checks that the compression is a known one.
+//
+// The empty compression is valid and means None.
+func (m Compression) Validate() error {
+	switch m {
+	case "", None, Gzip, Zstd:
+		return nil
+	default:
+		return fmt.Errorf("unknown compression %q: must be one of %q, %q or %q", string(m), None, Gzip, Zstd)
+	}
+}
+
+// Enabled reports whether the messages are compressed.
+func (m Compression) Enabled() bool {
+	return m != "" && m != None
+}
+
+// CallOptions returns the options compressing the messages sent by a
+// client.
+func (m Compression) CallOptions() []grpc.CallOption {
+	if !m.Enabled() {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(string(m))}
+}
+
+// SetSendCompressor compresses the response of the server handler the
+// context belongs to, if the client advertised support of the compression.
+//
+// Otherwise the response keeps the compression of the request.
+func (m Compression) SetSendCompressor(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	// It fails for clients not supporting the compression, which get the
+	// response as is.
+	_ = grpc.SetSendCompressor(ctx, string(m))
+}
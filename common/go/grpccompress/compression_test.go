@@ -0,0 +1,46 @@
+package grpccompress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompression_Validate(t *testing.T) {
+	for _, c := range []Compression{"", None, Gzip, Zstd} {
+		require.NoError(t, c.Validate(), c)
+	}
+	require.Error(t, Compression("lz4").Validate())
+
+	require.False(t, Compression("").Enabled())
+	require.False(t, None.Enabled())
+	require.Empty(t, None.CallOptions())
+	require.Len(t, Zstd.CallOptions(), 1)
+}
+
+func TestZstdCompressor(t *testing.T) {
+	compressor := encoding.GetCompressor(ZstdName)
+	require.NotNil(t, compressor)
+
+	payload := bytes.Repeat([]byte("10.0.0.0/24 via 192.0.2.1\n"), 1000)
+
+	// The second round reuses the pooled encoder and decoder.
+	for range 2 {
+		buf := &bytes.Buffer{}
+		w, err := compressor.Compress(buf)
+		require.NoError(t, err)
+		_, err = w.Write(payload)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.Less(t, buf.Len(), len(payload)/10)
+
+		r, err := compressor.Decompress(buf)
+		require.NoError(t, err)
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, payload, out)
+	}
+}
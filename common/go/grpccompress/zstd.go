@@ -0,0 +1,82 @@
+package grpccompress
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// ZstdName is the name the zstd compressor is registered under.
+const ZstdName = "zstd"
+
+func init() {
+	c := &zstdCompressor{}
+	c.encoders.New = func() any {
+		// Single-threaded encoders and decoders work synchronously, so
+		// the pooled ones hold no goroutines.
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return &zstdWriter{Encoder: encoder, pool: &c.encoders}
+	}
+	c.decoders.New = func() any {
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return &zstdReader{Decoder: decoder, pool: &c.decoders}
+	}
+	encoding.RegisterCompressor(c)
+}
+
+// zstdCompressor implements encoding.Compressor, pooling the encoders and
+// decoders as the gzip one does.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (m *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	z := m.encoders.Get().(*zstdWriter)
+	z.Encoder.Reset(w)
+	return z, nil
+}
+
+func (m *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	z := m.decoders.Get().(*zstdReader)
+	if err := z.Decoder.Reset(r); err != nil {
+		m.decoders.Put(z)
+		return nil, err
+	}
+	return z, nil
+}
+
+func (m *zstdCompressor) Name() string {
+	return ZstdName
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (m *zstdWriter) Close() error {
+	defer m.pool.Put(m)
+	return m.Encoder.Close()
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+}
+
+func (m *zstdReader) Read(p []byte) (int, error) {
+	n, err := m.Decoder.Read(p)
+	if err == io.EOF {
+		m.pool.Put(m)
+	}
+	return n, err
+}
@@ -0,0 +1,165 @@
+// Package grpctls builds the TLS transport credentials of the gRPC clients
+// and servers from the configuration.
+//
+// A nil configuration stands for plaintext, so that TLS stays opt-in in
+// every config carrying one.
+package grpctls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ClientConfig configures TLS of a gRPC client.
+//
+// All files must be PEM-encoded.
+type ClientConfig struct {
+	// CAFile is the path to the CA certificates the server certificate is
+	// verified against.
+	//
+	// Optional. Defaults to the system roots.
+	CAFile string `yaml:"ca_file"`
+	// CertFile is the path to the client certificate presented for mutual
+	// authentication.
+	//
+	// Optional, set together with KeyFile.
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path to the private key of CertFile.
+	KeyFile string `yaml:"key_file"`
+	// ServerName overrides the name the server certificate is verified
+	// against, also sent as SNI.
+	//
+	// Optional. Defaults to the host of the dialed endpoint.
+	ServerName string `yaml:"server_name"`
+}
+
+// Validate checks that the client certificate and key are set together.
+func (m *ClientConfig) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if (m.CertFile == "") != (m.KeyFile == "") {
+		return errors.New("TLS client cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Credentials loads the TLS material and returns gRPC client transport
+// credentials, insecure ones for a nil config.
+func (m *ClientConfig) Credentials() (credentials.TransportCredentials, error) {
+	if m == nil {
+		return insecure.NewCredentials(), nil
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		ServerName: m.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if m.CAFile != "" {
+		pool, err := LoadCertPool(m.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if m.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(m.CertFile, m.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client keypair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+// ServerConfig configures TLS of a gRPC server.
+//
+// All files must be PEM-encoded.
+type ServerConfig struct {
+	// CertFile is the path to the server certificate.
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path to the private key of CertFile.
+	KeyFile string `yaml:"key_file"`
+	// ClientCAFile is the path to the CA certificates client certificates
+	// are verified against.
+	//
+	// Optional. When set, clients must authenticate with a certificate
+	// signed by one of them.
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Validate checks that the server certificate and key are set.
+func (m *ServerConfig) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.CertFile == "" || m.KeyFile == "" {
+		return errors.New("TLS server cert_file and key_file are required")
+	}
+	return nil
+}
+
+// Credentials loads the TLS material and returns gRPC server transport
+// credentials, insecure ones for a nil config.
+func (m *ServerConfig) Credentials() (credentials.TransportCredentials, error) {
+	if m == nil {
+		return insecure.NewCredentials(), nil
+	}
+	cfg, err := m.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// TLSConfig loads the TLS material into a server TLS config, for the
+// listeners other than gRPC ones.
+func (m *ServerConfig) TLSConfig() (*tls.Config, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(m.CertFile, m.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS server keypair: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if m.ClientCAFile != "" {
+		pool, err := LoadCertPool(m.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// LoadCertPool reads a pool of PEM-encoded certificates from a file.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("failed to parse PEM certificates from %q", path)
+	}
+	return pool, nil
+}
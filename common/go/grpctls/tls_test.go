@@ -0,0 +1,154 @@
+package grpctls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testPKI writes a CA and the certificates it signs to a directory.
+type testPKI struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	m := &testPKI{dir: t.TempDir(), cert: cert, key: key}
+	m.writePEM(t, "ca.pem", "CERTIFICATE", der)
+	return m
+}
+
+func (m *testPKI) writePEM(t *testing.T, name string, kind string, der []byte) string {
+	path := filepath.Join(m.dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600))
+	return path
+}
+
+// issue writes a certificate of the given name signed by the CA, returning
+// the paths to the certificate and its key.
+func (m *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, m.cert, &key.PublicKey, m.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return m.writePEM(t, name+".pem", "CERTIFICATE", der), m.writePEM(t, name+".key", "EC PRIVATE KEY", keyDER)
+}
+
+func serve(t *testing.T, cfg *ServerConfig) string {
+	creds, err := cfg.Credentials()
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func check(t *testing.T, endpoint string, cfg *ClientConfig) error {
+	creds, err := cfg.Credentials()
+	require.NoError(t, err)
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(t.Context(), &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestCredentials(t *testing.T) {
+	pki := newTestPKI(t)
+	ca := filepath.Join(pki.dir, "ca.pem")
+	serverCert, serverKey := pki.issue(t, "gateway.test", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := pki.issue(t, "operator.test", x509.ExtKeyUsageClientAuth)
+
+	t.Run("plaintext", func(t *testing.T) {
+		endpoint := serve(t, nil)
+		require.NoError(t, check(t, endpoint, nil))
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		endpoint := serve(t, &ServerConfig{CertFile: serverCert, KeyFile: serverKey})
+
+		require.NoError(t, check(t, endpoint, &ClientConfig{CAFile: ca, ServerName: "gateway.test"}))
+		// The certificate is issued for another name than the dialed host.
+		require.Error(t, check(t, endpoint, &ClientConfig{CAFile: ca}))
+		require.Error(t, check(t, endpoint, nil))
+	})
+
+	t.Run("mutual", func(t *testing.T) {
+		endpoint := serve(t, &ServerConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca})
+
+		require.NoError(t, check(t, endpoint, &ClientConfig{
+			CAFile:     ca,
+			CertFile:   clientCert,
+			KeyFile:    clientKey,
+			ServerName: "gateway.test",
+		}))
+		require.Error(t, check(t, endpoint, &ClientConfig{CAFile: ca, ServerName: "gateway.test"}))
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, (*ClientConfig)(nil).Validate())
+	require.NoError(t, (&ClientConfig{}).Validate())
+	require.Error(t, (&ClientConfig{CertFile: "client.pem"}).Validate())
+
+	require.NoError(t, (*ServerConfig)(nil).Validate())
+	require.Error(t, (&ServerConfig{CertFile: "server.pem"}).Validate())
+
+	_, err := (&ServerConfig{CertFile: "missing.pem", KeyFile: "missing.key"}).Credentials()
+	require.Error(t, err)
+	_, err = (&ClientConfig{CAFile: "missing.pem"}).Credentials()
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+// Package metricshttp serves metrics in the Prometheus text exposition
+// format over HTTP.
+//
+// The metrics are the commonpb.Metric snapshots the gRPC metrics services
+// already return, so a component exposing those can be scraped without a
+// Prometheus client library.
+package metricshttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+)
+
+// ContentType is the content type of the Prometheus text exposition
+// format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Path is the path the metrics are served at.
+const Path = "/metrics"
+
+// shutdownTimeout bounds the time in-flight scrapes are waited for on
+// shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Collector returns a snapshot of the metrics to serve.
+type Collector func() []*commonpb.Metric
+
+// Handler returns the HTTP handler rendering the metrics of the collector
+// on every request.
+func Handler(collect Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = Write(w, collect())
+	})
+}
+
+// Write renders the metrics in the Prometheus text exposition format.
+//
+// The metrics of the same name are written together under a single TYPE
+// line, in the order their names first appear. The histogram buckets of
+// commonpb hold per-bucket counts, so they are accumulated into the
+// cumulative buckets Prometheus expects. No _sum series is written, since
+// the snapshots do not carry the sum of the observations.
+func Write(w io.Writer, metrics []*commonpb.Metric) error {
+	names := make([]string, 0)
+	families := map[string][]*commonpb.Metric{}
+	for _, metric := range metrics {
+		name := metric.GetName()
+		if _, ok := families[name]; !ok {
+			names = append(names, name)
+		}
+		families[name] = append(families[name], metric)
+	}
+
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		family := families[name]
+		fmt.Fprintf(out, "# TYPE %s %s\n", name, metricType(family[0]))
+		for _, metric := range family {
+			writeMetric(out, metric)
+		}
+	}
+	return out.Flush()
+}
+
+func metricType(metric *commonpb.Metric) string {
+	switch metric.GetValue().(type) {
+	case *commonpb.Metric_Counter:
+		return "counter"
+	case *commonpb.Metric_Gauge:
+		return "gauge"
+	case *commonpb.Metric_Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+func writeMetric(out *bufio.Writer, metric *commonpb.Metric) {
+	name := metric.GetName()
+	labels := metric.GetLabels()
+
+	switch value := metric.GetValue().(type) {
+	case *commonpb.Metric_Counter:
+		writeSample(out, name, labels, "", strconv.FormatUint(value.Counter, 10))
+	case *commonpb.Metric_Gauge:
+		writeSample(out, name, labels, "", formatFloat(value.Gauge))
+	case *commonpb.Metric_Histogram:
+		cumulative := uint64(0)
+		for _, bucket := range value.Histogram.GetBuckets() {
+			cumulative += bucket.GetCount()
+			le := fmt.Sprintf(`le="%s"`, formatFloat(bucket.GetUpperBound()))
+			writeSample(out, name+"_bucket", labels, le, strconv.FormatUint(cumulative, 10))
+		}
+		writeSample(out, name+"_count", labels, "", strconv.FormatUint(value.Histogram.GetTotalCount(), 10))
+	}
+}
+
+// writeSample writes a sample line, extra being a rendered label added
+// after the metric labels.
+func writeSample(out *bufio.Writer, name string, labels []*commonpb.Label, extra string, value string) {
+	out.WriteString(name)
+	if len(labels) > 0 || extra != "" {
+		out.WriteByte('{')
+		for idx, label := range labels {
+			if idx > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprintf(out, `%s="%s"`, label.GetName(), escapeLabelValue(label.GetValue()))
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(extra)
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(value)
+	out.WriteByte('\n')
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Serve serves the metrics of the collector at Path on the listener until
+// ctx is cancelled.
+//
+// In-flight scrapes are given a few seconds to complete on shutdown.
+func Serve(ctx context.Context, listener net.Listener, collect Collector, log *zap.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler(collect))
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warn("failed to shut down metrics server", zap.Error(err))
+		}
+	}()
+
+	log.Info("metrics server listening", zap.Stringer("addr", listener.Addr()))
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
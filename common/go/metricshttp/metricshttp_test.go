@@ -0,0 +1,91 @@
+package metricshttp
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+)
+
+func TestWrite(t *testing.T) {
+	label := func(name, value string) *commonpb.Label {
+		return &commonpb.Label{Name: name, Value: value}
+	}
+
+	metrics := []*commonpb.Metric{
+		{
+			Name:   "routes_total",
+			Labels: []*commonpb.Label{label("config", "bird0")},
+			Value:  &commonpb.Metric_Counter{Counter: 42},
+		},
+		{
+			Name:  "connected",
+			Value: &commonpb.Metric_Gauge{Gauge: 0.5},
+		},
+		{
+			Name:   "routes_total",
+			Labels: []*commonpb.Label{label("config", `a "quoted"\name`)},
+			Value:  &commonpb.Metric_Counter{Counter: 1},
+		},
+		{
+			Name:   "latency_seconds",
+			Labels: []*commonpb.Label{label("config", "bird0")},
+			Value: &commonpb.Metric_Histogram{Histogram: &commonpb.Histogram{
+				Buckets: []*commonpb.Bucket{
+					{UpperBound: 0.1, Count: 2},
+					{UpperBound: 1, Count: 3},
+					{UpperBound: math.Inf(1), Count: 1},
+				},
+				TotalCount: 6,
+			}},
+		},
+	}
+
+	out := strings.Builder{}
+	require.NoError(t, Write(&out, metrics))
+	require.Equal(t, `# TYPE routes_total counter
+routes_total{config="bird0"} 42
+routes_total{config="a \"quoted\"\\name"} 1
+# TYPE connected gauge
+connected 0.5
+# TYPE latency_seconds histogram
+latency_seconds_bucket{config="bird0",le="0.1"} 2
+latency_seconds_bucket{config="bird0",le="1"} 5
+latency_seconds_bucket{config="bird0",le="+Inf"} 6
+latency_seconds_count{config="bird0"} 6
+`, out.String())
+}
+
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collect := func() []*commonpb.Metric {
+		return []*commonpb.Metric{{Name: "up", Value: &commonpb.Metric_Gauge{Gauge: 1}}}
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, listener, collect, zap.NewNop())
+	}()
+
+	response, err := http.Get("http://" + listener.Addr().String() + Path)
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, response.Body.Close())
+	require.NoError(t, err)
+	require.Equal(t, ContentType, response.Header.Get("Content-Type"))
+	require.Equal(t, "# TYPE up gauge\nup 1\n", string(body))
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
 )
 
@@ -17,6 +18,9 @@ const (
 // GRPCServerConfig describes how to expose the operator's gRPC server.
 type GRPCServerConfig struct {
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
+	// TLS configures TLS of the server, optionally authenticating the
+	// clients. When nil, the server listens in plaintext.
+	TLS *grpctls.ServerConfig `yaml:"tls,omitempty"`
 }
 
 // GatewayConfig holds the name and gRPC endpoint of a single Gateway.
@@ -25,6 +29,10 @@ type GatewayConfig struct {
 	Name string `yaml:"name"`
 	// Endpoint is the gRPC address of the Gateway.
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
+	// TLS configures TLS of the connections to the Gateway, both for
+	// registration and for pushing configs. When nil, the Gateway is
+	// dialed in plaintext.
+	TLS *grpctls.ClientConfig `yaml:"tls,omitempty"`
 }
 
 // RegisterConfig holds the gateway registration heartbeat parameter.
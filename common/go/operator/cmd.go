@@ -28,14 +28,38 @@ type loggingConfig interface {
 	LoggingConfig() *logging.Config
 }
 
+// profiledConfig is implemented by config types holding several profiles
+// (environments), one of which is active.
+type profiledConfig interface {
+	// SelectProfile activates the named profile.
+	SelectProfile(name string) error
+}
+
+// ProfileSwitch is returned by Runnable.Run to request a restart of the
+// operator with another profile of its config.
+//
+// The config file is reloaded before the restart, so edits of the profiles
+// made since startup take effect.
+type ProfileSwitch struct {
+	// Profile is the name of the profile to activate.
+	Profile string
+}
+
+func (m ProfileSwitch) Error() string {
+	return fmt.Sprintf("switching to config profile %q", m.Profile)
+}
+
 // Run is the generic entry point for an operator binary.
 //
 // It wires up:
-//   - CLI command setup with required --config flag.
+//   - CLI command setup with required --config flag and optional --profile
+//     flag.
 //   - Loading and validating the YAML config.
 //   - Initializing logging from the config.
 //   - Constructing the Runnable from the build callback.
 //   - Running the Runnable in an errgroup alongside xcmd.WaitInterrupted.
+//   - A "validate" subcommand checking the config without running, see
+//     ValidateConfig.
 //
 // Note that xcmd.Interrupted is treated as a clean shutdown.
 func Run[C any](
@@ -44,6 +68,7 @@ func Run[C any](
 	factory func(*C, *zap.Logger) (Runnable, error),
 ) error {
 	var path string
+	var profile string
 
 	root := &cobra.Command{
 		Use:           use,
@@ -51,7 +76,7 @@ func Run[C any](
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := RunOperator(path, factory)
+			err := RunOperator(path, profile, factory)
 			if errors.Is(err, xcmd.Interrupted{}) {
 				return nil
 			}
@@ -63,23 +88,82 @@ func Run[C any](
 		&path, "config", "c", "",
 		"Path to the configuration file (required)",
 	)
+	root.Flags().StringVarP(
+		&profile, "profile", "p", "",
+		"Config profile to activate, overriding the one set in the config file",
+	)
 	if err := root.MarkFlagRequired("config"); err != nil {
 		return fmt.Errorf("failed to mark --config required: %w", err)
 	}
 
+	validate, err := newValidateCommand[C]()
+	if err != nil {
+		return err
+	}
+	root.AddCommand(validate)
+
 	return root.Execute()
 }
 
+func newValidateCommand[C any]() (*cobra.Command, error) {
+	var path string
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration file and exit",
+		Long: "Validate the configuration file the way it is loaded on start, without " +
+			"connecting anywhere, so that config changes can be checked before they are deployed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ValidateConfig[C](path, profile); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: config is valid\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(
+		&path, "config", "c", "",
+		"Path to the configuration file (required)",
+	)
+	cmd.Flags().StringVarP(
+		&profile, "profile", "p", "",
+		"Config profile to validate the activation of",
+	)
+	if err := cmd.MarkFlagRequired("config"); err != nil {
+		return nil, fmt.Errorf("failed to mark --config required: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// ValidateConfig loads the config specified by path the way RunOperator
+// does, activating the given profile if non-empty, and drops it.
+//
+// Neither the Gateways nor the dataplane are needed, so the pre-merge
+// checks of a config repository can run it.
+func ValidateConfig[C any](path string, profile string) error {
+	_, err := loadConfig[C](path, profile)
+	return err
+}
+
 // RunOperator loads the config specified by path and runs the Runnable
 // returned by build until the process is interrupted or any goroutine
 // returns an error.
+//
+// A non-empty profile activates that profile of the config. The Runnable
+// returning ProfileSwitch is closed and rebuilt from the reloaded config
+// with the requested profile active.
 func RunOperator[C any](
 	path string,
+	profile string,
 	factory func(*C, *zap.Logger) (Runnable, error),
 ) error {
-	cfg, err := xcfg.LoadConfig[C](path)
+	cfg, err := loadConfig[C](path, profile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
 	log, err := initLogging(cfg)
@@ -91,8 +175,52 @@ func RunOperator[C any](
 		_ = log.Sync()
 	}()
 
-	log.Debug("parsed config", zap.String("path", path), zap.Any("config", cfg))
+	for {
+		log.Debug("parsed config", zap.String("path", path), zap.Any("config", cfg))
+
+		err := runOnce(cfg, log, factory)
+
+		var profileSwitch ProfileSwitch
+		if !errors.As(err, &profileSwitch) {
+			return err
+		}
+
+		log.Info("switching config profile", zap.String("profile", profileSwitch.Profile))
+		if cfg, err = loadConfig[C](path, profileSwitch.Profile); err != nil {
+			return err
+		}
+	}
+}
 
+// loadConfig loads the config specified by path, activating the given
+// profile if non-empty.
+func loadConfig[C any](path string, profile string) (*C, error) {
+	cfg, err := xcfg.LoadConfig[C](path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if profile == "" {
+		return cfg, nil
+	}
+
+	profiled, ok := any(cfg).(profiledConfig)
+	if !ok {
+		return nil, fmt.Errorf("config does not support profiles, cannot activate %q", profile)
+	}
+	if err := profiled.SelectProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to activate config profile: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// runOnce runs a single Runnable built from cfg until it or the interrupt
+// watcher returns.
+func runOnce[C any](
+	cfg *C,
+	log *zap.Logger,
+	factory func(*C, *zap.Logger) (Runnable, error),
+) error {
 	runnable, err := factory(cfg, log)
 	if err != nil {
 		return fmt.Errorf("failed to construct operator: %w", err)
@@ -0,0 +1,62 @@
+package operator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testProfile struct {
+	Name string `yaml:"name"`
+}
+
+type testConfig struct {
+	Reconcile ReconcileConfig        `yaml:"reconcile"`
+	Profiles  map[string]testProfile `yaml:"profiles"`
+}
+
+func (m *testConfig) Default() {
+	m.Reconcile = ReconcileConfig{}
+}
+
+func (m *testConfig) SelectProfile(name string) error {
+	if _, ok := m.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	return nil
+}
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestValidateConfig(t *testing.T) {
+	path := writeTestConfig(t, `
+reconcile:
+  interval: 30s
+  initial_backoff: 1s
+  max_backoff: 10s
+profiles:
+  staging: {name: staging}
+`)
+	require.NoError(t, ValidateConfig[testConfig](path, ""))
+	require.NoError(t, ValidateConfig[testConfig](path, "staging"))
+	require.ErrorContains(t, ValidateConfig[testConfig](path, "production"), "unknown profile")
+
+	path = writeTestConfig(t, `
+reconcile:
+  interval: 30s
+  initial_backoff: 10s
+  max_backoff: 1s
+`)
+	require.ErrorContains(t, ValidateConfig[testConfig](path, ""), "max_backoff")
+
+	require.Error(t, ValidateConfig[testConfig](filepath.Join(t.TempDir(), "missing.yaml"), ""))
+}
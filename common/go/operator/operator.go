@@ -53,7 +53,7 @@ func NewOperator[T any](
 	actuator Actuator[T],
 	source StateSource[T],
 	options ...Option,
-) *Operator[T] {
+) (*Operator[T], error) {
 	opts := newOptions()
 	for _, o := range options {
 		o(opts)
@@ -68,11 +68,15 @@ func NewOperator[T any](
 	)
 
 	if opts.GRPCServer != nil {
-		server, serviceNames = NewGRPCServer(
+		var err error
+		server, serviceNames, err = NewGRPCServer(
 			opts.GRPCServer.cfg,
 			opts.GRPCServer.services,
 			WithGRPCLog(log),
 		)
+		if err != nil {
+			return nil, err
+		}
 		endpoint = opts.GRPCServer.cfg.Endpoint.Unwrap()
 	}
 
@@ -99,7 +103,7 @@ func NewOperator[T any](
 		register:     opts.Register,
 		serviceNames: serviceNames,
 		log:          log,
-	}
+	}, nil
 }
 
 // Close releases resources owned by the Operator.
@@ -94,9 +94,15 @@ func (m *GatewayRegRunner) Run(ctx context.Context) error {
 			zap.String("gateway_endpoint", cfg.Endpoint.Unwrap()),
 		)
 
+		creds, err := cfg.TLS.Credentials()
+		if err != nil {
+			return fmt.Errorf("failed to load TLS of gateway %q: %w", cfg.Name, err)
+		}
+
 		registrar, err := gateway.NewGatewayRegistrar(
 			cfg.Endpoint.Unwrap(),
 			nil,
+			gateway.WithRegistrarCredentials(creds),
 			gateway.WithBackOff(shortBackOff),
 			gateway.WithMaxElapsedTime(m.interval/2),
 			gateway.WithRegistrarLog(log),
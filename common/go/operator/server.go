@@ -42,13 +42,18 @@ func NewGRPCServer(
 	cfg *GRPCServerConfig,
 	services []ServiceRegistrar,
 	options ...GRPCServerOption,
-) (*GRPCServer, []string) {
+) (*GRPCServer, []string, error) {
 	opts := newGRPCServerOptions()
 	for _, o := range options {
 		o(opts)
 	}
 
-	server := grpc.NewServer()
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load gRPC server TLS: %w", err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds))
 	serviceNames := make([]string, len(services))
 	for idx, register := range services {
 		serviceNames[idx] = register(server)
@@ -58,7 +63,7 @@ func NewGRPCServer(
 		cfg:    cfg,
 		server: server,
 		log:    opts.Log,
-	}, serviceNames
+	}, serviceNames, nil
 }
 
 // Run serves until the supplied context is cancelled.
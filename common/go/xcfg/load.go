@@ -102,6 +102,18 @@ func validate(v reflect.Value, path string) error {
 		}
 	}
 
+	// Recurse into map values, copied first since they are not addressable.
+	if v.Kind() == reflect.Map {
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			if err := validate(value, fmt.Sprintf("%s[%v]", path, iter.Key())); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -194,6 +194,25 @@ func Test_Load_SliceElement_SecondElementMissingField(t *testing.T) {
 	require.Equal(t, "items[1].value", pathErr.Path)
 }
 
+func Test_Load_MapValue_MissingField(t *testing.T) {
+	type Item struct {
+		Name NonEmptyString `yaml:"name"`
+	}
+	type Config struct {
+		Items map[string]Item `yaml:"items"`
+	}
+
+	var cfg Config
+	require.NoError(t, Decode([]byte("items:\n  alpha:\n    name: a\n"), &cfg))
+
+	cfg = Config{}
+	err := Decode([]byte("items:\n  beta: {}\n"), &cfg)
+
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	require.Equal(t, "items[beta].name", pathErr.Path)
+}
+
 func Test_Load_LineErrorUnwrapsFromPathError(t *testing.T) {
 	// Verify that LineError from UnmarshalYAML is accessible via
 	// errors.As through the error chain, even when Decode doesn't
@@ -26,6 +26,8 @@ func WaitInterrupted(ctx context.Context) error {
 	ch := make(chan os.Signal, 1)
 
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(ch)
+
 	select {
 	case v := <-ch:
 		return Interrupted{Signal: v}
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
@@ -45,7 +47,10 @@ func (m *Device) List(
 	request *ynpb.ListDevicesRequest,
 ) (*ynpb.ListDevicesResponse, error) {
 	dpConfig := m.shm.DPConfig(m.instanceID)
-	devices := dpConfig.Devices()
+	devices, err := dpConfig.Devices()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list devices: %v", err)
+	}
 
 	ids := make([]*ynpb.DeviceId, len(devices))
 	for idx, device := range devices {
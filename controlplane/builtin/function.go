@@ -64,7 +64,10 @@ func (m *Function) List(
 ) (*ynpb.ListFunctionsResponse, error) {
 	dpConfig := m.shm.DPConfig(m.instanceID)
 
-	functions := dpConfig.Functions()
+	functions, err := dpConfig.Functions()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list functions: %v", err)
+	}
 
 	response := &ynpb.ListFunctionsResponse{
 		Ids: make([]*commonpb.FunctionId, len(functions)),
@@ -87,7 +90,10 @@ func (m *Function) Get(
 
 	reqId := request.Id
 
-	functions := dpConfig.Functions()
+	functions, err := dpConfig.Functions()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list functions: %v", err)
+	}
 	for _, function := range functions {
 		if reqId.Name == function.Name {
 			respChains := make([]*ynpb.FunctionChain, len(function.Chains))
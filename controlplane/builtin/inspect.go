@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
@@ -47,15 +49,36 @@ func (m *Inspect) Inspect(
 ) (*ynpb.InspectResponse, error) {
 	dpConfig := m.shm.DPConfig(m.instanceID)
 
+	cpConfigs, err := m.cpConfigs(dpConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list module configs: %v", err)
+	}
+	pipelines, err := m.pipelines(dpConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list pipelines: %v", err)
+	}
+	functions, err := m.functions(dpConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list functions: %v", err)
+	}
+	agents, err := m.agents(dpConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list agents: %v", err)
+	}
+	devices, err := m.devices(dpConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list devices: %v", err)
+	}
+
 	instanceInfo := &ynpb.InstanceInfo{
 		InstanceIdx: m.instanceID,
 		NumaIdx:     m.numaIdx(dpConfig),
 		DpModules:   m.dpModules(dpConfig),
-		CpConfigs:   m.cpConfigs(dpConfig),
-		Pipelines:   m.pipelines(dpConfig),
-		Functions:   m.functions(dpConfig),
-		Agents:      m.agents(dpConfig),
-		Devices:     m.devices(dpConfig),
+		CpConfigs:   cpConfigs,
+		Pipelines:   pipelines,
+		Functions:   functions,
+		Agents:      agents,
+		Devices:     devices,
 	}
 
 	response := &ynpb.InspectResponse{
@@ -78,8 +101,11 @@ func (m *Inspect) dpModules(dpConfig *ffi.DPConfig) []*ynpb.DPModuleInfo {
 	return out
 }
 
-func (m *Inspect) cpConfigs(dpConfig *ffi.DPConfig) []*ynpb.CPConfigInfo {
-	configs := dpConfig.CPConfigs()
+func (m *Inspect) cpConfigs(dpConfig *ffi.DPConfig) ([]*ynpb.CPConfigInfo, error) {
+	configs, err := dpConfig.CPConfigs()
+	if err != nil {
+		return nil, err
+	}
 
 	out := make([]*ynpb.CPConfigInfo, len(configs))
 	for idx, config := range configs {
@@ -90,13 +116,16 @@ func (m *Inspect) cpConfigs(dpConfig *ffi.DPConfig) []*ynpb.CPConfigInfo {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
-func (m *Inspect) functions(dpConfig *ffi.DPConfig) []*ynpb.FunctionInfo {
-	functions := dpConfig.Functions()
+func (m *Inspect) functions(dpConfig *ffi.DPConfig) ([]*ynpb.FunctionInfo, error) {
+	functions, err := dpConfig.Functions()
+	if err != nil {
+		return nil, err
+	}
 	if len(functions) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	out := make([]*ynpb.FunctionInfo, len(functions))
@@ -124,11 +153,14 @@ func (m *Inspect) functions(dpConfig *ffi.DPConfig) []*ynpb.FunctionInfo {
 		out[idx] = functionInfo
 	}
 
-	return out
+	return out, nil
 }
 
-func (m *Inspect) pipelines(dpConfig *ffi.DPConfig) []*ynpb.PipelineInfo {
-	pipelines := dpConfig.Pipelines()
+func (m *Inspect) pipelines(dpConfig *ffi.DPConfig) ([]*ynpb.PipelineInfo, error) {
+	pipelines, err := dpConfig.Pipelines()
+	if err != nil {
+		return nil, err
+	}
 
 	out := make([]*ynpb.PipelineInfo, len(pipelines))
 	for idx, pipeline := range pipelines {
@@ -143,11 +175,14 @@ func (m *Inspect) pipelines(dpConfig *ffi.DPConfig) []*ynpb.PipelineInfo {
 		out[idx] = pipelineInfo
 	}
 
-	return out
+	return out, nil
 }
 
-func (m *Inspect) agents(dpConfig *ffi.DPConfig) []*ynpb.AgentInfo {
-	agents := dpConfig.Agents()
+func (m *Inspect) agents(dpConfig *ffi.DPConfig) ([]*ynpb.AgentInfo, error) {
+	agents, err := dpConfig.Agents()
+	if err != nil {
+		return nil, err
+	}
 
 	out := make([]*ynpb.AgentInfo, len(agents))
 	for idx, agent := range agents {
@@ -168,13 +203,16 @@ func (m *Inspect) agents(dpConfig *ffi.DPConfig) []*ynpb.AgentInfo {
 		out[idx] = agentInfo
 	}
 
-	return out
+	return out, nil
 }
 
-func (m *Inspect) devices(dpConfig *ffi.DPConfig) []*ynpb.DeviceInfo {
-	devices := dpConfig.Devices()
+func (m *Inspect) devices(dpConfig *ffi.DPConfig) ([]*ynpb.DeviceInfo, error) {
+	devices, err := dpConfig.Devices()
+	if err != nil {
+		return nil, err
+	}
 	if len(devices) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	out := make([]*ynpb.DeviceInfo, len(devices))
@@ -204,7 +242,7 @@ func (m *Inspect) devices(dpConfig *ffi.DPConfig) []*ynpb.DeviceInfo {
 		out[idx] = deviceInfo
 	}
 
-	return out
+	return out, nil
 }
 
 func (m *Inspect) numaIdx(dpConfig *ffi.DPConfig) uint32 {
@@ -0,0 +1,66 @@
+package builtin
+
+import (
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+)
+
+// MemoryMetrics collects agent memory arena statistics of a dataplane
+// instance.
+//
+// Each agent is reported once, summed over all its live instances:
+//   - yanet_agent_memory_limit_bytes: arena size
+//   - yanet_agent_memory_free_bytes:  unallocated arena bytes
+//   - yanet_agent_memory_used_bytes:  allocated arena bytes
+//   - yanet_agent_instances:          live agent instances
+//
+// Nothing is reported while the agents cannot be listed, e.g. for shared
+// memory attached in read-only mode.
+type MemoryMetrics struct {
+	// agents lists the agents of the dataplane instance.
+	agents func() ([]ffi.AgentInfo, error)
+}
+
+// NewMemoryMetrics creates a new MemoryMetrics collector.
+func NewMemoryMetrics(instanceID uint32, shm *ffi.SharedMemory) *MemoryMetrics {
+	return &MemoryMetrics{
+		agents: func() ([]ffi.AgentInfo, error) {
+			return shm.DPConfig(instanceID).Agents()
+		},
+	}
+}
+
+// Collect returns a snapshot of the agent memory arena metrics.
+func (m *MemoryMetrics) Collect() []*commonpb.Metric {
+	agents, err := m.agents()
+	if err != nil {
+		return nil
+	}
+
+	out := make([]*commonpb.Metric, 0, 4*len(agents))
+	for _, agent := range agents {
+		limit, free := uint64(0), uint64(0)
+		for _, instance := range agent.Instances {
+			limit += instance.MemoryLimit
+			free += instance.FreeBytes
+		}
+
+		label := &commonpb.Label{Name: "agent", Value: agent.Name}
+		out = append(out,
+			makeGauge("yanet_agent_memory_limit_bytes", float64(limit), label),
+			makeGauge("yanet_agent_memory_free_bytes", float64(free), label),
+			makeGauge("yanet_agent_memory_used_bytes", float64(limit-free), label),
+			makeGauge("yanet_agent_instances", float64(len(agent.Instances)), label),
+		)
+	}
+
+	return out
+}
+
+func makeGauge(name string, value float64, labels ...*commonpb.Label) *commonpb.Metric {
+	return &commonpb.Metric{
+		Name:   name,
+		Labels: labels,
+		Value:  &commonpb.Metric_Gauge{Gauge: value},
+	}
+}
@@ -0,0 +1,54 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	metrics := &MemoryMetrics{
+		agents: func() ([]ffi.AgentInfo, error) {
+			return []ffi.AgentInfo{
+				{
+					Name: "route",
+					Instances: []ffi.AgentInstanceInfo{
+						{MemoryLimit: 1000, FreeBytes: 400},
+						{MemoryLimit: 500, FreeBytes: 100},
+					},
+				},
+				{Name: "dscp"},
+			}, nil
+		},
+	}
+
+	values := map[string]float64{}
+	for _, metric := range metrics.Collect() {
+		require.Len(t, metric.GetLabels(), 1)
+		require.Equal(t, "agent", metric.GetLabels()[0].GetName())
+		values[metric.GetName()+"/"+metric.GetLabels()[0].GetValue()] = metric.GetGauge()
+	}
+	require.Equal(t, map[string]float64{
+		"yanet_agent_memory_limit_bytes/route": 1500,
+		"yanet_agent_memory_free_bytes/route":  500,
+		"yanet_agent_memory_used_bytes/route":  1000,
+		"yanet_agent_instances/route":          2,
+		"yanet_agent_memory_limit_bytes/dscp":  0,
+		"yanet_agent_memory_free_bytes/dscp":   0,
+		"yanet_agent_memory_used_bytes/dscp":   0,
+		"yanet_agent_instances/dscp":           0,
+	}, values)
+}
+
+// TestMemoryMetrics_ReadOnly verifies that nothing is reported while the
+// agents of a read-only shared memory cannot be listed.
+func TestMemoryMetrics_ReadOnly(t *testing.T) {
+	metrics := &MemoryMetrics{
+		agents: func() ([]ffi.AgentInfo, error) {
+			return nil, ffi.ErrReadOnly
+		},
+	}
+	require.Empty(t, metrics.Collect())
+}
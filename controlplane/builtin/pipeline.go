@@ -64,7 +64,10 @@ func (m *Pipeline) List(
 ) (*ynpb.ListPipelinesResponse, error) {
 	dpConfig := m.shm.DPConfig(m.instanceID)
 
-	pipelines := dpConfig.Pipelines()
+	pipelines, err := dpConfig.Pipelines()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list pipelines: %v", err)
+	}
 
 	response := &ynpb.ListPipelinesResponse{
 		Ids: make([]*commonpb.PipelineId, len(pipelines)),
@@ -87,7 +90,10 @@ func (m *Pipeline) Get(
 
 	reqId := request.Id
 
-	pipelines := dpConfig.Pipelines()
+	pipelines, err := dpConfig.Pipelines()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list pipelines: %v", err)
+	}
 	for _, pipeline := range pipelines {
 		if reqId.Name == pipeline.Name {
 			respFunctions := make([]*commonpb.FunctionId, len(pipeline.Functions))
@@ -2,6 +2,12 @@ package bundle
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yanet-platform/yanet2/common/go/xcfg"
 
 	acl "github.com/yanet-platform/yanet2/modules/acl/controlplane"
 	blackhole "github.com/yanet-platform/yanet2/modules/blackhole/controlplane"
@@ -78,6 +84,88 @@ func DefaultDevicesConfig() DevicesConfig {
 	}
 }
 
+// moduleConfigs decode and validate the configuration of a bundled module,
+// keyed by its section in ModulesConfig.
+var moduleConfigs = map[string]func(buf []byte) error{
+	"route":      decodeModuleConfig(route.DefaultConfig),
+	"route-mpls": decodeModuleConfig(route_mpls.DefaultConfig),
+	"decap":      decodeModuleConfig(decap.DefaultConfig),
+	"dscp":       decodeModuleConfig(dscp.DefaultConfig),
+	"forward":    decodeModuleConfig(forward.DefaultConfig),
+	"mirror":     decodeModuleConfig(mirror.DefaultConfig),
+	"nat64":      decodeModuleConfig(nat64.DefaultConfig),
+	"pdump":      decodeModuleConfig(pdump.DefaultConfig),
+	"acl":        decodeModuleConfig(acl.DefaultConfig),
+	"blackhole":  decodeModuleConfig(blackhole.DefaultConfig),
+}
+
+func decodeModuleConfig[T any](defaultConfig func() *T) func(buf []byte) error {
+	return func(buf []byte) error {
+		return xcfg.Decode(buf, defaultConfig())
+	}
+}
+
+// ModuleNames returns the sections of the bundled modules in
+// ModulesConfig, sorted.
+func ModuleNames() []string {
+	return slices.Sorted(maps.Keys(moduleConfigs))
+}
+
+// ValidateModuleConfig validates the YAML configuration of a single bundled
+// module, decoded over its defaults like its section of the controlplane
+// configuration.
+func ValidateModuleConfig(module string, buf []byte) error {
+	decode, ok := moduleConfigs[module]
+	if !ok {
+		return fmt.Errorf("unknown module %q, expected one of %v", module, ModuleNames())
+	}
+	return decode(buf)
+}
+
+// moduleRuleSets validate a rule set of a bundled module against its
+// configuration, keyed like moduleConfigs.
+var moduleRuleSets = map[string]func(buf []byte, rules []byte) error{
+	"route": validateRuleSet(route.DefaultConfig, route.ValidateRuleSet),
+	"dscp":  validateRuleSet(dscp.DefaultConfig, dscp.ValidateRuleSet),
+}
+
+func validateRuleSet[T any, R any](
+	defaultConfig func() *T,
+	validate func(cfg *T, ruleSet *R) error,
+) func(buf []byte, rules []byte) error {
+	return func(buf []byte, rules []byte) error {
+		cfg := defaultConfig()
+		if err := xcfg.Decode(buf, cfg); err != nil {
+			return err
+		}
+		ruleSet := new(R)
+		if err := yaml.Unmarshal(rules, ruleSet); err != nil {
+			return fmt.Errorf("failed to parse rule set: %w", err)
+		}
+		return validate(cfg, ruleSet)
+	}
+}
+
+// RuleSetModuleNames returns the sections of the bundled modules whose
+// rule sets ValidateModuleRules checks, sorted.
+func RuleSetModuleNames() []string {
+	return slices.Sorted(maps.Keys(moduleRuleSets))
+}
+
+// ValidateModuleRules validates the YAML rule set of a single bundled
+// module against its YAML configuration, without a dataplane to apply it
+// to.
+//
+// The rule set is compiled like the module compiles the calls pushing it,
+// so it is refused for the same invalid prefixes, rules or limits.
+func ValidateModuleRules(module string, buf []byte, rules []byte) error {
+	validate, ok := moduleRuleSets[module]
+	if !ok {
+		return fmt.Errorf("module %q has no rule sets, expected one of %v", module, RuleSetModuleNames())
+	}
+	return validate(buf, rules)
+}
+
 // Validate validates the modules config.
 func (m *ModulesConfig) Validate() error {
 	if m.Route == nil {
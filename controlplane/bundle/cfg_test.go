@@ -0,0 +1,171 @@
+package bundle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleNames(t *testing.T) {
+	require.Equal(t, []string{
+		"acl",
+		"blackhole",
+		"decap",
+		"dscp",
+		"forward",
+		"mirror",
+		"nat64",
+		"pdump",
+		"route",
+		"route-mpls",
+	}, ModuleNames())
+	require.Equal(t, []string{"dscp", "route"}, RuleSetModuleNames())
+}
+
+func TestValidateModuleConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+		config string
+		err    string
+	}{
+		{
+			name:   "defaults",
+			module: "route",
+		},
+		{
+			name:   "valid",
+			module: "dscp",
+			config: "memory_quota: 4MB\nreserved_marks:\n  policy: warn\n",
+		},
+		{
+			name:   "unknown module",
+			module: "balancer",
+			err:    `unknown module "balancer"`,
+		},
+		{
+			name:   "invalid field",
+			module: "dscp",
+			config: "reserved_marks:\n  policy: ignore\n",
+			err:    `unknown reserved mark policy "ignore"`,
+		},
+		{
+			name:   "malformed",
+			module: "route",
+			config: "memory_path: [",
+			err:    "yaml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateModuleConfig(test.module, []byte(test.config))
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestValidateModuleRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+		config string
+		rules  string
+		err    string
+	}{
+		{
+			name:   "valid dscp",
+			module: "dscp",
+			rules: `
+configs:
+  dscp0:
+    prefixes: [10.0.0.0/24]
+    marking: {flag: 2, mark: 46}
+    rules:
+      - name: voip
+        proto: 17
+        dst_ports: {from: 5060, to: 5061}
+        marking: {flag: 2, mark: 34}
+`,
+		},
+		{
+			name:   "valid route",
+			module: "route",
+			rules: `
+configs:
+  route0:
+    - prefix: 10.0.0.0/24
+      nexthops:
+        - {device: eth0, dst_mac: "52:54:00:00:00:01", src_mac: "52:54:00:00:00:02"}
+    - prefix: 192.0.2.0/24
+      blackhole: true
+`,
+		},
+		{
+			name:   "unknown module",
+			module: "forward",
+			err:    `module "forward" has no rule sets`,
+		},
+		{
+			name:   "invalid config",
+			module: "dscp",
+			config: "rule_table:\n  refuse_threshold: 2\n",
+			err:    "rule table refuse threshold 2 is out of [0, 1]",
+		},
+		{
+			name:   "invalid prefix",
+			module: "dscp",
+			rules:  "configs:\n  dscp0:\n    prefixes: [10.0.0.0/33]\n",
+			err:    `failed to parse prefix "10.0.0.0/33"`,
+		},
+		{
+			name:   "reserved codepoint",
+			module: "dscp",
+			rules:  "configs:\n  dscp0:\n    marking: {flag: 2, mark: 48}\n",
+			err:    "reserved codepoint 48",
+		},
+		{
+			name:   "reserved codepoint allowed by the config",
+			module: "dscp",
+			config: "reserved_marks:\n  policy: allow\n",
+			rules:  "configs:\n  dscp0:\n    marking: {flag: 2, mark: 48}\n",
+		},
+		{
+			name:   "prefix conflict",
+			module: "route",
+			config: "prefix_conflicts:\n  policy: error\n",
+			rules: `
+configs:
+  route0:
+    - prefix: 10.0.0.0/24
+      blackhole: true
+  route1:
+    - prefix: 10.0.0.0/24
+      nexthops:
+        - {device: eth0, dst_mac: "52:54:00:00:00:01", src_mac: "52:54:00:00:00:02"}
+`,
+			err: "FIBs conflict on 1 prefixes",
+		},
+		{
+			name:   "malformed rule set",
+			module: "route",
+			rules:  "configs: [",
+			err:    "failed to parse rule set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateModuleRules(test.module, []byte(test.config), []byte(test.rules))
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
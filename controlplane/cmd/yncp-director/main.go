@@ -13,6 +13,7 @@ import (
 	"github.com/yanet-platform/yanet2/common/go/logging"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
 	"github.com/yanet-platform/yanet2/common/go/xcmd"
+	"github.com/yanet-platform/yanet2/controlplane/bundle"
 	"github.com/yanet-platform/yanet2/controlplane/yncp"
 )
 
@@ -40,9 +41,63 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// ValidateCmd is the command line arguments of the validate command.
+type ValidateCmd struct {
+	// ConfigPath is the path to the configuration file.
+	ConfigPath string
+	// Module is the module whose configuration alone the file holds, the
+	// whole controlplane configuration if empty.
+	Module string
+	// RulesPath is the path to a rule set of the module, validated against
+	// its configuration. Empty validates the configuration alone.
+	RulesPath string
+}
+
+var validateCmd ValidateCmd
+
+var validateCommand = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a configuration file without running",
+	Long: "Validate the controlplane configuration, or with --module the configuration of a " +
+		"single module, without access to the dataplane, e.g. in the pre-merge checks of a " +
+		"config repository. With --rules the rule set of the module is validated against " +
+		"its configuration too.",
+	Args: cobra.NoArgs,
+	RunE: func(rawCmd *cobra.Command, args []string) error {
+		if err := validate(validateCmd); err != nil {
+			return err
+		}
+		fmt.Fprintf(rawCmd.OutOrStdout(), "%s: config is valid\n", validateCmd.ConfigPath)
+		if validateCmd.RulesPath != "" {
+			fmt.Fprintf(rawCmd.OutOrStdout(), "%s: rule set is valid\n", validateCmd.RulesPath)
+		}
+		return nil
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
 func init() {
 	rootCmd.Flags().StringVarP(&cmd.ConfigPath, "config", "c", "", "Path to the configuration file (required)")
 	rootCmd.MarkFlagRequired("config")
+
+	validateCommand.Flags().StringVarP(&validateCmd.ConfigPath, "config", "c", "", "Path to the configuration file (required)")
+	validateCommand.Flags().StringVarP(
+		&validateCmd.Module,
+		"module",
+		"m",
+		"",
+		fmt.Sprintf("Module whose configuration alone the file holds, one of %v", bundle.ModuleNames()),
+	)
+	validateCommand.Flags().StringVarP(
+		&validateCmd.RulesPath,
+		"rules",
+		"r",
+		"",
+		fmt.Sprintf("Path to a rule set of the module, of one of %v", bundle.RuleSetModuleNames()),
+	)
+	validateCommand.MarkFlagRequired("config")
+	rootCmd.AddCommand(validateCommand)
 }
 
 func main() {
@@ -52,6 +107,38 @@ func main() {
 	}
 }
 
+func validate(cmd ValidateCmd) error {
+	if cmd.Module == "" && cmd.RulesPath != "" {
+		return fmt.Errorf("--rules requires --module")
+	}
+	if cmd.Module == "" {
+		if _, err := xcfg.LoadConfig[yncp.Config](cmd.ConfigPath); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return nil
+	}
+
+	buf, err := os.ReadFile(cmd.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if cmd.RulesPath == "" {
+		if err := bundle.ValidateModuleConfig(cmd.Module, buf); err != nil {
+			return fmt.Errorf("invalid %s module config: %w", cmd.Module, err)
+		}
+		return nil
+	}
+
+	rules, err := os.ReadFile(cmd.RulesPath)
+	if err != nil {
+		return fmt.Errorf("failed to read rule set file: %w", err)
+	}
+	if err := bundle.ValidateModuleRules(cmd.Module, buf, rules); err != nil {
+		return fmt.Errorf("invalid %s module rule set: %w", cmd.Module, err)
+	}
+	return nil
+}
+
 func run(cmd Cmd) error {
 	cfg, err := xcfg.LoadConfig[yncp.Config](cmd.ConfigPath)
 	if err != nil {
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes the content to a file of the test directory and
+// returns its path, empty for empty content.
+func writeFile(t *testing.T, name string, content string) string {
+	if content == "" {
+		return ""
+	}
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		module string
+		rules  string
+		err    string
+	}{
+		{
+			name:   "controlplane config",
+			config: "memory_path: /dev/hugepages/yanet\n",
+		},
+		{
+			name:   "invalid controlplane config",
+			config: "modules:\n  dscp:\n    reserved_marks:\n      policy: ignore\n",
+			err:    `unknown reserved mark policy "ignore"`,
+		},
+		{
+			name:   "module config",
+			config: "memory_quota: 4MB\n",
+			module: "dscp",
+		},
+		{
+			name:   "unknown module",
+			config: "memory_quota: 4MB\n",
+			module: "balancer",
+			err:    `invalid balancer module config: unknown module "balancer"`,
+		},
+		{
+			name:   "invalid module config",
+			config: "prefix_conflicts:\n  policy: drop\n",
+			module: "route",
+			err:    `invalid route module config: prefix_conflicts: unknown prefix conflict policy "drop"`,
+		},
+		{
+			name:   "rule set",
+			config: "memory_quota: 4MB\n",
+			module: "dscp",
+			rules:  "configs:\n  dscp0:\n    prefixes: [10.0.0.0/24]\n    marking: {flag: 2, mark: 46}\n",
+		},
+		{
+			name:   "invalid rule set",
+			config: "memory_quota: 4MB\n",
+			module: "dscp",
+			rules:  "configs:\n  dscp0:\n    marking: {flag: 2, mark: 56}\n",
+			err:    "invalid dscp module rule set",
+		},
+		{
+			name:   "rule set of a module without rule sets",
+			config: "memory_quota: 4MB\n",
+			module: "decap",
+			rules:  "configs: {}\n",
+			err:    `module "decap" has no rule sets`,
+		},
+		{
+			name:   "rule set without module",
+			config: "memory_path: /dev/hugepages/yanet\n",
+			rules:  "configs: {}\n",
+			err:    "--rules requires --module",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validate(ValidateCmd{
+				ConfigPath: writeFile(t, "config.yaml", test.config),
+				Module:     test.module,
+				RulesPath:  writeFile(t, "rules.yaml", test.rules),
+			})
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	config := writeFile(t, "config.yaml", "memory_quota: 4MB\n")
+	rules := writeFile(t, "rules.yaml", "configs:\n  route0:\n    - prefix: 192.0.2.0/24\n      blackhole: true\n")
+
+	out := bytes.Buffer{}
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"validate", "--config", config, "--module", "route", "--rules", rules})
+	t.Cleanup(func() {
+		validateCmd = ValidateCmd{}
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+	})
+
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, config+": config is valid\n"+rules+": rule set is valid\n", out.String())
+}
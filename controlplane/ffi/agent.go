@@ -17,11 +17,14 @@ package ffi
 //#define _GNU_SOURCE
 //#include "api/agent.h"
 //#include "controlplane/agent/agent.h"
+//#include "controlplane/config/cp_module.h"
 import "C"
 import (
 	"fmt"
 	"unsafe"
 
+	"github.com/c2h5oh/datasize"
+
 	"github.com/yanet-platform/yanet2/bindings/go/cerrors"
 )
 
@@ -51,6 +54,18 @@ func (m ModuleConfig) AsRawPtr() unsafe.Pointer {
 	return unsafe.Pointer(m.ptr)
 }
 
+// ID returns the module configuration identifier as "type:name".
+func (m ModuleConfig) ID() string {
+	return C.GoString(&m.ptr._type[0]) + ":" + C.GoString(&m.ptr.name[0])
+}
+
+// MemoryUsage returns the number of shared memory bytes currently held by
+// the module configuration.
+func (m ModuleConfig) MemoryUsage() uint64 {
+	ctx := &m.ptr.memory_context
+	return uint64(ctx.balloc_size - ctx.bfree_size)
+}
+
 // ShmDeviceConfig is a Go wrapper around a C cp_device pointer, representing
 // a device's shared memory configuration.
 //
@@ -80,6 +95,9 @@ func (m ShmDeviceConfig) AsRawPtr() unsafe.Pointer {
 type Agent struct {
 	name string
 	ptr  *C.struct_agent
+	// memoryQuota is the per-module memory quota in bytes. Zero means
+	// unlimited.
+	memoryQuota datasize.ByteSize
 }
 
 // BlockAllocatorFreeSize returns the total free memory in the agent's block
@@ -112,6 +130,9 @@ func (m *Agent) UpdateModules(modules []ModuleConfig) error {
 		if module.ptr == nil {
 			return fmt.Errorf("module config at index %d is nil", i)
 		}
+		if err := m.checkMemoryQuota(module); err != nil {
+			return err
+		}
 		configs[i] = (*C.struct_cp_module)(module.AsRawPtr())
 	}
 
@@ -133,6 +154,34 @@ func (m *Agent) UpdateModules(modules []ModuleConfig) error {
 	return nil
 }
 
+// checkMemoryQuota rejects a module configuration holding more memory than
+// the agent's per-module quota.
+func (m *Agent) checkMemoryQuota(module ModuleConfig) error {
+	if m.memoryQuota == 0 {
+		return nil
+	}
+
+	usage := datasize.ByteSize(module.MemoryUsage())
+	if usage > m.memoryQuota {
+		return &QuotaExceededError{
+			Module:    module.ID(),
+			Requested: usage,
+			Quota:     m.memoryQuota,
+		}
+	}
+
+	return nil
+}
+
+// Health reports whether the agent can serve, that is whether the dataplane
+// instance it is attached to is ready.
+func (m *Agent) Health() error {
+	if C.agent_dp_ready(m.ptr) == 0 {
+		return fmt.Errorf("dataplane instance of agent %q is not ready", m.name)
+	}
+	return nil
+}
+
 func (m *Agent) DPConfig() *DPConfig {
 	return &DPConfig{
 		ptr: C.agent_dp_config(m.ptr),
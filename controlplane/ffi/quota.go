@@ -0,0 +1,49 @@
+package ffi
+
+import (
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// QuotaExceededError reports a module configuration holding more shared
+// memory than its agent's per-module quota allows.
+//
+// It carries the ResourceExhausted gRPC code, so services returning it,
+// even wrapped, reply with that code.
+type QuotaExceededError struct {
+	// Module identifies the module configuration as "type:name".
+	Module string
+	// Requested is the amount of memory the configuration holds.
+	Requested datasize.ByteSize
+	// Quota is the per-module memory quota.
+	Quota datasize.ByteSize
+}
+
+// Error implements the error interface.
+func (m *QuotaExceededError) Error() string {
+	return fmt.Sprintf(
+		"module %q requested %s of memory, exceeding its quota of %s",
+		m.Module,
+		m.Requested.HR(),
+		m.Quota.HR(),
+	)
+}
+
+// GRPCStatus returns the ResourceExhausted status for the error.
+func (m *QuotaExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, m.Error())
+}
+
+// UpdateStatus wraps a failed module config update into a gRPC error,
+// keeping the code the failure carries, such as ResourceExhausted for a
+// QuotaExceededError, and Internal otherwise.
+func UpdateStatus(err error, format string, args ...any) error {
+	code := codes.Internal
+	if c := status.Code(err); c != codes.Unknown {
+		code = c
+	}
+	return status.Errorf(code, "%s: %v", fmt.Sprintf(format, args...), err)
+}
@@ -11,6 +11,7 @@ package ffi
 import "C"
 
 import (
+	"errors"
 	"fmt"
 	"iter"
 	"unsafe"
@@ -20,9 +21,14 @@ import (
 	"github.com/yanet-platform/yanet2/bindings/go/cerrors"
 )
 
+// ErrReadOnly is returned by the operations that write to shared memory
+// attached in read-only mode.
+var ErrReadOnly = errors.New("shared memory is attached in read-only mode")
+
 // SharedMemory represents a handle to YANET shared memory segment.
 type SharedMemory struct {
-	ptr *C.struct_yanet_shm
+	ptr      *C.struct_yanet_shm
+	readOnly bool
 }
 
 func NewSharedMemoryFromRaw(ptr unsafe.Pointer) *SharedMemory {
@@ -46,6 +52,35 @@ func AttachSharedMemory(path string) (*SharedMemory, error) {
 	return &SharedMemory{ptr: ptr}, nil
 }
 
+// AttachSharedMemoryReadOnly attaches to YANET shared memory segment
+// without write permission.
+//
+// The handle is meant for inspection tools: it never registers an agent
+// and cannot modify running configurations. Agents cannot be attached
+// through it, and accessors that must take the controlplane configuration
+// lock fail with ErrReadOnly, see DPConfig.ReadOnly.
+func AttachSharedMemoryReadOnly(path string) (*SharedMemory, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	ptr, err := C.yanet_shm_attach_ro(cPath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to attach to shared memory %q in read-only mode: %w",
+			path,
+			err,
+		)
+	}
+
+	return &SharedMemory{ptr: ptr, readOnly: true}, nil
+}
+
+// ReadOnly reports whether the shared memory is mapped without write
+// permission.
+func (m *SharedMemory) ReadOnly() bool {
+	return m.readOnly
+}
+
 // Detach detaches from YANET shared memory segment.
 func (m *SharedMemory) Detach() error {
 	if m.ptr != nil {
@@ -67,7 +102,7 @@ func (m *SharedMemory) AsRawPtr() unsafe.Pointer {
 func (m *SharedMemory) DPConfig(instanceIdx uint32) *DPConfig {
 	ptr := C.yanet_shm_dp_config(m.ptr, C.uint32_t(instanceIdx))
 
-	return &DPConfig{ptr: ptr}
+	return &DPConfig{ptr: ptr, readOnly: m.readOnly}
 }
 
 // DataplaneReady reports whether the dataplane instance has finished
@@ -76,12 +111,55 @@ func (m *SharedMemory) DataplaneReady(instanceIdx uint32) bool {
 	return C.agent_dp_config_ready(m.ptr, C.uint32_t(instanceIdx)) != 0
 }
 
+type agentOptions struct {
+	MemoryQuota datasize.ByteSize
+}
+
+func newAgentOptions() *agentOptions {
+	return &agentOptions{}
+}
+
+// AgentOption configures AgentAttach and AgentsAttach.
+type AgentOption func(*agentOptions)
+
+// WithModuleMemoryQuota limits the shared memory a single module
+// configuration of the agent may hold.
+//
+// Configuration updates exceeding the quota are rejected with a
+// QuotaExceededError. Zero, the default, disables the quota.
+func WithModuleMemoryQuota(quota datasize.ByteSize) AgentOption {
+	return func(o *agentOptions) {
+		o.MemoryQuota = quota
+	}
+}
+
 // AgentAttach attaches a module agent to shared memory on the dataplane instance.
+//
+// The module memory quota, if any, must fit into the agent memory size.
 func (m *SharedMemory) AgentAttach(
 	name string,
 	instanceIdx uint32,
 	size datasize.ByteSize,
+	options ...AgentOption,
 ) (*Agent, error) {
+	if m.readOnly {
+		return nil, fmt.Errorf("failed to attach agent %q: %w", name, ErrReadOnly)
+	}
+
+	opts := newAgentOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	if opts.MemoryQuota > size {
+		return nil, fmt.Errorf(
+			"memory quota %s of agent %q exceeds its memory size %s",
+			opts.MemoryQuota.HR(),
+			name,
+			size.HR(),
+		)
+	}
+
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
 
@@ -91,18 +169,25 @@ func (m *SharedMemory) AgentAttach(
 		return nil, fmt.Errorf("failed to attach agent %q: %w", name, cerrors.FromC(unsafe.Pointer(cErr)))
 	}
 
-	return &Agent{name: name, ptr: ptr}, nil
+	return &Agent{
+		name:        name,
+		ptr:         ptr,
+		memoryQuota: opts.MemoryQuota,
+	}, nil
 }
 
 // AgentsAttach attaches agents to shared memory on the specified list of instances.
+//
+// Every agent enforces the same module memory quota.
 func (m *SharedMemory) AgentsAttach(
 	name string,
 	instanceIndices []uint32,
 	size datasize.ByteSize,
+	options ...AgentOption,
 ) ([]*Agent, error) {
 	agents := make([]*Agent, 0, len(instanceIndices))
 	for _, instanceIdx := range instanceIndices {
-		agent, err := m.AgentAttach(name, instanceIdx, size)
+		agent, err := m.AgentAttach(name, instanceIdx, size, options...)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"failed to connect to shared memory on instance %d: %w",
@@ -118,13 +203,26 @@ func (m *SharedMemory) AgentsAttach(
 
 // DPConfig represents a handle to dataplane configuration.
 type DPConfig struct {
-	ptr *C.struct_dp_config
+	ptr      *C.struct_dp_config
+	readOnly bool
 }
 
 func NewDPConfigFromRaw(ptr unsafe.Pointer) *DPConfig {
 	return &DPConfig{ptr: (*C.struct_dp_config)(ptr)}
 }
 
+// ReadOnly reports whether the configuration is mapped without write
+// permission.
+//
+// Acquiring the controlplane configuration lock writes to shared memory,
+// so CPConfigs, Functions, Pipelines, Agents, Devices, AllModulePositions
+// and CountersByTags fail with ErrReadOnly for read-only configurations.
+// Dataplane modules and counters of known entities are read lock-free and
+// remain available.
+func (m *DPConfig) ReadOnly() bool {
+	return m.readOnly
+}
+
 func (m *DPConfig) NumaIdx() uint32 {
 	return uint32(C.dataplane_instance_numa_idx(m.ptr))
 }
@@ -155,7 +253,12 @@ func (m *DPConfig) Modules() []DPModule {
 	return out
 }
 
-func (m *DPConfig) CPConfigs() []CPConfig {
+// CPConfigs returns all module configurations from the dataplane.
+func (m *DPConfig) CPConfigs() ([]CPConfig, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	cpModulesListInfo := C.yanet_get_cp_module_list_info(m.ptr)
 	defer C.cp_module_list_info_free(cpModulesListInfo)
 
@@ -171,7 +274,7 @@ func (m *DPConfig) CPConfigs() []CPConfig {
 		})
 	}
 
-	return out
+	return out, nil
 }
 
 type ChainModule struct {
@@ -191,7 +294,11 @@ type Function struct {
 }
 
 // Functions returns all functions configurations from the dataplane.
-func (m *DPConfig) Functions() []Function {
+func (m *DPConfig) Functions() ([]Function, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	functionListInfo := C.yanet_get_cp_function_list_info(m.ptr)
 	defer C.cp_function_list_info_free(functionListInfo)
 
@@ -228,11 +335,15 @@ func (m *DPConfig) Functions() []Function {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
 // Pipelines returns all pipeline configurations from the dataplane.
-func (m *DPConfig) Pipelines() []Pipeline {
+func (m *DPConfig) Pipelines() ([]Pipeline, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	pipelineListInfo := C.yanet_get_cp_pipeline_list_info(m.ptr)
 	defer C.cp_pipeline_list_info_free(pipelineListInfo)
 
@@ -255,11 +366,15 @@ func (m *DPConfig) Pipelines() []Pipeline {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
 // Agents returns all agent information from the dataplane.
-func (m *DPConfig) Agents() []AgentInfo {
+func (m *DPConfig) Agents() ([]AgentInfo, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	agentListInfo := C.yanet_get_cp_agent_list_info(m.ptr)
 	defer C.cp_agent_list_info_free(agentListInfo)
 
@@ -297,7 +412,7 @@ func (m *DPConfig) Agents() []AgentInfo {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
 // DPModule represents a dataplane module in the YANET configuration.
@@ -364,10 +479,14 @@ type DeviceInfo struct {
 }
 
 // Devices returns all device information from the dataplane.
-func (m *DPConfig) Devices() []DeviceInfo {
+func (m *DPConfig) Devices() ([]DeviceInfo, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	deviceListInfo := C.yanet_get_cp_device_list_info(m.ptr)
 	if deviceListInfo == nil {
-		return nil
+		return nil, nil
 	}
 	defer C.cp_device_list_info_free(deviceListInfo)
 
@@ -419,7 +538,7 @@ func (m *DPConfig) Devices() []DeviceInfo {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
 type CounterInfo struct {
@@ -641,6 +760,10 @@ func (m *DPConfig) CountersByTags(
 	tags []CounterTag,
 	query []string,
 ) ([]CounterGroup, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	cTags := make([]C.struct_counter_tag, len(tags))
 	for idx, tag := range tags {
 		cKey := C.CString(tag.Key)
@@ -856,16 +979,27 @@ type ModuleReference struct {
 	ModuleName string
 }
 
-func (m *DPConfig) AllModulePositions(moduleType string) iter.Seq[ModuleReference] {
-	deviceList := m.Devices()
+// AllModulePositions returns the positions of the modules of the given type
+// in the device pipelines.
+func (m *DPConfig) AllModulePositions(moduleType string) (iter.Seq[ModuleReference], error) {
+	deviceList, err := m.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
 
-	pipelineList := m.Pipelines()
+	pipelineList, err := m.Pipelines()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pipelines: %w", err)
+	}
 	pipelineFunctions := make(map[string][]string)
 	for _, pipeline := range pipelineList {
 		pipelineFunctions[pipeline.Name] = pipeline.Functions
 	}
 
-	functionList := m.Functions()
+	functionList, err := m.Functions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list functions: %w", err)
+	}
 	functions := make(map[string][]Chain)
 	for _, function := range functionList {
 		functions[function.Name] = function.Chains
@@ -900,5 +1034,5 @@ func (m *DPConfig) AllModulePositions(moduleType string) iter.Seq[ModuleReferenc
 				}
 			}
 		}
-	}
+	}, nil
 }
@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/internal/auth/core"
+	"github.com/yanet-platform/yanet2/controlplane/internal/xgrpc"
+	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+)
+
+// applyOp is a config-mutating call tracked by the apply queue.
+type applyOp struct {
+	module     string
+	service    string
+	method     string
+	user       string
+	enqueuedAt time.Time
+	startedAt  time.Time
+	// admitted is closed once the call may run.
+	admitted chan struct{}
+}
+
+// ApplyQueue limits concurrent config-mutating calls to module services.
+//
+// Calls are admitted in FIFO order as long as both the per-module and the
+// global limits allow. A queued call whose module is saturated does not
+// block calls to other modules behind it.
+//
+// Only unary calls are queued: a stream would hold its slot for as long as
+// it stays open, so streaming methods bypass the queue.
+type ApplyQueue struct {
+	ynpb.UnimplementedApplyQueueServiceServer
+
+	cfg ApplyConfig
+	// isModule reports whether a gRPC service is a module service whose
+	// calls are subject to the queue.
+	isModule func(service string) bool
+	// isStreaming reports whether a full gRPC method streams requests or
+	// responses.
+	isStreaming func(fullMethod string) bool
+
+	mu      sync.Mutex
+	running []*applyOp
+	queued  []*applyOp
+	now     func() time.Time
+
+	log *zap.Logger
+}
+
+// NewApplyQueue creates an empty ApplyQueue.
+func NewApplyQueue(cfg ApplyConfig, isModule func(service string) bool, log *zap.Logger) *ApplyQueue {
+	return &ApplyQueue{
+		cfg:         cfg,
+		isModule:    isModule,
+		isStreaming: isStreamingMethod,
+		now:         time.Now,
+		log:         log,
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that queues config-mutating
+// calls to in-process module services.
+func (m *ApplyQueue) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		release, err := m.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that queues
+// config-mutating calls proxied to external module services.
+//
+// The proxy handles every call as a stream, so the methods are told apart
+// by their proto descriptors: streaming methods run immediately, unary
+// ones and the methods of services unknown to the gateway are queued.
+func (m *ApplyQueue) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if m.isStreaming(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		release, err := m.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
+
+// acquire waits until the call may run and returns the function that
+// releases its slot.
+//
+// Calls that are not config-mutating calls to module services run
+// immediately.
+func (m *ApplyQueue) acquire(ctx context.Context, fullMethod string) (func(), error) {
+	service, method, err := xgrpc.ParseFullMethod(fullMethod)
+	if err != nil || !m.isApply(service, method) {
+		return func() {}, nil
+	}
+
+	user := ""
+	if principal := core.FromContext(ctx); principal != nil {
+		user = principal.User
+	}
+
+	op := &applyOp{
+		module:   moduleOfService(service),
+		service:  service,
+		method:   method,
+		user:     user,
+		admitted: make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if m.cfg.MaxQueued > 0 && len(m.queued) >= int(m.cfg.MaxQueued) {
+		m.mu.Unlock()
+		return nil, status.Errorf(
+			codes.ResourceExhausted,
+			"apply queue is full: %d calls are waiting",
+			m.cfg.MaxQueued,
+		)
+	}
+	op.enqueuedAt = m.now()
+	m.queued = append(m.queued, op)
+	m.admitLocked()
+	position := slices.Index(m.queued, op) + 1
+	m.mu.Unlock()
+
+	if position > 0 {
+		m.log.Info("queued config apply",
+			zap.String("service", service),
+			zap.String("method", method),
+			zap.String("user", user),
+			zap.Int("position", position),
+		)
+	}
+
+	select {
+	case <-op.admitted:
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		// The call may have been admitted while the context was being
+		// canceled, in which case its slot must be handed over.
+		select {
+		case <-op.admitted:
+			m.releaseLocked(op)
+		default:
+			m.queued = slices.DeleteFunc(m.queued, func(v *applyOp) bool { return v == op })
+		}
+
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.releaseLocked(op)
+	}, nil
+}
+
+// isApply reports whether the method is a config-mutating call to a module
+// service.
+func (m *ApplyQueue) isApply(service string, method string) bool {
+	if m.cfg.ModuleConcurrency == 0 && m.cfg.GlobalConcurrency == 0 {
+		return false
+	}
+
+	matches := slices.ContainsFunc(m.cfg.MethodPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(method, prefix)
+	})
+
+	return matches && m.isModule(service)
+}
+
+func (m *ApplyQueue) releaseLocked(op *applyOp) {
+	m.running = slices.DeleteFunc(m.running, func(v *applyOp) bool { return v == op })
+	m.admitLocked()
+}
+
+// admitLocked starts queued calls in FIFO order while the limits allow.
+func (m *ApplyQueue) admitLocked() {
+	modules := map[string]uint32{}
+	for _, op := range m.running {
+		modules[op.module]++
+	}
+
+	now := m.now()
+	m.queued = slices.DeleteFunc(m.queued, func(op *applyOp) bool {
+		if m.cfg.GlobalConcurrency > 0 && len(m.running) >= int(m.cfg.GlobalConcurrency) {
+			return false
+		}
+		if m.cfg.ModuleConcurrency > 0 && modules[op.module] >= m.cfg.ModuleConcurrency {
+			return false
+		}
+
+		op.startedAt = now
+		modules[op.module]++
+		m.running = append(m.running, op)
+		close(op.admitted)
+
+		return true
+	})
+}
+
+// ListApplyQueue returns the running and queued config-mutating calls.
+func (m *ApplyQueue) ListApplyQueue(
+	ctx context.Context,
+	req *ynpb.ListApplyQueueRequest,
+) (*ynpb.ListApplyQueueResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	response := &ynpb.ListApplyQueueResponse{
+		Running: make([]*ynpb.ApplyOperation, 0, len(m.running)),
+		Queued:  make([]*ynpb.ApplyOperation, 0, len(m.queued)),
+		Limits: &ynpb.ApplyQueueLimits{
+			ModuleConcurrency: m.cfg.ModuleConcurrency,
+			GlobalConcurrency: m.cfg.GlobalConcurrency,
+			MaxQueued:         m.cfg.MaxQueued,
+		},
+	}
+	for _, op := range m.running {
+		response.Running = append(response.Running, applyOpToProto(op, 0))
+	}
+	for idx, op := range m.queued {
+		response.Queued = append(response.Queued, applyOpToProto(op, uint32(idx+1)))
+	}
+
+	return response, nil
+}
+
+// Collect returns the number of running and queued calls per module.
+//
+// Labels:
+//   - module: proto package of the module service
+func (m *ApplyQueue) Collect() []*commonpb.Metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type counts struct {
+		running int
+		queued  int
+	}
+	perModule := map[string]*counts{}
+	get := func(module string) *counts {
+		if _, ok := perModule[module]; !ok {
+			perModule[module] = &counts{}
+		}
+		return perModule[module]
+	}
+	for _, op := range m.running {
+		get(op.module).running++
+	}
+	for _, op := range m.queued {
+		get(op.module).queued++
+	}
+
+	modules := make([]string, 0, len(perModule))
+	for module := range perModule {
+		modules = append(modules, module)
+	}
+	slices.Sort(modules)
+
+	result := make([]*commonpb.Metric, 0, 2*len(modules))
+	for _, module := range modules {
+		labels := []*commonpb.Label{{Name: "module", Value: module}}
+		result = append(result,
+			&commonpb.Metric{
+				Name:   "gateway_apply_running",
+				Labels: labels,
+				Value:  &commonpb.Metric_Gauge{Gauge: float64(perModule[module].running)},
+			},
+			&commonpb.Metric{
+				Name:   "gateway_apply_queued",
+				Labels: labels,
+				Value:  &commonpb.Metric_Gauge{Gauge: float64(perModule[module].queued)},
+			},
+		)
+	}
+
+	return result
+}
+
+// isStreamingMethod reports whether a full gRPC method streams requests or
+// responses according to the proto descriptors linked into the binary.
+//
+// Methods of unknown services are reported as unary.
+func isStreamingMethod(fullMethod string) bool {
+	service, method, err := xgrpc.ParseFullMethod(fullMethod)
+	if err != nil {
+		return false
+	}
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return false
+	}
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return false
+	}
+
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method))
+	return methodDesc != nil && (methodDesc.IsStreamingClient() || methodDesc.IsStreamingServer())
+}
+
+// moduleOfService returns the proto package of a full gRPC service name.
+func moduleOfService(service string) string {
+	if pos := strings.LastIndex(service, "."); pos >= 0 {
+		return service[:pos]
+	}
+
+	return service
+}
+
+func applyOpToProto(op *applyOp, position uint32) *ynpb.ApplyOperation {
+	result := &ynpb.ApplyOperation{
+		Module:     op.module,
+		Service:    op.service,
+		Method:     op.method,
+		User:       op.user,
+		Position:   position,
+		EnqueuedAt: timestamppb.New(op.enqueuedAt),
+	}
+	if !op.startedAt.IsZero() {
+		result.StartedAt = timestamppb.New(op.startedAt)
+	}
+
+	return result
+}
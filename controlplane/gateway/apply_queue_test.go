@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+)
+
+func newTestApplyQueue(t *testing.T, cfg ApplyConfig) *ApplyQueue {
+	t.Helper()
+
+	cfg.MethodPrefixes = []string{"Update", "Delete"}
+	return NewApplyQueue(cfg, func(service string) bool {
+		return service != "controlplane.ynpb.v1.LockService"
+	}, zap.NewNop())
+}
+
+func listApplyQueue(t *testing.T, queue *ApplyQueue) *ynpb.ListApplyQueueResponse {
+	t.Helper()
+
+	response, err := queue.ListApplyQueue(t.Context(), &ynpb.ListApplyQueueRequest{})
+	require.NoError(t, err)
+	return response
+}
+
+// acquireAsync starts acquiring a slot and waits until the call is either
+// running or queued.
+func acquireAsync(t *testing.T, ctx context.Context, queue *ApplyQueue, method string) <-chan func() {
+	t.Helper()
+
+	before := listApplyQueue(t, queue)
+	total := len(before.GetRunning()) + len(before.GetQueued())
+
+	result := make(chan func(), 1)
+	go func() {
+		release, err := queue.acquire(ctx, method)
+		if err != nil {
+			close(result)
+			return
+		}
+		result <- release
+	}()
+
+	require.Eventually(t, func() bool {
+		after := listApplyQueue(t, queue)
+		return len(after.GetRunning())+len(after.GetQueued()) > total
+	}, 2*time.Second, 10*time.Millisecond)
+
+	return result
+}
+
+func TestApplyQueue_Limits(t *testing.T) {
+	queue := newTestApplyQueue(t, ApplyConfig{ModuleConcurrency: 1, GlobalConcurrency: 2})
+
+	route0 := <-acquireAsync(t, t.Context(), queue, "/modules.route.controlplane.routepb.v1.RouteService/UpdateConfig")
+	route1 := acquireAsync(t, t.Context(), queue, "/modules.route.controlplane.routepb.v1.RouteService/DeleteConfig")
+	acl0 := <-acquireAsync(t, t.Context(), queue, "/modules.acl.controlplane.aclpb.v1.AclService/UpdateConfig")
+	dscp0 := acquireAsync(t, t.Context(), queue, "/modules.dscp.controlplane.dscppb.v1.DscpService/UpdateConfig")
+
+	// Read-only calls and framework services bypass the queue.
+	release, err := queue.acquire(t.Context(), "/modules.route.controlplane.routepb.v1.RouteService/ShowConfig")
+	require.NoError(t, err)
+	release()
+	release, err = queue.acquire(t.Context(), "/controlplane.ynpb.v1.LockService/DeleteLock")
+	require.NoError(t, err)
+	release()
+
+	response := listApplyQueue(t, queue)
+	require.Len(t, response.GetRunning(), 2)
+	require.Len(t, response.GetQueued(), 2)
+	require.Equal(t, "DeleteConfig", response.GetQueued()[0].GetMethod())
+	require.Equal(t, uint32(1), response.GetQueued()[0].GetPosition())
+	require.Equal(t, "modules.dscp.controlplane.dscppb.v1", response.GetQueued()[1].GetModule())
+	require.Equal(t, uint32(2), response.GetQueued()[1].GetPosition())
+	require.Nil(t, response.GetQueued()[1].GetStartedAt())
+
+	// Releasing the ACL call frees a global slot, but the head of the
+	// queue still waits for its module, so the DSCP call overtakes it.
+	acl0()
+	dscp := <-dscp0
+	require.NotNil(t, dscp)
+	defer dscp()
+
+	response = listApplyQueue(t, queue)
+	require.Len(t, response.GetQueued(), 1)
+	require.Equal(t, uint32(1), response.GetQueued()[0].GetPosition())
+
+	route0()
+	route := <-route1
+	require.NotNil(t, route)
+	route()
+}
+
+func TestApplyQueue_QueueFull(t *testing.T) {
+	queue := newTestApplyQueue(t, ApplyConfig{GlobalConcurrency: 1, MaxQueued: 1})
+	method := "/modules.route.controlplane.routepb.v1.RouteService/UpdateConfig"
+
+	release := <-acquireAsync(t, t.Context(), queue, method)
+	acquireAsync(t, t.Context(), queue, method)
+
+	_, err := queue.acquire(t.Context(), method)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	release()
+}
+
+func TestApplyQueue_Cancel(t *testing.T) {
+	queue := newTestApplyQueue(t, ApplyConfig{ModuleConcurrency: 1})
+	method := "/modules.route.controlplane.routepb.v1.RouteService/UpdateConfig"
+
+	release := <-acquireAsync(t, t.Context(), queue, method)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	canceled := acquireAsync(t, ctx, queue, method)
+	cancel()
+	_, ok := <-canceled
+	require.False(t, ok)
+	require.Empty(t, listApplyQueue(t, queue).GetQueued())
+
+	release()
+	require.Empty(t, listApplyQueue(t, queue).GetRunning())
+}
+
+func TestApplyQueue_DisabledByDefault(t *testing.T) {
+	queue := NewApplyQueue(DefaultConfig().Apply, func(service string) bool { return true }, zap.NewNop())
+	method := "/modules.route.controlplane.routepb.v1.RouteService/UpdateConfig"
+
+	release, err := queue.acquire(t.Context(), method)
+	require.NoError(t, err)
+	defer release()
+	require.Empty(t, listApplyQueue(t, queue).GetRunning())
+}
+
+// applyTestStream is a server stream of the given context.
+type applyTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m *applyTestStream) Context() context.Context {
+	return m.ctx
+}
+
+func TestApplyQueue_StreamingMethods(t *testing.T) {
+	queue := newTestApplyQueue(t, ApplyConfig{ModuleConcurrency: 1})
+	queue.isStreaming = func(fullMethod string) bool {
+		return fullMethod == "/modules.route.controlplane.routepb.v1.RouteService/UpdateStream"
+	}
+	interceptor := queue.StreamServerInterceptor()
+	call := func(ctx context.Context, method string) error {
+		info := &grpc.StreamServerInfo{FullMethod: method, IsClientStream: true, IsServerStream: true}
+		return interceptor(nil, &applyTestStream{ctx: ctx}, info, func(any, grpc.ServerStream) error {
+			return nil
+		})
+	}
+
+	release := <-acquireAsync(t, t.Context(), queue, "/modules.route.controlplane.routepb.v1.RouteService/UpdateConfig")
+	defer release()
+
+	// A streaming method runs although its module is saturated.
+	require.NoError(t, call(t.Context(), "/modules.route.controlplane.routepb.v1.RouteService/UpdateStream"))
+
+	// A proxied unary call waits for its slot.
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err := call(ctx, "/modules.route.controlplane.routepb.v1.RouteService/DeleteConfig")
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestIsStreamingMethod(t *testing.T) {
+	require.True(t, isStreamingMethod("/grpc.health.v1.Health/Watch"))
+	require.False(t, isStreamingMethod("/grpc.health.v1.Health/Check"))
+	require.False(t, isStreamingMethod("/grpc.health.v1.Health/Missing"))
+	require.False(t, isStreamingMethod("/unknown.v1.Service/Update"))
+	require.False(t, isStreamingMethod("malformed"))
+}
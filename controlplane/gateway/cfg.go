@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
 	"github.com/yanet-platform/yanet2/controlplane/internal/auth"
 )
 
@@ -10,8 +11,14 @@ type Config struct {
 	InstanceID uint32 `yaml:"instance_id"`
 	// Server is the configuration for the gateway server.
 	Server ServerConfig `yaml:"server"`
+	// BackendTLS configures TLS of the connections to the external
+	// backends, the operators and module controlplanes registered from
+	// other processes. When nil, they are dialed in plaintext.
+	BackendTLS *grpctls.ClientConfig `yaml:"backend_tls,omitempty"`
 	// Auth is the configuration for authentication and authorization.
 	Auth auth.Config `yaml:"auth"`
+	// Apply limits concurrent config-mutating calls to module services.
+	Apply ApplyConfig `yaml:"apply"`
 }
 
 // ServerConfig is the configuration for the gateway server.
@@ -26,6 +33,26 @@ type ServerConfig struct {
 	TLS *TLSConfig `yaml:"tls,omitempty"`
 }
 
+// ApplyConfig is the configuration of the apply queue, which limits
+// concurrent config-mutating calls to module services.
+//
+// Calls above the concurrency limits wait in a FIFO queue. Zero limits mean
+// unlimited, and the queue is disabled while both are zero, which is the
+// default. Streaming methods are never queued.
+type ApplyConfig struct {
+	// ModuleConcurrency is the maximum number of concurrent calls per
+	// module.
+	ModuleConcurrency uint32 `yaml:"module_concurrency"`
+	// GlobalConcurrency is the maximum number of concurrent calls across
+	// all modules of the dataplane instance.
+	GlobalConcurrency uint32 `yaml:"global_concurrency"`
+	// MaxQueued is the maximum number of waiting calls. Calls above it
+	// fail with RESOURCE_EXHAUSTED.
+	MaxQueued uint32 `yaml:"max_queued"`
+	// MethodPrefixes select config-mutating methods by name prefix.
+	MethodPrefixes []string `yaml:"method_prefixes"`
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
@@ -33,5 +60,12 @@ func DefaultConfig() *Config {
 			Endpoint: "[::1]:8080",
 		},
 		Auth: auth.DefaultConfig(),
+		Apply: ApplyConfig{
+			MaxQueued: 256,
+			MethodPrefixes: []string{
+				"Update", "Set", "Delete", "Remove", "Add",
+				"Create", "Insert", "Flush", "Link",
+			},
+		},
 	}
 }
@@ -15,6 +15,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	// Registers zstd, so that zstd-compressed streams pass the proxy.
+	_ "github.com/yanet-platform/yanet2/common/go/grpccompress"
 	"github.com/yanet-platform/yanet2/common/go/grpcmetrics"
 	"github.com/yanet-platform/yanet2/common/go/metrics"
 	"github.com/yanet-platform/yanet2/common/go/readiness"
@@ -52,6 +54,16 @@ type ClosableService interface {
 	Close() error
 }
 
+// HealthCheckedService is an optional interface for out-of-process services
+// that report their health, such as their shared memory attachment, on the
+// grpc.health.v1.Health service of their gRPC server.
+//
+// Services not implementing it are reported serving while they run.
+type HealthCheckedService interface {
+	// Health returns nil if the service can serve its requests.
+	Health() error
+}
+
 // serviceEntry pairs a service with its declared backend kind.
 type serviceEntry struct {
 	service Service
@@ -75,6 +87,7 @@ type gatewayOptions struct {
 	Log            *zap.Logger
 	LogLevel       *zap.AtomicLevel
 	MetricsFactory MetricsFactory
+	Collectors     []MetricsCollector
 }
 
 func newGatewayOptions() *gatewayOptions {
@@ -136,6 +149,14 @@ func WithGRPCMetricsFactory(factory MetricsFactory) GatewayOption {
 	}
 }
 
+// WithMetricsCollector adds a collector whose metrics the gateway serves
+// alongside its own gRPC server metrics.
+func WithMetricsCollector(collector MetricsCollector) GatewayOption {
+	return func(o *gatewayOptions) {
+		o.Collectors = append(o.Collectors, collector)
+	}
+}
+
 // Gateway is the Gateway API to YANET modules.
 //
 // It is a gRPC server that acts as a proxy for each YANET module's
@@ -257,15 +278,24 @@ func NewGateway(cfg *Config, options ...GatewayOption) (*Gateway, error) {
 
 	serverMetrics := opts.MetricsFactory(retention, serviceFilter)
 
+	// Gateway-hosted framework services are never subject to the apply
+	// queue, only in-process and external module services are.
+	applyQueue := NewApplyQueue(cfg.Apply, func(service string) bool {
+		kind, ok := registry.GetKind(service)
+		return ok && kind != BackendKindBuiltin
+	}, log)
+
 	serverOpts := []grpc.ServerOption{
 		grpc.ChainUnaryInterceptor(
 			serverMetrics.UnaryServerInterceptor(),
 			auth.UnaryServerInterceptor(authManager, log),
 			xgrpc.AccessLogInterceptor(log),
+			applyQueue.UnaryServerInterceptor(),
 		),
 		grpc.ChainStreamInterceptor(
 			serverMetrics.StreamServerInterceptor(),
 			auth.StreamServerInterceptor(authManager, log),
+			applyQueue.StreamServerInterceptor(),
 		),
 		grpc.MaxRecvMsgSize(1024 * 1024 * 256),
 		grpc.MaxSendMsgSize(1024 * 1024 * 256),
@@ -283,7 +313,11 @@ func NewGateway(cfg *Config, options ...GatewayOption) (*Gateway, error) {
 	}
 	server = grpc.NewServer(serverOpts...)
 
-	gatewayService := NewGatewayService(registry, log)
+	backendCreds, err := cfg.BackendTLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("load backend TLS: %w", err)
+	}
+	gatewayService := NewGatewayService(registry, log, WithBackendCredentials(backendCreds))
 	ynpb.RegisterGatewayServer(server, gatewayService)
 	log.Info("registered service", zap.String("service", fmt.Sprintf("%T", gatewayService)))
 
@@ -299,7 +333,14 @@ func NewGateway(cfg *Config, options ...GatewayOption) (*Gateway, error) {
 	ynpb.RegisterReadinessServiceServer(server, readinessSvc)
 	log.Info("registered service", zap.String("service", fmt.Sprintf("%T", readinessSvc)))
 
-	metricsService := NewMetricsService(serverMetrics)
+	lockService := NewLockService(log)
+	ynpb.RegisterLockServiceServer(server, lockService)
+	log.Info("registered service", zap.String("service", fmt.Sprintf("%T", lockService)))
+
+	ynpb.RegisterApplyQueueServiceServer(server, applyQueue)
+	log.Info("registered service", zap.String("service", fmt.Sprintf("%T", applyQueue)))
+
+	metricsService := NewMetricsService(append([]MetricsCollector{serverMetrics, applyQueue}, opts.Collectors...)...)
 	ynpb.RegisterMetricsServiceServer(server, metricsService)
 	log.Info("registered service", zap.String("service", fmt.Sprintf("%T", metricsService)))
 
@@ -323,6 +364,8 @@ func NewGateway(cfg *Config, options ...GatewayOption) (*Gateway, error) {
 		"controlplane.ynpb.v1.Auth",
 		ynpb.ReadinessService_ServiceDesc.ServiceName,
 		ynpb.MetricsService_ServiceDesc.ServiceName,
+		ynpb.LockService_ServiceDesc.ServiceName,
+		ynpb.ApplyQueueService_ServiceDesc.ServiceName,
 	} {
 		registry.RegisterBackend(service, loopback, BackendKindBuiltin)
 		log.Info("registered built-in service in registry",
@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/yanet-platform/yanet2/controlplane/internal/auth/core"
+	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+)
+
+const (
+	// defaultLockTTL is the lease duration used when AcquireLock does not
+	// specify one.
+	defaultLockTTL = time.Minute
+	// maxLockTTL bounds the lease duration, so that a crashed holder
+	// cannot block a namespace for long.
+	maxLockTTL = time.Hour
+)
+
+// lockKey identifies a config namespace.
+type lockKey struct {
+	module     string
+	instance   uint32
+	configName string
+}
+
+func (m lockKey) String() string {
+	return fmt.Sprintf("%s/%d/%s", m.module, m.instance, m.configName)
+}
+
+// lease is an active lock on a config namespace.
+type lease struct {
+	owner      string
+	holder     string
+	token      string
+	acquiredAt time.Time
+	expiresAt  time.Time
+}
+
+// LockService implements advisory locking of module config namespaces.
+//
+// Leases live in the gateway memory only and are lost on restart, which is
+// acceptable for advisory locks: holders renew them periodically anyway.
+type LockService struct {
+	ynpb.UnimplementedLockServiceServer
+
+	mu     sync.Mutex
+	leases map[lockKey]lease
+	now    func() time.Time
+
+	log *zap.Logger
+}
+
+// NewLockService creates a LockService without any leases.
+func NewLockService(log *zap.Logger) *LockService {
+	return &LockService{
+		leases: map[lockKey]lease{},
+		now:    time.Now,
+		log:    log,
+	}
+}
+
+// AcquireLock takes or renews the lock of a config namespace.
+func (m *LockService) AcquireLock(
+	ctx context.Context,
+	req *ynpb.AcquireLockRequest,
+) (*ynpb.AcquireLockResponse, error) {
+	key, err := parseLockKey(req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultLockTTL
+	if req.GetTtl() != nil {
+		ttl = req.GetTtl().AsDuration()
+		if ttl <= 0 || ttl > maxLockTTL {
+			return nil, status.Errorf(codes.InvalidArgument, "lock ttl must be in (0, %s]", maxLockTTL)
+		}
+	}
+
+	owner := ""
+	if principal := core.FromContext(ctx); principal != nil {
+		owner = principal.User
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	current, ok := m.leases[key]
+	if ok && now.After(current.expiresAt) {
+		ok = false
+	}
+
+	switch {
+	case ok && req.GetToken() == current.token:
+		current.expiresAt = now.Add(ttl)
+		if req.GetHolder() != "" {
+			current.holder = req.GetHolder()
+		}
+	case ok:
+		return nil, status.Errorf(
+			codes.Aborted,
+			"config %s is locked by %s until %s",
+			key, describeLease(current), current.expiresAt.Format(time.RFC3339),
+		)
+	case req.GetToken() != "":
+		return nil, status.Errorf(codes.FailedPrecondition, "lock of config %s has expired", key)
+	default:
+		token, err := newLockToken()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate lock token: %v", err)
+		}
+		current = lease{
+			owner:      owner,
+			holder:     req.GetHolder(),
+			token:      token,
+			acquiredAt: now,
+			expiresAt:  now.Add(ttl),
+		}
+		m.log.Info("acquired config lock",
+			zap.Stringer("key", key),
+			zap.String("owner", owner),
+			zap.String("holder", current.holder),
+			zap.Duration("ttl", ttl),
+		)
+	}
+	m.leases[key] = current
+
+	return &ynpb.AcquireLockResponse{
+		Lock: lockToProto(key, current, true),
+	}, nil
+}
+
+// ReleaseLock releases a lock held with the request token.
+//
+// Releasing an expired or unknown lock succeeds, so that holders can
+// release unconditionally when they are done.
+func (m *LockService) ReleaseLock(
+	ctx context.Context,
+	req *ynpb.ReleaseLockRequest,
+) (*ynpb.ReleaseLockResponse, error) {
+	key, err := parseLockKey(req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.leases[key]
+	if !ok || m.now().After(current.expiresAt) {
+		delete(m.leases, key)
+		return &ynpb.ReleaseLockResponse{}, nil
+	}
+	if req.GetToken() != current.token {
+		return nil, status.Errorf(
+			codes.PermissionDenied,
+			"config %s is locked by %s",
+			key, describeLease(current),
+		)
+	}
+
+	delete(m.leases, key)
+	m.log.Info("released config lock",
+		zap.Stringer("key", key),
+		zap.String("owner", current.owner),
+		zap.String("holder", current.holder),
+	)
+
+	return &ynpb.ReleaseLockResponse{}, nil
+}
+
+// ListLocks returns all unexpired locks ordered by key, without their
+// tokens.
+func (m *LockService) ListLocks(
+	ctx context.Context,
+	req *ynpb.ListLocksRequest,
+) (*ynpb.ListLocksResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	keys := make([]lockKey, 0, len(m.leases))
+	for key, current := range m.leases {
+		if now.After(current.expiresAt) {
+			delete(m.leases, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b lockKey) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	response := &ynpb.ListLocksResponse{
+		Locks: make([]*ynpb.Lock, 0, len(keys)),
+	}
+	for _, key := range keys {
+		response.Locks = append(response.Locks, lockToProto(key, m.leases[key], false))
+	}
+
+	return response, nil
+}
+
+func parseLockKey(key *ynpb.LockKey) (lockKey, error) {
+	if key.GetModule() == "" {
+		return lockKey{}, status.Error(codes.InvalidArgument, "lock key module is required")
+	}
+	if key.GetConfigName() == "" {
+		return lockKey{}, status.Error(codes.InvalidArgument, "lock key config name is required")
+	}
+
+	return lockKey{
+		module:     key.GetModule(),
+		instance:   key.GetInstance(),
+		configName: key.GetConfigName(),
+	}, nil
+}
+
+// describeLease formats the lease holder for conflict errors.
+func describeLease(current lease) string {
+	owner := current.owner
+	if owner == "" {
+		owner = "anonymous"
+	}
+	if current.holder == "" {
+		return fmt.Sprintf("%q", owner)
+	}
+
+	return fmt.Sprintf("%q (%s)", owner, current.holder)
+}
+
+func lockToProto(key lockKey, current lease, withToken bool) *ynpb.Lock {
+	lock := &ynpb.Lock{
+		Key: &ynpb.LockKey{
+			Module:     key.module,
+			Instance:   key.instance,
+			ConfigName: key.configName,
+		},
+		Owner:      current.owner,
+		Holder:     current.holder,
+		AcquiredAt: timestamppb.New(current.acquiredAt),
+		ExpiresAt:  timestamppb.New(current.expiresAt),
+	}
+	if withToken {
+		lock.Token = current.token
+	}
+
+	return lock
+}
+
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/yanet-platform/yanet2/controlplane/internal/auth/core"
+	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+)
+
+func newTestLockService(t *testing.T) (*LockService, *time.Time) {
+	t.Helper()
+
+	now := time.Unix(1700000000, 0)
+	service := NewLockService(zap.NewNop())
+	service.now = func() time.Time { return now }
+
+	return service, &now
+}
+
+func TestLockService_Conflict(t *testing.T) {
+	service, _ := newTestLockService(t)
+	key := &ynpb.LockKey{Module: "route", Instance: 0, ConfigName: "route0"}
+
+	ctx := core.WithPrincipal(t.Context(), &core.Principal{User: "alice"})
+	first, err := service.AcquireLock(ctx, &ynpb.AcquireLockRequest{Key: key, Holder: "deploy #1"})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.GetLock().GetToken())
+	require.Equal(t, "alice", first.GetLock().GetOwner())
+
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key})
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), `"alice" (deploy #1)`)
+
+	// Other instances and configs are independent namespaces.
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{
+		Key: &ynpb.LockKey{Module: "route", Instance: 1, ConfigName: "route0"},
+	})
+	require.NoError(t, err)
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{
+		Key: &ynpb.LockKey{Module: "route", Instance: 0, ConfigName: "route1"},
+	})
+	require.NoError(t, err)
+
+	_, err = service.ReleaseLock(t.Context(), &ynpb.ReleaseLockRequest{Key: key, Token: "bogus"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = service.ReleaseLock(t.Context(), &ynpb.ReleaseLockRequest{Key: key, Token: first.GetLock().GetToken()})
+	require.NoError(t, err)
+
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key})
+	require.NoError(t, err)
+}
+
+func TestLockService_RenewAndExpire(t *testing.T) {
+	service, now := newTestLockService(t)
+	key := &ynpb.LockKey{Module: "acl", ConfigName: "acl0"}
+	ttl := durationpb.New(10 * time.Second)
+
+	first, err := service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key, Ttl: ttl})
+	require.NoError(t, err)
+	token := first.GetLock().GetToken()
+
+	*now = now.Add(8 * time.Second)
+	renewed, err := service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key, Ttl: ttl, Token: token})
+	require.NoError(t, err)
+	require.Equal(t, token, renewed.GetLock().GetToken())
+	require.True(t, now.Add(10*time.Second).Equal(renewed.GetLock().GetExpiresAt().AsTime()))
+
+	*now = now.Add(8 * time.Second)
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	locks, err := service.ListLocks(t.Context(), &ynpb.ListLocksRequest{})
+	require.NoError(t, err)
+	require.Len(t, locks.GetLocks(), 1)
+	require.Empty(t, locks.GetLocks()[0].GetToken())
+
+	*now = now.Add(3 * time.Second)
+	locks, err = service.ListLocks(t.Context(), &ynpb.ListLocksRequest{})
+	require.NoError(t, err)
+	require.Empty(t, locks.GetLocks())
+
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key, Token: token})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = service.AcquireLock(t.Context(), &ynpb.AcquireLockRequest{Key: key})
+	require.NoError(t, err)
+}
+
+func TestLockService_InvalidArgument(t *testing.T) {
+	service, _ := newTestLockService(t)
+
+	tests := []struct {
+		name string
+		req  *ynpb.AcquireLockRequest
+	}{
+		{
+			name: "missing key",
+			req:  &ynpb.AcquireLockRequest{},
+		},
+		{
+			name: "missing config name",
+			req:  &ynpb.AcquireLockRequest{Key: &ynpb.LockKey{Module: "route"}},
+		},
+		{
+			name: "ttl too long",
+			req: &ynpb.AcquireLockRequest{
+				Key: &ynpb.LockKey{Module: "route", ConfigName: "route0"},
+				Ttl: durationpb.New(2 * maxLockTTL),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.AcquireLock(t.Context(), tt.req)
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
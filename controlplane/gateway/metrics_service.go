@@ -7,28 +7,33 @@ import (
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
 )
 
-// metricsCollector provides the gateway's collected gRPC server metrics.
-type metricsCollector interface {
+// MetricsCollector provides a snapshot of collected metrics.
+type MetricsCollector interface {
 	Collect() []*commonpb.Metric
 }
 
-// MetricsService exposes gateway gRPC server metrics over its own gRPC
-// service.
+// MetricsService exposes gateway gRPC server metrics, along with the
+// metrics of any extra collectors, over its own gRPC service.
 type MetricsService struct {
 	ynpb.UnimplementedMetricsServiceServer
 
-	collector metricsCollector
+	collectors []MetricsCollector
 }
 
-// NewMetricsService creates a MetricsService backed by collector.
-func NewMetricsService(collector metricsCollector) *MetricsService {
-	return &MetricsService{collector: collector}
+// NewMetricsService creates a MetricsService backed by collectors.
+func NewMetricsService(collectors ...MetricsCollector) *MetricsService {
+	return &MetricsService{collectors: collectors}
 }
 
-// GetMetrics returns a snapshot of all gateway gRPC server metrics.
+// GetMetrics returns a snapshot of all gateway metrics.
 func (m *MetricsService) GetMetrics(
 	ctx context.Context,
 	req *ynpb.GetMetricsRequest,
 ) (*ynpb.GetMetricsResponse, error) {
-	return &ynpb.GetMetricsResponse{Metrics: m.collector.Collect()}, nil
+	var metrics []*commonpb.Metric
+	for _, collector := range m.collectors {
+		metrics = append(metrics, collector.Collect()...)
+	}
+
+	return &ynpb.GetMetricsResponse{Metrics: metrics}, nil
 }
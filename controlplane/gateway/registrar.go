@@ -9,6 +9,7 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding/gzip"
 
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
@@ -18,6 +19,7 @@ type gatewayRegistrarOptions struct {
 	Backoff        func() backoff.BackOff
 	MaxElapsedTime time.Duration
 	InProcess      bool
+	Credentials    credentials.TransportCredentials
 	Log            *zap.Logger
 }
 
@@ -68,6 +70,18 @@ func WithInProcess(inProcess bool) GatewayRegistrarOption {
 	}
 }
 
+// WithRegistrarCredentials sets the transport credentials of the
+// connection to the gateway, overriding the ones built from the gateway
+// TLS config.
+//
+// Used by the clients dialing a remote gateway, which authenticate with
+// their own TLS material.
+func WithRegistrarCredentials(creds credentials.TransportCredentials) GatewayRegistrarOption {
+	return func(o *gatewayRegistrarOptions) {
+		o.Credentials = creds
+	}
+}
+
 // GatewayRegistrar registers service backends in a single gateway endpoint.
 //
 // A single GatewayRegistrar instance is tied to exactly one endpoint.
@@ -92,9 +106,13 @@ func NewGatewayRegistrar(
 		o(opts)
 	}
 
-	creds, err := TransportCredentials(tlsConfig, endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize gateway transport credentials: %w", err)
+	creds := opts.Credentials
+	if creds == nil {
+		var err error
+		creds, err = TransportCredentials(tlsConfig, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize gateway transport credentials: %w", err)
+		}
 	}
 
 	conn, err := grpc.NewClient(
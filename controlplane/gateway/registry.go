@@ -92,6 +92,15 @@ func (m *BackendRegistry) GetBackend(service string) (proxy.Backend, bool) {
 	return entry.backend, ok
 }
 
+// GetKind returns the hosting kind of the backend for the given service.
+func (m *BackendRegistry) GetKind(service string) (BackendKind, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.backends[service]
+	return entry.kind, ok
+}
+
 // RegisterBackend stores or replaces the backend for service and reports how
 // the registry changed.
 //
@@ -7,10 +7,13 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/yanet-platform/yanet2/controlplane/internal/xgrpc"
 )
@@ -23,15 +26,26 @@ type UnaryInterceptedService interface {
 	UnaryServerInterceptors() []grpc.UnaryServerInterceptor
 }
 
+// healthCheckInterval is the interval the health of a HealthCheckedService
+// is checked at.
+const healthCheckInterval = 5 * time.Second
+
 // ServiceRunner runs an out-of-process Service on its own listener and
 // registers it with the gateway.
+//
+// The server also exposes grpc.health.v1.Health, reporting the status of
+// every service name of the Service and of the server as a whole under the
+// empty name.
 type ServiceRunner struct {
 	module          Service
 	gatewayEndpoint string
 	gatewayTLS      *TLSConfig
 	server          *grpc.Server
-	ready           chan struct{}
-	log             *zap.Logger
+	health          *health.Server
+	// healthErr is the error of the last health check.
+	healthErr error
+	ready     chan struct{}
+	log       *zap.Logger
 }
 
 // NewServiceRunner creates a new ServiceRunner for the given service.
@@ -56,8 +70,9 @@ func NewServiceRunner(
 			grpc.ChainUnaryInterceptor(interceptors...),
 			grpc.MaxRecvMsgSize(1024*1024*256), grpc.MaxSendMsgSize(1024*1024*256),
 		),
-		ready: make(chan struct{}),
-		log:   log,
+		health: health.NewServer(),
+		ready:  make(chan struct{}),
+		log:    log,
 	}
 }
 
@@ -85,6 +100,8 @@ func (m *ServiceRunner) Run(ctx context.Context) error {
 	}
 
 	m.module.RegisterService(m.server)
+	healthpb.RegisterHealthServer(m.server, m.health)
+	m.checkHealth()
 
 	wg, ctx := errgroup.WithContext(ctx)
 	if bg, ok := m.module.(BackgroundService); ok {
@@ -94,6 +111,12 @@ func (m *ServiceRunner) Run(ctx context.Context) error {
 			return bg.Run(ctx)
 		})
 	}
+	if _, ok := m.module.(HealthCheckedService); ok {
+		wg.Go(func() error {
+			m.runHealthChecks(ctx)
+			return nil
+		})
+	}
 	wg.Go(func() error {
 		m.log.Info("exposing gRPC API", zap.Stringer("addr", listener.Addr()))
 		return m.server.Serve(listener)
@@ -109,11 +132,55 @@ func (m *ServiceRunner) Run(ctx context.Context) error {
 	m.log.Info("stopping gRPC API", zap.Stringer("addr", listener.Addr()))
 	defer m.log.Info("stopped gRPC API", zap.Stringer("addr", listener.Addr()))
 
+	// Probes see the services go away before their connections do.
+	m.health.Shutdown()
 	m.server.GracefulStop()
 
 	return wg.Wait()
 }
 
+// runHealthChecks checks the health of the service until the context is
+// canceled.
+func (m *ServiceRunner) runHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkHealth()
+		}
+	}
+}
+
+// checkHealth sets the health status of every service name of the service,
+// logging its changes.
+func (m *ServiceRunner) checkHealth() {
+	var err error
+	if checked, ok := m.module.(HealthCheckedService); ok {
+		err = checked.Health()
+	}
+
+	switch {
+	case err != nil && m.healthErr == nil:
+		m.log.Warn("service became unhealthy", zap.Error(err))
+	case err == nil && m.healthErr != nil:
+		m.log.Info("service became healthy")
+	}
+	m.healthErr = err
+
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	m.health.SetServingStatus("", servingStatus)
+	for _, name := range m.module.ServicesNames() {
+		m.health.SetServingStatus(name, servingStatus)
+	}
+}
+
 func (m *ServiceRunner) listen() (net.Listener, error) {
 	endpoint := m.module.Endpoint()
 
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"errors"
 	"net"
 	"sync"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/stretchr/testify/require"
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
@@ -29,6 +31,18 @@ func (m *fakeService) ServicesNames() []string {
 
 func (m *fakeService) RegisterService(_ *grpc.Server) {}
 
+// fakeHealthCheckedService is a fakeService reporting the health it is
+// set to.
+type fakeHealthCheckedService struct {
+	fakeService
+
+	err error
+}
+
+func (m *fakeHealthCheckedService) Health() error {
+	return m.err
+}
+
 type fakeConnection struct {
 	net.Conn
 
@@ -132,3 +146,34 @@ func TestServiceRunner_registerClosesGatewayClientConnection(t *testing.T) {
 		return trackingListener.ActiveCount() == 0
 	}, 2*time.Second, 25*time.Millisecond, "registration client connections were not closed")
 }
+
+func TestServiceRunner_checkHealth(t *testing.T) {
+	service := &fakeHealthCheckedService{}
+	serviceRunner := NewServiceRunner(service, "127.0.0.1:0", nil, zap.NewNop())
+
+	servingStatus := func(name string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := serviceRunner.health.Check(t.Context(), &healthpb.HealthCheckRequest{Service: name})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
+
+	serviceRunner.checkHealth()
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(""))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus("fake.Service"))
+
+	service.err = errors.New("dataplane instance is not ready")
+	serviceRunner.checkHealth()
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(""))
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus("fake.Service"))
+
+	service.err = nil
+	serviceRunner.checkHealth()
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus("fake.Service"))
+
+	// Services without health checks are serving while they run.
+	plainRunner := NewServiceRunner(&fakeService{}, "127.0.0.1:0", nil, zap.NewNop())
+	plainRunner.checkHealth()
+	resp, err := plainRunner.health.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "fake.Service"})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
@@ -5,6 +5,7 @@ import (
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -41,15 +42,46 @@ func registrationStatusToProto(s RegistrationStatus) ynpb.RegistrationStatus {
 // GatewayService is the gRPC service for the Gateway API.
 type GatewayService struct {
 	ynpb.UnimplementedGatewayServer
-	registry *BackendRegistry
-	log      *zap.Logger
+	registry     *BackendRegistry
+	backendCreds credentials.TransportCredentials
+	log          *zap.Logger
+}
+
+type gatewayServiceOptions struct {
+	BackendCredentials credentials.TransportCredentials
+}
+
+// GatewayServiceOption configures NewGatewayService.
+type GatewayServiceOption func(*gatewayServiceOptions)
+
+// WithBackendCredentials sets the transport credentials of the
+// connections to external backends.
+//
+// In-process backends listen on the gateway host only and are always
+// dialed in plaintext. Without the option, external ones are too.
+func WithBackendCredentials(creds credentials.TransportCredentials) GatewayServiceOption {
+	return func(o *gatewayServiceOptions) {
+		o.BackendCredentials = creds
+	}
 }
 
 // NewGatewayService creates a new GatewayService.
-func NewGatewayService(registry *BackendRegistry, log *zap.Logger) *GatewayService {
+func NewGatewayService(
+	registry *BackendRegistry,
+	log *zap.Logger,
+	options ...GatewayServiceOption,
+) *GatewayService {
+	opts := &gatewayServiceOptions{
+		BackendCredentials: insecure.NewCredentials(),
+	}
+	for _, o := range options {
+		o(opts)
+	}
+
 	return &GatewayService{
-		registry: registry,
-		log:      log,
+		registry:     registry,
+		backendCreds: opts.BackendCredentials,
+		log:          log,
 	}
 }
 
@@ -119,7 +151,11 @@ func (m *GatewayService) Register(
 		return &ynpb.RegisterResponse{Status: registrationStatusToProto(RegistrationRenewed)}, nil
 	}
 
-	b, err := dialBackend(backendDesc.GetEndpoint(), insecure.NewCredentials())
+	creds := m.backendCreds
+	if kind == BackendKindInProcess {
+		creds = insecure.NewCredentials()
+	}
+	b, err := dialBackend(backendDesc.GetEndpoint(), creds)
 	if err != nil {
 		return nil, err
 	}
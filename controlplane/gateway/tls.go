@@ -8,12 +8,13 @@ import (
 
 	"google.golang.org/grpc/credentials"
 
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
 )
 
 // TLSConfig holds the gateway server TLS material.
 //
-// Both files must be PEM-encoded.
+// All files must be PEM-encoded.
 type TLSConfig struct {
 	// CertFile is the path to the PEM-encoded server certificate.
 	CertFile xcfg.NonEmptyString `yaml:"cert_file"`
@@ -24,6 +25,15 @@ type TLSConfig struct {
 	//
 	// Optional. Defaults to the host parsed from the dial endpoint.
 	ServerName string `yaml:"server_name"`
+	// ClientCAFile is the path to the CA certificates the gRPC clients
+	// authenticate against.
+	//
+	// Optional. When set, every gRPC client, operators and module
+	// controlplanes alike, must present a certificate signed by one of
+	// them. In-process clients present the gateway's own certificate, which
+	// must therefore be signed by one of them too. The HTTP listener does
+	// not request client certificates.
+	ClientCAFile string `yaml:"client_ca_file"`
 }
 
 // ServerCredentials loads the cert/key pair and returns gRPC server
@@ -34,16 +44,27 @@ func (m *TLSConfig) ServerCredentials() (credentials.TransportCredentials, error
 		return nil, fmt.Errorf("failed to load gateway TLS keypair: %w", err)
 	}
 
-	return credentials.NewTLS(&tls.Config{
+	cfg := &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		MinVersion:   tls.VersionTLS12,
-	}), nil
+	}
+	if m.ClientCAFile != "" {
+		pool, err := grpctls.LoadCertPool(m.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gateway TLS client CAs: %w", err)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(cfg), nil
 }
 
 // LoopbackClientCredentials returns gRPC client transport credentials
 // that trust the gateway's own server certificate as the only CA.
 //
-// Used by self-dials inside the controlplane-director process.
+// Used by self-dials inside the controlplane-director process. With
+// client authentication enabled, they present the server certificate.
 //
 // fallbackHost is used as ServerName when m.ServerName is empty.
 func (m *TLSConfig) LoopbackClientCredentials(
@@ -65,9 +86,18 @@ func (m *TLSConfig) LoopbackClientCredentials(
 		name = fallbackHost
 	}
 
-	return credentials.NewTLS(&tls.Config{
+	cfg := &tls.Config{
 		RootCAs:    pool,
 		ServerName: name,
 		MinVersion: tls.VersionTLS12,
-	}), nil
+	}
+	if m.ClientCAFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, m.KeyFile.Unwrap())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gateway TLS keypair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
 }
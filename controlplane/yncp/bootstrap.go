@@ -0,0 +1,134 @@
+package yncp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/common/go/xbackoff"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+)
+
+// BootstrapConfig configures how the director waits for the dataplane at
+// startup.
+type BootstrapConfig struct {
+	// Instances are the dataplane instances that must finish initializing
+	// their shared memory before modules attach to them.
+	//
+	// The gateway instance is always waited for.
+	Instances []uint32 `yaml:"instances"`
+	// Timeout is the maximum time to wait for the instances. Zero waits
+	// forever.
+	Timeout time.Duration `yaml:"timeout"`
+	// ProgressInterval is how often the instances still being waited for
+	// are logged.
+	ProgressInterval time.Duration `yaml:"progress_interval"`
+}
+
+// DefaultBootstrapConfig returns the default bootstrap configuration.
+func DefaultBootstrapConfig() BootstrapConfig {
+	return BootstrapConfig{
+		Timeout:          60 * time.Second,
+		ProgressInterval: 5 * time.Second,
+	}
+}
+
+// Validate validates the bootstrap configuration.
+func (m *BootstrapConfig) Validate() error {
+	if m.Timeout < 0 {
+		return fmt.Errorf("bootstrap timeout must not be negative, got %s", m.Timeout)
+	}
+	if m.ProgressInterval < 0 {
+		return fmt.Errorf("bootstrap progress interval must not be negative, got %s", m.ProgressInterval)
+	}
+	return nil
+}
+
+// waitDataplane attaches to the dataplane shared memory and blocks until
+// every listed instance finishes initializing it.
+//
+// The shared memory file may not exist yet, so attach errors are retried
+// as well. Instances seen ready once are not checked again.
+func waitDataplane(
+	ctx context.Context,
+	path string,
+	instances []uint32,
+	cfg BootstrapConfig,
+	log *zap.Logger,
+) (*ffi.SharedMemory, error) {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	log.Info("waiting for dataplane instances",
+		zap.String("path", path),
+		zap.Uint32s("instances", instances),
+		zap.Duration("timeout", cfg.Timeout),
+	)
+
+	startedAt := time.Now()
+	loggedAt := startedAt
+	pending := slices.Clone(instances)
+
+	var shm *ffi.SharedMemory
+	var lastErr error
+	bo := xbackoff.New(100*time.Millisecond, xbackoff.WithMax(2*time.Second))
+	err := bo.RunContext(ctx, func() error {
+		lastErr = func() error {
+			if shm == nil {
+				attached, err := ffi.AttachSharedMemory(path)
+				if err != nil {
+					return fmt.Errorf("failed to attach to shared memory %q: %w", path, err)
+				}
+				shm = attached
+			}
+
+			pending = slices.DeleteFunc(pending, func(instance uint32) bool {
+				if !shm.DataplaneReady(instance) {
+					return false
+				}
+				log.Info("dataplane instance ready", zap.Uint32("instance_id", instance))
+				return true
+			})
+			if len(pending) > 0 {
+				return fmt.Errorf("dataplane instances %v not ready", pending)
+			}
+			return nil
+		}()
+
+		if lastErr != nil && cfg.ProgressInterval > 0 && time.Since(loggedAt) >= cfg.ProgressInterval {
+			loggedAt = time.Now()
+			log.Info("still waiting for dataplane instances",
+				zap.Uint32s("pending", pending),
+				zap.Duration("elapsed", loggedAt.Sub(startedAt)),
+				zap.Error(lastErr),
+			)
+		}
+		return lastErr
+	})
+	if err != nil {
+		if shm != nil {
+			shm.Detach()
+		}
+		if errors.Is(err, context.DeadlineExceeded) && lastErr != nil {
+			err = lastErr
+		}
+		return nil, fmt.Errorf(
+			"dataplane not ready after %s: %w",
+			time.Since(startedAt).Round(time.Millisecond),
+			err,
+		)
+	}
+
+	log.Info("dataplane instances ready",
+		zap.Uint32s("instances", instances),
+		zap.Duration("elapsed", time.Since(startedAt)),
+	)
+	return shm, nil
+}
@@ -17,6 +17,8 @@ type config struct {
 	// MemoryPath is the path to the shared-memory file that is used to
 	// communicate with dataplane.
 	MemoryPath string `yaml:"memory_path"`
+	// Bootstrap configures waiting for the dataplane at startup.
+	Bootstrap BootstrapConfig `json:"bootstrap" yaml:"bootstrap"`
 	// Gateway configuration.
 	Gateway *gateway.Config `json:"gateway" yaml:"gateway"`
 	// Modules configuration.
@@ -35,6 +37,7 @@ func DefaultConfig() *Config {
 			Level: zapcore.InfoLevel,
 		},
 		MemoryPath: "/dev/hugepages/yanet",
+		Bootstrap:  DefaultBootstrapConfig(),
 		Gateway:    gateway.DefaultConfig(),
 		Modules:    bundle.DefaultModulesConfig(),
 		Devices:    bundle.DefaultDevicesConfig(),
@@ -56,6 +59,9 @@ func (m *Config) UnmarshalYAML(value *yaml.Node) error {
 
 // Validate validates the control plane configuration.
 func (m *Config) Validate() error {
+	if err := m.Bootstrap.Validate(); err != nil {
+		return err
+	}
 	err := m.Modules.Validate()
 	if err != nil {
 		return err
@@ -65,6 +65,12 @@ func (m *DevicePlainDevice) RegisterService(server *grpc.Server) {
 	plainpb.RegisterDevicePlainServiceServer(server, m.service)
 }
 
+// Health reports whether the device agent is attached to a ready dataplane
+// instance.
+func (m *DevicePlainDevice) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the device and releases all resources
 func (m *DevicePlainDevice) Close() error {
 	if err := m.agent.Close(); err != nil {
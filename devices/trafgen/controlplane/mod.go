@@ -101,6 +101,12 @@ func (m *TrafgenDevice) RegisterService(server *grpc.Server) {
 	trafgenpb.RegisterTrafgenServiceServer(server, m.service)
 }
 
+// Health reports whether the device agent is attached to a ready dataplane
+// instance.
+func (m *TrafgenDevice) Health() error {
+	return m.agent.Health()
+}
+
 // Close releases shared memory resources held by the device.
 func (m *TrafgenDevice) Close() error {
 	if err := m.agent.Close(); err != nil {
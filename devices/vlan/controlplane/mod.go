@@ -65,6 +65,12 @@ func (m *DeviceVlanDevice) RegisterService(server *grpc.Server) {
 	vlanpb.RegisterDeviceVlanServiceServer(server, m.service)
 }
 
+// Health reports whether the device agent is attached to a ready dataplane
+// instance.
+func (m *DeviceVlanDevice) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the device and releases all resources
 func (m *DeviceVlanDevice) Close() error {
 	if err := m.agent.Close(); err != nil {
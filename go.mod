@@ -9,6 +9,8 @@ require (
 	github.com/golang/protobuf v1.5.4 // Somehow, it's used in the package build process for ubuntu 22.04
 	github.com/google/go-cmp v0.7.0
 	github.com/gopacket/gopacket v1.6.1
+	github.com/klauspost/compress v1.18.6
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/siderolabs/grpc-proxy v0.5.2
 	github.com/spf13/cobra v1.10.2
 	github.com/stretchr/testify v1.11.1
@@ -31,8 +33,6 @@ require (
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/klauspost/compress v1.18.6 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 	github.com/vishvananda/netns v0.0.5 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
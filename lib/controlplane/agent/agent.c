@@ -26,9 +26,9 @@
 
 #include <stdio.h>
 
-struct yanet_shm *
-yanet_shm_attach(const char *path) {
-	int fd = open(path, O_RDWR, S_IRUSR | S_IWUSR);
+static struct yanet_shm *
+yanet_shm_map(const char *path, int flags, int prot) {
+	int fd = open(path, flags, S_IRUSR | S_IWUSR);
 	if (fd == -1) {
 		return NULL;
 	}
@@ -40,9 +40,7 @@ yanet_shm_attach(const char *path) {
 		return NULL;
 	}
 
-	void *ptr = mmap(
-		NULL, stat.st_size, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0
-	);
+	void *ptr = mmap(NULL, stat.st_size, prot, MAP_SHARED, fd, 0);
 	close(fd);
 	if (ptr == MAP_FAILED) {
 		return NULL;
@@ -52,6 +50,16 @@ yanet_shm_attach(const char *path) {
 	return shm;
 }
 
+struct yanet_shm *
+yanet_shm_attach(const char *path) {
+	return yanet_shm_map(path, O_RDWR, PROT_READ | PROT_WRITE);
+}
+
+struct yanet_shm *
+yanet_shm_attach_ro(const char *path) {
+	return yanet_shm_map(path, O_RDONLY, PROT_READ);
+}
+
 int
 yanet_shm_detach(struct yanet_shm *shm) {
 	// calculate total size of shared memory
@@ -101,6 +109,18 @@ dataplane_instance_worker_count(struct dp_config *dp_config) {
 	return (uint32_t)dp_config->worker_count;
 }
 
+// Reports whether the shared memory segment is mapped writable.
+//
+// Requesting the protection a page already has is a no-op for a writable
+// mapping, while the kernel refuses write access with EACCES for a mapping
+// of a file opened read-only, as made by yanet_shm_attach_ro.
+static bool
+yanet_shm_writable(struct yanet_shm *shm) {
+	uintptr_t page_size = (uintptr_t)sysconf(_SC_PAGESIZE);
+	void *page = (void *)((uintptr_t)shm & ~(page_size - 1));
+	return mprotect(page, page_size, PROT_READ | PROT_WRITE) == 0;
+}
+
 struct agent *
 agent_attach(
 	struct yanet_shm *shm,
@@ -130,6 +150,15 @@ agent_attach(
 		return NULL;
 	}
 
+	// Attaching an agent allocates its memory under the controlplane
+	// configuration lock, so it would fault on a read-only mapping.
+	if (!yanet_shm_writable(shm)) {
+		yanet_error_add(
+			err, "shared memory is attached in read-only mode"
+		);
+		return NULL;
+	}
+
 	struct cp_config *cp_config = ADDR_OF(&dp_config->cp_config);
 
 	cp_config_lock(cp_config);
@@ -405,6 +434,14 @@ agent_reattach(
 		return NULL;
 	}
 
+	// Same write access guard as in agent_attach.
+	if (!yanet_shm_writable(shm)) {
+		yanet_error_add(
+			err, "shared memory is attached in read-only mode"
+		);
+		return NULL;
+	}
+
 	struct cp_config *cp_config = ADDR_OF(&dp_config->cp_config);
 
 	cp_config_lock(cp_config);
@@ -1847,6 +1884,13 @@ agent_dp_config(struct agent *agent) {
 	return ADDR_OF(&agent->dp_config);
 }
 
+int
+agent_dp_ready(struct agent *agent) {
+	struct dp_config *dp_config = agent_dp_config(agent);
+	return __atomic_load_n(&dp_config->ready_magic, __ATOMIC_ACQUIRE) ==
+	       DP_CONFIG_READY_MAGIC;
+}
+
 void *
 agent_storage_read(struct agent *agent, const char *name) {
 	struct agent_storage *storage = ADDR_OF(&agent->storage);
@@ -1,5 +1,7 @@
+#include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
+#include <unistd.h>
 
 #include "api/agent.h"
 #include "common/memory_address.h"
@@ -194,6 +196,70 @@ test_attach_initialised_segment_succeeds() {
 	return TEST_SUCCESS;
 }
 
+// Verify that a segment attached with yanet_shm_attach_ro can be inspected,
+// while agent_attach refuses it with an error instead of faulting on the
+// first write. The initialised segment is stored to a file, as the mapping
+// is only read-only for a file opened without write permission.
+static int
+test_attach_read_only_segment_returns_error() {
+	void *storage = calloc(1, TEST_STORAGE_SIZE);
+	TEST_ASSERT_NOT_NULL(storage, "calloc failed");
+
+	struct dp_config *dp_config = NULL;
+	struct cp_config *cp_config = NULL;
+	int rc = dp_storage_init(
+		0,
+		0,
+		storage,
+		TEST_DP_MEMORY,
+		TEST_CP_MEMORY,
+		&dp_config,
+		&cp_config
+	);
+	TEST_ASSERT(rc == 0, "dp_storage_init failed");
+
+	dp_config->instance_count = 1;
+	cp_config_unlock(cp_config);
+	dp_config_mark_ready(dp_config);
+
+	char path[] = "/tmp/yanet_agent_test_XXXXXX";
+	int fd = mkstemp(path);
+	TEST_ASSERT(fd != -1, "mkstemp failed");
+	ssize_t written = write(fd, storage, TEST_STORAGE_SIZE);
+	close(fd);
+	free(storage);
+	TEST_ASSERT(
+		written == TEST_STORAGE_SIZE, "failed to write the segment file"
+	);
+
+	struct yanet_shm *shm = yanet_shm_attach_ro(path);
+	unlink(path);
+	TEST_ASSERT_NOT_NULL(shm, "yanet_shm_attach_ro failed");
+
+	TEST_ASSERT(
+		yanet_shm_instance_count(shm) == 1,
+		"read-only segment must report its instance count"
+	);
+	TEST_ASSERT(
+		agent_dp_config_ready(shm, 0) == 1,
+		"read-only segment must report the instance as ready"
+	);
+
+	yanet_error *err = NULL;
+	struct agent *result = agent_attach(shm, 0, "test-agent", 4096, &err);
+
+	TEST_ASSERT_NULL(
+		result, "agent_attach on read-only segment must return NULL"
+	);
+	TEST_ASSERT_NOT_NULL(
+		err, "agent_attach on read-only segment must set an error"
+	);
+	yanet_error_free(err);
+
+	TEST_ASSERT(yanet_shm_detach(shm) == 0, "yanet_shm_detach failed");
+	return TEST_SUCCESS;
+}
+
 int
 main() {
 	log_enable_name("error");
@@ -239,6 +305,13 @@ main() {
 		LOG(ERROR, "test_attach_initialised_segment_succeeds failed");
 	}
 
+	++tests_count;
+	if (test_attach_read_only_segment_returns_error() != TEST_SUCCESS) {
+		++tests_failed;
+		LOG(ERROR,
+		    "test_attach_read_only_segment_returns_error failed");
+	}
+
 	if (tests_failed != 0) {
 		LOG(ERROR, "%zu/%zu tests failed", tests_failed, tests_count);
 		return 1;
@@ -50,6 +50,11 @@ struct packet {
 	// can sum bytes without touching DPDK.
 	uint16_t data_len;
 
+	// Internal traffic class of the packet, set by classifying modules
+	// such as dscp for the modules down the pipeline. Zero for an
+	// unclassified packet.
+	uint8_t traffic_class;
+
 	struct network_header network_header;
 	struct transport_header transport_header;
 };
@@ -13,6 +13,10 @@ type Config struct {
 	MemoryPath xcfg.NonEmptyString `yaml:"memory_path"`
 	// MemoryRequirements specifies memory requirements for the module
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
 	// Endpoint is the gRPC endpoint address
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
 	// GatewayEndpoint is the address of the gateway service
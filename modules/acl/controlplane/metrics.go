@@ -2,6 +2,7 @@ package acl
 
 import (
 	"context"
+	"fmt"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	aclpb "github.com/yanet-platform/yanet2/modules/acl/controlplane/aclpb/v1"
@@ -87,7 +88,10 @@ func (m *ACLService) collectDataplaneMetrics() ([]*commonpb.Metric, error) {
 		return []*commonpb.Metric{}, nil
 	}
 
-	positions := dpConfig.AllModulePositions("acl")
+	positions, err := dpConfig.AllModulePositions("acl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module positions: %w", err)
+	}
 
 	result := make([]*commonpb.Metric, 0)
 	gaugesEmitted := make(map[string]struct{})
@@ -45,7 +45,12 @@ func NewACLModule(cfg *Config, log *zap.Logger) (*ACLModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach(agentName, cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		agentName,
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -107,6 +112,12 @@ func (m *ACLModule) ACLAdapter() *ACLAdapter {
 	return NewACLAdapter(m.aclService)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *ACLModule) Health() error {
+	return m.agent.Health()
+}
+
 func (m *ACLModule) Close() error {
 	if err := m.agent.Close(); err != nil {
 		m.log.Warn("failed to close shared memory agent", zap.Error(err))
@@ -261,12 +261,12 @@ func (m *ACLService) UpdateConfig(
 
 	handle, err := m.backend.NewModule(name)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to create module config")
 	}
 
 	if err := handle.UpdateRules(rules); err != nil {
 		handle.Free()
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	oldConfigs, ok := m.configs[name]
@@ -277,7 +277,7 @@ func (m *ACLService) UpdateConfig(
 
 	if err := m.backend.UpdateModule(handle); err != nil {
 		handle.Free()
-		return nil, status.Errorf(codes.Internal, "failed to update module: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module")
 	}
 
 	if oldConfigs.acl != nil {
@@ -64,7 +64,12 @@ func NewBlackholeModule(cfg *Config, options ...Option) (*BlackholeModule, error
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach(agentName, cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		agentName,
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -100,6 +105,12 @@ func (m *BlackholeModule) RegisterService(server *grpc.Server) {
 	blackholepb.RegisterBlackholeServiceServer(server, m.blackholeService)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *BlackholeModule) Health() error {
+	return m.agent.Health()
+}
+
 // Close releases shared memory resources held by the module.
 func (m *BlackholeModule) Close() error {
 	if err := m.agent.Close(); err != nil {
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	blackholepb "github.com/yanet-platform/yanet2/modules/blackhole/controlplane/blackholepb/v1"
 )
 
@@ -98,10 +99,7 @@ func (m *BlackholeService) UpdateConfig(
 	defer m.mu.Unlock()
 
 	if err := m.updateConfig(name); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update module config %q: %v", name, err,
-		)
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
 	}
 
 	return &blackholepb.UpdateConfigResponse{}, nil
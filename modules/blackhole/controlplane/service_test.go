@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	blackholepb "github.com/yanet-platform/yanet2/modules/blackhole/controlplane/blackholepb/v1"
 )
 
@@ -147,6 +148,23 @@ func Test_BlackholeService_UpdateFailureAtomic(t *testing.T) {
 	require.Equal(t, name, show.Name)
 }
 
+// quotaBackend fails every UpdateModule call for exceeding the memory quota.
+type quotaBackend struct {
+	mockBackend
+}
+
+func (m *quotaBackend) UpdateModule(name string) (ModuleHandle, error) {
+	return nil, fmt.Errorf("failed to update module: %w", &ffi.QuotaExceededError{Module: "blackhole:" + name})
+}
+
+func Test_BlackholeService_UpdateQuotaExceeded(t *testing.T) {
+	svc := NewBlackholeService(&quotaBackend{})
+
+	_, err := svc.UpdateConfig(t.Context(), &blackholepb.UpdateConfigRequest{Name: "blackhole0"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.ErrorContains(t, err, "exceeding its quota")
+}
+
 func Test_BlackholeService_ConcurrentAccess(t *testing.T) {
 	svc := newTestService(t)
 
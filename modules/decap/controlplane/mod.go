@@ -33,7 +33,12 @@ func NewDecapModule(cfg *Config, log *zap.Logger) (*DecapModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach("decap", cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		"decap",
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -65,6 +70,12 @@ func (m *DecapModule) RegisterService(server *grpc.Server) {
 	decappb.RegisterDecapServiceServer(server, m.decapService)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *DecapModule) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the module.
 func (m *DecapModule) Close() error {
 	if err := m.agent.Close(); err != nil {
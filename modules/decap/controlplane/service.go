@@ -11,6 +11,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/decap/controlplane/decappb/v1"
 )
 
@@ -128,10 +129,7 @@ func (m *DecapService) UpdateConfig(
 
 	cfg := &config{Prefixes: prefixes}
 	if err := m.updateConfig(name, cfg); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update module config %q: %v", name, err,
-		)
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
 	}
 
 	return &decappb.UpdateConfigResponse{}, nil
@@ -1,4 +1,7 @@
 #include <errno.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
 
 #include "common/lpm.h"
 #include "config.h"
@@ -10,6 +13,7 @@
 #include "lib/errors/errors.h"
 
 #include "controlplane/agent/agent.h"
+#include "controlplane/config/zone.h"
 #include "dataplane/config/zone.h"
 
 struct cp_module *
@@ -51,6 +55,70 @@ dscp_module_config_new(
 		return NULL;
 	}
 
+	config->egress_counter_id = counter_registry_register(
+		&config->cp_module.counter_registry,
+		DSCP_EGRESS_COUNTER,
+		DSCP_VALUES,
+		err
+	);
+	if (config->egress_counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err,
+			"failed to register counter '%s'",
+			DSCP_EGRESS_COUNTER
+		);
+		dscp_module_config_free(&config->cp_module);
+		return NULL;
+	}
+
+	config->ext_anomaly_counter_id = counter_registry_register(
+		&config->cp_module.counter_registry,
+		DSCP_EXT_ANOMALY_COUNTER,
+		DSCP_EXT_ANOMALY_COUNT,
+		err
+	);
+	if (config->ext_anomaly_counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err,
+			"failed to register counter '%s'",
+			DSCP_EXT_ANOMALY_COUNTER
+		);
+		dscp_module_config_free(&config->cp_module);
+		return NULL;
+	}
+
+	config->default_action_counter_id = counter_registry_register(
+		&config->cp_module.counter_registry,
+		DSCP_DEFAULT_ACTION_COUNTER,
+		1,
+		err
+	);
+	if (config->default_action_counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err,
+			"failed to register counter '%s'",
+			DSCP_DEFAULT_ACTION_COUNTER
+		);
+		dscp_module_config_free(&config->cp_module);
+		return NULL;
+	}
+
+	config->marking_counter_id = counter_registry_register(
+		&config->cp_module.counter_registry,
+		DSCP_MARKING_COUNTER,
+		DSCP_MARKING_COUNTER_SIZE,
+		err
+	);
+	if (config->marking_counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err,
+			"failed to register counter '%s'",
+			DSCP_MARKING_COUNTER
+		);
+		dscp_module_config_free(&config->cp_module);
+		return NULL;
+	}
+
 	return &config->cp_module;
 }
 
@@ -78,22 +146,89 @@ dscp_module_config_data_init(
 ) {
 	if (lpm_init(&config->lpm_v4, memory_context))
 		return -1;
-	if (lpm_init(&config->lpm_v6, memory_context)) {
-		lpm_free(&config->lpm_v4);
-		return -1;
-	}
+	if (lpm_init(&config->lpm_v6, memory_context))
+		goto error_lpm_v6;
+	if (lpm_init(&config->src_lpm_v4, memory_context))
+		goto error_src_lpm_v4;
+	if (lpm_init(&config->src_lpm_v6, memory_context))
+		goto error_src_lpm_v6;
 
 	// Initialize default DSCP config
 	config->dscp.flag = DSCP_MARK_NEVER;
 	config->dscp.mark = 0;
+	config->rule_count = 0;
+	config->rules = NULL;
+	config->fragment_policy = DSCP_FRAGMENT_MATCH;
+	config->ext_limits.max_headers = 0;
+	config->ext_limits.flags = 0;
+	config->default_action = DSCP_DEFAULT_PASS;
+	config->default_mark = 0;
+
+	config->rate_threshold = 0;
+	config->rate_burst = 0;
+	config->rate_bucket_count = 0;
+	config->rate_buckets = NULL;
+
+	config->flow_log_rate = 0;
+	config->flow_log_count = 0;
+	config->flow_logs = NULL;
+
+	config->egress_counter_id = (uint64_t)-1;
+	config->ext_anomaly_counter_id = (uint64_t)-1;
+	config->default_action_counter_id = (uint64_t)-1;
+	config->marking_counter_id = (uint64_t)-1;
 
 	return 0;
+
+error_src_lpm_v6:
+	lpm_free(&config->src_lpm_v4);
+error_src_lpm_v4:
+	lpm_free(&config->lpm_v6);
+error_lpm_v6:
+	lpm_free(&config->lpm_v4);
+	return -1;
 }
 
 void
 dscp_module_config_data_fini(struct dscp_module_config *config) {
 	lpm_free(&config->lpm_v4);
 	lpm_free(&config->lpm_v6);
+	lpm_free(&config->src_lpm_v4);
+	lpm_free(&config->src_lpm_v6);
+
+	struct dscp_rule *rules = ADDR_OF(&config->rules);
+	if (rules != NULL) {
+		memory_bfree(
+			&config->cp_module.memory_context,
+			rules,
+			sizeof(struct dscp_rule) * config->rule_count
+		);
+		config->rules = NULL;
+		config->rule_count = 0;
+	}
+
+	struct dscp_flow_log *flow_logs = ADDR_OF(&config->flow_logs);
+	if (flow_logs != NULL) {
+		memory_bfree(
+			&config->cp_module.memory_context,
+			flow_logs,
+			sizeof(struct dscp_flow_log) * config->flow_log_count
+		);
+		config->flow_logs = NULL;
+		config->flow_log_count = 0;
+	}
+
+	struct dscp_rate_bucket *rate_buckets = ADDR_OF(&config->rate_buckets);
+	if (rate_buckets != NULL) {
+		memory_bfree(
+			&config->cp_module.memory_context,
+			rate_buckets,
+			sizeof(struct dscp_rate_bucket) *
+				config->rate_bucket_count
+		);
+		config->rate_buckets = NULL;
+		config->rate_bucket_count = 0;
+	}
 }
 
 int
@@ -116,6 +251,26 @@ dscp_module_config_add_prefix_v6(
 	return lpm_insert(&config->lpm_v6, 16, addr_start, addr_end, 1);
 }
 
+int
+dscp_module_config_add_source_prefix_v4(
+	struct cp_module *module, uint8_t *addr_start, uint8_t *addr_end
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	return lpm_insert(&config->src_lpm_v4, 4, addr_start, addr_end, 1);
+}
+
+int
+dscp_module_config_add_source_prefix_v6(
+	struct cp_module *module, uint8_t *addr_start, uint8_t *addr_end
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	return lpm_insert(&config->src_lpm_v6, 16, addr_start, addr_end, 1);
+}
+
 int
 dscp_module_config_set_dscp_marking(
 	struct cp_module *module, uint8_t flag, uint8_t mark
@@ -128,3 +283,472 @@ dscp_module_config_set_dscp_marking(
 
 	return 0;
 }
+
+// Returns non-zero if the rule is one the dataplane can evaluate.
+static int
+dscp_rule_valid(const struct dscp_rule *rule) {
+	uint8_t max_prefix_len;
+	switch (rule->family) {
+	case 0:
+		max_prefix_len = 0;
+		break;
+	case 4:
+		max_prefix_len = 32;
+		break;
+	case 6:
+		max_prefix_len = 128;
+		break;
+	default:
+		return 0;
+	}
+
+	return rule->src_prefix_len <= max_prefix_len &&
+	       rule->dst_prefix_len <= max_prefix_len &&
+	       rule->port_min <= rule->port_max &&
+	       rule->dscp.flag <= DSCP_MARK_ALWAYS &&
+	       rule->dscp.mark < DSCP_VALUES;
+}
+
+int
+dscp_module_config_set_rules(
+	struct cp_module *module, const struct dscp_rule *rules, uint64_t count
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (count > DSCP_RULES_MAX) {
+		errno = EINVAL;
+		return -1;
+	}
+	for (uint64_t idx = 0; idx < count; idx++) {
+		if (!dscp_rule_valid(rules + idx)) {
+			errno = EINVAL;
+			return -1;
+		}
+	}
+
+	struct dscp_rule *new_rules = NULL;
+	if (count != 0) {
+		new_rules = memory_balloc(
+			&module->memory_context, sizeof(struct dscp_rule) * count
+		);
+		if (new_rules == NULL) {
+			errno = ENOMEM;
+			return -1;
+		}
+		memcpy(new_rules, rules, sizeof(struct dscp_rule) * count);
+		for (uint64_t idx = 0; idx < count; idx++) {
+			new_rules[idx].counter_id = (uint64_t)-1;
+		}
+	}
+
+	struct dscp_rule *old_rules = ADDR_OF(&config->rules);
+	if (old_rules != NULL) {
+		memory_bfree(
+			&module->memory_context,
+			old_rules,
+			sizeof(struct dscp_rule) * config->rule_count
+		);
+	}
+
+	config->rule_count = count;
+	SET_OFFSET_OF(&config->rules, new_rules);
+	return 0;
+}
+
+int
+dscp_module_config_set_rule_counter(
+	struct cp_module *module, uint64_t index, const char *name
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (index >= config->rule_count) {
+		errno = EINVAL;
+		return -1;
+	}
+
+	char counter_name[COUNTER_NAME_LEN];
+	int len = snprintf(
+		counter_name,
+		sizeof(counter_name),
+		"%s%s",
+		DSCP_RULE_COUNTER_PREFIX,
+		name
+	);
+	if (len < 0 || (size_t)len >= sizeof(counter_name)) {
+		errno = ENAMETOOLONG;
+		return -1;
+	}
+
+	uint64_t counter_id = counter_registry_register(
+		&module->counter_registry,
+		counter_name,
+		DSCP_MARKING_COUNTER_SIZE,
+		NULL
+	);
+	if (counter_id == (uint64_t)-1) {
+		errno = ENOMEM;
+		return -1;
+	}
+
+	ADDR_OF(&config->rules)[index].counter_id = counter_id;
+	return 0;
+}
+
+int
+dscp_module_config_set_fragment_policy(
+	struct cp_module *module, uint8_t policy
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (policy > DSCP_FRAGMENT_DROP) {
+		errno = EINVAL;
+		return -1;
+	}
+
+	config->fragment_policy = policy;
+	return 0;
+}
+
+int
+dscp_module_config_set_default_action(
+	struct cp_module *module, uint8_t action, uint8_t mark
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (action > DSCP_DEFAULT_DROP || mark >= DSCP_VALUES) {
+		errno = EINVAL;
+		return -1;
+	}
+
+	config->default_action = action;
+	config->default_mark = mark;
+	return 0;
+}
+
+int
+dscp_module_config_set_ext_limits(
+	struct cp_module *module, uint8_t max_headers, uint8_t flags
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (flags & ~(DSCP_EXT_SKIP_UNKNOWN | DSCP_EXT_DROP_ANOMALY)) {
+		errno = EINVAL;
+		return -1;
+	}
+
+	config->ext_limits.max_headers = max_headers;
+	config->ext_limits.flags = flags;
+	return 0;
+}
+
+int
+dscp_module_config_set_rate_threshold(
+	struct cp_module *module, uint32_t rate, uint32_t burst
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (rate == 0) {
+		config->rate_threshold = 0;
+		config->rate_burst = 0;
+		return 0;
+	}
+	if (burst == 0) {
+		burst = rate;
+	}
+
+	if (config->rate_buckets == NULL) {
+		struct agent *agent = ADDR_OF(&module->agent);
+		struct dp_config *dp_config = ADDR_OF(&agent->dp_config);
+		uint64_t count = dp_config->worker_count;
+
+		struct dscp_rate_bucket *rate_buckets = memory_balloc(
+			&module->memory_context,
+			sizeof(struct dscp_rate_bucket) * count
+		);
+		if (rate_buckets == NULL) {
+			errno = ENOMEM;
+			return -1;
+		}
+		memset(rate_buckets, 0, sizeof(struct dscp_rate_bucket) * count);
+
+		config->rate_bucket_count = count;
+		SET_OFFSET_OF(&config->rate_buckets, rate_buckets);
+	}
+
+	// Buckets start full, so the traffic of a new config is in profile
+	// up to the burst.
+	struct dscp_rate_bucket *rate_buckets = ADDR_OF(&config->rate_buckets);
+	for (uint64_t idx = 0; idx < config->rate_bucket_count; idx++) {
+		rate_buckets[idx].tokens =
+			(uint64_t)burst * DSCP_RATE_PACKET_TOKENS;
+		rate_buckets[idx].refilled_at = 0;
+	}
+
+	config->rate_threshold = rate;
+	config->rate_burst = burst;
+	return 0;
+}
+
+int
+dscp_module_config_set_flow_log(struct cp_module *module, uint32_t rate) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	if (rate == 0) {
+		config->flow_log_rate = 0;
+		return 0;
+	}
+
+	if (config->flow_logs == NULL) {
+		struct agent *agent = ADDR_OF(&module->agent);
+		struct dp_config *dp_config = ADDR_OF(&agent->dp_config);
+		uint64_t count = dp_config->worker_count;
+
+		struct dscp_flow_log *flow_logs = memory_balloc(
+			&module->memory_context,
+			sizeof(struct dscp_flow_log) * count
+		);
+		if (flow_logs == NULL) {
+			errno = ENOMEM;
+			return -1;
+		}
+		memset(flow_logs, 0, sizeof(struct dscp_flow_log) * count);
+
+		config->flow_log_count = count;
+		SET_OFFSET_OF(&config->flow_logs, flow_logs);
+	}
+
+	config->flow_log_rate = rate;
+	return 0;
+}
+
+uint64_t
+dscp_module_config_flow_log_count(struct cp_module *module) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	return config->flow_log_count;
+}
+
+uint64_t
+dscp_module_config_flow_log_read(
+	struct cp_module *module,
+	uint64_t worker_idx,
+	uint64_t from_idx,
+	struct dscp_flow_record *records,
+	uint64_t capacity,
+	uint64_t *records_count
+) {
+	struct dscp_module_config *config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	*records_count = 0;
+	if (worker_idx >= config->flow_log_count) {
+		return from_idx;
+	}
+
+	struct dscp_flow_log *log = ADDR_OF(&config->flow_logs) + worker_idx;
+	uint64_t write_idx =
+		atomic_load_explicit(&log->write_idx, memory_order_acquire);
+	if (write_idx - from_idx > DSCP_FLOW_LOG_RECORDS) {
+		from_idx = write_idx - DSCP_FLOW_LOG_RECORDS;
+	}
+	uint64_t count = write_idx - from_idx;
+	if (count > capacity) {
+		count = capacity;
+	}
+
+	for (uint64_t idx = 0; idx < count; idx++) {
+		records[idx] = log->records
+				       [(from_idx + idx) &
+					(DSCP_FLOW_LOG_RECORDS - 1)];
+	}
+
+	// The worker may have wrapped around while the records were copied;
+	// drop the ones that could have been overwritten.
+	uint64_t last_idx =
+		atomic_load_explicit(&log->write_idx, memory_order_acquire);
+	uint64_t lost = 0;
+	if (last_idx - from_idx > DSCP_FLOW_LOG_RECORDS) {
+		lost = last_idx - from_idx - DSCP_FLOW_LOG_RECORDS;
+	}
+	if (lost >= count) {
+		return from_idx + lost;
+	}
+
+	memmove(records, records + lost, sizeof(*records) * (count - lost));
+	*records_count = count - lost;
+	return from_idx + count;
+}
+
+// Copy the ranges of the LPM matched by any value into ranges.
+static int
+dscp_effective_ranges_read(
+	const struct lpm *lpm,
+	uint8_t key_size,
+	struct dscp_effective_ranges *ranges
+) {
+	uint8_t from[16];
+	uint8_t to[16];
+	memset(from, 0x00, sizeof(from));
+	memset(to, 0xff, sizeof(to));
+
+	uint64_t capacity = 0;
+	struct lpm_iter it;
+	lpm_iter_init(&it, lpm, key_size, from, to);
+	while (lpm_iter_next(&it)) {
+		if (it.cur_value == LPM_VALUE_INVALID) {
+			continue;
+		}
+
+		if (ranges->count == capacity) {
+			capacity = capacity == 0 ? 16 : capacity * 2;
+			struct dscp_effective_range *new_ranges = realloc(
+				ranges->ranges,
+				sizeof(struct dscp_effective_range) * capacity
+			);
+			if (new_ranges == NULL) {
+				errno = ENOMEM;
+				return -1;
+			}
+			ranges->ranges = new_ranges;
+		}
+
+		struct dscp_effective_range *range =
+			ranges->ranges + ranges->count++;
+		memset(range, 0, sizeof(*range));
+		memcpy(range->from, it.cur_from, key_size);
+		memcpy(range->to, it.cur_to, key_size);
+	}
+
+	return 0;
+}
+
+// Copy the marking rules of the module config with the names of their
+// counters.
+static int
+dscp_effective_rules_read(
+	struct dscp_module_config *module_config,
+	struct dscp_effective_config *config
+) {
+	if (module_config->rule_count == 0) {
+		return 0;
+	}
+
+	config->rules =
+		calloc(module_config->rule_count, sizeof(struct dscp_rule));
+	config->rule_names = calloc(
+		module_config->rule_count, DSCP_EFFECTIVE_RULE_NAME_LEN
+	);
+	if (config->rules == NULL || config->rule_names == NULL) {
+		errno = ENOMEM;
+		return -1;
+	}
+	config->rule_count = module_config->rule_count;
+
+	struct counter_registry *registry =
+		&module_config->cp_module.counter_registry;
+	struct counter *counters = ADDR_OF(&registry->names);
+	struct dscp_rule *rules = ADDR_OF(&module_config->rules);
+	for (uint64_t idx = 0; idx < config->rule_count; idx++) {
+		config->rules[idx] = rules[idx];
+
+		uint64_t counter_id = rules[idx].counter_id;
+		if (counter_id >= registry->count) {
+			continue;
+		}
+		const char *name = counters[counter_id].name;
+		size_t prefix_len = strlen(DSCP_RULE_COUNTER_PREFIX);
+		if (strncmp(name, DSCP_RULE_COUNTER_PREFIX, prefix_len) != 0) {
+			continue;
+		}
+		char *rule_name =
+			config->rule_names + idx * DSCP_EFFECTIVE_RULE_NAME_LEN;
+		strtcpy(rule_name,
+			name + prefix_len,
+			DSCP_EFFECTIVE_RULE_NAME_LEN);
+	}
+
+	return 0;
+}
+
+int
+dscp_effective_config_read(
+	struct agent *agent,
+	const char *name,
+	struct dscp_effective_config *config
+) {
+	memset(config, 0, sizeof(*config));
+
+	struct cp_config *cp_config = ADDR_OF(&agent->cp_config);
+	cp_config_lock(cp_config);
+
+	// The module of the active generation may be freed by the next
+	// update, so it is only read under the lock.
+	struct cp_config_gen *config_gen = ADDR_OF(&cp_config->cp_config_gen);
+	struct cp_module *module =
+		cp_config_gen_lookup_module(config_gen, "dscp", name);
+	if (module == NULL) {
+		errno = ENOENT;
+		goto error_unlock;
+	}
+	struct dscp_module_config *module_config =
+		container_of(module, struct dscp_module_config, cp_module);
+
+	config->config_gen = config_gen->gen;
+	config->module_gen = module->gen;
+
+	if (dscp_effective_ranges_read(
+		    &module_config->lpm_v4, 4, &config->prefixes_v4
+	    ) ||
+	    dscp_effective_ranges_read(
+		    &module_config->lpm_v6, 16, &config->prefixes_v6
+	    ) ||
+	    dscp_effective_ranges_read(
+		    &module_config->src_lpm_v4, 4, &config->source_prefixes_v4
+	    ) ||
+	    dscp_effective_ranges_read(
+		    &module_config->src_lpm_v6, 16, &config->source_prefixes_v6
+	    )) {
+		goto error_unlock;
+	}
+
+	if (dscp_effective_rules_read(module_config, config)) {
+		goto error_unlock;
+	}
+
+	config->flag = module_config->dscp.flag;
+	config->mark = module_config->dscp.mark;
+	config->fragment_policy = module_config->fragment_policy;
+	config->default_action = module_config->default_action;
+	config->default_mark = module_config->default_mark;
+	config->ext_max_headers = module_config->ext_limits.max_headers;
+	config->ext_flags = module_config->ext_limits.flags;
+	config->rate_threshold = module_config->rate_threshold;
+	config->rate_burst = module_config->rate_burst;
+	config->flow_log_rate = module_config->flow_log_rate;
+
+	cp_config_unlock(cp_config);
+	return 0;
+
+error_unlock:
+	cp_config_unlock(cp_config);
+	return -1;
+}
+
+void
+dscp_effective_config_free(struct dscp_effective_config *config) {
+	free(config->prefixes_v4.ranges);
+	free(config->prefixes_v6.ranges);
+	free(config->source_prefixes_v4.ranges);
+	free(config->source_prefixes_v6.ranges);
+	free(config->rules);
+	free(config->rule_names);
+	memset(config, 0, sizeof(*config));
+}
@@ -8,6 +8,8 @@ struct agent;
 struct cp_module;
 struct memory_context;
 struct dscp_module_config;
+struct dscp_flow_record;
+struct dscp_rule;
 
 // Create a new configuration for the DSCP module
 struct cp_module *
@@ -38,8 +40,156 @@ dscp_module_config_add_prefix_v6(
 	struct cp_module *module, uint8_t *addr_start, uint8_t *addr_end
 );
 
+// Add IPv4 prefix matched against the source address to the DSCP module
+// configuration
+int
+dscp_module_config_add_source_prefix_v4(
+	struct cp_module *module, uint8_t *addr_start, uint8_t *addr_end
+);
+
+// Add IPv6 prefix matched against the source address to the DSCP module
+// configuration
+int
+dscp_module_config_add_source_prefix_v6(
+	struct cp_module *module, uint8_t *addr_start, uint8_t *addr_end
+);
+
 // Set DSCP marking options for the module
 int
 dscp_module_config_set_dscp_marking(
 	struct cp_module *module, uint8_t flag, uint8_t mark
 );
+
+// Replace the marking rules of the module configuration with count rules,
+// at most DSCP_RULES_MAX, evaluated in the given order.
+int
+dscp_module_config_set_rules(
+	struct cp_module *module, const struct dscp_rule *rules, uint64_t count
+);
+
+// Register the counter of the marking rule at index under the rule name,
+// so that the counts of a rule survive the updates reordering the rules.
+//
+// Rules are not counted until their counter is registered. Must follow
+// dscp_module_config_set_rules.
+int
+dscp_module_config_set_rule_counter(
+	struct cp_module *module, uint64_t index, const char *name
+);
+
+// Set handling of non-initial fragments, one of enum dscp_fragment_policy.
+int
+dscp_module_config_set_fragment_policy(
+	struct cp_module *module, uint8_t policy
+);
+
+// Set the action taken on classified packets matching no prefix, one of
+// enum dscp_default_action. The mark is used by DSCP_DEFAULT_MARK only.
+int
+dscp_module_config_set_default_action(
+	struct cp_module *module, uint8_t action, uint8_t mark
+);
+
+// Limit the IPv6 extension header chain walked before classification to
+// max_headers headers, zero disables the walk. Flags are a combination of
+// DSCP_EXT_* flags.
+int
+dscp_module_config_set_ext_limits(
+	struct cp_module *module, uint8_t max_headers, uint8_t flags
+);
+
+// Mark only the matched packets exceeding rate packets per second per
+// worker, passing bursts of up to burst packets within the rate unmarked.
+// Zero rate marks every matched packet, zero burst allows one second worth
+// of packets.
+int
+dscp_module_config_set_rate_threshold(
+	struct cp_module *module, uint32_t rate, uint32_t burst
+);
+
+// Enable logging of matched flows, at most rate records per second per
+// worker. Zero rate disables logging.
+int
+dscp_module_config_set_flow_log(struct cp_module *module, uint32_t rate);
+
+// Number of per-worker flow logs allocated for the module.
+uint64_t
+dscp_module_config_flow_log_count(struct cp_module *module);
+
+// Copy flow records of the worker starting at from_idx into records.
+//
+// Returns the index to continue reading from. Records lost because the
+// worker overwrote them are skipped.
+uint64_t
+dscp_module_config_flow_log_read(
+	struct cp_module *module,
+	uint64_t worker_idx,
+	uint64_t from_idx,
+	struct dscp_flow_record *records,
+	uint64_t capacity,
+	uint64_t *records_count
+);
+
+// Range of addresses matched by one of the module LPMs; IPv4 addresses
+// occupy the first four bytes.
+struct dscp_effective_range {
+	uint8_t from[16];
+	uint8_t to[16];
+};
+
+// Ranges matched by one of the module LPMs, adjacent ranges merged.
+struct dscp_effective_ranges {
+	struct dscp_effective_range *ranges;
+	uint64_t count;
+};
+
+// Length of the rule names of struct dscp_effective_config, see
+// COUNTER_NAME_LEN.
+#define DSCP_EFFECTIVE_RULE_NAME_LEN 128
+
+// Copy of the module config of the active controlplane generation, as the
+// dataplane runs it.
+struct dscp_effective_config {
+	// Generation of the active controlplane config.
+	uint64_t config_gen;
+	// Generation the module config was published at.
+	uint64_t module_gen;
+
+	struct dscp_effective_ranges prefixes_v4;
+	struct dscp_effective_ranges prefixes_v6;
+	struct dscp_effective_ranges source_prefixes_v4;
+	struct dscp_effective_ranges source_prefixes_v6;
+
+	uint64_t rule_count;
+	// Copies of the marking rules in the order they are matched.
+	struct dscp_rule *rules;
+	// Names of the rule counters, DSCP_EFFECTIVE_RULE_NAME_LEN bytes per
+	// rule, empty for the rules not counted.
+	char *rule_names;
+
+	uint8_t flag;
+	uint8_t mark;
+	uint8_t fragment_policy;
+	uint8_t default_action;
+	uint8_t default_mark;
+	uint8_t ext_max_headers;
+	uint8_t ext_flags;
+	uint32_t rate_threshold;
+	uint32_t rate_burst;
+	uint32_t flow_log_rate;
+};
+
+// Decode the named module config of the active controlplane generation,
+// rather than the one the caller last built, into config.
+//
+// Fails with ENOENT if the generation has no such module. The config must
+// be released with dscp_effective_config_free, even on failure.
+int
+dscp_effective_config_read(
+	struct agent *agent,
+	const char *name,
+	struct dscp_effective_config *config
+);
+
+void
+dscp_effective_config_free(struct dscp_effective_config *config);
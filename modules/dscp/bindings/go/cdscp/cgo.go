@@ -5,13 +5,18 @@ package cdscp
 //
 //#include <stdlib.h>
 //#include "modules/dscp/api/controlplane.h"
+//#include "modules/dscp/dataplane/config.h"
 import "C"
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"syscall"
 	"unsafe"
 
 	"github.com/yanet-platform/yanet2/bindings/go/cerrors"
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
 	"github.com/yanet-platform/yanet2/controlplane/ffi"
 )
 
@@ -42,6 +47,11 @@ func (m *ModuleConfig) AsFFIModule() ffi.ModuleConfig {
 	return m.ptr
 }
 
+// MemoryUsage returns the shared memory held by the module config.
+func (m *ModuleConfig) MemoryUsage() uint64 {
+	return m.ptr.MemoryUsage()
+}
+
 func (m *ModuleConfig) Free() {
 	if ptr := m.asRawPtr(); ptr != nil {
 		C.dscp_module_config_free(ptr)
@@ -73,6 +83,30 @@ func (m *ModuleConfig) prefixAdd6(addrStart [16]byte, addrEnd [16]byte) error {
 	return nil
 }
 
+func (m *ModuleConfig) sourcePrefixAdd4(addrStart [4]byte, addrEnd [4]byte) error {
+	if rc := C.dscp_module_config_add_source_prefix_v4(
+		m.asRawPtr(),
+		(*C.uint8_t)(&addrStart[0]),
+		(*C.uint8_t)(&addrEnd[0]),
+	); rc != 0 {
+		return fmt.Errorf("failed to add v4 source prefix: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) sourcePrefixAdd6(addrStart [16]byte, addrEnd [16]byte) error {
+	if rc := C.dscp_module_config_add_source_prefix_v6(
+		m.asRawPtr(),
+		(*C.uint8_t)(&addrStart[0]),
+		(*C.uint8_t)(&addrEnd[0]),
+	); rc != 0 {
+		return fmt.Errorf("failed to add v6 source prefix: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
 func (m *ModuleConfig) SetDscpMarking(flag uint8, mark uint8) error {
 	if rc := C.dscp_module_config_set_dscp_marking(
 		m.asRawPtr(),
@@ -84,3 +118,320 @@ func (m *ModuleConfig) SetDscpMarking(flag uint8, mark uint8) error {
 
 	return nil
 }
+
+// SetRules replaces the marking rules of the module config, evaluated in
+// the given order.
+func (m *ModuleConfig) SetRules(rules []Rule) error {
+	cRules := make([]C.struct_dscp_rule, len(rules))
+	for idx, rule := range rules {
+		if err := ruleToC(rule, &cRules[idx]); err != nil {
+			return err
+		}
+	}
+
+	var ptr *C.struct_dscp_rule
+	if len(cRules) > 0 {
+		ptr = &cRules[0]
+	}
+	if rc := C.dscp_module_config_set_rules(
+		m.asRawPtr(),
+		ptr,
+		C.uint64_t(len(cRules)),
+	); rc != 0 {
+		return fmt.Errorf("failed to set rules: unknown error code=%d", rc)
+	}
+
+	for idx, rule := range rules {
+		if err := m.setRuleCounter(idx, rule.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) setRuleCounter(idx int, name string) error {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	if rc := C.dscp_module_config_set_rule_counter(
+		m.asRawPtr(),
+		C.uint64_t(idx),
+		cName,
+	); rc != 0 {
+		return fmt.Errorf("failed to register the counter of rule %q: unknown error code=%d", name, rc)
+	}
+
+	return nil
+}
+
+func ruleToC(rule Rule, out *C.struct_dscp_rule) error {
+	family := C.uint8_t(0)
+	for _, prefix := range []netip.Prefix{rule.Source, rule.Destination} {
+		if !prefix.IsValid() {
+			continue
+		}
+		prefixFamily := C.uint8_t(6)
+		if prefix.Addr().Is4() {
+			prefixFamily = 4
+		}
+		if family != 0 && family != prefixFamily {
+			return fmt.Errorf("rule prefixes %s and %s are of different families", rule.Source, rule.Destination)
+		}
+		family = prefixFamily
+	}
+
+	out.family = family
+	out.proto = C.uint8_t(rule.Proto)
+	out.port_min = C.uint16_t(rule.PortMin)
+	out.port_max = C.uint16_t(rule.PortMax)
+	out.dscp = C.struct_dscp_config{
+		flag: C.uint8_t(rule.Flag),
+		mark: C.uint8_t(rule.Mark),
+	}
+	out.traffic_class = C.uint8_t(rule.TrafficClass)
+	if rule.Source.IsValid() {
+		out.src_prefix_len = C.uint8_t(rule.Source.Bits())
+		copyRuleAddr(&out.src_addr, rule.Source.Masked().Addr())
+	}
+	if rule.Destination.IsValid() {
+		out.dst_prefix_len = C.uint8_t(rule.Destination.Bits())
+		copyRuleAddr(&out.dst_addr, rule.Destination.Masked().Addr())
+	}
+
+	return nil
+}
+
+// copyRuleAddr copies the address into a rule address, IPv4 addresses
+// occupying the first four bytes.
+func copyRuleAddr(out *[16]C.uint8_t, addr netip.Addr) {
+	for idx, b := range addr.AsSlice() {
+		out[idx] = C.uint8_t(b)
+	}
+}
+
+func (m *ModuleConfig) SetFragmentPolicy(policy uint8) error {
+	if rc := C.dscp_module_config_set_fragment_policy(
+		m.asRawPtr(),
+		C.uint8_t(policy),
+	); rc != 0 {
+		return fmt.Errorf("failed to set fragment policy: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) SetDefaultAction(action uint8, mark uint8) error {
+	if rc := C.dscp_module_config_set_default_action(
+		m.asRawPtr(),
+		C.uint8_t(action),
+		C.uint8_t(mark),
+	); rc != 0 {
+		return fmt.Errorf("failed to set default action: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) SetRateThreshold(rate uint32, burst uint32) error {
+	if rc := C.dscp_module_config_set_rate_threshold(
+		m.asRawPtr(),
+		C.uint32_t(rate),
+		C.uint32_t(burst),
+	); rc != 0 {
+		return fmt.Errorf("failed to set rate threshold: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) SetExtLimits(maxHeaders uint8, flags uint8) error {
+	if rc := C.dscp_module_config_set_ext_limits(
+		m.asRawPtr(),
+		C.uint8_t(maxHeaders),
+		C.uint8_t(flags),
+	); rc != 0 {
+		return fmt.Errorf("failed to set extension header limits: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+func (m *ModuleConfig) SetFlowLog(rate uint32) error {
+	if rc := C.dscp_module_config_set_flow_log(
+		m.asRawPtr(),
+		C.uint32_t(rate),
+	); rc != 0 {
+		return fmt.Errorf("failed to set flow log: unknown error code=%d", rc)
+	}
+
+	return nil
+}
+
+// FlowLogWorkers returns the number of per-worker flow logs allocated for
+// the module config.
+func (m *ModuleConfig) FlowLogWorkers() uint64 {
+	return uint64(C.dscp_module_config_flow_log_count(m.asRawPtr()))
+}
+
+// ReadFlows returns flow records of the given worker starting at fromIdx,
+// and the index to continue reading from.
+func (m *ModuleConfig) ReadFlows(workerIdx uint64, fromIdx uint64) ([]FlowRecord, uint64) {
+	records := make([]C.struct_dscp_flow_record, C.DSCP_FLOW_LOG_RECORDS)
+	count := C.uint64_t(0)
+
+	nextIdx := C.dscp_module_config_flow_log_read(
+		m.asRawPtr(),
+		C.uint64_t(workerIdx),
+		C.uint64_t(fromIdx),
+		&records[0],
+		C.uint64_t(len(records)),
+		&count,
+	)
+
+	out := make([]FlowRecord, 0, int(count))
+	for idx := range records[:count] {
+		out = append(out, flowRecordFromC(&records[idx]))
+	}
+
+	return out, uint64(nextIdx)
+}
+
+func flowRecordFromC(record *C.struct_dscp_flow_record) FlowRecord {
+	src := *(*[16]byte)(unsafe.Pointer(&record.src_addr[0]))
+	dst := *(*[16]byte)(unsafe.Pointer(&record.dst_addr[0]))
+
+	out := FlowRecord{
+		Timestamp:       uint64(record.timestamp),
+		SourcePort:      uint16(record.src_port),
+		DestinationPort: uint16(record.dst_port),
+		Proto:           uint8(record.proto),
+		OriginalDSCP:    uint8(record.original_dscp),
+		Remarked:        record.remarked != 0,
+	}
+	if record.family == 4 {
+		out.Source = netip.AddrFrom4([4]byte(src[:4]))
+		out.Destination = netip.AddrFrom4([4]byte(dst[:4]))
+	} else {
+		out.Source = netip.AddrFrom16(src)
+		out.Destination = netip.AddrFrom16(dst)
+	}
+
+	return out
+}
+
+// ErrNotActive is returned for a module config the active controlplane
+// generation does not have.
+var ErrNotActive = errors.New("module config is not active")
+
+// ReadEffectiveConfig decodes the named module config of the active
+// controlplane generation, which is the one the dataplane runs whatever
+// the controlplane last built.
+func ReadEffectiveConfig(agent *ffi.Agent, name string) (*EffectiveConfig, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	var config C.struct_dscp_effective_config
+	defer C.dscp_effective_config_free(&config)
+
+	rc, err := C.dscp_effective_config_read(
+		(*C.struct_agent)(agent.AsRawPtr()),
+		cName,
+		&config,
+	)
+	if rc != 0 {
+		if errors.Is(err, syscall.ENOENT) {
+			return nil, ErrNotActive
+		}
+		return nil, fmt.Errorf("failed to read effective config: %w", err)
+	}
+
+	out := &EffectiveConfig{
+		Generation:       uint64(config.config_gen),
+		ModuleGeneration: uint64(config.module_gen),
+		Prefixes: append(
+			rangesFromC(config.prefixes_v4, true),
+			rangesFromC(config.prefixes_v6, false)...,
+		),
+		SourcePrefixes: append(
+			rangesFromC(config.source_prefixes_v4, true),
+			rangesFromC(config.source_prefixes_v6, false)...,
+		),
+		Rules:          make([]Rule, 0, int(config.rule_count)),
+		Flag:           uint8(config.flag),
+		Mark:           uint8(config.mark),
+		FragmentPolicy: uint8(config.fragment_policy),
+		DefaultAction:  uint8(config.default_action),
+		DefaultMark:    uint8(config.default_mark),
+		RateThreshold:  uint32(config.rate_threshold),
+		RateBurst:      uint32(config.rate_burst),
+		ExtMaxHeaders:  uint8(config.ext_max_headers),
+		ExtFlags:       uint8(config.ext_flags),
+		FlowLogRate:    uint32(config.flow_log_rate),
+	}
+	if config.rule_count > 0 {
+		rules := unsafe.Slice(config.rules, config.rule_count)
+		names := unsafe.Slice(config.rule_names, config.rule_count*C.DSCP_EFFECTIVE_RULE_NAME_LEN)
+		for idx := range rules {
+			name := C.GoString(&names[idx*C.DSCP_EFFECTIVE_RULE_NAME_LEN])
+			out.Rules = append(out.Rules, ruleFromC(&rules[idx], name))
+		}
+	}
+
+	return out, nil
+}
+
+// rangesFromC returns the prefixes covering the address ranges, in the
+// order of the ranges.
+func rangesFromC(ranges C.struct_dscp_effective_ranges, is4 bool) []netip.Prefix {
+	out := []netip.Prefix{}
+	if ranges.count == 0 {
+		return out
+	}
+
+	for _, r := range unsafe.Slice(ranges.ranges, ranges.count) {
+		from := rangeAddr(&r.from, is4)
+		to := rangeAddr(&r.to, is4)
+		prefixes, ok := xnetip.RangeToCIDRs(from, to)
+		if !ok {
+			continue
+		}
+		out = append(out, prefixes...)
+	}
+
+	return out
+}
+
+func rangeAddr(addr *[16]C.uint8_t, is4 bool) netip.Addr {
+	bytes := *(*[16]byte)(unsafe.Pointer(&addr[0]))
+	if is4 {
+		return netip.AddrFrom4([4]byte(bytes[:4]))
+	}
+	return netip.AddrFrom16(bytes)
+}
+
+// ruleFromC decodes a rule published by SetRules, see Rule.Effective.
+func ruleFromC(rule *C.struct_dscp_rule, name string) Rule {
+	out := Rule{
+		Name:         name,
+		Proto:        uint8(rule.proto),
+		PortMin:      uint16(rule.port_min),
+		PortMax:      uint16(rule.port_max),
+		Flag:         uint8(rule.dscp.flag),
+		Mark:         uint8(rule.dscp.mark),
+		TrafficClass: uint8(rule.traffic_class),
+	}
+	if rule.family == 0 {
+		return out
+	}
+
+	is4 := rule.family == 4
+	if rule.src_prefix_len != 0 {
+		out.Source = netip.PrefixFrom(rangeAddr(&rule.src_addr, is4), int(rule.src_prefix_len))
+	}
+	if rule.dst_prefix_len != 0 || rule.src_prefix_len == 0 {
+		out.Destination = netip.PrefixFrom(rangeAddr(&rule.dst_addr, is4), int(rule.dst_prefix_len))
+	}
+
+	return out
+}
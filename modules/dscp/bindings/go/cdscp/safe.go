@@ -7,6 +7,101 @@ import (
 	"github.com/yanet-platform/yanet2/common/go/xnetip"
 )
 
+// FlowRecord is the tuple of a packet matched by the module prefixes, as
+// recorded by a dataplane worker.
+type FlowRecord struct {
+	// Timestamp is the worker time in nanoseconds.
+	Timestamp       uint64
+	Source          netip.Addr
+	Destination     netip.Addr
+	SourcePort      uint16
+	DestinationPort uint16
+	Proto           uint8
+	// OriginalDSCP is the DSCP value before marking.
+	OriginalDSCP uint8
+	// Remarked reports whether the DSCP value was rewritten.
+	Remarked bool
+}
+
+// Rule is a marking rule matched before the module prefixes.
+type Rule struct {
+	// Name keys the counter of the rule, so that it keeps counting while
+	// the rules around it change.
+	Name string
+	// Source and Destination are the prefixes the packet addresses are
+	// matched against. An invalid prefix matches any address, while the
+	// valid ones of a rule must be of the same family.
+	Source      netip.Prefix
+	Destination netip.Prefix
+	// Proto is the IP protocol number of the transport header, zero
+	// matches any.
+	Proto uint8
+	// PortMin and PortMax bound the destination port, inclusive.
+	PortMin uint16
+	PortMax uint16
+	// Flag and Mark are the marking of the matched packets, like the ones
+	// of SetDscpMarking.
+	Flag uint8
+	Mark uint8
+	// TrafficClass is the internal class set on the matched packets
+	// whatever their marking, zero to leave it as it is.
+	TrafficClass uint8
+}
+
+// Effective returns the rule as the dataplane runs it, matching the same
+// packets: its prefixes masked and its /0 prefixes dropped, but for the
+// family a rule of /0 prefixes only is restricted to, which the
+// destination keeps.
+func (m Rule) Effective() Rule {
+	out := m
+	out.Source = effectiveRulePrefix(m.Source)
+	out.Destination = effectiveRulePrefix(m.Destination)
+	if !out.Source.IsValid() && !out.Destination.IsValid() {
+		switch {
+		case m.Destination.IsValid():
+			out.Destination = m.Destination.Masked()
+		case m.Source.IsValid():
+			out.Destination = m.Source.Masked()
+		}
+	}
+	return out
+}
+
+func effectiveRulePrefix(prefix netip.Prefix) netip.Prefix {
+	if !prefix.IsValid() || prefix.Bits() == 0 {
+		return netip.Prefix{}
+	}
+	return prefix.Masked()
+}
+
+// EffectiveConfig is a module config as the dataplane runs it, decoded
+// from the active controlplane generation.
+type EffectiveConfig struct {
+	// Generation is the generation of the active controlplane config.
+	Generation uint64
+	// ModuleGeneration is the generation the module config was published
+	// at.
+	ModuleGeneration uint64
+	// Prefixes and SourcePrefixes are the smallest sets of prefixes
+	// covering the addresses matched, sorted.
+	Prefixes       []netip.Prefix
+	SourcePrefixes []netip.Prefix
+	// Rules are the marking rules in the order they are matched, see
+	// Rule.Effective. A rule is named after its counter, unnamed if not
+	// counted.
+	Rules          []Rule
+	Flag           uint8
+	Mark           uint8
+	FragmentPolicy uint8
+	DefaultAction  uint8
+	DefaultMark    uint8
+	RateThreshold  uint32
+	RateBurst      uint32
+	ExtMaxHeaders  uint8
+	ExtFlags       uint8
+	FlowLogRate    uint32
+}
+
 func (m *ModuleConfig) PrefixAdd(prefix netip.Prefix) error {
 	addrStart := prefix.Addr()
 	addrEnd := xnetip.LastAddr(prefix)
@@ -20,3 +115,19 @@ func (m *ModuleConfig) PrefixAdd(prefix netip.Prefix) error {
 
 	return fmt.Errorf("unsupported prefix: must be either IPv4 or IPv6")
 }
+
+// SourcePrefixAdd adds a prefix matched against the source address of
+// packets.
+func (m *ModuleConfig) SourcePrefixAdd(prefix netip.Prefix) error {
+	addrStart := prefix.Addr()
+	addrEnd := xnetip.LastAddr(prefix)
+
+	if addrStart.Is4() {
+		return m.sourcePrefixAdd4(addrStart.As4(), addrEnd.As4())
+	}
+	if addrStart.Is6() {
+		return m.sourcePrefixAdd6(addrStart.As16(), addrEnd.As16())
+	}
+
+	return fmt.Errorf("unsupported prefix: must be either IPv4 or IPv6")
+}
@@ -6,8 +6,9 @@ pub fn main() -> Result<(), Box<dyn Error>> {
     tonic_build::configure()
         .emit_rerun_if_changed(false)
         .build_server(false)
+        .extern_path(".common.commonpb.v1", "::commonpb::pb")
         .message_attribute(".", "#[derive(Serialize)]")
-        .compile_protos(&["dscppb/v1/dscp.proto"], &["../controlplane"])?;
+        .compile_protos(&["dscppb/v1/dscp.proto"], &["../../..", "../controlplane"])?;
 
     Ok(())
 }
@@ -1,8 +1,13 @@
 use clap::{ArgAction, CommandFactory, Parser};
 use clap_complete::CompleteEnv;
 use dscppb::{
-    AddPrefixesRequest, DscpConfig, RemovePrefixesRequest, SetDscpMarkingRequest, ShowConfigRequest,
-    ShowConfigResponse, dscp_service_client::DscpServiceClient,
+    AddPrefixesRequest, CloneConfigRequest, CloneTransforms, Config, DefaultAction, DefaultActionConfig,
+    DiffConfigRequest, DiffConfigResponse, DscpConfig, ExtAnomaly, ExtHeaderLimits, FlowLogConfig, FragmentPolicy,
+    AddRuleRequest, DeleteRuleRequest, GetFingerprintRequest, GetFingerprintResponse, ListRulesRequest,
+    ListRulesResponse, MarkingCounts, PortRange, PrefixDirection, RateThreshold, RemarkPolicy, RemovePrefixesRequest, Rule, SetDefaultActionRequest, SetDscpMarkingRequest,
+    SetExtHeaderLimitsRequest, SetFlowLogRequest, SetFragmentPolicyRequest, SetRateThresholdRequest,
+    SetRuleGroupEnabledRequest, SetRuleGroupMetadataRequest, SetStageRequest, Stage,
+    ShowConfigRequest, ShowConfigResponse, ShowEffectiveConfigRequest, ShowEffectiveConfigResponse, ShowStatsRequest, ShowStatsResponse, dscp_service_client::DscpServiceClient,
 };
 use netip::{Contiguous, IpNetwork};
 use ptree::TreeBuilder;
@@ -41,11 +46,56 @@ pub struct Cmd {
 
 #[derive(Debug, Clone, Parser)]
 pub enum ModeCmd {
-    List,
+    List(ListConfigsCmd),
     Show(ShowConfigCmd),
+    ShowEffective(ShowEffectiveConfigCmd),
     PrefixAdd(AddPrefixesCmd),
     PrefixRemove(RemovePrefixesCmd),
     SetMarking(SetDscpMarkingCmd),
+    SetRateThreshold(SetRateThresholdCmd),
+    SetFlowLog(SetFlowLogCmd),
+    SetFragmentPolicy(SetFragmentPolicyCmd),
+    SetDefaultAction(SetDefaultActionCmd),
+    SetExtLimits(SetExtLimitsCmd),
+    Diff(DiffConfigCmd),
+    Stats(ShowStatsCmd),
+    CloneConfig(CloneConfigCmd),
+    GroupEnable(RuleGroupCmd),
+    GroupDisable(RuleGroupCmd),
+    GroupMetadata(RuleGroupMetadataCmd),
+    SetStage(SetStageCmd),
+    RuleAdd(RuleAddCmd),
+    RuleDelete(RuleDeleteCmd),
+    Rules(RulesCmd),
+    Fingerprint(FingerprintCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ListConfigsCmd {
+    /// List only the configs with a rule group carrying this label, as
+    /// `key=value`; repeat to require several labels.
+    #[arg(long = "label", short = 'l', value_parser = parse_label)]
+    pub labels: Vec<(String, String)>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FingerprintCmd {
+    /// DSCP module name to fingerprint; every config of the instance when
+    /// unset.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: Option<String>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ShowStatsCmd {
+    /// DSCP module name to operate on; all the configs with a rule group
+    /// matching the labels when unset.
+    #[arg(long = "name", short = 'n', required_unless_present = "labels")]
+    pub config_name: Option<String>,
+    /// Sum the statistics of the configs with a rule group carrying this
+    /// label, as `key=value`; repeat to require several labels.
+    #[arg(long = "label", short = 'l', value_parser = parse_label)]
+    pub labels: Vec<(String, String)>,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -53,6 +103,17 @@ pub struct ShowConfigCmd {
     /// DSCP module name to operate on.
     #[arg(long = "name", short = 'n')]
     pub config_name: String,
+    /// Show only the rule groups carrying this label, as `key=value`;
+    /// repeat to require several labels.
+    #[arg(long = "label", short = 'l', value_parser = parse_label)]
+    pub labels: Vec<(String, String)>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ShowEffectiveConfigCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -63,6 +124,12 @@ pub struct AddPrefixesCmd {
     /// Prefix to be added to the input filter of the DSCP module.
     #[arg(long, short, required = true)]
     pub prefix: Vec<Contiguous<IpNetwork>>,
+    /// Packet address the prefixes are matched against.
+    #[arg(long, default_value = "dst")]
+    pub direction: PrefixDirectionArg,
+    /// Rule group the prefixes are added to; created enabled if missing.
+    #[arg(long, short)]
+    pub group: Option<String>,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -73,6 +140,184 @@ pub struct RemovePrefixesCmd {
     /// Prefix to be removed from the input filter of the DSCP module.
     #[arg(long, short, required = true)]
     pub prefix: Vec<Contiguous<IpNetwork>>,
+    /// Packet address the prefixes are removed from being matched against.
+    #[arg(long, default_value = "dst")]
+    pub direction: PrefixDirectionArg,
+    /// Rule group the prefixes are removed from.
+    #[arg(long, short)]
+    pub group: Option<String>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RuleGroupCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Rule group to enable or disable.
+    #[arg(long, short)]
+    pub group: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RuleGroupMetadataCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Rule group to describe.
+    #[arg(long, short)]
+    pub group: String,
+    /// Free-form description of the group.
+    #[arg(long, short, default_value = "")]
+    pub description: String,
+    /// Label of the group, as `key=value`; repeat for several labels. The
+    /// labels replace the current ones.
+    #[arg(long = "label", short = 'l', value_parser = parse_label)]
+    pub labels: Vec<(String, String)>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct SetStageCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Rule group to pin to the stage; the stage the config runs at when
+    /// unset.
+    #[arg(long, short)]
+    pub group: Option<String>,
+    /// Pipeline stage.
+    #[arg(long)]
+    pub stage: StageArg,
+}
+
+/// Pipeline stage of the DSCP module.
+#[derive(Debug, Clone, Copy, clap::ValueEnum)]
+pub enum StageArg {
+    /// No stage: undeclared for a config, every stage for a rule group.
+    Any,
+    /// Ingress classification, before routing and policing.
+    Ingress,
+    /// Egress marking, after routing and policing.
+    Egress,
+}
+
+impl From<StageArg> for Stage {
+    fn from(stage: StageArg) -> Self {
+        match stage {
+            StageArg::Any => Stage::Any,
+            StageArg::Ingress => Stage::Ingress,
+            StageArg::Egress => Stage::Egress,
+        }
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RuleAddCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Rule name; a rule of the same name is replaced.
+    #[arg(long, short)]
+    pub rule: String,
+    /// Rules are matched by ascending priority, then by name.
+    #[arg(long, default_value_t = 0)]
+    pub priority: u32,
+    /// Prefix matched against the source address; any address when unset.
+    #[arg(long)]
+    pub src: Option<Contiguous<IpNetwork>>,
+    /// Prefix matched against the destination address; any address when
+    /// unset.
+    #[arg(long)]
+    pub dst: Option<Contiguous<IpNetwork>>,
+    /// IP protocol number of the transport header; any protocol when unset.
+    #[arg(long)]
+    pub proto: Option<u32>,
+    /// Destination ports, as `port` or `from-to`; any port when unset.
+    #[arg(long, value_parser = parse_port_range)]
+    pub ports: Option<(u32, u32)>,
+    /// DSCP mark value (0-63).
+    #[arg(long)]
+    pub mark: u32,
+    /// When the rule rewrites the DSCP value of the matched packets.
+    #[arg(long, default_value = "always")]
+    pub remark: RemarkPolicyArg,
+    /// Internal traffic class (1-255) set on the matched packets for the
+    /// downstream modules, whatever the remark policy; unset when zero.
+    #[arg(long, default_value_t = 0)]
+    pub traffic_class: u32,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RuleDeleteCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Rule to delete.
+    #[arg(long, short)]
+    pub rule: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RulesCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+/// When a marking rule rewrites the DSCP value.
+#[derive(Debug, Clone, Copy, clap::ValueEnum)]
+pub enum RemarkPolicyArg {
+    /// Always rewrite.
+    Always,
+    /// Only rewrite the default DSCP value 0.
+    OnlyDefault,
+    /// Never rewrite, exempting the matched packets from the prefixes.
+    Never,
+}
+
+impl From<RemarkPolicyArg> for RemarkPolicy {
+    fn from(policy: RemarkPolicyArg) -> Self {
+        match policy {
+            RemarkPolicyArg::Always => RemarkPolicy::Always,
+            RemarkPolicyArg::OnlyDefault => RemarkPolicy::OnlyDefault,
+            RemarkPolicyArg::Never => RemarkPolicy::Never,
+        }
+    }
+}
+
+fn parse_port_range(s: &str) -> Result<(u32, u32), String> {
+    let parse = |port: &str| port.parse::<u16>().map(u32::from).map_err(|err| format!("invalid port {port:?}: {err}"));
+    match s.split_once('-') {
+        Some((from, to)) => Ok((parse(from)?, parse(to)?)),
+        None => parse(s).map(|port| (port, port)),
+    }
+}
+
+fn parse_label(s: &str) -> Result<(String, String), String> {
+    match s.split_once('=') {
+        Some((key, value)) if !key.is_empty() => Ok((key.to_string(), value.to_string())),
+        _ => Err("expected key=value".to_string()),
+    }
+}
+
+/// Packet address matched against the module prefixes.
+#[derive(Debug, Clone, Copy, clap::ValueEnum)]
+pub enum PrefixDirectionArg {
+    /// Destination address.
+    Dst,
+    /// Source address.
+    Src,
+    /// Either the source or the destination address.
+    Either,
+}
+
+impl From<PrefixDirectionArg> for PrefixDirection {
+    fn from(direction: PrefixDirectionArg) -> Self {
+        match direction {
+            PrefixDirectionArg::Dst => PrefixDirection::Destination,
+            PrefixDirectionArg::Src => PrefixDirection::Source,
+            PrefixDirectionArg::Either => PrefixDirection::Either,
+        }
+    }
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -89,6 +334,233 @@ pub struct SetDscpMarkingCmd {
     pub mark: u32,
 }
 
+#[derive(Debug, Clone, Parser)]
+pub struct SetFlowLogCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Maximum number of matched flows logged per second by each worker,
+    /// 0 - disabled
+    #[arg(long)]
+    pub rate_limit: u32,
+}
+
+/// Handling of non-initial fragments.
+#[derive(Debug, Clone, Copy, clap::ValueEnum)]
+pub enum FragmentPolicyArg {
+    /// Match and mark by the prefixes and the marking rules without
+    /// destination ports, skipping the rules with ports.
+    Match,
+    /// Pass without marking.
+    Pass,
+    /// Drop.
+    Drop,
+}
+
+impl From<FragmentPolicyArg> for FragmentPolicy {
+    fn from(policy: FragmentPolicyArg) -> Self {
+        match policy {
+            FragmentPolicyArg::Match => FragmentPolicy::Match,
+            FragmentPolicyArg::Pass => FragmentPolicy::Pass,
+            FragmentPolicyArg::Drop => FragmentPolicy::Drop,
+        }
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct SetFragmentPolicyCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Handling of non-initial fragments; initial fragments are always
+    /// matched by the module prefixes.
+    #[arg(long)]
+    pub policy: FragmentPolicyArg,
+}
+
+/// Handling of packets matching no prefix.
+#[derive(Debug, Clone, Copy, clap::ValueEnum)]
+pub enum DefaultActionArg {
+    /// Pass with the DSCP value unchanged.
+    Pass,
+    /// Mark with the default mark.
+    Mark,
+    /// Drop.
+    Drop,
+}
+
+impl From<DefaultActionArg> for DefaultAction {
+    fn from(action: DefaultActionArg) -> Self {
+        match action {
+            DefaultActionArg::Pass => DefaultAction::Pass,
+            DefaultActionArg::Mark => DefaultAction::Mark,
+            DefaultActionArg::Drop => DefaultAction::Drop,
+        }
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct SetDefaultActionCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Handling of packets matching no prefix; non-initial fragments and
+    /// packets with extension header anomalies passed unmarked are not
+    /// subject to it.
+    #[arg(long)]
+    pub action: DefaultActionArg,
+    /// DSCP value the mark action marks with (0-63).
+    #[arg(long, default_value_t = 0)]
+    pub mark: u32,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct SetRateThresholdCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Packets per second each worker passes unmarked; only the excess is
+    /// marked. Zero marks every matched packet.
+    #[arg(long)]
+    pub rate: u32,
+    /// Packets a worker passes unmarked at once; zero allows one second
+    /// worth of packets at the rate.
+    #[arg(long, default_value_t = 0)]
+    pub burst: u32,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct SetExtLimitsCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Maximum number of IPv6 extension headers walked before a packet is
+    /// classified (0-255); zero disables the walk.
+    #[arg(long)]
+    pub max_headers: u32,
+    /// Skip known extension headers the module cannot interpret instead of
+    /// treating them as an anomaly.
+    #[arg(long)]
+    pub skip_unknown: bool,
+    /// Drop anomalous packets instead of passing them without marking.
+    #[arg(long)]
+    pub drop_anomalies: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct DiffConfigCmd {
+    /// DSCP module name to operate on.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Proposed prefix of the input filter; the full proposed set is
+    /// compared with the applied one.
+    #[arg(long, short)]
+    pub prefix: Vec<Contiguous<IpNetwork>>,
+    /// Proposed source prefix of the input filter; the full proposed set
+    /// is compared with the applied one.
+    #[arg(long)]
+    pub source_prefix: Vec<Contiguous<IpNetwork>>,
+    /// Proposed DSCP marking flag; the marking is not compared when unset.
+    #[arg(long, requires = "mark")]
+    pub flag: Option<u32>,
+    /// Proposed DSCP mark value (0-63).
+    #[arg(long, requires = "flag")]
+    pub mark: Option<u32>,
+    /// Proposed flow log rate limit; flow logging is not compared when
+    /// unset.
+    #[arg(long)]
+    pub rate_limit: Option<u32>,
+    /// Proposed fragment policy; the policy is not compared when unset.
+    #[arg(long)]
+    pub fragment_policy: Option<FragmentPolicyArg>,
+    /// Proposed maximum number of IPv6 extension headers; the extension
+    /// header limits are not compared when unset.
+    #[arg(long)]
+    pub ext_max_headers: Option<u32>,
+    /// Proposed skipping of unknown extension headers.
+    #[arg(long, requires = "ext_max_headers")]
+    pub ext_skip_unknown: bool,
+    /// Proposed dropping of packets with extension header anomalies.
+    #[arg(long, requires = "ext_max_headers")]
+    pub ext_drop_anomalies: bool,
+    /// Proposed default action; the default action is not compared when
+    /// unset.
+    #[arg(long)]
+    pub default_action: Option<DefaultActionArg>,
+    /// Proposed default mark value (0-63).
+    #[arg(long, requires = "default_action", default_value_t = 0)]
+    pub default_mark: u32,
+    /// Proposed rate matched packets are marked above; the rate threshold
+    /// is not compared when unset.
+    #[arg(long)]
+    pub rate_threshold: Option<u32>,
+    /// Proposed burst of the rate threshold.
+    #[arg(long, requires = "rate_threshold", default_value_t = 0)]
+    pub rate_burst: u32,
+    /// Proposed stage; the stage is not compared when unset.
+    #[arg(long)]
+    pub stage: Option<StageArg>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct CloneConfigCmd {
+    /// DSCP module name to copy the configuration from.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// DSCP module name to copy the configuration to; its configuration is
+    /// replaced.
+    #[arg(long, short, required = true)]
+    pub target: Vec<String>,
+    /// Prefix added to the input filter of the copy.
+    #[arg(long)]
+    pub add_prefix: Vec<Contiguous<IpNetwork>>,
+    /// Prefix removed from the input filter of the copy.
+    #[arg(long)]
+    pub remove_prefix: Vec<Contiguous<IpNetwork>>,
+    /// Packet address the added and removed prefixes are matched against.
+    #[arg(long, default_value = "dst")]
+    pub direction: PrefixDirectionArg,
+    /// DSCP marking flag of the copy; the source marking is kept when unset.
+    #[arg(long, requires = "mark")]
+    pub flag: Option<u32>,
+    /// DSCP mark value of the copy (0-63).
+    #[arg(long, requires = "flag")]
+    pub mark: Option<u32>,
+    /// Flow log rate limit of the copy.
+    #[arg(long)]
+    pub rate_limit: Option<u32>,
+    /// Fragment policy of the copy.
+    #[arg(long)]
+    pub fragment_policy: Option<FragmentPolicyArg>,
+    /// Maximum number of IPv6 extension headers of the copy; the source
+    /// extension header limits are kept when unset.
+    #[arg(long)]
+    pub ext_max_headers: Option<u32>,
+    /// Skip unknown extension headers in the copy.
+    #[arg(long, requires = "ext_max_headers")]
+    pub ext_skip_unknown: bool,
+    /// Drop packets with extension header anomalies in the copy.
+    #[arg(long, requires = "ext_max_headers")]
+    pub ext_drop_anomalies: bool,
+    /// Default action of the copy; the source default action is kept when
+    /// unset.
+    #[arg(long)]
+    pub default_action: Option<DefaultActionArg>,
+    /// Default mark value of the copy (0-63).
+    #[arg(long, requires = "default_action", default_value_t = 0)]
+    pub default_mark: u32,
+    /// Rate matched packets of the copy are marked above; the source rate
+    /// threshold is kept when unset.
+    #[arg(long)]
+    pub rate_threshold: Option<u32>,
+    /// Burst of the rate threshold of the copy.
+    #[arg(long, requires = "rate_threshold", default_value_t = 0)]
+    pub rate_burst: u32,
+    /// Stage of the copy; the source stage is kept when unset.
+    #[arg(long)]
+    pub stage: Option<StageArg>,
+}
+
 /// The fully-qualified gRPC service name used in error messages.
 const SERVICE_NAME: &str = "modules.dscp.controlplane.dscppb.v1.DscpService";
 
@@ -108,11 +580,28 @@ async fn run(cmd: Cmd) -> Result<(), Error> {
     let mut service = DscpService::new(&cmd.connection).await?;
 
     match cmd.mode {
-        ModeCmd::List => service.list_configs().await,
+        ModeCmd::List(cmd) => service.list_configs(cmd).await,
         ModeCmd::Show(cmd) => service.show_config(cmd).await,
+        ModeCmd::ShowEffective(cmd) => service.show_effective_config(cmd).await,
         ModeCmd::PrefixAdd(cmd) => service.add_prefixes(cmd).await,
         ModeCmd::PrefixRemove(cmd) => service.remove_prefixes(cmd).await,
         ModeCmd::SetMarking(cmd) => service.set_dscp_marking(cmd).await,
+        ModeCmd::SetRateThreshold(cmd) => service.set_rate_threshold(cmd).await,
+        ModeCmd::SetFlowLog(cmd) => service.set_flow_log(cmd).await,
+        ModeCmd::SetFragmentPolicy(cmd) => service.set_fragment_policy(cmd).await,
+        ModeCmd::SetDefaultAction(cmd) => service.set_default_action(cmd).await,
+        ModeCmd::SetExtLimits(cmd) => service.set_ext_limits(cmd).await,
+        ModeCmd::Diff(cmd) => service.diff_config(cmd).await,
+        ModeCmd::Stats(cmd) => service.show_stats(cmd).await,
+        ModeCmd::CloneConfig(cmd) => service.clone_config(cmd).await,
+        ModeCmd::GroupEnable(cmd) => service.set_rule_group_enabled(cmd, true).await,
+        ModeCmd::GroupDisable(cmd) => service.set_rule_group_enabled(cmd, false).await,
+        ModeCmd::GroupMetadata(cmd) => service.set_rule_group_metadata(cmd).await,
+        ModeCmd::SetStage(cmd) => service.set_stage(cmd).await,
+        ModeCmd::RuleAdd(cmd) => service.add_rule(cmd).await,
+        ModeCmd::RuleDelete(cmd) => service.delete_rule(cmd).await,
+        ModeCmd::Rules(cmd) => service.list_rules(cmd).await,
+        ModeCmd::Fingerprint(cmd) => service.get_fingerprint(cmd).await,
     }
 }
 
@@ -132,8 +621,10 @@ impl DscpService {
         Ok(Self { service })
     }
 
-    pub async fn list_configs(&mut self) -> Result<(), Error> {
-        let request = ListConfigsRequest {};
+    pub async fn list_configs(&mut self, cmd: ListConfigsCmd) -> Result<(), Error> {
+        let request = ListConfigsRequest {
+            labels: cmd.labels.into_iter().collect(),
+        };
         log::trace!("list configs request: {request:?}");
         let response = self
             .service
@@ -160,8 +651,63 @@ impl DscpService {
         Ok(())
     }
 
+    pub async fn show_stats(&mut self, cmd: ShowStatsCmd) -> Result<(), Error> {
+        let request = ShowStatsRequest {
+            name: cmd.config_name.unwrap_or_default(),
+            labels: cmd.labels.into_iter().collect(),
+        };
+        log::trace!("show stats request: {request:?}");
+        let response = self
+            .service
+            .client()
+            .show_stats(request)
+            .await
+            .map_err(self.service.status("stats"))?
+            .into_inner();
+        log::debug!("show stats response: {response:?}");
+
+        output::data(
+            &response,
+            response.egress.is_empty()
+                && response.ext_anomalies.is_empty()
+                && response.default_action_hits == 0
+                && response.rules.is_empty(),
+            format_args!("No packets left the DSCP module yet."),
+            || print_stats_tree(&response),
+        );
+
+        Ok(())
+    }
+
+    pub async fn get_fingerprint(&mut self, cmd: FingerprintCmd) -> Result<(), Error> {
+        let request = GetFingerprintRequest {
+            name: cmd.config_name.unwrap_or_default(),
+        };
+        log::trace!("get fingerprint request: {request:?}");
+        let response = self
+            .service
+            .client()
+            .get_fingerprint(request)
+            .await
+            .map_err(self.service.status("fingerprint"))?
+            .into_inner();
+        log::debug!("get fingerprint response: {response:?}");
+
+        output::data(
+            &response,
+            response.configs.is_empty(),
+            format_args!("no dscp configs"),
+            || print_fingerprint_tree(&response),
+        );
+
+        Ok(())
+    }
+
     pub async fn show_config(&mut self, cmd: ShowConfigCmd) -> Result<(), Error> {
-        let request = ShowConfigRequest { name: cmd.config_name.to_owned() };
+        let request = ShowConfigRequest {
+            name: cmd.config_name.to_owned(),
+            labels: cmd.labels.into_iter().collect(),
+        };
         log::trace!("show config request: {request:?}");
         let response = self
             .service
@@ -177,10 +723,31 @@ impl DscpService {
         Ok(())
     }
 
+    pub async fn show_effective_config(&mut self, cmd: ShowEffectiveConfigCmd) -> Result<(), Error> {
+        let request = ShowEffectiveConfigRequest {
+            name: cmd.config_name.to_owned(),
+        };
+        log::trace!("show effective config request: {request:?}");
+        let response = self
+            .service
+            .client()
+            .show_effective_config(request)
+            .await
+            .map_err(self.service.status("show-effective"))?
+            .into_inner();
+        log::debug!("show effective config response: {response:?}");
+
+        output::data(&response, false, format_args!(""), || print_effective_tree(&response));
+
+        Ok(())
+    }
+
     pub async fn add_prefixes(&mut self, cmd: AddPrefixesCmd) -> Result<(), Error> {
         let request = AddPrefixesRequest {
             name: cmd.config_name.clone(),
             prefixes: cmd.prefix.iter().map(|p| p.to_string()).collect(),
+            direction: PrefixDirection::from(cmd.direction).into(),
+            group: cmd.group.clone().unwrap_or_default(),
         };
         log::trace!("AddPrefixesRequest: {request:?}");
         let response = self
@@ -204,6 +771,8 @@ impl DscpService {
         let request = RemovePrefixesRequest {
             name: cmd.config_name.clone(),
             prefixes: cmd.prefix.iter().map(|p| p.to_string()).collect(),
+            direction: PrefixDirection::from(cmd.direction).into(),
+            group: cmd.group.clone().unwrap_or_default(),
         };
         log::trace!("RemovePrefixesRequest: {request:?}");
         let response = self
@@ -254,27 +823,858 @@ impl DscpService {
 
         Ok(())
     }
-}
 
-fn print_tree(response: &ShowConfigResponse) {
-    let mut tree = TreeBuilder::new("View DSCP Config".to_string());
+    pub async fn set_flow_log(&mut self, cmd: SetFlowLogCmd) -> Result<(), Error> {
+        let request = SetFlowLogRequest {
+            name: cmd.config_name.clone(),
+            flow_log: Some(FlowLogConfig { rate_limit: cmd.rate_limit }),
+        };
+        log::trace!("SetFlowLogRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_flow_log(request)
+            .await
+            .map_err(self.service.status("set-flow-log"))?
+            .into_inner();
+        log::debug!("SetFlowLogResponse: {response:?}");
 
-    if let Some(config) = &response.config {
-        if let Some(dscp_config) = config.dscp_config {
-            tree.begin_child("DSCP Marking".to_string());
-            tree.add_empty_child(format!("Flag: {}", flag_to_string(dscp_config.flag)));
-            tree.add_empty_child(format!("Mark: {} (0x{:02x})", dscp_config.mark, dscp_config.mark));
-            tree.end_child();
-        }
+        output::success("set-flow-log", format_args!("Set flow log on {}.", cmd.config_name));
 
-        tree.begin_child("Prefixes".to_string());
-        for (idx, prefix) in config.prefixes.iter().enumerate() {
-            tree.add_empty_child(format!("{idx}: {prefix}"));
-        }
-        tree.end_child();
+        Ok(())
     }
 
-    let _ = ptree::print_tree(&tree.build());
+    pub async fn set_fragment_policy(&mut self, cmd: SetFragmentPolicyCmd) -> Result<(), Error> {
+        let request = SetFragmentPolicyRequest {
+            name: cmd.config_name.clone(),
+            policy: FragmentPolicy::from(cmd.policy).into(),
+        };
+        log::trace!("SetFragmentPolicyRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_fragment_policy(request)
+            .await
+            .map_err(self.service.status("set-fragment-policy"))?
+            .into_inner();
+        log::debug!("SetFragmentPolicyResponse: {response:?}");
+
+        output::success(
+            "set-fragment-policy",
+            format_args!("Set fragment policy on {}.", cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_default_action(&mut self, cmd: SetDefaultActionCmd) -> Result<(), Error> {
+        let request = SetDefaultActionRequest {
+            name: cmd.config_name.clone(),
+            default_action: Some(DefaultActionConfig {
+                action: DefaultAction::from(cmd.action).into(),
+                mark: cmd.mark,
+            }),
+        };
+        log::trace!("SetDefaultActionRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_default_action(request)
+            .await
+            .map_err(self.service.status("set-default-action"))?
+            .into_inner();
+        log::debug!("SetDefaultActionResponse: {response:?}");
+
+        output::success(
+            "set-default-action",
+            format_args!("Set default action on {}.", cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_rate_threshold(&mut self, cmd: SetRateThresholdCmd) -> Result<(), Error> {
+        let request = SetRateThresholdRequest {
+            name: cmd.config_name.clone(),
+            rate_threshold: Some(RateThreshold {
+                rate: cmd.rate,
+                burst: cmd.burst,
+            }),
+        };
+        log::trace!("SetRateThresholdRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_rate_threshold(request)
+            .await
+            .map_err(self.service.status("set-rate-threshold"))?
+            .into_inner();
+        log::debug!("SetRateThresholdResponse: {response:?}");
+
+        output::success(
+            "set-rate-threshold",
+            format_args!("Set rate threshold on {}.", cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_ext_limits(&mut self, cmd: SetExtLimitsCmd) -> Result<(), Error> {
+        let request = SetExtHeaderLimitsRequest {
+            name: cmd.config_name.clone(),
+            limits: Some(ExtHeaderLimits {
+                max_headers: cmd.max_headers,
+                skip_unknown: cmd.skip_unknown,
+                drop_anomalies: cmd.drop_anomalies,
+            }),
+        };
+        log::trace!("SetExtHeaderLimitsRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_ext_header_limits(request)
+            .await
+            .map_err(self.service.status("set-ext-limits"))?
+            .into_inner();
+        log::debug!("SetExtHeaderLimitsResponse: {response:?}");
+
+        output::success(
+            "set-ext-limits",
+            format_args!("Set extension header limits on {}.", cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn diff_config(&mut self, cmd: DiffConfigCmd) -> Result<(), Error> {
+        let dscp_config = match (cmd.flag, cmd.mark) {
+            (Some(flag), Some(mark)) => Some(DscpConfig { flag, mark }),
+            _ => None,
+        };
+
+        let request = DiffConfigRequest {
+            name: cmd.config_name.clone(),
+            config: Some(Config {
+                prefixes: cmd.prefix.iter().map(|p| p.to_string()).collect(),
+                source_prefixes: cmd.source_prefix.iter().map(|p| p.to_string()).collect(),
+                dscp_config,
+                flow_log: cmd.rate_limit.map(|rate_limit| FlowLogConfig { rate_limit }),
+                fragment_policy: cmd.fragment_policy.map(|policy| FragmentPolicy::from(policy).into()),
+                ext_header_limits: cmd.ext_max_headers.map(|max_headers| ExtHeaderLimits {
+                    max_headers,
+                    skip_unknown: cmd.ext_skip_unknown,
+                    drop_anomalies: cmd.ext_drop_anomalies,
+                }),
+                groups: Vec::new(),
+                default_action: cmd.default_action.map(|action| DefaultActionConfig {
+                    action: DefaultAction::from(action).into(),
+                    mark: cmd.default_mark,
+                }),
+                rate_threshold: cmd.rate_threshold.map(|rate| RateThreshold {
+                    rate,
+                    burst: cmd.rate_burst,
+                }),
+                stage: cmd.stage.map(|stage| Stage::from(stage).into()),
+                rules: Vec::new(),
+            }),
+            compare_rules: false,
+            compare_groups: false,
+        };
+        log::trace!("DiffConfigRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .diff_config(request)
+            .await
+            .map_err(self.service.status("diff"))?
+            .into_inner();
+        log::debug!("DiffConfigResponse: {response:?}");
+
+        let is_empty = response.added_prefixes.is_empty()
+            && response.removed_prefixes.is_empty()
+            && response.added_source_prefixes.is_empty()
+            && response.removed_source_prefixes.is_empty()
+            && response.dscp_config.is_none()
+            && response.flow_log.is_none()
+            && response.fragment_policy.is_none()
+            && response.ext_header_limits.is_none()
+            && response.default_action.is_none()
+            && response.rate_threshold.is_none()
+            && response.stage.is_none()
+            && response.added_rules.is_empty()
+            && response.removed_rules.is_empty()
+            && response.changed_rules.is_empty()
+            && response.added_groups.is_empty()
+            && response.removed_groups.is_empty()
+            && response.changed_groups.is_empty();
+
+        output::data(
+            &response,
+            is_empty,
+            format_args!("no changes for {}", cmd.config_name),
+            || print_diff_tree(&response),
+        );
+
+        Ok(())
+    }
+
+    pub async fn clone_config(&mut self, cmd: CloneConfigCmd) -> Result<(), Error> {
+        let dscp_config = match (cmd.flag, cmd.mark) {
+            (Some(flag), Some(mark)) => Some(DscpConfig { flag, mark }),
+            _ => None,
+        };
+
+        let request = CloneConfigRequest {
+            name: cmd.config_name.clone(),
+            targets: cmd.target.clone(),
+            transforms: Some(CloneTransforms {
+                add_prefixes: cmd.add_prefix.iter().map(|p| p.to_string()).collect(),
+                remove_prefixes: cmd.remove_prefix.iter().map(|p| p.to_string()).collect(),
+                direction: PrefixDirection::from(cmd.direction).into(),
+                dscp_config,
+                fragment_policy: cmd.fragment_policy.map(|policy| FragmentPolicy::from(policy).into()),
+                ext_header_limits: cmd.ext_max_headers.map(|max_headers| ExtHeaderLimits {
+                    max_headers,
+                    skip_unknown: cmd.ext_skip_unknown,
+                    drop_anomalies: cmd.ext_drop_anomalies,
+                }),
+                flow_log: cmd.rate_limit.map(|rate_limit| FlowLogConfig { rate_limit }),
+                default_action: cmd.default_action.map(|action| DefaultActionConfig {
+                    action: DefaultAction::from(action).into(),
+                    mark: cmd.default_mark,
+                }),
+                rate_threshold: cmd.rate_threshold.map(|rate| RateThreshold {
+                    rate,
+                    burst: cmd.rate_burst,
+                }),
+                stage: cmd.stage.map(|stage| Stage::from(stage).into()),
+            }),
+        };
+        log::trace!("CloneConfigRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .clone_config(request)
+            .await
+            .map_err(self.service.status("clone-config"))?
+            .into_inner();
+        log::debug!("CloneConfigResponse: {response:?}");
+
+        output::success(
+            "clone-config",
+            format_args!("Cloned {} to {}.", cmd.config_name, cmd.target.join(", ")),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_rule_group_enabled(&mut self, cmd: RuleGroupCmd, enabled: bool) -> Result<(), Error> {
+        let command = if enabled { "group-enable" } else { "group-disable" };
+        let request = SetRuleGroupEnabledRequest {
+            name: cmd.config_name.clone(),
+            group: cmd.group.clone(),
+            enabled,
+        };
+        log::trace!("SetRuleGroupEnabledRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_rule_group_enabled(request)
+            .await
+            .map_err(self.service.status(command))?
+            .into_inner();
+        log::debug!("SetRuleGroupEnabledResponse: {response:?}");
+
+        let state = if enabled { "Enabled" } else { "Disabled" };
+        output::success(
+            command,
+            format_args!("{state} group {} of {}.", cmd.group, cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_rule_group_metadata(&mut self, cmd: RuleGroupMetadataCmd) -> Result<(), Error> {
+        let request = SetRuleGroupMetadataRequest {
+            name: cmd.config_name.clone(),
+            group: cmd.group.clone(),
+            description: cmd.description,
+            labels: cmd.labels.into_iter().collect(),
+        };
+        log::trace!("SetRuleGroupMetadataRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .set_rule_group_metadata(request)
+            .await
+            .map_err(self.service.status("group-metadata"))?
+            .into_inner();
+        log::debug!("SetRuleGroupMetadataResponse: {response:?}");
+
+        output::success(
+            "group-metadata",
+            format_args!("Updated metadata of group {} of {}.", cmd.group, cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_stage(&mut self, cmd: SetStageCmd) -> Result<(), Error> {
+        let request = SetStageRequest {
+            name: cmd.config_name.clone(),
+            group: cmd.group.clone().unwrap_or_default(),
+            stage: Stage::from(cmd.stage).into(),
+        };
+        log::trace!("SetStageRequest: {request:?}");
+        let stage = stage_to_string(request.stage);
+        let response = self
+            .service
+            .client()
+            .set_stage(request)
+            .await
+            .map_err(self.service.status("set-stage"))?
+            .into_inner();
+        log::debug!("SetStageResponse: {response:?}");
+
+        match &cmd.group {
+            Some(group) => output::success(
+                "set-stage",
+                format_args!("Pinned group {group} of {} to {stage}.", cmd.config_name),
+            ),
+            None => output::success("set-stage", format_args!("Set stage of {} to {stage}.", cmd.config_name)),
+        }
+
+        Ok(())
+    }
+
+    pub async fn add_rule(&mut self, cmd: RuleAddCmd) -> Result<(), Error> {
+        let request = AddRuleRequest {
+            name: cmd.config_name.clone(),
+            rule: Some(Rule {
+                name: cmd.rule.clone(),
+                priority: cmd.priority,
+                src_prefix: cmd.src.map(|p| p.to_string()).unwrap_or_default(),
+                dst_prefix: cmd.dst.map(|p| p.to_string()).unwrap_or_default(),
+                proto: cmd.proto.unwrap_or_default(),
+                dst_ports: cmd.ports.map(|(from, to)| PortRange { from, to }),
+                mark: cmd.mark,
+                remark: RemarkPolicy::from(cmd.remark).into(),
+                traffic_class: cmd.traffic_class,
+            }),
+        };
+        log::trace!("AddRuleRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .add_rule(request)
+            .await
+            .map_err(self.service.status("rule-add"))?
+            .into_inner();
+        log::debug!("AddRuleResponse: {response:?}");
+
+        output::success(
+            "rule-add",
+            format_args!("Added rule {} to {}.", cmd.rule, cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn delete_rule(&mut self, cmd: RuleDeleteCmd) -> Result<(), Error> {
+        let request = DeleteRuleRequest {
+            name: cmd.config_name.clone(),
+            rule: cmd.rule.clone(),
+        };
+        log::trace!("DeleteRuleRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .delete_rule(request)
+            .await
+            .map_err(self.service.status("rule-delete"))?
+            .into_inner();
+        log::debug!("DeleteRuleResponse: {response:?}");
+
+        output::success(
+            "rule-delete",
+            format_args!("Deleted rule {} from {}.", cmd.rule, cmd.config_name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn list_rules(&mut self, cmd: RulesCmd) -> Result<(), Error> {
+        let request = ListRulesRequest {
+            name: cmd.config_name.clone(),
+        };
+        log::trace!("ListRulesRequest: {request:?}");
+        let response = self
+            .service
+            .client()
+            .list_rules(request)
+            .await
+            .map_err(self.service.status("rules"))?
+            .into_inner();
+        log::debug!("ListRulesResponse: {response:?}");
+
+        output::data(
+            &response,
+            response.rules.is_empty(),
+            format_args!("no marking rules in {}", cmd.config_name),
+            || print_rules_tree(&response),
+        );
+
+        Ok(())
+    }
+}
+
+fn print_diff_tree(response: &DiffConfigResponse) {
+    let mut tree = TreeBuilder::new("DSCP Config Diff".to_string());
+
+    if let Some(diff) = &response.dscp_config {
+        let current = diff.current.unwrap_or_default();
+        let proposed = diff.proposed.unwrap_or_default();
+
+        tree.begin_child("DSCP Marking".to_string());
+        if current.flag != proposed.flag {
+            tree.add_empty_child(format!(
+                "Flag: {} -> {}",
+                flag_to_string(current.flag),
+                flag_to_string(proposed.flag)
+            ));
+        }
+        if current.mark != proposed.mark {
+            tree.add_empty_child(format!("Mark: {} -> {}", current.mark, proposed.mark));
+        }
+        tree.end_child();
+    }
+
+    if let Some(diff) = &response.flow_log {
+        let current = diff.current.unwrap_or_default();
+        let proposed = diff.proposed.unwrap_or_default();
+        tree.add_empty_child(format!(
+            "Flow Log: {} -> {}",
+            flow_log_to_string(current.rate_limit),
+            flow_log_to_string(proposed.rate_limit)
+        ));
+    }
+
+    if let Some(diff) = &response.fragment_policy {
+        tree.add_empty_child(format!(
+            "Fragment Policy: {} -> {}",
+            fragment_policy_to_string(diff.current),
+            fragment_policy_to_string(diff.proposed)
+        ));
+    }
+
+    if let Some(diff) = &response.ext_header_limits {
+        tree.add_empty_child(format!(
+            "Extension Headers: {} -> {}",
+            ext_limits_to_string(&diff.current.unwrap_or_default()),
+            ext_limits_to_string(&diff.proposed.unwrap_or_default())
+        ));
+    }
+
+    if let Some(diff) = &response.default_action {
+        tree.add_empty_child(format!(
+            "Default Action: {} -> {}",
+            default_action_to_string(&diff.current.unwrap_or_default()),
+            default_action_to_string(&diff.proposed.unwrap_or_default())
+        ));
+    }
+
+    if let Some(diff) = &response.rate_threshold {
+        tree.add_empty_child(format!(
+            "Rate Threshold: {} -> {}",
+            rate_threshold_to_string(&diff.current.unwrap_or_default()),
+            rate_threshold_to_string(&diff.proposed.unwrap_or_default())
+        ));
+    }
+
+    if let Some(diff) = &response.stage {
+        tree.add_empty_child(format!(
+            "Stage: {} -> {}",
+            stage_to_string(diff.current),
+            stage_to_string(diff.proposed)
+        ));
+    }
+
+    if !response.added_prefixes.is_empty() || !response.removed_prefixes.is_empty() {
+        tree.begin_child("Prefixes".to_string());
+        for prefix in &response.added_prefixes {
+            tree.add_empty_child(format!("+ {prefix}"));
+        }
+        for prefix in &response.removed_prefixes {
+            tree.add_empty_child(format!("- {prefix}"));
+        }
+        tree.end_child();
+    }
+
+    if !response.added_source_prefixes.is_empty() || !response.removed_source_prefixes.is_empty() {
+        tree.begin_child("Source Prefixes".to_string());
+        for prefix in &response.added_source_prefixes {
+            tree.add_empty_child(format!("+ {prefix}"));
+        }
+        for prefix in &response.removed_source_prefixes {
+            tree.add_empty_child(format!("- {prefix}"));
+        }
+        tree.end_child();
+    }
+
+    if !response.added_rules.is_empty() || !response.removed_rules.is_empty() || !response.changed_rules.is_empty() {
+        tree.begin_child("Rules".to_string());
+        for rule in &response.added_rules {
+            tree.add_empty_child(format!("+ {}", rule_to_string(rule)));
+        }
+        for rule in &response.removed_rules {
+            tree.add_empty_child(format!("- {}", rule_to_string(rule)));
+        }
+        for diff in &response.changed_rules {
+            tree.add_empty_child(format!(
+                "~ {} -> {}",
+                diff.current.as_ref().map(rule_to_string).unwrap_or_default(),
+                diff.proposed.as_ref().map(rule_to_string).unwrap_or_default()
+            ));
+        }
+        tree.end_child();
+    }
+
+    if !response.added_groups.is_empty() || !response.removed_groups.is_empty() || !response.changed_groups.is_empty() {
+        tree.begin_child("Rule Groups".to_string());
+        for group in &response.added_groups {
+            tree.add_empty_child(format!("+ {}", group.name));
+        }
+        for group in &response.removed_groups {
+            tree.add_empty_child(format!("- {}", group.name));
+        }
+        for diff in &response.changed_groups {
+            tree.begin_child(format!("~ {}", diff.name));
+            if let Some(enabled) = &diff.enabled {
+                tree.add_empty_child(format!("Enabled: {} -> {}", enabled.current, enabled.proposed));
+            }
+            if let Some(stage) = &diff.stage {
+                tree.add_empty_child(format!(
+                    "Stage: {} -> {}",
+                    stage_to_string(stage.current),
+                    stage_to_string(stage.proposed)
+                ));
+            }
+            for prefix in &diff.added_prefixes {
+                tree.add_empty_child(format!("+ {prefix}"));
+            }
+            for prefix in &diff.removed_prefixes {
+                tree.add_empty_child(format!("- {prefix}"));
+            }
+            for prefix in &diff.added_source_prefixes {
+                tree.add_empty_child(format!("+ source {prefix}"));
+            }
+            for prefix in &diff.removed_source_prefixes {
+                tree.add_empty_child(format!("- source {prefix}"));
+            }
+            tree.end_child();
+        }
+        tree.end_child();
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn flow_log_to_string(rate_limit: u32) -> String {
+    match rate_limit {
+        0 => "disabled".to_string(),
+        rate => format!("{rate} flows/s per worker"),
+    }
+}
+
+fn fragment_policy_to_string(policy: i32) -> String {
+    match FragmentPolicy::try_from(policy) {
+        Ok(FragmentPolicy::Match) => "match".to_string(),
+        Ok(FragmentPolicy::Pass) => "pass unmarked".to_string(),
+        Ok(FragmentPolicy::Drop) => "drop".to_string(),
+        Err(_) => format!("unknown ({policy})"),
+    }
+}
+
+fn stage_to_string(stage: i32) -> String {
+    match Stage::try_from(stage) {
+        Ok(Stage::Any) => "any".to_string(),
+        Ok(Stage::Ingress) => "ingress".to_string(),
+        Ok(Stage::Egress) => "egress".to_string(),
+        Err(_) => format!("unknown ({stage})"),
+    }
+}
+
+fn default_action_to_string(action: &DefaultActionConfig) -> String {
+    match DefaultAction::try_from(action.action) {
+        Ok(DefaultAction::Pass) => "pass unchanged".to_string(),
+        Ok(DefaultAction::Mark) => format!("mark {} (0x{:02x})", action.mark, action.mark),
+        Ok(DefaultAction::Drop) => "drop".to_string(),
+        Err(_) => format!("unknown ({})", action.action),
+    }
+}
+
+fn rate_threshold_to_string(threshold: &RateThreshold) -> String {
+    match (threshold.rate, threshold.burst) {
+        (0, _) => "mark all".to_string(),
+        (rate, 0) => format!("mark above {rate} packets/s per worker"),
+        (rate, burst) => format!("mark above {rate} packets/s per worker, burst {burst}"),
+    }
+}
+
+fn ext_limits_to_string(limits: &ExtHeaderLimits) -> String {
+    if limits.max_headers == 0 {
+        return "unlimited".to_string();
+    }
+
+    let mut out = format!("at most {}", limits.max_headers);
+    if limits.skip_unknown {
+        out.push_str(", skip unknown");
+    }
+    if limits.drop_anomalies {
+        out.push_str(", drop anomalies");
+    } else {
+        out.push_str(", pass anomalies unmarked");
+    }
+    out
+}
+
+fn ext_anomaly_to_string(anomaly: i32) -> String {
+    match ExtAnomaly::try_from(anomaly) {
+        Ok(ExtAnomaly::Limit) => "too many headers".to_string(),
+        Ok(ExtAnomaly::Unknown) => "unknown header".to_string(),
+        Ok(ExtAnomaly::Malformed) => "malformed header".to_string(),
+        Err(_) => format!("unknown ({anomaly})"),
+    }
+}
+
+fn print_tree(response: &ShowConfigResponse) {
+    let mut tree = TreeBuilder::new("View DSCP Config".to_string());
+
+    if let Some(config) = &response.config {
+        add_config_children(&mut tree, config);
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn print_effective_tree(response: &ShowEffectiveConfigResponse) {
+    let mut tree = TreeBuilder::new(format!(
+        "Effective DSCP Config (generation {}, published at {})",
+        response.generation, response.module_generation
+    ));
+
+    if !response.applied {
+        tree.add_empty_child("Applied: no, active in the dataplane only".to_string());
+    } else if response.mismatches.is_empty() {
+        tree.add_empty_child("Applied: in sync".to_string());
+    } else {
+        tree.add_empty_child(format!("Applied: differs in {}", response.mismatches.join(", ")));
+    }
+
+    if let Some(config) = &response.config {
+        add_config_children(&mut tree, config);
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn add_config_children(tree: &mut TreeBuilder, config: &Config) {
+    if let Some(dscp_config) = config.dscp_config {
+        tree.begin_child("DSCP Marking".to_string());
+        tree.add_empty_child(format!("Flag: {}", flag_to_string(dscp_config.flag)));
+        tree.add_empty_child(format!("Mark: {} (0x{:02x})", dscp_config.mark, dscp_config.mark));
+        tree.end_child();
+    }
+
+    if let Some(flow_log) = config.flow_log {
+        match flow_log.rate_limit {
+            0 => tree.add_empty_child("Flow Log: disabled".to_string()),
+            rate => tree.add_empty_child(format!("Flow Log: {rate} flows/s per worker")),
+        };
+    }
+
+    if let Some(policy) = config.fragment_policy {
+        tree.add_empty_child(format!("Fragment Policy: {}", fragment_policy_to_string(policy)));
+    }
+
+    if let Some(limits) = &config.ext_header_limits {
+        tree.add_empty_child(format!("Extension Headers: {}", ext_limits_to_string(limits)));
+    }
+
+    if let Some(action) = &config.default_action {
+        tree.add_empty_child(format!("Default Action: {}", default_action_to_string(action)));
+    }
+
+    if let Some(threshold) = &config.rate_threshold {
+        tree.add_empty_child(format!("Rate Threshold: {}", rate_threshold_to_string(threshold)));
+    }
+
+    if let Some(stage) = config.stage {
+        tree.add_empty_child(format!("Stage: {}", stage_to_string(stage)));
+    }
+
+    tree.begin_child("Prefixes".to_string());
+    for (idx, prefix) in config.prefixes.iter().enumerate() {
+        tree.add_empty_child(format!("{idx}: {prefix}"));
+    }
+    tree.end_child();
+
+    if !config.source_prefixes.is_empty() {
+        tree.begin_child("Source Prefixes".to_string());
+        for (idx, prefix) in config.source_prefixes.iter().enumerate() {
+            tree.add_empty_child(format!("{idx}: {prefix}"));
+        }
+        tree.end_child();
+    }
+
+    if !config.rules.is_empty() {
+        tree.begin_child("Rules".to_string());
+        for rule in &config.rules {
+            tree.add_empty_child(rule_to_string(rule));
+        }
+        tree.end_child();
+    }
+
+    for group in &config.groups {
+        let state = if group.enabled { "enabled" } else { "disabled" };
+        match Stage::try_from(group.stage) {
+            Ok(Stage::Any) => tree.begin_child(format!("Group {} ({state})", group.name)),
+            _ => tree.begin_child(format!("Group {} ({state}, {})", group.name, stage_to_string(group.stage))),
+        };
+        if !group.description.is_empty() {
+            tree.add_empty_child(format!("description: {}", group.description));
+        }
+        if !group.labels.is_empty() {
+            let mut labels: Vec<String> = group.labels.iter().map(|(k, v)| format!("{k}={v}")).collect();
+            labels.sort();
+            tree.add_empty_child(format!("labels: {}", labels.join(", ")));
+        }
+        for prefix in &group.prefixes {
+            tree.add_empty_child(format!("dst: {prefix}"));
+        }
+        for prefix in &group.source_prefixes {
+            tree.add_empty_child(format!("src: {prefix}"));
+        }
+        tree.end_child();
+    }
+}
+
+fn print_stats_tree(response: &ShowStatsResponse) {
+    let total: u64 = response.egress.iter().map(|count| count.packets).sum();
+
+    let mut tree = TreeBuilder::new(format!(
+        "Egress DSCP of {} ({total} packets)",
+        response.configs.join(", ")
+    ));
+    for count in &response.egress {
+        let share = count.packets as f64 * 100.0 / total as f64;
+        tree.add_empty_child(format!(
+            "{:>2} (0x{:02x}): {} packets, {share:.1}%",
+            count.dscp, count.dscp, count.packets
+        ));
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+
+    if response.default_action_hits != 0 {
+        println!("Default action taken on {} packets", response.default_action_hits);
+    }
+
+    let marking = response.marking.clone().unwrap_or_default();
+    if marking.matched != 0 || !response.rules.is_empty() {
+        let mut tree = TreeBuilder::new(format!("Marking: {}", marking_counts_to_string(&marking)));
+        for rule in &response.rules {
+            tree.add_empty_child(format!(
+                "{}/{}: {}",
+                rule.config,
+                rule.name,
+                marking_counts_to_string(&rule.counts.clone().unwrap_or_default())
+            ));
+        }
+
+        let _ = ptree::print_tree(&tree.build());
+    }
+
+    if response.ext_anomalies.is_empty() {
+        return;
+    }
+
+    let mut tree = TreeBuilder::new("IPv6 Extension Header Anomalies".to_string());
+    for count in &response.ext_anomalies {
+        tree.add_empty_child(format!(
+            "{}: {} packets",
+            ext_anomaly_to_string(count.anomaly),
+            count.packets
+        ));
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn marking_counts_to_string(counts: &MarkingCounts) -> String {
+    format!(
+        "{} matched ({} bytes), {} remarked ({} bytes), {} untouched",
+        counts.matched, counts.matched_bytes, counts.remarked, counts.remarked_bytes, counts.untouched
+    )
+}
+
+fn print_fingerprint_tree(response: &GetFingerprintResponse) {
+    let title = if response.fingerprint.is_empty() {
+        "DSCP Fingerprints".to_string()
+    } else {
+        format!("DSCP Fingerprint: {}", response.fingerprint)
+    };
+
+    let mut tree = TreeBuilder::new(title);
+    for config in &response.configs {
+        tree.add_empty_child(format!("{}: {}", config.name, config.fingerprint));
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn print_rules_tree(response: &ListRulesResponse) {
+    let mut tree = TreeBuilder::new("DSCP Marking Rules".to_string());
+    for rule in &response.rules {
+        tree.add_empty_child(rule_to_string(rule));
+    }
+
+    let _ = ptree::print_tree(&tree.build());
+}
+
+fn rule_to_string(rule: &Rule) -> String {
+    let any = |prefix: &str| if prefix.is_empty() { "any".to_string() } else { prefix.to_string() };
+    let mut out = format!(
+        "{} (priority {}): {} -> {}",
+        rule.name,
+        rule.priority,
+        any(&rule.src_prefix),
+        any(&rule.dst_prefix)
+    );
+    if rule.proto != 0 {
+        out.push_str(&format!(", proto {}", rule.proto));
+    }
+    if let Some(ports) = &rule.dst_ports {
+        if ports.from == ports.to {
+            out.push_str(&format!(", port {}", ports.from));
+        } else {
+            out.push_str(&format!(", ports {}-{}", ports.from, ports.to));
+        }
+    }
+    out.push_str(&format!(", {}", remark_to_string(rule.remark, rule.mark)));
+    if rule.traffic_class != 0 {
+        out.push_str(&format!(", class {}", rule.traffic_class));
+    }
+    out
+}
+
+fn remark_to_string(remark: i32, mark: u32) -> String {
+    match RemarkPolicy::try_from(remark) {
+        Ok(RemarkPolicy::Always) => format!("mark {mark} (0x{mark:02x})"),
+        Ok(RemarkPolicy::OnlyDefault) => format!("mark {mark} (0x{mark:02x}) if unmarked"),
+        Ok(RemarkPolicy::Never) => "keep".to_string(),
+        Err(_) => format!("unknown ({remark})"),
+    }
 }
 
 fn flag_to_string(flag: u32) -> String {
@@ -2,7 +2,7 @@ package dscp
 
 import (
 	"fmt"
-	"net/netip"
+	"strings"
 
 	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
@@ -10,39 +10,79 @@ import (
 
 // backend is the real Backend implementation backed by shared memory.
 type backend struct {
-	agent *ffi.Agent
+	agent  *ffi.Agent
+	limits *ruleTableLimits
 }
 
-// newBackend creates a Backend that operates on real shared memory.
-func newBackend(agent *ffi.Agent) *backend {
+// newBackend creates a Backend that operates on real shared memory,
+// checking the module configs against the rule table limits.
+func newBackend(agent *ffi.Agent, limits *ruleTableLimits) *backend {
 	return &backend{
-		agent: agent,
+		agent:  agent,
+		limits: limits,
 	}
 }
 
-func (m *backend) UpdateModule(
-	name string,
-	prefixes []netip.Prefix,
-	flag uint8,
-	mark uint8,
-) (ModuleHandle, error) {
+func (m *backend) UpdateModule(name string, settings ModuleSettings) (ModuleHandle, error) {
 	module, err := cdscp.NewModuleConfig(m.agent, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create module config: %w", err)
 	}
 
-	for _, prefix := range prefixes {
+	for _, prefix := range settings.Prefixes {
 		if err := module.PrefixAdd(prefix); err != nil {
 			module.Free()
 			return nil, fmt.Errorf("failed to add prefix: %w", err)
 		}
 	}
 
-	if err := module.SetDscpMarking(flag, mark); err != nil {
+	for _, prefix := range settings.SourcePrefixes {
+		if err := module.SourcePrefixAdd(prefix); err != nil {
+			module.Free()
+			return nil, fmt.Errorf("failed to add source prefix: %w", err)
+		}
+	}
+
+	if err := module.SetRules(settings.Rules); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set rules: %w", err)
+	}
+
+	if err := module.SetDscpMarking(settings.Flag, settings.Mark); err != nil {
 		module.Free()
 		return nil, fmt.Errorf("failed to set DSCP marking: %w", err)
 	}
 
+	if err := module.SetFragmentPolicy(settings.FragmentPolicy); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set fragment policy: %w", err)
+	}
+
+	if err := module.SetDefaultAction(settings.DefaultAction, settings.DefaultMark); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set default action: %w", err)
+	}
+
+	if err := module.SetRateThreshold(settings.RateThreshold, settings.RateBurst); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set rate threshold: %w", err)
+	}
+
+	if err := module.SetExtLimits(settings.ExtMaxHeaders, settings.ExtFlags); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set extension header limits: %w", err)
+	}
+
+	if err := module.SetFlowLog(settings.FlowLogRate); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set flow log: %w", err)
+	}
+
+	if err := m.limits.Check(name, module.MemoryUsage()); err != nil {
+		module.Free()
+		return nil, err
+	}
+
 	if err := m.agent.UpdateModules([]ffi.ModuleConfig{module.AsFFIModule()}); err != nil {
 		module.Free()
 		return nil, fmt.Errorf("failed to update module: %w", err)
@@ -50,3 +90,67 @@ func (m *backend) UpdateModule(
 
 	return module, nil
 }
+
+// EgressStats implements EgressStatsReader.
+func (m *backend) EgressStats() ([]EgressStats, error) {
+	dpConfig := m.agent.DPConfig()
+	if dpConfig == nil {
+		return nil, nil
+	}
+
+	positions, err := dpConfig.AllModulePositions("dscp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module positions: %w", err)
+	}
+
+	result := make([]EgressStats, 0)
+	for pos := range positions {
+		counters := dpConfig.ModuleCounters(
+			pos.Device,
+			pos.Pipeline,
+			pos.Function,
+			pos.Chain,
+			"dscp",
+			pos.ModuleName,
+			// The rule counters are named after the rules, so every
+			// counter is read.
+			nil,
+		)
+
+		stats := EgressStats{Position: pos, Rules: map[string]MarkingCounts{}}
+		defaultActionHits := [1]uint64{}
+		for _, counter := range counters {
+			var slots []uint64
+			switch counter.Name {
+			case egressCounterName:
+				slots = stats.Packets[:]
+			case extAnomalyCounterName:
+				slots = stats.ExtAnomalies[:]
+			case defaultActionCounterName:
+				slots = defaultActionHits[:]
+			case markingCounterName:
+				stats.Marking = newMarkingCounts(counter.Values)
+				continue
+			default:
+				if rule, ok := strings.CutPrefix(counter.Name, ruleCounterPrefix); ok {
+					stats.Rules[rule] = newMarkingCounts(counter.Values)
+				}
+				continue
+			}
+			for _, workerVals := range counter.Values {
+				for idx, packets := range workerVals[:min(len(workerVals), len(slots))] {
+					slots[idx] += packets
+				}
+			}
+		}
+		stats.DefaultActionHits = defaultActionHits[0]
+		result = append(result, stats)
+	}
+
+	return result, nil
+}
+
+// EffectiveConfig implements EffectiveConfigReader.
+func (m *backend) EffectiveConfig(name string) (*cdscp.EffectiveConfig, error) {
+	return cdscp.ReadEffectiveConfig(m.agent, name)
+}
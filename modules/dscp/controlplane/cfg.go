@@ -14,6 +14,15 @@ type Config struct {
 	// MemoryRequirements is the amount of memory that is required for a single
 	// transaction.
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
+	// RuleTable bounds the rule table utilization of module configs.
+	RuleTable RuleTableConfig `yaml:"rule_table"`
+	// ReservedMarks guards the network control codepoints against marking
+	// rules.
+	ReservedMarks ReservedMarksConfig `yaml:"reserved_marks"`
 
 	Endpoint        xcfg.NonEmptyString `yaml:"endpoint"`
 	GatewayEndpoint xcfg.NonEmptyString `yaml:"gateway_endpoint"`
@@ -25,5 +34,11 @@ func DefaultConfig() *Config {
 		MemoryRequirements: xcfg.MustNonZero(16 * datasize.MB),
 		Endpoint:           xcfg.MustNonEmptyString("[::1]:0"),
 		GatewayEndpoint:    xcfg.MustNonEmptyString("[::1]:8080"),
+		RuleTable: RuleTableConfig{
+			WarnThreshold: 0.8,
+		},
+		ReservedMarks: ReservedMarksConfig{
+			Policy: ReservedMarkReject,
+		},
 	}
 }
@@ -25,7 +25,7 @@ func (m *AddPrefixesRequest) Validate() error {
 		return errConfigNameRequired
 	}
 
-	return nil
+	return validatePrefixDirection(m.Direction)
 }
 
 func (m *RemovePrefixesRequest) Validate() error {
@@ -33,9 +33,33 @@ func (m *RemovePrefixesRequest) Validate() error {
 		return errConfigNameRequired
 	}
 
+	return validatePrefixDirection(m.Direction)
+}
+
+func validatePrefixDirection(direction PrefixDirection) error {
+	if direction > PrefixDirection_PREFIX_DIRECTION_EITHER {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"invalid prefix direction %d",
+			direction,
+		)
+	}
+
 	return nil
 }
 
+// MatchesDestination reports whether prefixes of the direction are matched
+// against the destination address.
+func (m PrefixDirection) MatchesDestination() bool {
+	return m != PrefixDirection_PREFIX_DIRECTION_SOURCE
+}
+
+// MatchesSource reports whether prefixes of the direction are matched
+// against the source address.
+func (m PrefixDirection) MatchesSource() bool {
+	return m != PrefixDirection_PREFIX_DIRECTION_DESTINATION
+}
+
 func (m *SetDscpMarkingRequest) Validate() error {
 	if m.Name == "" {
 		return errConfigNameRequired
@@ -48,14 +72,18 @@ func (m *SetDscpMarkingRequest) Validate() error {
 		)
 	}
 
-	if m.DscpConfig.Flag > 2 {
+	return m.DscpConfig.Validate()
+}
+
+func (m *DscpConfig) Validate() error {
+	if m.Flag > 2 {
 		return status.Error(
 			codes.InvalidArgument,
 			"invalid flag value (must be 0, 1, or 2)",
 		)
 	}
 
-	if m.DscpConfig.Mark > 63 {
+	if m.Mark > 63 {
 		return status.Error(
 			codes.InvalidArgument,
 			"invalid mark value (must be 0-63)",
@@ -64,3 +92,523 @@ func (m *SetDscpMarkingRequest) Validate() error {
 
 	return nil
 }
+
+func (m *SetFragmentPolicyRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	return validateFragmentPolicy(m.Policy)
+}
+
+func validateFragmentPolicy(policy FragmentPolicy) error {
+	if policy > FragmentPolicy_FRAGMENT_POLICY_DROP {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"invalid fragment policy %d",
+			policy,
+		)
+	}
+
+	return nil
+}
+
+func (m *SetDefaultActionRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.DefaultAction == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"default action is required",
+		)
+	}
+
+	return m.DefaultAction.Validate()
+}
+
+func (m *DefaultActionConfig) Validate() error {
+	if m.Action > DefaultAction_DEFAULT_ACTION_DROP {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"invalid default action %d",
+			m.Action,
+		)
+	}
+
+	if m.Mark > 63 {
+		return status.Error(
+			codes.InvalidArgument,
+			"invalid default mark value (must be 0-63)",
+		)
+	}
+
+	return nil
+}
+
+func (m *SetRateThresholdRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.RateThreshold == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"rate threshold is required",
+		)
+	}
+
+	return m.RateThreshold.Validate()
+}
+
+func (m *RateThreshold) Validate() error {
+	if m.Rate == 0 && m.Burst != 0 {
+		return status.Error(
+			codes.InvalidArgument,
+			"burst requires a rate",
+		)
+	}
+
+	return nil
+}
+
+func (m *SetExtHeaderLimitsRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.Limits == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"extension header limits are required",
+		)
+	}
+
+	return m.Limits.Validate()
+}
+
+func (m *ExtHeaderLimits) Validate() error {
+	if m.MaxHeaders > 255 {
+		return status.Error(
+			codes.InvalidArgument,
+			"invalid max headers value (must be 0-255)",
+		)
+	}
+
+	return nil
+}
+
+func (m *SetFlowLogRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.FlowLog == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"flow log config is required",
+		)
+	}
+
+	return nil
+}
+
+func (m *WatchFlowsRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	return nil
+}
+
+func (m *DiffConfigRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.Config == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"proposed config is required",
+		)
+	}
+
+	if m.Config.DscpConfig != nil {
+		if err := m.Config.DscpConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.FragmentPolicy != nil {
+		if err := validateFragmentPolicy(*m.Config.FragmentPolicy); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.ExtHeaderLimits != nil {
+		if err := m.Config.ExtHeaderLimits.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.DefaultAction != nil {
+		if err := m.Config.DefaultAction.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.Stage != nil {
+		if err := validateStage(*m.Config.Stage); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.RateThreshold != nil {
+		if err := m.Config.RateThreshold.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.CompareRules {
+		names := map[string]struct{}{}
+		for _, rule := range m.Config.Rules {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+			if _, ok := names[rule.Name]; ok {
+				return status.Errorf(
+					codes.InvalidArgument,
+					"duplicate rule %q",
+					rule.Name,
+				)
+			}
+			names[rule.Name] = struct{}{}
+		}
+	}
+
+	if m.CompareGroups {
+		names := map[string]struct{}{}
+		for _, group := range m.Config.Groups {
+			if group.Name == "" {
+				return status.Error(
+					codes.InvalidArgument,
+					"rule group name is required",
+				)
+			}
+			if _, ok := names[group.Name]; ok {
+				return status.Errorf(
+					codes.InvalidArgument,
+					"duplicate rule group %q",
+					group.Name,
+				)
+			}
+			names[group.Name] = struct{}{}
+			if err := validateStage(group.Stage); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *ShowStatsRequest) Validate() error {
+	if m.Name == "" && len(m.Labels) == 0 {
+		return status.Error(
+			codes.InvalidArgument,
+			"config name or labels are required",
+		)
+	}
+
+	return nil
+}
+
+func (m *CloneConfigRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if len(m.Targets) == 0 {
+		return status.Error(
+			codes.InvalidArgument,
+			"at least one target config is required",
+		)
+	}
+
+	targets := map[string]struct{}{}
+	for _, target := range m.Targets {
+		if target == "" {
+			return status.Error(
+				codes.InvalidArgument,
+				"target config name is required",
+			)
+		}
+		if target == m.Name {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"target config %q is the source config",
+				target,
+			)
+		}
+		if _, ok := targets[target]; ok {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"duplicate target config %q",
+				target,
+			)
+		}
+		targets[target] = struct{}{}
+	}
+
+	if m.Transforms != nil {
+		return m.Transforms.Validate()
+	}
+
+	return nil
+}
+
+func (m *CloneTransforms) Validate() error {
+	if err := validatePrefixDirection(m.Direction); err != nil {
+		return err
+	}
+
+	if m.DscpConfig != nil {
+		if err := m.DscpConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.FragmentPolicy != nil {
+		if err := validateFragmentPolicy(*m.FragmentPolicy); err != nil {
+			return err
+		}
+	}
+
+	if m.ExtHeaderLimits != nil {
+		if err := m.ExtHeaderLimits.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.DefaultAction != nil {
+		if err := m.DefaultAction.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.Stage != nil {
+		if err := validateStage(*m.Stage); err != nil {
+			return err
+		}
+	}
+
+	if m.RateThreshold != nil {
+		return m.RateThreshold.Validate()
+	}
+
+	return nil
+}
+
+func (m *SetRuleGroupEnabledRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+	if m.Group == "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"rule group name is required",
+		)
+	}
+	return nil
+}
+
+func (m *SetRuleGroupMetadataRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+	if m.Group == "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"rule group name is required",
+		)
+	}
+	for key := range m.Labels {
+		if key == "" {
+			return status.Error(
+				codes.InvalidArgument,
+				"label key is required",
+			)
+		}
+	}
+	return nil
+}
+
+func (m *SetStageRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	return validateStage(m.Stage)
+}
+
+func validateStage(stage Stage) error {
+	if stage > Stage_STAGE_EGRESS {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"invalid stage %d",
+			stage,
+		)
+	}
+
+	return nil
+}
+
+// AppliesAt reports whether a rule group of the stage is matched by a config
+// running at the given stage.
+func (m Stage) AppliesAt(stage Stage) bool {
+	return m == Stage_STAGE_ANY || m == stage
+}
+
+func (m *AddRuleRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	if m.Rule == nil {
+		return status.Error(
+			codes.InvalidArgument,
+			"rule is required",
+		)
+	}
+
+	return m.Rule.Validate()
+}
+
+func (m *Rule) Validate() error {
+	if m.Name == "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"rule name is required",
+		)
+	}
+	if len(m.Name) > maxRuleNameLen {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"rule name must be at most %d bytes",
+			maxRuleNameLen,
+		)
+	}
+
+	if m.Proto > 255 {
+		return status.Error(
+			codes.InvalidArgument,
+			"invalid protocol value (must be 0-255)",
+		)
+	}
+
+	if m.DstPorts != nil {
+		if m.DstPorts.From > m.DstPorts.To || m.DstPorts.To > 65535 {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"invalid destination port range %d-%d",
+				m.DstPorts.From,
+				m.DstPorts.To,
+			)
+		}
+		if m.Proto != 0 && m.Proto != protoTCP && m.Proto != protoUDP {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"destination ports require TCP or UDP, got protocol %d",
+				m.Proto,
+			)
+		}
+	}
+
+	if m.Mark > 63 {
+		return status.Error(
+			codes.InvalidArgument,
+			"invalid mark value (must be 0-63)",
+		)
+	}
+
+	if m.Remark > RemarkPolicy_REMARK_POLICY_NEVER {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"invalid remark policy %d",
+			m.Remark,
+		)
+	}
+
+	if m.TrafficClass > 255 {
+		return status.Error(
+			codes.InvalidArgument,
+			"invalid traffic class value (must be 0-255)",
+		)
+	}
+
+	return nil
+}
+
+// maxRuleNameLen is the length of the longest rule name whose counter name,
+// prefixed with DSCP_RULE_COUNTER_PREFIX, fits COUNTER_NAME_LEN.
+const maxRuleNameLen = 117
+
+// IP protocol numbers of the transport headers carrying ports.
+const (
+	protoTCP = 6
+	protoUDP = 17
+)
+
+// Flag returns the DSCP marking flag the policy applies, see DscpConfig.
+func (m RemarkPolicy) Flag() uint32 {
+	switch m {
+	case RemarkPolicy_REMARK_POLICY_ONLY_DEFAULT:
+		return 1
+	case RemarkPolicy_REMARK_POLICY_NEVER:
+		return 0
+	default:
+		return 2
+	}
+}
+
+// RemarkPolicyFromFlag returns the policy applying a DSCP marking flag.
+func RemarkPolicyFromFlag(flag uint32) RemarkPolicy {
+	switch flag {
+	case 0:
+		return RemarkPolicy_REMARK_POLICY_NEVER
+	case 1:
+		return RemarkPolicy_REMARK_POLICY_ONLY_DEFAULT
+	default:
+		return RemarkPolicy_REMARK_POLICY_ALWAYS
+	}
+}
+
+func (m *DeleteRuleRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+	if m.Rule == "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"rule name is required",
+		)
+	}
+	return nil
+}
+
+func (m *ListRulesRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	return nil
+}
+
+func (m *ShowEffectiveConfigRequest) Validate() error {
+	if m.Name == "" {
+		return errConfigNameRequired
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package dscp
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// coveringPrefixes returns the smallest sorted set of prefixes covering
+// the addresses of the prefixes, the way the dataplane holds them.
+func coveringPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	out := []netip.Prefix{}
+
+	var from, to netip.Addr
+	flush := func() {
+		if !from.IsValid() {
+			return
+		}
+		cidrs, _ := xnetip.RangeToCIDRs(from, to)
+		out = append(out, cidrs...)
+	}
+	for _, prefix := range slices.SortedFunc(slices.Values(prefixes), xnetip.PrefixCompare) {
+		first := prefix.Masked().Addr()
+		last := xnetip.LastAddr(prefix)
+		if from.IsValid() && from.Is4() == first.Is4() && (first.Compare(to) <= 0 || to.Next() == first) {
+			if last.Compare(to) > 0 {
+				to = last
+			}
+			continue
+		}
+		flush()
+		from, to = first, last
+	}
+	flush()
+
+	return out
+}
+
+// effectiveConfig returns the module config the dataplane is expected to
+// run once the config is applied, in the form the backend decodes it.
+func (m *config) effectiveConfig() *cdscp.EffectiveConfig {
+	prefixes, sourcePrefixes := m.matchedPrefixes()
+
+	rules := m.backendRules()
+	for idx := range rules {
+		rules[idx] = rules[idx].Effective()
+	}
+
+	// The dataplane allows a second worth of packets for an unset burst.
+	rate := m.RateThreshold
+	if rate.rate == 0 {
+		rate.burst = 0
+	} else if rate.burst == 0 {
+		rate.burst = rate.rate
+	}
+
+	return &cdscp.EffectiveConfig{
+		Prefixes:       coveringPrefixes(prefixes),
+		SourcePrefixes: coveringPrefixes(sourcePrefixes),
+		Rules:          rules,
+		Flag:           m.Config.flag,
+		Mark:           m.Config.mark,
+		FragmentPolicy: uint8(m.FragmentPolicy),
+		DefaultAction:  uint8(m.DefaultAction.action),
+		DefaultMark:    m.DefaultAction.mark,
+		RateThreshold:  rate.rate,
+		RateBurst:      rate.burst,
+		ExtMaxHeaders:  m.ExtLimits.maxHeaders,
+		ExtFlags:       m.ExtLimits.flags(),
+		FlowLogRate:    m.FlowLogRate,
+	}
+}
+
+// effectiveMismatches returns the fields of Config the effective config
+// differs from the expected one in.
+func effectiveMismatches(expected *cdscp.EffectiveConfig, effective *cdscp.EffectiveConfig) []string {
+	out := []string{}
+	if !slices.Equal(expected.Prefixes, coveringPrefixes(effective.Prefixes)) {
+		out = append(out, "prefixes")
+	}
+	if !slices.Equal(expected.SourcePrefixes, coveringPrefixes(effective.SourcePrefixes)) {
+		out = append(out, "source_prefixes")
+	}
+	if expected.Flag != effective.Flag || expected.Mark != effective.Mark {
+		out = append(out, "dscp_config")
+	}
+	if expected.FlowLogRate != effective.FlowLogRate {
+		out = append(out, "flow_log")
+	}
+	if expected.FragmentPolicy != effective.FragmentPolicy {
+		out = append(out, "fragment_policy")
+	}
+	if expected.ExtMaxHeaders != effective.ExtMaxHeaders || expected.ExtFlags != effective.ExtFlags {
+		out = append(out, "ext_header_limits")
+	}
+	if expected.DefaultAction != effective.DefaultAction || expected.DefaultMark != effective.DefaultMark {
+		out = append(out, "default_action")
+	}
+	if expected.RateThreshold != effective.RateThreshold || expected.RateBurst != effective.RateBurst {
+		out = append(out, "rate_threshold")
+	}
+	if !slices.Equal(expected.Rules, effective.Rules) {
+		out = append(out, "rules")
+	}
+	return out
+}
+
+func effectiveConfigProto(config *cdscp.EffectiveConfig) *dscppb.Config {
+	fragmentPolicy := dscppb.FragmentPolicy(config.FragmentPolicy)
+
+	rules := make([]*dscppb.Rule, 0, len(config.Rules))
+	for idx, rule := range config.Rules {
+		rules = append(rules, markingRule{
+			Priority:     uint32(idx),
+			Source:       rule.Source,
+			Destination:  rule.Destination,
+			Proto:        rule.Proto,
+			PortMin:      rule.PortMin,
+			PortMax:      rule.PortMax,
+			Marking:      dscpConfig{flag: rule.Flag, mark: rule.Mark},
+			TrafficClass: rule.TrafficClass,
+		}.proto(rule.Name))
+	}
+
+	return &dscppb.Config{
+		Prefixes:       prefixStrings(config.Prefixes),
+		SourcePrefixes: prefixStrings(config.SourcePrefixes),
+		DscpConfig: &dscppb.DscpConfig{
+			Flag: uint32(config.Flag),
+			Mark: uint32(config.Mark),
+		},
+		FlowLog: &dscppb.FlowLogConfig{
+			RateLimit: config.FlowLogRate,
+		},
+		FragmentPolicy: &fragmentPolicy,
+		ExtHeaderLimits: &dscppb.ExtHeaderLimits{
+			MaxHeaders:    uint32(config.ExtMaxHeaders),
+			SkipUnknown:   config.ExtFlags&extSkipUnknown != 0,
+			DropAnomalies: config.ExtFlags&extDropAnomaly != 0,
+		},
+		DefaultAction: &dscppb.DefaultActionConfig{
+			Action: dscppb.DefaultAction(config.DefaultAction),
+			Mark:   uint32(config.DefaultMark),
+		},
+		RateThreshold: &dscppb.RateThreshold{
+			Rate:  config.RateThreshold,
+			Burst: config.RateBurst,
+		},
+		Rules: rules,
+	}
+}
+
+// ShowEffectiveConfig returns the module config of the active generation
+// and the fields it differs from the applied config in.
+//
+// Unlike ShowConfig, the config is decoded from shared memory, so it shows
+// what the dataplane runs after a partially failed update or while a
+// stale generation is still active.
+func (m *DscpService) ShowEffectiveConfig(
+	ctx context.Context,
+	request *dscppb.ShowEffectiveConfigRequest,
+) (*dscppb.ShowEffectiveConfigResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	reader, ok := m.backend.(EffectiveConfigReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "backend cannot read the effective config")
+	}
+
+	name := request.GetName()
+
+	// Updates are held off while the config is decoded so that it is
+	// compared with the config it was applied from.
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	effective, err := reader.EffectiveConfig(name)
+	if errors.Is(err, cdscp.ErrNotActive) {
+		return nil, status.Errorf(codes.NotFound, "config %q is not active", name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read effective config %q: %v", name, err)
+	}
+
+	response := &dscppb.ShowEffectiveConfigResponse{
+		Config:           effectiveConfigProto(effective),
+		Generation:       effective.Generation,
+		ModuleGeneration: effective.ModuleGeneration,
+		Mismatches:       []string{},
+	}
+	if config, ok := m.configs[name]; ok {
+		response.Applied = true
+		response.Mismatches = effectiveMismatches(config.effectiveConfig(), effective)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,152 @@
+package dscp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"maps"
+	"net/netip"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// fingerprintGaugeBits is the number of leading fingerprint bits exported
+// as a gauge, the most a float64 represents exactly.
+const fingerprintGaugeBits = 52
+
+// fingerprint returns the hex-encoded SHA-256 of the rule set a config
+// publishes to the dataplane.
+//
+// Only what the dataplane receives is hashed: the matched prefixes, sorted,
+// the marking rules in the order they are matched and the marking settings.
+// Two instances applying the same rule set get the same fingerprint whatever
+// order the prefixes were added in, while the metadata of the rule groups
+// never changes it.
+func (m *config) fingerprint() string {
+	prefixes, sourcePrefixes := m.matchedPrefixes()
+
+	h := sha256.New()
+	writeFingerprintPrefixes(h, "dst", prefixes)
+	writeFingerprintPrefixes(h, "src", sourcePrefixes)
+	for _, rule := range m.backendRules() {
+		fmt.Fprintf(h, "rule %s %s %d %d %d %d %d\n",
+			rule.Source, rule.Destination, rule.Proto, rule.PortMin, rule.PortMax, rule.Flag, rule.Mark,
+		)
+		// Appended only when set, keeping the fingerprints of rules
+		// without a traffic class.
+		if rule.TrafficClass != 0 {
+			fmt.Fprintf(h, "rule_class %d\n", rule.TrafficClass)
+		}
+	}
+	fmt.Fprintf(h, "marking %d %d\n", m.Config.flag, m.Config.mark)
+	fmt.Fprintf(h, "fragment %d\n", m.FragmentPolicy)
+	fmt.Fprintf(h, "default %d %d\n", m.DefaultAction.action, m.DefaultAction.mark)
+	fmt.Fprintf(h, "rate %d %d\n", m.RateThreshold.rate, m.RateThreshold.burst)
+	fmt.Fprintf(h, "ext %d %d\n", m.ExtLimits.maxHeaders, m.ExtLimits.flags())
+	fmt.Fprintf(h, "flow_log %d\n", m.FlowLogRate)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeFingerprintPrefixes(h hash.Hash, kind string, prefixes []netip.Prefix) {
+	for _, prefix := range slices.SortedFunc(slices.Values(prefixes), xnetip.PrefixCompare) {
+		fmt.Fprintf(h, "%s %s\n", kind, prefix)
+	}
+}
+
+// instanceFingerprint returns the fingerprint of every config of the
+// instance together, from the fingerprints of the configs by name.
+func instanceFingerprint(fingerprints map[string]string) string {
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(fingerprints)) {
+		fmt.Fprintf(h, "%s %s\n", name, fingerprints[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintGauge returns the leading bits of a fingerprint as a gauge
+// value, equal on two instances iff their fingerprints most likely are.
+func fingerprintGauge(fingerprint string) float64 {
+	buf, err := hex.DecodeString(fingerprint)
+	if err != nil || len(buf) < 8 {
+		return 0
+	}
+	return float64(binary.BigEndian.Uint64(buf) >> (64 - fingerprintGaugeBits))
+}
+
+// GetFingerprint returns the fingerprints of the applied configs.
+//
+// Comparing fingerprints lets reconciliation tooling detect instances that
+// drifted apart without downloading their rule sets.
+func (m *DscpService) GetFingerprint(
+	ctx context.Context,
+	request *dscppb.GetFingerprintRequest,
+) (*dscppb.GetFingerprintResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	response := &dscppb.GetFingerprintResponse{}
+
+	name := request.GetName()
+	if name != "" {
+		config, ok := m.configs[name]
+		if !ok {
+			return nil, status.Error(codes.NotFound, "config not found")
+		}
+		response.Configs = []*dscppb.ConfigFingerprint{{Name: name, Fingerprint: config.Fingerprint}}
+		return response, nil
+	}
+
+	fingerprints := m.fingerprints()
+	response.Fingerprint = instanceFingerprint(fingerprints)
+	for _, name := range slices.Sorted(maps.Keys(fingerprints)) {
+		response.Configs = append(response.Configs, &dscppb.ConfigFingerprint{
+			Name:        name,
+			Fingerprint: fingerprints[name],
+		})
+	}
+	return response, nil
+}
+
+// fingerprints returns the fingerprints of the applied configs by name.
+//
+// The caller must hold mu.
+func (m *DscpService) fingerprints() map[string]string {
+	fingerprints := make(map[string]string, len(m.configs))
+	for name, config := range m.configs {
+		fingerprints[name] = config.Fingerprint
+	}
+	return fingerprints
+}
+
+// fingerprintMetrics returns the fingerprint gauges of every config and of
+// the instance.
+func (m *DscpService) fingerprintMetrics() []*commonpb.Metric {
+	m.mu.RLock()
+	fingerprints := m.fingerprints()
+	m.mu.RUnlock()
+
+	result := make([]*commonpb.Metric, 0, len(fingerprints)+1)
+	for _, name := range slices.Sorted(maps.Keys(fingerprints)) {
+		result = append(result, &commonpb.Metric{
+			Name:   "dscp_config_fingerprint",
+			Labels: []*commonpb.Label{{Name: "config", Value: name}},
+			Value:  &commonpb.Metric_Gauge{Gauge: fingerprintGauge(fingerprints[name])},
+		})
+	}
+	result = append(result, &commonpb.Metric{
+		Name:  "dscp_fingerprint",
+		Value: &commonpb.Metric_Gauge{Gauge: fingerprintGauge(instanceFingerprint(fingerprints))},
+	})
+
+	return result
+}
@@ -0,0 +1,165 @@
+package dscp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// flowWatcherBufferSize is the number of records buffered per WatchFlows
+// subscriber before new records are dropped.
+const flowWatcherBufferSize = 1024
+
+// flowWatchers is a registry of WatchFlows subscribers keyed by config name.
+type flowWatchers struct {
+	mu       sync.Mutex
+	watchers map[string]map[chan *dscppb.FlowRecord]struct{}
+}
+
+func newFlowWatchers() *flowWatchers {
+	return &flowWatchers{
+		watchers: map[string]map[chan *dscppb.FlowRecord]struct{}{},
+	}
+}
+
+// Subscribe registers a subscriber for flows of the named config.
+//
+// The returned function must be called to release the subscription.
+func (m *flowWatchers) Subscribe(name string) (<-chan *dscppb.FlowRecord, func()) {
+	ch := make(chan *dscppb.FlowRecord, flowWatcherBufferSize)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.watchers[name]; !ok {
+		m.watchers[name] = map[chan *dscppb.FlowRecord]struct{}{}
+	}
+	m.watchers[name][ch] = struct{}{}
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.watchers[name], ch)
+		if len(m.watchers[name]) == 0 {
+			delete(m.watchers, name)
+		}
+	}
+}
+
+// Publish delivers the record to all subscribers of the named config.
+//
+// Subscribers with a full buffer miss the record.
+func (m *flowWatchers) Publish(name string, record *dscppb.FlowRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.watchers[name] {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
+
+// flowLogCursor is the read position of a module generation's flow logs.
+type flowLogCursor struct {
+	module  ModuleHandle
+	indices []uint64
+}
+
+// RunFlowLog periodically drains the matched-flow logs of all configs,
+// writing each record to the service log and to WatchFlows subscribers.
+//
+// It blocks until the context is canceled.
+func (m *DscpService) RunFlowLog(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	cursors := map[string]*flowLogCursor{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.pollFlowLog(cursors)
+		}
+	}
+}
+
+func (m *DscpService) pollFlowLog(cursors map[string]*flowLogCursor) {
+	// Handles are freed under the write lock when a config is replaced,
+	// so they must only be read while holding the read lock.
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name := range cursors {
+		if _, ok := m.configs[name]; !ok {
+			delete(cursors, name)
+		}
+	}
+
+	for name, cfg := range m.configs {
+		if cfg.FlowLogRate == 0 {
+			delete(cursors, name)
+			continue
+		}
+
+		reader, ok := cfg.Module.(FlowLogReader)
+		if !ok {
+			continue
+		}
+
+		// Each module generation owns fresh flow logs, so the cursor
+		// restarts from the beginning when the handle changes.
+		cursor, ok := cursors[name]
+		if !ok || cursor.module != cfg.Module {
+			cursor = &flowLogCursor{
+				module:  cfg.Module,
+				indices: make([]uint64, reader.FlowLogWorkers()),
+			}
+			cursors[name] = cursor
+		}
+
+		for workerIdx := range cursor.indices {
+			records, nextIdx := reader.ReadFlows(uint64(workerIdx), cursor.indices[workerIdx])
+			cursor.indices[workerIdx] = nextIdx
+
+			for _, record := range records {
+				m.emitFlow(name, uint64(workerIdx), record)
+			}
+		}
+	}
+}
+
+func (m *DscpService) emitFlow(name string, workerIdx uint64, record cdscp.FlowRecord) {
+	m.log.Info("matched flow",
+		zap.String("config", name),
+		zap.Uint64("worker", workerIdx),
+		zap.Stringer("src_addr", record.Source),
+		zap.Uint16("src_port", record.SourcePort),
+		zap.Stringer("dst_addr", record.Destination),
+		zap.Uint16("dst_port", record.DestinationPort),
+		zap.Uint8("proto", record.Proto),
+		zap.Uint8("original_dscp", record.OriginalDSCP),
+		zap.Bool("remarked", record.Remarked),
+	)
+
+	m.flows.Publish(name, &dscppb.FlowRecord{
+		Name:         name,
+		Worker:       workerIdx,
+		Timestamp:    record.Timestamp,
+		SrcAddr:      record.Source.String(),
+		DstAddr:      record.Destination.String(),
+		SrcPort:      uint32(record.SourcePort),
+		DstPort:      uint32(record.DestinationPort),
+		Proto:        uint32(record.Proto),
+		OriginalDscp: uint32(record.OriginalDSCP),
+		Remarked:     record.Remarked,
+	})
+}
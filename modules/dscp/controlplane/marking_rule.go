@@ -0,0 +1,319 @@
+package dscp
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"math"
+	"net/netip"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// maxMarkingRules is the number of marking rules a config may hold, see
+// DSCP_RULES_MAX.
+const maxMarkingRules = 256
+
+// markingRule is a marking rule of a config, matched before its prefixes.
+type markingRule struct {
+	Priority uint32
+	// Source and Destination are invalid to match any address.
+	Source      netip.Prefix
+	Destination netip.Prefix
+	// Proto is zero to match any protocol.
+	Proto   uint8
+	PortMin uint16
+	PortMax uint16
+	Marking dscpConfig
+	// TrafficClass is zero to leave the class of the packets unset.
+	TrafficClass uint8
+}
+
+func newMarkingRule(rule *dscppb.Rule) (markingRule, error) {
+	out := markingRule{
+		Priority: rule.GetPriority(),
+		Proto:    uint8(rule.GetProto()),
+		PortMax:  math.MaxUint16,
+		Marking: dscpConfig{
+			flag: uint8(rule.GetRemark().Flag()),
+			mark: uint8(rule.GetMark()),
+		},
+		TrafficClass: uint8(rule.GetTrafficClass()),
+	}
+	if ports := rule.GetDstPorts(); ports != nil {
+		out.PortMin = uint16(ports.GetFrom())
+		out.PortMax = uint16(ports.GetTo())
+	}
+
+	var err error
+	if out.Source, err = parseRulePrefix(rule.GetSrcPrefix()); err != nil {
+		return markingRule{}, err
+	}
+	if out.Destination, err = parseRulePrefix(rule.GetDstPrefix()); err != nil {
+		return markingRule{}, err
+	}
+	if out.Source.IsValid() && out.Destination.IsValid() && out.Source.Addr().Is4() != out.Destination.Addr().Is4() {
+		return markingRule{}, status.Errorf(
+			codes.InvalidArgument,
+			"rule prefixes %s and %s are of different families",
+			out.Source,
+			out.Destination,
+		)
+	}
+
+	return out, nil
+}
+
+// parseRulePrefix parses a prefix of a rule, the invalid prefix matching
+// any address for an empty one.
+func parseRulePrefix(prefix string) (netip.Prefix, error) {
+	if prefix == "" {
+		return netip.Prefix{}, nil
+	}
+	prefixes, err := parsePrefixes([]string{prefix})
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefixes[0], nil
+}
+
+func (m markingRule) proto(name string) *dscppb.Rule {
+	rule := &dscppb.Rule{
+		Name:         name,
+		Priority:     m.Priority,
+		Proto:        uint32(m.Proto),
+		Mark:         uint32(m.Marking.mark),
+		Remark:       dscppb.RemarkPolicyFromFlag(uint32(m.Marking.flag)),
+		TrafficClass: uint32(m.TrafficClass),
+	}
+	if m.Source.IsValid() {
+		rule.SrcPrefix = m.Source.String()
+	}
+	if m.Destination.IsValid() {
+		rule.DstPrefix = m.Destination.String()
+	}
+	if m.PortMin != 0 || m.PortMax != math.MaxUint16 {
+		rule.DstPorts = &dscppb.PortRange{
+			From: uint32(m.PortMin),
+			To:   uint32(m.PortMax),
+		}
+	}
+	return rule
+}
+
+// sortedRuleNames returns the names of the rules in the order they are
+// matched: by priority, then by name.
+func sortedRuleNames(rules map[string]markingRule) []string {
+	return slices.SortedFunc(maps.Keys(rules), func(a, b string) int {
+		return cmp.Or(cmp.Compare(rules[a].Priority, rules[b].Priority), cmp.Compare(a, b))
+	})
+}
+
+// backendRules returns the rules of the config published to the dataplane,
+// in the order they are matched.
+func (m *config) backendRules() []cdscp.Rule {
+	out := make([]cdscp.Rule, 0, len(m.Rules))
+	for _, name := range sortedRuleNames(m.Rules) {
+		rule := m.Rules[name]
+		out = append(out, cdscp.Rule{
+			Name:         name,
+			Source:       rule.Source,
+			Destination:  rule.Destination,
+			Proto:        rule.Proto,
+			PortMin:      rule.PortMin,
+			PortMax:      rule.PortMax,
+			Flag:         rule.Marking.flag,
+			Mark:         rule.Marking.mark,
+			TrafficClass: rule.TrafficClass,
+		})
+	}
+	return out
+}
+
+// ruleStats returns the marking counts of every marking rule of the
+// configs, in the order they are matched.
+func (m *DscpService) ruleStats(
+	configs []string,
+	counts map[string]map[string]MarkingCounts,
+) []*dscppb.RuleStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]*dscppb.RuleStats, 0)
+	for _, name := range configs {
+		config, ok := m.configs[name]
+		if !ok {
+			continue
+		}
+		for _, ruleName := range sortedRuleNames(config.Rules) {
+			out = append(out, &dscppb.RuleStats{
+				Config: name,
+				Name:   ruleName,
+				Counts: counts[name][ruleName].proto(),
+			})
+		}
+	}
+	return out
+}
+
+func rulesProto(rules map[string]markingRule) []*dscppb.Rule {
+	out := make([]*dscppb.Rule, 0, len(rules))
+	for _, name := range sortedRuleNames(rules) {
+		out = append(out, rules[name].proto(name))
+	}
+	return out
+}
+
+// diffRules returns the rules present only in the proposed set, the ones
+// present only in the applied set and the ones of both sets that differ,
+// matched by name and ordered as they are matched.
+func diffRules(
+	applied map[string]markingRule,
+	proposed map[string]markingRule,
+) (added []*dscppb.Rule, removed []*dscppb.Rule, changed []*dscppb.RuleDiff) {
+	for _, name := range sortedRuleNames(proposed) {
+		rule := proposed[name]
+		current, ok := applied[name]
+		switch {
+		case !ok:
+			added = append(added, rule.proto(name))
+		case current != rule:
+			changed = append(changed, &dscppb.RuleDiff{
+				Current:  current.proto(name),
+				Proposed: rule.proto(name),
+			})
+		}
+	}
+	for _, name := range sortedRuleNames(applied) {
+		if _, ok := proposed[name]; !ok {
+			removed = append(removed, applied[name].proto(name))
+		}
+	}
+	return added, removed, changed
+}
+
+// AddRule adds a marking rule to a config or replaces the rule of the same
+// name.
+//
+// Rules classify packets by their addresses, protocol and destination port
+// with a marking of their own, so a config can mark, say, the VoIP ports of
+// a customer differently from the rest of its traffic.
+func (m *DscpService) AddRule(
+	ctx context.Context,
+	request *dscppb.AddRuleRequest,
+) (*dscppb.AddRuleResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	ruleName := request.GetRule().GetName()
+	rule, err := newMarkingRule(request.GetRule())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.reservedMarks.Check(name, rule.Marking); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	if _, ok := cfg.Rules[ruleName]; !ok && len(cfg.Rules) >= maxMarkingRules {
+		return nil, status.Errorf(
+			codes.ResourceExhausted,
+			"module config %q already has %d marking rules",
+			name,
+			maxMarkingRules,
+		)
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = map[string]markingRule{}
+	}
+	cfg.Rules[ruleName] = rule
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	m.log.Info("added marking rule",
+		zap.String("name", name),
+		zap.String("rule", ruleName),
+		zap.Uint32("priority", rule.Priority),
+		zap.Uint8("traffic_class", rule.TrafficClass),
+	)
+
+	return &dscppb.AddRuleResponse{}, nil
+}
+
+// DeleteRule deletes a marking rule of a config.
+func (m *DscpService) DeleteRule(
+	ctx context.Context,
+	request *dscppb.DeleteRuleRequest,
+) (*dscppb.DeleteRuleResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	ruleName := request.GetRule()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currConfig, ok := m.configs[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "config not found")
+	}
+	if _, ok := currConfig.Rules[ruleName]; !ok {
+		return nil, status.Errorf(codes.NotFound, "marking rule %q not found", ruleName)
+	}
+
+	cfg := currConfig.Clone()
+	delete(cfg.Rules, ruleName)
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	m.log.Info("deleted marking rule",
+		zap.String("name", name),
+		zap.String("rule", ruleName),
+	)
+
+	return &dscppb.DeleteRuleResponse{}, nil
+}
+
+// ListRules returns the marking rules of a config in the order they are
+// matched.
+func (m *DscpService) ListRules(
+	ctx context.Context,
+	request *dscppb.ListRulesRequest,
+) (*dscppb.ListRulesResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config, ok := m.configs[request.GetName()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "config not found")
+	}
+
+	return &dscppb.ListRulesResponse{
+		Rules: rulesProto(config.Rules),
+	}, nil
+}
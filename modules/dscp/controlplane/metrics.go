@@ -0,0 +1,214 @@
+package dscp
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// MetricsService exposes DSCP module metrics over its own gRPC service.
+type MetricsService struct {
+	dscppb.UnimplementedMetricsServiceServer
+
+	service *DscpService
+}
+
+// NewMetricsService creates a MetricsService backed by the DSCP service.
+func NewMetricsService(service *DscpService) *MetricsService {
+	return &MetricsService{service: service}
+}
+
+// GetMetrics returns a snapshot of all DSCP module metrics.
+func (m *MetricsService) GetMetrics(
+	ctx context.Context,
+	req *dscppb.GetMetricsRequest,
+) (*dscppb.GetMetricsResponse, error) {
+	return &dscppb.GetMetricsResponse{Metrics: m.service.Metrics()}, nil
+}
+
+// Metrics returns the rule table utilization and the fingerprint gauges,
+// followed by the egress DSCP histograms, the IPv6 extension header anomalies, the
+// default action hits and the marking counts, of the configs and of their
+// rules, as packet counters.
+//
+// DSCP values, anomalies and default actions without packets are omitted
+// to reduce output noise. A fingerprint gauge carries the leading bits of
+// the fingerprint returned by GetFingerprint; the one without a config
+// label is the fingerprint of the whole instance.
+//
+// Labels:
+//   - config:   DSCP config name
+//   - device:   dataplane device name; counters only
+//   - pipeline: pipeline name; counters only
+//   - function: pipeline function name; counters only
+//   - chain:    pipeline chain name; counters only
+//   - dscp:     DSCP value of the packets, 0-63; egress only
+//   - anomaly:  extension header anomaly: limit, unknown or malformed;
+//     anomalies only
+//   - rule:     marking rule name; rule marking counts only
+//   - result:   matched, remarked or untouched; marking counts only
+func (m *DscpService) Metrics() []*commonpb.Metric {
+	result := m.ruleTableMetrics()
+	result = append(result, m.fingerprintMetrics()...)
+
+	reader, ok := m.backend.(EgressStatsReader)
+	if !ok {
+		return result
+	}
+
+	egressStats, err := reader.EgressStats()
+	if err != nil {
+		m.log.Warn("failed to read egress statistics", zap.Error(err))
+		return result
+	}
+
+	for _, stats := range egressStats {
+		for dscp, packets := range stats.Packets {
+			if packets == 0 {
+				continue
+			}
+
+			result = append(result, &commonpb.Metric{
+				Name: "dscp_egress_packets",
+				Labels: []*commonpb.Label{
+					{Name: "config", Value: stats.Position.ModuleName},
+					{Name: "device", Value: stats.Position.Device},
+					{Name: "pipeline", Value: stats.Position.Pipeline},
+					{Name: "function", Value: stats.Position.Function},
+					{Name: "chain", Value: stats.Position.Chain},
+					{Name: "dscp", Value: strconv.Itoa(dscp)},
+				},
+				Value: &commonpb.Metric_Counter{Counter: packets},
+			})
+		}
+
+		for anomaly, packets := range stats.ExtAnomalies {
+			if packets == 0 {
+				continue
+			}
+
+			result = append(result, &commonpb.Metric{
+				Name: "dscp_ext_anomaly_packets",
+				Labels: []*commonpb.Label{
+					{Name: "config", Value: stats.Position.ModuleName},
+					{Name: "device", Value: stats.Position.Device},
+					{Name: "pipeline", Value: stats.Position.Pipeline},
+					{Name: "function", Value: stats.Position.Function},
+					{Name: "chain", Value: stats.Position.Chain},
+					{Name: "anomaly", Value: extAnomalyLabel(dscppb.ExtAnomaly(anomaly))},
+				},
+				Value: &commonpb.Metric_Counter{Counter: packets},
+			})
+		}
+
+		if stats.DefaultActionHits != 0 {
+			result = append(result, &commonpb.Metric{
+				Name: "dscp_default_action_packets",
+				Labels: []*commonpb.Label{
+					{Name: "config", Value: stats.Position.ModuleName},
+					{Name: "device", Value: stats.Position.Device},
+					{Name: "pipeline", Value: stats.Position.Pipeline},
+					{Name: "function", Value: stats.Position.Function},
+					{Name: "chain", Value: stats.Position.Chain},
+				},
+				Value: &commonpb.Metric_Counter{Counter: stats.DefaultActionHits},
+			})
+		}
+
+		positionLabels := []*commonpb.Label{
+			{Name: "config", Value: stats.Position.ModuleName},
+			{Name: "device", Value: stats.Position.Device},
+			{Name: "pipeline", Value: stats.Position.Pipeline},
+			{Name: "function", Value: stats.Position.Function},
+			{Name: "chain", Value: stats.Position.Chain},
+		}
+		result = append(result, markingMetrics("dscp_marking_packets", "dscp_marking_bytes", stats.Marking, positionLabels)...)
+		for _, name := range slices.Sorted(maps.Keys(stats.Rules)) {
+			labels := append(slices.Clone(positionLabels), &commonpb.Label{Name: "rule", Value: name})
+			result = append(result, markingMetrics("dscp_rule_packets", "dscp_rule_bytes", stats.Rules[name], labels)...)
+		}
+	}
+
+	return result
+}
+
+// markingMetrics returns a packets and a bytes counter per result of the
+// marking counts, the ones without packets omitted.
+func markingMetrics(packetsName string, bytesName string, counts MarkingCounts, labels []*commonpb.Label) []*commonpb.Metric {
+	results := []struct {
+		name    string
+		packets uint64
+		bytes   uint64
+	}{
+		{"matched", counts.Matched, counts.MatchedBytes},
+		{"remarked", counts.Remarked, counts.RemarkedBytes},
+		{"untouched", counts.Untouched, counts.MatchedBytes - counts.RemarkedBytes},
+	}
+
+	out := make([]*commonpb.Metric, 0, 2*len(results))
+	for _, result := range results {
+		if result.packets == 0 {
+			continue
+		}
+		resultLabels := append(slices.Clone(labels), &commonpb.Label{Name: "result", Value: result.name})
+		out = append(out,
+			&commonpb.Metric{
+				Name:   packetsName,
+				Labels: resultLabels,
+				Value:  &commonpb.Metric_Counter{Counter: result.packets},
+			},
+			&commonpb.Metric{
+				Name:   bytesName,
+				Labels: slices.Clone(resultLabels),
+				Value:  &commonpb.Metric_Counter{Counter: result.bytes},
+			},
+		)
+	}
+	return out
+}
+
+// ruleTableMetrics returns the shared memory held by every config whose
+// module handle reports it, and its utilization of the rule table if the
+// capacity is known.
+func (m *DscpService) ruleTableMetrics() []*commonpb.Metric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*commonpb.Metric, 0)
+	for _, name := range slices.Sorted(maps.Keys(m.configs)) {
+		reader, ok := m.configs[name].Module.(MemoryUsageReader)
+		if !ok {
+			continue
+		}
+		usage := reader.MemoryUsage()
+		labels := []*commonpb.Label{{Name: "config", Value: name}}
+
+		result = append(result, &commonpb.Metric{
+			Name:   "dscp_rule_table_bytes",
+			Labels: labels,
+			Value:  &commonpb.Metric_Gauge{Gauge: float64(usage)},
+		})
+		if m.ruleTableCapacity != 0 {
+			result = append(result, &commonpb.Metric{
+				Name:   "dscp_rule_table_utilization",
+				Labels: labels,
+				Value:  &commonpb.Metric_Gauge{Gauge: float64(usage) / float64(m.ruleTableCapacity)},
+			})
+		}
+	}
+
+	return result
+}
+
+// extAnomalyLabel returns the metric label value of an extension header
+// anomaly, such as "malformed".
+func extAnomalyLabel(anomaly dscppb.ExtAnomaly) string {
+	return strings.ToLower(strings.TrimPrefix(anomaly.String(), "EXT_ANOMALY_"))
+}
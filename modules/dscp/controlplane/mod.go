@@ -1,7 +1,10 @@
 package dscp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -10,6 +13,9 @@ import (
 	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
 )
 
+// flowLogPollInterval is how often the matched-flow logs are drained.
+const flowLogPollInterval = 100 * time.Millisecond
+
 // DscpModule is a control-plane component of a module that is responsible for
 // DSCP marking of packets.
 type DscpModule struct {
@@ -17,12 +23,21 @@ type DscpModule struct {
 	shm         *ffi.SharedMemory
 	agent       *ffi.Agent
 	dscpService *DscpService
-	log         *zap.Logger
+	// metricsService exposes the egress DSCP histograms.
+	metricsService *MetricsService
+	log            *zap.Logger
 }
 
 func NewDSCPModule(cfg *Config, log *zap.Logger) (*DscpModule, error) {
 	log = log.With(zap.String("module", "dscp"))
 
+	if err := cfg.RuleTable.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ReservedMarks.Validate(); err != nil {
+		return nil, err
+	}
+
 	shm, err := ffi.AttachSharedMemory(cfg.MemoryPath.Unwrap())
 	if err != nil {
 		return nil, err
@@ -34,19 +49,35 @@ func NewDSCPModule(cfg *Config, log *zap.Logger) (*DscpModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach("dscp", cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		"dscp",
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
 
-	dscpService := NewDscpService(newBackend(agent))
+	capacity := cfg.MemoryRequirements.Unwrap()
+	if cfg.MemoryQuota != 0 {
+		capacity = cfg.MemoryQuota
+	}
+
+	dscpService := NewDscpService(
+		newBackend(agent, newRuleTableLimits(capacity, cfg.RuleTable, log)),
+		WithDscpServiceLog(log),
+		WithDscpServiceRuleTableCapacity(capacity),
+		WithDscpServiceReservedMarks(cfg.ReservedMarks),
+	)
 
 	return &DscpModule{
-		cfg:         cfg,
-		shm:         shm,
-		agent:       agent,
-		dscpService: dscpService,
-		log:         log,
+		cfg:            cfg,
+		shm:            shm,
+		agent:          agent,
+		dscpService:    dscpService,
+		metricsService: NewMetricsService(dscpService),
+		log:            log,
 	}, nil
 }
 
@@ -59,11 +90,31 @@ func (m *DscpModule) Endpoint() string {
 }
 
 func (m *DscpModule) ServicesNames() []string {
-	return []string{"modules.dscp.controlplane.dscppb.v1.DscpService"}
+	return []string{
+		"modules.dscp.controlplane.dscppb.v1.DscpService",
+		dscppb.MetricsService_ServiceDesc.ServiceName,
+	}
 }
 
 func (m *DscpModule) RegisterService(server *grpc.Server) {
 	dscppb.RegisterDscpServiceServer(server, m.dscpService)
+	dscppb.RegisterMetricsServiceServer(server, m.metricsService)
+}
+
+// Run runs the module until the specified context is canceled.
+// Implements the gateway.BackgroundService interface.
+func (m *DscpModule) Run(ctx context.Context) error {
+	err := m.dscpService.RunFlowLog(ctx, flowLogPollInterval)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *DscpModule) Health() error {
+	return m.agent.Health()
 }
 
 // Close closes the module.
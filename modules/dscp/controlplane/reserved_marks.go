@@ -0,0 +1,110 @@
+package dscp
+
+import (
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// dscpCS6 is the class selector 6 codepoint, used by routing
+	// protocols.
+	dscpCS6 = 48
+	// dscpCS7 is the class selector 7 codepoint, reserved for network
+	// control.
+	dscpCS7 = 56
+)
+
+// ReservedMarkPolicy is the handling of marking rules setting a reserved
+// codepoint.
+type ReservedMarkPolicy string
+
+const (
+	// ReservedMarkReject refuses the rule with INVALID_ARGUMENT.
+	ReservedMarkReject ReservedMarkPolicy = "reject"
+	// ReservedMarkWarn applies the rule, logging a warning.
+	ReservedMarkWarn ReservedMarkPolicy = "warn"
+	// ReservedMarkAllow applies the rule silently.
+	ReservedMarkAllow ReservedMarkPolicy = "allow"
+)
+
+// ReservedMarksConfig protects the codepoints of network control traffic.
+//
+// Marking user traffic with them would let it compete with routing
+// protocols and starve them under load.
+type ReservedMarksConfig struct {
+	// Policy is the handling of rules setting a reserved codepoint:
+	// reject, warn or allow.
+	Policy ReservedMarkPolicy `yaml:"policy"`
+	// Codepoints lists the reserved codepoints, CS6 and CS7 if empty.
+	Codepoints []uint8 `yaml:"codepoints"`
+	// AllowedConfigs lists the module configs explicitly allowed to set
+	// reserved codepoints, whatever the policy.
+	AllowedConfigs []string `yaml:"allowed_configs"`
+}
+
+// Validate validates the reserved marks config.
+func (m *ReservedMarksConfig) Validate() error {
+	switch m.Policy {
+	case ReservedMarkReject, ReservedMarkWarn, ReservedMarkAllow:
+	default:
+		return fmt.Errorf("unknown reserved mark policy %q", m.Policy)
+	}
+	for _, codepoint := range m.Codepoints {
+		if codepoint > 63 {
+			return fmt.Errorf("reserved codepoint %d is out of [0, 63]", codepoint)
+		}
+	}
+	return nil
+}
+
+// reservedMarks checks the marking rules against the reserved codepoints
+// before they are applied.
+type reservedMarks struct {
+	cfg ReservedMarksConfig
+	log *zap.Logger
+}
+
+func newReservedMarks(cfg ReservedMarksConfig, log *zap.Logger) *reservedMarks {
+	if len(cfg.Codepoints) == 0 {
+		cfg.Codepoints = []uint8{dscpCS6, dscpCS7}
+	}
+
+	return &reservedMarks{
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// Check refuses or warns about a marking rule of the named module config
+// setting a reserved codepoint, according to the policy.
+//
+// A nil reservedMarks allows every rule.
+func (m *reservedMarks) Check(name string, marking dscpConfig) error {
+	if m == nil || marking.flag == 0 || !slices.Contains(m.cfg.Codepoints, marking.mark) {
+		return nil
+	}
+	if slices.Contains(m.cfg.AllowedConfigs, name) {
+		return nil
+	}
+
+	switch m.cfg.Policy {
+	case ReservedMarkReject:
+		return status.Errorf(
+			codes.InvalidArgument,
+			"module config %q may not mark with the reserved codepoint %d",
+			name,
+			marking.mark,
+		)
+	case ReservedMarkWarn:
+		m.log.Warn("marking with a reserved codepoint",
+			zap.String("name", name),
+			zap.Uint8("mark", marking.mark),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package dscp
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// ruleGroup is a named set of prefixes matched only while the group is
+// enabled.
+type ruleGroup struct {
+	// Prefixes are matched against the destination address.
+	Prefixes []netip.Prefix
+	// SourcePrefixes are matched against the source address.
+	SourcePrefixes []netip.Prefix
+	Enabled        bool
+	// Stage is the pipeline stage the group applies at, any for
+	// dscppb.Stage_STAGE_ANY.
+	Stage dscppb.Stage
+	// Description and Labels are kept by the controlplane only, to audit
+	// and select groups, and are never published to the dataplane.
+	Description string
+	Labels      map[string]string
+}
+
+func (m *ruleGroup) Clone() *ruleGroup {
+	return &ruleGroup{
+		Prefixes:       slices.Clone(m.Prefixes),
+		SourcePrefixes: slices.Clone(m.SourcePrefixes),
+		Enabled:        m.Enabled,
+		Stage:          m.Stage,
+		Description:    m.Description,
+		Labels:         maps.Clone(m.Labels),
+	}
+}
+
+// MatchLabels reports whether the group carries every label of the
+// selector. An empty selector matches every group.
+func (m *ruleGroup) MatchLabels(selector map[string]string) bool {
+	for key, value := range selector {
+		if v, ok := m.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func cloneRuleGroups(groups map[string]*ruleGroup) map[string]*ruleGroup {
+	out := make(map[string]*ruleGroup, len(groups))
+	for name, group := range groups {
+		out[name] = group.Clone()
+	}
+	return out
+}
+
+// updateRules replaces the prefixes of the named rule group, or the
+// ungrouped prefixes for an empty group name, with the ones fn returns.
+//
+// A missing group is created enabled and a group left without prefixes is
+// deleted.
+func (m *config) updateRules(
+	group string,
+	fn func(prefixes, sourcePrefixes []netip.Prefix) ([]netip.Prefix, []netip.Prefix),
+) {
+	if group == "" {
+		m.Prefixes, m.SourcePrefixes = fn(m.Prefixes, m.SourcePrefixes)
+		return
+	}
+
+	rules, ok := m.Groups[group]
+	if !ok {
+		rules = &ruleGroup{Enabled: true}
+	}
+	rules.Prefixes, rules.SourcePrefixes = fn(rules.Prefixes, rules.SourcePrefixes)
+
+	if len(rules.Prefixes) == 0 && len(rules.SourcePrefixes) == 0 {
+		delete(m.Groups, group)
+		return
+	}
+	if m.Groups == nil {
+		m.Groups = map[string]*ruleGroup{}
+	}
+	m.Groups[group] = rules
+}
+
+// matchedPrefixes returns the destination and source prefixes published to
+// the dataplane: the ungrouped ones merged with those of the enabled rule
+// groups applying at the stage of the config.
+func (m *config) matchedPrefixes() ([]netip.Prefix, []netip.Prefix) {
+	prefixes := m.Prefixes
+	sourcePrefixes := m.SourcePrefixes
+	for _, group := range m.Groups {
+		if group.Enabled && group.Stage.AppliesAt(m.Stage) {
+			prefixes = mergePrefixes(prefixes, group.Prefixes)
+			sourcePrefixes = mergePrefixes(sourcePrefixes, group.SourcePrefixes)
+		}
+	}
+	return prefixes, sourcePrefixes
+}
+
+// hasGroupMatching reports whether a rule group of the config carries every
+// label of the selector.
+func (m *config) hasGroupMatching(selector map[string]string) bool {
+	for _, group := range m.Groups {
+		if group.MatchLabels(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleGroupsProto returns the rule groups matching the label selector.
+func ruleGroupsProto(groups map[string]*ruleGroup, selector map[string]string) []*dscppb.RuleGroup {
+	out := make([]*dscppb.RuleGroup, 0, len(groups))
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		group := groups[name]
+		if !group.MatchLabels(selector) {
+			continue
+		}
+		out = append(out, group.proto(name))
+	}
+	return out
+}
+
+func (m *ruleGroup) proto(name string) *dscppb.RuleGroup {
+	return &dscppb.RuleGroup{
+		Name:           name,
+		Prefixes:       prefixStrings(m.Prefixes),
+		SourcePrefixes: prefixStrings(m.SourcePrefixes),
+		Enabled:        m.Enabled,
+		Stage:          m.Stage,
+		Description:    m.Description,
+		Labels:         maps.Clone(m.Labels),
+	}
+}
+
+func newRuleGroup(group *dscppb.RuleGroup) (*ruleGroup, error) {
+	prefixes, err := parsePrefixes(group.GetPrefixes())
+	if err != nil {
+		return nil, err
+	}
+	sourcePrefixes, err := parsePrefixes(group.GetSourcePrefixes())
+	if err != nil {
+		return nil, err
+	}
+	return &ruleGroup{
+		Prefixes:       prefixes,
+		SourcePrefixes: sourcePrefixes,
+		Enabled:        group.GetEnabled(),
+		Stage:          group.GetStage(),
+		Description:    group.GetDescription(),
+		Labels:         maps.Clone(group.GetLabels()),
+	}, nil
+}
+
+// diffRuleGroups returns the groups present only in the proposed set, the
+// ones present only in the applied set and the ones of both sets whose
+// prefixes, state or stage differ, matched and ordered by name.
+func diffRuleGroups(
+	applied map[string]*ruleGroup,
+	proposed map[string]*ruleGroup,
+) (added []*dscppb.RuleGroup, removed []*dscppb.RuleGroup, changed []*dscppb.RuleGroupDiff) {
+	for _, name := range slices.Sorted(maps.Keys(proposed)) {
+		group := proposed[name]
+		current, ok := applied[name]
+		if !ok {
+			added = append(added, group.proto(name))
+			continue
+		}
+
+		addedPrefixes, removedPrefixes := diffPrefixes(current.Prefixes, group.Prefixes)
+		addedSource, removedSource := diffPrefixes(current.SourcePrefixes, group.SourcePrefixes)
+		diff := &dscppb.RuleGroupDiff{
+			Name:                  name,
+			AddedPrefixes:         prefixStrings(addedPrefixes),
+			RemovedPrefixes:       prefixStrings(removedPrefixes),
+			AddedSourcePrefixes:   prefixStrings(addedSource),
+			RemovedSourcePrefixes: prefixStrings(removedSource),
+		}
+		if group.Enabled != current.Enabled {
+			diff.Enabled = &dscppb.RuleGroupEnabledDiff{
+				Current:  current.Enabled,
+				Proposed: group.Enabled,
+			}
+		}
+		if group.Stage != current.Stage {
+			diff.Stage = &dscppb.StageDiff{
+				Current:  current.Stage,
+				Proposed: group.Stage,
+			}
+		}
+
+		if len(addedPrefixes) != 0 || len(removedPrefixes) != 0 ||
+			len(addedSource) != 0 || len(removedSource) != 0 ||
+			diff.Enabled != nil || diff.Stage != nil {
+			changed = append(changed, diff)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(applied)) {
+		if _, ok := proposed[name]; !ok {
+			removed = append(removed, applied[name].proto(name))
+		}
+	}
+	return added, removed, changed
+}
+
+// SetRuleGroupEnabled enables or disables a rule group of a config.
+//
+// The group keeps its prefixes while disabled, so an emergency "stop
+// marking this customer" action is a single reversible call.
+func (m *DscpService) SetRuleGroupEnabled(
+	ctx context.Context,
+	request *dscppb.SetRuleGroupEnabledRequest,
+) (*dscppb.SetRuleGroupEnabledResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	groupName := request.GetGroup()
+	enabled := request.GetEnabled()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currConfig, ok := m.configs[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "config not found")
+	}
+	group, ok := currConfig.Groups[groupName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "rule group %q not found", groupName)
+	}
+	if group.Enabled == enabled {
+		return &dscppb.SetRuleGroupEnabledResponse{}, nil
+	}
+
+	cfg := currConfig.Clone()
+	cfg.Groups[groupName].Enabled = enabled
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	m.log.Info("toggled rule group",
+		zap.String("name", name),
+		zap.String("group", groupName),
+		zap.Bool("enabled", enabled),
+	)
+
+	return &dscppb.SetRuleGroupEnabledResponse{}, nil
+}
+
+// SetRuleGroupMetadata replaces the description and the labels of a rule
+// group of a config.
+//
+// The metadata never reaches the dataplane, so the module config is not
+// updated.
+func (m *DscpService) SetRuleGroupMetadata(
+	ctx context.Context,
+	request *dscppb.SetRuleGroupMetadataRequest,
+) (*dscppb.SetRuleGroupMetadataResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	groupName := request.GetGroup()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currConfig, ok := m.configs[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "config not found")
+	}
+	group, ok := currConfig.Groups[groupName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "rule group %q not found", groupName)
+	}
+
+	group.Description = request.GetDescription()
+	group.Labels = maps.Clone(request.GetLabels())
+
+	m.log.Info("updated rule group metadata",
+		zap.String("name", name),
+		zap.String("group", groupName),
+		zap.String("description", group.Description),
+		zap.Any("labels", group.Labels),
+	)
+
+	return &dscppb.SetRuleGroupMetadataResponse{}, nil
+}
+
+// SetStage sets the pipeline stage a config runs at or, given a group, the
+// stage one of its rule groups applies at.
+//
+// Remarking before the routing and policing decisions changes what they
+// see, so a group can be pinned to the ingress classification or the egress
+// marking stage. A missing config is created with no prefixes, while a
+// missing group is refused.
+func (m *DscpService) SetStage(
+	ctx context.Context,
+	request *dscppb.SetStageRequest,
+) (*dscppb.SetStageResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	groupName := request.GetGroup()
+	stage := request.GetStage()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+
+	if groupName == "" {
+		cfg.Stage = stage
+	} else {
+		group, ok := cfg.Groups[groupName]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "rule group %q not found", groupName)
+		}
+		group.Stage = stage
+	}
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	m.log.Info("set stage",
+		zap.String("name", name),
+		zap.String("group", groupName),
+		zap.Stringer("stage", stage),
+	)
+
+	return &dscppb.SetStageResponse{}, nil
+}
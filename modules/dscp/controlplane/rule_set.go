@@ -0,0 +1,181 @@
+package dscp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
+)
+
+// RuleSet is the desired state of the module configs, as kept in a config
+// repository and pushed with the DscpService calls.
+type RuleSet struct {
+	// Configs are the module configs by name.
+	Configs map[string]RuleSetConfig `yaml:"configs"`
+}
+
+// RuleSetConfig is a module config of a rule set.
+type RuleSetConfig struct {
+	// Prefixes are matched against the destination address.
+	Prefixes []string `yaml:"prefixes"`
+	// SourcePrefixes are matched against the source address.
+	SourcePrefixes []string `yaml:"source_prefixes"`
+	// Marking is the marking of the packets matching the prefixes.
+	Marking RuleSetMarking `yaml:"marking"`
+	// Rules are the marking rules, matched before the prefixes.
+	Rules []RuleSetRule `yaml:"rules"`
+}
+
+// RuleSetMarking is a DSCP marking of a rule set.
+type RuleSetMarking struct {
+	// Flag is 0 to never mark, 1 to mark only the packets with a zero DSCP
+	// value and 2 to always mark.
+	Flag uint32 `yaml:"flag"`
+	// Mark is the DSCP value set, within [0, 63].
+	Mark uint32 `yaml:"mark"`
+}
+
+// RuleSetRule is a marking rule of a rule set.
+type RuleSetRule struct {
+	Name     string `yaml:"name"`
+	Priority uint32 `yaml:"priority"`
+	// SrcPrefix and DstPrefix are empty to match any address.
+	SrcPrefix string `yaml:"src_prefix"`
+	DstPrefix string `yaml:"dst_prefix"`
+	// Proto is zero to match any protocol.
+	Proto uint32 `yaml:"proto"`
+	// DstPorts is nil to match any destination port.
+	DstPorts     *RuleSetPorts  `yaml:"dst_ports"`
+	Marking      RuleSetMarking `yaml:"marking"`
+	TrafficClass uint32         `yaml:"traffic_class"`
+}
+
+// RuleSetPorts is an inclusive port range of a rule set.
+type RuleSetPorts struct {
+	From uint32 `yaml:"from"`
+	To   uint32 `yaml:"to"`
+}
+
+// ValidateRuleSet checks a rule set the way the module checks the calls
+// building its configs: the prefixes, the markings, the marking rules and
+// their count against the rule table, and the reserved codepoints.
+//
+// It needs no dataplane, so it suits the pre-merge checks of a config
+// repository. The shared memory a config holds is only known once it is
+// built, so the rule table utilization thresholds are left to the module.
+// Reserved codepoints under the warn policy pass silently.
+func ValidateRuleSet(cfg *Config, ruleSet *RuleSet) error {
+	if err := cfg.RuleTable.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.ReservedMarks.Validate(); err != nil {
+		return err
+	}
+
+	reserved := newReservedMarks(cfg.ReservedMarks, zap.NewNop())
+	for _, name := range slices.Sorted(maps.Keys(ruleSet.Configs)) {
+		config := ruleSet.Configs[name]
+		if err := config.validate(name, reserved); err != nil {
+			return fmt.Errorf("invalid module config %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *RuleSetConfig) validate(name string, reserved *reservedMarks) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "config name is required")
+	}
+	if _, err := parsePrefixes(m.Prefixes); err != nil {
+		return err
+	}
+	if _, err := parsePrefixes(m.SourcePrefixes); err != nil {
+		return err
+	}
+
+	marking, err := m.Marking.compile()
+	if err != nil {
+		return err
+	}
+	if err := reserved.Check(name, marking); err != nil {
+		return err
+	}
+
+	if len(m.Rules) > maxMarkingRules {
+		return status.Errorf(
+			codes.ResourceExhausted,
+			"module config %q has %d marking rules, at most %d fit the rule table",
+			name,
+			len(m.Rules),
+			maxMarkingRules,
+		)
+	}
+
+	names := map[string]struct{}{}
+	for _, r := range m.Rules {
+		if _, ok := names[r.Name]; ok {
+			return status.Errorf(codes.InvalidArgument, "duplicate marking rule %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		// The rule marking is checked before it is folded into a remark
+		// policy, which accepts any flag.
+		if _, err := r.Marking.compile(); err != nil {
+			return fmt.Errorf("invalid marking rule %q: %w", r.Name, err)
+		}
+
+		rule := r.proto()
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid marking rule %q: %w", r.Name, err)
+		}
+		compiled, err := newMarkingRule(rule)
+		if err != nil {
+			return fmt.Errorf("invalid marking rule %q: %w", r.Name, err)
+		}
+		if err := reserved.Check(name, compiled.Marking); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m RuleSetMarking) compile() (dscpConfig, error) {
+	marking := &dscppb.DscpConfig{
+		Flag: m.Flag,
+		Mark: m.Mark,
+	}
+	if err := marking.Validate(); err != nil {
+		return dscpConfig{}, err
+	}
+	return dscpConfig{
+		flag: uint8(m.Flag),
+		mark: uint8(m.Mark),
+	}, nil
+}
+
+func (m *RuleSetRule) proto() *dscppb.Rule {
+	rule := &dscppb.Rule{
+		Name:         m.Name,
+		Priority:     m.Priority,
+		SrcPrefix:    m.SrcPrefix,
+		DstPrefix:    m.DstPrefix,
+		Proto:        m.Proto,
+		Mark:         m.Marking.Mark,
+		Remark:       dscppb.RemarkPolicyFromFlag(m.Marking.Flag),
+		TrafficClass: m.TrafficClass,
+	}
+	if m.DstPorts != nil {
+		rule.DstPorts = &dscppb.PortRange{
+			From: m.DstPorts.From,
+			To:   m.DstPorts.To,
+		}
+	}
+	return rule
+}
@@ -0,0 +1,92 @@
+package dscp
+
+import (
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RuleTableConfig configures the rule table utilization thresholds.
+//
+// The utilization of a module config is the shared memory its prefixes
+// hold relative to the rule table capacity: the per-module memory quota if
+// set, the agent memory otherwise.
+type RuleTableConfig struct {
+	// WarnThreshold is the utilization, within [0, 1], above which config
+	// updates are logged as warnings.
+	//
+	// Zero disables the warnings.
+	WarnThreshold float64 `yaml:"warn_threshold"`
+	// RefuseThreshold is the utilization, within [0, 1], above which config
+	// updates are refused with RESOURCE_EXHAUSTED, keeping the previous
+	// config applied.
+	//
+	// Zero disables the refusals.
+	RefuseThreshold float64 `yaml:"refuse_threshold"`
+}
+
+// Validate validates the rule table config.
+func (m *RuleTableConfig) Validate() error {
+	if m.WarnThreshold < 0 || m.WarnThreshold > 1 {
+		return fmt.Errorf("rule table warn threshold %v is out of [0, 1]", m.WarnThreshold)
+	}
+	if m.RefuseThreshold < 0 || m.RefuseThreshold > 1 {
+		return fmt.Errorf("rule table refuse threshold %v is out of [0, 1]", m.RefuseThreshold)
+	}
+	return nil
+}
+
+// ruleTableLimits checks the module configs against the rule table
+// thresholds before they are published.
+type ruleTableLimits struct {
+	capacity datasize.ByteSize
+	cfg      RuleTableConfig
+	log      *zap.Logger
+}
+
+func newRuleTableLimits(capacity datasize.ByteSize, cfg RuleTableConfig, log *zap.Logger) *ruleTableLimits {
+	return &ruleTableLimits{
+		capacity: capacity,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// Utilization returns the fraction of the capacity the usage takes.
+func (m *ruleTableLimits) Utilization(usage uint64) float64 {
+	if m.capacity == 0 {
+		return 0
+	}
+	return float64(usage) / float64(m.capacity)
+}
+
+// Check refuses a module config whose usage crosses the refuse threshold
+// and warns about one crossing the warn threshold.
+func (m *ruleTableLimits) Check(name string, usage uint64) error {
+	utilization := m.Utilization(usage)
+
+	if m.cfg.RefuseThreshold > 0 && utilization > m.cfg.RefuseThreshold {
+		return status.Errorf(
+			codes.ResourceExhausted,
+			"module config %q would use %.1f%% of the rule table, above the refuse threshold of %.1f%%",
+			name,
+			utilization*100,
+			m.cfg.RefuseThreshold*100,
+		)
+	}
+
+	if m.cfg.WarnThreshold > 0 && utilization > m.cfg.WarnThreshold {
+		m.log.Warn("rule table utilization is above the warn threshold",
+			zap.String("name", name),
+			zap.Stringer("usage", datasize.ByteSize(usage)),
+			zap.Stringer("capacity", m.capacity),
+			zap.Float64("utilization", utilization),
+			zap.Float64("threshold", m.cfg.WarnThreshold),
+		)
+	}
+
+	return nil
+}
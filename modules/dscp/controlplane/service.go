@@ -2,14 +2,19 @@ package dscp
 
 import (
 	"context"
+	"maps"
 	"net/netip"
 	"slices"
 	"sync"
 
+	"github.com/c2h5oh/datasize"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
 	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
 )
 
@@ -25,11 +30,215 @@ type ModuleHandle interface {
 	Free()
 }
 
+// FlowLogReader is implemented by module handles that expose the
+// matched-flow log written by dataplane workers.
+type FlowLogReader interface {
+	// FlowLogWorkers returns the number of per-worker flow logs.
+	FlowLogWorkers() uint64
+	// ReadFlows returns records of the worker starting at fromIdx and
+	// the index to continue reading from.
+	ReadFlows(workerIdx uint64, fromIdx uint64) ([]cdscp.FlowRecord, uint64)
+}
+
+// MemoryUsageReader is implemented by module handles that report the
+// shared memory they hold.
+type MemoryUsageReader interface {
+	// MemoryUsage returns the number of shared memory bytes held.
+	MemoryUsage() uint64
+}
+
+const (
+	// dscpValues is the number of distinct DSCP values.
+	dscpValues = 64
+	// egressCounterName is the module counter holding the egress DSCP
+	// histogram, see DSCP_EGRESS_COUNTER.
+	egressCounterName = "dscp_egress"
+	// extAnomalies is the number of IPv6 extension header anomaly kinds,
+	// see enum dscp_ext_anomaly.
+	extAnomalies = 3
+	// extAnomalyCounterName is the module counter holding the IPv6
+	// extension header anomalies, see DSCP_EXT_ANOMALY_COUNTER.
+	extAnomalyCounterName = "dscp_ext_anomaly"
+	// defaultActionCounterName is the module counter holding the default
+	// action hits, see DSCP_DEFAULT_ACTION_COUNTER.
+	defaultActionCounterName = "dscp_default_action"
+	// markingCounterName is the module counter holding the marking counts
+	// of a config, see DSCP_MARKING_COUNTER.
+	markingCounterName = "dscp_marking"
+	// ruleCounterPrefix prefixes the rule name in the name of the module
+	// counter holding the marking counts of a rule, see
+	// DSCP_RULE_COUNTER_PREFIX.
+	ruleCounterPrefix = "dscp_rule:"
+)
+
+// MarkingCounts is the number of packets matching a marking rule or a
+// prefix, and of their bytes, by what marking did to them, see enum
+// dscp_marking_counter.
+type MarkingCounts struct {
+	Matched       uint64
+	Remarked      uint64
+	Untouched     uint64
+	MatchedBytes  uint64
+	RemarkedBytes uint64
+}
+
+// newMarkingCounts returns the counts of the slots of a marking counter
+// summed over the workers.
+func newMarkingCounts(workerVals [][]uint64) MarkingCounts {
+	slots := [5]uint64{}
+	for _, vals := range workerVals {
+		for idx, value := range vals[:min(len(vals), len(slots))] {
+			slots[idx] += value
+		}
+	}
+	return MarkingCounts{
+		Matched:       slots[0],
+		Remarked:      slots[1],
+		Untouched:     slots[2],
+		MatchedBytes:  slots[3],
+		RemarkedBytes: slots[4],
+	}
+}
+
+func (m *MarkingCounts) add(other MarkingCounts) {
+	m.Matched += other.Matched
+	m.Remarked += other.Remarked
+	m.Untouched += other.Untouched
+	m.MatchedBytes += other.MatchedBytes
+	m.RemarkedBytes += other.RemarkedBytes
+}
+
+func (m MarkingCounts) proto() *dscppb.MarkingCounts {
+	return &dscppb.MarkingCounts{
+		Matched:       m.Matched,
+		Remarked:      m.Remarked,
+		Untouched:     m.Untouched,
+		MatchedBytes:  m.MatchedBytes,
+		RemarkedBytes: m.RemarkedBytes,
+	}
+}
+
+// dscpMarkAlways is the marking flag overwriting the DSCP value of a
+// packet, see DSCP_MARK_ALWAYS.
+const dscpMarkAlways uint8 = 2
+
+// Extension header limit flags, see DSCP_EXT_* flags.
+const (
+	extSkipUnknown uint8 = 1 << 0
+	extDropAnomaly uint8 = 1 << 1
+)
+
+// EgressStats is the egress DSCP histogram of a module config at one
+// pipeline position.
+type EgressStats struct {
+	Position ffi.ModuleReference
+	// Packets is the number of packets that left the module, indexed by
+	// their DSCP value.
+	Packets [dscpValues]uint64
+	// ExtAnomalies is the number of IPv6 packets with an extension header
+	// anomaly, indexed by dscppb.ExtAnomaly.
+	ExtAnomalies [extAnomalies]uint64
+	// DefaultActionHits is the number of packets the default action was
+	// taken on.
+	DefaultActionHits uint64
+	// Marking is the number of packets matching a marking rule or a
+	// prefix.
+	Marking MarkingCounts
+	// Rules are the marking counts of the marking rules by rule name.
+	Rules map[string]MarkingCounts
+}
+
+// EgressStatsReader is implemented by backends that can read the egress
+// DSCP histograms from the dataplane counters.
+type EgressStatsReader interface {
+	// EgressStats returns the histograms of all dscp module positions.
+	EgressStats() ([]EgressStats, error)
+}
+
+// EffectiveConfigReader is implemented by backends that can decode the
+// module configs of the active generation.
+type EffectiveConfigReader interface {
+	// EffectiveConfig returns the named module config of the active
+	// generation, cdscp.ErrNotActive if there is none.
+	EffectiveConfig(name string) (*cdscp.EffectiveConfig, error)
+}
+
+// ModuleSettings is the module config published to the dataplane, as
+// lowered from a config of the service.
+type ModuleSettings struct {
+	// Prefixes are the destination prefixes the module matches.
+	Prefixes []netip.Prefix
+	// SourcePrefixes are the source prefixes the module matches.
+	SourcePrefixes []netip.Prefix
+	// Rules are the marking rules, matched before the prefixes.
+	Rules []cdscp.Rule
+	// Flag and Mark are the DSCP marking of the matched packets.
+	Flag uint8
+	Mark uint8
+	// FragmentPolicy is the handling of non-initial fragments.
+	FragmentPolicy uint8
+	// DefaultAction and DefaultMark are the handling of packets matching
+	// no prefix.
+	DefaultAction uint8
+	DefaultMark   uint8
+	// RateThreshold and RateBurst are the rate matched packets are marked
+	// above.
+	RateThreshold uint32
+	RateBurst     uint32
+	// ExtMaxHeaders and ExtFlags bound the IPv6 extension header chain.
+	ExtMaxHeaders uint8
+	ExtFlags      uint8
+	// FlowLogRate is the per-worker limit of logged flows per second.
+	FlowLogRate uint32
+}
+
 // Backend abstracts shared memory operations.
 type Backend interface {
-	// UpdateModule creates a module config, applies mutations, and publishes it
-	// to the dataplane.
-	UpdateModule(name string, prefixes []netip.Prefix, flag uint8, mark uint8) (ModuleHandle, error)
+	// UpdateModule creates a module config, applies the settings, and
+	// publishes it to the dataplane.
+	UpdateModule(name string, settings ModuleSettings) (ModuleHandle, error)
+}
+
+// DscpServiceOption configures the DscpService constructor.
+type DscpServiceOption func(*dscpServiceOptions)
+
+type dscpServiceOptions struct {
+	Log               *zap.Logger
+	RuleTableCapacity datasize.ByteSize
+	ReservedMarks     *ReservedMarksConfig
+}
+
+func newDscpServiceOptions() *dscpServiceOptions {
+	return &dscpServiceOptions{
+		Log: zap.NewNop(),
+	}
+}
+
+// WithDscpServiceLog sets the logger for the DscpService.
+func WithDscpServiceLog(log *zap.Logger) DscpServiceOption {
+	return func(o *dscpServiceOptions) {
+		o.Log = log
+	}
+}
+
+// WithDscpServiceRuleTableCapacity sets the rule table capacity the
+// utilization gauges are relative to.
+//
+// Zero, the default, exposes the held memory only.
+func WithDscpServiceRuleTableCapacity(capacity datasize.ByteSize) DscpServiceOption {
+	return func(o *dscpServiceOptions) {
+		o.RuleTableCapacity = capacity
+	}
+}
+
+// WithDscpServiceReservedMarks guards the reserved codepoints against
+// marking rules.
+//
+// Every codepoint may be set by default.
+func WithDscpServiceReservedMarks(cfg ReservedMarksConfig) DscpServiceOption {
+	return func(o *dscpServiceOptions) {
+		o.ReservedMarks = &cfg
+	}
 }
 
 type DscpService struct {
@@ -38,19 +247,62 @@ type DscpService struct {
 	mu      sync.RWMutex
 	backend Backend
 	configs map[string]*config
+
+	flows *flowWatchers
+
+	// ruleTableCapacity is the capacity of the rule table, zero if
+	// unknown.
+	ruleTableCapacity datasize.ByteSize
+	// reservedMarks is nil if every codepoint may be set.
+	reservedMarks *reservedMarks
+
+	log *zap.Logger
 }
 
 type config struct {
+	// Prefixes are matched against the destination address.
 	Prefixes []netip.Prefix
-	Config   dscpConfig
-	Module   ModuleHandle
+	// SourcePrefixes are matched against the source address.
+	SourcePrefixes []netip.Prefix
+	Config         dscpConfig
+	// FragmentPolicy is the handling of non-initial fragments.
+	FragmentPolicy dscppb.FragmentPolicy
+	// DefaultAction is the handling of packets matching no prefix.
+	DefaultAction defaultAction
+	// RateThreshold is the rate matched packets are marked above.
+	RateThreshold rateThreshold
+	// ExtLimits bounds the IPv6 extension header chain.
+	ExtLimits extLimits
+	// FlowLogRate is the per-worker limit of logged flows per second.
+	FlowLogRate uint32
+	// Stage is the pipeline stage the config runs at.
+	Stage dscppb.Stage
+	// Groups are the named rule groups, matched in addition to the
+	// ungrouped prefixes while enabled.
+	Groups map[string]*ruleGroup
+	// Rules are the marking rules by name, matched before the prefixes.
+	Rules map[string]markingRule
+	// Fingerprint is the fingerprint of the rule set published to the
+	// dataplane, empty until the config is applied.
+	Fingerprint string
+	Module      ModuleHandle
 }
 
 func (m *config) Clone() *config {
 	return &config{
-		Prefixes: slices.Clone(m.Prefixes),
-		Config:   m.Config,
-		Module:   m.Module,
+		Prefixes:       slices.Clone(m.Prefixes),
+		SourcePrefixes: slices.Clone(m.SourcePrefixes),
+		Config:         m.Config,
+		FragmentPolicy: m.FragmentPolicy,
+		DefaultAction:  m.DefaultAction,
+		RateThreshold:  m.RateThreshold,
+		ExtLimits:      m.ExtLimits,
+		FlowLogRate:    m.FlowLogRate,
+		Stage:          m.Stage,
+		Groups:         cloneRuleGroups(m.Groups),
+		Rules:          maps.Clone(m.Rules),
+		Fingerprint:    m.Fingerprint,
+		Module:         m.Module,
 	}
 }
 
@@ -59,10 +311,104 @@ type dscpConfig struct {
 	mark uint8
 }
 
-func NewDscpService(backend Backend) *DscpService {
+type defaultAction struct {
+	action dscppb.DefaultAction
+	mark   uint8
+}
+
+func newDefaultAction(action *dscppb.DefaultActionConfig) defaultAction {
+	return defaultAction{
+		action: action.GetAction(),
+		mark:   uint8(action.GetMark()),
+	}
+}
+
+func (m defaultAction) proto() *dscppb.DefaultActionConfig {
+	return &dscppb.DefaultActionConfig{
+		Action: m.action,
+		Mark:   uint32(m.mark),
+	}
+}
+
+// marking returns the marking the action applies, guarded by the reserved
+// codepoints like the marking of matched packets.
+func (m defaultAction) marking() dscpConfig {
+	if m.action != dscppb.DefaultAction_DEFAULT_ACTION_MARK {
+		return dscpConfig{}
+	}
+	return dscpConfig{flag: dscpMarkAlways, mark: m.mark}
+}
+
+type rateThreshold struct {
+	rate  uint32
+	burst uint32
+}
+
+func newRateThreshold(threshold *dscppb.RateThreshold) rateThreshold {
+	return rateThreshold{
+		rate:  threshold.GetRate(),
+		burst: threshold.GetBurst(),
+	}
+}
+
+func (m rateThreshold) proto() *dscppb.RateThreshold {
+	return &dscppb.RateThreshold{
+		Rate:  m.rate,
+		Burst: m.burst,
+	}
+}
+
+type extLimits struct {
+	maxHeaders    uint8
+	skipUnknown   bool
+	dropAnomalies bool
+}
+
+func newExtLimits(limits *dscppb.ExtHeaderLimits) extLimits {
+	return extLimits{
+		maxHeaders:    uint8(limits.GetMaxHeaders()),
+		skipUnknown:   limits.GetSkipUnknown(),
+		dropAnomalies: limits.GetDropAnomalies(),
+	}
+}
+
+func (m extLimits) flags() uint8 {
+	flags := uint8(0)
+	if m.skipUnknown {
+		flags |= extSkipUnknown
+	}
+	if m.dropAnomalies {
+		flags |= extDropAnomaly
+	}
+	return flags
+}
+
+func (m extLimits) proto() *dscppb.ExtHeaderLimits {
+	return &dscppb.ExtHeaderLimits{
+		MaxHeaders:    uint32(m.maxHeaders),
+		SkipUnknown:   m.skipUnknown,
+		DropAnomalies: m.dropAnomalies,
+	}
+}
+
+func NewDscpService(backend Backend, options ...DscpServiceOption) *DscpService {
+	opts := newDscpServiceOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	var marks *reservedMarks
+	if opts.ReservedMarks != nil {
+		marks = newReservedMarks(*opts.ReservedMarks, opts.Log)
+	}
+
 	return &DscpService{
-		backend: backend,
-		configs: map[string]*config{},
+		backend:           backend,
+		configs:           map[string]*config{},
+		flows:             newFlowWatchers(),
+		ruleTableCapacity: opts.RuleTableCapacity,
+		reservedMarks:     marks,
+		log:               opts.Log,
 	}
 }
 
@@ -77,7 +423,11 @@ func (m *DscpService) ListConfigs(
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for name := range m.configs {
+	selector := request.GetLabels()
+	for name, config := range m.configs {
+		if len(selector) > 0 && !config.hasGroupMatching(selector) {
+			continue
+		}
 		response.Configs = append(response.Configs, name)
 	}
 
@@ -103,17 +453,23 @@ func (m *DscpService) ShowConfig(
 		return nil, status.Error(codes.NotFound, "config not found")
 	}
 
-	prefixes := make([]string, 0, len(config.Prefixes))
-	for _, p := range config.Prefixes {
-		prefixes = append(prefixes, p.String())
-	}
-
 	response.Config = &dscppb.Config{
-		Prefixes: prefixes,
+		Prefixes:       prefixStrings(config.Prefixes),
+		SourcePrefixes: prefixStrings(config.SourcePrefixes),
 		DscpConfig: &dscppb.DscpConfig{
 			Flag: uint32(config.Config.flag),
 			Mark: uint32(config.Config.mark),
 		},
+		FlowLog: &dscppb.FlowLogConfig{
+			RateLimit: config.FlowLogRate,
+		},
+		FragmentPolicy:  &config.FragmentPolicy,
+		ExtHeaderLimits: config.ExtLimits.proto(),
+		Groups:          ruleGroupsProto(config.Groups, request.GetLabels()),
+		DefaultAction:   config.DefaultAction.proto(),
+		RateThreshold:   config.RateThreshold.proto(),
+		Stage:           &config.Stage,
+		Rules:           rulesProto(config.Rules),
 	}
 
 	return response, nil
@@ -141,18 +497,19 @@ func (m *DscpService) AddPrefixes(
 		cfg = currConfig.Clone()
 	}
 
-	cfg.Prefixes = slices.Compact(
-		slices.SortedFunc(
-			slices.Values(slices.Concat(cfg.Prefixes, toAdd)),
-			xnetip.PrefixCompare,
-		),
-	)
+	direction := request.GetDirection()
+	cfg.updateRules(request.GetGroup(), func(prefixes, sourcePrefixes []netip.Prefix) ([]netip.Prefix, []netip.Prefix) {
+		if direction.MatchesDestination() {
+			prefixes = mergePrefixes(prefixes, toAdd)
+		}
+		if direction.MatchesSource() {
+			sourcePrefixes = mergePrefixes(sourcePrefixes, toAdd)
+		}
+		return prefixes, sourcePrefixes
+	})
 
 	if err := m.updateModuleConfig(name, cfg); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update module config %q: %v", name, err,
-		)
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
 	}
 
 	return &dscppb.AddPrefixesResponse{}, nil
@@ -182,18 +539,19 @@ func (m *DscpService) RemovePrefixes(
 		cfg = currConfig.Clone()
 	}
 
-	cfg.Prefixes = slices.DeleteFunc(
-		cfg.Prefixes,
-		func(prefix netip.Prefix) bool {
-			return slices.Contains(toRemove, prefix)
-		},
-	)
+	direction := request.GetDirection()
+	cfg.updateRules(request.GetGroup(), func(prefixes, sourcePrefixes []netip.Prefix) ([]netip.Prefix, []netip.Prefix) {
+		if direction.MatchesDestination() {
+			prefixes = subtractPrefixes(prefixes, toRemove)
+		}
+		if direction.MatchesSource() {
+			sourcePrefixes = subtractPrefixes(sourcePrefixes, toRemove)
+		}
+		return prefixes, sourcePrefixes
+	})
 
 	if err := m.updateModuleConfig(name, cfg); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update module config %q: %v", name, err,
-		)
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
 	}
 
 	return &dscppb.RemovePrefixesResponse{}, nil
@@ -222,24 +580,438 @@ func (m *DscpService) SetDscpMarking(
 		flag: flag,
 		mark: mark,
 	}
+	if err := m.reservedMarks.Check(name, cfg.Config); err != nil {
+		return nil, err
+	}
 
 	if err := m.updateModuleConfig(name, cfg); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update module config %q: %v", name, err,
-		)
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
 	}
 
 	return &dscppb.SetDscpMarkingResponse{}, nil
 }
 
-func (m *DscpService) updateModuleConfig(name string, cfg *config) error {
-	module, err := m.backend.UpdateModule(
-		name,
-		cfg.Prefixes,
-		cfg.Config.flag,
-		cfg.Config.mark,
+// SetRateThreshold makes the marking of the config conditional on the
+// rate of the matched packets, marking only the packets exceeding it.
+//
+// The packets within the rate keep their DSCP value, so marking the excess
+// with a higher drop precedence implements the in and out of profile
+// marking of AF classes.
+func (m *DscpService) SetRateThreshold(
+	ctx context.Context,
+	request *dscppb.SetRateThresholdRequest,
+) (*dscppb.SetRateThresholdResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	cfg.RateThreshold = newRateThreshold(request.GetRateThreshold())
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	return &dscppb.SetRateThresholdResponse{}, nil
+}
+
+// SetFragmentPolicy sets how non-initial fragments are classified.
+//
+// Initial and unfragmented packets are always matched by the module
+// prefixes.
+func (m *DscpService) SetFragmentPolicy(
+	ctx context.Context,
+	request *dscppb.SetFragmentPolicyRequest,
+) (*dscppb.SetFragmentPolicyResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	cfg.FragmentPolicy = request.GetPolicy()
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	return &dscppb.SetFragmentPolicyResponse{}, nil
+}
+
+// SetDefaultAction sets the action taken on packets matching no prefix of
+// the config: passing them unchanged, the default, marking them with the
+// default mark or dropping them.
+//
+// The action applies once the module classifies packets, so it takes
+// effect even while the marking of matched packets is disabled.
+func (m *DscpService) SetDefaultAction(
+	ctx context.Context,
+	request *dscppb.SetDefaultActionRequest,
+) (*dscppb.SetDefaultActionResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	cfg.DefaultAction = newDefaultAction(request.GetDefaultAction())
+	if err := m.reservedMarks.Check(name, cfg.DefaultAction.marking()); err != nil {
+		return nil, err
+	}
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	return &dscppb.SetDefaultActionResponse{}, nil
+}
+
+// SetExtHeaderLimits bounds the IPv6 extension header chain walked before
+// a packet is classified.
+//
+// Packets with an anomalous chain are counted and either passed without
+// marking or dropped.
+func (m *DscpService) SetExtHeaderLimits(
+	ctx context.Context,
+	request *dscppb.SetExtHeaderLimitsRequest,
+) (*dscppb.SetExtHeaderLimitsResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	cfg.ExtLimits = newExtLimits(request.GetLimits())
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	return &dscppb.SetExtHeaderLimitsResponse{}, nil
+}
+
+// SetFlowLog configures rate-limited logging of flows matched by the
+// module prefixes.
+//
+// Logged flows are written to the service log and delivered to WatchFlows
+// subscribers.
+func (m *DscpService) SetFlowLog(
+	ctx context.Context,
+	request *dscppb.SetFlowLogRequest,
+) (*dscppb.SetFlowLogResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig.Clone()
+	}
+	cfg.FlowLogRate = request.GetFlowLog().GetRateLimit()
+
+	if err := m.updateModuleConfig(name, cfg); err != nil {
+		return nil, ffi.UpdateStatus(err, "failed to update module config %q", name)
+	}
+
+	return &dscppb.SetFlowLogResponse{}, nil
+}
+
+// WatchFlows streams flows matched by the prefixes of the named config.
+//
+// Records are dropped for subscribers that do not keep up with the flow
+// log rate.
+func (m *DscpService) WatchFlows(
+	request *dscppb.WatchFlowsRequest,
+	stream dscppb.DscpService_WatchFlowsServer,
+) error {
+	if err := request.Validate(); err != nil {
+		return err
+	}
+
+	ch, unsubscribe := m.flows.Subscribe(request.GetName())
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case record := <-ch:
+			if err := stream.Send(record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DiffConfig compares the proposed configuration with the applied one.
+//
+// Nothing is applied, so the result can be reviewed before issuing the
+// mutating requests.
+func (m *DscpService) DiffConfig(
+	ctx context.Context,
+	request *dscppb.DiffConfigRequest,
+) (*dscppb.DiffConfigResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+	proposed := request.GetConfig()
+	prefixes, err := parsePrefixes(proposed.GetPrefixes())
+	if err != nil {
+		return nil, err
+	}
+	sourcePrefixes, err := parsePrefixes(proposed.GetSourcePrefixes())
+	if err != nil {
+		return nil, err
+	}
+	rules := map[string]markingRule{}
+	if request.GetCompareRules() {
+		for _, rule := range proposed.GetRules() {
+			if rules[rule.GetName()], err = newMarkingRule(rule); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	groups := map[string]*ruleGroup{}
+	if request.GetCompareGroups() {
+		for _, group := range proposed.GetGroups() {
+			if groups[group.GetName()], err = newRuleGroup(group); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cfg := &config{}
+	if currConfig, ok := m.configs[name]; ok {
+		cfg = currConfig
+	}
+
+	added, removed := diffPrefixes(cfg.Prefixes, prefixes)
+	addedSource, removedSource := diffPrefixes(cfg.SourcePrefixes, sourcePrefixes)
+	response := &dscppb.DiffConfigResponse{
+		AddedPrefixes:         prefixStrings(added),
+		RemovedPrefixes:       prefixStrings(removed),
+		AddedSourcePrefixes:   prefixStrings(addedSource),
+		RemovedSourcePrefixes: prefixStrings(removedSource),
+	}
+
+	if dscpConfig := proposed.GetDscpConfig(); dscpConfig != nil {
+		if dscpConfig.GetFlag() != uint32(cfg.Config.flag) || dscpConfig.GetMark() != uint32(cfg.Config.mark) {
+			response.DscpConfig = &dscppb.DscpConfigDiff{
+				Current: &dscppb.DscpConfig{
+					Flag: uint32(cfg.Config.flag),
+					Mark: uint32(cfg.Config.mark),
+				},
+				Proposed: dscpConfig,
+			}
+		}
+	}
+
+	if flowLog := proposed.GetFlowLog(); flowLog != nil {
+		if flowLog.GetRateLimit() != cfg.FlowLogRate {
+			response.FlowLog = &dscppb.FlowLogConfigDiff{
+				Current: &dscppb.FlowLogConfig{
+					RateLimit: cfg.FlowLogRate,
+				},
+				Proposed: flowLog,
+			}
+		}
+	}
+
+	if policy := proposed.FragmentPolicy; policy != nil && *policy != cfg.FragmentPolicy {
+		response.FragmentPolicy = &dscppb.FragmentPolicyDiff{
+			Current:  cfg.FragmentPolicy,
+			Proposed: *policy,
+		}
+	}
+
+	if limits := proposed.GetExtHeaderLimits(); limits != nil && newExtLimits(limits) != cfg.ExtLimits {
+		response.ExtHeaderLimits = &dscppb.ExtHeaderLimitsDiff{
+			Current:  cfg.ExtLimits.proto(),
+			Proposed: limits,
+		}
+	}
+
+	if action := proposed.GetDefaultAction(); action != nil && newDefaultAction(action) != cfg.DefaultAction {
+		response.DefaultAction = &dscppb.DefaultActionDiff{
+			Current:  cfg.DefaultAction.proto(),
+			Proposed: action,
+		}
+	}
+
+	if threshold := proposed.GetRateThreshold(); threshold != nil && newRateThreshold(threshold) != cfg.RateThreshold {
+		response.RateThreshold = &dscppb.RateThresholdDiff{
+			Current:  cfg.RateThreshold.proto(),
+			Proposed: threshold,
+		}
+	}
+
+	if stage := proposed.Stage; stage != nil && *stage != cfg.Stage {
+		response.Stage = &dscppb.StageDiff{
+			Current:  cfg.Stage,
+			Proposed: *stage,
+		}
+	}
+
+	if request.GetCompareRules() {
+		response.AddedRules, response.RemovedRules, response.ChangedRules = diffRules(cfg.Rules, rules)
+	}
+	if request.GetCompareGroups() {
+		response.AddedGroups, response.RemovedGroups, response.ChangedGroups = diffRuleGroups(cfg.Groups, groups)
+	}
+
+	return response, nil
+}
+
+// CloneConfig copies the applied configuration of a config to the target
+// configs, applying the requested transforms to the copy.
+//
+// A module process serves a single dataplane instance, so a policy is
+// spread across instances by cloning it on each of them.
+func (m *DscpService) CloneConfig(
+	ctx context.Context,
+	request *dscppb.CloneConfigRequest,
+) (*dscppb.CloneConfigResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	transforms := request.GetTransforms()
+	toAdd, err := parsePrefixes(transforms.GetAddPrefixes())
+	if err != nil {
+		return nil, err
+	}
+	toRemove, err := parsePrefixes(transforms.GetRemovePrefixes())
+	if err != nil {
+		return nil, err
+	}
+
+	name := request.GetName()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source, ok := m.configs[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "config not found")
+	}
+
+	for _, target := range request.GetTargets() {
+		cfg := source.Clone()
+		// The module handle belongs to the source, the target one is freed
+		// once the copy is applied.
+		cfg.Module = nil
+		if currConfig, ok := m.configs[target]; ok {
+			cfg.Module = currConfig.Module
+		}
+
+		direction := transforms.GetDirection()
+		if direction.MatchesDestination() {
+			cfg.Prefixes = subtractPrefixes(mergePrefixes(cfg.Prefixes, toAdd), toRemove)
+		}
+		if direction.MatchesSource() {
+			cfg.SourcePrefixes = subtractPrefixes(mergePrefixes(cfg.SourcePrefixes, toAdd), toRemove)
+		}
+		if marking := transforms.GetDscpConfig(); marking != nil {
+			cfg.Config = dscpConfig{
+				flag: uint8(marking.GetFlag()),
+				mark: uint8(marking.GetMark()),
+			}
+		}
+		if transforms != nil && transforms.FragmentPolicy != nil {
+			cfg.FragmentPolicy = *transforms.FragmentPolicy
+		}
+		if limits := transforms.GetExtHeaderLimits(); limits != nil {
+			cfg.ExtLimits = newExtLimits(limits)
+		}
+		if flowLog := transforms.GetFlowLog(); flowLog != nil {
+			cfg.FlowLogRate = flowLog.GetRateLimit()
+		}
+		if action := transforms.GetDefaultAction(); action != nil {
+			cfg.DefaultAction = newDefaultAction(action)
+		}
+		if threshold := transforms.GetRateThreshold(); threshold != nil {
+			cfg.RateThreshold = newRateThreshold(threshold)
+		}
+		if transforms != nil && transforms.Stage != nil {
+			cfg.Stage = *transforms.Stage
+		}
+		if err := m.reservedMarks.Check(target, cfg.Config); err != nil {
+			return nil, err
+		}
+		if err := m.reservedMarks.Check(target, cfg.DefaultAction.marking()); err != nil {
+			return nil, err
+		}
+
+		if err := m.updateModuleConfig(target, cfg); err != nil {
+			return nil, ffi.UpdateStatus(err, "failed to update module config %q", target)
+		}
+	}
+
+	m.log.Info("cloned module config",
+		zap.String("name", name),
+		zap.Strings("targets", request.GetTargets()),
 	)
+
+	return &dscppb.CloneConfigResponse{}, nil
+}
+
+func (m *DscpService) updateModuleConfig(name string, cfg *config) error {
+	prefixes, sourcePrefixes := cfg.matchedPrefixes()
+	module, err := m.backend.UpdateModule(name, ModuleSettings{
+		Prefixes:       prefixes,
+		SourcePrefixes: sourcePrefixes,
+		Rules:          cfg.backendRules(),
+		Flag:           cfg.Config.flag,
+		Mark:           cfg.Config.mark,
+		FragmentPolicy: uint8(cfg.FragmentPolicy),
+		DefaultAction:  uint8(cfg.DefaultAction.action),
+		DefaultMark:    cfg.DefaultAction.mark,
+		RateThreshold:  cfg.RateThreshold.rate,
+		RateBurst:      cfg.RateThreshold.burst,
+		ExtMaxHeaders:  cfg.ExtLimits.maxHeaders,
+		ExtFlags:       cfg.ExtLimits.flags(),
+		FlowLogRate:    cfg.FlowLogRate,
+	})
 	if err != nil {
 		return err
 	}
@@ -249,9 +1021,19 @@ func (m *DscpService) updateModuleConfig(name string, cfg *config) error {
 	}
 
 	m.configs[name] = &config{
-		Prefixes: cfg.Prefixes,
-		Config:   cfg.Config,
-		Module:   module,
+		Prefixes:       cfg.Prefixes,
+		SourcePrefixes: cfg.SourcePrefixes,
+		Config:         cfg.Config,
+		FragmentPolicy: cfg.FragmentPolicy,
+		DefaultAction:  cfg.DefaultAction,
+		RateThreshold:  cfg.RateThreshold,
+		ExtLimits:      cfg.ExtLimits,
+		FlowLogRate:    cfg.FlowLogRate,
+		Stage:          cfg.Stage,
+		Groups:         cfg.Groups,
+		Rules:          cfg.Rules,
+		Fingerprint:    cfg.fingerprint(),
+		Module:         module,
 	}
 
 	return nil
@@ -273,3 +1055,172 @@ func parsePrefixes(prefixes []string) ([]netip.Prefix, error) {
 
 	return out, nil
 }
+
+// ShowStats returns the egress DSCP histogram, the IPv6 extension header
+// anomalies and the marking counts of the selected configs summed over
+// all of their pipeline positions.
+//
+// Every marking rule is listed, so that a rule matching no traffic shows
+// up with zero counts.
+func (m *DscpService) ShowStats(
+	ctx context.Context,
+	request *dscppb.ShowStatsRequest,
+) (*dscppb.ShowStatsResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	names, err := m.selectConfigs(request.GetName(), request.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	reader, ok := m.backend.(EgressStatsReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "egress statistics are not supported by the backend")
+	}
+
+	packets := [dscpValues]uint64{}
+	anomalies := [extAnomalies]uint64{}
+	defaultActionHits := uint64(0)
+	marking := MarkingCounts{}
+	rules := map[string]map[string]MarkingCounts{}
+	egressStats, err := reader.EgressStats()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read egress statistics: %v", err)
+	}
+	for _, stats := range egressStats {
+		if _, ok := names[stats.Position.ModuleName]; !ok {
+			continue
+		}
+		for dscp, count := range stats.Packets {
+			packets[dscp] += count
+		}
+		for anomaly, count := range stats.ExtAnomalies {
+			anomalies[anomaly] += count
+		}
+		defaultActionHits += stats.DefaultActionHits
+		marking.add(stats.Marking)
+
+		configRules := rules[stats.Position.ModuleName]
+		if configRules == nil {
+			configRules = map[string]MarkingCounts{}
+			rules[stats.Position.ModuleName] = configRules
+		}
+		for name, counts := range stats.Rules {
+			ruleCounts := configRules[name]
+			ruleCounts.add(counts)
+			configRules[name] = ruleCounts
+		}
+	}
+
+	configs := slices.Sorted(maps.Keys(names))
+	response := &dscppb.ShowStatsResponse{
+		Egress:       make([]*dscppb.DscpCount, 0),
+		ExtAnomalies: make([]*dscppb.ExtAnomalyCount, 0),
+		Configs:      configs,
+
+		DefaultActionHits: defaultActionHits,
+		Marking:           marking.proto(),
+		Rules:             m.ruleStats(configs, rules),
+	}
+	for dscp, count := range packets {
+		if count == 0 {
+			continue
+		}
+		response.Egress = append(response.Egress, &dscppb.DscpCount{
+			Dscp:    uint32(dscp),
+			Packets: count,
+		})
+	}
+	for anomaly, count := range anomalies {
+		if count == 0 {
+			continue
+		}
+		response.ExtAnomalies = append(response.ExtAnomalies, &dscppb.ExtAnomalyCount{
+			Anomaly: dscppb.ExtAnomaly(anomaly),
+			Packets: count,
+		})
+	}
+
+	return response, nil
+}
+
+// mergePrefixes returns the sorted union of the prefix sets.
+func mergePrefixes(prefixes []netip.Prefix, toAdd []netip.Prefix) []netip.Prefix {
+	return slices.Compact(
+		slices.SortedFunc(
+			slices.Values(slices.Concat(prefixes, toAdd)),
+			xnetip.PrefixCompare,
+		),
+	)
+}
+
+// subtractPrefixes removes the prefixes of toRemove from prefixes.
+func subtractPrefixes(prefixes []netip.Prefix, toRemove []netip.Prefix) []netip.Prefix {
+	return slices.DeleteFunc(
+		prefixes,
+		func(prefix netip.Prefix) bool {
+			return slices.Contains(toRemove, prefix)
+		},
+	)
+}
+
+// diffPrefixes returns the prefixes only present in proposed and the
+// prefixes only present in current, both sorted.
+func diffPrefixes(current []netip.Prefix, proposed []netip.Prefix) ([]netip.Prefix, []netip.Prefix) {
+	added := []netip.Prefix{}
+	for _, prefix := range proposed {
+		if !slices.Contains(current, prefix) {
+			added = append(added, prefix)
+		}
+	}
+
+	removed := []netip.Prefix{}
+	for _, prefix := range current {
+		if !slices.Contains(proposed, prefix) {
+			removed = append(removed, prefix)
+		}
+	}
+
+	slices.SortFunc(added, xnetip.PrefixCompare)
+	slices.SortFunc(removed, xnetip.PrefixCompare)
+
+	return slices.Compact(added), slices.Compact(removed)
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	out := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		out = append(out, p.String())
+	}
+
+	return out
+}
+
+// selectConfigs returns the names of the configs whose statistics are
+// summed: the named config, the configs with a rule group matching the
+// label selector, or the named config only if it has such a group.
+func (m *DscpService) selectConfigs(name string, selector map[string]string) (map[string]struct{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name != "" {
+		config, ok := m.configs[name]
+		if !ok {
+			return nil, status.Error(codes.NotFound, "config not found")
+		}
+		if len(selector) > 0 && !config.hasGroupMatching(selector) {
+			return nil, status.Errorf(codes.NotFound, "config %q has no rule group matching the labels", name)
+		}
+		return map[string]struct{}{name: {}}, nil
+	}
+
+	names := map[string]struct{}{}
+	for name, config := range m.configs {
+		if config.hasGroupMatching(selector) {
+			names[name] = struct{}{}
+		}
+	}
+	return names, nil
+}
@@ -3,15 +3,23 @@ package dscp
 import (
 	"fmt"
 	"net/netip"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/dscp/bindings/go/cdscp"
 	"github.com/yanet-platform/yanet2/modules/dscp/controlplane/dscppb/v1"
 )
 
@@ -23,43 +31,69 @@ type mockModuleHandle struct {
 func (m *mockModuleHandle) Free() {
 }
 
-type mockBackend struct{}
+// recordingBackend records the settings of the module configs published
+// by the service.
+type recordingBackend struct {
+	mu sync.Mutex
+	// numCalls is the number of UpdateModule calls, failed or not.
+	numCalls int
+	// settings are the settings last published by module config name.
+	settings map[string]ModuleSettings
+	// update, if set, publishes the module config in place of returning a
+	// mockModuleHandle, given the number of the call.
+	update func(call int, name string, settings ModuleSettings) (ModuleHandle, error)
+}
+
+func (m *recordingBackend) UpdateModule(name string, settings ModuleSettings) (ModuleHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.numCalls++
+	var module ModuleHandle = &mockModuleHandle{}
+	if m.update != nil {
+		var err error
+		if module, err = m.update(m.numCalls, name, settings); err != nil {
+			return nil, err
+		}
+	}
 
-func (m *mockBackend) UpdateModule(
-	name string,
-	prefixes []netip.Prefix,
-	flag uint8,
-	mark uint8,
-) (ModuleHandle, error) {
-	return &mockModuleHandle{}, nil
+	if m.settings == nil {
+		m.settings = map[string]ModuleSettings{}
+	}
+	m.settings[name] = settings
+	return module, nil
 }
 
-func newTestService(t *testing.T) *DscpService {
+// published returns the settings last published for the named module
+// config.
+func (m *recordingBackend) published(t *testing.T, name string) ModuleSettings {
 	t.Helper()
-	return NewDscpService(&mockBackend{})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.settings[name]
+	require.True(t, ok, "module config %q is not published", name)
+	return settings
 }
 
-type flakyBackend struct {
-	mu       sync.Mutex
-	numCalls int
-	backend  mockBackend
+func newTestService(t *testing.T) *DscpService {
+	t.Helper()
+	return NewDscpService(&recordingBackend{})
 }
 
-func (m *flakyBackend) UpdateModule(
-	name string,
-	prefixes []netip.Prefix,
-	flag uint8,
-	mark uint8,
-) (ModuleHandle, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+type flowLogModuleHandle struct {
+	mockModuleHandle
+	records [][]cdscp.FlowRecord
+}
 
-	m.numCalls++
-	if m.numCalls > 1 {
-		return nil, errBackendFailure
-	}
+func (m *flowLogModuleHandle) FlowLogWorkers() uint64 {
+	return uint64(len(m.records))
+}
 
-	return m.backend.UpdateModule(name, prefixes, flag, mark)
+func (m *flowLogModuleHandle) ReadFlows(workerIdx uint64, fromIdx uint64) ([]cdscp.FlowRecord, uint64) {
+	records := m.records[workerIdx]
+	return records[min(fromIdx, uint64(len(records))):], uint64(len(records))
 }
 
 func Test_DscpService_ListShowAddRemoveSetMarking(t *testing.T) {
@@ -139,6 +173,65 @@ func Test_DscpService_ListShowAddRemoveSetMarking(t *testing.T) {
 	}
 }
 
+func Test_DscpService_PrefixDirection(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	ctx := t.Context()
+
+	add := func(direction dscppb.PrefixDirection, prefixes ...string) {
+		response, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+			Name:      "dscp0",
+			Prefixes:  prefixes,
+			Direction: direction,
+		})
+		require.NotNil(t, response)
+		require.NoError(t, err)
+	}
+	show := func() *dscppb.Config {
+		response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+		require.NoError(t, err)
+		return response.Config
+	}
+
+	add(dscppb.PrefixDirection_PREFIX_DIRECTION_DESTINATION, "10.0.0.0/24")
+	add(dscppb.PrefixDirection_PREFIX_DIRECTION_SOURCE, "192.168.0.0/16")
+	add(dscppb.PrefixDirection_PREFIX_DIRECTION_EITHER, "2001:db8::/32")
+
+	cfg := show()
+	assert.Equal(t, []string{"10.0.0.0/24", "2001:db8::/32"}, cfg.Prefixes)
+	assert.Equal(t, []string{"192.168.0.0/16", "2001:db8::/32"}, cfg.SourcePrefixes)
+
+	{
+		response, err := service.RemovePrefixes(ctx, &dscppb.RemovePrefixesRequest{
+			Name:      "dscp0",
+			Prefixes:  []string{"2001:db8::/32"},
+			Direction: dscppb.PrefixDirection_PREFIX_DIRECTION_SOURCE,
+		})
+		require.NotNil(t, response)
+		require.NoError(t, err)
+	}
+
+	cfg = show()
+	assert.Equal(t, []string{"10.0.0.0/24", "2001:db8::/32"}, cfg.Prefixes)
+	assert.Equal(t, []string{"192.168.0.0/16"}, cfg.SourcePrefixes)
+
+	{
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes:       []string{"10.0.0.0/24", "2001:db8::/32"},
+				SourcePrefixes: []string{"172.16.0.0/12"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.AddedPrefixes)
+		assert.Empty(t, response.RemovedPrefixes)
+		assert.Equal(t, []string{"172.16.0.0/12"}, response.AddedSourcePrefixes)
+		assert.Equal(t, []string{"192.168.0.0/16"}, response.RemovedSourcePrefixes)
+	}
+}
+
 func Test_DscpService_RequestValidation(t *testing.T) {
 	t.Parallel()
 	service := newTestService(t)
@@ -194,6 +287,26 @@ func Test_DscpService_RequestValidation(t *testing.T) {
 		require.Equal(t, codes.InvalidArgument, status.Code(err))
 	})
 
+	t.Run("AddPrefixesInvalidDirection", func(t *testing.T) {
+		response, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+			Name:      "dscp0",
+			Prefixes:  []string{"10.0.0.0/24"},
+			Direction: dscppb.PrefixDirection(42),
+		})
+		require.Nil(t, response)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("RemovePrefixesInvalidDirection", func(t *testing.T) {
+		response, err := service.RemovePrefixes(ctx, &dscppb.RemovePrefixesRequest{
+			Name:      "dscp0",
+			Prefixes:  []string{"10.0.0.0/24"},
+			Direction: dscppb.PrefixDirection(42),
+		})
+		require.Nil(t, response)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
 	t.Run("SetDscpMarkingInvalidFlag", func(t *testing.T) {
 		response, err := service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
 			Name: "dscp0",
@@ -222,7 +335,15 @@ func Test_DscpService_RequestValidation(t *testing.T) {
 func Test_DscpService_NoUpdateOnFailure(t *testing.T) {
 	t.Parallel()
 
-	backend := &flakyBackend{}
+	// The backend fails every update after the first one.
+	backend := &recordingBackend{
+		update: func(call int, name string, settings ModuleSettings) (ModuleHandle, error) {
+			if call > 1 {
+				return nil, errBackendFailure
+			}
+			return &mockModuleHandle{}, nil
+		},
+	}
 	service := NewDscpService(backend)
 	ctx := t.Context()
 	name := "dscp0"
@@ -244,6 +365,7 @@ func Test_DscpService_NoUpdateOnFailure(t *testing.T) {
 	require.NotNil(t, response)
 	require.NoError(t, err)
 	assert.Equal(t, []string{"10.0.0.0/24"}, response.Config.Prefixes)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, backend.published(t, name).Prefixes)
 }
 
 func Test_DscpService_ConcurrentAccess(t *testing.T) {
@@ -294,3 +416,1800 @@ func Test_DscpService_ConcurrentAccess(t *testing.T) {
 
 	require.NoError(t, group.Wait())
 }
+
+func Test_DscpService_SetFlowLog(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request *dscppb.SetFlowLogRequest
+		code    codes.Code
+	}{
+		{
+			name:    "missing name",
+			request: &dscppb.SetFlowLogRequest{FlowLog: &dscppb.FlowLogConfig{RateLimit: 10}},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name:    "missing flow log",
+			request: &dscppb.SetFlowLogRequest{Name: "dscp0"},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name: "enable",
+			request: &dscppb.SetFlowLogRequest{
+				Name:    "dscp0",
+				FlowLog: &dscppb.FlowLogConfig{RateLimit: 10},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "disable",
+			request: &dscppb.SetFlowLogRequest{
+				Name:    "dscp0",
+				FlowLog: &dscppb.FlowLogConfig{},
+			},
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := newTestService(t)
+			ctx := t.Context()
+
+			_, err := service.SetFlowLog(ctx, tt.request)
+			require.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+
+			response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: tt.request.Name})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.FlowLog.RateLimit, response.Config.FlowLog.RateLimit)
+		})
+	}
+}
+
+func Test_DscpService_SetFragmentPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request *dscppb.SetFragmentPolicyRequest
+		code    codes.Code
+	}{
+		{
+			name:    "missing name",
+			request: &dscppb.SetFragmentPolicyRequest{Policy: dscppb.FragmentPolicy_FRAGMENT_POLICY_DROP},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name: "invalid policy",
+			request: &dscppb.SetFragmentPolicyRequest{
+				Name:   "dscp0",
+				Policy: dscppb.FragmentPolicy(3),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "pass",
+			request: &dscppb.SetFragmentPolicyRequest{
+				Name:   "dscp0",
+				Policy: dscppb.FragmentPolicy_FRAGMENT_POLICY_PASS,
+			},
+			code: codes.OK,
+		},
+		{
+			name: "drop",
+			request: &dscppb.SetFragmentPolicyRequest{
+				Name:   "dscp0",
+				Policy: dscppb.FragmentPolicy_FRAGMENT_POLICY_DROP,
+			},
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := newTestService(t)
+			ctx := t.Context()
+
+			_, err := service.SetFragmentPolicy(ctx, tt.request)
+			require.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+
+			response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: tt.request.Name})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.Policy, response.Config.GetFragmentPolicy())
+
+			match := dscppb.FragmentPolicy_FRAGMENT_POLICY_MATCH
+			diff, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+				Name:   tt.request.Name,
+				Config: &dscppb.Config{FragmentPolicy: &match},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.Policy, diff.GetFragmentPolicy().GetCurrent())
+			assert.Equal(t, match, diff.GetFragmentPolicy().GetProposed())
+		})
+	}
+}
+
+func Test_DscpService_SetExtHeaderLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request *dscppb.SetExtHeaderLimitsRequest
+		code    codes.Code
+		flags   uint8
+	}{
+		{
+			name:    "missing name",
+			request: &dscppb.SetExtHeaderLimitsRequest{Limits: &dscppb.ExtHeaderLimits{MaxHeaders: 8}},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name:    "missing limits",
+			request: &dscppb.SetExtHeaderLimitsRequest{Name: "dscp0"},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name: "too many headers",
+			request: &dscppb.SetExtHeaderLimitsRequest{
+				Name:   "dscp0",
+				Limits: &dscppb.ExtHeaderLimits{MaxHeaders: 256},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "max headers",
+			request: &dscppb.SetExtHeaderLimitsRequest{
+				Name:   "dscp0",
+				Limits: &dscppb.ExtHeaderLimits{MaxHeaders: 8},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "skip unknown and drop anomalies",
+			request: &dscppb.SetExtHeaderLimitsRequest{
+				Name: "dscp0",
+				Limits: &dscppb.ExtHeaderLimits{
+					MaxHeaders:    4,
+					SkipUnknown:   true,
+					DropAnomalies: true,
+				},
+			},
+			code:  codes.OK,
+			flags: extSkipUnknown | extDropAnomaly,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &recordingBackend{}
+			service := NewDscpService(backend)
+			ctx := t.Context()
+
+			_, err := service.SetExtHeaderLimits(ctx, tt.request)
+			require.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+			assert.Equal(t, ModuleSettings{
+				Rules:         []cdscp.Rule{},
+				ExtMaxHeaders: uint8(tt.request.Limits.MaxHeaders),
+				ExtFlags:      tt.flags,
+			}, backend.published(t, tt.request.Name))
+
+			response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: tt.request.Name})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.Limits.MaxHeaders, response.Config.GetExtHeaderLimits().GetMaxHeaders())
+			assert.Equal(t, tt.request.Limits.SkipUnknown, response.Config.GetExtHeaderLimits().GetSkipUnknown())
+			assert.Equal(t, tt.request.Limits.DropAnomalies, response.Config.GetExtHeaderLimits().GetDropAnomalies())
+
+			diff, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+				Name:   tt.request.Name,
+				Config: &dscppb.Config{ExtHeaderLimits: &dscppb.ExtHeaderLimits{}},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.Limits.MaxHeaders, diff.GetExtHeaderLimits().GetCurrent().GetMaxHeaders())
+			assert.Equal(t, uint32(0), diff.GetExtHeaderLimits().GetProposed().GetMaxHeaders())
+
+			diff, err = service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+				Name:   tt.request.Name,
+				Config: &dscppb.Config{ExtHeaderLimits: tt.request.Limits},
+			})
+			require.NoError(t, err)
+			assert.Nil(t, diff.GetExtHeaderLimits())
+		})
+	}
+}
+
+func Test_DscpService_SetDefaultAction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request *dscppb.SetDefaultActionRequest
+		code    codes.Code
+	}{
+		{
+			name: "missing name",
+			request: &dscppb.SetDefaultActionRequest{
+				DefaultAction: &dscppb.DefaultActionConfig{Action: dscppb.DefaultAction_DEFAULT_ACTION_DROP},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:    "missing action",
+			request: &dscppb.SetDefaultActionRequest{Name: "dscp0"},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name: "invalid action",
+			request: &dscppb.SetDefaultActionRequest{
+				Name:          "dscp0",
+				DefaultAction: &dscppb.DefaultActionConfig{Action: 3},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "invalid mark",
+			request: &dscppb.SetDefaultActionRequest{
+				Name: "dscp0",
+				DefaultAction: &dscppb.DefaultActionConfig{
+					Action: dscppb.DefaultAction_DEFAULT_ACTION_MARK,
+					Mark:   64,
+				},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "mark",
+			request: &dscppb.SetDefaultActionRequest{
+				Name: "dscp0",
+				DefaultAction: &dscppb.DefaultActionConfig{
+					Action: dscppb.DefaultAction_DEFAULT_ACTION_MARK,
+					Mark:   8,
+				},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "drop",
+			request: &dscppb.SetDefaultActionRequest{
+				Name:          "dscp0",
+				DefaultAction: &dscppb.DefaultActionConfig{Action: dscppb.DefaultAction_DEFAULT_ACTION_DROP},
+			},
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &recordingBackend{}
+			service := NewDscpService(backend)
+			ctx := t.Context()
+
+			_, err := service.SetDefaultAction(ctx, tt.request)
+			require.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+			assert.Equal(t, ModuleSettings{
+				Rules:         []cdscp.Rule{},
+				DefaultAction: uint8(tt.request.DefaultAction.Action),
+				DefaultMark:   uint8(tt.request.DefaultAction.Mark),
+			}, backend.published(t, tt.request.Name))
+
+			response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: tt.request.Name})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.DefaultAction.Action, response.Config.GetDefaultAction().GetAction())
+			assert.Equal(t, tt.request.DefaultAction.Mark, response.Config.GetDefaultAction().GetMark())
+
+			diff, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+				Name:   tt.request.Name,
+				Config: &dscppb.Config{DefaultAction: &dscppb.DefaultActionConfig{}},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.request.DefaultAction.Action, diff.GetDefaultAction().GetCurrent().GetAction())
+			assert.Equal(t, dscppb.DefaultAction_DEFAULT_ACTION_PASS, diff.GetDefaultAction().GetProposed().GetAction())
+
+			// Keeping the marking of matched packets leaves the default
+			// action in place.
+			_, err = service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+				Name:       tt.request.Name,
+				DscpConfig: &dscppb.DscpConfig{Flag: 2, Mark: 46},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, ModuleSettings{
+				Rules:         []cdscp.Rule{},
+				Flag:          2,
+				Mark:          46,
+				DefaultAction: uint8(tt.request.DefaultAction.Action),
+				DefaultMark:   uint8(tt.request.DefaultAction.Mark),
+			}, backend.published(t, tt.request.Name))
+		})
+	}
+}
+
+func Test_DscpService_DefaultActionReservedMarks(t *testing.T) {
+	t.Parallel()
+
+	service := NewDscpService(&recordingBackend{}, WithDscpServiceReservedMarks(ReservedMarksConfig{
+		Codepoints: []uint8{48},
+		Policy:     ReservedMarkReject,
+	}))
+
+	_, err := service.SetDefaultAction(t.Context(), &dscppb.SetDefaultActionRequest{
+		Name: "dscp0",
+		DefaultAction: &dscppb.DefaultActionConfig{
+			Action: dscppb.DefaultAction_DEFAULT_ACTION_MARK,
+			Mark:   48,
+		},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The default mark is only set by the mark action.
+	_, err = service.SetDefaultAction(t.Context(), &dscppb.SetDefaultActionRequest{
+		Name: "dscp0",
+		DefaultAction: &dscppb.DefaultActionConfig{
+			Action: dscppb.DefaultAction_DEFAULT_ACTION_DROP,
+			Mark:   48,
+		},
+	})
+	require.NoError(t, err)
+}
+
+func Test_DscpService_SetRateThreshold(t *testing.T) {
+	t.Parallel()
+
+	backend := &recordingBackend{}
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	for _, request := range []*dscppb.SetRateThresholdRequest{
+		{RateThreshold: &dscppb.RateThreshold{Rate: 1000}},
+		{Name: "dscp0"},
+		{Name: "dscp0", RateThreshold: &dscppb.RateThreshold{Burst: 100}},
+	} {
+		_, err := service.SetRateThreshold(ctx, request)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), request)
+	}
+
+	_, err := service.SetRateThreshold(ctx, &dscppb.SetRateThresholdRequest{
+		Name:          "dscp0",
+		RateThreshold: &dscppb.RateThreshold{Rate: 1000, Burst: 100},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ModuleSettings{Rules: []cdscp.Rule{}, RateThreshold: 1000, RateBurst: 100}, backend.published(t, "dscp0"))
+
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), response.Config.GetRateThreshold().GetRate())
+	assert.Equal(t, uint32(100), response.Config.GetRateThreshold().GetBurst())
+
+	// The threshold survives changes of the marking it applies to.
+	_, err = service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+		Name:       "dscp0",
+		DscpConfig: &dscppb.DscpConfig{Flag: 2, Mark: 12},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ModuleSettings{Rules: []cdscp.Rule{}, Flag: 2, Mark: 12, RateThreshold: 1000, RateBurst: 100}, backend.published(t, "dscp0"))
+
+	diff, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+		Name:   "dscp0",
+		Config: &dscppb.Config{RateThreshold: &dscppb.RateThreshold{}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), diff.GetRateThreshold().GetCurrent().GetRate())
+	assert.Equal(t, uint32(0), diff.GetRateThreshold().GetProposed().GetRate())
+
+	_, err = service.CloneConfig(ctx, &dscppb.CloneConfigRequest{
+		Name:    "dscp0",
+		Targets: []string{"dscp1"},
+		Transforms: &dscppb.CloneTransforms{
+			RateThreshold: &dscppb.RateThreshold{Rate: 500},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ModuleSettings{Rules: []cdscp.Rule{}, Flag: 2, Mark: 12, RateThreshold: 500}, backend.published(t, "dscp1"))
+}
+
+func Test_DscpService_PollFlowLog(t *testing.T) {
+	t.Parallel()
+
+	record := cdscp.FlowRecord{
+		Timestamp:       42,
+		Source:          netip.MustParseAddr("10.0.0.1"),
+		Destination:     netip.MustParseAddr("2001:db8::1"),
+		SourcePort:      1234,
+		DestinationPort: 80,
+		Proto:           6,
+		OriginalDSCP:    10,
+		Remarked:        true,
+	}
+	backend := &recordingBackend{
+		update: func(call int, name string, settings ModuleSettings) (ModuleHandle, error) {
+			return &flowLogModuleHandle{records: [][]cdscp.FlowRecord{{}, {record}}}, nil
+		},
+	}
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	_, err := service.SetFlowLog(ctx, &dscppb.SetFlowLogRequest{
+		Name:    "dscp0",
+		FlowLog: &dscppb.FlowLogConfig{RateLimit: 10},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ModuleSettings{Rules: []cdscp.Rule{}, FlowLogRate: 10}, backend.published(t, "dscp0"))
+
+	ch, unsubscribe := service.flows.Subscribe("dscp0")
+	defer unsubscribe()
+
+	cursors := map[string]*flowLogCursor{}
+	service.pollFlowLog(cursors)
+	// The second poll must not deliver already consumed records.
+	service.pollFlowLog(cursors)
+
+	require.Len(t, ch, 1)
+	assert.Equal(t, &dscppb.FlowRecord{
+		Name:         "dscp0",
+		Worker:       1,
+		Timestamp:    42,
+		SrcAddr:      "10.0.0.1",
+		DstAddr:      "2001:db8::1",
+		SrcPort:      1234,
+		DstPort:      80,
+		Proto:        6,
+		OriginalDscp: 10,
+		Remarked:     true,
+	}, <-ch)
+}
+
+func Test_DscpService_DiffConfig(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	ctx := t.Context()
+
+	{
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes: []string{"10.0.0.0/24"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/24"}, response.AddedPrefixes)
+		assert.Empty(t, response.RemovedPrefixes)
+	}
+
+	_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+		Name:       "dscp0",
+		DscpConfig: &dscppb.DscpConfig{Flag: 1, Mark: 8},
+	})
+	require.NoError(t, err)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes:   []string{"10.0.1.0/24", "10.0.0.0/24"},
+				DscpConfig: &dscppb.DscpConfig{Flag: 1, Mark: 8},
+				FlowLog:    &dscppb.FlowLogConfig{},
+			},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.AddedPrefixes)
+		assert.Empty(t, response.RemovedPrefixes)
+		assert.Nil(t, response.DscpConfig)
+		assert.Nil(t, response.FlowLog)
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes:   []string{"10.0.1.0/24", "2001:db8::1/32"},
+				DscpConfig: &dscppb.DscpConfig{Flag: 2, Mark: 8},
+				FlowLog:    &dscppb.FlowLogConfig{RateLimit: 10},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2001:db8::/32"}, response.AddedPrefixes)
+		assert.Equal(t, []string{"10.0.0.0/24"}, response.RemovedPrefixes)
+		require.NotNil(t, response.DscpConfig)
+		assert.Equal(t, uint32(1), response.DscpConfig.Current.Flag)
+		assert.Equal(t, uint32(2), response.DscpConfig.Proposed.Flag)
+		require.NotNil(t, response.FlowLog)
+		assert.Equal(t, uint32(0), response.FlowLog.Current.RateLimit)
+		assert.Equal(t, uint32(10), response.FlowLog.Proposed.RateLimit)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		for _, rule := range []*dscppb.Rule{
+			{Name: "voip", Proto: 17, DstPorts: &dscppb.PortRange{From: 5060, To: 5061}, Mark: 46},
+			{Name: "bulk", Priority: 10, DstPrefix: "10.0.0.0/24", Mark: 10},
+			{Name: "web", Proto: 6, Mark: 18, TrafficClass: 3},
+		} {
+			_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: rule})
+			require.NoError(t, err)
+		}
+
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+				Rules: []*dscppb.Rule{
+					{Name: "voip", Proto: 17, DstPorts: &dscppb.PortRange{From: 5060, To: 5061}, Mark: 46},
+					{Name: "web", Proto: 6, Mark: 18, TrafficClass: 4},
+					{Name: "dns", Proto: 17, DstPorts: &dscppb.PortRange{From: 53, To: 53}, Mark: 34},
+				},
+			},
+			CompareRules: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, response.AddedRules, 1)
+		assert.Equal(t, "dns", response.AddedRules[0].Name)
+		require.Len(t, response.RemovedRules, 1)
+		assert.Equal(t, "bulk", response.RemovedRules[0].Name)
+		// Only the traffic class of the rule differs.
+		require.Len(t, response.ChangedRules, 1)
+		assert.Equal(t, "web", response.ChangedRules[0].Current.Name)
+		assert.Equal(t, uint32(3), response.ChangedRules[0].Current.TrafficClass)
+		assert.Equal(t, uint32(4), response.ChangedRules[0].Proposed.TrafficClass)
+
+		// The rules are not compared unless asked to.
+		response, err = service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name:   "dscp0",
+			Config: &dscppb.Config{Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.AddedRules)
+		assert.Empty(t, response.RemovedRules)
+		assert.Empty(t, response.ChangedRules)
+
+		for _, rule := range []string{"voip", "bulk", "web"} {
+			_, err := service.DeleteRule(ctx, &dscppb.DeleteRuleRequest{Name: "dscp0", Rule: rule})
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("RuleGroups", func(t *testing.T) {
+		for _, group := range []string{"customer-a", "customer-b"} {
+			_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+				Name:     "dscp0",
+				Group:    group,
+				Prefixes: []string{"192.168.0.0/24"},
+			})
+			require.NoError(t, err)
+		}
+
+		response, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+				Groups: []*dscppb.RuleGroup{
+					{Name: "customer-a", Prefixes: []string{"192.168.0.0/24", "192.168.1.0/24"}},
+					{Name: "customer-c", Prefixes: []string{"192.168.2.0/24"}, Enabled: true},
+				},
+			},
+			CompareGroups: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, response.AddedGroups, 1)
+		assert.Equal(t, "customer-c", response.AddedGroups[0].Name)
+		require.Len(t, response.RemovedGroups, 1)
+		assert.Equal(t, "customer-b", response.RemovedGroups[0].Name)
+		require.Len(t, response.ChangedGroups, 1)
+		changed := response.ChangedGroups[0]
+		assert.Equal(t, "customer-a", changed.Name)
+		assert.Equal(t, []string{"192.168.1.0/24"}, changed.AddedPrefixes)
+		assert.Empty(t, changed.RemovedPrefixes)
+		require.NotNil(t, changed.Enabled)
+		assert.True(t, changed.Enabled.Current)
+		assert.False(t, changed.Enabled.Proposed)
+		assert.Nil(t, changed.Stage)
+
+		// Only the state of the group differs.
+		_, err = service.SetRuleGroupEnabled(ctx, &dscppb.SetRuleGroupEnabledRequest{
+			Name:  "dscp0",
+			Group: "customer-b",
+		})
+		require.NoError(t, err)
+		response, err = service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name: "dscp0",
+			Config: &dscppb.Config{
+				Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+				Groups: []*dscppb.RuleGroup{
+					{Name: "customer-a", Prefixes: []string{"192.168.0.0/24"}, Enabled: true},
+					{Name: "customer-b", Prefixes: []string{"192.168.0.0/24"}, Enabled: true},
+				},
+			},
+			CompareGroups: true,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.AddedGroups)
+		assert.Empty(t, response.RemovedGroups)
+		require.Len(t, response.ChangedGroups, 1)
+		assert.Equal(t, "customer-b", response.ChangedGroups[0].Name)
+		assert.Empty(t, response.ChangedGroups[0].AddedPrefixes)
+		require.NotNil(t, response.ChangedGroups[0].Enabled)
+		assert.True(t, response.ChangedGroups[0].Enabled.Proposed)
+
+		// The groups are not compared unless asked to.
+		response, err = service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+			Name:   "dscp0",
+			Config: &dscppb.Config{Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.AddedGroups)
+		assert.Empty(t, response.RemovedGroups)
+		assert.Empty(t, response.ChangedGroups)
+	})
+
+	t.Run("DoesNotApply", func(t *testing.T) {
+		response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24"}, response.Config.Prefixes)
+		assert.Equal(t, uint32(1), response.Config.DscpConfig.Flag)
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		for _, request := range []*dscppb.DiffConfigRequest{
+			{Config: &dscppb.Config{}},
+			{Name: "dscp0"},
+			{Name: "dscp0", Config: &dscppb.Config{Prefixes: []string{"bad-prefix"}}},
+			{Name: "dscp0", Config: &dscppb.Config{DscpConfig: &dscppb.DscpConfig{Mark: 64}}},
+			{Name: "dscp0", Config: &dscppb.Config{Rules: []*dscppb.Rule{{Mark: 8}}}, CompareRules: true},
+			{
+				Name:         "dscp0",
+				Config:       &dscppb.Config{Rules: []*dscppb.Rule{{Name: "r", Mark: 8}, {Name: "r", Mark: 10}}},
+				CompareRules: true,
+			},
+			{Name: "dscp0", Config: &dscppb.Config{Groups: []*dscppb.RuleGroup{{}}}, CompareGroups: true},
+			{
+				Name:          "dscp0",
+				Config:        &dscppb.Config{Groups: []*dscppb.RuleGroup{{Name: "g", Prefixes: []string{"bad-prefix"}}}},
+				CompareGroups: true,
+			},
+		} {
+			response, err := service.DiffConfig(ctx, request)
+			require.Nil(t, response)
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+	})
+}
+
+type statsBackend struct {
+	recordingBackend
+	stats []EgressStats
+}
+
+func (m *statsBackend) EgressStats() ([]EgressStats, error) {
+	return m.stats, nil
+}
+
+// withoutFingerprints drops the fingerprint gauges reported for every
+// applied config.
+func withoutFingerprints(metrics []*commonpb.Metric) []*commonpb.Metric {
+	return slices.DeleteFunc(metrics, func(metric *commonpb.Metric) bool {
+		return strings.HasSuffix(metric.GetName(), "_fingerprint")
+	})
+}
+
+func Test_DscpService_ShowStats(t *testing.T) {
+	ctx := t.Context()
+
+	backend := &statsBackend{}
+	backend.stats = []EgressStats{
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp0"}},
+		{Position: ffi.ModuleReference{Device: "port1", Pipeline: "in", ModuleName: "dscp0"}},
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp1"}},
+	}
+	backend.stats[0].Packets[0] = 5
+	backend.stats[0].Packets[46] = 10
+	backend.stats[1].Packets[46] = 2
+	backend.stats[2].Packets[8] = 100
+	service := NewDscpService(backend)
+
+	_, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp0"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24"},
+	})
+	require.NoError(t, err)
+
+	response, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	require.Len(t, response.Egress, 2)
+	assert.Equal(t, uint32(0), response.Egress[0].Dscp)
+	assert.Equal(t, uint64(5), response.Egress[0].Packets)
+	assert.Equal(t, uint32(46), response.Egress[1].Dscp)
+	assert.Equal(t, uint64(12), response.Egress[1].Packets)
+
+	metrics := withoutFingerprints(service.Metrics())
+	require.Len(t, metrics, 4)
+	for _, metric := range metrics {
+		assert.Equal(t, "dscp_egress_packets", metric.Name)
+	}
+
+	_, err = service.ShowStats(ctx, &dscppb.ShowStatsRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_DscpService_ShowStatsExtAnomalies(t *testing.T) {
+	ctx := t.Context()
+
+	backend := &statsBackend{}
+	backend.stats = []EgressStats{
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp0"}},
+		{Position: ffi.ModuleReference{Device: "port1", Pipeline: "in", ModuleName: "dscp0"}},
+	}
+	backend.stats[0].ExtAnomalies[dscppb.ExtAnomaly_EXT_ANOMALY_LIMIT] = 3
+	backend.stats[1].ExtAnomalies[dscppb.ExtAnomaly_EXT_ANOMALY_LIMIT] = 1
+	backend.stats[1].ExtAnomalies[dscppb.ExtAnomaly_EXT_ANOMALY_MALFORMED] = 7
+	service := NewDscpService(backend)
+
+	_, err := service.SetExtHeaderLimits(ctx, &dscppb.SetExtHeaderLimitsRequest{
+		Name:   "dscp0",
+		Limits: &dscppb.ExtHeaderLimits{MaxHeaders: 8},
+	})
+	require.NoError(t, err)
+
+	response, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Empty(t, response.Egress)
+	require.Len(t, response.ExtAnomalies, 2)
+	assert.Equal(t, dscppb.ExtAnomaly_EXT_ANOMALY_LIMIT, response.ExtAnomalies[0].Anomaly)
+	assert.Equal(t, uint64(4), response.ExtAnomalies[0].Packets)
+	assert.Equal(t, dscppb.ExtAnomaly_EXT_ANOMALY_MALFORMED, response.ExtAnomalies[1].Anomaly)
+	assert.Equal(t, uint64(7), response.ExtAnomalies[1].Packets)
+
+	metrics := withoutFingerprints(service.Metrics())
+	require.Len(t, metrics, 3)
+	for _, metric := range metrics {
+		assert.Equal(t, "dscp_ext_anomaly_packets", metric.Name)
+	}
+	assert.Equal(t, "malformed", metrics[2].Labels[5].Value)
+}
+
+func Test_DscpService_ShowStatsDefaultActionHits(t *testing.T) {
+	ctx := t.Context()
+
+	backend := &statsBackend{}
+	backend.stats = []EgressStats{
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp0"}, DefaultActionHits: 3},
+		{Position: ffi.ModuleReference{Device: "port1", Pipeline: "in", ModuleName: "dscp0"}, DefaultActionHits: 4},
+	}
+	service := NewDscpService(backend)
+
+	_, err := service.SetDefaultAction(ctx, &dscppb.SetDefaultActionRequest{
+		Name:          "dscp0",
+		DefaultAction: &dscppb.DefaultActionConfig{Action: dscppb.DefaultAction_DEFAULT_ACTION_DROP},
+	})
+	require.NoError(t, err)
+
+	response, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), response.DefaultActionHits)
+
+	metrics := withoutFingerprints(service.Metrics())
+	require.Len(t, metrics, 2)
+	for _, metric := range metrics {
+		assert.Equal(t, "dscp_default_action_packets", metric.Name)
+	}
+}
+
+func Test_DscpService_ShowStatsMarking(t *testing.T) {
+	ctx := t.Context()
+
+	backend := &statsBackend{}
+	backend.stats = []EgressStats{
+		{
+			Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp0"},
+			Marking:  MarkingCounts{Matched: 10, Remarked: 6, Untouched: 4, MatchedBytes: 1000, RemarkedBytes: 600},
+			Rules:    map[string]MarkingCounts{"voip": {Matched: 3, Remarked: 3, MatchedBytes: 300, RemarkedBytes: 300}},
+		},
+		{
+			Position: ffi.ModuleReference{Device: "port1", Pipeline: "in", ModuleName: "dscp0"},
+			Marking:  MarkingCounts{Matched: 2, Untouched: 2, MatchedBytes: 200},
+			Rules:    map[string]MarkingCounts{"voip": {Matched: 2, Untouched: 2, MatchedBytes: 200}},
+		},
+	}
+	service := NewDscpService(backend)
+
+	for _, rule := range []*dscppb.Rule{
+		{Name: "voip", Priority: 10, Proto: 17, Mark: 46},
+		{Name: "idle", Priority: 20, Mark: 8},
+	} {
+		_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: rule})
+		require.NoError(t, err)
+	}
+
+	response, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), response.GetMarking().GetMatched())
+	assert.Equal(t, uint64(6), response.GetMarking().GetRemarked())
+	assert.Equal(t, uint64(6), response.GetMarking().GetUntouched())
+	assert.Equal(t, uint64(1200), response.GetMarking().GetMatchedBytes())
+	assert.Equal(t, uint64(600), response.GetMarking().GetRemarkedBytes())
+
+	// The rule matching nothing is listed too.
+	require.Len(t, response.GetRules(), 2)
+	assert.Equal(t, "voip", response.GetRules()[0].GetName())
+	assert.Equal(t, "dscp0", response.GetRules()[0].GetConfig())
+	assert.Equal(t, uint64(5), response.GetRules()[0].GetCounts().GetMatched())
+	assert.Equal(t, uint64(3), response.GetRules()[0].GetCounts().GetRemarked())
+	assert.Equal(t, uint64(2), response.GetRules()[0].GetCounts().GetUntouched())
+	assert.Equal(t, uint64(500), response.GetRules()[0].GetCounts().GetMatchedBytes())
+	assert.Equal(t, uint64(300), response.GetRules()[0].GetCounts().GetRemarkedBytes())
+	assert.Equal(t, "idle", response.GetRules()[1].GetName())
+	assert.Zero(t, response.GetRules()[1].GetCounts().GetMatched())
+
+	metrics := withoutFingerprints(service.Metrics())
+	names := map[string]int{}
+	for _, metric := range metrics {
+		names[metric.GetName()]++
+	}
+	assert.Equal(t, map[string]int{
+		"dscp_marking_packets": 5,
+		"dscp_marking_bytes":   5,
+		"dscp_rule_packets":    4,
+		"dscp_rule_bytes":      4,
+	}, names)
+
+	for _, metric := range metrics {
+		labels := map[string]string{}
+		for _, label := range metric.GetLabels() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if metric.GetName() == "dscp_rule_bytes" && labels["device"] == "port1" {
+			assert.Equal(t, uint64(200), metric.GetCounter(), labels["result"])
+		}
+	}
+}
+
+func Test_DscpService_CloneConfig(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	ctx := t.Context()
+
+	_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:      "dscp0",
+		Prefixes:  []string{"192.0.2.0/24"},
+		Direction: dscppb.PrefixDirection_PREFIX_DIRECTION_SOURCE,
+	})
+	require.NoError(t, err)
+	_, err = service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+		Name:       "dscp0",
+		DscpConfig: &dscppb.DscpConfig{Flag: 1, Mark: 8},
+	})
+	require.NoError(t, err)
+	_, err = service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+		Name:       "dscp2",
+		DscpConfig: &dscppb.DscpConfig{Flag: 2, Mark: 46},
+	})
+	require.NoError(t, err)
+
+	t.Run("Copy", func(t *testing.T) {
+		_, err := service.CloneConfig(ctx, &dscppb.CloneConfigRequest{
+			Name:    "dscp0",
+			Targets: []string{"dscp1", "dscp2"},
+		})
+		require.NoError(t, err)
+
+		source, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+		require.NoError(t, err)
+		for _, name := range []string{"dscp1", "dscp2"} {
+			response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: name})
+			require.NoError(t, err)
+			assert.Equal(t, source.Config, response.Config)
+		}
+	})
+
+	t.Run("Transforms", func(t *testing.T) {
+		policy := dscppb.FragmentPolicy_FRAGMENT_POLICY_DROP
+		_, err := service.CloneConfig(ctx, &dscppb.CloneConfigRequest{
+			Name:    "dscp0",
+			Targets: []string{"dscp3"},
+			Transforms: &dscppb.CloneTransforms{
+				AddPrefixes:     []string{"2001:db8::/32"},
+				RemovePrefixes:  []string{"10.0.1.0/24"},
+				DscpConfig:      &dscppb.DscpConfig{Flag: 2, Mark: 10},
+				FragmentPolicy:  &policy,
+				ExtHeaderLimits: &dscppb.ExtHeaderLimits{MaxHeaders: 4},
+				FlowLog:         &dscppb.FlowLogConfig{RateLimit: 5},
+			},
+		})
+		require.NoError(t, err)
+
+		response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp3"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/24", "2001:db8::/32"}, response.Config.Prefixes)
+		assert.Equal(t, []string{"192.0.2.0/24"}, response.Config.SourcePrefixes)
+		assert.Equal(t, uint32(2), response.Config.DscpConfig.Flag)
+		assert.Equal(t, uint32(10), response.Config.DscpConfig.Mark)
+		assert.Equal(t, policy, response.Config.GetFragmentPolicy())
+		assert.Equal(t, uint32(4), response.Config.ExtHeaderLimits.MaxHeaders)
+		assert.Equal(t, uint32(5), response.Config.FlowLog.RateLimit)
+
+		// The source is left untouched.
+		response, err = service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24"}, response.Config.Prefixes)
+		assert.Equal(t, uint32(8), response.Config.DscpConfig.Mark)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := service.CloneConfig(ctx, &dscppb.CloneConfigRequest{
+			Name:    "missing",
+			Targets: []string{"dscp1"},
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		for _, request := range []*dscppb.CloneConfigRequest{
+			{Targets: []string{"dscp1"}},
+			{Name: "dscp0"},
+			{Name: "dscp0", Targets: []string{""}},
+			{Name: "dscp0", Targets: []string{"dscp0"}},
+			{Name: "dscp0", Targets: []string{"dscp1", "dscp1"}},
+			{Name: "dscp0", Targets: []string{"dscp1"}, Transforms: &dscppb.CloneTransforms{AddPrefixes: []string{"bad-prefix"}}},
+			{Name: "dscp0", Targets: []string{"dscp1"}, Transforms: &dscppb.CloneTransforms{DscpConfig: &dscppb.DscpConfig{Mark: 64}}},
+			{Name: "dscp0", Targets: []string{"dscp1"}, Transforms: &dscppb.CloneTransforms{Direction: dscppb.PrefixDirection(42)}},
+		} {
+			response, err := service.CloneConfig(ctx, request)
+			require.Nil(t, response)
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+	})
+}
+
+type memoryModuleHandle struct {
+	mockModuleHandle
+	usage uint64
+}
+
+func (m *memoryModuleHandle) MemoryUsage() uint64 {
+	return m.usage
+}
+
+// newRuleTableBackend returns a backend charging 1KB of shared memory per
+// prefix and checking the configs against the limits, as the shared memory
+// backend does.
+func newRuleTableBackend(limits *ruleTableLimits) *recordingBackend {
+	return &recordingBackend{
+		update: func(call int, name string, settings ModuleSettings) (ModuleHandle, error) {
+			usage := uint64(len(settings.Prefixes)+len(settings.SourcePrefixes)) * uint64(datasize.KB)
+			if err := limits.Check(name, usage); err != nil {
+				return nil, err
+			}
+			return &memoryModuleHandle{usage: usage}, nil
+		},
+	}
+}
+
+func Test_DscpService_RuleTableLimits(t *testing.T) {
+	ctx := t.Context()
+
+	core, logs := observer.New(zap.WarnLevel)
+	capacity := 4 * datasize.KB
+	limits := newRuleTableLimits(capacity, RuleTableConfig{WarnThreshold: 0.5, RefuseThreshold: 0.75}, zap.New(core))
+	service := NewDscpService(newRuleTableBackend(limits), WithDscpServiceRuleTableCapacity(capacity))
+
+	_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24", "10.0.1.0/24"},
+	})
+	require.NoError(t, err)
+	require.Zero(t, logs.Len())
+
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.2.0/24"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.FilterMessage("rule table utilization is above the warn threshold").Len())
+
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.3.0/24"},
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Len(t, response.Config.Prefixes, 3)
+
+	metrics := withoutFingerprints(service.Metrics())
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "dscp_rule_table_bytes", metrics[0].Name)
+	assert.Equal(t, float64(3*datasize.KB), metrics[0].GetGauge())
+	assert.Equal(t, "dscp_rule_table_utilization", metrics[1].Name)
+	assert.Equal(t, 0.75, metrics[1].GetGauge())
+}
+
+func Test_RuleTableConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultConfig().RuleTable.Validate())
+	require.Error(t, (&RuleTableConfig{WarnThreshold: -0.1}).Validate())
+	require.Error(t, (&RuleTableConfig{RefuseThreshold: 1.5}).Validate())
+}
+
+func Test_DscpService_ReservedMarks(t *testing.T) {
+	ctx := t.Context()
+
+	setMarking := func(service *DscpService, name string, flag uint32, mark uint32) error {
+		_, err := service.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+			Name:       name,
+			DscpConfig: &dscppb.DscpConfig{Flag: flag, Mark: mark},
+		})
+		return err
+	}
+
+	service := NewDscpService(&recordingBackend{}, WithDscpServiceReservedMarks(ReservedMarksConfig{
+		Policy:         ReservedMarkReject,
+		AllowedConfigs: []string{"control"},
+	}))
+	require.NoError(t, setMarking(service, "dscp0", 2, 46))
+	require.Equal(t, codes.InvalidArgument, status.Code(setMarking(service, "dscp0", 2, dscpCS6)))
+	require.Equal(t, codes.InvalidArgument, status.Code(setMarking(service, "dscp0", 1, dscpCS7)))
+	// Rules that never mark set nothing.
+	require.NoError(t, setMarking(service, "dscp0", 0, dscpCS7))
+	require.NoError(t, setMarking(service, "control", 2, dscpCS6))
+
+	// The refused rule keeps the previous one applied.
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(dscpCS7), response.Config.DscpConfig.Mark)
+	assert.Equal(t, uint32(0), response.Config.DscpConfig.Flag)
+
+	_, err = service.CloneConfig(ctx, &dscppb.CloneConfigRequest{Name: "control", Targets: []string{"dscp1"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	core, logs := observer.New(zap.WarnLevel)
+	service = NewDscpService(&recordingBackend{},
+		WithDscpServiceLog(zap.New(core)),
+		WithDscpServiceReservedMarks(ReservedMarksConfig{Policy: ReservedMarkWarn, Codepoints: []uint8{40}}),
+	)
+	require.NoError(t, setMarking(service, "dscp0", 2, dscpCS6))
+	require.Zero(t, logs.Len())
+	require.NoError(t, setMarking(service, "dscp0", 2, 40))
+	require.Equal(t, 1, logs.FilterMessage("marking with a reserved codepoint").Len())
+}
+
+func Test_ReservedMarksConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultConfig().ReservedMarks.Validate())
+	require.Error(t, (&ReservedMarksConfig{}).Validate())
+	require.Error(t, (&ReservedMarksConfig{Policy: ReservedMarkReject, Codepoints: []uint8{64}}).Validate())
+}
+
+func Test_DscpService_RuleGroups(t *testing.T) {
+	t.Parallel()
+
+	backend := &recordingBackend{}
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:      "dscp0",
+		Prefixes:  []string{"10.1.0.0/24", "2001:db8::/32"},
+		Direction: dscppb.PrefixDirection_PREFIX_DIRECTION_EITHER,
+		Group:     "customer-x",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.1.0.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, backend.published(t, "dscp0").Prefixes)
+	require.Len(t, backend.published(t, "dscp0").SourcePrefixes, 2)
+
+	_, err = service.SetRuleGroupEnabled(ctx, &dscppb.SetRuleGroupEnabledRequest{
+		Name:  "dscp0",
+		Group: "customer-x",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, backend.published(t, "dscp0").Prefixes)
+	require.Empty(t, backend.published(t, "dscp0").SourcePrefixes)
+
+	// The disabled group keeps its prefixes.
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/24"}, response.Config.GetPrefixes())
+	require.Len(t, response.Config.GetGroups(), 1)
+	group := response.Config.GetGroups()[0]
+	assert.Equal(t, "customer-x", group.GetName())
+	assert.False(t, group.GetEnabled())
+	assert.Equal(t, []string{"10.1.0.0/24", "2001:db8::/32"}, group.GetPrefixes())
+	assert.Equal(t, []string{"10.1.0.0/24", "2001:db8::/32"}, group.GetSourcePrefixes())
+
+	_, err = service.SetRuleGroupEnabled(ctx, &dscppb.SetRuleGroupEnabledRequest{
+		Name:    "dscp0",
+		Group:   "customer-x",
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, backend.published(t, "dscp0").Prefixes, 3)
+
+	// A group left without prefixes is deleted.
+	_, err = service.RemovePrefixes(ctx, &dscppb.RemovePrefixesRequest{
+		Name:      "dscp0",
+		Prefixes:  []string{"10.1.0.0/24", "2001:db8::/32"},
+		Direction: dscppb.PrefixDirection_PREFIX_DIRECTION_EITHER,
+		Group:     "customer-x",
+	})
+	require.NoError(t, err)
+	response, err = service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Empty(t, response.Config.GetGroups())
+
+	for _, request := range []*dscppb.SetRuleGroupEnabledRequest{
+		{Group: "customer-x"},
+		{Name: "dscp0"},
+	} {
+		_, err := service.SetRuleGroupEnabled(ctx, request)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	for _, request := range []*dscppb.SetRuleGroupEnabledRequest{
+		{Name: "dscp1", Group: "customer-x"},
+		{Name: "dscp0", Group: "customer-x"},
+	} {
+		_, err := service.SetRuleGroupEnabled(ctx, request)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	}
+}
+
+func Test_DscpService_Stages(t *testing.T) {
+	t.Parallel()
+
+	backend := &recordingBackend{}
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	for _, group := range []string{"", "ingress", "egress", "any"} {
+		prefix := map[string]string{
+			"":        "10.0.0.0/24",
+			"ingress": "10.1.0.0/24",
+			"egress":  "10.2.0.0/24",
+			"any":     "10.3.0.0/24",
+		}[group]
+		_, err := service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+			Name:     "dscp0",
+			Prefixes: []string{prefix},
+			Group:    group,
+		})
+		require.NoError(t, err)
+	}
+	for group, stage := range map[string]dscppb.Stage{
+		"ingress": dscppb.Stage_STAGE_INGRESS,
+		"egress":  dscppb.Stage_STAGE_EGRESS,
+	} {
+		_, err := service.SetStage(ctx, &dscppb.SetStageRequest{
+			Name:  "dscp0",
+			Group: group,
+			Stage: stage,
+		})
+		require.NoError(t, err)
+	}
+
+	// A config of no declared stage only matches the groups of any stage.
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.3.0.0/24"),
+	}, backend.published(t, "dscp0").Prefixes)
+
+	_, err := service.SetStage(ctx, &dscppb.SetStageRequest{
+		Name:  "dscp0",
+		Stage: dscppb.Stage_STAGE_EGRESS,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.2.0.0/24"),
+		netip.MustParsePrefix("10.3.0.0/24"),
+	}, backend.published(t, "dscp0").Prefixes)
+
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Equal(t, dscppb.Stage_STAGE_EGRESS, response.Config.GetStage())
+	stages := map[string]dscppb.Stage{}
+	for _, group := range response.Config.GetGroups() {
+		stages[group.GetName()] = group.GetStage()
+	}
+	assert.Equal(t, map[string]dscppb.Stage{
+		"any":     dscppb.Stage_STAGE_ANY,
+		"egress":  dscppb.Stage_STAGE_EGRESS,
+		"ingress": dscppb.Stage_STAGE_INGRESS,
+	}, stages)
+
+	// The copy runs at the ingress stage, with the same groups.
+	_, err = service.CloneConfig(ctx, &dscppb.CloneConfigRequest{
+		Name:       "dscp0",
+		Targets:    []string{"dscp1"},
+		Transforms: &dscppb.CloneTransforms{Stage: dscppb.Stage_STAGE_INGRESS.Enum()},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.1.0.0/24"),
+		netip.MustParsePrefix("10.3.0.0/24"),
+	}, backend.published(t, "dscp1").Prefixes)
+
+	diff, err := service.DiffConfig(ctx, &dscppb.DiffConfigRequest{
+		Name: "dscp1",
+		Config: &dscppb.Config{
+			Prefixes: []string{"10.0.0.0/24"},
+			Stage:    dscppb.Stage_STAGE_EGRESS.Enum(),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dscppb.Stage_STAGE_INGRESS, diff.GetStage().GetCurrent())
+	assert.Equal(t, dscppb.Stage_STAGE_EGRESS, diff.GetStage().GetProposed())
+
+	for _, request := range []*dscppb.SetStageRequest{
+		{Group: "ingress"},
+		{Name: "dscp0", Stage: dscppb.Stage(3)},
+	} {
+		_, err := service.SetStage(ctx, request)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	_, err = service.SetStage(ctx, &dscppb.SetStageRequest{Name: "dscp0", Group: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_DscpService_RuleGroupLabels(t *testing.T) {
+	t.Parallel()
+
+	backend := &statsBackend{}
+	backend.stats = []EgressStats{
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp0"}},
+		{Position: ffi.ModuleReference{Device: "port0", Pipeline: "in", ModuleName: "dscp1"}},
+	}
+	backend.stats[0].Packets[46] = 10
+	backend.stats[1].Packets[46] = 5
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	for _, request := range []*dscppb.AddPrefixesRequest{
+		{Name: "dscp0", Prefixes: []string{"10.0.0.0/24"}, Group: "customer-x"},
+		{Name: "dscp0", Prefixes: []string{"10.1.0.0/24"}, Group: "customer-y"},
+		{Name: "dscp1", Prefixes: []string{"10.2.0.0/24"}, Group: "customer-x"},
+	} {
+		_, err := service.AddPrefixes(ctx, request)
+		require.NoError(t, err)
+	}
+	for _, name := range []string{"dscp0", "dscp1"} {
+		_, err := service.SetRuleGroupMetadata(ctx, &dscppb.SetRuleGroupMetadataRequest{
+			Name:        name,
+			Group:       "customer-x",
+			Description: "NETOPS-1: mark customer X",
+			Labels:      map[string]string{"customer": "x", "tier": "gold"},
+		})
+		require.NoError(t, err)
+	}
+
+	selector := map[string]string{"customer": "x"}
+	response, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0", Labels: selector})
+	require.NoError(t, err)
+	require.Len(t, response.Config.GetGroups(), 1)
+	group := response.Config.GetGroups()[0]
+	assert.Equal(t, "customer-x", group.GetName())
+	assert.Equal(t, "NETOPS-1: mark customer X", group.GetDescription())
+	assert.Equal(t, map[string]string{"customer": "x", "tier": "gold"}, group.GetLabels())
+
+	response, err = service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	require.Len(t, response.Config.GetGroups(), 2)
+
+	list, err := service.ListConfigs(ctx, &dscppb.ListConfigsRequest{Labels: map[string]string{"tier": "gold"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dscp0", "dscp1"}, list.GetConfigs())
+	list, err = service.ListConfigs(ctx, &dscppb.ListConfigsRequest{Labels: map[string]string{"customer": "y"}})
+	require.NoError(t, err)
+	assert.Empty(t, list.GetConfigs())
+
+	// Statistics are summed over all the configs with a matching group.
+	stats, err := service.ShowStats(ctx, &dscppb.ShowStatsRequest{Labels: selector})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dscp0", "dscp1"}, stats.GetConfigs())
+	require.Len(t, stats.GetEgress(), 1)
+	assert.Equal(t, uint64(15), stats.GetEgress()[0].GetPackets())
+
+	_, err = service.ShowStats(ctx, &dscppb.ShowStatsRequest{Name: "dscp1", Labels: map[string]string{"tier": "silver"}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// The metadata is copied with the groups.
+	_, err = service.CloneConfig(ctx, &dscppb.CloneConfigRequest{Name: "dscp0", Targets: []string{"dscp2"}})
+	require.NoError(t, err)
+	response, err = service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp2", Labels: selector})
+	require.NoError(t, err)
+	require.Len(t, response.Config.GetGroups(), 1)
+	assert.Equal(t, "NETOPS-1: mark customer X", response.Config.GetGroups()[0].GetDescription())
+
+	for _, tc := range []struct {
+		request *dscppb.SetRuleGroupMetadataRequest
+		code    codes.Code
+	}{
+		{&dscppb.SetRuleGroupMetadataRequest{Group: "customer-x"}, codes.InvalidArgument},
+		{&dscppb.SetRuleGroupMetadataRequest{Name: "dscp0"}, codes.InvalidArgument},
+		{&dscppb.SetRuleGroupMetadataRequest{Name: "dscp0", Group: "customer-x", Labels: map[string]string{"": "x"}}, codes.InvalidArgument},
+		{&dscppb.SetRuleGroupMetadataRequest{Name: "dscp9", Group: "customer-x"}, codes.NotFound},
+		{&dscppb.SetRuleGroupMetadataRequest{Name: "dscp0", Group: "customer-z"}, codes.NotFound},
+	} {
+		_, err := service.SetRuleGroupMetadata(ctx, tc.request)
+		require.Equal(t, tc.code, status.Code(err))
+	}
+}
+
+func Test_DscpService_GetFingerprint(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	first := newTestService(t)
+	second := newTestService(t)
+
+	// The order prefixes are added in and the rule group metadata do not
+	// matter.
+	_, err := first.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24", "10.1.0.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = second.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.1.0.0/24"},
+		Group:    "customer-x",
+	})
+	require.NoError(t, err)
+	_, err = second.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.0.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = second.SetRuleGroupMetadata(ctx, &dscppb.SetRuleGroupMetadataRequest{
+		Name:        "dscp0",
+		Group:       "customer-x",
+		Description: "customer X",
+	})
+	require.NoError(t, err)
+
+	fingerprint := func(service *DscpService) *dscppb.GetFingerprintResponse {
+		t.Helper()
+		response, err := service.GetFingerprint(ctx, &dscppb.GetFingerprintRequest{})
+		require.NoError(t, err)
+		return response
+	}
+	expected := fingerprint(first)
+	require.Len(t, expected.GetFingerprint(), 64)
+	require.Len(t, expected.GetConfigs(), 1)
+	require.Equal(t, expected.GetFingerprint(), fingerprint(second).GetFingerprint())
+
+	response, err := first.GetFingerprint(ctx, &dscppb.GetFingerprintRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	require.Empty(t, response.GetFingerprint())
+	require.Equal(t, expected.GetConfigs()[0].GetFingerprint(), response.GetConfigs()[0].GetFingerprint())
+
+	// Disabling a rule group changes the matched prefixes.
+	_, err = second.SetRuleGroupEnabled(ctx, &dscppb.SetRuleGroupEnabledRequest{
+		Name:  "dscp0",
+		Group: "customer-x",
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, expected.GetFingerprint(), fingerprint(second).GetFingerprint())
+
+	_, err = first.SetDscpMarking(ctx, &dscppb.SetDscpMarkingRequest{
+		Name:       "dscp0",
+		DscpConfig: &dscppb.DscpConfig{Flag: 2, Mark: 8},
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, expected.GetFingerprint(), fingerprint(first).GetFingerprint())
+
+	metrics := map[string]float64{}
+	for _, metric := range first.Metrics() {
+		if strings.HasSuffix(metric.GetName(), "_fingerprint") {
+			metrics[metric.GetName()] = metric.GetGauge()
+		}
+	}
+	require.Equal(t, fingerprintGauge(fingerprint(first).GetFingerprint()), metrics["dscp_fingerprint"])
+	require.NotZero(t, metrics["dscp_config_fingerprint"])
+
+	_, err = first.GetFingerprint(ctx, &dscppb.GetFingerprintRequest{Name: "dscp1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_DscpService_MarkingRules(t *testing.T) {
+	t.Parallel()
+
+	backend := &recordingBackend{}
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	rules := []*dscppb.Rule{
+		{
+			Name:      "voip",
+			Priority:  10,
+			SrcPrefix: "10.0.0.0/8",
+			Proto:     17,
+			DstPorts:  &dscppb.PortRange{From: 5060, To: 5061},
+			Mark:      46,
+		},
+		{
+			Name:      "backup",
+			Priority:  20,
+			DstPrefix: "2001:db8::1/32",
+			Mark:      8,
+			Remark:    dscppb.RemarkPolicy_REMARK_POLICY_ONLY_DEFAULT,
+		},
+		{
+			// Classifies the packets without rewriting their DSCP value.
+			Name:         "exempt",
+			Priority:     10,
+			Remark:       dscppb.RemarkPolicy_REMARK_POLICY_NEVER,
+			TrafficClass: 7,
+		},
+	}
+	for _, rule := range rules {
+		_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: rule})
+		require.NoError(t, err)
+	}
+
+	// Rules are matched by priority, then by name.
+	require.Equal(t, []cdscp.Rule{
+		{Name: "exempt", PortMax: 65535, TrafficClass: 7},
+		{
+			Name:    "voip",
+			Source:  netip.MustParsePrefix("10.0.0.0/8"),
+			Proto:   17,
+			PortMin: 5060,
+			PortMax: 5061,
+			Flag:    dscpMarkAlways,
+			Mark:    46,
+		},
+		{
+			Name:        "backup",
+			Destination: netip.MustParsePrefix("2001:db8::/32"),
+			PortMax:     65535,
+			Flag:        1,
+			Mark:        8,
+		},
+	}, backend.published(t, "dscp0").Rules)
+
+	response, err := service.ListRules(ctx, &dscppb.ListRulesRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	names := []string{}
+	for _, rule := range response.GetRules() {
+		names = append(names, rule.GetName())
+	}
+	assert.Equal(t, []string{"exempt", "voip", "backup"}, names)
+	assert.Equal(t, "2001:db8::/32", response.GetRules()[2].GetDstPrefix())
+	assert.Nil(t, response.GetRules()[2].GetDstPorts())
+	assert.Equal(t, uint32(5060), response.GetRules()[1].GetDstPorts().GetFrom())
+	assert.Equal(t, uint32(7), response.GetRules()[0].GetTrafficClass())
+	assert.Equal(t, dscppb.RemarkPolicy_REMARK_POLICY_NEVER, response.GetRules()[0].GetRemark())
+
+	show, err := service.ShowConfig(ctx, &dscppb.ShowConfigRequest{Name: "dscp0"})
+	require.NoError(t, err)
+	assert.Len(t, show.GetConfig().GetRules(), 3)
+
+	// A rule of the same name is replaced.
+	_, err = service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: &dscppb.Rule{Name: "exempt", Priority: 30}})
+	require.NoError(t, err)
+	require.Len(t, backend.published(t, "dscp0").Rules, 3)
+	assert.Equal(t, dscpMarkAlways, backend.published(t, "dscp0").Rules[2].Flag)
+
+	_, err = service.DeleteRule(ctx, &dscppb.DeleteRuleRequest{Name: "dscp0", Rule: "voip"})
+	require.NoError(t, err)
+	require.Len(t, backend.published(t, "dscp0").Rules, 2)
+
+	_, err = service.DeleteRule(ctx, &dscppb.DeleteRuleRequest{Name: "dscp0", Rule: "voip"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = service.ListRules(ctx, &dscppb.ListRulesRequest{Name: "dscp1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	for _, rule := range []*dscppb.Rule{
+		{},
+		{Name: "r", Mark: 64},
+		{Name: "r", DstPorts: &dscppb.PortRange{From: 10, To: 1}},
+		{Name: "r", DstPorts: &dscppb.PortRange{From: 1, To: 70000}},
+		{Name: "r", Proto: 1, DstPorts: &dscppb.PortRange{From: 1, To: 2}},
+		{Name: "r", Remark: 3},
+		{Name: "r", TrafficClass: 256},
+		{Name: "r", SrcPrefix: "10.0.0.0/8", DstPrefix: "2001:db8::/32"},
+		{Name: "r", SrcPrefix: "10.0.0.0"},
+	} {
+		_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: rule})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", rule)
+	}
+	_, err = service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_DscpService_MarkingRulesLimit(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	ctx := t.Context()
+
+	for idx := range maxMarkingRules {
+		_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{
+			Name: "dscp0",
+			Rule: &dscppb.Rule{Name: fmt.Sprintf("rule%d", idx)},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: &dscppb.Rule{Name: "extra"}})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	// Replacing a rule is not limited.
+	_, err = service.AddRule(ctx, &dscppb.AddRuleRequest{Name: "dscp0", Rule: &dscppb.Rule{Name: "rule0", Mark: 10}})
+	require.NoError(t, err)
+}
+
+// effectiveBackend emulates the dataplane decoding the published module
+// configs.
+type effectiveBackend struct {
+	*recordingBackend
+	configs map[string]*cdscp.EffectiveConfig
+}
+
+func newEffectiveBackend() *effectiveBackend {
+	m := &effectiveBackend{
+		recordingBackend: &recordingBackend{},
+		configs:          map[string]*cdscp.EffectiveConfig{},
+	}
+	m.update = func(call int, name string, settings ModuleSettings) (ModuleHandle, error) {
+		burst := settings.RateBurst
+		if burst == 0 {
+			burst = settings.RateThreshold
+		}
+		rules := make([]cdscp.Rule, 0, len(settings.Rules))
+		for _, rule := range settings.Rules {
+			rules = append(rules, rule.Effective())
+		}
+
+		generation := uint64(call)
+		m.configs[name] = &cdscp.EffectiveConfig{
+			Generation:       generation,
+			ModuleGeneration: generation,
+			Prefixes:         coveringPrefixes(settings.Prefixes),
+			SourcePrefixes:   coveringPrefixes(settings.SourcePrefixes),
+			Rules:            rules,
+			Flag:             settings.Flag,
+			Mark:             settings.Mark,
+			FragmentPolicy:   settings.FragmentPolicy,
+			DefaultAction:    settings.DefaultAction,
+			DefaultMark:      settings.DefaultMark,
+			RateThreshold:    settings.RateThreshold,
+			RateBurst:        burst,
+			ExtMaxHeaders:    settings.ExtMaxHeaders,
+			ExtFlags:         settings.ExtFlags,
+			FlowLogRate:      settings.FlowLogRate,
+		}
+		return &mockModuleHandle{}, nil
+	}
+	return m
+}
+
+func (m *effectiveBackend) EffectiveConfig(name string) (*cdscp.EffectiveConfig, error) {
+	config, ok := m.configs[name]
+	if !ok {
+		return nil, cdscp.ErrNotActive
+	}
+	return config, nil
+}
+
+func Test_DscpService_ShowEffectiveConfig(t *testing.T) {
+	t.Parallel()
+
+	backend := newEffectiveBackend()
+	service := NewDscpService(backend)
+	ctx := t.Context()
+
+	request := &dscppb.ShowEffectiveConfigRequest{Name: "dscp0"}
+	_, err := service.ShowEffectiveConfig(ctx, request)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = service.AddPrefixes(ctx, &dscppb.AddPrefixesRequest{
+		Name:     "dscp0",
+		Prefixes: []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.0.128/25", "2001:db8::/32"},
+	})
+	require.NoError(t, err)
+	_, err = service.AddRule(ctx, &dscppb.AddRuleRequest{
+		Name: "dscp0",
+		Rule: &dscppb.Rule{Name: "any4", SrcPrefix: "0.0.0.0/0", DstPrefix: "0.0.0.0/0", Mark: 46},
+	})
+	require.NoError(t, err)
+	_, err = service.SetRateThreshold(ctx, &dscppb.SetRateThresholdRequest{
+		Name:          "dscp0",
+		RateThreshold: &dscppb.RateThreshold{Rate: 1000},
+	})
+	require.NoError(t, err)
+
+	// The prefixes are merged and the burst defaulted as the dataplane
+	// holds them, which is no mismatch.
+	response, err := service.ShowEffectiveConfig(ctx, request)
+	require.NoError(t, err)
+	require.True(t, response.GetApplied())
+	require.Empty(t, response.GetMismatches())
+	require.Equal(t, uint64(3), response.GetGeneration())
+	require.Equal(t, []string{"10.0.0.0/23", "2001:db8::/32"}, response.GetConfig().GetPrefixes())
+	require.Equal(t, uint32(1000), response.GetConfig().GetRateThreshold().GetBurst())
+	require.Len(t, response.GetConfig().GetRules(), 1)
+	require.Equal(t, "0.0.0.0/0", response.GetConfig().GetRules()[0].GetDstPrefix())
+	require.Empty(t, response.GetConfig().GetRules()[0].GetSrcPrefix())
+
+	// A stale generation is reported field by field.
+	stale := *backend.configs["dscp0"]
+	stale.Prefixes = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}
+	stale.Rules = nil
+	backend.configs["dscp0"] = &stale
+
+	response, err = service.ShowEffectiveConfig(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"prefixes", "rules"}, response.GetMismatches())
+
+	// A config active in the dataplane only is not compared.
+	backend.configs["dscp1"] = &stale
+	response, err = service.ShowEffectiveConfig(ctx, &dscppb.ShowEffectiveConfigRequest{Name: "dscp1"})
+	require.NoError(t, err)
+	require.False(t, response.GetApplied())
+	require.Empty(t, response.GetMismatches())
+
+	_, err = newTestService(t).ShowEffectiveConfig(ctx, request)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func Test_ValidateRuleSet(t *testing.T) {
+	valid := func() RuleSetConfig {
+		return RuleSetConfig{
+			Prefixes:       []string{"10.0.0.0/24"},
+			SourcePrefixes: []string{"2001:db8::/32"},
+			Marking:        RuleSetMarking{Flag: 2, Mark: 46},
+			Rules: []RuleSetRule{
+				{
+					Name:      "voip",
+					Priority:  10,
+					DstPrefix: "10.0.0.0/24",
+					Proto:     17,
+					DstPorts:  &RuleSetPorts{From: 5060, To: 5061},
+					Marking:   RuleSetMarking{Flag: 2, Mark: 34},
+				},
+			},
+		}
+	}
+
+	tooManyRules := valid()
+	tooManyRules.Rules = nil
+	for idx := range maxMarkingRules + 1 {
+		tooManyRules.Rules = append(tooManyRules.Rules, RuleSetRule{Name: fmt.Sprintf("rule%d", idx)})
+	}
+
+	tests := []struct {
+		name   string
+		cfg    func(cfg *Config)
+		config func(config *RuleSetConfig)
+		code   codes.Code
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:   "invalid prefix",
+			config: func(config *RuleSetConfig) { config.Prefixes = []string{"10.0.0.0/33"} },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "invalid source prefix",
+			config: func(config *RuleSetConfig) { config.SourcePrefixes = []string{"::1/129"} },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "invalid marking flag",
+			config: func(config *RuleSetConfig) { config.Marking.Flag = 3 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "invalid mark",
+			config: func(config *RuleSetConfig) { config.Marking.Mark = 64 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "reserved codepoint",
+			config: func(config *RuleSetConfig) { config.Marking.Mark = dscpCS6 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "reserved codepoint allowed",
+			cfg:    func(cfg *Config) { cfg.ReservedMarks.AllowedConfigs = []string{"dscp0"} },
+			config: func(config *RuleSetConfig) { config.Marking.Mark = dscpCS6 },
+		},
+		{
+			name:   "reserved codepoint of a rule",
+			config: func(config *RuleSetConfig) { config.Rules[0].Marking.Mark = dscpCS7 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "invalid rule marking flag",
+			config: func(config *RuleSetConfig) { config.Rules[0].Marking.Flag = 3 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "rule ports without a transport protocol",
+			config: func(config *RuleSetConfig) { config.Rules[0].Proto = 1 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "rule prefixes of different families",
+			config: func(config *RuleSetConfig) { config.Rules[0].SrcPrefix = "2001:db8::/32" },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "duplicate rule",
+			config: func(config *RuleSetConfig) { config.Rules = append(config.Rules, config.Rules[0]) },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "too many rules",
+			config: func(config *RuleSetConfig) { *config = tooManyRules },
+			code:   codes.ResourceExhausted,
+		},
+		{
+			name: "invalid rule table config",
+			cfg:  func(cfg *Config) { cfg.RuleTable.RefuseThreshold = 2 },
+			code: codes.Unknown,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if test.cfg != nil {
+				test.cfg(cfg)
+			}
+			config := valid()
+			if test.config != nil {
+				test.config(&config)
+			}
+
+			err := ValidateRuleSet(cfg, &RuleSet{Configs: map[string]RuleSetConfig{"dscp0": config}})
+			if test.code == codes.OK {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, test.code, status.Code(err), err.Error())
+		})
+	}
+}
@@ -1,13 +1,245 @@
 #pragma once
 
+#include <stdatomic.h>
+#include <stdint.h>
+
 #include "common/lpm.h"
 #include "controlplane/config/cp_module.h"
 #include "dataplane/packet/dscp.h"
 
+// Number of matched-flow records kept per worker. Must be a power of two.
+#define DSCP_FLOW_LOG_RECORDS 256
+
+// Number of distinct DSCP values, one egress histogram slot per value.
+#define DSCP_VALUES 64
+
+// Name of the module counter holding the egress DSCP histogram.
+#define DSCP_EGRESS_COUNTER "dscp_egress"
+
+// Handling of non-initial fragments. They carry no transport header, so
+// they are only classified by their addresses and protocol. Fragments are
+// not tracked, so a fragment may be classified otherwise than the initial
+// fragment of its packet.
+enum dscp_fragment_policy {
+	// Match and mark by the module prefixes and the marking rules without
+	// destination ports, skipping the rules with destination ports.
+	DSCP_FRAGMENT_MATCH = 0,
+	// Pass without marking.
+	DSCP_FRAGMENT_PASS = 1,
+	// Drop.
+	DSCP_FRAGMENT_DROP = 2,
+};
+
+// Action taken on classified packets matching no module prefix.
+enum dscp_default_action {
+	// Pass with the DSCP value unchanged.
+	DSCP_DEFAULT_PASS = 0,
+	// Mark with the default mark.
+	DSCP_DEFAULT_MARK = 1,
+	// Drop.
+	DSCP_DEFAULT_DROP = 2,
+};
+
+// Name of the module counter holding the number of packets the default
+// action was taken on.
+#define DSCP_DEFAULT_ACTION_COUNTER "dscp_default_action"
+
+// Slots of the marking counters, counting the packets matching a marking
+// rule or a module prefix, and their bytes, by what marking did to them.
+enum dscp_marking_counter {
+	// Packets matched.
+	DSCP_MARKING_MATCHED = 0,
+	// Matched packets written the mark.
+	DSCP_MARKING_REMARKED = 1,
+	// Matched packets left with their DSCP value, being in profile or
+	// exempt by the marking flag.
+	DSCP_MARKING_UNTOUCHED = 2,
+	// Bytes of the matched packets, counted by their mbuf packet length.
+	DSCP_MARKING_MATCHED_BYTES = 3,
+	// Bytes of the matched packets written the mark.
+	DSCP_MARKING_REMARKED_BYTES = 4,
+	DSCP_MARKING_COUNTER_SIZE = 5,
+};
+
+// Name of the module counter holding the marking slots of every packet
+// matching a marking rule or a module prefix.
+#define DSCP_MARKING_COUNTER "dscp_marking"
+
+// Prefix of the names of the module counters holding the marking slots of
+// a single marking rule, followed by the rule name.
+#define DSCP_RULE_COUNTER_PREFIX "dscp_rule:"
+
+// Name of the module counter holding the IPv6 extension header anomalies,
+// one slot per enum dscp_ext_anomaly.
+#define DSCP_EXT_ANOMALY_COUNTER "dscp_ext_anomaly"
+
+// Flags of struct dscp_ext_limits.
+//
+// Skip extension headers of known extension types the module cannot
+// interpret, such as Mobility or HIP, instead of treating them as an
+// anomaly.
+#define DSCP_EXT_SKIP_UNKNOWN (1 << 0)
+// Drop anomalous packets instead of passing them without marking.
+#define DSCP_EXT_DROP_ANOMALY (1 << 1)
+
+// Anomalies of an IPv6 extension header chain.
+enum dscp_ext_anomaly {
+	// The chain is longer than the configured maximum.
+	DSCP_EXT_ANOMALY_LIMIT = 0,
+	// The chain contains a header the module cannot interpret.
+	DSCP_EXT_ANOMALY_UNKNOWN = 1,
+	// A header is truncated or misplaced.
+	DSCP_EXT_ANOMALY_MALFORMED = 2,
+	DSCP_EXT_ANOMALY_COUNT,
+};
+
+// Limits of the IPv6 extension header chain walked before a packet is
+// classified.
+struct dscp_ext_limits {
+	// Maximum number of extension headers. Zero disables the walk.
+	uint8_t max_headers;
+	// Combination of DSCP_EXT_* flags.
+	uint8_t flags;
+};
+
+// Flow tuple of a packet that matched the module prefixes.
+struct dscp_flow_record {
+	// Worker time in nanoseconds when the packet was seen.
+	uint64_t timestamp;
+	// Source and destination addresses; IPv4 addresses occupy the first
+	// four bytes.
+	uint8_t src_addr[16];
+	uint8_t dst_addr[16];
+	// Transport ports in host byte order; zero for protocols without
+	// ports.
+	uint16_t src_port;
+	uint16_t dst_port;
+	// Address family: 4 or 6.
+	uint8_t family;
+	uint8_t proto;
+	// DSCP value of the packet before the module processed it.
+	uint8_t original_dscp;
+	// Non-zero if the module rewrote the DSCP value.
+	uint8_t remarked;
+};
+
+// Per-worker ring of matched-flow records.
+//
+// The worker is the only writer. Readers copy records below write_idx and
+// treat everything older than write_idx - DSCP_FLOW_LOG_RECORDS as lost.
+struct dscp_flow_log {
+	_Atomic uint64_t write_idx;
+	// Start of the current one-second rate limiting window.
+	uint64_t window_start;
+	// Number of records written in the current window.
+	uint32_t window_count;
+	struct dscp_flow_record records[DSCP_FLOW_LOG_RECORDS];
+};
+
+// Number of tokens a packet costs from a rate bucket. Buckets refill
+// rate_threshold tokens per nanosecond, which meters rate_threshold
+// packets per second.
+#define DSCP_RATE_PACKET_TOKENS 1000000000ULL
+
+// Per-worker token bucket metering the matched packets against the rate
+// threshold.
+//
+// The worker is the only writer.
+struct dscp_rate_bucket {
+	uint64_t tokens;
+	// Worker time in nanoseconds of the last refill.
+	uint64_t refilled_at;
+};
+
+// Maximum number of marking rules of a module config. Rules are scanned
+// in order for every classified packet, so the table is kept short.
+#define DSCP_RULES_MAX 256
+
+// Marking rule matching packets by their addresses, transport protocol
+// and destination port.
+//
+// Rules are evaluated in order before the module prefixes. The first
+// matching rule marks the packet with its own marking, which may also
+// leave the DSCP value as it is, and sets its traffic class if any.
+struct dscp_rule {
+	// Address family of the prefixes: 4 or 6, zero for a rule without
+	// prefixes that matches both families.
+	uint8_t family;
+	// IP protocol number of the transport header, zero matches any.
+	uint8_t proto;
+	// Prefix lengths, zero matches any address.
+	uint8_t src_prefix_len;
+	uint8_t dst_prefix_len;
+	// Masked prefix addresses; IPv4 addresses occupy the first four
+	// bytes.
+	uint8_t src_addr[16];
+	uint8_t dst_addr[16];
+	// Inclusive range of destination ports in host byte order. A range
+	// other than [0, 65535] only matches TCP and UDP packets carrying
+	// their transport header.
+	uint16_t port_min;
+	uint16_t port_max;
+	struct dscp_config dscp;
+	// Internal traffic class set on the matched packets, see struct
+	// packet, whatever the marking does to their DSCP value. Zero leaves
+	// the class of the packets as it is.
+	uint8_t traffic_class;
+	// Counter of DSCP_MARKING_COUNTER_SIZE slots counting the packets
+	// matching the rule, or -1 if not registered.
+	uint64_t counter_id;
+};
+
 struct dscp_module_config {
 	struct cp_module cp_module;
 
+	// Prefixes matched against the destination address.
 	struct lpm lpm_v4;
 	struct lpm lpm_v6;
+	// Prefixes matched against the source address. A packet is classified
+	// if either of its addresses matches.
+	struct lpm src_lpm_v4;
+	struct lpm src_lpm_v6;
 	struct dscp_config dscp;
+	uint64_t rule_count;
+	// Relative pointer to rule_count marking rules, matched before the
+	// prefixes.
+	struct dscp_rule *rules;
+	// One of enum dscp_fragment_policy.
+	uint8_t fragment_policy;
+	struct dscp_ext_limits ext_limits;
+	// One of enum dscp_default_action.
+	uint8_t default_action;
+	// DSCP value DSCP_DEFAULT_MARK marks with.
+	uint8_t default_mark;
+
+	// Rate matched packets are marked above, in packets per second per
+	// worker. Packets within the rate, with bursts of up to rate_burst
+	// packets, are in profile and keep their DSCP value. Zero marks every
+	// matched packet.
+	uint32_t rate_threshold;
+	uint32_t rate_burst;
+	uint64_t rate_bucket_count;
+	// Relative pointer to rate_bucket_count per-worker buckets.
+	struct dscp_rate_bucket *rate_buckets;
+
+	// Maximum number of matched flows recorded per second by each
+	// worker. Zero disables flow logging.
+	uint32_t flow_log_rate;
+	uint64_t flow_log_count;
+	// Relative pointer to flow_log_count per-worker logs.
+	struct dscp_flow_log *flow_logs;
+
+	// Counter of DSCP_VALUES slots counting packets leaving the module by
+	// their final DSCP value, or -1 if not registered.
+	uint64_t egress_counter_id;
+	// Counter of DSCP_EXT_ANOMALY_COUNT slots counting IPv6 packets by
+	// the anomaly of their extension header chain, or -1 if not
+	// registered.
+	uint64_t ext_anomaly_counter_id;
+	// Counter of a single slot counting packets the default action was
+	// taken on, or -1 if not registered.
+	uint64_t default_action_counter_id;
+	// Counter of DSCP_MARKING_COUNTER_SIZE slots counting the packets
+	// matching a marking rule or a prefix, or -1 if not registered.
+	uint64_t marking_counter_id;
 };
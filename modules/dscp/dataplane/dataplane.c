@@ -1,8 +1,14 @@
 #include "config.h"
 
+#include <netinet/in.h>
+#include <string.h>
+
 #include <rte_ether.h>
 #include <rte_ip.h>
+#include <rte_tcp.h>
+#include <rte_udp.h>
 
+#include "counters/counters.h"
 #include "dataplane/config/zone.h"
 
 #include "dataplane/module/module.h"
@@ -12,75 +18,639 @@
 #include "lib/dataplane/packet/data.h"
 #include "lib/dataplane/pipeline/econtext.h"
 
+// Result of classifying a packet that matched no module prefix, distinct
+// from the results of dscp_mark_v4 and dscp_mark_v6.
+#define DSCP_UNMATCHED 1
+
+// Returns the flow log slot for the next record or NULL if flow logging
+// is disabled or the per-second budget of the worker is exhausted.
+static inline struct dscp_flow_record *
+dscp_flow_log_reserve(
+	struct dscp_module_config *config, struct dp_worker *dp_worker
+) {
+	if (config->flow_log_rate == 0 || dp_worker == NULL ||
+	    dp_worker->idx >= config->flow_log_count) {
+		return NULL;
+	}
+
+	struct dscp_flow_log *log = ADDR_OF(&config->flow_logs) + dp_worker->idx;
+	if (dp_worker->current_time - log->window_start >= 1000000000ULL) {
+		log->window_start = dp_worker->current_time;
+		log->window_count = 0;
+	}
+	if (log->window_count >= config->flow_log_rate) {
+		return NULL;
+	}
+	log->window_count++;
+
+	uint64_t idx = atomic_load_explicit(&log->write_idx, memory_order_relaxed);
+	struct dscp_flow_record *record =
+		log->records + (idx & (DSCP_FLOW_LOG_RECORDS - 1));
+	memset(record, 0, sizeof(*record));
+	record->timestamp = dp_worker->current_time;
+	return record;
+}
+
+// Publishes the record reserved by dscp_flow_log_reserve.
+static inline void
+dscp_flow_log_commit(
+	struct dscp_module_config *config, struct dp_worker *dp_worker
+) {
+	struct dscp_flow_log *log = ADDR_OF(&config->flow_logs) + dp_worker->idx;
+	atomic_fetch_add_explicit(&log->write_idx, 1, memory_order_release);
+}
+
+// Returns non-zero if the matched packet is within the rate threshold of
+// the worker, taking its tokens from the worker bucket.
+//
+// Packets are out of profile when no threshold is set, so every matched
+// packet is marked.
+static inline int
+dscp_in_profile(
+	struct dscp_module_config *config, struct dp_worker *dp_worker
+) {
+	if (config->rate_threshold == 0 || dp_worker == NULL ||
+	    dp_worker->idx >= config->rate_bucket_count) {
+		return 0;
+	}
+
+	struct dscp_rate_bucket *bucket =
+		ADDR_OF(&config->rate_buckets) + dp_worker->idx;
+	uint64_t rate = config->rate_threshold;
+	uint64_t capacity = config->rate_burst * DSCP_RATE_PACKET_TOKENS;
+
+	uint64_t elapsed = dp_worker->current_time - bucket->refilled_at;
+	bucket->refilled_at = dp_worker->current_time;
+	// Compared by division, as the elapsed time of an idle bucket
+	// overflows the product.
+	if (elapsed >= (capacity - bucket->tokens) / rate) {
+		bucket->tokens = capacity;
+	} else {
+		bucket->tokens += elapsed * rate;
+	}
+
+	if (bucket->tokens < DSCP_RATE_PACKET_TOKENS) {
+		return 0;
+	}
+	bucket->tokens -= DSCP_RATE_PACKET_TOKENS;
+	return 1;
+}
+
+// Returns non-zero for fragments other than the first one of a packet.
+static inline int
+dscp_is_non_initial_fragment(struct packet *packet) {
+	return (packet->flags & (1 << PACKET_FLAG_FRAGMENTED)) &&
+	       packet->fragment_offset != 0;
+}
+
+// Walks the extension header chain of an IPv6 packet within the limits.
+//
+// Returns the enum dscp_ext_anomaly of the chain or -1 if the chain ends
+// with an upper layer header in time.
+static inline int
+dscp_ext_check(struct dscp_ext_limits limits, struct packet *packet) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	struct rte_ipv6_hdr *header = rte_pktmbuf_mtod_offset(
+		mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
+	);
+
+	uint32_t offset =
+		packet->network_header.offset + sizeof(struct rte_ipv6_hdr);
+	uint32_t max_offset = offset + rte_be_to_cpu_16(header->payload_len);
+	uint8_t type = header->proto;
+
+	for (uint32_t count = 0;; ++count) {
+		switch (type) {
+		case IPPROTO_HOPOPTS:
+			// Hop-by-Hop Options may only follow the IPv6 header.
+			if (count != 0) {
+				return DSCP_EXT_ANOMALY_MALFORMED;
+			}
+			break;
+		case IPPROTO_ROUTING:
+		case IPPROTO_DSTOPTS:
+		case IPPROTO_AH:
+		case IPPROTO_FRAGMENT:
+			break;
+		case 135: // Mobility
+		case 139: // HIP
+		case 140: // Shim6
+		case 253: // Experimentation
+		case 254:
+			if (!(limits.flags & DSCP_EXT_SKIP_UNKNOWN)) {
+				return DSCP_EXT_ANOMALY_UNKNOWN;
+			}
+			break;
+		default:
+			// Upper layer header or No Next Header.
+			if (offset > max_offset) {
+				return DSCP_EXT_ANOMALY_MALFORMED;
+			}
+			return -1;
+		}
+
+		if (count == limits.max_headers) {
+			return DSCP_EXT_ANOMALY_LIMIT;
+		}
+		if (max_offset < offset + 8) {
+			return DSCP_EXT_ANOMALY_MALFORMED;
+		}
+
+		struct yanet_ipv6_ext_2byte *ext = rte_pktmbuf_mtod_offset(
+			mbuf, struct yanet_ipv6_ext_2byte *, offset
+		);
+		if (type == IPPROTO_FRAGMENT) {
+			struct yanet_ipv6_ext_fragment *fragment =
+				(struct yanet_ipv6_ext_fragment *)ext;
+			// Non-initial fragments carry no further headers.
+			if (fragment->offset_flag & rte_cpu_to_be_16(0xFFF8)) {
+				return -1;
+			}
+			offset += RTE_IPV6_FRAG_HDR_SIZE;
+		} else if (type == IPPROTO_AH) {
+			offset += (2 + ext->extension_length) * 4;
+		} else {
+			offset += (1 + ext->extension_length) * 8;
+		}
+		type = ext->next_header;
+	}
+}
+
+// Reads the transport ports of a TCP or UDP packet in host byte order.
+//
+// Returns non-zero if the packet carries ports, which non-initial
+// fragments never do.
+static inline int
+dscp_packet_ports(
+	struct packet *packet, uint16_t *src_port, uint16_t *dst_port
+) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	if (dscp_is_non_initial_fragment(packet)) {
+		return 0;
+	}
+	uint16_t proto = packet->transport_header.type;
+	if (proto == IPPROTO_TCP) {
+		struct rte_tcp_hdr *tcp = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_tcp_hdr *, packet->transport_header.offset
+		);
+		*src_port = rte_be_to_cpu_16(tcp->src_port);
+		*dst_port = rte_be_to_cpu_16(tcp->dst_port);
+		return 1;
+	}
+	if (proto == IPPROTO_UDP) {
+		struct rte_udp_hdr *udp = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_udp_hdr *, packet->transport_header.offset
+		);
+		*src_port = rte_be_to_cpu_16(udp->src_port);
+		*dst_port = rte_be_to_cpu_16(udp->dst_port);
+		return 1;
+	}
+	return 0;
+}
+
+static inline void
+dscp_flow_record_ports(
+	struct dscp_flow_record *record, struct packet *packet
+) {
+	record->proto = packet->transport_header.type;
+	dscp_packet_ports(packet, &record->src_port, &record->dst_port);
+}
+
+// Returns non-zero if the leading len bits of the address match the
+// masked prefix address.
+static inline int
+dscp_prefix_match(const uint8_t *addr, const uint8_t *prefix, uint8_t len) {
+	uint8_t bytes = len / 8;
+	if (memcmp(addr, prefix, bytes) != 0) {
+		return 0;
+	}
+	uint8_t bits = len % 8;
+	if (bits == 0) {
+		return 1;
+	}
+	uint8_t mask = (uint8_t)(0xFF << (8 - bits));
+	return (addr[bytes] & mask) == prefix[bytes];
+}
+
+// Returns the first marking rule matching the packet or NULL.
+static inline struct dscp_rule *
+dscp_rule_lookup(
+	struct dscp_module_config *config,
+	struct packet *packet,
+	uint8_t family,
+	const uint8_t *src_addr,
+	const uint8_t *dst_addr
+) {
+	if (config->rule_count == 0) {
+		return NULL;
+	}
+
+	uint8_t proto = packet->transport_header.type;
+	uint16_t src_port = 0;
+	uint16_t dst_port = 0;
+	int has_ports = dscp_packet_ports(packet, &src_port, &dst_port);
+
+	struct dscp_rule *rules = ADDR_OF(&config->rules);
+	for (uint64_t idx = 0; idx < config->rule_count; idx++) {
+		struct dscp_rule *rule = rules + idx;
+		if (rule->family != 0 && rule->family != family) {
+			continue;
+		}
+		if (rule->proto != 0 && rule->proto != proto) {
+			continue;
+		}
+		if ((rule->port_min != 0 || rule->port_max != UINT16_MAX) &&
+		    (!has_ports || dst_port < rule->port_min ||
+		     dst_port > rule->port_max)) {
+			continue;
+		}
+		if (!dscp_prefix_match(
+			    src_addr, rule->src_addr, rule->src_prefix_len
+		    ) ||
+		    !dscp_prefix_match(
+			    dst_addr, rule->dst_addr, rule->dst_prefix_len
+		    )) {
+			continue;
+		}
+		return rule;
+	}
+	return NULL;
+}
+
+// Sets the traffic class of the rule on the matched packet.
+//
+// The class is set before the rate threshold is applied, so downstream
+// modules see the class of in-profile packets too.
+static inline void
+dscp_set_traffic_class(struct packet *packet, struct dscp_rule *rule) {
+	if (rule->traffic_class != 0) {
+		packet->traffic_class = rule->traffic_class;
+	}
+}
+
+// Accounts a packet matching a marking rule or a module prefix by the
+// result of marking it, in the counter of the rule too if any.
+static inline void
+dscp_marking_count(
+	struct dscp_module_config *config,
+	struct dp_worker *dp_worker,
+	struct counter_storage *counter_storage,
+	struct dscp_rule *rule,
+	struct packet *packet,
+	int result
+) {
+	if (dp_worker == NULL) {
+		return;
+	}
+
+	uint64_t bytes = rte_pktmbuf_pkt_len(packet_to_mbuf(packet));
+	uint64_t slot = result == 0 ? DSCP_MARKING_REMARKED
+				    : DSCP_MARKING_UNTOUCHED;
+	uint64_t counter_ids[] = {
+		config->marking_counter_id,
+		rule != NULL ? rule->counter_id : (uint64_t)-1,
+	};
+	for (size_t idx = 0; idx < sizeof(counter_ids) / sizeof(*counter_ids);
+	     idx++) {
+		if (counter_ids[idx] == (uint64_t)-1) {
+			continue;
+		}
+		uint64_t *counter = counter_get_address(
+			counter_ids[idx], dp_worker->idx, counter_storage
+		);
+		counter[DSCP_MARKING_MATCHED] += 1;
+		counter[slot] += 1;
+		counter[DSCP_MARKING_MATCHED_BYTES] += bytes;
+		if (result == 0) {
+			counter[DSCP_MARKING_REMARKED_BYTES] += bytes;
+		}
+	}
+}
+
 static int
-dscp_handle_v4(struct dscp_module_config *config, struct packet *packet) {
+dscp_handle_v4(
+	struct dscp_module_config *config,
+	struct dp_worker *dp_worker,
+	struct counter_storage *counter_storage,
+	struct packet *packet
+) {
 	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
 
 	struct rte_ipv4_hdr *header = rte_pktmbuf_mtod_offset(
 		mbuf, struct rte_ipv4_hdr *, packet->network_header.offset
 	);
 
-	if (lpm_lookup(&config->lpm_v4, 4, (uint8_t *)&header->dst_addr) !=
-	    LPM_VALUE_INVALID) {
-		return dscp_mark_v4(header, config->dscp);
+	struct dscp_config dscp = config->dscp;
+	struct dscp_rule *rule = dscp_rule_lookup(
+		config,
+		packet,
+		4,
+		(uint8_t *)&header->src_addr,
+		(uint8_t *)&header->dst_addr
+	);
+	if (rule != NULL) {
+		dscp = rule->dscp;
+		dscp_set_traffic_class(packet, rule);
+	} else if (lpm_lookup(
+			   &config->lpm_v4, 4, (uint8_t *)&header->dst_addr
+		   ) == LPM_VALUE_INVALID &&
+		   lpm_lookup(
+			   &config->src_lpm_v4,
+			   4,
+			   (uint8_t *)&header->src_addr
+		   ) == LPM_VALUE_INVALID) {
+		return DSCP_UNMATCHED;
+	}
+
+	struct dscp_flow_record *record =
+		dscp_flow_log_reserve(config, dp_worker);
+	if (record != NULL) {
+		record->family = 4;
+		memcpy(record->src_addr, &header->src_addr, 4);
+		memcpy(record->dst_addr, &header->dst_addr, 4);
+		record->original_dscp =
+			header->type_of_service >> DSCP_MARK_SHIFT;
+		dscp_flow_record_ports(record, packet);
 	}
 
-	return -1;
+	// In-profile packets keep their DSCP value, like packets dscp_mark_v4
+	// does not remark.
+	int result = -1;
+	if (!dscp_in_profile(config, dp_worker)) {
+		result = dscp_mark_v4(header, dscp);
+	}
+
+	if (record != NULL) {
+		record->remarked = result == 0;
+		dscp_flow_log_commit(config, dp_worker);
+	}
+	dscp_marking_count(
+		config, dp_worker, counter_storage, rule, packet, result
+	);
+
+	return result;
 }
 
 static int
-dscp_handle_v6(struct dscp_module_config *config, struct packet *packet) {
+dscp_handle_v6(
+	struct dscp_module_config *config,
+	struct dp_worker *dp_worker,
+	struct counter_storage *counter_storage,
+	struct packet *packet
+) {
 	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
 
 	struct rte_ipv6_hdr *header = rte_pktmbuf_mtod_offset(
 		mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
 	);
 
-	if (lpm_lookup(&config->lpm_v6, 16, (uint8_t *)&header->dst_addr) !=
-	    LPM_VALUE_INVALID) {
-		return dscp_mark_v6(header, config->dscp);
+	struct dscp_config dscp = config->dscp;
+	struct dscp_rule *rule = dscp_rule_lookup(
+		config,
+		packet,
+		6,
+		(uint8_t *)&header->src_addr,
+		(uint8_t *)&header->dst_addr
+	);
+	if (rule != NULL) {
+		dscp = rule->dscp;
+		dscp_set_traffic_class(packet, rule);
+	} else if (lpm_lookup(
+			   &config->lpm_v6, 16, (uint8_t *)&header->dst_addr
+		   ) == LPM_VALUE_INVALID &&
+		   lpm_lookup(
+			   &config->src_lpm_v6,
+			   16,
+			   (uint8_t *)&header->src_addr
+		   ) == LPM_VALUE_INVALID) {
+		return DSCP_UNMATCHED;
+	}
+
+	struct dscp_flow_record *record =
+		dscp_flow_log_reserve(config, dp_worker);
+	if (record != NULL) {
+		record->family = 6;
+		memcpy(record->src_addr, &header->src_addr, 16);
+		memcpy(record->dst_addr, &header->dst_addr, 16);
+		record->original_dscp =
+			(rte_be_to_cpu_32(header->vtc_flow) >>
+			 RTE_IPV6_HDR_TC_SHIFT) >>
+			DSCP_MARK_SHIFT;
+		dscp_flow_record_ports(record, packet);
+	}
+
+	int result = -1;
+	if (!dscp_in_profile(config, dp_worker)) {
+		result = dscp_mark_v6(header, dscp);
 	}
 
-	return -1;
+	if (record != NULL) {
+		record->remarked = result == 0;
+		dscp_flow_log_commit(config, dp_worker);
+	}
+	dscp_marking_count(
+		config, dp_worker, counter_storage, rule, packet, result
+	);
+
+	return result;
 }
 
+// Classifies and marks an IP packet by the marking rules, then by the
+// module prefixes.
+//
+// Returns DSCP_UNMATCHED if the packet matched neither, otherwise the
+// result of marking it. Non-IP packets are left as they are.
 static inline int
-dscp_handle(struct dscp_module_config *config, struct packet *packet) {
+dscp_handle(
+	struct dscp_module_config *config,
+	struct dp_worker *dp_worker,
+	struct counter_storage *counter_storage,
+	struct packet *packet
+) {
 	uint16_t type = packet->network_header.type;
-	int result = -1;
+	int result = 0;
 	if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4)) {
-		result = dscp_handle_v4(config, packet);
+		result = dscp_handle_v4(
+			config, dp_worker, counter_storage, packet
+		);
 	} else if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
-		result = dscp_handle_v6(config, packet);
+		result = dscp_handle_v6(
+			config, dp_worker, counter_storage, packet
+		);
 	}
 	return result;
 }
 
+// Marks an IP packet matching no prefix with the default mark.
+static inline void
+dscp_default_mark(struct packet *packet, uint8_t mark) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+	struct dscp_config config = {.flag = DSCP_MARK_ALWAYS, .mark = mark};
+
+	uint16_t type = packet->network_header.type;
+	if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4)) {
+		dscp_mark_v4(
+			rte_pktmbuf_mtod_offset(
+				mbuf,
+				struct rte_ipv4_hdr *,
+				packet->network_header.offset
+			),
+			config
+		);
+	} else if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
+		dscp_mark_v6(
+			rte_pktmbuf_mtod_offset(
+				mbuf,
+				struct rte_ipv6_hdr *,
+				packet->network_header.offset
+			),
+			config
+		);
+	}
+}
+
+// Accounts the final DSCP value of an IP packet in the egress histogram.
+static inline void
+dscp_egress_count(uint64_t *egress_counter, struct packet *packet) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	uint16_t type = packet->network_header.type;
+	uint8_t dscp;
+	if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4)) {
+		struct rte_ipv4_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv4_hdr *, packet->network_header.offset
+		);
+		dscp = header->type_of_service >> DSCP_MARK_SHIFT;
+	} else if (type == rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
+		struct rte_ipv6_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
+		);
+		dscp = (rte_be_to_cpu_32(header->vtc_flow) >>
+			RTE_IPV6_HDR_TC_SHIFT) >>
+		       DSCP_MARK_SHIFT;
+	} else {
+		return;
+	}
+
+	egress_counter[dscp & (DSCP_VALUES - 1)] += 1;
+}
+
 void
 dscp_handle_packets(
 	struct dp_worker *dp_worker,
 	struct module_ectx *module_ectx,
 	struct packet_front *packet_front
 ) {
-	(void)dp_worker;
 	struct dscp_module_config *dscp_config = container_of(
 		ADDR_OF(&module_ectx->cp_module),
 		struct dscp_module_config,
 		cp_module
 	);
 
-	if (dscp_config->dscp.flag != DSCP_MARK_NEVER) {
-		struct packet *packet;
-		while ((packet = packet_list_pop(&packet_front->input)) != NULL
-		) {
-			dscp_handle(dscp_config, packet);
-			packet_list_add(&packet_front->output, packet);
-		}
-	} else {
+	struct counter_storage *counter_storage =
+		ADDR_OF(&module_ectx->counter_storage);
+
+	uint64_t *egress_counter = NULL;
+	if (dscp_config->egress_counter_id != (uint64_t)-1 &&
+	    dp_worker != NULL) {
+		egress_counter = counter_get_address(
+			dscp_config->egress_counter_id,
+			dp_worker->idx,
+			counter_storage
+		);
+	}
+
+	struct dscp_ext_limits ext_limits = dscp_config->ext_limits;
+	uint64_t *ext_anomaly_counter = NULL;
+	if (ext_limits.max_headers != 0 &&
+	    dscp_config->ext_anomaly_counter_id != (uint64_t)-1 &&
+	    dp_worker != NULL) {
+		ext_anomaly_counter = counter_get_address(
+			dscp_config->ext_anomaly_counter_id,
+			dp_worker->idx,
+			counter_storage
+		);
+	}
+
+	// Packets are classified when they may be marked by the rules or the
+	// prefixes, or are subject to a default action other than passing
+	// them.
+	uint8_t default_action = dscp_config->default_action;
+	int classify_all = dscp_config->dscp.flag != DSCP_MARK_NEVER ||
+			   dscp_config->rule_count != 0 ||
+			   default_action != DSCP_DEFAULT_PASS;
+
+	uint64_t *default_action_counter = NULL;
+	if (classify_all &&
+	    dscp_config->default_action_counter_id != (uint64_t)-1 &&
+	    dp_worker != NULL) {
+		default_action_counter = counter_get_address(
+			dscp_config->default_action_counter_id,
+			dp_worker->idx,
+			counter_storage
+		);
+	}
+
+	uint8_t fragment_policy = dscp_config->fragment_policy;
+	if (!classify_all && egress_counter == NULL &&
+	    fragment_policy != DSCP_FRAGMENT_DROP &&
+	    ext_limits.max_headers == 0) {
 		packet_front_pass(packet_front);
+		return;
 	}
 
-	return;
+	struct packet *packet;
+	while ((packet = packet_list_pop(&packet_front->input)) != NULL) {
+		int classify = classify_all;
+		if (fragment_policy != DSCP_FRAGMENT_MATCH &&
+		    dscp_is_non_initial_fragment(packet)) {
+			if (fragment_policy == DSCP_FRAGMENT_DROP) {
+				packet_front_drop(packet_front, packet);
+				continue;
+			}
+			classify = 0;
+		}
+		if (ext_limits.max_headers != 0 &&
+		    packet->network_header.type ==
+			    rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
+			int anomaly = dscp_ext_check(ext_limits, packet);
+			if (anomaly >= 0) {
+				if (ext_anomaly_counter != NULL) {
+					ext_anomaly_counter[anomaly] += 1;
+				}
+				if (ext_limits.flags & DSCP_EXT_DROP_ANOMALY) {
+					packet_front_drop(packet_front, packet);
+					continue;
+				}
+				classify = 0;
+			}
+		}
+		// Packets passed unclassified by the fragment or extension
+		// header policies are not subject to the default action.
+		if (classify &&
+		    dscp_handle(
+			    dscp_config, dp_worker, counter_storage, packet
+		    ) == DSCP_UNMATCHED) {
+			if (default_action_counter != NULL) {
+				*default_action_counter += 1;
+			}
+			if (default_action == DSCP_DEFAULT_DROP) {
+				packet_front_drop(packet_front, packet);
+				continue;
+			}
+			if (default_action == DSCP_DEFAULT_MARK) {
+				dscp_default_mark(
+					packet, dscp_config->default_mark
+				);
+			}
+		}
+		if (egress_counter != NULL) {
+			dscp_egress_count(egress_counter, packet);
+		}
+		packet_list_add(&packet_front->output, packet);
+	}
 }
 
 struct dscp_module {
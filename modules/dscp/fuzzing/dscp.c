@@ -34,6 +34,26 @@ dscp_test_config(struct cp_module **cp_module) {
 	config->cp_module.dp_module_idx = 0;
 	config->cp_module.agent = NULL;
 
+	config->rule_count = 0;
+	config->rules = NULL;
+	config->fragment_policy = DSCP_FRAGMENT_MATCH;
+	config->default_action = DSCP_DEFAULT_PASS;
+	config->default_mark = 0;
+	// Walk extension header chains of fuzzed IPv6 packets.
+	config->ext_limits.max_headers = 8;
+	config->ext_limits.flags = DSCP_EXT_SKIP_UNKNOWN;
+	config->flow_log_rate = 0;
+	config->flow_log_count = 0;
+	config->flow_logs = NULL;
+	config->rate_threshold = 0;
+	config->rate_burst = 0;
+	config->rate_bucket_count = 0;
+	config->rate_buckets = NULL;
+	config->egress_counter_id = (uint64_t)-1;
+	config->ext_anomaly_counter_id = (uint64_t)-1;
+	config->default_action_counter_id = (uint64_t)-1;
+	config->marking_counter_id = (uint64_t)-1;
+
 	struct memory_context *memory_context =
 		&config->cp_module.memory_context;
 	if (lpm_init(&config->lpm_v4, memory_context)) {
@@ -42,6 +62,12 @@ dscp_test_config(struct cp_module **cp_module) {
 	if (lpm_init(&config->lpm_v6, memory_context)) {
 		goto error_lpm_v6;
 	}
+	if (lpm_init(&config->src_lpm_v4, memory_context)) {
+		goto error_src_lpm_v4;
+	}
+	if (lpm_init(&config->src_lpm_v6, memory_context)) {
+		goto error_src_lpm_v6;
+	}
 
 	// 127.0.0.0/24
 	int rc = dscp_module_config_add_prefix_v4(
@@ -50,7 +76,7 @@ dscp_test_config(struct cp_module **cp_module) {
 		(uint8_t[4]){127, 0, 0, 0xff}
 	);
 	if (rc != 0) {
-		goto error_lpm_v6;
+		goto error_prefixes;
 	}
 	// fe80::0/96
 	rc = dscp_module_config_add_prefix_v6(
@@ -61,19 +87,28 @@ dscp_test_config(struct cp_module **cp_module) {
 		}
 	);
 	if (rc != 0) {
-		goto error_lpm_v6;
+		goto error_prefixes;
 	}
 
 	rc = dscp_module_config_set_dscp_marking(
 		&config->cp_module, DSCP_MARK_DEFAULT, 46
 	);
 	if (rc != 0) {
-		goto error_lpm_v6;
+		goto error_prefixes;
 	}
 
 	*cp_module = (struct cp_module *)config;
 	return 0;
 
+error_prefixes:
+	lpm_free(&config->src_lpm_v6);
+
+error_src_lpm_v6:
+	lpm_free(&config->src_lpm_v4);
+
+error_src_lpm_v4:
+	lpm_free(&config->lpm_v6);
+
 error_lpm_v6:
 	lpm_free(&config->lpm_v4);
 
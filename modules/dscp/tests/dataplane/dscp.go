@@ -5,7 +5,13 @@ package dscp_test
 //#cgo LDFLAGS: -L../../../../build/lib/dataplane/packet -lpacket
 //#cgo LDFLAGS: -L../../../../build/lib/logging -llogging
 /*
+#include <string.h>
+
+#include "common/memory.h"
+#include "lib/counters/counters.h"
+#include "lib/dataplane/config/zone.h"
 #include "lib/dataplane/packet/dscp.h"
+#include "lib/dataplane/packet/packet.h"
 #include "lib/dataplane/pipeline/econtext.h"
 #include "modules/dscp/dataplane/config.h"
 
@@ -13,6 +19,50 @@ uint8_t dscp_mark_never = DSCP_MARK_NEVER;
 uint8_t dscp_mark_default = DSCP_MARK_DEFAULT;
 uint8_t dscp_mark_always = DSCP_MARK_ALWAYS;
 
+uint8_t dscp_fragment_match = DSCP_FRAGMENT_MATCH;
+uint8_t dscp_fragment_pass = DSCP_FRAGMENT_PASS;
+uint8_t dscp_fragment_drop = DSCP_FRAGMENT_DROP;
+
+uint8_t dscp_default_pass = DSCP_DEFAULT_PASS;
+uint8_t dscp_default_mark = DSCP_DEFAULT_MARK;
+uint8_t dscp_default_drop = DSCP_DEFAULT_DROP;
+
+uint8_t dscp_ext_skip_unknown = DSCP_EXT_SKIP_UNKNOWN;
+uint8_t dscp_ext_drop_anomaly = DSCP_EXT_DROP_ANOMALY;
+
+// Sets the rate threshold of a single worker with a full bucket.
+void
+test_dscp_set_rate_threshold(
+	struct dscp_module_config *config,
+	struct memory_context *memory_context,
+	uint32_t rate,
+	uint32_t burst
+) {
+	struct dscp_rate_bucket *bucket =
+		memory_balloc(memory_context, sizeof(struct dscp_rate_bucket));
+	bucket->tokens = (uint64_t)burst * DSCP_RATE_PACKET_TOKENS;
+	bucket->refilled_at = 0;
+	SET_OFFSET_OF(&config->rate_buckets, bucket);
+	config->rate_bucket_count = 1;
+	config->rate_threshold = rate;
+	config->rate_burst = burst;
+}
+
+// Sets the marking rules of the config, copied to the memory context.
+void
+test_dscp_set_rules(
+	struct dscp_module_config *config,
+	struct memory_context *memory_context,
+	struct dscp_rule *rules,
+	uint64_t count
+) {
+	struct dscp_rule *copy =
+		memory_balloc(memory_context, sizeof(struct dscp_rule) * count);
+	memcpy(copy, rules, sizeof(struct dscp_rule) * count);
+	SET_OFFSET_OF(&config->rules, copy);
+	config->rule_count = count;
+}
+
 void
 dscp_handle_packets(
 	struct dp_worker *dp_worker,
@@ -24,13 +74,54 @@ void
 test_dscp_handle_packets(
 	struct dp_worker *dp_worker,
 	struct cp_module *cp_module,
+	struct counter_storage *counter_storage,
 	struct packet_front *packet_front
 ) {
 	struct module_ectx module_ectx = {};
 	SET_OFFSET_OF(&module_ectx.cp_module, cp_module);
+	if (counter_storage != NULL) {
+		SET_OFFSET_OF(&module_ectx.counter_storage, counter_storage);
+	}
 	dscp_handle_packets(dp_worker, &module_ectx, packet_front);
 }
 
+// Marking counters of the first worker standing in for the counter storage
+// of an agent: the config counts in the first one and its rules in the
+// second one.
+struct test_dscp_marking_counters {
+	struct counter_storage storage;
+	struct counter_value_handle *handles[2];
+	uint64_t values[2][DSCP_MARKING_COUNTER_SIZE];
+};
+
+// Attaches zeroed marking counters to the config and its rules.
+struct test_dscp_marking_counters *
+test_dscp_attach_marking_counters(
+	struct dscp_module_config *config,
+	struct memory_context *memory_context
+) {
+	struct test_dscp_marking_counters *counters = memory_balloc(
+		memory_context, sizeof(struct test_dscp_marking_counters)
+	);
+	memset(counters, 0, sizeof(*counters));
+	for (size_t idx = 0; idx < 2; idx++) {
+		SET_OFFSET_OF(
+			&counters->handles[idx],
+			(struct counter_value_handle *)counters->values[idx]
+		);
+	}
+	SET_OFFSET_OF(
+		&counters->storage.counter_value_handles, &counters->handles[0]
+	);
+
+	config->marking_counter_id = 0;
+	struct dscp_rule *rules = ADDR_OF(&config->rules);
+	for (uint64_t idx = 0; idx < config->rule_count; idx++) {
+		rules[idx].counter_id = 1;
+	}
+	return counters;
+}
+
 */
 import "C"
 import (
@@ -49,6 +140,17 @@ var (
 	DSCPMarkNever   uint8 = uint8(C.dscp_mark_never)
 	DSCPMarkAlways  uint8 = uint8(C.dscp_mark_always)
 	DSCPMarkDefault uint8 = uint8(C.dscp_mark_default)
+
+	DSCPFragmentMatch uint8 = uint8(C.dscp_fragment_match)
+	DSCPFragmentPass  uint8 = uint8(C.dscp_fragment_pass)
+	DSCPFragmentDrop  uint8 = uint8(C.dscp_fragment_drop)
+
+	DSCPDefaultPass uint8 = uint8(C.dscp_default_pass)
+	DSCPDefaultMark uint8 = uint8(C.dscp_default_mark)
+	DSCPDefaultDrop uint8 = uint8(C.dscp_default_drop)
+
+	DSCPExtSkipUnknown uint8 = uint8(C.dscp_ext_skip_unknown)
+	DSCPExtDropAnomaly uint8 = uint8(C.dscp_ext_drop_anomaly)
 )
 
 func buildLPMs(
@@ -59,7 +161,10 @@ func buildLPMs(
 ) {
 	C.lpm_init(lpm4, memCtx)
 	C.lpm_init(lpm6, memCtx)
+	insertPrefixes(prefixes, lpm4, lpm6)
+}
 
+func insertPrefixes(prefixes []netip.Prefix, lpm4 *C.struct_lpm, lpm6 *C.struct_lpm) {
 	for _, prefix := range prefixes {
 		if prefix.Addr().Is4() {
 			ipv4 := prefix.Addr().As4()
@@ -82,15 +187,95 @@ func dscpModuleConfig(prefixes []netip.Prefix, flag, dscp uint8, memCtx testutil
 		cp_module: C.struct_cp_module{},
 	}
 	buildLPMs(prefixes, (*C.struct_memory_context)(memCtx.AsRawPtr()), &m.lpm_v4, &m.lpm_v6)
+	buildLPMs(nil, (*C.struct_memory_context)(memCtx.AsRawPtr()), &m.src_lpm_v4, &m.src_lpm_v6)
 
 	m.dscp = C.struct_dscp_config{
 		flag: C.uint8_t(flag),
 		mark: C.uint8_t(dscp),
 	}
+	// No counter storage is attached to the test module context.
+	m.egress_counter_id = C.uint64_t(^uint64(0))
+	m.ext_anomaly_counter_id = C.uint64_t(^uint64(0))
+	m.default_action_counter_id = C.uint64_t(^uint64(0))
+	m.marking_counter_id = C.uint64_t(^uint64(0))
 
 	return m
 }
 
+func setFragmentPolicy(mc *C.struct_dscp_module_config, policy uint8) {
+	mc.fragment_policy = C.uint8_t(policy)
+}
+
+func setDefaultAction(mc *C.struct_dscp_module_config, action uint8, mark uint8) {
+	mc.default_action = C.uint8_t(action)
+	mc.default_mark = C.uint8_t(mark)
+}
+
+func setExtLimits(mc *C.struct_dscp_module_config, maxHeaders uint8, flags uint8) {
+	mc.ext_limits.max_headers = C.uint8_t(maxHeaders)
+	mc.ext_limits.flags = C.uint8_t(flags)
+}
+
+func setRateThreshold(mc *C.struct_dscp_module_config, rate uint32, burst uint32, memCtx testutils.MemoryContext) {
+	C.test_dscp_set_rate_threshold(mc, (*C.struct_memory_context)(memCtx.AsRawPtr()), C.uint32_t(rate), C.uint32_t(burst))
+}
+
+// Rule is a marking rule of a test module config.
+type Rule struct {
+	// Source and Destination are left invalid to match any address.
+	Source      netip.Prefix
+	Destination netip.Prefix
+	Proto       uint8
+	PortMin     uint16
+	PortMax     uint16
+	Flag        uint8
+	Mark        uint8
+	// TrafficClass is the internal class the rule sets, zero for none.
+	TrafficClass uint8
+}
+
+func setRules(mc *C.struct_dscp_module_config, rules []Rule, memCtx testutils.MemoryContext) {
+	cRules := make([]C.struct_dscp_rule, len(rules))
+	for idx, rule := range rules {
+		out := &cRules[idx]
+		out.proto = C.uint8_t(rule.Proto)
+		out.port_min = C.uint16_t(rule.PortMin)
+		out.port_max = C.uint16_t(rule.PortMax)
+		out.counter_id = C.uint64_t(^uint64(0))
+		out.dscp = C.struct_dscp_config{
+			flag: C.uint8_t(rule.Flag),
+			mark: C.uint8_t(rule.Mark),
+		}
+		out.traffic_class = C.uint8_t(rule.TrafficClass)
+		for _, prefix := range []netip.Prefix{rule.Source, rule.Destination} {
+			if !prefix.IsValid() {
+				continue
+			}
+			out.family = 6
+			if prefix.Addr().Is4() {
+				out.family = 4
+			}
+		}
+		if rule.Source.IsValid() {
+			out.src_prefix_len = C.uint8_t(rule.Source.Bits())
+			for i, b := range rule.Source.Masked().Addr().AsSlice() {
+				out.src_addr[i] = C.uint8_t(b)
+			}
+		}
+		if rule.Destination.IsValid() {
+			out.dst_prefix_len = C.uint8_t(rule.Destination.Bits())
+			for i, b := range rule.Destination.Masked().Addr().AsSlice() {
+				out.dst_addr[i] = C.uint8_t(b)
+			}
+		}
+	}
+	C.test_dscp_set_rules(mc, (*C.struct_memory_context)(memCtx.AsRawPtr()), &cRules[0], C.uint64_t(len(cRules)))
+}
+
+func addSourcePrefixes(mc *C.struct_dscp_module_config, prefixes []netip.Prefix) {
+	insertPrefixes(prefixes, &mc.src_lpm_v4, &mc.src_lpm_v6)
+}
+
 func dscpHandlePackets(mc *C.struct_dscp_module_config, packets ...gopacket.Packet) dataplane.PacketFrontPayload {
 	pinner := runtime.Pinner{}
 	defer pinner.Unpin()
@@ -99,6 +284,85 @@ func dscpHandlePackets(mc *C.struct_dscp_module_config, packets ...gopacket.Pack
 	if err != nil {
 		panic(err)
 	}
-	C.test_dscp_handle_packets(nil, &mc.cp_module, (*C.struct_packet_front)(unsafe.Pointer(pf)))
+	C.test_dscp_handle_packets(nil, &mc.cp_module, nil, (*C.struct_packet_front)(unsafe.Pointer(pf)))
+	return pf.Payload()
+}
+
+// dscpHandlePacketsClasses handles the packets and returns the output ones
+// along with their traffic classes.
+func dscpHandlePacketsClasses(mc *C.struct_dscp_module_config, packets ...gopacket.Packet) (dataplane.PacketFrontPayload, []uint8) {
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+
+	pf, err := dataplane.NewPacketFrontFromPackets(&pinner, packets...)
+	if err != nil {
+		panic(err)
+	}
+	C.test_dscp_handle_packets(nil, &mc.cp_module, nil, (*C.struct_packet_front)(unsafe.Pointer(pf)))
+
+	classes := []uint8{}
+	for packet := pf.OutputList().First(); packet != nil; packet = packet.Next() {
+		classes = append(classes, uint8((*C.struct_packet)(unsafe.Pointer(packet)).traffic_class))
+	}
+	return pf.Payload(), classes
+}
+
+// dscpHandlePacketsAt handles the packets by the first worker at the given
+// worker time in nanoseconds.
+func dscpHandlePacketsAt(mc *C.struct_dscp_module_config, now uint64, packets ...gopacket.Packet) dataplane.PacketFrontPayload {
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+
+	pf, err := dataplane.NewPacketFrontFromPackets(&pinner, packets...)
+	if err != nil {
+		panic(err)
+	}
+	dpWorker := &C.struct_dp_worker{
+		idx:          0,
+		current_time: C.uint64_t(now),
+	}
+	C.test_dscp_handle_packets(dpWorker, &mc.cp_module, nil, (*C.struct_packet_front)(unsafe.Pointer(pf)))
 	return pf.Payload()
 }
+
+// MarkingCounts are the slots of a marking counter, see enum
+// dscp_marking_counter.
+type MarkingCounts struct {
+	Matched       uint64
+	Remarked      uint64
+	Untouched     uint64
+	MatchedBytes  uint64
+	RemarkedBytes uint64
+}
+
+func newMarkingCounts(values [C.DSCP_MARKING_COUNTER_SIZE]C.uint64_t) MarkingCounts {
+	return MarkingCounts{
+		Matched:       uint64(values[C.DSCP_MARKING_MATCHED]),
+		Remarked:      uint64(values[C.DSCP_MARKING_REMARKED]),
+		Untouched:     uint64(values[C.DSCP_MARKING_UNTOUCHED]),
+		MatchedBytes:  uint64(values[C.DSCP_MARKING_MATCHED_BYTES]),
+		RemarkedBytes: uint64(values[C.DSCP_MARKING_REMARKED_BYTES]),
+	}
+}
+
+// dscpHandlePacketsCounted handles the packets by the first worker and
+// returns the output ones along with the marking counts of the config and
+// of its rules. The rules must be set beforehand.
+func dscpHandlePacketsCounted(
+	mc *C.struct_dscp_module_config,
+	memCtx testutils.MemoryContext,
+	packets ...gopacket.Packet,
+) (dataplane.PacketFrontPayload, MarkingCounts, MarkingCounts) {
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+
+	counters := C.test_dscp_attach_marking_counters(mc, (*C.struct_memory_context)(memCtx.AsRawPtr()))
+
+	pf, err := dataplane.NewPacketFrontFromPackets(&pinner, packets...)
+	if err != nil {
+		panic(err)
+	}
+	dpWorker := &C.struct_dp_worker{idx: 0}
+	C.test_dscp_handle_packets(dpWorker, &mc.cp_module, &counters.storage, (*C.struct_packet_front)(unsafe.Pointer(pf)))
+	return pf.Payload(), newMarkingCounts(counters.values[0]), newMarkingCounts(counters.values[1])
+}
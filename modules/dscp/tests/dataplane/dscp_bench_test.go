@@ -0,0 +1,190 @@
+package dscp_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/common/go/testutils"
+	"github.com/yanet-platform/yanet2/common/go/xerror"
+	"github.com/yanet-platform/yanet2/common/go/xpacket"
+)
+
+// benchRuleCounts are the rule set sizes the benchmarks scale through.
+var benchRuleCounts = []int{100, 1000, 10000, 50000}
+
+// syntheticRules generates a reproducible rule set of the given number of
+// prefixes.
+//
+// Half of the prefixes are IPv4 ones of 10.0.0.0/8 and half IPv6 ones of
+// 2001:db8::/32, with lengths spread over the range customer rules use. A
+// quarter of them match the source address. Duplicates are kept, as the
+// control plane does not deduplicate the prefixes of different rule groups
+// either.
+func syntheticRules(count int) dscpRules {
+	rng := rand.New(rand.NewPCG(uint64(count), 0x44534350))
+
+	rules := dscpRules{
+		Flag:           DSCPMarkAlways,
+		Mark:           46,
+		FragmentPolicy: DSCPFragmentMatch,
+	}
+	for idx := range count {
+		var prefix netip.Prefix
+		if idx%2 == 0 {
+			addr := [4]byte{10, byte(rng.Uint32()), byte(rng.Uint32()), byte(rng.Uint32())}
+			prefix = netip.PrefixFrom(netip.AddrFrom4(addr), 16+rng.IntN(13)).Masked()
+		} else {
+			addr := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+			for pos := 4; pos < 8; pos++ {
+				addr[pos] = byte(rng.Uint32())
+			}
+			prefix = netip.PrefixFrom(netip.AddrFrom16(addr), 33+rng.IntN(16)).Masked()
+		}
+
+		if idx%4 == 3 {
+			rules.SourcePrefixes = append(rules.SourcePrefixes, prefix)
+		} else {
+			rules.Prefixes = append(rules.Prefixes, prefix)
+		}
+	}
+
+	return rules
+}
+
+// benchMemorySize returns the memory a module config compiled from the
+// given number of rules fits in.
+//
+// Every generated prefix takes at most two LPM pages of its own.
+func benchMemorySize(count int) datasize.ByteSize {
+	return 16*datasize.MB + datasize.ByteSize(count)*8*datasize.KB
+}
+
+// benchPackets builds UDP packets half of which hit a destination prefix
+// of the rule set, the others missing every rule.
+func benchPackets(b *testing.B, rules dscpRules, count int) []gopacket.Packet {
+	rng := rand.New(rand.NewPCG(uint64(count), 0x504b5453))
+
+	packets := make([]gopacket.Packet, 0, count)
+	for idx := range count {
+		dst := netip.MustParseAddr("198.51.100.1")
+		if idx%2 == 0 {
+			dst = rules.Prefixes[rng.IntN(len(rules.Prefixes))].Addr()
+		}
+		src := netip.MustParseAddr("203.0.113.1")
+		if !dst.Is4() {
+			src = netip.MustParseAddr("2001:db9::1")
+		}
+		if idx%4 == 1 {
+			// An IPv6 miss.
+			src = netip.MustParseAddr("2001:db9::1")
+			dst = netip.MustParseAddr("2001:db9::2")
+		}
+
+		eth := layers.Ethernet{
+			SrcMAC: xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+			DstMAC: xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		}
+		udp := layers.UDP{SrcPort: layers.UDPPort(40000 + idx), DstPort: 50000}
+		var network gopacket.SerializableLayer
+		if dst.Is4() {
+			eth.EthernetType = layers.EthernetTypeIPv4
+			ip4 := &layers.IPv4{
+				Version:  4,
+				TTL:      64,
+				Protocol: layers.IPProtocolUDP,
+				SrcIP:    src.AsSlice(),
+				DstIP:    dst.AsSlice(),
+			}
+			require.NoError(b, udp.SetNetworkLayerForChecksum(ip4))
+			network = ip4
+		} else {
+			eth.EthernetType = layers.EthernetTypeIPv6
+			ip6 := &layers.IPv6{
+				Version:    6,
+				NextHeader: layers.IPProtocolUDP,
+				HopLimit:   64,
+				SrcIP:      src.AsSlice(),
+				DstIP:      dst.AsSlice(),
+			}
+			require.NoError(b, udp.SetNetworkLayerForChecksum(ip6))
+			network = ip6
+		}
+
+		packet, err := xpacket.LayersToPacketChecked(&eth, network, &udp)
+		require.NoError(b, err)
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+// BenchmarkDSCPCompile measures building a module config through the
+// control plane API as the rule set grows, along with the shared memory
+// the compiled config holds.
+func BenchmarkDSCPCompile(b *testing.B) {
+	for _, count := range benchRuleCounts {
+		b.Run(fmt.Sprintf("rules=%d", count), func(b *testing.B) {
+			rules := syntheticRules(count)
+
+			var memory uint64
+			for b.Loop() {
+				b.StopTimer()
+				memCtx := testutils.NewMemoryContext("dscp_bench", benchMemorySize(count))
+				b.StartTimer()
+
+				config, err := compileModuleConfig(rules, memCtx)
+				require.NoError(b, err)
+
+				b.StopTimer()
+				memory = moduleConfigMemory(config)
+				memCtx.Free()
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(count), "ns/rule")
+			b.ReportMetric(float64(memory), "bytes/config")
+			b.ReportMetric(float64(memory)/float64(count), "bytes/rule")
+		})
+	}
+}
+
+// BenchmarkDSCPLookup measures the dataplane cost of classifying a burst
+// of packets, half of them matching, as the rule set grows.
+//
+// The lookup cost of an LPM is bound by the address length rather than the
+// rule count, so a ns/packet growing with the rule set points at cache
+// misses on the pages of a larger trie.
+func BenchmarkDSCPLookup(b *testing.B) {
+	const burst = 32
+
+	for _, count := range benchRuleCounts {
+		b.Run(fmt.Sprintf("rules=%d", count), func(b *testing.B) {
+			memCtx := testutils.NewMemoryContext("dscp_bench", benchMemorySize(count))
+			defer memCtx.Free()
+
+			rules := syntheticRules(count)
+			config, err := compileModuleConfig(rules, memCtx)
+			require.NoError(b, err)
+
+			// The packets are remarked in place, which leaves the
+			// classification of the following rounds unchanged.
+			packets := benchPackets(b, rules, burst)
+			for b.Loop() {
+				result := dscpHandlePackets(config, packets...)
+				if len(result.Output) != burst {
+					b.Fatalf("expected %d output packets, got %d", burst, len(result.Output))
+				}
+			}
+
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/burst, "ns/packet")
+		})
+	}
+}
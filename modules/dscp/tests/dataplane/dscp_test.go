@@ -153,3 +153,573 @@ func TestDSCP(t *testing.T) {
 	}
 
 }
+
+func TestDSCPFragments(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		Id:       1,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IPv4zero,
+		DstIP:    net.ParseIP("1.1.0.0"),
+	}
+	initial := ip4
+	initial.Flags = layers.IPv4MoreFragments
+	nonInitial := ip4
+	nonInitial.FragOffset = 100
+	payload := gopacket.Payload(make([]byte, 16))
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/32")),
+	}
+
+	cases := []struct {
+		name    string
+		ip      *layers.IPv4
+		policy  uint8
+		dropped bool
+		expt    uint8
+	}{
+		{"initial match", &initial, DSCPFragmentMatch, false, 10},
+		{"initial pass", &initial, DSCPFragmentPass, false, 10},
+		{"initial drop", &initial, DSCPFragmentDrop, false, 10},
+		{"non-initial match", &nonInitial, DSCPFragmentMatch, false, 10},
+		{"non-initial pass", &nonInitial, DSCPFragmentPass, false, 0},
+		{"non-initial drop", &nonInitial, DSCPFragmentDrop, true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pkt := xpacket.LayersToPacket(t, &eth, c.ip, &payload)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(prefixes, DSCPMarkAlways, 10, memCtx)
+			setFragmentPolicy(m, c.policy)
+			result := dscpHandlePackets(m, pkt)
+			if c.dropped {
+				require.Empty(t, result.Output)
+				require.Len(t, result.Drop, 1)
+				return
+			}
+			require.Len(t, result.Output, 1)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
+
+// TestDSCPFragmentRules verifies that under the match policy a non-initial
+// fragment, which carries no ports, skips the marking rules matching on
+// ports and is classified by the remaining rules and the prefixes.
+func TestDSCPFragmentRules(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	payload := gopacket.Payload(make([]byte, 16))
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24")),
+	}
+	rules := []Rule{
+		{
+			Proto:   uint8(layers.IPProtocolUDP),
+			PortMin: 5060,
+			PortMax: 5061,
+			Flag:    DSCPMarkAlways,
+			Mark:    46,
+		},
+		{
+			Destination: xerror.Unwrap(netip.ParsePrefix("1.1.0.128/25")),
+			PortMax:     65535,
+			Flag:        DSCPMarkAlways,
+			Mark:        34,
+		},
+	}
+
+	cases := []struct {
+		name    string
+		dst     string
+		initial bool
+		expt    uint8
+	}{
+		{"initial port rule", "1.1.0.1", true, 46},
+		{"non-initial skips port rule", "1.1.0.1", false, 10},
+		{"initial port rule before prefix rule", "1.1.0.129", true, 46},
+		{"non-initial prefix rule", "1.1.0.129", false, 34},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ip4 := layers.IPv4{
+				Version:  4,
+				Id:       1,
+				TTL:      64,
+				Protocol: layers.IPProtocolUDP,
+				SrcIP:    net.ParseIP("10.1.2.3"),
+				DstIP:    net.ParseIP(c.dst),
+			}
+			layerList := []gopacket.SerializableLayer{&eth, &ip4}
+			if c.initial {
+				ip4.Flags = layers.IPv4MoreFragments
+				udp := &layers.UDP{SrcPort: 1024, DstPort: 5060}
+				require.NoError(t, udp.SetNetworkLayerForChecksum(&ip4))
+				layerList = append(layerList, udp)
+			} else {
+				ip4.FragOffset = 100
+			}
+			layerList = append(layerList, &payload)
+			pkt := xpacket.LayersToPacket(t, layerList...)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(prefixes, DSCPMarkAlways, 10, memCtx)
+			setFragmentPolicy(m, DSCPFragmentMatch)
+			setRules(m, rules, memCtx)
+			result := dscpHandlePackets(m, pkt)
+			require.Len(t, result.Output, 1)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
+
+func TestDSCPSourcePrefixes(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	payload := gopacket.Payload(make([]byte, 16))
+
+	destination := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24")),
+	}
+	source := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("10.0.0.0/8")),
+	}
+
+	cases := []struct {
+		name string
+		src  string
+		dst  string
+		expt uint8
+	}{
+		{"destination match", "192.0.2.1", "1.1.0.1", 10},
+		{"source match", "10.1.2.3", "198.51.100.1", 10},
+		{"both match", "10.1.2.3", "1.1.0.1", 10},
+		{"no match", "192.0.2.1", "198.51.100.1", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ip4 := layers.IPv4{
+				Version:  4,
+				TTL:      64,
+				Protocol: layers.IPProtocolUDP,
+				SrcIP:    net.ParseIP(c.src),
+				DstIP:    net.ParseIP(c.dst),
+			}
+			pkt := xpacket.LayersToPacket(t, &eth, &ip4, &payload)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(destination, DSCPMarkAlways, 10, memCtx)
+			addSourcePrefixes(m, source)
+			result := dscpHandlePackets(m, pkt)
+			require.Len(t, result.Output, 1)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
+
+func TestDSCPRules(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	payload := gopacket.Payload(make([]byte, 16))
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24")),
+	}
+	rules := []Rule{
+		{
+			Source:  xerror.Unwrap(netip.ParsePrefix("10.0.0.0/12")),
+			Proto:   uint8(layers.IPProtocolUDP),
+			PortMin: 5060,
+			PortMax: 5061,
+			Flag:    DSCPMarkAlways,
+			Mark:    46,
+		},
+		{
+			Destination:  xerror.Unwrap(netip.ParsePrefix("1.1.0.128/25")),
+			PortMax:      65535,
+			Flag:         DSCPMarkNever,
+			TrafficClass: 3,
+		},
+	}
+
+	cases := []struct {
+		name  string
+		src   string
+		dst   string
+		proto layers.IPProtocol
+		port  uint16
+		expt  uint8
+		class uint8
+	}{
+		{"rule", "10.1.2.3", "198.51.100.1", layers.IPProtocolUDP, 5061, 46, 0},
+		{"rule before prefixes", "10.1.2.3", "1.1.0.1", layers.IPProtocolUDP, 5060, 46, 0},
+		{"port out of range", "10.1.2.3", "1.1.0.1", layers.IPProtocolUDP, 5062, 10, 0},
+		{"other protocol", "10.1.2.3", "1.1.0.1", layers.IPProtocolTCP, 5060, 10, 0},
+		{"source out of prefix", "10.16.0.1", "198.51.100.1", layers.IPProtocolUDP, 5060, 0, 0},
+		{"exempting rule", "192.0.2.1", "1.1.0.129", layers.IPProtocolTCP, 80, 0, 3},
+		{"prefixes", "192.0.2.1", "1.1.0.1", layers.IPProtocolTCP, 80, 10, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ip4 := layers.IPv4{
+				Version:  4,
+				TTL:      64,
+				Protocol: c.proto,
+				SrcIP:    net.ParseIP(c.src),
+				DstIP:    net.ParseIP(c.dst),
+			}
+			var transport gopacket.SerializableLayer
+			if c.proto == layers.IPProtocolUDP {
+				udp := &layers.UDP{SrcPort: 1024, DstPort: layers.UDPPort(c.port)}
+				require.NoError(t, udp.SetNetworkLayerForChecksum(&ip4))
+				transport = udp
+			} else {
+				tcp := &layers.TCP{SrcPort: 1024, DstPort: layers.TCPPort(c.port)}
+				require.NoError(t, tcp.SetNetworkLayerForChecksum(&ip4))
+				transport = tcp
+			}
+			pkt := xpacket.LayersToPacket(t, &eth, &ip4, transport, &payload)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(prefixes, DSCPMarkAlways, 10, memCtx)
+			setRules(m, rules, memCtx)
+			result, classes := dscpHandlePacketsClasses(m, pkt)
+			require.Len(t, result.Output, 1)
+			require.Equal(t, []uint8{c.class}, classes)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
+
+// TestDSCPMarkingCounters verifies that the packets matching a marking rule
+// or a prefix are counted along with their bytes, both in the counter of
+// the config and in the one of the rule.
+func TestDSCPMarkingCounters(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	udpPacket := func(dst string, port uint16, tos uint8, size int) gopacket.Packet {
+		ip4 := layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			TOS:      tos << 2,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    net.ParseIP("192.0.2.1"),
+			DstIP:    net.ParseIP(dst),
+		}
+		udp := &layers.UDP{SrcPort: 1024, DstPort: layers.UDPPort(port)}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(&ip4))
+		payload := gopacket.Payload(make([]byte, size))
+		return xpacket.LayersToPacket(t, &eth, &ip4, udp, &payload)
+	}
+
+	packets := []gopacket.Packet{
+		// Remarked by the rule.
+		udpPacket("198.51.100.1", 5060, 0, 100),
+		// Left with its DSCP value by the rule.
+		udpPacket("198.51.100.1", 5060, 8, 200),
+		// Remarked by the prefixes.
+		udpPacket("1.1.0.1", 80, 0, 300),
+		// Matched by nothing.
+		udpPacket("198.51.100.1", 80, 0, 400),
+	}
+	sizes := make([]uint64, 0, len(packets))
+	for _, pkt := range packets {
+		sizes = append(sizes, uint64(len(pkt.Data())))
+	}
+
+	memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+	defer memCtx.Free()
+
+	m := dscpModuleConfig([]netip.Prefix{xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24"))}, DSCPMarkAlways, 10, memCtx)
+	setRules(m, []Rule{
+		{
+			Proto:   uint8(layers.IPProtocolUDP),
+			PortMin: 5060,
+			PortMax: 5060,
+			Flag:    DSCPMarkDefault,
+			Mark:    46,
+		},
+	}, memCtx)
+	result, config, rule := dscpHandlePacketsCounted(m, memCtx, packets...)
+	require.Len(t, result.Output, len(packets))
+
+	require.Equal(t, MarkingCounts{
+		Matched:       2,
+		Remarked:      1,
+		Untouched:     1,
+		MatchedBytes:  sizes[0] + sizes[1],
+		RemarkedBytes: sizes[0],
+	}, rule)
+	require.Equal(t, MarkingCounts{
+		Matched:       3,
+		Remarked:      2,
+		Untouched:     1,
+		MatchedBytes:  sizes[0] + sizes[1] + sizes[2],
+		RemarkedBytes: sizes[0] + sizes[2],
+	}, config)
+}
+
+func TestDSCPDefaultAction(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	payload := gopacket.Payload(make([]byte, 16))
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24")),
+	}
+
+	cases := []struct {
+		name    string
+		flag    uint8
+		dst     string
+		action  uint8
+		dropped bool
+		expt    uint8
+	}{
+		{"match pass", DSCPMarkAlways, "1.1.0.1", DSCPDefaultPass, false, 10},
+		{"match mark", DSCPMarkAlways, "1.1.0.1", DSCPDefaultMark, false, 10},
+		{"match drop", DSCPMarkAlways, "1.1.0.1", DSCPDefaultDrop, false, 10},
+		{"no match pass", DSCPMarkAlways, "198.51.100.1", DSCPDefaultPass, false, 0},
+		{"no match mark", DSCPMarkAlways, "198.51.100.1", DSCPDefaultMark, false, 8},
+		{"no match drop", DSCPMarkAlways, "198.51.100.1", DSCPDefaultDrop, true, 0},
+		// The default action applies while marking is disabled.
+		{"never no match mark", DSCPMarkNever, "198.51.100.1", DSCPDefaultMark, false, 8},
+		{"never no match drop", DSCPMarkNever, "198.51.100.1", DSCPDefaultDrop, true, 0},
+		{"never match drop", DSCPMarkNever, "1.1.0.1", DSCPDefaultDrop, false, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ip4 := layers.IPv4{
+				Version:  4,
+				TTL:      64,
+				Protocol: layers.IPProtocolUDP,
+				SrcIP:    net.ParseIP("192.0.2.1"),
+				DstIP:    net.ParseIP(c.dst),
+			}
+			pkt := xpacket.LayersToPacket(t, &eth, &ip4, &payload)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(prefixes, c.flag, 10, memCtx)
+			setDefaultAction(m, c.action, 8)
+			result := dscpHandlePackets(m, pkt)
+			if c.dropped {
+				require.Empty(t, result.Output)
+				require.Len(t, result.Drop, 1)
+				return
+			}
+			require.Len(t, result.Output, 1)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
+
+func TestDSCPRateThreshold(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("192.0.2.1"),
+		DstIP:    net.ParseIP("1.1.0.1"),
+	}
+	payload := gopacket.Payload(make([]byte, 16))
+	pkt := xpacket.LayersToPacket(t, &eth, &ip4, &payload)
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("1.1.0.0/24")),
+	}
+
+	memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+	defer memCtx.Free()
+
+	// 1000 packets per second, so a packet is in profile every
+	// millisecond, with bursts of 2 packets.
+	m := dscpModuleConfig(prefixes, DSCPMarkAlways, 10, memCtx)
+	setRateThreshold(m, 1000, 2, memCtx)
+
+	steps := []struct {
+		name string
+		now  uint64
+		expt []uint8
+	}{
+		{"burst", 1_000_000, []uint8{0, 0, 10}},
+		{"refilled one", 2_000_000, []uint8{0, 10}},
+		{"nothing refilled", 2_000_100, []uint8{10}},
+		{"refilled above burst", 10_000_000, []uint8{0, 0, 10}},
+	}
+
+	for _, step := range steps {
+		packets := make([]gopacket.Packet, len(step.expt))
+		for idx := range packets {
+			packets[idx] = pkt
+		}
+
+		result := dscpHandlePacketsAt(m, step.now, packets...)
+		require.Len(t, result.Output, len(step.expt), step.name)
+		for idx, expt := range step.expt {
+			resultPkt := xpacket.ParseEtherPacket(result.Output[idx])
+			expectedPkt := mark(t, pkt, expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff, "%s: packet %d", step.name, idx)
+		}
+	}
+}
+
+// ipv6ExtChain returns the extension headers of the given types followed
+// by a UDP header. Every extension header is 8 bytes long.
+func ipv6ExtChain(types ...layers.IPProtocol) gopacket.Payload {
+	chain := make([]byte, 0, 8*len(types)+8)
+	for idx := range types {
+		next := layers.IPProtocolUDP
+		if idx+1 < len(types) {
+			next = types[idx+1]
+		}
+		// PadN option filling the rest of the header.
+		chain = append(chain, byte(next), 0, 1, 4, 0, 0, 0, 0)
+	}
+	// UDP header from port 1000 to port 2000 without checksum.
+	chain = append(chain, 0x03, 0xe8, 0x07, 0xd0, 0, 8, 0, 0)
+	return gopacket.Payload(chain)
+}
+
+func TestDSCPExtHeaderLimits(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+
+	prefixes := []netip.Prefix{
+		xerror.Unwrap(netip.ParsePrefix("2001:db8::/64")),
+	}
+
+	dstOpts := layers.IPProtocolIPv6Destination
+	hopByHop := layers.IPProtocolIPv6HopByHop
+	mobility := layers.IPProtocol(135)
+
+	cases := []struct {
+		name       string
+		chain      []layers.IPProtocol
+		maxHeaders uint8
+		flags      uint8
+		dropped    bool
+		expt       uint8
+	}{
+		{"disabled", []layers.IPProtocol{dstOpts, dstOpts, dstOpts}, 0, DSCPExtDropAnomaly, false, 10},
+		{"within limit", []layers.IPProtocol{hopByHop, dstOpts}, 2, 0, false, 10},
+		{"over limit pass", []layers.IPProtocol{dstOpts, dstOpts, dstOpts}, 2, 0, false, 0},
+		{"over limit drop", []layers.IPProtocol{dstOpts, dstOpts, dstOpts}, 2, DSCPExtDropAnomaly, true, 0},
+		{"unknown pass", []layers.IPProtocol{dstOpts, mobility}, 4, 0, false, 0},
+		{"unknown skip", []layers.IPProtocol{dstOpts, mobility}, 4, DSCPExtSkipUnknown, false, 10},
+		{"misplaced hop-by-hop", []layers.IPProtocol{dstOpts, hopByHop}, 4, DSCPExtDropAnomaly, true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ip6 := layers.IPv6{
+				Version:    6,
+				HopLimit:   64,
+				NextHeader: c.chain[0],
+				SrcIP:      net.ParseIP("2001:db8:1::1"),
+				DstIP:      net.ParseIP("2001:db8::1"),
+			}
+			chain := ipv6ExtChain(c.chain...)
+			pkt := xpacket.LayersToPacket(t, &eth, &ip6, &chain)
+
+			memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+			defer memCtx.Free()
+
+			m := dscpModuleConfig(prefixes, DSCPMarkAlways, 10, memCtx)
+			setExtLimits(m, c.maxHeaders, c.flags)
+			result := dscpHandlePackets(m, pkt)
+			if c.dropped {
+				require.Empty(t, result.Output)
+				require.Len(t, result.Drop, 1)
+				return
+			}
+			require.Len(t, result.Output, 1)
+
+			resultPkt := xpacket.ParseEtherPacket(result.Output[0])
+			expectedPkt := mark(t, pkt, c.expt)
+			diff := cmp.Diff(expectedPkt.Layers(), resultPkt.Layers(),
+				cmpopts.IgnoreUnexported(layers.IPv6{}, layers.ICMPv6{}),
+			)
+			require.Empty(t, diff)
+		})
+	}
+}
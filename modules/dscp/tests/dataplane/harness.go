@@ -0,0 +1,156 @@
+package dscp_test
+
+//#cgo CFLAGS: -I../../../.. -I../../../../lib -I../../../../common
+//#cgo LDFLAGS: -L../../../../build/modules/dscp/api -ldscp_cp
+//#cgo LDFLAGS: -L../../../../build/lib/errors -lerrors
+/*
+#include <stdlib.h>
+#include <string.h>
+
+#include "lib/controlplane/config/cp_module.h"
+#include "lib/counters/counters.h"
+#include "modules/dscp/api/controlplane.h"
+#include "modules/dscp/dataplane/config.h"
+
+// The harness compiles configs without an agent, so the agent-bound parts
+// of the control plane API are stubbed out.
+
+int
+cp_module_init(
+	struct cp_module *cp_module,
+	struct agent *agent,
+	const char *module_type,
+	const char *module_name,
+	yanet_error **err
+) {
+	(void)cp_module;
+	(void)agent;
+	(void)module_type;
+	(void)module_name;
+	(void)err;
+	return -1;
+}
+
+void
+cp_module_fini(struct cp_module *cp_module) {
+	(void)cp_module;
+}
+
+uint64_t
+counter_registry_register(
+	struct counter_registry *registry,
+	const char *name,
+	uint64_t size,
+	yanet_error **err
+) {
+	(void)registry;
+	(void)name;
+	(void)size;
+	(void)err;
+	return (uint64_t)-1;
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"net/netip"
+	"unsafe"
+
+	"github.com/yanet-platform/yanet2/common/go/testutils"
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+)
+
+// dscpRules is a DSCP module configuration as the control plane applies
+// it.
+type dscpRules struct {
+	Prefixes       []netip.Prefix
+	SourcePrefixes []netip.Prefix
+	Flag           uint8
+	Mark           uint8
+	FragmentPolicy uint8
+	DefaultAction  uint8
+	DefaultMark    uint8
+	ExtMaxHeaders  uint8
+	ExtFlags       uint8
+}
+
+// compileModuleConfig builds the module configuration in the shared memory
+// of the memory context through the control plane API, so packets are
+// processed against the same config layout the dataplane sees in
+// production.
+func compileModuleConfig(rules dscpRules, memCtx testutils.MemoryContext) (*C.struct_dscp_module_config, error) {
+	memoryContext := (*C.struct_memory_context)(memCtx.AsRawPtr())
+	config := (*C.struct_dscp_module_config)(C.memory_balloc(memoryContext, C.sizeof_struct_dscp_module_config))
+	if config == nil {
+		return nil, fmt.Errorf("failed to allocate module config")
+	}
+	C.memset(unsafe.Pointer(config), 0, C.sizeof_struct_dscp_module_config)
+
+	name := C.CString("dscp_test")
+	defer C.free(unsafe.Pointer(name))
+	C.memory_context_init_from(&config.cp_module.memory_context, memoryContext, name)
+
+	if rc := C.dscp_module_config_data_init(config, &config.cp_module.memory_context); rc != 0 {
+		return nil, fmt.Errorf("failed to init module config data: %d", rc)
+	}
+
+	module := &config.cp_module
+	for _, prefix := range rules.Prefixes {
+		if err := addPrefix(module, prefix, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, prefix := range rules.SourcePrefixes {
+		if err := addPrefix(module, prefix, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if rc := C.dscp_module_config_set_dscp_marking(module, C.uint8_t(rules.Flag), C.uint8_t(rules.Mark)); rc != 0 {
+		return nil, fmt.Errorf("failed to set DSCP marking: %d", rc)
+	}
+	if rc := C.dscp_module_config_set_fragment_policy(module, C.uint8_t(rules.FragmentPolicy)); rc != 0 {
+		return nil, fmt.Errorf("failed to set fragment policy: %d", rc)
+	}
+	if rc := C.dscp_module_config_set_default_action(module, C.uint8_t(rules.DefaultAction), C.uint8_t(rules.DefaultMark)); rc != 0 {
+		return nil, fmt.Errorf("failed to set default action: %d", rc)
+	}
+	if rc := C.dscp_module_config_set_ext_limits(module, C.uint8_t(rules.ExtMaxHeaders), C.uint8_t(rules.ExtFlags)); rc != 0 {
+		return nil, fmt.Errorf("failed to set extension header limits: %d", rc)
+	}
+
+	return config, nil
+}
+
+func addPrefix(module *C.struct_cp_module, prefix netip.Prefix, source bool) error {
+	var rc C.int
+	if prefix.Addr().Is4() {
+		from := prefix.Addr().As4()
+		to := xnetip.LastAddr(prefix).As4()
+		if source {
+			rc = C.dscp_module_config_add_source_prefix_v4(module, (*C.uint8_t)(&from[0]), (*C.uint8_t)(&to[0]))
+		} else {
+			rc = C.dscp_module_config_add_prefix_v4(module, (*C.uint8_t)(&from[0]), (*C.uint8_t)(&to[0]))
+		}
+	} else {
+		from := prefix.Addr().As16()
+		to := xnetip.LastAddr(prefix).As16()
+		if source {
+			rc = C.dscp_module_config_add_source_prefix_v6(module, (*C.uint8_t)(&from[0]), (*C.uint8_t)(&to[0]))
+		} else {
+			rc = C.dscp_module_config_add_prefix_v6(module, (*C.uint8_t)(&from[0]), (*C.uint8_t)(&to[0]))
+		}
+	}
+	if rc != 0 {
+		return fmt.Errorf("failed to add prefix %s: %d", prefix, rc)
+	}
+	return nil
+}
+
+// moduleConfigMemory returns the shared memory the compiled module config
+// holds.
+func moduleConfigMemory(config *C.struct_dscp_module_config) uint64 {
+	memoryContext := &config.cp_module.memory_context
+	return uint64(memoryContext.balloc_size - memoryContext.bfree_size)
+}
@@ -0,0 +1,235 @@
+package dscp_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/common/go/testutils"
+	"github.com/yanet-platform/yanet2/common/go/xerror"
+	"github.com/yanet-platform/yanet2/common/go/xpacket"
+)
+
+// markingPacket is a sample packet along with the offset of its IP header
+// in the frame.
+type markingPacket struct {
+	name   string
+	frame  []byte
+	offset int
+	is4    bool
+	dscp   uint8
+	ecn    uint8
+	match  bool
+}
+
+// markingFrame builds a UDP frame with the given DSCP and ECN bits,
+// optionally tagged with a VLAN.
+func markingFrame(t *testing.T, src string, dst string, vlan bool, dscp uint8, ecn uint8) ([]byte, int) {
+	t.Helper()
+
+	srcIP := net.ParseIP(src)
+	dstIP := net.ParseIP(dst)
+	is4 := srcIP.To4() != nil
+
+	eth := layers.Ethernet{
+		SrcMAC: xerror.Unwrap(net.ParseMAC("00:00:00:00:00:01")),
+		DstMAC: xerror.Unwrap(net.ParseMAC("00:11:22:33:44:55")),
+	}
+	etherType := layers.EthernetTypeIPv6
+	if is4 {
+		etherType = layers.EthernetTypeIPv4
+	}
+
+	lyrs := []gopacket.SerializableLayer{&eth}
+	offset := 14
+	if vlan {
+		eth.EthernetType = layers.EthernetTypeDot1Q
+		lyrs = append(lyrs, &layers.Dot1Q{VLANIdentifier: 100, Type: etherType})
+		offset += 4
+	} else {
+		eth.EthernetType = etherType
+	}
+
+	udp := layers.UDP{SrcPort: 40000, DstPort: 50000}
+	tc := dscp<<2 | ecn
+	if is4 {
+		ip4 := &layers.IPv4{
+			Version:  4,
+			TOS:      tc,
+			Id:       1,
+			TTL:      64,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    srcIP,
+			DstIP:    dstIP,
+		}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(ip4))
+		lyrs = append(lyrs, ip4)
+	} else {
+		ip6 := &layers.IPv6{
+			Version:      6,
+			TrafficClass: tc,
+			// Flow label bits adjacent to the traffic class must survive
+			// the remark.
+			FlowLabel:  0xabcde,
+			NextHeader: layers.IPProtocolUDP,
+			HopLimit:   64,
+			SrcIP:      srcIP,
+			DstIP:      dstIP,
+		}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(ip6))
+		lyrs = append(lyrs, ip6)
+	}
+	payload := gopacket.Payload(make([]byte, 32))
+	lyrs = append(lyrs, &udp, &payload)
+
+	return xpacket.LayersToPacket(t, lyrs...).Data(), offset
+}
+
+// frameTC returns the TOS byte of an IPv4 header or the traffic class of
+// an IPv6 header.
+func frameTC(frame []byte, offset int, is4 bool) uint8 {
+	if is4 {
+		return frame[offset+1]
+	}
+	return frame[offset]<<4 | frame[offset+1]>>4
+}
+
+// remarkFrame returns a copy of the frame with the traffic class set to
+// tc, updating the IPv4 header checksum from scratch.
+func remarkFrame(frame []byte, offset int, is4 bool, tc uint8) []byte {
+	frame = append([]byte(nil), frame...)
+	if !is4 {
+		frame[offset] = 6<<4 | tc>>4
+		frame[offset+1] = tc<<4 | frame[offset+1]&0x0f
+		return frame
+	}
+
+	frame[offset+1] = tc
+	header := frame[offset : offset+20]
+	binary.BigEndian.PutUint16(header[10:], 0)
+	sum := uint32(0)
+	for idx := 0; idx < len(header); idx += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[idx:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	binary.BigEndian.PutUint16(header[10:], ^uint16(sum))
+	return frame
+}
+
+// expectedDSCP is the reference model of the marking: matched packets get
+// the configured mark always or, by default, only when they are unmarked.
+func expectedDSCP(flag uint8, mark uint8, dscp uint8, match bool) uint8 {
+	if !match {
+		return dscp
+	}
+	switch flag {
+	case DSCPMarkAlways:
+		return mark
+	case DSCPMarkDefault:
+		if dscp == 0 {
+			return mark
+		}
+	}
+	return dscp
+}
+
+// TestDSCPMarkingBits runs every combination of marking rules against
+// IPv4 and IPv6 packets with every ECN codepoint through a config compiled
+// by the control plane API, and checks the resulting frames byte by byte.
+func TestDSCPMarkingBits(t *testing.T) {
+	rules := dscpRules{
+		Prefixes: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/24"),
+			netip.MustParsePrefix("2001:db8::/64"),
+		},
+		SourcePrefixes: []netip.Prefix{
+			netip.MustParsePrefix("192.0.2.0/24"),
+			netip.MustParsePrefix("2001:db8:1::/64"),
+		},
+		FragmentPolicy: DSCPFragmentMatch,
+	}
+
+	addrs := []struct {
+		name  string
+		src   string
+		dst   string
+		match bool
+	}{
+		{"v4 dst", "198.51.100.1", "10.0.0.1", true},
+		{"v4 src", "192.0.2.1", "198.51.100.1", true},
+		{"v4 none", "198.51.100.1", "198.51.100.2", false},
+		{"v6 dst", "2001:db8:2::1", "2001:db8::1", true},
+		{"v6 src", "2001:db8:1::1", "2001:db8:2::1", true},
+		{"v6 none", "2001:db8:2::1", "2001:db8:3::1", false},
+	}
+
+	packets := []markingPacket{}
+	for _, addr := range addrs {
+		for _, vlan := range []bool{false, true} {
+			for _, dscp := range []uint8{0, 1, 26, 63} {
+				for ecn := range uint8(4) {
+					frame, offset := markingFrame(t, addr.src, addr.dst, vlan, dscp, ecn)
+					packets = append(packets, markingPacket{
+						name:   fmt.Sprintf("%s vlan=%t dscp=%d ecn=%d", addr.name, vlan, dscp, ecn),
+						frame:  frame,
+						offset: offset,
+						is4:    netip.MustParseAddr(addr.src).Is4(),
+						dscp:   dscp,
+						ecn:    ecn,
+						match:  addr.match,
+					})
+				}
+			}
+		}
+	}
+
+	pkts := make([]gopacket.Packet, 0, len(packets))
+	for _, p := range packets {
+		pkts = append(pkts, xpacket.ParseEtherPacket(p.frame))
+	}
+
+	flags := []struct {
+		name string
+		flag uint8
+	}{
+		{"never", DSCPMarkNever},
+		{"default", DSCPMarkDefault},
+		{"always", DSCPMarkAlways},
+	}
+
+	for _, flag := range flags {
+		for _, mark := range []uint8{0, 10, 46, 63} {
+			t.Run(fmt.Sprintf("%s mark=%d", flag.name, mark), func(t *testing.T) {
+				memCtx := testutils.NewMemoryContext("dscp_test", datasize.MB)
+				defer memCtx.Free()
+
+				rules := rules
+				rules.Flag = flag.flag
+				rules.Mark = mark
+				m, err := compileModuleConfig(rules, memCtx)
+				require.NoError(t, err)
+
+				result := dscpHandlePackets(m, pkts...)
+				require.Empty(t, result.Drop)
+				require.Len(t, result.Output, len(packets))
+
+				for idx, p := range packets {
+					output := result.Output[idx]
+					tc := expectedDSCP(flag.flag, mark, p.dscp, p.match)<<2 | p.ecn
+
+					require.Equal(t, tc, frameTC(output, p.offset, p.is4), p.name)
+					require.Equal(t, remarkFrame(p.frame, p.offset, p.is4, tc), output, p.name)
+				}
+			})
+		}
+	}
+}
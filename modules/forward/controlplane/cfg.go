@@ -14,6 +14,10 @@ type Config struct {
 	// MemoryRequirements is the amount of memory that is required for a single
 	// transaction.
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
 
 	Endpoint        xcfg.NonEmptyString `yaml:"endpoint"`
 	GatewayEndpoint xcfg.NonEmptyString `yaml:"gateway_endpoint"`
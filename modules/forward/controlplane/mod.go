@@ -35,7 +35,12 @@ func NewForwardModule(cfg *Config, log *zap.Logger) (*ForwardModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach(agentName, cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		agentName,
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		cpffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -67,6 +72,12 @@ func (m *ForwardModule) RegisterService(server *grpc.Server) {
 	forwardpb.RegisterForwardServiceServer(server, m.forwardService)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *ForwardModule) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the module.
 func (m *ForwardModule) Close() error {
 	if err := m.agent.Close(); err != nil {
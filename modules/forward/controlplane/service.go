@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	filterpb "github.com/yanet-platform/yanet2/common/filterpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/forward/bindings/go/cforward"
 	forwardpb "github.com/yanet-platform/yanet2/modules/forward/controlplane/forwardpb/v1"
 )
@@ -150,7 +151,7 @@ func (m *ForwardService) UpdateConfig(ctx context.Context, req *forwardpb.Update
 
 	module, err := m.backend.UpdateModule(name, rules)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update module config: %w", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	if oldModule, ok := m.configs[name]; ok {
@@ -15,6 +15,10 @@ type Config struct {
 
 	// MemoryRequirements specifies memory requirements for the module
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
 
 	// Endpoint is the gRPC endpoint address
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
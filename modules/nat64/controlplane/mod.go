@@ -33,7 +33,12 @@ func NewNAT64Module(cfg *Config, log *zap.Logger) (*NAT64Module, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach("nat64", cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		"nat64",
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -65,6 +70,12 @@ func (m *NAT64Module) RegisterService(server *grpc.Server) {
 	nat64pb.RegisterNAT64ServiceServer(server, m.nat64Service)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *NAT64Module) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the module and releases all resources
 func (m *NAT64Module) Close() error {
 	if err := m.agent.Close(); err != nil {
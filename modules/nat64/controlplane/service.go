@@ -14,6 +14,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	nat64pb "github.com/yanet-platform/yanet2/modules/nat64/controlplane/nat64pb/v1"
 )
 
@@ -192,7 +193,7 @@ func (m *NAT64Service) AddPrefix(ctx context.Context, req *nat64pb.AddPrefixRequ
 	inst.config.Prefixes = append(inst.config.Prefixes, slices.Clone(req.Prefix))
 
 	if err := m.updateModuleConfig(name, inst); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.AddPrefixResponse{}, nil
@@ -232,7 +233,7 @@ func (m *NAT64Service) RemovePrefix(ctx context.Context, req *nat64pb.RemovePref
 	next.config.Mappings = adjustMappingsAfterPrefixRemove(next.config.Mappings, uint32(removeIdx))
 
 	if err := m.updateModuleConfig(name, next); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.RemovePrefixResponse{}, nil
@@ -278,7 +279,7 @@ func (m *NAT64Service) AddMapping(ctx context.Context, req *nat64pb.AddMappingRe
 	})
 
 	if err := m.updateModuleConfig(name, inst); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.AddMappingResponse{}, nil
@@ -315,7 +316,7 @@ func (m *NAT64Service) RemoveMapping(ctx context.Context, req *nat64pb.RemoveMap
 	}
 
 	if err := m.updateModuleConfig(name, next); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.RemoveMappingResponse{}, nil
@@ -347,7 +348,7 @@ func (m *NAT64Service) SetMTU(ctx context.Context, req *nat64pb.SetMTURequest) (
 	}
 
 	if err := m.updateModuleConfig(name, inst); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.SetMTUResponse{}, nil
@@ -367,7 +368,7 @@ func (m *NAT64Service) SetDropUnknown(ctx context.Context, req *nat64pb.SetDropU
 	inst.config.DropUnknownMapping = req.DropUnknownMapping
 
 	if err := m.updateModuleConfig(name, inst); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update module config: %v", err)
+		return nil, ffi.UpdateStatus(err, "failed to update module config")
 	}
 
 	return &nat64pb.SetDropUnknownResponse{}, nil
@@ -41,7 +41,12 @@ func NewPdumpModule(cfg *Config, log *zap.Logger) (*PdumpModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach("pdump", cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		"pdump",
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		ffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -82,6 +87,12 @@ func (m *PdumpModule) Run(ctx context.Context) error {
 	return nil
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *PdumpModule) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the module.
 func (m *PdumpModule) Close() error {
 	if err := m.agent.Close(); err != nil {
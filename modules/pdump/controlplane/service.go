@@ -318,7 +318,7 @@ func (m *PdumpService) updateModuleConfig(
 
 	ffiConfig, err := NewModuleConfig(m.agent, name)
 	if err != nil {
-		return fmt.Errorf("failed to create %q module config: %w", name, err)
+		return ffi.UpdateStatus(err, "failed to create %q module config", name)
 	}
 
 	if modConfig != nil {
@@ -330,7 +330,7 @@ func (m *PdumpService) updateModuleConfig(
 
 	if err := m.agent.UpdateModules([]ffi.ModuleConfig{ffiConfig.AsFFIModule()}); err != nil {
 		ffiConfig.Free()
-		return fmt.Errorf("failed to update module %s: %w", name, err)
+		return ffi.UpdateStatus(err, "failed to update module %s", name)
 	}
 
 	// Free old FFI module after successful update
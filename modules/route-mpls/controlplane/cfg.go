@@ -15,6 +15,10 @@ type Config struct {
 	// MemoryRequirements is the amount of shared memory required for a single
 	// transaction.
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
 	// Endpoint is the gRPC listen address for this module.
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
 }
@@ -63,7 +63,12 @@ func NewRouteMPLSModule(cfg *Config, options ...Option) (*RouteMPLSModule, error
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach(agentName, cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		agentName,
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		cpffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
@@ -101,6 +106,12 @@ func (m *RouteMPLSModule) RegisterService(server *grpc.Server) {
 	routemplspb.RegisterRouteMPLSServiceServer(server, m.service)
 }
 
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *RouteMPLSModule) Health() error {
+	return m.agent.Health()
+}
+
 // Close closes the module.
 func (m *RouteMPLSModule) Close() error {
 	if err := m.agent.Close(); err != nil {
@@ -1,5 +1,7 @@
 #include "controlplane.h"
 
+#include <stdio.h>
+
 #include "config.h"
 
 #include "common/container_of.h"
@@ -82,6 +84,14 @@ route_module_config_data_init(
 	config->route_index_count = 0;
 	config->route_indexes = NULL;
 
+	config->urpf_count = 0;
+	config->urpf = NULL;
+
+	config->neigh_proxy_count = 0;
+	config->neigh_proxy = NULL;
+
+	config->hash_flags = 0;
+
 	return 0;
 }
 
@@ -111,6 +121,22 @@ route_module_config_data_fini(struct route_module_config *config) {
 		config->route_index_count
 	);
 
+	struct route_urpf *urpf = ADDR_OF(&config->urpf);
+	mem_array_free_exp(
+		&config->cp_module.memory_context,
+		urpf,
+		sizeof(*urpf),
+		config->urpf_count
+	);
+
+	struct route_neigh_proxy *neigh_proxy = ADDR_OF(&config->neigh_proxy);
+	mem_array_free_exp(
+		&config->cp_module.memory_context,
+		neigh_proxy,
+		sizeof(*neigh_proxy),
+		config->neigh_proxy_count
+	);
+
 	lpm_free(&config->lpm_v6);
 	lpm_free(&config->lpm_v4);
 }
@@ -224,6 +250,154 @@ route_module_config_add_route_list(
 	return config->route_list_count - 1;
 }
 
+int
+route_module_config_set_hash(
+	struct cp_module *cp_module, uint8_t flags, yanet_error **err
+) {
+	struct route_module_config *config =
+		container_of(cp_module, struct route_module_config, cp_module);
+
+	const uint8_t known = ROUTE_HASH_CUSTOM | ROUTE_HASH_PORTS |
+			      ROUTE_HASH_FLOW_LABEL | ROUTE_HASH_SYMMETRIC;
+	if (flags & ~known) {
+		yanet_error_add(err, "invalid multipath hash flags %#x", flags);
+		return -1;
+	}
+
+	config->hash_flags = flags;
+	return 0;
+}
+
+int
+route_module_config_set_urpf(
+	struct cp_module *cp_module,
+	const char *device_name,
+	uint8_t mode,
+	yanet_error **err
+) {
+	struct route_module_config *config =
+		container_of(cp_module, struct route_module_config, cp_module);
+
+	if (mode > ROUTE_URPF_MODE_STRICT) {
+		yanet_error_add(err, "invalid uRPF mode %u", mode);
+		return -1;
+	}
+
+	uint64_t device_index;
+	if (cp_module_link_device(cp_module, device_name, &device_index, err)) {
+		return -1;
+	}
+
+	struct route_urpf *urpf = ADDR_OF(&config->urpf);
+	while (config->urpf_count <= device_index) {
+		if (mem_array_expand_exp(
+			    &config->cp_module.memory_context,
+			    (void **)&urpf,
+			    sizeof(*urpf),
+			    &config->urpf_count
+		    )) {
+			yanet_error_add(err, "failed to expand uRPF settings");
+			return -1;
+		}
+		urpf[config->urpf_count - 1] = (struct route_urpf){
+			.mode = ROUTE_URPF_MODE_NONE,
+			.drop_counter_id = (uint64_t)-1,
+		};
+		SET_OFFSET_OF(&config->urpf, urpf);
+	}
+
+	char counter_name[COUNTER_NAME_LEN];
+	snprintf(
+		counter_name, sizeof(counter_name), "urpf_drop %s", device_name
+	);
+	uint64_t counter_id = counter_registry_register(
+		&cp_module->counter_registry, counter_name, 1, err
+	);
+	if (counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err, "failed to register counter '%s'", counter_name
+		);
+		return -1;
+	}
+
+	urpf[device_index] = (struct route_urpf){
+		.mode = mode,
+		.drop_counter_id = counter_id,
+	};
+
+	return 0;
+}
+
+int
+route_module_config_set_neigh_proxy(
+	struct cp_module *cp_module,
+	const char *device_name,
+	uint8_t flags,
+	const struct ether_addr *mac,
+	yanet_error **err
+) {
+	struct route_module_config *config =
+		container_of(cp_module, struct route_module_config, cp_module);
+
+	if (flags & ~(ROUTE_NEIGH_PROXY_ARP | ROUTE_NEIGH_PROXY_NDP)) {
+		yanet_error_add(
+			err, "invalid neighbour proxy flags %#x", flags
+		);
+		return -1;
+	}
+
+	uint64_t device_index;
+	if (cp_module_link_device(cp_module, device_name, &device_index, err)) {
+		return -1;
+	}
+
+	struct route_neigh_proxy *neigh_proxy = ADDR_OF(&config->neigh_proxy);
+	while (config->neigh_proxy_count <= device_index) {
+		if (mem_array_expand_exp(
+			    &config->cp_module.memory_context,
+			    (void **)&neigh_proxy,
+			    sizeof(*neigh_proxy),
+			    &config->neigh_proxy_count
+		    )) {
+			yanet_error_add(
+				err, "failed to expand neighbour proxy settings"
+			);
+			return -1;
+		}
+		neigh_proxy[config->neigh_proxy_count - 1] =
+			(struct route_neigh_proxy){
+				.flags = 0,
+				.reply_counter_id = (uint64_t)-1,
+			};
+		SET_OFFSET_OF(&config->neigh_proxy, neigh_proxy);
+	}
+
+	char counter_name[COUNTER_NAME_LEN];
+	snprintf(
+		counter_name,
+		sizeof(counter_name),
+		"proxy_reply %s",
+		device_name
+	);
+	uint64_t counter_id = counter_registry_register(
+		&cp_module->counter_registry, counter_name, 1, err
+	);
+	if (counter_id == (uint64_t)-1) {
+		yanet_error_add(
+			err, "failed to register counter '%s'", counter_name
+		);
+		return -1;
+	}
+
+	neigh_proxy[device_index] = (struct route_neigh_proxy){
+		.flags = flags,
+		.mac = *mac,
+		.reply_counter_id = counter_id,
+	};
+
+	return 0;
+}
+
 int
 route_module_config_add_prefix_v4(
 	struct cp_module *cp_module,
@@ -50,6 +50,41 @@ route_module_config_add_route_list(
 	struct cp_module *cp_module, size_t count, const uint32_t *indexes
 );
 
+// Sets the inputs of the hash selecting the multipath nexthop of a flow.
+//
+// The flags are a combination of enum route_hash_flag; zero keeps the
+// packet hash computed on parsing.
+int
+route_module_config_set_hash(
+	struct cp_module *cp_module, uint8_t flags, yanet_error **err
+);
+
+// Sets the uRPF mode applied to packets received on the device.
+//
+// The mode is one of enum route_urpf_mode. A per-device drop counter named
+// "urpf_drop <device>" is registered on the module.
+int
+route_module_config_set_urpf(
+	struct cp_module *cp_module,
+	const char *device_name,
+	uint8_t mode,
+	yanet_error **err
+);
+
+// Sets the neighbour proxy applied to packets received on the device.
+//
+// The flags are a combination of enum route_neigh_proxy_flag; zero disables
+// proxying. Replies carry the given MAC address and are accounted in the
+// per-device counter named "proxy_reply <device>".
+int
+route_module_config_set_neigh_proxy(
+	struct cp_module *cp_module,
+	const char *device_name,
+	uint8_t flags,
+	const struct ether_addr *mac,
+	yanet_error **err
+);
+
 int
 route_module_config_add_prefix_v4(
 	struct cp_module *cp_module,
@@ -46,6 +46,11 @@ func (m *ModuleConfig) AsFFIModule() ffi.ModuleConfig {
 	return m.ptr
 }
 
+// MemoryUsage returns the shared memory held by the module config.
+func (m *ModuleConfig) MemoryUsage() uint64 {
+	return m.ptr.MemoryUsage()
+}
+
 // Free releases the underlying C memory.
 //
 // Safe to call multiple times: subsequent calls are no-ops.
@@ -76,6 +81,60 @@ func (m *ModuleConfig) addRoute(dstMAC [6]byte, srcMAC [6]byte, device string) (
 	return int(idx), nil
 }
 
+// setURPF maps 1:1 to route_module_config_set_urpf.
+func (m *ModuleConfig) setURPF(device string, mode URPFMode) error {
+	cName := C.CString(device)
+	defer C.free(unsafe.Pointer(cName))
+
+	var cErr *C.yanet_error
+	rc := C.route_module_config_set_urpf(
+		m.asRawPtr(),
+		cName,
+		C.uint8_t(mode),
+		&cErr,
+	)
+	if rc != 0 {
+		return fmt.Errorf("failed to set uRPF: %w", cerrors.FromC(unsafe.Pointer(cErr)))
+	}
+
+	return nil
+}
+
+// setHash maps 1:1 to route_module_config_set_hash.
+func (m *ModuleConfig) setHash(flags HashFlags) error {
+	var cErr *C.yanet_error
+	rc := C.route_module_config_set_hash(
+		m.asRawPtr(),
+		C.uint8_t(flags),
+		&cErr,
+	)
+	if rc != 0 {
+		return fmt.Errorf("failed to set multipath hash: %w", cerrors.FromC(unsafe.Pointer(cErr)))
+	}
+
+	return nil
+}
+
+// setNeighProxy maps 1:1 to route_module_config_set_neigh_proxy.
+func (m *ModuleConfig) setNeighProxy(device string, flags NeighProxyFlags, mac [6]byte) error {
+	cName := C.CString(device)
+	defer C.free(unsafe.Pointer(cName))
+
+	var cErr *C.yanet_error
+	rc := C.route_module_config_set_neigh_proxy(
+		m.asRawPtr(),
+		cName,
+		C.uint8_t(flags),
+		(*C.struct_ether_addr)(unsafe.Pointer(&mac)),
+		&cErr,
+	)
+	if rc != 0 {
+		return fmt.Errorf("failed to set neighbour proxy: %w", cerrors.FromC(unsafe.Pointer(cErr)))
+	}
+
+	return nil
+}
+
 // addRouteList maps 1:1 to route_module_config_add_route_list.
 func (m *ModuleConfig) addRouteList(indices []uint32) (int, error) {
 	cIndices := make([]C.uint32_t, len(indices))
@@ -83,10 +142,15 @@ func (m *ModuleConfig) addRouteList(indices []uint32) (int, error) {
 		cIndices[i] = C.uint32_t(v)
 	}
 
+	var cIndicesPtr *C.uint32_t
+	if len(cIndices) > 0 {
+		cIndicesPtr = &cIndices[0]
+	}
+
 	idx, err := C.route_module_config_add_route_list(
 		m.asRawPtr(),
 		C.size_t(len(indices)),
-		&cIndices[0],
+		cIndicesPtr,
 	)
 	if err != nil {
 		return -1, fmt.Errorf("route_module_config_add_route_list: %w", err)
@@ -1,9 +1,11 @@
 package croute
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"net/netip"
+	"slices"
 
 	"github.com/yanet-platform/yanet2/common/go/xnetip"
 )
@@ -13,11 +15,55 @@ const (
 	AddressFamilyIPv6 = 6
 )
 
+// URPFMode is the unicast reverse path forwarding check applied to packets
+// received on a device.
+type URPFMode uint8
+
+const (
+	// URPFModeNone disables the check.
+	URPFModeNone URPFMode = 0
+	// URPFModeLoose requires the source address to have a usable route.
+	URPFModeLoose URPFMode = 1
+	// URPFModeStrict additionally requires the route to egress through
+	// the receiving device.
+	URPFModeStrict URPFMode = 2
+)
+
+// NeighProxyFlags selects the neighbour discovery protocols answered by the
+// router on behalf of routed prefixes.
+type NeighProxyFlags uint8
+
+const (
+	// NeighProxyARP answers IPv4 ARP requests.
+	NeighProxyARP NeighProxyFlags = 1 << 0
+	// NeighProxyNDP answers IPv6 Neighbor Solicitations.
+	NeighProxyNDP NeighProxyFlags = 1 << 1
+)
+
+// HashFlags selects the inputs of the hash choosing the multipath nexthop
+// of a flow.
+type HashFlags uint8
+
+const (
+	// HashCustom hashes the addresses and the protocol, plus the inputs of
+	// the other flags, instead of using the packet hash computed on
+	// parsing.
+	HashCustom HashFlags = 1 << 0
+	// HashPorts adds the TCP/UDP ports, making a 5-tuple hash.
+	HashPorts HashFlags = 1 << 1
+	// HashFlowLabel adds the IPv6 flow label.
+	HashFlowLabel HashFlags = 1 << 2
+	// HashSymmetric hashes both directions of a flow alike.
+	HashSymmetric HashFlags = 1 << 3
+)
+
 // FIBNexthop represents a single ECMP nexthop in the FIB.
 type FIBNexthop struct {
 	DstMAC net.HardwareAddr
 	SrcMAC net.HardwareAddr
 	Device string
+	// Weight is the number of slots the nexthop takes in its route list.
+	Weight uint32
 }
 
 // FIBEntry represents a single FIB prefix with its nexthops.
@@ -52,6 +98,60 @@ func (m *ModuleConfig) AddRouteList(routeIndices []uint32) (int, error) {
 	return m.addRouteList(routeIndices)
 }
 
+// SetURPF sets the uRPF mode of packets received on the device.
+func (m *ModuleConfig) SetURPF(device string, mode URPFMode) error {
+	if device == "" {
+		return fmt.Errorf("device name is required")
+	}
+	if mode > URPFModeStrict {
+		return fmt.Errorf("unsupported uRPF mode: %d", mode)
+	}
+
+	return m.setURPF(device, mode)
+}
+
+// SetHash sets the inputs of the hash choosing the multipath nexthop of a
+// flow. Zero flags keep the packet hash computed on parsing.
+func (m *ModuleConfig) SetHash(flags HashFlags) error {
+	if flags&^(HashCustom|HashPorts|HashFlowLabel|HashSymmetric) != 0 {
+		return fmt.Errorf("unsupported multipath hash flags: %#x", uint8(flags))
+	}
+	if flags != 0 && flags&HashCustom == 0 {
+		return fmt.Errorf("multipath hash flags require the custom hash: %#x", uint8(flags))
+	}
+
+	return m.setHash(flags)
+}
+
+// SetNeighProxy enables answering neighbour requests received on the device
+// for addresses routed through other devices.
+//
+// Replies are sent with the given MAC address. Zero flags disable proxying.
+func (m *ModuleConfig) SetNeighProxy(device string, flags NeighProxyFlags, mac net.HardwareAddr) error {
+	if device == "" {
+		return fmt.Errorf("device name is required")
+	}
+	if flags&^(NeighProxyARP|NeighProxyNDP) != 0 {
+		return fmt.Errorf("unsupported neighbour proxy flags: %#x", uint8(flags))
+	}
+
+	var addr [6]byte
+	if flags != 0 {
+		if len(mac) != 6 {
+			return fmt.Errorf("unsupported proxy MAC address: must be EUI-48")
+		}
+		addr = [6]byte(mac)
+	}
+
+	return m.setNeighProxy(device, flags, addr)
+}
+
+// AddBlackholeRouteList adds an empty route list. Packets routed to it are
+// dropped by the dataplane.
+func (m *ModuleConfig) AddBlackholeRouteList() (int, error) {
+	return m.addRouteList(nil)
+}
+
 // AddPrefix adds a prefix to the LPM table, pointing at the given route list.
 func (m *ModuleConfig) AddPrefix(prefix netip.Prefix, routeListIdx uint32) error {
 	addrStart := prefix.Addr()
@@ -96,18 +196,31 @@ func (m *ModuleConfig) DumpFIB() ([]FIBEntry, error) {
 			continue
 		}
 
+		// Weighted nexthops are programmed as repeated slots of the route
+		// list, which are folded back into a single weighted nexthop.
 		nhCount := iter.nexthopCount()
-		nexthops := make([]FIBNexthop, nhCount)
+		nexthops := make([]FIBNexthop, 0, nhCount)
 
 		for idx := range nhCount {
 			dstMAC := iter.nexthopDstMAC(idx)
 			srcMAC := iter.nexthopSrcMAC(idx)
-
-			nexthops[idx] = FIBNexthop{
+			nexthop := FIBNexthop{
 				DstMAC: net.HardwareAddr(dstMAC[:]),
 				SrcMAC: net.HardwareAddr(srcMAC[:]),
 				Device: iter.nexthopDeviceName(idx),
+				Weight: 1,
+			}
+
+			pos := slices.IndexFunc(nexthops, func(nh FIBNexthop) bool {
+				return bytes.Equal(nh.DstMAC, nexthop.DstMAC) &&
+					bytes.Equal(nh.SrcMAC, nexthop.SrcMAC) &&
+					nh.Device == nexthop.Device
+			})
+			if pos >= 0 {
+				nexthops[pos].Weight++
+				continue
 			}
+			nexthops = append(nexthops, nexthop)
 		}
 
 		entries = append(entries, FIBEntry{
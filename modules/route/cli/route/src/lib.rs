@@ -8,7 +8,8 @@ pub mod routepb {
     tonic::include_proto!("modules.route.controlplane.routepb.v1");
 }
 
-fn format_mac(mac: Option<MacAddress>) -> String {
+/// Formats an optional MAC address for display.
+pub fn format_mac(mac: Option<MacAddress>) -> String {
     let mac = match mac {
         Some(mac) => match MacAddr::try_from(&mac) {
             Ok(mac) => return mac.to_string(),
@@ -30,6 +31,8 @@ pub struct FibDisplayEntry {
     pub src_mac: String,
     #[tabled(rename = "Device")]
     pub device: String,
+    #[tabled(rename = "Weight")]
+    pub weight: u32,
 }
 
 impl FibDisplayEntry {
@@ -51,6 +54,7 @@ impl FibDisplayEntry {
                     dst_mac: format_mac(nh.dst_mac),
                     src_mac: format_mac(nh.src_mac),
                     device: nh.device.clone(),
+                    weight: nh.weight.max(1),
                 })
             })
             .collect()
@@ -1,6 +1,6 @@
 //! CLI for YANET "route" module.
 
-use core::error::Error;
+use core::{error::Error, net::IpAddr};
 use std::{
     fs::File,
     path::{Path, PathBuf},
@@ -21,8 +21,16 @@ use tabled::{
 };
 use tonic::codec::CompressionEncoding;
 use yanet_cli_route::{
-    routepb::{self, route_service_client::RouteServiceClient, ListConfigsRequest, ShowFibRequest, UpdateFibRequest},
-    FibDisplayEntry,
+    routepb::{
+        self, route_service_client::RouteServiceClient, verify_routes_request, AddBlacklistRequest, BlacklistEntry,
+        DrainNexthopRequest, DumpTrieRequest, GetCapacityRequest, ListConfigsRequest, ListRoutesRequest, LookupRouteRequest,
+        MultipathHash, MultipathHashFields,
+        NeighbourProxyInterface, PrefixConflictPolicy, RemoveBlacklistRequest, SetMultipathHashRequest, SetNeighbourProxyRequest, SetUrpfRequest,
+        ShowBlacklistRequest, ShowDrainedNexthopsRequest, ShowFibRequest, ShowMultipathHashRequest, ShowNeighbourProxyRequest, ShowPrefixConflictsRequest, ShowUrpfRequest, TrieDumpFormat, TrieStats,
+        PrefixEventKind, RouteSource, UndrainNexthopRequest, UpdateFibRequest, UrpfInterface, UrpfMode, VerifyRoutesRequest,
+        WatchPrefixRequest,
+    },
+    format_mac, FibDisplayEntry,
 };
 use ync::{
     client::{ConnectionArgs, LayeredChannel},
@@ -34,6 +42,10 @@ struct FibNexthop {
     dst_mac: String,
     src_mac: String,
     device: String,
+    /// Share of the prefix traffic sent via the nexthop; all nexthops weigh
+    /// the same when omitted.
+    #[serde(default)]
+    weight: u32,
 }
 
 #[derive(Debug, Serialize, Deserialize)]
@@ -41,6 +53,9 @@ struct FibEntry {
     prefix: String,
     #[serde(default)]
     nexthops: Vec<FibNexthop>,
+    /// Drop traffic to the prefix; nexthops are ignored.
+    #[serde(default)]
+    blackhole: bool,
 }
 
 #[derive(Debug, Serialize, Deserialize)]
@@ -73,6 +88,7 @@ impl TryFrom<FibNexthop> for routepb::FibNexthop {
             dst_mac: Some(parse_mac(&nh.dst_mac)?),
             src_mac: Some(parse_mac(&nh.src_mac)?),
             device: nh.device,
+            weight: nh.weight,
         })
     }
 }
@@ -86,7 +102,12 @@ impl TryFrom<FibEntry> for routepb::FibEntry {
             .into_iter()
             .map(routepb::FibNexthop::try_from)
             .collect::<Result<Vec<_>, _>>()?;
-        Ok(Self { prefix: entry.prefix, nexthops })
+        Ok(Self {
+            prefix: entry.prefix,
+            nexthops,
+            blackhole: entry.blackhole,
+            sources: Vec::new(),
+        })
     }
 }
 
@@ -110,6 +131,265 @@ pub struct Cmd {
 pub enum ModeCmd {
     /// FIB (Forwarding Information Base) operations.
     Fib(FibCmd),
+    /// Unicast reverse path forwarding operations.
+    Urpf(UrpfCmd),
+    /// Proxy-ARP/proxy-NDP operations.
+    Proxy(ProxyCmd),
+    /// Nexthop maintenance operations.
+    Nexthop(NexthopCmd),
+    /// Multipath hash operations.
+    Hash(HashCmd),
+    /// Prefix blacklist operations, shared by all route configs.
+    Blacklist(BlacklistCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct BlacklistCmd {
+    #[clap(subcommand)]
+    pub action: BlacklistAction,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum BlacklistAction {
+    /// Keep prefixes out of every installed FIB.
+    Add(BlacklistAddCmd),
+    /// Install the routes of blacklisted prefixes again.
+    Remove(BlacklistRemoveCmd),
+    /// Show the blacklisted prefixes.
+    Show,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct BlacklistAddCmd {
+    /// Prefixes to blacklist.
+    #[arg(required = true)]
+    pub prefixes: Vec<String>,
+    /// Blacklist the more-specifics of the prefixes as well.
+    #[arg(long)]
+    pub or_longer: bool,
+    /// Reason of the blacklisting, e.g. a ticket.
+    #[arg(long, default_value = "")]
+    pub comment: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct BlacklistRemoveCmd {
+    /// Blacklisted prefixes to remove.
+    #[arg(required = true)]
+    pub prefixes: Vec<String>,
+}
+
+/// Blacklisted prefix for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct BlacklistDisplayEntry {
+    #[tabled(rename = "Prefix")]
+    prefix: String,
+    #[tabled(rename = "Or longer")]
+    or_longer: bool,
+    #[tabled(rename = "Comment")]
+    comment: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct HashCmd {
+    #[clap(subcommand)]
+    pub action: HashAction,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum HashAction {
+    /// Show the fields hashed to pick a multipath nexthop.
+    Show(HashShowCmd),
+    /// Replace the fields hashed to pick a multipath nexthop.
+    Set(HashSetCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct HashShowCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct HashSetCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Hashed fields: "default" for the NIC hash, "5-tuple" or "3-tuple".
+    #[arg(long, default_value = "default", value_parser = parse_hash_fields)]
+    pub fields: MultipathHashFields,
+    /// Hash the IPv6 flow label as well.
+    #[arg(long)]
+    pub flow_label: bool,
+    /// Hash both directions of a flow alike.
+    #[arg(long)]
+    pub symmetric: bool,
+}
+
+/// Multipath hash settings for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct HashDisplayEntry {
+    #[tabled(rename = "Fields")]
+    fields: String,
+    #[tabled(rename = "Flow label")]
+    flow_label: bool,
+    #[tabled(rename = "Symmetric")]
+    symmetric: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct NexthopCmd {
+    #[clap(subcommand)]
+    pub action: NexthopAction,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum NexthopAction {
+    /// Remove a nexthop from every multipath group.
+    ///
+    /// Routes with no other nexthop keep the drained one.
+    Drain(NexthopDrainCmd),
+    /// Reinstate a drained nexthop.
+    Undrain(NexthopDrainCmd),
+    /// Show the drained nexthops.
+    Show(NexthopShowCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct NexthopDrainCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Egress device of the nexthop.
+    #[arg(long)]
+    pub device: String,
+    /// Destination MAC address of the nexthop.
+    #[arg(long)]
+    pub dst_mac: String,
+}
+
+impl NexthopDrainCmd {
+    fn nexthop(&self) -> Result<routepb::FibNexthop, Box<dyn Error>> {
+        Ok(routepb::FibNexthop {
+            dst_mac: Some(parse_mac(&self.dst_mac)?),
+            src_mac: None,
+            device: self.device.clone(),
+            weight: 0,
+        })
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct NexthopShowCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+/// Drained nexthop for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct DrainedDisplayEntry {
+    #[tabled(rename = "Device")]
+    device: String,
+    #[tabled(rename = "Dst MAC")]
+    dst_mac: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ProxyCmd {
+    #[clap(subcommand)]
+    pub action: ProxyAction,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum ProxyAction {
+    /// Show per-interface neighbour proxy settings.
+    Show(ProxyShowCmd),
+    /// Replace per-interface neighbour proxy settings.
+    Set(ProxySetCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ProxyShowCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ProxySetCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Interfaces answering ARP requests for routed prefixes.
+    #[arg(long)]
+    pub arp: Vec<String>,
+    /// Interfaces answering Neighbor Solicitations for routed prefixes.
+    #[arg(long)]
+    pub ndp: Vec<String>,
+    /// MAC address advertised in replies.
+    #[arg(long, required_unless_present = "clear")]
+    pub mac: Option<String>,
+    /// Disable proxying on all interfaces.
+    #[arg(long, conflicts_with_all = ["arp", "ndp", "mac"])]
+    pub clear: bool,
+}
+
+/// Neighbour proxy setting for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct ProxyDisplayEntry {
+    #[tabled(rename = "Device")]
+    device: String,
+    #[tabled(rename = "ARP")]
+    arp: bool,
+    #[tabled(rename = "NDP")]
+    ndp: bool,
+    #[tabled(rename = "MAC")]
+    mac: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct UrpfCmd {
+    #[clap(subcommand)]
+    pub action: UrpfAction,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum UrpfAction {
+    /// Show per-interface uRPF settings.
+    Show(UrpfShowCmd),
+    /// Replace per-interface uRPF settings.
+    Set(UrpfSetCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct UrpfShowCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct UrpfSetCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Interfaces checked in strict mode.
+    #[arg(long)]
+    pub strict: Vec<String>,
+    /// Interfaces checked in loose mode.
+    #[arg(long)]
+    pub loose: Vec<String>,
+}
+
+/// uRPF setting for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct UrpfDisplayEntry {
+    #[tabled(rename = "Device")]
+    device: String,
+    #[tabled(rename = "Mode")]
+    mode: String,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -126,6 +406,291 @@ pub enum FibAction {
     Show(FibShowCmd),
     /// Replace the FIB atomically with entries from a YAML file.
     Update(FibUpdateCmd),
+    /// Check that the applied FIB matches the expected entries.
+    ///
+    /// Exits with an error if the dataplane has not converged.
+    Verify(FibVerifyCmd),
+    /// Describe the shape of the LPM trie built from the applied FIB.
+    Trie(FibTrieCmd),
+    /// Show the size of the applied FIB against the shared memory
+    /// available to it.
+    Capacity(FibCapacityCmd),
+    /// List the prefixes installed with different forwarding by several
+    /// route module configs.
+    Conflicts(FibConflictsCmd),
+    /// List the routes of the applied FIB as pushed, with their sources.
+    Routes(FibRoutesCmd),
+    /// Find the applied route with the longest prefix matching an address.
+    Lookup(FibLookupCmd),
+    /// Follow the changes of the routes overlapping a prefix for a while.
+    ///
+    /// The module also logs the changes while the watch lasts.
+    Watch(FibWatchCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibRoutesCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Show only IPv4 routes.
+    #[arg(long)]
+    pub ipv4: bool,
+    /// Show only IPv6 routes.
+    #[arg(long)]
+    pub ipv6: bool,
+    /// Show only routes of this prefix and its more-specifics.
+    #[arg(long)]
+    pub prefix: Option<String>,
+    /// Show only routes with a nexthop via this egress device.
+    #[arg(long)]
+    pub device: Option<String>,
+    /// Show only routes with a nexthop with this destination MAC address.
+    #[arg(long)]
+    pub dst_mac: Option<String>,
+    /// Show only routes built from this source.
+    #[arg(long, value_parser = ["static", "bird"])]
+    pub source: Option<String>,
+    /// Show at most this many routes.
+    #[arg(long)]
+    pub limit: Option<u32>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibLookupCmd {
+    /// Route config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Address to look up.
+    pub addr: IpAddr,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibWatchCmd {
+    /// Watch only this route config; every config if omitted.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: Option<String>,
+    /// Prefix to watch, with its more-specifics and the prefixes covering it.
+    pub prefix: String,
+    /// Seconds to watch for, at most an hour; ten minutes if omitted.
+    #[arg(long)]
+    pub ttl: Option<u32>,
+}
+
+/// Change of a watched route for display in the CLI.
+#[derive(Clone, Debug, Serialize)]
+struct PrefixEventDisplay {
+    name: String,
+    kind: String,
+    watched_prefix: String,
+    generation: u64,
+    routes: Vec<RouteDisplayEntry>,
+}
+
+impl PrefixEventDisplay {
+    fn from_event(event: routepb::PrefixEvent) -> Self {
+        let kind = match event.kind() {
+            PrefixEventKind::Added => "added",
+            PrefixEventKind::Changed => "changed",
+            PrefixEventKind::Removed => "removed",
+            PrefixEventKind::Unspecified => "unknown",
+        };
+
+        Self {
+            name: event.name,
+            kind: kind.to_string(),
+            watched_prefix: event.watched_prefix,
+            generation: event.generation,
+            routes: event.entry.map(RouteDisplayEntry::from_entry).unwrap_or_default(),
+        }
+    }
+}
+
+/// Route of the applied FIB for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct RouteDisplayEntry {
+    #[tabled(rename = "Prefix")]
+    prefix: String,
+    #[tabled(rename = "Dst MAC")]
+    dst_mac: String,
+    #[tabled(rename = "Src MAC")]
+    src_mac: String,
+    #[tabled(rename = "Device")]
+    device: String,
+    #[tabled(rename = "Weight")]
+    weight: u32,
+    #[tabled(rename = "Sources")]
+    sources: String,
+}
+
+impl RouteDisplayEntry {
+    /// Emits one row per nexthop, or a single row for a blackhole.
+    fn from_entry(entry: routepb::FibEntry) -> Vec<Self> {
+        let sources = entry
+            .sources()
+            .map(|source| match source {
+                RouteSource::Static => "static",
+                RouteSource::Bird => "bird",
+                RouteSource::Unspecified => "unknown",
+            })
+            .collect::<Vec<_>>()
+            .join(",");
+
+        if entry.blackhole {
+            return vec![Self {
+                prefix: entry.prefix,
+                dst_mac: String::new(),
+                src_mac: String::new(),
+                device: "blackhole".to_string(),
+                weight: 0,
+                sources,
+            }];
+        }
+
+        entry
+            .nexthops
+            .into_iter()
+            .map(|nh| Self {
+                prefix: entry.prefix.clone(),
+                dst_mac: format_mac(nh.dst_mac),
+                src_mac: format_mac(nh.src_mac),
+                device: nh.device,
+                weight: nh.weight.max(1),
+                sources: sources.clone(),
+            })
+            .collect()
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibConflictsCmd {
+    /// Show only the conflicts of this route module config.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: Option<String>,
+}
+
+/// Prefix conflict route for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct ConflictDisplayEntry {
+    #[tabled(rename = "Prefix")]
+    prefix: String,
+    #[tabled(rename = "Config")]
+    config: String,
+    #[tabled(rename = "Forwarding")]
+    forwarding: String,
+}
+
+/// Prefix conflicts with the policy resolving them.
+#[derive(Debug, Serialize)]
+struct ConflictsReport {
+    policy: String,
+    conflicts: Vec<ConflictDisplayEntry>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibCapacityCmd {
+    /// Route module config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+}
+
+/// FIB capacity for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct CapacityDisplayEntry {
+    #[tabled(rename = "IPv4 prefixes")]
+    ipv4_prefixes: u64,
+    #[tabled(rename = "IPv6 prefixes")]
+    ipv6_prefixes: u64,
+    #[tabled(rename = "Blackholes")]
+    blackhole_prefixes: u64,
+    #[tabled(rename = "Nexthop groups")]
+    nexthop_groups: u64,
+    #[tabled(rename = "Nexthops")]
+    nexthops: u64,
+    #[tabled(rename = "Memory")]
+    memory_bytes: u64,
+    #[tabled(rename = "Capacity")]
+    capacity_bytes: u64,
+    #[tabled(rename = "Utilization")]
+    utilization: String,
+    #[tabled(rename = "Max prefixes")]
+    max_prefixes: u64,
+    #[tabled(rename = "Headroom")]
+    headroom_prefixes: u64,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibTrieCmd {
+    /// Route module config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Print the trie pages in Graphviz DOT format instead of statistics.
+    #[arg(long)]
+    pub dot: bool,
+    /// Maximum number of pages in the DOT output.
+    #[arg(long, default_value_t = 1024, requires = "dot")]
+    pub max_nodes: u32,
+}
+
+/// Trie level for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct TrieLevelDisplayEntry {
+    #[tabled(rename = "Family")]
+    family: &'static str,
+    #[tabled(rename = "Depth")]
+    depth: u32,
+    #[tabled(rename = "Pages")]
+    pages: u64,
+    #[tabled(rename = "Prefixes")]
+    prefixes: u64,
+    #[tabled(rename = "Density")]
+    density: String,
+}
+
+impl TrieLevelDisplayEntry {
+    fn from_stats(family: &'static str, stats: TrieStats) -> impl Iterator<Item = Self> {
+        stats.levels.into_iter().map(move |level| Self {
+            family,
+            depth: level.depth,
+            pages: level.pages,
+            prefixes: level.prefixes,
+            density: format!("{:.2}", level.density),
+        })
+    }
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct FibVerifyCmd {
+    /// Route module config name.
+    #[arg(long = "name", short = 'n')]
+    pub config_name: String,
+    /// Path to the FIB YAML file with the expected entries.
+    #[arg(long = "rules", value_name = "PATH", required_unless_present = "digest")]
+    pub rules: Option<PathBuf>,
+    /// Expected FIB digest, as reported by a previous verification.
+    #[arg(long, conflicts_with = "rules")]
+    pub digest: Option<String>,
+}
+
+/// FIB difference for display in the CLI table.
+#[derive(Clone, Debug, Serialize, Tabled)]
+struct VerifyDisplayEntry {
+    #[tabled(rename = "Status")]
+    status: &'static str,
+    #[tabled(rename = "Prefix")]
+    prefix: String,
+    #[tabled(rename = "Expected")]
+    expected: String,
+    #[tabled(rename = "Actual")]
+    actual: String,
+}
+
+/// FIB verification result.
+#[derive(Debug, Serialize)]
+struct VerifyReport {
+    converged: bool,
+    digest: String,
+    differences: Vec<VerifyDisplayEntry>,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -149,8 +714,14 @@ pub struct FibShowCmd {
     /// Route config name.
     #[arg(long = "name", short = 'n')]
     pub config_name: String,
+    /// Show at most this many FIB entries.
+    #[arg(long)]
+    pub limit: Option<u32>,
 }
 
+/// Number of FIB entries fetched per ShowFIB request.
+const FIB_SHOW_PAGE_SIZE: u32 = 4096;
+
 #[tokio::main(flavor = "current_thread")]
 pub async fn main() {
     CompleteEnv::with_factory(Cmd::command).complete();
@@ -172,6 +743,35 @@ async fn run(cmd: Cmd) -> Result<(), Box<dyn Error>> {
             FibAction::List => service.list_fibs().await,
             FibAction::Show(cmd) => service.show_fib(cmd).await,
             FibAction::Update(cmd) => service.update_fib(cmd).await,
+            FibAction::Verify(cmd) => service.verify_fib(cmd).await,
+            FibAction::Trie(cmd) => service.dump_trie(cmd).await,
+            FibAction::Capacity(cmd) => service.show_capacity(cmd).await,
+            FibAction::Conflicts(cmd) => service.show_conflicts(cmd).await,
+            FibAction::Routes(cmd) => service.list_routes(cmd).await,
+            FibAction::Lookup(cmd) => service.lookup_route(cmd).await,
+            FibAction::Watch(cmd) => service.watch_prefix(cmd).await,
+        },
+        ModeCmd::Urpf(cmd) => match cmd.action {
+            UrpfAction::Show(cmd) => service.show_urpf(cmd).await,
+            UrpfAction::Set(cmd) => service.set_urpf(cmd).await,
+        },
+        ModeCmd::Proxy(cmd) => match cmd.action {
+            ProxyAction::Show(cmd) => service.show_proxy(cmd).await,
+            ProxyAction::Set(cmd) => service.set_proxy(cmd).await,
+        },
+        ModeCmd::Nexthop(cmd) => match cmd.action {
+            NexthopAction::Drain(cmd) => service.drain_nexthop(cmd).await,
+            NexthopAction::Undrain(cmd) => service.undrain_nexthop(cmd).await,
+            NexthopAction::Show(cmd) => service.show_drained(cmd).await,
+        },
+        ModeCmd::Hash(cmd) => match cmd.action {
+            HashAction::Show(cmd) => service.show_hash(cmd).await,
+            HashAction::Set(cmd) => service.set_hash(cmd).await,
+        },
+        ModeCmd::Blacklist(cmd) => match cmd.action {
+            BlacklistAction::Add(cmd) => service.add_blacklist(cmd).await,
+            BlacklistAction::Remove(cmd) => service.remove_blacklist(cmd).await,
+            BlacklistAction::Show => service.show_blacklist().await,
         },
     }
 }
@@ -200,6 +800,9 @@ impl RouteService {
         let request = UpdateFibRequest {
             module_name: cmd.config_name.clone(),
             entries,
+            delta: false,
+            base_generation: 0,
+            removed_prefixes: Vec::new(),
         };
         self.client.update_fib(request).await?;
 
@@ -210,6 +813,166 @@ impl RouteService {
         Ok(())
     }
 
+    pub async fn verify_fib(&mut self, cmd: FibVerifyCmd) -> Result<(), Box<dyn Error>> {
+        let expected = match (cmd.rules, cmd.digest) {
+            (Some(rules), _) => {
+                let entries = FibConfig::load(&rules)?
+                    .entries
+                    .into_iter()
+                    .map(routepb::FibEntry::try_from)
+                    .collect::<Result<Vec<_>, _>>()?;
+                verify_routes_request::Expected::Entries(routepb::FibEntryList { entries })
+            }
+            (None, Some(digest)) => verify_routes_request::Expected::Digest(digest),
+            (None, None) => return Err("either rules or digest is required".into()),
+        };
+
+        let request = VerifyRoutesRequest {
+            name: cmd.config_name.clone(),
+            expected: Some(expected),
+        };
+        let response = self.client.verify_routes(request).await?.into_inner();
+
+        let mut differences = Vec::new();
+        for entry in response.missing {
+            differences.push(VerifyDisplayEntry {
+                status: "missing",
+                expected: format_forwarding(&entry),
+                actual: String::new(),
+                prefix: entry.prefix,
+            });
+        }
+        for entry in response.extra {
+            differences.push(VerifyDisplayEntry {
+                status: "extra",
+                expected: String::new(),
+                actual: format_forwarding(&entry),
+                prefix: entry.prefix,
+            });
+        }
+        for mismatch in response.mismatched {
+            let expected = mismatch.expected.unwrap_or_default();
+            let actual = mismatch.actual.unwrap_or_default();
+            differences.push(VerifyDisplayEntry {
+                status: "mismatched",
+                expected: format_forwarding(&expected),
+                actual: format_forwarding(&actual),
+                prefix: expected.prefix,
+            });
+        }
+
+        let report = VerifyReport {
+            converged: response.converged,
+            digest: response.digest,
+            differences,
+        };
+        output::data(&report, false, format_args!(""), || {
+            println!("Digest: {}", report.digest);
+            if !report.differences.is_empty() {
+                print_table(report.differences.clone());
+            }
+        });
+
+        if !report.converged {
+            return Err(format!("FIB '{}' has not converged", cmd.config_name).into());
+        }
+        Ok(())
+    }
+
+    pub async fn dump_trie(&mut self, cmd: FibTrieCmd) -> Result<(), Box<dyn Error>> {
+        let format = if cmd.dot {
+            TrieDumpFormat::Graphviz
+        } else {
+            TrieDumpFormat::Stats
+        };
+        let request = DumpTrieRequest {
+            name: cmd.config_name.clone(),
+            format: format.into(),
+            max_nodes: cmd.max_nodes,
+        };
+        let response = self.client.dump_trie(request).await?.into_inner();
+
+        if cmd.dot {
+            print!("{}", response.dot);
+            if response.truncated {
+                log::warn!("DOT output truncated at {} pages", cmd.max_nodes);
+            }
+            return Ok(());
+        }
+
+        let ipv4 = TrieLevelDisplayEntry::from_stats("ipv4", response.ipv4.unwrap_or_default());
+        let ipv6 = TrieLevelDisplayEntry::from_stats("ipv6", response.ipv6.unwrap_or_default());
+        let entries: Vec<TrieLevelDisplayEntry> = ipv4.chain(ipv6).collect();
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No trie pages found for {}.", cmd.config_name),
+            || print_table(entries.clone()),
+        );
+        Ok(())
+    }
+
+    pub async fn show_capacity(&mut self, cmd: FibCapacityCmd) -> Result<(), Box<dyn Error>> {
+        let request = GetCapacityRequest { name: cmd.config_name.clone() };
+        let response = self.client.get_capacity(request).await?.into_inner();
+
+        let entry = CapacityDisplayEntry {
+            ipv4_prefixes: response.ipv4_prefixes,
+            ipv6_prefixes: response.ipv6_prefixes,
+            blackhole_prefixes: response.blackhole_prefixes,
+            nexthop_groups: response.nexthop_groups,
+            nexthops: response.nexthops,
+            memory_bytes: response.memory_bytes,
+            capacity_bytes: response.capacity_bytes,
+            utilization: format!("{:.1}%", response.utilization * 100.0),
+            max_prefixes: response.max_prefixes,
+            headroom_prefixes: response.headroom_prefixes,
+        };
+
+        output::data(&entry, false, format_args!(""), || print_table([entry.clone()]));
+        Ok(())
+    }
+
+    pub async fn show_conflicts(&mut self, cmd: FibConflictsCmd) -> Result<(), Box<dyn Error>> {
+        let request = ShowPrefixConflictsRequest {
+            name: cmd.config_name.unwrap_or_default(),
+        };
+        let response = self.client.show_prefix_conflicts(request).await?.into_inner();
+
+        let mut conflicts = Vec::new();
+        for conflict in response.conflicts {
+            for route in &conflict.routes {
+                conflicts.push(ConflictDisplayEntry {
+                    prefix: conflict.prefix.clone(),
+                    config: route.name.clone(),
+                    forwarding: format_forwarding(&route.entry.clone().unwrap_or_default()),
+                });
+            }
+            if let Some(resolved) = &conflict.resolved {
+                conflicts.push(ConflictDisplayEntry {
+                    prefix: conflict.prefix.clone(),
+                    config: "(installed)".to_string(),
+                    forwarding: format_forwarding(resolved),
+                });
+            }
+        }
+
+        let report = ConflictsReport {
+            policy: prefix_conflict_policy_to_string(response.policy()),
+            conflicts,
+        };
+        output::data(&report, false, format_args!(""), || {
+            println!("Policy: {}", report.policy);
+            if report.conflicts.is_empty() {
+                println!("No prefix conflicts found.");
+            } else {
+                print_table(report.conflicts.clone());
+            }
+        });
+        Ok(())
+    }
+
     pub async fn list_fibs(&mut self) -> Result<(), Box<dyn Error>> {
         let response = self.client.list_configs(ListConfigsRequest {}).await?.into_inner();
 
@@ -227,24 +990,400 @@ impl RouteService {
     }
 
     pub async fn show_fib(&mut self, cmd: FibShowCmd) -> Result<(), Box<dyn Error>> {
-        let request = ShowFibRequest {
+        let mut entries: Vec<FibDisplayEntry> = Vec::new();
+        let mut remaining = cmd.limit;
+        let mut page_token = String::new();
+        loop {
+            let page_size = remaining.map_or(FIB_SHOW_PAGE_SIZE, |r| r.min(FIB_SHOW_PAGE_SIZE));
+            if page_size == 0 {
+                break;
+            }
+
+            let request = ShowFibRequest {
+                name: cmd.config_name.clone(),
+                ipv4_only: cmd.ipv4,
+                ipv6_only: cmd.ipv6,
+                page_size,
+                page_token,
+                read_mask: None,
+            };
+            let response = self.client.show_fib(request).await?.into_inner();
+
+            remaining = remaining.map(|r| r - response.entries.len() as u32);
+            entries.extend(response.entries.into_iter().flat_map(FibDisplayEntry::from_range_entry));
+
+            if response.next_page_token.is_empty() {
+                break;
+            }
+            page_token = response.next_page_token;
+        }
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No FIB entries found for {}.", cmd.config_name),
+            || print_table(entries.clone()),
+        );
+
+        Ok(())
+    }
+
+    pub async fn list_routes(&mut self, cmd: FibRoutesCmd) -> Result<(), Box<dyn Error>> {
+        let nexthop = if cmd.device.is_some() || cmd.dst_mac.is_some() {
+            Some(routepb::FibNexthop {
+                dst_mac: cmd.dst_mac.as_deref().map(parse_mac).transpose()?,
+                src_mac: None,
+                device: cmd.device.clone().unwrap_or_default(),
+                weight: 0,
+            })
+        } else {
+            None
+        };
+        let source = match cmd.source.as_deref() {
+            Some("static") => RouteSource::Static,
+            Some("bird") => RouteSource::Bird,
+            _ => RouteSource::Unspecified,
+        };
+
+        let mut entries: Vec<RouteDisplayEntry> = Vec::new();
+        let mut remaining = cmd.limit;
+        let mut page_token = String::new();
+        loop {
+            let page_size = remaining.map_or(FIB_SHOW_PAGE_SIZE, |r| r.min(FIB_SHOW_PAGE_SIZE));
+            if page_size == 0 {
+                break;
+            }
+
+            let request = ListRoutesRequest {
+                name: cmd.config_name.clone(),
+                prefix: cmd.prefix.clone().unwrap_or_default(),
+                ipv4_only: cmd.ipv4,
+                ipv6_only: cmd.ipv6,
+                nexthop: nexthop.clone(),
+                source: source.into(),
+                page_size,
+                page_token,
+            };
+            let response = self.client.list_routes(request).await?.into_inner();
+
+            remaining = remaining.map(|r| r - response.routes.len() as u32);
+            entries.extend(response.routes.into_iter().flat_map(RouteDisplayEntry::from_entry));
+
+            if response.next_page_token.is_empty() {
+                break;
+            }
+            page_token = response.next_page_token;
+        }
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No routes found for {}.", cmd.config_name),
+            || print_table(entries.clone()),
+        );
+
+        Ok(())
+    }
+
+    pub async fn lookup_route(&mut self, cmd: FibLookupCmd) -> Result<(), Box<dyn Error>> {
+        let request = LookupRouteRequest {
             name: cmd.config_name.clone(),
-            ipv4_only: cmd.ipv4,
-            ipv6_only: cmd.ipv6,
+            addr: Some(cmd.addr.into()),
+        };
+        let response = self.client.lookup_route(request).await?.into_inner();
+
+        let entries: Vec<RouteDisplayEntry> = response
+            .route
+            .map(RouteDisplayEntry::from_entry)
+            .unwrap_or_default();
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No route to {} in {}.", cmd.addr, cmd.config_name),
+            || print_table(entries.clone()),
+        );
+
+        Ok(())
+    }
+
+    pub async fn watch_prefix(&mut self, cmd: FibWatchCmd) -> Result<(), Box<dyn Error>> {
+        let request = WatchPrefixRequest {
+            name: cmd.config_name.unwrap_or_default(),
+            prefix: cmd.prefix.clone(),
+            ttl: cmd.ttl.map(|secs| prost_types::Duration {
+                seconds: secs.into(),
+                nanos: 0,
+            }),
+        };
+        let mut stream = self.client.watch_prefix(request).await?.into_inner();
+
+        // The stream ends once the watch expires.
+        while let Some(event) = stream.message().await? {
+            let event = PrefixEventDisplay::from_event(event);
+            output::data(&event, false, format_args!(""), || {
+                println!(
+                    "{} {} in '{}' (watching {}, generation {})",
+                    event.kind,
+                    event.routes.first().map(|route| route.prefix.as_str()).unwrap_or_default(),
+                    event.name,
+                    event.watched_prefix,
+                    event.generation,
+                );
+                print_table(event.routes.clone());
+            });
+        }
+
+        output::success("watch", format_args!("Stopped watching {}.", cmd.prefix));
+        Ok(())
+    }
+
+    pub async fn set_urpf(&mut self, cmd: UrpfSetCmd) -> Result<(), Box<dyn Error>> {
+        let strict = cmd.strict.into_iter().map(|device| (device, UrpfMode::Strict));
+        let loose = cmd.loose.into_iter().map(|device| (device, UrpfMode::Loose));
+        let interfaces: Vec<UrpfInterface> = strict
+            .chain(loose)
+            .map(|(device, mode)| UrpfInterface { device, mode: mode.into() })
+            .collect();
+        let interface_count = interfaces.len();
+
+        let request = SetUrpfRequest {
+            module_name: cmd.config_name.clone(),
+            interfaces,
         };
+        self.client.set_urpf(request).await?;
 
-        let response = self.client.show_fib(request).await?.into_inner();
+        output::success(
+            "urpf-set",
+            format_args!("Updated uRPF on '{}' ({} interfaces).", cmd.config_name, interface_count),
+        );
+        Ok(())
+    }
 
-        let entries: Vec<FibDisplayEntry> = response
+    pub async fn show_urpf(&mut self, cmd: UrpfShowCmd) -> Result<(), Box<dyn Error>> {
+        let request = ShowUrpfRequest { name: cmd.config_name.clone() };
+        let response = self.client.show_urpf(request).await?.into_inner();
+
+        let entries: Vec<UrpfDisplayEntry> = response
+            .interfaces
+            .into_iter()
+            .map(|interface| UrpfDisplayEntry {
+                mode: urpf_mode_to_string(interface.mode()),
+                device: interface.device,
+            })
+            .collect();
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No uRPF settings found for {}.", cmd.config_name),
+            || print_table(entries.clone()),
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_hash(&mut self, cmd: HashSetCmd) -> Result<(), Box<dyn Error>> {
+        let request = SetMultipathHashRequest {
+            module_name: cmd.config_name.clone(),
+            hash: Some(MultipathHash {
+                fields: cmd.fields.into(),
+                flow_label: cmd.flow_label,
+                symmetric: cmd.symmetric,
+            }),
+        };
+        self.client.set_multipath_hash(request).await?;
+
+        output::success(
+            "hash-set",
+            format_args!(
+                "Updated multipath hash on '{}' ({}).",
+                cmd.config_name,
+                hash_fields_to_string(cmd.fields)
+            ),
+        );
+        Ok(())
+    }
+
+    pub async fn show_hash(&mut self, cmd: HashShowCmd) -> Result<(), Box<dyn Error>> {
+        let request = ShowMultipathHashRequest { name: cmd.config_name.clone() };
+        let response = self.client.show_multipath_hash(request).await?.into_inner();
+        let hash = response.hash.unwrap_or_default();
+
+        let entry = HashDisplayEntry {
+            fields: hash_fields_to_string(hash.fields()),
+            flow_label: hash.flow_label,
+            symmetric: hash.symmetric,
+        };
+
+        output::data(&entry, false, format_args!(""), || print_table([entry.clone()]));
+        Ok(())
+    }
+
+    pub async fn set_proxy(&mut self, cmd: ProxySetCmd) -> Result<(), Box<dyn Error>> {
+        let mut devices: Vec<(String, bool, bool)> = cmd.arp.into_iter().map(|device| (device, true, false)).collect();
+        for device in cmd.ndp {
+            match devices.iter_mut().find(|(name, ..)| *name == device) {
+                Some((_, _, ndp)) => *ndp = true,
+                None => devices.push((device, false, true)),
+            }
+        }
+
+        let interfaces = devices
+            .into_iter()
+            .map(|(device, arp, ndp)| {
+                let mac = cmd.mac.as_deref().map(parse_mac).transpose()?;
+                Ok(NeighbourProxyInterface { device, arp, ndp, mac })
+            })
+            .collect::<Result<Vec<_>, Box<dyn Error>>>()?;
+        let interface_count = interfaces.len();
+
+        let request = SetNeighbourProxyRequest {
+            module_name: cmd.config_name.clone(),
+            interfaces,
+        };
+        self.client.set_neighbour_proxy(request).await?;
+
+        output::success(
+            "proxy-set",
+            format_args!(
+                "Updated neighbour proxy on '{}' ({} interfaces).",
+                cmd.config_name, interface_count
+            ),
+        );
+        Ok(())
+    }
+
+    pub async fn drain_nexthop(&mut self, cmd: NexthopDrainCmd) -> Result<(), Box<dyn Error>> {
+        let request = DrainNexthopRequest {
+            name: cmd.config_name.clone(),
+            nexthop: Some(cmd.nexthop()?),
+        };
+        let response = self.client.drain_nexthop(request).await?.into_inner();
+
+        output::success(
+            "nexthop-drain",
+            format_args!(
+                "Drained {} via {} on '{}' ({} routes rebalanced, {} routes kept it as their only nexthop).",
+                cmd.dst_mac, cmd.device, cmd.config_name, response.drained_routes, response.kept_routes
+            ),
+        );
+        Ok(())
+    }
+
+    pub async fn undrain_nexthop(&mut self, cmd: NexthopDrainCmd) -> Result<(), Box<dyn Error>> {
+        let request = UndrainNexthopRequest {
+            name: cmd.config_name.clone(),
+            nexthop: Some(cmd.nexthop()?),
+        };
+        self.client.undrain_nexthop(request).await?;
+
+        output::success(
+            "nexthop-undrain",
+            format_args!("Reinstated {} via {} on '{}'.", cmd.dst_mac, cmd.device, cmd.config_name),
+        );
+        Ok(())
+    }
+
+    pub async fn show_drained(&mut self, cmd: NexthopShowCmd) -> Result<(), Box<dyn Error>> {
+        let request = ShowDrainedNexthopsRequest { name: cmd.config_name.clone() };
+        let response = self.client.show_drained_nexthops(request).await?.into_inner();
+
+        let entries: Vec<DrainedDisplayEntry> = response
+            .nexthops
+            .into_iter()
+            .map(|nh| DrainedDisplayEntry {
+                device: nh.device,
+                dst_mac: format_mac(nh.dst_mac),
+            })
+            .collect();
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No drained nexthops found for {}.", cmd.config_name),
+            || print_table(entries.clone()),
+        );
+        Ok(())
+    }
+
+    pub async fn add_blacklist(&mut self, cmd: BlacklistAddCmd) -> Result<(), Box<dyn Error>> {
+        let request = AddBlacklistRequest {
+            entries: cmd
+                .prefixes
+                .iter()
+                .map(|prefix| BlacklistEntry {
+                    prefix: prefix.clone(),
+                    or_longer: cmd.or_longer,
+                    comment: cmd.comment.clone(),
+                })
+                .collect(),
+        };
+        let response = self.client.add_blacklist(request).await?.into_inner();
+
+        output::success(
+            "blacklist-add",
+            format_args!(
+                "Blacklisted {} prefixes ({} routes withdrawn).",
+                cmd.prefixes.len(),
+                response.blocked_routes
+            ),
+        );
+        Ok(())
+    }
+
+    pub async fn remove_blacklist(&mut self, cmd: BlacklistRemoveCmd) -> Result<(), Box<dyn Error>> {
+        let request = RemoveBlacklistRequest { prefixes: cmd.prefixes.clone() };
+        self.client.remove_blacklist(request).await?;
+
+        output::success(
+            "blacklist-remove",
+            format_args!("Removed {} prefixes from the blacklist.", cmd.prefixes.len()),
+        );
+        Ok(())
+    }
+
+    pub async fn show_blacklist(&mut self) -> Result<(), Box<dyn Error>> {
+        let response = self.client.show_blacklist(ShowBlacklistRequest {}).await?.into_inner();
+
+        let entries: Vec<BlacklistDisplayEntry> = response
             .entries
             .into_iter()
-            .flat_map(FibDisplayEntry::from_range_entry)
+            .map(|entry| BlacklistDisplayEntry {
+                prefix: entry.prefix,
+                or_longer: entry.or_longer,
+                comment: entry.comment,
+            })
             .collect();
 
         output::data(
             &entries,
             entries.is_empty(),
-            format_args!("No FIB entries found for {}.", cmd.config_name),
+            format_args!("No blacklisted prefixes."),
+            || print_table(entries.clone()),
+        );
+        Ok(())
+    }
+
+    pub async fn show_proxy(&mut self, cmd: ProxyShowCmd) -> Result<(), Box<dyn Error>> {
+        let request = ShowNeighbourProxyRequest { name: cmd.config_name.clone() };
+        let response = self.client.show_neighbour_proxy(request).await?.into_inner();
+
+        let entries: Vec<ProxyDisplayEntry> = response
+            .interfaces
+            .into_iter()
+            .map(|interface| ProxyDisplayEntry {
+                device: interface.device,
+                arp: interface.arp,
+                ndp: interface.ndp,
+                mac: format_mac(interface.mac),
+            })
+            .collect();
+
+        output::data(
+            &entries,
+            entries.is_empty(),
+            format_args!("No neighbour proxy settings found for {}.", cmd.config_name),
             || print_table(entries.clone()),
         );
 
@@ -252,6 +1391,55 @@ impl RouteService {
     }
 }
 
+/// Formats the forwarding of a FIB entry as a comma-separated nexthop
+/// list.
+fn format_forwarding(entry: &routepb::FibEntry) -> String {
+    if entry.blackhole {
+        return "blackhole".to_string();
+    }
+
+    entry
+        .nexthops
+        .iter()
+        .map(|nh| format!("{} via {}", format_mac(nh.dst_mac), nh.device))
+        .collect::<Vec<_>>()
+        .join(", ")
+}
+
+fn prefix_conflict_policy_to_string(policy: PrefixConflictPolicy) -> String {
+    match policy {
+        PrefixConflictPolicy::Report => "report".to_string(),
+        PrefixConflictPolicy::Error => "error".to_string(),
+        PrefixConflictPolicy::Priority => "priority".to_string(),
+        PrefixConflictPolicy::Merge => "merge".to_string(),
+    }
+}
+
+fn urpf_mode_to_string(mode: UrpfMode) -> String {
+    match mode {
+        UrpfMode::None => "none".to_string(),
+        UrpfMode::Loose => "loose".to_string(),
+        UrpfMode::Strict => "strict".to_string(),
+    }
+}
+
+fn parse_hash_fields(value: &str) -> Result<MultipathHashFields, String> {
+    match value {
+        "default" => Ok(MultipathHashFields::Default),
+        "5-tuple" => Ok(MultipathHashFields::FiveTuple),
+        "3-tuple" => Ok(MultipathHashFields::ThreeTuple),
+        _ => Err(format!("unknown hash fields '{value}', expected default, 5-tuple or 3-tuple")),
+    }
+}
+
+fn hash_fields_to_string(fields: MultipathHashFields) -> String {
+    match fields {
+        MultipathHashFields::Default => "default".to_string(),
+        MultipathHashFields::FiveTuple => "5-tuple".to_string(),
+        MultipathHashFields::ThreeTuple => "3-tuple".to_string(),
+    }
+}
+
 fn print_table<I, T>(entries: I)
 where
     I: IntoIterator<Item = T>,
@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"cmp"
 	"fmt"
+	"maps"
 	"net"
 	"net/netip"
+	"slices"
 
-	"github.com/yanet-platform/yanet2/common/go/bitset"
 	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
 	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
@@ -28,8 +29,15 @@ var _ ModuleHandle = (*croute.ModuleConfig)(nil)
 // module.
 type Backend interface {
 	// UpdateModule builds a fresh ModuleConfig from the supplied FIB
-	// entries and publishes it to the dataplane atomically.
-	UpdateModule(name string, entries []*routepb.FIBEntry) (ModuleHandle, error)
+	// entries, uRPF, neighbour proxy and multipath hash settings and
+	// publishes it to the dataplane atomically.
+	UpdateModule(
+		name string,
+		entries []*routepb.FIBEntry,
+		urpf []*routepb.URPFInterface,
+		proxy []*routepb.NeighbourProxyInterface,
+		hash *routepb.MultipathHash,
+	) (ModuleHandle, error)
 	// DeleteModule removes a module config from the dataplane.
 	DeleteModule(name string) error
 }
@@ -46,18 +54,27 @@ func NewBackend(agent *ffi.Agent) Backend {
 	}
 }
 
-func (m *backend) UpdateModule(name string, entries []*routepb.FIBEntry) (ModuleHandle, error) {
+func (m *backend) UpdateModule(
+	name string,
+	entries []*routepb.FIBEntry,
+	urpf []*routepb.URPFInterface,
+	proxy []*routepb.NeighbourProxyInterface,
+	hash *routepb.MultipathHash,
+) (ModuleHandle, error) {
 	module, err := croute.NewModuleConfig(m.agent, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create module config: %w", err)
 	}
 
-	// Defensively dedup hardware routes per-prefix using TinyBitset:
-	// the operator already feeds deduplicated entries, but the wire
-	// format encodes a list-of-nexthops per prefix and we keep the
-	// route module robust to mistakes upstream.
+	// Defensively dedup hardware routes per-prefix: the operator already
+	// feeds deduplicated entries, but the wire format encodes a
+	// list-of-nexthops per prefix and we keep the route module robust to
+	// mistakes upstream. Duplicates keep the largest of their weights.
 	hardwareIndex := map[HardwareRoute]uint32{}
-	routeListIndex := map[bitset.TinyBitset]uint32{}
+	routeListIndex := map[string]uint32{}
+	// All blackhole prefixes share a single empty route list, created on
+	// first use.
+	blackholeListIdx := -1
 
 	for _, entry := range entries {
 		prefix, err := netip.ParsePrefix(entry.GetPrefix())
@@ -66,7 +83,23 @@ func (m *backend) UpdateModule(name string, entries []*routepb.FIBEntry) (Module
 			return nil, fmt.Errorf("failed to parse prefix %q: %w", entry.GetPrefix(), err)
 		}
 
-		key := bitset.TinyBitset{}
+		if entry.GetBlackhole() {
+			if blackholeListIdx < 0 {
+				blackholeListIdx, err = module.AddBlackholeRouteList()
+				if err != nil {
+					module.Free()
+					return nil, fmt.Errorf("failed to add blackhole route list: %w", err)
+				}
+			}
+
+			if err := module.AddPrefix(prefix, uint32(blackholeListIdx)); err != nil {
+				module.Free()
+				return nil, fmt.Errorf("failed to add blackhole prefix %q: %w", prefix, err)
+			}
+			continue
+		}
+
+		weights := map[uint32]uint32{}
 		for _, nh := range entry.GetNexthops() {
 			hardwareRoute, err := newHardwareRoute(nh)
 			if err != nil {
@@ -84,16 +117,18 @@ func (m *backend) UpdateModule(name string, entries []*routepb.FIBEntry) (Module
 				idx = uint32(added)
 				hardwareIndex[hardwareRoute] = idx
 			}
-			key.Insert(idx)
+			weights[idx] = max(weights[idx], nh.GetWeight(), 1)
 		}
 
-		if key.Count() == 0 {
+		if len(weights) == 0 {
 			continue
 		}
 
+		indices := routeListIndices(weights)
+		key := fmt.Sprint(indices)
 		listIdx, ok := routeListIndex[key]
 		if !ok {
-			added, err := module.AddRouteList(key.AsSlice())
+			added, err := module.AddRouteList(indices)
 			if err != nil {
 				module.Free()
 				return nil, fmt.Errorf("failed to add route list: %w", err)
@@ -108,6 +143,39 @@ func (m *backend) UpdateModule(name string, entries []*routepb.FIBEntry) (Module
 		}
 	}
 
+	for _, setting := range urpf {
+		if err := module.SetURPF(setting.GetDevice(), croute.URPFMode(setting.GetMode())); err != nil {
+			module.Free()
+			return nil, fmt.Errorf("failed to set uRPF on %q: %w", setting.GetDevice(), err)
+		}
+	}
+
+	for _, setting := range proxy {
+		flags := croute.NeighProxyFlags(0)
+		if setting.GetArp() {
+			flags |= croute.NeighProxyARP
+		}
+		if setting.GetNdp() {
+			flags |= croute.NeighProxyNDP
+		}
+
+		var mac net.HardwareAddr
+		if setting.GetMac() != nil {
+			eui48 := setting.GetMac().EUI48()
+			mac = eui48[:]
+		}
+
+		if err := module.SetNeighProxy(setting.GetDevice(), flags, mac); err != nil {
+			module.Free()
+			return nil, fmt.Errorf("failed to set neighbour proxy on %q: %w", setting.GetDevice(), err)
+		}
+	}
+
+	if err := module.SetHash(hashFlags(hash)); err != nil {
+		module.Free()
+		return nil, fmt.Errorf("failed to set multipath hash: %w", err)
+	}
+
 	if err := m.agent.UpdateModules([]ffi.ModuleConfig{module.AsFFIModule()}); err != nil {
 		module.Free()
 		return nil, fmt.Errorf("failed to update modules: %w", err)
@@ -120,6 +188,67 @@ func (m *backend) DeleteModule(name string) error {
 	return m.agent.DeleteModuleConfig(name)
 }
 
+// maxRouteListSize bounds the number of slots of a weighted ECMP group.
+const maxRouteListSize = 256
+
+// routeListIndices lays out the route indices of an ECMP group for the
+// dataplane, which picks a slot of the list by the flow hash.
+//
+// Each route takes a number of slots proportional to its weight, reduced
+// by the greatest common divisor of the weights, so equally weighted
+// routes take one slot each. Groups that would exceed maxRouteListSize
+// slots are scaled down, keeping at least one slot per route.
+func routeListIndices(weights map[uint32]uint32) []uint32 {
+	divisor := uint32(0)
+	total := uint64(0)
+	for _, weight := range weights {
+		divisor = gcd(divisor, weight)
+		total += uint64(weight)
+	}
+	total /= uint64(divisor)
+
+	indices := []uint32{}
+	for _, idx := range slices.Sorted(maps.Keys(weights)) {
+		slots := uint64(weights[idx] / divisor)
+		if total > maxRouteListSize {
+			slots = max(slots*maxRouteListSize/total, 1)
+		}
+		for range slots {
+			indices = append(indices, idx)
+		}
+	}
+
+	return indices
+}
+
+func gcd(a uint32, b uint32) uint32 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// hashFlags converts a multipath hash setting into the dataplane flags.
+func hashFlags(hash *routepb.MultipathHash) croute.HashFlags {
+	flags := croute.HashFlags(0)
+	switch hash.GetFields() {
+	case routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_FIVE_TUPLE:
+		flags = croute.HashCustom | croute.HashPorts
+	case routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_THREE_TUPLE:
+		flags = croute.HashCustom
+	default:
+		return 0
+	}
+	if hash.GetFlowLabel() {
+		flags |= croute.HashFlowLabel
+	}
+	if hash.GetSymmetric() {
+		flags |= croute.HashSymmetric
+	}
+
+	return flags
+}
+
 // HardwareRoute represents a route in the Layer 2 (L2) networking stack.
 type HardwareRoute struct {
 	// SourceMAC is the MAC address of the local interface that observed
@@ -0,0 +1,18 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteListIndices(t *testing.T) {
+	require.Equal(t, []uint32{1, 4}, routeListIndices(map[uint32]uint32{4: 1, 1: 1}))
+	require.Equal(t, []uint32{1, 4}, routeListIndices(map[uint32]uint32{4: 5, 1: 5}))
+	require.Equal(t, []uint32{0, 0, 0, 2}, routeListIndices(map[uint32]uint32{0: 30, 2: 10}))
+
+	// Oversized groups are scaled down, the lightest route keeping a slot.
+	indices := routeListIndices(map[uint32]uint32{0: 1000, 1: 1})
+	require.Len(t, indices, maxRouteListSize)
+	require.Equal(t, uint32(1), indices[len(indices)-1])
+}
@@ -0,0 +1,319 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// blacklist maps the blacklisted prefixes, masked, to their entries.
+type blacklist map[netip.Prefix]*routepb.BlacklistEntry
+
+// newBlacklist indexes blacklist entries by prefix, a later entry of the
+// same prefix replacing an earlier one.
+//
+// The entries are returned with their prefix in the masked form.
+func newBlacklist(entries []*routepb.BlacklistEntry) (blacklist, error) {
+	result := blacklist{}
+	if err := result.add(entries); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m blacklist) add(entries []*routepb.BlacklistEntry) error {
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return fmt.Errorf("invalid blacklisted prefix %q: %w", entry.GetPrefix(), err)
+		}
+		prefix = prefix.Masked()
+
+		m[prefix] = &routepb.BlacklistEntry{
+			Prefix:   prefix.String(),
+			OrLonger: entry.GetOrLonger(),
+			Comment:  entry.GetComment(),
+		}
+	}
+	return nil
+}
+
+// blocks reports whether a prefix is blacklisted, either itself or as a
+// more-specific of an or-longer entry.
+func (m blacklist) blocks(prefix netip.Prefix) bool {
+	if len(m) == 0 {
+		return false
+	}
+
+	prefix = prefix.Masked()
+	if _, ok := m[prefix]; ok {
+		return true
+	}
+	for bits := prefix.Bits() - 1; bits >= 0; bits-- {
+		if entry, ok := m[netip.PrefixFrom(prefix.Addr(), bits).Masked()]; ok && entry.GetOrLonger() {
+			return true
+		}
+	}
+	return false
+}
+
+// entries returns the blacklist entries ordered by prefix.
+func (m blacklist) entries() []*routepb.BlacklistEntry {
+	entries := make([]*routepb.BlacklistEntry, 0, len(m))
+	for _, prefix := range slices.SortedFunc(maps.Keys(m), xnetip.PrefixCompare) {
+		entries = append(entries, m[prefix])
+	}
+	return entries
+}
+
+// blacklistEntries returns the entries without the blacklisted ones, along
+// with the number of entries removed.
+//
+// The entries are copied only if the blacklist applies to them. Entries
+// with a malformed prefix are kept for the backend to reject.
+func blacklistEntries(entries []*routepb.FIBEntry, blacklisted blacklist) ([]*routepb.FIBEntry, uint64) {
+	if len(blacklisted) == 0 {
+		return entries, 0
+	}
+
+	blocked := func(entry *routepb.FIBEntry) bool {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		return err == nil && blacklisted.blocks(prefix)
+	}
+	if !slices.ContainsFunc(entries, blocked) {
+		return entries, 0
+	}
+
+	result := slices.DeleteFunc(slices.Clone(entries), blocked)
+	return result, uint64(len(entries) - len(result))
+}
+
+// blacklistFile is the content of the blacklist file, kept in YAML so that
+// it can be reviewed and edited by hand while the module is down.
+type blacklistFile struct {
+	Entries []blacklistFileEntry `yaml:"entries"`
+}
+
+type blacklistFileEntry struct {
+	Prefix   string `yaml:"prefix"`
+	OrLonger bool   `yaml:"or_longer,omitempty"`
+	Comment  string `yaml:"comment,omitempty"`
+}
+
+// BlacklistStore persists the blacklist to a file.
+//
+// A nil BlacklistStore persists nothing, leaving the blacklist in memory
+// only.
+type BlacklistStore struct {
+	path string
+}
+
+// NewBlacklistStore returns the store of the given blacklist file, nil for
+// an empty path.
+func NewBlacklistStore(path string) *BlacklistStore {
+	if path == "" {
+		return nil
+	}
+	return &BlacklistStore{path: path}
+}
+
+// Load returns the persisted blacklist entries, none if the file does not
+// exist yet.
+func (m *BlacklistStore) Load() ([]*routepb.BlacklistEntry, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(m.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blacklist file %q: %w", m.path, err)
+	}
+
+	content := blacklistFile{}
+	if err := yaml.Unmarshal(buf, &content); err != nil {
+		return nil, fmt.Errorf("failed to decode blacklist file %q: %w", m.path, err)
+	}
+
+	entries := make([]*routepb.BlacklistEntry, 0, len(content.Entries))
+	for _, entry := range content.Entries {
+		entries = append(entries, &routepb.BlacklistEntry{
+			Prefix:   entry.Prefix,
+			OrLonger: entry.OrLonger,
+			Comment:  entry.Comment,
+		})
+	}
+	return entries, nil
+}
+
+// Save replaces the persisted blacklist.
+//
+// The file is replaced atomically, so a crash leaves either the old or the
+// new blacklist behind.
+func (m *BlacklistStore) Save(entries []*routepb.BlacklistEntry) error {
+	if m == nil {
+		return nil
+	}
+
+	content := blacklistFile{Entries: make([]blacklistFileEntry, 0, len(entries))}
+	for _, entry := range entries {
+		content.Entries = append(content.Entries, blacklistFileEntry{
+			Prefix:   entry.GetPrefix(),
+			OrLonger: entry.GetOrLonger(),
+			Comment:  entry.GetComment(),
+		})
+	}
+
+	buf, err := yaml.Marshal(&content)
+	if err != nil {
+		return fmt.Errorf("failed to encode blacklist: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), ".blacklist-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blacklist file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write blacklist file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync blacklist file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close blacklist file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to replace blacklist file %q: %w", m.path, err)
+	}
+
+	return nil
+}
+
+// AddBlacklist blacklists prefixes in every route configuration.
+func (m *RouteService) AddBlacklist(
+	ctx context.Context,
+	req *routepb.AddBlacklistRequest,
+) (*routepb.AddBlacklistResponse, error) {
+	if len(req.GetEntries()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "blacklist entries are required")
+	}
+	added, err := newBlacklist(req.GetEntries())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	blacklisted := maps.Clone(m.blacklist)
+	maps.Copy(blacklisted, added)
+	if err := m.setBlacklist(blacklisted); err != nil {
+		return nil, err
+	}
+
+	response := &routepb.AddBlacklistResponse{}
+	for _, name := range slices.Sorted(maps.Keys(m.fibs)) {
+		_, blocked := blacklistEntries(m.fibs[name], added)
+		response.BlockedRoutes += blocked
+	}
+
+	m.log.Info("blacklisted prefixes",
+		zap.Int("entries", len(added)),
+		zap.Uint64("blocked_routes", response.BlockedRoutes),
+	)
+
+	return response, nil
+}
+
+// RemoveBlacklist removes prefixes from the blacklist.
+func (m *RouteService) RemoveBlacklist(
+	ctx context.Context,
+	req *routepb.RemoveBlacklistRequest,
+) (*routepb.RemoveBlacklistResponse, error) {
+	if len(req.GetPrefixes()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "blacklisted prefixes are required")
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	blacklisted := maps.Clone(m.blacklist)
+	for _, value := range req.GetPrefixes() {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid blacklisted prefix %q: %v", value, err)
+		}
+		prefix = prefix.Masked()
+		if _, ok := blacklisted[prefix]; !ok {
+			return nil, status.Errorf(codes.NotFound, "prefix %s is not blacklisted", prefix)
+		}
+		delete(blacklisted, prefix)
+	}
+	if err := m.setBlacklist(blacklisted); err != nil {
+		return nil, err
+	}
+
+	m.log.Info("removed blacklisted prefixes",
+		zap.Strings("prefixes", req.GetPrefixes()),
+	)
+
+	return &routepb.RemoveBlacklistResponse{}, nil
+}
+
+// ShowBlacklist lists the blacklisted prefixes.
+func (m *RouteService) ShowBlacklist(
+	ctx context.Context,
+	req *routepb.ShowBlacklistRequest,
+) (*routepb.ShowBlacklistResponse, error) {
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	return &routepb.ShowBlacklistResponse{Entries: m.blacklist.entries()}, nil
+}
+
+// setBlacklist persists a new blacklist, then rebuilds every module config
+// with it.
+//
+// The blacklist is persisted first, so that a blacklisted prefix is never
+// installed again after a restart once it has been withdrawn. A config
+// failing to rebuild keeps its previous module config and picks the
+// blacklist up on its next update.
+//
+// The caller must hold shmLock for writing.
+func (m *RouteService) setBlacklist(blacklisted blacklist) error {
+	if err := m.blacklistStore.Save(blacklisted.entries()); err != nil {
+		return status.Errorf(codes.Internal, "failed to persist blacklist: %v", err)
+	}
+	m.blacklist = blacklisted
+
+	for _, name := range slices.Sorted(maps.Keys(m.configs)) {
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], m.proxy[name], m.hashes[name]); err != nil {
+			m.log.Warn("failed to rebuild module config with updated blacklist",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
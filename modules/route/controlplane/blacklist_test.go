@@ -0,0 +1,92 @@
+package route
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestBlacklist(t *testing.T) {
+	store := NewBlacklistStore(filepath.Join(t.TempDir(), "blacklist.yaml"))
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend, WithRouteServiceBlacklist(store, nil))
+
+	entries := []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "192.0.2.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "192.0.2.128/25", Blackhole: true},
+	}
+	for _, name := range []string{"route0", "route1"} {
+		_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{ModuleName: name, Entries: entries})
+		require.NoError(t, err)
+	}
+
+	response, err := svc.AddBlacklist(t.Context(), &routepb.AddBlacklistRequest{
+		Entries: []*routepb.BlacklistEntry{
+			{Prefix: "10.0.1.1/24"},
+			{Prefix: "192.0.2.0/23", OrLonger: true, Comment: "hijacked"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), response.GetBlockedRoutes())
+	for _, name := range []string{"route0", "route1"} {
+		require.Len(t, backend.installed[name], 1)
+		require.Equal(t, "10.0.0.0/24", backend.installed[name][0].GetPrefix())
+	}
+
+	// The blacklist survives FIB updates, leaving the requested FIB as is.
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{ModuleName: "route0", Entries: entries})
+	require.NoError(t, err)
+	require.Len(t, backend.installed["route0"], 1)
+	require.Len(t, svc.fibs["route0"], 4)
+
+	shown, err := svc.ShowBlacklist(t.Context(), &routepb.ShowBlacklistRequest{})
+	require.NoError(t, err)
+	require.Len(t, shown.GetEntries(), 2)
+	require.Equal(t, "10.0.1.0/24", shown.GetEntries()[0].GetPrefix())
+	require.Equal(t, "192.0.2.0/23", shown.GetEntries()[1].GetPrefix())
+	require.Equal(t, "hijacked", shown.GetEntries()[1].GetComment())
+
+	// A restarted service restores the persisted blacklist.
+	persisted, err := store.Load()
+	require.NoError(t, err)
+	restored := NewRouteService(&installedBackend{installed: map[string][]*routepb.FIBEntry{}}, WithRouteServiceBlacklist(store, persisted))
+	require.True(t, restored.blacklist.blocks(netip.MustParsePrefix("192.0.2.0/26")))
+
+	_, err = svc.RemoveBlacklist(t.Context(), &routepb.RemoveBlacklistRequest{Prefixes: []string{"10.0.1.0/24"}})
+	require.NoError(t, err)
+	require.Len(t, backend.installed["route1"], 2)
+
+	_, err = svc.RemoveBlacklist(t.Context(), &routepb.RemoveBlacklistRequest{Prefixes: []string{"10.0.1.0/24"}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.AddBlacklist(t.Context(), &routepb.AddBlacklistRequest{
+		Entries: []*routepb.BlacklistEntry{{Prefix: "10.0.0.0"}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	persisted, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, persisted, 1)
+}
+
+func TestBlacklistBlocks(t *testing.T) {
+	blacklisted, err := newBlacklist([]*routepb.BlacklistEntry{
+		{Prefix: "10.0.0.0/8"},
+		{Prefix: "2001:db8::/32", OrLonger: true},
+	})
+	require.NoError(t, err)
+
+	require.True(t, blacklisted.blocks(netip.MustParsePrefix("10.0.0.0/8")))
+	require.False(t, blacklisted.blocks(netip.MustParsePrefix("10.0.0.0/16")))
+	require.False(t, blacklisted.blocks(netip.MustParsePrefix("0.0.0.0/0")))
+	require.True(t, blacklisted.blocks(netip.MustParsePrefix("2001:db8:1::/48")))
+	require.True(t, blacklisted.blocks(netip.MustParsePrefix("2001:db8::/32")))
+	require.False(t, blacklisted.blocks(netip.MustParsePrefix("2001:db8::/31")))
+}
@@ -0,0 +1,126 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/bitset"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// MemoryUsageReader is implemented by module handles that report the
+// shared memory they hold.
+type MemoryUsageReader interface {
+	MemoryUsage() uint64
+}
+
+// GetCapacity counts the applied FIB of a route configuration the way the
+// backend lays it out in shared memory and projects how many prefixes the
+// capacity fits at the current memory cost per prefix.
+//
+// The projection is an estimate: nexthop groups are shared between
+// prefixes, so the cost per prefix drops as the FIB grows.
+func (m *RouteService) GetCapacity(
+	ctx context.Context,
+	req *routepb.GetCapacityRequest,
+) (*routepb.GetCapacityResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	entries, ok := m.fibs[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no FIB applied to config %q", name)
+	}
+
+	response, err := countFIB(entries)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count applied FIB: %v", err)
+	}
+
+	if reader, ok := m.configs[name].(MemoryUsageReader); ok {
+		response.MemoryBytes = reader.MemoryUsage()
+	}
+	response.CapacityBytes = uint64(m.capacity)
+
+	if response.CapacityBytes == 0 {
+		return response, nil
+	}
+	response.Utilization = float64(response.MemoryBytes) / float64(response.CapacityBytes)
+
+	prefixes := response.Ipv4Prefixes + response.Ipv6Prefixes
+	if prefixes == 0 || response.MemoryBytes == 0 {
+		return response, nil
+	}
+	response.MaxPrefixes = uint64(float64(response.CapacityBytes) / (float64(response.MemoryBytes) / float64(prefixes)))
+	if response.MaxPrefixes > prefixes {
+		response.HeadroomPrefixes = response.MaxPrefixes - prefixes
+	}
+
+	return response, nil
+}
+
+// countFIB counts the prefixes, nexthop groups and nexthops of the FIB,
+// deduplicated as the backend does.
+func countFIB(entries []*routepb.FIBEntry) (*routepb.GetCapacityResponse, error) {
+	response := &routepb.GetCapacityResponse{}
+	nexthops := map[HardwareRoute]uint32{}
+	groups := map[bitset.TinyBitset]struct{}{}
+	blackhole := false
+
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return nil, err
+		}
+		if entry.GetBlackhole() {
+			response.BlackholePrefixes++
+			countPrefix(response, prefix)
+			blackhole = true
+			continue
+		}
+
+		key := bitset.TinyBitset{}
+		for _, nh := range entry.GetNexthops() {
+			route, err := newHardwareRoute(nh)
+			if err != nil {
+				return nil, err
+			}
+			idx, ok := nexthops[route]
+			if !ok {
+				idx = uint32(len(nexthops))
+				nexthops[route] = idx
+			}
+			key.Insert(idx)
+		}
+		if key.Count() == 0 {
+			continue
+		}
+		groups[key] = struct{}{}
+		countPrefix(response, prefix)
+	}
+
+	response.NexthopGroups = uint64(len(groups))
+	// All blackhole prefixes share a single empty route list.
+	if blackhole {
+		response.NexthopGroups++
+	}
+	response.Nexthops = uint64(len(nexthops))
+
+	return response, nil
+}
+
+func countPrefix(response *routepb.GetCapacityResponse, prefix netip.Prefix) {
+	if prefix.Addr().Is4() {
+		response.Ipv4Prefixes++
+	} else {
+		response.Ipv6Prefixes++
+	}
+}
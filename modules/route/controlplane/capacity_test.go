@@ -0,0 +1,170 @@
+package route
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// memoryModuleHandle holds 1KB of shared memory per FIB entry.
+type memoryModuleHandle struct {
+	usage uint64
+}
+
+func (m *memoryModuleHandle) DumpFIB() ([]croute.FIBEntry, error) {
+	return nil, nil
+}
+
+func (m *memoryModuleHandle) Free() {}
+
+func (m *memoryModuleHandle) MemoryUsage() uint64 {
+	return m.usage
+}
+
+type memoryBackend struct{}
+
+func (m *memoryBackend) UpdateModule(
+	name string,
+	entries []*routepb.FIBEntry,
+	urpf []*routepb.URPFInterface,
+	proxy []*routepb.NeighbourProxyInterface,
+	hash *routepb.MultipathHash,
+) (ModuleHandle, error) {
+	return &memoryModuleHandle{usage: uint64(len(entries)) * uint64(datasize.KB)}, nil
+}
+
+func (m *memoryBackend) DeleteModule(name string) error {
+	return nil
+}
+
+func TestGetCapacity(t *testing.T) {
+	svc := NewRouteService(&memoryBackend{}, WithRouteServiceCapacity(10*datasize.KB))
+
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port1", 2)}},
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2), testNexthop("port0", 1)}},
+			{Prefix: "10.0.2.0/24", Blackhole: true},
+			{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		},
+	})
+	require.NoError(t, err)
+
+	response, err := svc.GetCapacity(t.Context(), &routepb.GetCapacityRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), response.GetIpv4Prefixes())
+	require.Equal(t, uint64(1), response.GetIpv6Prefixes())
+	require.Equal(t, uint64(1), response.GetBlackholePrefixes())
+	// The reordered ECMP group is shared, plus the single nexthop group
+	// and the blackhole one.
+	require.Equal(t, uint64(3), response.GetNexthopGroups())
+	require.Equal(t, uint64(2), response.GetNexthops())
+	require.Equal(t, uint64(4*datasize.KB), response.GetMemoryBytes())
+	require.Equal(t, uint64(10*datasize.KB), response.GetCapacityBytes())
+	require.Equal(t, 0.4, response.GetUtilization())
+	require.Equal(t, uint64(10), response.GetMaxPrefixes())
+	require.Equal(t, uint64(6), response.GetHeadroomPrefixes())
+
+	_, err = svc.GetCapacity(t.Context(), &routepb.GetCapacityRequest{Name: "route1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.GetCapacity(t.Context(), &routepb.GetCapacityRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Without a known capacity only the FIB size is reported.
+	svc = NewRouteService(&memoryBackend{})
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries:    []*routepb.FIBEntry{{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}}},
+	})
+	require.NoError(t, err)
+	response, err = svc.GetCapacity(t.Context(), &routepb.GetCapacityRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.GetIpv4Prefixes())
+	require.Zero(t, response.GetMaxPrefixes())
+}
+
+func TestUpdateFIB_Generation(t *testing.T) {
+	svc := NewRouteService(&memoryBackend{})
+	request := &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries:    []*routepb.FIBEntry{{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}}},
+	}
+
+	response, err := svc.UpdateFIB(t.Context(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.GetGeneration())
+
+	response, err = svc.UpdateFIB(t.Context(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), response.GetGeneration())
+
+	// A recreated config continues from the generation it was deleted at.
+	_, err = svc.DeleteConfig(t.Context(), &routepb.DeleteConfigRequest{Name: "route0"})
+	require.NoError(t, err)
+	response, err = svc.UpdateFIB(t.Context(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), response.GetGeneration())
+}
+
+// quotaBackend refuses the module configs holding more memory than its
+// quota, 1KB per FIB entry and per uRPF interface, as the agent does.
+type quotaBackend struct {
+	memoryBackend
+	quota datasize.ByteSize
+}
+
+func (m *quotaBackend) UpdateModule(
+	name string,
+	entries []*routepb.FIBEntry,
+	urpf []*routepb.URPFInterface,
+	proxy []*routepb.NeighbourProxyInterface,
+	hash *routepb.MultipathHash,
+) (ModuleHandle, error) {
+	usage := datasize.ByteSize(len(entries)+len(urpf)) * datasize.KB
+	if usage > m.quota {
+		err := &ffi.QuotaExceededError{Module: "route:" + name, Requested: usage, Quota: m.quota}
+		return nil, fmt.Errorf("failed to update modules: %w", err)
+	}
+	return m.memoryBackend.UpdateModule(name, entries, urpf, proxy, hash)
+}
+
+// TestUpdateModule_QuotaExceeded verifies that a config refused for its
+// memory quota is reported as ResourceExhausted and keeps the applied one.
+func TestUpdateModule_QuotaExceeded(t *testing.T) {
+	svc := NewRouteService(&quotaBackend{quota: 2 * datasize.KB})
+
+	entries := []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+	}
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{ModuleName: "route0", Entries: entries})
+	require.NoError(t, err)
+
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries:    append(entries, &routepb.FIBEntry{Prefix: "10.0.2.0/24", Blackhole: true}),
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = svc.SetURPF(t.Context(), &routepb.SetURPFRequest{
+		ModuleName: "route0",
+		Interfaces: []*routepb.URPFInterface{{Device: "port0", Mode: routepb.URPFMode_URPF_MODE_STRICT}},
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	urpf, err := svc.ShowURPF(t.Context(), &routepb.ShowURPFRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Empty(t, urpf.GetInterfaces())
+	capacity, err := svc.GetCapacity(t.Context(), &routepb.GetCapacityRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), capacity.GetIpv4Prefixes())
+}
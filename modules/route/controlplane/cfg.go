@@ -15,8 +15,26 @@ type Config struct {
 	// MemoryRequirements is the amount of memory that is required for a
 	// single transaction.
 	MemoryRequirements xcfg.NonZero[datasize.ByteSize] `yaml:"memory_requirements"`
+	// MemoryQuota limits the memory a single module configuration may hold.
+	//
+	// Zero disables the quota.
+	MemoryQuota datasize.ByteSize `yaml:"memory_quota"`
 	// Endpoint is the gRPC endpoint of the route module shim.
 	Endpoint xcfg.NonEmptyString `yaml:"endpoint"`
+	// Interceptors lists the interceptors wrapping every RouteService call,
+	// outermost first.
+	//
+	// Besides the built-in ones, see Interceptor, a deployment may list the
+	// interceptors it registers with WithInterceptor, such as an auth check.
+	Interceptors []Interceptor `yaml:"interceptors"`
+	// PrefixConflicts configures the handling of a prefix installed with
+	// different forwarding by several module configs.
+	PrefixConflicts PrefixConflictConfig `yaml:"prefix_conflicts"`
+	// BlacklistPath is the file the blacklist is persisted to, restored on
+	// start.
+	//
+	// Empty keeps the blacklist in memory only, losing it on restart.
+	BlacklistPath string `yaml:"blacklist_path"`
 }
 
 // DefaultConfig returns a Config populated with sensible defaults.
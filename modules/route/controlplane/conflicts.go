@@ -0,0 +1,334 @@
+package route
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// PrefixConflictPolicy selects how a prefix installed with different
+// forwarding by several module configs of the instance is handled.
+//
+// Module configs installing a prefix with the same forwarding, such as an
+// anycast prefix, never conflict.
+type PrefixConflictPolicy string
+
+const (
+	// PrefixConflictReport installs every FIB as requested and only
+	// reports the conflicts.
+	PrefixConflictReport PrefixConflictPolicy = "report"
+	// PrefixConflictError rejects the UpdateFIB calls introducing a
+	// conflict.
+	PrefixConflictError PrefixConflictPolicy = "error"
+	// PrefixConflictPriority installs the forwarding of the module config
+	// with the highest priority in every conflicting one.
+	PrefixConflictPriority PrefixConflictPolicy = "priority"
+	// PrefixConflictMerge installs the union of the nexthops of the
+	// conflicting module configs as multipath in every one of them.
+	PrefixConflictMerge PrefixConflictPolicy = "merge"
+)
+
+// PrefixConflictConfig configures the handling of the prefixes installed
+// with different forwarding by several module configs.
+type PrefixConflictConfig struct {
+	// Policy defaults to report.
+	Policy PrefixConflictPolicy `yaml:"policy"`
+	// Priorities ranks the module configs for the priority policy, the
+	// highest winning. Unlisted configs rank zero; ties are won by the
+	// config name sorting first.
+	Priorities map[string]int `yaml:"priorities"`
+}
+
+// Validate validates the prefix conflict config.
+func (m *PrefixConflictConfig) Validate() error {
+	switch m.Policy {
+	case "", PrefixConflictReport, PrefixConflictError, PrefixConflictPriority, PrefixConflictMerge:
+		return nil
+	default:
+		return fmt.Errorf("unknown prefix conflict policy %q", m.Policy)
+	}
+}
+
+// policyProto returns the wire form of the policy.
+func (m *PrefixConflictConfig) policyProto() routepb.PrefixConflictPolicy {
+	switch m.Policy {
+	case PrefixConflictError:
+		return routepb.PrefixConflictPolicy_PREFIX_CONFLICT_POLICY_ERROR
+	case PrefixConflictPriority:
+		return routepb.PrefixConflictPolicy_PREFIX_CONFLICT_POLICY_PRIORITY
+	case PrefixConflictMerge:
+		return routepb.PrefixConflictPolicy_PREFIX_CONFLICT_POLICY_MERGE
+	default:
+		return routepb.PrefixConflictPolicy_PREFIX_CONFLICT_POLICY_REPORT
+	}
+}
+
+// rewrites reports whether the policy installs other forwarding than the
+// requested one for the conflicting prefixes.
+func (m *PrefixConflictConfig) rewrites() bool {
+	return m.Policy == PrefixConflictPriority || m.Policy == PrefixConflictMerge
+}
+
+// resolve returns the forwarding installed for a conflicting prefix.
+func (m *PrefixConflictConfig) resolve(conflict prefixConflict) verifyRoute {
+	names := conflict.names()
+
+	if m.Policy == PrefixConflictPriority {
+		winner := slices.MaxFunc(names, func(a, b string) int {
+			// A name sorting first ranks higher on a tie.
+			return cmp.Or(cmp.Compare(m.Priorities[a], m.Priorities[b]), strings.Compare(b, a))
+		})
+		return conflict.routes[winner]
+	}
+
+	resolved := verifyRoute{prefix: conflict.prefix}
+	for _, name := range names {
+		resolved.nexthops = append(resolved.nexthops, conflict.routes[name].nexthops...)
+	}
+	slices.SortFunc(resolved.nexthops, verifyNexthop.compare)
+	resolved.nexthops = slices.Compact(resolved.nexthops)
+	// Traffic is dropped only if every config drops it.
+	resolved.blackhole = len(resolved.nexthops) == 0
+
+	return resolved
+}
+
+// prefixConflict is a prefix installed with different forwarding by
+// several module configs.
+type prefixConflict struct {
+	prefix netip.Prefix
+	// routes are the requested routes by config name.
+	routes map[string]verifyRoute
+}
+
+// names returns the conflicting config names in order.
+func (m prefixConflict) names() []string {
+	return slices.Sorted(maps.Keys(m.routes))
+}
+
+// equal reports whether both routes forward the same way.
+func (m verifyRoute) equal(other verifyRoute) bool {
+	return m.prefix == other.prefix &&
+		m.blackhole == other.blackhole &&
+		(m.blackhole || slices.Equal(m.nexthops, other.nexthops))
+}
+
+// findConflicts returns the conflicts between the normalized FIBs of the
+// module configs the named one is involved in, all of them if the name is
+// empty, ordered by prefix.
+func findConflicts(fibs map[string]map[netip.Prefix]verifyRoute, name string) []prefixConflict {
+	seen := map[netip.Prefix]struct{}{}
+	conflicts := []prefixConflict{}
+
+	for _, config := range slices.Sorted(maps.Keys(fibs)) {
+		if name != "" && config != name {
+			continue
+		}
+
+		for prefix, route := range fibs[config] {
+			if _, ok := seen[prefix]; ok {
+				continue
+			}
+
+			conflict := prefixConflict{
+				prefix: prefix,
+				routes: map[string]verifyRoute{config: route},
+			}
+			differs := false
+			for other, routes := range fibs {
+				if other == config {
+					continue
+				}
+				if otherRoute, ok := routes[prefix]; ok {
+					conflict.routes[other] = otherRoute
+					differs = differs || !otherRoute.equal(route)
+				}
+			}
+			if differs {
+				seen[prefix] = struct{}{}
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	slices.SortFunc(conflicts, func(a, b prefixConflict) int {
+		return cmp.Or(
+			a.prefix.Addr().Compare(b.prefix.Addr()),
+			cmp.Compare(a.prefix.Bits(), b.prefix.Bits()),
+		)
+	})
+
+	return conflicts
+}
+
+// sharesPrefix reports whether the FIBs have a prefix in common.
+func sharesPrefix(a, b map[netip.Prefix]verifyRoute) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for prefix := range a {
+		if _, ok := b[prefix]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveFIB returns the FIB to install for the named config: the
+// requested entries, with the forwarding of the conflicting prefixes
+// replaced as the policy resolves them.
+//
+// The caller must hold shmLock.
+func (m *RouteService) effectiveFIB(name string, entries []*routepb.FIBEntry) []*routepb.FIBEntry {
+	if !m.conflicts.rewrites() {
+		return entries
+	}
+
+	conflicts := findConflicts(m.routes, name)
+	if len(conflicts) == 0 {
+		return entries
+	}
+
+	routes := maps.Clone(m.routes[name])
+	for _, conflict := range conflicts {
+		routes[conflict.prefix] = m.conflicts.resolve(conflict)
+	}
+
+	effective := make([]*routepb.FIBEntry, 0, len(routes))
+	for _, route := range sortedRoutes(routes) {
+		effective = append(effective, route.proto())
+	}
+	return effective
+}
+
+// setRoutes replaces the normalized FIB of the named config, nil routes
+// removing it, and returns the other configs whose installed FIB may
+// change as a result.
+//
+// The normalized FIBs are not kept under the report policy, which never
+// consults them on updates.
+//
+// The caller must hold shmLock.
+func (m *RouteService) setRoutes(name string, routes map[netip.Prefix]verifyRoute) []string {
+	if m.conflicts.Policy == PrefixConflictReport {
+		return nil
+	}
+
+	prev := m.routes[name]
+	if routes == nil {
+		delete(m.routes, name)
+	} else {
+		m.routes[name] = routes
+	}
+	if !m.conflicts.rewrites() {
+		return nil
+	}
+
+	affected := []string{}
+	for _, other := range slices.Sorted(maps.Keys(m.routes)) {
+		if other == name {
+			continue
+		}
+		if sharesPrefix(m.routes[other], prev) || sharesPrefix(m.routes[other], routes) {
+			affected = append(affected, other)
+		}
+	}
+	return affected
+}
+
+// checkConflicts rejects the routes of the named config under the error
+// policy if they conflict with another config.
+//
+// The caller must hold shmLock.
+func (m *RouteService) checkConflicts(name string, routes map[netip.Prefix]verifyRoute) error {
+	if m.conflicts.Policy != PrefixConflictError {
+		return nil
+	}
+
+	fibs := maps.Clone(m.routes)
+	fibs[name] = routes
+	conflicts := findConflicts(fibs, name)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	first := conflicts[0]
+	return status.Errorf(codes.FailedPrecondition,
+		"FIB of %q conflicts with other configs on %d prefixes, first %s installed by %s",
+		name, len(conflicts), first.prefix, strings.Join(first.names(), ", "),
+	)
+}
+
+// republish rebuilds the module configs whose installed FIB may have
+// changed by the resolution of their conflicts.
+//
+// A config failing to rebuild keeps its previous module config, which is
+// logged rather than failing the call that changed another config.
+//
+// The caller must hold shmLock.
+func (m *RouteService) republish(names []string) {
+	for _, name := range names {
+		if _, ok := m.configs[name]; !ok {
+			continue
+		}
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], m.proxy[name], m.hashes[name]); err != nil {
+			m.log.Warn("failed to rebuild module config with resolved prefix conflicts",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// ShowPrefixConflicts lists the prefixes installed with different
+// forwarding by several module configs.
+func (m *RouteService) ShowPrefixConflicts(
+	ctx context.Context,
+	req *routepb.ShowPrefixConflictsRequest,
+) (*routepb.ShowPrefixConflictsResponse, error) {
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	fibs := m.routes
+	if m.conflicts.Policy == PrefixConflictReport {
+		fibs = make(map[string]map[netip.Prefix]verifyRoute, len(m.fibs))
+		for name, entries := range m.fibs {
+			routes, err := normalizeRoutes(entries)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to normalize FIB of %q: %v", name, err)
+			}
+			fibs[name] = routes
+		}
+	}
+
+	response := &routepb.ShowPrefixConflictsResponse{
+		Policy: m.conflicts.policyProto(),
+	}
+	for _, conflict := range findConflicts(fibs, req.GetName()) {
+		entry := &routepb.PrefixConflict{
+			Prefix: conflict.prefix.String(),
+		}
+		for _, name := range conflict.names() {
+			entry.Routes = append(entry.Routes, &routepb.PrefixConflictRoute{
+				Name:  name,
+				Entry: conflict.routes[name].proto(),
+			})
+		}
+		if m.conflicts.rewrites() {
+			entry.Resolved = m.conflicts.resolve(conflict).proto()
+		}
+		response.Conflicts = append(response.Conflicts, entry)
+	}
+
+	return response, nil
+}
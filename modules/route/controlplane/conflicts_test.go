@@ -0,0 +1,176 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// installedBackend records the FIB, the uRPF settings and the multipath
+// hash installed for every module config.
+type installedBackend struct {
+	memoryBackend
+	installed map[string][]*routepb.FIBEntry
+	urpf      map[string][]*routepb.URPFInterface
+	hashes    map[string]*routepb.MultipathHash
+}
+
+func (m *installedBackend) UpdateModule(
+	name string,
+	entries []*routepb.FIBEntry,
+	urpf []*routepb.URPFInterface,
+	proxy []*routepb.NeighbourProxyInterface,
+	hash *routepb.MultipathHash,
+) (ModuleHandle, error) {
+	m.installed[name] = entries
+	if m.urpf != nil {
+		m.urpf[name] = urpf
+	}
+	if m.hashes != nil {
+		m.hashes[name] = hash
+	}
+	return m.memoryBackend.UpdateModule(name, entries, urpf, proxy, hash)
+}
+
+// installedRoute returns the normalized route installed for the prefix.
+func (m *installedBackend) installedRoute(t *testing.T, name string, prefix string) string {
+	routes, err := normalizeRoutes(m.installed[name])
+	require.NoError(t, err)
+
+	for _, route := range routes {
+		if route.prefix.String() == prefix {
+			return route.String()
+		}
+	}
+	return ""
+}
+
+func newConflictTestService(t *testing.T, config PrefixConflictConfig) (*RouteService, *installedBackend) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend, WithRouteServicePrefixConflicts(config))
+
+	for _, fib := range []struct {
+		name    string
+		entries []*routepb.FIBEntry
+	}{
+		{"route0", []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.2.0/24", Blackhole: true},
+		}},
+		{"route1", []*routepb.FIBEntry{
+			// Anycast: the same forwarding as route0.
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+			{Prefix: "10.0.2.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+		}},
+	} {
+		_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{ModuleName: fib.name, Entries: fib.entries})
+		if config.Policy == PrefixConflictError && fib.name == "route1" {
+			// Tested by TestPrefixConflicts_Error.
+			continue
+		}
+		require.NoError(t, err)
+	}
+
+	return svc, backend
+}
+
+func TestPrefixConflictConfig_Validate(t *testing.T) {
+	require.NoError(t, (&PrefixConflictConfig{}).Validate())
+	require.NoError(t, (&PrefixConflictConfig{Policy: PrefixConflictMerge}).Validate())
+	require.Error(t, (&PrefixConflictConfig{Policy: "last-writer-wins"}).Validate())
+}
+
+func TestPrefixConflicts_Report(t *testing.T) {
+	svc, backend := newConflictTestService(t, PrefixConflictConfig{})
+
+	// Both FIBs are installed as requested.
+	require.Equal(t, "10.0.1.0/24 via port0 000000000001 00000000cafe", backend.installedRoute(t, "route0", "10.0.1.0/24"))
+	require.Equal(t, "10.0.1.0/24 via port1 000000000002 00000000cafe", backend.installedRoute(t, "route1", "10.0.1.0/24"))
+
+	response, err := svc.ShowPrefixConflicts(t.Context(), &routepb.ShowPrefixConflictsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, routepb.PrefixConflictPolicy_PREFIX_CONFLICT_POLICY_REPORT, response.GetPolicy())
+	require.Len(t, response.GetConflicts(), 2)
+
+	conflict := response.GetConflicts()[0]
+	require.Equal(t, "10.0.1.0/24", conflict.GetPrefix())
+	require.Len(t, conflict.GetRoutes(), 2)
+	require.Equal(t, "route0", conflict.GetRoutes()[0].GetName())
+	require.Equal(t, "route1", conflict.GetRoutes()[1].GetName())
+	require.Nil(t, conflict.GetResolved())
+	require.Equal(t, "10.0.2.0/24", response.GetConflicts()[1].GetPrefix())
+}
+
+func TestPrefixConflicts_Error(t *testing.T) {
+	svc, backend := newConflictTestService(t, PrefixConflictConfig{Policy: PrefixConflictError})
+
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route1",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+		},
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.NotContains(t, backend.installed, "route1")
+
+	// Anycast routes are accepted.
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route1",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.3.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+		},
+	})
+	require.NoError(t, err)
+
+	response, err := svc.ShowPrefixConflicts(t.Context(), &routepb.ShowPrefixConflictsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, response.GetConflicts())
+}
+
+func TestPrefixConflicts_Priority(t *testing.T) {
+	svc, backend := newConflictTestService(t, PrefixConflictConfig{
+		Policy:     PrefixConflictPriority,
+		Priorities: map[string]int{"route1": 10},
+	})
+
+	// route1 wins in both configs, whichever was updated last.
+	for _, name := range []string{"route0", "route1"} {
+		require.Equal(t, "10.0.1.0/24 via port1 000000000002 00000000cafe", backend.installedRoute(t, name, "10.0.1.0/24"))
+		require.Equal(t, "10.0.2.0/24 via port1 000000000002 00000000cafe", backend.installedRoute(t, name, "10.0.2.0/24"))
+	}
+	// The requested FIB is kept for the verification.
+	require.Equal(t, "10.0.2.0/24", svc.fibs["route0"][2].GetPrefix())
+	require.True(t, svc.fibs["route0"][2].GetBlackhole())
+
+	response, err := svc.ShowPrefixConflicts(t.Context(), &routepb.ShowPrefixConflictsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, response.GetConflicts(), 2)
+	require.Equal(t, "port1", response.GetConflicts()[0].GetResolved().GetNexthops()[0].GetDevice())
+
+	// Deleting the winner restores the forwarding of route0.
+	_, err = svc.DeleteConfig(t.Context(), &routepb.DeleteConfigRequest{Name: "route1"})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.0/24 via port0 000000000001 00000000cafe", backend.installedRoute(t, "route0", "10.0.1.0/24"))
+	require.Equal(t, "10.0.2.0/24 blackhole", backend.installedRoute(t, "route0", "10.0.2.0/24"))
+}
+
+func TestPrefixConflicts_Merge(t *testing.T) {
+	_, backend := newConflictTestService(t, PrefixConflictConfig{Policy: PrefixConflictMerge})
+
+	for _, name := range []string{"route0", "route1"} {
+		require.Equal(t,
+			"10.0.1.0/24 via port0 000000000001 00000000cafe via port1 000000000002 00000000cafe",
+			backend.installedRoute(t, name, "10.0.1.0/24"),
+		)
+		// The blackhole is overridden by the config forwarding the prefix.
+		require.Equal(t, "10.0.2.0/24 via port1 000000000002 00000000cafe", backend.installedRoute(t, name, "10.0.2.0/24"))
+		require.Equal(t, "10.0.0.0/24 via port0 000000000001 00000000cafe", backend.installedRoute(t, name, "10.0.0.0/24"))
+	}
+}
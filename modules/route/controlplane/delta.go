@@ -0,0 +1,102 @@
+package route
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// applyFIBDelta returns the FIB with the entries of a delta put in place of
+// the ones of the same prefixes and the removed prefixes left out.
+//
+// The entries of the FIB keep their order, the added ones follow it in the
+// order of the delta. Prefixes are compared masked, so "10.0.1.1/24" and
+// "10.0.1.0/24" are the same prefix. Removing a prefix missing from the FIB
+// is not an error.
+//
+// A prefix the FIB lists more than once is merged as a single one, see
+// dedupFIB.
+func applyFIBDelta(
+	fib []*routepb.FIBEntry,
+	entries []*routepb.FIBEntry,
+	removed []string,
+) ([]*routepb.FIBEntry, error) {
+	changes := make(map[netip.Prefix]*routepb.FIBEntry, len(entries)+len(removed))
+	for _, p := range removed {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse removed prefix %q: %w", p, err)
+		}
+		changes[prefix.Masked()] = nil
+	}
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prefix %q: %w", entry.GetPrefix(), err)
+		}
+		prefix = prefix.Masked()
+		if change, ok := changes[prefix]; ok && change == nil {
+			return nil, fmt.Errorf("prefix %s is both changed and removed", prefix)
+		}
+		changes[prefix] = entry
+	}
+
+	base, err := dedupFIB(fib)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*routepb.FIBEntry, 0, len(base)+len(entries))
+	for _, entry := range base {
+		change, ok := changes[entry.prefix]
+		if !ok {
+			out = append(out, entry.entry)
+			continue
+		}
+		if change != nil {
+			out = append(out, change)
+		}
+		delete(changes, entry.prefix)
+	}
+	for _, entry := range entries {
+		prefix, _ := netip.ParsePrefix(entry.GetPrefix())
+		if change, ok := changes[prefix.Masked()]; ok && change == entry {
+			out = append(out, entry)
+		}
+	}
+
+	return out, nil
+}
+
+// fibEntry is an entry of an applied FIB along with its masked prefix.
+type fibEntry struct {
+	prefix netip.Prefix
+	entry  *routepb.FIBEntry
+}
+
+// dedupFIB returns the entries of an applied FIB with a single entry per
+// prefix.
+//
+// A full FIB may list a prefix more than once, the last entry wins like it
+// does when the module config is built. It takes the place of the first one
+// to keep the order of the FIB.
+func dedupFIB(fib []*routepb.FIBEntry) ([]fibEntry, error) {
+	out := make([]fibEntry, 0, len(fib))
+	index := make(map[netip.Prefix]int, len(fib))
+	for _, entry := range fib {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse applied prefix %q: %w", entry.GetPrefix(), err)
+		}
+		prefix = prefix.Masked()
+
+		if idx, ok := index[prefix]; ok {
+			out[idx].entry = entry
+			continue
+		}
+		index[prefix] = len(out)
+		out = append(out, fibEntry{prefix: prefix, entry: entry})
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func fibPrefixes(entries []*routepb.FIBEntry) []string {
+	prefixes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		prefixes = append(prefixes, entry.GetPrefix())
+	}
+	return prefixes
+}
+
+func TestApplyFIBDelta(t *testing.T) {
+	fib := []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.1.1/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.2.0/24", Blackhole: true},
+	}
+
+	out, err := applyFIBDelta(fib, []*routepb.FIBEntry{
+		{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+	}, []string{"10.0.1.0/24", "10.0.9.0/24"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/24", "10.0.2.0/24", "2001:db8::/32"}, fibPrefixes(out))
+	require.Equal(t, "port1", out[0].GetNexthops()[0].GetDevice())
+	// The applied FIB is left untouched.
+	require.Len(t, fib, 3)
+	require.Equal(t, "port0", fib[0].GetNexthops()[0].GetDevice())
+
+	_, err = applyFIBDelta(fib, []*routepb.FIBEntry{{Prefix: "10.0.0.0"}}, nil)
+	require.Error(t, err)
+	_, err = applyFIBDelta(fib, nil, []string{"::/129"})
+	require.Error(t, err)
+	_, err = applyFIBDelta(fib, []*routepb.FIBEntry{{Prefix: "10.0.0.0/24"}}, []string{"10.0.0.0/24"})
+	require.Error(t, err)
+}
+
+// TestApplyFIBDelta_DuplicatePrefix verifies that a prefix the applied FIB
+// lists more than once is changed and removed as a whole, its last entry
+// winning otherwise.
+func TestApplyFIBDelta_DuplicatePrefix(t *testing.T) {
+	fib := []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.0.1/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 1)}},
+		{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 1)}},
+		{Prefix: "10.0.2.0/24", Blackhole: true},
+		{Prefix: "10.0.2.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port2", 1)}},
+	}
+
+	out, err := applyFIBDelta(fib, []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port3", 2)}},
+	}, []string{"10.0.1.0/24"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/24", "10.0.2.0/24"}, fibPrefixes(out))
+	require.Equal(t, "port3", out[0].GetNexthops()[0].GetDevice())
+	require.Equal(t, "port2", out[1].GetNexthops()[0].GetDevice())
+}
+
+func TestUpdateFIB_Delta(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	delta := &routepb.UpdateFIBRequest{
+		ModuleName:      "route0",
+		Delta:           true,
+		BaseGeneration:  1,
+		Entries:         []*routepb.FIBEntry{{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}}},
+		RemovedPrefixes: []string{"10.0.0.0/24"},
+	}
+
+	// A delta needs a FIB to apply to.
+	_, err := svc.UpdateFIB(t.Context(), delta)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	response, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.GetGeneration())
+
+	response, err = svc.UpdateFIB(t.Context(), delta)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), response.GetGeneration())
+	require.Equal(t, []string{"2001:db8::/32", "10.0.1.0/24"}, fibPrefixes(backend.installed["route0"]))
+
+	response, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName:     "route0",
+		Delta:          true,
+		BaseGeneration: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), response.GetGeneration(), "not rebuilt")
+
+	// The FIB moved past the base generation.
+	_, err = svc.UpdateFIB(t.Context(), delta)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName:      "route0",
+		Delta:           true,
+		BaseGeneration:  2,
+		RemovedPrefixes: []string{"10.0.1.0"},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
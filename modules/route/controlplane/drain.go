@@ -0,0 +1,230 @@
+package route
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// drainKey identifies a nexthop to drain.
+//
+// The source MAC is left out, as it is the address of the local port
+// rather than of the neighbour under maintenance.
+type drainKey struct {
+	device string
+	dstMAC uint64
+}
+
+func newDrainKey(nh *routepb.FIBNexthop) (drainKey, error) {
+	if nh.GetDevice() == "" {
+		return drainKey{}, fmt.Errorf("nexthop device is required")
+	}
+	if nh.GetDstMac() == nil {
+		return drainKey{}, fmt.Errorf("nexthop destination MAC is required")
+	}
+
+	return drainKey{device: nh.GetDevice(), dstMAC: nh.GetDstMac().GetAddr()}, nil
+}
+
+// String returns the nexthop as "<dst MAC> via <device>".
+func (m drainKey) String() string {
+	mac := (&commonpb.MACAddress{Addr: m.dstMAC}).EUI48()
+	return fmt.Sprintf("%s via %s", net.HardwareAddr(mac[:]), m.device)
+}
+
+func (m drainKey) compare(other drainKey) int {
+	return cmp.Or(
+		strings.Compare(m.device, other.device),
+		cmp.Compare(m.dstMAC, other.dstMAC),
+	)
+}
+
+// drainStats counts the routes a drain affects.
+type drainStats struct {
+	// drained is the number of routes the drained nexthops were removed
+	// from.
+	drained uint64
+	// kept is the number of routes left with drained nexthops only, which
+	// keep them all.
+	kept uint64
+}
+
+// drainEntries returns the entries with the drained nexthops removed from
+// their multipath groups.
+//
+// The entries are copied only if a drain applies to them.
+func drainEntries(entries []*routepb.FIBEntry, drained map[drainKey]struct{}) ([]*routepb.FIBEntry, drainStats) {
+	stats := drainStats{}
+	if len(drained) == 0 {
+		return entries, stats
+	}
+
+	result := entries
+	copied := false
+	for idx, entry := range entries {
+		if entry.GetBlackhole() {
+			continue
+		}
+
+		nexthops := slices.DeleteFunc(slices.Clone(entry.GetNexthops()), func(nh *routepb.FIBNexthop) bool {
+			_, ok := drained[drainKey{device: nh.GetDevice(), dstMAC: nh.GetDstMac().GetAddr()}]
+			return ok
+		})
+		switch {
+		case len(nexthops) == len(entry.GetNexthops()):
+			continue
+		case len(nexthops) == 0:
+			stats.kept++
+			continue
+		}
+
+		if !copied {
+			result = slices.Clone(entries)
+			copied = true
+		}
+		result[idx] = &routepb.FIBEntry{
+			Prefix:   entry.GetPrefix(),
+			Nexthops: nexthops,
+		}
+		stats.drained++
+	}
+
+	return result, stats
+}
+
+// DrainNexthop removes a nexthop from every multipath group of a route
+// configuration.
+func (m *RouteService) DrainNexthop(
+	ctx context.Context,
+	req *routepb.DrainNexthopRequest,
+) (*routepb.DrainNexthopResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	key, err := newDrainKey(req.GetNexthop())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	prev := m.drained[name]
+	drained := maps.Clone(prev)
+	if drained == nil {
+		drained = map[drainKey]struct{}{}
+	}
+	drained[key] = struct{}{}
+
+	m.setDrained(name, drained)
+	// Without an applied FIB there is nothing to rebuild yet; the drain is
+	// picked up by the first UpdateFIB.
+	if _, ok := m.configs[name]; ok {
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], m.proxy[name], m.hashes[name]); err != nil {
+			m.setDrained(name, prev)
+			return nil, ffi.UpdateStatus(err, "failed to drain nexthop for %q", name)
+		}
+	}
+
+	_, stats := drainEntries(m.effectiveFIB(name, m.fibs[name]), map[drainKey]struct{}{key: {}})
+
+	m.log.Info("drained nexthop",
+		zap.String("name", name),
+		zap.Stringer("nexthop", key),
+		zap.Uint64("drained_routes", stats.drained),
+		zap.Uint64("kept_routes", stats.kept),
+	)
+
+	return &routepb.DrainNexthopResponse{
+		DrainedRoutes: stats.drained,
+		KeptRoutes:    stats.kept,
+	}, nil
+}
+
+// UndrainNexthop reinstates a drained nexthop in the multipath groups of a
+// route configuration.
+func (m *RouteService) UndrainNexthop(
+	ctx context.Context,
+	req *routepb.UndrainNexthopRequest,
+) (*routepb.UndrainNexthopResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	key, err := newDrainKey(req.GetNexthop())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	prev := m.drained[name]
+	if _, ok := prev[key]; !ok {
+		return nil, status.Errorf(codes.NotFound, "nexthop %s is not drained in %q", key, name)
+	}
+	drained := maps.Clone(prev)
+	delete(drained, key)
+
+	m.setDrained(name, drained)
+	if _, ok := m.configs[name]; ok {
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], m.proxy[name], m.hashes[name]); err != nil {
+			m.setDrained(name, prev)
+			return nil, ffi.UpdateStatus(err, "failed to undrain nexthop for %q", name)
+		}
+	}
+
+	m.log.Info("undrained nexthop",
+		zap.String("name", name),
+		zap.Stringer("nexthop", key),
+	)
+
+	return &routepb.UndrainNexthopResponse{}, nil
+}
+
+// ShowDrainedNexthops lists the drained nexthops of a route configuration.
+func (m *RouteService) ShowDrainedNexthops(
+	ctx context.Context,
+	req *routepb.ShowDrainedNexthopsRequest,
+) (*routepb.ShowDrainedNexthopsResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	response := &routepb.ShowDrainedNexthopsResponse{}
+	for _, key := range slices.SortedFunc(maps.Keys(m.drained[name]), drainKey.compare) {
+		response.Nexthops = append(response.Nexthops, &routepb.FIBNexthop{
+			DstMac: &commonpb.MACAddress{Addr: key.dstMAC},
+			Device: key.device,
+		})
+	}
+	return response, nil
+}
+
+// setDrained replaces the drained nexthops of a route configuration.
+//
+// The caller must hold shmLock for writing.
+func (m *RouteService) setDrained(name string, drained map[drainKey]struct{}) {
+	if len(drained) == 0 {
+		delete(m.drained, name)
+		return
+	}
+	m.drained[name] = drained
+}
@@ -0,0 +1,78 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestDrainNexthop(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port1", 2)}},
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.2.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2), testNexthop("port2", 3)}},
+			{Prefix: "10.0.3.0/24", Blackhole: true},
+		},
+	})
+	require.NoError(t, err)
+
+	response, err := svc.DrainNexthop(t.Context(), &routepb.DrainNexthopRequest{
+		Name:    "route0",
+		Nexthop: testNexthop("port0", 1),
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.GetDrainedRoutes())
+	// The only nexthop of 10.0.1.0/24 is kept.
+	require.Equal(t, uint64(1), response.GetKeptRoutes())
+	require.Equal(t, "10.0.0.0/24 via port1 000000000002 00000000cafe", backend.installedRoute(t, "route0", "10.0.0.0/24"))
+	require.Equal(t, "10.0.1.0/24 via port0 000000000001 00000000cafe", backend.installedRoute(t, "route0", "10.0.1.0/24"))
+
+	// The drain survives FIB updates.
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.4.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port2", 3)}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.4.0/24 via port2 000000000003 00000000cafe", backend.installedRoute(t, "route0", "10.0.4.0/24"))
+	// The requested FIB is kept for the verification.
+	require.Len(t, svc.fibs["route0"][0].GetNexthops(), 2)
+
+	drained, err := svc.ShowDrainedNexthops(t.Context(), &routepb.ShowDrainedNexthopsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, drained.GetNexthops(), 1)
+	require.Equal(t, "port0", drained.GetNexthops()[0].GetDevice())
+	require.Equal(t, uint64(1), drained.GetNexthops()[0].GetDstMac().GetAddr())
+
+	_, err = svc.UndrainNexthop(t.Context(), &routepb.UndrainNexthopRequest{
+		Name:    "route0",
+		Nexthop: testNexthop("port0", 1),
+	})
+	require.NoError(t, err)
+	require.Equal(t,
+		"10.0.4.0/24 via port0 000000000001 00000000cafe via port2 000000000003 00000000cafe",
+		backend.installedRoute(t, "route0", "10.0.4.0/24"),
+	)
+
+	_, err = svc.UndrainNexthop(t.Context(), &routepb.UndrainNexthopRequest{
+		Name:    "route0",
+		Nexthop: testNexthop("port0", 1),
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = svc.DrainNexthop(t.Context(), &routepb.DrainNexthopRequest{
+		Name:    "route0",
+		Nexthop: &routepb.FIBNexthop{Device: "port0"},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
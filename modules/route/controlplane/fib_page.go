@@ -0,0 +1,153 @@
+package route
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// fibReadMask selects the FIBRangeEntry fields returned by ShowFIB.
+type fibReadMask struct {
+	ipRange bool
+	dstMAC  bool
+	srcMAC  bool
+	device  bool
+	weight  bool
+}
+
+// newFIBReadMask parses the read mask of a ShowFIB request.
+//
+// An empty mask selects every field, and "nexthops" selects every nexthop
+// field.
+func newFIBReadMask(mask *fieldmaskpb.FieldMask) (fibReadMask, error) {
+	paths := mask.GetPaths()
+	if len(paths) == 0 {
+		return fibReadMask{ipRange: true, dstMAC: true, srcMAC: true, device: true, weight: true}, nil
+	}
+
+	m := fibReadMask{}
+	for _, path := range paths {
+		switch path {
+		case "range":
+			m.ipRange = true
+		case "nexthops":
+			m.dstMAC = true
+			m.srcMAC = true
+			m.device = true
+			m.weight = true
+		case "nexthops.dst_mac":
+			m.dstMAC = true
+		case "nexthops.src_mac":
+			m.srcMAC = true
+		case "nexthops.device":
+			m.device = true
+		case "nexthops.weight":
+			m.weight = true
+		default:
+			return fibReadMask{}, fmt.Errorf("unknown read mask path %q", path)
+		}
+	}
+
+	return m, nil
+}
+
+// nexthops reports whether any nexthop field is selected.
+func (m fibReadMask) nexthops() bool {
+	return m.dstMAC || m.srcMAC || m.device || m.weight
+}
+
+// entry converts a FIB entry into its protobuf form, filling only the
+// selected fields.
+func (m fibReadMask) entry(e croute.FIBEntry) (*routepb.FIBRangeEntry, error) {
+	entry := &routepb.FIBRangeEntry{}
+	if m.ipRange {
+		ipRange, err := commonpb.NewIPRange(e.PrefixFrom, e.PrefixTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build IP range from FIB entry: %w", err)
+		}
+		entry.Range = ipRange
+	}
+
+	if m.nexthops() {
+		entry.Nexthops = make([]*routepb.FIBNexthop, len(e.Nexthops))
+		for idx, nh := range e.Nexthops {
+			nexthop := &routepb.FIBNexthop{}
+			if m.dstMAC {
+				nexthop.DstMac = commonpb.NewMACAddressEUI48([6]byte(nh.DstMAC))
+			}
+			if m.srcMAC {
+				nexthop.SrcMac = commonpb.NewMACAddressEUI48([6]byte(nh.SrcMAC))
+			}
+			if m.device {
+				nexthop.Device = nh.Device
+			}
+			if m.weight {
+				nexthop.Weight = nh.Weight
+			}
+			entry.Nexthops[idx] = nexthop
+		}
+	}
+
+	return entry, nil
+}
+
+// fibPage returns the page of entries following the page token, along
+// with the token of the next page.
+//
+// Entries are sorted in place by their first address, which IPv4 entries
+// precede IPv6 ones in. The token is the last returned address, so a page
+// request stays meaningful after the FIB is replaced. A zero size returns
+// all the remaining entries.
+func fibPage(entries []croute.FIBEntry, token string, size uint32) ([]croute.FIBEntry, string, error) {
+	compare := func(e croute.FIBEntry, addr netip.Addr) int {
+		return e.PrefixFrom.Compare(addr)
+	}
+	slices.SortFunc(entries, func(a, b croute.FIBEntry) int {
+		return compare(a, b.PrefixFrom)
+	})
+
+	start := 0
+	if token != "" {
+		after, err := decodeFIBPageToken(token)
+		if err != nil {
+			return nil, "", err
+		}
+		idx, found := slices.BinarySearchFunc(entries, after, compare)
+		if found {
+			idx++
+		}
+		start = idx
+	}
+
+	entries = entries[start:]
+	if size == 0 || len(entries) <= int(size) {
+		return entries, "", nil
+	}
+
+	entries = entries[:size]
+	return entries, encodeFIBPageToken(entries[len(entries)-1].PrefixFrom), nil
+}
+
+func encodeFIBPageToken(addr netip.Addr) string {
+	return base64.RawURLEncoding.EncodeToString(addr.AsSlice())
+}
+
+func decodeFIBPageToken(token string) (netip.Addr, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("malformed page token: %w", err)
+	}
+	addr, ok := netip.AddrFromSlice(data)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("malformed page token: unexpected address length %d", len(data))
+	}
+
+	return addr, nil
+}
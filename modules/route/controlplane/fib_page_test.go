@@ -0,0 +1,132 @@
+package route
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
+)
+
+func testFIBEntry(from string) croute.FIBEntry {
+	addr := netip.MustParseAddr(from)
+	family := uint8(croute.AddressFamilyIPv4)
+	if addr.Is6() {
+		family = croute.AddressFamilyIPv6
+	}
+	return croute.FIBEntry{
+		AddressFamily: family,
+		PrefixFrom:    addr,
+		PrefixTo:      addr,
+		Nexthops: []croute.FIBNexthop{
+			{
+				DstMAC: net.HardwareAddr{0, 0, 0, 0, 0, 1},
+				SrcMAC: net.HardwareAddr{0, 0, 0, 0, 0, 2},
+				Device: "port0",
+			},
+		},
+	}
+}
+
+func fibPageAddrs(entries []croute.FIBEntry) []string {
+	addrs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		addrs = append(addrs, e.PrefixFrom.String())
+	}
+	return addrs
+}
+
+func TestFIBPage(t *testing.T) {
+	newEntries := func() []croute.FIBEntry {
+		return []croute.FIBEntry{
+			testFIBEntry("2001:db8::"),
+			testFIBEntry("10.0.1.0"),
+			testFIBEntry("10.0.0.0"),
+			testFIBEntry("10.0.2.0"),
+		}
+	}
+
+	pages := [][]string{}
+	token := ""
+	for {
+		page, next, err := fibPage(newEntries(), token, 3)
+		require.NoError(t, err)
+		pages = append(pages, fibPageAddrs(page))
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Equal(t, [][]string{
+		{"10.0.0.0", "10.0.1.0", "10.0.2.0"},
+		{"2001:db8::"},
+	}, pages)
+
+	t.Run("unlimited", func(t *testing.T) {
+		page, next, err := fibPage(newEntries(), "", 0)
+		require.NoError(t, err)
+		require.Len(t, page, 4)
+		require.Empty(t, next)
+	})
+
+	t.Run("removed last entry", func(t *testing.T) {
+		// The page resumes after the token address even if the entry it
+		// was taken from is gone.
+		token := encodeFIBPageToken(netip.MustParseAddr("10.0.0.128"))
+		page, _, err := fibPage(newEntries(), token, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"10.0.1.0", "10.0.2.0", "2001:db8::"}, fibPageAddrs(page))
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		_, _, err := fibPage(newEntries(), "!", 0)
+		require.Error(t, err)
+		_, _, err = fibPage(newEntries(), "AAE", 0)
+		require.Error(t, err)
+	})
+}
+
+func TestFIBReadMask(t *testing.T) {
+	entry := testFIBEntry("10.0.0.0")
+
+	t.Run("all", func(t *testing.T) {
+		mask, err := newFIBReadMask(nil)
+		require.NoError(t, err)
+		e, err := mask.entry(entry)
+		require.NoError(t, err)
+		require.NotNil(t, e.Range)
+		require.Len(t, e.Nexthops, 1)
+		require.NotNil(t, e.Nexthops[0].DstMac)
+		require.NotNil(t, e.Nexthops[0].SrcMac)
+		require.Equal(t, "port0", e.Nexthops[0].Device)
+	})
+
+	t.Run("device", func(t *testing.T) {
+		mask, err := newFIBReadMask(&fieldmaskpb.FieldMask{Paths: []string{"range", "nexthops.device"}})
+		require.NoError(t, err)
+		e, err := mask.entry(entry)
+		require.NoError(t, err)
+		require.NotNil(t, e.Range)
+		require.Len(t, e.Nexthops, 1)
+		require.Nil(t, e.Nexthops[0].DstMac)
+		require.Nil(t, e.Nexthops[0].SrcMac)
+		require.Equal(t, "port0", e.Nexthops[0].Device)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		mask, err := newFIBReadMask(&fieldmaskpb.FieldMask{Paths: []string{"range"}})
+		require.NoError(t, err)
+		e, err := mask.entry(entry)
+		require.NoError(t, err)
+		require.NotNil(t, e.Range)
+		require.Nil(t, e.Nexthops)
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		_, err := newFIBReadMask(&fieldmaskpb.FieldMask{Paths: []string{"prefix"}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,81 @@
+package route
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// SetMultipathHash replaces the fields hashed to pick a nexthop of the
+// multipath routes of a route configuration.
+func (m *RouteService) SetMultipathHash(
+	ctx context.Context,
+	req *routepb.SetMultipathHashRequest,
+) (*routepb.SetMultipathHashResponse, error) {
+	name := req.GetModuleName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module_name is required")
+	}
+	hash := req.GetHash()
+	if _, ok := routepb.MultipathHashFields_name[int32(hash.GetFields())]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported multipath hash fields %d", hash.GetFields())
+	}
+	// The default hash is computed by the NIC, which leaves nothing to
+	// tune.
+	if hash.GetFields() == routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_DEFAULT && (hash.GetFlowLabel() || hash.GetSymmetric()) {
+		return nil, status.Error(codes.InvalidArgument, "flow_label and symmetric require explicit hash fields")
+	}
+	if hash.GetFields() == routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_DEFAULT {
+		hash = nil
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	if _, ok := m.configs[name]; ok {
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], m.proxy[name], hash); err != nil {
+			return nil, ffi.UpdateStatus(err, "failed to apply multipath hash for %q", name)
+		}
+	}
+	if hash == nil {
+		delete(m.hashes, name)
+	} else {
+		m.hashes[name] = hash
+	}
+
+	m.log.Info("updated multipath hash settings",
+		zap.String("name", name),
+		zap.Stringer("fields", hash.GetFields()),
+		zap.Bool("flow_label", hash.GetFlowLabel()),
+		zap.Bool("symmetric", hash.GetSymmetric()),
+	)
+
+	return &routepb.SetMultipathHashResponse{}, nil
+}
+
+// ShowMultipathHash returns the multipath hash settings of a route
+// configuration.
+func (m *RouteService) ShowMultipathHash(
+	ctx context.Context,
+	req *routepb.ShowMultipathHashRequest,
+) (*routepb.ShowMultipathHashResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	hash, ok := m.hashes[name]
+	if !ok {
+		hash = &routepb.MultipathHash{}
+	}
+
+	return &routepb.ShowMultipathHashResponse{Hash: hash}, nil
+}
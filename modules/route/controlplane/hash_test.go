@@ -0,0 +1,83 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestSetMultipathHash(t *testing.T) {
+	backend := &installedBackend{
+		installed: map[string][]*routepb.FIBEntry{},
+		hashes:    map[string]*routepb.MultipathHash{},
+	}
+	svc := NewRouteService(backend)
+
+	hash := &routepb.MultipathHash{
+		Fields:    routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_FIVE_TUPLE,
+		FlowLabel: true,
+		Symmetric: true,
+	}
+
+	// Settings of a config without an applied FIB are kept for the first
+	// UpdateFIB.
+	_, err := svc.SetMultipathHash(t.Context(), &routepb.SetMultipathHashRequest{
+		ModuleName: "route0",
+		Hash:       hash,
+	})
+	require.NoError(t, err)
+	require.NotContains(t, backend.hashes, "route0")
+
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port1", 2)}},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(hash, backend.hashes["route0"]))
+
+	resp, err := svc.ShowMultipathHash(t.Context(), &routepb.ShowMultipathHashRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(hash, resp.GetHash()))
+
+	// Resetting to the default rebuilds the applied config.
+	_, err = svc.SetMultipathHash(t.Context(), &routepb.SetMultipathHashRequest{ModuleName: "route0"})
+	require.NoError(t, err)
+	require.Nil(t, backend.hashes["route0"])
+
+	resp, err = svc.ShowMultipathHash(t.Context(), &routepb.ShowMultipathHashRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_DEFAULT, resp.GetHash().GetFields())
+
+	for _, hash := range []*routepb.MultipathHash{
+		{Symmetric: true},
+		{FlowLabel: true},
+		{Fields: 42},
+	} {
+		_, err = svc.SetMultipathHash(t.Context(), &routepb.SetMultipathHashRequest{
+			ModuleName: "route0",
+			Hash:       hash,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), hash)
+	}
+}
+
+func TestHashFlags(t *testing.T) {
+	require.Equal(t, croute.HashFlags(0), hashFlags(nil))
+	require.Equal(t, croute.HashFlags(0), hashFlags(&routepb.MultipathHash{}))
+	require.Equal(t, croute.HashCustom, hashFlags(&routepb.MultipathHash{
+		Fields: routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_THREE_TUPLE,
+	}))
+	require.Equal(t, croute.HashCustom|croute.HashPorts|croute.HashFlowLabel|croute.HashSymmetric, hashFlags(&routepb.MultipathHash{
+		Fields:    routepb.MultipathHashFields_MULTIPATH_HASH_FIELDS_FIVE_TUPLE,
+		FlowLabel: true,
+		Symmetric: true,
+	}))
+}
@@ -0,0 +1,118 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/grpcmetrics"
+)
+
+// Interceptor names an interceptor of the RouteService server chain.
+type Interceptor string
+
+const (
+	// InterceptorRecovery turns a handler panic into an INTERNAL error
+	// instead of crashing the controlplane.
+	InterceptorRecovery Interceptor = "recovery"
+	// InterceptorLog logs every call with its status and duration on the
+	// module logger.
+	InterceptorLog Interceptor = "log"
+	// InterceptorMetrics records per-call RPC metrics, exposed through the
+	// route MetricsService.
+	InterceptorMetrics Interceptor = "metrics"
+)
+
+// newInterceptorChain resolves the configured interceptor names, outermost
+// first, against the built-in interceptors and the custom ones registered
+// with WithInterceptor.
+func newInterceptorChain(
+	names []Interceptor,
+	custom map[Interceptor]grpc.UnaryServerInterceptor,
+	serverMetrics *grpcmetrics.ServerMetrics,
+	log *zap.Logger,
+) ([]grpc.UnaryServerInterceptor, error) {
+	seen := map[Interceptor]struct{}{}
+	chain := make([]grpc.UnaryServerInterceptor, 0, len(names))
+
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("interceptor %q is listed more than once", name)
+		}
+		seen[name] = struct{}{}
+
+		if interceptor, ok := custom[name]; ok {
+			chain = append(chain, interceptor)
+			continue
+		}
+
+		switch name {
+		case InterceptorRecovery:
+			chain = append(chain, recoveryInterceptor(log))
+		case InterceptorLog:
+			chain = append(chain, logInterceptor(log))
+		case InterceptorMetrics:
+			chain = append(chain, serverMetrics.UnaryServerInterceptor())
+		default:
+			return nil, fmt.Errorf("unknown interceptor %q", name)
+		}
+	}
+
+	return chain, nil
+}
+
+// recoveryInterceptor recovers a panicking handler, logging the stack.
+func recoveryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("recovered from gRPC handler panic",
+					zap.String("method", info.FullMethod),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()),
+				)
+				resp, err = nil, status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// logInterceptor logs successful calls at debug level and failed ones as
+// warnings.
+func logInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		now := time.Now()
+		resp, err := handler(ctx, req)
+
+		fields := []zap.Field{
+			zap.String("method", info.FullMethod),
+			zap.String("status", status.Code(err).String()),
+			zap.Duration("duration", time.Since(now)),
+		}
+		if err != nil {
+			log.Warn("route call failed", append(fields, zap.Error(err))...)
+		} else {
+			log.Debug("route call completed", fields...)
+		}
+
+		return resp, err
+	}
+}
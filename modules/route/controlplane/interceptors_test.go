@@ -0,0 +1,68 @@
+package route
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/grpcmetrics"
+)
+
+// invokeChain runs handler through the chain the way grpc.ChainUnaryInterceptor
+// does.
+func invokeChain(chain []grpc.UnaryServerInterceptor, handler grpc.UnaryHandler) (any, error) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/modules.route.controlplane.routepb.v1.RouteService/ShowFIB"}
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		interceptor, next := chain[idx], handler
+		handler = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler(context.Background(), nil)
+}
+
+func TestNewInterceptorChain(t *testing.T) {
+	serverMetrics := grpcmetrics.New()
+
+	var calls []string
+	custom := map[Interceptor]grpc.UnaryServerInterceptor{
+		"auth": func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls = append(calls, "auth")
+			return handler(ctx, req)
+		},
+	}
+
+	chain, err := newInterceptorChain(
+		[]Interceptor{InterceptorRecovery, "auth", InterceptorMetrics, InterceptorLog},
+		custom,
+		serverMetrics,
+		zap.NewNop(),
+	)
+	require.NoError(t, err)
+	require.Len(t, chain, 4)
+
+	_, err = invokeChain(chain, func(ctx context.Context, req any) (any, error) {
+		calls = append(calls, "handler")
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Equal(t, []string{"auth", "handler"}, calls)
+	require.NotEmpty(t, serverMetrics.Collect())
+
+	for _, names := range [][]Interceptor{
+		{"unknown"},
+		{InterceptorLog, InterceptorLog},
+	} {
+		_, err := newInterceptorChain(names, custom, serverMetrics, zap.NewNop())
+		require.Error(t, err)
+	}
+
+	chain, err = newInterceptorChain(nil, custom, serverMetrics, zap.NewNop())
+	require.NoError(t, err)
+	require.Empty(t, chain)
+}
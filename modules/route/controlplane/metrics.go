@@ -0,0 +1,29 @@
+package route
+
+import (
+	"context"
+
+	"github.com/yanet-platform/yanet2/common/go/grpcmetrics"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// MetricsService exposes route module metrics over its own gRPC service.
+type MetricsService struct {
+	routepb.UnimplementedMetricsServiceServer
+
+	serverMetrics *grpcmetrics.ServerMetrics
+}
+
+// NewMetricsService creates a MetricsService backed by the RPC metrics of
+// the route module server.
+func NewMetricsService(serverMetrics *grpcmetrics.ServerMetrics) *MetricsService {
+	return &MetricsService{serverMetrics: serverMetrics}
+}
+
+// GetMetrics returns a snapshot of the route module RPC metrics.
+func (m *MetricsService) GetMetrics(
+	ctx context.Context,
+	req *routepb.GetMetricsRequest,
+) (*routepb.GetMetricsResponse, error) {
+	return &routepb.GetMetricsResponse{Metrics: m.serverMetrics.Collect()}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
+	"github.com/yanet-platform/yanet2/common/go/grpcmetrics"
 	cpffi "github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
 )
@@ -18,12 +19,14 @@ const (
 type Option func(*moduleOptions)
 
 type moduleOptions struct {
-	Log *zap.Logger
+	Log          *zap.Logger
+	Interceptors map[Interceptor]grpc.UnaryServerInterceptor
 }
 
 func newModuleOptions() *moduleOptions {
 	return &moduleOptions{
-		Log: zap.NewNop(),
+		Log:          zap.NewNop(),
+		Interceptors: map[Interceptor]grpc.UnaryServerInterceptor{},
 	}
 }
 
@@ -34,6 +37,14 @@ func WithLog(log *zap.Logger) Option {
 	}
 }
 
+// WithInterceptor registers a custom interceptor the config may list under
+// the given name, overriding a built-in one of the same name.
+func WithInterceptor(name Interceptor, interceptor grpc.UnaryServerInterceptor) Option {
+	return func(o *moduleOptions) {
+		o.Interceptors[name] = interceptor
+	}
+}
+
 // RouteModule is the slim route-module shim that owns shared memory and
 // exposes the routepb.RouteService gRPC surface.
 //
@@ -45,7 +56,10 @@ type RouteModule struct {
 	shm     *cpffi.SharedMemory
 	agent   *cpffi.Agent
 	service *RouteService
-	log     *zap.Logger
+	metrics *MetricsService
+	// interceptors is the unary interceptor chain built from the config.
+	interceptors []grpc.UnaryServerInterceptor
+	log          *zap.Logger
 }
 
 // NewRouteModule creates a new RouteModule.
@@ -57,6 +71,18 @@ func NewRouteModule(cfg *Config, options ...Option) (*RouteModule, error) {
 
 	log := opts.Log.With(zap.String("module", "modules.route.controlplane.routepb.v1.RouteService"))
 
+	serverMetrics := grpcmetrics.New()
+	interceptors, err := newInterceptorChain(cfg.Interceptors, opts.Interceptors, serverMetrics, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build interceptor chain: %w", err)
+	}
+
+	blacklistStore := NewBlacklistStore(cfg.BlacklistPath)
+	blacklist, err := blacklistStore.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blacklist: %w", err)
+	}
+
 	shm, err := cpffi.AttachSharedMemory(cfg.MemoryPath.Unwrap())
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach to shared memory %q: %w", cfg.MemoryPath, err)
@@ -67,19 +93,37 @@ func NewRouteModule(cfg *Config, options ...Option) (*RouteModule, error) {
 		zap.Stringer("size", cfg.MemoryRequirements),
 	)
 
-	agent, err := shm.AgentAttach(agentName, cfg.InstanceID, cfg.MemoryRequirements.Unwrap())
+	agent, err := shm.AgentAttach(
+		agentName,
+		cfg.InstanceID,
+		cfg.MemoryRequirements.Unwrap(),
+		cpffi.WithModuleMemoryQuota(cfg.MemoryQuota),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach agent to shared memory: %w", err)
 	}
 
-	service := NewRouteService(NewBackend(agent), WithRouteServiceLog(log))
+	capacity := cfg.MemoryRequirements.Unwrap()
+	if cfg.MemoryQuota != 0 {
+		capacity = cfg.MemoryQuota
+	}
+
+	service := NewRouteService(
+		NewBackend(agent),
+		WithRouteServiceCapacity(capacity),
+		WithRouteServicePrefixConflicts(cfg.PrefixConflicts),
+		WithRouteServiceBlacklist(blacklistStore, blacklist),
+		WithRouteServiceLog(log),
+	)
 
 	return &RouteModule{
-		cfg:     cfg,
-		shm:     shm,
-		agent:   agent,
-		service: service,
-		log:     log,
+		cfg:          cfg,
+		shm:          shm,
+		agent:        agent,
+		service:      service,
+		metrics:      NewMetricsService(serverMetrics),
+		interceptors: interceptors,
+		log:          log,
 	}, nil
 }
 
@@ -97,12 +141,25 @@ func (m *RouteModule) Endpoint() string {
 func (m *RouteModule) ServicesNames() []string {
 	return []string{
 		"modules.route.controlplane.routepb.v1.RouteService",
+		routepb.MetricsService_ServiceDesc.ServiceName,
 	}
 }
 
-// RegisterService registers the route module's gRPC service.
+// RegisterService registers the route module's gRPC services.
 func (m *RouteModule) RegisterService(server *grpc.Server) {
 	routepb.RegisterRouteServiceServer(server, m.service)
+	routepb.RegisterMetricsServiceServer(server, m.metrics)
+}
+
+// UnaryServerInterceptors returns the configured interceptor chain.
+func (m *RouteModule) UnaryServerInterceptors() []grpc.UnaryServerInterceptor {
+	return m.interceptors
+}
+
+// Health reports whether the module agent is attached to a ready dataplane
+// instance.
+func (m *RouteModule) Health() error {
+	return m.agent.Health()
 }
 
 // Close closes the module.
@@ -0,0 +1,240 @@
+package route
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// ListRoutes lists the routes of the FIB most recently applied to a route
+// configuration.
+func (m *RouteService) ListRoutes(
+	ctx context.Context,
+	req *routepb.ListRoutesRequest,
+) (*routepb.ListRoutesResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	filter, err := newRouteFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m.shmLock.RLock()
+	applied := m.fibs[name]
+	m.shmLock.RUnlock()
+
+	routes, err := appliedRoutes(applied)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to index applied FIB: %v", err)
+	}
+	maps.DeleteFunc(routes, func(prefix netip.Prefix, entry *routepb.FIBEntry) bool {
+		return !filter.match(prefix, entry)
+	})
+
+	page, nextPageToken, err := routesPage(routes, req.GetPageToken(), req.GetPageSize())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	response := &routepb.ListRoutesResponse{
+		Routes:        make([]*routepb.FIBEntry, 0, len(page)),
+		NextPageToken: nextPageToken,
+	}
+	for _, prefix := range page {
+		response.Routes = append(response.Routes, routes[prefix])
+	}
+	return response, nil
+}
+
+// LookupRoute returns the route of the applied FIB of a route
+// configuration with the longest prefix matching an address.
+func (m *RouteService) LookupRoute(
+	ctx context.Context,
+	req *routepb.LookupRouteRequest,
+) (*routepb.LookupRouteResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	addr, err := req.GetAddr().ToAddr()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address: %v", err)
+	}
+
+	m.shmLock.RLock()
+	applied, ok := m.fibs[name]
+	m.shmLock.RUnlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no FIB applied to config %q", name)
+	}
+
+	routes, err := appliedRoutes(applied)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to index applied FIB: %v", err)
+	}
+
+	addr = addr.Unmap()
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		prefix := netip.PrefixFrom(addr, bits).Masked()
+		if route, ok := routes[prefix]; ok {
+			return &routepb.LookupRouteResponse{Route: route}, nil
+		}
+	}
+
+	return &routepb.LookupRouteResponse{}, nil
+}
+
+// appliedRoutes indexes the entries of an applied FIB by their masked
+// prefix.
+//
+// Like normalizeRoutes, it skips the entries the backend does not install
+// and lets a later duplicate prefix override an earlier one. The entries
+// are returned with their prefix in the masked form.
+func appliedRoutes(entries []*routepb.FIBEntry) (map[netip.Prefix]*routepb.FIBEntry, error) {
+	routes := map[netip.Prefix]*routepb.FIBEntry{}
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prefix %q: %w", entry.GetPrefix(), err)
+		}
+		prefix = prefix.Masked()
+
+		if len(entry.GetNexthops()) == 0 && !entry.GetBlackhole() {
+			continue
+		}
+
+		routes[prefix] = &routepb.FIBEntry{
+			Prefix:    prefix.String(),
+			Nexthops:  entry.GetNexthops(),
+			Blackhole: entry.GetBlackhole(),
+			Sources:   entry.GetSources(),
+		}
+	}
+
+	return routes, nil
+}
+
+// routeFilter selects the routes listed by ListRoutes.
+type routeFilter struct {
+	// prefix matches the routes of itself and its more-specifics, any
+	// route if invalid.
+	prefix   netip.Prefix
+	ipv4Only bool
+	ipv6Only bool
+	nexthop  *routepb.FIBNexthop
+	source   routepb.RouteSource
+}
+
+func newRouteFilter(req *routepb.ListRoutesRequest) (routeFilter, error) {
+	filter := routeFilter{
+		ipv4Only: req.GetIpv4Only(),
+		ipv6Only: req.GetIpv6Only(),
+		nexthop:  req.GetNexthop(),
+		source:   req.GetSource(),
+	}
+	if req.GetPrefix() != "" {
+		prefix, err := netip.ParsePrefix(req.GetPrefix())
+		if err != nil {
+			return routeFilter{}, fmt.Errorf("invalid prefix filter: %w", err)
+		}
+		filter.prefix = prefix.Masked()
+	}
+
+	return filter, nil
+}
+
+func (m routeFilter) match(prefix netip.Prefix, entry *routepb.FIBEntry) bool {
+	if m.ipv4Only && !prefix.Addr().Is4() {
+		return false
+	}
+	if m.ipv6Only && !prefix.Addr().Is6() {
+		return false
+	}
+	if m.prefix.IsValid() && (m.prefix.Bits() > prefix.Bits() || !m.prefix.Contains(prefix.Addr())) {
+		return false
+	}
+	if m.source != routepb.RouteSource_ROUTE_SOURCE_UNSPECIFIED && !slices.Contains(entry.GetSources(), m.source) {
+		return false
+	}
+	if m.nexthop != nil && !slices.ContainsFunc(entry.GetNexthops(), m.matchNexthop) {
+		return false
+	}
+
+	return true
+}
+
+// matchNexthop reports whether a nexthop matches every field set in the
+// nexthop filter.
+func (m routeFilter) matchNexthop(nh *routepb.FIBNexthop) bool {
+	if device := m.nexthop.GetDevice(); device != "" && nh.GetDevice() != device {
+		return false
+	}
+	if mac := m.nexthop.GetDstMac(); mac != nil && nh.GetDstMac().GetAddr() != mac.GetAddr() {
+		return false
+	}
+	if mac := m.nexthop.GetSrcMac(); mac != nil && nh.GetSrcMac().GetAddr() != mac.GetAddr() {
+		return false
+	}
+
+	return true
+}
+
+// routesPage returns the page of prefixes following the page token, along
+// with the token of the next page.
+//
+// Prefixes are ordered by address, then by length. Like the ShowFIB token,
+// the token is the last returned prefix, so it stays meaningful after the
+// FIB is replaced.
+func routesPage(routes map[netip.Prefix]*routepb.FIBEntry, token string, size uint32) ([]netip.Prefix, string, error) {
+	prefixes := slices.SortedFunc(maps.Keys(routes), xnetip.PrefixCompare)
+
+	start := 0
+	if token != "" {
+		after, err := decodeRoutesPageToken(token)
+		if err != nil {
+			return nil, "", err
+		}
+		idx, found := slices.BinarySearchFunc(prefixes, after, xnetip.PrefixCompare)
+		if found {
+			idx++
+		}
+		start = idx
+	}
+
+	prefixes = prefixes[start:]
+	if size == 0 || len(prefixes) <= int(size) {
+		return prefixes, "", nil
+	}
+
+	prefixes = prefixes[:size]
+	return prefixes, encodeRoutesPageToken(prefixes[len(prefixes)-1]), nil
+}
+
+func encodeRoutesPageToken(prefix netip.Prefix) string {
+	data, _ := prefix.MarshalBinary()
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeRoutesPageToken(token string) (netip.Prefix, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("malformed page token: %w", err)
+	}
+	prefix := netip.Prefix{}
+	if err := prefix.UnmarshalBinary(data); err != nil {
+		return netip.Prefix{}, fmt.Errorf("malformed page token: %w", err)
+	}
+
+	return prefix, nil
+}
@@ -0,0 +1,111 @@
+package route
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestListRoutes(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	static := []routepb.RouteSource{routepb.RouteSource_ROUTE_SOURCE_STATIC}
+	bird := []routepb.RouteSource{routepb.RouteSource_ROUTE_SOURCE_BIRD}
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}, Sources: bird},
+			{Prefix: "10.0.1.1/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}, Sources: static},
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port1", 2)}, Sources: bird},
+			{Prefix: "10.0.2.0/24", Blackhole: true},
+			// Not installed.
+			{Prefix: "10.0.3.0/24"},
+		},
+	})
+	require.NoError(t, err)
+
+	list := func(req *routepb.ListRoutesRequest) ([]string, string) {
+		req.Name = "route0"
+		response, err := svc.ListRoutes(t.Context(), req)
+		require.NoError(t, err)
+
+		prefixes := []string{}
+		for _, route := range response.GetRoutes() {
+			prefixes = append(prefixes, route.GetPrefix())
+		}
+		return prefixes, response.GetNextPageToken()
+	}
+
+	prefixes, token := list(&routepb.ListRoutesRequest{})
+	require.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "2001:db8::/32"}, prefixes)
+	require.Empty(t, token)
+
+	prefixes, _ = list(&routepb.ListRoutesRequest{Ipv6Only: true})
+	require.Equal(t, []string{"2001:db8::/32"}, prefixes)
+	prefixes, _ = list(&routepb.ListRoutesRequest{Prefix: "10.0.0.0/23"})
+	require.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24"}, prefixes)
+	prefixes, _ = list(&routepb.ListRoutesRequest{Nexthop: &routepb.FIBNexthop{Device: "port1"}})
+	require.Equal(t, []string{"10.0.0.0/24", "2001:db8::/32"}, prefixes)
+	prefixes, _ = list(&routepb.ListRoutesRequest{
+		Nexthop: &routepb.FIBNexthop{DstMac: &commonpb.MACAddress{Addr: 1}},
+		Source:  routepb.RouteSource_ROUTE_SOURCE_STATIC,
+	})
+	require.Equal(t, []string{"10.0.1.0/24"}, prefixes)
+
+	prefixes, token = list(&routepb.ListRoutesRequest{PageSize: 3})
+	require.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"}, prefixes)
+	require.NotEmpty(t, token)
+	prefixes, token = list(&routepb.ListRoutesRequest{PageSize: 3, PageToken: token})
+	require.Equal(t, []string{"2001:db8::/32"}, prefixes)
+	require.Empty(t, token)
+
+	_, err = svc.ListRoutes(t.Context(), &routepb.ListRoutesRequest{Name: "route0", PageToken: "?"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	response, err := svc.ListRoutes(t.Context(), &routepb.ListRoutesRequest{Name: "route1"})
+	require.NoError(t, err)
+	require.Empty(t, response.GetRoutes())
+}
+
+func TestLookupRoute(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "0.0.0.0/0", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+			{Prefix: "10.0.0.0/8", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.1.0.0/16", Blackhole: true},
+		},
+	})
+	require.NoError(t, err)
+
+	lookup := func(addr string) string {
+		response, err := svc.LookupRoute(t.Context(), &routepb.LookupRouteRequest{
+			Name: "route0",
+			Addr: commonpb.NewIPAddressFromAddr(netip.MustParseAddr(addr)),
+		})
+		require.NoError(t, err)
+		return response.GetRoute().GetPrefix()
+	}
+
+	require.Equal(t, "10.1.0.0/16", lookup("10.1.2.3"))
+	require.Equal(t, "10.0.0.0/8", lookup("10.2.0.1"))
+	require.Equal(t, "0.0.0.0/0", lookup("192.0.2.1"))
+	require.Equal(t, "0.0.0.0/0", lookup("::ffff:192.0.2.1"))
+	require.Empty(t, lookup("2001:db8::1"))
+
+	_, err = svc.LookupRoute(t.Context(), &routepb.LookupRouteRequest{
+		Name: "route1",
+		Addr: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("10.0.0.1")),
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
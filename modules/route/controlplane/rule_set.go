@@ -0,0 +1,134 @@
+package route
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// RuleSet is the desired FIBs of the module configs, as kept in a config
+// repository and pushed with UpdateFIB.
+type RuleSet struct {
+	// Configs are the FIB entries by module config name.
+	Configs map[string][]RuleSetEntry `yaml:"configs"`
+}
+
+// RuleSetEntry is a FIB entry of a rule set.
+type RuleSetEntry struct {
+	Prefix string `yaml:"prefix"`
+	// Blackhole drops the traffic to the prefix, the nexthops are ignored.
+	Blackhole bool             `yaml:"blackhole"`
+	Nexthops  []RuleSetNexthop `yaml:"nexthops"`
+}
+
+// RuleSetNexthop is a nexthop of a rule set FIB entry.
+type RuleSetNexthop struct {
+	Device string `yaml:"device"`
+	DstMAC string `yaml:"dst_mac"`
+	SrcMAC string `yaml:"src_mac"`
+	// Weight is the share of the nexthop in a multipath entry, one if
+	// zero.
+	Weight uint32 `yaml:"weight"`
+}
+
+// ValidateRuleSet checks a rule set the way the module checks the FIBs it
+// applies: the prefixes and nexthops of every entry, and under the error
+// prefix conflict policy the prefixes installed with different forwarding
+// by several configs.
+//
+// It needs no dataplane, so it suits the pre-merge checks of a config
+// repository. The shared memory a FIB holds is only known once it is
+// built, so the memory quota is left to the module.
+func ValidateRuleSet(cfg *Config, ruleSet *RuleSet) error {
+	if err := cfg.PrefixConflicts.Validate(); err != nil {
+		return err
+	}
+
+	fibs := map[string]map[netip.Prefix]verifyRoute{}
+	for _, name := range slices.Sorted(maps.Keys(ruleSet.Configs)) {
+		if name == "" {
+			return fmt.Errorf("module_name is required")
+		}
+
+		entries, err := compileRuleSetFIB(ruleSet.Configs[name])
+		if err != nil {
+			return fmt.Errorf("invalid FIB for %q: %w", name, err)
+		}
+		if fibs[name], err = normalizeRoutes(entries); err != nil {
+			return fmt.Errorf("invalid FIB for %q: %w", name, err)
+		}
+	}
+
+	if cfg.PrefixConflicts.Policy != PrefixConflictError {
+		return nil
+	}
+	if conflicts := findConflicts(fibs, ""); len(conflicts) != 0 {
+		first := conflicts[0]
+		return fmt.Errorf("FIBs conflict on %d prefixes, first %s installed by %s",
+			len(conflicts), first.prefix, strings.Join(first.names(), ", "),
+		)
+	}
+
+	return nil
+}
+
+// compileRuleSetFIB converts the rule set entries into the FIB entries
+// UpdateFIB carries, checking the nexthops like the backend does.
+func compileRuleSetFIB(entries []RuleSetEntry) ([]*routepb.FIBEntry, error) {
+	out := make([]*routepb.FIBEntry, 0, len(entries))
+	for _, entry := range entries {
+		fibEntry := &routepb.FIBEntry{
+			Prefix:    entry.Prefix,
+			Blackhole: entry.Blackhole,
+		}
+		for _, nh := range entry.Nexthops {
+			nexthop, err := nh.proto()
+			if err != nil {
+				return nil, fmt.Errorf("invalid nexthop of %q: %w", entry.Prefix, err)
+			}
+			if _, err := newHardwareRoute(nexthop); err != nil {
+				return nil, fmt.Errorf("invalid nexthop of %q: %w", entry.Prefix, err)
+			}
+			fibEntry.Nexthops = append(fibEntry.Nexthops, nexthop)
+		}
+		out = append(out, fibEntry)
+	}
+	return out, nil
+}
+
+func (m *RuleSetNexthop) proto() (*routepb.FIBNexthop, error) {
+	nexthop := &routepb.FIBNexthop{
+		Device: m.Device,
+		Weight: m.Weight,
+	}
+
+	var err error
+	if nexthop.DstMac, err = parseRuleSetMAC(m.DstMAC); err != nil {
+		return nil, fmt.Errorf("invalid dst_mac: %w", err)
+	}
+	if nexthop.SrcMac, err = parseRuleSetMAC(m.SrcMAC); err != nil {
+		return nil, fmt.Errorf("invalid src_mac: %w", err)
+	}
+	return nexthop, nil
+}
+
+// parseRuleSetMAC parses an EUI-48 address, nil for an empty one.
+func parseRuleSetMAC(addr string) (*commonpb.MACAddress, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	mac, err := net.ParseMAC(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("expected 6 octets, got %d", len(mac))
+	}
+	return commonpb.NewMACAddressEUI48([6]byte(mac)), nil
+}
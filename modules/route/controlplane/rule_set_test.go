@@ -0,0 +1,109 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRuleSet(t *testing.T) {
+	nexthop := RuleSetNexthop{
+		Device: "eth0",
+		DstMAC: "52:54:00:00:00:01",
+		SrcMAC: "52:54:00:00:00:02",
+	}
+	other := RuleSetNexthop{
+		Device: "eth1",
+		DstMAC: "52:54:00:00:00:03",
+		SrcMAC: "52:54:00:00:00:04",
+	}
+
+	tests := []struct {
+		name    string
+		policy  PrefixConflictPolicy
+		configs map[string][]RuleSetEntry
+		err     string
+	}{
+		{
+			name: "valid",
+			configs: map[string][]RuleSetEntry{
+				"route0": {
+					{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{nexthop, other}},
+					{Prefix: "2001:db8::/32", Nexthops: []RuleSetNexthop{nexthop}},
+					{Prefix: "192.0.2.0/24", Blackhole: true},
+				},
+			},
+		},
+		{
+			name: "invalid prefix",
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/33", Nexthops: []RuleSetNexthop{nexthop}}},
+			},
+			err: `invalid FIB for "route0": failed to parse prefix "10.0.0.0/33"`,
+		},
+		{
+			name: "invalid mac",
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{{Device: "eth0", DstMAC: "52:54:00", SrcMAC: nexthop.SrcMAC}}}},
+			},
+			err: `invalid nexthop of "10.0.0.0/24": invalid dst_mac`,
+		},
+		{
+			name: "missing device",
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{{DstMAC: nexthop.DstMAC, SrcMAC: nexthop.SrcMAC}}}},
+			},
+			err: "device is required",
+		},
+		{
+			name: "missing src mac",
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{{Device: "eth0", DstMAC: nexthop.DstMAC}}}},
+			},
+			err: "src_mac is required",
+		},
+		{
+			name: "conflict reported",
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{nexthop}}},
+				"route1": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{other}}},
+			},
+		},
+		{
+			name:   "conflict refused",
+			policy: PrefixConflictError,
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{nexthop}}},
+				"route1": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{other}}},
+			},
+			err: "FIBs conflict on 1 prefixes, first 10.0.0.0/24 installed by route0, route1",
+		},
+		{
+			name:   "anycast is no conflict",
+			policy: PrefixConflictError,
+			configs: map[string][]RuleSetEntry{
+				"route0": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{nexthop}}},
+				"route1": {{Prefix: "10.0.0.0/24", Nexthops: []RuleSetNexthop{nexthop}}},
+			},
+		},
+		{
+			name:   "unknown conflict policy",
+			policy: "drop",
+			err:    `unknown prefix conflict policy "drop"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.PrefixConflicts.Policy = test.policy
+
+			err := ValidateRuleSet(cfg, &RuleSet{Configs: test.configs})
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
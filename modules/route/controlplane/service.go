@@ -2,14 +2,18 @@ package route
 
 import (
 	"context"
+	"net/netip"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 
+	"github.com/c2h5oh/datasize"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/controlplane/ffi"
 	"github.com/yanet-platform/yanet2/modules/route/bindings/go/croute"
 	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
 )
@@ -18,7 +22,13 @@ import (
 type RouteServiceOption func(*routeServiceOptions)
 
 type routeServiceOptions struct {
-	Log *zap.Logger
+	Capacity  datasize.ByteSize
+	Conflicts PrefixConflictConfig
+	// BlacklistStore and Blacklist are the store of the blacklist and
+	// the entries loaded from it.
+	BlacklistStore *BlacklistStore
+	Blacklist      []*routepb.BlacklistEntry
+	Log            *zap.Logger
 }
 
 func newRouteServiceOptions() *routeServiceOptions {
@@ -34,6 +44,37 @@ func WithRouteServiceLog(log *zap.Logger) RouteServiceOption {
 	}
 }
 
+// WithRouteServiceCapacity sets the shared memory available to a single
+// module config, which GetCapacity projects the headroom against.
+//
+// Zero, the default, reports the FIB size only.
+func WithRouteServiceCapacity(capacity datasize.ByteSize) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Capacity = capacity
+	}
+}
+
+// WithRouteServicePrefixConflicts sets how the prefixes installed with
+// different forwarding by several module configs are handled.
+//
+// By default the conflicts are installed as requested and only reported.
+func WithRouteServicePrefixConflicts(config PrefixConflictConfig) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Conflicts = config
+	}
+}
+
+// WithRouteServiceBlacklist sets the blacklist the service starts with and
+// the store its changes are persisted to.
+//
+// By default the blacklist starts empty and lives in memory only.
+func WithRouteServiceBlacklist(store *BlacklistStore, entries []*routepb.BlacklistEntry) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.BlacklistStore = store
+		o.Blacklist = entries
+	}
+}
+
 // RouteService is the gRPC service implementation backing the slim
 // route-module shim.
 type RouteService struct {
@@ -42,9 +83,43 @@ type RouteService struct {
 	backend Backend
 
 	// shmLock serializes shared-memory mutations and protects the
-	// configs map.
+	// configs, fibs, routes, urpf, proxy, hashes, drained, generations
+	// and blacklist maps.
 	shmLock sync.RWMutex
 	configs map[string]ModuleHandle
+	// fibs keeps the last applied FIB of each config, so that uRPF and
+	// neighbour proxy changes can rebuild the module config. It holds the
+	// FIB as requested, before the prefix conflicts are resolved.
+	fibs map[string][]*routepb.FIBEntry
+	// routes keeps the normalized fibs for the prefix conflict policies
+	// other than report.
+	routes map[string]map[netip.Prefix]verifyRoute
+	urpf   map[string][]*routepb.URPFInterface
+	proxy  map[string][]*routepb.NeighbourProxyInterface
+	// hashes keeps the multipath hash settings, absent for the default
+	// hash.
+	hashes map[string]*routepb.MultipathHash
+	// drained keeps the nexthops removed from the multipath groups of
+	// each config until they are undrained.
+	drained map[string]map[drainKey]struct{}
+	// generations counts the module configs published for each config
+	// name. It survives DeleteConfig, so a recreated config never reuses
+	// a generation.
+	generations map[string]uint64
+	// blacklist holds the prefixes left out of the installed FIB, shared
+	// by all configs.
+	blacklist      blacklist
+	blacklistStore *BlacklistStore
+
+	// capacity is the shared memory available to a single module config,
+	// zero if unknown.
+	capacity datasize.ByteSize
+	// conflicts selects the handling of the prefix conflicts between
+	// configs.
+	conflicts PrefixConflictConfig
+	// watches holds the prefixes whose FIB changes are logged and
+	// streamed, see WatchPrefix.
+	watches *prefixWatches
 
 	log *zap.Logger
 }
@@ -56,10 +131,33 @@ func NewRouteService(backend Backend, options ...RouteServiceOption) *RouteServi
 		o(opts)
 	}
 
+	if opts.Conflicts.Policy == "" {
+		opts.Conflicts.Policy = PrefixConflictReport
+	}
+
+	blacklisted := blacklist{}
+	for _, entry := range opts.Blacklist {
+		if err := blacklisted.add([]*routepb.BlacklistEntry{entry}); err != nil {
+			opts.Log.Warn("skipping invalid blacklist entry", zap.Error(err))
+		}
+	}
+
 	return &RouteService{
-		backend: backend,
-		configs: map[string]ModuleHandle{},
-		log:     opts.Log,
+		backend:        backend,
+		configs:        map[string]ModuleHandle{},
+		fibs:           map[string][]*routepb.FIBEntry{},
+		routes:         map[string]map[netip.Prefix]verifyRoute{},
+		urpf:           map[string][]*routepb.URPFInterface{},
+		proxy:          map[string][]*routepb.NeighbourProxyInterface{},
+		hashes:         map[string]*routepb.MultipathHash{},
+		drained:        map[string]map[drainKey]struct{}{},
+		generations:    map[string]uint64{},
+		blacklist:      blacklisted,
+		blacklistStore: opts.BlacklistStore,
+		capacity:       opts.Capacity,
+		conflicts:      opts.Conflicts,
+		watches:        newPrefixWatches(),
+		log:            opts.Log,
 	}
 }
 
@@ -92,6 +190,10 @@ func (m *RouteService) ShowFIB(
 	if name == "" {
 		return nil, status.Error(codes.InvalidArgument, "module config name is required")
 	}
+	mask, err := newFIBReadMask(req.GetReadMask())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	// Hold RLock for the entire DumpFIB call so a concurrent Free under
 	// shmLock.Lock cannot release the underlying shared memory.
@@ -108,35 +210,27 @@ func (m *RouteService) ShowFIB(
 		return nil, status.Errorf(codes.Internal, "failed to dump FIB: %v", err)
 	}
 
-	response := &routepb.ShowFIBResponse{
-		Entries: make([]*routepb.FIBRangeEntry, 0, len(entries)),
-	}
-	for _, e := range entries {
+	entries = slices.DeleteFunc(entries, func(e croute.FIBEntry) bool {
 		if req.GetIpv4Only() && e.AddressFamily != croute.AddressFamilyIPv4 {
-			continue
-		}
-		if req.GetIpv6Only() && e.AddressFamily != croute.AddressFamilyIPv6 {
-			continue
-		}
-
-		nexthops := make([]*routepb.FIBNexthop, len(e.Nexthops))
-		for idx, nh := range e.Nexthops {
-			nexthops[idx] = &routepb.FIBNexthop{
-				DstMac: commonpb.NewMACAddressEUI48([6]byte(nh.DstMAC)),
-				SrcMac: commonpb.NewMACAddressEUI48([6]byte(nh.SrcMAC)),
-				Device: nh.Device,
-			}
+			return true
 		}
+		return req.GetIpv6Only() && e.AddressFamily != croute.AddressFamilyIPv6
+	})
+	entries, nextPageToken, err := fibPage(entries, req.GetPageToken(), req.GetPageSize())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-		ipRange, err := commonpb.NewIPRange(e.PrefixFrom, e.PrefixTo)
+	response := &routepb.ShowFIBResponse{
+		Entries:       make([]*routepb.FIBRangeEntry, 0, len(entries)),
+		NextPageToken: nextPageToken,
+	}
+	for _, e := range entries {
+		entry, err := mask.entry(e)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to build IP range from FIB entry: %v", err)
+			return nil, status.Error(codes.Internal, err.Error())
 		}
-
-		response.Entries = append(response.Entries, &routepb.FIBRangeEntry{
-			Range:    ipRange,
-			Nexthops: nexthops,
-		})
+		response.Entries = append(response.Entries, entry)
 	}
 	return response, nil
 }
@@ -163,12 +257,24 @@ func (m *RouteService) DeleteConfig(
 		return nil, status.Errorf(codes.Internal, "failed to delete module config %q: %v", name, err)
 	}
 	module.Free()
+	m.watchFIB(name, m.fibs[name], nil, m.generations[name])
 	delete(m.configs, name)
+	delete(m.fibs, name)
+	delete(m.urpf, name)
+	delete(m.proxy, name)
+	delete(m.hashes, name)
+	delete(m.drained, name)
+	m.republish(m.setRoutes(name, nil))
 
 	return &routepb.DeleteConfigResponse{}, nil
 }
 
 // UpdateFIB applies a freshly-built FIB to the dataplane atomically.
+//
+// A delta is merged into the FIB last applied, which must still be at the
+// base generation of the delta. The module config is rebuilt from the
+// merged FIB like from a full one, the delta only spares the sender from
+// encoding and transferring the prefixes that did not change.
 func (m *RouteService) UpdateFIB(
 	ctx context.Context,
 	req *routepb.UpdateFIBRequest,
@@ -181,15 +287,275 @@ func (m *RouteService) UpdateFIB(
 	m.shmLock.Lock()
 	defer m.shmLock.Unlock()
 
-	module, err := m.backend.UpdateModule(name, req.GetEntries())
+	entries := req.GetEntries()
+	if req.GetDelta() {
+		if _, ok := m.configs[name]; !ok || m.generations[name] != req.GetBaseGeneration() {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"FIB of %q is at generation %d, not at the base generation %d of the delta",
+				name, m.generations[name], req.GetBaseGeneration(),
+			)
+		}
+		// An empty delta confirms the FIB is still at its base generation
+		// without rebuilding the module config.
+		if len(entries) == 0 && len(req.GetRemovedPrefixes()) == 0 {
+			return &routepb.UpdateFIBResponse{Generation: m.generations[name]}, nil
+		}
+
+		var err error
+		if entries, err = applyFIBDelta(m.fibs[name], entries, req.GetRemovedPrefixes()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid FIB delta for %q: %v", name, err)
+		}
+	}
+
+	var routes map[netip.Prefix]verifyRoute
+	if m.conflicts.Policy != PrefixConflictReport {
+		var err error
+		if routes, err = normalizeRoutes(entries); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid FIB for %q: %v", name, err)
+		}
+		if err := m.checkConflicts(name, routes); err != nil {
+			return nil, err
+		}
+	}
+
+	prev, hadRoutes := m.routes[name]
+	affected := m.setRoutes(name, routes)
+	if err := m.updateModule(name, entries, m.urpf[name], m.proxy[name], m.hashes[name]); err != nil {
+		if hadRoutes {
+			m.setRoutes(name, prev)
+		} else {
+			m.setRoutes(name, nil)
+		}
+		return nil, ffi.UpdateStatus(err, "failed to apply FIB for %q", name)
+	}
+	m.republish(affected)
+
+	return &routepb.UpdateFIBResponse{Generation: m.generations[name]}, nil
+}
+
+// SetURPF replaces the per-interface uRPF settings of a route
+// configuration.
+func (m *RouteService) SetURPF(
+	ctx context.Context,
+	req *routepb.SetURPFRequest,
+) (*routepb.SetURPFResponse, error) {
+	name := req.GetModuleName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module_name is required")
+	}
+
+	devices := map[string]struct{}{}
+	for _, setting := range req.GetInterfaces() {
+		device := setting.GetDevice()
+		if device == "" {
+			return nil, status.Error(codes.InvalidArgument, "interface device is required")
+		}
+		if _, ok := routepb.URPFMode_name[int32(setting.GetMode())]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported uRPF mode %d for %q", setting.GetMode(), device)
+		}
+		if _, ok := devices[device]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate interface %q", device)
+		}
+		devices[device] = struct{}{}
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	// Without an applied FIB there is nothing to rebuild yet; the settings
+	// are picked up by the first UpdateFIB.
+	if _, ok := m.configs[name]; ok {
+		if err := m.updateModule(name, m.fibs[name], req.GetInterfaces(), m.proxy[name], m.hashes[name]); err != nil {
+			return nil, ffi.UpdateStatus(err, "failed to apply uRPF for %q", name)
+		}
+	}
+	m.urpf[name] = req.GetInterfaces()
+
+	m.log.Info("updated uRPF settings",
+		zap.String("name", name),
+		zap.Int("interfaces", len(req.GetInterfaces())),
+	)
+
+	return &routepb.SetURPFResponse{}, nil
+}
+
+// ShowURPF returns the per-interface uRPF settings of a route
+// configuration.
+func (m *RouteService) ShowURPF(
+	ctx context.Context,
+	req *routepb.ShowURPFRequest,
+) (*routepb.ShowURPFResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	return &routepb.ShowURPFResponse{
+		Interfaces: m.urpf[name],
+	}, nil
+}
+
+// SetNeighbourProxy replaces the per-interface neighbour proxy settings of
+// a route configuration.
+func (m *RouteService) SetNeighbourProxy(
+	ctx context.Context,
+	req *routepb.SetNeighbourProxyRequest,
+) (*routepb.SetNeighbourProxyResponse, error) {
+	name := req.GetModuleName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module_name is required")
+	}
+
+	devices := map[string]struct{}{}
+	for _, setting := range req.GetInterfaces() {
+		device := setting.GetDevice()
+		if device == "" {
+			return nil, status.Error(codes.InvalidArgument, "interface device is required")
+		}
+		if (setting.GetArp() || setting.GetNdp()) && setting.GetMac() == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "proxy MAC address is required for %q", device)
+		}
+		if _, ok := devices[device]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate interface %q", device)
+		}
+		devices[device] = struct{}{}
+	}
+
+	m.shmLock.Lock()
+	defer m.shmLock.Unlock()
+
+	if _, ok := m.configs[name]; ok {
+		if err := m.updateModule(name, m.fibs[name], m.urpf[name], req.GetInterfaces(), m.hashes[name]); err != nil {
+			return nil, ffi.UpdateStatus(err, "failed to apply neighbour proxy for %q", name)
+		}
+	}
+	m.proxy[name] = req.GetInterfaces()
+
+	m.log.Info("updated neighbour proxy settings",
+		zap.String("name", name),
+		zap.Int("interfaces", len(req.GetInterfaces())),
+	)
+
+	return &routepb.SetNeighbourProxyResponse{}, nil
+}
+
+// ShowNeighbourProxy returns the per-interface neighbour proxy settings of
+// a route configuration.
+func (m *RouteService) ShowNeighbourProxy(
+	ctx context.Context,
+	req *routepb.ShowNeighbourProxyRequest,
+) (*routepb.ShowNeighbourProxyResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	defer m.shmLock.RUnlock()
+
+	return &routepb.ShowNeighbourProxyResponse{
+		Interfaces: m.proxy[name],
+	}, nil
+}
+
+// VerifyRoutes compares the applied FIB of a route configuration against
+// the expected route set.
+func (m *RouteService) VerifyRoutes(
+	ctx context.Context,
+	req *routepb.VerifyRoutesRequest,
+) (*routepb.VerifyRoutesResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	applied := m.fibs[name]
+	m.shmLock.RUnlock()
+
+	actual, err := normalizeRoutes(applied)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to normalize applied FIB: %v", err)
+	}
+
+	switch expected := req.GetExpected().(type) {
+	case *routepb.VerifyRoutesRequest_Entries:
+		routes, err := normalizeRoutes(expected.Entries.GetEntries())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expected routes: %v", err)
+		}
+
+		return verifyRoutes(routes, actual), nil
+	case *routepb.VerifyRoutesRequest_Digest:
+		digest := routesDigest(actual)
+
+		return &routepb.VerifyRoutesResponse{
+			Converged: strings.EqualFold(expected.Digest, digest),
+			Digest:    digest,
+		}, nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, "expected routes or digest are required")
+	}
+}
+
+// DumpTrie describes the shape of the LPM trie built from the applied FIB of
+// a route configuration.
+func (m *RouteService) DumpTrie(
+	ctx context.Context,
+	req *routepb.DumpTrieRequest,
+) (*routepb.DumpTrieResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	m.shmLock.RLock()
+	applied, ok := m.fibs[name]
+	m.shmLock.RUnlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no FIB applied to config %q", name)
+	}
+
+	routes, err := normalizeRoutes(applied)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to normalize applied FIB: %v", err)
+	}
+
+	return dumpTrie(routes, req.GetFormat(), req.GetMaxNodes()), nil
+}
+
+// updateModule publishes a new module config and releases the previous
+// one.
+//
+// The entries are recorded as requested, while the module config installs
+// them with the prefix conflicts resolved, the blacklisted prefixes left out
+// and the drained nexthops removed.
+//
+// The caller must hold shmLock for writing.
+func (m *RouteService) updateModule(
+	name string,
+	entries []*routepb.FIBEntry,
+	urpf []*routepb.URPFInterface,
+	proxy []*routepb.NeighbourProxyInterface,
+	hash *routepb.MultipathHash,
+) error {
+	installed, _ := blacklistEntries(m.effectiveFIB(name, entries), m.blacklist)
+	installed, _ = drainEntries(installed, m.drained[name])
+	module, err := m.backend.UpdateModule(name, installed, urpf, proxy, hash)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to apply FIB for %q: %v", name, err)
+		return err
 	}
 
 	if old, ok := m.configs[name]; ok {
 		old.Free()
 	}
 	m.configs[name] = module
+	m.generations[name]++
+	m.watchFIB(name, m.fibs[name], entries, m.generations[name])
+	m.fibs[name] = entries
 
-	return &routepb.UpdateFIBResponse{}, nil
+	return nil
 }
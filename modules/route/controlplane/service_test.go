@@ -0,0 +1,75 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestSetURPF(t *testing.T) {
+	backend := &installedBackend{
+		installed: map[string][]*routepb.FIBEntry{},
+		urpf:      map[string][]*routepb.URPFInterface{},
+	}
+	svc := NewRouteService(backend)
+
+	interfaces := []*routepb.URPFInterface{
+		{Device: "port0", Mode: routepb.URPFMode_URPF_MODE_STRICT},
+		{Device: "port1", Mode: routepb.URPFMode_URPF_MODE_LOOSE},
+	}
+
+	// Settings of a config without an applied FIB are kept for the first
+	// UpdateFIB.
+	_, err := svc.SetURPF(t.Context(), &routepb.SetURPFRequest{
+		ModuleName: "route0",
+		Interfaces: interfaces,
+	})
+	require.NoError(t, err)
+	require.NotContains(t, backend.urpf, "route0")
+
+	_, err = svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries: []*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, interfaces, backend.urpf["route0"])
+
+	resp, err := svc.ShowURPF(t.Context(), &routepb.ShowURPFRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, interfaces, resp.GetInterfaces())
+
+	for _, req := range []*routepb.SetURPFRequest{
+		{Interfaces: interfaces},
+		{ModuleName: "route0", Interfaces: []*routepb.URPFInterface{{Mode: routepb.URPFMode_URPF_MODE_LOOSE}}},
+		{ModuleName: "route0", Interfaces: []*routepb.URPFInterface{{Device: "port0", Mode: 42}}},
+		{ModuleName: "route0", Interfaces: []*routepb.URPFInterface{
+			{Device: "port0", Mode: routepb.URPFMode_URPF_MODE_LOOSE},
+			{Device: "port0", Mode: routepb.URPFMode_URPF_MODE_STRICT},
+		}},
+	} {
+		_, err = svc.SetURPF(t.Context(), req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), req)
+	}
+
+	// A refused request leaves the settings in place.
+	require.Equal(t, interfaces, backend.urpf["route0"])
+
+	// Clearing the settings rebuilds the applied config with its FIB.
+	_, err = svc.SetURPF(t.Context(), &routepb.SetURPFRequest{ModuleName: "route0"})
+	require.NoError(t, err)
+	require.Empty(t, backend.urpf["route0"])
+	require.Len(t, backend.installed["route0"], 1)
+
+	resp, err = svc.ShowURPF(t.Context(), &routepb.ShowURPFRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Empty(t, resp.GetInterfaces())
+
+	_, err = svc.ShowURPF(t.Context(), &routepb.ShowURPFRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,161 @@
+package route
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+const (
+	// trieStride is the number of address bits indexed by a single page.
+	trieStride = 8
+	// defaultTrieMaxNodes bounds the DOT output when the request does not.
+	defaultTrieMaxNodes = 1024
+)
+
+// triePage is a page of the LPM trie model.
+type triePage struct {
+	// key is the address prefix the page is reached by, its length is a
+	// multiple of trieStride.
+	key netip.Prefix
+	// prefixes is the number of routes whose values are stored in the page.
+	prefixes uint64
+	// children are ordered by address as long as routes are inserted in
+	// address order.
+	children []netip.Prefix
+}
+
+func (m *triePage) depth() int {
+	return m.key.Bits() / trieStride
+}
+
+// trie models the dataplane LPM page tree of a single address family.
+//
+// A route of length L stores its value in the page at depth (L-1)/8, so
+// every distinct d-byte key of routes longer than 8*d bits has a page at
+// depth d. Page compaction is not modelled.
+type trie struct {
+	root     netip.Prefix
+	pages    map[netip.Prefix]*triePage
+	prefixes uint64
+}
+
+func newTrie(root netip.Prefix) *trie {
+	return &trie{
+		root:  root,
+		pages: map[netip.Prefix]*triePage{root: {key: root}},
+	}
+}
+
+// insert adds the pages on the path to the prefix.
+func (m *trie) insert(prefix netip.Prefix) {
+	m.prefixes++
+
+	depth := 0
+	if prefix.Bits() > 0 {
+		depth = (prefix.Bits() - 1) / trieStride
+	}
+
+	parent := m.pages[m.root]
+	for d := 1; d <= depth; d++ {
+		key := netip.PrefixFrom(prefix.Addr(), d*trieStride).Masked()
+		page, ok := m.pages[key]
+		if !ok {
+			page = &triePage{key: key}
+			m.pages[key] = page
+			parent.children = append(parent.children, key)
+		}
+		parent = page
+	}
+	parent.prefixes++
+}
+
+func (m *trie) stats() *routepb.TrieStats {
+	stats := &routepb.TrieStats{
+		Prefixes: m.prefixes,
+		Pages:    uint64(len(m.pages)),
+	}
+
+	levels := []*routepb.TrieLevel{}
+	for _, page := range m.pages {
+		depth := page.depth()
+		for len(levels) <= depth {
+			levels = append(levels, &routepb.TrieLevel{Depth: uint32(len(levels))})
+		}
+		levels[depth].Pages++
+		levels[depth].Prefixes += page.prefixes
+	}
+	for _, level := range levels {
+		level.Density = float64(level.Prefixes) / float64(level.Pages)
+	}
+	stats.MaxDepth = uint32(len(levels) - 1)
+	stats.Levels = levels
+
+	return stats
+}
+
+// writeDot appends up to limit pages to the DOT output, visited breadth
+// first, and returns the number of pages written.
+func (m *trie) writeDot(b *strings.Builder, limit int) int {
+	queue := []netip.Prefix{m.root}
+	written := 0
+	for len(queue) > 0 && written < limit {
+		page := m.pages[queue[0]]
+		queue = queue[1:]
+
+		fmt.Fprintf(b, "  %q [label=\"%s\\n%d prefixes\"];\n",
+			page.key.String(), page.key, page.prefixes,
+		)
+		written++
+
+		for _, child := range page.children {
+			if written+len(queue) >= limit {
+				break
+			}
+			fmt.Fprintf(b, "  %q -> %q;\n", page.key.String(), child.String())
+			queue = append(queue, child)
+		}
+	}
+
+	return written
+}
+
+// dumpTrie builds the IPv4 and IPv6 trie models of the routes.
+func dumpTrie(routes map[netip.Prefix]verifyRoute, format routepb.TrieDumpFormat, maxNodes uint32) *routepb.DumpTrieResponse {
+	v4 := newTrie(netip.PrefixFrom(netip.IPv4Unspecified(), 0))
+	v6 := newTrie(netip.PrefixFrom(netip.IPv6Unspecified(), 0))
+	for _, route := range sortedRoutes(routes) {
+		if route.prefix.Addr().Is4() {
+			v4.insert(route.prefix)
+		} else {
+			v6.insert(route.prefix)
+		}
+	}
+
+	response := &routepb.DumpTrieResponse{
+		Ipv4: v4.stats(),
+		Ipv6: v6.stats(),
+	}
+	if format != routepb.TrieDumpFormat_TRIE_DUMP_FORMAT_GRAPHVIZ {
+		return response
+	}
+
+	limit := int(maxNodes)
+	if limit == 0 {
+		limit = defaultTrieMaxNodes
+	}
+
+	b := strings.Builder{}
+	b.WriteString("digraph fib {\n")
+	b.WriteString("  node [shape=box];\n")
+	written := v4.writeDot(&b, limit)
+	written += v6.writeDot(&b, limit-written)
+	b.WriteString("}\n")
+
+	response.Dot = b.String()
+	response.Truncated = uint64(written) < response.Ipv4.Pages+response.Ipv6.Pages
+
+	return response
+}
@@ -0,0 +1,61 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func TestDumpTrie(t *testing.T) {
+	routes, err := normalizeRoutes([]*routepb.FIBEntry{
+		{Prefix: "0.0.0.0/0", Blackhole: true},
+		{Prefix: "10.0.0.0/8", Blackhole: true},
+		{Prefix: "10.1.0.0/16", Blackhole: true},
+		{Prefix: "10.1.2.0/24", Blackhole: true},
+		{Prefix: "10.1.3.0/24", Blackhole: true},
+		{Prefix: "10.2.0.128/25", Blackhole: true},
+		{Prefix: "2001:db8::/32", Blackhole: true},
+	})
+	require.NoError(t, err)
+
+	t.Run("stats", func(t *testing.T) {
+		response := dumpTrie(routes, routepb.TrieDumpFormat_TRIE_DUMP_FORMAT_STATS, 0)
+		require.Empty(t, response.GetDot())
+
+		v4 := response.GetIpv4()
+		require.Equal(t, uint64(6), v4.GetPrefixes())
+		// Root, 10/8, 10.1/16, 10.2/16 and 10.2.0/24.
+		require.Equal(t, uint64(5), v4.GetPages())
+		require.Equal(t, uint32(3), v4.GetMaxDepth())
+
+		levels := v4.GetLevels()
+		require.Len(t, levels, 4)
+		require.Equal(t, uint64(2), levels[0].GetPrefixes())
+		require.Equal(t, uint64(1), levels[1].GetPages())
+		require.Equal(t, uint64(1), levels[1].GetPrefixes())
+		require.Equal(t, uint64(2), levels[2].GetPages())
+		require.Equal(t, uint64(2), levels[2].GetPrefixes())
+		require.Equal(t, 1.0, levels[2].GetDensity())
+		require.Equal(t, uint64(1), levels[3].GetPrefixes())
+
+		v6 := response.GetIpv6()
+		require.Equal(t, uint64(1), v6.GetPrefixes())
+		require.Equal(t, uint64(4), v6.GetPages())
+	})
+
+	t.Run("graphviz", func(t *testing.T) {
+		response := dumpTrie(routes, routepb.TrieDumpFormat_TRIE_DUMP_FORMAT_GRAPHVIZ, 0)
+		require.False(t, response.GetTruncated())
+		require.Contains(t, response.GetDot(), `"10.0.0.0/8" -> "10.1.0.0/16";`)
+		require.Contains(t, response.GetDot(), `"2001::/16" -> "2001:d00::/24";`)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		response := dumpTrie(routes, routepb.TrieDumpFormat_TRIE_DUMP_FORMAT_GRAPHVIZ, 3)
+		require.True(t, response.GetTruncated())
+		require.NotContains(t, response.GetDot(), "10.2.0.0/16")
+		require.NotContains(t, response.GetDot(), "::/0")
+	})
+}
@@ -0,0 +1,182 @@
+package route
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// verifyNexthop is the comparable form of a FIB nexthop.
+type verifyNexthop struct {
+	device string
+	dstMAC uint64
+	srcMAC uint64
+	// weight is the effective weight, at least one.
+	weight uint32
+}
+
+func (m verifyNexthop) compare(other verifyNexthop) int {
+	return cmp.Or(
+		strings.Compare(m.device, other.device),
+		cmp.Compare(m.dstMAC, other.dstMAC),
+		cmp.Compare(m.srcMAC, other.srcMAC),
+	)
+}
+
+// verifyRoute is a FIB entry normalized the way the backend installs it.
+type verifyRoute struct {
+	prefix    netip.Prefix
+	blackhole bool
+	nexthops  []verifyNexthop
+}
+
+// String returns the canonical form of the route, which is also the digest
+// input.
+func (m verifyRoute) String() string {
+	if m.blackhole {
+		return m.prefix.String() + " blackhole"
+	}
+
+	b := strings.Builder{}
+	b.WriteString(m.prefix.String())
+	for _, nh := range m.nexthops {
+		fmt.Fprintf(&b, " via %s %012x %012x", nh.device, nh.dstMAC, nh.srcMAC)
+		if nh.weight > 1 {
+			fmt.Fprintf(&b, " weight %d", nh.weight)
+		}
+	}
+
+	return b.String()
+}
+
+func (m verifyRoute) proto() *routepb.FIBEntry {
+	entry := &routepb.FIBEntry{
+		Prefix:    m.prefix.String(),
+		Blackhole: m.blackhole,
+	}
+	for _, nh := range m.nexthops {
+		nexthop := &routepb.FIBNexthop{
+			DstMac: &commonpb.MACAddress{Addr: nh.dstMAC},
+			SrcMac: &commonpb.MACAddress{Addr: nh.srcMAC},
+			Device: nh.device,
+		}
+		if nh.weight > 1 {
+			nexthop.Weight = nh.weight
+		}
+		entry.Nexthops = append(entry.Nexthops, nexthop)
+	}
+
+	return entry
+}
+
+// normalizeRoutes converts FIB entries into routes keyed by prefix.
+//
+// Prefixes are masked and nexthops are deduplicated and sorted, keeping the
+// largest weight of the duplicates like the backend does. Entries
+// without nexthops are skipped unless they are blackholes, because the
+// backend does not install them. A later duplicate prefix overrides an
+// earlier one, as the last LPM insert does.
+func normalizeRoutes(entries []*routepb.FIBEntry) (map[netip.Prefix]verifyRoute, error) {
+	routes := map[netip.Prefix]verifyRoute{}
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prefix %q: %w", entry.GetPrefix(), err)
+		}
+		prefix = prefix.Masked()
+
+		if entry.GetBlackhole() {
+			routes[prefix] = verifyRoute{prefix: prefix, blackhole: true}
+			continue
+		}
+
+		nexthops := make([]verifyNexthop, 0, len(entry.GetNexthops()))
+		for _, nh := range entry.GetNexthops() {
+			nexthops = append(nexthops, verifyNexthop{
+				device: nh.GetDevice(),
+				dstMAC: nh.GetDstMac().GetAddr(),
+				srcMAC: nh.GetSrcMac().GetAddr(),
+				weight: max(nh.GetWeight(), 1),
+			})
+		}
+		slices.SortFunc(nexthops, func(a, b verifyNexthop) int {
+			return cmp.Or(a.compare(b), -cmp.Compare(a.weight, b.weight))
+		})
+		nexthops = slices.CompactFunc(nexthops, func(a, b verifyNexthop) bool {
+			return a.compare(b) == 0
+		})
+		if len(nexthops) == 0 {
+			continue
+		}
+
+		routes[prefix] = verifyRoute{prefix: prefix, nexthops: nexthops}
+	}
+
+	return routes, nil
+}
+
+// sortedRoutes returns the routes ordered by prefix.
+func sortedRoutes(routes map[netip.Prefix]verifyRoute) []verifyRoute {
+	sorted := make([]verifyRoute, 0, len(routes))
+	for _, route := range routes {
+		sorted = append(sorted, route)
+	}
+	slices.SortFunc(sorted, func(a, b verifyRoute) int {
+		return cmp.Or(
+			a.prefix.Addr().Compare(b.prefix.Addr()),
+			cmp.Compare(a.prefix.Bits(), b.prefix.Bits()),
+		)
+	})
+
+	return sorted
+}
+
+// routesDigest returns the hex-encoded SHA-256 digest of the canonical
+// routes.
+func routesDigest(routes map[netip.Prefix]verifyRoute) string {
+	h := sha256.New()
+	for _, route := range sortedRoutes(routes) {
+		h.Write([]byte(route.String()))
+		h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifyRoutes compares the expected routes against the actual ones.
+func verifyRoutes(expected, actual map[netip.Prefix]verifyRoute) *routepb.VerifyRoutesResponse {
+	response := &routepb.VerifyRoutesResponse{
+		Digest: routesDigest(actual),
+	}
+
+	for _, route := range sortedRoutes(expected) {
+		current, ok := actual[route.prefix]
+		switch {
+		case !ok:
+			response.Missing = append(response.Missing, route.proto())
+		case current.String() != route.String():
+			response.Mismatched = append(response.Mismatched, &routepb.RouteMismatch{
+				Expected: route.proto(),
+				Actual:   current.proto(),
+			})
+		}
+	}
+	for _, route := range sortedRoutes(actual) {
+		if _, ok := expected[route.prefix]; !ok {
+			response.Extra = append(response.Extra, route.proto())
+		}
+	}
+
+	response.Converged = len(response.Missing) == 0 &&
+		len(response.Extra) == 0 &&
+		len(response.Mismatched) == 0
+
+	return response
+}
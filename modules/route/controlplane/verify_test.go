@@ -0,0 +1,97 @@
+package route
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+func testNexthop(device string, dst uint64) *routepb.FIBNexthop {
+	return &routepb.FIBNexthop{
+		DstMac: &commonpb.MACAddress{Addr: dst},
+		SrcMac: &commonpb.MACAddress{Addr: 0xcafe},
+		Device: device,
+	}
+}
+
+func TestVerifyRoutes(t *testing.T) {
+	applied := []*routepb.FIBEntry{
+		{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port1", 2)}},
+		{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		{Prefix: "10.0.2.0/24", Blackhole: true},
+		{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 3)}},
+	}
+	actual, err := normalizeRoutes(applied)
+	require.NoError(t, err)
+
+	t.Run("converged", func(t *testing.T) {
+		// Unmasked prefixes, reordered and duplicate nexthops and
+		// nexthops of blackholes do not matter.
+		expected, err := normalizeRoutes([]*routepb.FIBEntry{
+			{Prefix: "2001:db8::1/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 3)}},
+			{Prefix: "10.0.2.0/24", Blackhole: true, Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.1.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1), testNexthop("port0", 1)}},
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2), testNexthop("port0", 1)}},
+		})
+		require.NoError(t, err)
+
+		response := verifyRoutes(expected, actual)
+		require.True(t, response.GetConverged())
+		require.Equal(t, routesDigest(expected), response.GetDigest())
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		expected, err := normalizeRoutes([]*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+			{Prefix: "10.0.2.0/24", Blackhole: true},
+			{Prefix: "2001:db8::/32", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 3)}},
+			{Prefix: "192.168.0.0/16", Nexthops: []*routepb.FIBNexthop{testNexthop("port1", 2)}},
+		})
+		require.NoError(t, err)
+
+		response := verifyRoutes(expected, actual)
+		require.False(t, response.GetConverged())
+		require.NotEqual(t, routesDigest(expected), response.GetDigest())
+
+		require.Len(t, response.GetMissing(), 1)
+		require.Equal(t, "192.168.0.0/16", response.GetMissing()[0].GetPrefix())
+		require.Len(t, response.GetExtra(), 1)
+		require.Equal(t, "10.0.1.0/24", response.GetExtra()[0].GetPrefix())
+		require.Len(t, response.GetMismatched(), 1)
+		require.Equal(t, "10.0.0.0/24", response.GetMismatched()[0].GetActual().GetPrefix())
+		require.Len(t, response.GetMismatched()[0].GetActual().GetNexthops(), 2)
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		weighted := func(device string, mac uint64, weight uint32) *routepb.FIBNexthop {
+			nh := testNexthop(device, mac)
+			nh.Weight = weight
+			return nh
+		}
+
+		// A weight of one is the same as none.
+		same, err := normalizeRoutes([]*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{weighted("port0", 1, 1), testNexthop("port1", 2)}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, actual[netip.MustParsePrefix("10.0.0.0/24")].String(), same[netip.MustParsePrefix("10.0.0.0/24")].String())
+
+		expected, err := normalizeRoutes([]*routepb.FIBEntry{
+			{Prefix: "10.0.0.0/24", Nexthops: []*routepb.FIBNexthop{weighted("port0", 1, 3), testNexthop("port1", 2)}},
+		})
+		require.NoError(t, err)
+
+		response := verifyRoutes(expected, actual)
+		require.Len(t, response.GetMismatched(), 1)
+		require.Equal(t, uint32(3), response.GetMismatched()[0].GetExpected().GetNexthops()[0].GetWeight())
+	})
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		_, err := normalizeRoutes([]*routepb.FIBEntry{{Prefix: "10.0.0.0"}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,247 @@
+package route
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+const (
+	// defaultPrefixWatchTTL is the time a prefix is watched for unless the
+	// request sets one.
+	defaultPrefixWatchTTL = 10 * time.Minute
+	// maxPrefixWatchTTL bounds the time a prefix is watched for, so that a
+	// forgotten watch does not keep logging for good.
+	maxPrefixWatchTTL = time.Hour
+)
+
+// prefixWatchBufferSize is the number of events buffered per WatchPrefix
+// stream before new events are dropped.
+const prefixWatchBufferSize = 1024
+
+// prefixWatch is a prefix watched by a WatchPrefix stream.
+type prefixWatch struct {
+	// config is the watched config, empty for every config.
+	config string
+	prefix netip.Prefix
+	events chan *routepb.PrefixEvent
+}
+
+// prefixWatches is the registry of the watched prefixes.
+type prefixWatches struct {
+	mu      sync.Mutex
+	watches map[*prefixWatch]struct{}
+}
+
+func newPrefixWatches() *prefixWatches {
+	return &prefixWatches{
+		watches: map[*prefixWatch]struct{}{},
+	}
+}
+
+// Add watches the prefix in the named config, every config if empty.
+//
+// The returned function must be called to stop watching.
+func (m *prefixWatches) Add(config string, prefix netip.Prefix) (*prefixWatch, func()) {
+	watch := &prefixWatch{
+		config: config,
+		prefix: prefix.Masked(),
+		events: make(chan *routepb.PrefixEvent, prefixWatchBufferSize),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.watches[watch] = struct{}{}
+
+	return watch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.watches, watch)
+	}
+}
+
+// Config returns the watches of the named config.
+func (m *prefixWatches) Config(config string) []*prefixWatch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	watches := []*prefixWatch{}
+	for watch := range m.watches {
+		if watch.config == "" || watch.config == config {
+			watches = append(watches, watch)
+		}
+	}
+	return watches
+}
+
+// watchedEntry is a FIB entry overlapping a watched prefix.
+type watchedEntry struct {
+	entry *routepb.FIBEntry
+	// route is the entry normalized, to compare its forwarding.
+	route verifyRoute
+}
+
+// watchedEntries indexes the entries overlapping any of the watched
+// prefixes by their masked prefix.
+//
+// Only the entries the backend installs are kept, see normalizeRoutes. The
+// entries with a malformed prefix are never published, so they are left
+// out too.
+func watchedEntries(entries []*routepb.FIBEntry, watches []*prefixWatch) map[netip.Prefix]watchedEntry {
+	watched := map[netip.Prefix]*routepb.FIBEntry{}
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.GetPrefix())
+		if err != nil {
+			continue
+		}
+		prefix = prefix.Masked()
+
+		if slices.ContainsFunc(watches, func(watch *prefixWatch) bool {
+			return watch.prefix.Overlaps(prefix)
+		}) {
+			watched[prefix] = entry
+		}
+	}
+
+	routes, _ := normalizeRoutes(slices.Collect(maps.Values(watched)))
+	out := make(map[netip.Prefix]watchedEntry, len(routes))
+	for prefix, route := range routes {
+		out[prefix] = watchedEntry{entry: watched[prefix], route: route}
+	}
+	return out
+}
+
+// watchFIB reports the changes between two FIBs of a config to the
+// watches of the prefixes they touch.
+//
+// The caller must hold shmLock for writing, so that the changes of a config
+// are reported in the order they are published.
+func (m *RouteService) watchFIB(name string, prev []*routepb.FIBEntry, next []*routepb.FIBEntry, generation uint64) {
+	watches := m.watches.Config(name)
+	if len(watches) == 0 {
+		return
+	}
+
+	before := watchedEntries(prev, watches)
+	after := watchedEntries(next, watches)
+	now := time.Now()
+
+	prefixes := slices.Collect(maps.Keys(after))
+	for prefix := range before {
+		if _, ok := after[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	slices.SortFunc(prefixes, xnetip.PrefixCompare)
+
+	for _, prefix := range prefixes {
+		old, hadEntry := before[prefix]
+		curr, hasEntry := after[prefix]
+
+		var kind routepb.PrefixEventKind
+		switch {
+		case !hadEntry:
+			kind = routepb.PrefixEventKind_PREFIX_EVENT_KIND_ADDED
+		case !hasEntry:
+			kind = routepb.PrefixEventKind_PREFIX_EVENT_KIND_REMOVED
+			curr = old
+		case !old.route.equal(curr.route):
+			kind = routepb.PrefixEventKind_PREFIX_EVENT_KIND_CHANGED
+		default:
+			continue
+		}
+		entry := curr.entry
+
+		watched := []string{}
+		for _, watch := range watches {
+			if !watch.prefix.Overlaps(prefix) {
+				continue
+			}
+			watched = append(watched, watch.prefix.String())
+
+			event := &routepb.PrefixEvent{
+				Name:          name,
+				Kind:          kind,
+				Entry:         entry,
+				Generation:    generation,
+				WatchedPrefix: watch.prefix.String(),
+				Timestamp:     now.UnixNano(),
+			}
+			// A stream that does not keep up misses the event, which is
+			// still logged.
+			select {
+			case watch.events <- event:
+			default:
+			}
+		}
+		slices.Sort(watched)
+
+		m.log.Info("watched prefix changed",
+			zap.String("name", name),
+			zap.Stringer("kind", kind),
+			zap.Stringer("prefix", prefix),
+			zap.Strings("watched", slices.Compact(watched)),
+			zap.Int("nexthops", len(entry.GetNexthops())),
+			zap.Bool("blackhole", entry.GetBlackhole()),
+			zap.Uint64("generation", generation),
+		)
+	}
+}
+
+// WatchPrefix streams the changes of the FIB entries overlapping a prefix
+// until the TTL of the watch expires or the client goes away.
+//
+// Events are dropped for streams that do not keep up with the changes.
+func (m *RouteService) WatchPrefix(
+	req *routepb.WatchPrefixRequest,
+	stream routepb.RouteService_WatchPrefixServer,
+) error {
+	prefix, err := netip.ParsePrefix(req.GetPrefix())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid prefix %q: %v", req.GetPrefix(), err)
+	}
+
+	ttl := defaultPrefixWatchTTL
+	if req.GetTtl() != nil {
+		ttl = req.GetTtl().AsDuration()
+		if ttl <= 0 || ttl > maxPrefixWatchTTL {
+			return status.Errorf(codes.InvalidArgument, "ttl must be within (0, %s], got %s", maxPrefixWatchTTL, ttl)
+		}
+	}
+
+	watch, stop := m.watches.Add(req.GetName(), prefix)
+	defer stop()
+
+	log := m.log.With(
+		zap.String("name", req.GetName()),
+		zap.Stringer("prefix", watch.prefix),
+	)
+	log.Info("watching prefix", zap.Duration("ttl", ttl))
+
+	ctx, cancel := context.WithTimeout(stream.Context(), ttl)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stopped watching prefix")
+			return nil
+		case event := <-watch.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
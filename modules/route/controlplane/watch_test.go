@@ -0,0 +1,141 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+)
+
+// fakeWatchPrefixStream collects the events sent by WatchPrefix.
+type fakeWatchPrefixStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	events chan *routepb.PrefixEvent
+}
+
+func (m *fakeWatchPrefixStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *fakeWatchPrefixStream) Send(event *routepb.PrefixEvent) error {
+	m.events <- event
+	return nil
+}
+
+// watchedEvents returns the kinds and prefixes of the events queued for a
+// watch.
+func watchedEvents(watch *prefixWatch) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-watch.events:
+			events = append(events, event.GetKind().String()+" "+event.GetEntry().GetPrefix())
+		default:
+			return events
+		}
+	}
+}
+
+func TestWatchFIB(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	watch, stop := svc.watches.Add("route0", netip.MustParsePrefix("10.0.1.0/24"))
+	defer stop()
+	everywhere, stopEverywhere := svc.watches.Add("", netip.MustParsePrefix("10.1.0.0/16"))
+	defer stopEverywhere()
+
+	update := func(name string, entries ...*routepb.FIBEntry) {
+		_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{ModuleName: name, Entries: entries})
+		require.NoError(t, err)
+	}
+	update("route0",
+		&routepb.FIBEntry{Prefix: "10.0.0.0/16", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		&routepb.FIBEntry{Prefix: "10.0.1.128/25", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+		&routepb.FIBEntry{Prefix: "10.0.2.0/24", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+	)
+	// The less-specific covering the watched prefix and its more-specific
+	// are reported, the unrelated prefix is not.
+	require.Equal(t, []string{
+		"PREFIX_EVENT_KIND_ADDED 10.0.0.0/16",
+		"PREFIX_EVENT_KIND_ADDED 10.0.1.128/25",
+	}, watchedEvents(watch))
+
+	// Only the entries that changed are reported.
+	update("route0",
+		&routepb.FIBEntry{Prefix: "10.0.0.0/16", Blackhole: true},
+		&routepb.FIBEntry{Prefix: "10.0.1.128/25", Nexthops: []*routepb.FIBNexthop{testNexthop("port0", 1)}},
+	)
+	require.Equal(t, []string{"PREFIX_EVENT_KIND_CHANGED 10.0.0.0/16"}, watchedEvents(watch))
+
+	// The watch of every config sees the other configs, the watch of a
+	// config does not.
+	update("route1", &routepb.FIBEntry{Prefix: "10.0.1.0/24", Blackhole: true}, &routepb.FIBEntry{Prefix: "10.1.1.0/24", Blackhole: true})
+	require.Empty(t, watchedEvents(watch))
+	require.Equal(t, []string{"PREFIX_EVENT_KIND_ADDED 10.1.1.0/24"}, watchedEvents(everywhere))
+
+	_, err := svc.DeleteConfig(t.Context(), &routepb.DeleteConfigRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"PREFIX_EVENT_KIND_REMOVED 10.0.0.0/16",
+		"PREFIX_EVENT_KIND_REMOVED 10.0.1.128/25",
+	}, watchedEvents(watch))
+
+	// Nothing is reported once the watch stops.
+	stop()
+	update("route0", &routepb.FIBEntry{Prefix: "10.0.1.0/24", Blackhole: true})
+	require.Empty(t, watchedEvents(watch))
+}
+
+func TestWatchPrefix(t *testing.T) {
+	backend := &installedBackend{installed: map[string][]*routepb.FIBEntry{}}
+	svc := NewRouteService(backend)
+
+	stream := &fakeWatchPrefixStream{ctx: t.Context(), events: make(chan *routepb.PrefixEvent, 1)}
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.WatchPrefix(&routepb.WatchPrefixRequest{
+			Name:   "route0",
+			Prefix: "10.0.1.0/24",
+			Ttl:    durationpb.New(200 * time.Millisecond),
+		}, stream)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(svc.watches.Config("route0")) == 1
+	}, time.Second, time.Millisecond)
+	_, err := svc.UpdateFIB(t.Context(), &routepb.UpdateFIBRequest{
+		ModuleName: "route0",
+		Entries:    []*routepb.FIBEntry{{Prefix: "10.0.1.0/24", Blackhole: true}},
+	})
+	require.NoError(t, err)
+
+	event := <-stream.events
+	require.Equal(t, "route0", event.GetName())
+	require.Equal(t, routepb.PrefixEventKind_PREFIX_EVENT_KIND_ADDED, event.GetKind())
+	require.Equal(t, "10.0.1.0/24", event.GetWatchedPrefix())
+	require.Equal(t, uint64(1), event.GetGeneration())
+
+	// The watch ends with its TTL.
+	require.NoError(t, <-done)
+	require.Empty(t, svc.watches.Config("route0"))
+
+	for _, req := range []*routepb.WatchPrefixRequest{
+		{Prefix: "10.0.1.0"},
+		{Prefix: "10.0.1.0/24", Ttl: durationpb.New(2 * time.Hour)},
+		{Prefix: "10.0.1.0/24", Ttl: durationpb.New(0)},
+	} {
+		err := svc.WatchPrefix(req, stream)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+}
@@ -20,6 +20,67 @@ struct route_list {
 	uint64_t count;
 };
 
+/*
+ * Unicast reverse path forwarding (RFC 3704) check applied to packets
+ * received on a device.
+ *
+ * Loose mode accepts a packet when its source address has a usable route
+ * in the FIB. Strict mode additionally requires one of the nexthops of
+ * that route to egress through the device the packet was received on.
+ */
+enum route_urpf_mode {
+	ROUTE_URPF_MODE_NONE = 0,
+	ROUTE_URPF_MODE_LOOSE = 1,
+	ROUTE_URPF_MODE_STRICT = 2,
+};
+
+struct route_urpf {
+	uint64_t mode;
+	// Counter of packets dropped by the check on this device
+	uint64_t drop_counter_id;
+};
+
+/*
+ * Neighbour proxy answering on behalf of routed prefixes.
+ *
+ * A device with proxy-ARP enabled replies to ARP requests and a device
+ * with proxy-NDP enabled replies to Neighbor Solicitations for target
+ * addresses that have a usable route egressing through another device.
+ */
+enum route_neigh_proxy_flag {
+	ROUTE_NEIGH_PROXY_ARP = 1 << 0,
+	ROUTE_NEIGH_PROXY_NDP = 1 << 1,
+};
+
+struct route_neigh_proxy {
+	uint64_t flags;
+	// Link-layer address advertised in replies
+	struct ether_addr mac;
+	// Counter of replies sent on this device
+	uint64_t reply_counter_id;
+};
+
+/*
+ * Inputs of the hash selecting the nexthop of a flow among the multipath
+ * routes of its destination.
+ *
+ * Without ROUTE_HASH_CUSTOM the packet hash computed on parsing, covering
+ * the addresses and the TCP/UDP ports, is used and the other flags are
+ * ignored. Otherwise the hash covers the addresses and the protocol, plus
+ * the flags set.
+ */
+enum route_hash_flag {
+	ROUTE_HASH_CUSTOM = 1 << 0,
+	// TCP/UDP ports, making a 5-tuple hash of a 3-tuple one. Fragments
+	// carry no ports, so they always hash by the 3-tuple.
+	ROUTE_HASH_PORTS = 1 << 1,
+	// IPv6 flow label
+	ROUTE_HASH_FLOW_LABEL = 1 << 2,
+	// Hash both directions of a flow alike by ordering the source and
+	// destination fields before hashing them
+	ROUTE_HASH_SYMMETRIC = 1 << 3,
+};
+
 /*
  * Route module configuration. Handler lookups route list index using
  * corresponding lpm and retrieves start position and count of applicable
@@ -43,4 +104,15 @@ struct route_module_config {
 	// Route indexes storage
 	uint64_t route_index_count;
 	uint64_t *route_indexes;
+
+	// uRPF settings indexed by the module device index
+	uint64_t urpf_count;
+	struct route_urpf *urpf;
+
+	// Neighbour proxy settings indexed by the module device index
+	uint64_t neigh_proxy_count;
+	struct route_neigh_proxy *neigh_proxy;
+
+	// Combination of enum route_hash_flag
+	uint64_t hash_flags;
 };
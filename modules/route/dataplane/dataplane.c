@@ -1,10 +1,15 @@
 #include "config.h"
 
+#include <rte_arp.h>
 #include <rte_ether.h>
+#include <rte_icmp.h>
 #include <rte_ip.h>
 #include <rte_mbuf.h>
+#include <rte_udp.h>
 
+#include "common/crc32.h"
 #include "common/memory.h"
+#include "counters/counters.h"
 #include "lib/logging/log.h"
 
 #include "dataplane/config/zone.h"
@@ -60,6 +65,479 @@ route_handle_v6(struct route_module_config *config, struct packet *packet) {
 	return lpm_lookup(&config->lpm_v6, 16, header->dst_addr);
 }
 
+/*
+ * Folds the source and destination fields of a flow into the hash.
+ *
+ * Symmetric hashing folds the lower one first, so both directions of the
+ * flow produce the same hash.
+ */
+static inline uint32_t
+route_hash_pair(
+	const void *src, const void *dst, uint64_t len, int symmetric, uint32_t hash
+) {
+	if (symmetric && memcmp(src, dst, len) > 0) {
+		const void *tmp = src;
+		src = dst;
+		dst = tmp;
+	}
+	hash = crc32(src, len, hash);
+	return crc32(dst, len, hash);
+}
+
+/*
+ * Returns the hash selecting the multipath nexthop of the packet.
+ *
+ * The packet is known to be IPv4 or IPv6 with its headers validated by the
+ * parser.
+ */
+static uint32_t
+route_flow_hash(struct route_module_config *config, struct packet *packet) {
+	uint64_t flags = config->hash_flags;
+	if (!(flags & ROUTE_HASH_CUSTOM)) {
+		return packet->hash;
+	}
+
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+	int symmetric = (flags & ROUTE_HASH_SYMMETRIC) != 0;
+	uint32_t hash = 0;
+
+	if (packet->network_header.type ==
+	    rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4)) {
+		struct rte_ipv4_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv4_hdr *, packet->network_header.offset
+		);
+		hash = route_hash_pair(
+			&header->src_addr, &header->dst_addr, 4, symmetric, hash
+		);
+	} else {
+		struct rte_ipv6_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
+		);
+		hash = route_hash_pair(
+			header->src_addr, header->dst_addr, 16, symmetric, hash
+		);
+		if (flags & ROUTE_HASH_FLOW_LABEL) {
+			uint32_t flow_label =
+				rte_be_to_cpu_32(header->vtc_flow) & 0xFFFFF;
+			hash = crc32(&flow_label, sizeof(flow_label), hash);
+		}
+	}
+
+	uint8_t proto = (uint8_t)packet->transport_header.type;
+	hash = crc32(&proto, sizeof(proto), hash);
+
+	if ((flags & ROUTE_HASH_PORTS) &&
+	    !(packet->flags & (1 << PACKET_FLAG_FRAGMENTED)) &&
+	    (proto == IPPROTO_TCP || proto == IPPROTO_UDP)) {
+		// TCP and UDP headers both start with the ports, which the
+		// parser checked to be present.
+		struct rte_udp_hdr *ports = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_udp_hdr *, packet->transport_header.offset
+		);
+		hash = route_hash_pair(
+			&ports->src_port, &ports->dst_port, 2, symmetric, hash
+		);
+	}
+
+	return hash;
+}
+
+/*
+ * Returns true if any nexthop of the route list egresses through the
+ * device.
+ */
+static bool
+route_list_uses_device(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	struct route_list *route_list,
+	uint16_t device_id
+) {
+	uint64_t *route_indexes = ADDR_OF(&config->route_indexes);
+	struct route *routes = ADDR_OF(&config->routes);
+	for (uint64_t idx = 0; idx < route_list->count; ++idx) {
+		struct route *route =
+			routes + route_indexes[route_list->start + idx];
+		if (module_ectx_encode_device(module_ectx, route->device_id) ==
+		    device_id) {
+			return true;
+		}
+	}
+
+	return false;
+}
+
+/*
+ * Returns the uRPF settings of the device the packet was received on or
+ * NULL if the device is not linked to the module or has no uRPF settings.
+ */
+static struct route_urpf *
+route_urpf_get(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	struct packet *packet
+) {
+	if (config->urpf_count == 0 ||
+	    packet->rx_device_id >= module_ectx->cm_index_size) {
+		return NULL;
+	}
+
+	/*
+	 * Devices not linked to the module are mapped to index 0, so the
+	 * reverse mapping is checked to tell them apart from device 0.
+	 */
+	uint64_t device_index =
+		module_ectx_decode_device(module_ectx, packet->rx_device_id);
+	if (device_index >= config->urpf_count ||
+	    module_ectx_encode_device(module_ectx, device_index) !=
+		    packet->rx_device_id) {
+		return NULL;
+	}
+
+	return ADDR_OF(&config->urpf) + device_index;
+}
+
+static uint32_t
+route_urpf_lookup(struct route_module_config *config, struct packet *packet) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	if (packet->network_header.type ==
+	    rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4)) {
+		struct rte_ipv4_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv4_hdr *, packet->network_header.offset
+		);
+		return lpm_lookup(
+			&config->lpm_v4, 4, (uint8_t *)&header->src_addr
+		);
+	}
+
+	if (packet->network_header.type ==
+	    rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
+		struct rte_ipv6_hdr *header = rte_pktmbuf_mtod_offset(
+			mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
+		);
+		return lpm_lookup(&config->lpm_v6, 16, header->src_addr);
+	}
+
+	return LPM_VALUE_INVALID;
+}
+
+/*
+ * Checks the packet source address against the FIB according to the uRPF
+ * mode of the receiving device.
+ *
+ * Blackhole routes have no nexthops and never pass the check.
+ */
+static bool
+route_urpf_check(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	struct route_urpf *urpf,
+	struct packet *packet
+) {
+	uint32_t route_list_id = route_urpf_lookup(config, packet);
+	if (route_list_id == LPM_VALUE_INVALID) {
+		return false;
+	}
+
+	struct route_list *route_list =
+		ADDR_OF(&config->route_lists) + route_list_id;
+	if (route_list->count == 0) {
+		return false;
+	}
+
+	if (urpf->mode != ROUTE_URPF_MODE_STRICT) {
+		return true;
+	}
+
+	return route_list_uses_device(
+		config, module_ectx, route_list, packet->rx_device_id
+	);
+}
+
+/*
+ * Returns the neighbour proxy settings of the device the packet was
+ * received on or NULL if proxying is not configured for the device.
+ */
+static struct route_neigh_proxy *
+route_neigh_proxy_get(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	struct packet *packet
+) {
+	if (config->neigh_proxy_count == 0 ||
+	    packet->rx_device_id >= module_ectx->cm_index_size) {
+		return NULL;
+	}
+
+	uint64_t device_index =
+		module_ectx_decode_device(module_ectx, packet->rx_device_id);
+	if (device_index >= config->neigh_proxy_count ||
+	    module_ectx_encode_device(module_ectx, device_index) !=
+		    packet->rx_device_id) {
+		return NULL;
+	}
+
+	struct route_neigh_proxy *neigh_proxy =
+		ADDR_OF(&config->neigh_proxy) + device_index;
+	if (neigh_proxy->flags == 0) {
+		return NULL;
+	}
+
+	return neigh_proxy;
+}
+
+/*
+ * Checks whether the router owns the target address: the FIB must hold a
+ * usable route for it that does not lead back to the receiving device,
+ * otherwise the real owner is on the same segment and answers itself.
+ */
+static bool
+route_neigh_proxy_owns(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	struct packet *packet,
+	uint32_t route_list_id
+) {
+	if (route_list_id == LPM_VALUE_INVALID) {
+		return false;
+	}
+
+	struct route_list *route_list =
+		ADDR_OF(&config->route_lists) + route_list_id;
+	if (route_list->count == 0) {
+		return false;
+	}
+
+	return !route_list_uses_device(
+		config, module_ectx, route_list, packet->rx_device_id
+	);
+}
+
+static void
+route_neigh_proxy_set_ether(
+	struct packet *packet, const struct route_neigh_proxy *neigh_proxy
+) {
+	struct rte_ether_hdr *ether_hdr = rte_pktmbuf_mtod_offset(
+		packet_to_mbuf(packet), struct rte_ether_hdr *, 0
+	);
+
+	rte_ether_addr_copy(&ether_hdr->src_addr, &ether_hdr->dst_addr);
+	memcpy(ether_hdr->src_addr.addr_bytes,
+	       neigh_proxy->mac.addr,
+	       sizeof(neigh_proxy->mac));
+}
+
+/*
+ * Turns an ARP request for an owned IPv4 address into a reply in place.
+ *
+ * Returns true if the packet was turned into a reply.
+ */
+static bool
+route_neigh_proxy_arp(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	const struct route_neigh_proxy *neigh_proxy,
+	struct packet *packet
+) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	if (rte_pktmbuf_pkt_len(mbuf) <
+	    (uint32_t)packet->network_header.offset +
+		    sizeof(struct rte_arp_hdr)) {
+		return false;
+	}
+
+	struct rte_arp_hdr *arp_hdr = rte_pktmbuf_mtod_offset(
+		mbuf, struct rte_arp_hdr *, packet->network_header.offset
+	);
+	if (arp_hdr->arp_hardware != rte_cpu_to_be_16(RTE_ARP_HRD_ETHER) ||
+	    arp_hdr->arp_protocol != rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV4) ||
+	    arp_hdr->arp_hlen != RTE_ETHER_ADDR_LEN ||
+	    arp_hdr->arp_plen != sizeof(rte_be32_t) ||
+	    arp_hdr->arp_opcode != rte_cpu_to_be_16(RTE_ARP_OP_REQUEST)) {
+		return false;
+	}
+
+	struct rte_arp_ipv4 *arp_data = &arp_hdr->arp_data;
+	// Gratuitous ARP announces the sender itself and needs no reply.
+	if (arp_data->arp_sip == arp_data->arp_tip) {
+		return false;
+	}
+
+	uint32_t route_list_id = lpm_lookup(
+		&config->lpm_v4, 4, (uint8_t *)&arp_data->arp_tip
+	);
+	if (!route_neigh_proxy_owns(
+		    config, module_ectx, packet, route_list_id
+	    )) {
+		return false;
+	}
+
+	rte_be32_t target = arp_data->arp_tip;
+	arp_hdr->arp_opcode = rte_cpu_to_be_16(RTE_ARP_OP_REPLY);
+	rte_ether_addr_copy(&arp_data->arp_sha, &arp_data->arp_tha);
+	arp_data->arp_tip = arp_data->arp_sip;
+	memcpy(arp_data->arp_sha.addr_bytes,
+	       neigh_proxy->mac.addr,
+	       sizeof(neigh_proxy->mac));
+	arp_data->arp_sip = target;
+
+	route_neigh_proxy_set_ether(packet, neigh_proxy);
+
+	return true;
+}
+
+#define ROUTE_ND_NEIGHBOR_SOLICIT 135
+#define ROUTE_ND_NEIGHBOR_ADVERT 136
+#define ROUTE_ND_OPT_TARGET_LL_ADDR 2
+
+#define ROUTE_ND_NA_FLAG_SOLICITED 0x40
+#define ROUTE_ND_NA_FLAG_OVERRIDE 0x20
+
+/*
+ * Neighbor Solicitation and Advertisement share the layout up to the
+ * target address, see RFC 4861 sections 4.3 and 4.4.
+ */
+struct route_nd_msg {
+	uint8_t type;
+	uint8_t code;
+	rte_be16_t checksum;
+	uint8_t flags;
+	uint8_t reserved[3];
+	uint8_t target[16];
+} __rte_packed;
+
+struct route_nd_opt_ll_addr {
+	uint8_t type;
+	uint8_t len;
+	struct rte_ether_addr addr;
+} __rte_packed;
+
+/*
+ * Turns a Neighbor Solicitation for an owned IPv6 address into a solicited
+ * Neighbor Advertisement with the target link-layer address option.
+ *
+ * Returns true if the packet was turned into an advertisement.
+ */
+static bool
+route_neigh_proxy_ndp(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	const struct route_neigh_proxy *neigh_proxy,
+	struct packet *packet
+) {
+	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
+
+	// Solicitations carrying extension headers are left to the host.
+	uint16_t nd_offset =
+		packet->network_header.offset + sizeof(struct rte_ipv6_hdr);
+	if (packet->transport_header.type != IPPROTO_ICMPV6 ||
+	    packet->transport_header.offset != nd_offset ||
+	    !rte_pktmbuf_is_contiguous(mbuf) ||
+	    rte_pktmbuf_pkt_len(mbuf) <
+		    (uint32_t)nd_offset + sizeof(struct route_nd_msg)) {
+		return false;
+	}
+
+	struct rte_ipv6_hdr *ipv6_hdr = rte_pktmbuf_mtod_offset(
+		mbuf, struct rte_ipv6_hdr *, packet->network_header.offset
+	);
+	struct route_nd_msg *nd_msg =
+		rte_pktmbuf_mtod_offset(mbuf, struct route_nd_msg *, nd_offset);
+	if (nd_msg->type != ROUTE_ND_NEIGHBOR_SOLICIT || nd_msg->code != 0 ||
+	    ipv6_hdr->hop_limits != 255) {
+		return false;
+	}
+
+	// Duplicate address detection probes come from the unspecified
+	// address and are never proxied.
+	static const uint8_t unspecified[16] = {0};
+	if (memcmp(ipv6_hdr->src_addr, unspecified, sizeof(unspecified)) ==
+	    0) {
+		return false;
+	}
+
+	uint32_t route_list_id =
+		lpm_lookup(&config->lpm_v6, 16, nd_msg->target);
+	if (!route_neigh_proxy_owns(
+		    config, module_ectx, packet, route_list_id
+	    )) {
+		return false;
+	}
+
+	uint16_t reply_len = sizeof(struct route_nd_msg) +
+			     sizeof(struct route_nd_opt_ll_addr);
+	uint32_t pkt_len = (uint32_t)nd_offset + reply_len;
+	if (rte_pktmbuf_pkt_len(mbuf) > pkt_len) {
+		rte_pktmbuf_trim(mbuf, rte_pktmbuf_pkt_len(mbuf) - pkt_len);
+	} else if (rte_pktmbuf_pkt_len(mbuf) < pkt_len &&
+		   rte_pktmbuf_append(
+			   mbuf, pkt_len - rte_pktmbuf_pkt_len(mbuf)
+		   ) == NULL) {
+		return false;
+	}
+	packet->data_len = rte_pktmbuf_data_len(mbuf);
+
+	memcpy(ipv6_hdr->dst_addr,
+	       ipv6_hdr->src_addr,
+	       sizeof(ipv6_hdr->dst_addr));
+	memcpy(ipv6_hdr->src_addr, nd_msg->target, sizeof(nd_msg->target));
+	ipv6_hdr->payload_len = rte_cpu_to_be_16(reply_len);
+
+	nd_msg->type = ROUTE_ND_NEIGHBOR_ADVERT;
+	nd_msg->flags = ROUTE_ND_NA_FLAG_SOLICITED | ROUTE_ND_NA_FLAG_OVERRIDE;
+	memset(nd_msg->reserved, 0, sizeof(nd_msg->reserved));
+
+	struct route_nd_opt_ll_addr *opt =
+		(struct route_nd_opt_ll_addr *)(nd_msg + 1);
+	opt->type = ROUTE_ND_OPT_TARGET_LL_ADDR;
+	opt->len = 1;
+	memcpy(opt->addr.addr_bytes,
+	       neigh_proxy->mac.addr,
+	       sizeof(neigh_proxy->mac));
+
+	nd_msg->checksum = 0;
+	nd_msg->checksum = rte_ipv6_udptcp_cksum(ipv6_hdr, nd_msg);
+
+	route_neigh_proxy_set_ether(packet, neigh_proxy);
+
+	return true;
+}
+
+/*
+ * Answers ARP requests and Neighbor Solicitations for owned addresses on
+ * devices with the neighbour proxy enabled.
+ *
+ * Returns true if the packet was turned into a reply which must be sent
+ * back through the receiving device.
+ */
+static bool
+route_neigh_proxy_handle(
+	struct route_module_config *config,
+	struct module_ectx *module_ectx,
+	const struct route_neigh_proxy *neigh_proxy,
+	struct packet *packet
+) {
+	if (packet->network_header.type ==
+	    rte_cpu_to_be_16(RTE_ETHER_TYPE_ARP)) {
+		return (neigh_proxy->flags & ROUTE_NEIGH_PROXY_ARP) &&
+		       route_neigh_proxy_arp(
+			       config, module_ectx, neigh_proxy, packet
+		       );
+	}
+
+	if (packet->network_header.type ==
+	    rte_cpu_to_be_16(RTE_ETHER_TYPE_IPV6)) {
+		return (neigh_proxy->flags & ROUTE_NEIGH_PROXY_NDP) &&
+		       route_neigh_proxy_ndp(
+			       config, module_ectx, neigh_proxy, packet
+		       );
+	}
+
+	return false;
+}
+
 static void
 route_set_packet_destination(struct packet *packet, struct route *route) {
 	struct rte_mbuf *mbuf = packet_to_mbuf(packet);
@@ -113,8 +591,6 @@ route_handle_packets(
 	struct module_ectx *module_ectx,
 	struct packet_front *packet_front
 ) {
-	(void)dp_worker;
-
 	struct route_module_config *route_config = container_of(
 		ADDR_OF(&module_ectx->cp_module),
 		struct route_module_config,
@@ -123,6 +599,40 @@ route_handle_packets(
 
 	struct packet *packet;
 	while ((packet = packet_list_pop(&packet_front->input)) != NULL) {
+		struct route_neigh_proxy *neigh_proxy = route_neigh_proxy_get(
+			route_config, module_ectx, packet
+		);
+		if (neigh_proxy != NULL &&
+		    route_neigh_proxy_handle(
+			    route_config, module_ectx, neigh_proxy, packet
+		    )) {
+			uint64_t *reply_counter = counter_get_address(
+				neigh_proxy->reply_counter_id,
+				dp_worker->idx,
+				ADDR_OF(&module_ectx->counter_storage)
+			);
+			reply_counter[0] += 1;
+
+			packet->tx_device_id = packet->rx_device_id;
+			packet_list_add(&packet_front->pending_output, packet);
+			continue;
+		}
+
+		struct route_urpf *urpf =
+			route_urpf_get(route_config, module_ectx, packet);
+		if (urpf != NULL && urpf->mode != ROUTE_URPF_MODE_NONE &&
+		    !route_urpf_check(route_config, module_ectx, urpf, packet)) {
+			uint64_t *drop_counter = counter_get_address(
+				urpf->drop_counter_id,
+				dp_worker->idx,
+				ADDR_OF(&module_ectx->counter_storage)
+			);
+			drop_counter[0] += 1;
+
+			packet_front_drop(packet_front, packet);
+			continue;
+		}
+
 		uint32_t route_list_id = 0;
 
 		if (packet->network_header.type ==
@@ -147,10 +657,9 @@ route_handle_packets(
 			continue;
 		}
 
-		// TODO: Route selection should be based on hash/NUMA/dp
-		// instance/etc
+		uint32_t hash = route_flow_hash(route_config, packet);
 		uint64_t route_index = ADDR_OF(&route_config->route_indexes
-		)[route_list->start + packet->hash % route_list->count];
+		)[route_list->start + hash % route_list->count];
 
 		struct route *route =
 			ADDR_OF(&route_config->routes) + route_index;
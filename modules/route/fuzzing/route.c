@@ -54,6 +54,14 @@ route_test_config(struct cp_module **cp_module, yanet_error **err) {
 	config->route_index_count = 0;
 	config->route_indexes = NULL;
 
+	config->urpf_count = 0;
+	config->urpf = NULL;
+
+	config->neigh_proxy_count = 0;
+	config->neigh_proxy = NULL;
+
+	config->hash_flags = 0;
+
 	struct cp_module *rmc = &config->cp_module;
 
 	int route_idx = route_module_config_add_route(
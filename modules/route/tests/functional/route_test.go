@@ -8,6 +8,7 @@ import (
 	"github.com/c2h5oh/datasize"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/layers"
 	"github.com/stretchr/testify/require"
 
@@ -133,6 +134,18 @@ func applyFIB(
 	entries []FIBEntry,
 ) route.ModuleHandle {
 	tb.Helper()
+	return applyFIBWithURPF(tb, backend, name, entries, nil)
+}
+
+// applyFIBWithURPF is applyFIB also applying the given uRPF settings.
+func applyFIBWithURPF(
+	tb testing.TB,
+	backend route.Backend,
+	name string,
+	entries []FIBEntry,
+	urpf []*routepb.URPFInterface,
+) route.ModuleHandle {
+	tb.Helper()
 
 	pbEntries := make([]*routepb.FIBEntry, 0, len(entries))
 	for _, e := range entries {
@@ -150,7 +163,7 @@ func applyFIB(
 		})
 	}
 
-	handle, err := backend.UpdateModule(name, pbEntries)
+	handle, err := backend.UpdateModule(name, pbEntries, urpf, nil, nil)
 	require.NoError(tb, err)
 	tb.Cleanup(handle.Free)
 	return handle
@@ -450,6 +463,248 @@ func TestRoute_NonIP_Drop(t *testing.T) {
 	require.Len(t, result.Drop, 1, "expected exactly one dropped packet")
 }
 
+// TestRoute_URPF verifies that packets received on a device with uRPF
+// enabled are dropped unless their source address passes the check, counting
+// the drops per device.
+//
+// The source prefixes are routed through the receiving device ("port0") and
+// through another one ("phantom"), which only the strict mode tells apart.
+func TestRoute_URPF(t *testing.T) {
+	otherHop := FIBNexthop{
+		DstMAC: xerror.Unwrap(net.ParseMAC("de:ad:00:00:00:ff")),
+		SrcMAC: xerror.Unwrap(net.ParseMAC("ca:fe:00:00:00:ff")),
+		Device: "phantom",
+	}
+	entries := []FIBEntry{
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Nexthops: []FIBNexthop{routeNextHop}},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Nexthops: []FIBNexthop{routeNextHop}},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/24"), Nexthops: []FIBNexthop{otherHop}},
+	}
+
+	cases := []struct {
+		name            string
+		mode            routepb.URPFMode
+		src             string
+		expectForwarded bool
+	}{
+		{name: "none_no_route_forward", mode: routepb.URPFMode_URPF_MODE_NONE, src: "10.9.0.1", expectForwarded: true},
+		{name: "loose_same_device_forward", mode: routepb.URPFMode_URPF_MODE_LOOSE, src: "10.0.0.1", expectForwarded: true},
+		{name: "loose_other_device_forward", mode: routepb.URPFMode_URPF_MODE_LOOSE, src: "10.1.0.1", expectForwarded: true},
+		{name: "loose_no_route_drop", mode: routepb.URPFMode_URPF_MODE_LOOSE, src: "10.9.0.1", expectForwarded: false},
+		{name: "strict_same_device_forward", mode: routepb.URPFMode_URPF_MODE_STRICT, src: "10.0.0.1", expectForwarded: true},
+		{name: "strict_other_device_drop", mode: routepb.URPFMode_URPF_MODE_STRICT, src: "10.1.0.1", expectForwarded: false},
+		{name: "strict_no_route_drop", mode: routepb.URPFMode_URPF_MODE_STRICT, src: "10.9.0.1", expectForwarded: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			eth, ip4, _, icmp := testingEtherLayers()
+			ip4.SrcIP = net.ParseIP(tc.src)
+
+			pkt := xpacket.LayersToPacket(t, &eth, &ip4, &icmp)
+
+			h, agent, backend := setupRouteHarness(t, "port0")
+			applyFIBWithURPF(t, backend, "test", entries, []*routepb.URPFInterface{
+				{Device: "port0", Mode: tc.mode},
+			})
+			wirePipeline(t, agent, "port0", "test")
+
+			result, err := h.HandlePackets(pkt)
+			require.NoError(t, err)
+
+			drops := uint64(1)
+			if tc.expectForwarded {
+				drops = 0
+				require.Len(t, result.Output, 1, "expected forwarded packet")
+				require.Empty(t, result.Drop, "expected no dropped packets")
+			} else {
+				require.Empty(t, result.Output, "expected no forwarded packets")
+				require.Len(t, result.Drop, 1, "expected dropped packet")
+			}
+
+			byName := dataplaneut.SingleValueCounters(h.SharedMemory().DPConfig(0).ModuleCounters(
+				"port0", "test", "test", "test_chain", "route", "test",
+				[]string{"urpf_drop port0"},
+			))
+			require.Contains(t, byName, "urpf_drop port0")
+			require.Equal(t, drops, byName["urpf_drop port0"], "uRPF drop counter mismatch")
+		})
+	}
+}
+
+// TestRoute_NeighbourProxy_ARP verifies that an ARP request for an address
+// routed through another device is answered on the receiving device when
+// proxy-ARP is enabled on it.
+func TestRoute_NeighbourProxy_ARP(t *testing.T) {
+	proxyMAC := xerror.Unwrap(net.ParseMAC("ca:fe:00:00:00:01"))
+
+	h, agent, backend := setupRouteHarness(t, "port0")
+	handle, err := backend.UpdateModule("test", []*routepb.FIBEntry{
+		{
+			Prefix: "10.0.0.0/24",
+			Nexthops: []*routepb.FIBNexthop{{
+				DstMac: commonpb.NewMACAddressEUI48([6]byte(routeNextHop.DstMAC)),
+				SrcMac: commonpb.NewMACAddressEUI48([6]byte(routeNextHop.SrcMAC)),
+				Device: "phantom",
+			}},
+		},
+	}, nil, []*routepb.NeighbourProxyInterface{
+		{
+			Device: "port0",
+			Arp:    true,
+			Mac:    commonpb.NewMACAddressEUI48([6]byte(proxyMAC)),
+		},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(handle.Free)
+	wirePipeline(t, agent, "port0", "test")
+
+	eth := layers.Ethernet{
+		SrcMAC:       xerror.Unwrap(net.ParseMAC("aa:bb:cc:dd:ee:ff")),
+		DstMAC:       xerror.Unwrap(net.ParseMAC("ff:ff:ff:ff:ff:ff")),
+		EthernetType: layers.EthernetTypeARP,
+	}
+	arp := layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   eth.SrcMAC,
+		SourceProtAddress: net.ParseIP("192.168.1.1").To4(),
+		DstHwAddress:      net.HardwareAddr{0, 0, 0, 0, 0, 0},
+		DstProtAddress:    net.ParseIP("10.0.0.2").To4(),
+	}
+
+	pkt := xpacket.LayersToPacket(t, &eth, &arp)
+	t.Log("Origin packet", pkt)
+
+	result, err := h.HandlePackets(pkt)
+	require.NoError(t, err)
+	require.Empty(t, result.Drop, "proxied ARP request must not be dropped")
+	require.Len(t, result.Output, 1, "expected exactly one reply")
+
+	out := xpacket.ParseEtherPacket(result.Output[0].RawData)
+	t.Log("Result packet", out)
+
+	replyEth := out.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	require.Equal(t, eth.SrcMAC, replyEth.DstMAC)
+	require.Equal(t, proxyMAC, replyEth.SrcMAC)
+
+	reply := out.Layer(layers.LayerTypeARP).(*layers.ARP)
+	require.Equal(t, uint16(layers.ARPReply), reply.Operation)
+	require.Equal(t, []byte(proxyMAC), reply.SourceHwAddress)
+	require.Equal(t, arp.DstProtAddress, net.IP(reply.SourceProtAddress))
+	require.Equal(t, []byte(eth.SrcMAC), reply.DstHwAddress)
+	require.Equal(t, arp.SourceProtAddress, net.IP(reply.DstProtAddress))
+}
+
+// TestRoute_NeighbourProxy_NDP verifies that a Neighbor Solicitation for
+// an address routed through another device is answered with a solicited
+// Neighbor Advertisement on the receiving device when proxy-NDP is enabled
+// on it, while duplicate address detection probes are left unanswered.
+func TestRoute_NeighbourProxy_NDP(t *testing.T) {
+	proxyMAC := xerror.Unwrap(net.ParseMAC("ca:fe:00:00:00:01"))
+	hostMAC := xerror.Unwrap(net.ParseMAC("aa:bb:cc:dd:ee:ff"))
+	target := net.ParseIP("2001:db8::2")
+
+	solicitation := func(t *testing.T, src net.IP) gopacket.Packet {
+		eth := layers.Ethernet{
+			SrcMAC:       hostMAC,
+			DstMAC:       xerror.Unwrap(net.ParseMAC("33:33:ff:00:00:02")),
+			EthernetType: layers.EthernetTypeIPv6,
+		}
+		ip6 := layers.IPv6{
+			Version:    6,
+			HopLimit:   255,
+			NextHeader: layers.IPProtocolICMPv6,
+			SrcIP:      src,
+			DstIP:      net.ParseIP("ff02::1:ff00:2"),
+		}
+		icmp6 := layers.ICMPv6{
+			TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborSolicitation, 0),
+		}
+		icmp6.SetNetworkLayerForChecksum(&ip6)
+		ns := layers.ICMPv6NeighborSolicitation{
+			TargetAddress: target,
+			Options: layers.ICMPv6Options{
+				{Type: layers.ICMPv6OptSourceAddress, Data: hostMAC},
+			},
+		}
+		return xpacket.LayersToPacket(t, &eth, &ip6, &icmp6, &ns)
+	}
+
+	setup := func(t *testing.T) *dataplaneut.Harness {
+		h, agent, backend := setupRouteHarness(t, "port0")
+		handle, err := backend.UpdateModule("test", []*routepb.FIBEntry{
+			{
+				Prefix: "2001:db8::/32",
+				Nexthops: []*routepb.FIBNexthop{{
+					DstMac: commonpb.NewMACAddressEUI48([6]byte(routeNextHop.DstMAC)),
+					SrcMac: commonpb.NewMACAddressEUI48([6]byte(routeNextHop.SrcMAC)),
+					Device: "phantom",
+				}},
+			},
+		}, nil, []*routepb.NeighbourProxyInterface{
+			{
+				Device: "port0",
+				Ndp:    true,
+				Mac:    commonpb.NewMACAddressEUI48([6]byte(proxyMAC)),
+			},
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(handle.Free)
+		wirePipeline(t, agent, "port0", "test")
+		return h
+	}
+
+	t.Run("solicitation", func(t *testing.T) {
+		h := setup(t)
+
+		src := net.ParseIP("fe80::1")
+		pkt := solicitation(t, src)
+		t.Log("Origin packet", pkt)
+
+		result, err := h.HandlePackets(pkt)
+		require.NoError(t, err)
+		require.Empty(t, result.Drop, "proxied solicitation must not be dropped")
+		require.Len(t, result.Output, 1, "expected exactly one advertisement")
+
+		out := xpacket.ParseEtherPacket(result.Output[0].RawData)
+		t.Log("Result packet", out)
+
+		replyEth := out.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+		require.Equal(t, hostMAC, replyEth.DstMAC)
+		require.Equal(t, proxyMAC, replyEth.SrcMAC)
+
+		replyIP := out.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+		require.True(t, target.Equal(replyIP.SrcIP), "advertisement must be sent from the target")
+		require.True(t, src.Equal(replyIP.DstIP), "advertisement must be sent to the soliciting host")
+		require.Equal(t, uint8(255), replyIP.HopLimit)
+
+		replyICMP := out.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6)
+		require.Equal(t, uint8(layers.ICMPv6TypeNeighborAdvertisement), replyICMP.TypeCode.Type())
+
+		advert := out.Layer(layers.LayerTypeICMPv6NeighborAdvertisement).(*layers.ICMPv6NeighborAdvertisement)
+		require.True(t, advert.Solicited(), "advertisement must be solicited")
+		require.True(t, advert.Override(), "advertisement must override the cache entry")
+		require.False(t, advert.Router())
+		require.True(t, target.Equal(advert.TargetAddress))
+		require.Equal(t, layers.ICMPv6Options{
+			{Type: layers.ICMPv6OptTargetAddress, Data: []byte(proxyMAC)},
+		}, advert.Options)
+	})
+
+	t.Run("duplicate_address_detection", func(t *testing.T) {
+		h := setup(t)
+
+		result, err := h.HandlePackets(solicitation(t, net.IPv6unspecified))
+		require.NoError(t, err)
+		require.Empty(t, result.Output, "duplicate address detection probe must not be answered")
+		require.Len(t, result.Drop, 1, "expected exactly one dropped packet")
+	})
+}
+
 // TestRoute_ECMP_HashSelection verifies ECMP nexthop selection based on
 // per-packet hash values.
 //
@@ -6,6 +6,7 @@ import (
 
 	"github.com/c2h5oh/datasize"
 	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/kernel"
 )
 
 func (m *ImportConfig) ToConfig(cfg *bird.Config) {
@@ -19,4 +20,35 @@ func (m *ImportConfig) ToConfig(cfg *bird.Config) {
 	if m.DumpTimeout != 0 {
 		cfg.DumpTimeout = time.Duration(m.DumpTimeout)
 	}
+	if m.QueueHighWatermark != 0 {
+		cfg.QueueHighWatermark = int(m.QueueHighWatermark)
+	}
+	if m.QueueLowWatermark != 0 {
+		cfg.QueueLowWatermark = int(m.QueueLowWatermark)
+	}
+	if m.IdleTimeout != 0 {
+		cfg.IdleTimeout = time.Duration(m.IdleTimeout)
+	}
+}
+
+// ToKernelConfig returns the kernel FIB import configuration, batching the
+// updates like the BIRD one, or nil unless the netlink import is set.
+func (m *ImportConfig) ToKernelConfig(cfg *bird.Config) *kernel.Config {
+	netlinkImport := m.GetNetlinkImport()
+	if netlinkImport == nil {
+		return nil
+	}
+
+	kernelCfg := &kernel.Config{
+		DumpTimeout:   cfg.DumpTimeout,
+		DumpThreshold: cfg.DumpThreshold,
+	}
+	for _, table := range netlinkImport.GetTables() {
+		kernelCfg.Tables = append(kernelCfg.Tables, int(table))
+	}
+	for _, protocol := range netlinkImport.GetProtocols() {
+		kernelCfg.Protocols = append(kernelCfg.Protocols, int(protocol))
+	}
+
+	return kernelCfg
 }
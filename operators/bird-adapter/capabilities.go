@@ -0,0 +1,81 @@
+package bird_adapter
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/common/go/coordinator"
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// CapabilityConfig controls the check of the capabilities the
+// configurations require.
+type CapabilityConfig struct {
+	// FailOpen accepts the configurations fed to a dataplane instance that
+	// cannot be probed, e.g. while it restarts, reporting them unchecked in
+	// the SetupConfig response. By default they fail with UNAVAILABLE,
+	// which the client retries.
+	FailOpen bool `yaml:"fail_open"`
+}
+
+// CapabilityCheck refuses the configurations requiring capabilities the
+// dataplane instance they are fed to does not report, such as the
+// route-mpls module their MPLS routes are sent to.
+//
+// The capabilities of the instance are the dataplane modules it has
+// loaded, probed through the InspectService of its gateway.
+//
+// A nil CapabilityCheck accepts every configuration.
+type CapabilityCheck struct {
+	negotiator *coordinator.Negotiator
+	instance   uint32
+}
+
+// NewCapabilityCheck creates the check of the configurations fed to the
+// instance behind the gateway inspected by the client.
+func NewCapabilityCheck(
+	cfg CapabilityConfig,
+	instance uint32,
+	client ynpb.InspectServiceClient,
+	log *zap.Logger,
+) *CapabilityCheck {
+	return &CapabilityCheck{
+		negotiator: coordinator.NewNegotiator(
+			newCapabilityProbe(client),
+			coordinator.WithFailOpen(cfg.FailOpen),
+			coordinator.WithNegotiatorLog(log),
+		),
+		instance: instance,
+	}
+}
+
+// Check fails with FAILED_PRECONDITION if the instance does not report a
+// capability the configuration requires, and with UNAVAILABLE if the
+// instance cannot be probed. It returns true if the check was skipped
+// instead, see CapabilityConfig.FailOpen.
+func (m *CapabilityCheck) Check(ctx context.Context, req *adapterpb.SetupConfigRequest) (bool, error) {
+	if m == nil {
+		return false, nil
+	}
+	return m.negotiator.Require(ctx, m.instance, req.GetCapabilities()...)
+}
+
+// newCapabilityProbe returns the probe of the dataplane modules loaded by
+// the instance served by the inspected gateway.
+func newCapabilityProbe(client ynpb.InspectServiceClient) coordinator.CapabilityProbe {
+	return func(ctx context.Context, instance uint32) (coordinator.Capabilities, error) {
+		resp, err := client.Inspect(ctx, &ynpb.InspectRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect the dataplane instance: %w", err)
+		}
+
+		capabilities := coordinator.NewCapabilities()
+		for _, module := range resp.GetInstanceInfo().GetDpModules() {
+			capabilities[module.GetName()] = struct{}{}
+		}
+		return capabilities, nil
+	}
+}
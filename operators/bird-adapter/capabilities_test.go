@@ -0,0 +1,152 @@
+package bird_adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// fakeModules reports a dataplane instance with the given modules loaded.
+//
+// A non-nil err fails the inspection instead.
+type fakeModules struct {
+	modules []string
+	err     error
+}
+
+func (m *fakeModules) Inspect(
+	ctx context.Context,
+	req *ynpb.InspectRequest,
+	opts ...grpc.CallOption,
+) (*ynpb.InspectResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	modules := []*ynpb.DPModuleInfo{}
+	for _, name := range m.modules {
+		modules = append(modules, &ynpb.DPModuleInfo{Name: name})
+	}
+	return &ynpb.InspectResponse{
+		InstanceInfo: &ynpb.InstanceInfo{DpModules: modules},
+	}, nil
+}
+
+func TestCapabilityProbe(t *testing.T) {
+	probe := newCapabilityProbe(&fakeModules{modules: []string{"route", "route-mpls"}})
+
+	capabilities, err := probe(t.Context(), 0)
+	require.NoError(t, err)
+	require.True(t, capabilities.Has("route-mpls"))
+	require.False(t, capabilities.Has("dscp"))
+}
+
+// TestSetupConfig_Capabilities verifies that a configuration requiring a
+// module the dataplane instance lacks is refused before its import is set
+// up.
+func TestSetupConfig_Capabilities(t *testing.T) {
+	capabilities := NewCapabilityCheck(CapabilityConfig{}, 0, &fakeModules{modules: []string{"route"}}, zap.NewNop())
+	svc := NewAdapterService(
+		"127.0.0.1:1",
+		insecure.NewCredentials(),
+		grpccompress.None,
+		routepb.Heartbeat{},
+		nil,
+		nil,
+		nil,
+		nil,
+		capabilities,
+		FreshnessSLO{},
+		SetupRetryConfig{},
+		false,
+		zap.NewNop(),
+	)
+
+	req := &adapterpb.SetupConfigRequest{
+		Name:         "route0",
+		Capabilities: []string{"route", "route-mpls"},
+	}
+	_, err := svc.SetupConfig(t.Context(), req)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "route-mpls")
+
+	sessions, err := svc.ListSessions(t.Context(), nil)
+	require.NoError(t, err)
+	require.Empty(t, sessions.GetSessions())
+
+	// A nil check accepts every configuration.
+	var disabled *CapabilityCheck
+	skipped, err := disabled.Check(t.Context(), req)
+	require.NoError(t, err)
+	require.False(t, skipped)
+}
+
+// TestCapabilityCheck_FailOpen verifies that a configuration fed to an
+// instance that cannot be probed is refused unless the check fails open.
+func TestCapabilityCheck_FailOpen(t *testing.T) {
+	down := &fakeModules{err: status.Error(codes.Unavailable, "gateway is down")}
+	req := &adapterpb.SetupConfigRequest{
+		Name:         "route0",
+		Capabilities: []string{"route-mpls"},
+	}
+
+	_, err := NewCapabilityCheck(CapabilityConfig{}, 0, down, zap.NewNop()).Check(t.Context(), req)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	skipped, err := NewCapabilityCheck(CapabilityConfig{FailOpen: true}, 0, down, zap.NewNop()).Check(t.Context(), req)
+	require.NoError(t, err)
+	require.True(t, skipped)
+}
+
+// TestSetupConfig_SignedRetry verifies that a signed configuration refused
+// after its signature is accepted can be resent unchanged.
+func TestSetupConfig_SignedRetry(t *testing.T) {
+	public, key := newTestKey(1)
+	signatures, err := NewSignatureVerifier(SignatureConfig{
+		Keys:    map[string]string{"release": public},
+		Configs: map[string][]string{AnyConfig: {"release"}},
+	})
+	require.NoError(t, err)
+
+	capabilities := NewCapabilityCheck(CapabilityConfig{}, 0, &fakeModules{modules: []string{"route"}}, zap.NewNop())
+	svc := NewAdapterService(
+		"127.0.0.1:1",
+		insecure.NewCredentials(),
+		grpccompress.None,
+		routepb.Heartbeat{},
+		signatures,
+		nil,
+		nil,
+		nil,
+		capabilities,
+		FreshnessSLO{},
+		SetupRetryConfig{},
+		false,
+		zap.NewNop(),
+	)
+
+	req := &adapterpb.SetupConfigRequest{
+		Name:         "route0",
+		Capabilities: []string{"route", "route-mpls"},
+	}
+	require.NoError(t, signSetupConfig(req, "release", key, time.Now()))
+
+	// The resend is refused for the missing capability again, not as a
+	// replay of the first attempt.
+	for range 2 {
+		_, err := svc.SetupConfig(t.Context(), req)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.ErrorContains(t, err, "route-mpls")
+	}
+}
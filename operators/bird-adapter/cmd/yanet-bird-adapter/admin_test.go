@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+	birdAdapter "github.com/yanet-platform/yanet2/operators/bird-adapter"
+)
+
+// idleInspect reports a dataplane instance without module configs.
+type idleInspect struct{}
+
+func (idleInspect) Inspect(
+	ctx context.Context,
+	req *ynpb.InspectRequest,
+	opts ...grpc.CallOption,
+) (*ynpb.InspectResponse, error) {
+	return &ynpb.InspectResponse{InstanceInfo: &ynpb.InstanceInfo{}}, nil
+}
+
+// TestAdminCommand verifies that the admin command manages the recovery
+// of the imports through the admin API served by the adapter.
+func TestAdminCommand(t *testing.T) {
+	recovery := birdAdapter.NewRecovery(birdAdapter.DefaultRecovery(), idleInspect{}, zap.NewNop())
+	applied := 0
+	recovery.Record(0, "route", "route0", func(ctx context.Context) error {
+		applied++
+		return nil
+	})
+
+	server := grpc.NewServer()
+	registerAdminService(server, recovery, zap.NewNop())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	admin := func(args ...string) error {
+		rootCmd.SetArgs(append([]string{"admin", "--endpoint", listener.Addr().String()}, args...))
+		return rootCmd.Execute()
+	}
+
+	require.NoError(t, admin("repush", "--instance", "0"))
+	require.Equal(t, 1, applied)
+
+	require.NoError(t, admin("pause"))
+	require.True(t, recovery.Paused())
+	require.NoError(t, admin("resume"))
+	require.False(t, recovery.Paused())
+
+	require.NoError(t, admin("list-configs"))
+	require.NoError(t, admin("status"))
+
+	// No configuration of the module is known.
+	require.Error(t, admin("repush", "--instance", "0", "--module", "dscp"))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/netip"
 	"os"
 	"strings"
@@ -11,11 +12,14 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/xbackoff"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
+	birdAdapter "github.com/yanet-platform/yanet2/operators/bird-adapter"
 	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
 )
 
@@ -32,9 +36,44 @@ var clientCmdArgs struct {
 	ServerConfigPath string
 	ConfigName       string
 	Sockets          []string
+	Netlink          bool
+	NetlinkTables    []uint
+	NetlinkProtocols []uint
 	LogLevel         logLevelFlag
 	SourceV4         string
 	SourceV6         string
+	Author           string
+	Ticket           string
+	Description      string
+	SignKeyPath      string
+	SignKeyID        string
+	Teardown         string
+	Tags             []string
+	Capabilities     []string
+	DryRun           bool
+	Retry            setupRetryConfig
+}
+
+// teardownPolicies maps the --teardown values to the import teardown
+// policies.
+var teardownPolicies = map[string]adapterpb.TeardownPolicy{
+	"stale-timeout": adapterpb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT,
+	"withdraw":      adapterpb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW,
+	"keep":          adapterpb.TeardownPolicy_TEARDOWN_POLICY_KEEP,
+}
+
+// setupRetryConfig controls retries of SetupConfig while the adapter is
+// restarting.
+type setupRetryConfig struct {
+	// MaxAttempts is the number of SetupConfig calls made before giving up.
+	MaxAttempts int
+	// AttemptTimeout bounds each SetupConfig call.
+	AttemptTimeout time.Duration
+	// InitialBackoff is the delay before the first retry, doubled on every
+	// following one.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
 }
 
 func init() {
@@ -58,22 +97,51 @@ This command connects to the adapter server and configures BIRD route import.`,
 func init() {
 	clientCmd.Flags().StringVarP(&clientCmdArgs.ServerConfigPath, "server-config", "s", "", "Path to the server configuration file (required)")
 	clientCmd.Flags().StringVar(&clientCmdArgs.ConfigName, "config", "", "Configuration name (required)")
-	clientCmd.Flags().StringSliceVar(&clientCmdArgs.Sockets, "sockets", nil, "List of BIRD socket paths (required)")
+	clientCmd.Flags().StringSliceVar(&clientCmdArgs.Sockets, "sockets", nil, "List of BIRD socket paths (required unless --netlink)")
+	clientCmd.Flags().BoolVar(&clientCmdArgs.Netlink, "netlink", false, "Import the routes of the kernel FIB instead of reading BIRD sockets")
+	clientCmd.Flags().UintSliceVar(&clientCmdArgs.NetlinkTables, "netlink-tables", nil, "Kernel routing tables to import with --netlink (default: the main table)")
+	clientCmd.Flags().UintSliceVar(&clientCmdArgs.NetlinkProtocols, "netlink-protocols", nil, "Kernel route protocols to import with --netlink, e.g. 186 for zebra BGP routes (default: all but kernel routes)")
 	clientCmd.Flags().Var(&clientCmdArgs.LogLevel, "log-level", "Log level for this client. If not set, logging is disabled.")
 	clientCmd.Flags().StringVar(&clientCmdArgs.SourceV4, "source-v4", "", "MPLS source IPv4 address (required)")
 	clientCmd.Flags().StringVar(&clientCmdArgs.SourceV6, "source-v6", "", "MPLS source IPv6 address (required)")
+	clientCmd.Flags().StringVar(&clientCmdArgs.Author, "author", os.Getenv("USER"), "Author of the change, stored with the applied generation")
+	clientCmd.Flags().StringVar(&clientCmdArgs.Ticket, "ticket", "", "Ticket of the change, stored with the applied generation")
+	clientCmd.Flags().StringVar(&clientCmdArgs.Description, "description", "", "Description of the change, stored with the applied generation")
+	clientCmd.Flags().StringVar(&clientCmdArgs.SignKeyPath, "sign-key", "", "Path to the private key signing the configuration, as written by keygen")
+	clientCmd.Flags().StringVar(&clientCmdArgs.SignKeyID, "sign-key-id", "", "ID the adapter trusts the signing key under (required with --sign-key)")
+	clientCmd.Flags().StringVar(&clientCmdArgs.Teardown, "teardown", "stale-timeout", "What happens to the imported routes once the import stops or is replaced: stale-timeout, withdraw or keep")
+	clientCmd.Flags().StringSliceVar(&clientCmdArgs.Tags, "tags", nil, "Comma-separated tags attached to every imported route")
+	clientCmd.Flags().StringSliceVar(&clientCmdArgs.Capabilities, "capabilities", nil, "Comma-separated dataplane modules the configuration requires, e.g. route-mpls")
+	clientCmd.Flags().BoolVar(&clientCmdArgs.DryRun, "dry-run", false, "Validate the configuration and check the BIRD sockets are reachable without applying it")
+	clientCmd.Flags().IntVar(&clientCmdArgs.Retry.MaxAttempts, "max-attempts", 5, "Number of SetupConfig attempts made while the adapter is unavailable")
+	clientCmd.Flags().DurationVar(&clientCmdArgs.Retry.AttemptTimeout, "attempt-timeout", 10*time.Second, "Timeout of a single SetupConfig attempt")
+	clientCmd.Flags().DurationVar(&clientCmdArgs.Retry.InitialBackoff, "initial-backoff", 500*time.Millisecond, "Delay before the first SetupConfig retry")
+	clientCmd.Flags().DurationVar(&clientCmdArgs.Retry.MaxBackoff, "max-backoff", 10*time.Second, "Maximum delay between SetupConfig retries")
 
 	clientCmd.MarkFlagRequired("server-config")
 	clientCmd.MarkFlagRequired("config")
-	clientCmd.MarkFlagRequired("sockets")
+	clientCmd.MarkFlagsOneRequired("sockets", "netlink")
+	clientCmd.MarkFlagsMutuallyExclusive("sockets", "netlink")
 	clientCmd.MarkFlagRequired("source-v4")
 	clientCmd.MarkFlagRequired("source-v6")
+	clientCmd.MarkFlagsRequiredTogether("sign-key", "sign-key-id")
 }
 
 func runClient() error {
-	if len(clientCmdArgs.Sockets) == 0 {
+	if len(clientCmdArgs.Sockets) == 0 && !clientCmdArgs.Netlink {
 		return fmt.Errorf("at least one BIRD socket path must be provided")
 	}
+	netlinkImport, err := newNetlinkImport(clientCmdArgs.Netlink, clientCmdArgs.NetlinkTables, clientCmdArgs.NetlinkProtocols)
+	if err != nil {
+		return err
+	}
+	teardown, ok := teardownPolicies[clientCmdArgs.Teardown]
+	if !ok {
+		return fmt.Errorf("--teardown must be one of stale-timeout, withdraw or keep, got %q", clientCmdArgs.Teardown)
+	}
+	if clientCmdArgs.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("--max-attempts must be positive, got %d", clientCmdArgs.Retry.MaxAttempts)
+	}
 
 	addrV4, err := netip.ParseAddr(clientCmdArgs.SourceV4)
 	if err != nil {
@@ -99,17 +167,9 @@ func runClient() error {
 
 	fmt.Printf("Connecting to BIRD adapter at %s...\n", serverCfg.ListenAddr)
 
-	// Create gRPC connection to the adapter server
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	conn, err := grpc.NewClient(
-		serverCfg.ListenAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
-	)
+	conn, err := dialAdapter(serverCfg)
 	if err != nil {
-		return fmt.Errorf("failed to connect to adapter server: %w", err)
+		return err
 	}
 	defer conn.Close()
 
@@ -127,20 +187,205 @@ func runClient() error {
 		SourceV4: commonpb.NewIPAddressFromAddr(addrV4),
 		SourceV6: commonpb.NewIPAddressFromAddr(addrV6),
 		Config: &adapterpb.ImportConfig{
-			Sockets:  clientCmdArgs.Sockets,
-			LogLevel: logLevel,
+			Sockets:       clientCmdArgs.Sockets,
+			LogLevel:      logLevel,
+			Teardown:      teardown,
+			NetlinkImport: netlinkImport,
+			Tags:          clientCmdArgs.Tags,
+		},
+		Metadata: &adapterpb.ConfigMetadata{
+			Author:      clientCmdArgs.Author,
+			Ticket:      clientCmdArgs.Ticket,
+			Description: clientCmdArgs.Description,
 		},
+		Capabilities: clientCmdArgs.Capabilities,
+	}
+
+	var sign signConfigFunc
+	if clientCmdArgs.SignKeyPath != "" {
+		key, err := loadSigningKey(clientCmdArgs.SignKeyPath)
+		if err != nil {
+			return err
+		}
+		sign = func(req *adapterpb.SetupConfigRequest) error {
+			return birdAdapter.SignSetupConfig(req, clientCmdArgs.SignKeyID, key)
+		}
+	}
+
+	if clientCmdArgs.DryRun {
+		if sign != nil {
+			if err := sign(req); err != nil {
+				return fmt.Errorf("failed to sign config: %w", err)
+			}
+		}
+		return validateConfig(client, req)
 	}
 
-	_, err = client.SetupConfig(ctx, req)
+	resp, err := setupConfigWithRetry(context.Background(), client.SetupConfig, req, sign, clientCmdArgs.Retry)
 	if err != nil {
 		return fmt.Errorf("failed to setup config: %w", err)
 	}
 
-	fmt.Println("Successfully configured")
+	if resp.Unchanged {
+		fmt.Printf("Configuration unchanged, import kept running (generation %d, version %d)\n", resp.Generation, resp.Version)
+	} else {
+		fmt.Printf("Successfully configured (generation %d, version %d)\n", resp.Generation, resp.Version)
+	}
+	if resp.CapabilitiesUnchecked {
+		fmt.Println("The dataplane instance could not be probed, the required capabilities were not checked")
+	}
+	if resp.Pending {
+		fmt.Println("The route operator is not reachable yet, the import is retried in the background")
+	}
 	return nil
 }
 
+// validateConfig checks the configuration on the adapter without applying
+// it, failing if any problem is found.
+func validateConfig(client adapterpb.AdapterServiceClient, req *adapterpb.SetupConfigRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), clientCmdArgs.Retry.AttemptTimeout)
+	defer cancel()
+
+	resp, err := client.ValidateConfig(ctx, &adapterpb.ValidateConfigRequest{Request: req})
+	if err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if len(resp.Errors) != 0 {
+		for _, configErr := range resp.Errors {
+			field := configErr.Field
+			if field == "" {
+				field = "request"
+			}
+			fmt.Printf("%s: %s\n", field, configErr.Message)
+		}
+		return fmt.Errorf("configuration is invalid: %d problems found", len(resp.Errors))
+	}
+
+	if resp.Unchanged {
+		fmt.Printf("Configuration is valid and already running (digest %s)\n", resp.Digest)
+		return nil
+	}
+	fmt.Printf("Configuration is valid (digest %s)\n", resp.Digest)
+	return nil
+}
+
+// newNetlinkImport returns the kernel FIB import requested by the --netlink
+// flags, nil unless enabled.
+func newNetlinkImport(enabled bool, tables []uint, protocols []uint) (*adapterpb.NetlinkImport, error) {
+	if !enabled {
+		if len(tables) != 0 || len(protocols) != 0 {
+			return nil, fmt.Errorf("--netlink-tables and --netlink-protocols require --netlink")
+		}
+		return nil, nil
+	}
+
+	netlinkImport := &adapterpb.NetlinkImport{}
+	for _, table := range tables {
+		if table > math.MaxUint32 {
+			return nil, fmt.Errorf("--netlink-tables: table %d is out of range", table)
+		}
+		netlinkImport.Tables = append(netlinkImport.Tables, uint32(table))
+	}
+	for _, protocol := range protocols {
+		if protocol > math.MaxUint8 {
+			return nil, fmt.Errorf("--netlink-protocols: protocol %d is out of range", protocol)
+		}
+		netlinkImport.Protocols = append(netlinkImport.Protocols, uint32(protocol))
+	}
+
+	return netlinkImport, nil
+}
+
+// dialAdapter connects to the adapter server described by its config.
+func dialAdapter(serverCfg *ServerConfig) (*grpc.ClientConn, error) {
+	creds, err := serverCfg.ClientTLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load adapter client TLS: %w", err)
+	}
+
+	conn, err := grpc.NewClient(
+		serverCfg.ListenAddr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to adapter server: %w", err)
+	}
+	return conn, nil
+}
+
+// setupConfigFunc is the signature of AdapterServiceClient.SetupConfig.
+type setupConfigFunc func(
+	ctx context.Context,
+	req *adapterpb.SetupConfigRequest,
+	opts ...grpc.CallOption,
+) (*adapterpb.SetupConfigResponse, error)
+
+// signConfigFunc signs a SetupConfig request as of the current time.
+type signConfigFunc func(req *adapterpb.SetupConfigRequest) error
+
+// setupConfigWithRetry calls SetupConfig, retrying with exponential
+// backoff while the adapter is unavailable or does not answer in time,
+// which is what a restarting adapter looks like.
+//
+// Other errors are returned immediately, as are transient ones once the
+// attempt budget is spent. A non-nil sign signs every attempt anew, since
+// the adapter rejects a signed request it applied already as a replay: a
+// call that reached the adapter after all is then repeated as a newer
+// request of the same configuration, which keeps its import running.
+func setupConfigWithRetry(
+	ctx context.Context,
+	setup setupConfigFunc,
+	req *adapterpb.SetupConfigRequest,
+	sign signConfigFunc,
+	cfg setupRetryConfig,
+) (*adapterpb.SetupConfigResponse, error) {
+	bo := xbackoff.New(cfg.InitialBackoff, xbackoff.WithMax(cfg.MaxBackoff))
+	for attempt := 1; ; attempt++ {
+		if sign != nil {
+			if err := sign(req); err != nil {
+				return nil, fmt.Errorf("failed to sign config: %w", err)
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, cfg.AttemptTimeout)
+		resp, err := setup(attemptCtx, req)
+		cancel()
+		if err == nil {
+			return resp, nil
+		}
+		if !isTransientSetupError(err) {
+			return nil, err
+		}
+		if attempt >= cfg.MaxAttempts {
+			return nil, fmt.Errorf("adapter still unavailable after %d attempts: %w", attempt, err)
+		}
+
+		delay := bo.Next()
+		fmt.Printf("Attempt %d/%d failed: %v; retrying in %s...\n", attempt, cfg.MaxAttempts, err, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isTransientSetupError reports whether a SetupConfig error is worth
+// retrying.
+func isTransientSetupError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
 var listSessionsCmdArgs struct {
 	ServerConfigPath string
 }
@@ -173,13 +418,9 @@ func runListSessions() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	conn, err := grpc.NewClient(
-		serverCfg.ListenAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
-	)
+	conn, err := dialAdapter(serverCfg)
 	if err != nil {
-		return fmt.Errorf("failed to connect to adapter server: %w", err)
+		return err
 	}
 	defer conn.Close()
 
@@ -208,12 +449,238 @@ func runListSessions() error {
 		fmt.Printf("Sockets:    %s\n", strings.Join(session.Sockets, ", "))
 		fmt.Printf("Created:    %s (uptime: %s)\n", createdAt.Format(time.RFC3339), uptime)
 		fmt.Printf("Connection: %s\n", connStateStr)
+		if session.GetPending() {
+			fmt.Println("Pending:    stream not opened yet, retrying")
+		}
+		if session.GetPolicy() != "" {
+			fmt.Printf("Policy:     %s\n", session.GetPolicy())
+		}
+		if session.Generation != nil {
+			fmt.Printf("Generation: %s\n", generationToString(session.Generation))
+		}
+		printFeedFreshness(session.GetFreshness())
+		printRouteRejects(session.GetRejects())
 		fmt.Println(strings.Repeat("-", 80))
 	}
 
 	return nil
 }
 
+// printFeedFreshness prints the health of a session feed along with the
+// time since its last update.
+func printFeedFreshness(freshness *adapterpb.FeedFreshness) {
+	lastUpdate := time.Unix(0, freshness.GetLastUpdateAt())
+	fmt.Printf("Health:     %s (last update: %s ago)\n",
+		feedHealthToString(freshness.GetHealth()),
+		time.Since(lastUpdate).Round(time.Second),
+	)
+	if freshness.GetStaleInWindow() > 0 || freshness.GetBurnRate() > 0 {
+		fmt.Printf("Stale:      %s in window (burn rate: %.2f)\n",
+			time.Duration(freshness.GetStaleInWindow()).Round(time.Second),
+			freshness.GetBurnRate(),
+		)
+	}
+}
+
+// printRouteRejects prints the rejected route counters of a session
+// followed by the recent samples.
+func printRouteRejects(rejects *adapterpb.RouteRejects) {
+	if len(rejects.GetCounts()) == 0 {
+		fmt.Println("Rejected:   none")
+		return
+	}
+
+	counts := make([]string, 0, len(rejects.GetCounts()))
+	for _, count := range rejects.GetCounts() {
+		counts = append(counts, fmt.Sprintf("%s=%d", rejectReasonToString(count.GetReason()), count.GetCount()))
+	}
+	fmt.Printf("Rejected:   %s\n", strings.Join(counts, ", "))
+
+	for _, sample := range rejects.GetSamples() {
+		prefix := sample.GetPrefix()
+		if prefix == "" {
+			prefix = "-"
+		}
+		op := "update"
+		if sample.GetIsDelete() {
+			op = "withdraw"
+		}
+		fmt.Printf("  %s %s %s %s peer=%s next_hop=%s: %s\n",
+			time.Unix(0, sample.GetRejectedAt()).Format(time.RFC3339),
+			rejectReasonToString(sample.GetReason()),
+			op,
+			prefix,
+			sample.GetPeer(),
+			sample.GetNextHop(),
+			sample.GetError(),
+		)
+	}
+}
+
+func rejectReasonToString(reason adapterpb.RouteRejectReason) string {
+	switch reason {
+	case adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_BAD_PREFIX:
+		return "BAD_PREFIX"
+	case adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNKNOWN_FAMILY:
+		return "UNKNOWN_FAMILY"
+	case adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNSUPPORTED_RD:
+		return "UNSUPPORTED_RD"
+	case adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_INVALID_NEXT_HOP:
+		return "INVALID_NEXT_HOP"
+	case adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_POLICY:
+		return "POLICY"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+var listGenerationsCmdArgs struct {
+	ServerConfigPath string
+	ConfigName       string
+}
+
+var listGenerationsCmd = &cobra.Command{
+	Use:   "list-generations",
+	Short: "List applied generations of a configuration",
+	Long: `List recently applied generations of a configuration on the adapter
+server together with who applied them and why, newest first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListGenerations(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	listGenerationsCmd.Flags().StringVarP(&listGenerationsCmdArgs.ServerConfigPath, "server-config", "s", "", "Path to the server configuration file (required)")
+	listGenerationsCmd.Flags().StringVar(&listGenerationsCmdArgs.ConfigName, "config", "", "Configuration name (required)")
+	listGenerationsCmd.MarkFlagRequired("server-config")
+	listGenerationsCmd.MarkFlagRequired("config")
+}
+
+func runListGenerations() error {
+	serverCfg, err := xcfg.LoadConfig[ServerConfig](listGenerationsCmdArgs.ServerConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load server config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := dialAdapter(serverCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := adapterpb.NewAdapterServiceClient(conn)
+
+	resp, err := client.ListConfigGenerations(ctx, &adapterpb.ListConfigGenerationsRequest{
+		Name: listGenerationsCmdArgs.ConfigName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list generations: %w", err)
+	}
+
+	if len(resp.Generations) == 0 {
+		fmt.Println("No applied generations")
+		return nil
+	}
+
+	fmt.Printf("Generations of '%s' (%d):\n", listGenerationsCmdArgs.ConfigName, len(resp.Generations))
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, generation := range resp.Generations {
+		metadata := generation.GetMetadata()
+
+		fmt.Printf("Generation:  %s\n", generationToString(generation))
+		fmt.Printf("Sockets:     %s\n", strings.Join(generation.Sockets, ", "))
+		fmt.Printf("Digest:      %s\n", generation.Digest)
+		fmt.Printf("Author:      %s\n", metadata.GetAuthor())
+		fmt.Printf("Ticket:      %s\n", metadata.GetTicket())
+		fmt.Printf("Description: %s\n", metadata.GetDescription())
+		fmt.Println(strings.Repeat("-", 80))
+	}
+
+	return nil
+}
+
+var teardownCmdArgs struct {
+	ServerConfigPath string
+	ConfigName       string
+	Withdraw         bool
+}
+
+var teardownCmd = &cobra.Command{
+	Use:   "teardown",
+	Short: "Stop the BIRD import of a configuration",
+	Long: `Stop the BIRD import of a configuration on the adapter server, closing
+its stream to the route operator cleanly. The routes of the import are torn
+down by the configured policy, unless --withdraw removes them at once.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTeardown(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	teardownCmd.Flags().StringVarP(&teardownCmdArgs.ServerConfigPath, "server-config", "s", "", "Path to the server configuration file (required)")
+	teardownCmd.Flags().StringVar(&teardownCmdArgs.ConfigName, "config", "", "Configuration name (required)")
+	teardownCmd.Flags().BoolVar(&teardownCmdArgs.Withdraw, "withdraw", false, "Withdraw the routes of the import at once")
+	teardownCmd.MarkFlagRequired("server-config")
+	teardownCmd.MarkFlagRequired("config")
+}
+
+func runTeardown() error {
+	serverCfg, err := xcfg.LoadConfig[ServerConfig](teardownCmdArgs.ServerConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load server config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := dialAdapter(serverCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := adapterpb.NewAdapterServiceClient(conn)
+
+	_, err = client.TeardownConfig(ctx, &adapterpb.TeardownConfigRequest{
+		Name:     teardownCmdArgs.ConfigName,
+		Withdraw: teardownCmdArgs.Withdraw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tear down the configuration: %w", err)
+	}
+
+	fmt.Printf("Stopped the import of '%s'\n", teardownCmdArgs.ConfigName)
+	return nil
+}
+
+func generationToString(generation *adapterpb.ConfigGeneration) string {
+	appliedAt := time.Unix(0, generation.AppliedAt)
+	return fmt.Sprintf("%d (applied: %s)", generation.Generation, appliedAt.Format(time.RFC3339))
+}
+
+func feedHealthToString(health adapterpb.FeedHealth) string {
+	switch health {
+	case adapterpb.FeedHealth_FEED_HEALTH_HEALTHY:
+		return "HEALTHY"
+	case adapterpb.FeedHealth_FEED_HEALTH_DEGRADED:
+		return "DEGRADED"
+	case adapterpb.FeedHealth_FEED_HEALTH_DISCONNECTED:
+		return "DISCONNECTED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 func connectionStateToString(state adapterpb.ConnectionState) string {
 	switch state {
 	case adapterpb.ConnectionState_CONNECTION_STATE_IDLE:
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+func testRetryConfig() setupRetryConfig {
+	return setupRetryConfig{
+		MaxAttempts:    3,
+		AttemptTimeout: time.Second,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+}
+
+// failingSetup returns a SetupConfig stub failing with errs in turn, then
+// succeeding.
+func failingSetup(calls *int, errs ...error) setupConfigFunc {
+	return func(
+		ctx context.Context,
+		req *adapterpb.SetupConfigRequest,
+		opts ...grpc.CallOption,
+	) (*adapterpb.SetupConfigResponse, error) {
+		*calls++
+		if *calls <= len(errs) {
+			return nil, errs[*calls-1]
+		}
+		return &adapterpb.SetupConfigResponse{Generation: 7}, nil
+	}
+}
+
+func TestSetupConfigWithRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	deadline := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	invalid := status.Error(codes.InvalidArgument, "bad socket")
+
+	tests := []struct {
+		name  string
+		errs  []error
+		calls int
+		code  codes.Code
+	}{
+		{name: "first attempt", calls: 1, code: codes.OK},
+		{name: "transient", errs: []error{unavailable, deadline}, calls: 3, code: codes.OK},
+		{name: "budget spent", errs: []error{unavailable, unavailable, unavailable}, calls: 3, code: codes.Unavailable},
+		{name: "permanent", errs: []error{unavailable, invalid}, calls: 2, code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			resp, err := setupConfigWithRetry(
+				t.Context(),
+				failingSetup(&calls, tt.errs...),
+				&adapterpb.SetupConfigRequest{Name: "route0"},
+				nil,
+				testRetryConfig(),
+			)
+			require.Equal(t, tt.calls, calls)
+			require.Equal(t, tt.code, status.Code(err), "error: %v", err)
+			if tt.code == codes.OK {
+				require.NoError(t, err)
+				require.Equal(t, uint64(7), resp.Generation)
+			}
+		})
+	}
+}
+
+// TestSetupConfigWithRetry_Signs verifies that every attempt is signed
+// anew, so a retry of a signed request is not rejected as a replay of the
+// attempt that reached the adapter after all.
+func TestSetupConfigWithRetry_Signs(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	signatures := 0
+	sign := func(req *adapterpb.SetupConfigRequest) error {
+		signatures++
+		req.Signature = &adapterpb.ConfigSignature{KeyId: "release", SignedAt: int64(signatures)}
+		return nil
+	}
+
+	signedAt := []int64{}
+	calls := 0
+	setup := failingSetup(&calls, unavailable, unavailable)
+	resp, err := setupConfigWithRetry(
+		t.Context(),
+		func(
+			ctx context.Context,
+			req *adapterpb.SetupConfigRequest,
+			opts ...grpc.CallOption,
+		) (*adapterpb.SetupConfigResponse, error) {
+			signedAt = append(signedAt, req.GetSignature().GetSignedAt())
+			return setup(ctx, req, opts...)
+		},
+		&adapterpb.SetupConfigRequest{Name: "route0"},
+		sign,
+		testRetryConfig(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), resp.Generation)
+	require.Equal(t, []int64{1, 2, 3}, signedAt)
+
+	// A failing signature fails the call before anything is sent.
+	calls = 0
+	_, err = setupConfigWithRetry(
+		t.Context(),
+		failingSetup(&calls),
+		&adapterpb.SetupConfigRequest{Name: "route0"},
+		func(req *adapterpb.SetupConfigRequest) error {
+			return errors.New("no key")
+		},
+		testRetryConfig(),
+	)
+	require.ErrorContains(t, err, "no key")
+	require.Zero(t, calls)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var keygenCmdArgs struct {
+	Output string
+}
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a configuration signing key",
+	Long: `Generate an ed25519 key pair for signing SetupConfig calls.
+The private key is written to the output file, the public key to trust in
+the server configuration is printed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runKeygen(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	keygenCmd.Flags().StringVarP(&keygenCmdArgs.Output, "output", "o", "", "Path to write the private key to (required)")
+	keygenCmd.MarkFlagRequired("output")
+}
+
+func runKeygen() error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(private.Seed()) + "\n"
+	// O_EXCL keeps an existing key from being overwritten by accident.
+	file, err := os.OpenFile(keygenCmdArgs.Output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create private key file: %w", err)
+	}
+	if _, err := file.WriteString(encoded); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	fmt.Printf("Public key: %s\n", base64.StdEncoding.EncodeToString(public))
+	return nil
+}
+
+// loadSigningKey reads a private key written by keygen.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key %q: %w", path, err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key %q is %d bytes long, expected %d", path, len(seed), ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/yanet-platform/yanet2/common/go/coordinator/admincli"
 	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/version"
 )
 
@@ -19,6 +20,10 @@ func init() {
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(clientCmd)
 	rootCmd.AddCommand(listSessionsCmd)
+	rootCmd.AddCommand(listGenerationsCmd)
+	rootCmd.AddCommand(teardownCmd)
+	rootCmd.AddCommand(keygenCmd)
+	rootCmd.AddCommand(admincli.NewCommand())
 }
 
 func main() {
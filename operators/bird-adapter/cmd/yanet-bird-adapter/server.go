@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -14,13 +15,23 @@ import (
 	"google.golang.org/grpc"
 	_ "google.golang.org/grpc/encoding/gzip"
 
+	"github.com/yanet-platform/yanet2/common/coordinatorpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/coordinator"
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
 	"github.com/yanet-platform/yanet2/common/go/logging"
+	"github.com/yanet-platform/yanet2/common/go/metricshttp"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
 	"github.com/yanet-platform/yanet2/common/go/xcmd"
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
 	birdAdapter "github.com/yanet-platform/yanet2/operators/bird-adapter"
 	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
 )
 
+// importsStopTimeout bounds the graceful stop of the imports on shutdown.
+const importsStopTimeout = 30 * time.Second
+
 var serverCmdArgs struct {
 	ConfigPath string
 }
@@ -51,10 +62,54 @@ type ServerConfig struct {
 	Logging logging.Config `yaml:"logging"`
 	// ListenAddr is the gRPC endpoint to listen on (e.g., "localhost:50051").
 	ListenAddr string `yaml:"listen_addr"`
+	// TLS configures TLS of the gRPC server, optionally authenticating
+	// the clients. When nil, the server listens in plaintext.
+	TLS *grpctls.ServerConfig `yaml:"tls,omitempty"`
+	// ClientTLS configures TLS of the client commands connecting to
+	// ListenAddr. When nil, they connect in plaintext.
+	ClientTLS *grpctls.ClientConfig `yaml:"client_tls,omitempty"`
 	// RouteOperatorEndpoint is the gRPC endpoint serving the route operator's
 	// RouteService for RIB updates — either the route operator directly or the
 	// gateway that proxies it.
 	RouteOperatorEndpoint string `yaml:"route_operator_endpoint"`
+	// RouteOperatorCompression is the compression of the FeedRIB streams
+	// sent to the route operator: none, gzip or zstd.
+	RouteOperatorCompression grpccompress.Compression `yaml:"route_operator_compression"`
+	// RouteOperatorTLS configures TLS of the connections to the route
+	// operator endpoint. When nil, it is dialed in plaintext.
+	RouteOperatorTLS *grpctls.ClientConfig `yaml:"route_operator_tls,omitempty"`
+	// RouteOperatorHeartbeat keeps the FeedRIB streams alive and
+	// re-establishes the ones the route operator stopped answering on.
+	RouteOperatorHeartbeat routepb.Heartbeat `yaml:"route_operator_heartbeat"`
+	// WithdrawOnStop withdraws the routes of an import from the route
+	// operator RIB at once when the import is replaced, torn down or
+	// stopped on shutdown, regardless of its teardown policy. The policy
+	// still applies to the routes of an import losing its stream.
+	WithdrawOnStop bool `yaml:"withdraw_on_stop"`
+	// Signatures lists the configurations whose SetupConfig calls must be
+	// signed and the keys trusted to sign them.
+	Signatures birdAdapter.SignatureConfig `yaml:"signatures"`
+	// ImportPolicies filter the routes of the imports and set their
+	// attributes before they are sent to the route operator.
+	ImportPolicies birdAdapter.ImportPolicyConfig `yaml:"import_policies"`
+	// StateDir is the directory the applied configurations are persisted
+	// to and restored from on start. Empty disables the persistence.
+	StateDir string `yaml:"state_dir"`
+	// Freshness is the objective on the time the BIRD feeds may go
+	// without updates.
+	Freshness birdAdapter.FreshnessSLO `yaml:"freshness"`
+	// SetupRetry retries in the background the imports whose stream to
+	// the route operator fails to open on setup.
+	SetupRetry birdAdapter.SetupRetryConfig `yaml:"setup_retry"`
+	// Recovery sets the imports up again once the dataplane instance they
+	// are fed to restarts and loses their MPLS routes.
+	Recovery birdAdapter.RecoveryConfig `yaml:"recovery"`
+	// Capabilities controls the check of the dataplane modules the
+	// configurations require, probed through the recovery endpoint.
+	Capabilities birdAdapter.CapabilityConfig `yaml:"capabilities"`
+	// MetricsAddr is the HTTP endpoint serving the adapter metrics in the
+	// Prometheus text format at /metrics. Empty disables the listener.
+	MetricsAddr string `yaml:"metrics_addr"`
 }
 
 func (m *ServerConfig) Default() {
@@ -67,8 +122,14 @@ func DefaultServerConfig() *ServerConfig {
 		Logging: logging.Config{
 			Level: zapcore.InfoLevel,
 		},
-		ListenAddr:            "localhost:50051",
-		RouteOperatorEndpoint: "localhost:50052",
+		ListenAddr:               "localhost:50051",
+		RouteOperatorEndpoint:    "localhost:50052",
+		RouteOperatorCompression: grpccompress.Gzip,
+		RouteOperatorHeartbeat:   routepb.DefaultHeartbeat(),
+		Freshness:                birdAdapter.DefaultFreshnessSLO(),
+		SetupRetry:               birdAdapter.DefaultSetupRetry(),
+		Recovery:                 birdAdapter.DefaultRecovery(),
+		Signatures:               birdAdapter.DefaultSignatures(),
 	}
 }
 
@@ -87,14 +148,78 @@ func runServer() error {
 	log.Info("starting BIRD adapter service",
 		zap.String("listen_addr", cfg.ListenAddr),
 		zap.String("route_operator_endpoint", cfg.RouteOperatorEndpoint),
+		zap.String("route_operator_compression", string(cfg.RouteOperatorCompression)),
+		zap.Bool("withdraw_on_stop", cfg.WithdrawOnStop),
 	)
 
+	signatures, err := birdAdapter.NewSignatureVerifier(cfg.Signatures)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	policies, err := birdAdapter.NewImportPolicies(cfg.ImportPolicies)
+	if err != nil {
+		return fmt.Errorf("failed to load import policies: %w", err)
+	}
+
+	state, err := birdAdapter.NewStateStore(cfg.StateDir)
+	if err != nil {
+		return err
+	}
+
+	routeOperatorCreds, err := cfg.RouteOperatorTLS.Credentials()
+	if err != nil {
+		return fmt.Errorf("failed to load route operator TLS: %w", err)
+	}
+	serverCreds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return fmt.Errorf("failed to load server TLS: %w", err)
+	}
+
+	// The probes of the recovery and of the capabilities share the
+	// credentials of the route operator, whose endpoint is the gateway of
+	// the instance unless configured otherwise.
+	recoveryEndpoint := cfg.Recovery.Endpoint
+	if recoveryEndpoint == "" {
+		recoveryEndpoint = cfg.RouteOperatorEndpoint
+	}
+	recoveryConn, err := grpc.NewClient(recoveryEndpoint, grpc.WithTransportCredentials(routeOperatorCreds))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the recovery endpoint: %w", err)
+	}
+	defer recoveryConn.Close()
+	inspect := ynpb.NewInspectServiceClient(recoveryConn)
+	recovery := birdAdapter.NewRecovery(cfg.Recovery, inspect, log)
+	capabilities := birdAdapter.NewCapabilityCheck(cfg.Capabilities, cfg.Recovery.Instance, inspect, log)
+
 	// Create the adapter service
-	adapterService := birdAdapter.NewAdapterService(cfg.RouteOperatorEndpoint, log)
+	adapterService := birdAdapter.NewAdapterService(
+		cfg.RouteOperatorEndpoint,
+		routeOperatorCreds,
+		cfg.RouteOperatorCompression,
+		cfg.RouteOperatorHeartbeat,
+		signatures,
+		policies,
+		state,
+		recovery,
+		capabilities,
+		cfg.Freshness,
+		cfg.SetupRetry,
+		cfg.WithdrawOnStop,
+		log,
+	)
+
+	// The imports are restored before serving, so the configurations pushed
+	// again find them running. A configuration failing to restore waits for
+	// its next push.
+	if err := adapterService.Restore(); err != nil {
+		log.Warn("failed to restore some configurations", zap.Error(err))
+	}
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.Creds(serverCreds))
 	adapterpb.RegisterAdapterServiceServer(grpcServer, adapterService)
+	adapterpb.RegisterMetricsServiceServer(grpcServer, birdAdapter.NewMetricsService(adapterService))
+	registerAdminService(grpcServer, recovery, log)
 
 	// Listen on the configured address
 	listener, err := net.Listen("tcp", cfg.ListenAddr)
@@ -102,6 +227,14 @@ func runServer() error {
 		return fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddr, err)
 	}
 
+	var metricsListener net.Listener
+	if cfg.MetricsAddr != "" {
+		metricsListener, err = net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", cfg.MetricsAddr, err)
+		}
+	}
+
 	wg, ctx := errgroup.WithContext(context.Background())
 
 	// Start gRPC server
@@ -113,14 +246,44 @@ func runServer() error {
 		return nil
 	})
 
+	wg.Go(func() error {
+		if err := recovery.Run(ctx); !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("recovery failed: %w", err)
+		}
+		return nil
+	})
+
+	if metricsListener != nil {
+		wg.Go(func() error {
+			return metricshttp.Serve(ctx, metricsListener, adapterService.Collect, log)
+		})
+	}
+
 	// Wait for interrupt signal
 	wg.Go(func() error {
 		err := xcmd.WaitInterrupted(ctx)
 		log.Info("caught signal", zap.Error(err))
 		log.Info("shutting down gRPC server")
 		grpcServer.GracefulStop()
+
+		log.Info("stopping BIRD imports")
+		stopCtx, cancel := context.WithTimeout(context.Background(), importsStopTimeout)
+		defer cancel()
+		if err := adapterService.Shutdown(stopCtx); err != nil {
+			log.Warn("BIRD imports did not shut down cleanly", zap.Error(err))
+		}
 		return err
 	})
 
 	return wg.Wait()
 }
+
+// registerAdminService serves the coordinator admin API managing the
+// recovery of the imports, see the admin command.
+func registerAdminService(server grpc.ServiceRegistrar, recovery *birdAdapter.Recovery, log *zap.Logger) {
+	adminService := coordinator.NewAdminService(
+		coordinator.WithAdminRecovery(recovery.Recovery),
+		coordinator.WithAdminLog(log),
+	)
+	coordinatorpb.RegisterAdminServiceServer(server, adminService)
+}
@@ -0,0 +1,216 @@
+package bird_adapter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// freshnessBuckets is the number of buckets the SLO window is split into
+// to account the stale time.
+const freshnessBuckets = 60
+
+// FreshnessSLO configures the freshness objective of the BIRD feeds.
+//
+// A feed is stale while no update arrived for longer than MaxSilence. A
+// full BGP table churns continuously, so a silent feed whose sockets stay
+// connected points at a wedged BIRD daemon, which would otherwise go
+// unnoticed until the routes age out.
+type FreshnessSLO struct {
+	// MaxSilence is the longest time a feed may go without updates.
+	//
+	// Zero disables the objective.
+	MaxSilence time.Duration `yaml:"max_silence"`
+	// Objective is the fraction of the window a feed must be fresh for.
+	Objective float64 `yaml:"objective"`
+	// Window is the period the error budget is computed over.
+	Window time.Duration `yaml:"window"`
+}
+
+// DefaultFreshnessSLO returns the freshness objective used unless
+// configured, disabled until a MaxSilence is set.
+func DefaultFreshnessSLO() FreshnessSLO {
+	return FreshnessSLO{
+		Objective: 0.999,
+		Window:    time.Hour,
+	}
+}
+
+// Validate validates the freshness objective.
+func (m *FreshnessSLO) Validate() error {
+	if m.MaxSilence == 0 {
+		return nil
+	}
+	if m.MaxSilence < 0 {
+		return fmt.Errorf("max_silence must not be negative")
+	}
+	if m.Objective <= 0 || m.Objective >= 1 {
+		return fmt.Errorf("objective must be within (0, 1), got %v", m.Objective)
+	}
+	if m.Window < freshnessBuckets*time.Second {
+		return fmt.Errorf("window must be at least %s, got %s", freshnessBuckets*time.Second, m.Window)
+	}
+	return nil
+}
+
+// enabled reports whether the objective is set.
+func (m *FreshnessSLO) enabled() bool {
+	return m.MaxSilence > 0
+}
+
+// staleBucket is the stale time accounted to a slice of the SLO window.
+type staleBucket struct {
+	// slice is the number of the window slice since the Unix epoch, which
+	// tells a bucket reused by a later slice from a current one.
+	slice int64
+	stale time.Duration
+}
+
+// feedFreshness tracks the updates of a BIRD feed against the freshness
+// objective.
+//
+// The stale time is accounted once the silence ends, and the ongoing one
+// is added when the status is taken. It is safe for concurrent use, since
+// updates are observed by both the socket readers and the update callback.
+type feedFreshness struct {
+	slo       FreshnessSLO
+	createdAt time.Time
+
+	mu         sync.Mutex
+	lastUpdate time.Time
+	// staleTotal is the stale time accounted since the creation.
+	staleTotal time.Duration
+	buckets    [freshnessBuckets]staleBucket
+}
+
+func newFeedFreshness(slo FreshnessSLO, now time.Time) *feedFreshness {
+	return &feedFreshness{
+		slo:        slo,
+		createdAt:  now,
+		lastUpdate: now,
+	}
+}
+
+// Observe records an update of the feed.
+func (m *feedFreshness) Observe(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !now.After(m.lastUpdate) {
+		return
+	}
+	if m.slo.enabled() {
+		if staleFrom := m.lastUpdate.Add(m.slo.MaxSilence); now.After(staleFrom) {
+			m.addStale(staleFrom, now)
+		}
+	}
+	m.lastUpdate = now
+}
+
+// addStale accounts the stale interval, splitting it over the buckets of
+// the window slices it spans.
+//
+// The caller must hold mu.
+func (m *feedFreshness) addStale(from time.Time, to time.Time) {
+	m.staleTotal += to.Sub(from)
+
+	width := m.slo.Window / freshnessBuckets
+	// Only the part within the window ending now is ever read back.
+	if windowStart := to.Add(-m.slo.Window); from.Before(windowStart) {
+		from = windowStart
+	}
+	for from.Before(to) {
+		slice := from.UnixNano() / int64(width)
+		end := time.Unix(0, (slice+1)*int64(width))
+		if end.After(to) {
+			end = to
+		}
+
+		bucket := &m.buckets[slice%freshnessBuckets]
+		if bucket.slice != slice {
+			*bucket = staleBucket{slice: slice}
+		}
+		bucket.stale += end.Sub(from)
+		from = end
+	}
+}
+
+// feedStatus is a snapshot of the freshness of a feed.
+type feedStatus struct {
+	LastUpdate    time.Time
+	SinceUpdate   time.Duration
+	Stale         bool
+	StaleTotal    time.Duration
+	StaleInWindow time.Duration
+	BurnRate      float64
+}
+
+// Status returns the freshness of the feed at the given time.
+func (m *feedFreshness) Status(now time.Time) feedStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := feedStatus{
+		LastUpdate:  m.lastUpdate,
+		SinceUpdate: max(now.Sub(m.lastUpdate), 0),
+		StaleTotal:  m.staleTotal,
+	}
+	if !m.slo.enabled() {
+		return status
+	}
+
+	windowStart := now.Add(-m.slo.Window)
+	width := m.slo.Window / freshnessBuckets
+	oldest := windowStart.UnixNano() / int64(width)
+	for _, bucket := range m.buckets {
+		// The bucket of the slice the window starts in is partially
+		// outside of it, which the accounting tolerates.
+		if bucket.slice >= oldest {
+			status.StaleInWindow += bucket.stale
+		}
+	}
+
+	// The ongoing silence is not accounted yet.
+	if staleFrom := m.lastUpdate.Add(m.slo.MaxSilence); now.After(staleFrom) {
+		status.Stale = true
+		status.StaleTotal += now.Sub(staleFrom)
+		if staleFrom.Before(windowStart) {
+			staleFrom = windowStart
+		}
+		status.StaleInWindow += now.Sub(staleFrom)
+	}
+
+	// A feed younger than the window is judged over its lifetime.
+	elapsed := min(now.Sub(m.createdAt), m.slo.Window)
+	if elapsed > 0 {
+		budget := 1 - m.slo.Objective
+		status.BurnRate = status.StaleInWindow.Seconds() / elapsed.Seconds() / budget
+	}
+
+	return status
+}
+
+// feedHealth returns the health of a feed given whether its export
+// sockets are connected.
+func feedHealth(status feedStatus, connected bool) adapterpb.FeedHealth {
+	switch {
+	case !connected:
+		return adapterpb.FeedHealth_FEED_HEALTH_DISCONNECTED
+	case status.Stale:
+		return adapterpb.FeedHealth_FEED_HEALTH_DEGRADED
+	default:
+		return adapterpb.FeedHealth_FEED_HEALTH_HEALTHY
+	}
+}
+
+// Proto returns the freshness of the feed in its wire form.
+func (m feedStatus) Proto(connected bool) *adapterpb.FeedFreshness {
+	return &adapterpb.FeedFreshness{
+		Health:        feedHealth(m, connected),
+		LastUpdateAt:  m.LastUpdate.UnixNano(),
+		StaleInWindow: int64(m.StaleInWindow),
+		BurnRate:      m.BurnRate,
+	}
+}
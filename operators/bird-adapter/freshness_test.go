@@ -0,0 +1,75 @@
+package bird_adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+func TestFreshnessSLO_Validate(t *testing.T) {
+	slo := DefaultFreshnessSLO()
+	require.NoError(t, slo.Validate())
+
+	slo.MaxSilence = 5 * time.Minute
+	require.NoError(t, slo.Validate())
+
+	slo.Objective = 1
+	require.Error(t, slo.Validate())
+
+	slo = DefaultFreshnessSLO()
+	slo.MaxSilence = 5 * time.Minute
+	slo.Window = time.Second
+	require.Error(t, slo.Validate())
+}
+
+func TestFeedFreshness(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	freshness := newFeedFreshness(FreshnessSLO{
+		MaxSilence: time.Minute,
+		Objective:  0.99,
+		Window:     time.Hour,
+	}, start)
+
+	freshness.Observe(start.Add(30 * time.Second))
+	status := freshness.Status(start.Add(time.Minute))
+	require.False(t, status.Stale)
+	require.Equal(t, 30*time.Second, status.SinceUpdate)
+	require.Zero(t, status.BurnRate)
+	require.Equal(t, adapterpb.FeedHealth_FEED_HEALTH_HEALTHY, feedHealth(status, true))
+
+	// Silent for 90s past the allowed minute, while connected.
+	status = freshness.Status(start.Add(3 * time.Minute))
+	require.True(t, status.Stale)
+	require.Equal(t, 90*time.Second, status.StaleInWindow)
+	require.InDelta(t, 50, status.BurnRate, 1e-9)
+	require.Equal(t, adapterpb.FeedHealth_FEED_HEALTH_DEGRADED, feedHealth(status, true))
+	require.Equal(t, adapterpb.FeedHealth_FEED_HEALTH_DISCONNECTED, feedHealth(status, false))
+
+	// The update ends the silence, which stays accounted.
+	freshness.Observe(start.Add(3 * time.Minute))
+	status = freshness.Status(start.Add(4 * time.Minute))
+	require.False(t, status.Stale)
+	require.Equal(t, 90*time.Second, status.StaleInWindow)
+	require.Equal(t, 90*time.Second, status.StaleTotal)
+
+	// The accounted silence leaves the window, the ongoing one fills it.
+	status = freshness.Status(start.Add(3*time.Minute + 2*time.Hour))
+	require.True(t, status.Stale)
+	require.Equal(t, time.Hour, status.StaleInWindow)
+	require.Equal(t, 90*time.Second+2*time.Hour-time.Minute, status.StaleTotal)
+	require.InDelta(t, 100, status.BurnRate, 1e-9)
+}
+
+func TestFeedFreshness_Disabled(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	freshness := newFeedFreshness(DefaultFreshnessSLO(), start)
+
+	status := freshness.Status(start.Add(24 * time.Hour))
+	require.False(t, status.Stale)
+	require.Equal(t, 24*time.Hour, status.SinceUpdate)
+	require.Zero(t, status.StaleInWindow)
+	require.Equal(t, adapterpb.FeedHealth_FEED_HEALTH_HEALTHY, feedHealth(status, true))
+}
@@ -0,0 +1,94 @@
+package bird_adapter
+
+import (
+	"slices"
+	"time"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// maxConfigGenerations is the number of recent generations kept per
+// configuration name.
+const maxConfigGenerations = 64
+
+// configHistory records applied generations of every configuration name.
+//
+// It is not synchronized, callers must hold AdapterService.importsMu.
+type configHistory struct {
+	// generations are ordered from the oldest to the newest.
+	generations map[string][]*adapterpb.ConfigGeneration
+	// last is the last generation number assigned to the name. It keeps
+	// growing when old generations are evicted.
+	last map[string]uint64
+}
+
+func newConfigHistory() *configHistory {
+	return &configHistory{
+		generations: map[string][]*adapterpb.ConfigGeneration{},
+		last:        map[string]uint64{},
+	}
+}
+
+// Add records a new generation of the named configuration.
+func (m *configHistory) Add(
+	name string,
+	sockets []string,
+	metadata *adapterpb.ConfigMetadata,
+	digest string,
+	now time.Time,
+) *adapterpb.ConfigGeneration {
+	m.last[name]++
+
+	if metadata == nil {
+		metadata = &adapterpb.ConfigMetadata{}
+	}
+	generation := &adapterpb.ConfigGeneration{
+		Generation: m.last[name],
+		AppliedAt:  now.UnixNano(),
+		Metadata:   metadata,
+		Sockets:    slices.Clone(sockets),
+		Digest:     digest,
+	}
+
+	generations := append(m.generations[name], generation)
+	if len(generations) > maxConfigGenerations {
+		generations = slices.Delete(generations, 0, len(generations)-maxConfigGenerations)
+	}
+	m.generations[name] = generations
+
+	return generation
+}
+
+// List returns the recorded generations of the named configuration,
+// newest first.
+func (m *configHistory) List(name string) []*adapterpb.ConfigGeneration {
+	generations := slices.Clone(m.generations[name])
+	slices.Reverse(generations)
+	return generations
+}
+
+// Restore replaces the recorded generations of the named configuration
+// with the persisted ones, ordered from the oldest to the newest.
+func (m *configHistory) Restore(name string, generations []*adapterpb.ConfigGeneration, last uint64) {
+	if len(generations) > maxConfigGenerations {
+		generations = generations[len(generations)-maxConfigGenerations:]
+	}
+	for _, generation := range generations {
+		last = max(last, generation.GetGeneration())
+	}
+
+	m.generations[name] = slices.Clone(generations)
+	m.last[name] = last
+}
+
+// Applied returns the state of the named configuration to persist, applied
+// by the request.
+func (m *configHistory) Applied(req *adapterpb.SetupConfigRequest, digest string) *adapterpb.AppliedConfig {
+	name := req.GetName()
+	return &adapterpb.AppliedConfig{
+		Request:        req,
+		Digest:         digest,
+		Generations:    m.generations[name],
+		LastGeneration: m.last[name],
+	}
+}
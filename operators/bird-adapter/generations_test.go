@@ -0,0 +1,47 @@
+package bird_adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+func TestConfigHistory(t *testing.T) {
+	history := newConfigHistory()
+	now := time.Unix(1700000000, 0)
+
+	first := history.Add("route0", []string{"/run/bird.sock"}, &adapterpb.ConfigMetadata{
+		Author: "alice",
+		Ticket: "NET-1",
+	}, "", now)
+	require.Equal(t, uint64(1), first.GetGeneration())
+	require.Equal(t, now.UnixNano(), first.GetAppliedAt())
+
+	second := history.Add("route0", []string{"/run/bird6.sock"}, nil, "", now.Add(time.Minute))
+	require.Equal(t, uint64(2), second.GetGeneration())
+	require.NotNil(t, second.GetMetadata())
+
+	// Generations are numbered per configuration name.
+	require.Equal(t, uint64(1), history.Add("route1", nil, nil, "", now).GetGeneration())
+
+	generations := history.List("route0")
+	require.Len(t, generations, 2)
+	require.Equal(t, uint64(2), generations[0].GetGeneration())
+	require.Equal(t, "alice", generations[1].GetMetadata().GetAuthor())
+	require.Empty(t, history.List("route2"))
+}
+
+func TestConfigHistory_Eviction(t *testing.T) {
+	history := newConfigHistory()
+	for range maxConfigGenerations + 10 {
+		history.Add("route0", nil, nil, "", time.Now())
+	}
+
+	generations := history.List("route0")
+	require.Len(t, generations, maxConfigGenerations)
+	require.Equal(t, uint64(maxConfigGenerations+10), generations[0].GetGeneration())
+	require.Equal(t, uint64(11), generations[len(generations)-1].GetGeneration())
+}
@@ -0,0 +1,133 @@
+package bird_adapter
+
+import (
+	"sync/atomic"
+	"time"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/metrics"
+)
+
+// flushLatencyBuckets are the bucket boundaries of the flush latency in
+// seconds.
+//
+// A backpressured route operator holds a batch for seconds, so the ladder
+// goes higher than a healthy flush ever takes.
+var flushLatencyBuckets = []float64{
+	0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// streamState is the state of the FeedRIB stream of an import.
+type streamState int32
+
+const (
+	// streamUp is a stream the routes are sent on.
+	streamUp streamState = iota
+	// streamBackoff is a failed reader waiting before it is run again.
+	streamBackoff
+	// streamReconnecting is a stream being established again.
+	streamReconnecting
+	// streamClosed is the stream of a stopped import.
+	streamClosed
+	// streamPending is the stream of an import that failed to open on
+	// setup, retried in the background.
+	streamPending
+)
+
+var streamStateNames = [...]string{
+	streamUp:           "up",
+	streamBackoff:      "backoff",
+	streamReconnecting: "reconnecting",
+	streamClosed:       "closed",
+	streamPending:      "pending",
+}
+
+func (m streamState) String() string {
+	return streamStateNames[m]
+}
+
+// importMetrics tracks the routes of an import on their way from the
+// source to the FeedRIB stream.
+//
+// It is updated by the reader callbacks and the import loop while the
+// metrics are collected, so every value is atomic.
+type importMetrics struct {
+	// received is the number of routes read from the source, rejected
+	// ones included.
+	received metrics.Counter
+	// sent is the number of routes sent on the stream.
+	sent metrics.Counter
+	// reconnects is the number of times the stream was established again.
+	reconnects metrics.Counter
+	// stale is the number of times the BIRD sockets went silent for the
+	// idle timeout and were reconnected to.
+	stale metrics.Counter
+	// flushLatency is the time from the start of sending a batch to its
+	// flush being sent, in seconds.
+	flushLatency *metrics.Histogram
+	// backoff is the wait before the failed reader is run again, zero
+	// unless backing off.
+	backoff metrics.Gauge
+	state   atomic.Int32
+}
+
+func newImportMetrics() *importMetrics {
+	return &importMetrics{
+		flushLatency: metrics.NewHistogram(flushLatencyBuckets),
+	}
+}
+
+// SetState sets the state of the stream.
+func (m *importMetrics) SetState(state streamState) {
+	m.state.Store(int32(state))
+}
+
+// State returns the state of the stream.
+func (m *importMetrics) State() streamState {
+	return streamState(m.state.Load())
+}
+
+// BackingOff records the wait before the failed reader is run again.
+func (m *importMetrics) BackingOff(wait time.Duration) {
+	m.SetState(streamBackoff)
+	m.backoff.Store(wait.Seconds())
+}
+
+// Reconnecting records the stream being established again after the
+// backoff.
+func (m *importMetrics) Reconnecting() {
+	m.SetState(streamReconnecting)
+	m.backoff.Store(0)
+}
+
+// Reconnected records the stream established again.
+func (m *importMetrics) Reconnected() {
+	m.reconnects.Inc()
+	m.SetState(streamUp)
+}
+
+// Collect renders the metrics with the given labels.
+//
+// The stream state is one gauge per state, set for the current one, so that
+// alerts select a state by label.
+func (m *importMetrics) Collect(labels ...*commonpb.Label) []*commonpb.Metric {
+	state := m.State()
+
+	out := []*commonpb.Metric{
+		makeCounter("bird_adapter_import_routes_received_total", m.received.Load(), labels...),
+		makeCounter("bird_adapter_import_routes_sent_total", m.sent.Load(), labels...),
+		makeCounter("bird_adapter_import_reconnects_total", m.reconnects.Load(), labels...),
+		makeCounter("bird_adapter_import_stale_total", m.stale.Load(), labels...),
+		{
+			Name:   "bird_adapter_import_flush_latency_seconds",
+			Labels: labels,
+			Value:  commonpb.MetricValueToProto(m.flushLatency),
+		},
+		makeGauge("bird_adapter_import_backoff_seconds", m.backoff.Load(), labels...),
+	}
+	for idx, name := range streamStateNames {
+		stateLabels := append(append([]*commonpb.Label{}, labels...), makeLabel("state", name))
+		out = append(out, makeGauge("bird_adapter_import_stream_state", boolGauge(streamState(idx) == state), stateLabels...))
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package bird_adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+)
+
+func TestImportMetrics(t *testing.T) {
+	metrics := newImportMetrics()
+	metrics.received.Add(3)
+	metrics.sent.Add(2)
+	metrics.stale.Inc()
+	metrics.flushLatency.Observe(0.02)
+
+	// collect returns the values by name, the stream state by its state
+	// label.
+	collect := func() map[string]*commonpb.Metric {
+		out := map[string]*commonpb.Metric{}
+		for _, metric := range metrics.Collect(makeLabel("config", "bird0")) {
+			require.Equal(t, "bird0", metric.GetLabels()[0].GetValue())
+
+			name := metric.GetName()
+			if len(metric.GetLabels()) > 1 {
+				name += "/" + metric.GetLabels()[1].GetValue()
+			}
+			out[name] = metric
+		}
+		return out
+	}
+
+	out := collect()
+	require.Equal(t, uint64(3), out["bird_adapter_import_routes_received_total"].GetCounter())
+	require.Equal(t, uint64(2), out["bird_adapter_import_routes_sent_total"].GetCounter())
+	require.Equal(t, uint64(1), out["bird_adapter_import_stale_total"].GetCounter())
+	require.Equal(t, uint64(1), out["bird_adapter_import_flush_latency_seconds"].GetHistogram().GetTotalCount())
+	require.Equal(t, float64(1), out["bird_adapter_import_stream_state/up"].GetGauge())
+	require.Equal(t, float64(0), out["bird_adapter_import_stream_state/backoff"].GetGauge())
+
+	metrics.BackingOff(1500 * time.Millisecond)
+	out = collect()
+	require.Equal(t, 1.5, out["bird_adapter_import_backoff_seconds"].GetGauge())
+	require.Equal(t, float64(0), out["bird_adapter_import_stream_state/up"].GetGauge())
+	require.Equal(t, float64(1), out["bird_adapter_import_stream_state/backoff"].GetGauge())
+
+	metrics.Reconnecting()
+	require.Equal(t, float64(1), collect()["bird_adapter_import_stream_state/reconnecting"].GetGauge())
+
+	metrics.Reconnected()
+	out = collect()
+	require.Equal(t, uint64(1), out["bird_adapter_import_reconnects_total"].GetCounter())
+	require.Equal(t, float64(0), out["bird_adapter_import_backoff_seconds"].GetGauge())
+	require.Equal(t, float64(1), out["bird_adapter_import_stream_state/up"].GetGauge())
+}
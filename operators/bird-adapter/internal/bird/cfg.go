@@ -1,6 +1,7 @@
 package bird
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/c2h5oh/datasize"
@@ -16,12 +17,48 @@ type Config struct {
 	DumpTimeout time.Duration `yaml:"dump_timeout"`
 	// DumpThreshold configures the threshold beyond which routes are forcibly dumped.
 	DumpThreshold int `yaml:"dump_threshold"`
+	// QueueHighWatermark is the number of routes queued for the updater at
+	// which the sockets stop being read.
+	//
+	// Updates of the routes already queued replace them rather than
+	// counting towards the watermark.
+	QueueHighWatermark int `yaml:"queue_high_watermark"`
+	// QueueLowWatermark is the number of queued routes the updater has to
+	// drain the queue down to before the sockets are read again.
+	QueueLowWatermark int `yaml:"queue_low_watermark"`
+	// IdleTimeout is how long a socket may stay silent before the reader
+	// considers it stale and fails with ErrStale, zero to wait forever.
+	//
+	// BIRD sends nothing while no route changes, so the timeout has to
+	// exceed the quiet periods of the exported table.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		ParserBufSize: datasize.MB,
-		DumpTimeout:   time.Second,
-		DumpThreshold: 10_000,
+		ParserBufSize:      datasize.MB,
+		DumpTimeout:        time.Second,
+		DumpThreshold:      10_000,
+		QueueHighWatermark: 100_000,
+		QueueLowWatermark:  50_000,
 	}
 }
+
+// Validate checks that the queue watermarks are consistent and the idle
+// timeout is not negative.
+func (m *Config) Validate() error {
+	if m.QueueHighWatermark <= 0 {
+		return fmt.Errorf("queue high watermark must be positive, got %d", m.QueueHighWatermark)
+	}
+	if m.QueueLowWatermark < 0 || m.QueueLowWatermark >= m.QueueHighWatermark {
+		return fmt.Errorf(
+			"queue low watermark %d must be below the high watermark %d",
+			m.QueueLowWatermark,
+			m.QueueHighWatermark,
+		)
+	}
+	if m.IdleTimeout < 0 {
+		return fmt.Errorf("idle timeout must not be negative, got %s", m.IdleTimeout)
+	}
+	return nil
+}
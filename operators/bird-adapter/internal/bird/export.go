@@ -3,8 +3,11 @@ package bird
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,6 +19,22 @@ import (
 type Updater func(context.Context, []rib.Route) error
 type Notifier func() error
 
+// Rejecter is called for every route update skipped because it cannot be
+// imported, along with the partially decoded route and the decode error.
+type Rejecter func(*rib.Route, error)
+
+// isRejectedRoute reports whether the decode error concerns only the route
+// itself, so that the update can be skipped without breaking the stream.
+func isRejectedRoute(err error) bool {
+	return errors.Is(err, ErrBadPrefix) ||
+		errors.Is(err, ErrUnsupportedPrefix) ||
+		errors.Is(err, ErrUnsupportedRDType)
+}
+
+// ErrStale is returned by Run when a socket sends nothing for the idle
+// timeout of the config while staying open.
+var ErrStale = errors.New("bird export socket is stale")
+
 type exportSocket struct {
 	path    string
 	bufSize int
@@ -27,10 +46,41 @@ type Export struct {
 	cfg      *Config
 	updater  Updater
 	notifier Notifier
-	log      *zap.Logger
+	// endOfRIB is called once per Run, when the initial dump is drained.
+	endOfRIB Notifier
+	rejecter Rejecter
+	// queue holds the routes read from the sockets until the updater takes
+	// them.
+	queue *routeQueue
+	// connected is the number of sockets currently dialed by Run.
+	connected atomic.Int32
+	log       *zap.Logger
 }
 
-func NewExportReader(cfg *Config, onUpdate Updater, onFlush Notifier, log *zap.Logger) *Export {
+// NewExportReader creates a reader for the BIRD export sockets.
+//
+// BIRD does not mark the end of the table dump it sends on connect, so the
+// reader treats the first DumpTimeout of silence as the end of the initial
+// dump and calls onEndOfRIB right after the pending batch is flushed.
+//
+// Updates with a bad prefix or an unsupported network or route
+// distinguisher type are passed to onReject and skipped.
+//
+// With a nonzero IdleTimeout, a socket silent for that long fails Run with
+// ErrStale, so that a BIRD daemon that stopped exporting without closing
+// the socket is reconnected to.
+//
+// The routes read are queued for onUpdate up to the high watermark of the
+// config, a newer update of a queued route replacing it, so that a slow
+// onUpdate holds the sockets back instead of the routes piling up.
+func NewExportReader(
+	cfg *Config,
+	onUpdate Updater,
+	onFlush Notifier,
+	onEndOfRIB Notifier,
+	onReject Rejecter,
+	log *zap.Logger,
+) *Export {
 	sockets := make([]exportSocket, 0, len(cfg.Sockets))
 	for _, s := range cfg.Sockets {
 		sockets = append(sockets, exportSocket{
@@ -43,23 +93,39 @@ func NewExportReader(cfg *Config, onUpdate Updater, onFlush Notifier, log *zap.L
 		cfg:      cfg,
 		updater:  onUpdate,
 		notifier: onFlush,
+		endOfRIB: onEndOfRIB,
+		rejecter: onReject,
+		queue:    newRouteQueue(cfg.QueueHighWatermark, cfg.QueueLowWatermark),
 		log:      log,
 	}
 }
 
+// QueueStats returns a snapshot of the queue of the routes read.
+func (m *Export) QueueStats() QueueStats {
+	return m.queue.Stats()
+}
+
+// Connected reports whether Run is reading from every export socket.
+//
+// A connected reader receiving no updates points at a wedged BIRD daemon
+// rather than a dead one.
+func (m *Export) Connected() bool {
+	return len(m.sockets) > 0 && int(m.connected.Load()) == len(m.sockets)
+}
+
 func (m *Export) Run(ctx context.Context) error {
 	if len(m.cfg.Sockets) == 0 {
 		m.log.Info("bird export reader is disabled, no sockets provided")
 		return nil
 	}
 
-	// Any value greater then zero will be sufficient for the channel capacity.
-	// A buffered channel will reduce concurrency pressure, but it seems that
-	// the reading part can easily keep up with the parsing part.
-	// On the other hand, if RIBUpdater.BulkUpdate cannot catch up with the
-	// parser's speed, there is no reason to hold too many routes in memory.
-	updates := make(chan *rib.Route, 10)
-	defer close(updates)
+	// The routes left by the previous run are dumped again by BIRD on
+	// connect.
+	m.queue.Reset()
+
+	// Flushing before the high watermark is reached keeps the readers from
+	// waiting for the dump timeout.
+	threshold := min(m.cfg.DumpThreshold, m.cfg.QueueHighWatermark)
 
 	ctx, cancel := context.WithCancelCause(ctx)
 	defer cancel(nil)
@@ -75,6 +141,8 @@ func (m *Export) Run(ctx context.Context) error {
 			if err != nil {
 				return fmt.Errorf("failed to dial bird export socket '%s': %w", socket.path, err)
 			}
+			m.connected.Add(1)
+			defer m.connected.Add(-1)
 			go func() {
 				<-ctx.Done()
 				if err := c.Close(); err != nil {
@@ -84,26 +152,38 @@ func (m *Export) Run(ctx context.Context) error {
 			reader := bufio.NewReader(c)
 			parser := NewParser(reader, socket.bufSize, m.log)
 			for {
+				if m.cfg.IdleTimeout > 0 {
+					if err := c.SetReadDeadline(time.Now().Add(m.cfg.IdleTimeout)); err != nil {
+						return fmt.Errorf("failed to set bird export socket deadline: %w", err)
+					}
+				}
 				update, err := parser.Next()
+				if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() == nil {
+					m.log.Warn("bird export socket is stale, no update received",
+						zap.String("path", socket.path),
+						zap.Duration("idle_timeout", m.cfg.IdleTimeout),
+					)
+					// Returned rather than cancelled with, so that the group
+					// reports it instead of the other readers cancelled.
+					return fmt.Errorf("%w: no update from '%s' for %s", ErrStale, socket.path, m.cfg.IdleTimeout)
+				}
 				if err != nil {
-					if err == ErrUnsupportedRDType {
-						// FIXME add telemetry
-						continue
-					}
 					cancel(err)
 					return fmt.Errorf("failed to parse next update chunk: %w", err)
 				}
 				route := &rib.Route{}
 				if err := update.Decode(route); err != nil {
+					if isRejectedRoute(err) {
+						m.rejecter(route, err)
+						continue
+					}
 					cancel(err)
 					return fmt.Errorf("failed to decode next route update: %w", err)
 				}
 				route.SourceID = rib.RouteSourceBird
 
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case updates <- route:
+				if err := m.queue.Push(ctx, route); err != nil {
+					return err
 				}
 			}
 		})
@@ -112,34 +192,49 @@ func (m *Export) Run(ctx context.Context) error {
 
 	wg.Go(func() error {
 		m.log.Info("starting batch processor for bird route updates")
-		batch := make([]rib.Route, 0, m.cfg.DumpThreshold)
+		batch := make([]rib.Route, 0, threshold)
 		tick := time.NewTicker(m.cfg.DumpTimeout)
 		timeout := false
+		initialDump := true
 		for {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case route := <-updates:
-				batch = append(batch, *route)
+			case <-m.queue.Ready():
 				tick.Reset(m.cfg.DumpTimeout)
 			case <-tick.C:
-				if len(batch) == 0 {
+				if m.queue.Len() == 0 {
+					if initialDump {
+						initialDump = false
+						if err := m.endOfRIB(); err != nil {
+							return fmt.Errorf("failed to call end-of-RIB notifier: %w", err)
+						}
+					}
 					continue
 				}
 				timeout = true
 			}
 
-			if timeout || len(batch) >= m.cfg.DumpThreshold {
+			// Every full batch is sent, and on timeout the remainder too.
+			for m.queue.Len() >= threshold || (timeout && m.queue.Len() > 0) {
+				batch = m.queue.Pop(batch[:0], threshold)
 				m.log.Debug("send RIB update", zap.Int("size", len(batch)),
 					zap.Bool("isTimeout", timeout))
 				if err := m.updater(ctx, batch); err != nil {
 					return fmt.Errorf("failed to call updater: %w", err)
 				}
-				batch = batch[:0]
 
 				if err := m.notifier(); err != nil {
 					return fmt.Errorf("failed to call notifier: %w", err)
 				}
+
+				if timeout && initialDump {
+					initialDump = false
+					m.log.Info("bird initial dump is complete")
+					if err := m.endOfRIB(); err != nil {
+						return fmt.Errorf("failed to call end-of-RIB notifier: %w", err)
+					}
+				}
 			}
 
 			timeout = false
@@ -0,0 +1,58 @@
+package bird
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// TestExportIdleTimeout verifies that a socket kept open without sending
+// anything fails the reader as stale.
+func TestExportIdleTimeout(t *testing.T) {
+	// Unix socket paths are short, so the default temporary directory of
+	// the test may not fit.
+	dir, err := os.MkdirTemp("", "bird")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "export.sock")
+
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		// The connection is held open and silent until the test ends.
+		conn, err := listener.Accept()
+		if err == nil {
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	cfg := DefaultConfig()
+	cfg.Sockets = []string{path}
+	cfg.DumpTimeout = 10 * time.Millisecond
+	cfg.IdleTimeout = 50 * time.Millisecond
+	notify := func() error { return nil }
+	export := NewExportReader(
+		cfg,
+		func(context.Context, []rib.Route) error { return nil },
+		notify,
+		notify,
+		func(*rib.Route, error) {},
+		zap.NewNop(),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = export.Run(ctx)
+	require.ErrorIs(t, err, ErrStale)
+	require.GreaterOrEqual(t, time.Since(start), cfg.IdleTimeout)
+}
@@ -0,0 +1,186 @@
+package bird
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// routeKey identifies the route a BIRD update is about, so that a newer
+// update of the same route supersedes a queued one.
+type routeKey struct {
+	prefix netip.Prefix
+	peer   netip.Addr
+	rd     uint64
+}
+
+func newRouteKey(route *rib.Route) routeKey {
+	return routeKey{
+		prefix: route.Prefix,
+		peer:   route.Peer,
+		rd:     route.RD,
+	}
+}
+
+// QueueStats is a snapshot of the queue between the BIRD socket readers
+// and the updater.
+type QueueStats struct {
+	// Depth is the number of routes queued.
+	Depth int
+	// Coalesced is the number of route updates superseded by a newer update
+	// of the same route before being passed on.
+	Coalesced uint64
+	// Paused is the number of times the readers stopped reading at the high
+	// watermark.
+	Paused uint64
+}
+
+// routeQueue is a bounded queue of the latest state of the routes read from
+// the BIRD sockets.
+//
+// An update of a route already queued replaces it in place, so a slow
+// updater sees the latest state of a flapping route instead of its whole
+// history. Once the number of queued routes reaches the high watermark,
+// updates of the routes not queued yet block until the updater drains the
+// queue down to the low watermark, leaving the rest of the backlog buffered
+// by the sockets.
+type routeQueue struct {
+	mu   sync.Mutex
+	high int
+	low  int
+	// keys are the queued routes in the order they were first queued,
+	// starting at head.
+	keys   []routeKey
+	head   int
+	routes map[routeKey]rib.Route
+	// resume is closed once the queue is drained down to the low
+	// watermark, nil unless paused.
+	resume chan struct{}
+	// ready is signalled whenever a route is queued.
+	ready chan struct{}
+
+	coalesced atomic.Uint64
+	paused    atomic.Uint64
+}
+
+func newRouteQueue(high int, low int) *routeQueue {
+	return &routeQueue{
+		high:   high,
+		low:    low,
+		routes: map[routeKey]rib.Route{},
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// Push queues the route, waiting while the queue is paused unless the
+// route replaces a queued one.
+func (m *routeQueue) Push(ctx context.Context, route *rib.Route) error {
+	key := newRouteKey(route)
+
+	m.mu.Lock()
+	for {
+		if _, ok := m.routes[key]; ok {
+			m.routes[key] = *route
+			m.mu.Unlock()
+			m.coalesced.Add(1)
+			m.signal()
+			return nil
+		}
+		if m.resume == nil {
+			break
+		}
+
+		resume := m.resume
+		m.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-resume:
+		}
+		m.mu.Lock()
+	}
+
+	m.keys = append(m.keys, key)
+	m.routes[key] = *route
+	if len(m.routes) >= m.high {
+		m.resume = make(chan struct{})
+		m.paused.Add(1)
+	}
+	m.mu.Unlock()
+
+	m.signal()
+	return nil
+}
+
+func (m *routeQueue) signal() {
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready returns the channel signalled whenever a route is queued.
+func (m *routeQueue) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Len returns the number of queued routes.
+func (m *routeQueue) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.routes)
+}
+
+// Pop appends up to limit queued routes to the batch, in the order they
+// were first queued.
+func (m *routeQueue) Pop(batch []rib.Route, limit int) []rib.Route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ; limit > 0 && m.head < len(m.keys); limit-- {
+		key := m.keys[m.head]
+		m.head++
+		batch = append(batch, m.routes[key])
+		delete(m.routes, key)
+	}
+	if m.head == len(m.keys) {
+		m.keys = m.keys[:0]
+		m.head = 0
+	} else if m.head > len(m.keys)/2 {
+		m.keys = m.keys[:copy(m.keys, m.keys[m.head:])]
+		m.head = 0
+	}
+
+	if m.resume != nil && len(m.routes) <= m.low {
+		close(m.resume)
+		m.resume = nil
+	}
+	return batch
+}
+
+// Reset drops the queued routes.
+func (m *routeQueue) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys = m.keys[:0]
+	m.head = 0
+	clear(m.routes)
+	if m.resume != nil {
+		close(m.resume)
+		m.resume = nil
+	}
+}
+
+// Stats returns a snapshot of the queue.
+func (m *routeQueue) Stats() QueueStats {
+	return QueueStats{
+		Depth:     m.Len(),
+		Coalesced: m.coalesced.Load(),
+		Paused:    m.paused.Load(),
+	}
+}
@@ -0,0 +1,77 @@
+package bird
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+func queueRoute(prefix string, med uint32) *rib.Route {
+	return &rib.Route{
+		Prefix: netip.MustParsePrefix(prefix),
+		Peer:   netip.MustParseAddr("192.0.2.1"),
+		Med:    med,
+	}
+}
+
+func TestRouteQueue_Coalesce(t *testing.T) {
+	queue := newRouteQueue(10, 5)
+	ctx := context.Background()
+
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.0.0/24", 1)))
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.1.0/24", 1)))
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.0.0/24", 2)))
+	withdrawal := queueRoute("10.0.1.0/24", 0)
+	withdrawal.ToRemove = true
+	require.NoError(t, queue.Push(ctx, withdrawal))
+
+	stats := queue.Stats()
+	require.Equal(t, 2, stats.Depth)
+	require.Equal(t, uint64(2), stats.Coalesced)
+
+	// The latest state of each route, in the order they were first queued.
+	batch := queue.Pop(nil, 10)
+	require.Len(t, batch, 2)
+	require.Equal(t, uint32(2), batch[0].Med)
+	require.True(t, batch[1].ToRemove)
+	require.Zero(t, queue.Len())
+}
+
+func TestRouteQueue_Watermarks(t *testing.T) {
+	queue := newRouteQueue(2, 1)
+	ctx := context.Background()
+
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.0.0/24", 1)))
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.1.0/24", 1)))
+	require.Equal(t, uint64(1), queue.Stats().Paused)
+
+	// A queued route is still replaced at the high watermark.
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.1.0/24", 2)))
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- queue.Push(ctx, queueRoute("10.0.2.0/24", 1))
+	}()
+	select {
+	case <-pushed:
+		require.FailNow(t, "pushed over the high watermark")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Len(t, queue.Pop(nil, 1), 1)
+	require.NoError(t, <-pushed)
+	require.Equal(t, 2, queue.Len())
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, queue.Push(timeoutCtx, queueRoute("10.0.3.0/24", 1)), context.DeadlineExceeded)
+
+	queue.Reset()
+	require.Zero(t, queue.Len())
+	require.NoError(t, queue.Push(ctx, queueRoute("10.0.3.0/24", 1)))
+}
@@ -395,19 +395,7 @@ func (m *updateDecoder) decodeComplexAttribute(route *rib.Route, data []byte, ty
 			return fmt.Errorf("unhandled ASPath attribute data len=%d: %#v: %w", len(data), data, ErrAttrsUnexpectedEOD)
 		}
 	case AttrNextHop:
-		switch len(data) {
-		case net.IPv6len:
-			route.NextHop = netipAddrFrom4U32([16]byte(data[:net.IPv6len]))
-		case net.IPv6len * 2: // Link-Local next hop?
-			// Try to use the second addr.
-			route.NextHop = netipAddrFrom4U32([16]byte(data[net.IPv6len:]))
-			if route.NextHop.IsUnspecified() {
-				// If the second addr is zero, use the first addr.
-				route.NextHop = netipAddrFrom4U32([16]byte(data[:net.IPv6len]))
-			}
-		default:
-			m.log.Debug("unexpected next_hop attribute length", zap.Int("data_len", len(data)))
-		}
+		route.NextHop = m.decodeNextHop(data)
 	case AttrCommunity:
 		if len(data)%(int(sizeOfUint16)+int(sizeOfUint16)) != 0 {
 			return fmt.Errorf("invalid Communities size: %d", len(data))
@@ -482,6 +470,32 @@ func (m *updateDecoder) decodeComplexAttribute(route *rib.Route, data []byte, ty
 	return nil
 }
 
+// decodeNextHop decodes the NEXT_HOP attribute, which BIRD stores as one or
+// two 16-byte addresses regardless of the prefix family.
+//
+// An IPv4 next hop is IPv4-mapped. An IPv6 one may be announced for an IPv4
+// prefix as well, when the session negotiated the extended next hop
+// encoding (RFC 8950, formerly RFC 5549) as unnumbered BGP fabrics do. Two
+// addresses are the global and the link-local next hop of RFC 2545; the
+// link-local one is used unless it is unspecified, since it is the address
+// the neighbour is resolved by on the shared link. An invalid address is
+// returned for any other length.
+func (m *updateDecoder) decodeNextHop(data []byte) netip.Addr {
+	switch len(data) {
+	case net.IPv6len:
+		return netipAddrFrom4U32([16]byte(data[:net.IPv6len]))
+	case net.IPv6len * 2:
+		linkLocal := netipAddrFrom4U32([16]byte(data[net.IPv6len:]))
+		if !linkLocal.IsUnspecified() {
+			return linkLocal
+		}
+		return netipAddrFrom4U32([16]byte(data[:net.IPv6len]))
+	default:
+		m.log.Debug("unexpected next_hop attribute length", zap.Int("data_len", len(data)))
+		return netip.Addr{}
+	}
+}
+
 func netipAddrFrom4U32(b [16]byte) netip.Addr {
 	return netip.AddrFrom16([16]byte{
 		b[3], b[2], b[1], b[0],
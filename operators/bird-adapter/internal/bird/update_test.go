@@ -366,6 +366,57 @@ func TestDecodeUpdate(t *testing.T) {
 				ASPathLen: 1,
 			},
 		},
+		{
+			// IPv4 prefix via an IPv6 next hop (RFC 8950).
+			name: "OK ipv4 extended next hop",
+			data: []byte{
+				0: 0x1,    // NetIP4
+				1: 0x10,   // prefix len 16
+				2: 0x8, 0, // NetAddrUnion length 8
+				4: 0, 0, 1, 10, // prefix 10.1.0.0 LE u32
+				40: 0x1, 0, 0, 0, // opType insert
+				// peer addr all zero
+				60: 24, 0, 0, 0, // attrsAreaSize = 4+4+16 = 24
+				// NEXT_HOP attr: type + PROTOCOL_BGP
+				64: 0x3, 0x4, 0, 0,
+				// attr data size = 16 bytes - one ipv6 addr
+				68: 0x10, 0, 0, 0,
+				// 2001:db8::1 as 4 LE u32
+				72: 0xb8, 0xd, 0x1, 0x20, 0, 0, 0, 0, 0, 0, 0, 0, 0x1, 0, 0, 0,
+			},
+			expected: rib.Route{
+				Prefix:  netip.MustParsePrefix("10.1.0.0/16"),
+				NextHop: netip.MustParseAddr("2001:db8::1"),
+				Peer:    netip.IPv6Unspecified(),
+			},
+		},
+		{
+			// IPv4 prefix via the global and the link-local IPv6 next hops
+			// (RFC 2545 §3): the link-local one wins.
+			name: "OK ipv4 extended link-local next hop",
+			data: []byte{
+				0: 0x1,    // NetIP4
+				1: 0x10,   // prefix len 16
+				2: 0x8, 0, // NetAddrUnion length 8
+				4: 0, 0, 1, 10, // prefix 10.1.0.0 LE u32
+				40: 0x1, 0, 0, 0, // opType insert
+				// peer addr all zero
+				60: 40, 0, 0, 0, // attrsAreaSize = 4+4+32 = 40
+				// NEXT_HOP attr: type + PROTOCOL_BGP
+				64: 0x3, 0x4, 0, 0,
+				// attr data size = 32 bytes - two ipv6 addrs
+				68: 0x20, 0, 0, 0,
+				// 2001:db8::1 as 4 LE u32
+				72: 0xb8, 0xd, 0x1, 0x20, 0, 0, 0, 0, 0, 0, 0, 0, 0x1, 0, 0, 0,
+				// fe80::1 as 4 LE u32
+				88: 0, 0, 0x80, 0xfe, 0, 0, 0, 0, 0, 0, 0, 0, 0x1, 0, 0, 0,
+			},
+			expected: rib.Route{
+				Prefix:  netip.MustParsePrefix("10.1.0.0/16"),
+				NextHop: netip.MustParseAddr("fe80::1"),
+				Peer:    netip.IPv6Unspecified(),
+			},
+		},
 		{
 			name:   "ERR Empty",
 			data:   []byte{},
@@ -0,0 +1,22 @@
+package kernel
+
+import (
+	"time"
+)
+
+// Config configures the import of the kernel FIB.
+type Config struct {
+	// Tables are the routing tables to import the routes of. The main
+	// table is imported if empty.
+	Tables []int
+	// Protocols are the route protocols to import the routes of, e.g. 186
+	// for the BGP routes installed by zebra. Every route but the ones the
+	// kernel creates on its own is imported if empty.
+	Protocols []int
+	// DumpTimeout configures the timeout after which routes are forcibly
+	// dumped.
+	DumpTimeout time.Duration
+	// DumpThreshold configures the threshold beyond which routes are
+	// forcibly dumped.
+	DumpThreshold int
+}
@@ -0,0 +1,171 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// updateQueueSize is the capacity of the route notification queue, which
+// buffers the notifications arriving during the initial dump.
+const updateQueueSize = 4096
+
+var errSubscriptionClosed = errors.New("netlink route subscription closed")
+
+type Updater func(context.Context, []rib.Route) error
+type Notifier func() error
+
+// Import reads the routes of the kernel FIB via rtnetlink.
+//
+// It is the socket-free counterpart of the BIRD export reader, for routing
+// daemons such as FRR that install their routes into the kernel.
+type Import struct {
+	cfg      *Config
+	updater  Updater
+	notifier Notifier
+	// endOfRIB is called once per Run, when the initial dump is sent.
+	endOfRIB Notifier
+	// connected reports whether Run is subscribed to the notifications.
+	connected atomic.Bool
+	log       *zap.Logger
+}
+
+// NewImportReader creates a reader of the kernel FIB.
+//
+// Every Run dumps the routes of the kernel FIB first, calling onEndOfRIB
+// once they are sent, and then follows the route notifications, batching
+// them like the BIRD export reader does.
+func NewImportReader(
+	cfg *Config,
+	onUpdate Updater,
+	onFlush Notifier,
+	onEndOfRIB Notifier,
+	log *zap.Logger,
+) *Import {
+	return &Import{
+		cfg:      cfg,
+		updater:  onUpdate,
+		notifier: onFlush,
+		endOfRIB: onEndOfRIB,
+		log:      log,
+	}
+}
+
+// Connected reports whether Run follows the route notifications.
+func (m *Import) Connected() bool {
+	return m.connected.Load()
+}
+
+// Run imports the kernel FIB until ctx is cancelled or the subscription
+// fails.
+func (m *Import) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before the dump, so that no change is missed in between.
+	// Notifications for the routes already dumped are no-ops.
+	updates := make(chan netlink.RouteUpdate, updateQueueSize)
+	errs := make(chan error, 1)
+	err := netlink.RouteSubscribeWithOptions(updates, ctx.Done(), netlink.RouteSubscribeOptions{
+		ErrorCallback: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to netlink route notifications: %w", err)
+	}
+	// The subscription stops once ctx is cancelled, but may block on a full
+	// queue until then.
+	defer func() {
+		go func() {
+			for range updates {
+			}
+		}()
+	}()
+	m.connected.Store(true)
+	defer m.connected.Store(false)
+
+	// Every Run feeds a new stream, which starts from an empty RIB.
+	table := newTable(m.cfg)
+
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: unix.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to dump the kernel routes: %w", err)
+	}
+
+	batch := make([]rib.Route, 0, m.cfg.DumpThreshold)
+	for idx := range routes {
+		batch = append(batch, table.Apply(&routes[idx], false)...)
+		if len(batch) >= m.cfg.DumpThreshold {
+			if err := m.flush(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := m.flush(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+	m.log.Info("kernel routes initial dump is complete", zap.Int("routes", len(routes)))
+	if err := m.endOfRIB(); err != nil {
+		return fmt.Errorf("failed to call end-of-RIB notifier: %w", err)
+	}
+
+	tick := time.NewTicker(m.cfg.DumpTimeout)
+	defer tick.Stop()
+	for {
+		timeout := false
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return fmt.Errorf("netlink route subscription failed: %w", err)
+		case update, ok := <-updates:
+			if !ok {
+				return errSubscriptionClosed
+			}
+			switch update.Type {
+			case unix.RTM_NEWROUTE:
+				batch = append(batch, table.Apply(&update.Route, false)...)
+			case unix.RTM_DELROUTE:
+				batch = append(batch, table.Apply(&update.Route, true)...)
+			}
+			tick.Reset(m.cfg.DumpTimeout)
+		case <-tick.C:
+			timeout = true
+		}
+
+		if len(batch) > 0 && (timeout || len(batch) >= m.cfg.DumpThreshold) {
+			m.log.Debug("send RIB update", zap.Int("size", len(batch)), zap.Bool("isTimeout", timeout))
+			if err := m.flush(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush sends a batch of updates and commits it.
+func (m *Import) flush(ctx context.Context, batch []rib.Route) error {
+	if err := m.updater(ctx, batch); err != nil {
+		return fmt.Errorf("failed to call updater: %w", err)
+	}
+	if err := m.notifier(); err != nil {
+		return fmt.Errorf("failed to call notifier: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package kernel
+
+import (
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// routeVariant tells apart the kernel routes of the same prefix.
+type routeVariant struct {
+	table    int
+	priority int
+}
+
+// gateways maps the gateways of a kernel route to their weights.
+type gateways map[netip.Addr]uint32
+
+// table tracks the imported kernel routes, turning the route notifications
+// into RIB updates.
+//
+// The kernel forwards a prefix via its routes of the lowest metric, so only
+// their gateways are announced, with the metric as the MED. Every gateway
+// is announced as a route of its own peer, which makes the gateways of a
+// multipath route an ECMP group of the RIB.
+type table struct {
+	cfg    *Config
+	routes map[netip.Prefix]map[routeVariant]gateways
+}
+
+func newTable(cfg *Config) *table {
+	return &table{
+		cfg:    cfg,
+		routes: map[netip.Prefix]map[routeVariant]gateways{},
+	}
+}
+
+// Apply records a route added to or deleted from the kernel FIB, returning
+// the RIB updates it results in.
+//
+// Routes of other tables, protocols or types and routes without a gateway
+// are ignored.
+func (m *table) Apply(route *netlink.Route, deleted bool) []rib.Route {
+	prefix, gws, ok := m.convert(route)
+	if !ok {
+		return nil
+	}
+
+	before, beforeMetric := m.preferred(prefix)
+
+	variant := routeVariant{table: route.Table, priority: route.Priority}
+	variants, ok := m.routes[prefix]
+	switch {
+	case deleted && ok:
+		delete(variants, variant)
+		if len(variants) == 0 {
+			delete(m.routes, prefix)
+		}
+	case !deleted:
+		if !ok {
+			variants = map[routeVariant]gateways{}
+			m.routes[prefix] = variants
+		}
+		variants[variant] = gws
+	}
+
+	after, afterMetric := m.preferred(prefix)
+
+	updates := []rib.Route{}
+	for _, gw := range slices.SortedFunc(maps.Keys(before), netip.Addr.Compare) {
+		if _, ok := after[gw]; !ok {
+			updates = append(updates, newRoute(prefix, gw, before[gw], beforeMetric, true))
+		}
+	}
+	for _, gw := range slices.SortedFunc(maps.Keys(after), netip.Addr.Compare) {
+		weight, ok := before[gw]
+		if ok && weight == after[gw] && beforeMetric == afterMetric {
+			continue
+		}
+		updates = append(updates, newRoute(prefix, gw, after[gw], afterMetric, false))
+	}
+
+	return updates
+}
+
+// preferred returns the gateways of the routes of the lowest metric of a
+// prefix, along with the metric.
+func (m *table) preferred(prefix netip.Prefix) (gateways, int) {
+	variants := m.routes[prefix]
+	if len(variants) == 0 {
+		return gateways{}, 0
+	}
+
+	metric := -1
+	for variant := range variants {
+		if metric < 0 || variant.priority < metric {
+			metric = variant.priority
+		}
+	}
+
+	// Routes of the same metric from different tables are merged.
+	preferred := gateways{}
+	for variant, gws := range variants {
+		if variant.priority != metric {
+			continue
+		}
+		for gw, weight := range gws {
+			preferred[gw] += weight
+		}
+	}
+
+	return preferred, metric
+}
+
+// convert returns the prefix and the gateways of a kernel route, reporting
+// whether the route is imported.
+func (m *table) convert(route *netlink.Route) (netip.Prefix, gateways, bool) {
+	if route.Type != unix.RTN_UNICAST || !m.importsTable(route.Table) || !m.importsProtocol(route.Protocol) {
+		return netip.Prefix{}, nil, false
+	}
+
+	var prefix netip.Prefix
+	switch {
+	case route.Dst != nil:
+		addr, ok := netip.AddrFromSlice(route.Dst.IP)
+		if !ok {
+			return netip.Prefix{}, nil, false
+		}
+		ones, _ := route.Dst.Mask.Size()
+		prefix = netip.PrefixFrom(addr.Unmap(), ones).Masked()
+	case route.Family == netlink.FAMILY_V4:
+		prefix = netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+	case route.Family == netlink.FAMILY_V6:
+		prefix = netip.PrefixFrom(netip.IPv6Unspecified(), 0)
+	default:
+		return netip.Prefix{}, nil, false
+	}
+	if !prefix.IsValid() {
+		return netip.Prefix{}, nil, false
+	}
+
+	gws := gateways{}
+	if len(route.MultiPath) == 0 {
+		addGateway(gws, route.Gw, 1)
+	}
+	for _, nh := range route.MultiPath {
+		// The kernel stores the weight of a nexthop decremented.
+		addGateway(gws, nh.Gw, uint32(nh.Hops)+1)
+	}
+	if len(gws) == 0 {
+		return netip.Prefix{}, nil, false
+	}
+
+	return prefix, gws, true
+}
+
+func (m *table) importsTable(table int) bool {
+	if len(m.cfg.Tables) == 0 {
+		return table == unix.RT_TABLE_MAIN
+	}
+	return slices.Contains(m.cfg.Tables, table)
+}
+
+func (m *table) importsProtocol(protocol netlink.RouteProtocol) bool {
+	if len(m.cfg.Protocols) == 0 {
+		return protocol != unix.RTPROT_KERNEL
+	}
+	return slices.Contains(m.cfg.Protocols, int(protocol))
+}
+
+func addGateway(gws gateways, ip net.IP, weight uint32) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok || addr.IsUnspecified() {
+		return
+	}
+	gws[addr.Unmap()] += weight
+}
+
+// newRoute returns the RIB route of a kernel route gateway.
+//
+// Kernel routes are fed like the BIRD ones, as dynamic routes.
+func newRoute(prefix netip.Prefix, gw netip.Addr, weight uint32, metric int, withdraw bool) rib.Route {
+	nexthop := gw
+	if gw.Is4() {
+		nexthop = netip.AddrFrom16(gw.As16())
+	}
+
+	return rib.Route{
+		Prefix:   prefix,
+		NextHop:  nexthop,
+		Peer:     gw,
+		Med:      uint32(metric),
+		Weight:   weight,
+		SourceID: rib.RouteSourceBird,
+		ToRemove: withdraw,
+	}
+}
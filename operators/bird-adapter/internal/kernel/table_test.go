@@ -0,0 +1,131 @@
+package kernel
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+func kernelRoute(t *testing.T, prefix string, priority int, gws ...string) *netlink.Route {
+	t.Helper()
+
+	_, dst, err := net.ParseCIDR(prefix)
+	require.NoError(t, err)
+
+	route := &netlink.Route{
+		Dst:      dst,
+		Table:    unix.RT_TABLE_MAIN,
+		Type:     unix.RTN_UNICAST,
+		Protocol: unix.RTPROT_BGP,
+		Priority: priority,
+	}
+	if len(gws) == 1 {
+		route.Gw = net.ParseIP(gws[0])
+		return route
+	}
+	for idx, gw := range gws {
+		route.MultiPath = append(route.MultiPath, &netlink.NexthopInfo{Gw: net.ParseIP(gw), Hops: idx})
+	}
+	return route
+}
+
+type announce struct {
+	gw       string
+	med      uint32
+	weight   uint32
+	withdraw bool
+}
+
+func announces(routes []rib.Route) []announce {
+	out := []announce{}
+	for _, route := range routes {
+		out = append(out, announce{
+			gw:       route.Peer.String(),
+			med:      route.Med,
+			weight:   route.Weight,
+			withdraw: route.ToRemove,
+		})
+	}
+	return out
+}
+
+func TestTable(t *testing.T) {
+	table := newTable(&Config{})
+
+	// A multipath route announces every gateway with its weight.
+	updates := table.Apply(kernelRoute(t, "10.0.0.0/24", 20, "192.0.2.1", "192.0.2.2"), false)
+	require.Equal(t, []announce{
+		{gw: "192.0.2.1", med: 20, weight: 1},
+		{gw: "192.0.2.2", med: 20, weight: 2},
+	}, announces(updates))
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/24"), updates[0].Prefix)
+	require.Equal(t, netip.MustParseAddr("::ffff:192.0.2.1"), updates[0].NextHop)
+	require.Equal(t, rib.RouteSourceBird, updates[0].SourceID)
+
+	// Notifying the same route again changes nothing.
+	require.Empty(t, table.Apply(kernelRoute(t, "10.0.0.0/24", 20, "192.0.2.1", "192.0.2.2"), false))
+
+	// A route of a higher metric is not forwarded via.
+	require.Empty(t, table.Apply(kernelRoute(t, "10.0.0.0/24", 30, "192.0.2.3"), false))
+
+	// Replacing the route withdraws the gateways it no longer has.
+	updates = table.Apply(kernelRoute(t, "10.0.0.0/24", 20, "192.0.2.2"), false)
+	require.Equal(t, []announce{
+		{gw: "192.0.2.1", med: 20, weight: 1, withdraw: true},
+		{gw: "192.0.2.2", med: 20, weight: 1},
+	}, announces(updates))
+
+	// Deleting the preferred route falls back to the one of a higher metric.
+	updates = table.Apply(kernelRoute(t, "10.0.0.0/24", 20, "192.0.2.2"), true)
+	require.Equal(t, []announce{
+		{gw: "192.0.2.2", med: 20, weight: 1, withdraw: true},
+		{gw: "192.0.2.3", med: 30, weight: 1},
+	}, announces(updates))
+
+	updates = table.Apply(kernelRoute(t, "10.0.0.0/24", 30, "192.0.2.3"), true)
+	require.Equal(t, []announce{
+		{gw: "192.0.2.3", med: 30, weight: 1, withdraw: true},
+	}, announces(updates))
+	require.Empty(t, table.routes)
+}
+
+func TestTable_Filters(t *testing.T) {
+	table := newTable(&Config{})
+
+	connected := kernelRoute(t, "10.0.0.0/24", 0, "192.0.2.1")
+	connected.Protocol = unix.RTPROT_KERNEL
+	require.Empty(t, table.Apply(connected, false))
+
+	other := kernelRoute(t, "10.0.0.0/24", 0, "192.0.2.1")
+	other.Table = 100
+	require.Empty(t, table.Apply(other, false))
+
+	local := kernelRoute(t, "10.0.0.0/24", 0, "192.0.2.1")
+	local.Type = unix.RTN_LOCAL
+	require.Empty(t, table.Apply(local, false))
+
+	device := kernelRoute(t, "10.0.0.0/24", 0, "192.0.2.1")
+	device.Gw = nil
+	require.Empty(t, table.Apply(device, false))
+
+	// The default route has no destination.
+	def := kernelRoute(t, "2001:db8::/32", 0, "fe80::1")
+	def.Dst = nil
+	def.Family = netlink.FAMILY_V6
+	updates := table.Apply(def, false)
+	require.Len(t, updates, 1)
+	require.Equal(t, netip.MustParsePrefix("::/0"), updates[0].Prefix)
+	require.Equal(t, netip.MustParseAddr("fe80::1"), updates[0].NextHop)
+
+	// Selected tables and protocols replace the defaults.
+	table = newTable(&Config{Tables: []int{100}, Protocols: []int{unix.RTPROT_KERNEL}})
+	require.Empty(t, table.Apply(kernelRoute(t, "10.0.0.0/24", 0, "192.0.2.1"), false))
+	other.Protocol = unix.RTPROT_KERNEL
+	require.Len(t, table.Apply(other, false), 1)
+}
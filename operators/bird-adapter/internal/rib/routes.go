@@ -3,6 +3,7 @@ package rib
 import (
 	"fmt"
 	"net/netip"
+	"slices"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	filterpb "github.com/yanet-platform/yanet2/common/filterpb/v1"
@@ -23,6 +24,10 @@ type Community struct {
 	Value uint16
 }
 
+// BlackholeCommunity is the well-known BLACKHOLE community defined in
+// RFC 7999.
+var BlackholeCommunity = Community{ASN: 65535, Value: 666}
+
 type ExtCommunity struct {
 	Type    uint8
 	SubType uint8
@@ -46,6 +51,9 @@ type Route struct {
 	// an IPv6 network prefix. IPv4 addresses are stored as IPv6-mapped addresses.
 	Prefix netip.Prefix
 	// NextHop is the IP address where traffic should be forwarded next.
+	//
+	// IPv4 next hops are IPv4-mapped. An IPv4 prefix may have an IPv6 next
+	// hop, see HasExtendedNextHop.
 	NextHop netip.Addr
 	// Peer is the IP address of the BGP peer that advertised this route.
 	//
@@ -84,10 +92,19 @@ type Route struct {
 	// SourceID identifies the origin of this route's information,
 	// such as static or Bird.
 	SourceID RouteSourceID
+	// Weight is the share of the prefix traffic the route takes in its ECMP
+	// group. Zero counts as one.
+	Weight uint32
 	// ToRemove signals whether the route has been withdrawn from the routing table.
 	ToRemove bool
 }
 
+// HasExtendedNextHop reports whether an IPv4 route is forwarded via an IPv6
+// next hop, announced with the extended next hop encoding of RFC 8950.
+func (m *Route) HasExtendedNextHop() bool {
+	return m.Prefix.Addr().Is4() && m.NextHop.Is6() && !m.NextHop.Is4In6()
+}
+
 func convertLargeCommunity(community LargeCommunity) *routepb.LargeCommunity {
 	return &routepb.LargeCommunity{
 		GlobalAdministrator: community.ASN,
@@ -102,6 +119,15 @@ func ToPBRoute(route *Route) *routepb.Route {
 		communities = append(communities, convertLargeCommunity(c))
 	}
 
+	standard := make([]*routepb.Community, 0, len(route.Communities))
+	for _, c := range route.Communities {
+		standard = append(standard, &routepb.Community{Asn: uint32(c.ASN), Value: uint32(c.Value)})
+	}
+	extended := make([]*routepb.ExtCommunity, 0, len(route.ExtCommunities))
+	for _, c := range route.ExtCommunities {
+		extended = append(extended, &routepb.ExtCommunity{Type: uint32(c.Type), SubType: uint32(c.SubType), Value: c.Value})
+	}
+
 	var peer *commonpb.IPAddress
 	if route.Peer.IsValid() {
 		peer = commonpb.NewIPAddressFromAddr(route.Peer)
@@ -124,7 +150,11 @@ func ToPBRoute(route *Route) *routepb.Route {
 		Pref:             route.Pref,
 		AsPathLen:        route.ASPathLen,
 		Source:           routepb.RouteSourceID(route.SourceID),
+		Communities:      standard,
+		ExtCommunities:   extended,
 		LargeCommunities: communities,
+		Blackhole:        slices.Contains(route.Communities, BlackholeCommunity),
+		Weight:           route.Weight,
 	}
 }
 
@@ -138,7 +168,7 @@ func ToPBMPLSRoute(route *Route, source netip.Addr) *routemplspb.Rule {
 
 	destination := route.NextHop
 	if route.Prefix.Addr().Is4() {
-		// Unmap NextHop in case of V4 nexthop
+		// Unmap NextHop in case of V4 nexthop; an extended one stays IPv6.
 		destination = destination.Unmap()
 	}
 
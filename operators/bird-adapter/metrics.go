@@ -0,0 +1,119 @@
+package bird_adapter
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+)
+
+// queuedReader is a route reader queueing the routes read for the stream,
+// the BIRD one.
+type queuedReader interface {
+	QueueStats() bird.QueueStats
+}
+
+// MetricsService exposes the BIRD adapter metrics over gRPC.
+type MetricsService struct {
+	adapterpb.UnimplementedMetricsServiceServer
+
+	adapter *AdapterService
+}
+
+// NewMetricsService constructs a MetricsService reporting the imports of
+// the given adapter.
+func NewMetricsService(adapter *AdapterService) *MetricsService {
+	return &MetricsService{
+		adapter: adapter,
+	}
+}
+
+// GetMetrics returns the current snapshot of the adapter metrics.
+func (m *MetricsService) GetMetrics(
+	ctx context.Context,
+	req *adapterpb.GetMetricsRequest,
+) (*adapterpb.GetMetricsResponse, error) {
+	return &adapterpb.GetMetricsResponse{
+		Metrics: m.adapter.Collect(),
+	}, nil
+}
+
+// Collect renders the freshness, the route counters and the stream state
+// of every BIRD import as metrics labelled by the configuration name.
+//
+// The SLO metrics are only reported when the freshness objective is set.
+func (m *AdapterService) Collect() []*commonpb.Metric {
+	m.importsMu.Lock()
+	defer m.importsMu.Unlock()
+
+	now := time.Now()
+	out := make([]*commonpb.Metric, 0)
+	for _, name := range slices.Sorted(maps.Keys(m.imports)) {
+		holder := m.imports[name]
+		status := holder.freshness.Status(now)
+		health := feedHealth(status, holder.export.Connected())
+		label := makeLabel("config", name)
+
+		out = append(out,
+			makeGauge("bird_adapter_feed_seconds_since_update", status.SinceUpdate.Seconds(), label),
+			makeGauge("bird_adapter_feed_connected", boolGauge(health != adapterpb.FeedHealth_FEED_HEALTH_DISCONNECTED), label),
+		)
+		out = append(out, holder.metrics.Collect(label)...)
+		if reader, ok := holder.export.(queuedReader); ok {
+			out = append(out, collectQueue(reader.QueueStats(), label)...)
+		}
+		if !m.freshness.enabled() {
+			continue
+		}
+		out = append(out,
+			makeGauge("bird_adapter_feed_degraded", boolGauge(health == adapterpb.FeedHealth_FEED_HEALTH_DEGRADED), label),
+			makeCounter("bird_adapter_feed_stale_seconds_total", uint64(status.StaleTotal.Seconds()), label),
+			makeGauge("bird_adapter_feed_slo_burn_rate", status.BurnRate, label),
+		)
+	}
+
+	return out
+}
+
+// collectQueue renders the queue between the BIRD sockets and the stream.
+//
+// A depth stuck near the high watermark along with a growing pause counter
+// points at a route operator not keeping up with BIRD.
+func collectQueue(stats bird.QueueStats, labels ...*commonpb.Label) []*commonpb.Metric {
+	return []*commonpb.Metric{
+		makeGauge("bird_adapter_import_queue_depth", float64(stats.Depth), labels...),
+		makeCounter("bird_adapter_import_queue_coalesced_total", stats.Coalesced, labels...),
+		makeCounter("bird_adapter_import_queue_paused_total", stats.Paused, labels...),
+	}
+}
+
+func boolGauge(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func makeLabel(name, value string) *commonpb.Label {
+	return &commonpb.Label{Name: name, Value: value}
+}
+
+func makeCounter(name string, value uint64, labels ...*commonpb.Label) *commonpb.Metric {
+	return &commonpb.Metric{
+		Name:   name,
+		Labels: labels,
+		Value:  &commonpb.Metric_Counter{Counter: value},
+	}
+}
+
+func makeGauge(name string, value float64, labels ...*commonpb.Label) *commonpb.Metric {
+	return &commonpb.Metric{
+		Name:   name,
+		Labels: labels,
+		Value:  &commonpb.Metric_Gauge{Gauge: value},
+	}
+}
@@ -0,0 +1,534 @@
+package bird_adapter
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// errPolicyRejected is the error of routes rejected by the import policy.
+var errPolicyRejected = errors.New("rejected by import policy")
+
+// PolicyAction is what a policy term does with the routes it matches.
+type PolicyAction string
+
+const (
+	// PolicyAccept applies the set of the term and sends the route.
+	PolicyAccept PolicyAction = "accept"
+	// PolicyReject drops the route.
+	PolicyReject PolicyAction = "reject"
+	// PolicySet applies the set of the term, then goes on with the next
+	// term.
+	PolicySet PolicyAction = "set"
+)
+
+// ImportPolicyConfig configures the policies the routes of the imports
+// pass before they are sent to the route operator.
+//
+// It keeps a full-table feed from flooding the route operator with routes
+// nobody asked for.
+type ImportPolicyConfig struct {
+	// PrefixLists are the named prefix lists the policy terms match on.
+	PrefixLists map[string][]string `yaml:"prefix_lists"`
+	// Policies are the policies by name.
+	Policies map[string]PolicyConfig `yaml:"policies"`
+	// Configs names the policy of a configuration, by configuration name.
+	//
+	// The AnyConfig entry applies to the configurations not listed.
+	// Configurations matching no entry import every route.
+	Configs map[string]string `yaml:"configs"`
+}
+
+// PolicyConfig is an ordered list of terms, the first accepting or
+// rejecting term deciding the fate of a route.
+type PolicyConfig struct {
+	Terms []PolicyTermConfig `yaml:"terms"`
+	// Default is the action on the routes no term accepts or rejects,
+	// accept if empty.
+	Default PolicyAction `yaml:"default"`
+}
+
+// PolicyTermConfig is a term of a policy.
+type PolicyTermConfig struct {
+	// Name identifies the term in the rejected route samples.
+	Name   string            `yaml:"name"`
+	Match  PolicyMatchConfig `yaml:"match"`
+	Action PolicyAction      `yaml:"action"`
+	Set    PolicySetConfig   `yaml:"set"`
+}
+
+// PolicyMatchConfig selects the routes of a term.
+//
+// A route matches if it meets every criterion set, and a list criterion
+// is met by any of its entries. An empty match matches every route.
+type PolicyMatchConfig struct {
+	// PrefixList names the prefix list the prefix must be covered by,
+	// itself or as a more-specific of an entry.
+	PrefixList string `yaml:"prefix_list"`
+	// Prefixes are the prefixes the prefix must be covered by, like
+	// PrefixList.
+	Prefixes []string `yaml:"prefixes"`
+	// PrefixLength is the range the prefix length must fall into.
+	PrefixLength *PrefixLengthRange `yaml:"prefix_length"`
+	// Communities are standard communities as "ASN:value", one of which
+	// the route must carry.
+	Communities []string `yaml:"communities"`
+	// LargeCommunities are large communities as "GA:LD1:LD2", one of
+	// which the route must carry.
+	LargeCommunities []string `yaml:"large_communities"`
+	// NextHops are the next hops the route must be via one of.
+	NextHops []string `yaml:"next_hops"`
+}
+
+// PrefixLengthRange is an inclusive range of prefix lengths.
+type PrefixLengthRange struct {
+	Min int `yaml:"min"`
+	// Max is the longest length, unbounded if zero.
+	Max int `yaml:"max"`
+}
+
+// PolicySetConfig are the attributes a term sets on the routes it matches.
+type PolicySetConfig struct {
+	// Pref replaces the local preference.
+	Pref *uint32 `yaml:"pref"`
+	// Med replaces the MED.
+	Med *uint32 `yaml:"med"`
+	// AddCommunities are standard communities added to the route.
+	AddCommunities []string `yaml:"add_communities"`
+	// AddLargeCommunities are large communities added to the route.
+	AddLargeCommunities []string `yaml:"add_large_communities"`
+}
+
+// Validate validates the import policy config.
+func (m *ImportPolicyConfig) Validate() error {
+	_, err := NewImportPolicies(*m)
+	return err
+}
+
+// ImportPolicies are the compiled import policies.
+//
+// A nil ImportPolicies imports every route.
+type ImportPolicies struct {
+	policies map[string]*importPolicy
+	configs  map[string]string
+}
+
+// NewImportPolicies compiles the policies of the config, returning nil if
+// no configuration has a policy.
+func NewImportPolicies(cfg ImportPolicyConfig) (*ImportPolicies, error) {
+	prefixLists := map[string][]netip.Prefix{}
+	for name, entries := range cfg.PrefixLists {
+		prefixes, err := parsePolicyPrefixes(entries)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix list %q: %w", name, err)
+		}
+		prefixLists[name] = prefixes
+	}
+
+	policies := map[string]*importPolicy{}
+	for name, policyCfg := range cfg.Policies {
+		policy, err := newImportPolicy(name, policyCfg, prefixLists)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %q: %w", name, err)
+		}
+		policies[name] = policy
+	}
+
+	for config, name := range cfg.Configs {
+		if _, ok := policies[name]; !ok {
+			return nil, fmt.Errorf("configuration %q uses unknown policy %q", config, name)
+		}
+	}
+
+	if len(cfg.Configs) == 0 {
+		return nil, nil
+	}
+
+	return &ImportPolicies{
+		policies: policies,
+		configs:  cfg.Configs,
+	}, nil
+}
+
+// forConfig returns the policy of a configuration, nil if it imports every
+// route.
+func (m *ImportPolicies) forConfig(config string) *importPolicy {
+	if m == nil {
+		return nil
+	}
+
+	name, ok := m.configs[config]
+	if !ok {
+		name, ok = m.configs[AnyConfig]
+	}
+	if !ok {
+		return nil
+	}
+	return m.policies[name]
+}
+
+// importPolicy is a compiled policy.
+type importPolicy struct {
+	name          string
+	terms         []policyTerm
+	defaultAction PolicyAction
+}
+
+func newImportPolicy(name string, cfg PolicyConfig, prefixLists map[string][]netip.Prefix) (*importPolicy, error) {
+	defaultAction := cfg.Default
+	switch defaultAction {
+	case "":
+		defaultAction = PolicyAccept
+	case PolicyAccept, PolicyReject:
+	default:
+		return nil, fmt.Errorf("default action must be accept or reject, got %q", defaultAction)
+	}
+
+	terms := make([]policyTerm, 0, len(cfg.Terms))
+	for idx, termCfg := range cfg.Terms {
+		term, err := newPolicyTerm(termCfg, prefixLists)
+		if err != nil {
+			return nil, fmt.Errorf("term #%d: %w", idx, err)
+		}
+		if term.name == "" {
+			term.name = fmt.Sprintf("#%d", idx)
+		}
+		terms = append(terms, term)
+	}
+
+	return &importPolicy{
+		name:          name,
+		terms:         terms,
+		defaultAction: defaultAction,
+	}, nil
+}
+
+// Evaluate runs a route through the policy, setting the attributes of the
+// matching terms on it. It returns nil for an accepted route, the
+// rejection error otherwise.
+func (m *importPolicy) Evaluate(route *rib.Route) error {
+	if m == nil {
+		return nil
+	}
+
+	for idx := range m.terms {
+		term := &m.terms[idx]
+		if !term.matches(route) {
+			continue
+		}
+		switch term.action {
+		case PolicyReject:
+			return fmt.Errorf("%w %q, term %q", errPolicyRejected, m.name, term.name)
+		case PolicyAccept:
+			term.set.apply(route)
+			return nil
+		default:
+			term.set.apply(route)
+		}
+	}
+
+	if m.defaultAction == PolicyReject {
+		return fmt.Errorf("%w %q by default", errPolicyRejected, m.name)
+	}
+	return nil
+}
+
+// policyTerm is a compiled policy term. Empty criteria match every route.
+type policyTerm struct {
+	name string
+	// prefixes is nil without a prefix criterion.
+	prefixes         []netip.Prefix
+	minLength        int
+	maxLength        int
+	communities      []rib.Community
+	largeCommunities []rib.LargeCommunity
+	nextHops         []netip.Addr
+	action           PolicyAction
+	set              policySet
+}
+
+func newPolicyTerm(cfg PolicyTermConfig, prefixLists map[string][]netip.Prefix) (policyTerm, error) {
+	term := policyTerm{
+		name:      cfg.Name,
+		maxLength: 128,
+		action:    cfg.Action,
+	}
+
+	switch cfg.Action {
+	case PolicyAccept, PolicyReject, PolicySet:
+	default:
+		return policyTerm{}, fmt.Errorf("action must be accept, reject or set, got %q", cfg.Action)
+	}
+
+	match := cfg.Match
+	if match.PrefixList != "" {
+		prefixes, ok := prefixLists[match.PrefixList]
+		if !ok {
+			return policyTerm{}, fmt.Errorf("unknown prefix list %q", match.PrefixList)
+		}
+		term.prefixes = append(term.prefixes, prefixes...)
+	}
+	if len(match.Prefixes) != 0 {
+		prefixes, err := parsePolicyPrefixes(match.Prefixes)
+		if err != nil {
+			return policyTerm{}, err
+		}
+		term.prefixes = append(term.prefixes, prefixes...)
+	}
+	if term.prefixes == nil && match.PrefixList != "" {
+		// An empty prefix list covers no prefix rather than any.
+		term.prefixes = []netip.Prefix{}
+	}
+
+	if length := match.PrefixLength; length != nil {
+		if length.Max == 0 {
+			length = &PrefixLengthRange{Min: length.Min, Max: 128}
+		}
+		if length.Min < 0 || length.Min > length.Max || length.Max > 128 {
+			return policyTerm{}, fmt.Errorf("invalid prefix length range %d-%d", length.Min, length.Max)
+		}
+		term.minLength = length.Min
+		term.maxLength = length.Max
+	}
+
+	for _, value := range match.Communities {
+		community, err := parseCommunity(value)
+		if err != nil {
+			return policyTerm{}, err
+		}
+		term.communities = append(term.communities, community)
+	}
+	for _, value := range match.LargeCommunities {
+		community, err := parseLargeCommunity(value)
+		if err != nil {
+			return policyTerm{}, err
+		}
+		term.largeCommunities = append(term.largeCommunities, community)
+	}
+	for _, value := range match.NextHops {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return policyTerm{}, fmt.Errorf("invalid next hop %q: %w", value, err)
+		}
+		term.nextHops = append(term.nextHops, addr.Unmap())
+	}
+
+	set, err := newPolicySet(cfg.Set)
+	if err != nil {
+		return policyTerm{}, err
+	}
+	if cfg.Action == PolicyReject && !set.isEmpty() {
+		return policyTerm{}, fmt.Errorf("a rejecting term sets no attributes")
+	}
+	term.set = set
+
+	return term, nil
+}
+
+func (m *policyTerm) matches(route *rib.Route) bool {
+	prefix := route.Prefix
+	if m.prefixes != nil && !slices.ContainsFunc(m.prefixes, func(entry netip.Prefix) bool {
+		return entry.Bits() <= prefix.Bits() && entry.Contains(prefix.Addr())
+	}) {
+		return false
+	}
+	if prefix.Bits() < m.minLength || prefix.Bits() > m.maxLength {
+		return false
+	}
+	if len(m.communities) != 0 && !slices.ContainsFunc(m.communities, func(c rib.Community) bool {
+		return slices.Contains(route.Communities, c)
+	}) {
+		return false
+	}
+	if len(m.largeCommunities) != 0 && !slices.ContainsFunc(m.largeCommunities, func(c rib.LargeCommunity) bool {
+		return slices.Contains(route.LargeCommunities, c)
+	}) {
+		return false
+	}
+	if len(m.nextHops) != 0 && !slices.Contains(m.nextHops, route.NextHop.Unmap()) {
+		return false
+	}
+	return true
+}
+
+// policySet is a compiled PolicySetConfig.
+type policySet struct {
+	pref             *uint32
+	med              *uint32
+	communities      []rib.Community
+	largeCommunities []rib.LargeCommunity
+}
+
+func newPolicySet(cfg PolicySetConfig) (policySet, error) {
+	set := policySet{
+		pref: cfg.Pref,
+		med:  cfg.Med,
+	}
+	for _, value := range cfg.AddCommunities {
+		community, err := parseCommunity(value)
+		if err != nil {
+			return policySet{}, err
+		}
+		set.communities = append(set.communities, community)
+	}
+	for _, value := range cfg.AddLargeCommunities {
+		community, err := parseLargeCommunity(value)
+		if err != nil {
+			return policySet{}, err
+		}
+		set.largeCommunities = append(set.largeCommunities, community)
+	}
+	return set, nil
+}
+
+func (m *policySet) isEmpty() bool {
+	return m.pref == nil && m.med == nil && len(m.communities) == 0 && len(m.largeCommunities) == 0
+}
+
+func (m *policySet) apply(route *rib.Route) {
+	if m.pref != nil {
+		route.Pref = *m.pref
+	}
+	if m.med != nil {
+		route.Med = *m.med
+	}
+	// The community slices may be shared with the decoder, so they are
+	// copied before growing.
+	for _, community := range m.communities {
+		if !slices.Contains(route.Communities, community) {
+			route.Communities = append(slices.Clip(route.Communities), community)
+		}
+	}
+	for _, community := range m.largeCommunities {
+		if !slices.Contains(route.LargeCommunities, community) {
+			route.LargeCommunities = append(slices.Clip(route.LargeCommunities), community)
+		}
+	}
+}
+
+// policyVerdict is what becomes of a route read from an import.
+type policyVerdict int
+
+const (
+	// policySend sends the route as it is.
+	policySend policyVerdict = iota
+	// policyWithdraw sends the withdrawal of the route.
+	policyWithdraw
+	// policyDrop sends nothing.
+	policyDrop
+)
+
+// policyRouteKey identifies the route of a prefix from a peer, the way the
+// route operator does for dynamic routes.
+type policyRouteKey struct {
+	prefix netip.Prefix
+	peer   netip.Addr
+}
+
+// policyFilter applies the policy of an import to its routes.
+//
+// It remembers the routes it let through, so that a route rejected after
+// its attributes changed is withdrawn from the route operator, while the
+// rejected routes never announced cost the operator nothing. It is used
+// by the update callback alone, which is never run concurrently.
+type policyFilter struct {
+	policy   *importPolicy
+	accepted map[policyRouteKey]struct{}
+}
+
+func newPolicyFilter(policy *importPolicy) *policyFilter {
+	return &policyFilter{
+		policy:   policy,
+		accepted: map[policyRouteKey]struct{}{},
+	}
+}
+
+// Name returns the name of the policy, empty without one.
+func (m *policyFilter) Name() string {
+	if m.policy == nil {
+		return ""
+	}
+	return m.policy.name
+}
+
+// Filter runs a route through the policy, setting the attributes of the
+// matching terms on it. Along with the verdict, it returns the rejection
+// error of an announcement the policy rejected.
+func (m *policyFilter) Filter(route *rib.Route) (policyVerdict, error) {
+	if m.policy == nil {
+		return policySend, nil
+	}
+
+	key := policyRouteKey{prefix: route.Prefix, peer: route.Peer}
+	_, known := m.accepted[key]
+
+	if route.ToRemove {
+		if !known {
+			return policyDrop, nil
+		}
+		delete(m.accepted, key)
+		return policySend, nil
+	}
+
+	if err := m.policy.Evaluate(route); err != nil {
+		if !known {
+			return policyDrop, err
+		}
+		delete(m.accepted, key)
+		return policyWithdraw, err
+	}
+
+	m.accepted[key] = struct{}{}
+	return policySend, nil
+}
+
+func parsePolicyPrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func parseCommunity(value string) (rib.Community, error) {
+	parts, err := parseCommunityParts(value, 2, 16)
+	if err != nil {
+		return rib.Community{}, err
+	}
+	return rib.Community{ASN: uint16(parts[0]), Value: uint16(parts[1])}, nil
+}
+
+func parseLargeCommunity(value string) (rib.LargeCommunity, error) {
+	parts, err := parseCommunityParts(value, 3, 32)
+	if err != nil {
+		return rib.LargeCommunity{}, err
+	}
+	return rib.LargeCommunity{ASN: uint32(parts[0]), Function: uint32(parts[1]), Value: uint32(parts[2])}, nil
+}
+
+// parseCommunityParts parses the colon-separated parts of a community.
+func parseCommunityParts(value string, count int, bitSize int) ([]uint64, error) {
+	fields := strings.Split(value, ":")
+	if len(fields) != count {
+		return nil, fmt.Errorf("invalid community %q: expected %d colon-separated parts", value, count)
+	}
+
+	parts := make([]uint64, 0, count)
+	for _, field := range fields {
+		part, err := strconv.ParseUint(field, 10, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid community %q: %w", value, err)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
@@ -0,0 +1,210 @@
+package bird_adapter
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+const testPolicyConfig = `
+prefix_lists:
+  customers: [10.0.0.0/8, 2001:db8::/32]
+policies:
+  full-table:
+    default: reject
+    terms:
+      - name: blackholes
+        match:
+          communities: ["65535:666"]
+        action: reject
+      - name: backup
+        match:
+          next_hops: [192.0.2.2]
+        action: set
+        set:
+          pref: 50
+      - name: customers
+        match:
+          prefix_list: customers
+          prefix_length: {min: 8, max: 24}
+        action: accept
+        set:
+          add_large_communities: ["13238:1:2"]
+configs:
+  "*": full-table
+`
+
+func newTestImportPolicies(t *testing.T) *ImportPolicies {
+	t.Helper()
+
+	cfg := ImportPolicyConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(testPolicyConfig), &cfg))
+	policies, err := NewImportPolicies(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, policies)
+	return policies
+}
+
+func TestImportPolicy_Evaluate(t *testing.T) {
+	policy := newTestImportPolicies(t).forConfig("route0")
+	require.NotNil(t, policy)
+
+	route := func(prefix string, nexthop string) *rib.Route {
+		return &rib.Route{
+			Prefix:  netip.MustParsePrefix(prefix),
+			NextHop: netip.MustParseAddr(nexthop),
+			Pref:    100,
+		}
+	}
+
+	accepted := route("10.1.0.0/16", "::ffff:192.0.2.1")
+	require.NoError(t, policy.Evaluate(accepted))
+	require.Equal(t, uint32(100), accepted.Pref)
+	require.Equal(t, []rib.LargeCommunity{{ASN: 13238, Function: 1, Value: 2}}, accepted.LargeCommunities)
+
+	// The set term goes on with the next terms.
+	backup := route("10.2.0.0/16", "::ffff:192.0.2.2")
+	require.NoError(t, policy.Evaluate(backup))
+	require.Equal(t, uint32(50), backup.Pref)
+
+	ipv6 := route("2001:db8:1::/48", "2001:db8::1")
+	require.ErrorIs(t, policy.Evaluate(ipv6), errPolicyRejected, "longer than the range")
+
+	blackhole := route("10.3.0.0/16", "::ffff:192.0.2.1")
+	blackhole.Communities = []rib.Community{rib.BlackholeCommunity}
+	err := policy.Evaluate(blackhole)
+	require.ErrorIs(t, err, errPolicyRejected)
+	require.Contains(t, err.Error(), `"blackholes"`)
+
+	err = policy.Evaluate(route("192.168.0.0/16", "::ffff:192.0.2.1"))
+	require.ErrorIs(t, err, errPolicyRejected)
+	require.Equal(t, adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_POLICY, rejectReason(err))
+}
+
+func TestImportPolicies_ForConfig(t *testing.T) {
+	policies, err := NewImportPolicies(ImportPolicyConfig{
+		Policies: map[string]PolicyConfig{"strict": {Default: PolicyReject}},
+		Configs:  map[string]string{"route0": "strict"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, policies.forConfig("route0"))
+	require.Nil(t, policies.forConfig("route1"))
+
+	policies, err = NewImportPolicies(ImportPolicyConfig{
+		Policies: map[string]PolicyConfig{"strict": {Default: PolicyReject}},
+	})
+	require.NoError(t, err)
+	require.Nil(t, policies)
+	require.Nil(t, policies.forConfig("route0"))
+}
+
+func TestNewImportPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ImportPolicyConfig
+	}{
+		{
+			"unknown policy",
+			ImportPolicyConfig{Configs: map[string]string{"*": "missing"}},
+		},
+		{
+			"unknown prefix list",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Terms: []PolicyTermConfig{
+				{Match: PolicyMatchConfig{PrefixList: "missing"}, Action: PolicyAccept},
+			}}}},
+		},
+		{
+			"bad prefix",
+			ImportPolicyConfig{PrefixLists: map[string][]string{"l": {"10.0.0.0"}}},
+		},
+		{
+			"bad action",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Terms: []PolicyTermConfig{{Action: "drop"}}}}},
+		},
+		{
+			"bad default",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Default: PolicySet}}},
+		},
+		{
+			"bad community",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Terms: []PolicyTermConfig{
+				{Match: PolicyMatchConfig{Communities: []string{"65536:1"}}, Action: PolicyAccept},
+			}}}},
+		},
+		{
+			"bad length range",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Terms: []PolicyTermConfig{
+				{Match: PolicyMatchConfig{PrefixLength: &PrefixLengthRange{Min: 24, Max: 8}}, Action: PolicyAccept},
+			}}}},
+		},
+		{
+			"rejecting term sets",
+			ImportPolicyConfig{Policies: map[string]PolicyConfig{"p": {Terms: []PolicyTermConfig{
+				{Action: PolicyReject, Set: PolicySetConfig{AddCommunities: []string{"65000:1"}}},
+			}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewImportPolicies(tt.cfg)
+			require.Error(t, err)
+		})
+	}
+}
+
+// TestPolicyFilter verifies that the routes never let through cost the
+// route operator nothing, while a route rejected after being let through
+// is withdrawn.
+func TestPolicyFilter(t *testing.T) {
+	filter := newPolicyFilter(newTestImportPolicies(t).forConfig("route0"))
+	require.Equal(t, "full-table", filter.Name())
+
+	peer := netip.MustParseAddr("192.0.2.10")
+	route := func(prefix string, communities ...rib.Community) *rib.Route {
+		return &rib.Route{
+			Prefix:      netip.MustParsePrefix(prefix),
+			NextHop:     netip.MustParseAddr("::ffff:192.0.2.1"),
+			Peer:        peer,
+			Communities: communities,
+		}
+	}
+	withdrawal := func(prefix string) *rib.Route {
+		r := route(prefix)
+		r.ToRemove = true
+		return r
+	}
+
+	verdict, err := filter.Filter(route("192.168.0.0/16"))
+	require.Error(t, err)
+	require.Equal(t, policyDrop, verdict)
+	verdict, err = filter.Filter(withdrawal("192.168.0.0/16"))
+	require.NoError(t, err)
+	require.Equal(t, policyDrop, verdict)
+
+	verdict, err = filter.Filter(route("10.0.0.0/16"))
+	require.NoError(t, err)
+	require.Equal(t, policySend, verdict)
+
+	verdict, err = filter.Filter(route("10.0.0.0/16", rib.BlackholeCommunity))
+	require.Error(t, err)
+	require.Equal(t, policyWithdraw, verdict)
+	verdict, _ = filter.Filter(route("10.0.0.0/16", rib.BlackholeCommunity))
+	require.Equal(t, policyDrop, verdict, "withdrawn once")
+
+	verdict, _ = filter.Filter(route("10.1.0.0/16"))
+	require.Equal(t, policySend, verdict)
+	verdict, err = filter.Filter(withdrawal("10.1.0.0/16"))
+	require.NoError(t, err)
+	require.Equal(t, policySend, verdict)
+
+	// Without a policy every route is sent.
+	verdict, err = newPolicyFilter(nil).Filter(withdrawal("192.168.0.0/16"))
+	require.NoError(t, err)
+	require.Equal(t, policySend, verdict)
+}
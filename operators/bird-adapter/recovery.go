@@ -0,0 +1,152 @@
+package bird_adapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/common/go/coordinator"
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+)
+
+// recoveryModule is the module the configurations of the adapter are
+// recorded under by the recovery.
+const recoveryModule = "route"
+
+// RecoveryConfig controls the recovery of the configurations lost by a
+// restart of the dataplane instance they are fed to.
+//
+// The MPLS routes of an import are sent as deltas of the routes it already
+// sent, so a dataplane instance starting from empty shared memory would
+// miss them until the import is set up again. The recovery probes the
+// memory generation of the instance through the InspectService of its
+// gateway, and sets the imports up again once it goes backwards.
+type RecoveryConfig struct {
+	// Endpoint is the gateway of the dataplane instance serving the
+	// InspectService. Empty probes the route operator endpoint, which is
+	// expected to be that gateway.
+	Endpoint string `yaml:"endpoint"`
+	// Instance is the index of the dataplane instance behind the gateway,
+	// which the configurations are recorded for.
+	Instance uint32 `yaml:"instance"`
+	// ProbeInterval is how often the instance is probed for restarts.
+	//
+	// Zero disables the probes, the configurations are still recorded to
+	// be pushed again on request.
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+}
+
+// DefaultRecovery returns the recovery used unless configured.
+func DefaultRecovery() RecoveryConfig {
+	return RecoveryConfig{
+		ProbeInterval: coordinator.DefaultProbeInterval,
+	}
+}
+
+// Validate validates the recovery.
+func (m *RecoveryConfig) Validate() error {
+	if m.ProbeInterval < 0 {
+		return fmt.Errorf("probe_interval must not be negative")
+	}
+	return nil
+}
+
+// Recovery applies the configurations of the adapter again to the
+// dataplane instance that restarted, see coordinator.Recovery.
+//
+// A nil Recovery records nothing.
+type Recovery struct {
+	*coordinator.Recovery
+
+	cfg RecoveryConfig
+}
+
+// NewRecovery creates the recovery of the instance behind the gateway
+// inspected by the client.
+func NewRecovery(cfg RecoveryConfig, client ynpb.InspectServiceClient, log *zap.Logger) *Recovery {
+	probe := newGenerationProbe(client)
+	options := []coordinator.RecoveryOption{coordinator.WithRecoveryLog(log)}
+	if cfg.ProbeInterval > 0 {
+		options = append(options, coordinator.WithProbeInterval(cfg.ProbeInterval))
+	}
+
+	return &Recovery{
+		Recovery: coordinator.NewRecovery(probe, options...),
+		cfg:      cfg,
+	}
+}
+
+// Run probes the instance until ctx is cancelled, or just waits for it if
+// the probes are disabled.
+func (m *Recovery) Run(ctx context.Context) error {
+	if m.cfg.ProbeInterval == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return m.Recovery.Run(ctx)
+}
+
+// apply sets the named configuration up with push, recording the push to
+// the apply history and to be done again once the instance restarts. It
+// returns the version of the push, zero if nothing is recorded.
+func (m *Recovery) apply(
+	ctx context.Context,
+	name string,
+	digest string,
+	push func(ctx context.Context) (*importHolder, error),
+) (*importHolder, uint64, error) {
+	if m == nil {
+		holder, err := push(ctx)
+		return holder, 0, err
+	}
+	return coordinator.ApplyResult(ctx, m.Recovery, m.cfg.Instance, recoveryModule, name, digest, push)
+}
+
+// version returns the version of the push that set the named
+// configuration up, zero if nothing is recorded.
+func (m *Recovery) version(name string) uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.Version(m.cfg.Instance, recoveryModule, name)
+}
+
+// record remembers how to set the named configuration up again once the
+// instance restarts, without adding a push to the apply history.
+func (m *Recovery) record(name string, apply coordinator.ApplyFunc) {
+	if m == nil {
+		return
+	}
+	m.Record(m.cfg.Instance, recoveryModule, name, apply)
+}
+
+// forget stops setting the named configuration up again.
+func (m *Recovery) forget(name string) {
+	if m == nil {
+		return
+	}
+	m.Forget(m.cfg.Instance, recoveryModule, name)
+}
+
+// newGenerationProbe returns the probe of the memory generation of the
+// instance served by the inspected gateway.
+//
+// The generation of a module config grows with every update for as long as
+// the shared memory lives, so the highest one goes backwards once the
+// instance restarts.
+func newGenerationProbe(client ynpb.InspectServiceClient) coordinator.GenerationProbe {
+	return func(ctx context.Context, instance uint32) (uint64, error) {
+		resp, err := client.Inspect(ctx, &ynpb.InspectRequest{})
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect the dataplane instance: %w", err)
+		}
+
+		generation := uint64(0)
+		for _, config := range resp.GetInstanceInfo().GetCpConfigs() {
+			generation = max(generation, config.GetGeneration())
+		}
+		return generation, nil
+	}
+}
@@ -0,0 +1,249 @@
+package bird_adapter
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/common/coordinatorpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/coordinator"
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// fakeInspect reports the module configs of a dataplane instance of the
+// given generations.
+type fakeInspect struct {
+	generations []uint64
+}
+
+func (m *fakeInspect) Inspect(
+	ctx context.Context,
+	req *ynpb.InspectRequest,
+	opts ...grpc.CallOption,
+) (*ynpb.InspectResponse, error) {
+	configs := []*ynpb.CPConfigInfo{}
+	for _, generation := range m.generations {
+		configs = append(configs, &ynpb.CPConfigInfo{Type: "route-mpls", Generation: generation})
+	}
+	return &ynpb.InspectResponse{
+		InstanceInfo: &ynpb.InstanceInfo{CpConfigs: configs},
+	}, nil
+}
+
+func TestRecoveryConfig_Validate(t *testing.T) {
+	cfg := DefaultRecovery()
+	require.NoError(t, cfg.Validate())
+
+	cfg.ProbeInterval = 0
+	require.NoError(t, cfg.Validate())
+
+	cfg.ProbeInterval = -1
+	require.Error(t, cfg.Validate())
+}
+
+func TestGenerationProbe(t *testing.T) {
+	inspect := &fakeInspect{generations: []uint64{3, 7, 5}}
+	probe := newGenerationProbe(inspect)
+
+	generation, err := probe(t.Context(), 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), generation)
+
+	// An instance without module configs is at the first generation.
+	inspect.generations = nil
+	generation, err = probe(t.Context(), 0)
+	require.NoError(t, err)
+	require.Zero(t, generation)
+}
+
+func TestRecovery(t *testing.T) {
+	inspect := &fakeInspect{generations: []uint64{10}}
+	recovery := NewRecovery(DefaultRecovery(), inspect, zap.NewNop())
+
+	applied := 0
+	recovery.record("route0", func(ctx context.Context) error {
+		applied++
+		return nil
+	})
+
+	recovery.Check(t.Context())
+	require.Zero(t, applied)
+
+	// The instance restarted and starts its generations over.
+	inspect.generations = []uint64{1}
+	recovery.Check(t.Context())
+	require.Equal(t, 1, applied)
+	require.Equal(t, uint64(1), recovery.Stats(0).Recoveries)
+
+	// A torn down configuration is not set up again.
+	recovery.forget("route0")
+	inspect.generations = nil
+	recovery.Check(t.Context())
+	require.Equal(t, 1, applied)
+
+	// A nil recovery records nothing.
+	var disabled *Recovery
+	disabled.record("route0", nil)
+	disabled.forget("route0")
+}
+
+// newRecoveryTestService returns a service whose route operator endpoint
+// is down, recording to the recovery.
+func newRecoveryTestService(t *testing.T, recovery *Recovery, setupRetry SetupRetryConfig) *AdapterService {
+	svc := NewAdapterService(
+		"127.0.0.1:1",
+		insecure.NewCredentials(),
+		grpccompress.None,
+		routepb.Heartbeat{},
+		nil,
+		nil,
+		nil,
+		recovery,
+		nil,
+		FreshnessSLO{},
+		setupRetry,
+		false,
+		zap.NewNop(),
+	)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, svc.Shutdown(ctx))
+	})
+	return svc
+}
+
+// TestSetupConfig_ApplyHistory verifies that the SetupConfig calls are
+// recorded to the apply history served by the admin API.
+func TestSetupConfig_ApplyHistory(t *testing.T) {
+	recovery := NewRecovery(DefaultRecovery(), &fakeInspect{}, zap.NewNop())
+	// The setup is not retried, so the stream fails to open.
+	svc := newRecoveryTestService(t, recovery, SetupRetryConfig{})
+
+	req := recoveryTestRequest("/run/bird.sock")
+	_, err := svc.SetupConfig(t.Context(), req)
+	require.Error(t, err)
+	digest, err := ConfigDigest(req)
+	require.NoError(t, err)
+
+	events := recovery.History(0, time.Time{}, time.Time{})
+	require.Len(t, events, 1)
+	require.Equal(t, recoveryModule, events[0].Module)
+	require.Equal(t, "route0", events[0].Name)
+	require.Equal(t, digest, events[0].Digest)
+	require.Equal(t, coordinator.ApplyPushed, events[0].Kind)
+	require.Error(t, events[0].Err)
+
+	// A failed push leaves nothing to set up again.
+	require.Empty(t, recovery.Configs())
+}
+
+// recoveryTestRequest returns a valid request of the route0 configuration
+// reading the socket.
+func recoveryTestRequest(socket string) *adapterpb.SetupConfigRequest {
+	req := testSetupConfigRequest("route0")
+	req.Config.Sockets = []string{socket}
+	req.SourceV4 = commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))
+	req.SourceV6 = commonpb.NewIPAddressFromAddr(netip.MustParseAddr("2001:db8::1"))
+	return req
+}
+
+// TestRollbackConfig verifies that the admin API rolls a configuration
+// of the adapter back to a previous version as a new generation.
+func TestRollbackConfig(t *testing.T) {
+	recovery := NewRecovery(DefaultRecovery(), &fakeInspect{}, zap.NewNop())
+	// The imports stay pending, retried in the background.
+	svc := newRecoveryTestService(t, recovery, SetupRetryConfig{Budget: time.Minute, MaxInterval: 10 * time.Millisecond})
+	admin := coordinator.NewAdminService(coordinator.WithAdminRecovery(recovery.Recovery))
+
+	first := recoveryTestRequest("/run/bird.sock")
+	resp, err := svc.SetupConfig(t.Context(), first)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.GetVersion())
+	require.Equal(t, uint64(1), resp.GetGeneration())
+
+	second := recoveryTestRequest("/run/bird6.sock")
+	resp, err = svc.SetupConfig(t.Context(), second)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.GetVersion())
+
+	rollback, err := admin.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{
+		Instance: 0,
+		Module:   recoveryModule,
+		Name:     "route0",
+		Version:  1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), rollback.GetVersion())
+
+	// The first request runs again as a new generation.
+	generations, err := svc.ListConfigGenerations(t.Context(), &adapterpb.ListConfigGenerationsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, generations.GetGenerations(), 3)
+	require.Equal(t, uint64(3), generations.GetGenerations()[0].GetGeneration())
+	require.Equal(t, []string{"/run/bird.sock"}, generations.GetGenerations()[0].GetSockets())
+
+	// Pushing it again keeps the import, reporting the rollback version.
+	resp, err = svc.SetupConfig(t.Context(), first)
+	require.NoError(t, err)
+	require.True(t, resp.GetUnchanged())
+	require.Equal(t, uint64(3), resp.GetVersion())
+
+	_, err = admin.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{
+		Module:  recoveryModule,
+		Name:    "route0",
+		Version: 7,
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestRollbackConfig_Signed verifies that the admin API does not roll a
+// signed configuration back to a request superseded by a later one.
+func TestRollbackConfig_Signed(t *testing.T) {
+	public, key := newTestKey(1)
+	signatures, err := NewSignatureVerifier(SignatureConfig{
+		Keys:    map[string]string{"release": public},
+		Configs: map[string][]string{AnyConfig: {"release"}},
+	})
+	require.NoError(t, err)
+
+	recovery := NewRecovery(DefaultRecovery(), &fakeInspect{}, zap.NewNop())
+	svc := newRecoveryTestService(t, recovery, SetupRetryConfig{Budget: time.Minute, MaxInterval: 10 * time.Millisecond})
+	svc.signatures = signatures
+	admin := coordinator.NewAdminService(coordinator.WithAdminRecovery(recovery.Recovery))
+
+	now := time.Now()
+	first := recoveryTestRequest("/run/bird.sock")
+	require.NoError(t, signSetupConfig(first, "release", key, now.Add(-time.Second)))
+	_, err = svc.SetupConfig(t.Context(), first)
+	require.NoError(t, err)
+
+	second := recoveryTestRequest("/run/bird6.sock")
+	require.NoError(t, signSetupConfig(second, "release", key, now))
+	_, err = svc.SetupConfig(t.Context(), second)
+	require.NoError(t, err)
+
+	_, err = admin.RollbackConfig(t.Context(), &coordinatorpb.RollbackConfigRequest{
+		Module:  recoveryModule,
+		Name:    "route0",
+		Version: 1,
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The second request keeps running.
+	generations, err := svc.ListConfigGenerations(t.Context(), &adapterpb.ListConfigGenerationsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/run/bird6.sock"}, generations.GetGenerations()[0].GetSockets())
+}
@@ -0,0 +1,124 @@
+package bird_adapter
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+// maxRejectSamples is the number of recently rejected routes kept per
+// import.
+const maxRejectSamples = 64
+
+// errInvalidNextHop is the error of routes rejected for having no valid
+// next-hop.
+var errInvalidNextHop = errors.New("route has invalid next_hop")
+
+// routeRejects counts the routes of an import rejected before reaching the
+// route operator and keeps samples of the most recent ones.
+//
+// It is safe for concurrent use, since routes are rejected both by the
+// socket readers and by the update callback.
+type routeRejects struct {
+	mu     sync.Mutex
+	counts map[adapterpb.RouteRejectReason]uint64
+	// samples is a ring buffer of the last rejected routes, next is the
+	// index the following sample is written at.
+	samples []*adapterpb.RouteRejectSample
+	next    int
+}
+
+func newRouteRejects() *routeRejects {
+	return &routeRejects{
+		counts:  map[adapterpb.RouteRejectReason]uint64{},
+		samples: make([]*adapterpb.RouteRejectSample, 0, maxRejectSamples),
+	}
+}
+
+// rejectReason classifies the error a route was rejected with.
+func rejectReason(err error) adapterpb.RouteRejectReason {
+	switch {
+	case errors.Is(err, bird.ErrBadPrefix):
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_BAD_PREFIX
+	case errors.Is(err, bird.ErrUnsupportedPrefix):
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNKNOWN_FAMILY
+	case errors.Is(err, bird.ErrUnsupportedRDType):
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNSUPPORTED_RD
+	case errors.Is(err, errInvalidNextHop):
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_INVALID_NEXT_HOP
+	case errors.Is(err, errPolicyRejected):
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_POLICY
+	default:
+		return adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNKNOWN
+	}
+}
+
+// Add records a route rejected with the given error.
+func (m *routeRejects) Add(route *rib.Route, err error, now time.Time) {
+	reason := rejectReason(err)
+
+	sample := &adapterpb.RouteRejectSample{
+		Reason:     reason,
+		RejectedAt: now.UnixNano(),
+		IsDelete:   route.ToRemove,
+		Error:      err.Error(),
+	}
+	if route.Prefix.IsValid() {
+		sample.Prefix = route.Prefix.String()
+	}
+	if route.Peer.IsValid() {
+		sample.Peer = route.Peer.String()
+	}
+	if route.NextHop.IsValid() {
+		sample.NextHop = route.NextHop.String()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counts[reason]++
+	if len(m.samples) < maxRejectSamples {
+		m.samples = append(m.samples, sample)
+	} else {
+		m.samples[m.next] = sample
+	}
+	m.next = (m.next + 1) % maxRejectSamples
+}
+
+// Proto returns the counters ordered by reason and the samples, newest
+// first.
+func (m *routeRejects) Proto() *adapterpb.RouteRejects {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make([]*adapterpb.RouteRejectCount, 0, len(m.counts))
+	for reason, count := range m.counts {
+		counts = append(counts, &adapterpb.RouteRejectCount{
+			Reason: reason,
+			Count:  count,
+		})
+	}
+	slices.SortFunc(counts, func(a, b *adapterpb.RouteRejectCount) int {
+		return int(a.Reason) - int(b.Reason)
+	})
+
+	// Oldest samples start at next once the ring is full.
+	samples := make([]*adapterpb.RouteRejectSample, 0, len(m.samples))
+	if len(m.samples) == maxRejectSamples {
+		samples = append(samples, m.samples[m.next:]...)
+		samples = append(samples, m.samples[:m.next]...)
+	} else {
+		samples = append(samples, m.samples...)
+	}
+	slices.Reverse(samples)
+
+	return &adapterpb.RouteRejects{
+		Counts:  counts,
+		Samples: samples,
+	}
+}
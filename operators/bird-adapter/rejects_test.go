@@ -0,0 +1,62 @@
+package bird_adapter
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
+)
+
+func TestRouteRejects(t *testing.T) {
+	rejects := newRouteRejects()
+	now := time.Unix(1700000000, 0)
+	peer := netip.MustParseAddr("192.0.2.1")
+
+	rejects.Add(&rib.Route{Peer: peer}, fmt.Errorf("decode: %w", bird.ErrUnsupportedRDType), now)
+	rejects.Add(&rib.Route{
+		Prefix:   netip.MustParsePrefix("10.0.0.0/8"),
+		Peer:     peer,
+		ToRemove: true,
+	}, errInvalidNextHop, now.Add(time.Second))
+	rejects.Add(&rib.Route{}, fmt.Errorf("decode: %w", bird.ErrBadPrefix), now.Add(2*time.Second))
+	rejects.Add(&rib.Route{}, fmt.Errorf("decode: %w", bird.ErrBadPrefix), now.Add(3*time.Second))
+
+	pb := rejects.Proto()
+	require.Equal(t, []*adapterpb.RouteRejectCount{
+		{Reason: adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_BAD_PREFIX, Count: 2},
+		{Reason: adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNSUPPORTED_RD, Count: 1},
+		{Reason: adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_INVALID_NEXT_HOP, Count: 1},
+	}, pb.GetCounts())
+
+	samples := pb.GetSamples()
+	require.Len(t, samples, 4)
+	require.Equal(t, now.Add(3*time.Second).UnixNano(), samples[0].GetRejectedAt())
+	require.Equal(t, "10.0.0.0/8", samples[2].GetPrefix())
+	require.Equal(t, "192.0.2.1", samples[2].GetPeer())
+	require.Empty(t, samples[2].GetNextHop())
+	require.True(t, samples[2].GetIsDelete())
+	require.Empty(t, samples[3].GetPrefix())
+	require.Equal(t, adapterpb.RouteRejectReason_ROUTE_REJECT_REASON_UNSUPPORTED_RD, samples[3].GetReason())
+}
+
+func TestRouteRejects_Eviction(t *testing.T) {
+	rejects := newRouteRejects()
+	now := time.Unix(1700000000, 0)
+	for idx := range maxRejectSamples + 10 {
+		rejects.Add(&rib.Route{}, errInvalidNextHop, now.Add(time.Duration(idx)*time.Second))
+	}
+
+	pb := rejects.Proto()
+	require.Equal(t, uint64(maxRejectSamples+10), pb.GetCounts()[0].GetCount())
+
+	samples := pb.GetSamples()
+	require.Len(t, samples, maxRejectSamples)
+	require.Equal(t, now.Add((maxRejectSamples+9)*time.Second).UnixNano(), samples[0].GetRejectedAt())
+	require.Equal(t, now.Add(10*time.Second).UnixNano(), samples[len(samples)-1].GetRejectedAt())
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
@@ -13,13 +15,17 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 
+	"github.com/yanet-platform/yanet2/common/go/coordinator"
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
 	"github.com/yanet-platform/yanet2/modules/route-mpls/controlplane/routemplspb/v1"
 	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
 	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/kernel"
 	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/mpls"
 	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/rib"
 	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
@@ -49,18 +55,56 @@ type AdapterService struct {
 
 	importsMu             sync.Mutex
 	imports               map[string]*importHolder
-	routeOperatorEndpoint string    // gRPC endpoint of the route operator's RouteService for RIB updates
-	quitCh                chan bool // Signals all background BIRD import loops to stop
+	history               *configHistory
+	routeOperatorEndpoint string                           // gRPC endpoint of the route operator's RouteService for RIB updates
+	routeOperatorCreds    credentials.TransportCredentials // Transport credentials of the route operator connections
+	compression           grpccompress.Compression         // Compression of the FeedRIB streams
+	heartbeat             routepb.Heartbeat                // Keepalives of the FeedRIB streams
+	signatures            *SignatureVerifier               // Verifies SetupConfig signatures; nil accepts all
+	policies              *ImportPolicies                  // Filter the routes of the imports; nil imports all
+	state                 *StateStore                      // Persists the applied configurations; nil persists nothing
+	recovery              *Recovery                        // Sets the configurations up again once the dataplane restarts; nil records nothing
+	capabilities          *CapabilityCheck                 // Refuses the configurations the dataplane does not support; nil accepts all
+	freshness             FreshnessSLO                     // Freshness objective of the BIRD feeds
+	setupRetry            SetupRetryConfig                 // Retries of the imports whose stream failed to open
+	withdrawOnStop        bool                             // Withdraws the routes of the imports replaced or stopped by Shutdown
+	shuttingDown          bool                             // Set by Shutdown, guarded by importsMu; no import is set up after it
+	loops                 sync.WaitGroup                   // Running BIRD import loops, waited for by Shutdown
+	quitOnce              sync.Once                        // Closes quitCh once
+	quitCh                chan bool                        // Signals all background BIRD import loops to stop
 	log                   *zap.Logger
 }
 
 func NewAdapterService(
 	routeOperatorEndpoint string,
+	routeOperatorCreds credentials.TransportCredentials,
+	compression grpccompress.Compression,
+	heartbeat routepb.Heartbeat,
+	signatures *SignatureVerifier,
+	policies *ImportPolicies,
+	state *StateStore,
+	recovery *Recovery,
+	capabilities *CapabilityCheck,
+	freshness FreshnessSLO,
+	setupRetry SetupRetryConfig,
+	withdrawOnStop bool,
 	log *zap.Logger,
 ) *AdapterService {
 	return &AdapterService{
 		imports:               make(map[string]*importHolder),
+		history:               newConfigHistory(),
 		routeOperatorEndpoint: routeOperatorEndpoint,
+		routeOperatorCreds:    routeOperatorCreds,
+		compression:           compression,
+		heartbeat:             heartbeat,
+		signatures:            signatures,
+		policies:              policies,
+		state:                 state,
+		recovery:              recovery,
+		capabilities:          capabilities,
+		freshness:             freshness,
+		setupRetry:            setupRetry,
+		withdrawOnStop:        withdrawOnStop,
 		quitCh:                make(chan bool),
 		log:                   log,
 	}
@@ -74,6 +118,7 @@ func (m *AdapterService) ListSessions(
 	m.importsMu.Lock()
 	defer m.importsMu.Unlock()
 
+	now := time.Now()
 	sessions := make([]*adapterpb.SessionInfo, 0, len(m.imports))
 	for name, holder := range m.imports {
 		connState := adapterpb.ConnectionState_CONNECTION_STATE_UNKNOWN
@@ -97,6 +142,12 @@ func (m *AdapterService) ListSessions(
 			Sockets:         holder.sockets,
 			CreatedAt:       holder.createdAt.UnixNano(),
 			ConnectionState: connState,
+			Generation:      holder.generation,
+			Rejects:         holder.rejects.Proto(),
+			Freshness:       holder.freshness.Status(now).Proto(holder.export.Connected()),
+			NetlinkImport:   holder.netlink,
+			Policy:          holder.policy.Name(),
+			Pending:         holder.pending.Load(),
 		})
 	}
 
@@ -105,39 +156,345 @@ func (m *AdapterService) ListSessions(
 	}, nil
 }
 
+// ListConfigGenerations returns the recently applied generations of a
+// configuration, newest first.
+//
+// Generations outlive the session they were applied to, so the provenance
+// of a configuration is available even after its import has been replaced.
+func (m *AdapterService) ListConfigGenerations(
+	ctx context.Context,
+	req *adapterpb.ListConfigGenerationsRequest,
+) (*adapterpb.ListConfigGenerationsResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "config name is required")
+	}
+
+	m.importsMu.Lock()
+	defer m.importsMu.Unlock()
+
+	return &adapterpb.ListConfigGenerationsResponse{
+		Generations: m.history.List(req.GetName()),
+	}, nil
+}
+
+// SetupConfig starts or replaces the BIRD import of a configuration.
+//
+// An import whose stream to the route operator fails to open is reported
+// pending and retried in the background, see SetupRetryConfig.
+//
+// Every successful call is recorded as a new generation of the
+// configuration together with the request metadata. A configuration whose
+// import already runs with the same digest is left untouched, so pushing
+// every configuration again, e.g. after a restart of the caller, does not
+// restart the imports.
+//
+// A configuration requiring capabilities the dataplane instance does not
+// report fails with FAILED_PRECONDITION, see CapabilityCheck, and one fed
+// to an instance that cannot be probed with UNAVAILABLE, unless the check
+// fails open. So does a
+// signed request replaying an applied one, see SignatureVerifier.Accept.
+//
+// The call is recorded to the apply history of the recovery, failed ones
+// included, as a new version of the configuration, and the applied
+// configuration is set up again once the dataplane instance it is fed to
+// restarts. The coordinator admin API rolls the configuration back to any
+// of its recent versions, which sets the request of that version up again
+// as a new generation, unless the signature policy refuses it, see
+// configPush.
 func (m *AdapterService) SetupConfig(
 	ctx context.Context,
 	req *adapterpb.SetupConfigRequest,
 ) (*adapterpb.SetupConfigResponse, error) {
 	name := req.GetName()
-	mplsV4Src, err := req.GetSourceV4().ToAddr()
+	if err := m.signatures.Accept(req); err != nil {
+		m.log.Warn("rejected the configuration", zap.String("name", name), zap.Error(err))
+		return nil, err
+	}
+
+	unchecked, err := m.capabilities.Check(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("invalid v4 source (bytes=%x): %w", req.GetSourceV4().GetAddr(), err)
+		m.log.Warn("rejected the configuration", zap.String("name", name), zap.Error(err))
+		return nil, err
 	}
-	if !mplsV4Src.Is4() {
-		return nil, fmt.Errorf("v4 source %q is not an IPv4 address", mplsV4Src)
+
+	digest, err := ConfigDigest(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to encode the configuration: %v", err)
+	}
+	if holder, ok := m.runningImport(name, digest); ok {
+		m.log.Info("configuration is unchanged, keeping the import",
+			zap.String("name", name),
+			zap.Uint64("generation", holder.generation.GetGeneration()),
+		)
+		m.signatures.Applied(req)
+		return &adapterpb.SetupConfigResponse{
+			Generation:            holder.generation.GetGeneration(),
+			Unchanged:             true,
+			Pending:               holder.pending.Load(),
+			Version:               m.recovery.version(name),
+			CapabilitiesUnchecked: unchecked,
+		}, nil
 	}
-	mplsV6Src, err := req.GetSourceV6().ToAddr()
+
+	holder, version, err := m.recovery.apply(ctx, name, digest, m.configPush(req, digest))
 	if err != nil {
-		return nil, fmt.Errorf("invalid v6 source (bytes=%x): %w", req.GetSourceV6().GetAddr(), err)
+		return nil, err
+	}
+	m.signatures.Applied(req)
+
+	return &adapterpb.SetupConfigResponse{
+		Generation:            holder.generation.GetGeneration(),
+		Pending:               holder.pending.Load(),
+		Version:               version,
+		CapabilitiesUnchecked: unchecked,
+	}, nil
+}
+
+// TeardownConfig stops the BIRD import of a configuration gracefully.
+//
+// The reader stops first, then the stream to the route operator is closed
+// cleanly after a flush event, so the routes of the import are torn down
+// by the configured policy, or withdrawn at once if requested or if the
+// service withdraws the routes of every stopped import. Unlike a
+// replacement, the configuration is also removed from the state directory,
+// so it is not restored on restart. Its generations are kept.
+//
+// An import whose stream is down, or that does not stop before the
+// request deadline, is cancelled, leaving its routes to the configured
+// policy.
+func (m *AdapterService) TeardownConfig(
+	ctx context.Context,
+	req *adapterpb.TeardownConfigRequest,
+) (*adapterpb.TeardownConfigResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "config name is required")
+	}
+
+	m.importsMu.Lock()
+	holder, ok := m.imports[name]
+	if ok {
+		delete(m.imports, name)
+	}
+	m.importsMu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no import of config %q", name)
+	}
+
+	log := m.log.With(zap.String("config", name))
+	withdraw := req.GetWithdraw() || m.withdrawOnStop
+	log.Info("stopping the BIRD import", zap.Bool("withdraw", withdraw))
+	m.stopImport(ctx, holder, withdraw, log)
+
+	m.recovery.forget(name)
+	if err := m.state.Delete(name); err != nil {
+		log.Warn("failed to remove the persisted configuration", zap.Error(err))
+	}
+
+	return &adapterpb.TeardownConfigResponse{}, nil
+}
+
+// Shutdown stops every BIRD import gracefully, see TeardownConfig, and
+// waits for all the import loops to finish.
+//
+// The routes of the imports are withdrawn if the service withdraws the
+// routes of stopped imports, otherwise they are torn down by the policy of
+// their configuration. Each stream is closed after a flush event, so the
+// route operator applies the updates sent on it instead of seeing the
+// stream cut halfway. The configurations are kept in the state directory,
+// so they are restored on the next start, and no import is set up once
+// Shutdown is called.
+//
+// Imports that do not stop before the context is done are cancelled. The
+// quit signal then ends the loops left, such as the ones of the replaced
+// imports, and an error is returned if they do not finish before the
+// context is done either.
+func (m *AdapterService) Shutdown(ctx context.Context) error {
+	m.importsMu.Lock()
+	m.shuttingDown = true
+	imports := m.imports
+	m.imports = map[string]*importHolder{}
+	m.importsMu.Unlock()
+
+	wg := sync.WaitGroup{}
+	for name, holder := range imports {
+		log := m.log.With(zap.String("config", name))
+		log.Info("stopping the BIRD import on shutdown", zap.Bool("withdraw", m.withdrawOnStop))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.stopImport(ctx, holder, m.withdrawOnStop, log)
+		}()
+	}
+	wg.Wait()
+	m.quitOnce.Do(func() { close(m.quitCh) })
+
+	done := make(chan struct{})
+	go func() {
+		m.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("BIRD import loops did not finish in time: %w", ctx.Err())
+	}
+}
+
+// stopImport stops an import gracefully and waits for its loop to return.
+//
+// The reader stops first, then the stream is closed after a flush event
+// carrying the teardown policy, WITHDRAW if the routes are withdrawn. The
+// route operator withdraws the routes of the stopped stream only, so the
+// ones a replacing import has already announced again are kept.
+func (m *AdapterService) stopImport(ctx context.Context, holder *importHolder, withdraw bool, log *zap.Logger) {
+	if withdraw {
+		holder.stopTeardown <- routepb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW
+	}
+	holder.stopReader()
+
+	select {
+	case <-holder.done:
+	case <-ctx.Done():
+		log.Warn("BIRD import did not stop in time, cancelling it")
+		holder.cancel()
+		<-holder.done
+	}
+}
+
+// Restore sets up the imports of the configurations persisted to the
+// state directory, keeping the generations they were applied as.
+//
+// It is called before the service starts serving. The configurations that
+// fail to restore are skipped and reported in the joined error.
+func (m *AdapterService) Restore() error {
+	configs, err := m.state.Load()
+
+	for _, config := range configs {
+		req := config.GetRequest()
+		name := req.GetName()
+
+		digest, e := ConfigDigest(req)
+		if e != nil {
+			err = errors.Join(err, fmt.Errorf("failed to encode configuration %q: %w", name, e))
+			continue
+		}
+
+		m.signatures.Restored(req)
+
+		generations := config.GetGenerations()
+		m.importsMu.Lock()
+		m.history.Restore(name, generations, config.GetLastGeneration())
+		m.importsMu.Unlock()
+
+		var restored *adapterpb.ConfigGeneration
+		if len(generations) > 0 {
+			restored = generations[len(generations)-1]
+		}
+		holder, e := m.setupConfig(req, digest, restored)
+		if e != nil {
+			err = errors.Join(err, fmt.Errorf("failed to restore configuration %q: %w", name, e))
+			continue
+		}
+		m.recovery.record(name, func(ctx context.Context) error {
+			_, err := m.configPush(req, digest)(ctx)
+			return err
+		})
+
+		m.log.Info("restored the configuration",
+			zap.String("name", name),
+			zap.Uint64("generation", holder.generation.GetGeneration()),
+			zap.Bool("pending", holder.pending.Load()),
+		)
+	}
+
+	return err
+}
+
+// configPush returns the push setting the configuration up as a new
+// generation, or again as the generation its import runs as if it already
+// runs with the same digest, which is how the recovery sets the imports up
+// again once the dataplane instance restarts.
+//
+// A rollback through the coordinator admin API sets up a request accepted
+// before, so it is verified again like a SetupConfig call: a signed request
+// is older than the last accepted one and is refused, keeping the admin API
+// from replaying the configurations a signature policy superseded. The
+// recovery and the admin repush set up the request applied last, which is
+// not checked again.
+func (m *AdapterService) configPush(
+	req *adapterpb.SetupConfigRequest,
+	digest string,
+) func(ctx context.Context) (*importHolder, error) {
+	return func(ctx context.Context) (*importHolder, error) {
+		rollback := coordinator.ApplyKindFromContext(ctx) == coordinator.ApplyRolledBack
+		if rollback {
+			if err := m.signatures.Accept(req); err != nil {
+				m.log.Warn("refused to roll the configuration back", zap.String("name", req.GetName()), zap.Error(err))
+				return nil, err
+			}
+		}
+
+		var restored *adapterpb.ConfigGeneration
+		if holder, ok := m.runningImport(req.GetName(), digest); ok {
+			restored = holder.generation
+		}
+		holder, err := m.setupConfig(req, digest, restored)
+		if err == nil && rollback {
+			m.signatures.Applied(req)
+		}
+		return holder, err
+	}
+}
+
+// runningImport returns the running import of the named configuration if
+// it was applied with the given digest.
+func (m *AdapterService) runningImport(name string, digest string) (*importHolder, bool) {
+	m.importsMu.Lock()
+	defer m.importsMu.Unlock()
+
+	holder, ok := m.imports[name]
+	if !ok || holder.generation.GetDigest() != digest {
+		return nil, false
 	}
-	if !mplsV6Src.Is6() || mplsV6Src.Is4In6() {
-		return nil, fmt.Errorf("v6 source %q is not a pure IPv6 address", mplsV6Src)
+	// The import loop closes the connection once it terminates for good.
+	if holder.conn.GetState() == connectivity.Shutdown {
+		return nil, false
+	}
+
+	return holder, true
+}
+
+// setupConfig starts or replaces the BIRD import of a configuration.
+//
+// A nil restored generation records the import as a new generation,
+// otherwise it runs as the restored one.
+func (m *AdapterService) setupConfig(
+	req *adapterpb.SetupConfigRequest,
+	digest string,
+	restored *adapterpb.ConfigGeneration,
+) (*importHolder, error) {
+	name := req.GetName()
+
+	settings, errs := parseSetupConfig(req)
+	if len(errs) != 0 {
+		return nil, errs[0].err
 	}
 	logLevelStr := req.GetConfig().GetLogLevel()
+	metadata := req.GetMetadata()
 
 	m.log.Info("setting up the configuration",
 		zap.String("name", name),
 		zap.String("log_level", logLevelStr),
+		zap.Stringer("teardown", settings.teardown),
+		zap.Strings("tags", req.GetConfig().GetTags()),
+		zap.String("author", metadata.GetAuthor()),
+		zap.String("ticket", metadata.GetTicket()),
+		zap.String("description", metadata.GetDescription()),
 	)
 
-	cfg := bird.DefaultConfig()
-	req.GetConfig().ToConfig(cfg)
-	if len(cfg.Sockets) == 0 {
-		// We do not need this connection if there is no background stream for import
-		return nil, fmt.Errorf("no export sockets provided")
-	}
-
 	// Create per-client logger based on requested log level
 	var clientLog *zap.Logger
 	if logLevelStr != "" {
@@ -168,35 +525,106 @@ func (m *AdapterService) SetupConfig(
 
 	conn, err := grpc.NewClient(
 		m.routeOperatorEndpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+		grpc.WithTransportCredentials(m.routeOperatorCreds),
+		grpc.WithDefaultCallOptions(m.compression.CallOptions()...),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to the route operator endpoint: %w", err)
 	}
 
 	// And then add dynamic routes, if any.
-	if err := m.processBirdImport(conn, cfg, name, mplsV4Src, mplsV6Src, clientLog); err != nil {
+	holder, err := m.processBirdImport(
+		conn,
+		settings.cfg,
+		settings.kernelCfg,
+		req,
+		digest,
+		restored,
+		settings.mplsV4Src,
+		settings.mplsV6Src,
+		settings.teardown,
+		clientLog,
+	)
+	if err != nil {
 		_ = conn.Close()
 		return nil, fmt.Errorf("failed to setup bird import reader: %w ", err)
 	}
 
-	return &adapterpb.SetupConfigResponse{}, nil
+	return holder, nil
+}
+
+// teardownPolicy converts the teardown policy of an import into the one
+// sent to the route operator.
+func teardownPolicy(policy adapterpb.TeardownPolicy) (routepb.TeardownPolicy, error) {
+	switch policy {
+	case adapterpb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT:
+		return routepb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT, nil
+	case adapterpb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW:
+		return routepb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW, nil
+	case adapterpb.TeardownPolicy_TEARDOWN_POLICY_KEEP:
+		return routepb.TeardownPolicy_TEARDOWN_POLICY_KEEP, nil
+	default:
+		return 0, fmt.Errorf("invalid teardown policy %d", policy)
+	}
+}
+
+// maxImportTags is the number of tags the route operator accepts on a
+// route.
+const maxImportTags = 16
+
+// validateImportTags checks the tags of an import, which the route operator
+// would otherwise reject with every route.
+func validateImportTags(tags []string) error {
+	if len(tags) > maxImportTags {
+		return fmt.Errorf("too many tags: %d, at most %d allowed", len(tags), maxImportTags)
+	}
+	if slices.Contains(tags, "") {
+		return fmt.Errorf("empty tag")
+	}
+	return nil
 }
 
-var errStreamClosed = fmt.Errorf("stream closed")
+// importStopTimeout bounds the graceful stop of a replaced import.
+const importStopTimeout = 10 * time.Second
+
+var (
+	errStreamClosed = fmt.Errorf("stream closed")
+	errStreamEnded  = fmt.Errorf("route operator ended the stream")
+)
+
+// routeReader reads the routes of an import, either from the BIRD export
+// sockets or from the kernel FIB.
+type routeReader interface {
+	// Run reads the routes until ctx is cancelled or reading fails.
+	Run(ctx context.Context) error
+	// Connected reports whether Run is connected to the route source.
+	Connected() bool
+}
 
 // importHolder bundles resources for one BIRD import: the BIRD data reader,
 // a cancellable context for its goroutines, the gRPC connection to the RIB service,
 // and the active gRPC stream for sending updates.
 type importHolder struct {
-	export        *bird.Export                                                       // Reads/parses routes from BIRD
-	cancel        context.CancelFunc                                                 // Stops this import's goroutines (runBirdImportLoop, export.Run)
-	conn          *grpc.ClientConn                                                   // gRPC connection to the route operator's RouteService
-	currentStream *grpc.ClientStreamingClient[routepb.Update, routepb.UpdateSummary] // Active gRPC stream for RIB updates; replaced on reconnect
-	sockets       []string                                                           // Unix socket paths being read from
-	createdAt     time.Time                                                          // Timestamp when the session was created
-	mplsRib       mpls.Rib                                                           // Store mpls routes
+	export        routeReader                 // Reads/parses routes from BIRD or the kernel FIB
+	cancel        context.CancelFunc          // Stops this import's goroutines (runBirdImportLoop, export.Run)
+	readerCtx     context.Context             // Governs export.Run only, so the stream outlives a graceful stop
+	stopReader    context.CancelFunc          // Stops export.Run for a graceful stop
+	stopTeardown  chan routepb.TeardownPolicy // Teardown policy of a graceful stop, sent before stopReader
+	done          chan struct{}               // Closed once runBirdImportLoop returns
+	teardown      routepb.TeardownPolicy      // Teardown policy of the configuration
+	conn          *grpc.ClientConn            // gRPC connection to the route operator's RouteService
+	currentStream *routepb.FeedRIBStream      // Active gRPC stream for RIB updates; replaced on reconnect
+	sockets       []string                    // Unix socket paths being read from
+	createdAt     time.Time                   // Timestamp when the session was created
+	generation    *adapterpb.ConfigGeneration // Generation the session was set up by
+	mplsRib       mpls.Rib                    // Store mpls routes
+	rejects       *routeRejects               // Routes rejected before reaching the route operator
+	policy        *policyFilter               // Import policy the routes pass before being sent
+	freshness     *feedFreshness              // Time since the last update from BIRD against the SLO
+	metrics       *importMetrics              // Routes and stream state of the import
+	netlink       bool                        // Whether routes are imported from the kernel FIB
+	pending       atomic.Bool                 // Whether the stream is not open yet, retried in the background
+	name          string                      // Configuration name
 }
 
 // processBirdImport streams BIRD route updates to the control plane RIB.
@@ -204,38 +632,79 @@ type importHolder struct {
 // Handles automatic reconnection and graceful cleanup of existing imports.
 // It establishes the initial gRPC stream to the route operator's RouteService,
 // sets up callbacks for the bird.Export reader, and manages replacement of
-// existing imports.
+// existing imports. Every update carries the teardown policy, so the route
+// operator applies it however the stream ends. The import is recorded as a
+// new generation of the configuration, unless it restores one, and
+// returned. The applied configuration is persisted to the state directory.
+//
+// If the initial stream fails to open and setup retries are enabled, the
+// import is registered as pending and its loop keeps opening the stream
+// within the retry budget.
+//
+// With a non-nil kernelCfg, the routes are read from the kernel FIB instead
+// of the BIRD sockets, through the same stream and reconnection handling.
 func (m *AdapterService) processBirdImport(
 	conn *grpc.ClientConn,
 	cfg *bird.Config,
-	name string,
+	kernelCfg *kernel.Config,
+	req *adapterpb.SetupConfigRequest,
+	digest string,
+	restored *adapterpb.ConfigGeneration,
 	mplsV4Src netip.Addr,
 	mplsV6Src netip.Addr,
+	teardown routepb.TeardownPolicy,
 	clientLog *zap.Logger,
-) error {
+) (*importHolder, error) {
+	name := req.GetName()
+	tags := req.GetConfig().GetTags()
+	log := m.log.With(zap.String("config", name))
+
 	// streamCtx governs this specific import's gRPC stream and BIRD reader.
 	// Cancelled via holder.cancel on replacement or service stop.
 	streamCtx, cancel := context.WithCancel(context.Background())
 	client := routepb.NewRouteServiceClient(conn)
-	stream, err := client.FeedRIB(streamCtx)
-	if err != nil {
+	stream, err := routepb.OpenFeedRIB(streamCtx, client, name, m.heartbeat)
+	if err != nil && !m.setupRetry.enabled() {
 		cancel() // cleanup context if stream setup fails
-		return fmt.Errorf("failed to setup initial BIRD import stream: %w", err)
+		return nil, fmt.Errorf("failed to setup initial BIRD import stream: %w", err)
 	}
 
 	holder := new(importHolder)
-	holder.currentStream = &stream
+	// The stream of a pending import is opened by its loop.
+	holder.currentStream = stream
+	holder.pending.Store(err != nil)
+	holder.readerCtx, holder.stopReader = context.WithCancel(streamCtx)
+	holder.stopTeardown = make(chan routepb.TeardownPolicy, 1)
+	holder.done = make(chan struct{})
+	holder.teardown = teardown
+	holder.name = name
 
 	routeMPLSClient := routemplspb.NewRouteMPLSServiceClient(conn)
 	holder.mplsRib = mpls.NewRib()
+	holder.rejects = newRouteRejects()
+	holder.policy = newPolicyFilter(m.policies.forConfig(name))
+	holder.freshness = newFeedFreshness(m.freshness, time.Now())
+	holder.metrics = newImportMetrics()
+	if holder.pending.Load() {
+		holder.metrics.SetState(streamPending)
+		log.Warn("failed to open the BIRD import stream, retrying in the background",
+			zap.Duration("budget", m.setupRetry.Budget),
+			zap.Error(err),
+		)
+	}
 
-	log := m.log.With(zap.String("config", name))
+	// batchStart is when the batch being sent was received. The readers
+	// flush each batch from the goroutine that sent it.
+	var batchStart time.Time
 
 	// onUpdate sends route batches over the gRPC stream. Called by bird.Export.
 	onUpdate := func(ctx context.Context, routes []rib.Route) error {
 		log.Debug("processing BIRD routes",
 			zap.Int("count", len(routes)),
 		)
+		batchStart = time.Now()
+		holder.freshness.Observe(batchStart)
+		holder.metrics.received.Add(uint64(len(routes)))
 
 		// Batch mpls module updates
 		mplsUpdates := make([]*routemplspb.UpdateEvent, 0)
@@ -243,10 +712,15 @@ func (m *AdapterService) processBirdImport(
 		for idx := range routes {
 			select {
 			case <-ctx.Done():
+				if streamCtx.Err() == nil {
+					// The reader alone is stopped by a graceful stop,
+					// which closes the stream itself.
+					return ctx.Err()
+				}
 				log.Warn("update stream send cancelled",
 					zap.Error(ctx.Err()),
 				)
-				_, closeErr := (*holder.currentStream).CloseAndRecv()
+				_, closeErr := holder.currentStream.CloseAndRecv()
 				return errors.Join(ctx.Err(), closeErr, errStreamClosed) // Signal runBirdImportLoop
 			default:
 			}
@@ -258,6 +732,7 @@ func (m *AdapterService) processBirdImport(
 					zap.String("next_hop", routes[idx].NextHop.String()),
 					zap.Binary("next_hop_bytes", routes[idx].NextHop.AsSlice()),
 				)
+				holder.rejects.Add(&routes[idx], errInvalidNextHop, time.Now())
 				continue
 			}
 
@@ -266,8 +741,10 @@ func (m *AdapterService) processBirdImport(
 				updates := holder.mplsRib.Apply(routes[idx])
 				for idx := range updates {
 					update := updates[idx]
+					// The tunnel source follows the family of the
+					// destination, which is IPv6 for an extended next hop.
 					source := mplsV4Src
-					if update.Prefix.Addr().Is6() {
+					if update.Prefix.Addr().Is6() || update.HasExtendedNextHop() {
 						source = mplsV6Src
 					}
 					if update.ToRemove {
@@ -293,15 +770,37 @@ func (m *AdapterService) processBirdImport(
 				continue
 			}
 
-			err := (*holder.currentStream).Send(&routepb.Update{
+			verdict, err := holder.policy.Filter(&routes[idx])
+			if err != nil {
+				clientLog.Debug("route rejected by import policy",
+					zap.String("prefix", routes[idx].Prefix.String()),
+					zap.Stringer("peer", routes[idx].Peer),
+					zap.Error(err),
+				)
+				holder.rejects.Add(&routes[idx], err, time.Now())
+			}
+			switch verdict {
+			case policyDrop:
+				continue
+			case policyWithdraw:
+				// The route was accepted before, so its previous
+				// attributes are installed.
+				routes[idx].ToRemove = true
+			}
+
+			route := rib.ToPBRoute(&routes[idx])
+			route.Tags = tags
+			err = holder.currentStream.Send(&routepb.Update{
 				Name:     name,
 				IsDelete: routes[idx].ToRemove,
-				Route:    rib.ToPBRoute(&routes[idx]),
+				Route:    route,
+				Teardown: teardown,
 			})
 			if err != nil {
 				// This error stops bird.Export, triggering reconnection in runBirdImportLoop
 				return fmt.Errorf("send BIRD route update for %s failed: %w", routes[idx].Prefix, err)
 			}
+			holder.metrics.sent.Inc()
 		}
 
 		if len(mplsUpdates) > 0 {
@@ -320,21 +819,54 @@ func (m *AdapterService) processBirdImport(
 
 	// onFlush commits updates to dataplane. Called by bird.Export.
 	onFlush := func() error {
+		holder.freshness.Observe(time.Now())
 		// update without route indicates flush event
-		err := (*holder.currentStream).Send(&routepb.Update{Name: name})
+		err := holder.currentStream.Send(&routepb.Update{Name: name, Teardown: teardown})
 		if err != nil {
 			return fmt.Errorf("flush BIRD routes failed: %w", err)
 		}
+		holder.metrics.flushLatency.Observe(time.Since(batchStart).Seconds())
 		return nil
 	}
 
-	export := bird.NewExportReader(cfg, onUpdate, onFlush, clientLog)
+	// onEndOfRIB tells the RIB that the initial BIRD dump is complete.
+	// Called by bird.Export once per stream.
+	onEndOfRIB := func() error {
+		err := holder.currentStream.Send(&routepb.Update{Name: name, EndOfRib: true, Teardown: teardown})
+		if err != nil {
+			return fmt.Errorf("send BIRD end-of-RIB marker failed: %w", err)
+		}
+		return nil
+	}
+
+	// onReject records routes the reader could not decode. Called by
+	// bird.Export.
+	onReject := func(route *rib.Route, err error) {
+		clientLog.Debug("rejected BIRD route update, skip",
+			zap.Stringer("peer", route.Peer),
+			zap.Error(err),
+		)
+		now := time.Now()
+		holder.rejects.Add(route, err, now)
+		holder.freshness.Observe(now)
+		holder.metrics.received.Inc()
+	}
+
+	var export routeReader = bird.NewExportReader(cfg, onUpdate, onFlush, onEndOfRIB, onReject, clientLog)
+	if kernelCfg != nil {
+		export = kernel.NewImportReader(kernelCfg, onUpdate, onFlush, onEndOfRIB, clientLog)
+	}
 
 	// Lock to safely access and modify m.imports.
 	m.importsMu.Lock()
-	defer m.importsMu.Unlock()
+	if m.shuttingDown {
+		m.importsMu.Unlock()
+		cancel()
+		return nil, status.Error(codes.Unavailable, "the adapter is shutting down")
+	}
 	// Ensure only one active import per target: stop and replace if one exists.
-	if oldHolder, ok := m.imports[name]; ok {
+	oldHolder, replaced := m.imports[name]
+	if replaced && !m.withdrawOnStop {
 		log.Info("replacing existing BIRD import")
 		if oldHolder.cancel != nil { // Defensive check
 			oldHolder.cancel()
@@ -348,13 +880,39 @@ func (m *AdapterService) processBirdImport(
 	holder.cancel = cancel
 	holder.conn = conn
 	holder.sockets = cfg.Sockets
+	holder.netlink = kernelCfg != nil
 	holder.createdAt = time.Now()
+	holder.generation = restored
+	if holder.generation == nil {
+		holder.generation = m.history.Add(name, cfg.Sockets, req.GetMetadata(), digest, holder.createdAt)
+	}
 	m.imports[name] = holder
 
+	log.Info("applied configuration generation",
+		zap.Uint64("generation", holder.generation.GetGeneration()),
+	)
+
+	// The import runs either way; a configuration failing to persist is
+	// pushed again after a restart.
+	if err := m.state.Save(m.history.Applied(req, digest)); err != nil {
+		log.Warn("failed to persist the configuration", zap.Error(err))
+	}
+
 	// Launch goroutine for BIRD reading and stream lifecycle management.
+	m.loops.Add(1)
 	go m.runBirdImportLoop(streamCtx, holder, client, log)
+	m.importsMu.Unlock()
 
-	return nil
+	// The replaced import is stopped once the replacing one runs, so the
+	// routes it announces again are not withdrawn in between.
+	if replaced && m.withdrawOnStop {
+		log.Info("replacing existing BIRD import, withdrawing its routes")
+		ctx, cancel := context.WithTimeout(context.Background(), importStopTimeout)
+		m.stopImport(ctx, oldHolder, true, log)
+		cancel()
+	}
+
+	return holder, nil
 }
 
 // runBirdImportLoop is the main goroutine for an active BIRD import.
@@ -371,6 +929,9 @@ func (m *AdapterService) runBirdImportLoop(
 		log.Info("BIRD import loop cleanup: closing connection and cancelling context")
 		holder.cancel()         // Ensure BIRD reader's context is cancelled
 		_ = holder.conn.Close() // Close gRPC client connection
+		holder.metrics.SetState(streamClosed)
+		close(holder.done)
+		m.loops.Done()
 	}()
 
 	runBackoff := backoff.ExponentialBackOff{
@@ -382,6 +943,9 @@ func (m *AdapterService) runBirdImportLoop(
 	runBackoff.Reset()
 	backoffResetTimeout := 10 * time.Minute
 
+	if holder.pending.Load() && !m.openPendingStream(ctx, client, holder, log) {
+		return
+	}
 	streamActive := true
 
 	for {
@@ -392,6 +956,11 @@ func (m *AdapterService) runBirdImportLoop(
 		case <-m.quitCh:
 			log.Info("BIRD import loop stopping due to service quit signal")
 			return
+		case <-holder.readerCtx.Done():
+			if streamActive && ctx.Err() == nil {
+				m.closeImportStream(holder, log)
+			}
+			return
 		default:
 		}
 
@@ -402,19 +971,31 @@ func (m *AdapterService) runBirdImportLoop(
 
 		if !streamActive {
 			log.Info("attempting to re-establish BIRD route update stream")
-			if !m.reconnectStream(ctx, client, holder.currentStream, log) {
+			holder.metrics.Reconnecting()
+			if !m.reconnectStream(ctx, client, holder, log) {
 				log.Info("stream reconnection aborted, terminating BIRD import loop")
 				return // Reconnect failed due to ctx / quitCh
 			}
 			streamActive = true
+			holder.metrics.Reconnected()
 			log.Info("successfully re-established BIRD route update stream")
 		}
 
 		log.Info("starting BIRD export reader")
 		lastRunAttempt := time.Now()
-		err := holder.export.Run(ctx) // Blocking call
+		err := m.runExport(holder) // Blocking call
+		if holder.readerCtx.Err() != nil && ctx.Err() == nil {
+			log.Info("BIRD export reader stopped for a graceful stop")
+			m.closeImportStream(holder, log)
+			return
+		}
 		if err != nil {
-			log.Warn("BIRD export reader stopped with error", zap.Error(err))
+			if errors.Is(err, bird.ErrStale) {
+				log.Warn("BIRD import is stale, reconnecting", zap.Error(err))
+				holder.metrics.stale.Inc()
+			} else {
+				log.Warn("BIRD export reader stopped with error", zap.Error(err))
+			}
 			streamActive = false // Stream needs re-establishment
 
 			// If context cancellation caused reader to stop, exit loop
@@ -426,7 +1007,7 @@ func (m *AdapterService) runBirdImportLoop(
 			// If stream wasn't closed by onUpdate's error path, try to close it here
 			if !errors.Is(err, errStreamClosed) {
 				log.Info("closing client stream after BIRD export reader error")
-				if _, closeErr := (*holder.currentStream).CloseAndRecv(); closeErr != nil {
+				if _, closeErr := holder.currentStream.CloseAndRecv(); closeErr != nil {
 					log.Warn("error closing client stream post-reader failure", zap.Error(closeErr))
 				}
 			}
@@ -435,6 +1016,8 @@ func (m *AdapterService) runBirdImportLoop(
 				runBackoff.Reset()
 			}
 			// Apply exponential backoff before retrying the export reader
+			wait := runBackoff.NextBackOff()
+			holder.metrics.BackingOff(wait)
 			select {
 			case <-ctx.Done():
 				log.Info("BIRD import loop cancelled via context", zap.Error(ctx.Err()))
@@ -442,7 +1025,10 @@ func (m *AdapterService) runBirdImportLoop(
 			case <-m.quitCh:
 				log.Info("BIRD import loop stopping due to service quit signal")
 				return
-			case <-time.After(runBackoff.NextBackOff()):
+			case <-holder.readerCtx.Done():
+				log.Info("BIRD import loop stopping gracefully while the stream is down")
+				return
+			case <-time.After(wait):
 			}
 			// Loop continues to attempt reconnection unless ctx/quitCh terminates it
 		} else {
@@ -452,13 +1038,60 @@ func (m *AdapterService) runBirdImportLoop(
 	}
 }
 
+// runExport runs the reader of an import until it fails or the stream
+// ends.
+//
+// An idle reader sends nothing, so it would not notice an idle stream
+// broken or aborted for a dead route operator. The reader is stopped
+// instead, as a failed send would.
+func (m *AdapterService) runExport(holder *importHolder) error {
+	stream := holder.currentStream
+	runCtx, stopRun := context.WithCancelCause(holder.readerCtx)
+	defer stopRun(nil)
+
+	go func() {
+		select {
+		case <-stream.Done():
+			stopRun(errStreamEnded)
+		case <-runCtx.Done():
+		}
+	}()
+
+	err := holder.export.Run(runCtx)
+	if errors.Is(context.Cause(runCtx), errStreamEnded) && holder.readerCtx.Err() == nil {
+		return errors.Join(errStreamEnded, stream.Err(), errStreamClosed)
+	}
+	return err
+}
+
+// closeImportStream ends the stream of a gracefully stopped import with a
+// flush event carrying the teardown policy of the stop, which the route
+// operator applies to the routes of the session once the stream closes.
+func (m *AdapterService) closeImportStream(holder *importHolder, log *zap.Logger) {
+	teardown := holder.teardown
+	select {
+	case teardown = <-holder.stopTeardown:
+	default:
+	}
+
+	stream := holder.currentStream
+	if err := stream.Send(&routepb.Update{Name: holder.name, Teardown: teardown}); err != nil {
+		log.Warn("failed to send the teardown policy of the stopped import", zap.Error(err))
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		log.Warn("error closing client stream of the stopped import", zap.Error(err))
+		return
+	}
+	log.Info("closed the stream of the stopped import", zap.Stringer("teardown", teardown))
+}
+
 // reconnectStream attempts to re-establish the gRPC stream with exponential backoff.
 // Returns true if reconnection succeeds, false if aborted by context or quit signal.
-// Updates `currentStream` with the new stream on success.
+// Updates `holder.currentStream` with the new stream on success.
 func (m *AdapterService) reconnectStream(
 	ctx context.Context,
 	client routepb.RouteServiceClient,
-	currentStream *grpc.ClientStreamingClient[routepb.Update, routepb.UpdateSummary],
+	holder *importHolder,
 	log *zap.Logger,
 ) bool {
 	log.Info("attempting to re-establish BIRD route update stream with exponential backoff")
@@ -481,13 +1114,13 @@ func (m *AdapterService) reconnectStream(
 			return false
 		case <-ticker.C:
 			log.Info("attempting FeedRIB call for new stream")
-			newStream, err := client.FeedRIB(ctx) // Use import's context
+			newStream, err := routepb.OpenFeedRIB(ctx, client, holder.name, m.heartbeat) // Use import's context
 			if err != nil {
 				log.Warn("failed to re-establish stream, retrying via ticker", zap.Error(err))
 				continue // Ticker schedules next attempt
 			}
 
-			*currentStream = newStream // Update to new stream
+			holder.currentStream = newStream // Update to new stream
 			return true
 		}
 	}
@@ -0,0 +1,384 @@
+package bird_adapter
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// feedSession is a FeedRIB stream received by the fake route operator.
+type feedSession struct {
+	routes   map[string]struct{}    // Prefixes installed by the stream
+	teardown routepb.TeardownPolicy // Teardown policy of the last update
+	closed   bool                   // Whether the adapter closed the stream
+}
+
+// fakeRouteOperator is the RouteService of the route operator receiving
+// the FeedRIB streams of the imports.
+//
+// Like the route operator, it withdraws the routes of a stream closed
+// after an update carrying the WITHDRAW teardown policy, and keeps the
+// ones of the other streams.
+type fakeRouteOperator struct {
+	routepb.UnimplementedRouteServiceServer
+
+	mu       sync.Mutex
+	sessions []*feedSession
+	// closing is called with the index of every stream the adapter closes.
+	closing func(idx int)
+}
+
+func (m *fakeRouteOperator) FeedRIB(stream routepb.RouteService_FeedRIBServer) error {
+	m.mu.Lock()
+	idx := len(m.sessions)
+	session := &feedSession{routes: map[string]struct{}{}}
+	m.sessions = append(m.sessions, session)
+	m.mu.Unlock()
+
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			m.mu.Lock()
+			closing := m.closing
+			m.mu.Unlock()
+			if closing != nil {
+				closing(idx)
+			}
+
+			m.mu.Lock()
+			session.closed = true
+			if session.teardown == routepb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW {
+				clear(session.routes)
+			}
+			m.mu.Unlock()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if update.GetHeartbeat() {
+			continue
+		}
+
+		m.mu.Lock()
+		session.teardown = update.GetTeardown()
+		if route := update.GetRoute(); route != nil {
+			if update.GetIsDelete() {
+				delete(session.routes, route.GetPrefix())
+			} else {
+				session.routes[route.GetPrefix()] = struct{}{}
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// session returns a copy of the idx-th stream received.
+func (m *fakeRouteOperator) session(idx int) feedSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if idx >= len(m.sessions) {
+		return feedSession{}
+	}
+	session := *m.sessions[idx]
+	session.routes = maps.Clone(session.routes)
+	return session
+}
+
+// routes returns the sorted prefixes installed by the streams received.
+func (m *fakeRouteOperator) routes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := []string{}
+	for _, session := range m.sessions {
+		for prefix := range session.routes {
+			if !slices.Contains(routes, prefix) {
+				routes = append(routes, prefix)
+			}
+		}
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// onClose sets the function called with the index of every stream the
+// adapter closes, before its routes are withdrawn.
+func (m *fakeRouteOperator) onClose(closing func(idx int)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closing = closing
+}
+
+// newFakeRouteOperator serves the fake route operator, returning it with
+// its endpoint.
+func newFakeRouteOperator(t *testing.T) (*fakeRouteOperator, string) {
+	operator := &fakeRouteOperator{}
+	server := grpc.NewServer()
+	routepb.RegisterRouteServiceServer(server, operator)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return operator, listener.Addr().String()
+}
+
+// birdUpdate encodes the chunk of the BIRD export socket announcing the
+// IPv6 prefix via the nexthop.
+//
+// The addresses are written as native-endian 32-bit words, the way BIRD
+// stores them.
+func birdUpdate(prefix netip.Prefix, nexthop netip.Addr) []byte {
+	putAddr := func(buf []byte, addr netip.Addr) {
+		bytes := addr.As16()
+		for idx := 0; idx < len(bytes); idx += 4 {
+			binary.LittleEndian.PutUint32(buf[idx:], binary.BigEndian.Uint32(bytes[idx:]))
+		}
+	}
+
+	// The network, the operation, the peer and the attributes area holding
+	// the NEXT_HOP attribute alone.
+	data := make([]byte, 64+24)
+	data[0] = 0x2 // NetIP6
+	data[1] = byte(prefix.Bits())
+	binary.LittleEndian.PutUint16(data[2:], 20)
+	putAddr(data[4:], prefix.Addr())
+	binary.LittleEndian.PutUint32(data[40:], 1) // Update
+	binary.LittleEndian.PutUint32(data[60:], 24)
+	data[64] = byte(bird.AttrNextHop)
+	data[65] = 0x4 // PROTOCOL_BGP
+	binary.LittleEndian.PutUint32(data[68:], 16)
+	putAddr(data[72:], nexthop)
+
+	// The chunk size excludes itself.
+	chunk := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	return append(chunk, data...)
+}
+
+// newFakeBIRD serves a BIRD export socket announcing the IPv6 prefixes on
+// every connection, returning its path.
+func newFakeBIRD(t *testing.T, prefixes ...string) string {
+	// Unix socket paths are short, so the default temporary directory of
+	// the test may not fit.
+	dir, err := os.MkdirTemp("", "bird")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "export.sock")
+
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	nexthop := netip.MustParseAddr("2001:db8::1")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for _, prefix := range prefixes {
+					update := birdUpdate(netip.MustParsePrefix(prefix), nexthop)
+					if _, err := conn.Write(update); err != nil {
+						return
+					}
+				}
+				// BIRD holds the socket open until the reader leaves.
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	return path
+}
+
+// newShutdownTestService returns a service feeding the route operator
+// endpoint.
+func newShutdownTestService(endpoint string, withdrawOnStop bool) *AdapterService {
+	return NewAdapterService(
+		endpoint,
+		insecure.NewCredentials(),
+		grpccompress.None,
+		routepb.Heartbeat{},
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		FreshnessSLO{},
+		SetupRetryConfig{},
+		withdrawOnStop,
+		zap.NewNop(),
+	)
+}
+
+// startImport sets the import of the named configuration up, reading the
+// BIRD socket.
+func startImport(t *testing.T, svc *AdapterService, name string, socket string) (*importHolder, error) {
+	conn, err := grpc.NewClient(
+		svc.routeOperatorEndpoint,
+		grpc.WithTransportCredentials(svc.routeOperatorCreds),
+	)
+	require.NoError(t, err)
+
+	cfg := bird.DefaultConfig()
+	cfg.Sockets = []string{socket}
+	cfg.DumpTimeout = 10 * time.Millisecond
+
+	holder, err := svc.processBirdImport(
+		conn,
+		cfg,
+		nil,
+		testSetupConfigRequest(name),
+		socket,
+		nil,
+		netip.Addr{},
+		netip.Addr{},
+		routepb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT,
+		zap.NewNop(),
+	)
+	if err != nil {
+		_ = conn.Close()
+	}
+	return holder, err
+}
+
+// TestShutdown verifies that Shutdown closes the streams of the imports
+// cleanly, stops their loops and refuses the imports set up after it.
+func TestShutdown(t *testing.T) {
+	operator, endpoint := newFakeRouteOperator(t)
+	svc := newShutdownTestService(endpoint, false)
+
+	holder, err := startImport(t, svc, "route0", newFakeBIRD(t, "2001:db8:1::/48"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"2001:db8:1::/48"}, operator.routes())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Shutdown(ctx))
+
+	// The loop is done and the stream closed after a flush carrying the
+	// teardown policy of the configuration, which keeps the routes.
+	require.Eventually(t, func() bool { return operator.session(0).closed }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-holder.done:
+	default:
+		t.Fatal("the import loop is still running")
+	}
+	session := operator.session(0)
+	require.Equal(t, routepb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT, session.teardown)
+	require.Equal(t, []string{"2001:db8:1::/48"}, operator.routes())
+
+	// The quit signal is sent once, however many times Shutdown is called.
+	select {
+	case <-svc.quitCh:
+	default:
+		t.Fatal("the quit signal is not sent")
+	}
+	require.NoError(t, svc.Shutdown(ctx))
+
+	// No import is set up once shutting down.
+	_, err = startImport(t, svc, "route1", newFakeBIRD(t, "2001:db8:2::/48"))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	resp, err := svc.ListSessions(t.Context(), nil)
+	require.NoError(t, err)
+	require.Empty(t, resp.GetSessions())
+}
+
+// TestShutdown_Deadline verifies that Shutdown fails once its context is
+// done while import loops are still running.
+func TestShutdown_Deadline(t *testing.T) {
+	svc := newShutdownTestService("127.0.0.1:1", false)
+
+	// A loop that outlives the quit signal.
+	svc.loops.Add(1)
+	defer svc.loops.Done()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err := svc.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestProcessBirdImport_WithdrawOnStop verifies that an import replaced by
+// a service withdrawing the routes of stopped imports is stopped after the
+// replacing one runs, and that only the routes of its own stream are
+// withdrawn.
+func TestProcessBirdImport_WithdrawOnStop(t *testing.T) {
+	operator, endpoint := newFakeRouteOperator(t)
+	svc := newShutdownTestService(endpoint, true)
+
+	old, err := startImport(t, svc, "route0", newFakeBIRD(t, "2001:db8:1::/48", "2001:db8:2::/48"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"2001:db8:1::/48", "2001:db8:2::/48"}, operator.routes())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The replaced stream is closed once the replacing import runs.
+	running := make(chan bool, 1)
+	operator.onClose(func(idx int) {
+		svc.importsMu.Lock()
+		holder := svc.imports["route0"]
+		svc.importsMu.Unlock()
+		running <- idx == 0 && holder != nil && holder != old
+	})
+
+	// The replacing import announces one of the routes again.
+	replacing, err := startImport(t, svc, "route0", newFakeBIRD(t, "2001:db8:1::/48", "2001:db8:3::/48"))
+	require.NoError(t, err)
+	require.NotSame(t, old, replacing)
+
+	// The replaced import is stopped, its stream closed after a flush
+	// withdrawing the routes.
+	select {
+	case <-old.done:
+	default:
+		t.Fatal("the replaced import loop is still running")
+	}
+	require.True(t, <-running)
+	operator.onClose(nil)
+	replaced := operator.session(0)
+	require.True(t, replaced.closed)
+	require.Equal(t, routepb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW, replaced.teardown)
+	require.Empty(t, replaced.routes)
+
+	// The routes of the replacing stream are kept.
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"2001:db8:1::/48", "2001:db8:3::/48"}, operator.routes())
+	}, 5*time.Second, 10*time.Millisecond)
+	session := operator.session(1)
+	require.False(t, session.closed)
+	require.Equal(t, routepb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT, session.teardown)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Shutdown(ctx))
+}
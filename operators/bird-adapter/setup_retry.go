@@ -0,0 +1,115 @@
+package bird_adapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"go.uber.org/zap"
+
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// SetupRetryConfig controls the retries of the imports whose FeedRIB
+// stream SetupConfig fails to open.
+//
+// Such an import is registered as pending instead of failing the call, and
+// its stream is opened in the background, so the configurations may be
+// pushed before the route operator, or the gateway proxying it, is up.
+type SetupRetryConfig struct {
+	// Budget is the time a pending import is retried for before it is
+	// given up.
+	//
+	// Zero fails SetupConfig at once instead.
+	Budget time.Duration `yaml:"budget"`
+	// MaxInterval bounds the backoff between the attempts.
+	MaxInterval time.Duration `yaml:"max_interval"`
+}
+
+// DefaultSetupRetry returns the setup retries used unless configured.
+func DefaultSetupRetry() SetupRetryConfig {
+	return SetupRetryConfig{
+		Budget:      10 * time.Minute,
+		MaxInterval: 30 * time.Second,
+	}
+}
+
+// Validate validates the setup retries.
+func (m *SetupRetryConfig) Validate() error {
+	if m.Budget == 0 {
+		return nil
+	}
+	if m.Budget < 0 {
+		return fmt.Errorf("budget must not be negative")
+	}
+	if m.MaxInterval <= 0 {
+		return fmt.Errorf("max_interval must be positive, got %s", m.MaxInterval)
+	}
+	return nil
+}
+
+// enabled reports whether the failed setups are retried.
+func (m *SetupRetryConfig) enabled() bool {
+	return m.Budget > 0
+}
+
+// openPendingStream opens the stream of a pending import, retrying with
+// backoff until the setup retry budget is spent.
+//
+// It returns false if the import is given up or stopped meanwhile.
+func (m *AdapterService) openPendingStream(
+	ctx context.Context,
+	client routepb.RouteServiceClient,
+	holder *importHolder,
+	log *zap.Logger,
+) bool {
+	defer holder.pending.Store(false)
+
+	budget := time.NewTimer(m.setupRetry.Budget)
+	defer budget.Stop()
+
+	ticker := backoff.NewTicker(&backoff.ExponentialBackOff{
+		InitialInterval:     backoff.DefaultInitialInterval,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
+		Multiplier:          backoff.DefaultMultiplier,
+		MaxInterval:         m.setupRetry.MaxInterval,
+	})
+	defer ticker.Stop()
+
+	attempts := 0
+	for {
+		select {
+		case <-m.quitCh:
+			log.Info("pending BIRD import stopping due to service quit signal")
+			return false
+		case <-ctx.Done():
+			log.Info("pending BIRD import cancelled via context", zap.Error(ctx.Err()))
+			return false
+		case <-holder.readerCtx.Done():
+			log.Info("pending BIRD import stopped before its stream opened")
+			return false
+		case <-budget.C:
+			log.Error("gave up the pending BIRD import, its setup retry budget is spent",
+				zap.Duration("budget", m.setupRetry.Budget),
+				zap.Int("attempts", attempts),
+			)
+			return false
+		case <-ticker.C:
+			attempts++
+			stream, err := routepb.OpenFeedRIB(ctx, client, holder.name, m.heartbeat)
+			if err != nil {
+				log.Warn("failed to open the stream of the pending BIRD import, retrying",
+					zap.Int("attempt", attempts),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			holder.currentStream = stream
+			holder.metrics.SetState(streamUp)
+			log.Info("opened the stream of the pending BIRD import", zap.Int("attempts", attempts))
+			return true
+		}
+	}
+}
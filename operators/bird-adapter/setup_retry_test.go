@@ -0,0 +1,25 @@
+package bird_adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupRetryConfig_Validate(t *testing.T) {
+	retry := DefaultSetupRetry()
+	require.NoError(t, retry.Validate())
+	require.True(t, retry.enabled())
+
+	retry.MaxInterval = 0
+	require.Error(t, retry.Validate())
+
+	// Retries are disabled without a budget, whatever the interval.
+	retry.Budget = 0
+	require.NoError(t, retry.Validate())
+	require.False(t, retry.enabled())
+
+	retry.Budget = -time.Second
+	require.Error(t, retry.Validate())
+}
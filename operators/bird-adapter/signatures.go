@@ -0,0 +1,294 @@
+package bird_adapter
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// AnyConfig is the SignatureConfig.Configs entry applying to the
+// configurations not listed explicitly.
+const AnyConfig = "*"
+
+// SignatureConfig configures verification of SetupConfig signatures.
+//
+// It keeps a compromised orchestration host, which can reach the adapter
+// but holds no signing key, from replacing the BIRD imports, or from
+// replaying the signed requests it captured to set a previous configuration
+// up again.
+type SignatureConfig struct {
+	// Keys are the trusted ed25519 public keys, base64-encoded, by key ID.
+	Keys map[string]string `yaml:"keys"`
+	// Configs lists the IDs of the keys allowed to sign the SetupConfig
+	// calls of a configuration, by configuration name.
+	//
+	// The AnyConfig entry applies to the configurations not listed.
+	// Configurations matching no entry are applied unsigned.
+	Configs map[string][]string `yaml:"configs"`
+	// MaxAge is how long a signed request is accepted for after it was
+	// signed, and how far ahead of the adapter clock its signing time may
+	// be.
+	//
+	// Zero accepts requests of any age, only rejecting those signed no
+	// later than the last one accepted.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// DefaultSignatureMaxAge is the SignatureConfig.MaxAge used unless
+// configured.
+const DefaultSignatureMaxAge = 5 * time.Minute
+
+// DefaultSignatures returns the signature config used unless configured,
+// verifying no signatures.
+func DefaultSignatures() SignatureConfig {
+	return SignatureConfig{MaxAge: DefaultSignatureMaxAge}
+}
+
+// Validate validates the signature config.
+func (m *SignatureConfig) Validate() error {
+	if m.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+	_, err := NewSignatureVerifier(*m)
+	return err
+}
+
+// SignatureVerifier verifies SetupConfig signatures.
+//
+// A nil SignatureVerifier accepts every request.
+type SignatureVerifier struct {
+	keys    map[string]ed25519.PublicKey
+	configs map[string][]string
+	maxAge  time.Duration
+	now     func() time.Time
+
+	mu sync.Mutex
+	// accepted is the last request accepted by configuration name.
+	accepted map[string]acceptedSignature
+}
+
+// acceptedSignature is the signature of an accepted request.
+type acceptedSignature struct {
+	signedAt  int64
+	signature []byte
+	// applied is set once the request is applied, so it is not accepted
+	// again.
+	applied bool
+}
+
+// NewSignatureVerifier decodes the keys of the config, returning nil if no
+// configuration requires signatures.
+func NewSignatureVerifier(cfg SignatureConfig) (*SignatureVerifier, error) {
+	keys := map[string]ed25519.PublicKey{}
+	for id, encoded := range cfg.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signing key %q: %w", id, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("signing key %q is %d bytes long, expected %d", id, len(key), ed25519.PublicKeySize)
+		}
+		keys[id] = ed25519.PublicKey(key)
+	}
+
+	for name, ids := range cfg.Configs {
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("configuration %q allows no signing keys", name)
+		}
+		for _, id := range ids {
+			if _, ok := keys[id]; !ok {
+				return nil, fmt.Errorf("configuration %q allows unknown signing key %q", name, id)
+			}
+		}
+	}
+
+	if len(cfg.Configs) == 0 {
+		return nil, nil
+	}
+
+	return &SignatureVerifier{
+		keys:     keys,
+		configs:  cfg.Configs,
+		maxAge:   cfg.MaxAge,
+		now:      time.Now,
+		accepted: map[string]acceptedSignature{},
+	}, nil
+}
+
+// Verify checks the signature of the request if its configuration
+// requires one, and that the request is not a replay of an applied one,
+// without accepting it.
+func (m *SignatureVerifier) Verify(req *adapterpb.SetupConfigRequest) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.verify(req)
+}
+
+// Accept verifies the request like Verify and, if it is signed, remembers
+// its signing time, so that the requests signed before it are rejected from
+// then on.
+//
+// The request itself is accepted again, resent unchanged, until Applied
+// reports it applied, so a caller can retry a request whose apply failed
+// after it was accepted.
+func (m *SignatureVerifier) Accept(req *adapterpb.SetupConfigRequest) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.verify(req); err != nil {
+		return err
+	}
+	m.remember(req, false)
+	return nil
+}
+
+// Applied reports the accepted request applied, so that it is rejected
+// from then on like the requests signed before it.
+func (m *SignatureVerifier) Applied(req *adapterpb.SetupConfigRequest) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remember(req, true)
+}
+
+// Restored remembers the signing time of a request applied before the
+// adapter restarted, without verifying it, so that the earlier requests
+// are not accepted again.
+func (m *SignatureVerifier) Restored(req *adapterpb.SetupConfigRequest) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remember(req, true)
+}
+
+func (m *SignatureVerifier) remember(req *adapterpb.SetupConfigRequest, applied bool) {
+	signature := req.GetSignature()
+	if signature == nil {
+		return
+	}
+
+	name := req.GetName()
+	last := m.accepted[name]
+	switch {
+	case signature.GetSignedAt() > last.signedAt:
+		m.accepted[name] = acceptedSignature{
+			signedAt:  signature.GetSignedAt(),
+			signature: signature.GetSignature(),
+			applied:   applied,
+		}
+	case signature.GetSignedAt() == last.signedAt && bytes.Equal(signature.GetSignature(), last.signature):
+		last.applied = last.applied || applied
+		m.accepted[name] = last
+	}
+}
+
+func (m *SignatureVerifier) verify(req *adapterpb.SetupConfigRequest) error {
+	name := req.GetName()
+	allowed, ok := m.configs[name]
+	if !ok {
+		allowed, ok = m.configs[AnyConfig]
+	}
+	if !ok {
+		return nil
+	}
+
+	signature := req.GetSignature()
+	if signature == nil {
+		return status.Errorf(codes.Unauthenticated, "configuration %q requires a signed request", name)
+	}
+	keyID := signature.GetKeyId()
+	if !slices.Contains(allowed, keyID) {
+		return status.Errorf(codes.PermissionDenied, "key %q is not allowed to sign configuration %q", keyID, name)
+	}
+
+	payload, err := SignedPayload(req)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to encode the signed payload: %v", err)
+	}
+	if !ed25519.Verify(m.keys[keyID], payload, signature.GetSignature()) {
+		return status.Errorf(codes.Unauthenticated, "invalid signature of configuration %q by key %q", name, keyID)
+	}
+
+	signedAt := signature.GetSignedAt()
+	if signedAt == 0 {
+		return status.Errorf(codes.InvalidArgument, "signature of configuration %q carries no signing time", name)
+	}
+	last := m.accepted[name]
+	resent := signedAt == last.signedAt && !last.applied && bytes.Equal(signature.GetSignature(), last.signature)
+	if signedAt <= last.signedAt && !resent {
+		return status.Errorf(codes.FailedPrecondition,
+			"configuration %q is signed at %s, not after the last accepted request, signed at %s",
+			name,
+			time.Unix(0, signedAt).UTC().Format(time.RFC3339Nano),
+			time.Unix(0, last.signedAt).UTC().Format(time.RFC3339Nano),
+		)
+	}
+	if m.maxAge > 0 {
+		age := m.now().Sub(time.Unix(0, signedAt))
+		if age > m.maxAge || age < -m.maxAge {
+			return status.Errorf(codes.FailedPrecondition,
+				"configuration %q is signed %s away from the adapter clock, more than the %s allowed",
+				name,
+				age.Abs().Round(time.Second),
+				m.maxAge,
+			)
+		}
+	}
+
+	return nil
+}
+
+// SignedPayload returns the bytes the signature of the request covers: the
+// request with the signature bytes unset, keeping the key ID and the
+// signing time.
+func SignedPayload(req *adapterpb.SetupConfigRequest) ([]byte, error) {
+	unsigned := proto.Clone(req).(*adapterpb.SetupConfigRequest)
+	if unsigned.Signature != nil {
+		unsigned.Signature.Signature = nil
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+}
+
+// SignSetupConfig signs the request with the key as of the current time,
+// replacing its previous signature.
+func SignSetupConfig(req *adapterpb.SetupConfigRequest, keyID string, key ed25519.PrivateKey) error {
+	return signSetupConfig(req, keyID, key, time.Now())
+}
+
+func signSetupConfig(req *adapterpb.SetupConfigRequest, keyID string, key ed25519.PrivateKey, signedAt time.Time) error {
+	req.Signature = &adapterpb.ConfigSignature{
+		KeyId:    keyID,
+		SignedAt: signedAt.UnixNano(),
+	}
+	payload, err := SignedPayload(req)
+	if err != nil {
+		req.Signature = nil
+		return fmt.Errorf("failed to encode the signed payload: %w", err)
+	}
+
+	req.Signature.Signature = ed25519.Sign(key, payload)
+	return nil
+}
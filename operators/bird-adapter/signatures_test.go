@@ -0,0 +1,177 @@
+package bird_adapter
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// newTestKey returns a deterministic key with its base64-encoded public
+// part.
+func newTestKey(seed byte) (string, ed25519.PrivateKey) {
+	seedBytes := make([]byte, ed25519.SeedSize)
+	seedBytes[0] = seed
+	key := ed25519.NewKeyFromSeed(seedBytes)
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), key
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	releasePublic, release := newTestKey(1)
+	opsPublic, ops := newTestKey(2)
+
+	verifier, err := NewSignatureVerifier(SignatureConfig{
+		Keys: map[string]string{"release": releasePublic, "ops": opsPublic},
+		Configs: map[string][]string{
+			"route0":  {"release"},
+			AnyConfig: {"release", "ops"},
+		},
+	})
+	require.NoError(t, err)
+
+	newRequest := func(name string) *adapterpb.SetupConfigRequest {
+		return &adapterpb.SetupConfigRequest{
+			Name:   name,
+			Config: &adapterpb.ImportConfig{Sockets: []string{"/run/bird.sock"}},
+		}
+	}
+
+	req := newRequest("route0")
+	require.Equal(t, codes.Unauthenticated, status.Code(verifier.Verify(req)))
+
+	require.NoError(t, SignSetupConfig(req, "release", release))
+	require.NoError(t, verifier.Verify(req))
+
+	// Any change of the signed request invalidates the signature.
+	req.Config.Sockets = append(req.Config.Sockets, "/run/evil.sock")
+	require.Equal(t, codes.Unauthenticated, status.Code(verifier.Verify(req)))
+
+	req = newRequest("route0")
+	require.NoError(t, SignSetupConfig(req, "ops", ops))
+	require.Equal(t, codes.PermissionDenied, status.Code(verifier.Verify(req)))
+
+	// The wildcard entry covers the configurations not listed.
+	req = newRequest("route1")
+	require.NoError(t, SignSetupConfig(req, "ops", ops))
+	require.NoError(t, verifier.Verify(req))
+
+	// A key signing under another key's ID is rejected.
+	req = newRequest("route1")
+	require.NoError(t, SignSetupConfig(req, "release", ops))
+	require.Equal(t, codes.Unauthenticated, status.Code(verifier.Verify(req)))
+}
+
+func TestNewSignatureVerifier(t *testing.T) {
+	public, _ := newTestKey(1)
+
+	verifier, err := NewSignatureVerifier(SignatureConfig{Keys: map[string]string{"release": public}})
+	require.NoError(t, err)
+	require.Nil(t, verifier)
+	require.NoError(t, verifier.Verify(&adapterpb.SetupConfigRequest{Name: "route0"}))
+
+	for _, cfg := range []SignatureConfig{
+		{Keys: map[string]string{"release": "not base64"}},
+		{Keys: map[string]string{"release": base64.StdEncoding.EncodeToString([]byte("short"))}},
+		{Configs: map[string][]string{"route0": {"release"}}},
+		{Keys: map[string]string{"release": public}, Configs: map[string][]string{"route0": {}}},
+	} {
+		_, err := NewSignatureVerifier(cfg)
+		require.Error(t, err)
+		require.Error(t, cfg.Validate())
+	}
+	cfg := DefaultSignatures()
+	require.NoError(t, cfg.Validate())
+	cfg.MaxAge = -time.Second
+	require.Error(t, cfg.Validate())
+}
+
+// TestSignatureVerifier_Replay verifies that a signed request is accepted
+// until applied, and only if signed after the last accepted one and
+// recently enough.
+func TestSignatureVerifier_Replay(t *testing.T) {
+	public, key := newTestKey(1)
+
+	verifier, err := NewSignatureVerifier(SignatureConfig{
+		Keys:    map[string]string{"release": public},
+		Configs: map[string][]string{AnyConfig: {"release"}},
+		MaxAge:  time.Minute,
+	})
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	verifier.now = func() time.Time { return now }
+
+	newRequest := func(socket string, signedAt time.Time) *adapterpb.SetupConfigRequest {
+		req := &adapterpb.SetupConfigRequest{
+			Name:   "route0",
+			Config: &adapterpb.ImportConfig{Sockets: []string{socket}},
+		}
+		require.NoError(t, signSetupConfig(req, "release", key, signedAt))
+		return req
+	}
+
+	first := newRequest("/run/bird.sock", now.Add(-10*time.Second))
+	second := newRequest("/run/bird6.sock", now.Add(-5*time.Second))
+
+	require.NoError(t, verifier.Accept(first))
+	require.NoError(t, verifier.Accept(second))
+
+	// Neither the applied requests nor the ones signed before the accepted
+	// ones are accepted again.
+	verifier.Applied(second)
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Accept(second)))
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Verify(first)))
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Accept(
+		newRequest("/run/bird.sock", now.Add(-7*time.Second)),
+	)))
+
+	// The signing time is covered by the signature.
+	forged := newRequest("/run/bird.sock", now.Add(-10*time.Second))
+	forged.Signature.SignedAt = now.UnixNano()
+	require.Equal(t, codes.Unauthenticated, status.Code(verifier.Accept(forged)))
+
+	// Requests signed too long ago or too far ahead are rejected.
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Verify(
+		newRequest("/run/bird.sock", now.Add(-2*time.Minute)),
+	)))
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Verify(
+		newRequest("/run/bird.sock", now.Add(2*time.Minute)),
+	)))
+
+	// A signature without a signing time is rejected.
+	unsigned := newRequest("/run/bird.sock", time.Unix(0, 0))
+	require.Equal(t, codes.InvalidArgument, status.Code(verifier.Verify(unsigned)))
+
+	// Verifying a request does not accept it.
+	third := newRequest("/run/bird.sock", now)
+	require.NoError(t, verifier.Verify(third))
+	require.NoError(t, verifier.Accept(third))
+
+	// An accepted request whose apply failed is accepted again when resent
+	// unchanged, but not once applied.
+	require.NoError(t, verifier.Accept(third))
+	verifier.Applied(third)
+	require.Equal(t, codes.FailedPrecondition, status.Code(verifier.Accept(third)))
+
+	// The other configurations are tracked on their own.
+	other := newRequest("/run/bird.sock", now.Add(-30*time.Second))
+	other.Name = "route1"
+	require.NoError(t, signSetupConfig(other, "release", key, now.Add(-30*time.Second)))
+	require.NoError(t, verifier.Accept(other))
+
+	// A restored request keeps the earlier ones from being accepted after
+	// a restart.
+	restarted, err := NewSignatureVerifier(SignatureConfig{
+		Keys:    map[string]string{"release": public},
+		Configs: map[string][]string{AnyConfig: {"release"}},
+	})
+	require.NoError(t, err)
+	restarted.Restored(second)
+	require.Equal(t, codes.FailedPrecondition, status.Code(restarted.Accept(first)))
+	require.NoError(t, restarted.Accept(third))
+}
@@ -0,0 +1,160 @@
+package bird_adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// stateFileExt is the extension of the files of the state directory.
+const stateFileExt = ".pb"
+
+// ConfigDigest returns the digest identifying the content of the request.
+//
+// The metadata and the signature are left out, so re-sending the same
+// configuration with another provenance yields the same digest.
+func ConfigDigest(req *adapterpb.SetupConfigRequest) (string, error) {
+	content := proto.Clone(req).(*adapterpb.SetupConfigRequest)
+	content.Metadata = nil
+	content.Signature = nil
+
+	buf, err := proto.MarshalOptions{Deterministic: true}.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StateStore persists the applied configurations to a directory, one file
+// per configuration name, so that a restarted adapter restores its imports
+// instead of waiting for every configuration to be pushed again.
+//
+// A nil StateStore persists nothing.
+type StateStore struct {
+	dir string
+}
+
+// NewStateStore creates the state directory if needed, returning nil for
+// an empty path.
+func NewStateStore(dir string) (*StateStore, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %q: %w", dir, err)
+	}
+
+	return &StateStore{dir: dir}, nil
+}
+
+// Save replaces the persisted state of a configuration.
+//
+// The file is replaced atomically, so a crash leaves either the old or the
+// new state behind.
+func (m *StateStore) Save(config *adapterpb.AppliedConfig) error {
+	if m == nil {
+		return nil
+	}
+
+	buf, err := proto.MarshalOptions{Deterministic: true}.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	path := m.path(config.GetRequest().GetName())
+	tmp, err := os.CreateTemp(m.dir, ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// Delete removes the persisted state of a configuration, if any.
+func (m *StateStore) Delete(name string) error {
+	if m == nil {
+		return nil
+	}
+
+	path := m.path(name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove state file %q: %w", path, err)
+	}
+	return nil
+}
+
+// Load returns the persisted state of every configuration.
+//
+// Files that fail to decode are reported in the joined error and skipped,
+// so a single corrupted file does not lose the others.
+func (m *StateStore) Load() ([]*adapterpb.AppliedConfig, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state directory %q: %w", m.dir, err)
+	}
+
+	var configs []*adapterpb.AppliedConfig
+	var errs error
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), stateFileExt) {
+			continue
+		}
+
+		path := filepath.Join(m.dir, entry.Name())
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to read state file %q: %w", path, err))
+			continue
+		}
+		config := &adapterpb.AppliedConfig{}
+		if err := proto.Unmarshal(buf, config); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to decode state file %q: %w", path, err))
+			continue
+		}
+		if config.GetRequest().GetName() == "" {
+			errs = errors.Join(errs, fmt.Errorf("state file %q has no configuration name", path))
+			continue
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs, errs
+}
+
+// path returns the state file of a configuration, escaping the name so
+// that any name maps to a file of the directory.
+func (m *StateStore) path(name string) string {
+	return filepath.Join(m.dir, url.PathEscape(name)+stateFileExt)
+}
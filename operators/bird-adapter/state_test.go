@@ -0,0 +1,106 @@
+package bird_adapter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+func testSetupConfigRequest(name string) *adapterpb.SetupConfigRequest {
+	return &adapterpb.SetupConfigRequest{
+		Name: name,
+		Config: &adapterpb.ImportConfig{
+			Sockets: []string{"/run/bird.sock"},
+		},
+		Metadata: &adapterpb.ConfigMetadata{Author: "alice"},
+	}
+}
+
+func TestConfigDigest(t *testing.T) {
+	req := testSetupConfigRequest("route0")
+	digest, err := ConfigDigest(req)
+	require.NoError(t, err)
+	require.Len(t, digest, 64)
+
+	// The provenance and the signature do not change the digest.
+	other := proto.Clone(req).(*adapterpb.SetupConfigRequest)
+	other.Metadata = &adapterpb.ConfigMetadata{Author: "bob", Ticket: "NET-2"}
+	other.Signature = &adapterpb.ConfigSignature{KeyId: "release"}
+	otherDigest, err := ConfigDigest(other)
+	require.NoError(t, err)
+	require.Equal(t, digest, otherDigest)
+
+	other.Config.Sockets = []string{"/run/bird6.sock"}
+	otherDigest, err = ConfigDigest(other)
+	require.NoError(t, err)
+	require.NotEqual(t, digest, otherDigest)
+}
+
+func TestStateStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store, err := NewStateStore(dir)
+	require.NoError(t, err)
+
+	history := newConfigHistory()
+	for _, name := range []string{"route0", "route/1"} {
+		req := testSetupConfigRequest(name)
+		history.Add(name, req.GetConfig().GetSockets(), req.GetMetadata(), "digest", time.Unix(1700000000, 0))
+		require.NoError(t, store.Save(history.Applied(req, "digest")))
+	}
+
+	// Saving again replaces the state of the configuration.
+	req := testSetupConfigRequest("route0")
+	history.Add("route0", req.GetConfig().GetSockets(), req.GetMetadata(), "digest", time.Unix(1700000060, 0))
+	require.NoError(t, store.Save(history.Applied(req, "digest")))
+
+	// Unrelated and corrupted files do not prevent loading the others.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("state"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pb"), []byte{0xff}, 0o600))
+
+	configs, err := store.Load()
+	require.Error(t, err)
+	require.Len(t, configs, 2)
+
+	byName := map[string]*adapterpb.AppliedConfig{}
+	for _, config := range configs {
+		byName[config.GetRequest().GetName()] = config
+	}
+	require.Len(t, byName["route0"].GetGenerations(), 2)
+	require.Equal(t, uint64(2), byName["route0"].GetLastGeneration())
+	require.Equal(t, "digest", byName["route0"].GetDigest())
+	require.Equal(t, "alice", byName["route0"].GetRequest().GetMetadata().GetAuthor())
+	require.Len(t, byName["route/1"].GetGenerations(), 1)
+
+	// A deleted configuration is not loaded anymore, deleting it again is
+	// no error.
+	require.NoError(t, store.Delete("route/1"))
+	require.NoError(t, store.Delete("route/1"))
+	require.NoError(t, os.Remove(filepath.Join(dir, "broken.pb")))
+	configs, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+
+	// A restored history continues the generation numbers.
+	restored := newConfigHistory()
+	restored.Restore("route0", byName["route0"].GetGenerations(), byName["route0"].GetLastGeneration())
+	require.Equal(t, uint64(2), restored.List("route0")[0].GetGeneration())
+	require.Equal(t, uint64(3), restored.Add("route0", nil, nil, "", time.Now()).GetGeneration())
+}
+
+func TestStateStore_Disabled(t *testing.T) {
+	store, err := NewStateStore("")
+	require.NoError(t, err)
+	require.Nil(t, store)
+
+	require.NoError(t, store.Save(&adapterpb.AppliedConfig{}))
+	require.NoError(t, store.Delete("route0"))
+	configs, err := store.Load()
+	require.NoError(t, err)
+	require.Empty(t, configs)
+}
@@ -0,0 +1,170 @@
+package bird_adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/bird"
+	"github.com/yanet-platform/yanet2/operators/bird-adapter/internal/kernel"
+	routepb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// socketDialTimeout bounds the connection attempt made to check that a
+// BIRD socket is reachable.
+const socketDialTimeout = time.Second
+
+// configError is a problem found in a SetupConfig request.
+type configError struct {
+	// field is the path of the request field, empty for the whole request.
+	field string
+	err   error
+}
+
+func (m configError) proto() *adapterpb.ConfigError {
+	return &adapterpb.ConfigError{
+		Field:   m.field,
+		Message: m.err.Error(),
+	}
+}
+
+// setupSettings are the import settings of a SetupConfig request.
+type setupSettings struct {
+	mplsV4Src netip.Addr
+	mplsV6Src netip.Addr
+	teardown  routepb.TeardownPolicy
+	cfg       *bird.Config
+	// kernelCfg is nil unless the routes are imported from the kernel FIB.
+	kernelCfg *kernel.Config
+}
+
+// parseSetupConfig parses the import settings of a SetupConfig request.
+//
+// Every problem found is returned rather than the first one, so that a
+// request can be fixed in one go.
+func parseSetupConfig(req *adapterpb.SetupConfigRequest) (*setupSettings, []configError) {
+	settings := &setupSettings{}
+	errs := []configError{}
+	fail := func(field string, err error) {
+		errs = append(errs, configError{field: field, err: err})
+	}
+
+	var err error
+	settings.mplsV4Src, err = req.GetSourceV4().ToAddr()
+	if err != nil {
+		fail("source_v4", fmt.Errorf("invalid v4 source (bytes=%x): %w", req.GetSourceV4().GetAddr(), err))
+	} else if !settings.mplsV4Src.Is4() {
+		fail("source_v4", fmt.Errorf("v4 source %q is not an IPv4 address", settings.mplsV4Src))
+	}
+	settings.mplsV6Src, err = req.GetSourceV6().ToAddr()
+	if err != nil {
+		fail("source_v6", fmt.Errorf("invalid v6 source (bytes=%x): %w", req.GetSourceV6().GetAddr(), err))
+	} else if !settings.mplsV6Src.Is6() || settings.mplsV6Src.Is4In6() {
+		fail("source_v6", fmt.Errorf("v6 source %q is not a pure IPv6 address", settings.mplsV6Src))
+	}
+
+	if settings.teardown, err = teardownPolicy(req.GetConfig().GetTeardown()); err != nil {
+		fail("config.teardown", err)
+	}
+	if err := validateImportTags(req.GetConfig().GetTags()); err != nil {
+		fail("config.tags", err)
+	}
+
+	settings.cfg = bird.DefaultConfig()
+	req.GetConfig().ToConfig(settings.cfg)
+	if err := settings.cfg.Validate(); err != nil {
+		fail("config", err)
+	}
+	settings.kernelCfg = req.GetConfig().ToKernelConfig(settings.cfg)
+	if len(settings.cfg.Sockets) == 0 && settings.kernelCfg == nil {
+		// We do not need this connection if there is no background stream for import
+		fail("config.sockets", fmt.Errorf("no export sockets or netlink import provided"))
+	}
+	if len(settings.cfg.Sockets) != 0 && settings.kernelCfg != nil {
+		fail("config.netlink_import", fmt.Errorf("export sockets and netlink import are mutually exclusive"))
+	}
+
+	return settings, errs
+}
+
+// checkSockets checks that the BIRD sockets accept connections.
+//
+// SetupConfig does not require it, as the import reconnects to the sockets
+// until BIRD is up, but a socket that is not reachable yet is usually a
+// mistyped path.
+func checkSockets(ctx context.Context, sockets []string) []configError {
+	errs := []configError{}
+	dialer := net.Dialer{Timeout: socketDialTimeout}
+	for idx, path := range sockets {
+		field := fmt.Sprintf("config.sockets[%d]", idx)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, configError{field: field, err: err})
+			continue
+		}
+		if info.Mode()&os.ModeSocket == 0 {
+			errs = append(errs, configError{field: field, err: fmt.Errorf("%s is not a Unix socket", path)})
+			continue
+		}
+
+		conn, err := dialer.DialContext(ctx, "unix", path)
+		if err != nil {
+			errs = append(errs, configError{field: field, err: fmt.Errorf("socket is not reachable: %w", err)})
+			continue
+		}
+		_ = conn.Close()
+	}
+	return errs
+}
+
+// ValidateConfig checks a SetupConfig request without applying it.
+//
+// The request is verified, checked against the capabilities of the
+// dataplane instance and parsed like SetupConfig does, and its BIRD
+// sockets are connected to. Neither the running imports nor the route
+// operator are touched.
+func (m *AdapterService) ValidateConfig(
+	ctx context.Context,
+	req *adapterpb.ValidateConfigRequest,
+) (*adapterpb.ValidateConfigResponse, error) {
+	setupReq := req.GetRequest()
+	if setupReq == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+
+	digest, err := ConfigDigest(setupReq)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to encode the configuration: %v", err)
+	}
+
+	errs := []configError{}
+	if err := m.signatures.Verify(setupReq); err != nil {
+		errs = append(errs, configError{field: "signature", err: errors.New(status.Convert(err).Message())})
+	}
+	if _, err := m.capabilities.Check(ctx, setupReq); err != nil {
+		errs = append(errs, configError{field: "capabilities", err: errors.New(status.Convert(err).Message())})
+	}
+	_, parseErrs := parseSetupConfig(setupReq)
+	errs = append(errs, parseErrs...)
+	errs = append(errs, checkSockets(ctx, setupReq.GetConfig().GetSockets())...)
+
+	response := &adapterpb.ValidateConfigResponse{
+		Errors: make([]*adapterpb.ConfigError, 0, len(errs)),
+		Digest: digest,
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.proto())
+	}
+	_, response.Unchanged = m.runningImport(setupReq.GetName(), digest)
+
+	return response, nil
+}
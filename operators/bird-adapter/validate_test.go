@@ -0,0 +1,79 @@
+package bird_adapter
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	adapterpb "github.com/yanet-platform/yanet2/operators/bird-adapter/adapterpb/v1"
+)
+
+// configErrorFields returns the fields of the problems found.
+func configErrorFields(errs []configError) []string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.field)
+	}
+	return fields
+}
+
+func TestParseSetupConfig(t *testing.T) {
+	req := &adapterpb.SetupConfigRequest{
+		Name:     "route0",
+		SourceV4: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+		SourceV6: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("2001:db8::1")),
+		Config: &adapterpb.ImportConfig{
+			Sockets:  []string{"/run/bird/export.sock"},
+			Teardown: adapterpb.TeardownPolicy_TEARDOWN_POLICY_KEEP,
+		},
+	}
+	settings, errs := parseSetupConfig(req)
+	require.Empty(t, errs)
+	require.Equal(t, netip.MustParseAddr("192.0.2.1"), settings.mplsV4Src)
+	require.Equal(t, []string{"/run/bird/export.sock"}, settings.cfg.Sockets)
+	require.Nil(t, settings.kernelCfg)
+
+	// Every problem is reported at once.
+	req = &adapterpb.SetupConfigRequest{
+		Name:     "route0",
+		SourceV4: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("2001:db8::1")),
+		SourceV6: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("2001:db8::1")),
+		Config: &adapterpb.ImportConfig{
+			Sockets:            []string{"/run/bird/export.sock"},
+			NetlinkImport:      &adapterpb.NetlinkImport{},
+			Tags:               []string{""},
+			QueueHighWatermark: 10,
+			QueueLowWatermark:  20,
+		},
+	}
+	_, errs = parseSetupConfig(req)
+	require.Equal(t, []string{"source_v4", "config.tags", "config", "config.netlink_import"}, configErrorFields(errs))
+}
+
+func TestCheckSockets(t *testing.T) {
+	dir := t.TempDir()
+
+	listening := filepath.Join(dir, "listening.sock")
+	listener, err := net.Listen("unix", listening)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// A socket file left behind by a BIRD that is gone.
+	stale := filepath.Join(dir, "stale.sock")
+	staleListener, err := net.Listen("unix", stale)
+	require.NoError(t, err)
+	staleListener.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, staleListener.Close())
+
+	regular := filepath.Join(dir, "regular")
+	require.NoError(t, os.WriteFile(regular, nil, 0o600))
+
+	errs := checkSockets(t.Context(), []string{listening, stale, regular, filepath.Join(dir, "missing.sock")})
+	require.Equal(t, []string{"config.sockets[1]", "config.sockets[2]", "config.sockets[3]"}, configErrorFields(errs))
+	require.ErrorContains(t, errs[1].err, "not a Unix socket")
+}
@@ -2,6 +2,7 @@ use core::error::Error;
 
 pub fn main() -> Result<(), Box<dyn Error>> {
     println!("cargo:rerun-if-changed=../../../operators/decap/operatorpb/v1/readiness.proto");
+    println!("cargo:rerun-if-changed=../../../operators/decap/operatorpb/v1/config.proto");
     println!("cargo:rerun-if-changed=../../../common/readinesspb/v1/readiness.proto");
 
     tonic_build::configure()
@@ -9,7 +10,10 @@ pub fn main() -> Result<(), Box<dyn Error>> {
         .build_server(false)
         .extern_path(".common.readinesspb.v1", "::readinesspb::pb")
         .compile_protos(
-            &["../../../operators/decap/operatorpb/v1/readiness.proto"],
+            &[
+                "../../../operators/decap/operatorpb/v1/readiness.proto",
+                "../../../operators/decap/operatorpb/v1/config.proto",
+            ],
             &["../../../"],
         )
         .map_err(Into::into)
@@ -1,7 +1,9 @@
-//! CLI for the YANET decap operator (readiness commands).
+//! CLI for the YANET decap operator.
 //!
 //! Connects to a gRPC endpoint exposing the operator's `ReadinessService`
-//! and reports per-scope readiness state.
+//! and `ConfigService`, reporting per-scope readiness state and the drift
+//! between declared and applied module configs, and switching between the
+//! config profiles of the operator.
 
 use core::fmt::{self, Display, Formatter};
 
@@ -23,7 +25,10 @@ use ync::{
     output::{self, CommonFormat},
 };
 
-use crate::operatorpb::readiness_service_client::ReadinessServiceClient;
+use crate::operatorpb::{
+    ActivateProfileRequest, ConfigDiff, DiffRequest, ListProfilesRequest, config_service_client::ConfigServiceClient,
+    readiness_service_client::ReadinessServiceClient,
+};
 
 #[allow(clippy::all, non_snake_case)]
 pub mod operatorpb {
@@ -33,10 +38,14 @@ pub mod operatorpb {
 /// The fully-qualified gRPC service name used in error messages.
 const SERVICE_NAME: &str = "operators.decap.operatorpb.v1.ReadinessService";
 
-/// Exit code used when the RPC succeeds but not all scopes are `STATE_READY`.
+/// The fully-qualified name of the config service used in error messages.
+const CONFIG_SERVICE_NAME: &str = "operators.decap.operatorpb.v1.ConfigService";
+
+/// Exit code used when the RPC succeeds but not all scopes are `STATE_READY`,
+/// or some module configs differ from the declared ones.
 const EXIT_NOT_READY: i32 = 2;
 
-/// Decap operator CLI (readiness commands).
+/// Decap operator CLI.
 #[derive(Debug, Clone, Parser)]
 #[command(version, about)]
 #[command(flatten_help = true)]
@@ -56,6 +65,26 @@ pub struct Cmd {
 pub enum ModeCmd {
     /// Show per-scope readiness of the decap operator.
     Ready(ReadyCmd),
+    /// Show what a re-push would change in the module configs applied on
+    /// the gateways, as a unified diff.
+    Diff(DiffCmd),
+    /// Show or switch the config profile of the decap operator.
+    #[clap(subcommand)]
+    Profile(ProfileCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub enum ProfileCmd {
+    /// List the config profiles, marking the active one.
+    List,
+    /// Restart the operator with another config profile active.
+    Activate(ActivateProfileCmd),
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ActivateProfileCmd {
+    /// Name of the profile to activate.
+    pub name: String,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -64,6 +93,28 @@ pub struct ReadyCmd {
     pub scopes: Vec<String>,
 }
 
+#[derive(Debug, Clone, Parser)]
+pub struct DiffCmd {
+    /// Restrict the diff to these gateways; empty means all.
+    #[arg(long = "gateway", short)]
+    pub gateways: Vec<String>,
+    /// Compare against a local prefixes file instead of the one loaded by
+    /// the operator, given as MODULE=PATH.
+    #[arg(long = "file", short, value_name = "MODULE=PATH", value_parser = parse_declared_file)]
+    pub files: Vec<(String, Vec<u8>)>,
+}
+
+/// Parses a `MODULE=PATH` pair, reading the file at `PATH`.
+fn parse_declared_file(s: &str) -> Result<(String, Vec<u8>), String> {
+    match s.split_once('=') {
+        Some((module, path)) if !module.is_empty() && !path.is_empty() => {
+            let data = std::fs::read(path).map_err(|err| format!("failed to read prefixes file {path:?}: {err}"))?;
+            Ok((module.to_string(), data))
+        }
+        _ => Err(format!("expected MODULE=PATH, got {s:?}")),
+    }
+}
+
 #[tokio::main(flavor = "current_thread")]
 pub async fn main() {
     CompleteEnv::with_factory(Cmd::command).complete();
@@ -85,18 +136,23 @@ pub async fn main() {
 /// Run the requested subcommand.
 ///
 /// Returns `Ok(true)` when the RPC succeeded and every returned scope is
-/// `STATE_READY`, `Ok(false)` when the RPC succeeded but at least one scope
-/// is not ready, and `Err(_)` on transport or RPC failure.
+/// `STATE_READY` or every module config is in sync, `Ok(false)` when the RPC
+/// succeeded but at least one scope is not ready or one module config
+/// differs, and `Err(_)` on transport or RPC failure.
 async fn run(cmd: Cmd) -> Result<bool, Error> {
     let mut service = DecapOperatorService::new(&cmd.connection).await?;
 
     match cmd.mode {
         ModeCmd::Ready(cmd) => service.ready(cmd).await,
+        ModeCmd::Diff(cmd) => service.diff(cmd).await,
+        ModeCmd::Profile(ProfileCmd::List) => service.list_profiles().await,
+        ModeCmd::Profile(ProfileCmd::Activate(cmd)) => service.activate_profile(cmd).await,
     }
 }
 
 pub struct DecapOperatorService {
     service: Service<ReadinessServiceClient<LayeredChannel>>,
+    config: Service<ConfigServiceClient<LayeredChannel>>,
 }
 
 impl DecapOperatorService {
@@ -107,8 +163,107 @@ impl DecapOperatorService {
                 .accept_compressed(CompressionEncoding::Gzip)
         })
         .await?;
+        let config = Service::connect(connection, CONFIG_SERVICE_NAME, |channel| {
+            ConfigServiceClient::new(channel)
+                .send_compressed(CompressionEncoding::Gzip)
+                .accept_compressed(CompressionEncoding::Gzip)
+        })
+        .await?;
 
-        Ok(Self { service })
+        Ok(Self { service, config })
+    }
+
+    pub async fn diff(&mut self, cmd: DiffCmd) -> Result<bool, Error> {
+        let request = DiffRequest {
+            gateways: cmd.gateways,
+            declared: cmd.files.into_iter().collect(),
+        };
+
+        let response = self
+            .config
+            .client()
+            .diff(request)
+            .await
+            .map_err(self.config.status("diff"))?
+            .into_inner();
+
+        let entries: Vec<DiffEntry> = response.diffs.iter().map(DiffEntry::from).collect();
+        let in_sync = entries
+            .iter()
+            .all(|entry| entry.error.is_empty() && entry.diff.is_empty());
+
+        output::data(&entries, entries.is_empty(), format_args!("no module configs"), || {
+            for entry in &entries {
+                if !entry.error.is_empty() {
+                    let line = format!(
+                        "{}/{}: failed to fetch applied config: {}",
+                        entry.gateway, entry.module, entry.error
+                    );
+                    if output::is_colored() {
+                        println!("{}", line.red());
+                    } else {
+                        println!("{line}");
+                    }
+                    continue;
+                }
+                print!("{}", DiffLines(&entry.diff));
+            }
+
+            let drifted = entries
+                .iter()
+                .filter(|entry| !entry.error.is_empty() || !entry.diff.is_empty())
+                .count();
+            println!("summary: {}/{} in sync", entries.len() - drifted, entries.len());
+        });
+
+        Ok(in_sync)
+    }
+
+    pub async fn list_profiles(&mut self) -> Result<bool, Error> {
+        let response = self
+            .config
+            .client()
+            .list_profiles(ListProfilesRequest {})
+            .await
+            .map_err(self.config.status("list profiles"))?
+            .into_inner();
+
+        let profiles = ProfilesEntry {
+            profiles: response.profiles,
+            active: response.active,
+        };
+
+        output::data(
+            &profiles,
+            profiles.profiles.is_empty(),
+            format_args!("no profiles"),
+            || {
+                for name in &profiles.profiles {
+                    if *name == profiles.active {
+                        println!("* {name}");
+                    } else {
+                        println!("  {name}");
+                    }
+                }
+            },
+        );
+
+        Ok(true)
+    }
+
+    pub async fn activate_profile(&mut self, cmd: ActivateProfileCmd) -> Result<bool, Error> {
+        self.config
+            .client()
+            .activate_profile(ActivateProfileRequest { name: cmd.name.clone() })
+            .await
+            .map_err(self.config.status("activate profile"))?;
+
+        output::success(
+            "activate",
+            format_args!("Activated profile {}, the operator is restarting.", cmd.name),
+        );
+
+        Ok(true)
     }
 
     pub async fn ready(&mut self, cmd: ReadyCmd) -> Result<bool, Error> {
@@ -183,6 +338,65 @@ impl DecapOperatorService {
     }
 }
 
+/// The config profiles as printed by the `profile list` command.
+#[derive(Debug, serde::Serialize)]
+pub struct ProfilesEntry {
+    pub profiles: Vec<String>,
+    pub active: String,
+}
+
+/// A module config diff as printed by the `diff` command.
+#[derive(Debug, serde::Serialize)]
+pub struct DiffEntry {
+    pub gateway: String,
+    pub module: String,
+    pub missing: bool,
+    pub applied: String,
+    pub diff: String,
+    pub error: String,
+}
+
+impl From<&ConfigDiff> for DiffEntry {
+    fn from(diff: &ConfigDiff) -> Self {
+        Self {
+            gateway: diff.gateway.clone(),
+            module: diff.module.clone(),
+            missing: diff.missing,
+            applied: String::from_utf8_lossy(&diff.applied).into_owned(),
+            diff: diff.diff.clone(),
+            error: diff.error.clone(),
+        }
+    }
+}
+
+/// Displays a unified diff, coloring added and removed lines.
+pub struct DiffLines<'a>(&'a str);
+
+impl Display for DiffLines<'_> {
+    fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
+        let DiffLines(diff) = self;
+
+        if !output::is_colored() {
+            return write!(f, "{diff}");
+        }
+
+        for line in diff.lines() {
+            if line.starts_with("+++") || line.starts_with("---") {
+                writeln!(f, "{}", line.bold())?;
+            } else if line.starts_with('+') {
+                writeln!(f, "{}", line.green())?;
+            } else if line.starts_with('-') {
+                writeln!(f, "{}", line.red())?;
+            } else if line.starts_with("@@") {
+                writeln!(f, "{}", line.cyan())?;
+            } else {
+                writeln!(f, "{line}")?;
+            }
+        }
+        Ok(())
+    }
+}
+
 /// Wraps a readiness state for colored display in the table.
 pub struct StateCell(readinesspb::pb::State);
 
@@ -297,6 +511,13 @@ fn print_readiness_table(rows: Vec<ReadinessRow>) {
 mod test {
     use super::*;
 
+    #[test]
+    fn parse_declared_file_requires_module_and_path() {
+        assert!(parse_declared_file("decap0").is_err());
+        assert!(parse_declared_file("=/etc/decap.yaml").is_err());
+        assert!(parse_declared_file("decap0=").is_err());
+    }
+
     #[test]
     fn format_age_none_returns_dash() {
         assert_eq!("-", format_age(None));
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	"github.com/yanet-platform/yanet2/common/go/operator"
@@ -45,9 +47,13 @@ func NewGatewayActuator(
 	}
 
 	endpoint := cfg.Endpoint.Unwrap()
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS of gateway %q: %w", cfg.Name, err)
+	}
 	conn, err := grpc.NewClient(
 		endpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial gateway %q at %q: %w", cfg.Name, endpoint, err)
@@ -84,8 +90,8 @@ func NewGatewayActuator(
 	}, nil
 }
 
-// Apply pushes every module config and then ensures all function
-// definitions are correct on the gateway.
+// Apply pushes every module config that drifted from the desired state
+// and then ensures all function definitions are correct on the gateway.
 //
 // Errors from individual modules or functions are joined; the reconcile
 // loop applies backoff, so each Apply pass tries everything regardless
@@ -94,6 +100,10 @@ func (m *GatewayActuator) Apply(ctx context.Context, state State) error {
 	var err error
 
 	for _, mc := range state.Modules {
+		if m.inSync(ctx, mc) {
+			continue
+		}
+
 		_, e := m.decap.UpdateConfig(ctx, &decappb.UpdateConfigRequest{
 			Name:     mc.Name,
 			Prefixes: mc.Prefixes,
@@ -129,6 +139,61 @@ func (m *GatewayActuator) Apply(ctx context.Context, state State) error {
 	return err
 }
 
+// inSync reports whether the gateway already holds the desired prefixes
+// of the module config.
+//
+// A module config missing on the gateway, as after a module restart with
+// empty state, is out of sync. So is one the gateway fails to report: it
+// is pushed unconditionally, the same way it was before inspecting.
+func (m *GatewayActuator) inSync(ctx context.Context, mc ModuleConfig) bool {
+	actual, err := m.AppliedPrefixes(ctx, mc.Name)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		m.log.Warn("module config is missing on gateway, re-pushing",
+			zap.String("module", mc.Name),
+		)
+		return false
+	case err != nil:
+		m.log.Warn("failed to inspect module config, pushing",
+			zap.String("module", mc.Name),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	if !slices.Equal(actual, mc.Prefixes) {
+		m.log.Info("module config drifted from desired state, pushing",
+			zap.String("module", mc.Name),
+			zap.Int("actual_prefixes", len(actual)),
+			zap.Int("desired_prefixes", len(mc.Prefixes)),
+		)
+		return false
+	}
+
+	m.log.Debug("module config already in sync, skipped",
+		zap.String("module", mc.Name),
+	)
+	return true
+}
+
+// Name returns the name of the gateway.
+func (m *GatewayActuator) Name() string {
+	return m.name
+}
+
+// AppliedPrefixes returns the sorted prefixes of the module config applied
+// on the gateway.
+//
+// A module config missing on the gateway is reported as a codes.NotFound
+// error.
+func (m *GatewayActuator) AppliedPrefixes(ctx context.Context, module string) ([]string, error) {
+	resp, err := m.decap.ShowConfig(ctx, &decappb.ShowConfigRequest{Name: module})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(slices.Values(resp.GetPrefixes())), nil
+}
+
 // Close releases the underlying gRPC connection.
 func (m *GatewayActuator) Close() error {
 	return m.conn.Close()
@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	decappb "github.com/yanet-platform/yanet2/modules/decap/controlplane/decappb/v1"
+)
+
+// fakeDecapClient emulates the decap module service of a gateway.
+type fakeDecapClient struct {
+	decappb.DecapServiceClient
+
+	configs map[string][]string
+	updates int
+}
+
+func (m *fakeDecapClient) ShowConfig(
+	ctx context.Context,
+	req *decappb.ShowConfigRequest,
+	opts ...grpc.CallOption,
+) (*decappb.ShowConfigResponse, error) {
+	prefixes, ok := m.configs[req.GetName()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no config found")
+	}
+	return &decappb.ShowConfigResponse{Prefixes: prefixes}, nil
+}
+
+func (m *fakeDecapClient) UpdateConfig(
+	ctx context.Context,
+	req *decappb.UpdateConfigRequest,
+	opts ...grpc.CallOption,
+) (*decappb.UpdateConfigResponse, error) {
+	m.configs[req.GetName()] = req.GetPrefixes()
+	m.updates++
+	return &decappb.UpdateConfigResponse{}, nil
+}
+
+func TestGatewayActuator_ApplyDrift(t *testing.T) {
+	client := &fakeDecapClient{configs: map[string][]string{}}
+	actuator := &GatewayActuator{
+		name:  "numa0",
+		decap: client,
+		log:   zap.NewNop(),
+	}
+	state := State{Modules: []ModuleConfig{
+		{Name: "decap0", Prefixes: []string{"10.0.0.0/8", "2000::/3"}},
+	}}
+
+	// The module config is missing, so it is pushed.
+	require.NoError(t, actuator.Apply(t.Context(), state))
+	require.Equal(t, 1, client.updates)
+
+	// The gateway already holds the desired prefixes.
+	require.NoError(t, actuator.Apply(t.Context(), state))
+	require.Equal(t, 1, client.updates)
+
+	// A module restarted with empty state is re-pushed.
+	delete(client.configs, "decap0")
+	require.NoError(t, actuator.Apply(t.Context(), state))
+	require.Equal(t, 2, client.updates)
+
+	// So is one changed behind the operator's back.
+	client.configs["decap0"] = []string{"10.0.0.0/8"}
+	require.NoError(t, actuator.Apply(t.Context(), state))
+	require.Equal(t, 3, client.updates)
+	require.Equal(t, state.Modules[0].Prefixes, client.configs["decap0"])
+}
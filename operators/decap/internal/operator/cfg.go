@@ -3,6 +3,9 @@ package operator
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
@@ -20,6 +23,44 @@ type Config struct {
 	Register  operator.RegisterConfig    `yaml:"register"`
 	Reconcile operator.ReconcileConfig   `yaml:"reconcile"`
 	Functions []FunctionConfig           `yaml:"functions"`
+	Watch     WatchConfig                `yaml:"watch"`
+	// Profile is the name of the active profile, empty to run the base
+	// config as is.
+	//
+	// The --profile flag and the ConfigService.ActivateProfile RPC override
+	// it.
+	Profile string `yaml:"profile"`
+	// Profiles are the environments (e.g. "prod", "staging") kept in the
+	// same config file, keyed by name.
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+}
+
+// ProfileConfig overrides parts of the base config while its profile is
+// active.
+//
+// Omitted fields keep their base value.
+type ProfileConfig struct {
+	// Server overrides the operator gRPC server endpoint.
+	Server *operator.GRPCServerConfig `yaml:"server"`
+	// Gateways replace the base gateways.
+	Gateways []operator.GatewayConfig `yaml:"gateways"`
+	// Functions replace the base functions with their module configs.
+	Functions []FunctionConfig `yaml:"functions"`
+}
+
+// defaultWatchInterval is the default period between rereads of the
+// prefixes files.
+const defaultWatchInterval = 5 * time.Second
+
+// WatchConfig controls how the operator follows the desired state kept in
+// the prefixes files.
+type WatchConfig struct {
+	// Interval is the period between rereads of the prefixes files.
+	//
+	// Changed files are reconciled right away instead of on the next
+	// reconcile pass. Zero disables rereading: the files are loaded once
+	// at startup.
+	Interval time.Duration `yaml:"interval"`
 }
 
 // Default resets the config to built-in defaults.
@@ -33,14 +74,72 @@ func (m *Config) LoggingConfig() *logging.Config {
 	return &m.Logging
 }
 
-// Validate checks that the config is structurally sound.
+// SelectProfile activates the named profile.
+func (m *Config) SelectProfile(name string) error {
+	if _, ok := m.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	m.Profile = name
+	return nil
+}
+
+// Resolve returns a copy of the config with the active profile applied.
+func (m *Config) Resolve() *Config {
+	cfg := *m
+
+	profile, ok := m.Profiles[m.Profile]
+	if !ok {
+		return &cfg
+	}
+	if profile.Server != nil {
+		cfg.Server = profile.Server
+	}
+	if len(profile.Gateways) > 0 {
+		cfg.Gateways = profile.Gateways
+	}
+	if len(profile.Functions) > 0 {
+		cfg.Functions = profile.Functions
+	}
+
+	return &cfg
+}
+
+// Validate checks that the config is structurally sound with the active
+// profile and with each of the others, so that any of them can be
+// activated at runtime.
 func (m *Config) Validate() error {
+	if m.Profile != "" {
+		if _, ok := m.Profiles[m.Profile]; !ok {
+			return fmt.Errorf("unknown profile %q", m.Profile)
+		}
+	}
+	if err := m.Resolve().validateResolved(); err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.Profiles)) {
+		cfg := *m
+		cfg.Profile = name
+		if err := cfg.Resolve().validateResolved(); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateResolved checks a config with its profile already applied.
+func (m *Config) validateResolved() error {
 	if len(m.Gateways) == 0 {
 		return errors.New("at least one gateway must be configured")
 	}
 	if len(m.Functions) == 0 {
 		return errors.New("at least one function must be configured")
 	}
+	if m.Watch.Interval < 0 {
+		return errors.New("watch interval must not be negative")
+	}
 
 	gatewayNames := map[string]struct{}{}
 	for idx, gw := range m.Gateways {
@@ -87,6 +186,9 @@ func DefaultConfig() *Config {
 			Interval: xcfg.MustNonZero(operator.DefaultRegisterInterval),
 		},
 		Functions: []FunctionConfig{},
+		Watch: WatchConfig{
+			Interval: defaultWatchInterval,
+		},
 	}
 }
 
@@ -156,6 +156,26 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unknown active profile",
+			build: func() *Config {
+				cfg := validConfig()
+				cfg.Profile = "staging"
+				return cfg
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid profile",
+			build: func() *Config {
+				cfg := validConfig()
+				cfg.Profiles = map[string]ProfileConfig{
+					"staging": {Functions: append(validConfig().Functions, validConfig().Functions...)},
+				}
+				return cfg
+			},
+			wantErr: true,
+		},
 		{
 			name: "duplicate gateway name",
 			build: func() *Config {
@@ -181,3 +201,25 @@ func TestConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigResolve(t *testing.T) {
+	staging := []operator.GatewayConfig{
+		{Name: "staging0", Endpoint: xcfg.MustNonEmptyString("[::1]:9080")},
+	}
+	cfg := validConfig()
+	cfg.Profiles = map[string]ProfileConfig{
+		"prod":    {},
+		"staging": {Gateways: staging},
+	}
+	require.NoError(t, cfg.Validate())
+
+	// The base config runs as is without an active profile.
+	require.Equal(t, cfg.Gateways, cfg.Resolve().Gateways)
+
+	require.Error(t, cfg.SelectProfile("dev"))
+	require.NoError(t, cfg.SelectProfile("staging"))
+	resolved := cfg.Resolve()
+	require.Equal(t, staging, resolved.Gateways)
+	require.Equal(t, cfg.Functions, resolved.Functions)
+	require.Equal(t, "numa0", cfg.Gateways[0].Name)
+}
@@ -0,0 +1,176 @@
+package operator
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	operatorpb "github.com/yanet-platform/yanet2/operators/decap/operatorpb/v1"
+)
+
+// ConfigInspector fetches the module configs applied on a single gateway.
+type ConfigInspector interface {
+	// Name returns the name of the gateway.
+	Name() string
+	// AppliedPrefixes returns the sorted prefixes of the module config
+	// applied on the gateway, or a codes.NotFound error if the gateway has
+	// no such module config.
+	AppliedPrefixes(ctx context.Context, module string) ([]string, error)
+}
+
+// ConfigService implements operatorpb.ConfigServiceServer, comparing the
+// module configs declared in the prefixes files with the ones applied on
+// the gateways.
+type ConfigService struct {
+	operatorpb.UnimplementedConfigServiceServer
+
+	source   *FileSource
+	gateways []ConfigInspector
+	profiles *Profiles
+}
+
+// NewConfigService constructs a ConfigService comparing the desired state
+// of the source with the given gateways and switching between the given
+// profiles.
+func NewConfigService(source *FileSource, gateways []ConfigInspector, profiles *Profiles) *ConfigService {
+	return &ConfigService{
+		source:   source,
+		gateways: gateways,
+		profiles: profiles,
+	}
+}
+
+// ListProfiles returns the config profiles and the active one.
+func (m *ConfigService) ListProfiles(
+	ctx context.Context,
+	req *operatorpb.ListProfilesRequest,
+) (*operatorpb.ListProfilesResponse, error) {
+	return &operatorpb.ListProfilesResponse{
+		Profiles: m.profiles.Names(),
+		Active:   m.profiles.Active(),
+	}, nil
+}
+
+// ActivateProfile requests a restart of the operator with the given
+// profile active.
+func (m *ConfigService) ActivateProfile(
+	ctx context.Context,
+	req *operatorpb.ActivateProfileRequest,
+) (*operatorpb.ActivateProfileResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "profile name is required")
+	}
+	if err := m.profiles.Activate(req.GetName()); err != nil {
+		return nil, err
+	}
+
+	return &operatorpb.ActivateProfileResponse{}, nil
+}
+
+// Diff renders both sides of every module config in the prefixes file
+// format and diffs them, so the output reads the same as an edit of the
+// prefixes file would.
+func (m *ConfigService) Diff(
+	ctx context.Context,
+	req *operatorpb.DiffRequest,
+) (*operatorpb.DiffResponse, error) {
+	gateways, err := m.diffGateways(req.GetGateways())
+	if err != nil {
+		return nil, err
+	}
+
+	state, _ := m.source.Snapshot()
+	declared := map[string][]byte{}
+	for _, mc := range state.Modules {
+		declared[mc.Name] = FormatDecapPrefixes(mc.Prefixes)
+	}
+	for name, data := range req.GetDeclared() {
+		if _, ok := declared[name]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown module config %q", name)
+		}
+		prefixes, err := ParseDecapPrefixes(data)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to parse declared module config %q: %v", name, err)
+		}
+		declared[name] = FormatDecapPrefixes(prefixes)
+	}
+	modules := slices.Sorted(maps.Keys(declared))
+
+	diffs := make([]*operatorpb.ConfigDiff, 0, len(gateways)*len(modules))
+	for _, gw := range gateways {
+		for _, module := range modules {
+			diff := &operatorpb.ConfigDiff{
+				Gateway: gw.Name(),
+				Module:  module,
+			}
+			diffs = append(diffs, diff)
+
+			prefixes, err := gw.AppliedPrefixes(ctx, module)
+			switch {
+			case status.Code(err) == codes.NotFound:
+				diff.Missing = true
+			case err != nil:
+				diff.Error = err.Error()
+				continue
+			default:
+				diff.Applied = FormatDecapPrefixes(prefixes)
+			}
+
+			text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        splitLines(diff.Applied),
+				B:        splitLines(declared[module]),
+				FromFile: gw.Name() + "/" + module,
+				ToFile:   "declared/" + module,
+				Context:  3,
+			})
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to diff module config %q: %v", module, err)
+			}
+			diff.Diff = text
+		}
+	}
+
+	return &operatorpb.DiffResponse{Diffs: diffs}, nil
+}
+
+// diffGateways returns the gateways with the given names, or all of them
+// if no names are given.
+func (m *ConfigService) diffGateways(names []string) ([]ConfigInspector, error) {
+	if len(names) == 0 {
+		return m.gateways, nil
+	}
+
+	gateways := make([]ConfigInspector, 0, len(names))
+	for _, name := range names {
+		idx := slices.IndexFunc(m.gateways, func(gw ConfigInspector) bool {
+			return gw.Name() == name
+		})
+		if idx < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown gateway %q", name)
+		}
+		if slices.Contains(gateways, m.gateways[idx]) {
+			continue
+		}
+		gateways = append(gateways, m.gateways[idx])
+	}
+
+	return gateways, nil
+}
+
+// splitLines splits text into lines keeping their terminators, as the
+// unified diff expects.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/operator"
+	"github.com/yanet-platform/yanet2/common/go/xcfg"
+	operatorpb "github.com/yanet-platform/yanet2/operators/decap/operatorpb/v1"
+)
+
+func TestConfigService_Diff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("prefixes: [2000::/3, 10.0.0.0/8]\n"), 0o644))
+
+	source, err := NewFileSource([]FunctionConfig{
+		{
+			Name:         xcfg.MustNonEmptyString("fn:decap"),
+			Chain:        xcfg.MustNonEmptyString("default"),
+			Module:       xcfg.MustNonEmptyString("decap0"),
+			PrefixesFile: xcfg.MustNonEmptyString(path),
+		},
+	}, WithSourceLog(zap.NewNop()))
+	require.NoError(t, err)
+
+	synced := &GatewayActuator{
+		name:  "numa0",
+		decap: &fakeDecapClient{configs: map[string][]string{"decap0": {"10.0.0.0/8", "2000::/3"}}},
+		log:   zap.NewNop(),
+	}
+	drifted := &GatewayActuator{
+		name:  "numa1",
+		decap: &fakeDecapClient{configs: map[string][]string{"decap0": {"10.0.0.0/8", "192.0.2.0/24"}}},
+		log:   zap.NewNop(),
+	}
+	empty := &GatewayActuator{
+		name:  "numa2",
+		decap: &fakeDecapClient{configs: map[string][]string{}},
+		log:   zap.NewNop(),
+	}
+	svc := NewConfigService(source, []ConfigInspector{synced, drifted, empty}, NewProfiles(&Config{}))
+
+	resp, err := svc.Diff(t.Context(), &operatorpb.DiffRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Diffs, 3)
+
+	require.Equal(t, "numa0", resp.Diffs[0].Gateway)
+	require.Equal(t, "decap0", resp.Diffs[0].Module)
+	require.Equal(t, "prefixes:\n  - 10.0.0.0/8\n  - 2000::/3\n", string(resp.Diffs[0].Applied))
+	require.Empty(t, resp.Diffs[0].Diff)
+
+	require.Equal(t, "--- numa1/decap0\n"+
+		"+++ declared/decap0\n"+
+		"@@ -1,3 +1,3 @@\n"+
+		" prefixes:\n"+
+		"   - 10.0.0.0/8\n"+
+		"-  - 192.0.2.0/24\n"+
+		"+  - 2000::/3\n", resp.Diffs[1].Diff)
+
+	require.True(t, resp.Diffs[2].Missing)
+	require.Empty(t, resp.Diffs[2].Applied)
+	require.Contains(t, resp.Diffs[2].Diff, "+  - 2000::/3\n")
+
+	// A local file overrides the loaded one.
+	resp, err = svc.Diff(t.Context(), &operatorpb.DiffRequest{
+		Gateways: []string{"numa1"},
+		Declared: map[string][]byte{"decap0": []byte("prefixes: [192.0.2.7/24, 10.0.0.0/8]\n")},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Diffs, 1)
+	require.Empty(t, resp.Diffs[0].Diff)
+
+	for _, req := range []*operatorpb.DiffRequest{
+		{Gateways: []string{"numa3"}},
+		{Declared: map[string][]byte{"decap1": []byte("prefixes: []\n")}},
+		{Declared: map[string][]byte{"decap0": []byte("prefixes: [bad]\n")}},
+	} {
+		_, err := svc.Diff(t.Context(), req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestConfigService_Profiles(t *testing.T) {
+	cfg := validConfig()
+	cfg.Profile = "prod"
+	cfg.Profiles = map[string]ProfileConfig{"staging": {}, "prod": {}}
+	profiles := NewProfiles(cfg)
+	svc := NewConfigService(nil, nil, profiles)
+
+	resp, err := svc.ListProfiles(t.Context(), &operatorpb.ListProfilesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"prod", "staging"}, resp.GetProfiles())
+	require.Equal(t, "prod", resp.GetActive())
+
+	// The active profile needs no restart.
+	_, err = svc.ActivateProfile(t.Context(), &operatorpb.ActivateProfileRequest{Name: "prod"})
+	require.NoError(t, err)
+
+	_, err = svc.ActivateProfile(t.Context(), &operatorpb.ActivateProfileRequest{Name: "staging"})
+	require.NoError(t, err)
+	_, err = svc.ActivateProfile(t.Context(), &operatorpb.ActivateProfileRequest{Name: "staging"})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	var profileSwitch operator.ProfileSwitch
+	require.ErrorAs(t, profiles.Run(t.Context()), &profileSwitch)
+	require.Equal(t, "staging", profileSwitch.Profile)
+
+	_, err = svc.ActivateProfile(t.Context(), &operatorpb.ActivateProfileRequest{Name: "dev"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.ActivateProfile(t.Context(), &operatorpb.ActivateProfileRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -28,17 +28,16 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 	}
 
 	log := opts.Log
+	profiles := NewProfiles(cfg)
+	cfg = cfg.Resolve()
 
-	modules := make([]ModuleConfig, 0, len(cfg.Functions))
-	for _, fn := range cfg.Functions {
-		prefixes, err := LoadDecapPrefixes(fn.PrefixesFile.Unwrap())
-		if err != nil {
-			return nil, fmt.Errorf("failed to load prefixes file %q: %w", fn.PrefixesFile.Unwrap(), err)
-		}
-		modules = append(modules, ModuleConfig{
-			Name:     fn.Module.Unwrap(),
-			Prefixes: prefixes,
-		})
+	source, err := NewFileSource(
+		cfg.Functions,
+		WithSourceInterval(cfg.Watch.Interval),
+		WithSourceLog(log),
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	// One config:<gateway> scope per gateway, covering all module configs pushed there.
@@ -51,6 +50,7 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 	)
 
 	actuators := make([]operator.Actuator[State], 0, len(cfg.Gateways))
+	inspectors := make([]ConfigInspector, 0, len(cfg.Gateways))
 	for _, gw := range cfg.Gateways {
 		actuator, err := NewGatewayActuator(gw, cfg.Functions, WithGatewayActuatorLog(log))
 		if err != nil {
@@ -61,30 +61,44 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		}
 		observed := operator.NewObservedActuator(actuator, fmt.Sprintf("config:%s", gw.Name), tracker.Observe)
 		actuators = append(actuators, observed)
+		inspectors = append(inspectors, actuator)
 	}
 
 	fanOut := operator.NewFanOutActuator(actuators, operator.WithFanOutLog(log))
-	source := NewStaticSource(modules, WithSourceLog(log))
 
-	svc := NewReadinessService(tracker)
-	registrar := func(s *grpc.Server) string {
-		operatorpb.RegisterReadinessServiceServer(s, svc)
-		return operatorpb.ReadinessService_ServiceDesc.ServiceName
+	readinessSvc := NewReadinessService(tracker)
+	configSvc := NewConfigService(source, inspectors, profiles)
+	services := []operator.ServiceRegistrar{
+		func(s *grpc.Server) string {
+			operatorpb.RegisterReadinessServiceServer(s, readinessSvc)
+			return operatorpb.ReadinessService_ServiceDesc.ServiceName
+		},
+		func(s *grpc.Server) string {
+			operatorpb.RegisterConfigServiceServer(s, configSvc)
+			return operatorpb.ConfigService_ServiceDesc.ServiceName
+		},
 	}
 
-	app := operator.NewOperator(
+	app, err := operator.NewOperator(
 		fanOut,
 		source,
-		operator.WithGRPCServer(cfg.Server, registrar),
+		operator.WithGRPCServer(cfg.Server, services...),
 		operator.WithGateways(cfg.Register, cfg.Gateways...),
-		operator.WithWorkers(func(ctx context.Context) error {
-			<-ctx.Done()
-			tracker.Drain()
-			return nil
-		}),
+		operator.WithWorkers(
+			func(ctx context.Context) error {
+				<-ctx.Done()
+				tracker.Drain()
+				return nil
+			},
+			source.Run,
+			profiles.Run,
+		),
 		operator.WithLog(log),
 		operator.WithReconcile(cfg.Reconcile),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operator: %w", err)
+	}
 
 	return &Operator{
 		cfg: cfg,
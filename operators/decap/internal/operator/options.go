@@ -1,6 +1,10 @@
 package operator
 
-import "go.uber.org/zap"
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
 
 // options holds operator-level configuration.
 type options struct {
@@ -44,23 +48,32 @@ func WithGatewayActuatorLog(log *zap.Logger) GatewayActuatorOption {
 	}
 }
 
-// staticSourceOptions holds configuration for the staticSource.
-type staticSourceOptions struct {
-	Log *zap.Logger
+// fileSourceOptions holds configuration for the FileSource.
+type fileSourceOptions struct {
+	Interval time.Duration
+	Log      *zap.Logger
 }
 
-func newStaticSourceOptions() *staticSourceOptions {
-	return &staticSourceOptions{
+func newFileSourceOptions() *fileSourceOptions {
+	return &fileSourceOptions{
 		Log: zap.NewNop(),
 	}
 }
 
-// StaticSourceOption configures NewStaticSource.
-type StaticSourceOption func(*staticSourceOptions)
+// FileSourceOption configures NewFileSource.
+type FileSourceOption func(*fileSourceOptions)
+
+// WithSourceInterval sets the period between rereads of the prefixes
+// files. Zero, the default, makes Run a no-op.
+func WithSourceInterval(interval time.Duration) FileSourceOption {
+	return func(o *fileSourceOptions) {
+		o.Interval = interval
+	}
+}
 
-// WithSourceLog sets the logger for the staticSource.
-func WithSourceLog(log *zap.Logger) StaticSourceOption {
-	return func(o *staticSourceOptions) {
+// WithSourceLog sets the logger for the FileSource.
+func WithSourceLog(log *zap.Logger) FileSourceOption {
+	return func(o *fileSourceOptions) {
 		o.Log = log
 	}
 }
@@ -1,9 +1,11 @@
 package operator
 
 import (
+	"bytes"
 	"fmt"
 	"net/netip"
 	"os"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,16 +22,28 @@ type yamlPrefixFile struct {
 //	  - 2000::/3
 //
 // Each entry is parsed with netip.ParsePrefix to fail fast on malformed
-// input. The returned slice contains canonical Masked().String() forms.
+// input. The returned slice contains canonical Masked().String() forms,
+// sorted and deduplicated so that prefix sets compare equal regardless of
+// the file order.
 func LoadDecapPrefixes(path string) ([]string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read prefixes file %q: %w", path, err)
 	}
 
+	prefixes, err := ParseDecapPrefixes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prefixes file %q: %w", path, err)
+	}
+	return prefixes, nil
+}
+
+// ParseDecapPrefixes parses the contents of a prefixes file, see
+// LoadDecapPrefixes.
+func ParseDecapPrefixes(data []byte) ([]string, error) {
 	var file yamlPrefixFile
 	if err := yaml.Unmarshal(data, &file); err != nil {
-		return nil, fmt.Errorf("failed to parse prefixes file %q: %w", path, err)
+		return nil, err
 	}
 
 	out := make([]string, 0, len(file.Prefixes))
@@ -40,5 +54,23 @@ func LoadDecapPrefixes(path string) ([]string, error) {
 		}
 		out = append(out, prefix.Masked().String())
 	}
-	return out, nil
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// FormatDecapPrefixes renders a prefix set as a prefixes file, one prefix
+// per line, sorted, so that equal sets render to equal bytes.
+func FormatDecapPrefixes(prefixes []string) []byte {
+	prefixes = slices.Compact(slices.Sorted(slices.Values(prefixes)))
+
+	buf := bytes.Buffer{}
+	if len(prefixes) == 0 {
+		buf.WriteString("prefixes: []\n")
+		return buf.Bytes()
+	}
+	buf.WriteString("prefixes:\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(&buf, "  - %s\n", prefix)
+	}
+	return buf.Bytes()
 }
@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/operator"
+)
+
+// Profiles tracks the config profiles the operator can switch between.
+//
+// Activating a profile makes Run return operator.ProfileSwitch, which
+// restarts the whole operator from the reloaded config.
+type Profiles struct {
+	names    []string
+	active   string
+	switched chan string
+}
+
+// NewProfiles constructs Profiles listing the profiles of cfg.
+func NewProfiles(cfg *Config) *Profiles {
+	return &Profiles{
+		names:    slices.Sorted(maps.Keys(cfg.Profiles)),
+		active:   cfg.Profile,
+		switched: make(chan string, 1),
+	}
+}
+
+// Names returns the sorted profile names.
+func (m *Profiles) Names() []string {
+	return m.names
+}
+
+// Active returns the name of the active profile.
+func (m *Profiles) Active() string {
+	return m.active
+}
+
+// Activate requests a restart with the named profile active.
+//
+// Activating the already active profile is a no-op.
+func (m *Profiles) Activate(name string) error {
+	if !slices.Contains(m.names, name) {
+		return status.Errorf(codes.NotFound, "unknown profile %q", name)
+	}
+	if name == m.active {
+		return nil
+	}
+
+	select {
+	case m.switched <- name:
+		return nil
+	default:
+		return status.Error(codes.Aborted, "another profile switch is in progress")
+	}
+}
+
+// Run waits for a profile activation until the context is cancelled.
+func (m *Profiles) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case name := <-m.switched:
+		return operator.ProfileSwitch{Profile: name}
+	}
+}
@@ -1,9 +1,13 @@
 package operator
 
 import (
-	"go.uber.org/zap"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
-	"github.com/yanet-platform/yanet2/common/go/operator"
+	"go.uber.org/zap"
 )
 
 // ModuleConfig holds the desired prefix set for one decap module config.
@@ -17,42 +21,141 @@ type State struct {
 	Modules []ModuleConfig
 }
 
-// staticSource is a StateSource that holds a fixed set of module configs
-// loaded once at construction time. Snapshot always returns the same
-// slice. Wake is never signalled — the reconcile interval is the sole
-// pacing mechanism.
-type staticSource struct {
+// FileSource is a StateSource that follows the desired module configs
+// kept in the prefixes files of the configured functions.
+//
+// Run rereads the files periodically and wakes the Reconciler once their
+// contents change, so edits are applied without waiting for the reconcile
+// interval. A file that fails to load keeps its previously loaded
+// prefixes: a half-written edit never withdraws prefixes already
+// installed.
+type FileSource struct {
+	functions []FunctionConfig
+	interval  time.Duration
+
+	mu      sync.Mutex
 	modules []ModuleConfig
-	wake    chan struct{}
-	log     *zap.Logger
+
+	wake chan struct{}
+	log  *zap.Logger
 }
 
-// NewStaticSource constructs a staticSource holding the supplied module
-// configs. The slice is not copied; callers must not modify it after
-// passing it in.
-func NewStaticSource(modules []ModuleConfig, options ...StaticSourceOption) operator.StateSource[State] {
-	opts := newStaticSourceOptions()
+// NewFileSource constructs a FileSource, loading the prefixes file of
+// every function.
+//
+// Unlike the periodic rereads, a failure to load any file at construction
+// time is returned as an error.
+func NewFileSource(functions []FunctionConfig, options ...FileSourceOption) (*FileSource, error) {
+	opts := newFileSourceOptions()
 	for _, o := range options {
 		o(opts)
 	}
-	return &staticSource{
-		modules: modules,
-		wake:    make(chan struct{}),
-		log:     opts.Log,
+
+	modules := make([]ModuleConfig, 0, len(functions))
+	for _, fn := range functions {
+		prefixes, err := LoadDecapPrefixes(fn.PrefixesFile.Unwrap())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prefixes file %q: %w", fn.PrefixesFile.Unwrap(), err)
+		}
+		modules = append(modules, ModuleConfig{
+			Name:     fn.Module.Unwrap(),
+			Prefixes: prefixes,
+		})
 	}
+
+	return &FileSource{
+		functions: functions,
+		interval:  opts.Interval,
+		modules:   modules,
+		wake:      make(chan struct{}, 1),
+		log:       opts.Log,
+	}, nil
 }
 
-// Snapshot returns the fixed module configs as the current desired state.
-func (m *staticSource) Snapshot() (State, bool) {
+// Snapshot returns the last loaded module configs as the current desired
+// state.
+func (m *FileSource) Snapshot() (State, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return State{Modules: m.modules}, true
 }
 
 // Wake returns the channel the Reconciler monitors for eager wakeups.
 //
-// staticSource never signals it; the reconcile interval is the sole
-// pacing mechanism.
-func (m *staticSource) Wake() <-chan struct{} { return m.wake }
+// It is signalled whenever a reread changes the desired state.
+func (m *FileSource) Wake() <-chan struct{} { return m.wake }
+
+// Advance is a no-op: every pass pushes the full desired state.
+func (m *FileSource) Advance(_ State) {}
+
+// Run rereads the prefixes files every interval until the context is
+// cancelled.
+func (m *FileSource) Run(ctx context.Context) error {
+	if m.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
 
-// Advance is a no-op: the module configs are fixed for the lifetime of
-// the source.
-func (m *staticSource) Advance(_ State) {}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.Reload()
+		}
+	}
+}
+
+// Reload rereads the prefixes files, reporting whether the desired state
+// changed.
+func (m *FileSource) Reload() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	modules := make([]ModuleConfig, 0, len(m.functions))
+	changed := false
+	for idx, fn := range m.functions {
+		current := m.modules[idx]
+
+		path := fn.PrefixesFile.Unwrap()
+		prefixes, err := LoadDecapPrefixes(path)
+		if err != nil {
+			m.log.Warn("failed to reload prefixes file, keeping previous prefixes",
+				zap.String("module", current.Name),
+				zap.String("path", path),
+				zap.Error(err),
+			)
+			modules = append(modules, current)
+			continue
+		}
+
+		if !slices.Equal(prefixes, current.Prefixes) {
+			m.log.Info("reloaded changed prefixes file",
+				zap.String("module", current.Name),
+				zap.String("path", path),
+				zap.Int("prefixes", len(prefixes)),
+			)
+			changed = true
+		}
+		modules = append(modules, ModuleConfig{
+			Name:     current.Name,
+			Prefixes: prefixes,
+		})
+	}
+
+	if !changed {
+		return false
+	}
+	// Snapshots handed out earlier keep referencing the old slice.
+	m.modules = modules
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package operator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/common/go/xcfg"
+)
+
+func TestFileSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yaml")
+	writePrefixes := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	writePrefixes("prefixes: [2000::/3, 10.0.0.0/8]\n")
+
+	source, err := NewFileSource([]FunctionConfig{
+		{
+			Name:         xcfg.MustNonEmptyString("fn:decap"),
+			Chain:        xcfg.MustNonEmptyString("default"),
+			Module:       xcfg.MustNonEmptyString("decap0"),
+			PrefixesFile: xcfg.MustNonEmptyString(path),
+		},
+	}, WithSourceLog(zap.NewNop()))
+	require.NoError(t, err)
+
+	state, ok := source.Snapshot()
+	require.True(t, ok)
+	require.Equal(t, []ModuleConfig{
+		{Name: "decap0", Prefixes: []string{"10.0.0.0/8", "2000::/3"}},
+	}, state.Modules)
+
+	// Reordering prefixes does not change the desired state.
+	writePrefixes("prefixes: [10.0.0.0/8, 2000::/3, 10.0.0.0/8]\n")
+	require.False(t, source.Reload())
+	require.Empty(t, source.Wake())
+
+	writePrefixes("prefixes: [10.0.0.0/8]\n")
+	require.True(t, source.Reload())
+	require.Len(t, source.Wake(), 1)
+
+	// A broken file keeps the previous prefixes.
+	writePrefixes("prefixes: [not-a-prefix]\n")
+	require.False(t, source.Reload())
+
+	state, _ = source.Snapshot()
+	require.Equal(t, []string{"10.0.0.0/8"}, state.Modules[0].Prefixes)
+}
+
+func TestFileSource_MissingFile(t *testing.T) {
+	_, err := NewFileSource([]FunctionConfig{
+		{
+			Name:         xcfg.MustNonEmptyString("fn:decap"),
+			Chain:        xcfg.MustNonEmptyString("default"),
+			Module:       xcfg.MustNonEmptyString("decap0"),
+			PrefixesFile: xcfg.MustNonEmptyString(filepath.Join(t.TempDir(), "missing.yaml")),
+		},
+	})
+	require.Error(t, err)
+}
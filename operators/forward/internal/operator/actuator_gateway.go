@@ -7,7 +7,6 @@ import (
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	"github.com/yanet-platform/yanet2/common/go/operator"
@@ -45,9 +44,13 @@ func NewGatewayActuator(
 	}
 
 	endpoint := cfg.Endpoint.Unwrap()
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS of gateway %q: %w", cfg.Name, err)
+	}
 	conn, err := grpc.NewClient(
 		endpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial gateway %q at %q: %w", cfg.Name, endpoint, err)
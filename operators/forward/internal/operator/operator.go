@@ -72,7 +72,7 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		return operatorpb.ReadinessService_ServiceDesc.ServiceName
 	}
 
-	app := operator.NewOperator(
+	app, err := operator.NewOperator(
 		fanOut,
 		source,
 		operator.WithGRPCServer(cfg.Server, registrar),
@@ -85,6 +85,9 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		operator.WithLog(log),
 		operator.WithReconcile(cfg.Reconcile),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operator: %w", err)
+	}
 
 	return &Operator{
 		cfg: cfg,
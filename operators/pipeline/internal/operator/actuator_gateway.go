@@ -6,7 +6,6 @@ import (
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/yanet-platform/yanet2/common/go/operator"
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
@@ -47,9 +46,13 @@ func NewGatewayActuator(
 	}
 
 	endpoint := cfg.Endpoint.Unwrap()
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS of gateway %q: %w", cfg.Name, err)
+	}
 	conn, err := grpc.NewClient(
 		endpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial gateway %q at %q: %w", cfg.Name, endpoint, err)
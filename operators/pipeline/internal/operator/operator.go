@@ -95,7 +95,7 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		},
 	}
 
-	app := operator.NewOperator(
+	app, err := operator.NewOperator(
 		fanOut,
 		source,
 		operator.WithGRPCServer(cfg.Server, services...),
@@ -122,6 +122,9 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		operator.WithMetrics(metrics),
 		operator.WithLog(log),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operator: %w", err)
+	}
 
 	return &Operator{
 		app: app,
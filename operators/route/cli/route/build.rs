@@ -3,6 +3,7 @@ use core::error::Error;
 pub fn main() -> Result<(), Box<dyn Error>> {
     println!("cargo:rerun-if-changed=../../operatorpb/v1/route.proto");
     println!("cargo:rerun-if-changed=../../operatorpb/v1/readiness.proto");
+    println!("cargo:rerun-if-changed=../../operatorpb/v1/operator.proto");
 
     tonic_build::configure()
         .emit_rerun_if_changed(false)
@@ -20,12 +21,25 @@ pub fn main() -> Result<(), Box<dyn Error>> {
             ".operators.route.operatorpb.v1.Route.peer",
             "#[serde(serialize_with = \"crate::serialize_ip_addr\")]",
         )
+        .field_attribute(
+            ".operators.route.operatorpb.v1.RouteEvent.kind",
+            "#[serde(serialize_with = \"crate::serialize_route_event_kind\")]",
+        )
+        .field_attribute(
+            ".operators.route.operatorpb.v1.RouteEvent.timestamp",
+            "#[serde(serialize_with = \"crate::serialize_timestamp\")]",
+        )
+        .field_attribute(
+            ".operators.route.operatorpb.v1.FlushResult.duration",
+            "#[serde(serialize_with = \"crate::serialize_duration\")]",
+        )
         .extern_path(".common.commonpb.v1", "::commonpb::pb")
         .extern_path(".common.readinesspb.v1", "::readinesspb::pb")
         .compile_protos(
             &[
                 "../../../../operators/route/operatorpb/v1/route.proto",
                 "../../../../operators/route/operatorpb/v1/readiness.proto",
+                "../../../../operators/route/operatorpb/v1/operator.proto",
             ],
             &["../../../../"],
         )
@@ -3,6 +3,9 @@
 //! Connects to a gRPC endpoint exposing the operator's `RouteService`
 //! (the operator process directly, or the gateway once registration
 //! has propagated) and drives the operator-owned RIB.
+//!
+//! The `resync` command talks to the operator's `RouteOperatorService`
+//! instead.
 
 use core::{
     fmt::{self, Display, Formatter},
@@ -30,8 +33,11 @@ use ync::{
 };
 
 use crate::operatorpb::{
-    DeleteRouteRequest, FlushRoutesRequest, InsertRouteRequest, ListConfigsRequest, LookupRouteRequest, RouteSourceId,
-    ShowRoutesRequest, readiness_service_client::ReadinessServiceClient, route_service_client::RouteServiceClient,
+    DeleteRouteRequest, DeleteRoutesByFilterRequest, ExportRibRequest, FlushRoutesRequest, ImportRibRequest,
+    InsertRouteRequest, ListConfigsRequest,
+    ListRouteTagsRequest, LookupRouteRequest, MonitorRoutesRequest, ResyncRequest, RouteEventKind, RouteFilter,
+    RouteSourceId, SetRoutesPrefByFilterRequest, ShowRoutesRequest, SimulateRequest, readiness_service_client::ReadinessServiceClient,
+    route_operator_service_client::RouteOperatorServiceClient, route_service_client::RouteServiceClient,
 };
 
 #[allow(clippy::all, non_snake_case)]
@@ -45,6 +51,9 @@ const SERVICE_NAME: &str = "operators.route.operatorpb.v1.RouteService";
 /// The fully-qualified gRPC readiness service name used in error messages.
 const READINESS_SERVICE_NAME: &str = "operators.route.operatorpb.v1.ReadinessService";
 
+/// The fully-qualified gRPC operator service name used in error messages.
+const OPERATOR_SERVICE_NAME: &str = "operators.route.operatorpb.v1.RouteOperatorService";
+
 /// Exit code used when the RPC succeeds but not all scopes are `STATE_READY`.
 const EXIT_NOT_READY: i32 = 2;
 
@@ -76,10 +85,29 @@ pub enum ModeCmd {
     Insert(RouteInsertCmd),
     /// Remove a unicast static route.
     Remove(RouteRemoveCmd),
+    /// Remove all routes matching a filter.
+    RemoveByFilter(RouteRemoveByFilterCmd),
+    /// List the route tags with the size of their groups.
+    Tags(RouteTagsCmd),
+    /// Set the local preference of all routes matching a filter.
+    SetPref(RouteSetPrefCmd),
+    /// Predict the best route changes of hypothetical withdrawals and
+    /// announcements without applying them.
+    Simulate(RouteSimulateCmd),
     /// Flush RIB to FIB for a configuration.
     Flush(RouteFlushCmd),
     /// Show per-scope readiness of the route operator.
     Ready(ReadyCmd),
+    /// Print RIB changes as they happen.
+    Monitor(RouteMonitorCmd),
+    /// Rebuild the FIBs from the RIB and push them to the gateways.
+    Resync(ResyncCmd),
+    /// Write a snapshot of the RIB to the snapshot directory of the
+    /// operator.
+    Export(RouteExportCmd),
+    /// Restore the BIRD routes of the RIB from its snapshot in the snapshot
+    /// directory of the operator.
+    Import(RouteImportCmd),
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -96,6 +124,9 @@ pub struct RouteShowCmd {
     /// Show only IPv6 routes.
     #[arg(long)]
     pub ipv6: bool,
+    /// Show only routes carrying this tag.
+    #[arg(long = "tag")]
+    pub tag: Option<String>,
     /// Configuration name.
     #[arg(long = "name", short = 'n')]
     pub name: String,
@@ -121,9 +152,22 @@ pub struct RouteInsertCmd {
     /// ECMP.
     #[arg(long = "via", required = true)]
     pub nexthop_addrs: Vec<IpAddr>,
+    /// Share of the traffic of the nexthop given by the `--via` at the same
+    /// position; repeat once per `--via`. All nexthops weigh the same when
+    /// omitted.
+    #[arg(long = "weight")]
+    pub nexthop_weights: Vec<u32>,
     /// Route source type (static or bird). Defaults to static.
     #[arg(long = "source", default_value = "static")]
     pub source: RouteSource,
+    /// Tag of the route, e.g. `maintenance-1234`; repeat `--tag` to attach
+    /// several.
+    #[arg(long = "tag")]
+    pub tags: Vec<String>,
+    /// Commit the change right away, bypassing the flush batching and the
+    /// commit rate limit of the operator.
+    #[arg(long)]
+    pub emergency: bool,
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -140,6 +184,128 @@ pub struct RouteRemoveCmd {
     /// Route source type (static or bird). Defaults to static.
     #[arg(long = "source", default_value = "static")]
     pub source: RouteSource,
+    /// Commit the change right away, bypassing the flush batching and the
+    /// commit rate limit of the operator.
+    #[arg(long)]
+    pub emergency: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteRemoveByFilterCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+    /// Remove only routes of this prefix and its more-specifics.
+    #[arg(long = "prefix")]
+    pub prefix: Option<Contiguous<IpNetwork>>,
+    /// Remove only routes from this source.
+    #[arg(long = "source")]
+    pub source: Option<RouteSource>,
+    /// Remove only routes via this next-hop IP address.
+    #[arg(long = "via")]
+    pub nexthop_addr: Option<IpAddr>,
+    /// Remove only routes carrying this large community, as
+    /// `global:local1:local2`.
+    #[arg(long = "community", value_parser = parse_large_community)]
+    pub community: Option<operatorpb::LargeCommunity>,
+    /// Remove only routes carrying this tag.
+    #[arg(long = "tag")]
+    pub tag: Option<String>,
+    /// Only print the matching routes without removing them.
+    #[arg(long)]
+    pub dry_run: bool,
+    /// Commit the change right away, bypassing the flush batching and the
+    /// commit rate limit of the operator.
+    #[arg(long)]
+    pub emergency: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteTagsCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteSetPrefCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+    /// Local preference to set.
+    #[arg(long = "pref")]
+    pub pref: u32,
+    /// Change only routes of this prefix and its more-specifics.
+    #[arg(long = "prefix")]
+    pub prefix: Option<Contiguous<IpNetwork>>,
+    /// Change only routes from this source.
+    #[arg(long = "source")]
+    pub source: Option<RouteSource>,
+    /// Change only routes via this next-hop IP address.
+    #[arg(long = "via")]
+    pub nexthop_addr: Option<IpAddr>,
+    /// Change only routes carrying this large community, as
+    /// `global:local1:local2`.
+    #[arg(long = "community", value_parser = parse_large_community)]
+    pub community: Option<operatorpb::LargeCommunity>,
+    /// Change only routes carrying this tag.
+    #[arg(long = "tag")]
+    pub tag: Option<String>,
+    /// Only print the matching routes without changing them.
+    #[arg(long)]
+    pub dry_run: bool,
+    /// Commit the change right away, bypassing the flush batching and the
+    /// commit rate limit of the operator.
+    #[arg(long)]
+    pub emergency: bool,
+}
+
+fn parse_large_community(s: &str) -> Result<operatorpb::LargeCommunity, String> {
+    let parts = s
+        .split(':')
+        .map(str::parse::<u32>)
+        .collect::<Result<Vec<_>, _>>()
+        .map_err(|err| err.to_string())?;
+    let [global_administrator, local_data_part1, local_data_part2] = parts[..] else {
+        return Err("expected global:local1:local2".to_string());
+    };
+
+    Ok(operatorpb::LargeCommunity {
+        global_administrator,
+        local_data_part1,
+        local_data_part2,
+    })
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteSimulateCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+    /// Withdraw all routes via this next-hop IP address, e.g. to predict a
+    /// peer going down; repeat to withdraw several.
+    #[arg(long = "withdraw-via")]
+    pub withdraw_nexthops: Vec<IpAddr>,
+    /// Withdraw all routes of this prefix and its more-specifics; repeat to
+    /// withdraw several.
+    #[arg(long = "withdraw-prefix")]
+    pub withdraw_prefixes: Vec<Contiguous<IpNetwork>>,
+    /// Announce a route as `prefix@nexthop`; repeat to announce several.
+    #[arg(long = "announce", value_parser = parse_announce)]
+    pub announce: Vec<(Contiguous<IpNetwork>, IpAddr)>,
+    /// Source of the announced routes (static or bird). Defaults to static.
+    #[arg(long = "source", default_value = "static")]
+    pub source: RouteSource,
+}
+
+fn parse_announce(s: &str) -> Result<(Contiguous<IpNetwork>, IpAddr), String> {
+    let Some((prefix, nexthop)) = s.split_once('@') else {
+        return Err("expected prefix@nexthop".to_string());
+    };
+    let prefix = prefix.parse::<Contiguous<IpNetwork>>().map_err(|err| err.to_string())?;
+    let nexthop = nexthop.parse::<IpAddr>().map_err(|err| err.to_string())?;
+
+    Ok((prefix, nexthop))
 }
 
 #[derive(Debug, Clone, Parser)]
@@ -147,6 +313,48 @@ pub struct RouteFlushCmd {
     /// Configuration name.
     #[arg(long = "name", short = 'n')]
     pub name: String,
+    /// Commit the change right away, bypassing the flush batching and the
+    /// commit rate limit of the operator.
+    #[arg(long)]
+    pub emergency: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteExportCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteImportCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+    /// Flush the restored routes to the FIB right away.
+    #[arg(long)]
+    pub flush: bool,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct RouteMonitorCmd {
+    /// Configuration name.
+    #[arg(long = "name", short = 'n')]
+    pub name: String,
+    /// Show only changes of this prefix and its more-specifics.
+    #[arg(long = "prefix")]
+    pub prefix: Option<Contiguous<IpNetwork>>,
+    /// Show only changes of routes from this source.
+    #[arg(long = "source")]
+    pub source: Option<RouteSource>,
+}
+
+#[derive(Debug, Clone, Parser)]
+pub struct ResyncCmd {
+    /// Resync only this gateway; repeat to resync several. Defaults to all
+    /// gateways.
+    #[arg(long = "gateway", short = 'g')]
+    pub gateways: Vec<String>,
 }
 
 #[derive(Debug, Clone, clap::ValueEnum)]
@@ -203,14 +411,23 @@ async fn run(cmd: Cmd) -> Result<bool, Error> {
         ModeCmd::Lookup(c) => service.lookup_route(c).await.map(|()| true),
         ModeCmd::Insert(c) => service.insert_route(c).await.map(|()| true),
         ModeCmd::Remove(c) => service.remove_route(c).await.map(|()| true),
+        ModeCmd::RemoveByFilter(c) => service.remove_routes_by_filter(c).await.map(|()| true),
+        ModeCmd::Tags(c) => service.list_route_tags(c).await.map(|()| true),
+        ModeCmd::SetPref(c) => service.set_routes_pref(c).await.map(|()| true),
+        ModeCmd::Simulate(c) => service.simulate(c).await.map(|()| true),
         ModeCmd::Flush(c) => service.flush_routes(c).await.map(|()| true),
         ModeCmd::Ready(c) => service.ready(c).await,
+        ModeCmd::Monitor(c) => service.monitor_routes(c).await.map(|()| true),
+        ModeCmd::Resync(c) => service.resync(c).await.map(|()| true),
+        ModeCmd::Export(c) => service.export_rib(c).await.map(|()| true),
+        ModeCmd::Import(c) => service.import_rib(c).await.map(|()| true),
     }
 }
 
 pub struct RouteService {
     service: Service<RouteServiceClient<LayeredChannel>>,
     readiness: Service<ReadinessServiceClient<LayeredChannel>>,
+    operator: Service<RouteOperatorServiceClient<LayeredChannel>>,
 }
 
 impl RouteService {
@@ -227,7 +444,13 @@ impl RouteService {
                 .accept_compressed(CompressionEncoding::Gzip)
         });
 
-        Ok(Self { service, readiness })
+        let operator = Service::new(&conn, OPERATOR_SERVICE_NAME, |channel| {
+            RouteOperatorServiceClient::new(channel)
+                .send_compressed(CompressionEncoding::Gzip)
+                .accept_compressed(CompressionEncoding::Gzip)
+        });
+
+        Ok(Self { service, readiness, operator })
     }
 
     pub async fn list_configs(&mut self) -> Result<(), Error> {
@@ -258,6 +481,7 @@ impl RouteService {
             name: cmd.name.clone(),
             ipv4_only: cmd.ipv4,
             ipv6_only: cmd.ipv6,
+            tag: cmd.tag.clone().unwrap_or_default(),
         };
 
         let response = self
@@ -305,6 +529,11 @@ impl RouteService {
                 let mut entries: Vec<RouteEntry> = response.routes.iter().cloned().map(RouteEntry::from).collect();
                 annotate_ecmp_groups(&mut entries);
                 print_route_table(entries);
+                if !response.tags.is_empty() {
+                    let mut tags: Vec<_> = response.tags.iter().map(|(k, v)| format!("{k}={v}")).collect();
+                    tags.sort();
+                    println!("tags: {}", tags.join(", "));
+                }
             },
         );
 
@@ -320,6 +549,9 @@ impl RouteService {
             nexthop_addrs,
             do_flush: true,
             source_id: cmd.source.to_proto().into(),
+            emergency: cmd.emergency,
+            nexthop_weights: cmd.nexthop_weights.clone(),
+            tags: cmd.tags.clone(),
         };
 
         self.service
@@ -358,6 +590,7 @@ impl RouteService {
             nexthop_addrs,
             do_flush: true,
             source_id: cmd.source.to_proto().into(),
+            emergency: cmd.emergency,
         };
 
         self.service
@@ -387,20 +620,307 @@ impl RouteService {
         Ok(())
     }
 
+    pub async fn remove_routes_by_filter(&mut self, cmd: RouteRemoveByFilterCmd) -> Result<(), Error> {
+        let request = DeleteRoutesByFilterRequest {
+            name: cmd.name.clone(),
+            filter: Some(RouteFilter {
+                prefix: cmd.prefix.map(|p| p.to_string()).unwrap_or_default(),
+                source: cmd.source.as_ref().map(RouteSource::to_proto).unwrap_or_default().into(),
+                next_hop: cmd.nexthop_addr.map(Into::into),
+                large_community: cmd.community,
+                tag: cmd.tag.clone().unwrap_or_default(),
+            }),
+            dry_run: cmd.dry_run,
+            do_flush: true,
+            emergency: cmd.emergency,
+        };
+
+        let response = self
+            .service
+            .client()
+            .delete_routes_by_filter(request)
+            .await
+            .map_err(self.service.status("remove-by-filter"))?
+            .into_inner();
+
+        output::data(
+            &response.routes,
+            response.routes.is_empty(),
+            format_args!("no matching routes in {}", cmd.name),
+            || {
+                let mut entries: Vec<RouteEntry> = response.routes.iter().cloned().map(RouteEntry::from).collect();
+                entries.sort_by_key(|entry| entry.prefix.0);
+                print_route_table(entries);
+                if cmd.dry_run {
+                    println!("{} routes would be removed from {}", response.routes.len(), cmd.name);
+                } else {
+                    println!("removed {} routes from {}", response.routes.len(), cmd.name);
+                }
+            },
+        );
+
+        Ok(())
+    }
+
+    pub async fn list_route_tags(&mut self, cmd: RouteTagsCmd) -> Result<(), Error> {
+        let request = ListRouteTagsRequest { name: cmd.name.clone() };
+
+        let response = self
+            .service
+            .client()
+            .list_route_tags(request)
+            .await
+            .map_err(self.service.status("tags"))?
+            .into_inner();
+
+        output::data(
+            &response.tags,
+            response.tags.is_empty(),
+            format_args!("no tagged routes in {}", cmd.name),
+            || {
+                let entries: Vec<RouteTagEntry> = response.tags.iter().map(RouteTagEntry::from).collect();
+                let mut table = Table::new(&entries);
+                table.with(Style::modern().remove_horizontal());
+                if output::is_colored() {
+                    table.modify(Columns::new(..), BorderColor::filled(Color::rgb_fg(0x4e, 0x4e, 0x4e)));
+                    table.modify(Rows::first(), Color::BOLD);
+                }
+                println!("{table}");
+            },
+        );
+
+        Ok(())
+    }
+
+    pub async fn set_routes_pref(&mut self, cmd: RouteSetPrefCmd) -> Result<(), Error> {
+        let request = SetRoutesPrefByFilterRequest {
+            name: cmd.name.clone(),
+            filter: Some(RouteFilter {
+                prefix: cmd.prefix.map(|p| p.to_string()).unwrap_or_default(),
+                source: cmd.source.as_ref().map(RouteSource::to_proto).unwrap_or_default().into(),
+                next_hop: cmd.nexthop_addr.map(Into::into),
+                large_community: cmd.community,
+                tag: cmd.tag.clone().unwrap_or_default(),
+            }),
+            pref: cmd.pref,
+            dry_run: cmd.dry_run,
+            do_flush: true,
+            emergency: cmd.emergency,
+        };
+
+        let response = self
+            .service
+            .client()
+            .set_routes_pref_by_filter(request)
+            .await
+            .map_err(self.service.status("set-pref"))?
+            .into_inner();
+
+        output::data(
+            &response.routes,
+            response.routes.is_empty(),
+            format_args!("no matching routes in {}", cmd.name),
+            || {
+                let mut entries: Vec<RouteEntry> = response.routes.iter().cloned().map(RouteEntry::from).collect();
+                entries.sort_by_key(|entry| entry.prefix.0);
+                print_route_table(entries);
+                if cmd.dry_run {
+                    println!("{} routes would be set to pref {} in {}", response.routes.len(), cmd.pref, cmd.name);
+                } else {
+                    println!("set {} routes to pref {} in {}", response.routes.len(), cmd.pref, cmd.name);
+                }
+            },
+        );
+
+        Ok(())
+    }
+
+    pub async fn simulate(&mut self, cmd: RouteSimulateCmd) -> Result<(), Error> {
+        let mut withdraw_filters: Vec<RouteFilter> = cmd
+            .withdraw_nexthops
+            .iter()
+            .map(|addr| RouteFilter {
+                next_hop: Some((*addr).into()),
+                ..Default::default()
+            })
+            .collect();
+        withdraw_filters.extend(cmd.withdraw_prefixes.iter().map(|prefix| RouteFilter {
+            prefix: prefix.to_string(),
+            ..Default::default()
+        }));
+
+        let announce = cmd
+            .announce
+            .iter()
+            .map(|(prefix, nexthop)| operatorpb::Route {
+                prefix: prefix.to_string(),
+                next_hop: Some((*nexthop).into()),
+                peer: Some((*nexthop).into()),
+                source: cmd.source.to_proto().into(),
+                ..Default::default()
+            })
+            .collect();
+
+        let request = SimulateRequest {
+            name: cmd.name.clone(),
+            withdraw_filters,
+            withdraw: Vec::new(),
+            announce,
+        };
+
+        let response = self
+            .service
+            .client()
+            .simulate(request)
+            .await
+            .map_err(self.service.status("simulate"))?
+            .into_inner();
+
+        output::data(
+            &response,
+            response.changes.is_empty(),
+            format_args!("no best route changes in {}", cmd.name),
+            || {
+                for change in &response.changes {
+                    println!("{}", change.prefix.bold());
+                    for route in &change.before {
+                        println!("  {} {}", "-".red(), SimulatedRoute(route));
+                    }
+                    for route in &change.after {
+                        println!("  {} {}", "+".green(), SimulatedRoute(route));
+                    }
+                }
+                println!(
+                    "{} prefixes would change best routes in {}, {} routes withdrawn",
+                    response.changes.len(),
+                    cmd.name,
+                    response.withdrawn_routes
+                );
+            },
+        );
+
+        Ok(())
+    }
+
     pub async fn flush_routes(&mut self, cmd: RouteFlushCmd) -> Result<(), Error> {
-        let request = FlushRoutesRequest { name: cmd.name.clone() };
+        let request = FlushRoutesRequest {
+            name: cmd.name.clone(),
+            emergency: cmd.emergency,
+        };
 
-        self.service
+        let response = self
+            .service
             .client()
             .flush_routes(request)
             .await
-            .map_err(self.service.status("flush"))?;
+            .map_err(self.service.status("flush"))?
+            .into_inner();
+
+        for result in &response.results {
+            output::data(result, false, format_args!(""), || {
+                println!("{}", FlushResultLine(result));
+            });
+        }
 
         output::success("flush", format_args!("Flushed {}.", cmd.name));
 
         Ok(())
     }
 
+    pub async fn export_rib(&mut self, cmd: RouteExportCmd) -> Result<(), Error> {
+        let request = ExportRibRequest { name: cmd.name.clone() };
+
+        let response = self
+            .service
+            .client()
+            .export_rib(request)
+            .await
+            .map_err(self.service.status("export"))?
+            .into_inner();
+
+        output::success(
+            "export",
+            format_args!(
+                "Exported {} routes of {} to {}.",
+                response.routes, cmd.name, response.path
+            ),
+        );
+
+        Ok(())
+    }
+
+    pub async fn import_rib(&mut self, cmd: RouteImportCmd) -> Result<(), Error> {
+        let request = ImportRibRequest {
+            name: cmd.name.clone(),
+            do_flush: cmd.flush,
+        };
+
+        let response = self
+            .service
+            .client()
+            .import_rib(request)
+            .await
+            .map_err(self.service.status("import"))?
+            .into_inner();
+
+        output::success(
+            "import",
+            format_args!("Imported {} routes into {}.", response.routes, cmd.name),
+        );
+
+        Ok(())
+    }
+
+    pub async fn monitor_routes(&mut self, cmd: RouteMonitorCmd) -> Result<(), Error> {
+        let request = MonitorRoutesRequest {
+            name: cmd.name.clone(),
+            prefix: cmd.prefix.map(|p| p.to_string()).unwrap_or_default(),
+            source: cmd.source.as_ref().map(RouteSource::to_proto).unwrap_or_default().into(),
+        };
+
+        let mut stream = self
+            .service
+            .client()
+            .monitor_routes(request)
+            .await
+            .map_err(self.service.status("monitor"))?
+            .into_inner();
+
+        while let Some(event) = stream.message().await.map_err(self.service.status("monitor"))? {
+            output::data(&event, false, format_args!(""), || {
+                println!("{}", RouteEventLine(&event));
+            });
+        }
+
+        Ok(())
+    }
+
+    pub async fn resync(&mut self, cmd: ResyncCmd) -> Result<(), Error> {
+        let request = ResyncRequest { gateways: cmd.gateways };
+
+        let mut stream = self
+            .operator
+            .client()
+            .resync(request)
+            .await
+            .map_err(self.operator.status("resync"))?
+            .into_inner();
+
+        // The stream ends with an error status if any FIB failed, after the
+        // progress of every FIB has been printed.
+        let mut total = 0;
+        while let Some(progress) = stream.message().await.map_err(self.operator.status("resync"))? {
+            total = progress.total;
+            output::data(&progress, false, format_args!(""), || {
+                println!("{}", ResyncProgressLine(&progress));
+            });
+        }
+
+        output::success("resync", format_args!("Resynced {total} FIBs."));
+
+        Ok(())
+    }
+
     pub async fn ready(&mut self, cmd: ReadyCmd) -> Result<bool, Error> {
         let request = readinesspb::pb::ReadyRequest { scopes: cmd.scopes.clone() };
 
@@ -500,14 +1020,37 @@ impl Display for LargeCommunity {
     }
 }
 
+/// Renders the communities of a route, the standard ones as `ASN:value`,
+/// the extended ones as `type:subtype:value` with the type and the subtype
+/// in hex, and the large ones as `GA:LD1:LD2`.
 #[derive(Debug)]
-pub struct Communities(pub Vec<LargeCommunity>);
+pub struct Communities(pub Vec<String>);
+
+impl Communities {
+    pub fn of(route: &operatorpb::Route) -> Self {
+        let standard = route.communities.iter().map(|c| format!("{}:{}", c.asn, c.value));
+        let extended = route
+            .ext_communities
+            .iter()
+            .map(|c| format!("{:#04x}:{:#04x}:{}", c.r#type, c.sub_type, c.value));
+        let large = route
+            .large_communities
+            .iter()
+            .cloned()
+            .map(|c| LargeCommunity::from(c).to_string());
+
+        Self(standard.chain(extended).chain(large).collect())
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.0.is_empty()
+    }
+}
 
 impl Display for Communities {
     fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
         let Self(communities) = self;
-        let strings: Vec<String> = communities.iter().map(|c| c.to_string()).collect();
-        write!(f, "{}", strings.join(" "))
+        write!(f, "{}", communities.join(" "))
     }
 }
 
@@ -581,13 +1124,17 @@ pub struct RouteEntry {
     pub pref: u32,
     #[tabled(rename = "MED")]
     pub med: u32,
+    #[tabled(rename = "Weight")]
+    pub weight: u32,
     #[tabled(rename = "Communities")]
     pub communities: Communities,
+    #[tabled(rename = "Tags")]
+    pub tags: String,
 }
 
 impl From<operatorpb::Route> for RouteEntry {
     fn from(route: operatorpb::Route) -> Self {
-        let communities = route.large_communities.into_iter().map(|c| c.into()).collect();
+        let communities = Communities::of(&route);
         let prefix = Contiguous::<IpNetwork>::parse(&route.prefix).expect("must be valid prefix");
 
         Self {
@@ -599,7 +1146,174 @@ impl From<operatorpb::Route> for RouteEntry {
             origin_as: route.origin_as,
             pref: route.pref,
             med: route.med,
-            communities: Communities(communities),
+            weight: route.weight.max(1),
+            communities,
+            tags: route.tags.join(" "),
+        }
+    }
+}
+
+#[derive(Debug, Tabled)]
+pub struct RouteTagEntry {
+    #[tabled(rename = "Tag")]
+    pub tag: String,
+    #[tabled(rename = "Routes")]
+    pub routes: u64,
+    #[tabled(rename = "Prefixes")]
+    pub prefixes: u64,
+}
+
+impl From<&operatorpb::RouteTag> for RouteTagEntry {
+    fn from(tag: &operatorpb::RouteTag) -> Self {
+        Self {
+            tag: tag.tag.clone(),
+            routes: tag.routes,
+            prefixes: tag.prefixes,
+        }
+    }
+}
+
+/// Renders a simulated best route as `via <nexthop> [<source>]`.
+pub struct SimulatedRoute<'a>(&'a operatorpb::Route);
+
+impl Display for SimulatedRoute<'_> {
+    fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
+        let SimulatedRoute(route) = self;
+        let next_hop = route.next_hop.as_ref().map(|a| a.to_string()).unwrap_or_default();
+
+        write!(f, "via {} [{}]", next_hop, route_source_name(route.source))
+    }
+}
+
+/// Renders a `RouteEvent` as a single BIRD-like line.
+///
+/// The line starts with `+` for an added route, `-` for a withdrawn one
+/// and `*` for a route that became best, followed by the route attributes.
+pub struct RouteEventLine<'a>(&'a operatorpb::RouteEvent);
+
+impl Display for RouteEventLine<'_> {
+    fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
+        let RouteEventLine(event) = self;
+        let kind = RouteEventKind::try_from(event.kind).unwrap_or_default();
+        let Some(route) = event.route.as_ref() else {
+            return Ok(());
+        };
+
+        let marker = match kind {
+            RouteEventKind::Added => "+",
+            RouteEventKind::Withdrawn => "-",
+            RouteEventKind::BestChanged => "*",
+            RouteEventKind::Unknown => "?",
+        };
+        let marker = if output::is_colored() {
+            match kind {
+                RouteEventKind::Added => marker.green().to_string(),
+                RouteEventKind::Withdrawn => marker.red().to_string(),
+                RouteEventKind::BestChanged => marker.yellow().to_string(),
+                RouteEventKind::Unknown => marker.to_string(),
+            }
+        } else {
+            marker.to_string()
+        };
+
+        let next_hop = route.next_hop.as_ref().map(|a| a.to_string()).unwrap_or_default();
+        write!(
+            f,
+            "{marker} {} via {next_hop} [{}]",
+            route.prefix,
+            route_source_name(route.source)
+        )?;
+        if let Some(peer) = route.peer.as_ref() {
+            write!(f, " from {peer}")?;
+        }
+        if route.peer_as != 0 {
+            write!(f, " AS{}", route.peer_as)?;
+        }
+        write!(f, " pref {} med {}", route.pref, route.med)?;
+        if route.origin_as != 0 {
+            write!(f, " origin AS{}", route.origin_as)?;
+        }
+        if route.weight > 1 {
+            write!(f, " weight {}", route.weight)?;
+        }
+        if route.blackhole {
+            write!(f, " blackhole")?;
+        }
+        let communities = Communities::of(route);
+        if !communities.is_empty() {
+            write!(f, " communities {communities}")?;
+        }
+        if !route.tags.is_empty() {
+            write!(f, " tags {}", route.tags.join(","))?;
+        }
+        if kind == RouteEventKind::BestChanged {
+            write!(f, " (best)")?;
+        }
+
+        Ok(())
+    }
+}
+
+/// Renders a single FIB processed by a resync as one line.
+pub struct ResyncProgressLine<'a>(&'a operatorpb::ResyncProgress);
+
+impl Display for ResyncProgressLine<'_> {
+    fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
+        let ResyncProgressLine(progress) = self;
+
+        write!(
+            f,
+            "[{}/{}] {} {}: ",
+            progress.done, progress.total, progress.gateway, progress.module
+        )?;
+        if progress.error.is_empty() {
+            let status = format!("{} entries", progress.entries);
+            if output::is_colored() {
+                write!(f, "{}", status.green())
+            } else {
+                write!(f, "{status}")
+            }
+        } else {
+            let status = format!("failed: {}", progress.error);
+            if output::is_colored() {
+                write!(f, "{}", status.red())
+            } else {
+                write!(f, "{status}")
+            }
+        }
+    }
+}
+
+/// Renders the commit of a flush on a single gateway as one line.
+pub struct FlushResultLine<'a>(&'a operatorpb::FlushResult);
+
+impl Display for FlushResultLine<'_> {
+    fn fmt(&self, f: &mut Formatter) -> Result<(), fmt::Error> {
+        let FlushResultLine(result) = self;
+
+        write!(f, "{}: ", result.gateway)?;
+        if result.error.is_empty() {
+            let duration = result
+                .duration
+                .and_then(|d| std::time::Duration::try_from(d).ok())
+                .unwrap_or_default();
+            let mut status = format!("{} entries", result.entries);
+            if result.delta {
+                status.push_str(&format!(" ({} changed)", result.changed));
+            }
+            status.push_str(&format!(", generation {}, {:?}", result.generation, duration));
+            if output::is_colored() {
+                write!(f, "{}", status.green())
+            } else {
+                write!(f, "{status}")
+            }
+        } else {
+            let status = format!("failed: {}", result.error);
+            if output::is_colored() {
+                write!(f, "{}", status.red())
+            } else {
+                write!(f, "{status}")
+            }
         }
     }
 }
@@ -779,6 +1493,45 @@ where
     serializer.serialize_str(&route_source_name(*value))
 }
 
+/// Serializes the `kind` field of `RouteEvent` as a lowercase string name
+/// (e.g. `"added"`, `"withdrawn"`).
+pub fn serialize_route_event_kind<S>(value: &i32, serializer: S) -> Result<S::Ok, S::Error>
+where
+    S: serde::Serializer,
+{
+    let name = RouteEventKind::try_from(*value)
+        .unwrap_or_default()
+        .as_str_name()
+        .strip_prefix("ROUTE_EVENT_KIND_")
+        .unwrap_or_default()
+        .to_lowercase();
+    serializer.serialize_str(&name)
+}
+
+/// Serializes an optional `Timestamp` field as Unix time in nanoseconds or
+/// JSON `null` when absent.
+pub fn serialize_timestamp<S>(value: &Option<prost_types::Timestamp>, serializer: S) -> Result<S::Ok, S::Error>
+where
+    S: serde::Serializer,
+{
+    match value {
+        Some(ts) => serializer.serialize_i64(ts.seconds * 1_000_000_000 + i64::from(ts.nanos)),
+        None => serializer.serialize_none(),
+    }
+}
+
+/// Serializes an optional `Duration` field as nanoseconds or JSON `null`
+/// when absent.
+pub fn serialize_duration<S>(value: &Option<prost_types::Duration>, serializer: S) -> Result<S::Ok, S::Error>
+where
+    S: serde::Serializer,
+{
+    match value {
+        Some(d) => serializer.serialize_i64(d.seconds * 1_000_000_000 + i64::from(d.nanos)),
+        None => serializer.serialize_none(),
+    }
+}
+
 /// Serializes an optional `IpAddress` field as a string (e.g. `"10.0.0.1"`)
 /// or JSON `null` when absent.
 pub fn serialize_ip_addr<S>(value: &Option<commonpb::pb::IpAddress>, serializer: S) -> Result<S::Ok, S::Error>
@@ -927,7 +1680,9 @@ mod test {
             origin_as: 0,
             pref: 0,
             med: 0,
+            weight: 1,
             communities: Communities(vec![]),
+            tags: String::new(),
         }
     }
 
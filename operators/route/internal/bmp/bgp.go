@@ -0,0 +1,272 @@
+package bmp
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// BGP message types, RFC 4271 Section 4.1.
+const (
+	bgpMsgOpen   = 1
+	bgpMsgUpdate = 2
+)
+
+// bgpHeaderLen is the length of the BGP message header: the marker, the
+// length and the type.
+const bgpHeaderLen = 19
+
+// BGP path attribute flags, RFC 4271 Section 4.3.
+const (
+	attrFlagOptional   = 0x80
+	attrFlagTransitive = 0x40
+	attrFlagExtLen     = 0x10
+)
+
+// BGP path attribute types.
+const (
+	attrOrigin           = 1
+	attrASPath           = 2
+	attrNextHop          = 3
+	attrMed              = 4
+	attrLocalPref        = 5
+	attrCommunities      = 8
+	attrMPReachNLRI      = 14
+	attrMPUnreachNLRI    = 15
+	attrExtCommunities   = 16
+	attrLargeCommunities = 32
+)
+
+const (
+	// originIncomplete is the ORIGIN of every exported route, as the RIB
+	// does not keep the origin the routes were announced with.
+	originIncomplete = 2
+	// asPathSequence is the AS_SEQUENCE path segment type.
+	asPathSequence = 2
+	// asTrans stands for a 4-octet AS in the 2-octet AS field of OPEN
+	// messages, RFC 6793.
+	asTrans = 23456
+)
+
+// Address families, RFC 4760.
+const (
+	afiIPv4     = 1
+	afiIPv6     = 2
+	safiUnicast = 1
+)
+
+// BGP capability codes and the OPEN parameter carrying them, RFC 5492.
+const (
+	openParamCapabilities = 2
+	capMultiprotocol      = 1
+	capFourOctetAS        = 65
+)
+
+// Update is a route announced in a BGP UPDATE message.
+type Update struct {
+	// Prefix is the announced prefix. IPv4-mapped prefixes are announced
+	// as IPv4 ones.
+	Prefix  netip.Prefix
+	NextHop netip.Addr
+	// ASPath is the AS_PATH of the route, the neighbour AS first.
+	ASPath    []uint32
+	Med       uint32
+	LocalPref uint32
+	// Communities are the standard communities, RFC 1997.
+	Communities []uint32
+	// ExtCommunities are the extended communities, RFC 4360.
+	ExtCommunities [][8]byte
+	// LargeCommunities are the large communities, RFC 8092.
+	LargeCommunities [][3]uint32
+}
+
+// unmapPrefix returns the prefix with its IPv4-mapped address unmapped,
+// the way prefixes are kept in the RIB.
+func unmapPrefix(prefix netip.Prefix) netip.Prefix {
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96).Masked()
+	}
+	return prefix.Masked()
+}
+
+// appendNLRI appends the prefix encoded as NLRI, RFC 4271 Section 4.3.
+func appendNLRI(b []byte, prefix netip.Prefix) []byte {
+	addr := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+	b = append(b, byte(bits))
+	return append(b, addr[:(bits+7)/8]...)
+}
+
+func appendAttr(b []byte, flags byte, code byte, value []byte) []byte {
+	if len(value) > 0xff {
+		b = append(b, flags|attrFlagExtLen, code)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	} else {
+		b = append(b, flags, code, byte(len(value)))
+	}
+	return append(b, value...)
+}
+
+// appendBGPMessage appends the BGP message of the type with the body.
+func appendBGPMessage(b []byte, msgType byte, body []byte) []byte {
+	for range 16 {
+		b = append(b, 0xff)
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(bgpHeaderLen+len(body)))
+	b = append(b, msgType)
+	return append(b, body...)
+}
+
+// appendUpdate appends the UPDATE message with the withdrawn routes and
+// the path attributes, the IPv4 unicast announcements in nlri.
+func appendUpdate(b []byte, withdrawn []byte, attrs []byte, nlri []byte) []byte {
+	body := make([]byte, 0, 4+len(withdrawn)+len(attrs)+len(nlri))
+	body = binary.BigEndian.AppendUint16(body, uint16(len(withdrawn)))
+	body = append(body, withdrawn...)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(attrs)))
+	body = append(body, attrs...)
+	body = append(body, nlri...)
+	return appendBGPMessage(b, bgpMsgUpdate, body)
+}
+
+func afi(prefix netip.Prefix) uint16 {
+	if prefix.Addr().Is4() {
+		return afiIPv4
+	}
+	return afiIPv6
+}
+
+// Marshal returns the BGP UPDATE message announcing the route.
+//
+// IPv4 routes forwarding to an IPv4 nexthop are announced in the NLRI of
+// the message, the other ones in MP_REACH_NLRI, IPv4 routes with an IPv6
+// nexthop as extended nexthops (RFC 8950).
+func (m *Update) Marshal() []byte {
+	prefix := unmapPrefix(m.Prefix)
+	nexthop := m.NextHop.Unmap()
+
+	attrs := appendAttr(nil, attrFlagTransitive, attrOrigin, []byte{originIncomplete})
+
+	asPath := []byte{}
+	for idx := 0; idx < len(m.ASPath); idx += 255 {
+		segment := m.ASPath[idx:min(idx+255, len(m.ASPath))]
+		asPath = append(asPath, asPathSequence, byte(len(segment)))
+		for _, as := range segment {
+			asPath = binary.BigEndian.AppendUint32(asPath, as)
+		}
+	}
+	attrs = appendAttr(attrs, attrFlagTransitive, attrASPath, asPath)
+
+	var nlri []byte
+	if prefix.Addr().Is4() && nexthop.Is4() {
+		attrs = appendAttr(attrs, attrFlagTransitive, attrNextHop, nexthop.AsSlice())
+		nlri = appendNLRI(nil, prefix)
+	}
+
+	attrs = appendAttr(attrs, attrFlagOptional, attrMed, binary.BigEndian.AppendUint32(nil, m.Med))
+	attrs = appendAttr(attrs, attrFlagTransitive, attrLocalPref, binary.BigEndian.AppendUint32(nil, m.LocalPref))
+
+	if len(m.Communities) > 0 {
+		value := make([]byte, 0, 4*len(m.Communities))
+		for _, community := range m.Communities {
+			value = binary.BigEndian.AppendUint32(value, community)
+		}
+		attrs = appendAttr(attrs, attrFlagOptional|attrFlagTransitive, attrCommunities, value)
+	}
+
+	if nlri == nil {
+		value := binary.BigEndian.AppendUint16(nil, afi(prefix))
+		value = append(value, safiUnicast)
+		if nexthop.IsValid() {
+			value = append(value, byte(nexthop.BitLen()/8))
+			value = append(value, nexthop.AsSlice()...)
+		} else {
+			value = append(value, 0)
+		}
+		// The reserved octet.
+		value = append(value, 0)
+		value = appendNLRI(value, prefix)
+		attrs = appendAttr(attrs, attrFlagOptional, attrMPReachNLRI, value)
+	}
+
+	if len(m.ExtCommunities) > 0 {
+		value := make([]byte, 0, 8*len(m.ExtCommunities))
+		for _, community := range m.ExtCommunities {
+			value = append(value, community[:]...)
+		}
+		attrs = appendAttr(attrs, attrFlagOptional|attrFlagTransitive, attrExtCommunities, value)
+	}
+	if len(m.LargeCommunities) > 0 {
+		value := make([]byte, 0, 12*len(m.LargeCommunities))
+		for _, community := range m.LargeCommunities {
+			for _, part := range community {
+				value = binary.BigEndian.AppendUint32(value, part)
+			}
+		}
+		attrs = appendAttr(attrs, attrFlagOptional|attrFlagTransitive, attrLargeCommunities, value)
+	}
+
+	return appendUpdate(nil, nil, attrs, nlri)
+}
+
+// Withdraw returns the BGP UPDATE message withdrawing the prefix.
+func Withdraw(prefix netip.Prefix) []byte {
+	prefix = unmapPrefix(prefix)
+	if prefix.Addr().Is4() {
+		return appendUpdate(nil, appendNLRI(nil, prefix), nil, nil)
+	}
+
+	value := binary.BigEndian.AppendUint16(nil, afiIPv6)
+	value = append(value, safiUnicast)
+	value = appendNLRI(value, prefix)
+	return appendUpdate(nil, nil, appendAttr(nil, attrFlagOptional, attrMPUnreachNLRI, value), nil)
+}
+
+// EndOfRIB returns the End-of-RIB marker (RFC 4724) of the IPv4 or the
+// IPv6 unicast routes.
+func EndOfRIB(ipv6 bool) []byte {
+	if !ipv6 {
+		return appendUpdate(nil, nil, nil, nil)
+	}
+
+	value := binary.BigEndian.AppendUint16(nil, afiIPv6)
+	value = append(value, safiUnicast)
+	return appendUpdate(nil, nil, appendAttr(nil, attrFlagOptional, attrMPUnreachNLRI, value), nil)
+}
+
+// appendOpen appends a BGP OPEN message of the speaker, advertising the
+// IPv4 and IPv6 unicast families and 4-octet ASes.
+func appendOpen(b []byte, as uint32, bgpID netip.Addr) []byte {
+	caps := []byte{}
+	for _, family := range []uint16{afiIPv4, afiIPv6} {
+		caps = append(caps, capMultiprotocol, 4)
+		caps = binary.BigEndian.AppendUint16(caps, family)
+		caps = append(caps, 0, safiUnicast)
+	}
+	caps = append(caps, capFourOctetAS, 4)
+	caps = binary.BigEndian.AppendUint32(caps, as)
+
+	myAS := uint16(asTrans)
+	if as <= 0xffff {
+		myAS = uint16(as)
+	}
+
+	body := []byte{4}
+	body = binary.BigEndian.AppendUint16(body, myAS)
+	// The hold time is irrelevant to the monitoring station.
+	body = binary.BigEndian.AppendUint16(body, 0)
+	body = append(body, bgpIDBytes(bgpID)...)
+	body = append(body, byte(2+len(caps)), openParamCapabilities, byte(len(caps)))
+	body = append(body, caps...)
+
+	return appendBGPMessage(b, bgpMsgOpen, body)
+}
+
+// bgpIDBytes returns the BGP identifier, zero unless it is an IPv4
+// address.
+func bgpIDBytes(bgpID netip.Addr) []byte {
+	bgpID = bgpID.Unmap()
+	if !bgpID.Is4() {
+		return make([]byte, 4)
+	}
+	return bgpID.AsSlice()
+}
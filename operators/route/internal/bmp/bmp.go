@@ -0,0 +1,186 @@
+// Package bmp encodes the BGP Monitoring Protocol messages, RFC 7854.
+package bmp
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"time"
+)
+
+// version is the BMP version of the encoded messages.
+const version = 3
+
+// commonHeaderLen is the length of the common header of every message.
+const commonHeaderLen = 6
+
+// perPeerHeaderLen is the length of the per-peer header of the messages
+// about a monitored peer.
+const perPeerHeaderLen = 42
+
+// BMP message types, RFC 7854 Section 4.1.
+const (
+	msgRouteMonitoring = 0
+	msgPeerDown        = 2
+	msgPeerUp          = 3
+	msgInitiation      = 4
+	msgTermination     = 5
+)
+
+// Information TLV types of the Initiation and Peer Up messages.
+const (
+	infoString   = 0
+	infoSysDescr = 1
+	infoSysName  = 2
+)
+
+// Per-peer header flags, RFC 7854 Section 4.2.
+const (
+	peerFlagIPv6       = 0x80
+	peerFlagPostPolicy = 0x40
+)
+
+// PeerType is the type of a monitored peer.
+type PeerType uint8
+
+const (
+	// PeerTypeGlobal is a peer of the global routing instance.
+	PeerTypeGlobal PeerType = 0
+	// PeerTypeRD is a peer of the routing instance identified by the
+	// distinguisher.
+	PeerTypeRD PeerType = 1
+	// PeerTypeLocal is a peer of a local routing instance, also standing
+	// for the routes originated locally.
+	PeerTypeLocal PeerType = 2
+)
+
+// PeerDownReason is the reason a monitored peer is reported down.
+type PeerDownReason uint8
+
+// PeerDownDeconfigured reports that the messages about the peer are no
+// longer sent for configuration reasons.
+const PeerDownDeconfigured PeerDownReason = 5
+
+// TerminationReason is the reason the monitoring session is terminated.
+type TerminationReason uint16
+
+// TerminationAdminClosed reports that the session is closed
+// administratively.
+const TerminationAdminClosed TerminationReason = 0
+
+// Peer is a monitored peer, identified in the per-peer header.
+type Peer struct {
+	Type PeerType
+	// Distinguisher identifies the routing instance of a PeerTypeRD peer.
+	Distinguisher uint64
+	Address       netip.Addr
+	AS            uint32
+	// BGPID is the BGP identifier of the peer, zero unless it is an IPv4
+	// address.
+	BGPID netip.Addr
+	// PostPolicy reports that the routes of the peer are monitored after
+	// the import policy is applied.
+	PostPolicy bool
+}
+
+// Speaker is the local BGP speaker the monitored peers are reported to
+// peer with.
+type Speaker struct {
+	Address netip.Addr
+	AS      uint32
+	BGPID   netip.Addr
+}
+
+func appendCommonHeader(b []byte, msgType byte, length int) []byte {
+	b = append(b, version)
+	b = binary.BigEndian.AppendUint32(b, uint32(commonHeaderLen+length))
+	return append(b, msgType)
+}
+
+// appendAddr appends the address as the 16 octets BMP headers carry, an
+// IPv4 address in the last 4 of them.
+func appendAddr(b []byte, addr netip.Addr) []byte {
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return append(b, make([]byte, 16)...)
+	}
+	if addr.Is4() {
+		b = append(b, make([]byte, 12)...)
+	}
+	return append(b, addr.AsSlice()...)
+}
+
+func (m *Peer) appendHeader(b []byte, ts time.Time) []byte {
+	flags := byte(0)
+	if addr := m.Address.Unmap(); addr.IsValid() && !addr.Is4() {
+		flags |= peerFlagIPv6
+	}
+	if m.PostPolicy {
+		flags |= peerFlagPostPolicy
+	}
+
+	b = append(b, byte(m.Type), flags)
+	b = binary.BigEndian.AppendUint64(b, m.Distinguisher)
+	b = appendAddr(b, m.Address)
+	b = binary.BigEndian.AppendUint32(b, m.AS)
+	b = append(b, bgpIDBytes(m.BGPID)...)
+	if ts.IsZero() {
+		return append(b, make([]byte, 8)...)
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(ts.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(ts.Nanosecond()/1000))
+}
+
+func appendInfo(b []byte, infoType uint16, value string) []byte {
+	b = binary.BigEndian.AppendUint16(b, infoType)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	return append(b, value...)
+}
+
+// Initiation returns the Initiation message opening a monitoring session.
+func Initiation(sysName string, sysDescr string) []byte {
+	body := appendInfo(nil, infoSysDescr, sysDescr)
+	body = appendInfo(body, infoSysName, sysName)
+	return append(appendCommonHeader(nil, msgInitiation, len(body)), body...)
+}
+
+// Termination returns the Termination message closing a monitoring
+// session.
+func Termination(reason TerminationReason) []byte {
+	body := binary.BigEndian.AppendUint16(nil, 1)
+	body = binary.BigEndian.AppendUint16(body, 2)
+	body = binary.BigEndian.AppendUint16(body, uint16(reason))
+	return append(appendCommonHeader(nil, msgTermination, len(body)), body...)
+}
+
+// PeerUp returns the Peer Up notification of the peer, describing the
+// session between the speaker and the peer with the OPEN messages they are
+// reported to have exchanged.
+//
+// The info string, if any, is carried as an Information TLV.
+func PeerUp(peer Peer, ts time.Time, speaker Speaker, info string) []byte {
+	body := peer.appendHeader(nil, ts)
+	body = appendAddr(body, speaker.Address)
+	// The ports of the session are unknown.
+	body = append(body, 0, 0, 0, 0)
+	body = appendOpen(body, speaker.AS, speaker.BGPID)
+	body = appendOpen(body, peer.AS, peer.BGPID)
+	if info != "" {
+		body = appendInfo(body, infoString, info)
+	}
+	return append(appendCommonHeader(nil, msgPeerUp, len(body)), body...)
+}
+
+// PeerDown returns the Peer Down notification of the peer.
+func PeerDown(peer Peer, ts time.Time, reason PeerDownReason) []byte {
+	body := peer.appendHeader(nil, ts)
+	body = append(body, byte(reason))
+	return append(appendCommonHeader(nil, msgPeerDown, len(body)), body...)
+}
+
+// RouteMonitoring returns the Route Monitoring message carrying the BGP
+// UPDATE message of the peer.
+func RouteMonitoring(peer Peer, ts time.Time, update []byte) []byte {
+	b := appendCommonHeader(nil, msgRouteMonitoring, perPeerHeaderLen+len(update))
+	b = peer.appendHeader(b, ts)
+	return append(b, update...)
+}
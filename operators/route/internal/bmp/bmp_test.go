@@ -0,0 +1,240 @@
+package bmp
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// parseHeader checks the common header of the message, returning its type
+// and body.
+func parseHeader(t *testing.T, msg []byte) (byte, []byte) {
+	t.Helper()
+
+	require.GreaterOrEqual(t, len(msg), commonHeaderLen)
+	require.Equal(t, byte(version), msg[0])
+	require.Equal(t, uint32(len(msg)), binary.BigEndian.Uint32(msg[1:5]))
+	return msg[5], msg[commonHeaderLen:]
+}
+
+// parseBGPMessage checks the BGP header of the message at the start of b,
+// returning its type, body and the remaining bytes.
+func parseBGPMessage(t *testing.T, b []byte) (byte, []byte, []byte) {
+	t.Helper()
+
+	require.GreaterOrEqual(t, len(b), bgpHeaderLen)
+	for idx := range 16 {
+		require.Equal(t, byte(0xff), b[idx])
+	}
+	length := int(binary.BigEndian.Uint16(b[16:18]))
+	require.GreaterOrEqual(t, len(b), length)
+	return b[18], b[bgpHeaderLen:length], b[length:]
+}
+
+// parseUpdate returns the path attributes of an UPDATE body by their type,
+// along with the withdrawn routes and the NLRI.
+func parseUpdate(t *testing.T, body []byte) ([]byte, map[byte][]byte, []byte) {
+	t.Helper()
+
+	withdrawnLen := int(binary.BigEndian.Uint16(body))
+	withdrawn := body[2 : 2+withdrawnLen]
+	body = body[2+withdrawnLen:]
+	attrsLen := int(binary.BigEndian.Uint16(body))
+	attrs := body[2 : 2+attrsLen]
+	nlri := body[2+attrsLen:]
+
+	out := map[byte][]byte{}
+	for len(attrs) > 0 {
+		flags, code := attrs[0], attrs[1]
+		var length, offset int
+		if flags&attrFlagExtLen != 0 {
+			length, offset = int(binary.BigEndian.Uint16(attrs[2:])), 4
+		} else {
+			length, offset = int(attrs[2]), 3
+		}
+		out[code] = attrs[offset : offset+length]
+		attrs = attrs[offset+length:]
+	}
+	return withdrawn, out, nlri
+}
+
+func TestRouteMonitoringIPv4(t *testing.T) {
+	peer := Peer{
+		Type:          PeerTypeRD,
+		Distinguisher: 0x0001_0000_0000_0002,
+		Address:       netip.MustParseAddr("192.0.2.1"),
+		AS:            64512,
+		BGPID:         netip.MustParseAddr("192.0.2.1"),
+		PostPolicy:    true,
+	}
+	ts := time.Unix(1700000000, 5000)
+
+	update := Update{
+		Prefix:           netip.MustParsePrefix("::ffff:10.0.0.0/104"),
+		NextHop:          netip.MustParseAddr("::ffff:192.0.2.1"),
+		ASPath:           []uint32{64512, 64512, 65001},
+		Med:              10,
+		LocalPref:        100,
+		Communities:      []uint32{65535<<16 | 666},
+		LargeCommunities: [][3]uint32{{64512, 1, 2}},
+	}
+	msgType, body := parseHeader(t, RouteMonitoring(peer, ts, update.Marshal()))
+	require.Equal(t, byte(msgRouteMonitoring), msgType)
+
+	header := body[:perPeerHeaderLen]
+	require.Equal(t, byte(PeerTypeRD), header[0])
+	require.Equal(t, byte(peerFlagPostPolicy), header[1])
+	require.Equal(t, peer.Distinguisher, binary.BigEndian.Uint64(header[2:10]))
+	require.Equal(t, append(make([]byte, 12), 192, 0, 2, 1), header[10:26])
+	require.Equal(t, uint32(64512), binary.BigEndian.Uint32(header[26:30]))
+	require.Equal(t, []byte{192, 0, 2, 1}, header[30:34])
+	require.Equal(t, uint32(1700000000), binary.BigEndian.Uint32(header[34:38]))
+	require.Equal(t, uint32(5), binary.BigEndian.Uint32(header[38:42]))
+
+	bgpType, bgpBody, rest := parseBGPMessage(t, body[perPeerHeaderLen:])
+	require.Equal(t, byte(bgpMsgUpdate), bgpType)
+	require.Empty(t, rest)
+
+	withdrawn, attrs, nlri := parseUpdate(t, bgpBody)
+	require.Empty(t, withdrawn)
+	require.Equal(t, []byte{8, 10}, nlri)
+	require.Equal(t, []byte{originIncomplete}, attrs[attrOrigin])
+	require.Equal(t, []byte{
+		asPathSequence, 3,
+		0, 0, 0xfc, 0x00,
+		0, 0, 0xfc, 0x00,
+		0, 0, 0xfd, 0xe9,
+	}, attrs[attrASPath])
+	require.Equal(t, []byte{192, 0, 2, 1}, attrs[attrNextHop])
+	require.Equal(t, []byte{0, 0, 0, 10}, attrs[attrMed])
+	require.Equal(t, []byte{0, 0, 0, 100}, attrs[attrLocalPref])
+	require.Equal(t, []byte{0xff, 0xff, 0x02, 0x9a}, attrs[attrCommunities])
+	require.Equal(t, []byte{0, 0, 0xfc, 0, 0, 0, 0, 1, 0, 0, 0, 2}, attrs[attrLargeCommunities])
+	require.NotContains(t, attrs, byte(attrMPReachNLRI))
+}
+
+func TestUpdateMultiprotocol(t *testing.T) {
+	// IPv6 routes and IPv4 routes with an IPv6 nexthop are announced in
+	// MP_REACH_NLRI.
+	for _, tc := range []struct {
+		prefix string
+		afi    uint16
+		nlri   []byte
+	}{
+		{prefix: "2001:db8::/32", afi: afiIPv6, nlri: []byte{32, 0x20, 0x01, 0x0d, 0xb8}},
+		{prefix: "::ffff:198.51.100.0/120", afi: afiIPv4, nlri: []byte{24, 198, 51, 100}},
+	} {
+		nexthop := netip.MustParseAddr("2001:db8::1")
+		update := Update{Prefix: netip.MustParsePrefix(tc.prefix), NextHop: nexthop}
+
+		_, body, _ := parseBGPMessage(t, update.Marshal())
+		_, attrs, nlri := parseUpdate(t, body)
+		require.Empty(t, nlri)
+		require.NotContains(t, attrs, byte(attrNextHop))
+
+		reach := attrs[attrMPReachNLRI]
+		require.Equal(t, tc.afi, binary.BigEndian.Uint16(reach))
+		require.Equal(t, byte(safiUnicast), reach[2])
+		require.Equal(t, byte(16), reach[3])
+		require.Equal(t, nexthop.AsSlice(), reach[4:20])
+		require.Equal(t, byte(0), reach[20])
+		require.Equal(t, tc.nlri, reach[21:])
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	_, body, _ := parseBGPMessage(t, Withdraw(netip.MustParsePrefix("::ffff:10.1.128.0/113")))
+	withdrawn, attrs, nlri := parseUpdate(t, body)
+	require.Equal(t, []byte{17, 10, 1, 128}, withdrawn)
+	require.Empty(t, attrs)
+	require.Empty(t, nlri)
+
+	_, body, _ = parseBGPMessage(t, Withdraw(netip.MustParsePrefix("2001:db8::/48")))
+	withdrawn, attrs, _ = parseUpdate(t, body)
+	require.Empty(t, withdrawn)
+	require.Equal(t, []byte{0, afiIPv6, safiUnicast, 48, 0x20, 0x01, 0x0d, 0xb8, 0, 0}, attrs[attrMPUnreachNLRI])
+
+	// The End-of-RIB markers are empty UPDATE messages.
+	_, body, _ = parseBGPMessage(t, EndOfRIB(false))
+	require.Equal(t, []byte{0, 0, 0, 0}, body)
+	_, body, _ = parseBGPMessage(t, EndOfRIB(true))
+	_, attrs, _ = parseUpdate(t, body)
+	require.Equal(t, []byte{0, afiIPv6, safiUnicast}, attrs[attrMPUnreachNLRI])
+}
+
+func TestLongAttributes(t *testing.T) {
+	update := Update{
+		Prefix:      netip.MustParsePrefix("10.0.0.0/8"),
+		NextHop:     netip.MustParseAddr("192.0.2.1"),
+		ASPath:      make([]uint32, 300),
+		Communities: make([]uint32, 100),
+	}
+	_, body, rest := parseBGPMessage(t, update.Marshal())
+	require.Empty(t, rest)
+
+	_, attrs, _ := parseUpdate(t, body)
+	require.Len(t, attrs[attrCommunities], 400)
+	// The path is split into segments of at most 255 ASes.
+	asPath := attrs[attrASPath]
+	require.Len(t, asPath, 2+255*4+2+45*4)
+	require.Equal(t, []byte{asPathSequence, 255}, asPath[:2])
+	require.Equal(t, []byte{asPathSequence, 45}, asPath[2+255*4:2+255*4+2])
+}
+
+func TestPeerUp(t *testing.T) {
+	peer := Peer{
+		Type:    PeerTypeGlobal,
+		Address: netip.MustParseAddr("2001:db8::2"),
+		AS:      4200000000,
+	}
+	speaker := Speaker{
+		Address: netip.MustParseAddr("2001:db8::1"),
+		AS:      64512,
+		BGPID:   netip.MustParseAddr("192.0.2.254"),
+	}
+	msgType, body := parseHeader(t, PeerUp(peer, time.Time{}, speaker, "config route0"))
+	require.Equal(t, byte(msgPeerUp), msgType)
+
+	require.Equal(t, byte(peerFlagIPv6), body[1])
+	require.Equal(t, make([]byte, 8), body[34:42])
+	body = body[perPeerHeaderLen:]
+	require.Equal(t, speaker.Address.AsSlice(), body[:16])
+	body = body[20:]
+
+	bgpType, sent, body := parseBGPMessage(t, body)
+	require.Equal(t, byte(bgpMsgOpen), bgpType)
+	require.Equal(t, byte(4), sent[0])
+	require.Equal(t, uint16(64512), binary.BigEndian.Uint16(sent[1:3]))
+	require.Equal(t, []byte{192, 0, 2, 254}, sent[5:9])
+
+	_, received, body := parseBGPMessage(t, body)
+	// 4-octet ASes are announced as AS_TRANS, the AS is in the capability.
+	require.Equal(t, uint16(asTrans), binary.BigEndian.Uint16(received[1:3]))
+	caps := received[12:]
+	require.Equal(t, []byte{capFourOctetAS, 4}, caps[len(caps)-6:len(caps)-4])
+	require.Equal(t, uint32(4200000000), binary.BigEndian.Uint32(caps[len(caps)-4:]))
+
+	require.Equal(t, []byte{0, infoString, 0, 13}, body[:4])
+	require.Equal(t, "config route0", string(body[4:]))
+}
+
+func TestSessionMessages(t *testing.T) {
+	msgType, body := parseHeader(t, Initiation("yanet", "yanet route operator"))
+	require.Equal(t, byte(msgInitiation), msgType)
+	require.Equal(t, append([]byte{0, infoSysDescr, 0, 20}, "yanet route operator"...), body[:24])
+	require.Equal(t, append([]byte{0, infoSysName, 0, 5}, "yanet"...), body[24:])
+
+	msgType, body = parseHeader(t, Termination(TerminationAdminClosed))
+	require.Equal(t, byte(msgTermination), msgType)
+	require.Equal(t, []byte{0, 1, 0, 2, 0, 0}, body)
+
+	peer := Peer{Type: PeerTypeLocal}
+	msgType, body = parseHeader(t, PeerDown(peer, time.Unix(1, 0), PeerDownDeconfigured))
+	require.Equal(t, byte(msgPeerDown), msgType)
+	require.Len(t, body, perPeerHeaderLen+1)
+	require.Equal(t, byte(PeerTypeLocal), body[0])
+	require.Equal(t, byte(PeerDownDeconfigured), body[perPeerHeaderLen])
+}
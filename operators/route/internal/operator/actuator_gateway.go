@@ -4,16 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	"github.com/yanet-platform/yanet2/common/go/operator"
 	ynpb "github.com/yanet-platform/yanet2/controlplane/ynpb/v1"
 	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
 	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
 )
 
 // GatewayActuator applies route-operator state to a single Gateway via
@@ -24,8 +30,51 @@ type GatewayActuator struct {
 	routes      routepb.RouteServiceClient
 	funcApplier *operator.FunctionApplier
 	devices     []string
-	onFIBBuilt  func(module string, stats FIBBuildStats)
-	log         *zap.Logger
+	// maxBlackholes limits the number of drop routes per FIB.
+	maxBlackholes int
+	// delta enables pushing the FIBs as deltas of their previous push.
+	delta      bool
+	onFIBBuilt func(module string, stats FIBBuildStats)
+	faults     *FaultInjector
+	log        *zap.Logger
+
+	mu        sync.Mutex
+	lastApply GatewayCommit
+	// pushed keeps the FIB last pushed of every module config, the base of
+	// its next delta. Empty unless delta is set.
+	pushed map[string]pushedFIB
+}
+
+// pushedFIB is a FIB the gateway acknowledged.
+type pushedFIB struct {
+	generation uint64
+	entries    map[netip.Prefix]FIBEntry
+}
+
+// FIBPush is the outcome of pushing the FIB of one module config to a
+// gateway.
+type FIBPush struct {
+	Module  string
+	Entries int
+	// Generation is the module config generation the gateway published
+	// the FIB as, zero if the push failed.
+	Generation uint64
+	// Delta reports that only the changes since the previous push were
+	// sent, Changed of them.
+	Delta    bool
+	Changed  int
+	Duration time.Duration
+	Err      error
+}
+
+// GatewayCommit is the outcome of an Apply on a single gateway.
+type GatewayCommit struct {
+	Gateway string
+	// FIBs are the pushes of every module config, ordered by module name.
+	FIBs []FIBPush
+	// Err is the joined error of the Apply, including the function
+	// publishing one.
+	Err error
 }
 
 // NewGatewayActuator dials the Gateway endpoint and returns a
@@ -44,9 +93,13 @@ func NewGatewayActuator(
 	}
 
 	endpoint := cfg.Endpoint.Unwrap()
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS of gateway %q: %w", cfg.Name, err)
+	}
 	conn, err := grpc.NewClient(
 		endpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial gateway %q at %q: %w", cfg.Name, endpoint, err)
@@ -72,12 +125,16 @@ func NewGatewayActuator(
 			spec,
 			operator.WithIgnorePdump(fn.IgnorePdump),
 		),
-		devices:    opts.Devices,
-		onFIBBuilt: opts.OnFIBBuilt,
+		devices:       opts.Devices,
+		maxBlackholes: opts.MaxBlackholes,
+		delta:         opts.Delta,
+		onFIBBuilt:    opts.OnFIBBuilt,
+		faults:        opts.Faults,
 		log: opts.Log.With(
 			zap.String("gateway", cfg.Name),
 			zap.String("function", fn.Name.Unwrap()),
 		),
+		pushed: map[string]pushedFIB{},
 	}, nil
 }
 
@@ -86,27 +143,90 @@ func (m *GatewayActuator) Close() error {
 	return m.conn.Close()
 }
 
+// Name returns the name of the gateway.
+func (m *GatewayActuator) Name() string {
+	return m.name
+}
+
 // Apply builds and pushes the FIB for each module config to the gateway,
 // then republishes the operator's network function.
 //
 // Every FIB is attempted and the function is published even on a partial
 // failure; the joined errors let the reconcile loop retry under backoff.
+//
+// The outcome is kept until the next Apply, see LastApply.
 func (m *GatewayActuator) Apply(ctx context.Context, snapshot RouteSnapshot) error {
+	var pushes []FIBPush
+	err := m.apply(ctx, snapshot, false, func(push FIBPush) {
+		pushes = append(pushes, push)
+	})
+	sort.Slice(pushes, func(i, j int) bool {
+		return pushes[i].Module < pushes[j].Module
+	})
+
+	m.mu.Lock()
+	m.lastApply = GatewayCommit{Gateway: m.name, FIBs: pushes, Err: err}
+	m.mu.Unlock()
+
+	return err
+}
+
+// LastApply returns the outcome of the last Apply.
+//
+// Resyncs are not recorded.
+func (m *GatewayActuator) LastApply() GatewayCommit {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastApply
+}
+
+// Resync applies the snapshot like Apply, reporting the outcome of every
+// FIB to onFIB as soon as it is pushed.
+//
+// The FIBs are always pushed in full, so a resync recovers a gateway whose
+// module configs drifted from the deltas pushed to it.
+func (m *GatewayActuator) Resync(
+	ctx context.Context,
+	snapshot RouteSnapshot,
+	onFIB func(module string, entries int, err error),
+) error {
+	return m.apply(ctx, snapshot, true, func(push FIBPush) {
+		onFIB(push.Module, push.Entries, push.Err)
+	})
+}
+
+// apply builds and pushes the FIB of every module config in the snapshot,
+// then republishes the function, reporting every push to onFIB.
+//
+// With full set, the FIBs are pushed in full even if delta pushes are
+// enabled.
+func (m *GatewayActuator) apply(ctx context.Context, snapshot RouteSnapshot, full bool, onFIB func(FIBPush)) error {
 	neighbours := neigh.FilterByDevices(snapshot.Neighbours, m.devices)
 
 	var err error
 	for name, dump := range snapshot.RIBs {
 		if name == "" {
-			err = errors.Join(err, fmt.Errorf("FIB is missing module config name"))
+			e := fmt.Errorf("FIB is missing module config name")
+			onFIB(FIBPush{Err: e})
+			err = errors.Join(err, e)
 			continue
 		}
 
-		fib, stats := BuildFIB(dump, neighbours)
+		fib, stats := BuildFIB(dump, neighbours, m.maxBlackholes, snapshot.Degraded)
 		fib.Name = name
 		m.onFIBBuilt(name, stats)
-		if e := m.pushFIB(ctx, fib); e != nil {
-			err = errors.Join(err, fmt.Errorf("failed to push FIB to gateway %q: %w", m.name, e))
+
+		start := time.Now()
+		push, e := m.pushFIB(ctx, fib, full)
+		push.Module = name
+		push.Entries = len(fib.Entries)
+		push.Duration = time.Since(start)
+		if e != nil {
+			push.Err = fmt.Errorf("failed to push FIB to gateway %q: %w", m.name, e)
+			err = errors.Join(err, push.Err)
 		}
+		onFIB(push)
 	}
 
 	return errors.Join(err, m.applyFunction(ctx))
@@ -129,24 +249,125 @@ func (m *GatewayActuator) applyFunction(ctx context.Context) error {
 	return nil
 }
 
-// pushFIB applies fib to the gateway via the UpdateFIB unary RPC.
-func (m *GatewayActuator) pushFIB(ctx context.Context, fib FIB) error {
+// pushFIB applies fib to the gateway, reporting the generation of the
+// module config it was published as.
+//
+// With delta pushes enabled, only the changes since the previous push of
+// the module config are sent, unless full is set. A delta the gateway
+// refuses because its module config moved on, e.g. after a restart, is
+// followed by a full push, and so is the push after a failed one.
+func (m *GatewayActuator) pushFIB(ctx context.Context, fib FIB, full bool) (FIBPush, error) {
+	if m.faults.CorruptFIB() {
+		m.log.Warn("corrupted FIB by injected fault", zap.String("name", fib.Name))
+		m.forgetFIB(fib.Name)
+		generation, err := m.updateFIB(ctx, &routepb.UpdateFIBRequest{
+			ModuleName: fib.Name,
+			Entries:    []*routepb.FIBEntry{{Prefix: corruptedFIBPrefix}},
+		})
+		return FIBPush{Generation: generation}, err
+	}
+
+	if !m.delta {
+		generation, err := m.updateFIB(ctx, fullFIBRequest(fib))
+		return FIBPush{Generation: generation}, err
+	}
+
+	m.mu.Lock()
+	base, ok := m.pushed[fib.Name]
+	m.mu.Unlock()
+
+	push := FIBPush{}
+	var err error
+	if ok && !full {
+		delta := diffFIB(base.entries, fib)
+		push.Generation, err = m.updateFIB(ctx, deltaFIBRequest(fib.Name, base.generation, delta))
+		switch {
+		case err == nil:
+			push.Delta = true
+			push.Changed = delta.Len()
+		case status.Code(err) == codes.FailedPrecondition:
+			m.log.Info("gateway refused FIB delta, pushing full FIB",
+				zap.String("name", fib.Name),
+				zap.Uint64("base_generation", base.generation),
+			)
+			push.Generation, err = m.updateFIB(ctx, fullFIBRequest(fib))
+		}
+	} else {
+		push.Generation, err = m.updateFIB(ctx, fullFIBRequest(fib))
+	}
+	if err != nil {
+		m.forgetFIB(fib.Name)
+		return push, err
+	}
+
+	m.mu.Lock()
+	m.pushed[fib.Name] = pushedFIB{
+		generation: push.Generation,
+		entries:    fibEntriesByPrefix(fib),
+	}
+	m.mu.Unlock()
+
+	return push, nil
+}
+
+// forgetFIB drops the base of the next delta of a module config, so the
+// next push is a full one.
+func (m *GatewayActuator) forgetFIB(name string) {
+	m.mu.Lock()
+	delete(m.pushed, name)
+	m.mu.Unlock()
+}
+
+// updateFIB calls the UpdateFIB unary RPC, returning the module config
+// generation the FIB was published as.
+func (m *GatewayActuator) updateFIB(ctx context.Context, req *routepb.UpdateFIBRequest) (uint64, error) {
+	response, err := m.routes.UpdateFIB(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call UpdateFIB: %w", err)
+	}
+
+	m.log.Debug("pushed FIB to gateway",
+		zap.String("name", req.GetModuleName()),
+		zap.Bool("delta", req.GetDelta()),
+		zap.Int("entries", len(req.GetEntries())),
+		zap.Int("removed", len(req.GetRemovedPrefixes())),
+		zap.Uint64("generation", response.GetGeneration()),
+	)
+	return response.GetGeneration(), nil
+}
+
+// fullFIBRequest returns the request pushing the whole FIB.
+func fullFIBRequest(fib FIB) *routepb.UpdateFIBRequest {
 	entries := make([]*routepb.FIBEntry, len(fib.Entries))
 	for idx, entry := range fib.Entries {
 		entries[idx] = fibEntryToProto(entry)
 	}
 
-	req := &routepb.UpdateFIBRequest{
+	return &routepb.UpdateFIBRequest{
 		ModuleName: fib.Name,
 		Entries:    entries,
 	}
+}
 
-	if _, err := m.routes.UpdateFIB(ctx, req); err != nil {
-		return fmt.Errorf("failed to call UpdateFIB: %w", err)
+// deltaFIBRequest returns the request pushing the changes of a FIB since
+// the base generation.
+func deltaFIBRequest(name string, base uint64, delta fibDelta) *routepb.UpdateFIBRequest {
+	entries := make([]*routepb.FIBEntry, len(delta.Changed))
+	for idx, entry := range delta.Changed {
+		entries[idx] = fibEntryToProto(entry)
+	}
+	removed := make([]string, len(delta.Removed))
+	for idx, prefix := range delta.Removed {
+		removed[idx] = prefix.String()
 	}
 
-	m.log.Debug("pushed FIB to gateway", zap.String("name", fib.Name))
-	return nil
+	return &routepb.UpdateFIBRequest{
+		ModuleName:      name,
+		Entries:         entries,
+		Delta:           true,
+		BaseGeneration:  base,
+		RemovedPrefixes: removed,
+	}
 }
 
 // fibEntryToProto converts an internal FIBEntry to the wire format.
@@ -158,9 +379,29 @@ func fibEntryToProto(entry FIBEntry) *routepb.FIBEntry {
 			DstMac: commonpb.NewMACAddressEUI48(nh.DestinationMAC),
 			Device: nh.Device,
 		}
+		if entry.Weights != nil {
+			nexthops[idx].Weight = entry.Weights[idx]
+		}
+	}
+	sources := make([]routepb.RouteSource, 0, len(entry.Sources))
+	for _, source := range entry.Sources {
+		sources = append(sources, routeSourceToProto(source))
 	}
 	return &routepb.FIBEntry{
-		Prefix:   entry.Prefix.String(),
-		Nexthops: nexthops,
+		Prefix:    entry.Prefix.String(),
+		Nexthops:  nexthops,
+		Blackhole: entry.Blackhole,
+		Sources:   sources,
+	}
+}
+
+func routeSourceToProto(source rib.RouteSourceID) routepb.RouteSource {
+	switch source {
+	case rib.RouteSourceStatic:
+		return routepb.RouteSource_ROUTE_SOURCE_STATIC
+	case rib.RouteSourceBird:
+		return routepb.RouteSource_ROUTE_SOURCE_BIRD
+	default:
+		return routepb.RouteSource_ROUTE_SOURCE_UNSPECIFIED
 	}
 }
@@ -0,0 +1,126 @@
+package operator
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/modules/route/controlplane/routepb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+)
+
+// fakeRouteClient accepts the FIBs pushed to it like the route module
+// does, refusing the deltas that are not based on its generation.
+type fakeRouteClient struct {
+	routepb.RouteServiceClient
+	generation uint64
+	requests   []*routepb.UpdateFIBRequest
+}
+
+func (m *fakeRouteClient) UpdateFIB(
+	ctx context.Context,
+	req *routepb.UpdateFIBRequest,
+	opts ...grpc.CallOption,
+) (*routepb.UpdateFIBResponse, error) {
+	m.requests = append(m.requests, req)
+	if req.GetDelta() && req.GetBaseGeneration() != m.generation {
+		return nil, status.Error(codes.FailedPrecondition, "stale base generation")
+	}
+	if !req.GetDelta() || len(req.GetEntries())+len(req.GetRemovedPrefixes()) > 0 {
+		m.generation++
+	}
+	return &routepb.UpdateFIBResponse{Generation: m.generation}, nil
+}
+
+func TestGatewayActuator_PushFIBDelta(t *testing.T) {
+	client := &fakeRouteClient{}
+	actuator := &GatewayActuator{
+		name:   "gw0",
+		routes: client,
+		delta:  true,
+		log:    zap.NewNop(),
+		pushed: map[string]pushedFIB{},
+	}
+
+	route := neigh.HardwareRoute{Device: "eth0"}
+	entry := func(prefix string) FIBEntry {
+		return FIBEntry{Prefix: netip.MustParsePrefix(prefix), Nexthops: []neigh.HardwareRoute{route}}
+	}
+	fib := FIB{Name: "route0", Entries: []FIBEntry{entry("10.0.0.0/24"), entry("10.0.1.0/24")}}
+
+	// The first push has no base.
+	push, err := actuator.pushFIB(t.Context(), fib, false)
+	require.NoError(t, err)
+	require.False(t, push.Delta)
+	require.Equal(t, uint64(1), push.Generation)
+
+	fib.Entries = []FIBEntry{entry("10.0.0.0/24"), entry("10.0.2.0/24")}
+	push, err = actuator.pushFIB(t.Context(), fib, false)
+	require.NoError(t, err)
+	require.True(t, push.Delta)
+	require.Equal(t, 2, push.Changed)
+	last := client.requests[len(client.requests)-1]
+	require.True(t, last.GetDelta())
+	require.Equal(t, uint64(1), last.GetBaseGeneration())
+	require.Len(t, last.GetEntries(), 1)
+	require.Equal(t, "10.0.2.0/24", last.GetEntries()[0].GetPrefix())
+	require.Equal(t, []string{"10.0.1.0/24"}, last.GetRemovedPrefixes())
+
+	// An unchanged FIB is confirmed with an empty delta.
+	push, err = actuator.pushFIB(t.Context(), fib, false)
+	require.NoError(t, err)
+	require.True(t, push.Delta)
+	require.Zero(t, push.Changed)
+	require.Equal(t, uint64(2), push.Generation)
+
+	// The gateway restarted: the delta is refused and the FIB pushed in
+	// full.
+	client.generation = 0
+	requests := len(client.requests)
+	push, err = actuator.pushFIB(t.Context(), fib, false)
+	require.NoError(t, err)
+	require.False(t, push.Delta)
+	require.Equal(t, uint64(1), push.Generation)
+	require.Len(t, client.requests, requests+2)
+	require.Len(t, client.requests[requests+1].GetEntries(), 2)
+
+	push, err = actuator.pushFIB(t.Context(), fib, true)
+	require.NoError(t, err)
+	require.False(t, push.Delta, "full push requested")
+}
+
+func TestDiffFIB(t *testing.T) {
+	route := func(device string) []neigh.HardwareRoute {
+		return []neigh.HardwareRoute{{Device: device}}
+	}
+	base := fibEntriesByPrefix(FIB{Entries: []FIBEntry{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Nexthops: route("eth0")},
+		{Prefix: netip.MustParsePrefix("10.0.1.0/24"), Nexthops: route("eth0")},
+		{Prefix: netip.MustParsePrefix("10.0.2.0/24"), Nexthops: route("eth0")},
+		{Prefix: netip.MustParsePrefix("10.0.3.0/32"), Blackhole: true},
+	}})
+
+	delta := diffFIB(base, FIB{Entries: []FIBEntry{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Nexthops: route("eth0")},
+		{Prefix: netip.MustParsePrefix("10.0.1.0/24"), Nexthops: route("eth1")},
+		{Prefix: netip.MustParsePrefix("10.0.3.0/32"), Nexthops: route("eth0")},
+		{Prefix: netip.MustParsePrefix("10.0.4.0/24"), Nexthops: route("eth0")},
+	}})
+	changed := []netip.Prefix{}
+	for _, entry := range delta.Changed {
+		changed = append(changed, entry.Prefix)
+	}
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.1.0/24"),
+		netip.MustParsePrefix("10.0.3.0/32"),
+		netip.MustParsePrefix("10.0.4.0/24"),
+	}, changed)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.2.0/24")}, delta.Removed)
+	require.Equal(t, 4, delta.Len())
+}
@@ -0,0 +1,444 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/bmp"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// defaultBMPReconnectInterval is the default delay before a failed
+// collector session is started over.
+const defaultBMPReconnectInterval = 10 * time.Second
+
+const (
+	// bmpDiscoveryInterval is the period the exporter looks for the RIBs
+	// created since the session started at.
+	bmpDiscoveryInterval = time.Second
+	// bmpWriteTimeout bounds the time a message may take to be written, so
+	// that a stuck collector does not hold its session forever.
+	bmpWriteTimeout = 30 * time.Second
+)
+
+// bmpSysDescr describes the operator in the Initiation messages.
+const bmpSysDescr = "yanet2 route operator"
+
+// errBMPMissedChanges is returned when a session misses RIB changes.
+var errBMPMissedChanges = errors.New("missed RIB changes, starting over")
+
+// BMPExporter streams the RIBs to BMP collectors.
+//
+// A session is kept per collector: it reports the routes of every exported
+// RIB, then their changes as they happen. The collectors keep a single
+// route per peer and prefix, so static routes of a prefix with several
+// nexthops show up as one of them.
+//
+// A session that falls behind the RIB changes is closed and started over
+// rather than leaving the collector with a stale view.
+type BMPExporter struct {
+	collectors        []string
+	configs           []string
+	sysName           string
+	speaker           bmp.Speaker
+	reconnectInterval time.Duration
+	ribs              *RIBStore
+	log               *zap.Logger
+}
+
+// NewBMPExporter creates a BMPExporter of the RIBs of the store, returning
+// nil if no collectors are configured.
+func NewBMPExporter(cfg BMPConfig, ribs *RIBStore, log *zap.Logger) (*BMPExporter, error) {
+	if len(cfg.Collectors) == 0 {
+		return nil, nil
+	}
+
+	var routerID netip.Addr
+	if cfg.RouterID != "" {
+		addr, err := netip.ParseAddr(cfg.RouterID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BMP router ID %q: %w", cfg.RouterID, err)
+		}
+		if !addr.Is4() {
+			return nil, fmt.Errorf("BMP router ID %q is not an IPv4 address", cfg.RouterID)
+		}
+		routerID = addr
+	}
+
+	sysName := cfg.SysName
+	if sysName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get host name: %w", err)
+		}
+		sysName = hostname
+	}
+
+	reconnectInterval := cfg.ReconnectInterval
+	if reconnectInterval <= 0 {
+		reconnectInterval = defaultBMPReconnectInterval
+	}
+
+	return &BMPExporter{
+		collectors: cfg.Collectors,
+		configs:    cfg.Configs,
+		sysName:    sysName,
+		speaker: bmp.Speaker{
+			Address: routerID,
+			AS:      cfg.LocalAS,
+			BGPID:   routerID,
+		},
+		reconnectInterval: reconnectInterval,
+		ribs:              ribs,
+		log:               log,
+	}, nil
+}
+
+// Run exports the RIBs to every collector until the context is cancelled.
+func (m *BMPExporter) Run(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for _, addr := range m.collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.runCollector(ctx, addr)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// runCollector keeps a session to the collector, connecting again after
+// the session fails.
+func (m *BMPExporter) runCollector(ctx context.Context, addr string) {
+	log := m.log.With(zap.String("collector", addr))
+	dialer := net.Dialer{Timeout: m.reconnectInterval}
+
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			log.Info("connected to BMP collector")
+			err = m.export(ctx, conn)
+			_ = conn.Close()
+		}
+		if ctx.Err() != nil {
+			log.Info("stopped exporting to BMP collector")
+			return
+		}
+		log.Warn("BMP collector session failed, reconnecting",
+			zap.Duration("interval", m.reconnectInterval),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.reconnectInterval):
+		}
+	}
+}
+
+// exported reports whether the named RIB is exported.
+func (m *BMPExporter) exported(name string) bool {
+	return len(m.configs) == 0 || slices.Contains(m.configs, name)
+}
+
+// export runs a session over the connection until the context is
+// cancelled, which terminates the session, or the session fails.
+func (m *BMPExporter) export(ctx context.Context, conn net.Conn) error {
+	session := &bmpSession{conn: conn, speaker: m.speaker}
+	if err := session.write(bmp.Initiation(m.sysName, bmpSysDescr)); err != nil {
+		return err
+	}
+
+	sessionCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(bmpDiscoveryInterval)
+	defer ticker.Stop()
+
+	started := map[string]struct{}{}
+	for {
+		for name, holder := range m.ribs.Snapshot() {
+			if _, ok := started[name]; ok || !m.exported(name) {
+				continue
+			}
+			started[name] = struct{}{}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := m.exportRIB(sessionCtx, session, name, holder); err != nil {
+					cancel(fmt.Errorf("failed to export RIB %q: %w", name, err))
+				}
+			}()
+		}
+
+		select {
+		case <-sessionCtx.Done():
+			if ctx.Err() == nil {
+				return context.Cause(sessionCtx)
+			}
+			wg.Wait()
+			return session.write(bmp.Termination(bmp.TerminationAdminClosed))
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportRIB reports the routes of the RIB, then its changes until the
+// context is cancelled.
+func (m *BMPExporter) exportRIB(ctx context.Context, session *bmpSession, name string, holder *rib.RIB) error {
+	// The RIB is watched before it is dumped, so that no change is lost in
+	// between.
+	events, missed, stop := holder.WatchMissed()
+	defer stop()
+
+	export := newBMPRIBExport(session, name)
+
+	dump := holder.DumpRoutes()
+	for _, lists := range dump {
+		for _, list := range lists {
+			// The best routes go last, as the collectors keep the last
+			// route of a peer.
+			for _, route := range slices.Backward(list.Routes) {
+				if err := export.announce(&route); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := export.endOfRIB(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if missed() {
+				return errBMPMissedChanges
+			}
+
+			var err error
+			switch event.Kind {
+			case rib.RouteEventAdded:
+				err = export.announce(&event.Route)
+			case rib.RouteEventWithdrawn:
+				err = export.withdraw(holder, &event.Route, event.At)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// bmpSession is a session to a BMP collector, shared by the exported
+// RIBs.
+type bmpSession struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	speaker bmp.Speaker
+}
+
+func (m *bmpSession) write(msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.conn.SetWriteDeadline(time.Now().Add(bmpWriteTimeout)); err != nil {
+		return err
+	}
+	_, err := m.conn.Write(msg)
+	return err
+}
+
+// bmpRIBExport reports the routes of a RIB over a session.
+type bmpRIBExport struct {
+	session *bmpSession
+	name    string
+	// localDistinguisher tells the local instance peers of the RIBs apart.
+	localDistinguisher uint64
+	// peers are the peers reported up, with the address families of their
+	// routes since.
+	peers map[bmp.Peer]*bmpPeerFamilies
+}
+
+// bmpPeerFamilies are the address families a peer has routes in.
+type bmpPeerFamilies struct {
+	ipv4 bool
+	ipv6 bool
+}
+
+func newBMPRIBExport(session *bmpSession, name string) *bmpRIBExport {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+
+	return &bmpRIBExport{
+		session:            session,
+		name:               name,
+		localDistinguisher: hash.Sum64(),
+		peers:              map[bmp.Peer]*bmpPeerFamilies{},
+	}
+}
+
+// peer returns the monitored peer the route was learned from.
+func (m *bmpRIBExport) peer(route *rib.Route) bmp.Peer {
+	if route.SourceID != rib.RouteSourceBird {
+		return bmp.Peer{
+			Type:          bmp.PeerTypeLocal,
+			Distinguisher: m.localDistinguisher,
+			AS:            m.session.speaker.AS,
+			BGPID:         m.session.speaker.BGPID,
+			PostPolicy:    true,
+		}
+	}
+
+	peer := bmp.Peer{
+		Type:          bmp.PeerTypeGlobal,
+		Distinguisher: route.RD,
+		Address:       route.Peer.Unmap(),
+		AS:            route.PeerAS,
+		BGPID:         route.Peer.Unmap(),
+		PostPolicy:    true,
+	}
+	if route.RD != 0 {
+		peer.Type = bmp.PeerTypeRD
+	}
+	return peer
+}
+
+// families returns the address families of the peer, reporting the peer
+// up first if it was not yet.
+func (m *bmpRIBExport) families(peer bmp.Peer) (*bmpPeerFamilies, error) {
+	if families, ok := m.peers[peer]; ok {
+		return families, nil
+	}
+
+	msg := bmp.PeerUp(peer, time.Now(), m.session.speaker, "config "+m.name)
+	if err := m.session.write(msg); err != nil {
+		return nil, err
+	}
+	families := &bmpPeerFamilies{}
+	m.peers[peer] = families
+	return families, nil
+}
+
+func (m *bmpRIBExport) announce(route *rib.Route) error {
+	peer := m.peer(route)
+	families, err := m.families(peer)
+	if err != nil {
+		return err
+	}
+	if route.Prefix.Addr().Unmap().Is4() {
+		families.ipv4 = true
+	} else {
+		families.ipv6 = true
+	}
+
+	update := bmpUpdate(route)
+	return m.session.write(bmp.RouteMonitoring(peer, route.UpdatedAt, update.Marshal()))
+}
+
+// withdraw reports the route withdrawn.
+//
+// The peer is reported to announce another of its routes for the prefix
+// instead, if any is left.
+func (m *bmpRIBExport) withdraw(holder *rib.RIB, route *rib.Route, at time.Time) error {
+	peer := m.peer(route)
+	if _, ok := m.peers[peer]; !ok {
+		return nil
+	}
+
+	// Only static routes share their peer with other routes of the prefix.
+	if route.SourceID != rib.RouteSourceBird {
+		for _, other := range holder.MatchRoutes(rib.RouteFilter{Prefix: route.Prefix, SourceID: route.SourceID}) {
+			if other.Prefix == route.Prefix && m.peer(&other) == peer {
+				return m.announce(&other)
+			}
+		}
+	}
+
+	return m.session.write(bmp.RouteMonitoring(peer, at, bmp.Withdraw(route.Prefix)))
+}
+
+// endOfRIB reports the End-of-RIB markers of the peers reported so far,
+// once the routes of the RIB are.
+func (m *bmpRIBExport) endOfRIB() error {
+	now := time.Now()
+	for peer, families := range m.peers {
+		if families.ipv4 {
+			if err := m.session.write(bmp.RouteMonitoring(peer, now, bmp.EndOfRIB(false))); err != nil {
+				return err
+			}
+		}
+		if families.ipv6 {
+			if err := m.session.write(bmp.RouteMonitoring(peer, now, bmp.EndOfRIB(true))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bmpUpdate returns the BGP announcement of the route.
+func bmpUpdate(route *rib.Route) bmp.Update {
+	update := bmp.Update{
+		Prefix:    route.Prefix,
+		NextHop:   route.NextHop,
+		ASPath:    bmpASPath(route),
+		Med:       route.Med,
+		LocalPref: route.Pref,
+	}
+	for _, community := range route.Communities {
+		update.Communities = append(update.Communities, uint32(community.ASN)<<16|uint32(community.Value))
+	}
+	for _, community := range route.ExtCommunities {
+		value := [8]byte{community.Type, community.SubType}
+		for idx := range 6 {
+			value[2+idx] = byte(community.Value >> (8 * (5 - idx)))
+		}
+		update.ExtCommunities = append(update.ExtCommunities, value)
+	}
+	for _, community := range route.LargeCommunities {
+		update.LargeCommunities = append(update.LargeCommunities, [3]uint32{
+			community.GlobalAdministrator,
+			community.LocalDataPart1,
+			community.LocalDataPart2,
+		})
+	}
+	return update
+}
+
+// bmpASPath rebuilds the AS path of the route.
+//
+// The RIB keeps the neighbour and the origin ASes and the path length
+// only, so the path is the neighbour AS prepended up to the length,
+// followed by the origin AS.
+func bmpASPath(route *rib.Route) []uint32 {
+	if route.ASPathLen == 0 {
+		return nil
+	}
+
+	path := make([]uint32, route.ASPathLen)
+	for idx := range path {
+		path[idx] = route.PeerAS
+	}
+	path[len(path)-1] = route.OriginAS
+	return path
+}
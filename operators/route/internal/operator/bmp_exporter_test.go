@@ -0,0 +1,139 @@
+package operator
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// readBMPMessage reads a BMP message, returning its type and body.
+func readBMPMessage(t *testing.T, conn net.Conn) (byte, []byte) {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	header := make([]byte, 6)
+	_, err := io.ReadFull(conn, header)
+	require.NoError(t, err)
+	require.Equal(t, byte(3), header[0])
+
+	body := make([]byte, binary.BigEndian.Uint32(header[1:5])-6)
+	_, err = io.ReadFull(conn, body)
+	require.NoError(t, err)
+	return header[5], body
+}
+
+func TestBMPExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ribs := newRIBStore(zap.NewNop())
+	holder := ribs.GetOrCreate("route0")
+	holder.Update(rib.Route{
+		Prefix:    netip.MustParsePrefix("::ffff:10.0.0.0/120"),
+		NextHop:   netip.MustParseAddr("::ffff:192.0.2.1"),
+		Peer:      netip.MustParseAddr("::ffff:192.0.2.1"),
+		PeerAS:    65001,
+		OriginAS:  65002,
+		ASPathLen: 2,
+		SourceID:  rib.RouteSourceBird,
+	})
+	// Routes of the RIBs that are not exported are not reported.
+	ribs.GetOrCreate("route1").Update(rib.Route{
+		Prefix:   netip.MustParsePrefix("::ffff:10.1.0.0/120"),
+		NextHop:  netip.MustParseAddr("::ffff:192.0.2.1"),
+		Peer:     netip.MustParseAddr("::ffff:192.0.2.1"),
+		SourceID: rib.RouteSourceBird,
+	})
+
+	exporter, err := NewBMPExporter(BMPConfig{
+		Collectors: []string{listener.Addr().String()},
+		Configs:    []string{"route0"},
+		SysName:    "yanet",
+		LocalAS:    64512,
+		RouterID:   "192.0.2.254",
+	}, ribs, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = exporter.Run(ctx)
+	}()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	msgType, _ := readBMPMessage(t, conn)
+	require.Equal(t, byte(4), msgType)
+
+	// The peer is reported up before its routes, followed by End-of-RIB.
+	msgType, body := readBMPMessage(t, conn)
+	require.Equal(t, byte(3), msgType)
+	require.Equal(t, []byte{192, 0, 2, 1}, body[22:26])
+	require.Equal(t, uint32(65001), binary.BigEndian.Uint32(body[26:30]))
+
+	msgType, body = readBMPMessage(t, conn)
+	require.Equal(t, byte(0), msgType)
+	require.Equal(t, []byte{24, 10, 0, 0}, body[len(body)-4:])
+	require.Contains(t, string(body), string([]byte{2, 2, 0, 0, 0xfd, 0xe9, 0, 0, 0xfd, 0xea}))
+
+	msgType, body = readBMPMessage(t, conn)
+	require.Equal(t, byte(0), msgType)
+	require.Len(t, body, 42+23)
+
+	// Static routes are routes of the local instance peer.
+	prefix := netip.MustParsePrefix("198.51.100.0/24")
+	require.NoError(t, holder.AddUnicastRoute(prefix, netip.MustParseAddr("192.0.2.2"), rib.RouteSourceStatic))
+	require.NoError(t, holder.AddUnicastRoute(prefix, netip.MustParseAddr("192.0.2.3"), rib.RouteSourceStatic))
+
+	msgType, body = readBMPMessage(t, conn)
+	require.Equal(t, byte(3), msgType)
+	require.Equal(t, byte(2), body[0])
+	require.Equal(t, uint32(64512), binary.BigEndian.Uint32(body[26:30]))
+	for _, nexthop := range []byte{2, 3} {
+		msgType, body = readBMPMessage(t, conn)
+		require.Equal(t, byte(0), msgType)
+		require.Contains(t, string(body), string([]byte{0x40, 3, 4, 192, 0, 2, nexthop}))
+	}
+
+	// A static route withdrawn leaves the other one of the prefix
+	// announced, until it is withdrawn too.
+	require.NoError(t, holder.RemoveUnicastRoute(prefix, netip.MustParseAddr("192.0.2.3"), rib.RouteSourceStatic))
+	_, body = readBMPMessage(t, conn)
+	require.Contains(t, string(body), string([]byte{0x40, 3, 4, 192, 0, 2, 2}))
+
+	require.NoError(t, holder.RemoveUnicastRoute(prefix, netip.MustParseAddr("192.0.2.2"), rib.RouteSourceStatic))
+	_, body = readBMPMessage(t, conn)
+	require.Equal(t, []byte{0, 4, 24, 198, 51, 100, 0, 0}, body[42+19:])
+
+	cancel()
+	msgType, _ = readBMPMessage(t, conn)
+	require.Equal(t, byte(5), msgType)
+	<-done
+}
+
+func TestNewBMPExporter(t *testing.T) {
+	exporter, err := NewBMPExporter(BMPConfig{}, newRIBStore(zap.NewNop()), zap.NewNop())
+	require.NoError(t, err)
+	require.Nil(t, exporter)
+
+	for _, routerID := range []string{"router", "2001:db8::1"} {
+		_, err := NewBMPExporter(BMPConfig{
+			Collectors: []string{"127.0.0.1:11019"},
+			RouterID:   routerID,
+		}, newRIBStore(zap.NewNop()), zap.NewNop())
+		require.Error(t, err)
+	}
+}
@@ -2,13 +2,19 @@ package operator
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
 	"time"
 
 	"go.uber.org/zap/zapcore"
 
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/common/go/grpctls"
 	"github.com/yanet-platform/yanet2/common/go/logging"
 	"github.com/yanet-platform/yanet2/common/go/operator"
 	"github.com/yanet-platform/yanet2/common/go/xcfg"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
 )
 
 const (
@@ -24,9 +30,29 @@ const (
 	// RIB readiness helper.
 	defaultSampleInterval = 1 * time.Second
 
+	// defaultBlackholeMaxCount is the default limit of prefixes installed
+	// as drop routes because of the BLACKHOLE community.
+	defaultBlackholeMaxCount = 10000
+
 	// defaultReconnectGrace is the default time after a BIRD session ends
 	// before the bird-session reason flips from RECONNECTING to DOWN.
 	defaultReconnectGrace = 15 * time.Second
+
+	// defaultRoutesFileInterval is the default period between rereads of
+	// the static routes file.
+	defaultRoutesFileInterval = 10 * time.Second
+
+	// defaultEnrichmentInterval is the default period between sweeps
+	// tagging newly learned prefixes.
+	defaultEnrichmentInterval = 10 * time.Second
+
+	// defaultPerformanceMaxLoss is the default loss fraction above which a
+	// measured nexthop is degraded.
+	defaultPerformanceMaxLoss = 0.1
+
+	// defaultPerformanceTTL is the default time a nexthop measurement
+	// stays valid without a fresh report.
+	defaultPerformanceTTL = 30 * time.Second
 )
 
 const (
@@ -48,12 +74,51 @@ type Config struct {
 	RIBTTL         time.Duration        `yaml:"rib_ttl"`
 	NetlinkMonitor NetlinkMonitorConfig `yaml:"netlink_monitor"`
 	Readiness      ReadinessConfig      `yaml:"readiness"`
+	Blackhole      BlackholeConfig      `yaml:"blackhole"`
 	// GatewayDevices maps each gateway name to the egress device names its
 	// dataplane instance owns.
 	//
 	// A gateway absent from the map or mapped to an empty list owns every
 	// device and receives the full FIB unfiltered.
 	GatewayDevices map[string][]string `yaml:"gateway_devices"`
+	// Mirror copies FeedRIB sessions to a secondary route operator.
+	Mirror MirrorConfig `yaml:"mirror"`
+	// Flush controls adaptive batching of RIB flushes.
+	Flush FlushConfig `yaml:"flush"`
+	// MassWithdraw controls the fast path of FeedRIB sessions withdrawing
+	// many prefixes at once.
+	MassWithdraw MassWithdrawConfig `yaml:"mass_withdraw"`
+	// FeedHeartbeat keeps the FeedRIB streams alive and aborts the ones
+	// whose sender went silent.
+	FeedHeartbeat operatorpb.Heartbeat `yaml:"feed_heartbeat"`
+	// LoopProtection keeps locally originated routes from being
+	// re-imported through BIRD.
+	LoopProtection LoopProtectionConfig `yaml:"loop_protection"`
+	// StrictNexthop restricts the nexthops of the FeedRIB routes of a
+	// config to its peer networks.
+	StrictNexthop StrictNexthopConfig `yaml:"strict_nexthop"`
+	// Enrichment tags RIB prefixes with operational metadata.
+	Enrichment EnrichmentConfig `yaml:"enrichment"`
+	// Performance biases ECMP nexthop selection with measurements fed by
+	// external probes.
+	Performance PerformanceConfig `yaml:"performance"`
+	// Compression compresses the responses of the full-table reads,
+	// ShowRoutes and MonitorRoutes, for clients supporting it: none, gzip
+	// or zstd.
+	//
+	// FeedRIB streams are decompressed whatever compression their senders
+	// chose.
+	Compression grpccompress.Compression `yaml:"compression"`
+	// PrefixACL restricts the prefixes API callers may insert or delete
+	// routes for.
+	PrefixACL PrefixACLConfig `yaml:"prefix_acl"`
+	// Tenants scopes the RouteService callers to the RIBs of their
+	// tenant.
+	Tenants TenantsConfig `yaml:"tenants"`
+	// BMP exports the RIBs to BGP Monitoring Protocol collectors.
+	BMP BMPConfig `yaml:"bmp"`
+	// Snapshots stores the RIBs to files and restores them.
+	Snapshots SnapshotsConfig `yaml:"snapshots"`
 }
 
 // ReadinessConfig controls the operator's readiness reporting.
@@ -89,6 +154,294 @@ type ReadinessConfig struct {
 	ReconnectGrace time.Duration `yaml:"reconnect_grace"`
 }
 
+// BlackholeConfig controls community-based automatic blackholing
+// (RFC 7999).
+//
+// Host routes carrying the BLACKHOLE community (65535:666) are installed
+// into the FIB as drop routes.
+type BlackholeConfig struct {
+	// MaxCount is the maximum number of drop routes per FIB.
+	//
+	// Blackhole prefixes over the limit are built from their other routes.
+	// Zero disables automatic blackholing; routes carrying the BLACKHOLE
+	// community are then never used for forwarding.
+	MaxCount int `yaml:"max_count"`
+}
+
+// MirrorConfig controls best-effort mirroring of FeedRIB sessions to a
+// secondary RouteService.
+//
+// It allows shadow-testing a candidate version against the production BGP
+// feed: the secondary builds its RIB from the same updates while its
+// gateways carry no traffic.
+type MirrorConfig struct {
+	// Endpoint is the gRPC address of the secondary RouteService. Empty
+	// disables mirroring.
+	Endpoint string `yaml:"endpoint"`
+	// QueueSize is the number of updates buffered per session before the
+	// mirror starts dropping them.
+	QueueSize int `yaml:"queue_size"`
+	// Compression compresses the mirrored FeedRIB streams: none, gzip or
+	// zstd.
+	Compression grpccompress.Compression `yaml:"compression"`
+	// TLS configures TLS of the connection to the secondary. When nil, it
+	// is dialed in plaintext.
+	TLS *grpctls.ClientConfig `yaml:"tls,omitempty"`
+	// Heartbeat keeps the mirrored streams alive while the primary
+	// sessions are idle.
+	Heartbeat operatorpb.Heartbeat `yaml:"heartbeat"`
+}
+
+// FlushConfig controls adaptive batching of RIB flushes.
+//
+// Instead of committing the FIB on every flush request, the operator
+// delays it by an interval that widens under RIB update pressure or slow
+// dataplane commits and narrows back once the load drops, see
+// FlushScheduler.
+type FlushConfig struct {
+	// MinInterval is the lower bound of the flush interval, the latency
+	// of a flush on an idle operator.
+	MinInterval time.Duration `yaml:"min_interval"`
+	// MaxInterval is the upper bound of the flush interval. Zero disables
+	// batching: every flush wakes the reconcile loop immediately.
+	MaxInterval time.Duration `yaml:"max_interval"`
+	// BusyRate is the RIB update rate (updates per second) from which the
+	// flush interval widens.
+	BusyRate float64 `yaml:"busy_rate"`
+	// Deadline is the maximum time an accepted RIB change waits before a
+	// reconcile pass is forced, whether or not a flush was requested. Zero
+	// disables the deadline.
+	Deadline time.Duration `yaml:"deadline"`
+	// MaxCommitRate is the maximum rate, in commits per second, of the
+	// reconcile passes woken by RIB changes, see CommitLimiter. Nexthop
+	// failures and emergency API changes are not limited. Zero disables
+	// the limit.
+	MaxCommitRate float64 `yaml:"max_commit_rate"`
+	// CommitBurst is the number of commits allowed in a row above
+	// MaxCommitRate after a quiet period.
+	CommitBurst int `yaml:"commit_burst"`
+	// DeltaTransfer sends only the prefixes changed since the previous
+	// commit to the gateways instead of the whole FIBs. Resyncs always send
+	// the whole FIBs.
+	//
+	// Only the transfer is incremental: the gateways merge the changes into
+	// the FIBs they applied and still recompile the module configs from the
+	// whole merged FIBs, so the cost of a commit on the dataplane side is
+	// that of a full one.
+	DeltaTransfer bool `yaml:"delta_transfer"`
+}
+
+// MassWithdrawConfig controls the handling of mass withdrawals, as BIRD
+// sends when a BGP peer goes down.
+//
+// Consecutive withdrawals of a FeedRIB session are applied to the RIB in
+// batches, and a flush event following a mass withdrawal commits the FIB
+// at once, skipping the flush batching and the commit rate limit.
+type MassWithdrawConfig struct {
+	// BatchSize is the maximum number of consecutive withdrawals applied
+	// to the RIB at once. One applies every withdrawal on its own.
+	BatchSize int `yaml:"batch_size"`
+	// Threshold is the number of withdrawals received between two flush
+	// events from which the session is considered mass withdrawing. Zero
+	// disables the detection.
+	Threshold int `yaml:"threshold"`
+}
+
+// LoopProtectionConfig controls detection of locally originated routes
+// looping back through BIRD.
+//
+// Routes originated by this node are tagged with the origin community:
+// static routes carry it in the RIB, and the BIRD configuration is
+// expected to attach it to the exported VIP announcements. A route
+// carrying the community is never imported from a FeedRIB session, so it
+// cannot keep a withdrawn local route installed.
+type LoopProtectionConfig struct {
+	// OriginCommunity is the large community written as "GA:LD1:LD2".
+	// Empty disables the protection.
+	OriginCommunity string `yaml:"origin_community"`
+}
+
+// StrictNexthopConfig lists the networks the nexthops of the routes fed
+// over FeedRIB must fall into, by config.
+//
+// It catches a BIRD misconfiguration exporting routes via nexthops that
+// are not on a peer network, which the FIB could never resolve.
+type StrictNexthopConfig struct {
+	// Configs maps a config name to the subnets allowed for the nexthops
+	// of its routes. Configs not listed accept any nexthop.
+	Configs map[string][]string `yaml:"configs"`
+}
+
+// EnrichmentConfig controls tagging of RIB prefixes with operational
+// metadata, such as the country or the customer a prefix belongs to.
+//
+// Tags are returned by route lookups and never affect forwarding.
+type EnrichmentConfig struct {
+	// TagsFile names a file of tagged prefixes, see LoadFilePrefixTagger
+	// for its format. Empty disables enrichment unless a tagger is
+	// plugged in with WithPrefixTagger.
+	TagsFile string `yaml:"tags_file"`
+	// Interval is the period between sweeps tagging the prefixes learned
+	// since the previous one.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// PerformanceConfig controls performance-based nexthop selection.
+//
+// External probes report per-nexthop latency and loss to the
+// NexthopService. A measured nexthop exceeding any threshold is degraded
+// and left out of the ECMP groups of the FIB, unless every nexthop of the
+// group is degraded.
+type PerformanceConfig struct {
+	// Enabled makes the FIB avoid degraded nexthops. Measurements are
+	// accepted and listed either way.
+	Enabled bool `yaml:"enabled"`
+	// MaxLatency is the latency above which a nexthop is degraded. Zero
+	// ignores latency.
+	MaxLatency time.Duration `yaml:"max_latency"`
+	// MaxLoss is the loss fraction, within [0, 1], above which a nexthop
+	// is degraded.
+	MaxLoss float64 `yaml:"max_loss"`
+	// TTL is the time a measurement stays valid without a fresh report.
+	// Nexthops whose probes stop reporting are used again after it.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// BMPConfig controls exporting the RIBs to BMP (RFC 7854) collectors.
+//
+// Each RIB route is reported as a post-policy route of the peer it was
+// learned from, static routes as routes of a local instance peer, so the
+// collectors see the routes the FIB is built from.
+type BMPConfig struct {
+	// Collectors are the "host:port" addresses of the collectors, connected
+	// to by the operator. Empty disables the exporter.
+	Collectors []string `yaml:"collectors"`
+	// Configs are the RIBs exported. Empty exports every RIB.
+	Configs []string `yaml:"configs"`
+	// SysName identifies the operator to the collectors, the host name
+	// when empty.
+	SysName string `yaml:"sys_name"`
+	// LocalAS and RouterID describe the local BGP speaker the peers are
+	// reported to peer with, and the one originating the static routes.
+	// The router ID must be an IPv4 address.
+	LocalAS  uint32 `yaml:"local_as"`
+	RouterID string `yaml:"router_id"`
+	// ReconnectInterval is the delay before a collector is connected to
+	// again after its session failed.
+	ReconnectInterval time.Duration `yaml:"reconnect_interval"`
+}
+
+// SnapshotsConfig controls the RIB snapshot files written by ExportRIB and
+// restored by ImportRIB.
+//
+// Each RIB is stored to its own file of the directory, named after its
+// config, in the format of the ribsnapshot package.
+type SnapshotsConfig struct {
+	// Dir is the directory of the snapshot files. Empty disables the
+	// snapshot files.
+	Dir string `yaml:"dir"`
+	// Restore imports every snapshot of the directory at startup, before
+	// the BIRD sessions are started.
+	Restore bool `yaml:"restore"`
+}
+
+// PrefixACLConfig restricts the prefixes each API caller may insert or
+// delete routes for through the RouteService.
+//
+// Callers identify themselves with a bearer token in the "authorization"
+// metadata. A caller may change the routes of its prefixes and of their
+// more-specifics, e.g. a DDoS blackholing automation limited to the
+// customer ranges cannot touch the backbone prefixes. FeedRIB sessions and
+// static routes are not restricted.
+type PrefixACLConfig struct {
+	// Enabled turns the ACL on. Every caller may change every prefix
+	// otherwise.
+	Enabled bool `yaml:"enabled"`
+	// Callers lists the identified API callers.
+	Callers []PrefixACLCallerConfig `yaml:"callers"`
+	// Anonymous lists the prefixes callers presenting no token may
+	// change. Empty makes the routes read-only for them.
+	Anonymous []string `yaml:"anonymous"`
+}
+
+// PrefixACLCallerConfig describes a single API caller of the prefix ACL.
+type PrefixACLCallerConfig struct {
+	// Name identifies the caller in logs and errors.
+	Name string `yaml:"name"`
+	// TokenFile is the path to the file holding the caller's bearer token.
+	TokenFile string `yaml:"token_file"`
+	// Prefixes lists the prefixes the caller may change, in CIDR notation.
+	Prefixes []string `yaml:"prefixes"`
+}
+
+// TenantsConfig scopes the RouteService callers to the routes of their
+// tenant, so that several teams may share a dataplane.
+//
+// Tenants identify themselves with a bearer token in the "authorization"
+// metadata, like the prefix ACL callers. A tenant sees and changes the
+// RIBs of its configs only, usually the VRFs it was given, and may be
+// further narrowed to the routes carrying its tag within them. The prefix
+// ACL still applies to the changes of a tenant.
+type TenantsConfig struct {
+	// Enabled turns tenancy on. Every caller sees every RIB otherwise.
+	Enabled bool `yaml:"enabled"`
+	// Tenants lists the tenants.
+	Tenants []TenantConfig `yaml:"tenants"`
+	// AllowAnonymous leaves the callers presenting no token unscoped, as
+	// the agents of the platform itself, such as the BIRD adapter,
+	// usually are. Callers without a token are rejected otherwise.
+	AllowAnonymous bool `yaml:"allow_anonymous"`
+}
+
+// TenantConfig describes a single tenant.
+type TenantConfig struct {
+	// Name identifies the tenant in logs and errors.
+	Name string `yaml:"name"`
+	// TokenFile is the path to the file holding the tenant's bearer token.
+	TokenFile string `yaml:"token_file"`
+	// Configs lists the RIB config names the tenant may access.
+	Configs []string `yaml:"configs"`
+	// Tag, when set, narrows the tenant to the routes tagged with it in
+	// its configs.
+	//
+	// The routes the tenant inserts or feeds are tagged with it, and the
+	// routes of other tenants sharing a config are hidden from it.
+	Tag string `yaml:"tag"`
+}
+
+// Community returns the parsed origin community, nil when the protection
+// is disabled.
+func (m *LoopProtectionConfig) Community() (*rib.LargeCommunity, error) {
+	if m.OriginCommunity == "" {
+		return nil, nil
+	}
+	community, err := rib.ParseLargeCommunity(m.OriginCommunity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid loop protection origin community: %w", err)
+	}
+	return &community, nil
+}
+
+// Networks returns the parsed allowed nexthop subnets by config name.
+func (m *StrictNexthopConfig) Networks() (map[string][]netip.Prefix, error) {
+	out := make(map[string][]netip.Prefix, len(m.Configs))
+	for name, values := range m.Configs {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("strict nexthop networks of config %q are empty", name)
+		}
+		networks := make([]netip.Prefix, 0, len(values))
+		for _, value := range values {
+			network, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid strict nexthop network of config %q: %w", name, err)
+			}
+			networks = append(networks, network.Masked())
+		}
+		out[name] = networks
+	}
+	return out, nil
+}
+
 func (m *Config) Default() {
 	*m = *DefaultConfig()
 }
@@ -103,6 +456,55 @@ func (m *Config) Validate() error {
 	if len(m.Gateways) == 0 {
 		return errors.New("at least one gateway must be configured")
 	}
+	if m.Flush.MinInterval < 0 || m.Flush.MaxInterval < 0 {
+		return errors.New("flush intervals must not be negative")
+	}
+	if m.Flush.MaxInterval > 0 && m.Flush.MinInterval > m.Flush.MaxInterval {
+		return fmt.Errorf(
+			"flush min interval %s exceeds max interval %s",
+			m.Flush.MinInterval,
+			m.Flush.MaxInterval,
+		)
+	}
+	if m.Flush.Deadline < 0 {
+		return errors.New("flush deadline must not be negative")
+	}
+	if m.Flush.MaxCommitRate < 0 {
+		return errors.New("flush max commit rate must not be negative")
+	}
+	if m.Flush.CommitBurst < 0 {
+		return errors.New("flush commit burst must not be negative")
+	}
+	if m.MassWithdraw.BatchSize <= 0 {
+		return errors.New("mass withdraw batch size must be positive")
+	}
+	if m.MassWithdraw.Threshold < 0 {
+		return errors.New("mass withdraw threshold must not be negative")
+	}
+	if _, err := m.LoopProtection.Community(); err != nil {
+		return err
+	}
+	if _, err := m.StrictNexthop.Networks(); err != nil {
+		return err
+	}
+	if m.Static.RoutesFileInterval < 0 {
+		return errors.New("static routes file interval must not be negative")
+	}
+	if m.Enrichment.Interval <= 0 {
+		return errors.New("enrichment interval must be positive")
+	}
+	if m.Performance.MaxLatency < 0 {
+		return errors.New("performance max latency must not be negative")
+	}
+	if m.Performance.MaxLoss < 0 || m.Performance.MaxLoss > 1 {
+		return fmt.Errorf("performance max loss %v is out of [0, 1]", m.Performance.MaxLoss)
+	}
+	if m.Performance.TTL <= 0 {
+		return errors.New("performance measurement TTL must be positive")
+	}
+	if m.Snapshots.Restore && m.Snapshots.Dir == "" {
+		return errors.New("snapshot restore requires a snapshot directory")
+	}
 
 	return nil
 }
@@ -144,6 +546,41 @@ func DefaultConfig() *Config {
 			SampleInterval:  defaultSampleInterval,
 			ReconnectGrace:  defaultReconnectGrace,
 		},
+		Blackhole: BlackholeConfig{
+			MaxCount: defaultBlackholeMaxCount,
+		},
+		Mirror: MirrorConfig{
+			QueueSize:   defaultMirrorQueueSize,
+			Compression: grpccompress.Gzip,
+			Heartbeat:   operatorpb.DefaultHeartbeat(),
+		},
+		Compression: grpccompress.Gzip,
+		Flush: FlushConfig{
+			MinInterval:   defaultFlushMinInterval,
+			MaxInterval:   defaultFlushMaxInterval,
+			BusyRate:      defaultFlushBusyRate,
+			Deadline:      defaultFlushDeadline,
+			MaxCommitRate: defaultFlushMaxCommitRate,
+			CommitBurst:   defaultFlushCommitBurst,
+		},
+		MassWithdraw: MassWithdrawConfig{
+			BatchSize: defaultMassWithdrawBatchSize,
+			Threshold: defaultMassWithdrawThreshold,
+		},
+		FeedHeartbeat: operatorpb.DefaultHeartbeat(),
+		Static: StaticConfig{
+			RoutesFileInterval: defaultRoutesFileInterval,
+		},
+		Enrichment: EnrichmentConfig{
+			Interval: defaultEnrichmentInterval,
+		},
+		Performance: PerformanceConfig{
+			MaxLoss: defaultPerformanceMaxLoss,
+			TTL:     defaultPerformanceTTL,
+		},
+		BMP: BMPConfig{
+			ReconnectInterval: defaultBMPReconnectInterval,
+		},
 	}
 }
 
@@ -174,6 +611,15 @@ type FunctionConfig struct {
 type StaticConfig struct {
 	Routes     []StaticRouteConfig     `yaml:"routes"`
 	Neighbours []StaticNeighbourConfig `yaml:"neighbours"`
+	// RoutesFile optionally names a file of additional static routes, see
+	// LoadStaticRoutes for its format.
+	//
+	// Unlike Routes, the file is reread every RoutesFileInterval and the
+	// RIB follows its changes.
+	RoutesFile string `yaml:"routes_file"`
+	// RoutesFileInterval is the period between rereads of RoutesFile.
+	// Zero loads the file once at startup.
+	RoutesFileInterval time.Duration `yaml:"routes_file_interval"`
 }
 
 // StaticRouteConfig describes a single static-route entry to seed. The
@@ -183,6 +629,10 @@ type StaticRouteConfig struct {
 	// Prefix is the destination prefix in CIDR notation.
 	Prefix string `yaml:"prefix"`
 	// NexthopAddr is the next-hop IP address.
+	//
+	// An IPv6 link-local next-hop may carry its egress interface as the
+	// address zone, "fe80::1%eth0". An IPv4 prefix may be reached via an
+	// IPv6 next-hop, an IPv6 prefix only via an IPv6 one.
 	NexthopAddr string `yaml:"nexthop_addr"`
 }
 
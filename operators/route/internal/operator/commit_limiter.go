@@ -0,0 +1,172 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CommitLimiter bounds the rate of the reconcile passes, that is of the FIB
+// commits to the shared memory of the dataplane, woken by RIB churn.
+//
+// It is a token bucket refilled at FlushConfig.MaxCommitRate and holding
+// up to FlushConfig.CommitBurst tokens. Every wake takes a token; a wake
+// finding none is deferred until the bucket refills, and the wakes
+// deferred meanwhile are coalesced into a single pass.
+//
+// Emergency wakes, such as a nexthop going down or a change invoked by an
+// operator, bypass the limit so failures keep converging fast. They still
+// take a token when one is available, so the churn following them is
+// limited against the commit they caused.
+//
+// With MaxCommitRate set to zero every wake passes through.
+type CommitLimiter struct {
+	rate       float64
+	burst      float64
+	wake       func()
+	onDeferred func()
+	now        func() time.Time
+	deferredCh chan struct{}
+
+	mu       sync.Mutex
+	tokens   float64
+	filledAt time.Time
+	deferred bool
+
+	log *zap.Logger
+}
+
+// NewCommitLimiter creates a CommitLimiter waking the reconcile loop
+// through wake.
+//
+// The onDeferred callback is invoked for every wake deferred by the limit.
+func NewCommitLimiter(cfg FlushConfig, wake func(), onDeferred func(), log *zap.Logger) *CommitLimiter {
+	burst := float64(max(cfg.CommitBurst, 1))
+
+	return &CommitLimiter{
+		rate:       cfg.MaxCommitRate,
+		burst:      burst,
+		wake:       wake,
+		onDeferred: onDeferred,
+		now:        time.Now,
+		deferredCh: make(chan struct{}, 1),
+		tokens:     burst,
+		filledAt:   time.Now(),
+		log:        log,
+	}
+}
+
+// Enabled reports whether the commit rate is limited.
+func (m *CommitLimiter) Enabled() bool {
+	return m.rate > 0
+}
+
+// Wake wakes the reconcile loop if the commit rate allows it, deferring
+// the wake otherwise.
+//
+// It never blocks.
+func (m *CommitLimiter) Wake() {
+	if !m.Enabled() {
+		m.wake()
+		return
+	}
+
+	m.mu.Lock()
+	m.refill()
+	if m.deferred || m.tokens < 1 {
+		m.deferred = true
+		m.mu.Unlock()
+
+		m.onDeferred()
+		select {
+		case m.deferredCh <- struct{}{}:
+		default:
+		}
+		return
+	}
+	m.tokens--
+	m.mu.Unlock()
+
+	m.wake()
+}
+
+// WakeNow wakes the reconcile loop right away, whatever the commit rate.
+//
+// The pass it wakes also commits the changes of the deferred wakes, which
+// are therefore dropped.
+func (m *CommitLimiter) WakeNow() {
+	if m.Enabled() {
+		m.mu.Lock()
+		m.refill()
+		m.tokens = max(m.tokens-1, 0)
+		m.deferred = false
+		m.mu.Unlock()
+	}
+
+	m.wake()
+}
+
+// Run wakes the reconcile loop for the deferred wakes once the bucket
+// refills, until the context is cancelled.
+func (m *CommitLimiter) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.deferredCh:
+		}
+
+		for {
+			delay, ok := m.takeDeferred()
+			if ok {
+				break
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// takeDeferred wakes the reconcile loop for the deferred wakes if a token
+// is available, returning the time until the next one otherwise.
+//
+// It reports true if no deferred wake is left.
+func (m *CommitLimiter) takeDeferred() (time.Duration, bool) {
+	m.mu.Lock()
+	if !m.deferred {
+		// An emergency wake committed the deferred changes meanwhile.
+		m.mu.Unlock()
+		return 0, true
+	}
+
+	m.refill()
+	if m.tokens < 1 {
+		delay := time.Duration((1 - m.tokens) / m.rate * float64(time.Second))
+		m.mu.Unlock()
+		return delay, false
+	}
+	m.tokens--
+	m.deferred = false
+	m.mu.Unlock()
+
+	m.log.Debug("woke reconcile pass deferred by the commit rate limit")
+	m.wake()
+	return 0, true
+}
+
+// refill adds the tokens accrued since the last refill.
+//
+// The caller must hold the mutex.
+func (m *CommitLimiter) refill() {
+	now := m.now()
+	m.tokens = min(m.tokens+now.Sub(m.filledAt).Seconds()*m.rate, m.burst)
+	m.filledAt = now
+}
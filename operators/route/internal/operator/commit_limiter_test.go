@@ -0,0 +1,95 @@
+package operator
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestCommitLimiter_Wake verifies that wakes above the commit rate are
+// deferred, and that emergency wakes bypass the limit.
+func TestCommitLimiter_Wake(t *testing.T) {
+	wakes, deferred := 0, 0
+	limiter := NewCommitLimiter(
+		FlushConfig{MaxCommitRate: 2, CommitBurst: 2},
+		func() { wakes++ },
+		func() { deferred++ },
+		zap.NewNop(),
+	)
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+	limiter.filledAt = now
+
+	// The burst passes through, then the wakes are deferred.
+	for range 4 {
+		limiter.Wake()
+	}
+	require.Equal(t, 2, wakes)
+	require.Equal(t, 2, deferred)
+
+	_, ok := limiter.takeDeferred()
+	require.False(t, ok)
+	require.Equal(t, 2, wakes)
+
+	// Half a second refills one token, which the deferred wakes take as a
+	// single pass.
+	now = now.Add(500 * time.Millisecond)
+	_, ok = limiter.takeDeferred()
+	require.True(t, ok)
+	require.Equal(t, 3, wakes)
+
+	// The bucket is empty, but emergency wakes are never limited.
+	limiter.Wake()
+	require.Equal(t, 3, wakes)
+	limiter.WakeNow()
+	limiter.WakeNow()
+	require.Equal(t, 5, wakes)
+
+	// The emergency wake committed the deferred changes.
+	_, ok = limiter.takeDeferred()
+	require.True(t, ok)
+	require.Equal(t, 5, wakes)
+}
+
+func TestCommitLimiter_Disabled(t *testing.T) {
+	wakes := 0
+	limiter := NewCommitLimiter(FlushConfig{}, func() { wakes++ }, func() {}, zap.NewNop())
+	require.False(t, limiter.Enabled())
+
+	for range 100 {
+		limiter.Wake()
+	}
+	require.Equal(t, 100, wakes)
+}
+
+// TestCommitLimiter_Run verifies that a deferred wake is delivered once
+// the bucket refills.
+func TestCommitLimiter_Run(t *testing.T) {
+	var wakes atomic.Int32
+	limiter := NewCommitLimiter(
+		FlushConfig{MaxCommitRate: 50, CommitBurst: 1},
+		func() { wakes.Add(1) },
+		func() {},
+		zap.NewNop(),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = limiter.Run(ctx)
+	}()
+
+	limiter.Wake()
+	limiter.Wake()
+	require.Equal(t, int32(1), wakes.Load())
+	require.Eventually(t, func() bool { return wakes.Load() == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
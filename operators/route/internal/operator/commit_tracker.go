@@ -0,0 +1,159 @@
+package operator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yanet-platform/yanet2/common/go/operator"
+)
+
+// CommitReporter reports the outcome of the last Apply on a gateway.
+type CommitReporter interface {
+	LastApply() GatewayCommit
+}
+
+// Commit is the outcome of an attempt of a reconcile pass on every
+// gateway.
+type Commit struct {
+	// Pass is the number of the reconcile pass, see CommitTracker.
+	Pass     uint64
+	Gateways []GatewayCommit
+}
+
+// CommitTracker records the outcome of the reconcile passes, so that the
+// callers changing the RIBs can wait for the pass committing their
+// changes.
+//
+// Passes are numbered by the snapshots the reconcile loop takes, starting
+// from 1. Every attempt to apply a snapshot completes its pass, whether it
+// succeeded or not, so a waiter learns about a failed commit without
+// waiting for the retries.
+type CommitTracker struct {
+	gateways []CommitReporter
+
+	mu    sync.Mutex
+	taken uint64
+	last  Commit
+	// doneCh is closed and replaced by every completed attempt.
+	doneCh chan struct{}
+}
+
+// NewCommitTracker creates a CommitTracker collecting the outcome of the
+// passes from the given gateways.
+func NewCommitTracker(gateways ...CommitReporter) *CommitTracker {
+	return &CommitTracker{
+		gateways: gateways,
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// AddGateway adds a gateway to collect the outcome of the passes from.
+//
+// It must be called before the reconcile loop starts.
+func (m *CommitTracker) AddGateway(gw CommitReporter) {
+	m.gateways = append(m.gateways, gw)
+}
+
+// Pass returns the number of the last pass taken by the reconcile loop.
+//
+// Any pass numbered above it observes the changes made before the call.
+func (m *CommitTracker) Pass() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.taken
+}
+
+// Wait blocks until an attempt of the given pass, or of a later one,
+// completes and returns its outcome.
+func (m *CommitTracker) Wait(ctx context.Context, pass uint64) (Commit, error) {
+	for {
+		m.mu.Lock()
+		last, doneCh := m.last, m.doneCh
+		m.mu.Unlock()
+
+		if last.Pass >= pass {
+			return last, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Commit{}, ctx.Err()
+		case <-doneCh:
+		}
+	}
+}
+
+// onSnapshot starts a new pass.
+func (m *CommitTracker) onSnapshot() {
+	m.mu.Lock()
+	m.taken++
+	m.mu.Unlock()
+}
+
+// onApplied completes an attempt of the current pass, collecting its
+// outcome from the gateways.
+func (m *CommitTracker) onApplied() {
+	gateways := make([]GatewayCommit, 0, len(m.gateways))
+	for _, gw := range m.gateways {
+		gateways = append(gateways, gw.LastApply())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.last = Commit{Pass: m.taken, Gateways: gateways}
+	close(m.doneCh)
+	m.doneCh = make(chan struct{})
+}
+
+// commitTrackedActuator reports the completion of every Apply to the
+// commit tracker.
+type commitTrackedActuator struct {
+	inner   Actuator
+	tracker *CommitTracker
+}
+
+// newCommitTrackedActuator wraps inner, which must apply the snapshot to
+// every gateway of the tracker before returning.
+func newCommitTrackedActuator(inner Actuator, tracker *CommitTracker) *commitTrackedActuator {
+	return &commitTrackedActuator{
+		inner:   inner,
+		tracker: tracker,
+	}
+}
+
+// Apply delegates to the inner actuator, then completes the attempt of
+// the current pass.
+func (m *commitTrackedActuator) Apply(ctx context.Context, snapshot RouteSnapshot) error {
+	err := m.inner.Apply(ctx, snapshot)
+	m.tracker.onApplied()
+	return err
+}
+
+// Close delegates to the inner actuator.
+func (m *commitTrackedActuator) Close() error {
+	return m.inner.Close()
+}
+
+// commitTrackedSource numbers the snapshots taken by the reconcile loop.
+type commitTrackedSource struct {
+	operator.StateSource[RouteSnapshot]
+	tracker *CommitTracker
+}
+
+// newCommitTrackedSource wraps inner so its snapshots start the passes of
+// the commit tracker.
+func newCommitTrackedSource(inner operator.StateSource[RouteSnapshot], tracker *CommitTracker) *commitTrackedSource {
+	return &commitTrackedSource{
+		StateSource: inner,
+		tracker:     tracker,
+	}
+}
+
+// Snapshot starts a new pass before taking the inner snapshot, so the pass
+// observes every change made before it is numbered.
+func (m *commitTrackedSource) Snapshot() (RouteSnapshot, bool) {
+	m.tracker.onSnapshot()
+	return m.StateSource.Snapshot()
+}
@@ -0,0 +1,134 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+type fakeCommitReporter struct {
+	commit GatewayCommit
+}
+
+func (m *fakeCommitReporter) LastApply() GatewayCommit {
+	return m.commit
+}
+
+func TestCommitTracker_Wait(t *testing.T) {
+	gw := &fakeCommitReporter{commit: GatewayCommit{Gateway: "gw0"}}
+	tracker := NewCommitTracker(gw)
+	source := newCommitTrackedSource(NewRouteSource(neigh.NewNeighTable(), newRIBStore(zap.NewNop())), tracker)
+	actuator := newCommitTrackedActuator(&fakeActuator{}, tracker)
+
+	pass := tracker.Pass() + 1
+	require.Equal(t, uint64(1), pass)
+
+	// The pass is not taken yet.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := tracker.Wait(ctx, pass)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		snapshot, _ := source.Snapshot()
+		_ = actuator.Apply(t.Context(), snapshot)
+	}()
+
+	commit, err := tracker.Wait(t.Context(), pass)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), commit.Pass)
+	require.Equal(t, []GatewayCommit{{Gateway: "gw0"}}, commit.Gateways)
+
+	// Completed passes are returned right away.
+	commit, err = tracker.Wait(t.Context(), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), commit.Pass)
+}
+
+// TestFlushRoutes_Results verifies that FlushRoutes waits for the pass
+// committing the flush and reports the push of the flushed module config
+// on every gateway.
+func TestFlushRoutes_Results(t *testing.T) {
+	errFunction := errors.New("failed to update function")
+	gateways := []*fakeCommitReporter{
+		{commit: GatewayCommit{
+			Gateway: "gw0",
+			FIBs: []FIBPush{
+				{Module: "route0", Entries: 2, Generation: 7, Duration: time.Millisecond},
+				{Module: "route1", Entries: 5, Generation: 3},
+			},
+		}},
+		{commit: GatewayCommit{
+			Gateway: "gw1",
+			FIBs:    []FIBPush{{Module: "route0", Entries: 2, Generation: 4}},
+			Err:     errFunction,
+		}},
+	}
+	tracker := NewCommitTracker(gateways[0], gateways[1])
+	actuator := newCommitTrackedActuator(&fakeActuator{}, tracker)
+
+	// The reconcile loop is emulated by the flush callback.
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceCommits(tracker),
+		WithRouteServiceOnChanged(func() {
+			go func() {
+				tracker.onSnapshot()
+				_ = actuator.Apply(context.Background(), RouteSnapshot{})
+			}()
+		}),
+	)
+	defer svc.Close()
+
+	_, err := svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.0.0/24",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.168.1.1"))},
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.NoError(t, err)
+
+	response, err := svc.FlushRoutes(t.Context(), &operatorpb.FlushRoutesRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.GetPass())
+	require.Len(t, response.GetResults(), 2)
+
+	result := response.GetResults()[0]
+	require.Equal(t, "gw0", result.GetGateway())
+	require.Equal(t, uint64(2), result.GetEntries())
+	require.Equal(t, uint64(7), result.GetGeneration())
+	require.Equal(t, time.Millisecond, result.GetDuration().AsDuration())
+	require.Empty(t, result.GetError())
+
+	result = response.GetResults()[1]
+	require.Equal(t, "gw1", result.GetGateway())
+	require.Equal(t, uint64(4), result.GetGeneration())
+	require.Equal(t, errFunction.Error(), result.GetError())
+
+	// A flush never committed fails once the deadline expires.
+	svc = NewRouteService(neigh.NewNeighTable(), WithRouteServiceCommits(NewCommitTracker()))
+	defer svc.Close()
+	_, err = svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.0.0/24",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.168.1.1"))},
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = svc.FlushRoutes(ctx, &operatorpb.FlushRoutesRequest{Name: "route0"})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
@@ -0,0 +1,171 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// corruptedFIBPrefix is the prefix pushed in place of a FIB when a
+// corruption fault fires. The gateway must reject it.
+const corruptedFIBPrefix = "corrupted"
+
+// FaultInjector holds the faults armed for resilience testing.
+//
+// A nil FaultInjector is valid and never fires, which is how production
+// builds run.
+type FaultInjector struct {
+	mu                 sync.Mutex
+	dropFeedRIBStreams uint32
+	flushRoutesDelay   time.Duration
+	corruptFIBPushes   uint32
+	log                *zap.Logger
+}
+
+// NewFaultInjector creates a FaultInjector with no faults armed.
+func NewFaultInjector(log *zap.Logger) *FaultInjector {
+	return &FaultInjector{
+		log: log,
+	}
+}
+
+// Set replaces the armed fault set.
+func (m *FaultInjector) Set(faults *operatorpb.Faults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dropFeedRIBStreams = faults.GetDropFeedRibStreams()
+	m.flushRoutesDelay = faults.GetFlushRoutesDelay().AsDuration()
+	m.corruptFIBPushes = faults.GetCorruptFibPushes()
+
+	m.log.Warn("armed faults",
+		zap.Uint32("drop_feed_rib_streams", m.dropFeedRIBStreams),
+		zap.Duration("flush_routes_delay", m.flushRoutesDelay),
+		zap.Uint32("corrupt_fib_pushes", m.corruptFIBPushes),
+	)
+}
+
+// Get returns the currently armed fault set.
+func (m *FaultInjector) Get() *operatorpb.Faults {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &operatorpb.Faults{
+		DropFeedRibStreams: m.dropFeedRIBStreams,
+		FlushRoutesDelay:   durationpb.New(m.flushRoutesDelay),
+		CorruptFibPushes:   m.corruptFIBPushes,
+	}
+}
+
+// DropFeedRIB reports whether the calling FeedRIB stream must be aborted,
+// consuming one armed drop if so.
+func (m *FaultInjector) DropFeedRIB() bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dropFeedRIBStreams == 0 {
+		return false
+	}
+	m.dropFeedRIBStreams--
+
+	return true
+}
+
+// DelayFlushRoutes blocks for the armed FlushRoutes delay or until the
+// context is done, returning the context error in the latter case.
+func (m *FaultInjector) DelayFlushRoutes(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	delay := m.flushRoutesDelay
+	m.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CorruptFIB reports whether the next FIB push must carry an invalid
+// config, consuming one armed corruption if so.
+func (m *FaultInjector) CorruptFIB() bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.corruptFIBPushes == 0 {
+		return false
+	}
+	m.corruptFIBPushes--
+
+	return true
+}
+
+// FaultService implements the debug FaultService surface on top of a
+// FaultInjector.
+type FaultService struct {
+	operatorpb.UnimplementedFaultServiceServer
+
+	faults *FaultInjector
+}
+
+// NewFaultService creates a FaultService controlling faults.
+func NewFaultService(faults *FaultInjector) *FaultService {
+	return &FaultService{
+		faults: faults,
+	}
+}
+
+// SetFaults replaces the armed fault set.
+func (m *FaultService) SetFaults(
+	ctx context.Context,
+	req *operatorpb.SetFaultsRequest,
+) (*operatorpb.SetFaultsResponse, error) {
+	if delay := req.GetFaults().GetFlushRoutesDelay(); delay != nil {
+		if err := delay.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid flush routes delay: %v", err)
+		}
+		if delay.AsDuration() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "flush routes delay must not be negative")
+		}
+	}
+
+	m.faults.Set(req.GetFaults())
+
+	return &operatorpb.SetFaultsResponse{}, nil
+}
+
+// ShowFaults returns the currently armed fault set.
+func (m *FaultService) ShowFaults(
+	ctx context.Context,
+	req *operatorpb.ShowFaultsRequest,
+) (*operatorpb.ShowFaultsResponse, error) {
+	return &operatorpb.ShowFaultsResponse{
+		Faults: m.faults.Get(),
+	}, nil
+}
@@ -0,0 +1,7 @@
+//go:build chaos
+
+package operator
+
+// faultInjectionEnabled reports whether the operator exposes the debug
+// FaultService.
+const faultInjectionEnabled = true
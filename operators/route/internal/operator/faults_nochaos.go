@@ -0,0 +1,9 @@
+//go:build !chaos
+
+package operator
+
+// faultInjectionEnabled reports whether the operator exposes the debug
+// FaultService.
+//
+// Build with the "chaos" tag to enable it.
+const faultInjectionEnabled = false
@@ -0,0 +1,173 @@
+package operator
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// fakeFeedRIBStream replays a fixed list of updates to FeedRIB.
+type fakeFeedRIBStream struct {
+	grpc.ServerStream
+
+	updates []*operatorpb.Update
+	closed  bool
+}
+
+func (m *fakeFeedRIBStream) Recv() (*operatorpb.Update, error) {
+	if len(m.updates) == 0 {
+		return nil, io.EOF
+	}
+	update := m.updates[0]
+	m.updates = m.updates[1:]
+	return update, nil
+}
+
+func (m *fakeFeedRIBStream) Send(summary *operatorpb.UpdateSummary) error {
+	if !summary.GetHeartbeat() {
+		m.closed = true
+	}
+	return nil
+}
+
+func TestFaultInjector_Nil(t *testing.T) {
+	var faults *FaultInjector
+
+	require.False(t, faults.DropFeedRIB())
+	require.False(t, faults.CorruptFIB())
+	require.NoError(t, faults.DelayFlushRoutes(t.Context()))
+}
+
+func TestFaultInjector_ConsumeArmedFaults(t *testing.T) {
+	faults := NewFaultInjector(zap.NewNop())
+	faults.Set(&operatorpb.Faults{
+		DropFeedRibStreams: 1,
+		CorruptFibPushes:   2,
+	})
+
+	require.True(t, faults.DropFeedRIB())
+	require.False(t, faults.DropFeedRIB())
+
+	require.True(t, faults.CorruptFIB())
+	require.True(t, faults.CorruptFIB())
+	require.False(t, faults.CorruptFIB())
+
+	require.Zero(t, faults.Get().GetDropFeedRibStreams())
+	require.Zero(t, faults.Get().GetCorruptFibPushes())
+}
+
+func TestFaultInjector_DelayFlushRoutes(t *testing.T) {
+	faults := NewFaultInjector(zap.NewNop())
+	faults.Set(&operatorpb.Faults{
+		FlushRoutesDelay: durationpb.New(time.Hour),
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	err := faults.DelayFlushRoutes(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFaultService_SetFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  *operatorpb.Faults
+		code    codes.Code
+		expects *operatorpb.Faults
+	}{
+		{
+			name: "arm all faults",
+			faults: &operatorpb.Faults{
+				DropFeedRibStreams: 3,
+				FlushRoutesDelay:   durationpb.New(time.Second),
+				CorruptFibPushes:   1,
+			},
+			code: codes.OK,
+			expects: &operatorpb.Faults{
+				DropFeedRibStreams: 3,
+				FlushRoutesDelay:   durationpb.New(time.Second),
+				CorruptFibPushes:   1,
+			},
+		},
+		{
+			name:   "disarm with empty fault set",
+			faults: nil,
+			code:   codes.OK,
+			expects: &operatorpb.Faults{
+				FlushRoutesDelay: durationpb.New(0),
+			},
+		},
+		{
+			name: "negative flush delay",
+			faults: &operatorpb.Faults{
+				FlushRoutesDelay: durationpb.New(-time.Second),
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewFaultService(NewFaultInjector(zap.NewNop()))
+
+			_, err := svc.SetFaults(t.Context(), &operatorpb.SetFaultsRequest{Faults: tt.faults})
+			require.Equal(t, tt.code, status.Code(err))
+			if err != nil {
+				return
+			}
+
+			resp, err := svc.ShowFaults(t.Context(), &operatorpb.ShowFaultsRequest{})
+			require.NoError(t, err)
+			require.Equal(t, tt.expects.GetDropFeedRibStreams(), resp.GetFaults().GetDropFeedRibStreams())
+			require.Equal(t, tt.expects.GetFlushRoutesDelay().AsDuration(), resp.GetFaults().GetFlushRoutesDelay().AsDuration())
+			require.Equal(t, tt.expects.GetCorruptFibPushes(), resp.GetFaults().GetCorruptFibPushes())
+		})
+	}
+}
+
+func TestRouteService_FeedRIB_DroppedByFault(t *testing.T) {
+	faults := NewFaultInjector(zap.NewNop())
+	faults.Set(&operatorpb.Faults{DropFeedRibStreams: 1})
+
+	sessionEnds := 0
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceFaults(faults),
+		WithRouteServiceOnRIBSessionEnd(func(string, uint64) {
+			sessionEnds++
+		}),
+	)
+	defer svc.Close()
+
+	newStream := func() *fakeFeedRIBStream {
+		return &fakeFeedRIBStream{
+			updates: []*operatorpb.Update{
+				{Name: "route0", EndOfRib: true},
+			},
+		}
+	}
+
+	dropped := newStream()
+	err := svc.FeedRIB(dropped)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.False(t, dropped.closed)
+	require.Equal(t, 1, sessionEnds)
+
+	// The fault is consumed, so the reconnected stream is served normally.
+	reconnected := newStream()
+	require.NoError(t, svc.FeedRIB(reconnected))
+	require.True(t, reconnected.closed)
+	require.Equal(t, 2, sessionEnds)
+}
@@ -1,6 +1,8 @@
 package operator
 
 import (
+	"cmp"
+	"maps"
 	"net/netip"
 	"slices"
 
@@ -16,6 +18,25 @@ type FIBEntry struct {
 	// Nexthops are the resolved hardware routes for the prefix. The slice
 	// is deduplicated.
 	Nexthops []neigh.HardwareRoute
+	// Weights are the shares of the prefix traffic the nexthops take, in
+	// the order of Nexthops. Nil when the nexthops share it equally.
+	Weights []uint32
+	// Blackhole reports that traffic to Prefix is discarded. Nexthops is
+	// empty for blackhole entries.
+	Blackhole bool
+	// Sources are the distinct sources of the routes the nexthops were
+	// resolved from, ordered. Empty for blackhole entries.
+	Sources []rib.RouteSourceID
+}
+
+// Equal reports whether two entries install the same forwarding for the
+// same prefix.
+func (m FIBEntry) Equal(other FIBEntry) bool {
+	return m.Prefix == other.Prefix &&
+		m.Blackhole == other.Blackhole &&
+		slices.Equal(m.Nexthops, other.Nexthops) &&
+		slices.Equal(m.Weights, other.Weights) &&
+		slices.Equal(m.Sources, other.Sources)
 }
 
 // FIB is the complete forwarding table for one module config.
@@ -26,6 +47,50 @@ type FIB struct {
 	Entries []FIBEntry
 }
 
+// fibDelta is the change of the FIB of a module config since the previous
+// push.
+type fibDelta struct {
+	// Changed are the entries added or changed, in the order of the FIB.
+	Changed []FIBEntry
+	// Removed are the prefixes no longer in the FIB, ordered.
+	Removed []netip.Prefix
+}
+
+// Len returns the number of prefixes the delta changes.
+func (m fibDelta) Len() int {
+	return len(m.Changed) + len(m.Removed)
+}
+
+// fibEntriesByPrefix returns the entries of the FIB by prefix.
+func fibEntriesByPrefix(fib FIB) map[netip.Prefix]FIBEntry {
+	entries := make(map[netip.Prefix]FIBEntry, len(fib.Entries))
+	for _, entry := range fib.Entries {
+		entries[entry.Prefix] = entry
+	}
+	return entries
+}
+
+// diffFIB returns the changes turning the base entries into the FIB.
+func diffFIB(base map[netip.Prefix]FIBEntry, fib FIB) fibDelta {
+	var delta fibDelta
+
+	seen := make(map[netip.Prefix]struct{}, len(fib.Entries))
+	for _, entry := range fib.Entries {
+		seen[entry.Prefix] = struct{}{}
+		if prev, ok := base[entry.Prefix]; !ok || !prev.Equal(entry) {
+			delta.Changed = append(delta.Changed, entry)
+		}
+	}
+	for prefix := range base {
+		if _, ok := seen[prefix]; !ok {
+			delta.Removed = append(delta.Removed, prefix)
+		}
+	}
+	slices.SortFunc(delta.Removed, comparePrefix)
+
+	return delta
+}
+
 // FIBBuildStats summarises a BuildFIB pass for observability.
 type FIBBuildStats struct {
 	TotalPrefixes     int
@@ -37,6 +102,18 @@ type FIBBuildStats struct {
 	// FilteredRoutes counts eligible routes dropped because a better route
 	// of the same source exists.
 	FilteredRoutes int
+	// DegradedRoutes counts best routes left out because their nexthop is
+	// degraded while a healthy one serves the prefix.
+	DegradedRoutes int
+	// ExtendedNexthops counts nexthops of IPv4 prefixes resolved through
+	// IPv6 neighbours, as announced with an extended next hop (RFC 8950).
+	ExtendedNexthops int
+	// Blackholes counts prefixes installed as drop routes.
+	Blackholes int
+	// RejectedBlackholes counts prefixes carrying the BLACKHOLE community
+	// that were not installed as drop routes, either because they are not
+	// host prefixes or because the blackhole limit was reached.
+	RejectedBlackholes int
 }
 
 // BuildFIB resolves a RIB dump against the supplied neighbour view and
@@ -47,13 +124,27 @@ type FIBBuildStats struct {
 // devices. The best routes per source are chosen among the eligible routes,
 // so a gateway can fall back to a lower-priority route it can actually reach
 // rather than a globally best one it cannot.
+//
+// Host prefixes (/32 and /128) with a route carrying the BLACKHOLE
+// community (RFC 7999) are installed as drop routes without nexthop
+// resolution, up to maxBlackholes of them, lowest prefixes first. Blackhole
+// routes never forward traffic: other prefixes, and host prefixes over the
+// limit, are built from their remaining routes only. A zero maxBlackholes
+// disables automatic blackholing.
+//
+// Best routes via a degraded nexthop are left out of the ECMP group as
+// long as a route via a healthy nexthop remains, so measurements shift
+// traffic between nexthops but never withdraw a prefix.
 func BuildFIB(
 	ribDump maptrie.MapTrie[netip.Prefix, netip.Addr, rib.RoutesList],
 	neighbours neigh.NexthopCacheView,
+	maxBlackholes int,
+	degraded map[netip.Addr]struct{},
 ) (FIB, FIBBuildStats) {
 	var stats FIBBuildStats
 
 	entries := make([]FIBEntry, 0)
+	blackholes := map[netip.Prefix][]rib.Route{}
 
 	for prefixLen := range ribDump {
 		for prefix, routesList := range ribDump[prefixLen] {
@@ -65,44 +156,135 @@ func BuildFIB(
 
 			stats.TotalRoutes += len(routesList.Routes)
 
-			local := make([]rib.Route, 0, len(routesList.Routes))
-			for _, r := range routesList.Routes {
-				if _, ok := neighbours.Lookup(r.NextHop.Unmap()); !ok {
-					stats.NeighbourNotFound++
+			routes := routesList.Routes
+			if slices.ContainsFunc(routes, isBlackholeRoute) {
+				routes = slices.DeleteFunc(slices.Clone(routes), isBlackholeRoute)
+				if prefix.IsSingleIP() {
+					blackholes[prefix] = routes
 					continue
 				}
-
-				local = append(local, r)
-			}
-
-			if len(local) == 0 {
-				continue
+				stats.RejectedBlackholes++
 			}
 
-			// The best route of each source is chosen among the resolvable
-			// routes only, so the gateway falls back to a reachable route
-			// when its source's best one has no neighbour.
-			localList := rib.RoutesList{Routes: local}
-			bestRoutes := localList.BestPerSource()
-			stats.FilteredRoutes += len(local) - len(bestRoutes)
-
-			nexthops := make([]neigh.HardwareRoute, 0, len(bestRoutes))
-			for _, r := range bestRoutes {
-				entry, _ := neighbours.Lookup(r.NextHop.Unmap())
-				nexthops = append(nexthops, entry.HardwareRoute)
+			if entry, ok := buildFIBEntry(prefix, routes, neighbours, degraded, &stats); ok {
+				entries = append(entries, entry)
 			}
+		}
+	}
 
-			slices.SortFunc(nexthops, neigh.HardwareRoute.Compare)
-			nexthops = slices.Compact(nexthops)
-
+	prefixes := slices.SortedFunc(maps.Keys(blackholes), comparePrefix)
+	for idx, prefix := range prefixes {
+		if idx < maxBlackholes {
 			entries = append(entries, FIBEntry{
-				Prefix:   prefix,
-				Nexthops: nexthops,
+				Prefix:    prefix,
+				Blackhole: true,
 			})
 			stats.PrefixesAdded++
-			stats.HardwareRoutes += len(nexthops)
+			stats.Blackholes++
+			continue
+		}
+
+		stats.RejectedBlackholes++
+		if entry, ok := buildFIBEntry(prefix, blackholes[prefix], neighbours, degraded, &stats); ok {
+			entries = append(entries, entry)
 		}
 	}
 
 	return FIB{Entries: entries}, stats
 }
+
+// buildFIBEntry resolves the routes of a single prefix into an ECMP entry.
+//
+// It returns false when none of the routes has a resolvable nexthop.
+func buildFIBEntry(
+	prefix netip.Prefix,
+	routes []rib.Route,
+	neighbours neigh.NexthopCacheView,
+	degraded map[netip.Addr]struct{},
+	stats *FIBBuildStats,
+) (FIBEntry, bool) {
+	local := make([]rib.Route, 0, len(routes))
+	for _, r := range routes {
+		if _, ok := neighbours.Lookup(r.NextHop.Unmap()); !ok {
+			stats.NeighbourNotFound++
+			continue
+		}
+
+		local = append(local, r)
+	}
+
+	if len(local) == 0 {
+		return FIBEntry{}, false
+	}
+
+	// The best route of each source is chosen among the resolvable
+	// routes only, so the gateway falls back to a reachable route
+	// when its source's best one has no neighbour.
+	localList := rib.RoutesList{Routes: local}
+	bestRoutes := localList.BestPerSource()
+	stats.FilteredRoutes += len(local) - len(bestRoutes)
+
+	if len(degraded) > 0 {
+		healthy := slices.DeleteFunc(slices.Clone(bestRoutes), func(r rib.Route) bool {
+			_, ok := degraded[r.NextHop.Unmap()]
+			return ok
+		})
+		if len(healthy) > 0 {
+			stats.DegradedRoutes += len(bestRoutes) - len(healthy)
+			bestRoutes = healthy
+		}
+	}
+
+	// IPv4 and IPv6 nexthops resolve to hardware routes alike, so an IPv4
+	// prefix may mix both in its ECMP group. Routes resolving to the same
+	// hardware route add up their weights.
+	weights := map[neigh.HardwareRoute]uint32{}
+	sources := make([]rib.RouteSourceID, 0, 1)
+	for _, r := range bestRoutes {
+		sources = append(sources, r.SourceID)
+		entry, _ := neighbours.Lookup(r.NextHop.Unmap())
+		weights[entry.HardwareRoute] += r.EffectiveWeight()
+		if prefix.Addr().Is4() && !r.NextHop.Unmap().Is4() {
+			stats.ExtendedNexthops++
+		}
+	}
+
+	nexthops := slices.SortedFunc(maps.Keys(weights), neigh.HardwareRoute.Compare)
+	slices.Sort(sources)
+	sources = slices.Compact(sources)
+
+	stats.PrefixesAdded++
+	stats.HardwareRoutes += len(nexthops)
+
+	return FIBEntry{
+		Prefix:   prefix,
+		Nexthops: nexthops,
+		Weights:  nexthopWeights(nexthops, weights),
+		Sources:  sources,
+	}, true
+}
+
+// nexthopWeights returns the weights of the nexthops in their order, nil
+// when they are all equal.
+func nexthopWeights(nexthops []neigh.HardwareRoute, weights map[neigh.HardwareRoute]uint32) []uint32 {
+	ordered := make([]uint32, len(nexthops))
+	for idx, nh := range nexthops {
+		ordered[idx] = weights[nh]
+	}
+	if !slices.ContainsFunc(ordered, func(w uint32) bool { return w != ordered[0] }) {
+		return nil
+	}
+
+	return ordered
+}
+
+func isBlackholeRoute(route rib.Route) bool {
+	return route.Blackhole
+}
+
+func comparePrefix(a netip.Prefix, b netip.Prefix) int {
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Bits(), b.Bits())
+}
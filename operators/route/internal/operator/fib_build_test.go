@@ -53,7 +53,7 @@ func Test_BuildFIB_BestPerSourceFiltersWorse(t *testing.T) {
 		},
 	}
 
-	fib, stats := BuildFIB(ribDump, cache.View())
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
 
 	require.Equal(t, 2, stats.TotalRoutes)
 	require.Equal(t, 1, stats.FilteredRoutes)
@@ -90,7 +90,7 @@ func Test_BuildFIB_EqualCostECMPPreserved(t *testing.T) {
 		},
 	}
 
-	fib, stats := BuildFIB(ribDump, cache.View())
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
 
 	require.Equal(t, 2, stats.TotalRoutes)
 	require.Equal(t, 0, stats.FilteredRoutes)
@@ -98,6 +98,55 @@ func Test_BuildFIB_EqualCostECMPPreserved(t *testing.T) {
 	require.Len(t, fib.Entries[0].Nexthops, 2)
 }
 
+// Test_BuildFIB_DegradedNexthopsAvoided verifies that degraded nexthops are
+// left out of an ECMP group while a healthy one remains, and kept when the
+// whole group is degraded.
+func Test_BuildFIB_DegradedNexthopsAvoided(t *testing.T) {
+	cache := rcucache.NewEmptyCache[netip.Addr, neigh.NeighbourEntry]()
+	routeFor := func(addr, sourceMAC, destinationMAC, device string) {
+		cache.Set(netip.MustParseAddr(addr), neigh.NeighbourEntry{
+			HardwareRoute: neigh.HardwareRoute{
+				SourceMAC:      mustParseMAC(t, sourceMAC),
+				DestinationMAC: mustParseMAC(t, destinationMAC),
+				Device:         device,
+			},
+		})
+	}
+
+	routeFor("10.0.0.1", "0a:00:00:00:00:01", "0a:00:00:00:10:00", "eth1")
+	routeFor("10.0.0.2", "0a:00:00:00:00:02", "0a:00:00:00:20:00", "eth2")
+
+	p1 := netip.MustParseAddr("192.0.2.1")
+	p2 := netip.MustParseAddr("192.0.2.2")
+
+	ribDump := maptrie.NewMapTrie[netip.Prefix, netip.Addr, rib.RoutesList](2)
+	ribDump[24][netip.MustParsePrefix("10.0.0.0/24")] = rib.RoutesList{
+		Routes: []rib.Route{
+			{NextHop: netip.MustParseAddr("10.0.0.1"), Peer: p1, SourceID: rib.RouteSourceBird, Pref: 100},
+			{NextHop: netip.MustParseAddr("10.0.0.2"), Peer: p2, SourceID: rib.RouteSourceBird, Pref: 100},
+		},
+	}
+	ribDump[24][netip.MustParsePrefix("10.1.0.0/24")] = rib.RoutesList{
+		Routes: []rib.Route{
+			{NextHop: netip.MustParseAddr("10.0.0.2"), Peer: p2, SourceID: rib.RouteSourceBird, Pref: 100},
+		},
+	}
+
+	degraded := map[netip.Addr]struct{}{netip.MustParseAddr("10.0.0.2"): {}}
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, degraded)
+
+	require.Equal(t, 1, stats.DegradedRoutes)
+	require.Len(t, fib.Entries, 2)
+	for _, entry := range fib.Entries {
+		require.Len(t, entry.Nexthops, 1)
+		if entry.Prefix == netip.MustParsePrefix("10.0.0.0/24") {
+			require.Equal(t, "eth1", entry.Nexthops[0].Device, "degraded nexthop must be avoided")
+		} else {
+			require.Equal(t, "eth2", entry.Nexthops[0].Device, "the only nexthop must be kept even if degraded")
+		}
+	}
+}
+
 // Test_BuildFIB_StaticAndBirdBothInFIB verifies that static and BGP routes for
 // the same prefix each contribute their best nexthop to the FIB independently.
 func Test_BuildFIB_StaticAndBirdBothInFIB(t *testing.T) {
@@ -128,7 +177,7 @@ func Test_BuildFIB_StaticAndBirdBothInFIB(t *testing.T) {
 		},
 	}
 
-	fib, stats := BuildFIB(ribDump, cache.View())
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
 
 	require.Equal(t, 2, stats.TotalRoutes)
 	require.Equal(t, 0, stats.FilteredRoutes, "static route is its own source's best — not filtered")
@@ -195,7 +244,7 @@ func Test_BuildFIB_PerGatewayDeviceFilter(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			view := neigh.FilterByDevices(cache.View(), tc.devices)
-			fib, _ := BuildFIB(ribDump, view)
+			fib, _ := BuildFIB(ribDump, view, 0, nil)
 			require.Len(t, fib.Entries, 1)
 			require.Len(t, fib.Entries[0].Nexthops, 1)
 			require.Equal(t, tc.wantDevice, fib.Entries[0].Nexthops[0].Device)
@@ -223,7 +272,7 @@ func Test_BuildFIB_DeviceFilterStarvesPrefix(t *testing.T) {
 	}
 
 	view := neigh.FilterByDevices(cache.View(), []string{"eth2"})
-	fib, stats := BuildFIB(ribDump, view)
+	fib, stats := BuildFIB(ribDump, view, 0, nil)
 	require.Empty(t, fib.Entries)
 	require.Equal(t, 1, stats.NeighbourNotFound)
 }
@@ -259,7 +308,7 @@ func Test_BuildFIB_FallsBackToLiveRouteWhenBestUnresolvable(t *testing.T) {
 		},
 	}
 
-	fib, stats := BuildFIB(ribDump, cache.View())
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
 
 	require.Len(t, fib.Entries, 1)
 	require.Len(t, fib.Entries[0].Nexthops, 1)
@@ -307,7 +356,7 @@ func Test_BuildFIB_MultiSourceWithDeviceFilter(t *testing.T) {
 	}
 
 	view := neigh.FilterByDevices(cache.View(), []string{"eth1"})
-	fib, stats := BuildFIB(ribDump, view)
+	fib, stats := BuildFIB(ribDump, view, 0, nil)
 
 	require.Len(t, fib.Entries, 1)
 	require.Len(t, fib.Entries[0].Nexthops, 2, "bird fallback and static, both on eth1")
@@ -363,7 +412,7 @@ func Test_BuildFIB_MultiPrefix(t *testing.T) {
 	}
 
 	view := neigh.FilterByDevices(cache.View(), []string{"eth1"})
-	fib, stats := BuildFIB(ribDump, view)
+	fib, stats := BuildFIB(ribDump, view, 0, nil)
 
 	require.Len(t, fib.Entries, 2)
 	require.Equal(t, 1, stats.NeighbourNotFound, "only the eth2 route of the wider prefix is dropped")
@@ -413,7 +462,7 @@ func Test_BuildFIB_DedupsNexthops(t *testing.T) {
 		},
 	}
 
-	fib, stats := BuildFIB(ribDump, cache.View())
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
 
 	require.Len(t, fib.Entries, 1)
 	require.Equal(t, 1, stats.PrefixesAdded)
@@ -441,3 +490,198 @@ func Test_BuildFIB_DedupsNexthops(t *testing.T) {
 	}
 	require.Equal(t, expected, fib.Entries[0].Nexthops)
 }
+
+// Test_BuildFIB_WeightedNexthops verifies that the weights of the routes
+// are carried over to their nexthops, adding up for routes resolving to
+// the same hardware route, and omitted when the nexthops weigh the same.
+func Test_BuildFIB_WeightedNexthops(t *testing.T) {
+	cache := rcucache.NewEmptyCache[netip.Addr, neigh.NeighbourEntry]()
+	routeFor := func(addr, sourceMAC, destinationMAC, device string) {
+		cache.Set(netip.MustParseAddr(addr), neigh.NeighbourEntry{
+			HardwareRoute: neigh.HardwareRoute{
+				SourceMAC:      mustParseMAC(t, sourceMAC),
+				DestinationMAC: mustParseMAC(t, destinationMAC),
+				Device:         device,
+			},
+		})
+	}
+
+	routeFor("10.0.0.1", "0a:00:00:00:00:01", "0a:00:00:00:10:00", "eth1")
+	routeFor("10.0.0.2", "0a:00:00:00:00:01", "0a:00:00:00:20:00", "eth1")
+	routeFor("10.0.0.3", "0a:00:00:00:00:01", "0a:00:00:00:20:00", "eth1")
+
+	weighted := netip.MustParsePrefix("10.1.0.0/24")
+	equal := netip.MustParsePrefix("10.2.0.0/24")
+
+	ribDump := maptrie.NewMapTrie[netip.Prefix, netip.Addr, rib.RoutesList](2)
+	ribDump[24][weighted] = rib.RoutesList{
+		Routes: []rib.Route{
+			{NextHop: netip.MustParseAddr("10.0.0.1"), SourceID: rib.RouteSourceStatic, Weight: 3},
+			// Both resolve to the same hardware route, one weighing one.
+			{NextHop: netip.MustParseAddr("10.0.0.2"), SourceID: rib.RouteSourceStatic},
+			{NextHop: netip.MustParseAddr("10.0.0.3"), SourceID: rib.RouteSourceStatic, Weight: 4},
+		},
+	}
+	ribDump[24][equal] = rib.RoutesList{
+		Routes: []rib.Route{
+			{NextHop: netip.MustParseAddr("10.0.0.1"), SourceID: rib.RouteSourceStatic, Weight: 2},
+			{NextHop: netip.MustParseAddr("10.0.0.2"), SourceID: rib.RouteSourceStatic, Weight: 2},
+		},
+	}
+
+	fib, _ := BuildFIB(ribDump, cache.View(), 0, nil)
+
+	entries := map[netip.Prefix]FIBEntry{}
+	for _, entry := range fib.Entries {
+		entries[entry.Prefix] = entry
+	}
+	require.Len(t, entries, 2)
+
+	require.Len(t, entries[weighted].Nexthops, 2)
+	require.Equal(t, mustParseMAC(t, "0a:00:00:00:10:00"), entries[weighted].Nexthops[0].DestinationMAC)
+	require.Equal(t, []uint32{3, 5}, entries[weighted].Weights)
+
+	require.Len(t, entries[equal].Nexthops, 2)
+	require.Nil(t, entries[equal].Weights)
+}
+
+// Test_BuildFIB_Blackhole verifies that host routes carrying the BLACKHOLE
+// community are installed as drop routes within the limit, while other
+// blackhole routes never forward traffic.
+func Test_BuildFIB_Blackhole(t *testing.T) {
+	cache := rcucache.NewEmptyCache[netip.Addr, neigh.NeighbourEntry]()
+	cache.Set(netip.MustParseAddr("10.0.0.1"), neigh.NeighbourEntry{
+		HardwareRoute: neigh.HardwareRoute{
+			SourceMAC:      mustParseMAC(t, "0a:00:00:00:00:01"),
+			DestinationMAC: mustParseMAC(t, "0a:00:00:00:10:00"),
+			Device:         "eth1",
+		},
+	})
+
+	peer := netip.MustParseAddr("192.0.2.1")
+	blackhole := rib.Route{
+		NextHop:   netip.MustParseAddr("10.0.0.1"),
+		Peer:      peer,
+		SourceID:  rib.RouteSourceBird,
+		Blackhole: true,
+	}
+	static := rib.Route{
+		NextHop:  netip.MustParseAddr("10.0.0.1"),
+		SourceID: rib.RouteSourceStatic,
+	}
+
+	tests := []struct {
+		name          string
+		routes        map[netip.Prefix][]rib.Route
+		maxBlackholes int
+		blackholes    []string
+		forwarded     []string
+		rejected      int
+	}{
+		{
+			name: "host prefixes dropped",
+			routes: map[netip.Prefix][]rib.Route{
+				netip.MustParsePrefix("198.51.100.1/32"): {blackhole},
+				netip.MustParsePrefix("2001:db8::1/128"): {blackhole},
+			},
+			maxBlackholes: 10,
+			blackholes:    []string{"198.51.100.1/32", "2001:db8::1/128"},
+		},
+		{
+			name: "non-host prefix rejected",
+			routes: map[netip.Prefix][]rib.Route{
+				netip.MustParsePrefix("198.51.100.0/24"): {blackhole, static},
+			},
+			maxBlackholes: 10,
+			forwarded:     []string{"198.51.100.0/24"},
+			rejected:      1,
+		},
+		{
+			name: "limit keeps lowest prefixes",
+			routes: map[netip.Prefix][]rib.Route{
+				netip.MustParsePrefix("198.51.100.2/32"): {blackhole, static},
+				netip.MustParsePrefix("198.51.100.1/32"): {blackhole},
+				netip.MustParsePrefix("198.51.100.3/32"): {blackhole},
+			},
+			maxBlackholes: 1,
+			blackholes:    []string{"198.51.100.1/32"},
+			forwarded:     []string{"198.51.100.2/32"},
+			rejected:      2,
+		},
+		{
+			name: "disabled",
+			routes: map[netip.Prefix][]rib.Route{
+				netip.MustParsePrefix("198.51.100.1/32"): {blackhole},
+			},
+			rejected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ribDump := maptrie.NewMapTrie[netip.Prefix, netip.Addr, rib.RoutesList](len(tt.routes))
+			for prefix, routes := range tt.routes {
+				ribDump[prefix.Bits()][prefix] = rib.RoutesList{Routes: routes}
+			}
+
+			fib, stats := BuildFIB(ribDump, cache.View(), tt.maxBlackholes, nil)
+
+			var blackholes []string
+			var forwarded []string
+			for _, entry := range fib.Entries {
+				if entry.Blackhole {
+					require.Empty(t, entry.Nexthops)
+					blackholes = append(blackholes, entry.Prefix.String())
+					continue
+				}
+				forwarded = append(forwarded, entry.Prefix.String())
+			}
+
+			require.ElementsMatch(t, tt.blackholes, blackholes)
+			require.ElementsMatch(t, tt.forwarded, forwarded)
+			require.Equal(t, len(tt.blackholes), stats.Blackholes)
+			require.Equal(t, tt.rejected, stats.RejectedBlackholes)
+		})
+	}
+}
+
+// Test_BuildFIB_ExtendedNexthop verifies that an IPv4 prefix announced via
+// IPv6 nexthops (RFC 8950) resolves through the IPv6 neighbours, alongside
+// an IPv4-mapped nexthop in the same ECMP group.
+func Test_BuildFIB_ExtendedNexthop(t *testing.T) {
+	cache := rcucache.NewEmptyCache[netip.Addr, neigh.NeighbourEntry]()
+	routeFor := func(addr, sourceMAC, destinationMAC, device string) {
+		cache.Set(netip.MustParseAddr(addr), neigh.NeighbourEntry{
+			HardwareRoute: neigh.HardwareRoute{
+				SourceMAC:      mustParseMAC(t, sourceMAC),
+				DestinationMAC: mustParseMAC(t, destinationMAC),
+				Device:         device,
+			},
+		})
+	}
+
+	routeFor("fe80::1", "0a:00:00:00:00:01", "0a:00:00:00:10:00", "eth1")
+	routeFor("10.0.0.2", "0a:00:00:00:00:02", "0a:00:00:00:20:00", "eth2")
+
+	p1 := netip.MustParseAddr("fe80::1")
+	p2 := netip.MustParseAddr("192.0.2.2")
+
+	prefix := netip.MustParsePrefix("10.1.0.0/16")
+	ribDump := maptrie.NewMapTrie[netip.Prefix, netip.Addr, rib.RoutesList](2)
+	ribDump[16][prefix] = rib.RoutesList{
+		Routes: []rib.Route{
+			{Prefix: prefix, NextHop: netip.MustParseAddr("fe80::1"), Peer: p1, SourceID: rib.RouteSourceBird, Pref: 100},
+			{Prefix: prefix, NextHop: netip.MustParseAddr("::ffff:10.0.0.2"), Peer: p2, SourceID: rib.RouteSourceBird, Pref: 100},
+		},
+	}
+
+	fib, stats := BuildFIB(ribDump, cache.View(), 0, nil)
+
+	require.Zero(t, stats.NeighbourNotFound)
+	require.Equal(t, 1, stats.ExtendedNexthops)
+	require.Len(t, fib.Entries, 1)
+	require.Equal(t, prefix, fib.Entries[0].Prefix)
+	require.Len(t, fib.Entries[0].Nexthops, 2)
+	require.Equal(t, "eth1", fib.Entries[0].Nexthops[0].Device)
+	require.Equal(t, "eth2", fib.Entries[0].Nexthops[1].Device)
+}
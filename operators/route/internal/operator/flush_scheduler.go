@@ -0,0 +1,406 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultFlushMinInterval is the default lower bound of the adaptive
+	// flush interval.
+	defaultFlushMinInterval = 10 * time.Millisecond
+
+	// defaultFlushMaxInterval is the default upper bound of the adaptive
+	// flush interval.
+	defaultFlushMaxInterval = 1 * time.Second
+
+	// defaultFlushBusyRate is the default RIB update rate (updates/sec)
+	// above which the flush interval widens.
+	defaultFlushBusyRate = 1000.0
+
+	// defaultFlushDeadline is the default maximum time an accepted RIB
+	// change waits for a reconcile pass.
+	defaultFlushDeadline = 5 * time.Second
+
+	// defaultFlushMaxCommitRate is the default maximum rate of the
+	// reconcile passes woken by RIB changes, in commits per second.
+	defaultFlushMaxCommitRate = 10.0
+
+	// defaultFlushCommitBurst is the default number of commits allowed in
+	// a row above the commit rate.
+	defaultFlushCommitBurst = 5
+
+	// flushCommitSmoothing is the weight of the latest commit duration in
+	// its moving average.
+	flushCommitSmoothing = 0.25
+)
+
+// FlushPressure describes the load the flush scheduler adapts to.
+type FlushPressure struct {
+	// UpdateRate is the RIB update arrival rate, in updates per second,
+	// observed since the previous flush.
+	UpdateRate float64
+	// CommitDuration is the moving average of the time a reconcile pass
+	// takes to commit the FIB to the dataplane.
+	CommitDuration time.Duration
+	// Interval is the batching interval applied to the next flush.
+	Interval time.Duration
+}
+
+// FlushScheduler batches RIB flush requests before waking the reconcile
+// loop.
+//
+// Every flush request is delayed by the current interval, and requests
+// arriving meanwhile are coalesced into a single reconcile pass. After
+// each pass the interval doubles while the RIB update rate stays at
+// FlushConfig.BusyRate or the dataplane commit takes more than half of
+// the interval, and halves otherwise. It is kept within the configured
+// bounds and never drops below twice the commit duration, so commits
+// cannot pile up.
+//
+// With batching disabled every flush request wakes the reconcile loop
+// immediately.
+//
+// Independently of batching, the scheduler bounds the time between a RIB
+// change being accepted and its commit by FlushConfig.Deadline: a change
+// no reconcile pass picked up within the deadline wakes the reconcile loop
+// right away, see RunDeadline.
+type FlushScheduler struct {
+	cfg        FlushConfig
+	wake       func()
+	onAdjusted func(FlushPressure)
+	onViolated func(time.Duration)
+	pendingCh  chan struct{}
+	updates    atomic.Uint64
+
+	// acceptedSince is the time, in Unix nanoseconds, of the oldest
+	// accepted change no snapshot has picked up yet, zero if there is
+	// none.
+	acceptedSince atomic.Int64
+	acceptedCh    chan struct{}
+
+	mu       sync.Mutex
+	rate     float64
+	commit   time.Duration
+	interval time.Duration
+	// inflightSince is the time of the oldest accepted change picked up by
+	// a snapshot but not committed yet.
+	inflightSince time.Time
+
+	log *zap.Logger
+}
+
+// NewFlushScheduler creates a FlushScheduler waking the reconcile loop
+// through wake.
+//
+// The onAdjusted callback receives the pressure observed on every
+// interval adjustment, the onViolated callback the commit latency of
+// every change committed past the deadline.
+func NewFlushScheduler(
+	cfg FlushConfig,
+	wake func(),
+	onAdjusted func(FlushPressure),
+	onViolated func(time.Duration),
+	log *zap.Logger,
+) *FlushScheduler {
+	return &FlushScheduler{
+		cfg:        cfg,
+		wake:       wake,
+		onAdjusted: onAdjusted,
+		onViolated: onViolated,
+		pendingCh:  make(chan struct{}, 1),
+		acceptedCh: make(chan struct{}, 1),
+		interval:   cfg.MinInterval,
+		log:        log,
+	}
+}
+
+// DeadlineEnabled reports whether accepted changes are committed within
+// the flush deadline.
+func (m *FlushScheduler) DeadlineEnabled() bool {
+	return m.cfg.Deadline > 0
+}
+
+// Enabled reports whether flush requests are batched.
+func (m *FlushScheduler) Enabled() bool {
+	return m.cfg.MaxInterval > 0
+}
+
+// Flush requests a reconcile pass.
+//
+// It never blocks and is suitable for the RouteService OnChanged
+// callback.
+func (m *FlushScheduler) Flush() {
+	if !m.Enabled() {
+		m.wake()
+		return
+	}
+
+	select {
+	case m.pendingCh <- struct{}{}:
+	default:
+	}
+}
+
+// OnUpdate accounts n applied RIB updates in the arrival rate.
+func (m *FlushScheduler) OnUpdate(n int) {
+	m.updates.Add(uint64(n))
+}
+
+// Accept records a RIB change that must be committed within the deadline.
+//
+// It never blocks and is cheap enough for the FeedRIB hot path: only the
+// first change since the last snapshot is timestamped.
+func (m *FlushScheduler) Accept() {
+	if !m.DeadlineEnabled() || m.acceptedSince.Load() != 0 {
+		return
+	}
+	if !m.acceptedSince.CompareAndSwap(0, time.Now().UnixNano()) {
+		return
+	}
+
+	select {
+	case m.acceptedCh <- struct{}{}:
+	default:
+	}
+}
+
+// onSnapshot marks the accepted changes as picked up by the snapshot of a
+// reconcile pass.
+func (m *FlushScheduler) onSnapshot() {
+	since := m.acceptedSince.Swap(0)
+	if since == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A failed pass leaves its changes in flight, and the retry commits
+	// them together with the newer ones.
+	if m.inflightSince.IsZero() {
+		m.inflightSince = time.Unix(0, since)
+	}
+}
+
+// onCommitted reports the in-flight changes committed, accounting a
+// deadline violation if the oldest of them waited for longer than the
+// deadline.
+func (m *FlushScheduler) onCommitted() {
+	m.mu.Lock()
+	since := m.inflightSince
+	m.inflightSince = time.Time{}
+	m.mu.Unlock()
+
+	if since.IsZero() || !m.DeadlineEnabled() {
+		return
+	}
+
+	latency := time.Since(since)
+	if latency <= m.cfg.Deadline {
+		return
+	}
+	m.log.Warn("committed RIB changes past the flush deadline",
+		zap.Duration("latency", latency),
+		zap.Duration("deadline", m.cfg.Deadline),
+	)
+	m.onViolated(latency)
+}
+
+// ObserveCommit records the duration of a reconcile pass.
+func (m *FlushScheduler) ObserveCommit(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.commit == 0 {
+		m.commit = d
+		return
+	}
+	m.commit += time.Duration(flushCommitSmoothing * float64(d-m.commit))
+}
+
+// Pressure returns the currently observed pressure.
+func (m *FlushScheduler) Pressure() FlushPressure {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return FlushPressure{
+		UpdateRate:     m.rate,
+		CommitDuration: m.commit,
+		Interval:       m.interval,
+	}
+}
+
+// Run delays pending flush requests by the adaptive interval until the
+// context is cancelled.
+func (m *FlushScheduler) Run(ctx context.Context) error {
+	sampledAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.pendingCh:
+		}
+
+		timer := time.NewTimer(m.Pressure().Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		m.adjust(m.updates.Swap(0), now.Sub(sampledAt))
+		sampledAt = now
+
+		m.wake()
+	}
+}
+
+// RunDeadline wakes the reconcile loop whenever an accepted change waits
+// for a snapshot for longer than the deadline, until the context is
+// cancelled.
+//
+// The wake bypasses the batching interval, so the change is committed in
+// the next pass even if no flush has been requested for it.
+func (m *FlushScheduler) RunDeadline(ctx context.Context) error {
+	// forced is the accepted timestamp the reconcile loop has already been
+	// woken for, so a busy loop is not woken again until it takes a new
+	// snapshot.
+	forced := int64(0)
+	for {
+		since := m.acceptedSince.Load()
+		if since == 0 || since == forced {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-m.acceptedCh:
+			}
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(time.Unix(0, since).Add(m.cfg.Deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if m.acceptedSince.Load() != since {
+			// A snapshot picked the changes up in time.
+			continue
+		}
+		forced = since
+		m.log.Debug("forced reconcile pass on flush deadline",
+			zap.Duration("deadline", m.cfg.Deadline),
+		)
+		m.wake()
+	}
+}
+
+// adjust recomputes the interval from the number of updates received
+// within the elapsed time.
+func (m *FlushScheduler) adjust(updates uint64, elapsed time.Duration) FlushPressure {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(updates) / elapsed.Seconds()
+	}
+
+	m.mu.Lock()
+	prev := m.interval
+	interval := prev
+	if rate >= m.cfg.BusyRate || m.commit > interval/2 {
+		interval *= 2
+	} else {
+		interval /= 2
+	}
+	interval = min(max(interval, m.cfg.MinInterval, 2*m.commit), m.cfg.MaxInterval)
+	m.rate = rate
+	m.interval = interval
+
+	pressure := FlushPressure{
+		UpdateRate:     rate,
+		CommitDuration: m.commit,
+		Interval:       interval,
+	}
+	m.mu.Unlock()
+
+	if interval != prev {
+		m.log.Debug("adjusted flush interval",
+			zap.Duration("interval", interval),
+			zap.Duration("prev_interval", prev),
+			zap.Float64("update_rate", rate),
+			zap.Duration("commit_duration", pressure.CommitDuration),
+		)
+	}
+	m.onAdjusted(pressure)
+
+	return pressure
+}
+
+// flushObservedActuator reports the duration of every Apply to the flush
+// scheduler.
+type flushObservedActuator struct {
+	inner     Actuator
+	scheduler *FlushScheduler
+}
+
+// newFlushObservedActuator wraps inner so its commit durations drive the
+// flush interval.
+func newFlushObservedActuator(inner Actuator, scheduler *FlushScheduler) *flushObservedActuator {
+	return &flushObservedActuator{
+		inner:     inner,
+		scheduler: scheduler,
+	}
+}
+
+// Apply times the inner actuator's Apply call, returning its error
+// unchanged.
+func (m *flushObservedActuator) Apply(ctx context.Context, snapshot RouteSnapshot) error {
+	start := time.Now()
+	err := m.inner.Apply(ctx, snapshot)
+	m.scheduler.ObserveCommit(time.Since(start))
+	return err
+}
+
+// Close delegates to the inner actuator.
+func (m *flushObservedActuator) Close() error {
+	return m.inner.Close()
+}
+
+// flushTrackedSource reports the snapshots taken and committed by the
+// reconcile loop to the flush scheduler, which tracks the deadline of the
+// accepted changes through them.
+type flushTrackedSource struct {
+	*RouteSource
+	scheduler *FlushScheduler
+}
+
+// newFlushTrackedSource wraps inner so its snapshots drive the flush
+// deadline.
+func newFlushTrackedSource(inner *RouteSource, scheduler *FlushScheduler) *flushTrackedSource {
+	return &flushTrackedSource{
+		RouteSource: inner,
+		scheduler:   scheduler,
+	}
+}
+
+// Snapshot marks the changes accepted so far as picked up before taking
+// the inner snapshot, so none of them can be missed by it.
+func (m *flushTrackedSource) Snapshot() (RouteSnapshot, bool) {
+	m.scheduler.onSnapshot()
+	return m.RouteSource.Snapshot()
+}
+
+// Advance reports the snapshot committed, then delegates to the inner
+// source.
+func (m *flushTrackedSource) Advance(snapshot RouteSnapshot) {
+	m.scheduler.onCommitted()
+	m.RouteSource.Advance(snapshot)
+}
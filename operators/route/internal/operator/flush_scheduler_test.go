@@ -0,0 +1,178 @@
+package operator
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestFlushScheduler(wake func()) *FlushScheduler {
+	cfg := FlushConfig{
+		MinInterval: 10 * time.Millisecond,
+		MaxInterval: 160 * time.Millisecond,
+		BusyRate:    100,
+	}
+	return NewFlushScheduler(cfg, wake, func(FlushPressure) {}, func(time.Duration) {}, zap.NewNop())
+}
+
+// TestFlushScheduler_Adjust verifies that the interval widens under update
+// and commit pressure within the configured bounds, and narrows back once
+// the pressure drops.
+func TestFlushScheduler_Adjust(t *testing.T) {
+	scheduler := newTestFlushScheduler(func() {})
+
+	// 1000 updates/sec are above the busy rate.
+	intervals := []time.Duration{}
+	for range 5 {
+		intervals = append(intervals, scheduler.adjust(100, 100*time.Millisecond).Interval)
+	}
+	require.Equal(t, []time.Duration{
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+		160 * time.Millisecond,
+		160 * time.Millisecond,
+	}, intervals)
+
+	pressure := scheduler.adjust(1, time.Second)
+	require.Equal(t, 80*time.Millisecond, pressure.Interval)
+	require.Equal(t, 1.0, pressure.UpdateRate)
+	for range 5 {
+		pressure = scheduler.adjust(0, time.Second)
+	}
+	require.Equal(t, 10*time.Millisecond, pressure.Interval)
+
+	// Slow commits keep the interval at twice the commit duration even when
+	// no updates arrive.
+	scheduler.ObserveCommit(30 * time.Millisecond)
+	pressure = scheduler.adjust(0, time.Second)
+	require.Equal(t, 30*time.Millisecond, pressure.CommitDuration)
+	require.Equal(t, 60*time.Millisecond, pressure.Interval)
+	require.Equal(t, 60*time.Millisecond, scheduler.adjust(0, time.Second).Interval)
+}
+
+func TestFlushScheduler_ObserveCommit(t *testing.T) {
+	scheduler := newTestFlushScheduler(func() {})
+
+	scheduler.ObserveCommit(100 * time.Millisecond)
+	require.Equal(t, 100*time.Millisecond, scheduler.Pressure().CommitDuration)
+
+	scheduler.ObserveCommit(200 * time.Millisecond)
+	require.Equal(t, 125*time.Millisecond, scheduler.Pressure().CommitDuration)
+}
+
+// TestFlushScheduler_Coalesce verifies that flush requests arriving within
+// the interval wake the reconcile loop once.
+func TestFlushScheduler_Coalesce(t *testing.T) {
+	wakes := atomic.Int64{}
+	scheduler := newTestFlushScheduler(func() { wakes.Add(1) })
+
+	for range 10 {
+		scheduler.Flush()
+	}
+	go scheduler.Run(t.Context())
+
+	require.Eventually(t, func() bool {
+		return wakes.Load() == 1
+	}, 5*time.Second, time.Millisecond)
+	require.Never(t, func() bool {
+		return wakes.Load() > 1
+	}, 50*time.Millisecond, time.Millisecond)
+}
+
+func TestFlushScheduler_Disabled(t *testing.T) {
+	wakes := atomic.Int64{}
+	scheduler := NewFlushScheduler(FlushConfig{}, func() { wakes.Add(1) }, func(FlushPressure) {}, func(time.Duration) {}, zap.NewNop())
+	require.False(t, scheduler.Enabled())
+
+	scheduler.Flush()
+	scheduler.Flush()
+	require.Equal(t, int64(2), wakes.Load())
+}
+
+func newTestDeadlineScheduler(wake func(), onViolated func(time.Duration)) *FlushScheduler {
+	cfg := FlushConfig{
+		Deadline: 20 * time.Millisecond,
+	}
+	return NewFlushScheduler(cfg, wake, func(FlushPressure) {}, onViolated, zap.NewNop())
+}
+
+// TestFlushScheduler_Deadline verifies that accepted changes nobody flushed
+// force a single reconcile pass once the deadline passes.
+func TestFlushScheduler_Deadline(t *testing.T) {
+	wakes := atomic.Int64{}
+	scheduler := newTestDeadlineScheduler(func() { wakes.Add(1) }, func(time.Duration) {})
+	require.True(t, scheduler.DeadlineEnabled())
+	go scheduler.RunDeadline(t.Context())
+
+	for range 10 {
+		scheduler.Accept()
+	}
+	require.Eventually(t, func() bool {
+		return wakes.Load() == 1
+	}, 5*time.Second, time.Millisecond)
+	require.Never(t, func() bool {
+		return wakes.Load() > 1
+	}, 60*time.Millisecond, time.Millisecond)
+
+	// Changes accepted after the pass picked up the previous ones start a
+	// new deadline.
+	scheduler.onSnapshot()
+	scheduler.onCommitted()
+	scheduler.Accept()
+	require.Eventually(t, func() bool {
+		return wakes.Load() == 2
+	}, 5*time.Second, time.Millisecond)
+}
+
+// TestFlushScheduler_DeadlineMet verifies that a reconcile pass picking up
+// the accepted changes in time prevents the forced one.
+func TestFlushScheduler_DeadlineMet(t *testing.T) {
+	wakes := atomic.Int64{}
+	violations := atomic.Int64{}
+	scheduler := newTestDeadlineScheduler(func() { wakes.Add(1) }, func(time.Duration) { violations.Add(1) })
+	go scheduler.RunDeadline(t.Context())
+
+	scheduler.Accept()
+	scheduler.onSnapshot()
+	scheduler.onCommitted()
+
+	require.Never(t, func() bool {
+		return wakes.Load() > 0
+	}, 60*time.Millisecond, time.Millisecond)
+	require.Zero(t, violations.Load())
+}
+
+// TestFlushScheduler_DeadlineViolated verifies that committing changes
+// accepted longer than the deadline ago is reported.
+func TestFlushScheduler_DeadlineViolated(t *testing.T) {
+	latencies := []time.Duration{}
+	scheduler := newTestDeadlineScheduler(func() {}, func(latency time.Duration) {
+		latencies = append(latencies, latency)
+	})
+
+	scheduler.Accept()
+	scheduler.onSnapshot()
+	time.Sleep(30 * time.Millisecond)
+
+	// A failed pass keeps the oldest change in flight for the next one.
+	scheduler.Accept()
+	scheduler.onSnapshot()
+	scheduler.onCommitted()
+	require.Len(t, latencies, 1)
+	require.GreaterOrEqual(t, latencies[0], 30*time.Millisecond)
+
+	scheduler.onCommitted()
+	require.Len(t, latencies, 1)
+}
+
+func TestFlushScheduler_DeadlineDisabled(t *testing.T) {
+	scheduler := newTestFlushScheduler(func() {})
+	require.False(t, scheduler.DeadlineEnabled())
+
+	scheduler.Accept()
+	require.Zero(t, scheduler.acceptedSince.Load())
+}
@@ -0,0 +1,65 @@
+package operator
+
+import (
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+const (
+	// defaultMassWithdrawBatchSize is the default maximum number of
+	// consecutive FeedRIB withdrawals applied to the RIB at once.
+	defaultMassWithdrawBatchSize = 1024
+
+	// defaultMassWithdrawThreshold is the default number of withdrawals
+	// between two FeedRIB flush events considered a mass withdrawal.
+	defaultMassWithdrawThreshold = 10000
+)
+
+// withdrawBatch accumulates the consecutive withdrawals of a FeedRIB
+// session.
+//
+// A BGP peer going down makes BIRD withdraw every prefix learned from it
+// in a row. Applying them one by one takes the RIB lock and fires the
+// update callbacks per prefix; a batch applies them under a single lock
+// and reports them at once.
+type withdrawBatch struct {
+	routes []rib.Route
+	size   int
+	// withdrawn is the number of withdrawals received since the last
+	// flush event.
+	withdrawn int
+}
+
+func newWithdrawBatch(size int) *withdrawBatch {
+	return &withdrawBatch{
+		routes: make([]rib.Route, 0, min(size, defaultMassWithdrawBatchSize)),
+		size:   max(size, 1),
+	}
+}
+
+// Add appends a withdrawal, reporting whether the batch is full.
+func (m *withdrawBatch) Add(route rib.Route) bool {
+	m.routes = append(m.routes, route)
+	m.withdrawn++
+	return len(m.routes) >= m.size
+}
+
+// Apply withdraws the accumulated routes from the RIB and empties the
+// batch, returning the number of routes it contained and the number of
+// them that changed the RIB.
+func (m *withdrawBatch) Apply(ribRef *rib.RIB) (int, int) {
+	if len(m.routes) == 0 {
+		return 0, 0
+	}
+	count := len(m.routes)
+	changed := ribRef.Update(m.routes...)
+	m.routes = m.routes[:0]
+	return count, changed
+}
+
+// Flushed resets the withdrawals counted since the last flush event,
+// returning their number.
+func (m *withdrawBatch) Flushed() int {
+	withdrawn := m.withdrawn
+	m.withdrawn = 0
+	return withdrawn
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/yanet-platform/yanet2/common/go/metrics"
 	"github.com/yanet-platform/yanet2/common/go/operator"
 	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
 )
 
 // applyDurationBounds are the histogram bucket upper bounds, in seconds,
@@ -38,9 +39,19 @@ type Metrics struct {
 	states          map[operator.ReconcilerState]*metrics.Gauge
 	backoffSeconds  metrics.Gauge
 
-	ribSessionStarts *metrics.MetricMap[*metrics.Counter]
-	ribSessionEnds   *metrics.MetricMap[*metrics.Counter]
-	ribFeedUpdates   metrics.Counter
+	ribSessionStarts       *metrics.MetricMap[*metrics.Counter]
+	ribSessionEnds         *metrics.MetricMap[*metrics.Counter]
+	ribFeedUpdates         metrics.Counter
+	ribFeedDuplicates      metrics.Counter
+	ribFeedLooped          metrics.Counter
+	ribFeedNexthopRejected *metrics.MetricMap[*metrics.Counter]
+	ribMirrorDropped       metrics.Counter
+
+	flushInterval       metrics.Gauge
+	flushUpdateRate     metrics.Gauge
+	flushCommitDuration metrics.Gauge
+	flushDeadlineMissed metrics.Counter
+	flushDeferred       metrics.Counter
 
 	neighbourHealthy metrics.Gauge
 	neighbourResyncs metrics.Counter
@@ -64,13 +75,14 @@ func NewMetrics(ribs *RIBStore, neighTable *neigh.NeighTable, options ...Metrics
 	}
 
 	return &Metrics{
-		ribs:                  ribs,
-		neighTable:            neighTable,
-		netlinkMonitorEnabled: opts.NetlinkMonitorEnabled,
-		states:                states,
-		ribSessionStarts:      metrics.NewMetricMap[*metrics.Counter](),
-		ribSessionEnds:        metrics.NewMetricMap[*metrics.Counter](),
-		gateways:              map[string]*GatewayMetrics{},
+		ribs:                   ribs,
+		neighTable:             neighTable,
+		netlinkMonitorEnabled:  opts.NetlinkMonitorEnabled,
+		states:                 states,
+		ribSessionStarts:       metrics.NewMetricMap[*metrics.Counter](),
+		ribSessionEnds:         metrics.NewMetricMap[*metrics.Counter](),
+		ribFeedNexthopRejected: metrics.NewMetricMap[*metrics.Counter](),
+		gateways:               map[string]*GatewayMetrics{},
 	}
 }
 
@@ -159,6 +171,51 @@ func (m *Metrics) OnRIBUpdate(n int) {
 	m.ribFeedUpdates.Add(uint64(n))
 }
 
+// OnRIBDuplicate records that n FeedRIB updates were suppressed because
+// they did not change the stored routes.
+func (m *Metrics) OnRIBDuplicate(n int) {
+	m.ribFeedDuplicates.Add(uint64(n))
+}
+
+// OnRIBLooped records that n FeedRIB updates were dropped because they
+// carried the origin community of this node.
+func (m *Metrics) OnRIBLooped(n int) {
+	m.ribFeedLooped.Add(uint64(n))
+}
+
+// OnRIBNexthopRejected records that n FeedRIB updates of the named module
+// config were dropped because their nexthop is outside its strict nexthop
+// networks.
+func (m *Metrics) OnRIBNexthopRejected(name string, n int) {
+	m.ribSessionCounter(m.ribFeedNexthopRejected, name).Add(uint64(n))
+}
+
+// OnRIBMirrorDropped records that n FeedRIB updates were not copied to the
+// mirror.
+func (m *Metrics) OnRIBMirrorDropped(n int) {
+	m.ribMirrorDropped.Add(uint64(n))
+}
+
+// OnFlushAdjusted records the pressure the flush scheduler adapted its
+// interval to.
+func (m *Metrics) OnFlushAdjusted(pressure FlushPressure) {
+	m.flushInterval.Store(pressure.Interval.Seconds())
+	m.flushUpdateRate.Store(pressure.UpdateRate)
+	m.flushCommitDuration.Store(pressure.CommitDuration.Seconds())
+}
+
+// OnFlushDeadlineViolated records a reconcile pass committing RIB changes
+// accepted longer than the flush deadline ago.
+func (m *Metrics) OnFlushDeadlineViolated(latency time.Duration) {
+	m.flushDeadlineMissed.Inc()
+}
+
+// OnCommitDeferred records a reconcile pass deferred by the commit rate
+// limit.
+func (m *Metrics) OnCommitDeferred() {
+	m.flushDeferred.Inc()
+}
+
 // OnNeighbourSynced records the transition to a healthy neighbour table
 // after the initial sync.
 func (m *Metrics) OnNeighbourSynced() {
@@ -234,6 +291,18 @@ func (m *Metrics) Collect() []*commonpb.Metric {
 				module,
 			),
 		)
+		if convergence, ok := ribRef.Convergence(rib.RouteSourceBird); ok {
+			converged := 0.0
+			if convergence.Converged {
+				converged = 1
+			}
+			out = append(out, makeGauge(
+				"route_operator_rib_converged",
+				converged,
+				module,
+				makeLabel("source", "bird"),
+			))
+		}
 	}
 
 	for _, src := range m.neighTable.ListSources() {
@@ -260,7 +329,24 @@ func (m *Metrics) Collect() []*commonpb.Metric {
 			makeLabel("module", entry.ID.Labels["module"]),
 		))
 	}
-	out = append(out, makeCounter("route_operator_rib_feed_updates_total", m.ribFeedUpdates.Load()))
+	for _, entry := range m.ribFeedNexthopRejected.Metrics() {
+		out = append(out, makeCounter(
+			"route_operator_rib_feed_nexthop_rejected_total",
+			entry.Value.Load(),
+			makeLabel("module", entry.ID.Labels["module"]),
+		))
+	}
+	out = append(out,
+		makeCounter("route_operator_rib_feed_updates_total", m.ribFeedUpdates.Load()),
+		makeCounter("route_operator_rib_feed_duplicates_total", m.ribFeedDuplicates.Load()),
+		makeCounter("route_operator_rib_feed_looped_total", m.ribFeedLooped.Load()),
+		makeCounter("route_operator_rib_mirror_dropped_total", m.ribMirrorDropped.Load()),
+		makeGauge("route_operator_flush_interval_seconds", m.flushInterval.Load()),
+		makeGauge("route_operator_flush_update_rate", m.flushUpdateRate.Load()),
+		makeGauge("route_operator_flush_commit_duration_seconds", m.flushCommitDuration.Load()),
+		makeCounter("route_operator_flush_deadline_violations_total", m.flushDeadlineMissed.Load()),
+		makeCounter("route_operator_flush_commits_deferred_total", m.flushDeferred.Load()),
+	)
 
 	if m.netlinkMonitorEnabled {
 		out = append(out,
@@ -292,6 +378,10 @@ type fibGauges struct {
 	unresolvedNexthops metrics.Gauge
 	skippedPrefixes    metrics.Gauge
 	filteredRoutes     metrics.Gauge
+	degradedRoutes     metrics.Gauge
+	extendedNexthops   metrics.Gauge
+	blackholes         metrics.Gauge
+	rejectedBlackholes metrics.Gauge
 }
 
 // GatewayMetrics is the per-gateway observability sink, fed by
@@ -341,6 +431,10 @@ func (m *GatewayMetrics) OnFIBBuilt(module string, stats FIBBuildStats) {
 	g.unresolvedNexthops.Store(float64(stats.NeighbourNotFound))
 	g.skippedPrefixes.Store(float64(stats.SkippedPrefixes))
 	g.filteredRoutes.Store(float64(stats.FilteredRoutes))
+	g.degradedRoutes.Store(float64(stats.DegradedRoutes))
+	g.extendedNexthops.Store(float64(stats.ExtendedNexthops))
+	g.blackholes.Store(float64(stats.Blackholes))
+	g.rejectedBlackholes.Store(float64(stats.RejectedBlackholes))
 }
 
 // collect renders this gateway's metrics as a slice of commonpb.Metric
@@ -366,6 +460,10 @@ func (m *GatewayMetrics) collect() []*commonpb.Metric {
 			makeGauge("route_operator_fib_unresolved_nexthops", g.unresolvedNexthops.Load(), labels...),
 			makeGauge("route_operator_fib_skipped_prefixes", g.skippedPrefixes.Load(), labels...),
 			makeGauge("route_operator_fib_filtered_routes", g.filteredRoutes.Load(), labels...),
+			makeGauge("route_operator_fib_degraded_routes", g.degradedRoutes.Load(), labels...),
+			makeGauge("route_operator_fib_extended_nexthops", g.extendedNexthops.Load(), labels...),
+			makeGauge("route_operator_fib_blackholes", g.blackholes.Load(), labels...),
+			makeGauge("route_operator_fib_rejected_blackholes", g.rejectedBlackholes.Load(), labels...),
 		)
 	}
 
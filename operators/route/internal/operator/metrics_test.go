@@ -111,6 +111,7 @@ func TestMetrics_RIBFeedEvents(t *testing.T) {
 	m.OnRIBSessionStart("route1", 1)
 	m.OnRIBUpdate(3)
 	m.OnRIBUpdate(2)
+	m.OnRIBDuplicate(4)
 
 	metricList := m.Collect()
 
@@ -129,6 +130,10 @@ func TestMetrics_RIBFeedEvents(t *testing.T) {
 	updates := findMetric(metricList, "route_operator_rib_feed_updates_total", map[string]string{})
 	require.NotNil(t, updates)
 	require.Equal(t, uint64(5), updates.GetCounter())
+
+	duplicates := findMetric(metricList, "route_operator_rib_feed_duplicates_total", map[string]string{})
+	require.NotNil(t, duplicates)
+	require.Equal(t, uint64(4), duplicates.GetCounter())
 }
 
 // TestMetrics_NeighbourEvents verifies that the neighbour health gauge and
@@ -202,6 +207,33 @@ func TestMetrics_PullRIBStats(t *testing.T) {
 	))
 }
 
+// TestMetrics_PullRIBConvergence verifies that the convergence gauge is
+// reported only for RIBs fed by BIRD and follows the end-of-RIB marker.
+func TestMetrics_PullRIBConvergence(t *testing.T) {
+	store := newRIBStore(zap.NewNop())
+	store.GetOrCreate("static0")
+	ribRef := store.GetOrCreate("route0")
+	sessionID, _ := ribRef.NewSession()
+
+	m := NewMetrics(store, neigh.NewNeighTable())
+
+	labels := map[string]string{"module": "route0", "source": "bird"}
+	converged := findMetric(m.Collect(), "route_operator_rib_converged", labels)
+	require.NotNil(t, converged)
+	require.Equal(t, 0.0, converged.GetGauge())
+
+	require.True(t, ribRef.MarkEndOfRIB(sessionID))
+	converged = findMetric(m.Collect(), "route_operator_rib_converged", labels)
+	require.NotNil(t, converged)
+	require.Equal(t, 1.0, converged.GetGauge())
+
+	require.Nil(t, findMetric(
+		m.Collect(),
+		"route_operator_rib_converged",
+		map[string]string{"module": "static0", "source": "bird"},
+	))
+}
+
 // TestMetrics_PullNeighbourStats verifies that neighbour gauges are pulled
 // from the live NeighTable's sources and merged view at Collect time.
 func TestMetrics_PullNeighbourStats(t *testing.T) {
@@ -247,18 +279,20 @@ func TestMetrics_PullNeighbourStats(t *testing.T) {
 	require.Equal(t, 2.0, nexthops.GetGauge())
 }
 
-// TestGatewayMetrics_FIBBuilt verifies that OnFIBBuilt renders the five FIB
+// TestGatewayMetrics_FIBBuilt verifies that OnFIBBuilt renders the FIB
 // gauges from FIBBuildStats, keyed by gateway and module.
 func TestGatewayMetrics_FIBBuilt(t *testing.T) {
 	m := NewMetrics(newRIBStore(zap.NewNop()), neigh.NewNeighTable())
 
 	gateway := m.Gateway("gw0")
 	gateway.OnFIBBuilt("route0", FIBBuildStats{
-		TotalRoutes:       4,
-		SkippedPrefixes:   1,
-		NeighbourNotFound: 2,
-		PrefixesAdded:     3,
-		FilteredRoutes:    5,
+		TotalRoutes:        4,
+		SkippedPrefixes:    1,
+		NeighbourNotFound:  2,
+		PrefixesAdded:      3,
+		FilteredRoutes:     5,
+		Blackholes:         6,
+		RejectedBlackholes: 7,
 	})
 
 	metricList := m.Collect()
@@ -283,6 +317,14 @@ func TestGatewayMetrics_FIBBuilt(t *testing.T) {
 	filtered := findMetric(metricList, "route_operator_fib_filtered_routes", labels)
 	require.NotNil(t, filtered)
 	require.Equal(t, 5.0, filtered.GetGauge())
+
+	blackholes := findMetric(metricList, "route_operator_fib_blackholes", labels)
+	require.NotNil(t, blackholes)
+	require.Equal(t, 6.0, blackholes.GetGauge())
+
+	rejected := findMetric(metricList, "route_operator_fib_rejected_blackholes", labels)
+	require.NotNil(t, rejected)
+	require.Equal(t, 7.0, rejected.GetGauge())
 }
 
 // TestGatewayMetrics_ObserveApply verifies that ObserveApply updates the
@@ -0,0 +1,189 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// defaultMirrorQueueSize is the default number of updates buffered per
+// mirrored FeedRIB session.
+const defaultMirrorQueueSize = 16384
+
+// FeedMirror copies FeedRIB sessions to a secondary RouteService.
+//
+// Mirroring is best-effort: every primary session gets its own stream to
+// the secondary, fed through a bounded queue, and updates that do not fit
+// or cannot be sent are dropped without affecting the primary session.
+//
+// A nil FeedMirror mirrors nothing.
+type FeedMirror struct {
+	conn      io.Closer
+	client    operatorpb.RouteServiceClient
+	queueSize int
+	heartbeat operatorpb.Heartbeat
+	onDropped func(n int)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log *zap.Logger
+}
+
+// NewFeedMirror creates a FeedMirror to the configured endpoint.
+//
+// The connection is established lazily, so an unreachable secondary does
+// not prevent the operator from starting.
+func NewFeedMirror(cfg MirrorConfig, onDropped func(n int), log *zap.Logger) (*FeedMirror, error) {
+	creds, err := cfg.TLS.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mirror TLS: %w", err)
+	}
+	conn, err := grpc.NewClient(
+		cfg.Endpoint,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(cfg.Compression.CallOptions()...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial mirror at %q: %w", cfg.Endpoint, err)
+	}
+
+	log = log.With(zap.String("mirror", cfg.Endpoint))
+
+	return newFeedMirror(conn, operatorpb.NewRouteServiceClient(conn), cfg.QueueSize, cfg.Heartbeat, onDropped, log), nil
+}
+
+func newFeedMirror(
+	conn io.Closer,
+	client operatorpb.RouteServiceClient,
+	queueSize int,
+	heartbeat operatorpb.Heartbeat,
+	onDropped func(n int),
+	log *zap.Logger,
+) *FeedMirror {
+	if queueSize <= 0 {
+		queueSize = defaultMirrorQueueSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &FeedMirror{
+		conn:      conn,
+		client:    client,
+		queueSize: queueSize,
+		heartbeat: heartbeat,
+		onDropped: onDropped,
+		ctx:       ctx,
+		cancel:    cancel,
+		log:       log,
+	}
+}
+
+// Start opens a mirrored session for the named RIB.
+func (m *FeedMirror) Start(name string, sessionID uint64) *MirrorSession {
+	if m == nil {
+		return nil
+	}
+
+	session := &MirrorSession{
+		updates: make(chan *operatorpb.Update, m.queueSize),
+		done:    make(chan struct{}),
+		mirror:  m,
+	}
+	go session.run(name, sessionID)
+
+	return session
+}
+
+// Close aborts all mirrored sessions and closes the connection.
+func (m *FeedMirror) Close() error {
+	if m == nil {
+		return nil
+	}
+
+	m.cancel()
+	return m.conn.Close()
+}
+
+// MirrorSession is a single FeedRIB session copied to the secondary.
+//
+// A nil MirrorSession discards all updates.
+type MirrorSession struct {
+	updates chan *operatorpb.Update
+	done    chan struct{}
+	mirror  *FeedMirror
+}
+
+// Send queues the update for mirroring without blocking.
+//
+// The update must not be modified afterwards.
+func (m *MirrorSession) Send(update *operatorpb.Update) {
+	if m == nil {
+		return
+	}
+
+	select {
+	case m.updates <- update:
+	default:
+		m.mirror.onDropped(1)
+	}
+}
+
+// Close finishes the mirrored session once the queued updates are sent.
+//
+// It does not wait for the secondary, use Done for that.
+func (m *MirrorSession) Close() {
+	if m == nil {
+		return
+	}
+
+	close(m.updates)
+}
+
+// Done is closed once the mirrored session is finished.
+func (m *MirrorSession) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *MirrorSession) run(name string, sessionID uint64) {
+	defer close(m.done)
+
+	log := m.mirror.log.With(
+		zap.String("name", name),
+		zap.Uint64("session_id", sessionID),
+	)
+
+	stream, err := operatorpb.OpenFeedRIB(m.mirror.ctx, m.mirror.client, name, m.mirror.heartbeat)
+	if err != nil {
+		log.Warn("failed to open mirrored FeedRIB session", zap.Error(err))
+		m.discard()
+		return
+	}
+
+	for update := range m.updates {
+		if err := stream.Send(update); err != nil {
+			log.Warn("failed to mirror FeedRIB update", zap.Error(err))
+			m.mirror.onDropped(1)
+			m.discard()
+			return
+		}
+	}
+
+	if _, err := stream.CloseAndRecv(); err != nil {
+		log.Warn("mirrored FeedRIB session failed", zap.Error(err))
+		return
+	}
+	log.Debug("finished mirrored FeedRIB session")
+}
+
+// discard drops the remaining updates of a broken session.
+func (m *MirrorSession) discard() {
+	for range m.updates {
+		m.mirror.onDropped(1)
+	}
+}
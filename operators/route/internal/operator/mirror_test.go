@@ -0,0 +1,132 @@
+package operator
+
+import (
+	"context"
+	"io"
+	"net/netip"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+func newMirrorTestUpdates() []*operatorpb.Update {
+	route := &operatorpb.Route{
+		Prefix:  "10.0.0.0/24",
+		NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+		Peer:    commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+		Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+	}
+
+	return []*operatorpb.Update{
+		{Name: "route0", Route: route},
+		{Name: "route0"},
+	}
+}
+
+// fakeFeedRIBClient records the updates of mirrored FeedRIB sessions.
+type fakeFeedRIBClient struct {
+	operatorpb.RouteServiceClient
+
+	err      error
+	sessions chan []*operatorpb.Update
+}
+
+func (m *fakeFeedRIBClient) FeedRIB(
+	ctx context.Context,
+	opts ...grpc.CallOption,
+) (grpc.BidiStreamingClient[operatorpb.Update, operatorpb.UpdateSummary], error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &fakeFeedRIBClientStream{
+		sessions: m.sessions,
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+type fakeFeedRIBClientStream struct {
+	grpc.ClientStream
+
+	updates  []*operatorpb.Update
+	sessions chan []*operatorpb.Update
+	closed   chan struct{}
+}
+
+func (m *fakeFeedRIBClientStream) Send(update *operatorpb.Update) error {
+	m.updates = append(m.updates, update)
+	return nil
+}
+
+func (m *fakeFeedRIBClientStream) CloseSend() error {
+	m.sessions <- m.updates
+	close(m.closed)
+	return nil
+}
+
+// Recv ends the stream once the sending side is closed, as the operator
+// does.
+func (m *fakeFeedRIBClientStream) Recv() (*operatorpb.UpdateSummary, error) {
+	<-m.closed
+	return nil, io.EOF
+}
+
+func TestFeedMirror(t *testing.T) {
+	client := &fakeFeedRIBClient{sessions: make(chan []*operatorpb.Update, 1)}
+	dropped := atomic.Int64{}
+	mirror := newFeedMirror(io.NopCloser(nil), client, 0, operatorpb.Heartbeat{}, func(n int) { dropped.Add(int64(n)) }, zap.NewNop())
+	defer mirror.Close()
+
+	primary := NewRouteService(neigh.NewNeighTable(), WithRouteServiceMirror(mirror))
+	defer primary.Close()
+
+	updates := newMirrorTestUpdates()
+	require.NoError(t, primary.FeedRIB(&fakeFeedRIBStream{updates: slices.Clone(updates)}))
+
+	select {
+	case mirrored := <-client.sessions:
+		require.Equal(t, updates, mirrored)
+	case <-time.After(5 * time.Second):
+		t.Fatal("mirrored session was not finished")
+	}
+	require.Zero(t, dropped.Load())
+}
+
+func TestFeedMirror_Unavailable(t *testing.T) {
+	client := &fakeFeedRIBClient{err: status.Error(codes.Unavailable, "connection refused")}
+	dropped := atomic.Int64{}
+	mirror := newFeedMirror(io.NopCloser(nil), client, 1, operatorpb.Heartbeat{}, func(n int) { dropped.Add(int64(n)) }, zap.NewNop())
+	defer mirror.Close()
+
+	// A broken mirror never affects the primary session.
+	primary := NewRouteService(neigh.NewNeighTable(), WithRouteServiceMirror(mirror))
+	defer primary.Close()
+	require.NoError(t, primary.FeedRIB(&fakeFeedRIBStream{updates: newMirrorTestUpdates()}))
+
+	resp, err := primary.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRoutes(), 1)
+
+	require.Eventually(t, func() bool {
+		return dropped.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFeedMirror_Nil(t *testing.T) {
+	var mirror *FeedMirror
+
+	session := mirror.Start("route0", 1)
+	session.Send(&operatorpb.Update{Name: "route0"})
+	session.Close()
+	require.NoError(t, mirror.Close())
+}
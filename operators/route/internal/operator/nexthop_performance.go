@@ -0,0 +1,208 @@
+package operator
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// NexthopMeasurement is the latest performance measurement of a nexthop
+// reported by an external probe.
+type NexthopMeasurement struct {
+	// Latency is the measured round-trip time.
+	Latency time.Duration
+	// Loss is the fraction of lost probes, within [0, 1].
+	Loss float64
+	// Source names the probe that reported the measurement.
+	Source string
+	// ReportedAt is the time the measurement was received.
+	ReportedAt time.Time
+}
+
+// NexthopPerformanceOption configures the NexthopPerformance constructor.
+type NexthopPerformanceOption func(*nexthopPerformanceOptions)
+
+type nexthopPerformanceOptions struct {
+	OnChanged func()
+	Now       func() time.Time
+	Log       *zap.Logger
+}
+
+func newNexthopPerformanceOptions() *nexthopPerformanceOptions {
+	return &nexthopPerformanceOptions{
+		OnChanged: func() {},
+		Now:       time.Now,
+		Log:       zap.NewNop(),
+	}
+}
+
+// WithNexthopPerformanceOnChanged sets the callback invoked whenever a
+// nexthop turns degraded or healthy again.
+func WithNexthopPerformanceOnChanged(fn func()) NexthopPerformanceOption {
+	return func(o *nexthopPerformanceOptions) {
+		o.OnChanged = fn
+	}
+}
+
+// WithNexthopPerformanceClock overrides the clock used to timestamp and
+// expire measurements.
+func WithNexthopPerformanceClock(now func() time.Time) NexthopPerformanceOption {
+	return func(o *nexthopPerformanceOptions) {
+		o.Now = now
+	}
+}
+
+// WithNexthopPerformanceLog sets the logger.
+func WithNexthopPerformanceLog(log *zap.Logger) NexthopPerformanceOption {
+	return func(o *nexthopPerformanceOptions) {
+		o.Log = log
+	}
+}
+
+// NexthopPerformance keeps the nexthop measurements fed by external probes
+// and classifies each measured nexthop as healthy or degraded against the
+// configured thresholds.
+//
+// The dataplane balances over the ECMP nexthops of a prefix with equal
+// weights, so the measurements bias the selection by excluding degraded
+// nexthops from the FIB, see BuildFIB. Only a change of the classification
+// wakes the reconcile loop: a probe reporting every second costs no FIB
+// rebuilds while its nexthops stay on the same side of the thresholds.
+type NexthopPerformance struct {
+	cfg PerformanceConfig
+
+	mu           sync.Mutex
+	measurements map[netip.Addr]NexthopMeasurement
+	degraded     map[netip.Addr]struct{}
+
+	onChanged func()
+	now       func() time.Time
+	log       *zap.Logger
+}
+
+// NewNexthopPerformance creates an empty NexthopPerformance.
+func NewNexthopPerformance(cfg PerformanceConfig, options ...NexthopPerformanceOption) *NexthopPerformance {
+	opts := newNexthopPerformanceOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &NexthopPerformance{
+		cfg:          cfg,
+		measurements: map[netip.Addr]NexthopMeasurement{},
+		degraded:     map[netip.Addr]struct{}{},
+		onChanged:    opts.OnChanged,
+		now:          opts.Now,
+		log:          opts.Log,
+	}
+}
+
+// isDegraded reports whether the measurement exceeds any of the
+// thresholds.
+func (m *NexthopPerformance) isDegraded(measurement NexthopMeasurement) bool {
+	if m.cfg.MaxLatency > 0 && measurement.Latency > m.cfg.MaxLatency {
+		return true
+	}
+	return measurement.Loss > m.cfg.MaxLoss
+}
+
+// Report records the measurements, replacing the previous ones of the
+// same nexthops.
+func (m *NexthopPerformance) Report(measurements map[netip.Addr]NexthopMeasurement) {
+	now := m.now()
+	changed := false
+
+	m.mu.Lock()
+	for addr, measurement := range measurements {
+		addr = addr.Unmap()
+		measurement.ReportedAt = now
+		m.measurements[addr] = measurement
+
+		_, was := m.degraded[addr]
+		if is := m.isDegraded(measurement); is != was {
+			if is {
+				m.degraded[addr] = struct{}{}
+			} else {
+				delete(m.degraded, addr)
+			}
+			m.log.Info("nexthop performance changed",
+				zap.Stringer("nexthop", addr),
+				zap.Bool("degraded", is),
+				zap.Duration("latency", measurement.Latency),
+				zap.Float64("loss", measurement.Loss),
+				zap.String("source", measurement.Source),
+			)
+			changed = true
+		}
+	}
+	m.mu.Unlock()
+
+	if changed && m.cfg.Enabled {
+		m.onChanged()
+	}
+}
+
+// Expire drops the measurements not refreshed within the TTL, so a
+// nexthop whose probe stopped reporting is no longer avoided.
+func (m *NexthopPerformance) Expire() {
+	now := m.now()
+	changed := false
+
+	m.mu.Lock()
+	for addr, measurement := range m.measurements {
+		if now.Sub(measurement.ReportedAt) <= m.cfg.TTL {
+			continue
+		}
+		delete(m.measurements, addr)
+		if _, ok := m.degraded[addr]; ok {
+			delete(m.degraded, addr)
+			m.log.Info("expired degraded nexthop measurement", zap.Stringer("nexthop", addr))
+			changed = true
+		}
+	}
+	m.mu.Unlock()
+
+	if changed && m.cfg.Enabled {
+		m.onChanged()
+	}
+}
+
+// Measurements returns a copy of the current measurements.
+func (m *NexthopPerformance) Measurements() map[netip.Addr]NexthopMeasurement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.measurements)
+}
+
+// Degraded returns the nexthops the FIB should avoid, nil unless
+// performance-based selection is enabled.
+func (m *NexthopPerformance) Degraded() map[netip.Addr]struct{} {
+	if !m.cfg.Enabled {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.degraded)
+}
+
+// Run expires stale measurements until the context is cancelled.
+func (m *NexthopPerformance) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.TTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.Expire()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package operator
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// TestNexthopPerformance verifies that only classification changes wake the
+// FIB rebuild and that stale measurements expire.
+func TestNexthopPerformance(t *testing.T) {
+	now := time.Unix(1000, 0)
+	changes := 0
+	performance := NewNexthopPerformance(
+		PerformanceConfig{
+			Enabled:    true,
+			MaxLatency: 10 * time.Millisecond,
+			MaxLoss:    0.1,
+			TTL:        30 * time.Second,
+		},
+		WithNexthopPerformanceOnChanged(func() { changes++ }),
+		WithNexthopPerformanceClock(func() time.Time { return now }),
+	)
+
+	fast := netip.MustParseAddr("10.0.0.1")
+	slow := netip.MustParseAddr("10.0.0.2")
+
+	performance.Report(map[netip.Addr]NexthopMeasurement{
+		fast: {Latency: time.Millisecond},
+		slow: {Latency: 50 * time.Millisecond},
+	})
+	require.Equal(t, 1, changes)
+	require.Equal(t, map[netip.Addr]struct{}{slow: {}}, performance.Degraded())
+
+	// Still degraded, just by another threshold.
+	performance.Report(map[netip.Addr]NexthopMeasurement{
+		slow: {Latency: time.Millisecond, Loss: 0.5},
+	})
+	require.Equal(t, 1, changes)
+
+	now = now.Add(20 * time.Second)
+	performance.Report(map[netip.Addr]NexthopMeasurement{
+		fast: {Latency: time.Millisecond},
+	})
+	now = now.Add(20 * time.Second)
+	performance.Expire()
+	require.Equal(t, 2, changes)
+	require.Empty(t, performance.Degraded())
+	require.Len(t, performance.Measurements(), 1)
+
+	disabled := NewNexthopPerformance(PerformanceConfig{MaxLoss: 0.1, TTL: time.Second})
+	disabled.Report(map[netip.Addr]NexthopMeasurement{slow: {Loss: 1}})
+	require.Nil(t, disabled.Degraded())
+}
+
+func TestNexthopService(t *testing.T) {
+	performance := NewNexthopPerformance(PerformanceConfig{Enabled: true, MaxLoss: 0.1, TTL: time.Second})
+	svc := NewNexthopService(performance)
+
+	_, err := svc.ReportMeasurements(t.Context(), &operatorpb.ReportMeasurementsRequest{
+		Measurements: []*operatorpb.NexthopMeasurement{
+			{NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("10.0.0.2")), Loss: 0.5, Source: "probe"},
+			{NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("10.0.0.1")), LatencyNs: 1000},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := svc.ListMeasurements(t.Context(), &operatorpb.ListMeasurementsRequest{})
+	require.NoError(t, err)
+	require.True(t, resp.GetEnabled())
+	require.Len(t, resp.GetMeasurements(), 2)
+	require.False(t, resp.GetMeasurements()[0].GetDegraded())
+	require.Equal(t, uint64(1000), resp.GetMeasurements()[0].GetLatencyNs())
+	require.True(t, resp.GetMeasurements()[1].GetDegraded())
+	require.Equal(t, "probe", resp.GetMeasurements()[1].GetSource())
+
+	for _, measurement := range []*operatorpb.NexthopMeasurement{
+		{},
+		{NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("10.0.0.3")), Loss: 1.5},
+	} {
+		_, err := svc.ReportMeasurements(t.Context(), &operatorpb.ReportMeasurementsRequest{
+			Measurements: []*operatorpb.NexthopMeasurement{measurement},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	require.Len(t, performance.Measurements(), 2)
+}
@@ -23,6 +23,7 @@ import (
 type ribReadiness interface {
 	OnSessionStart(name string, sessionID uint64)
 	OnUpdate(n int)
+	OnEndOfRIB(name string, sessionID uint64)
 	OnSessionEnd(name string, sessionID uint64)
 	Run(ctx context.Context) error
 }
@@ -43,6 +44,7 @@ type Operator struct {
 	cfg        *Config
 	app        *operator.Operator[RouteSnapshot]
 	routeSvc   *RouteService
+	mirror     *FeedMirror
 	neighTable *neigh.NeighTable
 	source     *RouteSource
 	log        *zap.Logger
@@ -126,16 +128,78 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		neighMonitor = monitor
 	}
 
-	source := NewRouteSource(neighTable, routeRIBStore)
-	wake := source.WakeFunc()
+	// The measurements wake the reconcile loop of the source they feed. A
+	// degraded nexthop is a failure to converge on, so it bypasses the
+	// commit rate limit.
+	var limiter *CommitLimiter
+	performance := NewNexthopPerformance(
+		cfg.Performance,
+		WithNexthopPerformanceOnChanged(func() { limiter.WakeNow() }),
+		WithNexthopPerformanceLog(log),
+	)
+	source := NewRouteSource(neighTable, routeRIBStore, WithRouteSourceNexthopPerformance(performance))
+	limiter = NewCommitLimiter(cfg.Flush, source.WakeFunc(), metrics.OnCommitDeferred, log)
+	wake := limiter.WakeNow
 	ribHelper := newRIBReadiness(cfg.Readiness, routeRIBStore, moduleName, tracker, log)
+	flush := NewFlushScheduler(cfg.Flush, limiter.Wake, metrics.OnFlushAdjusted, metrics.OnFlushDeadlineViolated, log)
+
+	// Faults stay nil, and therefore never fire, unless the operator is
+	// built with the chaos tag.
+	var faults *FaultInjector
+	if faultInjectionEnabled {
+		faults = NewFaultInjector(log)
+	}
+
+	originCommunity, err := cfg.LoopProtection.Community()
+	if err != nil {
+		return nil, err
+	}
+
+	strictNexthop, err := NewStrictNexthop(cfg.StrictNexthop)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixACL, err := NewPrefixACL(cfg.PrefixACL, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prefix ACL: %w", err)
+	}
+	tenants, err := NewTenants(cfg.Tenants, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenants: %w", err)
+	}
+
+	var mirror *FeedMirror
+	if cfg.Mirror.Endpoint != "" {
+		m, err := NewFeedMirror(cfg.Mirror, metrics.OnRIBMirrorDropped, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create FeedRIB mirror: %w", err)
+		}
+		mirror = m
+	}
+
+	// The gateways are added to the tracker as their actuators are built.
+	commits := NewCommitTracker()
 
 	routeSvc := NewRouteService(
 		neighTable,
 		WithRouteServiceRIBStore(routeRIBStore),
 		WithRouteServiceRIBTTL(ribTTL(cfg)),
-		WithRouteServiceOnChanged(wake),
+		WithRouteServiceOnChanged(flush.Flush),
+		WithRouteServiceOnAccepted(flush.Accept),
+		WithRouteServiceOnEmergency(wake),
 		WithRouteServiceLog(log),
+		WithRouteServiceFaults(faults),
+		WithRouteServiceCommits(commits),
+		WithRouteServiceMirror(mirror),
+		WithRouteServiceOriginCommunity(originCommunity),
+		WithRouteServiceStrictNexthop(strictNexthop),
+		WithRouteServiceCompression(cfg.Compression),
+		WithRouteServicePrefixACL(prefixACL),
+		WithRouteServiceTenants(tenants),
+		WithRouteServiceMassWithdraw(cfg.MassWithdraw),
+		WithRouteServiceFeedHeartbeat(cfg.FeedHeartbeat),
+		WithRouteServiceSnapshotDir(cfg.Snapshots.Dir),
 		WithRouteServiceOnRIBSessionStart(func(name string, sessionID uint64) {
 			ribHelper.OnSessionStart(name, sessionID)
 			metrics.OnRIBSessionStart(name, sessionID)
@@ -143,6 +207,13 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		WithRouteServiceOnRIBUpdate(func(n int) {
 			ribHelper.OnUpdate(n)
 			metrics.OnRIBUpdate(n)
+			flush.OnUpdate(n)
+		}),
+		WithRouteServiceOnRIBDuplicate(metrics.OnRIBDuplicate),
+		WithRouteServiceOnRIBLooped(metrics.OnRIBLooped),
+		WithRouteServiceOnRIBNexthopRejected(metrics.OnRIBNexthopRejected),
+		WithRouteServiceOnRIBEndOfRIB(func(name string, sessionID uint64) {
+			ribHelper.OnEndOfRIB(name, sessionID)
 		}),
 		WithRouteServiceOnRIBSessionEnd(func(name string, sessionID uint64) {
 			ribHelper.OnSessionEnd(name, sessionID)
@@ -150,6 +221,55 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		}),
 	)
 
+	var routesFile *StaticRoutesFile
+	if cfg.Static.RoutesFile != "" {
+		// Routes of the main config stay installed whatever the file lists.
+		pinned := make([]StaticRoute, 0, len(cfg.Static.Routes))
+		for _, r := range cfg.Static.Routes {
+			route, err := parseStaticRoute(r.Prefix, r.NexthopAddr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse static route: %w", err)
+			}
+			pinned = append(pinned, route)
+		}
+
+		routesFile = NewStaticRoutesFile(
+			cfg.Static.RoutesFile,
+			moduleName,
+			routeSvc,
+			WithStaticRoutesFileInterval(cfg.Static.RoutesFileInterval),
+			WithStaticRoutesFilePinned(pinned),
+			WithStaticRoutesFileOnChanged(func() {
+				flush.Accept()
+				flush.Flush()
+			}),
+			WithStaticRoutesFileLog(log),
+		)
+	}
+
+	tagger := opts.PrefixTagger
+	if tagger == nil && cfg.Enrichment.TagsFile != "" {
+		fileTagger, err := LoadFilePrefixTagger(cfg.Enrichment.TagsFile)
+		if err != nil {
+			return nil, err
+		}
+		tagger = fileTagger
+	}
+	var enricher *PrefixEnricher
+	if tagger != nil {
+		enricher = NewPrefixEnricher(
+			tagger,
+			routeRIBStore,
+			WithPrefixEnricherInterval(cfg.Enrichment.Interval),
+			WithPrefixEnricherLog(log),
+		)
+	}
+
+	bmpExporter, err := NewBMPExporter(cfg.BMP, routeRIBStore, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BMP exporter: %w", err)
+	}
+
 	neighbourSvc := NewNeighbourService(
 		neighTable,
 		WithNeighbourServiceOnChanged(wake),
@@ -157,9 +277,9 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 	metricsSvc := NewMetricsService(
 		WithMetricsServiceCollector(metrics),
 	)
-	operatorSvc := NewRouteOperatorService()
 
 	actuators := make([]Actuator, 0, len(cfg.Gateways))
+	resyncers := make([]FIBResyncer, 0, len(cfg.Gateways))
 	for _, gw := range cfg.Gateways {
 		gatewayMetrics := metrics.Gateway(gw.Name)
 
@@ -168,7 +288,10 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 			WithGatewayActuatorLog(log),
 			WithGatewayActuatorFunction(cfg.Function),
 			WithGatewayActuatorDevices(cfg.GatewayDevices[gw.Name]),
+			WithGatewayActuatorMaxBlackholes(cfg.Blackhole.MaxCount),
+			WithGatewayActuatorDelta(cfg.Flush.DeltaTransfer),
 			WithGatewayActuatorOnFIBBuilt(gatewayMetrics.OnFIBBuilt),
+			WithGatewayActuatorFaults(faults),
 		)
 		if err != nil {
 			for _, a := range actuators {
@@ -182,14 +305,29 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		metered := newMeteredActuator(actuator, gatewayMetrics)
 		observed := operator.NewObservedActuator(metered, fmt.Sprintf("fib:%s:%s", gw.Name, moduleName), tracker.Observe)
 		actuators = append(actuators, observed)
+		resyncers = append(resyncers, actuator)
+		commits.AddGateway(actuator)
 	}
 
+	// Resyncs bypass the apply metrics and readiness wrappers; the reconcile
+	// pass woken afterwards refreshes both.
+	operatorSvc := NewRouteOperatorService(
+		WithOperatorServiceSnapshot(source.Dump),
+		WithOperatorServiceGateways(resyncers...),
+		WithOperatorServiceOnResynced(wake),
+		WithOperatorServiceLog(log),
+	)
+
 	fanOut := operator.NewFanOutActuator(
 		actuators,
 		operator.WithFanOutLog(log),
 	)
+	// The whole fan-out is timed, as a reconcile pass commits only once
+	// every gateway has applied the FIB.
+	committed := newFlushObservedActuator(newCommitTrackedActuator(fanOut, commits), flush)
 
 	readinessSvc := NewReadinessService(tracker)
+	nexthopSvc := NewNexthopService(performance)
 
 	services := []operator.ServiceRegistrar{
 		func(s *grpc.Server) string {
@@ -212,6 +350,18 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 			operatorpb.RegisterReadinessServiceServer(s, readinessSvc)
 			return operatorpb.ReadinessService_ServiceDesc.ServiceName
 		},
+		func(s *grpc.Server) string {
+			operatorpb.RegisterNexthopServiceServer(s, nexthopSvc)
+			return operatorpb.NexthopService_ServiceDesc.ServiceName
+		},
+	}
+
+	if faults != nil {
+		faultSvc := NewFaultService(faults)
+		services = append(services, func(s *grpc.Server) string {
+			operatorpb.RegisterFaultServiceServer(s, faultSvc)
+			return operatorpb.FaultService_ServiceDesc.ServiceName
+		})
 	}
 
 	workers := []operator.Runner{
@@ -221,27 +371,58 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 			return nil
 		},
 		ribHelper.Run,
+		performance.Run,
 	}
 	if !cfg.NetlinkMonitor.Disabled {
 		workers = append(workers, neighMonitor.Run)
 	}
+	if flush.Enabled() {
+		workers = append(workers, flush.Run)
+	}
+	if flush.DeadlineEnabled() {
+		workers = append(workers, flush.RunDeadline)
+	}
+	if limiter.Enabled() {
+		workers = append(workers, limiter.Run)
+	}
+	if routesFile != nil {
+		workers = append(workers, routesFile.Run)
+	}
+	if enricher != nil {
+		workers = append(workers, enricher.Run)
+	}
+	if bmpExporter != nil {
+		workers = append(workers, bmpExporter.Run)
+	}
 
-	app := operator.NewOperator(
-		fanOut,
-		source,
+	app, err := operator.NewOperator(
+		committed,
+		newCommitTrackedSource(newFlushTrackedSource(source, flush), commits),
 		operator.WithGRPCServer(cfg.Server, services...),
 		operator.WithLog(log),
 		operator.WithReconcile(cfg.Reconcile),
 		operator.WithGateways(cfg.Register, cfg.Gateways...),
 		operator.WithMetrics(metrics),
 		operator.WithPreRun(func(ctx context.Context) error {
+			// Snapshots are restored first, so that they are refused by no
+			// BIRD session yet.
+			restored := cfg.Snapshots.Restore && routeSvc.restoreSnapshots()
 			if err := applyStaticSeed(cfg, routeSvc, neighTable); err != nil {
 				return err
 			}
 
+			seeded := restored || len(cfg.Static.Routes) > 0 || len(cfg.Static.Neighbours) > 0
+			if routesFile != nil {
+				changed, err := routesFile.Reload()
+				if err != nil {
+					return err
+				}
+				seeded = seeded || changed
+			}
+
 			// The route source is woken so the first reconcile pass observes
 			// the seeded state.
-			if len(cfg.Static.Routes) > 0 || len(cfg.Static.Neighbours) > 0 {
+			if seeded {
 				source.WakeFunc()()
 			}
 
@@ -249,11 +430,15 @@ func NewOperator(cfg *Config, options ...Option) (*Operator, error) {
 		}),
 		operator.WithWorkers(workers...),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operator: %w", err)
+	}
 
 	return &Operator{
 		cfg:        cfg,
 		app:        app,
 		routeSvc:   routeSvc,
+		mirror:     mirror,
 		neighTable: neighTable,
 		source:     source,
 		log:        log,
@@ -303,6 +488,9 @@ func (m *Operator) Close() error {
 	if err := m.routeSvc.Close(); err != nil {
 		m.log.Warn("failed to close route service", zap.Error(err))
 	}
+	if err := m.mirror.Close(); err != nil {
+		m.log.Warn("failed to close FeedRIB mirror", zap.Error(err))
+	}
 	return m.app.Close()
 }
 
@@ -318,20 +506,25 @@ func applyStaticSeed(
 	neighTable *neigh.NeighTable,
 ) error {
 	module := cfg.Function.Module.Unwrap()
-	for _, route := range cfg.Static.Routes {
-		prefix, err := netip.ParsePrefix(route.Prefix)
+	routes := make([]StaticRoute, 0, len(cfg.Static.Routes))
+	for idx, r := range cfg.Static.Routes {
+		route, err := parseStaticRoute(r.Prefix, r.NexthopAddr)
 		if err != nil {
-			return fmt.Errorf("failed to parse static route prefix %q: %w", route.Prefix, err)
-		}
-
-		nexthop, err := netip.ParseAddr(route.NexthopAddr)
-		if err != nil {
-			return fmt.Errorf("failed to parse static route nexthop %q: %w", route.NexthopAddr, err)
+			return fmt.Errorf("invalid static route routes[%d]: %w", idx, err)
 		}
+		routes = append(routes, route)
+	}
+	if err := checkStaticRouteDevices(routes); err != nil {
+		return fmt.Errorf("invalid static routes: %w", err)
+	}
+	if err := checkStaticNeighbourDevices(routes, cfg.Static.Neighbours); err != nil {
+		return err
+	}
 
+	for _, route := range routes {
 		holder := routeSvc.getOrCreateRib(module)
-		if err := holder.AddUnicastRoute(prefix, nexthop, rib.RouteSourceStatic); err != nil {
-			return fmt.Errorf("failed to seed static route %s via %s: %w", prefix, nexthop, err)
+		if err := holder.AddUnicastRoute(route.Prefix, route.NexthopAddr, rib.RouteSourceStatic, routeSvc.localCommunities()...); err != nil {
+			return fmt.Errorf("failed to seed static route %s via %s: %w", route.Prefix, route.NexthopAddr, err)
 		}
 	}
 
@@ -381,6 +574,35 @@ func applyStaticSeed(
 	return nil
 }
 
+// checkStaticNeighbourDevices checks that the static neighbours of the
+// link-local next-hops the routes name an interface of are seeded on that
+// interface.
+func checkStaticNeighbourDevices(routes []StaticRoute, neighbours []StaticNeighbourConfig) error {
+	devices := map[netip.Addr]string{}
+	for _, route := range routes {
+		if route.Device != "" {
+			devices[route.NexthopAddr] = route.Device
+		}
+	}
+
+	for _, n := range neighbours {
+		addr, err := netip.ParseAddr(n.NextHop)
+		if err != nil {
+			// Reported when the neighbours are seeded.
+			continue
+		}
+		device, ok := devices[addr.Unmap()]
+		if ok && n.Device != device {
+			return fmt.Errorf(
+				"static routes reach nexthop %s via interface %q, but its static neighbour is on device %q",
+				addr, device, n.Device,
+			)
+		}
+	}
+
+	return nil
+}
+
 // parseMAC parses an EUI-48 MAC address into a 6-byte array.
 func parseMAC(s string) ([6]byte, error) {
 	hw, err := net.ParseMAC(s)
@@ -6,11 +6,15 @@ import (
 	"go.uber.org/zap"
 
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
 )
 
 type options struct {
-	Log     *zap.Logger
-	Metrics MetricsFactory
+	Log          *zap.Logger
+	Metrics      MetricsFactory
+	PrefixTagger PrefixTagger
 }
 
 func newOptions() *options {
@@ -41,25 +45,64 @@ func WithMetrics(factory MetricsFactory) Option {
 	}
 }
 
+// WithPrefixTagger sets the source tagging RIB prefixes, overriding the
+// tags file of the enrichment config.
+//
+// Use this to plug in an external enrichment service or a GeoIP database.
+func WithPrefixTagger(tagger PrefixTagger) Option {
+	return func(o *options) {
+		o.PrefixTagger = tagger
+	}
+}
+
 type routeServiceOptions struct {
-	RIBs              *RIBStore
-	RIBTTL            time.Duration
-	OnChanged         func()
-	OnRIBSessionStart func(name string, sessionID uint64)
-	OnRIBUpdate       func(n int)
-	OnRIBSessionEnd   func(name string, sessionID uint64)
-	Log               *zap.Logger
+	RIBs                 *RIBStore
+	RIBTTL               time.Duration
+	OnChanged            func()
+	OnAccepted           func()
+	OnEmergency          func()
+	OnRIBSessionStart    func(name string, sessionID uint64)
+	OnRIBUpdate          func(n int)
+	OnRIBDuplicate       func(n int)
+	OnRIBLooped          func(n int)
+	OnRIBNexthopRejected func(name string, n int)
+	OnRIBEndOfRIB        func(name string, sessionID uint64)
+	OnRIBSessionEnd      func(name string, sessionID uint64)
+	OnMonitorSubscribed  func(name string)
+	Faults               *FaultInjector
+	Commits              *CommitTracker
+	Mirror               *FeedMirror
+	OriginCommunity      *rib.LargeCommunity
+	StrictNexthop        *StrictNexthop
+	Compression          grpccompress.Compression
+	PrefixACL            *PrefixACL
+	Tenants              *Tenants
+	MassWithdraw         MassWithdrawConfig
+	FeedHeartbeat        operatorpb.Heartbeat
+	SnapshotDir          string
+	Log                  *zap.Logger
 }
 
 func newRouteServiceOptions() *routeServiceOptions {
 	return &routeServiceOptions{
-		RIBs:              newRIBStore(zap.NewNop()),
-		RIBTTL:            DefaultRIBTTL,
-		OnChanged:         func() {},
-		OnRIBSessionStart: func(string, uint64) {},
-		OnRIBUpdate:       func(int) {},
-		OnRIBSessionEnd:   func(string, uint64) {},
-		Log:               zap.NewNop(),
+		RIBs:                 newRIBStore(zap.NewNop()),
+		RIBTTL:               DefaultRIBTTL,
+		OnChanged:            func() {},
+		OnAccepted:           func() {},
+		OnRIBSessionStart:    func(string, uint64) {},
+		OnRIBUpdate:          func(int) {},
+		OnRIBDuplicate:       func(int) {},
+		OnRIBLooped:          func(int) {},
+		OnRIBNexthopRejected: func(string, int) {},
+		OnRIBEndOfRIB:        func(string, uint64) {},
+		OnRIBSessionEnd:      func(string, uint64) {},
+		OnMonitorSubscribed:  func(string) {},
+		MassWithdraw: MassWithdrawConfig{
+			BatchSize: defaultMassWithdrawBatchSize,
+			Threshold: defaultMassWithdrawThreshold,
+		},
+		FeedHeartbeat: operatorpb.DefaultHeartbeat(),
+		Log:           zap.NewNop(),
 	}
 }
 
@@ -88,6 +131,74 @@ func WithRouteServiceOnChanged(fn func()) RouteServiceOption {
 	}
 }
 
+// WithRouteServiceOnEmergency registers a callback fired instead of the
+// OnChanged one for the flushes requested with the emergency flag, which
+// must not wait for batching or rate limits.
+//
+// Without it emergency flushes fire the OnChanged callback.
+func WithRouteServiceOnEmergency(fn func()) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnEmergency = fn
+	}
+}
+
+// WithRouteServiceOnAccepted registers a callback fired whenever the RIB
+// accepts a change, whether or not a flush is requested for it.
+func WithRouteServiceOnAccepted(fn func()) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnAccepted = fn
+	}
+}
+
+// WithRouteServiceCompression sets the compression of the full-table read
+// responses.
+func WithRouteServiceCompression(compression grpccompress.Compression) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Compression = compression
+	}
+}
+
+// WithRouteServicePrefixACL restricts the prefixes API callers may insert
+// or delete routes for.
+func WithRouteServicePrefixACL(acl *PrefixACL) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.PrefixACL = acl
+	}
+}
+
+// WithRouteServiceTenants scopes API callers to the routes of their
+// tenant.
+func WithRouteServiceTenants(tenants *Tenants) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Tenants = tenants
+	}
+}
+
+// WithRouteServiceMassWithdraw sets the batching of FeedRIB withdrawals
+// and the threshold from which a flush event following them is committed
+// as an emergency one.
+func WithRouteServiceMassWithdraw(cfg MassWithdrawConfig) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.MassWithdraw = cfg
+	}
+}
+
+// WithRouteServiceFeedHeartbeat sets the heartbeats of the FeedRIB
+// streams.
+func WithRouteServiceFeedHeartbeat(heartbeat operatorpb.Heartbeat) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.FeedHeartbeat = heartbeat
+	}
+}
+
+// WithRouteServiceSnapshotDir sets the directory of the RIB snapshot files
+// of ExportRIB and ImportRIB, both disabled while it is empty.
+func WithRouteServiceSnapshotDir(dir string) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.SnapshotDir = dir
+	}
+}
+
 // WithRouteServiceLog sets the logger for the RouteService.
 func WithRouteServiceLog(log *zap.Logger) RouteServiceOption {
 	return func(o *routeServiceOptions) {
@@ -116,6 +227,48 @@ func WithRouteServiceOnRIBUpdate(fn func(n int)) RouteServiceOption {
 	}
 }
 
+// WithRouteServiceOnRIBDuplicate registers a callback invoked for FeedRIB
+// updates suppressed because they do not change the stored route.
+//
+// The callback receives the count of suppressed routes.
+func WithRouteServiceOnRIBDuplicate(fn func(n int)) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnRIBDuplicate = fn
+	}
+}
+
+// WithRouteServiceOnRIBLooped registers a callback invoked for FeedRIB
+// updates dropped because they carry the origin community.
+//
+// The callback receives the count of dropped routes.
+func WithRouteServiceOnRIBLooped(fn func(n int)) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnRIBLooped = fn
+	}
+}
+
+// WithRouteServiceOnRIBNexthopRejected registers a callback invoked for
+// FeedRIB updates dropped because their nexthop is outside the strict
+// nexthop networks of their config.
+//
+// The callback receives the config name and the count of dropped routes.
+func WithRouteServiceOnRIBNexthopRejected(fn func(name string, n int)) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnRIBNexthopRejected = fn
+	}
+}
+
+// WithRouteServiceOnRIBEndOfRIB registers a callback invoked when a
+// FeedRIB stream session delivers its end-of-RIB marker.
+//
+// The callback receives the config name and the session id that finished
+// its initial dump. Markers from superseded sessions are not reported.
+func WithRouteServiceOnRIBEndOfRIB(fn func(name string, sessionID uint64)) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnRIBEndOfRIB = fn
+	}
+}
+
 // WithRouteServiceOnRIBSessionEnd registers a callback invoked when a
 // FeedRIB stream session ends for the named config.
 //
@@ -127,6 +280,80 @@ func WithRouteServiceOnRIBSessionEnd(fn func(name string, sessionID uint64)) Rou
 	}
 }
 
+// WithRouteServiceOnMonitorSubscribed registers a callback invoked once a
+// MonitorRoutes stream has subscribed to the changes of the named RIB, so
+// that every change made from then on is streamed.
+func WithRouteServiceOnMonitorSubscribed(fn func(name string)) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OnMonitorSubscribed = fn
+	}
+}
+
+// WithRouteServiceFaults attaches the fault injector consulted by FeedRIB
+// and FlushRoutes.
+func WithRouteServiceFaults(faults *FaultInjector) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Faults = faults
+	}
+}
+
+// WithRouteServiceCommits attaches the tracker FlushRoutes waits on for
+// the reconcile pass committing the flush.
+//
+// Without it FlushRoutes returns once the pass is requested, with no
+// results.
+func WithRouteServiceCommits(commits *CommitTracker) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Commits = commits
+	}
+}
+
+// WithRouteServiceMirror attaches the mirror FeedRIB sessions are copied
+// to.
+func WithRouteServiceMirror(mirror *FeedMirror) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.Mirror = mirror
+	}
+}
+
+// WithRouteServiceOriginCommunity sets the large community tagging routes
+// originated by this node.
+//
+// Static routes are inserted with the community, and FeedRIB routes
+// carrying it are dropped as looped back. A nil community disables both.
+func WithRouteServiceOriginCommunity(community *rib.LargeCommunity) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.OriginCommunity = community
+	}
+}
+
+// WithRouteServiceStrictNexthop sets the allowed nexthop networks of the
+// FeedRIB routes. A nil policy accepts any nexthop.
+func WithRouteServiceStrictNexthop(strict *StrictNexthop) RouteServiceOption {
+	return func(o *routeServiceOptions) {
+		o.StrictNexthop = strict
+	}
+}
+
+type routeSourceOptions struct {
+	Performance *NexthopPerformance
+}
+
+func newRouteSourceOptions() *routeSourceOptions {
+	return &routeSourceOptions{}
+}
+
+// RouteSourceOption configures NewRouteSource.
+type RouteSourceOption func(*routeSourceOptions)
+
+// WithRouteSourceNexthopPerformance sets the nexthop measurements whose
+// degraded nexthops the snapshots carry.
+func WithRouteSourceNexthopPerformance(performance *NexthopPerformance) RouteSourceOption {
+	return func(o *routeSourceOptions) {
+		o.Performance = performance
+	}
+}
+
 type neighbourServiceOptions struct {
 	OnChanged func()
 }
@@ -178,20 +405,63 @@ func WithMetricsServiceCollector(c MetricsCollector) MetricsServiceOption {
 	}
 }
 
-type operatorServiceOptions struct{}
+type operatorServiceOptions struct {
+	Snapshot   func() RouteSnapshot
+	Gateways   []FIBResyncer
+	OnResynced func()
+	Log        *zap.Logger
+}
 
 func newOperatorServiceOptions() *operatorServiceOptions {
-	return &operatorServiceOptions{}
+	return &operatorServiceOptions{
+		Snapshot:   func() RouteSnapshot { return RouteSnapshot{} },
+		OnResynced: func() {},
+		Log:        zap.NewNop(),
+	}
 }
 
 // OperatorServiceOption configures NewRouteOperatorService.
 type OperatorServiceOption func(*operatorServiceOptions)
 
+// WithOperatorServiceSnapshot sets the function returning the state a
+// resync rebuilds the FIBs from.
+func WithOperatorServiceSnapshot(fn func() RouteSnapshot) OperatorServiceOption {
+	return func(o *operatorServiceOptions) {
+		o.Snapshot = fn
+	}
+}
+
+// WithOperatorServiceGateways sets the gateways a resync pushes the FIBs
+// to.
+func WithOperatorServiceGateways(gateways ...FIBResyncer) OperatorServiceOption {
+	return func(o *operatorServiceOptions) {
+		o.Gateways = gateways
+	}
+}
+
+// WithOperatorServiceOnResynced registers a callback invoked after every
+// resync, whether it succeeded or not.
+func WithOperatorServiceOnResynced(fn func()) OperatorServiceOption {
+	return func(o *operatorServiceOptions) {
+		o.OnResynced = fn
+	}
+}
+
+// WithOperatorServiceLog sets the logger for the RouteOperatorService.
+func WithOperatorServiceLog(log *zap.Logger) OperatorServiceOption {
+	return func(o *operatorServiceOptions) {
+		o.Log = log
+	}
+}
+
 type gatewayActuatorOptions struct {
-	Function   FunctionConfig
-	Devices    []string
-	OnFIBBuilt func(module string, stats FIBBuildStats)
-	Log        *zap.Logger
+	Function      FunctionConfig
+	Devices       []string
+	MaxBlackholes int
+	Delta         bool
+	OnFIBBuilt    func(module string, stats FIBBuildStats)
+	Faults        *FaultInjector
+	Log           *zap.Logger
 }
 
 func newGatewayActuatorOptions() *gatewayActuatorOptions {
@@ -230,6 +500,28 @@ func WithGatewayActuatorDevices(devices []string) GatewayActuatorOption {
 	}
 }
 
+// WithGatewayActuatorMaxBlackholes limits the number of prefixes installed
+// as drop routes because of the BLACKHOLE community.
+//
+// Zero, the default, disables automatic blackholing.
+func WithGatewayActuatorMaxBlackholes(n int) GatewayActuatorOption {
+	return func(o *gatewayActuatorOptions) {
+		o.MaxBlackholes = n
+	}
+}
+
+// WithGatewayActuatorDelta makes the actuator push the FIBs as deltas of
+// the prefixes changed since their previous push.
+//
+// By default every push carries the whole FIB. A delta only shrinks the
+// request: the gateway recompiles the module config from the whole merged
+// FIB either way.
+func WithGatewayActuatorDelta(enabled bool) GatewayActuatorOption {
+	return func(o *gatewayActuatorOptions) {
+		o.Delta = enabled
+	}
+}
+
 // WithGatewayActuatorOnFIBBuilt registers a callback invoked with the build
 // statistics of every FIB built during Apply.
 func WithGatewayActuatorOnFIBBuilt(fn func(module string, stats FIBBuildStats)) GatewayActuatorOption {
@@ -237,3 +529,11 @@ func WithGatewayActuatorOnFIBBuilt(fn func(module string, stats FIBBuildStats))
 		o.OnFIBBuilt = fn
 	}
 }
+
+// WithGatewayActuatorFaults attaches the fault injector consulted before
+// every FIB push.
+func WithGatewayActuatorFaults(faults *FaultInjector) GatewayActuatorOption {
+	return func(o *gatewayActuatorOptions) {
+		o.Faults = faults
+	}
+}
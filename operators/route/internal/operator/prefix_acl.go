@@ -0,0 +1,188 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// prefixACLCaller is a caller identified by its token.
+type prefixACLCaller struct {
+	name     string
+	prefixes []netip.Prefix
+}
+
+// PrefixACL authorizes the RouteService mutations of API callers against
+// the prefixes they own.
+//
+// A nil PrefixACL authorizes everything.
+type PrefixACL struct {
+	// callers are keyed by the SHA-256 digest of their token, so that a
+	// lookup never compares the tokens themselves.
+	callers   map[[sha256.Size]byte]*prefixACLCaller
+	anonymous *prefixACLCaller
+	log       *zap.Logger
+}
+
+// NewPrefixACL loads the caller tokens, returning nil if the ACL is
+// disabled.
+func NewPrefixACL(cfg PrefixACLConfig, log *zap.Logger) (*PrefixACL, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	anonymous, err := parseACLPrefixes(cfg.Anonymous)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymous prefix ACL: %w", err)
+	}
+
+	acl := &PrefixACL{
+		callers:   map[[sha256.Size]byte]*prefixACLCaller{},
+		anonymous: &prefixACLCaller{name: "anonymous", prefixes: anonymous},
+		log:       log,
+	}
+
+	names := map[string]struct{}{}
+	for idx, caller := range cfg.Callers {
+		if caller.Name == "" {
+			return nil, fmt.Errorf("prefix ACL caller at index %d has no name", idx)
+		}
+		if _, ok := names[caller.Name]; ok {
+			return nil, fmt.Errorf("duplicate prefix ACL caller %q", caller.Name)
+		}
+		names[caller.Name] = struct{}{}
+
+		prefixes, err := parseACLPrefixes(caller.Prefixes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix ACL of caller %q: %w", caller.Name, err)
+		}
+
+		digest, err := readTokenDigest(caller.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token of prefix ACL caller %q: %w", caller.Name, err)
+		}
+		if other, ok := acl.callers[digest]; ok {
+			return nil, fmt.Errorf("prefix ACL callers %q and %q share a token", other.name, caller.Name)
+		}
+		acl.callers[digest] = &prefixACLCaller{name: caller.Name, prefixes: prefixes}
+	}
+
+	return acl, nil
+}
+
+func parseACLPrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// readTokenDigest reads a bearer token from a file, returning its SHA-256
+// digest.
+func readTokenDigest(path string) ([sha256.Size]byte, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to read token: %w", err)
+	}
+	token := strings.TrimSpace(string(buf))
+	if token == "" {
+		return [sha256.Size]byte{}, fmt.Errorf("token file %q is empty", path)
+	}
+	return sha256.Sum256([]byte(token)), nil
+}
+
+// bearerTokenDigest returns the SHA-256 digest of the bearer token of the
+// request, false if the request carries none.
+func bearerTokenDigest(ctx context.Context) ([sha256.Size]byte, bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return [sha256.Size]byte{}, false, nil
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return [sha256.Size]byte{}, false, status.Error(
+			codes.Unauthenticated,
+			"authorization metadata must carry a bearer token",
+		)
+	}
+	return sha256.Sum256([]byte(token)), true, nil
+}
+
+// caller identifies the caller of the request by its bearer token.
+func (m *PrefixACL) caller(ctx context.Context) (*prefixACLCaller, error) {
+	digest, ok, err := bearerTokenDigest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return m.anonymous, nil
+	}
+
+	caller, ok := m.callers[digest]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown bearer token")
+	}
+
+	return caller, nil
+}
+
+// Authorize checks that the caller of the request may change the routes of
+// the prefix.
+func (m *PrefixACL) Authorize(ctx context.Context, prefix netip.Prefix) error {
+	if m == nil {
+		return nil
+	}
+
+	caller, err := m.caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	prefix = prefix.Masked()
+	for _, owned := range caller.prefixes {
+		if owned.Bits() <= prefix.Bits() && owned.Contains(prefix.Addr()) {
+			return nil
+		}
+	}
+
+	m.log.Warn("denied route change outside of the caller prefix ACL",
+		zap.String("caller", caller.name),
+		zap.Stringer("prefix", prefix),
+	)
+	return status.Errorf(codes.PermissionDenied, "caller %q may not change routes of %s", caller.name, prefix)
+}
+
+// AuthorizeFilter checks that the caller of the request may change the
+// routes of every prefix the filter matches.
+//
+// A filter without a prefix may match any route, so it is denied.
+func (m *PrefixACL) AuthorizeFilter(ctx context.Context, filter *rib.RouteFilter) error {
+	if m == nil {
+		return nil
+	}
+	if !filter.Prefix.IsValid() {
+		return status.Error(
+			codes.PermissionDenied,
+			"filtered route deletions must be narrowed to a prefix while the prefix ACL is enabled",
+		)
+	}
+
+	return m.Authorize(ctx, filter.Prefix)
+}
@@ -0,0 +1,96 @@
+package operator
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+func TestPrefixACL(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "ddos.token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600))
+
+	acl, err := NewPrefixACL(PrefixACLConfig{
+		Enabled: true,
+		Callers: []PrefixACLCallerConfig{
+			{Name: "ddos", TokenFile: tokenFile, Prefixes: []string{"198.51.100.0/24", "2001:db8::/32"}},
+		},
+		Anonymous: []string{"10.0.0.0/8"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	svc := NewRouteService(neigh.NewNeighTable(), WithRouteServicePrefixACL(acl))
+	ddos := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer s3cret"))
+	insert := func(ctx context.Context, prefix string) error {
+		_, err := svc.InsertRoute(ctx, &operatorpb.InsertRouteRequest{
+			Name:         "route0",
+			Prefix:       prefix,
+			NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))},
+			SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+		})
+		return err
+	}
+
+	require.NoError(t, insert(ddos, "198.51.100.7/32"))
+	require.NoError(t, insert(ddos, "198.51.100.0/24"))
+	require.NoError(t, insert(ddos, "2001:db8::1/128"))
+	require.Equal(t, codes.PermissionDenied, status.Code(insert(ddos, "198.51.0.0/16")))
+	require.Equal(t, codes.PermissionDenied, status.Code(insert(ddos, "10.1.0.0/16")))
+
+	require.NoError(t, insert(t.Context(), "10.1.0.0/16"))
+	require.Equal(t, codes.PermissionDenied, status.Code(insert(t.Context(), "198.51.100.7/32")))
+
+	unknown := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer other"))
+	require.Equal(t, codes.Unauthenticated, status.Code(insert(unknown, "10.1.0.0/16")))
+
+	_, err = svc.DeleteRoute(t.Context(), &operatorpb.DeleteRouteRequest{
+		Name:         "route0",
+		Prefix:       "198.51.100.7/32",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = svc.DeleteRoutesByFilter(ddos, &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "route0",
+		Filter: &operatorpb.RouteFilter{Prefix: "198.51.100.0/24"},
+	})
+	require.NoError(t, err)
+	_, err = svc.DeleteRoutesByFilter(ddos, &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "route0",
+		Filter: &operatorpb.RouteFilter{NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestNewPrefixACL(t *testing.T) {
+	acl, err := NewPrefixACL(PrefixACLConfig{Callers: []PrefixACLCallerConfig{{Name: "ddos"}}}, zap.NewNop())
+	require.NoError(t, err)
+	require.Nil(t, acl)
+	require.NoError(t, acl.Authorize(t.Context(), netip.MustParsePrefix("10.0.0.0/8")))
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+
+	for _, callers := range [][]PrefixACLCallerConfig{
+		{{TokenFile: tokenFile}},
+		{{Name: "ddos", TokenFile: tokenFile + ".missing"}},
+		{{Name: "ddos", TokenFile: tokenFile, Prefixes: []string{"bad"}}},
+		{{Name: "ddos", TokenFile: tokenFile}, {Name: "ddos", TokenFile: tokenFile}},
+		{{Name: "ddos", TokenFile: tokenFile}, {Name: "ops", TokenFile: tokenFile}},
+	} {
+		_, err := NewPrefixACL(PrefixACLConfig{Enabled: true, Callers: callers}, zap.NewNop())
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,208 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yanet-platform/yanet2/common/go/maptrie"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// PrefixTagger resolves the tags of RIB prefixes, such as the country or
+// the customer a prefix belongs to.
+//
+// It is the hook for external enrichment sources: a GeoIP database, an
+// IPAM service or a local file, see FilePrefixTagger.
+type PrefixTagger interface {
+	// Tags returns the tags of the prefix.
+	//
+	// A prefix unknown to the source gets empty tags. An error leaves the
+	// prefix untagged, so it is retried on the next sweep.
+	Tags(ctx context.Context, prefix netip.Prefix) (rib.PrefixTags, error)
+}
+
+// yamlPrefixTagsFile is the YAML form of a prefix tags file.
+type yamlPrefixTagsFile struct {
+	Prefixes []struct {
+		Prefix string            `yaml:"prefix"`
+		Tags   map[string]string `yaml:"tags"`
+	} `yaml:"prefixes"`
+}
+
+// FilePrefixTagger tags prefixes from a local list of tagged prefixes.
+//
+// A RIB prefix gets the tags of the most specific listed prefix covering
+// it, so a list of allocations tags every route carved out of them.
+type FilePrefixTagger struct {
+	prefixes maptrie.MapTrie[netip.Prefix, netip.Addr, rib.PrefixTags]
+}
+
+// LoadFilePrefixTagger reads a prefix tags file:
+//
+//	prefixes:
+//	  - prefix: 10.0.0.0/8
+//	    tags:
+//	      country: nl
+//	      customer_id: "42"
+func LoadFilePrefixTagger(path string) (*FilePrefixTagger, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prefix tags file %q: %w", path, err)
+	}
+
+	var file yamlPrefixTagsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse prefix tags file %q: %w", path, err)
+	}
+
+	prefixes := maptrie.NewMapTrie[netip.Prefix, netip.Addr, rib.PrefixTags](0)
+	for idx, entry := range file.Prefixes {
+		prefix, err := netip.ParsePrefix(entry.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prefix tags file %q: prefixes[%d]: %w", path, idx, err)
+		}
+		tags := rib.PrefixTags(maps.Clone(entry.Tags))
+		prefixes.InsertOrUpdate(
+			prefix,
+			func() rib.PrefixTags { return tags },
+			func(rib.PrefixTags) rib.PrefixTags { return tags },
+		)
+	}
+
+	return &FilePrefixTagger{prefixes: prefixes}, nil
+}
+
+// Tags implements PrefixTagger.
+func (m *FilePrefixTagger) Tags(ctx context.Context, prefix netip.Prefix) (rib.PrefixTags, error) {
+	tags := rib.PrefixTags{}
+	found := false
+	m.prefixes.LookupTraverseRev(prefix.Addr(), func(p netip.Prefix, t rib.PrefixTags) bool {
+		// Longer listed prefixes match the address but do not cover the
+		// whole RIB prefix.
+		if found || p.Bits() > prefix.Bits() {
+			return true
+		}
+		maps.Copy(tags, t)
+		found = true
+		return false
+	})
+	return tags, nil
+}
+
+// PrefixEnricherOption configures the PrefixEnricher constructor.
+type PrefixEnricherOption func(*prefixEnricherOptions)
+
+type prefixEnricherOptions struct {
+	Interval time.Duration
+	Log      *zap.Logger
+}
+
+func newPrefixEnricherOptions() *prefixEnricherOptions {
+	return &prefixEnricherOptions{
+		Interval: defaultEnrichmentInterval,
+		Log:      zap.NewNop(),
+	}
+}
+
+// WithPrefixEnricherInterval sets the period between sweeps.
+func WithPrefixEnricherInterval(interval time.Duration) PrefixEnricherOption {
+	return func(o *prefixEnricherOptions) {
+		o.Interval = interval
+	}
+}
+
+// WithPrefixEnricherLog sets the logger.
+func WithPrefixEnricherLog(log *zap.Logger) PrefixEnricherOption {
+	return func(o *prefixEnricherOptions) {
+		o.Log = log
+	}
+}
+
+// PrefixEnricher periodically tags the prefixes of every RIB that were
+// not tagged yet.
+//
+// Tagging runs off the update path, so a slow or unavailable tagger never
+// delays route updates. Tags are informational and do not affect the FIB.
+type PrefixEnricher struct {
+	tagger   PrefixTagger
+	ribs     *RIBStore
+	interval time.Duration
+	log      *zap.Logger
+}
+
+// NewPrefixEnricher creates a PrefixEnricher tagging the RIBs of the store
+// with the tagger.
+func NewPrefixEnricher(tagger PrefixTagger, ribs *RIBStore, options ...PrefixEnricherOption) *PrefixEnricher {
+	opts := newPrefixEnricherOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &PrefixEnricher{
+		tagger:   tagger,
+		ribs:     ribs,
+		interval: opts.Interval,
+		log:      opts.Log,
+	}
+}
+
+// Enrich tags the untagged prefixes of every RIB, returning the number of
+// prefixes tagged.
+//
+// Prefixes the tagger fails on stay untagged until the next call.
+func (m *PrefixEnricher) Enrich(ctx context.Context) int {
+	tagged := 0
+	for name, holder := range m.ribs.Snapshot() {
+		failed := 0
+		var lastErr error
+		for _, prefix := range holder.UntaggedPrefixes() {
+			if ctx.Err() != nil {
+				return tagged
+			}
+
+			tags, err := m.tagger.Tags(ctx, prefix)
+			if err != nil {
+				failed++
+				lastErr = err
+				continue
+			}
+			if holder.SetPrefixTags(prefix, tags) {
+				tagged++
+			}
+		}
+
+		if failed > 0 {
+			m.log.Warn("failed to tag prefixes, retrying on next sweep",
+				zap.String("name", name),
+				zap.Int("failed", failed),
+				zap.Error(lastErr),
+			)
+		}
+	}
+
+	return tagged
+}
+
+// Run enriches the RIBs every interval until the context is cancelled.
+func (m *PrefixEnricher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if tagged := m.Enrich(ctx); tagged > 0 {
+				m.log.Debug("tagged prefixes", zap.Int("tagged", tagged))
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+func TestFilePrefixTagger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"prefixes:\n"+
+			"  - prefix: 10.0.0.0/8\n"+
+			"    tags: {country: nl}\n"+
+			"  - prefix: 10.1.0.0/16\n"+
+			"    tags: {country: nl, customer_id: \"42\"}\n"+
+			"  - prefix: 2001:db8::/32\n"+
+			"    tags: {country: de}\n",
+	), 0o644))
+
+	tagger, err := LoadFilePrefixTagger(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		prefix string
+		tags   rib.PrefixTags
+	}{
+		{prefix: "10.1.2.0/24", tags: rib.PrefixTags{"country": "nl", "customer_id": "42"}},
+		{prefix: "10.1.0.0/16", tags: rib.PrefixTags{"country": "nl", "customer_id": "42"}},
+		// The /16 matches the address, but does not cover the whole /12.
+		{prefix: "10.0.0.0/12", tags: rib.PrefixTags{"country": "nl"}},
+		{prefix: "2001:db8:1::/48", tags: rib.PrefixTags{"country": "de"}},
+		{prefix: "192.0.2.0/24", tags: rib.PrefixTags{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			tags, err := tagger.Tags(t.Context(), netip.MustParsePrefix(tt.prefix))
+			require.NoError(t, err)
+			require.Equal(t, tt.tags, tags)
+		})
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("prefixes:\n  - prefix: bad\n"), 0o644))
+	_, err = LoadFilePrefixTagger(path)
+	require.Error(t, err)
+}
+
+// flakyPrefixTagger fails on the prefixes listed in fail and tags the
+// others with their own address.
+type flakyPrefixTagger struct {
+	fail map[netip.Prefix]bool
+}
+
+func (m *flakyPrefixTagger) Tags(ctx context.Context, prefix netip.Prefix) (rib.PrefixTags, error) {
+	if m.fail[prefix] {
+		return nil, errors.New("unavailable")
+	}
+	return rib.PrefixTags{"net": prefix.Addr().String()}, nil
+}
+
+// TestPrefixEnricher verifies that swept prefixes are returned tagged by
+// lookups and that prefixes the tagger fails on are retried.
+func TestPrefixEnricher(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+	defer svc.Close()
+
+	holder := svc.getOrCreateRib("route0")
+	nexthop := netip.MustParseAddr("192.0.2.1")
+	failing := netip.MustParsePrefix("10.2.0.0/16")
+	require.NoError(t, holder.AddUnicastRoute(netip.MustParsePrefix("10.1.0.0/16"), nexthop, rib.RouteSourceStatic))
+	require.NoError(t, holder.AddUnicastRoute(failing, nexthop, rib.RouteSourceStatic))
+
+	tagger := &flakyPrefixTagger{fail: map[netip.Prefix]bool{failing: true}}
+	enricher := NewPrefixEnricher(tagger, svc.ribs, WithPrefixEnricherLog(zap.NewNop()))
+
+	require.Equal(t, 1, enricher.Enrich(t.Context()))
+	require.Equal(t, []netip.Prefix{failing}, holder.UntaggedPrefixes())
+
+	resp, err := svc.LookupRoute(t.Context(), &operatorpb.LookupRouteRequest{
+		Name:   "route0",
+		IpAddr: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("10.1.2.3")),
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"net": "10.1.0.0"}, resp.GetTags())
+
+	tagger.fail = nil
+	require.Equal(t, 1, enricher.Enrich(t.Context()))
+	require.Empty(t, holder.UntaggedPrefixes())
+
+	// Already tagged prefixes are not swept again.
+	require.Zero(t, enricher.Enrich(t.Context()))
+}
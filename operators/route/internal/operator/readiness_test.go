@@ -272,6 +272,69 @@ func TestRIBReadiness_SessionEnd_Degraded(t *testing.T) {
 	_ = eg.Wait()
 }
 
+// TestRIBReadiness_EndOfRIB verifies that an end-of-RIB marker from the
+// current session settles the rib scope without waiting for the stability
+// window, and that a marker from a superseded session is ignored.
+func TestRIBReadiness_EndOfRIB(t *testing.T) {
+	tests := []struct {
+		name           string
+		seedRoutes     bool
+		markerSession  uint64
+		wantState      readinesspb.State
+		wantReasonCode string
+	}{
+		{
+			name:          "current session with routes",
+			seedRoutes:    true,
+			markerSession: 2,
+			wantState:     readinesspb.State_STATE_READY,
+		},
+		{
+			name:           "current session without routes",
+			markerSession:  2,
+			wantState:      readinesspb.State_STATE_NOT_READY,
+			wantReasonCode: "NO_ROUTES",
+		},
+		{
+			name:           "superseded session",
+			seedRoutes:     true,
+			markerSession:  1,
+			wantState:      readinesspb.State_STATE_DEGRADED,
+			wantReasonCode: "SYNCING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ReadinessConfig{
+				RateThreshold:   1000,
+				StabilityWindow: time.Hour,
+				SampleInterval:  time.Hour,
+			}
+
+			store := newRIBStore(zap.NewNop())
+			if tt.seedRoutes {
+				seedRoute(t, store, "route0")
+			}
+			tracker := readiness.NewTracker([]string{"rib"})
+			helper := newBirdRIBReadiness(cfg, store, "route0", tracker, zap.NewNop())
+
+			helper.OnSessionStart("route0", 1)
+			helper.OnSessionStart("route0", 2)
+			helper.OnEndOfRIB("route0", tt.markerSession)
+
+			s := requireScope(t, tracker, "rib")
+			assert.Equal(t, tt.wantState, s.State)
+			if tt.wantReasonCode == "" {
+				assert.Empty(t, s.Reasons)
+			} else {
+				require.NotEmpty(t, s.Reasons)
+				assert.Equal(t, tt.wantReasonCode, s.Reasons[0].Code)
+			}
+		})
+	}
+}
+
 // seedRoute adds a single static unicast route to the named RIB in the store.
 //
 // This makes Stats().Routes > 0 so that syncingState returns DEGRADED rather
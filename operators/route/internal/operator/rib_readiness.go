@@ -36,7 +36,9 @@ func newRIBReadiness(
 //
 // The bulk-settle gate (RateThreshold/StabilityWindow) is sticky within a
 // session — a rate spike after settling does not flip the rib scope back to
-// NOT_READY. It resets on each SessionStart.
+// NOT_READY. It resets on each SessionStart. An end-of-RIB marker from the
+// current session settles the gate immediately, so senders that announce
+// the end of their initial dump do not wait for the stability window.
 type birdRIBReadiness struct {
 	store      *RIBStore
 	configName string
@@ -155,6 +157,34 @@ func (m *birdRIBReadiness) OnUpdate(n int) {
 	m.counter.Add(int64(n))
 }
 
+// OnEndOfRIB is called by FeedRIB when the stream delivers its end-of-RIB
+// marker for the given config.
+//
+// The bulk-settle gate is closed right away and the rib scope is
+// re-evaluated without waiting for the next tick. Markers from a session
+// other than the current one are ignored.
+func (m *birdRIBReadiness) OnEndOfRIB(name string, sessionID uint64) {
+	if name != m.configName {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.sessionActive || sessionID != m.currentSession {
+		return
+	}
+	m.bulkSettled = true
+
+	if m.hasRoutes() {
+		m.tracker.Set("rib", readinesspb.State_STATE_READY)
+	} else {
+		m.tracker.SetWithReason("rib",
+			readinesspb.State_STATE_NOT_READY,
+			&readinesspb.Reason{Code: "NO_ROUTES"},
+		)
+	}
+}
+
 // OnSessionEnd is called by FeedRIB when the stream ends for the given config.
 //
 // sessionID must match the id passed to the most recent OnSessionStart for
@@ -292,7 +322,7 @@ func (m *birdRIBReadiness) tick(now time.Time, rate float64) {
 // staticRIBReadiness drives only the "rib" readiness scope based on
 // whether the store holds any routes, without any BIRD session awareness.
 //
-// OnSessionStart, OnUpdate, and OnSessionEnd are no-ops: FeedRIB route
+// OnSessionStart, OnUpdate, OnEndOfRIB, and OnSessionEnd are no-ops: FeedRIB route
 // updates still get applied by RouteService regardless, but session events
 // carry no meaning for the readiness model when BIRD is not expected.
 // Run evaluates hasRoutes() once on entry and then on every SampleInterval
@@ -338,6 +368,9 @@ func (m *staticRIBReadiness) OnSessionStart(_ string, _ uint64) {}
 // OnUpdate is a no-op for static deployments.
 func (m *staticRIBReadiness) OnUpdate(_ int) {}
 
+// OnEndOfRIB is a no-op for static deployments.
+func (m *staticRIBReadiness) OnEndOfRIB(_ string, _ uint64) {}
+
 // OnSessionEnd is a no-op for static deployments.
 func (m *staticRIBReadiness) OnSessionEnd(_ string, _ uint64) {}
 
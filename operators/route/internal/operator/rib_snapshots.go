@@ -0,0 +1,247 @@
+package operator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+	"github.com/yanet-platform/yanet2/operators/route/ribsnapshot"
+)
+
+const (
+	// snapshotFileExt is the extension of the RIB snapshot files, named
+	// after the config of their RIB.
+	snapshotFileExt = ".ribsnap"
+
+	// snapshotImportBatch is the number of restored routes applied to the
+	// RIB at once.
+	snapshotImportBatch = 1024
+)
+
+// snapshotPath returns the path of the snapshot file of the config.
+func (m *RouteService) snapshotPath(name string) (string, error) {
+	if m.snapshotDir == "" {
+		return "", status.Error(codes.FailedPrecondition, "RIB snapshots are disabled: no snapshot directory is configured")
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", status.Errorf(codes.InvalidArgument, "config name %q is not a valid snapshot file name", name)
+	}
+	return filepath.Join(m.snapshotDir, name+snapshotFileExt), nil
+}
+
+// authorizeSnapshots checks that the caller may access the snapshot file
+// of the config, which unscoped callers only may.
+func (m *RouteService) authorizeSnapshots(ctx context.Context, name string) error {
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return err
+	}
+	if tenant != nil {
+		return status.Errorf(codes.PermissionDenied, "tenant %q may not access RIB snapshots", tenant.name)
+	}
+	return nil
+}
+
+// writeRIBSnapshot writes a snapshot of the routes of the RIB the tenant
+// sees to w, returning the number of routes written.
+func writeRIBSnapshot(w io.Writer, name string, holder *rib.RIB, tenant *tenant) (uint64, error) {
+	createdAt := time.Now()
+	ribDump := holder.DumpRoutes()
+
+	writer, err := ribsnapshot.NewWriter(w, ribsnapshot.Header{Config: name, CreatedAt: createdAt})
+	if err != nil {
+		return 0, err
+	}
+	count := uint64(0)
+	for prefixLen := range ribDump {
+		for _, routesList := range ribDump[prefixLen] {
+			for idx := range routesList.Routes {
+				if !tenant.visible(&routesList.Routes[idx]) {
+					continue
+				}
+				if err := writer.Write(&routesList.Routes[idx]); err != nil {
+					return 0, err
+				}
+				count++
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// exportRIBSnapshot writes a snapshot of the RIB to the file at path.
+//
+// The snapshot is written to a temporary file renamed over the previous
+// one once complete, so that a failed export keeps the previous snapshot.
+func exportRIBSnapshot(path string, name string, holder *rib.RIB) (uint64, error) {
+	dir, base := filepath.Split(path)
+	file, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+
+	buffered := bufio.NewWriterSize(file, snapshotChunkSize)
+	count, err := writeRIBSnapshot(buffered, name, holder, nil)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// importRIBSnapshot restores the BIRD routes of the snapshot at path into
+// the RIB of the config, returning the header of the snapshot and the
+// number of routes restored, even on failure.
+//
+// Static routes are seeded from the config and the other sources insert
+// theirs again, so their routes are not restored. The restored routes
+// belong to no session: the ones no BIRD session announces again are
+// withdrawn after the RIB TTL, as the routes of an ended session are. A
+// damaged snapshot keeps the routes restored before the damage, withdrawn
+// likewise.
+func (m *RouteService) importRIBSnapshot(path string, name string) (ribsnapshot.Header, uint64, error) {
+	if holder, ok := m.getRib(name); ok {
+		if _, ok := holder.Convergence(rib.RouteSourceBird); ok {
+			return ribsnapshot.Header{}, 0, status.Errorf(
+				codes.FailedPrecondition,
+				"RIB %q is already fed by a BIRD session",
+				name,
+			)
+		}
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ribsnapshot.Header{}, 0, status.Errorf(codes.NotFound, "no snapshot of RIB %q", name)
+	}
+	if err != nil {
+		return ribsnapshot.Header{}, 0, status.Errorf(codes.Internal, "failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := ribsnapshot.NewReader(bufio.NewReaderSize(file, snapshotChunkSize))
+	if err != nil {
+		return ribsnapshot.Header{}, 0, status.Errorf(codes.DataLoss, "failed to read snapshot %s: %v", path, err)
+	}
+	defer reader.Close()
+	header := reader.Header()
+
+	holder := m.getOrCreateRib(name)
+	restored := 0
+	batch := make([]rib.Route, 0, snapshotImportBatch)
+	apply := func() {
+		restored += holder.Restore(batch...)
+		batch = batch[:0]
+	}
+	for {
+		route, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			apply()
+			m.onRestored(holder, name, restored)
+			return ribsnapshot.Header{}, uint64(restored), status.Errorf(
+				codes.DataLoss,
+				"failed to read snapshot %s after restoring %d routes: %v",
+				path,
+				restored,
+				err,
+			)
+		}
+		if route.SourceID != rib.RouteSourceBird {
+			continue
+		}
+		if batch = append(batch, route); len(batch) == snapshotImportBatch {
+			apply()
+		}
+	}
+	apply()
+	m.onRestored(holder, name, restored)
+
+	m.log.Info("imported RIB snapshot",
+		zap.String("name", name),
+		zap.String("path", path),
+		zap.Time("created_at", header.CreatedAt),
+		zap.Int("routes", restored),
+	)
+
+	return header, uint64(restored), nil
+}
+
+// onRestored accounts the routes restored into the RIB and schedules their
+// withdrawal.
+func (m *RouteService) onRestored(holder *rib.RIB, name string, restored int) {
+	if restored == 0 {
+		return
+	}
+	m.onAccepted()
+	m.onRIBUpdate(restored)
+
+	m.log.Info("scheduling cleanup of restored routes",
+		zap.String("name", name),
+		zap.Duration("ttl", m.ribTTL),
+	)
+	// BIRD sessions start at 1, so the session 0 holds the restored routes
+	// only.
+	go holder.CleanupTask(0, m.quitCh, m.ribTTL)
+}
+
+// restoreSnapshots imports every snapshot of the snapshot directory into
+// the RIB of the config its file is named after.
+//
+// A snapshot failing to import is logged and skipped, so that a damaged
+// file never keeps the operator from starting. Returns whether any route
+// was restored.
+func (m *RouteService) restoreSnapshots() bool {
+	entries, err := os.ReadDir(m.snapshotDir)
+	if err != nil {
+		m.log.Warn("failed to list RIB snapshots", zap.String("dir", m.snapshotDir), zap.Error(err))
+		return false
+	}
+
+	restored := false
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), snapshotFileExt)
+		if !ok || name == "" || entry.IsDir() {
+			continue
+		}
+		_, count, err := m.importRIBSnapshot(filepath.Join(m.snapshotDir, entry.Name()), name)
+		restored = restored || count > 0
+		if err != nil {
+			m.log.Warn("failed to restore RIB snapshot",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+		}
+	}
+	return restored
+}
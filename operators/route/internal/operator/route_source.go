@@ -20,6 +20,8 @@ type RouteSnapshot struct {
 	RIBs map[string]maptrie.MapTrie[netip.Prefix, netip.Addr, rib.RoutesList]
 	// Neighbours is the neighbour view used to resolve route nexthops.
 	Neighbours neigh.NexthopCacheView
+	// Degraded are the nexthops the FIBs avoid, see BuildFIB.
+	Degraded map[netip.Addr]struct{}
 }
 
 // RouteSource is the operator.StateSource[RouteSnapshot] used by the route
@@ -36,6 +38,7 @@ type RouteSnapshot struct {
 type RouteSource struct {
 	routeReader routeSnapshot
 	neighTable  *neigh.NeighTable
+	performance *NexthopPerformance
 	wakeCh      chan struct{}
 }
 
@@ -47,10 +50,17 @@ type RouteSource struct {
 func NewRouteSource(
 	neighTable *neigh.NeighTable,
 	ribReader routeSnapshot,
+	options ...RouteSourceOption,
 ) *RouteSource {
+	opts := newRouteSourceOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
 	return &RouteSource{
 		routeReader: ribReader,
 		neighTable:  neighTable,
+		performance: opts.Performance,
 		wakeCh:      make(chan struct{}, 1),
 	}
 }
@@ -67,13 +77,24 @@ func (m *RouteSource) Snapshot() (RouteSnapshot, bool) {
 	default:
 	}
 
+	return m.Dump(), true
+}
+
+// Dump returns the current desired state without consuming a pending
+// wake, so callers outside the reconcile loop never swallow a wake meant
+// for it.
+func (m *RouteSource) Dump() RouteSnapshot {
 	ribs := m.routeReader.Snapshot()
 
 	dumps := make(map[string]maptrie.MapTrie[netip.Prefix, netip.Addr, rib.RoutesList], len(ribs))
 	for name, ribRef := range ribs {
 		dumps[name] = ribRef.DumpRoutes()
 	}
-	return RouteSnapshot{RIBs: dumps, Neighbours: m.neighTable.View()}, true
+	snapshot := RouteSnapshot{RIBs: dumps, Neighbours: m.neighTable.View()}
+	if m.performance != nil {
+		snapshot.Degraded = m.performance.Degraded()
+	}
+	return snapshot
 }
 
 func (m *RouteSource) Wake() <-chan struct{} {
@@ -96,7 +96,7 @@ func Test_RouteSource_SnapshotIncludesRIBs(t *testing.T) {
 	require.Len(t, snapshot.RIBs, 1)
 	require.Contains(t, snapshot.RIBs, "route0")
 
-	fib, _ := BuildFIB(snapshot.RIBs["route0"], snapshot.Neighbours)
+	fib, _ := BuildFIB(snapshot.RIBs["route0"], snapshot.Neighbours, 0, snapshot.Degraded)
 	fib.Name = "route0"
 
 	expected := FIB{
@@ -111,6 +111,7 @@ func Test_RouteSource_SnapshotIncludesRIBs(t *testing.T) {
 						Device:         "eth0",
 					},
 				},
+				Sources: []rib.RouteSourceID{rib.RouteSourceStatic},
 			},
 		},
 	}
@@ -0,0 +1,84 @@
+package operator
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// NexthopService implements the NexthopService surface fed by external
+// probes.
+type NexthopService struct {
+	operatorpb.UnimplementedNexthopServiceServer
+
+	performance *NexthopPerformance
+}
+
+// NewNexthopService constructs a NexthopService recording measurements
+// into performance.
+func NewNexthopService(performance *NexthopPerformance) *NexthopService {
+	return &NexthopService{
+		performance: performance,
+	}
+}
+
+// ReportMeasurements validates every measurement before recording any, so
+// a malformed report leaves the state untouched.
+func (m *NexthopService) ReportMeasurements(
+	ctx context.Context,
+	req *operatorpb.ReportMeasurementsRequest,
+) (*operatorpb.ReportMeasurementsResponse, error) {
+	measurements := make(map[netip.Addr]NexthopMeasurement, len(req.GetMeasurements()))
+	for idx, measurement := range req.GetMeasurements() {
+		addr, err := measurement.GetNextHop().ToAddr()
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "measurements[%d]: invalid next_hop: %v", idx, err)
+		}
+		loss := measurement.GetLoss()
+		if !(loss >= 0 && loss <= 1) {
+			return nil, status.Errorf(codes.InvalidArgument, "measurements[%d]: loss %v is out of [0, 1]", idx, loss)
+		}
+
+		measurements[addr] = NexthopMeasurement{
+			Latency: time.Duration(measurement.GetLatencyNs()),
+			Loss:    loss,
+			Source:  measurement.GetSource(),
+		}
+	}
+
+	m.performance.Report(measurements)
+	return &operatorpb.ReportMeasurementsResponse{}, nil
+}
+
+func (m *NexthopService) ListMeasurements(
+	ctx context.Context,
+	req *operatorpb.ListMeasurementsRequest,
+) (*operatorpb.ListMeasurementsResponse, error) {
+	measurements := m.performance.Measurements()
+
+	out := make([]*operatorpb.NexthopMeasurement, 0, len(measurements))
+	for _, addr := range slices.SortedFunc(maps.Keys(measurements), netip.Addr.Compare) {
+		measurement := measurements[addr]
+		out = append(out, &operatorpb.NexthopMeasurement{
+			NextHop:    commonpb.NewIPAddressFromAddr(addr),
+			LatencyNs:  uint64(measurement.Latency),
+			Loss:       measurement.Loss,
+			Source:     measurement.Source,
+			ReportedAt: measurement.ReportedAt.UnixNano(),
+			Degraded:   m.performance.isDegraded(measurement),
+		})
+	}
+
+	return &operatorpb.ListMeasurementsResponse{
+		Measurements: out,
+		Enabled:      m.performance.cfg.Enabled,
+	}, nil
+}
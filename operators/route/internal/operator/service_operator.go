@@ -2,17 +2,39 @@ package operator
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"sync"
 
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
 )
 
+// FIBResyncer rebuilds and pushes the FIBs of a single gateway.
+type FIBResyncer interface {
+	// Name returns the name of the gateway.
+	Name() string
+	// Resync builds the FIB of every module config in the snapshot and
+	// pushes it to the gateway, reporting the outcome of every FIB to
+	// onFIB.
+	Resync(ctx context.Context, snapshot RouteSnapshot, onFIB func(module string, entries int, err error)) error
+}
+
 // RouteOperatorService is the intent surface for the route operator.
 // Phase 1: Switch / Status return codes.Unimplemented.
 type RouteOperatorService struct {
 	operatorpb.UnimplementedRouteOperatorServiceServer
+
+	// resyncMu serializes resyncs, so concurrent requests never interleave
+	// their pushes.
+	resyncMu   sync.Mutex
+	snapshot   func() RouteSnapshot
+	gateways   []FIBResyncer
+	onResynced func()
+	log        *zap.Logger
 }
 
 // NewRouteOperatorService constructs a RouteOperatorService.
@@ -22,7 +44,12 @@ func NewRouteOperatorService(options ...OperatorServiceOption) *RouteOperatorSer
 		o(opts)
 	}
 
-	return &RouteOperatorService{}
+	return &RouteOperatorService{
+		snapshot:   opts.Snapshot,
+		gateways:   opts.Gateways,
+		onResynced: opts.OnResynced,
+		log:        opts.Log,
+	}
 }
 
 func (m *RouteOperatorService) Switch(
@@ -38,3 +65,104 @@ func (m *RouteOperatorService) Status(
 ) (*operatorpb.StatusResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
+
+// Resync rebuilds the FIBs from a fresh dump of the RIBs and pushes them
+// to the requested gateways one by one.
+//
+// The reconcile loop may push a FIB concurrently, so the loop is woken
+// once the resync is over: whichever push lands last, the next pass
+// converges the gateways on the latest RIB.
+func (m *RouteOperatorService) Resync(
+	req *operatorpb.ResyncRequest,
+	stream operatorpb.RouteOperatorService_ResyncServer,
+) error {
+	gateways, err := m.resyncGateways(req.GetGateways())
+	if err != nil {
+		return err
+	}
+
+	m.resyncMu.Lock()
+	defer m.resyncMu.Unlock()
+	defer m.onResynced()
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	snapshot := m.snapshot()
+	total := uint32(len(gateways) * len(snapshot.RIBs))
+
+	m.log.Info("started resync",
+		zap.Int("gateways", len(gateways)),
+		zap.Int("modules", len(snapshot.RIBs)),
+	)
+
+	done := uint32(0)
+	failed := 0
+	var sendErr error
+	var resyncErr error
+	for _, gw := range gateways {
+		err := gw.Resync(ctx, snapshot, func(module string, entries int, err error) {
+			done++
+			progress := &operatorpb.ResyncProgress{
+				Gateway: gw.Name(),
+				Module:  module,
+				Entries: uint64(entries),
+				Done:    done,
+				Total:   total,
+			}
+			if err != nil {
+				failed++
+				progress.Error = err.Error()
+			}
+
+			if sendErr != nil {
+				return
+			}
+			// The client is gone, so there is no point in pushing the
+			// remaining FIBs.
+			if sendErr = stream.Send(progress); sendErr != nil {
+				cancel()
+			}
+		})
+		if sendErr != nil {
+			return sendErr
+		}
+		resyncErr = errors.Join(resyncErr, err)
+	}
+
+	if resyncErr != nil {
+		m.log.Warn("failed to resync",
+			zap.Int("failed", failed),
+			zap.Uint32("total", total),
+			zap.Error(resyncErr),
+		)
+		return status.Errorf(codes.Internal, "failed to resync: %v", resyncErr)
+	}
+
+	m.log.Info("finished resync", zap.Uint32("total", total))
+	return nil
+}
+
+// resyncGateways returns the gateways with the given names, or all of them
+// if no names are given.
+func (m *RouteOperatorService) resyncGateways(names []string) ([]FIBResyncer, error) {
+	if len(names) == 0 {
+		return m.gateways, nil
+	}
+
+	gateways := make([]FIBResyncer, 0, len(names))
+	for _, name := range names {
+		idx := slices.IndexFunc(m.gateways, func(gw FIBResyncer) bool {
+			return gw.Name() == name
+		})
+		if idx < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown gateway %q", name)
+		}
+		if slices.Contains(gateways, m.gateways[idx]) {
+			continue
+		}
+		gateways = append(gateways, m.gateways[idx])
+	}
+
+	return gateways, nil
+}
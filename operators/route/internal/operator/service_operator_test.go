@@ -0,0 +1,135 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/common/go/maptrie"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+// fakeResyncer pushes nothing, reporting every module config of the
+// snapshot with a fixed number of entries, and fails the ones listed in
+// fail.
+type fakeResyncer struct {
+	name  string
+	fail  map[string]error
+	calls int
+}
+
+func (m *fakeResyncer) Name() string {
+	return m.name
+}
+
+func (m *fakeResyncer) Resync(ctx context.Context, snapshot RouteSnapshot, onFIB func(string, int, error)) error {
+	m.calls++
+
+	var err error
+	for name := range snapshot.RIBs {
+		if e := m.fail[name]; e != nil {
+			onFIB(name, 0, e)
+			err = errors.Join(err, e)
+			continue
+		}
+		onFIB(name, 3, nil)
+	}
+	return err
+}
+
+// fakeResyncStream collects the progress sent by Resync.
+type fakeResyncStream struct {
+	grpc.ServerStream
+
+	ctx      context.Context
+	err      error
+	progress []*operatorpb.ResyncProgress
+}
+
+func (m *fakeResyncStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *fakeResyncStream) Send(progress *operatorpb.ResyncProgress) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.progress = append(m.progress, progress)
+	return nil
+}
+
+func newTestResyncSnapshot(modules ...string) RouteSnapshot {
+	ribs := map[string]maptrie.MapTrie[netip.Prefix, netip.Addr, rib.RoutesList]{}
+	for _, module := range modules {
+		ribs[module] = maptrie.MapTrie[netip.Prefix, netip.Addr, rib.RoutesList]{}
+	}
+	return RouteSnapshot{RIBs: ribs}
+}
+
+func TestRouteOperatorService_Resync(t *testing.T) {
+	gw0 := &fakeResyncer{name: "gw0"}
+	gw1 := &fakeResyncer{name: "gw1"}
+	resynced := 0
+	svc := NewRouteOperatorService(
+		WithOperatorServiceSnapshot(func() RouteSnapshot { return newTestResyncSnapshot("route0") }),
+		WithOperatorServiceGateways(gw0, gw1),
+		WithOperatorServiceOnResynced(func() { resynced++ }),
+	)
+
+	stream := &fakeResyncStream{ctx: t.Context()}
+	require.NoError(t, svc.Resync(&operatorpb.ResyncRequest{}, stream))
+	require.Len(t, stream.progress, 2)
+	for idx, gw := range []string{"gw0", "gw1"} {
+		progress := stream.progress[idx]
+		require.Equal(t, gw, progress.Gateway)
+		require.Equal(t, "route0", progress.Module)
+		require.Equal(t, uint64(3), progress.Entries)
+		require.Empty(t, progress.Error)
+		require.Equal(t, uint32(idx+1), progress.Done)
+		require.Equal(t, uint32(2), progress.Total)
+	}
+	require.Equal(t, 1, resynced)
+
+	// Only the requested gateways are resynced, each once.
+	stream = &fakeResyncStream{ctx: t.Context()}
+	require.NoError(t, svc.Resync(&operatorpb.ResyncRequest{Gateways: []string{"gw1", "gw1"}}, stream))
+	require.Len(t, stream.progress, 1)
+	require.Equal(t, "gw1", stream.progress[0].Gateway)
+	require.Equal(t, 1, gw0.calls)
+	require.Equal(t, 2, gw1.calls)
+	require.Equal(t, 2, resynced)
+
+	err := svc.Resync(&operatorpb.ResyncRequest{Gateways: []string{"gw2"}}, &fakeResyncStream{ctx: t.Context()})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestRouteOperatorService_ResyncFailed verifies that a failed FIB is
+// reported in the progress, the remaining gateways are still resynced and
+// the stream ends with an error.
+func TestRouteOperatorService_ResyncFailed(t *testing.T) {
+	gw0 := &fakeResyncer{name: "gw0", fail: map[string]error{"route0": errors.New("unavailable")}}
+	gw1 := &fakeResyncer{name: "gw1"}
+	svc := NewRouteOperatorService(
+		WithOperatorServiceSnapshot(func() RouteSnapshot { return newTestResyncSnapshot("route0") }),
+		WithOperatorServiceGateways(gw0, gw1),
+	)
+
+	stream := &fakeResyncStream{ctx: t.Context()}
+	err := svc.Resync(&operatorpb.ResyncRequest{}, stream)
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Len(t, stream.progress, 2)
+	require.Equal(t, "unavailable", stream.progress[0].Error)
+	require.Empty(t, stream.progress[1].Error)
+
+	// A client gone mid-resync stops it.
+	stream = &fakeResyncStream{ctx: t.Context(), err: context.Canceled}
+	require.ErrorIs(t, svc.Resync(&operatorpb.ResyncRequest{}, stream), context.Canceled)
+	require.Equal(t, 1, gw1.calls)
+}
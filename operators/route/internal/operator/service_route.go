@@ -1,21 +1,30 @@
 package operator
 
 import (
+	"bufio"
 	"context"
 	"io"
 	"net/netip"
+	"slices"
 	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/yanet-platform/yanet2/common/go/grpccompress"
 	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
 	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
 	"github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
 )
 
+// DefaultFlushWaitTimeout bounds the wait of FlushRoutes for the reconcile
+// pass committing the flush, unless the caller sets a deadline.
+const DefaultFlushWaitTimeout = 30 * time.Second
+
 // RouteService implements the operator-owned RouteService surface.
 //
 // Mutation RPCs update the RIB held in this process and wake the
@@ -27,12 +36,30 @@ type RouteService struct {
 	ribs       *RIBStore
 	neighTable *neigh.NeighTable
 
-	ribTTL            time.Duration
-	quitCh            chan bool
-	onChanged         func()
-	onRIBSessionStart func(name string, sessionID uint64)
-	onRIBUpdate       func(n int)
-	onRIBSessionEnd   func(name string, sessionID uint64)
+	ribTTL               time.Duration
+	quitCh               chan bool
+	onChanged            func()
+	onAccepted           func()
+	onEmergency          func()
+	onRIBSessionStart    func(name string, sessionID uint64)
+	onRIBUpdate          func(n int)
+	onRIBDuplicate       func(n int)
+	onRIBLooped          func(n int)
+	onRIBNexthopRejected func(name string, n int)
+	onRIBEndOfRIB        func(name string, sessionID uint64)
+	onRIBSessionEnd      func(name string, sessionID uint64)
+	onMonitorSubscribed  func(name string)
+	faults               *FaultInjector
+	commits              *CommitTracker
+	mirror               *FeedMirror
+	originCommunity      *rib.LargeCommunity
+	strictNexthop        *StrictNexthop
+	compression          grpccompress.Compression
+	prefixACL            *PrefixACL
+	tenants              *Tenants
+	massWithdraw         MassWithdrawConfig
+	feedHeartbeat        operatorpb.Heartbeat
+	snapshotDir          string
 
 	log *zap.Logger
 }
@@ -48,16 +75,39 @@ func NewRouteService(
 		o(opts)
 	}
 
+	onEmergency := opts.OnEmergency
+	if onEmergency == nil {
+		onEmergency = opts.OnChanged
+	}
+
 	return &RouteService{
-		ribs:              opts.RIBs,
-		neighTable:        neighTable,
-		ribTTL:            opts.RIBTTL,
-		quitCh:            make(chan bool),
-		onChanged:         opts.OnChanged,
-		onRIBSessionStart: opts.OnRIBSessionStart,
-		onRIBUpdate:       opts.OnRIBUpdate,
-		onRIBSessionEnd:   opts.OnRIBSessionEnd,
-		log:               opts.Log,
+		ribs:                 opts.RIBs,
+		neighTable:           neighTable,
+		ribTTL:               opts.RIBTTL,
+		quitCh:               make(chan bool),
+		onChanged:            opts.OnChanged,
+		onAccepted:           opts.OnAccepted,
+		onEmergency:          onEmergency,
+		onRIBSessionStart:    opts.OnRIBSessionStart,
+		onRIBUpdate:          opts.OnRIBUpdate,
+		onRIBDuplicate:       opts.OnRIBDuplicate,
+		onRIBLooped:          opts.OnRIBLooped,
+		onRIBNexthopRejected: opts.OnRIBNexthopRejected,
+		onRIBEndOfRIB:        opts.OnRIBEndOfRIB,
+		onRIBSessionEnd:      opts.OnRIBSessionEnd,
+		onMonitorSubscribed:  opts.OnMonitorSubscribed,
+		faults:               opts.Faults,
+		commits:              opts.Commits,
+		mirror:               opts.Mirror,
+		originCommunity:      opts.OriginCommunity,
+		strictNexthop:        opts.StrictNexthop,
+		compression:          opts.Compression,
+		prefixACL:            opts.PrefixACL,
+		tenants:              opts.Tenants,
+		massWithdraw:         opts.MassWithdraw,
+		feedHeartbeat:        opts.FeedHeartbeat,
+		snapshotDir:          opts.SnapshotDir,
+		log:                  opts.Log,
 	}
 }
 
@@ -74,13 +124,18 @@ func (m *RouteService) Configs() []string {
 }
 
 // ListConfigs returns the names of all RIB configs known to the
-// operator.
+// operator, only those of the tenant of the caller with tenancy enabled.
 func (m *RouteService) ListConfigs(
 	ctx context.Context,
 	req *operatorpb.ListConfigsRequest,
 ) (*operatorpb.ListConfigsResponse, error) {
+	tenant, err := m.tenants.identify(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &operatorpb.ListConfigsResponse{
-		Configs: m.Configs(),
+		Configs: tenant.visibleConfigs(m.Configs()),
 	}, nil
 }
 
@@ -97,6 +152,11 @@ func (m *RouteService) ShowRoutes(
 	if name == "" {
 		return nil, status.Error(codes.InvalidArgument, "module config name is required")
 	}
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	m.compression.SetSendCompressor(ctx)
 
 	holder, ok := m.getRib(name)
 	if !ok {
@@ -121,6 +181,12 @@ func (m *RouteService) ShowRoutes(
 
 			bestMask := routesList.BestPerSourceMask()
 			for idx, r := range routesList.Routes {
+				if req.GetTag() != "" && !slices.Contains(r.Tags, req.GetTag()) {
+					continue
+				}
+				if !tenant.visible(&r) {
+					continue
+				}
 				response.Routes = append(response.Routes, operatorpb.FromRIBRoute(&r, bestMask[idx]))
 			}
 		}
@@ -138,6 +204,11 @@ func (m *RouteService) LookupRoute(
 		return nil, status.Error(codes.InvalidArgument, "module config name is required")
 	}
 
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
 	addr, err := req.GetIpAddr().ToAddr()
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid ip_addr (bytes=%x): %v", req.GetIpAddr().GetAddr(), err)
@@ -152,14 +223,24 @@ func (m *RouteService) LookupRoute(
 	if !ok {
 		return &operatorpb.LookupRouteResponse{}, nil
 	}
+	// The longest match of a tenant narrowed to its tag is looked up among
+	// all the routes, so a prefix of another tenant hides the tenant's less
+	// specific ones.
+	if !slices.ContainsFunc(routes.Routes, func(r rib.Route) bool { return tenant.visible(&r) }) {
+		return &operatorpb.LookupRouteResponse{}, nil
+	}
 
 	response := &operatorpb.LookupRouteResponse{
 		Prefix: prefix.String(),
 		Routes: make([]*operatorpb.Route, 0, len(routes.Routes)),
+		Tags:   routes.Tags,
 	}
 
 	bestMask := routes.BestPerSourceMask()
 	for idx, r := range routes.Routes {
+		if !tenant.visible(&r) {
+			continue
+		}
 		response.Routes = append(response.Routes, operatorpb.FromRIBRoute(&r, bestMask[idx]))
 	}
 
@@ -179,6 +260,13 @@ func (m *RouteService) InsertRoute(
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to parse prefix %q: %v", req.GetPrefix(), err)
 	}
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.prefixACL.Authorize(ctx, prefix); err != nil {
+		return nil, err
+	}
 
 	addrs := req.GetNexthopAddrs()
 	if len(addrs) == 0 {
@@ -194,6 +282,19 @@ func (m *RouteService) InsertRoute(
 		nexthops = append(nexthops, nexthop)
 	}
 
+	weights := req.GetNexthopWeights()
+	if len(weights) != 0 && len(weights) != len(nexthops) {
+		return nil, status.Errorf(codes.InvalidArgument, "got %d nexthop weights for %d nexthops", len(weights), len(nexthops))
+	}
+	if slices.Contains(weights, 0) {
+		return nil, status.Error(codes.InvalidArgument, "nexthop weights must be positive")
+	}
+
+	tags, err := rib.NormalizeTags(tenant.routeTags(req.GetTags()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	sourceID := req.RouteSourceID()
 
 	// Non-static sources use peer identity to distinguish routes: the unary
@@ -204,18 +305,31 @@ func (m *RouteService) InsertRoute(
 		return nil, status.Error(codes.InvalidArgument, "multiple nexthops are only supported for static routes")
 	}
 
+	// Static routes are originated by this node, whatever API they come
+	// from.
+	var communities []rib.LargeCommunity
+	if sourceID == rib.RouteSourceStatic {
+		communities = m.localCommunities()
+	}
+
 	holder := m.getOrCreateRib(name)
 
-	for _, nexthopAddr := range nexthops {
-		if err := holder.AddUnicastRoute(prefix, nexthopAddr, sourceID); err != nil {
+	for idx, nexthopAddr := range nexthops {
+		weight := uint32(0)
+		if len(weights) != 0 {
+			weight = weights[idx]
+		}
+		if err := holder.AddWeightedUnicastRoute(prefix, nexthopAddr, weight, tags, sourceID, communities...); err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to add unicast route: %v", err)
 		}
 	}
+	m.onAccepted()
 
 	// Wake the reconcile loop only when the caller explicitly asks for a
-	// flush; otherwise the RIB mutation is buffered until a later flush.
+	// flush; otherwise the RIB mutation is buffered until a later flush or
+	// the flush deadline.
 	if req.GetDoFlush() {
-		m.onChanged()
+		m.flush(req.GetEmergency())
 	}
 
 	return &operatorpb.InsertRouteResponse{}, nil
@@ -234,6 +348,13 @@ func (m *RouteService) DeleteRoute(
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to parse prefix: %v", err)
 	}
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.prefixACL.Authorize(ctx, prefix); err != nil {
+		return nil, err
+	}
 
 	addrs := req.GetNexthopAddrs()
 	if len(addrs) == 0 {
@@ -254,22 +375,283 @@ func (m *RouteService) DeleteRoute(
 	if !ok {
 		return &operatorpb.DeleteRouteResponse{}, nil
 	}
+	if err := tenant.authorizeRoutes(holder, prefix, nexthops, sourceID); err != nil {
+		return nil, err
+	}
 
 	for _, nexthopAddr := range nexthops {
 		if err := holder.RemoveUnicastRoute(prefix, nexthopAddr, sourceID); err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to remove unicast route: %v", err)
 		}
 	}
+	m.onAccepted()
 
 	// Wake the reconcile loop only when the caller explicitly asks for a
-	// flush; otherwise the RIB mutation is buffered until a later flush.
+	// flush; otherwise the RIB mutation is buffered until a later flush or
+	// the flush deadline.
 	if req.GetDoFlush() {
-		m.onChanged()
+		m.flush(req.GetEmergency())
 	}
 
 	return &operatorpb.DeleteRouteResponse{}, nil
 }
 
+// DeleteRoutesByFilter deletes every route of the named RIB matching the
+// filter and returns the deleted routes.
+//
+// An empty filter is rejected so that a malformed request cannot wipe the
+// whole RIB; use dry_run to review the selection first. Routes of dynamic
+// sources come back when re-announced by their peers.
+func (m *RouteService) DeleteRoutesByFilter(
+	ctx context.Context,
+	req *operatorpb.DeleteRoutesByFilterRequest,
+) (*operatorpb.DeleteRoutesByFilterResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	filter, err := req.GetFilter().ToRIBFilter()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+	}
+	if filter.IsEmpty() {
+		return nil, status.Error(codes.InvalidArgument, "at least one filter criterion is required")
+	}
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.narrow(&filter); err != nil {
+		return nil, err
+	}
+	if err := m.prefixACL.AuthorizeFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		return &operatorpb.DeleteRoutesByFilterResponse{}, nil
+	}
+
+	var routes []rib.Route
+	if req.GetDryRun() {
+		routes = holder.MatchRoutes(filter)
+	} else {
+		routes = holder.RemoveRoutes(filter)
+	}
+
+	response := &operatorpb.DeleteRoutesByFilterResponse{
+		Routes: make([]*operatorpb.Route, 0, len(routes)),
+	}
+	for idx := range routes {
+		response.Routes = append(response.Routes, operatorpb.FromRIBRoute(&routes[idx], false))
+	}
+
+	if !req.GetDryRun() && len(routes) > 0 {
+		m.onAccepted()
+	}
+
+	// Wake the reconcile loop only when the caller explicitly asks for a
+	// flush; otherwise the RIB mutation is buffered until a later flush or
+	// the flush deadline.
+	if req.GetDoFlush() && !req.GetDryRun() && len(routes) > 0 {
+		m.flush(req.GetEmergency())
+	}
+
+	return response, nil
+}
+
+// ListRouteTags lists the tags of the routes of the named RIB with the
+// size of their groups.
+func (m *RouteService) ListRouteTags(
+	ctx context.Context,
+	req *operatorpb.ListRouteTagsRequest,
+) (*operatorpb.ListRouteTagsResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		return &operatorpb.ListRouteTagsResponse{}, nil
+	}
+
+	groups := holder.RouteGroups()
+	response := &operatorpb.ListRouteTagsResponse{
+		Tags: make([]*operatorpb.RouteTag, 0, len(groups)),
+	}
+	for _, group := range groups {
+		if !tenant.visibleTag(group.Tag) {
+			continue
+		}
+		response.Tags = append(response.Tags, &operatorpb.RouteTag{
+			Tag:      group.Tag,
+			Routes:   uint64(group.Routes),
+			Prefixes: uint64(group.Prefixes),
+		})
+	}
+
+	return response, nil
+}
+
+// SetRoutesPrefByFilter sets the local preference of every route of the
+// named RIB matching the filter and returns the matched routes.
+//
+// The routes are changed under a single RIB lock, so no reconcile pass
+// sees a part of them changed. Like DeleteRoutesByFilter, an empty filter
+// is rejected.
+func (m *RouteService) SetRoutesPrefByFilter(
+	ctx context.Context,
+	req *operatorpb.SetRoutesPrefByFilterRequest,
+) (*operatorpb.SetRoutesPrefByFilterResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	filter, err := req.GetFilter().ToRIBFilter()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+	}
+	if filter.IsEmpty() {
+		return nil, status.Error(codes.InvalidArgument, "at least one filter criterion is required")
+	}
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.narrow(&filter); err != nil {
+		return nil, err
+	}
+	if err := m.prefixACL.AuthorizeFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		return &operatorpb.SetRoutesPrefByFilterResponse{}, nil
+	}
+
+	var routes []rib.Route
+	if req.GetDryRun() {
+		routes = holder.MatchRoutes(filter)
+	} else {
+		routes = holder.SetPref(filter, req.GetPref())
+	}
+
+	response := &operatorpb.SetRoutesPrefByFilterResponse{
+		Routes: make([]*operatorpb.Route, 0, len(routes)),
+	}
+	for idx := range routes {
+		response.Routes = append(response.Routes, operatorpb.FromRIBRoute(&routes[idx], false))
+	}
+
+	if !req.GetDryRun() && len(routes) > 0 {
+		m.onAccepted()
+	}
+	if req.GetDoFlush() && !req.GetDryRun() && len(routes) > 0 {
+		m.flush(req.GetEmergency())
+	}
+
+	return response, nil
+}
+
+// Simulate predicts how the best routes of the named RIB would change if
+// the requested routes were withdrawn and announced, leaving the RIB
+// untouched.
+//
+// A RIB that does not exist yet is simulated as empty.
+func (m *RouteService) Simulate(
+	ctx context.Context,
+	req *operatorpb.SimulateRequest,
+) (*operatorpb.SimulateResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+
+	tenant, err := m.tenants.authorize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := rib.Simulation{}
+	for _, f := range req.GetWithdrawFilters() {
+		filter, err := f.ToRIBFilter()
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+		}
+		if filter.IsEmpty() {
+			return nil, status.Error(codes.InvalidArgument, "at least one filter criterion is required")
+		}
+		if err := tenant.narrow(&filter); err != nil {
+			return nil, err
+		}
+		sim.Withdraw = append(sim.Withdraw, filter)
+	}
+	for _, r := range req.GetWithdraw() {
+		route, err := operatorpb.ToRIBRoute(r, true)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid withdrawn route: %v", err)
+		}
+		sim.Routes = append(sim.Routes, *route)
+	}
+	for _, r := range req.GetAnnounce() {
+		route, err := operatorpb.ToRIBRoute(r, false)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid announced route: %v", err)
+		}
+		if route.Tags, err = rib.NormalizeTags(tenant.routeTags(route.Tags)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid announced route: %v", err)
+		}
+		sim.Routes = append(sim.Routes, *route)
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		holder = rib.NewRIB(m.log)
+	}
+	result := holder.Simulate(sim)
+
+	response := &operatorpb.SimulateResponse{
+		Changes:         make([]*operatorpb.BestPathChange, 0, len(result.Changes)),
+		WithdrawnRoutes: uint64(result.Withdrawn),
+	}
+	for _, change := range result.Changes {
+		// A tenant narrowed to its tag sees the changes of its own best
+		// routes only.
+		before := slices.DeleteFunc(change.Before, func(r rib.Route) bool { return !tenant.visible(&r) })
+		after := slices.DeleteFunc(change.After, func(r rib.Route) bool { return !tenant.visible(&r) })
+		if len(before) == 0 && len(after) == 0 {
+			continue
+		}
+		response.Changes = append(response.Changes, &operatorpb.BestPathChange{
+			Prefix: change.Prefix.String(),
+			Before: bestRoutesProto(before),
+			After:  bestRoutesProto(after),
+		})
+	}
+
+	return response, nil
+}
+
+func bestRoutesProto(routes []rib.Route) []*operatorpb.Route {
+	out := make([]*operatorpb.Route, 0, len(routes))
+	for idx := range routes {
+		out = append(out, operatorpb.FromRIBRoute(&routes[idx], true))
+	}
+	return out
+}
+
+// FlushRoutes requests a reconcile pass and waits for it to be attempted
+// on every gateway, reporting the commit of the module config on each.
 func (m *RouteService) FlushRoutes(
 	ctx context.Context,
 	req *operatorpb.FlushRoutesRequest,
@@ -278,20 +660,108 @@ func (m *RouteService) FlushRoutes(
 	if name == "" {
 		return nil, status.Error(codes.InvalidArgument, "module config name is required")
 	}
+	if _, err := m.tenants.authorize(ctx, name); err != nil {
+		return nil, err
+	}
 	if _, ok := m.getRib(name); !ok {
 		return &operatorpb.FlushRoutesResponse{}, nil
 	}
 
-	m.onChanged()
+	if err := m.faults.DelayFlushRoutes(ctx); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	if m.commits == nil {
+		m.flush(req.GetEmergency())
+		return &operatorpb.FlushRoutesResponse{}, nil
+	}
+
+	pass := m.commits.Pass() + 1
+	m.flush(req.GetEmergency())
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultFlushWaitTimeout)
+		defer cancel()
+	}
+	commit, err := m.commits.Wait(ctx, pass)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	return flushRoutesResponse(name, commit), nil
+}
 
-	return &operatorpb.FlushRoutesResponse{}, nil
+// flushRoutesResponse reports the commit of the named module config in a
+// reconcile pass.
+func flushRoutesResponse(name string, commit Commit) *operatorpb.FlushRoutesResponse {
+	response := &operatorpb.FlushRoutesResponse{Pass: commit.Pass}
+	for _, gw := range commit.Gateways {
+		result := &operatorpb.FlushResult{Gateway: gw.Gateway}
+		if gw.Err != nil {
+			result.Error = gw.Err.Error()
+		}
+
+		for _, push := range gw.FIBs {
+			if push.Module != name {
+				continue
+			}
+			result.Entries = uint64(push.Entries)
+			result.Generation = push.Generation
+			result.Delta = push.Delta
+			result.Changed = uint64(push.Changed)
+			result.Duration = durationpb.New(push.Duration)
+			if push.Err != nil {
+				result.Error = push.Err.Error()
+			}
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	return response
+}
+
+// flush wakes the reconcile loop for a flush requested through the API.
+//
+// An emergency flush skips the batching and the commit rate limit.
+func (m *RouteService) flush(emergency bool) {
+	if emergency {
+		m.log.Info("requested emergency flush")
+		m.onEmergency()
+		return
+	}
+	m.onChanged()
 }
 
 // FeedRIB receives a stream of route updates and applies them to the
 // matching RIB. Session semantics mirror the legacy route-module
 // implementation: a new stream supersedes any prior session for the
-// same RIB and stale routes are cleaned up after RIBTTL.
-func (m *RouteService) FeedRIB(stream operatorpb.RouteService_FeedRIBServer) error {
+// same RIB and the routes it did not re-announce are handled by the
+// teardown policy of the sender, by default cleaned up after RIBTTL.
+//
+// An update carrying end_of_rib marks the end of the sender's initial dump
+// and records the session as converged.
+//
+// Updates that do not change the stored route, as sent by BIRD when it
+// re-dumps after a route refresh, are suppressed: they neither count as RIB
+// updates nor make the next flush event schedule a reconcile pass.
+//
+// Consecutive withdrawals are applied to the RIB in batches. Once the
+// withdrawals received since the previous flush event reach the mass
+// withdraw threshold, as when a BGP peer goes down, the next flush event
+// commits the FIB as an emergency one.
+//
+// Heartbeats of the sender are answered and never reach the RIB, see
+// operatorpb.FeedRIBServerStream. A sender silent for the dead interval
+// ends the session as a broken stream would.
+//
+// With a mirror configured, every received update is also copied to the
+// secondary RouteService, see FeedMirror.
+func (m *RouteService) FeedRIB(rawStream operatorpb.RouteService_FeedRIBServer) error {
+	stream := operatorpb.NewFeedRIBServerStream(rawStream, m.feedHeartbeat)
+	defer stream.Close()
+
 	var (
 		update     *operatorpb.Update
 		name       string
@@ -299,11 +769,19 @@ func (m *RouteService) FeedRIB(stream operatorpb.RouteService_FeedRIBServer) err
 		ribRef     *rib.RIB
 		sessionID  uint64
 		terminated *atomic.Bool
+		mirror     *MirrorSession
+		tenant     *tenant
+		// dirty reports whether the RIB changed since the last flush event.
+		dirty bool
+		// teardown is the policy of the last received update.
+		teardown operatorpb.TeardownPolicy
+		// withdrawals are the withdrawals not yet applied to the RIB.
+		withdrawals = newWithdrawBatch(m.massWithdraw.BatchSize)
 	)
 	for {
 		update, err = stream.Recv()
 		if err == io.EOF {
-			err = stream.SendAndClose(&operatorpb.UpdateSummary{})
+			err = stream.Send(&operatorpb.UpdateSummary{})
 			break
 		}
 		if err != nil {
@@ -316,6 +794,11 @@ func (m *RouteService) FeedRIB(stream operatorpb.RouteService_FeedRIBServer) err
 				err = status.Error(codes.InvalidArgument, "module config name is required")
 				break
 			}
+			if m.tenants != nil {
+				if tenant, err = m.tenants.authorize(rawStream.Context(), name); err != nil {
+					break
+				}
+			}
 			ribRef = m.getOrCreateRib(name)
 			sessionID, terminated = ribRef.NewSession()
 			m.log.Info("started FeedRIB session",
@@ -323,26 +806,76 @@ func (m *RouteService) FeedRIB(stream operatorpb.RouteService_FeedRIBServer) err
 				zap.String("name", name),
 			)
 			m.onRIBSessionStart(name, sessionID)
+			mirror = m.mirror.Start(name, sessionID)
 		}
+		mirror.Send(update)
+		teardown = update.GetTeardown()
 
 		if terminated.Load() {
 			m.log.Warn("FeedRIB session terminated by a newer session",
 				zap.Uint64("session_id", sessionID),
 				zap.String("name", name),
 			)
-			err = stream.SendAndClose(&operatorpb.UpdateSummary{})
+			err = stream.Send(&operatorpb.UpdateSummary{})
 			break
 		}
+		if m.faults.DropFeedRIB() {
+			m.log.Warn("dropped FeedRIB session by injected fault",
+				zap.Uint64("session_id", sessionID),
+				zap.String("name", name),
+			)
+			err = status.Error(codes.Unavailable, "FeedRIB stream dropped by injected fault")
+			break
+		}
+		if update.GetEndOfRib() {
+			if m.applyWithdrawals(ribRef, withdrawals) {
+				dirty = true
+			}
+			if !ribRef.MarkEndOfRIB(sessionID) {
+				continue
+			}
+			m.log.Info("received end-of-RIB marker",
+				zap.Uint64("session_id", sessionID),
+				zap.String("name", name),
+			)
+			m.onRIBEndOfRIB(name, sessionID)
+			m.onChanged()
+			continue
+		}
 		if update.GetRoute() == nil {
+			if m.applyWithdrawals(ribRef, withdrawals) {
+				dirty = true
+			}
+			withdrawn := withdrawals.Flushed()
+			if !dirty {
+				m.log.Debug("skipped FeedRIB flush event without changes",
+					zap.Uint64("session_id", sessionID),
+					zap.String("name", name),
+				)
+				continue
+			}
 			m.log.Info("flushed routes due to FeedRIB flush event",
 				zap.Uint64("session_id", sessionID),
 				zap.String("name", name),
 			)
+			dirty = false
+			if m.isMassWithdrawal(withdrawn) {
+				m.log.Warn("detected mass withdrawal; flushing routes at once",
+					zap.Uint64("session_id", sessionID),
+					zap.String("name", name),
+					zap.Int("withdrawn", withdrawn),
+				)
+				m.onEmergency()
+				continue
+			}
 			m.onChanged()
 			continue
 		}
 
 		route, convertErr := operatorpb.ToRIBRoute(update.GetRoute(), update.GetIsDelete())
+		if convertErr == nil {
+			route.Tags, convertErr = rib.NormalizeTags(tenant.routeTags(route.Tags))
+		}
 		if convertErr != nil {
 			m.log.Error("failed to convert proto route to RIB route",
 				zap.Uint64("session_id", sessionID),
@@ -350,25 +883,316 @@ func (m *RouteService) FeedRIB(stream operatorpb.RouteService_FeedRIBServer) err
 			)
 			continue
 		}
+		if m.isLooped(route) {
+			m.log.Debug("dropped FeedRIB route carrying the origin community",
+				zap.Uint64("session_id", sessionID),
+				zap.Stringer("prefix", route.Prefix),
+				zap.Stringer("nexthop", route.NextHop),
+			)
+			m.onRIBLooped(1)
+			continue
+		}
+		if !route.ToRemove && !m.strictNexthop.Allows(name, route.NextHop) {
+			m.log.Debug("dropped FeedRIB route via a nexthop outside the peer networks",
+				zap.Uint64("session_id", sessionID),
+				zap.String("name", name),
+				zap.Stringer("prefix", route.Prefix),
+				zap.Stringer("nexthop", route.NextHop),
+			)
+			m.onRIBNexthopRejected(name, 1)
+			continue
+		}
 		route.SessionID = sessionID
-		ribRef.Update(*route)
+		if route.ToRemove {
+			if withdrawals.Add(*route) && m.applyWithdrawals(ribRef, withdrawals) {
+				dirty = true
+			}
+			continue
+		}
+		if m.applyWithdrawals(ribRef, withdrawals) {
+			dirty = true
+		}
+		if ribRef.Update(*route) == 0 {
+			m.onRIBDuplicate(1)
+			continue
+		}
+		dirty = true
+		m.onAccepted()
 		m.onRIBUpdate(1)
 	}
 
 	if ribRef != nil {
-		m.log.Info("FeedRIB session ended; scheduling cleanup",
-			zap.Uint64("session_id", sessionID),
-			zap.String("name", name),
-			zap.Duration("ttl", m.ribTTL),
-		)
+		m.applyWithdrawals(ribRef, withdrawals)
 		m.onRIBSessionEnd(name, sessionID)
-		go ribRef.CleanupTask(sessionID, m.quitCh, m.ribTTL)
-		m.onChanged()
+		mirror.Close()
+		m.teardownSession(ribRef, name, sessionID, teardown)
+		if withdrawn := withdrawals.Flushed(); m.isMassWithdrawal(withdrawn) {
+			m.log.Warn("detected mass withdrawal before FeedRIB session end; flushing routes at once",
+				zap.Uint64("session_id", sessionID),
+				zap.String("name", name),
+				zap.Int("withdrawn", withdrawn),
+			)
+			m.onEmergency()
+		} else {
+			m.onChanged()
+		}
 	}
 
 	return err
 }
 
+// applyWithdrawals applies the pending withdrawals of a FeedRIB session to
+// its RIB, reporting whether they changed it.
+func (m *RouteService) applyWithdrawals(ribRef *rib.RIB, withdrawals *withdrawBatch) bool {
+	count, changed := withdrawals.Apply(ribRef)
+	if count > changed {
+		m.onRIBDuplicate(count - changed)
+	}
+	if changed == 0 {
+		return false
+	}
+	m.onAccepted()
+	m.onRIBUpdate(changed)
+	return true
+}
+
+// isMassWithdrawal reports whether the number of withdrawals received
+// between two flush events reaches the mass withdraw threshold.
+func (m *RouteService) isMassWithdrawal(withdrawn int) bool {
+	return m.massWithdraw.Threshold > 0 && withdrawn >= m.massWithdraw.Threshold
+}
+
+// teardownSession applies the teardown policy to the routes of an ended
+// FeedRIB session.
+func (m *RouteService) teardownSession(
+	ribRef *rib.RIB,
+	name string,
+	sessionID uint64,
+	teardown operatorpb.TeardownPolicy,
+) {
+	log := m.log.With(
+		zap.Uint64("session_id", sessionID),
+		zap.String("name", name),
+		zap.Stringer("teardown", teardown),
+	)
+
+	switch teardown {
+	case operatorpb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW:
+		log.Info("FeedRIB session ended; withdrawing its routes")
+		ribRef.CleanupTask(sessionID, m.quitCh, 0)
+	case operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP:
+		log.Info("FeedRIB session ended; keeping its routes")
+	default:
+		log.Info("FeedRIB session ended; scheduling cleanup", zap.Duration("ttl", m.ribTTL))
+		go ribRef.CleanupTask(sessionID, m.quitCh, m.ribTTL)
+	}
+}
+
+// localCommunities returns the communities locally originated routes are
+// tagged with.
+func (m *RouteService) localCommunities() []rib.LargeCommunity {
+	if m.originCommunity == nil {
+		return nil
+	}
+	return []rib.LargeCommunity{*m.originCommunity}
+}
+
+// isLooped reports whether a FeedRIB route is a locally originated one
+// re-imported through BIRD.
+//
+// Such routes would outlive the local route they were exported from: a
+// withdrawn static route would stay installed until BIRD withdrew its copy
+// as well.
+func (m *RouteService) isLooped(route *rib.Route) bool {
+	return m.originCommunity != nil && slices.Contains(route.LargeCommunities, *m.originCommunity)
+}
+
+// MonitorRoutes streams changes of the named RIB until the client goes
+// away or the service is closed.
+//
+// The RIB must exist: monitoring never creates one, so that a mistyped
+// name does not make the reconciler push an empty FIB for it.
+//
+// Only changes applied after the call are reported; the current content is
+// available through ShowRoutes.
+func (m *RouteService) MonitorRoutes(
+	req *operatorpb.MonitorRoutesRequest,
+	stream operatorpb.RouteService_MonitorRoutesServer,
+) error {
+	name := req.GetName()
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	var filter netip.Prefix
+	if req.GetPrefix() != "" {
+		prefix, err := netip.ParsePrefix(req.GetPrefix())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to parse prefix %q: %v", req.GetPrefix(), err)
+		}
+		filter = prefix.Masked()
+	}
+	source := req.GetSource()
+	tenant, err := m.tenants.authorize(stream.Context(), name)
+	if err != nil {
+		return err
+	}
+	holder, ok := m.getRib(name)
+	if !ok {
+		return status.Errorf(codes.NotFound, "RIB %q not found", name)
+	}
+	m.compression.SetSendCompressor(stream.Context())
+
+	events, cancel := holder.Watch()
+	defer cancel()
+	m.onMonitorSubscribed(name)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-m.quitCh:
+			return status.Error(codes.Unavailable, "route service is shutting down")
+		case event := <-events:
+			if source != operatorpb.RouteSourceID_ROUTE_SOURCE_ID_UNKNOWN &&
+				operatorpb.RouteSourceID(event.Route.SourceID) != source {
+				continue
+			}
+			if filter.IsValid() && !containsPrefix(filter, event.Route.Prefix) {
+				continue
+			}
+			if !tenant.visible(&event.Route) {
+				continue
+			}
+			if err := stream.Send(operatorpb.FromRIBRouteEvent(&event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// snapshotChunkSize is the size of the chunks DownloadSnapshot streams.
+const snapshotChunkSize = 64 << 10
+
+// DownloadSnapshot streams a snapshot of the named RIB, as written by the
+// ribsnapshot package, in chunks of snapshotChunkSize.
+//
+// The snapshot holds the routes the caller is allowed to see, taken at
+// once so that it is consistent. A RIB that does not exist is reported as
+// not found.
+func (m *RouteService) DownloadSnapshot(
+	req *operatorpb.DownloadSnapshotRequest,
+	stream operatorpb.RouteService_DownloadSnapshotServer,
+) error {
+	name := req.GetName()
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	tenant, err := m.tenants.authorize(stream.Context(), name)
+	if err != nil {
+		return err
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		return status.Errorf(codes.NotFound, "RIB %q not found", name)
+	}
+	chunks := bufio.NewWriterSize(snapshotChunkWriter{stream: stream}, snapshotChunkSize)
+	if _, err := writeRIBSnapshot(chunks, name, holder, tenant); err != nil {
+		return err
+	}
+
+	return chunks.Flush()
+}
+
+// ExportRIB writes a snapshot of the RIB of a module config to the
+// snapshot directory.
+//
+// Snapshot files are shared by every caller, so unscoped callers only may
+// write them.
+func (m *RouteService) ExportRIB(
+	ctx context.Context,
+	req *operatorpb.ExportRIBRequest,
+) (*operatorpb.ExportRIBResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	path, err := m.snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.authorizeSnapshots(ctx, name); err != nil {
+		return nil, err
+	}
+
+	holder, ok := m.getRib(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "RIB %q not found", name)
+	}
+	count, err := exportRIBSnapshot(path, name, holder)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to export RIB %q: %v", name, err)
+	}
+	m.log.Info("exported RIB snapshot",
+		zap.String("name", name),
+		zap.String("path", path),
+		zap.Uint64("routes", count),
+	)
+
+	return &operatorpb.ExportRIBResponse{Path: path, Routes: count}, nil
+}
+
+// ImportRIB restores the BIRD routes of a module config from its snapshot
+// in the snapshot directory.
+func (m *RouteService) ImportRIB(
+	ctx context.Context,
+	req *operatorpb.ImportRIBRequest,
+) (*operatorpb.ImportRIBResponse, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "module config name is required")
+	}
+	path, err := m.snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.authorizeSnapshots(ctx, name); err != nil {
+		return nil, err
+	}
+
+	header, count, err := m.importRIBSnapshot(path, name)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetDoFlush() {
+		m.flush(false)
+	}
+
+	response := &operatorpb.ImportRIBResponse{Routes: count}
+	if !header.CreatedAt.IsZero() {
+		response.CreatedAt = timestamppb.New(header.CreatedAt)
+	}
+	return response, nil
+}
+
+// snapshotChunkWriter sends the data written to it as snapshot chunks.
+type snapshotChunkWriter struct {
+	stream operatorpb.RouteService_DownloadSnapshotServer
+}
+
+func (m snapshotChunkWriter) Write(p []byte) (int, error) {
+	if err := m.stream.Send(&operatorpb.SnapshotChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// containsPrefix reports whether prefix equals outer or is one of its
+// more-specifics.
+func containsPrefix(outer netip.Prefix, prefix netip.Prefix) bool {
+	return outer.Bits() <= prefix.Bits() && outer.Contains(prefix.Addr())
+}
+
 func (m *RouteService) getRib(name string) (*rib.RIB, bool) {
 	return m.ribs.Get(name)
 }
@@ -1,11 +1,18 @@
 package operator
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -13,6 +20,7 @@ import (
 	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
 	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
 	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/ribsnapshot"
 )
 
 // TestShowRoutes_StaticECMP_BothBest verifies that two static ECMP nexthops
@@ -102,3 +110,896 @@ func TestInsertRoute_NonStaticMultipleNexthops_InvalidArgument(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, codes.InvalidArgument, st.Code())
 }
+
+// TestInsertRoute_NexthopWeights verifies that the nexthop weights are
+// stored on the routes, replaced by a later insert, and rejected unless
+// there is a positive one per nexthop.
+func TestInsertRoute_NexthopWeights(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+
+	nh1 := commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.168.1.1"))
+	nh2 := commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.168.1.2"))
+	insert := func(weights ...uint32) error {
+		_, err := svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+			Name:           "route0",
+			Prefix:         "10.0.0.0/24",
+			NexthopAddrs:   []*commonpb.IPAddress{nh1, nh2},
+			SourceId:       operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+			NexthopWeights: weights,
+		})
+		return err
+	}
+	weights := func() map[string]uint32 {
+		resp, err := svc.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0"})
+		require.NoError(t, err)
+
+		weights := map[string]uint32{}
+		for _, r := range resp.Routes {
+			addr, err := r.GetNextHop().ToAddr()
+			require.NoError(t, err)
+			weights[addr.String()] = r.GetWeight()
+		}
+		return weights
+	}
+
+	require.NoError(t, insert(3, 1))
+	require.Equal(t, map[string]uint32{"192.168.1.1": 3, "192.168.1.2": 1}, weights())
+
+	require.NoError(t, insert(1, 2))
+	require.Equal(t, map[string]uint32{"192.168.1.1": 1, "192.168.1.2": 2}, weights())
+
+	for _, invalid := range [][]uint32{{1}, {1, 2, 3}, {0, 1}} {
+		err := insert(invalid...)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "weights %v", invalid)
+	}
+	require.Equal(t, map[string]uint32{"192.168.1.1": 1, "192.168.1.2": 2}, weights())
+}
+
+// TestInsertRoute_EmergencyFlush verifies that an emergency flush wakes
+// the reconcile loop through the emergency callback only.
+func TestInsertRoute_EmergencyFlush(t *testing.T) {
+	wakes, emergencies := 0, 0
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceOnChanged(func() { wakes++ }),
+		WithRouteServiceOnEmergency(func() { emergencies++ }),
+	)
+	defer svc.Close()
+
+	req := &operatorpb.InsertRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.0.0/24",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.168.1.1"))},
+		DoFlush:      true,
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	}
+	_, err := svc.InsertRoute(t.Context(), req)
+	require.NoError(t, err)
+	require.Equal(t, 1, wakes)
+	require.Equal(t, 0, emergencies)
+
+	req.Emergency = true
+	_, err = svc.InsertRoute(t.Context(), req)
+	require.NoError(t, err)
+	require.Equal(t, 1, wakes)
+	require.Equal(t, 1, emergencies)
+
+	_, err = svc.FlushRoutes(t.Context(), &operatorpb.FlushRoutesRequest{Name: "route0", Emergency: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, wakes)
+	require.Equal(t, 2, emergencies)
+}
+
+// TestFeedRIB_DuplicateSuppression verifies that a re-dump carrying only
+// unchanged routes neither counts as RIB updates nor wakes the reconcile
+// loop on its flush event.
+func TestFeedRIB_DuplicateSuppression(t *testing.T) {
+	route := &operatorpb.Route{
+		Prefix:  "10.0.0.0/24",
+		NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+		Peer:    commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+		Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+	}
+
+	updates, duplicates, wakes := 0, 0, 0
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceOnChanged(func() { wakes++ }),
+		WithRouteServiceOnRIBUpdate(func(n int) { updates += n }),
+		WithRouteServiceOnRIBDuplicate(func(n int) { duplicates += n }),
+	)
+	defer svc.Close()
+
+	dump := func() *fakeFeedRIBStream {
+		return &fakeFeedRIBStream{
+			updates: []*operatorpb.Update{
+				{Name: "route0", Route: route},
+				{Name: "route0"},
+			},
+		}
+	}
+
+	// The initial dump changes the RIB: one wake for the flush event and
+	// one for the session end.
+	require.NoError(t, svc.FeedRIB(dump()))
+	require.Equal(t, 1, updates)
+	require.Equal(t, 0, duplicates)
+	require.Equal(t, 2, wakes)
+
+	// The re-dump is a no-op, so only the session end wakes the loop.
+	require.NoError(t, svc.FeedRIB(dump()))
+	require.Equal(t, 1, updates)
+	require.Equal(t, 1, duplicates)
+	require.Equal(t, 3, wakes)
+}
+
+// TestFeedRIB_Teardown verifies that the teardown policy of the last update
+// decides whether the routes of an ended session are withdrawn at once or
+// kept.
+func TestFeedRIB_Teardown(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		teardown operatorpb.TeardownPolicy
+		routes   int
+	}{
+		{"withdraw", operatorpb.TeardownPolicy_TEARDOWN_POLICY_WITHDRAW, 0},
+		{"keep", operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP, 1},
+		{"stale timeout", operatorpb.TeardownPolicy_TEARDOWN_POLICY_STALE_TIMEOUT, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewRouteService(neigh.NewNeighTable())
+			defer svc.Close()
+
+			stream := &fakeFeedRIBStream{
+				updates: []*operatorpb.Update{
+					{
+						Name: "route0",
+						Route: &operatorpb.Route{
+							Prefix:  "10.0.0.0/24",
+							NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+							Peer:    commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+							Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+						},
+						Teardown: tt.teardown,
+					},
+					{Name: "route0", Teardown: tt.teardown},
+				},
+			}
+			require.NoError(t, svc.FeedRIB(stream))
+
+			routes := svc.getOrCreateRib("route0").MatchRoutes(rib.RouteFilter{})
+			require.Len(t, routes, tt.routes)
+		})
+	}
+}
+
+// TestFeedRIB_LoopProtection verifies that static routes are tagged with
+// the origin community and FeedRIB routes carrying it are not imported.
+func TestFeedRIB_LoopProtection(t *testing.T) {
+	origin := rib.LargeCommunity{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 1}
+
+	looped := 0
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceOriginCommunity(&origin),
+		WithRouteServiceOnRIBLooped(func(n int) { looped += n }),
+	)
+	defer svc.Close()
+
+	_, err := svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.0.0/24",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))},
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.NoError(t, err)
+
+	bird := func(prefix string, communities ...*operatorpb.LargeCommunity) *operatorpb.Update {
+		return &operatorpb.Update{
+			Name: "route0",
+			Route: &operatorpb.Route{
+				Prefix:           prefix,
+				NextHop:          commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.2")),
+				Peer:             commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.2")),
+				Source:           operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+				LargeCommunities: communities,
+			},
+		}
+	}
+	stream := &fakeFeedRIBStream{
+		updates: []*operatorpb.Update{
+			bird("10.0.0.0/24", &operatorpb.LargeCommunity{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 1}),
+			bird("10.0.1.0/24", &operatorpb.LargeCommunity{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 2}),
+		},
+	}
+	require.NoError(t, svc.FeedRIB(stream))
+	require.Equal(t, 1, looped)
+
+	routes := svc.getOrCreateRib("route0").MatchRoutes(rib.RouteFilter{})
+	require.Len(t, routes, 2)
+	for _, route := range routes {
+		switch route.SourceID {
+		case rib.RouteSourceStatic:
+			require.Equal(t, []rib.LargeCommunity{origin}, route.LargeCommunities)
+		case rib.RouteSourceBird:
+			require.Equal(t, netip.MustParsePrefix("10.0.1.0/24"), route.Prefix)
+		}
+	}
+}
+
+// TestFeedRIB_StrictNexthop verifies that the routes of a restricted config
+// are imported only via the nexthops of its peer networks, while their
+// withdrawals and the routes of other configs pass.
+func TestFeedRIB_StrictNexthop(t *testing.T) {
+	strict, err := NewStrictNexthop(StrictNexthopConfig{
+		Configs: map[string][]string{"route0": {"192.0.2.0/24", "2001:db8::/64"}},
+	})
+	require.NoError(t, err)
+
+	rejected := map[string]int{}
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceStrictNexthop(strict),
+		WithRouteServiceOnRIBNexthopRejected(func(name string, n int) { rejected[name] += n }),
+	)
+	defer svc.Close()
+
+	bird := func(name string, prefix string, nexthop string, isDelete bool) *operatorpb.Update {
+		return &operatorpb.Update{
+			Name: name,
+			Route: &operatorpb.Route{
+				Prefix:  prefix,
+				NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr(nexthop)),
+				Peer:    commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+				Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+			},
+			IsDelete: isDelete,
+		}
+	}
+	require.NoError(t, svc.FeedRIB(&fakeFeedRIBStream{
+		updates: []*operatorpb.Update{
+			bird("route0", "10.0.0.0/24", "192.0.2.2", false),
+			bird("route0", "10.0.1.0/24", "198.51.100.1", false),
+			bird("route0", "10.0.2.0/24", "2001:db8::1", false),
+			bird("route0", "10.0.3.0/24", "2001:db8:1::1", false),
+			bird("route0", "10.0.4.0/24", "198.51.100.1", true),
+			{Name: "route0", Teardown: operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP},
+		},
+	}))
+	require.NoError(t, svc.FeedRIB(&fakeFeedRIBStream{
+		updates: []*operatorpb.Update{
+			bird("route1", "10.0.1.0/24", "198.51.100.1", false),
+			{Name: "route1", Teardown: operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP},
+		},
+	}))
+	require.Equal(t, map[string]int{"route0": 2}, rejected)
+
+	prefixes := func(name string) []netip.Prefix {
+		out := []netip.Prefix{}
+		for _, route := range svc.getOrCreateRib(name).MatchRoutes(rib.RouteFilter{}) {
+			out = append(out, route.Prefix)
+		}
+		return out
+	}
+	require.ElementsMatch(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.0.2.0/24"),
+	}, prefixes("route0"))
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24")}, prefixes("route1"))
+}
+
+// fakeMonitorRoutesStream collects the events sent by MonitorRoutes.
+type fakeMonitorRoutesStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	limit  int
+	events []*operatorpb.RouteEvent
+}
+
+func (m *fakeMonitorRoutesStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *fakeMonitorRoutesStream) Send(event *operatorpb.RouteEvent) error {
+	m.events = append(m.events, event)
+	if len(m.events) == m.limit {
+		m.cancel()
+	}
+	return nil
+}
+
+func TestMonitorRoutes_InvalidArgument(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+	defer svc.Close()
+
+	tests := []struct {
+		name string
+		req  *operatorpb.MonitorRoutesRequest
+	}{
+		{
+			name: "missing name",
+			req:  &operatorpb.MonitorRoutesRequest{},
+		},
+		{
+			name: "invalid prefix",
+			req:  &operatorpb.MonitorRoutesRequest{Name: "route0", Prefix: "10.0.0.0/33"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.MonitorRoutes(tt.req, &fakeMonitorRoutesStream{ctx: t.Context()})
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, codes.InvalidArgument, st.Code())
+		})
+	}
+}
+
+// TestMonitorRoutes_NotFound verifies that monitoring an unknown RIB fails
+// without creating it.
+func TestMonitorRoutes_NotFound(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+	defer svc.Close()
+
+	err := svc.MonitorRoutes(
+		&operatorpb.MonitorRoutesRequest{Name: "route0"},
+		&fakeMonitorRoutesStream{ctx: t.Context()},
+	)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, ok := svc.getRib("route0")
+	require.False(t, ok)
+}
+
+// TestMonitorRoutes_Filters verifies that only changes matching the
+// requested prefix and source are streamed.
+func TestMonitorRoutes_Filters(t *testing.T) {
+	// Closed once the stream has subscribed to the RIB, so the changes
+	// below are all streamed.
+	subscribed := make(chan struct{})
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceOnMonitorSubscribed(func(string) { close(subscribed) }),
+	)
+	defer svc.Close()
+	svc.getOrCreateRib("route0")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	stream := &fakeMonitorRoutesStream{
+		ctx:    ctx,
+		cancel: cancel,
+		limit:  2,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.MonitorRoutes(&operatorpb.MonitorRoutesRequest{
+			Name:   "route0",
+			Prefix: "10.0.0.0/16",
+			Source: operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+		}, stream)
+	}()
+	<-subscribed
+
+	nexthop := []*commonpb.IPAddress{
+		commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+	}
+	insert := func(prefix string, source operatorpb.RouteSourceID) {
+		_, err := svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+			Name:         "route0",
+			Prefix:       prefix,
+			NexthopAddrs: nexthop,
+			SourceId:     source,
+		})
+		require.NoError(t, err)
+	}
+
+	insert("192.168.0.0/24", operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC)
+	insert("10.0.1.0/24", operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD)
+	insert("10.0.0.0/8", operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC)
+	insert("10.0.1.0/24", operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC)
+	_, err := svc.DeleteRoute(t.Context(), &operatorpb.DeleteRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.1.0/24",
+		NexthopAddrs: nexthop,
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.NoError(t, err)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("MonitorRoutes did not stream the matching changes")
+	}
+	require.Len(t, stream.events, 2)
+	for idx, kind := range []operatorpb.RouteEventKind{
+		operatorpb.RouteEventKind_ROUTE_EVENT_KIND_ADDED,
+		operatorpb.RouteEventKind_ROUTE_EVENT_KIND_WITHDRAWN,
+	} {
+		require.Equal(t, kind, stream.events[idx].GetKind())
+		require.Equal(t, "10.0.1.0/24", stream.events[idx].GetRoute().GetPrefix())
+		require.Equal(t, operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC, stream.events[idx].GetRoute().GetSource())
+	}
+}
+
+func TestDeleteRoutesByFilter(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+
+	decommissioned := netip.MustParseAddr("192.0.2.1")
+	community := rib.LargeCommunity{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 2}
+	svc.getOrCreateRib("route0").Update(
+		rib.Route{Prefix: netip.MustParsePrefix("10.0.0.0/24"), NextHop: decommissioned, Peer: decommissioned, SourceID: rib.RouteSourceBird},
+		rib.Route{Prefix: netip.MustParsePrefix("10.0.1.0/24"), NextHop: decommissioned, Peer: decommissioned, SourceID: rib.RouteSourceBird},
+		rib.Route{
+			Prefix:           netip.MustParsePrefix("10.0.1.0/24"),
+			NextHop:          netip.MustParseAddr("192.0.2.2"),
+			Peer:             netip.MustParseAddr("192.0.2.2"),
+			SourceID:         rib.RouteSourceBird,
+			LargeCommunities: []rib.LargeCommunity{community},
+		},
+		rib.Route{Prefix: netip.MustParsePrefix("10.1.0.0/24"), NextHop: decommissioned, Peer: decommissioned, SourceID: rib.RouteSourceBird},
+	)
+
+	showRoutes := func() []*operatorpb.Route {
+		resp, err := svc.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0"})
+		require.NoError(t, err)
+		return resp.GetRoutes()
+	}
+
+	_, err := svc.DeleteRoutesByFilter(t.Context(), &operatorpb.DeleteRoutesByFilterRequest{Name: "route0"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	filter := &operatorpb.RouteFilter{
+		Prefix:  "10.0.0.0/16",
+		NextHop: commonpb.NewIPAddressFromAddr(decommissioned),
+	}
+
+	resp, err := svc.DeleteRoutesByFilter(t.Context(), &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "route0",
+		Filter: filter,
+		DryRun: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRoutes(), 2)
+	require.Len(t, showRoutes(), 4)
+
+	resp, err = svc.DeleteRoutesByFilter(t.Context(), &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "route0",
+		Filter: filter,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRoutes(), 2)
+	require.Len(t, showRoutes(), 2)
+
+	resp, err = svc.DeleteRoutesByFilter(t.Context(), &operatorpb.DeleteRoutesByFilterRequest{
+		Name: "route0",
+		Filter: &operatorpb.RouteFilter{
+			LargeCommunity: &operatorpb.LargeCommunity{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 2},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRoutes(), 1)
+	require.Equal(t, "10.0.1.0/24", resp.GetRoutes()[0].GetPrefix())
+
+	routes := showRoutes()
+	require.Len(t, routes, 1)
+	require.Equal(t, "10.1.0.0/24", routes[0].GetPrefix())
+}
+
+// TestRouteTags verifies that tags attached at insert time select the
+// routes of a group to list, re-prioritize and delete.
+func TestRouteTags(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+
+	insert := func(prefix string, nexthop string, tags ...string) error {
+		_, err := svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+			Name:         "route0",
+			Prefix:       prefix,
+			NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr(nexthop))},
+			SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+			Tags:         tags,
+		})
+		return err
+	}
+	require.NoError(t, insert("10.0.0.0/24", "192.0.2.1", "mw-1234", "mw-1234"))
+	require.NoError(t, insert("10.0.1.0/24", "192.0.2.1", "mw-1234", "drain"))
+	require.NoError(t, insert("10.0.1.0/24", "192.0.2.2"))
+	require.Equal(t, codes.InvalidArgument, status.Code(insert("10.0.2.0/24", "192.0.2.1", "")))
+
+	tags, err := svc.ListRouteTags(t.Context(), &operatorpb.ListRouteTagsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, tags.GetTags(), 2)
+	require.Equal(t, "drain", tags.GetTags()[0].GetTag())
+	require.Equal(t, "mw-1234", tags.GetTags()[1].GetTag())
+	require.Equal(t, uint64(2), tags.GetTags()[1].GetRoutes())
+	require.Equal(t, uint64(2), tags.GetTags()[1].GetPrefixes())
+
+	shown, err := svc.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0", Tag: "drain"})
+	require.NoError(t, err)
+	require.Len(t, shown.GetRoutes(), 1)
+	require.Equal(t, []string{"drain", "mw-1234"}, shown.GetRoutes()[0].GetTags())
+
+	_, err = svc.SetRoutesPrefByFilter(t.Context(), &operatorpb.SetRoutesPrefByFilterRequest{Name: "route0", Pref: 50})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	group := &operatorpb.RouteFilter{Tag: "mw-1234"}
+	pref, err := svc.SetRoutesPrefByFilter(t.Context(), &operatorpb.SetRoutesPrefByFilterRequest{
+		Name:   "route0",
+		Filter: group,
+		Pref:   50,
+	})
+	require.NoError(t, err)
+	require.Len(t, pref.GetRoutes(), 2)
+
+	shown, err = svc.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, shown.GetRoutes(), 3)
+	for _, route := range shown.GetRoutes() {
+		require.Equal(t, len(route.GetTags()) != 0, route.GetPref() == 50, "route %s via %s", route.GetPrefix(), route.GetNextHop())
+	}
+
+	deleted, err := svc.DeleteRoutesByFilter(t.Context(), &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "route0",
+		Filter: group,
+	})
+	require.NoError(t, err)
+	require.Len(t, deleted.GetRoutes(), 2)
+
+	tags, err = svc.ListRouteTags(t.Context(), &operatorpb.ListRouteTagsRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Empty(t, tags.GetTags())
+}
+
+func TestSimulate(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+
+	peer1 := netip.MustParseAddr("192.0.2.1")
+	peer2 := netip.MustParseAddr("192.0.2.2")
+	svc.getOrCreateRib("route0").Update(
+		rib.Route{Prefix: netip.MustParsePrefix("10.0.0.0/24"), NextHop: peer1, Peer: peer1, SourceID: rib.RouteSourceBird, Pref: 200},
+		rib.Route{Prefix: netip.MustParsePrefix("10.0.0.0/24"), NextHop: peer2, Peer: peer2, SourceID: rib.RouteSourceBird, Pref: 100},
+		rib.Route{Prefix: netip.MustParsePrefix("10.1.0.0/24"), NextHop: peer1, Peer: peer1, SourceID: rib.RouteSourceBird},
+	)
+
+	_, err := svc.Simulate(t.Context(), &operatorpb.SimulateRequest{
+		Name:            "route0",
+		WithdrawFilters: []*operatorpb.RouteFilter{{}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := svc.Simulate(t.Context(), &operatorpb.SimulateRequest{
+		Name: "route0",
+		WithdrawFilters: []*operatorpb.RouteFilter{
+			{NextHop: commonpb.NewIPAddressFromAddr(peer1)},
+		},
+		Announce: []*operatorpb.Route{
+			{
+				Prefix:  "10.2.0.0/24",
+				NextHop: commonpb.NewIPAddressFromAddr(peer2),
+				Peer:    commonpb.NewIPAddressFromAddr(peer2),
+				Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.GetWithdrawnRoutes())
+
+	changes := resp.GetChanges()
+	require.Len(t, changes, 3)
+
+	require.Equal(t, "10.0.0.0/24", changes[0].GetPrefix())
+	require.Len(t, changes[0].GetBefore(), 1)
+	require.Len(t, changes[0].GetAfter(), 1)
+	after, err := changes[0].GetAfter()[0].GetNextHop().ToAddr()
+	require.NoError(t, err)
+	require.Equal(t, peer2, after)
+
+	require.Equal(t, "10.1.0.0/24", changes[1].GetPrefix())
+	require.Empty(t, changes[1].GetAfter())
+
+	require.Equal(t, "10.2.0.0/24", changes[2].GetPrefix())
+	require.Empty(t, changes[2].GetBefore())
+	require.Len(t, changes[2].GetAfter(), 1)
+
+	// The RIB is left untouched.
+	routes, err := svc.ShowRoutes(t.Context(), &operatorpb.ShowRoutesRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Len(t, routes.GetRoutes(), 3)
+}
+
+// TestFeedRIB_MassWithdrawal verifies that consecutive withdrawals are
+// applied in batches and that the flush event following a mass withdrawal
+// wakes the reconcile loop as an emergency.
+func TestFeedRIB_MassWithdrawal(t *testing.T) {
+	bird := func(prefix string, isDelete bool) *operatorpb.Update {
+		return &operatorpb.Update{
+			Name: "route0",
+			Route: &operatorpb.Route{
+				Prefix:  prefix,
+				NextHop: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+				Peer:    commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1")),
+				Source:  operatorpb.RouteSourceID_ROUTE_SOURCE_ID_BIRD,
+			},
+			IsDelete: isDelete,
+		}
+	}
+
+	updates, duplicates, batches, wakes, emergencies := 0, 0, 0, 0, 0
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceOnChanged(func() { wakes++ }),
+		WithRouteServiceOnEmergency(func() { emergencies++ }),
+		WithRouteServiceOnRIBUpdate(func(n int) {
+			updates += n
+			batches++
+		}),
+		WithRouteServiceOnRIBDuplicate(func(n int) { duplicates += n }),
+		WithRouteServiceMassWithdraw(MassWithdrawConfig{BatchSize: 4, Threshold: 6}),
+	)
+	defer svc.Close()
+
+	announce := &fakeFeedRIBStream{}
+	for idx := range 10 {
+		announce.updates = append(announce.updates, bird(fmt.Sprintf("10.0.%d.0/24", idx), false))
+	}
+	announce.updates = append(announce.updates, &operatorpb.Update{Name: "route0", Teardown: operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP})
+	require.NoError(t, svc.FeedRIB(announce))
+	require.Equal(t, 10, updates)
+	require.Equal(t, 2, wakes)
+	require.Equal(t, 0, emergencies)
+
+	// Withdrawals below the threshold are batched but flushed as usual.
+	updates, batches = 0, 0
+	partial := &fakeFeedRIBStream{
+		updates: []*operatorpb.Update{
+			bird("10.0.0.0/24", true),
+			bird("10.0.1.0/24", true),
+			bird("10.0.0.0/24", true),
+			{Name: "route0", Teardown: operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP},
+		},
+	}
+	require.NoError(t, svc.FeedRIB(partial))
+	require.Equal(t, 2, updates)
+	require.Equal(t, 1, batches)
+	require.Equal(t, 1, duplicates)
+	require.Equal(t, 4, wakes)
+	require.Equal(t, 0, emergencies)
+
+	// A mass withdrawal is applied in full batches and flushed at once.
+	updates, batches = 0, 0
+	mass := &fakeFeedRIBStream{}
+	for idx := 2; idx < 10; idx++ {
+		mass.updates = append(mass.updates, bird(fmt.Sprintf("10.0.%d.0/24", idx), true))
+	}
+	mass.updates = append(mass.updates, &operatorpb.Update{Name: "route0", Teardown: operatorpb.TeardownPolicy_TEARDOWN_POLICY_KEEP})
+	require.NoError(t, svc.FeedRIB(mass))
+	require.Equal(t, 8, updates)
+	require.Equal(t, 2, batches)
+	require.Equal(t, 1, emergencies)
+	require.Equal(t, 5, wakes)
+
+	require.Empty(t, svc.getOrCreateRib("route0").MatchRoutes(rib.RouteFilter{}))
+}
+
+// liveFeedRIBStream feeds FeedRIB the updates of a channel, recording the
+// summaries sent back.
+type liveFeedRIBStream struct {
+	grpc.ServerStream
+
+	updates   chan *operatorpb.Update
+	summaries chan *operatorpb.UpdateSummary
+}
+
+func newLiveFeedRIBStream() *liveFeedRIBStream {
+	return &liveFeedRIBStream{
+		updates:   make(chan *operatorpb.Update),
+		summaries: make(chan *operatorpb.UpdateSummary, 64),
+	}
+}
+
+func (m *liveFeedRIBStream) Recv() (*operatorpb.Update, error) {
+	update, ok := <-m.updates
+	if !ok {
+		return nil, io.EOF
+	}
+	return update, nil
+}
+
+func (m *liveFeedRIBStream) Send(summary *operatorpb.UpdateSummary) error {
+	m.summaries <- summary
+	return nil
+}
+
+func TestFeedRIB_Heartbeat(t *testing.T) {
+	started := make(chan struct{}, 2)
+	svc := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceFeedHeartbeat(operatorpb.Heartbeat{
+			Interval:     10 * time.Millisecond,
+			DeadInterval: 50 * time.Millisecond,
+		}),
+		WithRouteServiceOnRIBSessionStart(func(string, uint64) { started <- struct{}{} }),
+	)
+	defer svc.Close()
+
+	feed := func(stream *liveFeedRIBStream) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- svc.FeedRIB(stream) }()
+		return done
+	}
+
+	// A sender without heartbeats is never declared dead.
+	legacy := newLiveFeedRIBStream()
+	done := feed(legacy)
+	legacy.updates <- &operatorpb.Update{Name: "route0", EndOfRib: true}
+	time.Sleep(100 * time.Millisecond)
+	require.Empty(t, legacy.summaries)
+	close(legacy.updates)
+	require.NoError(t, <-done)
+	require.False(t, (<-legacy.summaries).GetHeartbeat())
+	<-started
+
+	// Heartbeats are answered without starting a session, and a sender
+	// going silent after one ends the session.
+	stream := newLiveFeedRIBStream()
+	defer close(stream.updates)
+	done = feed(stream)
+	stream.updates <- &operatorpb.Update{Name: "route0", Heartbeat: true}
+	select {
+	case summary := <-stream.summaries:
+		require.True(t, summary.GetHeartbeat())
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat answered")
+	}
+	require.Empty(t, started)
+
+	stream.updates <- &operatorpb.Update{Name: "route0", EndOfRib: true}
+	<-started
+
+	select {
+	case err := <-done:
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatal("dead sender not detected")
+	}
+}
+
+// fakeDownloadSnapshotStream collects the chunks sent by DownloadSnapshot.
+type fakeDownloadSnapshotStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	data   []byte
+	chunks int
+}
+
+func (m *fakeDownloadSnapshotStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *fakeDownloadSnapshotStream) Send(chunk *operatorpb.SnapshotChunk) error {
+	m.data = append(m.data, chunk.GetData()...)
+	m.chunks++
+	return nil
+}
+
+func TestDownloadSnapshot(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+	defer svc.Close()
+
+	holder := svc.getOrCreateRib("route0")
+	for idx := range 1000 {
+		prefix := netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(idx >> 8), byte(idx), 0}), 24)
+		require.NoError(t, holder.AddUnicastRoute(prefix, netip.MustParseAddr("192.0.2.1"), rib.RouteSourceStatic))
+	}
+
+	stream := &fakeDownloadSnapshotStream{ctx: t.Context()}
+	require.NoError(t, svc.DownloadSnapshot(&operatorpb.DownloadSnapshotRequest{Name: "route0"}, stream))
+	require.NotZero(t, stream.chunks)
+
+	header, routes, err := ribsnapshot.ReadAll(bytes.NewReader(stream.data))
+	require.NoError(t, err)
+	require.Equal(t, "route0", header.Config)
+	require.Len(t, routes, 1000)
+	for _, route := range routes {
+		require.Equal(t, rib.RouteSourceStatic, route.SourceID)
+		require.Equal(t, netip.MustParseAddr("192.0.2.1"), route.NextHop)
+	}
+
+	err = svc.DownloadSnapshot(&operatorpb.DownloadSnapshotRequest{Name: "route1"}, stream)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	err = svc.DownloadSnapshot(&operatorpb.DownloadSnapshotRequest{}, stream)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestExportImportRIB(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewRouteService(neigh.NewNeighTable(), WithRouteServiceSnapshotDir(dir))
+	defer svc.Close()
+	ctx := t.Context()
+
+	holder := svc.getOrCreateRib("route0")
+	sessionID, _ := holder.NewSession()
+	birdRoutes := []rib.Route{}
+	for idx, peer := range []string{"192.0.2.1", "192.0.2.2"} {
+		birdRoutes = append(birdRoutes, rib.Route{
+			Prefix:    netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 0, byte(idx), 0}), 24),
+			NextHop:   netip.MustParseAddr(peer),
+			Peer:      netip.MustParseAddr(peer),
+			PeerAS:    64512,
+			Pref:      100,
+			SourceID:  rib.RouteSourceBird,
+			SessionID: sessionID,
+		})
+	}
+	require.Equal(t, 2, holder.Update(birdRoutes...))
+	require.NoError(t, holder.AddUnicastRoute(netip.MustParsePrefix("10.1.0.0/24"), netip.MustParseAddr("192.0.2.3"), rib.RouteSourceStatic))
+
+	exported, err := svc.ExportRIB(ctx, &operatorpb.ExportRIBRequest{Name: "route0"})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "route0"+snapshotFileExt), exported.GetPath())
+	require.Equal(t, uint64(3), exported.GetRoutes())
+
+	// The routes of the BIRD session are fresher than the snapshot.
+	_, err = svc.ImportRIB(ctx, &operatorpb.ImportRIBRequest{Name: "route0"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// A restarted operator restores the BIRD routes only, the ones BIRD
+	// does not announce again withdrawn after the RIB TTL.
+	restarted := NewRouteService(
+		neigh.NewNeighTable(),
+		WithRouteServiceSnapshotDir(dir),
+		WithRouteServiceRIBTTL(50*time.Millisecond),
+	)
+	defer restarted.Close()
+	require.True(t, restarted.restoreSnapshots())
+
+	restoredHolder, ok := restarted.getRib("route0")
+	require.True(t, ok)
+	require.Equal(t, 2, restoredHolder.Stats().Routes)
+	_, restored, ok := restoredHolder.LongestMatch(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+	require.Len(t, restored.Routes, 1)
+	require.Zero(t, restored.Routes[0].SessionID)
+	require.Equal(t, uint32(64512), restored.Routes[0].PeerAS)
+
+	sessionID, _ = restoredHolder.NewSession()
+	announced := birdRoutes[0]
+	announced.SessionID = sessionID
+	require.Zero(t, restoredHolder.Update(announced))
+	require.Eventually(t, func() bool {
+		return restoredHolder.Stats().Routes == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	_, _, ok = restoredHolder.LongestMatch(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+
+	_, err = restarted.ImportRIB(ctx, &operatorpb.ImportRIBRequest{Name: "route1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = restarted.ImportRIB(ctx, &operatorpb.ImportRIBRequest{Name: "../route0"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = restarted.ExportRIB(ctx, &operatorpb.ExportRIBRequest{Name: "route1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	disabled := NewRouteService(neigh.NewNeighTable())
+	defer disabled.Close()
+	_, err = disabled.ExportRIB(ctx, &operatorpb.ExportRIBRequest{Name: "route0"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestImportRIB_Damaged(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewRouteService(neigh.NewNeighTable(), WithRouteServiceSnapshotDir(dir))
+	defer svc.Close()
+
+	path := filepath.Join(dir, "route0"+snapshotFileExt)
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+	_, err := svc.ImportRIB(t.Context(), &operatorpb.ImportRIBRequest{Name: "route0"})
+	require.Equal(t, codes.DataLoss, status.Code(err))
+
+	// Restoring at startup skips the damaged snapshot.
+	require.False(t, svc.restoreSnapshots())
+}
@@ -0,0 +1,417 @@
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// StaticRoute is a single static route, the destination prefix reached
+// via the next-hop address.
+type StaticRoute struct {
+	Prefix      netip.Prefix
+	NexthopAddr netip.Addr
+	// Device is the interface of an IPv6 link-local next-hop, given as
+	// the zone of its address, empty if the route names none.
+	//
+	// The RIB keeps the next-hop without its zone, the neighbour of the
+	// next-hop is expected on this interface.
+	Device string
+}
+
+// compareStaticRoutes orders static routes by prefix, then by next-hop.
+func compareStaticRoutes(a, b StaticRoute) int {
+	if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+		return c
+	}
+	if c := a.Prefix.Bits() - b.Prefix.Bits(); c != 0 {
+		return c
+	}
+	if c := a.NexthopAddr.Compare(b.NexthopAddr); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Device, b.Device)
+}
+
+// parseStaticRoute parses the textual prefix and next-hop of a route,
+// checking that the next-hop can forward the prefix.
+//
+// IPv4-mapped prefixes and next-hops are taken as IPv4 ones. An IPv4
+// prefix may be reached via an IPv6 next-hop (RFC 8950), but not the other
+// way around. An IPv6 link-local next-hop may name its interface as the
+// zone of the address, "fe80::1%eth0", other next-hops may not.
+func parseStaticRoute(prefix string, nexthopAddr string) (StaticRoute, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return StaticRoute{}, fmt.Errorf("failed to parse prefix %q: %w", prefix, err)
+	}
+	if p.Addr().Is4In6() {
+		if p.Bits() < 96 {
+			return StaticRoute{}, fmt.Errorf("IPv4-mapped prefix %s is shorter than /96", p)
+		}
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+
+	nexthop, err := netip.ParseAddr(nexthopAddr)
+	if err != nil {
+		return StaticRoute{}, fmt.Errorf("failed to parse nexthop %q: %w", nexthopAddr, err)
+	}
+	device := nexthop.Zone()
+	nexthop = nexthop.WithZone("").Unmap()
+
+	switch {
+	case nexthop.IsUnspecified():
+		return StaticRoute{}, fmt.Errorf("nexthop %s is unspecified", nexthop)
+	case nexthop.IsMulticast():
+		return StaticRoute{}, fmt.Errorf("nexthop %s is a multicast address", nexthop)
+	case p.Addr().Is6() && nexthop.Is4():
+		return StaticRoute{}, fmt.Errorf("IPv6 prefix %s cannot be reached via IPv4 nexthop %s", p, nexthop)
+	}
+
+	if device != "" {
+		if !nexthop.Is6() || !nexthop.IsLinkLocalUnicast() {
+			return StaticRoute{}, fmt.Errorf("nexthop %q names interface %q, only IPv6 link-local nexthops may", nexthopAddr, device)
+		}
+		if len(device) > maxDeviceNameLen || strings.ContainsAny(device, "/ \t") {
+			return StaticRoute{}, fmt.Errorf("nexthop %q names invalid interface %q", nexthopAddr, device)
+		}
+	}
+
+	return StaticRoute{Prefix: p.Masked(), NexthopAddr: nexthop, Device: device}, nil
+}
+
+// maxDeviceNameLen is the longest interface name the kernel accepts.
+const maxDeviceNameLen = 15
+
+// checkStaticRouteDevices checks that the routes via the same link-local
+// next-hop agree on its interface.
+//
+// The RIB and the neighbour tables know a next-hop by its address only, so
+// the same address cannot stand for neighbours on two interfaces.
+func checkStaticRouteDevices(routes []StaticRoute) error {
+	devices := map[netip.Addr]string{}
+	for _, route := range routes {
+		if route.Device == "" {
+			continue
+		}
+		device, ok := devices[route.NexthopAddr]
+		if ok && device != route.Device {
+			return fmt.Errorf("nexthop %s is named on both interfaces %q and %q", route.NexthopAddr, device, route.Device)
+		}
+		devices[route.NexthopAddr] = route.Device
+	}
+
+	return nil
+}
+
+// yamlStaticRoutesFile is the YAML form of a static routes file.
+type yamlStaticRoutesFile struct {
+	Routes []StaticRouteConfig `yaml:"routes"`
+}
+
+// LoadStaticRoutes reads a static routes file.
+//
+// Files with a ".yaml" or ".yml" extension hold the same list as the
+// static.routes config section:
+//
+//	routes:
+//	  - prefix: 10.0.0.0/8
+//	    nexthop_addr: 192.0.2.1
+//
+// Any other file holds one route per line, the prefix followed by the
+// next-hop address. Blank lines and "#" comments are ignored:
+//
+//	# prefix       nexthop
+//	10.0.0.0/8     192.0.2.1
+//	2000::/3       fe80::1
+//	2001:db8::/32  fe80::2%eth0
+//
+// See parseStaticRoute for the accepted prefix and next-hop combinations.
+//
+// The returned routes are sorted and deduplicated, so route sets compare
+// equal regardless of the file order.
+func LoadStaticRoutes(path string) ([]StaticRoute, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read static routes file %q: %w", path, err)
+	}
+
+	var routes []StaticRoute
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		routes, err = parseYAMLStaticRoutes(data)
+	default:
+		routes, err = parseTextStaticRoutes(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse static routes file %q: %w", path, err)
+	}
+	if err := checkStaticRouteDevices(routes); err != nil {
+		return nil, fmt.Errorf("invalid static routes file %q: %w", path, err)
+	}
+
+	slices.SortFunc(routes, compareStaticRoutes)
+	return slices.Compact(routes), nil
+}
+
+func parseYAMLStaticRoutes(data []byte) ([]StaticRoute, error) {
+	var file yamlStaticRoutesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	routes := make([]StaticRoute, 0, len(file.Routes))
+	for idx, r := range file.Routes {
+		route, err := parseStaticRoute(r.Prefix, r.NexthopAddr)
+		if err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", idx, err)
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+func parseTextStaticRoutes(data []byte) ([]StaticRoute, error) {
+	routes := []StaticRoute{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected prefix and nexthop, got %d fields", line, len(fields))
+		}
+
+		route, err := parseStaticRoute(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		routes = append(routes, route)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// StaticRoutesFileOption configures the StaticRoutesFile constructor.
+type StaticRoutesFileOption func(*staticRoutesFileOptions)
+
+type staticRoutesFileOptions struct {
+	Interval  time.Duration
+	Pinned    []StaticRoute
+	OnChanged func()
+	Log       *zap.Logger
+}
+
+func newStaticRoutesFileOptions() *staticRoutesFileOptions {
+	return &staticRoutesFileOptions{
+		OnChanged: func() {},
+		Log:       zap.NewNop(),
+	}
+}
+
+// WithStaticRoutesFileInterval sets the period between rereads. Zero
+// disables rereads.
+func WithStaticRoutesFileInterval(interval time.Duration) StaticRoutesFileOption {
+	return func(o *staticRoutesFileOptions) {
+		o.Interval = interval
+	}
+}
+
+// WithStaticRoutesFilePinned sets the routes seeded from the main config.
+//
+// They are never removed by the file, even if it stops listing them.
+func WithStaticRoutesFilePinned(routes []StaticRoute) StaticRoutesFileOption {
+	return func(o *staticRoutesFileOptions) {
+		o.Pinned = routes
+	}
+}
+
+// WithStaticRoutesFileOnChanged sets the callback invoked after a reread
+// changed the RIB.
+func WithStaticRoutesFileOnChanged(fn func()) StaticRoutesFileOption {
+	return func(o *staticRoutesFileOptions) {
+		o.OnChanged = fn
+	}
+}
+
+// WithStaticRoutesFileLog sets the logger.
+func WithStaticRoutesFileLog(log *zap.Logger) StaticRoutesFileOption {
+	return func(o *staticRoutesFileOptions) {
+		o.Log = log
+	}
+}
+
+// StaticRoutesFile keeps the routes listed in a static routes file
+// installed in the RIB of a module.
+//
+// Every reread adds the routes that appeared in the file and removes the
+// ones that disappeared from it. A file that fails to load keeps the
+// previously loaded routes, so a half-written edit never withdraws routes
+// already installed.
+type StaticRoutesFile struct {
+	path     string
+	module   string
+	interval time.Duration
+	pinned   []StaticRoute
+	routeSvc *RouteService
+
+	// routes are the sorted routes installed from the file.
+	routes []StaticRoute
+
+	onChanged func()
+	log       *zap.Logger
+}
+
+// NewStaticRoutesFile creates a StaticRoutesFile installing the routes of
+// the file at path into the RIB of the module.
+//
+// Nothing is loaded until the first Reload.
+func NewStaticRoutesFile(
+	path string,
+	module string,
+	routeSvc *RouteService,
+	options ...StaticRoutesFileOption,
+) *StaticRoutesFile {
+	opts := newStaticRoutesFileOptions()
+	for _, o := range options {
+		o(opts)
+	}
+
+	pinned := slices.Clone(opts.Pinned)
+	slices.SortFunc(pinned, compareStaticRoutes)
+
+	return &StaticRoutesFile{
+		path:      path,
+		module:    module,
+		interval:  opts.Interval,
+		pinned:    slices.Compact(pinned),
+		routeSvc:  routeSvc,
+		onChanged: opts.OnChanged,
+		log:       opts.Log,
+	}
+}
+
+// Reload rereads the file and applies its changes to the RIB, reporting
+// whether the RIB changed.
+//
+// On failure the RIB is left untouched.
+func (m *StaticRoutesFile) Reload() (bool, error) {
+	routes, err := LoadStaticRoutes(m.path)
+	if err != nil {
+		return false, err
+	}
+	routes = slices.DeleteFunc(routes, func(route StaticRoute) bool {
+		_, pinned := slices.BinarySearchFunc(m.pinned, route, compareStaticRoutes)
+		return pinned
+	})
+	if err := checkStaticRouteDevices(append(slices.Clone(m.pinned), routes...)); err != nil {
+		return false, fmt.Errorf("invalid static routes file %q: %w", m.path, err)
+	}
+
+	added, removed := diffStaticRoutes(m.routes, routes)
+	if len(added) == 0 && len(removed) == 0 {
+		return false, nil
+	}
+
+	holder := m.routeSvc.getOrCreateRib(m.module)
+	for _, route := range removed {
+		if err := holder.RemoveUnicastRoute(route.Prefix, route.NexthopAddr, rib.RouteSourceStatic); err != nil {
+			return false, fmt.Errorf("failed to remove static route %s via %s: %w", route.Prefix, route.NexthopAddr, err)
+		}
+	}
+	for _, route := range added {
+		if err := holder.AddUnicastRoute(route.Prefix, route.NexthopAddr, rib.RouteSourceStatic, m.routeSvc.localCommunities()...); err != nil {
+			return false, fmt.Errorf("failed to add static route %s via %s: %w", route.Prefix, route.NexthopAddr, err)
+		}
+	}
+	m.routes = routes
+
+	m.log.Info("applied static routes file",
+		zap.String("path", m.path),
+		zap.Int("routes", len(routes)),
+		zap.Int("added", len(added)),
+		zap.Int("removed", len(removed)),
+	)
+
+	return true, nil
+}
+
+// Run rereads the file every interval until the context is cancelled.
+func (m *StaticRoutesFile) Run(ctx context.Context) error {
+	if m.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := m.Reload()
+			if err != nil {
+				m.log.Warn("failed to reload static routes file, keeping previous routes",
+					zap.String("path", m.path),
+					zap.Error(err),
+				)
+				continue
+			}
+			if changed {
+				m.onChanged()
+			}
+		}
+	}
+}
+
+// diffStaticRoutes returns the routes present only in next and the ones
+// present only in prev, both sorted.
+func diffStaticRoutes(prev []StaticRoute, next []StaticRoute) ([]StaticRoute, []StaticRoute) {
+	added := []StaticRoute{}
+	removed := []StaticRoute{}
+
+	i, j := 0, 0
+	for i < len(prev) || j < len(next) {
+		switch {
+		case j == len(next):
+			removed = append(removed, prev[i])
+			i++
+		case i == len(prev):
+			added = append(added, next[j])
+			j++
+		default:
+			switch c := compareStaticRoutes(prev[i], next[j]); {
+			case c < 0:
+				removed = append(removed, prev[i])
+				i++
+			case c > 0:
+				added = append(added, next[j])
+				j++
+			default:
+				i++
+				j++
+			}
+		}
+	}
+
+	return added, removed
+}
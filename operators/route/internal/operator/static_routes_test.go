@@ -0,0 +1,242 @@
+package operator
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+func writeStaticRoutesFile(t *testing.T, path string, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func TestLoadStaticRoutes(t *testing.T) {
+	expected := []StaticRoute{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.2")},
+		{Prefix: netip.MustParsePrefix("2000::/3"), NexthopAddr: netip.MustParseAddr("fe80::1")},
+	}
+
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{
+			name: "text",
+			file: "routes.txt",
+			data: "# prefix nexthop\n" +
+				"2000::/3 fe80::1\n" +
+				"\n" +
+				"10.1.2.3/8\t192.0.2.2 # masked\n" +
+				"10.0.0.0/8 192.0.2.1\n" +
+				"10.0.0.0/8 192.0.2.1\n",
+		},
+		{
+			name: "yaml",
+			file: "routes.yaml",
+			data: "routes:\n" +
+				"  - prefix: 2000::/3\n" +
+				"    nexthop_addr: fe80::1\n" +
+				"  - prefix: 10.0.0.0/8\n" +
+				"    nexthop_addr: 192.0.2.2\n" +
+				"  - prefix: 10.0.0.0/8\n" +
+				"    nexthop_addr: 192.0.2.1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeStaticRoutesFile(t, path, tt.data)
+
+			routes, err := LoadStaticRoutes(path)
+			require.NoError(t, err)
+			require.Equal(t, expected, routes)
+		})
+	}
+}
+
+func TestLoadStaticRoutes_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{name: "missing nexthop", file: "routes", data: "10.0.0.0/8\n"},
+		{name: "bad prefix", file: "routes", data: "10.0.0.0/33 192.0.2.1\n"},
+		{name: "bad nexthop", file: "routes", data: "10.0.0.0/8 192.0.2.256\n"},
+		{name: "bad yaml", file: "routes.yml", data: "routes: [\n"},
+		{name: "bad yaml prefix", file: "routes.yml", data: "routes:\n  - prefix: bad\n    nexthop_addr: 192.0.2.1\n"},
+		{name: "ipv6 via ipv4", file: "routes", data: "2000::/3 192.0.2.1\n"},
+		{name: "conflicting interfaces", file: "routes", data: "2000::/3 fe80::1%eth0\n3000::/4 fe80::1%eth1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeStaticRoutesFile(t, path, tt.data)
+
+			_, err := LoadStaticRoutes(path)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := LoadStaticRoutes(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestParseStaticRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		nexthop  string
+		expected StaticRoute
+	}{
+		{
+			name:     "ipv4",
+			prefix:   "10.1.0.0/8",
+			nexthop:  "192.0.2.1",
+			expected: StaticRoute{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		},
+		{
+			name:     "ipv4 via ipv6",
+			prefix:   "10.0.0.0/8",
+			nexthop:  "2001:db8::1",
+			expected: StaticRoute{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("2001:db8::1")},
+		},
+		{
+			name:     "ipv4-mapped",
+			prefix:   "::ffff:10.0.0.0/104",
+			nexthop:  "::ffff:192.0.2.1",
+			expected: StaticRoute{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		},
+		{
+			name:     "link-local",
+			prefix:   "2000::/3",
+			nexthop:  "fe80::1",
+			expected: StaticRoute{Prefix: netip.MustParsePrefix("2000::/3"), NexthopAddr: netip.MustParseAddr("fe80::1")},
+		},
+		{
+			name:    "link-local with interface",
+			prefix:  "2000::/3",
+			nexthop: "fe80::1%eth0",
+			expected: StaticRoute{
+				Prefix:      netip.MustParsePrefix("2000::/3"),
+				NexthopAddr: netip.MustParseAddr("fe80::1"),
+				Device:      "eth0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := parseStaticRoute(tt.prefix, tt.nexthop)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, route)
+		})
+	}
+}
+
+func TestParseStaticRoute_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		nexthop string
+	}{
+		{name: "ipv6 via ipv4", prefix: "2000::/3", nexthop: "192.0.2.1"},
+		{name: "ipv6 via ipv4-mapped", prefix: "2000::/3", nexthop: "::ffff:192.0.2.1"},
+		{name: "short ipv4-mapped prefix", prefix: "::ffff:0.0.0.0/80", nexthop: "2001:db8::1"},
+		{name: "unspecified nexthop", prefix: "10.0.0.0/8", nexthop: "0.0.0.0"},
+		{name: "multicast nexthop", prefix: "2000::/3", nexthop: "ff02::1"},
+		{name: "interface on global nexthop", prefix: "2000::/3", nexthop: "2001:db8::1%eth0"},
+		{name: "invalid interface", prefix: "2000::/3", nexthop: "fe80::1%eth0/1"},
+		{name: "long interface", prefix: "2000::/3", nexthop: "fe80::1%ethernet0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseStaticRoute(tt.prefix, tt.nexthop)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCheckStaticRouteDevices(t *testing.T) {
+	nexthop := netip.MustParseAddr("fe80::1")
+	routes := []StaticRoute{
+		{Prefix: netip.MustParsePrefix("2000::/3"), NexthopAddr: nexthop, Device: "eth0"},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), NexthopAddr: nexthop},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: nexthop, Device: "eth0"},
+	}
+	require.NoError(t, checkStaticRouteDevices(routes))
+
+	routes = append(routes, StaticRoute{Prefix: netip.MustParsePrefix("3000::/4"), NexthopAddr: nexthop, Device: "eth1"})
+	require.Error(t, checkStaticRouteDevices(routes))
+
+	// The static neighbour of the nexthop must be on its interface.
+	require.NoError(t, checkStaticNeighbourDevices(routes[:1], []StaticNeighbourConfig{{NextHop: "fe80::1", Device: "eth0"}}))
+	require.Error(t, checkStaticNeighbourDevices(routes[:1], []StaticNeighbourConfig{{NextHop: "fe80::1", Device: "eth1"}}))
+}
+
+// TestStaticRoutesFile_Reload verifies that rereads add and remove the
+// routes changed in the file, keep the pinned routes and leave the RIB
+// untouched when the file is broken.
+func TestStaticRoutesFile_Reload(t *testing.T) {
+	svc := NewRouteService(neigh.NewNeighTable())
+	defer svc.Close()
+
+	pinned := StaticRoute{Prefix: netip.MustParsePrefix("10.9.0.0/16"), NexthopAddr: netip.MustParseAddr("192.0.2.9")}
+	holder := svc.getOrCreateRib("route0")
+	require.NoError(t, holder.AddUnicastRoute(pinned.Prefix, pinned.NexthopAddr, rib.RouteSourceStatic))
+
+	path := filepath.Join(t.TempDir(), "routes")
+	file := NewStaticRoutesFile(path, "route0", svc, WithStaticRoutesFilePinned([]StaticRoute{pinned}))
+
+	installed := func() []StaticRoute {
+		routes := []StaticRoute{}
+		for _, route := range holder.MatchRoutes(rib.RouteFilter{SourceID: rib.RouteSourceStatic}) {
+			routes = append(routes, StaticRoute{Prefix: route.Prefix, NexthopAddr: route.NextHop})
+		}
+		return routes
+	}
+
+	_, err := file.Reload()
+	require.Error(t, err)
+
+	writeStaticRoutesFile(t, path, "10.0.0.0/8 192.0.2.1\n10.1.0.0/16 192.0.2.1\n10.9.0.0/16 192.0.2.9\n")
+	changed, err := file.Reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.ElementsMatch(t, []StaticRoute{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		pinned,
+	}, installed())
+
+	changed, err = file.Reload()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	writeStaticRoutesFile(t, path, "10.0.0.0/8 192.0.2.1\n10.2.0.0/16 192.0.2.2\n")
+	changed, err = file.Reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.ElementsMatch(t, []StaticRoute{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), NexthopAddr: netip.MustParseAddr("192.0.2.1")},
+		{Prefix: netip.MustParsePrefix("10.2.0.0/16"), NexthopAddr: netip.MustParseAddr("192.0.2.2")},
+		pinned,
+	}, installed())
+
+	writeStaticRoutesFile(t, path, "10.0.0.0/8\n")
+	_, err = file.Reload()
+	require.Error(t, err)
+	require.Len(t, installed(), 3)
+}
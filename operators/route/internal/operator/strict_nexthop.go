@@ -0,0 +1,48 @@
+package operator
+
+import (
+	"net/netip"
+)
+
+// StrictNexthop restricts the nexthops of the FeedRIB routes of the
+// configs it lists to their peer networks.
+//
+// A nil StrictNexthop allows every nexthop.
+type StrictNexthop struct {
+	networks map[string][]netip.Prefix
+}
+
+// NewStrictNexthop parses the allowed nexthop networks, returning nil if no
+// config is restricted.
+func NewStrictNexthop(cfg StrictNexthopConfig) (*StrictNexthop, error) {
+	networks, err := cfg.Networks()
+	if err != nil {
+		return nil, err
+	}
+	if len(networks) == 0 {
+		return nil, nil
+	}
+	return &StrictNexthop{networks: networks}, nil
+}
+
+// Allows reports whether a route of the config may be installed via the
+// nexthop.
+//
+// A route of a restricted config without a nexthop is not allowed, as it
+// could not be resolved either.
+func (m *StrictNexthop) Allows(name string, nexthop netip.Addr) bool {
+	if m == nil {
+		return true
+	}
+	networks, ok := m.networks[name]
+	if !ok {
+		return true
+	}
+	nexthop = nexthop.Unmap()
+	for _, network := range networks {
+		if network.Contains(nexthop) {
+			return true
+		}
+	}
+	return false
+}
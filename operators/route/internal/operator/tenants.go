@@ -0,0 +1,200 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// tenant is a RouteService caller scoped to its own routes.
+//
+// A nil tenant is not scoped and sees every route.
+type tenant struct {
+	name string
+	// configs are the RIBs of the tenant.
+	configs map[string]struct{}
+	// tag, when set, narrows the tenant to the routes tagged with it.
+	tag string
+}
+
+// Tenants scopes the RouteService callers to the routes of their tenant.
+//
+// A nil Tenants leaves every caller unscoped.
+type Tenants struct {
+	// tenants are keyed by the SHA-256 digest of their token, like the
+	// prefix ACL callers.
+	tenants        map[[sha256.Size]byte]*tenant
+	allowAnonymous bool
+	log            *zap.Logger
+}
+
+// NewTenants loads the tenant tokens, returning nil if tenancy is
+// disabled.
+func NewTenants(cfg TenantsConfig, log *zap.Logger) (*Tenants, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tenants := &Tenants{
+		tenants:        map[[sha256.Size]byte]*tenant{},
+		allowAnonymous: cfg.AllowAnonymous,
+		log:            log,
+	}
+
+	names := map[string]struct{}{}
+	for idx, tenantCfg := range cfg.Tenants {
+		if tenantCfg.Name == "" {
+			return nil, fmt.Errorf("tenant at index %d has no name", idx)
+		}
+		if _, ok := names[tenantCfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate tenant %q", tenantCfg.Name)
+		}
+		names[tenantCfg.Name] = struct{}{}
+
+		if len(tenantCfg.Configs) == 0 {
+			return nil, fmt.Errorf("tenant %q has no configs", tenantCfg.Name)
+		}
+		configs := map[string]struct{}{}
+		for _, name := range tenantCfg.Configs {
+			if name == "" {
+				return nil, fmt.Errorf("tenant %q lists an empty config name", tenantCfg.Name)
+			}
+			configs[name] = struct{}{}
+		}
+
+		digest, err := readTokenDigest(tenantCfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token of tenant %q: %w", tenantCfg.Name, err)
+		}
+		if other, ok := tenants.tenants[digest]; ok {
+			return nil, fmt.Errorf("tenants %q and %q share a token", other.name, tenantCfg.Name)
+		}
+		tenants.tenants[digest] = &tenant{
+			name:    tenantCfg.Name,
+			configs: configs,
+			tag:     tenantCfg.Tag,
+		}
+	}
+
+	return tenants, nil
+}
+
+// identify identifies the tenant of the request by its bearer token.
+func (m *Tenants) identify(ctx context.Context) (*tenant, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	digest, ok, err := bearerTokenDigest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if m.allowAnonymous {
+			return nil, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "a tenant bearer token is required")
+	}
+
+	caller, ok := m.tenants[digest]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown bearer token")
+	}
+	return caller, nil
+}
+
+// authorize identifies the tenant of the request and checks that it may
+// access the named config.
+func (m *Tenants) authorize(ctx context.Context, name string) (*tenant, error) {
+	caller, err := m.identify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caller == nil {
+		return nil, nil
+	}
+
+	if _, ok := caller.configs[name]; !ok {
+		m.log.Warn("denied access to a config of another tenant",
+			zap.String("tenant", caller.name),
+			zap.String("name", name),
+		)
+		return nil, status.Errorf(codes.PermissionDenied, "tenant %q may not access config %q", caller.name, name)
+	}
+	return caller, nil
+}
+
+// visibleConfigs returns the config names the tenant may access.
+func (m *tenant) visibleConfigs(names []string) []string {
+	if m == nil {
+		return names
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		_, ok := m.configs[name]
+		return !ok
+	})
+}
+
+// visible reports whether the route belongs to the tenant.
+func (m *tenant) visible(route *rib.Route) bool {
+	return m == nil || m.tag == "" || slices.Contains(route.Tags, m.tag)
+}
+
+// visibleTag reports whether the routes tagged with the tag may belong to
+// the tenant.
+func (m *tenant) visibleTag(tag string) bool {
+	return m == nil || m.tag == "" || tag == m.tag
+}
+
+// routeTags returns the tags of a route inserted by the tenant, which
+// carries the tag of the tenant.
+func (m *tenant) routeTags(tags []string) []string {
+	if m == nil || m.tag == "" || slices.Contains(tags, m.tag) {
+		return tags
+	}
+	return append(slices.Clone(tags), m.tag)
+}
+
+// narrow narrows a route filter to the routes of the tenant.
+func (m *tenant) narrow(filter *rib.RouteFilter) error {
+	if m == nil || m.tag == "" {
+		return nil
+	}
+	if filter.Tag != "" && filter.Tag != m.tag {
+		return status.Errorf(codes.PermissionDenied, "tenant %q may not select routes tagged %q", m.name, filter.Tag)
+	}
+	filter.Tag = m.tag
+	return nil
+}
+
+// authorizeRoutes checks that the routes of the prefix forwarding to the
+// nexthops belong to the tenant, before they are deleted.
+func (m *tenant) authorizeRoutes(
+	holder *rib.RIB,
+	prefix netip.Prefix,
+	nexthops []netip.Addr,
+	sourceID rib.RouteSourceID,
+) error {
+	if m == nil || m.tag == "" {
+		return nil
+	}
+
+	prefix = prefix.Masked()
+	for _, nexthop := range nexthops {
+		routes := holder.MatchRoutes(rib.RouteFilter{Prefix: prefix, SourceID: sourceID, NextHop: nexthop})
+		for idx := range routes {
+			if routes[idx].Prefix.Masked() == prefix && !m.visible(&routes[idx]) {
+				return status.Errorf(codes.PermissionDenied, "route of %s via %s belongs to another tenant", prefix, nexthop)
+			}
+		}
+	}
+	return nil
+}
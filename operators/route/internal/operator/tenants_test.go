@@ -0,0 +1,158 @@
+package operator
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
+	"github.com/yanet-platform/yanet2/operators/route/internal/discovery/neigh"
+	operatorpb "github.com/yanet-platform/yanet2/operators/route/operatorpb/v1"
+)
+
+func TestTenants(t *testing.T) {
+	dir := t.TempDir()
+	tenantsCfg := TenantsConfig{Enabled: true}
+	tokens := map[string]context.Context{}
+	for _, tenant := range []TenantConfig{
+		{Name: "red", Configs: []string{"vrf-red"}},
+		{Name: "blue", Configs: []string{"shared"}, Tag: "blue"},
+		{Name: "green", Configs: []string{"shared"}, Tag: "green"},
+	} {
+		tenant.TokenFile = filepath.Join(dir, tenant.Name+".token")
+		require.NoError(t, os.WriteFile(tenant.TokenFile, []byte(tenant.Name+"-token\n"), 0o600))
+		tenantsCfg.Tenants = append(tenantsCfg.Tenants, tenant)
+		tokens[tenant.Name] = metadata.NewIncomingContext(
+			t.Context(),
+			metadata.Pairs("authorization", "Bearer "+tenant.Name+"-token"),
+		)
+	}
+
+	tenants, err := NewTenants(tenantsCfg, zap.NewNop())
+	require.NoError(t, err)
+	svc := NewRouteService(neigh.NewNeighTable(), WithRouteServiceTenants(tenants))
+
+	nexthop := commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))
+	insert := func(ctx context.Context, name string, prefix string) error {
+		_, err := svc.InsertRoute(ctx, &operatorpb.InsertRouteRequest{
+			Name:         name,
+			Prefix:       prefix,
+			NexthopAddrs: []*commonpb.IPAddress{nexthop},
+			SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+		})
+		return err
+	}
+	showRoutes := func(ctx context.Context, name string) []string {
+		resp, err := svc.ShowRoutes(ctx, &operatorpb.ShowRoutesRequest{Name: name})
+		require.NoError(t, err)
+		prefixes := []string{}
+		for _, route := range resp.GetRoutes() {
+			prefixes = append(prefixes, route.GetPrefix())
+		}
+		return prefixes
+	}
+
+	require.NoError(t, insert(tokens["red"], "vrf-red", "10.0.0.0/8"))
+	require.NoError(t, insert(tokens["blue"], "shared", "198.51.100.0/24"))
+	require.NoError(t, insert(tokens["green"], "shared", "203.0.113.0/24"))
+	require.Equal(t, codes.PermissionDenied, status.Code(insert(tokens["red"], "shared", "10.0.0.0/8")))
+	require.Equal(t, codes.PermissionDenied, status.Code(insert(tokens["blue"], "vrf-red", "10.0.0.0/8")))
+
+	// Callers without a token or with an unknown one are rejected.
+	require.Equal(t, codes.Unauthenticated, status.Code(insert(t.Context(), "shared", "10.0.0.0/8")))
+	unknown := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer other"))
+	require.Equal(t, codes.Unauthenticated, status.Code(insert(unknown, "shared", "10.0.0.0/8")))
+
+	configs, err := svc.ListConfigs(tokens["red"], &operatorpb.ListConfigsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"vrf-red"}, configs.GetConfigs())
+
+	// The tenants sharing a config see their tagged routes only.
+	require.Equal(t, []string{"198.51.100.0/24"}, showRoutes(tokens["blue"], "shared"))
+	require.Equal(t, []string{"203.0.113.0/24"}, showRoutes(tokens["green"], "shared"))
+
+	tags, err := svc.ListRouteTags(tokens["blue"], &operatorpb.ListRouteTagsRequest{Name: "shared"})
+	require.NoError(t, err)
+	require.Len(t, tags.GetTags(), 1)
+	require.Equal(t, "blue", tags.GetTags()[0].GetTag())
+
+	lookup, err := svc.LookupRoute(tokens["green"], &operatorpb.LookupRouteRequest{
+		Name:   "shared",
+		IpAddr: commonpb.NewIPAddressFromAddr(netip.MustParseAddr("198.51.100.1")),
+	})
+	require.NoError(t, err)
+	require.Empty(t, lookup.GetRoutes())
+
+	// A tenant can neither delete nor select the routes of another one.
+	_, err = svc.DeleteRoute(tokens["green"], &operatorpb.DeleteRouteRequest{
+		Name:         "shared",
+		Prefix:       "198.51.100.0/24",
+		NexthopAddrs: []*commonpb.IPAddress{nexthop},
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = svc.DeleteRoutesByFilter(tokens["green"], &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "shared",
+		Filter: &operatorpb.RouteFilter{Tag: "blue"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	deleted, err := svc.DeleteRoutesByFilter(tokens["green"], &operatorpb.DeleteRoutesByFilterRequest{
+		Name:   "shared",
+		Filter: &operatorpb.RouteFilter{Prefix: "0.0.0.0/0"},
+	})
+	require.NoError(t, err)
+	require.Len(t, deleted.GetRoutes(), 1)
+	require.Equal(t, "203.0.113.0/24", deleted.GetRoutes()[0].GetPrefix())
+	require.Equal(t, []string{"198.51.100.0/24"}, showRoutes(tokens["blue"], "shared"))
+}
+
+func TestTenantsAllowAnonymous(t *testing.T) {
+	tenants, err := NewTenants(TenantsConfig{Enabled: true, AllowAnonymous: true}, zap.NewNop())
+	require.NoError(t, err)
+	svc := NewRouteService(neigh.NewNeighTable(), WithRouteServiceTenants(tenants))
+
+	_, err = svc.InsertRoute(t.Context(), &operatorpb.InsertRouteRequest{
+		Name:         "route0",
+		Prefix:       "10.0.0.0/8",
+		NexthopAddrs: []*commonpb.IPAddress{commonpb.NewIPAddressFromAddr(netip.MustParseAddr("192.0.2.1"))},
+		SourceId:     operatorpb.RouteSourceID_ROUTE_SOURCE_ID_STATIC,
+	})
+	require.NoError(t, err)
+
+	configs, err := svc.ListConfigs(t.Context(), &operatorpb.ListConfigsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"route0"}, configs.GetConfigs())
+}
+
+func TestNewTenants(t *testing.T) {
+	tenants, err := NewTenants(TenantsConfig{Tenants: []TenantConfig{{Name: "red"}}}, zap.NewNop())
+	require.NoError(t, err)
+	require.Nil(t, tenants)
+	_, err = tenants.authorize(t.Context(), "route0")
+	require.NoError(t, err)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+
+	for _, tenants := range [][]TenantConfig{
+		{{TokenFile: tokenFile, Configs: []string{"route0"}}},
+		{{Name: "red", TokenFile: tokenFile}},
+		{{Name: "red", TokenFile: tokenFile, Configs: []string{""}}},
+		{{Name: "red", TokenFile: tokenFile + ".missing", Configs: []string{"route0"}}},
+		{{Name: "red", TokenFile: tokenFile, Configs: []string{"route0"}}, {Name: "red", TokenFile: tokenFile, Configs: []string{"route1"}}},
+		{{Name: "red", TokenFile: tokenFile, Configs: []string{"route0"}}, {Name: "blue", TokenFile: tokenFile, Configs: []string{"route1"}}},
+	} {
+		_, err := NewTenants(TenantsConfig{Enabled: true, Tenants: tenants}, zap.NewNop())
+		require.Error(t, err)
+	}
+}
@@ -16,6 +16,8 @@ type RIB struct {
 	mu               sync.RWMutex
 	routes           maptrie.MapTrie[netip.Prefix, netip.Addr, RoutesList]
 	stats            *RIBStats
+	convergence      *RIBConvergence
+	watchers         *routeWatchers
 	currentSessionId *atomic.Uint64 // Monotonically increasing ID for BIRD import sessions
 	// sessionTerminator points to a flag signaling the active FeedRIB stream to terminate;
 	// swapped on NewSession to invalidate the previous stream.
@@ -30,19 +32,47 @@ func NewRIB(log *zap.Logger) *RIB {
 	return &RIB{
 		routes:            maptrie.NewMapTrie[netip.Prefix, netip.Addr, RoutesList](1024),
 		stats:             NewRIBStats(),
+		convergence:       NewRIBConvergence(),
+		watchers:          newRouteWatchers(),
 		currentSessionId:  &atomic.Uint64{},
 		sessionTerminator: sessionTerminator,
 		log:               log,
 	}
 }
 
-func (m *RIB) AddUnicastRoute(prefix netip.Prefix, nexthopAddr netip.Addr, sourceID RouteSourceID) error {
+// AddUnicastRoute adds a peerless route, tagged with the given large
+// communities.
+func (m *RIB) AddUnicastRoute(
+	prefix netip.Prefix,
+	nexthopAddr netip.Addr,
+	sourceID RouteSourceID,
+	communities ...LargeCommunity,
+) error {
+	return m.AddWeightedUnicastRoute(prefix, nexthopAddr, 0, nil, sourceID, communities...)
+}
+
+// AddWeightedUnicastRoute adds a peerless route taking the given share of
+// the traffic of its ECMP group, replacing the weight and the tags of the
+// same route added before.
+//
+// The tags must be normalized, see NormalizeTags.
+func (m *RIB) AddWeightedUnicastRoute(
+	prefix netip.Prefix,
+	nexthopAddr netip.Addr,
+	weight uint32,
+	tags []string,
+	sourceID RouteSourceID,
+	communities ...LargeCommunity,
+) error {
 	route := Route{
-		Prefix:    prefix,
-		NextHop:   nexthopAddr,
-		Peer:      netip.IPv6Unspecified(),
-		SourceID:  sourceID,
-		UpdatedAt: time.Now(),
+		Prefix:           prefix,
+		NextHop:          nexthopAddr,
+		Peer:             netip.IPv6Unspecified(),
+		LargeCommunities: communities,
+		SourceID:         sourceID,
+		Weight:           weight,
+		Tags:             tags,
+		UpdatedAt:        time.Now(),
 	}
 
 	m.mu.Lock()
@@ -51,14 +81,17 @@ func (m *RIB) AddUnicastRoute(prefix netip.Prefix, nexthopAddr netip.Addr, sourc
 		func() RoutesList {
 			m.stats.OnPrefixAdded()
 			m.stats.OnRouteAdded(1)
+			m.publish(nil, []Route{route})
 			return RoutesList{
 				Routes: []Route{route},
 			}
 		},
 		func(rl RoutesList) RoutesList {
+			prev := m.snapshot(rl)
 			if rl.Insert(route) {
 				m.stats.OnRouteAdded(1)
 			}
+			m.publish(prev, rl.Routes)
 			return rl
 		},
 	)
@@ -88,6 +121,7 @@ func (m *RIB) RemoveUnicastRoute(prefix netip.Prefix, nexthopAddr netip.Addr, so
 	m.routes.UpdateOrDelete(
 		prefix,
 		func(routesList RoutesList) (RoutesList, bool) {
+			prev := m.snapshot(routesList)
 			newRoutes := make([]Route, 0, len(routesList.Routes))
 			for _, r := range routesList.Routes {
 				if r.isSameIdentity(candidate) {
@@ -97,6 +131,7 @@ func (m *RIB) RemoveUnicastRoute(prefix netip.Prefix, nexthopAddr netip.Addr, so
 				newRoutes = append(newRoutes, r)
 			}
 			routesList.Routes = newRoutes
+			m.publish(prev, routesList.Routes)
 			// Delete the prefix entry if no routes remain.
 			isEmpty := len(routesList.Routes) == 0
 			if isEmpty {
@@ -126,6 +161,50 @@ func (m *RIB) RemoveUnicastRoute(prefix netip.Prefix, nexthopAddr netip.Addr, so
 	return nil
 }
 
+// MatchRoutes returns the routes selected by the filter.
+func (m *RIB) MatchRoutes(filter RouteFilter) []Route {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.matchRoutes(filter)
+}
+
+// RemoveRoutes removes the routes selected by the filter and returns them.
+//
+// Routes of dynamic sources are removed until re-announced by the source.
+func (m *RIB) RemoveRoutes(filter RouteFilter) []Route {
+	m.mu.Lock()
+	matched := m.matchRoutes(filter)
+	withdrawn := make([]Route, 0, len(matched))
+	for _, route := range matched {
+		route.ToRemove = true
+		withdrawn = append(withdrawn, route)
+	}
+	removed := m.update(withdrawn...)
+	m.mu.Unlock()
+
+	if removed > 0 {
+		m.stats.OnChanged()
+		m.log.Info("RIB: removed routes by filter",
+			zap.Int("count", removed),
+		)
+	}
+
+	return matched
+}
+
+func (m *RIB) matchRoutes(filter RouteFilter) []Route {
+	matched := []Route{}
+	for _, routesList := range m.routes.Dump() {
+		for idx := range routesList.Routes {
+			if filter.Match(&routesList.Routes[idx]) {
+				matched = append(matched, routesList.Routes[idx])
+			}
+		}
+	}
+	return matched
+}
+
 func (m *RIB) DumpRoutes() maptrie.MapTrie[netip.Prefix, netip.Addr, RoutesList] {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -138,6 +217,7 @@ func (m *RIB) DumpRoutes() maptrie.MapTrie[netip.Prefix, netip.Addr, RoutesList]
 			dump[idx][key] = RoutesList{
 				// replace with a copy of the routes slice to avoid sharing data
 				Routes: slices.Clone(dump[idx][key].Routes),
+				Tags:   dump[idx][key].Tags,
 			}
 		}
 	}
@@ -154,21 +234,71 @@ func (m *RIB) LongestMatch(addr netip.Addr) (netip.Prefix, RoutesList, bool) {
 	return prefix, list, ok
 }
 
-func (m *RIB) Update(routes ...Route) {
+// UntaggedPrefixes returns the prefixes that are not enriched with tags yet.
+func (m *RIB) UntaggedPrefixes() []netip.Prefix {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefixes := []netip.Prefix{}
+	for idx := range m.routes {
+		for prefix, rl := range m.routes[idx] {
+			if rl.Tags == nil {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return prefixes
+}
+
+// SetPrefixTags replaces the tags of a prefix.
+//
+// Returns false if the prefix is not in the RIB, e.g. since it was
+// withdrawn while its tags were being resolved. Tags are dropped together
+// with the last route of their prefix.
+func (m *RIB) SetPrefixTags(prefix netip.Prefix, tags PrefixTags) bool {
+	if tags == nil {
+		tags = PrefixTags{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := false
+	m.routes.UpdateOrDelete(prefix, func(rl RoutesList) (RoutesList, bool) {
+		found = true
+		rl.Tags = tags
+		return rl, false
+	})
+	return found
+}
+
+// Update applies routes to the RIB.
+//
+// Returns the number of routes that changed the stored state. Announcements
+// identical to a stored route only adopt the new session and are not
+// counted, nor do they bump the RIB change timestamp.
+func (m *RIB) Update(routes ...Route) int {
 	m.mu.Lock()
-	m.update(routes...)
+	changed := m.update(routes...)
 	m.mu.Unlock()
-	m.stats.OnChanged()
+	if changed > 0 {
+		m.stats.OnChanged()
+	}
+	return changed
 }
 
-func (m *RIB) update(routes ...Route) {
+func (m *RIB) update(routes ...Route) int {
+	changed := 0
 	for _, route := range routes {
 		if route.ToRemove {
 			m.routes.UpdateOrDelete(
 				route.Prefix,
 				func(rl RoutesList) (RoutesList, bool) {
+					prev := m.snapshot(rl)
 					if rl.Remove(route) {
 						m.stats.OnRouteRemoved(1)
+						m.publish(prev, rl.Routes)
+						changed++
 					}
 					isEmpty := len(rl.Routes) == 0
 					if isEmpty {
@@ -183,19 +313,90 @@ func (m *RIB) update(routes ...Route) {
 				func() RoutesList {
 					m.stats.OnPrefixAdded()
 					m.stats.OnRouteAdded(1)
+					m.publish(nil, []Route{route})
+					changed++
 					return RoutesList{
 						Routes: []Route{route},
 					}
 				},
 				func(rl RoutesList) RoutesList {
+					if rl.Refresh(route) {
+						return rl
+					}
+					prev := m.snapshot(rl)
 					if rl.Insert(route) {
 						m.stats.OnRouteAdded(1)
 					}
+					m.publish(prev, rl.Routes)
+					changed++
 					return rl
 				},
 			)
 		}
 	}
+	return changed
+}
+
+// Restore adds routes restored from a snapshot to the RIB.
+//
+// Unlike Update, a restored route never replaces a stored route of the
+// same identity, which is fresher, nor makes it adopt its session.
+//
+// Returns the number of routes added.
+func (m *RIB) Restore(routes ...Route) int {
+	m.mu.Lock()
+	added := 0
+	for _, route := range routes {
+		m.routes.InsertOrUpdate(
+			route.Prefix,
+			func() RoutesList {
+				m.stats.OnPrefixAdded()
+				m.stats.OnRouteAdded(1)
+				m.publish(nil, []Route{route})
+				added++
+				return RoutesList{
+					Routes: []Route{route},
+				}
+			},
+			func(rl RoutesList) RoutesList {
+				for _, r := range rl.Routes {
+					if r.isSameIdentity(route) {
+						return rl
+					}
+				}
+				prev := m.snapshot(rl)
+				rl.Insert(route)
+				m.stats.OnRouteAdded(1)
+				m.publish(prev, rl.Routes)
+				added++
+				return rl
+			},
+		)
+	}
+	m.mu.Unlock()
+	if added > 0 {
+		m.stats.OnChanged()
+	}
+	return added
+}
+
+// snapshot copies the routes of a prefix for a later publish call.
+//
+// The copy is only taken while somebody watches the RIB.
+func (m *RIB) snapshot(rl RoutesList) []Route {
+	if !m.watchers.Active() {
+		return nil
+	}
+	return slices.Clone(rl.Routes)
+}
+
+// publish notifies watchers about the routes of a prefix turning from prev
+// into next.
+func (m *RIB) publish(prev []Route, next []Route) {
+	if !m.watchers.Active() {
+		return
+	}
+	m.watchers.Publish(diffRoutes(prev, next))
 }
 
 // Stats returns an O(1) snapshot of RIB counters.
@@ -213,9 +414,25 @@ func (m *RIB) NewSession() (uint64, *atomic.Bool) {
 	oldSessionTerminator := m.sessionTerminator.Swap(newSessionTerminator)
 	// Signal the previous stream, identified by oldSessionTerminator, to stop.
 	oldSessionTerminator.Store(true)
+	m.convergence.OnSessionStart(RouteSourceBird, id)
 	return id, newSessionTerminator
 }
 
+// MarkEndOfRIB records that the BIRD import session finished its initial dump.
+//
+// It returns false if the session has been superseded by a newer one.
+func (m *RIB) MarkEndOfRIB(sessionID uint64) bool {
+	return m.convergence.OnEndOfRIB(RouteSourceBird, sessionID)
+}
+
+// Convergence returns the initial-dump state of the given route source.
+//
+// The second return value is false for sources that never started an
+// import session.
+func (m *RIB) Convergence(sourceID RouteSourceID) (Convergence, bool) {
+	return m.convergence.Get(sourceID)
+}
+
 // CleanupTask removes stale BIRD routes (those with sessionID <= provided sessionID) after a TTL.
 // It's launched when a BIRD import stream ends, targeting routes from that now-defunct session.
 // The 'quit' channel allows for early termination, e.g., on service shutdown.
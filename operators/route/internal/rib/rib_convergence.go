@@ -0,0 +1,74 @@
+package rib
+
+import (
+	"sync"
+	"time"
+)
+
+// Convergence describes the initial-dump state of a single route source.
+type Convergence struct {
+	// SessionID is the import session the state belongs to.
+	SessionID uint64
+	// Converged reports whether the session delivered its end-of-RIB
+	// marker.
+	Converged bool
+	// ConvergedAt is the time the end-of-RIB marker was received. Zero
+	// while the session is still dumping.
+	ConvergedAt time.Time
+}
+
+// RIBConvergence tracks per-source initial convergence of a RIB.
+//
+// A source becomes tracked when its first import session starts. Each new
+// session resets the source to the dumping state, so a reconnect after a
+// BIRD restart is not reported as converged until the new dump completes.
+type RIBConvergence struct {
+	mu      sync.Mutex
+	sources map[RouteSourceID]Convergence
+}
+
+// NewRIBConvergence creates an empty RIBConvergence.
+func NewRIBConvergence() *RIBConvergence {
+	return &RIBConvergence{
+		sources: map[RouteSourceID]Convergence{},
+	}
+}
+
+// OnSessionStart resets the source to the dumping state for sessionID.
+func (m *RIBConvergence) OnSessionStart(sourceID RouteSourceID, sessionID uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sources[sourceID] = Convergence{SessionID: sessionID}
+}
+
+// OnEndOfRIB marks the source as converged.
+//
+// It returns false when sessionID does not belong to the most recent
+// session of the source, in which case the marker is stale and ignored.
+func (m *RIBConvergence) OnEndOfRIB(sourceID RouteSourceID, sessionID uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sources[sourceID]
+	if !ok || state.SessionID != sessionID {
+		return false
+	}
+	if !state.Converged {
+		state.Converged = true
+		state.ConvergedAt = time.Now()
+		m.sources[sourceID] = state
+	}
+
+	return true
+}
+
+// Get returns the convergence state of the source and whether the source
+// is tracked at all.
+func (m *RIBConvergence) Get(sourceID RouteSourceID) (Convergence, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sources[sourceID]
+	return state, ok
+}
@@ -0,0 +1,96 @@
+package rib
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+
+	"github.com/yanet-platform/yanet2/common/go/xnetip"
+)
+
+// Simulation is a set of hypothetical RIB changes.
+type Simulation struct {
+	// Withdraw selects the routes withdrawn first, e.g. all routes via
+	// the nexthop of a peer going down.
+	Withdraw []RouteFilter
+	// Routes are then applied in order, announced or, with ToRemove set,
+	// withdrawn like by Update.
+	Routes []Route
+}
+
+// BestPathChange is a prefix whose best routes differ after a simulation.
+type BestPathChange struct {
+	Prefix netip.Prefix
+	// Before are the best routes of each source currently in the RIB.
+	// Empty for a prefix the simulation adds.
+	Before []Route
+	// After are the best routes of each source after the simulation.
+	// Empty for a prefix left without routes.
+	After []Route
+}
+
+// SimulationResult is the outcome of a simulation.
+type SimulationResult struct {
+	// Changes are the prefixes whose best routes change, ordered by prefix.
+	Changes []BestPathChange
+	// Withdrawn is the number of routes the simulation withdraws.
+	Withdrawn int
+}
+
+// Simulate computes how the best routes of the RIB would change if the
+// simulated changes were applied, leaving the RIB untouched.
+//
+// Only the prefixes touched by the simulation are copied, so simulating a
+// peer going down costs a walk over the RIB to match its routes, but not a
+// copy of it.
+func (m *RIB) Simulate(sim Simulation) SimulationResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routes := []Route{}
+	for _, filter := range sim.Withdraw {
+		for _, route := range m.matchRoutes(filter) {
+			route.ToRemove = true
+			routes = append(routes, route)
+		}
+	}
+	routes = append(routes, sim.Routes...)
+
+	result := SimulationResult{}
+	lists := map[netip.Prefix]*RoutesList{}
+	for _, route := range routes {
+		prefix := route.Prefix.Masked()
+		rl, ok := lists[prefix]
+		if !ok {
+			current := m.routes[prefix.Bits()][prefix]
+			rl = &RoutesList{Routes: slices.Clone(current.Routes)}
+			lists[prefix] = rl
+		}
+
+		if route.ToRemove {
+			if rl.Remove(route) {
+				result.Withdrawn++
+			}
+			continue
+		}
+		route.Prefix = prefix
+		rl.Insert(route)
+	}
+
+	for _, prefix := range slices.SortedFunc(maps.Keys(lists), xnetip.PrefixCompare) {
+		current := m.routes[prefix.Bits()][prefix]
+		before := current.BestPerSource()
+		after := lists[prefix].BestPerSource()
+		if slices.EqualFunc(before, after, Route.isSameAttributes) {
+			continue
+		}
+
+		result.Changes = append(result.Changes, BestPathChange{
+			Prefix: prefix,
+			Before: before,
+			After:  after,
+		})
+	}
+
+	return result
+}
@@ -100,3 +100,355 @@ func TestRemoveUnicastRoute_StaticSingle(t *testing.T) {
 	routes = routesForPrefix(t, r, pfx)
 	require.Nil(t, routes, "prefix entry must be absent after removing the sole nexthop")
 }
+
+// TestSetPrefixTags verifies that tags survive route updates, are returned
+// by lookups and are dropped together with the prefix.
+func TestSetPrefixTags(t *testing.T) {
+	pfx := netip.MustParsePrefix("10.0.0.0/24")
+	addr := netip.MustParseAddr("10.0.0.1")
+
+	r := newTestRIB(t)
+
+	require.False(t, r.SetPrefixTags(pfx, PrefixTags{"country": "nl"}), "absent prefix must not be tagged")
+
+	require.NoError(t, r.AddUnicastRoute(pfx, netip.MustParseAddr("192.0.2.1"), RouteSourceStatic))
+	require.Equal(t, []netip.Prefix{pfx}, r.UntaggedPrefixes())
+
+	require.True(t, r.SetPrefixTags(pfx, PrefixTags{"country": "nl"}))
+	require.Empty(t, r.UntaggedPrefixes())
+
+	require.NoError(t, r.AddUnicastRoute(pfx, netip.MustParseAddr("192.0.2.2"), RouteSourceStatic))
+	_, list, ok := r.LongestMatch(addr)
+	require.True(t, ok)
+	require.Len(t, list.Routes, 2)
+	require.Equal(t, PrefixTags{"country": "nl"}, list.Tags)
+	require.Equal(t, PrefixTags{"country": "nl"}, r.DumpRoutes()[pfx.Bits()][pfx].Tags)
+
+	// A prefix without metadata is still enriched, just with no tags.
+	require.True(t, r.SetPrefixTags(pfx, nil))
+	require.Empty(t, r.UntaggedPrefixes())
+
+	require.NoError(t, r.RemoveUnicastRoute(pfx, netip.MustParseAddr("192.0.2.1"), RouteSourceStatic))
+	require.NoError(t, r.RemoveUnicastRoute(pfx, netip.MustParseAddr("192.0.2.2"), RouteSourceStatic))
+	require.NoError(t, r.AddUnicastRoute(pfx, netip.MustParseAddr("192.0.2.1"), RouteSourceStatic))
+	require.Equal(t, []netip.Prefix{pfx}, r.UntaggedPrefixes(), "re-added prefix must be enriched again")
+}
+
+// TestMarkEndOfRIB verifies that only the most recent BIRD session can mark
+// the RIB converged and that a new session resets the state.
+func TestMarkEndOfRIB(t *testing.T) {
+	r := newTestRIB(t)
+
+	_, ok := r.Convergence(RouteSourceBird)
+	require.False(t, ok, "source must be untracked before the first session")
+
+	first, _ := r.NewSession()
+	second, _ := r.NewSession()
+
+	require.False(t, r.MarkEndOfRIB(first), "superseded session must not converge the RIB")
+	state, ok := r.Convergence(RouteSourceBird)
+	require.True(t, ok)
+	require.False(t, state.Converged)
+
+	require.True(t, r.MarkEndOfRIB(second))
+	state, ok = r.Convergence(RouteSourceBird)
+	require.True(t, ok)
+	require.True(t, state.Converged)
+	require.Equal(t, second, state.SessionID)
+	require.False(t, state.ConvergedAt.IsZero())
+
+	third, _ := r.NewSession()
+	state, _ = r.Convergence(RouteSourceBird)
+	require.False(t, state.Converged, "a new session must restart the initial dump")
+	require.Equal(t, third, state.SessionID)
+}
+
+// TestUpdate_SuppressesDuplicates verifies that re-announcing an unchanged
+// route is not counted as a change but still adopts the new session, so
+// the stale-session cleanup keeps it.
+func TestUpdate_SuppressesDuplicates(t *testing.T) {
+	pfx := netip.MustParsePrefix("10.0.0.0/24")
+	base := Route{
+		Prefix:    pfx,
+		NextHop:   netip.MustParseAddr("192.0.2.1"),
+		Peer:      netip.MustParseAddr("192.0.2.1"),
+		Pref:      100,
+		SourceID:  RouteSourceBird,
+		SessionID: 1,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Route)
+		changed int
+	}{
+		{
+			name:    "identical route",
+			modify:  func(r *Route) {},
+			changed: 0,
+		},
+		{
+			name:    "new nexthop",
+			modify:  func(r *Route) { r.NextHop = netip.MustParseAddr("192.0.2.2") },
+			changed: 1,
+		},
+		{
+			name:    "new preference",
+			modify:  func(r *Route) { r.Pref = 200 },
+			changed: 1,
+		},
+		{
+			name: "new large communities",
+			modify: func(r *Route) {
+				r.LargeCommunities = []LargeCommunity{{GlobalAdministrator: 1}}
+			},
+			changed: 1,
+		},
+		{
+			name:    "blackhole flag",
+			modify:  func(r *Route) { r.Blackhole = true },
+			changed: 1,
+		},
+		{
+			name:    "new peer",
+			modify:  func(r *Route) { r.Peer = netip.MustParseAddr("192.0.2.9") },
+			changed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRIB(t)
+			require.Equal(t, 1, r.Update(base))
+
+			next := base
+			next.SessionID = 2
+			tt.modify(&next)
+			require.Equal(t, tt.changed, r.Update(next))
+
+			for _, route := range routesForPrefix(t, r, pfx) {
+				if route.isSameIdentity(next) {
+					require.Equal(t, uint64(2), route.SessionID)
+				}
+			}
+		})
+	}
+}
+
+// TestRestore verifies that restored routes never replace the stored ones.
+func TestRestore(t *testing.T) {
+	pfx := netip.MustParsePrefix("10.0.0.0/24")
+	live := Route{
+		Prefix:    pfx,
+		NextHop:   netip.MustParseAddr("192.0.2.1"),
+		Peer:      netip.MustParseAddr("192.0.2.1"),
+		Pref:      100,
+		SourceID:  RouteSourceBird,
+		SessionID: 1,
+	}
+	r := newTestRIB(t)
+	require.Equal(t, 1, r.Update(live))
+
+	stale := live
+	stale.SessionID = 0
+	stale.Pref = 50
+	other := stale
+	other.Peer = netip.MustParseAddr("192.0.2.2")
+	elsewhere := stale
+	elsewhere.Prefix = netip.MustParsePrefix("10.0.1.0/24")
+	require.Equal(t, 2, r.Restore(stale, other, elsewhere))
+
+	routes := routesForPrefix(t, r, pfx)
+	require.Len(t, routes, 2)
+	require.Equal(t, live, routes[0])
+	require.Equal(t, other, routes[1])
+	require.Equal(t, []Route{elsewhere}, routesForPrefix(t, r, elsewhere.Prefix))
+	require.Equal(t, 3, r.Stats().Routes)
+	require.Equal(t, 2, r.Stats().Prefixes)
+}
+
+// TestWatch_ReportsChanges verifies the events delivered to a watcher as
+// BIRD routes of a prefix are announced, improved and withdrawn.
+func TestWatch_ReportsChanges(t *testing.T) {
+	pfx := netip.MustParsePrefix("10.0.0.0/24")
+	routeA := Route{
+		Prefix:   pfx,
+		NextHop:  netip.MustParseAddr("192.0.2.1"),
+		Peer:     netip.MustParseAddr("192.0.2.1"),
+		Pref:     100,
+		SourceID: RouteSourceBird,
+	}
+	routeB := Route{
+		Prefix:   pfx,
+		NextHop:  netip.MustParseAddr("192.0.2.2"),
+		Peer:     netip.MustParseAddr("192.0.2.2"),
+		Pref:     200,
+		SourceID: RouteSourceBird,
+	}
+	withdrawn := func(r Route) Route {
+		r.ToRemove = true
+		return r
+	}
+	repref := func(r Route, pref uint32) Route {
+		r.Pref = pref
+		return r
+	}
+
+	type event struct {
+		kind    RouteEventKind
+		nexthop string
+	}
+
+	tests := []struct {
+		name   string
+		setup  []Route
+		update Route
+		events []event
+	}{
+		{
+			name:   "first route",
+			update: routeA,
+			events: []event{{RouteEventAdded, "192.0.2.1"}},
+		},
+		{
+			name:   "duplicate route",
+			setup:  []Route{routeA},
+			update: routeA,
+		},
+		{
+			name:   "better route",
+			setup:  []Route{routeA},
+			update: routeB,
+			events: []event{
+				{RouteEventAdded, "192.0.2.2"},
+				{RouteEventBestChanged, "192.0.2.2"},
+			},
+		},
+		{
+			name:   "worse route",
+			setup:  []Route{routeB},
+			update: routeA,
+			events: []event{{RouteEventAdded, "192.0.2.1"}},
+		},
+		{
+			name:   "best route attributes changed",
+			setup:  []Route{routeA, routeB},
+			update: repref(routeB, 300),
+			events: []event{{RouteEventAdded, "192.0.2.2"}},
+		},
+		{
+			name:   "best route withdrawn",
+			setup:  []Route{routeA, routeB},
+			update: withdrawn(routeB),
+			events: []event{
+				{RouteEventWithdrawn, "192.0.2.2"},
+				{RouteEventBestChanged, "192.0.2.1"},
+			},
+		},
+		{
+			name:   "last route withdrawn",
+			setup:  []Route{routeA},
+			update: withdrawn(routeA),
+			events: []event{{RouteEventWithdrawn, "192.0.2.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRIB(t)
+			r.Update(tt.setup...)
+
+			events, cancel := r.Watch()
+			defer cancel()
+
+			r.Update(tt.update)
+
+			got := []event{}
+			for len(events) > 0 {
+				ev := <-events
+				got = append(got, event{ev.Kind, ev.Route.NextHop.String()})
+			}
+			want := tt.events
+			if want == nil {
+				want = []event{}
+			}
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestWatchMissed(t *testing.T) {
+	r := newTestRIB(t)
+	events, missed, cancel := r.WatchMissed()
+	defer cancel()
+
+	route := func(idx int) Route {
+		return Route{
+			Prefix:   netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(idx >> 8), byte(idx), 0}), 24),
+			NextHop:  netip.MustParseAddr("192.0.2.1"),
+			Peer:     netip.MustParseAddr("10.1.1.1"),
+			SourceID: RouteSourceBird,
+		}
+	}
+
+	for idx := range routeWatcherBufferSize {
+		r.Update(route(idx))
+	}
+	require.Len(t, events, routeWatcherBufferSize)
+	require.False(t, missed())
+
+	r.Update(route(routeWatcherBufferSize))
+	require.True(t, missed())
+}
+
+func TestSimulate(t *testing.T) {
+	pfx0 := netip.MustParsePrefix("10.0.0.0/24")
+	pfx1 := netip.MustParsePrefix("10.0.1.0/24")
+	pfx2 := netip.MustParsePrefix("10.0.2.0/24")
+	nh1 := netip.MustParseAddr("192.0.2.1")
+	nh2 := netip.MustParseAddr("192.0.2.2")
+	peer1 := netip.MustParseAddr("10.1.1.1")
+	peer2 := netip.MustParseAddr("10.1.1.2")
+
+	r := newTestRIB(t)
+	r.Update(
+		Route{Prefix: pfx0, NextHop: nh1, Peer: peer1, Pref: 200, SourceID: RouteSourceBird},
+		Route{Prefix: pfx0, NextHop: nh2, Peer: peer2, Pref: 100, SourceID: RouteSourceBird},
+		Route{Prefix: pfx1, NextHop: nh1, Peer: peer1, Pref: 200, SourceID: RouteSourceBird},
+	)
+
+	// The peer behind nh1 goes down while a static route is added.
+	result := r.Simulate(Simulation{
+		Withdraw: []RouteFilter{{NextHop: nh1}},
+		Routes: []Route{
+			{Prefix: pfx2, NextHop: nh2, Peer: netip.IPv6Unspecified(), SourceID: RouteSourceStatic},
+		},
+	})
+	require.Equal(t, 2, result.Withdrawn)
+	require.Len(t, result.Changes, 3)
+
+	require.Equal(t, pfx0, result.Changes[0].Prefix)
+	require.Len(t, result.Changes[0].Before, 1)
+	require.Equal(t, peer1, result.Changes[0].Before[0].Peer)
+	require.Len(t, result.Changes[0].After, 1)
+	require.Equal(t, peer2, result.Changes[0].After[0].Peer)
+
+	require.Equal(t, pfx1, result.Changes[1].Prefix)
+	require.Empty(t, result.Changes[1].After)
+
+	require.Equal(t, pfx2, result.Changes[2].Prefix)
+	require.Empty(t, result.Changes[2].Before)
+	require.Len(t, result.Changes[2].After, 1)
+
+	// The RIB is left untouched.
+	require.Len(t, routesForPrefix(t, r, pfx0), 2)
+	require.Len(t, routesForPrefix(t, r, pfx1), 1)
+	require.Nil(t, routesForPrefix(t, r, pfx2))
+
+	// Withdrawing a route that is not the best one changes nothing.
+	result = r.Simulate(Simulation{
+		Withdraw: []RouteFilter{{NextHop: nh2}},
+	})
+	require.Equal(t, 1, result.Withdrawn)
+	require.Empty(t, result.Changes)
+}
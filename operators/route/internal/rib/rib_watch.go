@@ -0,0 +1,160 @@
+package rib
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// routeWatcherBufferSize is the number of events buffered per watcher
+// before new events are dropped.
+const routeWatcherBufferSize = 1024
+
+// RouteEventKind describes how a RIB change affected a route.
+type RouteEventKind uint8
+
+const (
+	// RouteEventAdded reports a route announced or re-announced with new
+	// attributes.
+	RouteEventAdded RouteEventKind = iota + 1
+	// RouteEventWithdrawn reports a route removed from the RIB.
+	RouteEventWithdrawn
+	// RouteEventBestChanged reports a route that became a member of its
+	// source's best-cost group, replacing the previous best routes.
+	RouteEventBestChanged
+)
+
+// RouteEvent is a single RIB change delivered to watchers.
+type RouteEvent struct {
+	Kind  RouteEventKind
+	Route Route
+	At    time.Time
+}
+
+// routeWatchers is a registry of RIB change subscribers.
+type routeWatchers struct {
+	mu sync.Mutex
+	// watchers are mapped to the flag set once they miss an event.
+	watchers map[chan RouteEvent]*atomic.Bool
+	// count mirrors len(watchers) so that the update path can skip event
+	// computation without taking the lock.
+	count atomic.Int64
+}
+
+func newRouteWatchers() *routeWatchers {
+	return &routeWatchers{
+		watchers: map[chan RouteEvent]*atomic.Bool{},
+	}
+}
+
+// Active reports whether any watcher is subscribed.
+func (m *routeWatchers) Active() bool {
+	return m.count.Load() > 0
+}
+
+// Subscribe registers a watcher, returning the flag set once it misses an
+// event.
+//
+// The returned function must be called to release the subscription.
+func (m *routeWatchers) Subscribe() (<-chan RouteEvent, *atomic.Bool, func()) {
+	ch := make(chan RouteEvent, routeWatcherBufferSize)
+	missed := &atomic.Bool{}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.watchers[ch] = missed
+	m.count.Add(1)
+
+	return ch, missed, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.watchers[ch]; ok {
+			delete(m.watchers, ch)
+			m.count.Add(-1)
+		}
+	}
+}
+
+// Publish delivers events to all watchers.
+//
+// Watchers with a full buffer miss the events that do not fit.
+func (m *routeWatchers) Publish(events []RouteEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch, missed := range m.watchers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				missed.Store(true)
+			}
+		}
+	}
+}
+
+// Watch subscribes to changes of the RIB.
+//
+// Events are delivered in the order the changes were applied. A watcher
+// that falls behind by more than its buffer misses events. The returned
+// function must be called to release the subscription.
+func (m *RIB) Watch() (<-chan RouteEvent, func()) {
+	events, _, cancel := m.watchers.Subscribe()
+	return events, cancel
+}
+
+// WatchMissed subscribes to changes of the RIB like Watch, also returning
+// a function reporting whether the watcher missed events since it
+// subscribed.
+//
+// It is meant for watchers mirroring the RIB, which must start over once
+// they miss a change.
+func (m *RIB) WatchMissed() (<-chan RouteEvent, func() bool, func()) {
+	events, missed, cancel := m.watchers.Subscribe()
+	return events, missed.Load, cancel
+}
+
+// diffRoutes computes the events turning the routes of a prefix from prev
+// into next.
+//
+// Both lists must be sorted best-first, as RoutesList keeps them.
+func diffRoutes(prev []Route, next []Route) []RouteEvent {
+	now := time.Now()
+
+	var events []RouteEvent
+	for _, route := range prev {
+		if !slices.ContainsFunc(next, route.isSameIdentity) {
+			events = append(events, RouteEvent{Kind: RouteEventWithdrawn, Route: route, At: now})
+		}
+	}
+	for _, route := range next {
+		idx := slices.IndexFunc(prev, route.isSameIdentity)
+		if idx == -1 || !prev[idx].isSameAttributes(route) {
+			events = append(events, RouteEvent{Kind: RouteEventAdded, Route: route, At: now})
+		}
+	}
+
+	// A best group appearing from nothing or vanishing is already reported
+	// by the added and withdrawn events.
+	prevList := RoutesList{Routes: prev}
+	nextList := RoutesList{Routes: next}
+	prevBest := prevList.BestPerSource()
+	for _, route := range nextList.BestPerSource() {
+		hadBest := slices.ContainsFunc(prevBest, func(r Route) bool {
+			return r.SourceID == route.SourceID
+		})
+		wasBest := slices.ContainsFunc(prevBest, route.isSameIdentity)
+		if hadBest && !wasBest {
+			events = append(events, RouteEvent{Kind: RouteEventBestChanged, Route: route, At: now})
+		}
+	}
+
+	return events
+}
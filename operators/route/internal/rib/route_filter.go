@@ -0,0 +1,56 @@
+package rib
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// RouteFilter selects routes by their attributes.
+//
+// Zero-valued criteria match every route, set ones must all match.
+type RouteFilter struct {
+	// Prefix matches routes for the prefix and its more-specifics.
+	Prefix netip.Prefix
+	// SourceID matches routes of the source.
+	SourceID RouteSourceID
+	// NextHop matches routes forwarding to the address.
+	NextHop netip.Addr
+	// LargeCommunity matches routes carrying the community.
+	LargeCommunity *LargeCommunity
+	// Tag matches routes tagged with it.
+	Tag string
+}
+
+// IsEmpty reports whether the filter matches every route.
+func (m *RouteFilter) IsEmpty() bool {
+	return !m.Prefix.IsValid() &&
+		m.SourceID == RouteSourceUnknown &&
+		!m.NextHop.IsValid() &&
+		m.LargeCommunity == nil &&
+		m.Tag == ""
+}
+
+// Match reports whether the route is selected by the filter.
+func (m *RouteFilter) Match(route *Route) bool {
+	if m.Prefix.IsValid() {
+		if m.Prefix.Bits() > route.Prefix.Bits() || !m.Prefix.Contains(route.Prefix.Addr()) {
+			return false
+		}
+	}
+	if m.SourceID != RouteSourceUnknown && m.SourceID != route.SourceID {
+		return false
+	}
+	// Nexthops are compared in normalized form, the same way static route
+	// identities are.
+	if m.NextHop.IsValid() && m.NextHop.Unmap() != route.NextHop.Unmap() {
+		return false
+	}
+	if m.LargeCommunity != nil && !slices.Contains(route.LargeCommunities, *m.LargeCommunity) {
+		return false
+	}
+	if m.Tag != "" && !slices.Contains(route.Tags, m.Tag) {
+		return false
+	}
+
+	return true
+}
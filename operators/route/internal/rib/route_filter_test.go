@@ -0,0 +1,56 @@
+package rib
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteFilter_Match(t *testing.T) {
+	route := Route{
+		Prefix:   netip.MustParsePrefix("10.1.2.0/24"),
+		NextHop:  netip.MustParseAddr("192.0.2.1"),
+		SourceID: RouteSourceStatic,
+		LargeCommunities: []LargeCommunity{
+			{GlobalAdministrator: 13238, LocalDataPart1: 1, LocalDataPart2: 2},
+		},
+		Tags: []string{"drain", "maintenance-1234"},
+	}
+
+	tests := []struct {
+		name   string
+		filter RouteFilter
+		match  bool
+	}{
+		{"empty", RouteFilter{}, true},
+		{"same prefix", RouteFilter{Prefix: netip.MustParsePrefix("10.1.2.0/24")}, true},
+		{"less specific prefix", RouteFilter{Prefix: netip.MustParsePrefix("10.0.0.0/8")}, true},
+		{"more specific prefix", RouteFilter{Prefix: netip.MustParsePrefix("10.1.2.0/25")}, false},
+		{"other prefix", RouteFilter{Prefix: netip.MustParsePrefix("10.2.0.0/16")}, false},
+		{"source", RouteFilter{SourceID: RouteSourceStatic}, true},
+		{"other source", RouteFilter{SourceID: RouteSourceBird}, false},
+		{"mapped nexthop", RouteFilter{NextHop: netip.MustParseAddr("::ffff:192.0.2.1")}, true},
+		{"other nexthop", RouteFilter{NextHop: netip.MustParseAddr("192.0.2.2")}, false},
+		{"community", RouteFilter{LargeCommunity: &LargeCommunity{13238, 1, 2}}, true},
+		{"other community", RouteFilter{LargeCommunity: &LargeCommunity{13238, 1, 3}}, false},
+		{"tag", RouteFilter{Tag: "maintenance-1234"}, true},
+		{"other tag", RouteFilter{Tag: "maintenance-1235"}, false},
+		{
+			"all criteria",
+			RouteFilter{
+				Prefix:         netip.MustParsePrefix("10.1.0.0/16"),
+				SourceID:       RouteSourceStatic,
+				NextHop:        netip.MustParseAddr("192.0.2.1"),
+				LargeCommunity: &LargeCommunity{13238, 1, 2},
+			},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.match, tt.filter.Match(&route))
+		})
+	}
+}
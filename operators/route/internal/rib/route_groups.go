@@ -0,0 +1,95 @@
+package rib
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// MaxTags is the maximum number of tags of a route.
+const MaxTags = 16
+
+// NormalizeTags returns the tags of a route sorted and without duplicates,
+// rejecting empty tags and more than MaxTags distinct ones.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if slices.Contains(tags, "") {
+		return nil, fmt.Errorf("route tags must not be empty")
+	}
+
+	result := slices.Compact(slices.Sorted(slices.Values(tags)))
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("got %d route tags, at most %d are allowed", len(result), MaxTags)
+	}
+	return result, nil
+}
+
+// RouteGroup is the set of the routes sharing a tag.
+type RouteGroup struct {
+	Tag string
+	// Routes is the number of routes tagged with the tag.
+	Routes int
+	// Prefixes is the number of prefixes with at least one route tagged
+	// with the tag.
+	Prefixes int
+}
+
+// RouteGroups returns the groups of the tagged routes, ordered by tag.
+func (m *RIB) RouteGroups() []RouteGroup {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := map[string]*RouteGroup{}
+	for _, routesList := range m.routes.Dump() {
+		seen := map[string]struct{}{}
+		for idx := range routesList.Routes {
+			for _, tag := range routesList.Routes[idx].Tags {
+				group, ok := groups[tag]
+				if !ok {
+					group = &RouteGroup{Tag: tag}
+					groups[tag] = group
+				}
+				group.Routes++
+				if _, ok := seen[tag]; !ok {
+					seen[tag] = struct{}{}
+					group.Prefixes++
+				}
+			}
+		}
+	}
+
+	result := make([]RouteGroup, 0, len(groups))
+	for _, tag := range slices.Sorted(maps.Keys(groups)) {
+		result = append(result, *groups[tag])
+	}
+	return result
+}
+
+// SetPref sets the local preference of the routes selected by the filter
+// and returns them with the new preference, all at once.
+//
+// Routes of dynamic sources get the preference of their source back when
+// re-announced.
+func (m *RIB) SetPref(filter RouteFilter, pref uint32) []Route {
+	m.mu.Lock()
+	matched := m.matchRoutes(filter)
+	for idx := range matched {
+		matched[idx].Pref = pref
+	}
+	changed := m.update(matched...)
+	m.mu.Unlock()
+
+	if changed > 0 {
+		m.stats.OnChanged()
+		m.log.Info("RIB: set preference of routes by filter",
+			zap.Uint32("pref", pref),
+			zap.Int("count", changed),
+		)
+	}
+
+	return matched
+}
@@ -0,0 +1,86 @@
+package rib
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags(nil)
+	require.NoError(t, err)
+	require.Nil(t, tags)
+
+	tags, err = NormalizeTags([]string{"b", "a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, tags)
+
+	_, err = NormalizeTags([]string{"a", ""})
+	require.Error(t, err)
+
+	many := make([]string, MaxTags+1)
+	for idx := range many {
+		many[idx] = string(rune('a' + idx))
+	}
+	_, err = NormalizeTags(many)
+	require.Error(t, err)
+
+	// Duplicates do not count against the limit.
+	_, err = NormalizeTags(append(many[:MaxTags], many[0]))
+	require.NoError(t, err)
+}
+
+// TestRouteGroups verifies that every tag of a route counts it in the group
+// of the tag, and a prefix is counted once per group.
+func TestRouteGroups(t *testing.T) {
+	pfx1 := netip.MustParsePrefix("10.0.0.0/24")
+	pfx2 := netip.MustParsePrefix("10.0.1.0/24")
+	nh1 := netip.MustParseAddr("192.0.2.1")
+	nh2 := netip.MustParseAddr("192.0.2.2")
+
+	r := newTestRIB(t)
+	require.NoError(t, r.AddWeightedUnicastRoute(pfx1, nh1, 0, []string{"drain", "mw-1"}, RouteSourceStatic))
+	require.NoError(t, r.AddWeightedUnicastRoute(pfx1, nh2, 0, []string{"mw-1"}, RouteSourceStatic))
+	require.NoError(t, r.AddWeightedUnicastRoute(pfx2, nh1, 0, []string{"mw-1"}, RouteSourceStatic))
+	require.NoError(t, r.AddUnicastRoute(pfx2, nh2, RouteSourceStatic))
+
+	require.Equal(t, []RouteGroup{
+		{Tag: "drain", Routes: 1, Prefixes: 1},
+		{Tag: "mw-1", Routes: 3, Prefixes: 2},
+	}, r.RouteGroups())
+
+	removed := r.RemoveRoutes(RouteFilter{Tag: "drain"})
+	require.Len(t, removed, 1)
+	require.Equal(t, []RouteGroup{
+		{Tag: "mw-1", Routes: 2, Prefixes: 2},
+	}, r.RouteGroups())
+	require.Len(t, routesForPrefix(t, r, pfx1), 1)
+	require.Len(t, routesForPrefix(t, r, pfx2), 2)
+}
+
+// TestSetPref verifies that only the routes of the group get the new
+// preference.
+func TestSetPref(t *testing.T) {
+	pfx := netip.MustParsePrefix("10.0.0.0/24")
+	nh1 := netip.MustParseAddr("192.0.2.1")
+	nh2 := netip.MustParseAddr("192.0.2.2")
+
+	r := newTestRIB(t)
+	require.NoError(t, r.AddWeightedUnicastRoute(pfx, nh1, 0, []string{"mw-1"}, RouteSourceStatic))
+	require.NoError(t, r.AddUnicastRoute(pfx, nh2, RouteSourceStatic))
+
+	changed := r.SetPref(RouteFilter{Tag: "mw-1"}, 50)
+	require.Len(t, changed, 1)
+	require.Equal(t, nh1, changed[0].NextHop)
+	require.Equal(t, uint32(50), changed[0].Pref)
+
+	for _, route := range routesForPrefix(t, r, pfx) {
+		if route.NextHop == nh1 {
+			require.Equal(t, uint32(50), route.Pref)
+		} else {
+			require.NotEqual(t, uint32(50), route.Pref)
+		}
+	}
+	require.Empty(t, r.SetPref(RouteFilter{Tag: "mw-2"}, 50))
+}
@@ -1,8 +1,11 @@
 package rib
 
 import (
+	"fmt"
 	"net/netip"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,12 +17,67 @@ const (
 	RouteSourceBird
 )
 
+// Community is a standard BGP community (RFC 1997).
+type Community struct {
+	ASN   uint16
+	Value uint16
+}
+
+// String returns the community in the "ASN:value" notation.
+func (m Community) String() string {
+	return fmt.Sprintf("%d:%d", m.ASN, m.Value)
+}
+
+// ExtCommunity is an extended BGP community (RFC 4360).
+type ExtCommunity struct {
+	Type    uint8
+	SubType uint8
+	// Value is the 48-bit value of the community, its administrator and
+	// local parts as laid out by the type.
+	Value uint64
+}
+
+// String returns the community as "type:subtype:value", the type and the
+// subtype in hex, so it cannot be mistaken for a large community.
+func (m ExtCommunity) String() string {
+	return fmt.Sprintf("%#02x:%#02x:%d", m.Type, m.SubType, m.Value)
+}
+
 type LargeCommunity struct {
 	GlobalAdministrator uint32
 	LocalDataPart1      uint32
 	LocalDataPart2      uint32
 }
 
+// ParseLargeCommunity parses a large community (RFC 8092) written as
+// "GA:LD1:LD2".
+func ParseLargeCommunity(s string) (LargeCommunity, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return LargeCommunity{}, fmt.Errorf("large community %q must have 3 parts, got %d", s, len(parts))
+	}
+
+	values := [3]uint32{}
+	for idx, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return LargeCommunity{}, fmt.Errorf("invalid large community %q: %w", s, err)
+		}
+		values[idx] = uint32(v)
+	}
+
+	return LargeCommunity{
+		GlobalAdministrator: values[0],
+		LocalDataPart1:      values[1],
+		LocalDataPart2:      values[2],
+	}, nil
+}
+
+// String returns the community in the "GA:LD1:LD2" notation.
+func (m LargeCommunity) String() string {
+	return fmt.Sprintf("%d:%d:%d", m.GlobalAdministrator, m.LocalDataPart1, m.LocalDataPart2)
+}
+
 // Route stores information about network routes and associated BGP attributes.
 // This information helps compute route costs, enabling the data plane to select
 // the optimal route for traffic forwarding
@@ -31,6 +89,9 @@ type Route struct {
 	// an IPv6 network prefix. IPv4 addresses are stored as IPv6-mapped addresses.
 	Prefix netip.Prefix
 	// NextHop is the IP address where traffic should be forwarded next.
+	//
+	// It is an IPv6 address for an IPv4 prefix announced with an extended
+	// next hop (RFC 8950).
 	NextHop netip.Addr
 	// Peer is the IP address of the BGP peer that advertised this route.
 	//
@@ -41,6 +102,12 @@ type Route struct {
 	//
 	// This field is used to distinguish similar routes to different systems.
 	RD uint64
+	// Communities are the standard communities the route was announced
+	// with.
+	Communities []Community
+	// ExtCommunities are the extended communities the route was announced
+	// with.
+	ExtCommunities []ExtCommunity
 	// LargeCommunities is used for link bandwidth information.
 	LargeCommunities []LargeCommunity
 	// UpdatedAt notes the last time the route was added or modified in the RIB.
@@ -65,6 +132,18 @@ type Route struct {
 	// SourceID identifies the origin of this route's information,
 	// such as static or Bird.
 	SourceID RouteSourceID
+	// Blackhole reports that the route carries the BLACKHOLE community
+	// (RFC 7999) and traffic to the prefix should be discarded.
+	Blackhole bool
+	// Weight is the share of the prefix traffic the route takes relative
+	// to the other routes of its ECMP group.
+	//
+	// Zero counts as one, so unweighted routes share the traffic equally.
+	Weight uint32
+	// Tags group the route with others, e.g. the routes of a maintenance
+	// window, see RouteGroups. Sorted and free of duplicates, see
+	// NormalizeTags.
+	Tags []string
 	// ToRemove signals whether the route has been withdrawn from the routing table.
 	ToRemove bool
 }
@@ -88,6 +167,35 @@ func (m Route) isSameIdentity(other Route) bool {
 	return true
 }
 
+// isSameAttributes reports whether two routes with the same RIB identity
+// carry the same forwarding and selection attributes.
+//
+// Session bookkeeping (SessionID, UpdatedAt, ToRemove) is ignored, so a
+// route re-announced unchanged by a new session compares equal.
+func (m Route) isSameAttributes(other Route) bool {
+	return m.Prefix == other.Prefix &&
+		m.NextHop == other.NextHop &&
+		m.Peer == other.Peer &&
+		m.RD == other.RD &&
+		slices.Equal(m.Communities, other.Communities) &&
+		slices.Equal(m.ExtCommunities, other.ExtCommunities) &&
+		slices.Equal(m.LargeCommunities, other.LargeCommunities) &&
+		m.PeerAS == other.PeerAS &&
+		m.OriginAS == other.OriginAS &&
+		m.Med == other.Med &&
+		m.Pref == other.Pref &&
+		m.ASPathLen == other.ASPathLen &&
+		m.SourceID == other.SourceID &&
+		m.Blackhole == other.Blackhole &&
+		m.EffectiveWeight() == other.EffectiveWeight() &&
+		slices.Equal(m.Tags, other.Tags)
+}
+
+// EffectiveWeight returns the weight of the route in its ECMP group.
+func (m Route) EffectiveWeight() uint32 {
+	return max(m.Weight, 1)
+}
+
 func routeCompare(a Route, b Route) int {
 	// higher priority is better
 	if prefDiff := int(a.Pref) - int(b.Pref); prefDiff != 0 {
@@ -106,8 +214,17 @@ func routeCompareRev(a Route, b Route) int {
 	return -routeCompare(a, b)
 }
 
+// PrefixTags is operational metadata attached to a prefix by an
+// enrichment source, such as the country or the customer it belongs to.
+type PrefixTags map[string]string
+
 type RoutesList struct {
 	Routes []Route
+	// Tags of the prefix, nil until the prefix is enriched.
+	//
+	// Tags are replaced as a whole and never modified in place, so copies
+	// of the list may share them.
+	Tags PrefixTags
 }
 
 // Insert adds or replaces a route in the list.
@@ -133,6 +250,24 @@ func (m *RoutesList) Insert(route Route) bool {
 	return insertedIdx == -1
 }
 
+// Refresh adopts the session of route for an identical stored route.
+//
+// Returns false when the list holds no route with the same identity and
+// attributes, in which case the list is left untouched.
+func (m *RoutesList) Refresh(route Route) bool {
+	for idx, r := range m.Routes {
+		if r.isSameIdentity(route) {
+			if !r.isSameAttributes(route) {
+				return false
+			}
+			m.Routes[idx].SessionID = route.SessionID
+			return true
+		}
+	}
+
+	return false
+}
+
 // BestPerSourceMask returns a boolean slice, aligned index-for-index with
 // Routes, where true means the route at that index is a member of its
 // source's best-cost group.
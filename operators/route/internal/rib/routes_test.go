@@ -446,3 +446,40 @@ func TestRoutesListDeletion(t *testing.T) {
 	require.Equal(t, 2, len(routesTruncated))
 
 }
+
+func TestParseLargeCommunity(t *testing.T) {
+	community, err := ParseLargeCommunity("13238:1:4294967295")
+	require.NoError(t, err)
+	require.Equal(t, LargeCommunity{13238, 1, 4294967295}, community)
+	require.Equal(t, "13238:1:4294967295", community.String())
+
+	for _, s := range []string{"", "13238:1", "13238:1:2:3", "13238:x:1", "13238:1:4294967296"} {
+		_, err := ParseLargeCommunity(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestCommunityString(t *testing.T) {
+	require.Equal(t, "65535:666", Community{ASN: 65535, Value: 666}.String())
+	require.Equal(t, "0x00:0x02:281474976710655", ExtCommunity{Type: 0, SubType: 2, Value: 1<<48 - 1}.String())
+	require.Equal(t, "0x40:0x04:100", ExtCommunity{Type: 0x40, SubType: 4, Value: 100}.String())
+}
+
+func TestRouteSameAttributesCommunities(t *testing.T) {
+	route := Route{
+		Prefix:         netip.MustParsePrefix("10.0.0.0/24"),
+		NextHop:        netip.MustParseAddr("192.0.2.1"),
+		Communities:    []Community{{ASN: 65000, Value: 100}},
+		ExtCommunities: []ExtCommunity{{Type: 0, SubType: 2, Value: 42}},
+	}
+	require.True(t, route.isSameAttributes(route))
+
+	// A re-announcement changing the communities only is not a duplicate.
+	other := route
+	other.Communities = []Community{{ASN: 65000, Value: 200}}
+	require.False(t, route.isSameAttributes(other))
+
+	other = route
+	other.ExtCommunities = nil
+	require.False(t, route.isSameAttributes(other))
+}
@@ -6,6 +6,8 @@ import (
 	"net/netip"
 	"time"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
+
 	commonpb "github.com/yanet-platform/yanet2/common/commonpb/v1"
 	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
 )
@@ -22,6 +24,15 @@ func FromRIBRoute(route *rib.Route, isBest bool) *Route {
 		peer = commonpb.NewIPAddressFromAddr(route.Peer)
 	}
 
+	standard := make([]*Community, 0, len(route.Communities))
+	for _, c := range route.Communities {
+		standard = append(standard, &Community{Asn: uint32(c.ASN), Value: uint32(c.Value)})
+	}
+	extended := make([]*ExtCommunity, 0, len(route.ExtCommunities))
+	for _, c := range route.ExtCommunities {
+		extended = append(extended, &ExtCommunity{Type: uint32(c.Type), SubType: uint32(c.SubType), Value: c.Value})
+	}
+
 	return &Route{
 		Prefix:           route.Prefix.String(),
 		NextHop:          commonpb.NewIPAddressFromAddr(route.NextHop),
@@ -31,8 +42,35 @@ func FromRIBRoute(route *rib.Route, isBest bool) *Route {
 		Med:              route.Med,
 		Pref:             route.Pref,
 		Source:           RouteSourceID(route.SourceID),
+		Communities:      standard,
+		ExtCommunities:   extended,
 		LargeCommunities: communities,
 		IsBest:           isBest,
+		Blackhole:        route.Blackhole,
+		Weight:           route.Weight,
+		Tags:             route.Tags,
+	}
+}
+
+// FromRIBRouteEvent converts an internal rib.RouteEvent to the wire
+// RouteEvent message.
+//
+// The route of a best-change event is reported as best.
+func FromRIBRouteEvent(event *rib.RouteEvent) *RouteEvent {
+	kind := RouteEventKind_ROUTE_EVENT_KIND_UNKNOWN
+	switch event.Kind {
+	case rib.RouteEventAdded:
+		kind = RouteEventKind_ROUTE_EVENT_KIND_ADDED
+	case rib.RouteEventWithdrawn:
+		kind = RouteEventKind_ROUTE_EVENT_KIND_WITHDRAWN
+	case rib.RouteEventBestChanged:
+		kind = RouteEventKind_ROUTE_EVENT_KIND_BEST_CHANGED
+	}
+
+	return &RouteEvent{
+		Kind:      kind,
+		Route:     FromRIBRoute(&event.Route, event.Kind == rib.RouteEventBestChanged),
+		Timestamp: timestamppb.New(event.At),
 	}
 }
 
@@ -63,6 +101,18 @@ func ToRIBRoute(route *Route, toRemove bool) (*rib.Route, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid peer (bytes=%x): %w", route.GetPeer().GetAddr(), err)
 	}
+	communities, err := toRIBCommunities(route.GetCommunities())
+	if err != nil {
+		return nil, err
+	}
+	extCommunities, err := toRIBExtCommunities(route.GetExtCommunities())
+	if err != nil {
+		return nil, err
+	}
+	tags, err := rib.NormalizeTags(route.GetTags())
+	if err != nil {
+		return nil, err
+	}
 	largeCommunities := make([]rib.LargeCommunity, 0, len(route.LargeCommunities))
 	for _, community := range route.LargeCommunities {
 		largeCommunities = append(largeCommunities, rib.LargeCommunity{
@@ -85,6 +135,8 @@ func ToRIBRoute(route *Route, toRemove bool) (*rib.Route, error) {
 		NextHop:          nexthop,
 		Peer:             peer,
 		RD:               route.GetRouteDistinguisher(),
+		Communities:      communities,
+		ExtCommunities:   extCommunities,
 		LargeCommunities: largeCommunities,
 		UpdatedAt:        time.Now(),
 		PeerAS:           route.GetPeerAs(),
@@ -95,10 +147,54 @@ func ToRIBRoute(route *Route, toRemove bool) (*rib.Route, error) {
 		// instead of wrapping to best.
 		ASPathLen: uint8(min(route.GetAsPathLen(), uint32(math.MaxUint8))),
 		SourceID:  sourceID,
+		Blackhole: route.GetBlackhole(),
+		Weight:    route.GetWeight(),
+		Tags:      tags,
 		ToRemove:  toRemove,
 	}, nil
 }
 
+// toRIBCommunities converts wire standard communities, rejecting the ones
+// whose parts do not fit 16 bits.
+func toRIBCommunities(communities []*Community) ([]rib.Community, error) {
+	if len(communities) == 0 {
+		return nil, nil
+	}
+
+	result := make([]rib.Community, 0, len(communities))
+	for _, community := range communities {
+		if community.GetAsn() > math.MaxUint16 || community.GetValue() > math.MaxUint16 {
+			return nil, fmt.Errorf("community %d:%d does not fit 16-bit parts", community.GetAsn(), community.GetValue())
+		}
+		result = append(result, rib.Community{
+			ASN:   uint16(community.GetAsn()),
+			Value: uint16(community.GetValue()),
+		})
+	}
+	return result, nil
+}
+
+// toRIBExtCommunities converts wire extended communities, rejecting the
+// ones whose type, subtype or value are out of range.
+func toRIBExtCommunities(communities []*ExtCommunity) ([]rib.ExtCommunity, error) {
+	if len(communities) == 0 {
+		return nil, nil
+	}
+
+	result := make([]rib.ExtCommunity, 0, len(communities))
+	for _, community := range communities {
+		if community.GetType() > math.MaxUint8 || community.GetSubType() > math.MaxUint8 || community.GetValue() >= 1<<48 {
+			return nil, fmt.Errorf("extended community %d:%d:%d is out of range", community.GetType(), community.GetSubType(), community.GetValue())
+		}
+		result = append(result, rib.ExtCommunity{
+			Type:    uint8(community.GetType()),
+			SubType: uint8(community.GetSubType()),
+			Value:   community.GetValue(),
+		})
+	}
+	return result, nil
+}
+
 // RouteSourceID returns the internal rib.RouteSourceID for an
 // InsertRouteRequest. Defaults to RouteSourceStatic.
 func (m *InsertRouteRequest) RouteSourceID() rib.RouteSourceID {
@@ -120,3 +216,43 @@ func (m *DeleteRouteRequest) RouteSourceID() rib.RouteSourceID {
 		return rib.RouteSourceStatic
 	}
 }
+
+// ToRIBFilter converts the wire RouteFilter to an internal rib.RouteFilter.
+func (m *RouteFilter) ToRIBFilter() (rib.RouteFilter, error) {
+	filter := rib.RouteFilter{}
+
+	if m.GetPrefix() != "" {
+		prefix, err := netip.ParsePrefix(m.GetPrefix())
+		if err != nil {
+			return rib.RouteFilter{}, fmt.Errorf("invalid prefix %q: %w", m.GetPrefix(), err)
+		}
+		filter.Prefix = prefix.Masked()
+	}
+
+	switch m.GetSource() {
+	case RouteSourceID_ROUTE_SOURCE_ID_BIRD:
+		filter.SourceID = rib.RouteSourceBird
+	case RouteSourceID_ROUTE_SOURCE_ID_STATIC:
+		filter.SourceID = rib.RouteSourceStatic
+	}
+
+	if m.GetNextHop() != nil {
+		nexthop, err := m.GetNextHop().ToAddr()
+		if err != nil {
+			return rib.RouteFilter{}, fmt.Errorf("invalid next_hop (bytes=%x): %w", m.GetNextHop().GetAddr(), err)
+		}
+		filter.NextHop = nexthop
+	}
+
+	filter.Tag = m.GetTag()
+
+	if community := m.GetLargeCommunity(); community != nil {
+		filter.LargeCommunity = &rib.LargeCommunity{
+			GlobalAdministrator: community.GetGlobalAdministrator(),
+			LocalDataPart1:      community.GetLocalDataPart1(),
+			LocalDataPart2:      community.GetLocalDataPart2(),
+		}
+	}
+
+	return filter, nil
+}
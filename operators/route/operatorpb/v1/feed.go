@@ -0,0 +1,362 @@
+package operatorpb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrDeadPeer is the cause of a FeedRIB stream aborted because the peer
+// stopped sending heartbeats.
+var ErrDeadPeer = errors.New("FeedRIB peer stopped sending heartbeats")
+
+// Heartbeat configures the keepalives of FeedRIB streams.
+//
+// The heartbeats detect half-open streams left behind by network
+// partitions, which TCP keepalives take minutes to notice. A peer is
+// only declared dead once it sent a heartbeat itself, so either end keeps
+// working with a peer that predates them.
+type Heartbeat struct {
+	// Interval is the period heartbeats are sent at.
+	//
+	// Zero disables both the heartbeats and the dead-peer detection.
+	Interval time.Duration `yaml:"interval"`
+	// DeadInterval is the silence after which the peer is declared dead.
+	DeadInterval time.Duration `yaml:"dead_interval"`
+}
+
+// DefaultHeartbeat returns the heartbeat settings used unless configured.
+func DefaultHeartbeat() Heartbeat {
+	return Heartbeat{
+		Interval:     5 * time.Second,
+		DeadInterval: 15 * time.Second,
+	}
+}
+
+// Validate validates the heartbeat settings.
+func (m *Heartbeat) Validate() error {
+	if m.Interval == 0 {
+		return nil
+	}
+	if m.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	if m.DeadInterval <= m.Interval {
+		return fmt.Errorf("dead_interval must exceed interval %s, got %s", m.Interval, m.DeadInterval)
+	}
+	return nil
+}
+
+// Enabled reports whether heartbeats are sent.
+func (m *Heartbeat) Enabled() bool {
+	return m.Interval > 0
+}
+
+// FeedRIBStream is the sending end of a FeedRIB stream keeping it alive
+// with heartbeats.
+//
+// Send may be called concurrently with the heartbeats, but not with
+// itself.
+type FeedRIBStream struct {
+	stream    RouteService_FeedRIBClient
+	name      string
+	heartbeat Heartbeat
+	cancel    context.CancelCauseFunc
+
+	mu     sync.Mutex
+	closed bool
+	// lastRecv is when the operator last sent a summary, in Unix
+	// nanoseconds; zero until the first one.
+	lastRecv atomic.Int64
+	// lastSent is when an update was last sent, in Unix nanoseconds.
+	lastSent atomic.Int64
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	doneErr   error
+	streamCtx context.Context
+}
+
+// OpenFeedRIB opens a FeedRIB stream of the named config.
+//
+// The heartbeats carry the config name, so that an operator which predates
+// them still accepts one as the first update of the stream.
+func OpenFeedRIB(
+	ctx context.Context,
+	client RouteServiceClient,
+	name string,
+	heartbeat Heartbeat,
+	opts ...grpc.CallOption,
+) (*FeedRIBStream, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stream, err := client.FeedRIB(ctx, opts...)
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	m := &FeedRIBStream{
+		stream:    stream,
+		name:      name,
+		heartbeat: heartbeat,
+		cancel:    cancel,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		streamCtx: ctx,
+	}
+	go m.receive()
+	if heartbeat.Enabled() {
+		go m.keepalive()
+	}
+	return m, nil
+}
+
+// Send sends an update.
+func (m *FeedRIBStream) Send(update *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.stream.Send(update); err != nil {
+		return err
+	}
+	m.lastSent.Store(time.Now().UnixNano())
+	return nil
+}
+
+// Done is closed once the stream ends, whether it was closed by the
+// operator, broken or aborted for a dead peer.
+func (m *FeedRIBStream) Done() <-chan struct{} {
+	return m.done
+}
+
+// Err returns the error the stream ended with once Done is closed, nil
+// if the operator closed it.
+func (m *FeedRIBStream) Err() error {
+	if cause := context.Cause(m.streamCtx); errors.Is(cause, ErrDeadPeer) {
+		return cause
+	}
+	return m.doneErr
+}
+
+// CloseAndRecv closes the sending side and waits for the operator to end
+// the stream.
+func (m *FeedRIBStream) CloseAndRecv() (*UpdateSummary, error) {
+	m.stopOnce.Do(func() { close(m.stop) })
+
+	m.mu.Lock()
+	m.closed = true
+	err := m.stream.CloseSend()
+	m.mu.Unlock()
+	if err != nil {
+		m.cancel(err)
+		return nil, err
+	}
+
+	<-m.done
+	m.cancel(nil)
+	if err := m.Err(); err != nil {
+		return nil, err
+	}
+	return &UpdateSummary{}, nil
+}
+
+func (m *FeedRIBStream) receive() {
+	defer close(m.done)
+	for {
+		if _, err := m.stream.Recv(); err != nil {
+			if err != io.EOF {
+				m.doneErr = err
+			}
+			return
+		}
+		m.lastRecv.Store(time.Now().UnixNano())
+	}
+}
+
+// keepalive sends a heartbeat every interval the stream went without
+// updates, and aborts the stream once the operator stays silent for the
+// dead interval after its first heartbeat.
+func (m *FeedRIBStream) keepalive() {
+	ticker := time.NewTicker(m.heartbeat.Interval)
+	defer ticker.Stop()
+
+	// The first heartbeat is sent at once, so that the operator starts
+	// answering before the dead interval can elapse.
+	m.sendHeartbeat()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			if lastRecv := m.lastRecv.Load(); lastRecv != 0 && now.Sub(time.Unix(0, lastRecv)) > m.heartbeat.DeadInterval {
+				m.cancel(ErrDeadPeer)
+				return
+			}
+			if now.Sub(time.Unix(0, m.lastSent.Load())) >= m.heartbeat.Interval {
+				m.sendHeartbeat()
+			}
+		}
+	}
+}
+
+func (m *FeedRIBStream) sendHeartbeat() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	// A failed send breaks the stream, which the receiver reports.
+	if err := m.stream.Send(&Update{Name: m.name, Heartbeat: true}); err == nil {
+		m.lastSent.Store(time.Now().UnixNano())
+	}
+}
+
+// feedRecv is an update received by the receiver of a FeedRIBServerStream.
+type feedRecv struct {
+	update *Update
+	err    error
+}
+
+// FeedRIBServerStream is the receiving end of a FeedRIB stream answering
+// the heartbeats of the sender.
+//
+// Heartbeats are consumed by Recv, which only returns the other updates.
+// Recv and Send must be called from a single goroutine.
+type FeedRIBServerStream struct {
+	stream    RouteService_FeedRIBServer
+	heartbeat Heartbeat
+
+	requests chan struct{}
+	results  chan feedRecv
+	done     chan struct{}
+	ticker   *time.Ticker
+	// receiving reports whether an update was requested from the receiver.
+	receiving bool
+	// armed reports whether the sender sent a heartbeat.
+	armed    bool
+	lastRecv time.Time
+	// err is the error the stream failed with.
+	err error
+}
+
+// NewFeedRIBServerStream wraps the stream of a FeedRIB call.
+//
+// With heartbeats enabled, the stream is received from by a goroutine,
+// stopped by Close. An update is only received once requested by Recv,
+// so the flow control of the stream is left to the caller.
+func NewFeedRIBServerStream(stream RouteService_FeedRIBServer, heartbeat Heartbeat) *FeedRIBServerStream {
+	m := &FeedRIBServerStream{
+		stream:    stream,
+		heartbeat: heartbeat,
+		lastRecv:  time.Now(),
+	}
+	if !heartbeat.Enabled() {
+		return m
+	}
+
+	m.requests = make(chan struct{})
+	m.results = make(chan feedRecv)
+	m.done = make(chan struct{})
+	m.ticker = time.NewTicker(heartbeat.Interval)
+	go m.receive()
+	return m
+}
+
+// Recv returns the next update other than a heartbeat.
+//
+// Once the sender sent a heartbeat, it is answered every interval, and a
+// sender silent for the dead interval fails Recv with UNAVAILABLE.
+func (m *FeedRIBServerStream) Recv() (*Update, error) {
+	if !m.heartbeat.Enabled() {
+		for {
+			update, err := m.stream.Recv()
+			if err != nil || !update.GetHeartbeat() {
+				return update, err
+			}
+		}
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for {
+		if !m.receiving {
+			m.requests <- struct{}{}
+			m.receiving = true
+		}
+
+		select {
+		case result := <-m.results:
+			m.receiving = false
+			if result.err != nil {
+				m.err = result.err
+				return nil, m.err
+			}
+			m.lastRecv = time.Now()
+			if result.update.GetHeartbeat() {
+				m.armed = true
+				continue
+			}
+			return result.update, nil
+		case now := <-m.ticker.C:
+			if !m.armed {
+				continue
+			}
+			if silence := now.Sub(m.lastRecv); silence > m.heartbeat.DeadInterval {
+				m.err = status.Errorf(codes.Unavailable, "%v for %s", ErrDeadPeer, silence.Truncate(time.Millisecond))
+				return nil, m.err
+			}
+			if err := m.stream.Send(&UpdateSummary{Heartbeat: true}); err != nil {
+				m.err = err
+				return nil, m.err
+			}
+		}
+	}
+}
+
+// Send sends a summary.
+func (m *FeedRIBServerStream) Send(summary *UpdateSummary) error {
+	return m.stream.Send(summary)
+}
+
+// Close stops the receiving goroutine.
+func (m *FeedRIBServerStream) Close() {
+	if m.done == nil {
+		return
+	}
+	m.ticker.Stop()
+	close(m.done)
+}
+
+func (m *FeedRIBServerStream) receive() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.requests:
+		}
+
+		update, err := m.stream.Recv()
+		select {
+		case <-m.done:
+			return
+		case m.results <- feedRecv{update: update, err: err}:
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Package ribsnapshot reads and writes the binary snapshots of a RIB, as
+// downloaded with the DownloadSnapshot RPC of the route operator.
+//
+// A snapshot starts with an uncompressed preamble: the "YRIBSNAP" magic,
+// the big-endian 16-bit format version and the 8-bit compression of the
+// rest of the snapshot, zstd.
+//
+// The compressed body holds the header, the routes and the trailer. The
+// header carries the name of the RIB config, the time the snapshot was
+// taken and the schema: the name and the type of every field of the
+// routes, in the order they are encoded in. Each route is a record, its
+// uvarint length followed by its fields. An empty record ends the routes
+// and is followed by the uvarint number of routes, so that a truncated
+// snapshot is told apart from a complete one.
+//
+// Readers decode the fields by the schema of the snapshot, skipping the
+// fields they do not know and leaving the ones the snapshot lacks zero.
+// Fields may thus be added without changing the version, which is bumped
+// only for changes older readers cannot cope with.
+package ribsnapshot
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// Version is the format version of the written snapshots.
+const Version = 1
+
+// magic opens every snapshot.
+const magic = "YRIBSNAP"
+
+// preambleLen is the length of the magic, the version and the compression.
+const preambleLen = len(magic) + 3
+
+// maxRecordLen bounds the length of a record, so that a corrupted
+// snapshot cannot make the reader allocate arbitrary amounts of memory.
+const maxRecordLen = 1 << 20
+
+// Compression is the compression of the body of a snapshot.
+type Compression uint8
+
+// CompressionZstd compresses the body as a single zstd stream, the only
+// compression of version 1.
+const CompressionZstd Compression = 1
+
+// FieldType is the encoding of a field of the routes.
+type FieldType uint8
+
+const (
+	// FieldUint is an unsigned integer, as a uvarint.
+	FieldUint FieldType = 1
+	// FieldInt is a signed integer, as a varint.
+	FieldInt FieldType = 2
+	// FieldAddr is an IP address, its length in bytes (0, 4 or 16)
+	// followed by the address.
+	FieldAddr FieldType = 3
+	// FieldPrefix is an IP prefix, its address as a FieldAddr followed by
+	// its length in bits.
+	FieldPrefix FieldType = 4
+	// FieldUintList is a list of unsigned integers, the uvarint count
+	// followed by the uvarint values.
+	FieldUintList FieldType = 5
+	// FieldStringList is a list of strings, the uvarint count followed by
+	// the strings, each one its uvarint length and its bytes.
+	FieldStringList FieldType = 6
+)
+
+// String returns the name of the field type.
+func (m FieldType) String() string {
+	switch m {
+	case FieldUint:
+		return "uint"
+	case FieldInt:
+		return "int"
+	case FieldAddr:
+		return "addr"
+	case FieldPrefix:
+		return "prefix"
+	case FieldUintList:
+		return "uint_list"
+	case FieldStringList:
+		return "string_list"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(m))
+	}
+}
+
+// Field describes a field of the routes in the schema of a snapshot.
+type Field struct {
+	Name string
+	Type FieldType
+}
+
+// Header describes a snapshot.
+type Header struct {
+	// Config is the name of the RIB config the snapshot was taken of.
+	Config string
+	// CreatedAt is the time the snapshot was taken.
+	CreatedAt time.Time
+	// Schema lists the fields of the routes in the order they are
+	// encoded in. It is set by the writer.
+	Schema []Field
+}
+
+// value is a decoded field, the member of its type set.
+type value struct {
+	Uint   uint64
+	Int    int64
+	Addr   netip.Addr
+	Prefix netip.Prefix
+	Uints  []uint64
+	Strs   []string
+}
+
+// routeField encodes and decodes a field of the routes.
+type routeField struct {
+	Field
+	put func(v *value, route *rib.Route)
+	set func(route *rib.Route, v *value) error
+}
+
+// fields are the fields of the routes written by this package, set apart
+// from the session bookkeeping of the RIB.
+var fields = []routeField{
+	{
+		Field: Field{Name: "prefix", Type: FieldPrefix},
+		put:   func(v *value, r *rib.Route) { v.Prefix = r.Prefix },
+		set:   func(r *rib.Route, v *value) error { r.Prefix = v.Prefix; return nil },
+	},
+	{
+		Field: Field{Name: "next_hop", Type: FieldAddr},
+		put:   func(v *value, r *rib.Route) { v.Addr = r.NextHop },
+		set:   func(r *rib.Route, v *value) error { r.NextHop = v.Addr; return nil },
+	},
+	{
+		Field: Field{Name: "peer", Type: FieldAddr},
+		put:   func(v *value, r *rib.Route) { v.Addr = r.Peer },
+		set:   func(r *rib.Route, v *value) error { r.Peer = v.Addr; return nil },
+	},
+	{
+		Field: Field{Name: "rd", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = r.RD },
+		set:   func(r *rib.Route, v *value) error { r.RD = v.Uint; return nil },
+	},
+	{
+		Field: Field{Name: "source", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.SourceID) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.SourceID, v.Uint) },
+	},
+	{
+		Field: Field{Name: "peer_as", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.PeerAS) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.PeerAS, v.Uint) },
+	},
+	{
+		Field: Field{Name: "origin_as", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.OriginAS) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.OriginAS, v.Uint) },
+	},
+	{
+		Field: Field{Name: "med", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.Med) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.Med, v.Uint) },
+	},
+	{
+		Field: Field{Name: "pref", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.Pref) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.Pref, v.Uint) },
+	},
+	{
+		Field: Field{Name: "as_path_len", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.ASPathLen) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.ASPathLen, v.Uint) },
+	},
+	{
+		Field: Field{Name: "blackhole", Type: FieldUint},
+		put: func(v *value, r *rib.Route) {
+			if r.Blackhole {
+				v.Uint = 1
+			}
+		},
+		set: func(r *rib.Route, v *value) error { r.Blackhole = v.Uint != 0; return nil },
+	},
+	{
+		Field: Field{Name: "weight", Type: FieldUint},
+		put:   func(v *value, r *rib.Route) { v.Uint = uint64(r.Weight) },
+		set:   func(r *rib.Route, v *value) error { return setUint(&r.Weight, v.Uint) },
+	},
+	{
+		// Each community as ASN<<16 | value.
+		Field: Field{Name: "communities", Type: FieldUintList},
+		put: func(v *value, r *rib.Route) {
+			for _, c := range r.Communities {
+				v.Uints = append(v.Uints, uint64(c.ASN)<<16|uint64(c.Value))
+			}
+		},
+		set: func(r *rib.Route, v *value) error {
+			for _, c := range v.Uints {
+				if c > 0xffffffff {
+					return fmt.Errorf("community %d overflows 32 bits", c)
+				}
+				r.Communities = append(r.Communities, rib.Community{ASN: uint16(c >> 16), Value: uint16(c)})
+			}
+			return nil
+		},
+	},
+	{
+		// Each community as type<<56 | subtype<<48 | value.
+		Field: Field{Name: "ext_communities", Type: FieldUintList},
+		put: func(v *value, r *rib.Route) {
+			for _, c := range r.ExtCommunities {
+				v.Uints = append(v.Uints, uint64(c.Type)<<56|uint64(c.SubType)<<48|c.Value&(1<<48-1))
+			}
+		},
+		set: func(r *rib.Route, v *value) error {
+			for _, c := range v.Uints {
+				r.ExtCommunities = append(r.ExtCommunities, rib.ExtCommunity{
+					Type:    uint8(c >> 56),
+					SubType: uint8(c >> 48),
+					Value:   c & (1<<48 - 1),
+				})
+			}
+			return nil
+		},
+	},
+	{
+		// Three values per community: the global administrator and the
+		// two local data parts.
+		Field: Field{Name: "large_communities", Type: FieldUintList},
+		put: func(v *value, r *rib.Route) {
+			for _, c := range r.LargeCommunities {
+				v.Uints = append(v.Uints,
+					uint64(c.GlobalAdministrator),
+					uint64(c.LocalDataPart1),
+					uint64(c.LocalDataPart2),
+				)
+			}
+		},
+		set: func(r *rib.Route, v *value) error {
+			if len(v.Uints)%3 != 0 {
+				return fmt.Errorf("%d large community values are not triples", len(v.Uints))
+			}
+			for idx := 0; idx < len(v.Uints); idx += 3 {
+				var c rib.LargeCommunity
+				if err := setUint(&c.GlobalAdministrator, v.Uints[idx]); err != nil {
+					return err
+				}
+				if err := setUint(&c.LocalDataPart1, v.Uints[idx+1]); err != nil {
+					return err
+				}
+				if err := setUint(&c.LocalDataPart2, v.Uints[idx+2]); err != nil {
+					return err
+				}
+				r.LargeCommunities = append(r.LargeCommunities, c)
+			}
+			return nil
+		},
+	},
+	{
+		Field: Field{Name: "tags", Type: FieldStringList},
+		put:   func(v *value, r *rib.Route) { v.Strs = r.Tags },
+		set:   func(r *rib.Route, v *value) error { r.Tags = v.Strs; return nil },
+	},
+	{
+		// Unix nanoseconds, zero for an unset time.
+		Field: Field{Name: "updated_at", Type: FieldInt},
+		put: func(v *value, r *rib.Route) {
+			if !r.UpdatedAt.IsZero() {
+				v.Int = r.UpdatedAt.UnixNano()
+			}
+		},
+		set: func(r *rib.Route, v *value) error {
+			if v.Int != 0 {
+				r.UpdatedAt = time.Unix(0, v.Int)
+			}
+			return nil
+		},
+	},
+}
+
+// fieldsByName indexes the known fields for the readers.
+var fieldsByName = func() map[string]*routeField {
+	out := make(map[string]*routeField, len(fields))
+	for idx := range fields {
+		out[fields[idx].Name] = &fields[idx]
+	}
+	return out
+}()
+
+// Schema returns the schema of the snapshots written by this package.
+func Schema() []Field {
+	schema := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		schema = append(schema, f.Field)
+	}
+	return schema
+}
+
+// setUint stores v into the unsigned field, failing if it does not fit.
+func setUint[T ~uint8 | ~uint16 | ~uint32](field *T, v uint64) error {
+	if uint64(T(v)) != v {
+		return fmt.Errorf("value %d overflows the field", v)
+	}
+	*field = T(v)
+	return nil
+}
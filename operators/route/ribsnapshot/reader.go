@@ -0,0 +1,364 @@
+package ribsnapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// maxSchemaFields bounds the number of fields of a schema.
+const maxSchemaFields = 1024
+
+// Reader reads a snapshot, its routes one by one.
+type Reader struct {
+	decoder *zstd.Decoder
+	r       *bufio.Reader
+	header  Header
+	// fields are the fields of the schema of the snapshot known to this
+	// package, nil for the ones to skip.
+	fields []*routeField
+	buf    []byte
+	count  uint64
+	done   bool
+}
+
+// NewReader starts reading the snapshot from r, reading its preamble and
+// header.
+//
+// The reader must be closed to release its decoder.
+func NewReader(r io.Reader) (*Reader, error) {
+	preamble := make([]byte, preambleLen)
+	if _, err := io.ReadFull(r, preamble); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot preamble: %w", err)
+	}
+	if string(preamble[:len(magic)]) != magic {
+		return nil, errors.New("not a RIB snapshot")
+	}
+	if version := binary.BigEndian.Uint16(preamble[len(magic):]); version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", version, Version)
+	}
+	if compression := Compression(preamble[len(magic)+2]); compression != CompressionZstd {
+		return nil, fmt.Errorf("unsupported snapshot compression %d", compression)
+	}
+
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	m := &Reader{decoder: decoder, r: bufio.NewReader(decoder)}
+	if err := m.readHeader(); err != nil {
+		decoder.Close()
+		return nil, fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m *Reader) readHeader() error {
+	config, err := m.readString()
+	if err != nil {
+		return err
+	}
+	createdAt, err := binary.ReadVarint(m.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	m.header.Config = config
+	if createdAt != 0 {
+		m.header.CreatedAt = time.Unix(0, createdAt)
+	}
+
+	count, err := binary.ReadUvarint(m.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if count > maxSchemaFields {
+		return fmt.Errorf("schema of %d fields exceeds %d", count, maxSchemaFields)
+	}
+	for range count {
+		name, err := m.readString()
+		if err != nil {
+			return err
+		}
+		typ, err := m.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		f := Field{Name: name, Type: FieldType(typ)}
+		if f.Type < FieldUint || f.Type > FieldStringList {
+			return fmt.Errorf("field %q has unknown type %s", f.Name, f.Type)
+		}
+
+		known := fieldsByName[f.Name]
+		if known != nil && known.Type != f.Type {
+			return fmt.Errorf("field %q has type %s, expected %s", f.Name, f.Type, known.Type)
+		}
+		m.header.Schema = append(m.header.Schema, f)
+		m.fields = append(m.fields, known)
+	}
+
+	return nil
+}
+
+func (m *Reader) readString() (string, error) {
+	length, err := binary.ReadUvarint(m.r)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if length > maxRecordLen {
+		return "", fmt.Errorf("string of %d bytes exceeds %d", length, maxRecordLen)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(m.r, b); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return string(b), nil
+}
+
+// Header returns the header of the snapshot.
+func (m *Reader) Header() Header {
+	return m.header
+}
+
+// Next returns the next route of the snapshot.
+//
+// It returns io.EOF once the routes are over, and an error wrapping
+// io.ErrUnexpectedEOF if the snapshot is truncated.
+func (m *Reader) Next() (rib.Route, error) {
+	if m.done {
+		return rib.Route{}, io.EOF
+	}
+
+	length, err := binary.ReadUvarint(m.r)
+	if err != nil {
+		return rib.Route{}, unexpectedEOF(err)
+	}
+	if length == 0 {
+		return rib.Route{}, m.readTrailer()
+	}
+	if length > maxRecordLen {
+		return rib.Route{}, fmt.Errorf("route record of %d bytes exceeds %d", length, maxRecordLen)
+	}
+
+	if uint64(cap(m.buf)) < length {
+		m.buf = make([]byte, length)
+	}
+	m.buf = m.buf[:length]
+	if _, err := io.ReadFull(m.r, m.buf); err != nil {
+		return rib.Route{}, unexpectedEOF(err)
+	}
+
+	route, err := m.decodeRoute(m.buf)
+	if err != nil {
+		return rib.Route{}, fmt.Errorf("failed to decode route %d: %w", m.count, err)
+	}
+	m.count++
+
+	return route, nil
+}
+
+func (m *Reader) readTrailer() error {
+	count, err := binary.ReadUvarint(m.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if count != m.count {
+		return fmt.Errorf("snapshot lists %d routes, read %d", count, m.count)
+	}
+	m.done = true
+	return io.EOF
+}
+
+func (m *Reader) decodeRoute(b []byte) (rib.Route, error) {
+	route := rib.Route{}
+	d := decoder{b: b}
+	for idx, f := range m.header.Schema {
+		var v value
+		if err := d.value(f.Type, &v); err != nil {
+			return rib.Route{}, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		if known := m.fields[idx]; known != nil {
+			if err := known.set(&route, &v); err != nil {
+				return rib.Route{}, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+		}
+	}
+	if len(d.b) != 0 {
+		return rib.Route{}, fmt.Errorf("%d trailing bytes", len(d.b))
+	}
+
+	return route, nil
+}
+
+// Close releases the decoder of the reader.
+//
+// It does not close the underlying reader.
+func (m *Reader) Close() {
+	m.decoder.Close()
+}
+
+// ReadAll reads the whole snapshot from r.
+func ReadAll(r io.Reader) (Header, []rib.Route, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer reader.Close()
+
+	routes := []rib.Route{}
+	for {
+		route, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return reader.Header(), routes, nil
+		}
+		if err != nil {
+			return Header{}, nil, err
+		}
+		routes = append(routes, route)
+	}
+}
+
+// unexpectedEOF reports the end of the data in the middle of a snapshot as
+// a truncation.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("snapshot is truncated: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// decoder decodes the fields of a record.
+type decoder struct {
+	b []byte
+}
+
+var errShortRecord = errors.New("record is too short")
+
+func (m *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(m.b)
+	if n <= 0 {
+		return 0, errShortRecord
+	}
+	m.b = m.b[n:]
+	return v, nil
+}
+
+func (m *decoder) varint() (int64, error) {
+	v, n := binary.Varint(m.b)
+	if n <= 0 {
+		return 0, errShortRecord
+	}
+	m.b = m.b[n:]
+	return v, nil
+}
+
+func (m *decoder) bytes(n uint64) ([]byte, error) {
+	if uint64(len(m.b)) < n {
+		return nil, errShortRecord
+	}
+	out := m.b[:n]
+	m.b = m.b[n:]
+	return out, nil
+}
+
+func (m *decoder) addr() (netip.Addr, error) {
+	length, err := m.bytes(1)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	switch length[0] {
+	case 0:
+		return netip.Addr{}, nil
+	case 4, 16:
+		raw, err := m.bytes(uint64(length[0]))
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		addr, _ := netip.AddrFromSlice(raw)
+		return addr, nil
+	default:
+		return netip.Addr{}, fmt.Errorf("address of %d bytes", length[0])
+	}
+}
+
+// count reads the length of a list, bounded by the remaining bytes as
+// every element takes at least one.
+func (m *decoder) count() (uint64, error) {
+	count, err := m.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(len(m.b)) {
+		return 0, errShortRecord
+	}
+	return count, nil
+}
+
+// value decodes a field of the type into the matching member of v.
+func (m *decoder) value(typ FieldType, v *value) error {
+	var err error
+	switch typ {
+	case FieldUint:
+		v.Uint, err = m.uvarint()
+	case FieldInt:
+		v.Int, err = m.varint()
+	case FieldAddr:
+		v.Addr, err = m.addr()
+	case FieldPrefix:
+		var addr netip.Addr
+		if addr, err = m.addr(); err != nil {
+			return err
+		}
+		bits, err := m.bytes(1)
+		if err != nil {
+			return err
+		}
+		if !addr.IsValid() {
+			return nil
+		}
+		if int(bits[0]) > addr.BitLen() {
+			return fmt.Errorf("prefix length %d exceeds %d", bits[0], addr.BitLen())
+		}
+		v.Prefix = netip.PrefixFrom(addr, int(bits[0]))
+	case FieldUintList:
+		var count uint64
+		if count, err = m.count(); err != nil {
+			return err
+		}
+		for range count {
+			u, err := m.uvarint()
+			if err != nil {
+				return err
+			}
+			v.Uints = append(v.Uints, u)
+		}
+	case FieldStringList:
+		var count uint64
+		if count, err = m.count(); err != nil {
+			return err
+		}
+		for range count {
+			length, err := m.uvarint()
+			if err != nil {
+				return err
+			}
+			s, err := m.bytes(length)
+			if err != nil {
+				return err
+			}
+			v.Strs = append(v.Strs, string(s))
+		}
+	default:
+		return fmt.Errorf("unknown field type %s", typ)
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+package ribsnapshot
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+func writeSnapshot(t *testing.T, header Header, routes []rib.Route) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writer, err := NewWriter(buf, header)
+	require.NoError(t, err)
+	for idx := range routes {
+		require.NoError(t, writer.Write(&routes[idx]))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	routes := []rib.Route{
+		{
+			Prefix:           netip.MustParsePrefix("10.0.0.0/8"),
+			NextHop:          netip.MustParseAddr("2001:db8::1"),
+			Peer:             netip.MustParseAddr("192.0.2.1"),
+			RD:               0x0001_0000_0000_0002,
+			Communities:      []rib.Community{{ASN: 65535, Value: 666}},
+			ExtCommunities:   []rib.ExtCommunity{{Type: 0x00, SubType: 0x02, Value: 0xfde8_0000_0064}},
+			LargeCommunities: []rib.LargeCommunity{{GlobalAdministrator: 64512, LocalDataPart1: 1, LocalDataPart2: 2}},
+			UpdatedAt:        time.Unix(1700000000, 42),
+			PeerAS:           64512,
+			OriginAS:         65001,
+			Med:              10,
+			Pref:             100,
+			ASPathLen:        3,
+			SourceID:         rib.RouteSourceBird,
+			Blackhole:        true,
+			Weight:           5,
+			Tags:             []string{"maintenance", "red"},
+		},
+		{
+			Prefix:   netip.MustParsePrefix("2001:db8::/32"),
+			NextHop:  netip.MustParseAddr("fe80::1"),
+			SourceID: rib.RouteSourceStatic,
+		},
+	}
+	createdAt := time.Unix(1700000001, 0)
+
+	data := writeSnapshot(t, Header{Config: "route0", CreatedAt: createdAt}, routes)
+	header, read, err := ReadAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "route0", header.Config)
+	require.True(t, createdAt.Equal(header.CreatedAt))
+	require.Equal(t, Schema(), header.Schema)
+
+	require.Len(t, read, len(routes))
+	require.True(t, routes[0].UpdatedAt.Equal(read[0].UpdatedAt))
+	read[0].UpdatedAt = routes[0].UpdatedAt
+	require.Equal(t, routes, read)
+
+	// The session bookkeeping is not written.
+	data = writeSnapshot(t, Header{}, []rib.Route{{Prefix: routes[1].Prefix, SessionID: 7, ToRemove: true}})
+	header, read, err = ReadAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, header.CreatedAt.IsZero())
+	require.Equal(t, []rib.Route{{Prefix: routes[1].Prefix}}, read)
+}
+
+// TestUnknownFields verifies that readers skip the fields they do not
+// know, as written by a newer writer.
+func TestUnknownFields(t *testing.T) {
+	known := fields
+	t.Cleanup(func() { fields = known })
+	fields = append([]routeField{{
+		Field: Field{Name: "future", Type: FieldStringList},
+		put:   func(v *value, _ *rib.Route) { v.Strs = []string{"a", "b"} },
+	}}, known...)
+
+	route := rib.Route{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Med: 10}
+	data := writeSnapshot(t, Header{Config: "route0"}, []rib.Route{route})
+
+	header, read, err := ReadAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, Field{Name: "future", Type: FieldStringList}, header.Schema[0])
+	require.Equal(t, []rib.Route{route}, read)
+}
+
+func TestTruncated(t *testing.T) {
+	routes := make([]rib.Route, 100)
+	for idx := range routes {
+		routes[idx].Prefix = netip.PrefixFrom(netip.AddrFrom4([4]byte{10, 0, byte(idx), 0}), 24)
+	}
+	data := writeSnapshot(t, Header{Config: "route0"}, routes)
+
+	_, _, err := ReadAll(bytes.NewReader(data[:len(data)-1]))
+	require.Error(t, err)
+
+	// A reader stopping early sees its routes complete.
+	reader, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer reader.Close()
+	for range routes {
+		_, err := reader.Next()
+		require.NoError(t, err)
+	}
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.EOF)
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestInvalidPreamble(t *testing.T) {
+	data := writeSnapshot(t, Header{Config: "route0"}, nil)
+
+	for _, corrupt := range []func([]byte){
+		func(b []byte) { b[0] = 'X' },
+		func(b []byte) { b[len(magic)+1] = Version + 1 },
+		func(b []byte) { b[len(magic)+2] = 0 },
+	} {
+		b := bytes.Clone(data)
+		corrupt(b)
+		_, err := NewReader(bytes.NewReader(b))
+		require.Error(t, err)
+	}
+
+	_, err := NewReader(bytes.NewReader(data[:4]))
+	require.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package ribsnapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/yanet-platform/yanet2/operators/route/internal/rib"
+)
+
+// Writer writes a snapshot, its routes one by one.
+type Writer struct {
+	encoder *zstd.Encoder
+	// buf is the record being encoded, reused across routes.
+	buf    []byte
+	v      value
+	count  uint64
+	closed bool
+}
+
+// NewWriter starts the snapshot described by the header on w, writing the
+// preamble and the header.
+//
+// The schema of the header is replaced by the one of this package.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	preamble := binary.BigEndian.AppendUint16([]byte(magic), Version)
+	preamble = append(preamble, byte(CompressionZstd))
+	if _, err := w.Write(preamble); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot preamble: %w", err)
+	}
+
+	encoder, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+
+	b := appendString(nil, header.Config)
+	createdAt := int64(0)
+	if !header.CreatedAt.IsZero() {
+		createdAt = header.CreatedAt.UnixNano()
+	}
+	b = binary.AppendVarint(b, createdAt)
+	b = binary.AppendUvarint(b, uint64(len(fields)))
+	for _, f := range fields {
+		b = appendString(b, f.Name)
+		b = append(b, byte(f.Type))
+	}
+	if _, err := encoder.Write(b); err != nil {
+		encoder.Close()
+		return nil, fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+
+	return &Writer{encoder: encoder}, nil
+}
+
+// Write appends the route to the snapshot.
+//
+// The session bookkeeping of the route, its session and withdrawal flag,
+// is not written.
+func (m *Writer) Write(route *rib.Route) error {
+	if m.closed {
+		return errors.New("snapshot writer is closed")
+	}
+
+	record := m.buf[:0]
+	for _, f := range fields {
+		m.v = value{Uints: m.v.Uints[:0]}
+		f.put(&m.v, route)
+		record = appendValue(record, f.Type, &m.v)
+	}
+	m.buf = record
+
+	header := binary.AppendUvarint(nil, uint64(len(record)))
+	if _, err := m.encoder.Write(header); err != nil {
+		return fmt.Errorf("failed to write route: %w", err)
+	}
+	if _, err := m.encoder.Write(record); err != nil {
+		return fmt.Errorf("failed to write route: %w", err)
+	}
+	m.count++
+
+	return nil
+}
+
+// Close writes the trailer and flushes the snapshot.
+//
+// It does not close the underlying writer.
+func (m *Writer) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	trailer := binary.AppendUvarint([]byte{0}, m.count)
+	if _, err := m.encoder.Write(trailer); err != nil {
+		m.encoder.Close()
+		return fmt.Errorf("failed to write snapshot trailer: %w", err)
+	}
+	if err := m.encoder.Close(); err != nil {
+		return fmt.Errorf("failed to flush snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendAddr(b []byte, addr netip.Addr) []byte {
+	if !addr.IsValid() {
+		return append(b, 0)
+	}
+	raw := addr.AsSlice()
+	b = append(b, byte(len(raw)))
+	return append(b, raw...)
+}
+
+// appendValue appends the member of v the type selects.
+func appendValue(b []byte, typ FieldType, v *value) []byte {
+	switch typ {
+	case FieldUint:
+		return binary.AppendUvarint(b, v.Uint)
+	case FieldInt:
+		return binary.AppendVarint(b, v.Int)
+	case FieldAddr:
+		return appendAddr(b, v.Addr)
+	case FieldPrefix:
+		b = appendAddr(b, v.Prefix.Addr())
+		return append(b, byte(v.Prefix.Bits()))
+	case FieldUintList:
+		b = binary.AppendUvarint(b, uint64(len(v.Uints)))
+		for _, u := range v.Uints {
+			b = binary.AppendUvarint(b, u)
+		}
+		return b
+	case FieldStringList:
+		b = binary.AppendUvarint(b, uint64(len(v.Strs)))
+		for _, s := range v.Strs {
+			b = appendString(b, s)
+		}
+		return b
+	default:
+		panic(fmt.Sprintf("unexpected field type %s", typ))
+	}
+}